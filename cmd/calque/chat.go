@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/calque-ai/go-calque/pkg/flowconfig"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/repl"
+)
+
+func cmdChat(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("chat", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	file := flags.String("f", "flow.yaml", "flow file to chat with")
+	noMemory := flags.Bool("no-memory", false, "send each message on its own, without earlier turns")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: calque chat -f flow.yaml [-no-memory]")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	def, err := flowconfig.Load(*file)
+	if err != nil {
+		return err
+	}
+	usage := &repl.Usage{}
+	flow, err := def.BuildWithConfig(&flowconfig.BuildConfig{
+		AgentOptions: []ai.AgentOption{ai.WithUsageHandler(usage.Record)},
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	name := def.Name
+	if name == "" {
+		name = *file
+	}
+	return repl.RunWithConfig(flow, &repl.Config{
+		Context:       ctx,
+		Input:         stdin,
+		Output:        stdout,
+		Greeting:      fmt.Sprintf("Chatting with %s. Type /help for commands, /exit to quit.", name),
+		DisableMemory: *noMemory,
+		Usage:         usage,
+	})
+}
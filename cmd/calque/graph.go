@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/flowconfig"
+)
+
+func cmdGraph(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("graph", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	file := flags.String("f", "flow.yaml", "flow file to draw")
+	format := flags.String("format", string(calque.DiagramMermaid), "diagram format: mermaid or dot")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: calque graph -f flow.yaml [-format mermaid|dot]")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	def, err := flowconfig.Load(*file)
+	if err != nil {
+		return err
+	}
+	flow, err := def.BuildWithConfig(&flowconfig.BuildConfig{Wrap: labelStep})
+	if err != nil {
+		return err
+	}
+	diagram, err := flow.Visualize(calque.DiagramFormat(*format))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, diagram)
+	return err
+}
+
+// labelStep names plain steps after their flow file entry; structured ones
+// such as ctrl.timeout keep drawing their own shape
+func labelStep(_ int, step flowconfig.Step, handler calque.Handler) calque.Handler {
+	if _, ok := handler.(calque.Describer); ok {
+		return handler
+	}
+	return calque.Described(handler, func() calque.Node {
+		return calque.Node{Label: step.Label()}
+	})
+}
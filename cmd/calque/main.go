@@ -0,0 +1,89 @@
+// Command calque scaffolds, runs, chats with and inspects flows defined in YAML.
+//
+// Usage:
+//
+//	calque new [-module path] <template> [dir]   create a project from a template
+//	calque run -f flow.yaml [--input text]       run a flow file, recording a trace
+//	calque chat -f flow.yaml                     chat with a flow interactively
+//	calque trace [run-id]                        list recorded runs or show one
+//	calque graph -f flow.yaml [-format dot]      draw a flow file as a Mermaid or DOT diagram
+//
+// Flow files are described in package flowconfig. Input for run comes from
+// --input, from a file with --input @path, or from stdin.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	os.Exit(cli(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+const usage = `calque scaffolds, runs, chats with and inspects flows defined in YAML.
+
+Usage:
+  calque new [-module path] <template> [dir]   create a project from a template
+  calque run -f flow.yaml [--input text]       run a flow file, recording a trace
+  calque chat -f flow.yaml                     chat with a flow interactively
+  calque trace [run-id]                        list recorded runs or show one
+  calque graph -f flow.yaml [-format dot]      draw a flow file as a Mermaid or DOT diagram
+
+Run "calque <command> -h" for command flags.
+`
+
+// cli runs a command and returns the process exit code
+func cli(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "new":
+		err = cmdNew(args[1:], stdout, stderr)
+	case "run":
+		err = cmdRun(args[1:], stdin, stdout, stderr)
+	case "chat":
+		err = cmdChat(args[1:], stdin, stdout, stderr)
+	case "trace":
+		err = cmdTrace(args[1:], stdout, stderr)
+	case "graph":
+		err = cmdGraph(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "calque: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		fmt.Fprintf(stderr, "calque %s: %v\n", args[0], err)
+		return 1
+	}
+}
+
+// errUsage reports bad arguments whose message has already been printed
+var errUsage = errors.New("usage")
+
+// parseFlags parses command flags, printing problems to the flag set's output
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/flowconfig"
+)
+
+const mockFlow = `
+name: greeter
+provider:
+  type: mock
+  response: "  Hello from the mock  "
+steps:
+  - use: prompt.system
+    with:
+      text: Be friendly.
+  - use: ai.agent
+    name: agent
+  - use: text.trim
+`
+
+func runCLI(t *testing.T, stdin string, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code = cli(args, strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func writeFlow(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "flow.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNew(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mybot")
+	code, stdout, stderr := runCLI(t, "", "new", "-module", "example.com/mybot", "agent-flow", dir)
+	if code != 0 {
+		t.Fatalf("exit = %d, stderr = %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "calque run") {
+		t.Errorf("stdout missing next steps: %s", stdout)
+	}
+
+	for _, name := range []string{"flow.yaml", "main.go", "go.mod", "README.md"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("missing %s: %v", name, err)
+		}
+	}
+	gomod, _ := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if !strings.HasPrefix(string(gomod), "module example.com/mybot\n") {
+		t.Errorf("go.mod = %q", gomod)
+	}
+	flow, _ := os.ReadFile(filepath.Join(dir, "flow.yaml"))
+	if _, err := flowconfig.Parse(flow); err != nil {
+		t.Errorf("scaffolded flow.yaml doesn't parse: %v", err)
+	}
+
+	if code, _, stderr := runCLI(t, "", "new", "agent-flow", dir); code != 1 || !strings.Contains(stderr, "not empty") {
+		t.Errorf("second scaffold exit = %d, stderr = %s", code, stderr)
+	}
+	if code, _, stderr := runCLI(t, "", "new", "nope", filepath.Join(t.TempDir(), "x")); code != 1 || !strings.Contains(stderr, "agent-flow") {
+		t.Errorf("unknown template exit = %d, stderr = %s", code, stderr)
+	}
+	if code, _, _ := runCLI(t, "", "new"); code != 2 {
+		t.Errorf("missing template exit = %d, want 2", code)
+	}
+}
+
+func TestRunAndTrace(t *testing.T) {
+	flow := writeFlow(t, mockFlow)
+	traces := filepath.Join(t.TempDir(), "traces")
+
+	code, stdout, stderr := runCLI(t, "", "run", "-f", flow, "-trace-dir", traces, "-input", "hi")
+	if code != 0 {
+		t.Fatalf("exit = %d, stderr = %s", code, stderr)
+	}
+	if stdout != "Hello from the mock\n" {
+		t.Errorf("stdout = %q", stdout)
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(traces, "*.json"))
+	if len(paths) != 1 {
+		t.Fatalf("traces = %v", paths)
+	}
+	data, _ := os.ReadFile(paths[0])
+	var trace runTrace
+	if err := json.Unmarshal(data, &trace); err != nil {
+		t.Fatal(err)
+	}
+	if trace.Flow != "greeter" || trace.Input != "hi" || trace.Output != "Hello from the mock" || len(trace.Steps) != 3 {
+		t.Fatalf("trace = %+v", trace)
+	}
+	if s := trace.Steps[1]; s.Name != "agent" || s.Use != "ai.agent" || strings.TrimSpace(s.Output) != "Hello from the mock" {
+		t.Errorf("agent step = %+v", s)
+	}
+	if !strings.Contains(stderr, trace.ID) {
+		t.Errorf("stderr doesn't mention run id: %s", stderr)
+	}
+
+	code, stdout, _ = runCLI(t, "", "trace", "-dir", traces)
+	if code != 0 || !strings.Contains(stdout, trace.ID) || !strings.Contains(stdout, "greeter") {
+		t.Errorf("trace list exit = %d, stdout = %s", code, stdout)
+	}
+
+	code, stdout, stderr = runCLI(t, "", "trace", "-dir", traces, trace.ID[:6])
+	if code != 0 {
+		t.Fatalf("trace show exit = %d, stderr = %s", code, stderr)
+	}
+	for _, want := range []string{"run " + trace.ID, "Input:", "  hi", "2.", "agent", "Output:", "ok"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("trace output missing %q:\n%s", want, stdout)
+		}
+	}
+
+	if code, _, stderr := runCLI(t, "", "trace", "-dir", traces, "zzz"); code != 1 || !strings.Contains(stderr, "no trace") {
+		t.Errorf("unknown run exit = %d, stderr = %s", code, stderr)
+	}
+}
+
+func TestRunInputSources(t *testing.T) {
+	flow := writeFlow(t, "steps:\n  - use: text.upper\n")
+	inputFile := filepath.Join(t.TempDir(), "in.txt")
+	if err := os.WriteFile(inputFile, []byte("from file"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+		want  string
+	}{
+		{name: "flag", args: []string{"-input", "from flag"}, want: "FROM FLAG\n"},
+		{name: "file", args: []string{"-input", "@" + inputFile}, want: "FROM FILE\n"},
+		{name: "stdin", stdin: "from stdin\n", want: "FROM STDIN\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"run", "-no-trace", "-f", flow}, tt.args...)
+			code, stdout, stderr := runCLI(t, tt.stdin, args...)
+			if code != 0 || stdout != tt.want {
+				t.Errorf("exit = %d, stdout = %q, stderr = %s", code, stdout, stderr)
+			}
+		})
+	}
+}
+
+func TestRunFailureIsTraced(t *testing.T) {
+	flow := writeFlow(t, "steps:\n  - use: ctrl.timeout\n    name: slow\n    with:\n      duration: 1ns\n      step: {use: ctrl.ratelimit, with: {rate: 1, per: 1h}}\n")
+	traces := filepath.Join(t.TempDir(), "traces")
+
+	code, _, stderr := runCLI(t, "", "run", "-f", flow, "-trace-dir", traces, "-input", "x")
+	if code != 1 {
+		t.Fatalf("exit = %d, want 1; stderr = %s", code, stderr)
+	}
+	code, stdout, _ := runCLI(t, "", "trace", "-dir", traces)
+	if code != 0 || !strings.Contains(stdout, "failed") {
+		t.Errorf("trace list = %s", stdout)
+	}
+}
+
+func TestChat(t *testing.T) {
+	flow := writeFlow(t, mockFlow)
+
+	code, stdout, stderr := runCLI(t, "hi\n/memory\n/usage\n/exit\n", "chat", "-f", flow)
+	if code != 0 {
+		t.Fatalf("exit = %d, stderr = %s", code, stderr)
+	}
+	for _, want := range []string{"Chatting with greeter", "> Hello from the mock\n", "user: hi", "assistant: Hello from the mock", "turns: 1"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestGraph(t *testing.T) {
+	flow := writeFlow(t, "provider: {type: mock}\nsteps:\n  - use: prompt.system\n    with: {text: hi}\n  - use: ctrl.timeout\n    with: {duration: 5s, step: {use: ai.agent}}\n")
+
+	code, stdout, stderr := runCLI(t, "", "graph", "-f", flow)
+	if code != 0 {
+		t.Fatalf("exit = %d, stderr = %s", code, stderr)
+	}
+	for _, want := range []string{"flowchart TD", `["prompt.system"]`, `subgraph c1["timeout 5s"]`, `["ai.Agent"]`} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("mermaid missing %q:\n%s", want, stdout)
+		}
+	}
+
+	code, stdout, _ = runCLI(t, "", "graph", "-f", flow, "-format", "dot")
+	if code != 0 || !strings.HasPrefix(stdout, "digraph flow {") {
+		t.Errorf("dot exit = %d, stdout = %s", code, stdout)
+	}
+	if code, _, stderr := runCLI(t, "", "graph", "-f", flow, "-format", "png"); code != 1 || !strings.Contains(stderr, "unknown diagram format") {
+		t.Errorf("bad format exit = %d, stderr = %s", code, stderr)
+	}
+}
+
+func TestCLIErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "no command", args: nil, wantCode: 2, wantErr: "Usage"},
+		{name: "unknown command", args: []string{"deploy"}, wantCode: 2, wantErr: `unknown command "deploy"`},
+		{name: "bad flag", args: []string{"run", "-bogus"}, wantCode: 2, wantErr: "bogus"},
+		{name: "missing flow", args: []string{"run", "-f", "does-not-exist.yaml", "-input", "x"}, wantCode: 1, wantErr: "calque run:"},
+		{name: "help", args: []string{"run", "-h"}, wantCode: 0, wantErr: "Usage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := runCLI(t, "", tt.args...)
+			if code != tt.wantCode || !strings.Contains(stderr, tt.wantErr) {
+				t.Errorf("exit = %d, stderr = %q; want %d, %q", code, stderr, tt.wantCode, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// scaffoldData is available to .tmpl files as template data
+type scaffoldData struct {
+	Name   string // Project directory name
+	Module string // Go module path
+}
+
+func cmdNew(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("new", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	module := flags.String("module", "", "Go module path (default: the directory name)")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: calque new [-module path] <template> [dir]\n\nTemplates: %s\n\n", strings.Join(templateNames(), ", "))
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		return errUsage
+	}
+
+	name := flags.Arg(0)
+	dir := name
+	if flags.NArg() == 2 {
+		dir = flags.Arg(1)
+	}
+	data := scaffoldData{Name: filepath.Base(dir), Module: *module}
+	if data.Module == "" {
+		data.Module = data.Name
+	}
+
+	files, err := scaffold(name, dir, data)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		fmt.Fprintf(stdout, "  created %s\n", f)
+	}
+	fmt.Fprintf(stdout, "\nNext:\n  cd %s\n  calque run -f flow.yaml --input \"Hello\"\n", dir)
+	return nil
+}
+
+// scaffold writes template name into dir, executing .tmpl files, and returns the created paths
+func scaffold(name, dir string, data scaffoldData) ([]string, error) {
+	root := path.Join("templates", name)
+	if _, err := fs.Stat(templates, root); err != nil {
+		return nil, fmt.Errorf("unknown template %q (available: %s)", name, strings.Join(templateNames(), ", "))
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("directory %s already exists and is not empty", dir)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	var created []string
+	err := fs.WalkDir(templates, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := templates.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		rel := strings.TrimPrefix(p, root+"/")
+		if strings.HasSuffix(rel, ".tmpl") {
+			rel = strings.TrimSuffix(rel, ".tmpl")
+			tmpl, err := template.New(rel).Parse(string(content))
+			if err != nil {
+				return err
+			}
+			var b strings.Builder
+			if err := tmpl.Execute(&b, data); err != nil {
+				return err
+			}
+			content = []byte(b.String())
+		}
+
+		dst := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, content, 0o644); err != nil {
+			return err
+		}
+		created = append(created, dst)
+		return nil
+	})
+	return created, err
+}
+
+// templateNames lists the embedded project templates
+func templateNames() []string {
+	entries, _ := templates.ReadDir("templates")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
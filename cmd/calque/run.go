@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/flowconfig"
+)
+
+// defaultTraceDir is where run records traces and trace reads them
+const defaultTraceDir = ".calque/traces"
+
+// maxCapture bounds how much of each input and output a trace keeps
+const maxCapture = 16 << 10
+
+func cmdRun(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	file := flags.String("f", "flow.yaml", "flow file to run")
+	input := flags.String("input", "", "input text, or @path to read a file (default: stdin)")
+	traceDir := flags.String("trace-dir", defaultTraceDir, "directory for run traces")
+	noTrace := flags.Bool("no-trace", false, "don't record a trace")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: calque run -f flow.yaml [--input text | --input @file]")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	in, err := readInput(*input, stdin)
+	if err != nil {
+		return err
+	}
+	def, err := flowconfig.Load(*file)
+	if err != nil {
+		return err
+	}
+
+	trace := &runTrace{ID: newRunID(), Flow: def.Name, File: *file, Input: capture(in)}
+	rec := &recorder{trace: trace}
+	flow, err := def.BuildWithConfig(&flowconfig.BuildConfig{Wrap: rec.wrap})
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx = calque.WithRequestID(ctx, trace.ID)
+
+	output := &limitedBuffer{limit: maxCapture}
+	trace.Started = time.Now()
+	runErr := flow.Run(ctx, in, io.MultiWriter(stdout, output))
+	trace.Duration = time.Since(trace.Started)
+	trace.Output, trace.Truncated = output.String(), output.truncated
+	if runErr != nil {
+		trace.Error = runErr.Error()
+	}
+	if !strings.HasSuffix(output.String(), "\n") {
+		fmt.Fprintln(stdout)
+	}
+
+	if !*noTrace {
+		path, err := saveTrace(*traceDir, trace)
+		if err != nil {
+			fmt.Fprintf(stderr, "calque run: failed to save trace: %v\n", err)
+		} else {
+			fmt.Fprintf(stderr, "run %s (%s), trace: %s\n", trace.ID, trace.Duration.Round(time.Millisecond), path)
+		}
+	}
+	return runErr
+}
+
+// readInput resolves the --input flag: literal text, @file or stdin
+func readInput(flagValue string, stdin io.Reader) (string, error) {
+	switch {
+	case strings.HasPrefix(flagValue, "@"):
+		data, err := os.ReadFile(flagValue[1:])
+		return string(data), err
+	case flagValue != "":
+		return flagValue, nil
+	default:
+		data, err := io.ReadAll(stdin)
+		return string(data), err
+	}
+}
+
+// recorder fills in a trace's steps as the flow runs
+type recorder struct {
+	mu    sync.Mutex
+	trace *runTrace
+}
+
+// wrap records timing, output and errors for one top-level step
+func (r *recorder) wrap(index int, step flowconfig.Step, handler calque.Handler) calque.Handler {
+	r.trace.Steps = append(r.trace.Steps, stepTrace{Index: index + 1, Name: step.Label(), Use: step.Use})
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		out := &limitedBuffer{limit: maxCapture}
+		started := time.Now()
+		err := handler.ServeFlow(req, calque.NewResponse(io.MultiWriter(res.Data, out)))
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		s := &r.trace.Steps[index]
+		s.Started = started
+		s.Duration = time.Since(started)
+		s.Output, s.Truncated = out.String(), out.truncated
+		if err != nil {
+			s.Error = err.Error()
+		}
+		return err
+	})
+}
+
+func newRunID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func capture(s string) string {
+	if len(s) > maxCapture {
+		return strings.ToValidUTF8(s[:maxCapture], "")
+	}
+	return s
+}
+
+// limitedBuffer keeps the first limit bytes written and discards the rest
+type limitedBuffer struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		b.buf.Write(p[:max(room, 0)])
+		b.truncated = true
+	} else {
+		b.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.truncated {
+		return strings.ToValidUTF8(b.buf.String(), "")
+	}
+	return b.buf.String()
+}
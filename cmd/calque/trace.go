@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runTrace is the record calque run writes for each execution
+type runTrace struct {
+	ID        string        `json:"id"`
+	Flow      string        `json:"flow,omitempty"`
+	File      string        `json:"file"`
+	Input     string        `json:"input"`
+	Output    string        `json:"output"`
+	Truncated bool          `json:"truncated,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Started   time.Time     `json:"started"`
+	Duration  time.Duration `json:"duration"`
+	Steps     []stepTrace   `json:"steps"`
+}
+
+// stepTrace records one top-level step of a run
+type stepTrace struct {
+	Index     int           `json:"index"`
+	Name      string        `json:"name"`
+	Use       string        `json:"use"`
+	Started   time.Time     `json:"started,omitzero"`
+	Duration  time.Duration `json:"duration"`
+	Output    string        `json:"output"`
+	Truncated bool          `json:"truncated,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+func saveTrace(dir string, trace *runTrace) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(trace, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, trace.ID+".json")
+	return path, os.WriteFile(path, data, 0o644)
+}
+
+func loadTrace(path string) (*runTrace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var trace runTrace
+	if err := json.Unmarshal(data, &trace); err != nil {
+		return nil, fmt.Errorf("invalid trace %s: %w", path, err)
+	}
+	return &trace, nil
+}
+
+func cmdTrace(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("trace", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dir := flags.String("dir", defaultTraceDir, "directory holding run traces")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: calque trace [-dir path] [run-id]")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	switch flags.NArg() {
+	case 0:
+		return listTraces(*dir, stdout)
+	case 1:
+		path, err := findTrace(*dir, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		trace, err := loadTrace(path)
+		if err != nil {
+			return err
+		}
+		printTrace(stdout, trace)
+		return nil
+	default:
+		flags.Usage()
+		return errUsage
+	}
+}
+
+// findTrace resolves a run ID or a unique prefix of one to its trace file
+func findTrace(dir, id string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, id+"*.json"))
+	if err != nil {
+		return "", err
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no trace for run %q in %s", id, dir)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("run id %q is ambiguous (%d matches)", id, len(matches))
+	}
+}
+
+func listTraces(dir string, w io.Writer) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	traces := make([]*runTrace, 0, len(paths))
+	for _, p := range paths {
+		trace, err := loadTrace(p)
+		if err != nil {
+			continue
+		}
+		traces = append(traces, trace)
+	}
+	if len(traces) == 0 {
+		if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) || err == nil {
+			fmt.Fprintf(w, "no runs recorded in %s\n", dir)
+			return nil
+		}
+		return err
+	}
+	sort.Slice(traces, func(i, j int) bool { return traces[i].Started.After(traces[j].Started) })
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RUN\tSTARTED\tDURATION\tSTATUS\tFLOW")
+	for _, t := range traces {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", t.ID, t.Started.Local().Format(time.DateTime),
+			t.Duration.Round(time.Millisecond), status(t.Error), flowName(t))
+	}
+	return tw.Flush()
+}
+
+func printTrace(w io.Writer, t *runTrace) {
+	fmt.Fprintf(w, "run %s  %s  %s  %s\n", t.ID, flowName(t), status(t.Error), t.Duration.Round(time.Millisecond))
+	fmt.Fprintf(w, "started %s\n\n", t.Started.Local().Format(time.DateTime))
+
+	fmt.Fprintln(w, "Input:")
+	fmt.Fprintln(w, indent(t.Input))
+
+	fmt.Fprintln(w, "\nSteps:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, s := range t.Steps {
+		detail := preview(s.Output, s.Truncated)
+		if s.Error != "" {
+			detail = s.Error
+		}
+		fmt.Fprintf(tw, "  %d.\t%s\t%s\t%s\t%s\n", s.Index, s.Name, s.Duration.Round(time.Millisecond), status(s.Error), detail)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "\nOutput:")
+	out := t.Output
+	if t.Truncated {
+		out += "…"
+	}
+	fmt.Fprintln(w, indent(out))
+	if t.Error != "" {
+		fmt.Fprintf(w, "\nError:\n%s\n", indent(t.Error))
+	}
+}
+
+func status(errMsg string) string {
+	if errMsg != "" {
+		return "failed"
+	}
+	return "ok"
+}
+
+func flowName(t *runTrace) string {
+	if t.Flow != "" {
+		return t.Flow
+	}
+	return t.File
+}
+
+// preview returns the first line of s, shortened to fit a table row
+func preview(s string, truncated bool) string {
+	const width = 80
+	s = strings.TrimSpace(s)
+	line, rest, more := strings.Cut(s, "\n")
+	runes := []rune(line)
+	if len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	if more && rest != "" || truncated {
+		return line + " …"
+	}
+	return line
+}
+
+func indent(s string) string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return "  (empty)"
+	}
+	return "  " + strings.ReplaceAll(s, "\n", "\n  ")
+}
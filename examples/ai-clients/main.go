@@ -7,11 +7,8 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
-	"github.com/joho/godotenv"
-
 	"github.com/calque-ai/go-calque/pkg/calque"
 	"github.com/calque-ai/go-calque/pkg/helpers"
 	"github.com/calque-ai/go-calque/pkg/middleware/ai"
@@ -64,20 +61,18 @@ func ollamaExample() {
 
 func geminiExample() {
 
-	// Load environment variables from .env file
-	// Make sure to have GOOGLE_API_KEY set in your .env file
-	err := godotenv.Load(".env")
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-
-	// Create an optional custom gemini configuration
-	config := &gemini.Config{
-		Temperature: helpers.PtrOf(float32(1.1)),
+	// Layer the .env file and GOOGLE_* environment variables onto the defaults
+	// Make sure to have GOOGLE_API_KEY set in your .env file or environment
+	config := gemini.DefaultConfig()
+	if err := ai.LoadConfig(config, ai.ConfigSources{DotEnv: ".env", EnvPrefix: "GOOGLE"}); err != nil {
+		log.Fatal(err)
 	}
 
-	// Create Gemini example client (reads GOOGLE_API_KEY from env unless set in the config)
-	client, err := gemini.New("gemini-2.0-flash", gemini.WithConfig(config))
+	// Settings in code are applied last, over the loaded config
+	client, err := gemini.New("gemini-2.0-flash",
+		gemini.WithConfig(config),
+		gemini.WithConfig(&gemini.Config{Temperature: helpers.PtrOf(float32(1.1))}),
+	)
 	if err != nil {
 		log.Fatal("Failed to create Gemini client:", err)
 	}
@@ -105,29 +100,22 @@ func geminiExample() {
 
 func openaiExample() {
 
-	// Load environment variables from .env file
-	// Make sure to have OPENAI_API_KEY set in your .env file
-	err := godotenv.Load(".env")
-	if err != nil {
-		log.Printf("Warning: Could not load .env file: %v", err)
+	// Layer the .env file and OPENAI_* environment variables (OPENAI_API_KEY,
+	// OPENAI_TEMPERATURE, OPENAI_MAX_TOKENS, ...) onto the defaults
+	config := openai.DefaultConfig()
+	config.MaxTokens = helpers.PtrOf(150) // default unless the environment sets OPENAI_MAX_TOKENS
+	if err := ai.LoadConfig(config, ai.ConfigSources{DotEnv: ".env", EnvPrefix: "OPENAI"}); err != nil {
+		log.Printf("Invalid OpenAI configuration: %v", err)
+		return
+	}
+	if config.APIKey == "" {
 		log.Println("To run OpenAI example:")
 		log.Println("  1. Get API key from: https://platform.openai.com/api-keys")
 		log.Println("  2. Create .env file with: OPENAI_API_KEY=your_api_key")
 		return
 	}
 
-	// Create an optional custom OpenAI configuration
-	config := &openai.Config{
-		APIKey:           os.Getenv("OPENAI_API_KEY"), // Load from env
-		Temperature:      helpers.PtrOf(float32(1.0)),
-		TopP:             helpers.PtrOf(float32(1.0)),
-		N:                helpers.PtrOf(1),
-		PresencePenalty:  helpers.PtrOf(float32(0.0)),
-		FrequencyPenalty: helpers.PtrOf(float32(0.0)),
-		MaxTokens:        helpers.PtrOf(150),
-	}
-
-	// Create OpenAI client (reads OPENAI_API_KEY from env unless set in the config)
+	// Create OpenAI client from the loaded config
 	client, err := openai.New("gpt-4o-mini", openai.WithConfig(config))
 	if err != nil {
 		log.Printf("Could not connect to OpenAI: %v", err)
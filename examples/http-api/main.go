@@ -3,7 +3,9 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -38,9 +40,19 @@ func main() {
 	http.HandleFunc("POST /agent", handleAgent(agentFlow))
 
 	// Start the HTTP server
+	server := &http.Server{Addr: ":8080", ReadHeaderTimeout: 10 * time.Second}
 	fmt.Println("\nServer starting on port 8080...")
 	fmt.Println("Try: curl -X POST http://localhost:8080/agent -H 'Content-Type: application/json' -d '{\"message\":\"hello world\",\"user_id\":\"123\"}'")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	// On SIGINT/SIGTERM stop accepting connections, then let in-flight runs finish
+	if err := calque.GracefulShutdown(context.Background(), 25*time.Second, server, agentFlow); err != nil {
+		log.Printf("shutdown: %v", err)
+	}
 }
 
 // createAgentFlow builds the processing flow that will handle requests
@@ -0,0 +1,195 @@
+package calque
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Common content types used when handlers declare what they accept and produce.
+const (
+	ContentTypeAny    = ""
+	ContentTypeText   = "text/plain"
+	ContentTypeJSON   = "application/json"
+	ContentTypeBinary = "application/octet-stream"
+)
+
+// ContentTyped is implemented by handlers that declare the content types
+// they accept and produce.
+//
+// An empty Accepts slice or an empty Produces value means the handler places
+// no constraint on that side. Accepted types may use wildcards such as
+// "text/*" or "*/*".
+//
+// Example:
+//
+//	type summarizer struct{}
+//
+//	func (summarizer) Accepts() []string { return []string{calque.ContentTypeText} }
+//	func (summarizer) Produces() string  { return calque.ContentTypeJSON }
+type ContentTyped interface {
+	Handler
+	Accepts() []string
+	Produces() string
+}
+
+// contentTypedHandler wraps a handler with declared content types
+type contentTypedHandler struct {
+	Handler
+	accepts  []string
+	produces string
+}
+
+func (h *contentTypedHandler) Accepts() []string { return h.accepts }
+func (h *contentTypedHandler) Produces() string  { return h.produces }
+
+// Warmup forwards to the wrapped handler so declaring content types does not hide it from Flow.Warmup
+func (h *contentTypedHandler) Warmup(ctx context.Context) error {
+	return WarmupHandler(ctx, h.Handler)
+}
+
+// Shutdown forwards to the wrapped handler so Flow.Shutdown can release it
+func (h *contentTypedHandler) Shutdown(ctx context.Context) error {
+	return ShutdownHandler(ctx, h.Handler)
+}
+
+// WithContentTypes declares the content types of an existing handler.
+//
+// Input: handler, produced content type, accepted content types
+// Output: ContentTyped handler
+// Behavior: STREAMING - delegates directly to the wrapped handler
+//
+// Example:
+//
+//	extract := calque.WithContentTypes(extractor, calque.ContentTypeJSON, calque.ContentTypeText)
+func WithContentTypes(handler Handler, produces string, accepts ...string) ContentTyped {
+	return &contentTypedHandler{Handler: handler, accepts: accepts, produces: produces}
+}
+
+// ContentTypesOf returns the declared content types of a handler.
+//
+// Handlers that do not implement ContentTyped accept and produce ContentTypeAny.
+func ContentTypesOf(handler Handler) (accepts []string, produces string) {
+	if ct, ok := handler.(ContentTyped); ok {
+		return ct.Accepts(), ct.Produces()
+	}
+	return nil, ContentTypeAny
+}
+
+// ContentTypeAccepted reports whether a produced content type satisfies a list of accepted types.
+//
+// Unknown producers and unconstrained consumers are always compatible. Parameters
+// such as "; charset=utf-8" are ignored when matching.
+func ContentTypeAccepted(produced string, accepts []string) bool {
+	produced = baseContentType(produced)
+	if produced == ContentTypeAny || len(accepts) == 0 {
+		return true
+	}
+
+	for _, accept := range accepts {
+		accept = baseContentType(accept)
+		switch {
+		case accept == ContentTypeAny, accept == "*/*", accept == produced:
+			return true
+		case strings.HasSuffix(accept, "/*"):
+			if strings.HasPrefix(produced, strings.TrimSuffix(accept, "*")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// contentConversion identifies a converter by source and target content type
+type contentConversion struct {
+	from string
+	to   string
+}
+
+// contentConverters holds the registered content type converters
+var contentConverters = struct {
+	sync.RWMutex
+	m map[contentConversion]Handler
+}{
+	m: map[contentConversion]Handler{
+		{from: ContentTypeJSON, to: ContentTypeText}: HandlerFunc(jsonToText),
+	},
+}
+
+// RegisterContentConverter registers a handler that converts between two content types.
+//
+// Input: source content type, target content type, converting handler
+// Output: none
+// Behavior: Global registration used by Flow.Use during content negotiation
+//
+// When a flow connects a handler producing `from` to a handler that only
+// accepts `to`, the registered converter is inserted between them. Registering
+// the same pair again replaces the previous converter; a nil handler removes it.
+// A JSON to text converter is registered by default.
+//
+// Example:
+//
+//	calque.RegisterContentConverter("text/markdown", calque.ContentTypeText, stripMarkdown)
+func RegisterContentConverter(from, to string, handler Handler) {
+	key := contentConversion{from: baseContentType(from), to: baseContentType(to)}
+
+	contentConverters.Lock()
+	defer contentConverters.Unlock()
+
+	if handler == nil {
+		delete(contentConverters.m, key)
+		return
+	}
+	contentConverters.m[key] = handler
+}
+
+// ContentConverter returns the registered converter between two content types.
+func ContentConverter(from, to string) (Handler, bool) {
+	contentConverters.RLock()
+	defer contentConverters.RUnlock()
+
+	h, ok := contentConverters.m[contentConversion{from: baseContentType(from), to: baseContentType(to)}]
+	return h, ok
+}
+
+// negotiateContentType finds a converter that lets next consume what the previous handler produced.
+//
+// Returns a nil handler when no conversion is needed, and an error when the
+// types are incompatible and no converter is registered.
+func negotiateContentType(produced string, accepts []string) (Handler, error) {
+	if ContentTypeAccepted(produced, accepts) {
+		return nil, nil
+	}
+
+	for _, accept := range accepts {
+		if conv, ok := ContentConverter(produced, accept); ok {
+			return WithContentTypes(conv, accept, produced), nil
+		}
+	}
+
+	return nil, fmt.Errorf("accepts %v but receives %s and no converter is registered", accepts, produced)
+}
+
+// jsonToText unwraps JSON string values into plain text and passes other JSON through unchanged
+func jsonToText(req *Request, res *Response) error {
+	var input []byte
+	if err := Read(req, &input); err != nil {
+		return err
+	}
+
+	var text string
+	if err := json.Unmarshal(input, &text); err == nil {
+		return Write(res, text)
+	}
+	return Write(res, input)
+}
+
+// baseContentType strips parameters and normalizes case
+func baseContentType(ct string) string {
+	if idx := strings.IndexByte(ct, ';'); idx >= 0 {
+		ct = ct[:idx]
+	}
+	return strings.ToLower(strings.TrimSpace(ct))
+}
@@ -0,0 +1,167 @@
+package calque
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestContentTypeAccepted(t *testing.T) {
+	tests := []struct {
+		produced string
+		accepts  []string
+		want     bool
+	}{
+		{ContentTypeAny, []string{ContentTypeText}, true},
+		{ContentTypeJSON, nil, true},
+		{ContentTypeJSON, []string{ContentTypeJSON}, true},
+		{"application/json; charset=utf-8", []string{"Application/JSON"}, true},
+		{"image/png", []string{"image/*"}, true},
+		{"image/png", []string{"*/*"}, true},
+		{"image/png", []string{ContentTypeText, ContentTypeJSON}, false},
+	}
+
+	for _, tt := range tests {
+		if got := ContentTypeAccepted(tt.produced, tt.accepts); got != tt.want {
+			t.Errorf("ContentTypeAccepted(%q, %v) = %v, want %v", tt.produced, tt.accepts, got, tt.want)
+		}
+	}
+}
+
+func TestContentTypesOf(t *testing.T) {
+	plain := HandlerFunc(func(*Request, *Response) error { return nil })
+
+	accepts, produces := ContentTypesOf(plain)
+	if accepts != nil || produces != ContentTypeAny {
+		t.Errorf("undeclared handler = (%v, %q), want (nil, %q)", accepts, produces, ContentTypeAny)
+	}
+
+	accepts, produces = ContentTypesOf(WithContentTypes(plain, ContentTypeJSON, ContentTypeText, "text/markdown"))
+	if len(accepts) != 2 || accepts[1] != "text/markdown" || produces != ContentTypeJSON {
+		t.Errorf("declared handler = (%v, %q)", accepts, produces)
+	}
+}
+
+func TestFlow_ContentNegotiation(t *testing.T) {
+	emitJSON := WithContentTypes(HandlerFunc(func(_ *Request, res *Response) error {
+		return Write(res, `"hello world"`)
+	}), ContentTypeJSON)
+
+	var received string
+	textConsumer := WithContentTypes(HandlerFunc(func(req *Request, res *Response) error {
+		if err := Read(req, &received); err != nil {
+			return err
+		}
+		return Write(res, strings.ToUpper(received))
+	}), ContentTypeText, ContentTypeText)
+
+	flow := NewFlow().Use(emitJSON).Use(textConsumer)
+	if err := flow.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if len(flow.handlers) != 3 {
+		t.Fatalf("expected converter to be inserted, got %d handlers", len(flow.handlers))
+	}
+
+	var out string
+	if err := flow.Run(context.Background(), "", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if received != "hello world" {
+		t.Errorf("text consumer received %q, want unwrapped JSON string", received)
+	}
+	if out != "HELLO WORLD" {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestFlow_ContentNegotiationFailure(t *testing.T) {
+	var executed bool
+	pass := HandlerFunc(func(req *Request, res *Response) error {
+		executed = true
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+
+	flow := NewFlow().
+		Use(WithContentTypes(pass, ContentTypeJSON)).
+		Use(WithContentTypes(pass, "image/png", "image/png"))
+
+	err := flow.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	if !strings.Contains(err.Error(), "handler 1 accepts [image/png] but receives application/json") {
+		t.Errorf("unexpected error %q", err.Error())
+	}
+
+	var out string
+	if runErr := flow.Run(context.Background(), "x", &out); runErr == nil {
+		t.Error("expected Run to fail")
+	}
+	if executed {
+		t.Error("handlers must not run when negotiation failed")
+	}
+}
+
+func TestRegisterContentConverter(t *testing.T) {
+	const markdown = "text/markdown"
+
+	strip := HandlerFunc(func(req *Request, res *Response) error {
+		var s string
+		if err := Read(req, &s); err != nil {
+			return err
+		}
+		return Write(res, strings.TrimPrefix(s, "# "))
+	})
+	RegisterContentConverter(markdown, ContentTypeText, strip)
+	defer RegisterContentConverter(markdown, ContentTypeText, nil)
+
+	if _, ok := ContentConverter("TEXT/MARKDOWN", ContentTypeText); !ok {
+		t.Fatal("expected registered converter to be found")
+	}
+
+	emit := WithContentTypes(HandlerFunc(func(_ *Request, res *Response) error {
+		return Write(res, "# Title")
+	}), markdown)
+	pass := WithContentTypes(HandlerFunc(func(req *Request, res *Response) error {
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	}), ContentTypeText, ContentTypeText)
+
+	var out string
+	if err := NewFlow().Use(emit).Use(pass).Run(context.Background(), "", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != "Title" {
+		t.Errorf("output = %q, want %q", out, "Title")
+	}
+
+	RegisterContentConverter(markdown, ContentTypeText, nil)
+	if _, ok := ContentConverter(markdown, ContentTypeText); ok {
+		t.Error("expected converter to be removed")
+	}
+}
+
+func TestJSONToText(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`"quoted\nline"`, "quoted\nline"},
+		{`{"a":1}`, `{"a":1}`},
+		{`not json`, `not json`},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+		err := HandlerFunc(jsonToText).ServeFlow(NewRequest(context.Background(), strings.NewReader(tt.input)), NewResponse(&out))
+		if err != nil {
+			t.Fatalf("jsonToText(%q) error = %v", tt.input, err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("jsonToText(%q) = %q, want %q", tt.input, out.String(), tt.want)
+		}
+	}
+}
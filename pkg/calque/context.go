@@ -17,10 +17,18 @@ import (
 type ctxKey string
 
 const (
-	metadataBusKey ctxKey = "calque.metadata_bus"
-	loggerKey      ctxKey = "calque.logger"
-	traceIDKey     ctxKey = "calque.trace_id"
-	requestIDKey   ctxKey = "calque.request_id"
+	metadataBusKey    ctxKey = "calque.metadata_bus"
+	loggerKey         ctxKey = "calque.logger"
+	traceIDKey        ctxKey = "calque.trace_id"
+	requestIDKey      ctxKey = "calque.request_id"
+	progressKey       ctxKey = "calque.progress"
+	idempotencyKeyKey ctxKey = "calque.idempotency_key"
+	userKey           ctxKey = "calque.user"
+	tenantKey         ctxKey = "calque.tenant"
+	localeKey         ctxKey = "calque.locale"
+	spanStarterKey    ctxKey = "calque.span_starter"
+	spanKey           ctxKey = "calque.span"
+	seedKey           ctxKey = "calque.seed"
 )
 
 // DefaultMetadataBusBuffer is the default buffer size for MetadataBus channels.
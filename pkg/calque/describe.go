@@ -0,0 +1,152 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// NodeKind is the shape of a handler in a pipeline diagram
+type NodeKind int
+
+const (
+	// NodeStep is a single handler with no visible structure
+	NodeStep NodeKind = iota
+	// NodeSequence runs its children one after another, e.g. a Flow or Chain
+	NodeSequence
+	// NodeBranch runs one of its children, e.g. Branch, Router or Fallback
+	NodeBranch
+	// NodeParallel runs all of its children on the same input and merges the results
+	NodeParallel
+)
+
+// Node describes a handler and the handlers nested inside it.
+//
+// Wrappers such as Timeout or Retry are sequences with a single child, so
+// diagrams draw them as a labeled box around what they wrap.
+type Node struct {
+	Label    string   // Text shown for the node or around its children
+	Kind     NodeKind // How children are connected
+	Edge     string   // Label on the edge into this node from a branch or parallel parent (optional)
+	Egress   []string // Off-host destinations the handler sends data to, see Egress (optional)
+	Children []Node
+}
+
+// WithEdge returns a copy of the node with the label for its incoming edge
+func (n Node) WithEdge(label string) Node {
+	n.Edge = label
+	return n
+}
+
+// Describer is implemented by handlers that can describe their structure for
+// diagrams. Handlers that don't implement it are drawn as a single step named
+// after their type or constructor.
+type Describer interface {
+	Describe() Node
+}
+
+// describedHandler attaches a description to a handler
+type describedHandler struct {
+	Handler
+	describe func() Node
+	children []Handler
+}
+
+func (d *describedHandler) Describe() Node {
+	return d.describe()
+}
+
+// Warmup warms the handler and every child it wraps
+func (d *describedHandler) Warmup(ctx context.Context) error {
+	errs := []error{WarmupHandler(ctx, d.Handler)}
+	for _, child := range d.children {
+		errs = append(errs, WarmupHandler(ctx, child))
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown shuts down the handler and every child it wraps
+func (d *describedHandler) Shutdown(ctx context.Context) error {
+	errs := []error{ShutdownHandler(ctx, d.Handler)}
+	for _, child := range d.children {
+		errs = append(errs, ShutdownHandler(ctx, child))
+	}
+	return errors.Join(errs...)
+}
+
+// Described attaches a diagram description to a handler.
+//
+// Input: handler and a function returning its description
+// Output: Handler that also implements Describer
+// Behavior: STREAMING - delegates directly to the wrapped handler
+//
+// describe is called each time the handler is described, so it can include
+// children with DescribeHandler. Pass the handlers it wraps as children so
+// Flow.Warmup and Flow.Shutdown reach them through the wrapper.
+//
+// Example:
+//
+//	func Audit(inner calque.Handler) calque.Handler {
+//		h := calque.HandlerFunc(...)
+//		return calque.Described(h, func() calque.Node {
+//			return calque.Node{Label: "audit", Kind: calque.NodeSequence, Children: []calque.Node{calque.DescribeHandler(inner)}}
+//		}, inner)
+//	}
+func Described(handler Handler, describe func() Node, children ...Handler) Handler {
+	return &describedHandler{Handler: handler, describe: describe, children: children}
+}
+
+// DescribeHandler returns the diagram description of any handler, including
+// the destinations it declares with Egress
+func DescribeHandler(handler Handler) Node {
+	node := Node{Label: handlerName(handler)}
+	if d, ok := handler.(Describer); ok {
+		node = d.Describe()
+	}
+	for _, dest := range EgressOf(handler) {
+		if !slices.Contains(node.Egress, dest) {
+			node.Egress = append(node.Egress, dest)
+		}
+	}
+	return node
+}
+
+// Describe returns the flow as a sequence of its handlers, including any
+// converters inserted by content type negotiation
+func (f *Flow) Describe() Node {
+	node := Node{Label: "flow", Kind: NodeSequence, Children: make([]Node, len(f.handlers))}
+	for i, h := range f.handlers {
+		node.Children[i] = DescribeHandler(h)
+	}
+	return node
+}
+
+// Describe delegates to the wrapped handler
+func (c *contentTypedHandler) Describe() Node {
+	return DescribeHandler(c.Handler)
+}
+
+// closureSuffix matches the ".func1", "-fm" and "[...]" parts of compiler-generated names
+var closureSuffix = regexp.MustCompile(`(\.func\d+(\.\d+)*|-fm)+$|\[\.\.\.\]`)
+
+// handlerName derives a readable label such as "ctrl.RateLimit" from a handler's
+// constructor or type
+func handlerName(handler Handler) string {
+	if handler == nil {
+		return "nil"
+	}
+	v := reflect.ValueOf(handler)
+	if v.Kind() == reflect.Func {
+		if fn := runtime.FuncForPC(v.Pointer()); fn != nil {
+			name := fn.Name()
+			name = name[strings.LastIndex(name, "/")+1:]
+			return closureSuffix.ReplaceAllString(name, "")
+		}
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", handler), "*")
+}
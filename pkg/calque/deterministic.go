@@ -0,0 +1,129 @@
+package calque
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// NondeterminismMetadataKey is the MetadataBus key holding the nondeterministic
+// points recorded in a deterministic run. Read it with NondeterminismFrom.
+const NondeterminismMetadataKey = "calque.nondeterminism"
+
+// DeterministicEpoch is the time Now reports in deterministic runs
+var DeterministicEpoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// NondeterministicPoint is a step of a deterministic run whose result may
+// still change between runs, such as a model client that can't be seeded
+type NondeterministicPoint struct {
+	Source string `json:"source"` // Handler or client, e.g. "ai.Agent"
+	Reason string `json:"reason"`
+}
+
+// nondeterminism is the accumulator stored on the MetadataBus
+type nondeterminism struct {
+	mu     sync.Mutex
+	points []NondeterministicPoint
+}
+
+// Deterministic makes a run reproducible under seed.
+//
+// Input: seed passed to model providers
+// Output: RunOption for Flow.Run
+// Behavior: Handlers see the seed through DeterministicSeed
+//
+// Providers that support seeded sampling send the seed with every request,
+// sampling strategies such as ai.SelfConsistency take a single sample, and Now
+// returns DeterministicEpoch so time-dependent templates render the same text.
+// Steps that can't be made reproducible are recorded with RecordNondeterminism;
+// attach a MetadataBus to read them afterwards.
+//
+// Example:
+//
+//	ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+//	err := flow.Run(ctx, testCase.Input, &output, calque.Deterministic(42))
+//	for _, p := range calque.NondeterminismFrom(ctx) {
+//		log.Printf("not reproducible: %s: %s", p.Source, p.Reason)
+//	}
+func Deterministic(seed int64) RunOption {
+	return func(o *runOptions) {
+		o.seed = &seed
+	}
+}
+
+// WithDeterministicSeed marks the context as a deterministic run under seed,
+// for code that calls handlers outside Flow.Run. See Deterministic.
+func WithDeterministicSeed(ctx context.Context, seed int64) context.Context {
+	return context.WithValue(ctx, seedKey, seed)
+}
+
+// DeterministicSeed returns the seed of a deterministic run, false otherwise
+func DeterministicSeed(ctx context.Context) (int64, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	seed, ok := ctx.Value(seedKey).(int64)
+	return seed, ok
+}
+
+// Now returns the current time, or DeterministicEpoch in a deterministic run.
+//
+// Handlers that put dates or times into prompts should use it instead of
+// time.Now.
+func Now(ctx context.Context) time.Time {
+	if _, ok := DeterministicSeed(ctx); ok {
+		return DeterministicEpoch
+	}
+	return time.Now()
+}
+
+// RecordNondeterminism notes that a step of a deterministic run may not be
+// reproducible. It does nothing outside deterministic runs or without a
+// MetadataBus, and records each source and reason once.
+//
+// Example:
+//
+//	calque.RecordNondeterminism(req.Context, "search", "results depend on the live index")
+func RecordNondeterminism(ctx context.Context, source, reason string) {
+	if _, ok := DeterministicSeed(ctx); !ok {
+		return
+	}
+	LogDebug(ctx, "nondeterministic step in deterministic run", "source", source, "reason", reason)
+
+	bus := GetMetadataBus(ctx)
+	if bus == nil {
+		return
+	}
+	v, _ := bus.store.LoadOrStore(NondeterminismMetadataKey, &nondeterminism{})
+	n, ok := v.(*nondeterminism)
+	if !ok {
+		return
+	}
+	point := NondeterministicPoint{Source: source, Reason: reason}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if !slices.Contains(n.points, point) {
+		n.points = append(n.points, point)
+	}
+}
+
+// NondeterminismFrom returns the nondeterministic points recorded so far in
+// the request's flow run, in the order they were first recorded
+func NondeterminismFrom(ctx context.Context) []NondeterministicPoint {
+	bus := GetMetadataBus(ctx)
+	if bus == nil {
+		return nil
+	}
+	v, ok := bus.store.Load(NondeterminismMetadataKey)
+	if !ok {
+		return nil
+	}
+	n, ok := v.(*nondeterminism)
+	if !ok {
+		return nil
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return slices.Clone(n.points)
+}
@@ -0,0 +1,65 @@
+package calque
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDeterministic(t *testing.T) {
+	t.Parallel()
+
+	var (
+		seed   int64
+		seeded bool
+		now    time.Time
+	)
+	flow := NewFlow().UseFunc(func(req *Request, res *Response) error {
+		seed, seeded = DeterministicSeed(req.Context)
+		now = Now(req.Context)
+		RecordNondeterminism(req.Context, "search", "live index")
+		RecordNondeterminism(req.Context, "search", "live index")
+		RecordNondeterminism(req.Context, "ai.Agent", "client cannot be seeded")
+		return passthrough(req, res)
+	})
+
+	ctx := WithMetadataBus(context.Background(), NewMetadataBus(0))
+	var out string
+	if err := flow.Run(ctx, "hi", &out, Deterministic(42)); err != nil || out != "hi" {
+		t.Fatalf("Run() = %q, %v", out, err)
+	}
+	if !seeded || seed != 42 {
+		t.Errorf("DeterministicSeed() = %d, %v; want 42, true", seed, seeded)
+	}
+	if !now.Equal(DeterministicEpoch) {
+		t.Errorf("Now() = %v, want DeterministicEpoch", now)
+	}
+	want := []NondeterministicPoint{{Source: "search", Reason: "live index"}, {Source: "ai.Agent", Reason: "client cannot be seeded"}}
+	if got := NondeterminismFrom(ctx); !slices.Equal(got, want) {
+		t.Errorf("NondeterminismFrom() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDeterministicOff(t *testing.T) {
+	t.Parallel()
+
+	ctx := WithMetadataBus(context.Background(), NewMetadataBus(0))
+	if _, ok := DeterministicSeed(ctx); ok {
+		t.Error("DeterministicSeed() reported a seed outside a deterministic run")
+	}
+	if Now(ctx).Equal(DeterministicEpoch) {
+		t.Error("Now() frozen outside a deterministic run")
+	}
+	RecordNondeterminism(ctx, "search", "live index")
+	if got := NondeterminismFrom(ctx); got != nil {
+		t.Errorf("NondeterminismFrom() = %+v, want nothing recorded outside a deterministic run", got)
+	}
+
+	// Without a bus, points are only logged
+	seeded := WithDeterministicSeed(context.Background(), 7)
+	RecordNondeterminism(seeded, "search", "live index")
+	if got := NondeterminismFrom(seeded); got != nil {
+		t.Errorf("NondeterminismFrom() without a bus = %+v", got)
+	}
+}
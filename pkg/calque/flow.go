@@ -2,9 +2,11 @@ package calque
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"runtime"
 	"sync"
+	"time"
 )
 
 // ConcurrencyUnlimited disables concurrency limits, allowing unlimited handler goroutines.
@@ -61,10 +63,18 @@ const DefaultCPUMultiplier = 50
 //
 //	// With custom MetadataBus buffer
 //	flow := calque.NewFlow(calque.FlowConfig{MetadataBusBuffer: 200})
+//
+// IdempotencyStore records results of runs started with WithIdempotencyKey for
+// IdempotencyTTL. If nil, an in-process store is used.
+//
+// DataPolicy restricts where handlers may send data, see WithDataPolicy.
 type FlowConfig struct {
-	MaxConcurrent     int // ConcurrencyUnlimited, ConcurrencyAuto, or positive integer
-	CPUMultiplier     int // multiplier for GOMAXPROCS (used when MaxConcurrent = ConcurrencyAuto)
-	MetadataBusBuffer int // buffer size for MetadataBus channel (0 = DefaultMetadataBusBuffer)
+	MaxConcurrent     int              // ConcurrencyUnlimited, ConcurrencyAuto, or positive integer
+	CPUMultiplier     int              // multiplier for GOMAXPROCS (used when MaxConcurrent = ConcurrencyAuto)
+	MetadataBusBuffer int              // buffer size for MetadataBus channel (0 = DefaultMetadataBusBuffer)
+	IdempotencyStore  IdempotencyStore // result store for idempotent runs (nil = in-memory)
+	IdempotencyTTL    time.Duration    // how long idempotent results are kept (0 = DefaultIdempotencyTTL)
+	DataPolicy        DataPolicy       // where handlers may send data (AllowRemote = no restriction)
 }
 
 // Flow is the core flow orchestration primitive
@@ -72,6 +82,12 @@ type Flow struct {
 	handlers          []Handler
 	sem               chan struct{} // nil = unlimited concurrency
 	metadataBusBuffer int           // buffer size for auto-created MetadataBus
+	produces          string        // content type produced by the last handler
+	buildErr          error         // first content negotiation failure
+	idempotency       *idempotency  // result store for runs with an idempotency key
+	lifecycle         lifecycle     // in-flight tracking for Shutdown
+	dataPolicy        DataPolicy    // where handlers may send data
+	violations        []string      // handlers that break dataPolicy
 }
 
 // NewFlow creates a new flow with optional concurrency configuration.
@@ -82,6 +98,7 @@ type Flow struct {
 //
 // With no config, uses unlimited concurrency (good for development and moderate load).
 // With config, applies semaphore-based goroutine limiting for resource protection.
+// Multiple configs are merged, with non-zero fields of later configs taking precedence.
 // Each handler in the flow runs in its own goroutine, connected by io.Pipe.
 //
 // The semaphore limits the total number of handler goroutines across ALL flow
@@ -103,7 +120,7 @@ type Flow struct {
 func NewFlow(configs ...FlowConfig) *Flow {
 	var config FlowConfig
 	if len(configs) > 0 {
-		config = configs[0]
+		config = mergeFlowConfigs(configs)
 	} else {
 		// Default: unlimited concurrency
 		config = FlowConfig{
@@ -138,7 +155,38 @@ func NewFlow(configs ...FlowConfig) *Flow {
 		mbBuffer = DefaultMetadataBusBuffer
 	}
 
-	return &Flow{sem: sem, metadataBusBuffer: mbBuffer}
+	return &Flow{
+		sem:               sem,
+		metadataBusBuffer: mbBuffer,
+		idempotency:       newIdempotency(config.IdempotencyStore, config.IdempotencyTTL),
+		dataPolicy:        config.DataPolicy,
+	}
+}
+
+// mergeFlowConfigs overlays the non-zero fields of each config onto the previous ones
+func mergeFlowConfigs(configs []FlowConfig) FlowConfig {
+	config := configs[0]
+	for _, c := range configs[1:] {
+		if c.MaxConcurrent != ConcurrencyUnlimited {
+			config.MaxConcurrent = c.MaxConcurrent
+		}
+		if c.CPUMultiplier != 0 {
+			config.CPUMultiplier = c.CPUMultiplier
+		}
+		if c.MetadataBusBuffer != 0 {
+			config.MetadataBusBuffer = c.MetadataBusBuffer
+		}
+		if c.IdempotencyStore != nil {
+			config.IdempotencyStore = c.IdempotencyStore
+		}
+		if c.IdempotencyTTL != 0 {
+			config.IdempotencyTTL = c.IdempotencyTTL
+		}
+		if c.DataPolicy != AllowRemote {
+			config.DataPolicy = c.DataPolicy
+		}
+	}
+	return config
 }
 
 // Use adds a handler to the flow chain.
@@ -151,6 +199,12 @@ func NewFlow(configs ...FlowConfig) *Flow {
 // goroutine and connects to the next handler via io.Pipe for streaming data flow.
 // The flow supports unlimited handler chaining.
 //
+// When both the previous handler and the new handler declare content types
+// (see ContentTyped), Use negotiates between them: a registered converter is
+// inserted automatically, otherwise the mismatch is recorded and reported by
+// Validate, Run and ServeFlow before any handler executes. Handlers that break
+// the flow's DataPolicy are recorded and reported the same way.
+//
 // Example:
 //
 //	flow := calque.NewFlow().
@@ -158,10 +212,42 @@ func NewFlow(configs ...FlowConfig) *Flow {
 //		Use(ai.Agent(client)).
 //		Use(logger.Print("OUTPUT"))
 func (f *Flow) Use(handler Handler) *Flow {
+	f.checkPolicy(len(f.handlers), handler)
+	accepts, produces := ContentTypesOf(handler)
+
+	conv, err := negotiateContentType(f.produces, accepts)
+	switch {
+	case err != nil && f.buildErr == nil:
+		f.buildErr = NewErr(context.Background(), fmt.Sprintf("handler %d %v", len(f.handlers), err))
+	case conv != nil:
+		f.handlers = append(f.handlers, conv)
+	}
+
 	f.handlers = append(f.handlers, handler)
+	f.produces = produces
 	return f
 }
 
+// Validate reports problems detected while handlers were added.
+//
+// Input: none
+// Output: error describing the first incompatible handler pair or every
+// DataPolicy violation, nil if the flow is valid
+// Behavior: No handlers are executed
+//
+// Example:
+//
+//	flow := calque.NewFlow().Use(jsonExtractor).Use(imageResizer)
+//	if err := flow.Validate(); err != nil {
+//		log.Fatal(err) // handler 1 accepts [image/png] but receives application/json ...
+//	}
+func (f *Flow) Validate() error {
+	if f.buildErr != nil {
+		return f.buildErr
+	}
+	return f.policyErr()
+}
+
 // UseFunc adds a function as a handler using the HandlerFunc adapter.
 //
 // Input: HandlerFunc - function matching the handler signature
@@ -197,12 +283,19 @@ func (f *Flow) UseFunc(fn HandlerFunc) *Flow {
 //	subFlow := calque.NewFlow().Use(handler1).Use(handler2)
 //	mainFlow := calque.NewFlow().Use(subFlow).Use(handler3)
 func (f *Flow) ServeFlow(req *Request, res *Response) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	if err := f.lifecycle.enter(req.Context); err != nil {
+		return err
+	}
+	defer f.lifecycle.leave()
 	return f.runWithStreaming(req.Context, req.Data, res.Data)
 }
 
 // Run executes the flow with streaming data flow and concurrent handler processing.
 //
-// Input: context.Context for cancellation, input data (any type), output pointer (any type)
+// Input: context.Context for cancellation, input data (any type), output pointer (any type), optional RunOptions
 // Output: error if flow execution fails
 // Behavior: CONCURRENT - each handler runs in its own goroutine connected by io.Pipe
 //
@@ -219,6 +312,12 @@ func (f *Flow) ServeFlow(req *Request, res *Response) error {
 // Context cancellation propagates through all handlers for clean shutdown.
 // Flow execution fails if any handler returns an error.
 //
+// Once Shutdown has been called, Run fails with ErrFlowShutdown.
+//
+// Pass WithIdempotencyKey to return the recorded result of an earlier run with
+// the same key instead of executing the handlers again, and Deterministic to
+// make the run reproducible.
+//
 // Example:
 //
 //	var result string
@@ -227,7 +326,30 @@ func (f *Flow) ServeFlow(req *Request, res *Response) error {
 //		log.Fatal(err)
 //	}
 //	fmt.Println("Output:", result)
-func (f *Flow) Run(ctx context.Context, input any, output any) error {
+func (f *Flow) Run(ctx context.Context, input any, output any, opts ...RunOption) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	if err := f.lifecycle.enter(ctx); err != nil {
+		return err
+	}
+	defer f.lifecycle.leave()
+
+	var options runOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.seed != nil {
+		ctx = WithDeterministicSeed(ctx, *options.seed)
+	}
+	if options.idempotencyKey != "" {
+		return f.runIdempotent(ctx, options.idempotencyKey, input, output)
+	}
+	return f.run(ctx, input, output)
+}
+
+// run executes the handlers once, converting input and output
+func (f *Flow) run(ctx context.Context, input any, output any) error {
 	// Auto-create MetadataBus if not present in context
 	var mb *MetadataBus
 	if GetMetadataBus(ctx) == nil {
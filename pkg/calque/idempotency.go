@@ -0,0 +1,181 @@
+package calque
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultIdempotencyTTL is how long idempotent run results are kept when FlowConfig.IdempotencyTTL is unset.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// idempotencyKeyPrefix namespaces run results in stores shared with other middleware
+const idempotencyKeyPrefix = "idempotency:"
+
+// IdempotencyStore records flow results by idempotency key.
+//
+// The method set matches cache.Store, so any cache backend (in-memory, Redis,
+// DynamoDB, SQLite) can be passed as FlowConfig.IdempotencyStore. Get returns
+// nil when the key is unknown or expired.
+type IdempotencyStore interface {
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte, ttl time.Duration) error
+}
+
+// RunOption configures a single Flow.Run call.
+type RunOption func(*runOptions)
+
+type runOptions struct {
+	idempotencyKey string
+	seed           *int64 // set by Deterministic
+}
+
+// WithIdempotencyKey makes a run idempotent under key.
+//
+// The first successful run with a key records its output in the flow's
+// IdempotencyStore; re-submissions with the same key return the recorded output
+// without executing any handler. Concurrent runs with the same key wait for the
+// first one. Failed runs are not recorded, so they can be retried. Output is
+// buffered before it is written, so idempotent runs do not stream.
+//
+// The key is also available to handlers through IdempotencyKey(ctx).
+//
+// Example:
+//
+//	flow := calque.NewFlow(calque.FlowConfig{IdempotencyStore: redisStore})
+//	err := flow.Run(ctx, order, &receipt, calque.WithIdempotencyKey(r.Header.Get("Idempotency-Key")))
+func WithIdempotencyKey(key string) RunOption {
+	return func(o *runOptions) {
+		o.idempotencyKey = key
+	}
+}
+
+// IdempotencyKey returns the idempotency key of the current run, or "" if none was given.
+func IdempotencyKey(ctx context.Context) string {
+	if key, ok := ctx.Value(idempotencyKeyKey).(string); ok {
+		return key
+	}
+	return ""
+}
+
+// idempotency holds a flow's result store and the runs currently executing per key
+type idempotency struct {
+	store    IdempotencyStore
+	ttl      time.Duration
+	mu       sync.Mutex
+	inFlight map[string]chan struct{}
+}
+
+func newIdempotency(store IdempotencyStore, ttl time.Duration) *idempotency {
+	if store == nil {
+		store = newMemoryResultStore()
+	}
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &idempotency{store: store, ttl: ttl, inFlight: make(map[string]chan struct{})}
+}
+
+// acquire waits until no other run holds key, then claims it
+func (i *idempotency) acquire(ctx context.Context, key string) (release func(), err error) {
+	for {
+		i.mu.Lock()
+		wait, busy := i.inFlight[key]
+		if !busy {
+			done := make(chan struct{})
+			i.inFlight[key] = done
+			i.mu.Unlock()
+			return func() {
+				i.mu.Lock()
+				delete(i.inFlight, key)
+				i.mu.Unlock()
+				close(done)
+			}, nil
+		}
+		i.mu.Unlock()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// runIdempotent replays a recorded result for key, or runs the flow and records it
+func (f *Flow) runIdempotent(ctx context.Context, key string, input any, output any) error {
+	release, err := f.idempotency.acquire(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	storeKey := idempotencyKeyPrefix + key
+	recorded, err := f.idempotency.store.Get(storeKey)
+	if err != nil {
+		return WrapErr(ctx, err, "failed to read idempotent result")
+	}
+	if recorded != nil {
+		LogDebug(ctx, "replaying idempotent flow result", "idempotency_key", key)
+		return f.copyInputToOutput(recorded, output)
+	}
+
+	var buf bytes.Buffer
+	if err := f.run(context.WithValue(ctx, idempotencyKeyKey, key), input, &buf); err != nil {
+		return err
+	}
+
+	result := buf.Bytes()
+	if result == nil {
+		result = []byte{}
+	}
+	if err := f.idempotency.store.Set(storeKey, result, f.idempotency.ttl); err != nil {
+		return WrapErr(ctx, err, "failed to record idempotent result")
+	}
+	return f.copyInputToOutput(result, output)
+}
+
+// memoryResultStore is the default in-process IdempotencyStore
+type memoryResultStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryResult
+}
+
+type memoryResult struct {
+	data    []byte
+	expires time.Time
+}
+
+func newMemoryResultStore() *memoryResultStore {
+	return &memoryResultStore{entries: make(map[string]memoryResult)}
+}
+
+func (s *memoryResultStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(entry.expires) {
+		delete(s.entries, key)
+		return nil, nil
+	}
+	return bytes.Clone(entry.data), nil
+}
+
+func (s *memoryResultStore) Set(key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range s.entries {
+		if now.After(entry.expires) {
+			delete(s.entries, k)
+		}
+	}
+	s.entries[key] = memoryResult{data: bytes.Clone(value), expires: now.Add(ttl)}
+	return nil
+}
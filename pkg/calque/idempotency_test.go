@@ -0,0 +1,181 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sideEffect counts executions, standing in for a side-effecting tool call
+func sideEffect(calls *atomic.Int32) HandlerFunc {
+	return func(req *Request, res *Response) error {
+		n := calls.Add(1)
+		data, err := io.ReadAll(req.Data)
+		if err != nil {
+			return err
+		}
+		_, err = res.Data.Write([]byte(strings.ToUpper(string(data)) + " #" + string(rune('0'+n))))
+		return err
+	}
+}
+
+func TestFlow_RunWithIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		keys      []string
+		wantCalls int32
+	}{
+		{"same key runs once", []string{"order-1", "order-1", "order-1"}, 1},
+		{"different keys run each time", []string{"order-1", "order-2"}, 2},
+		{"no key always runs", []string{"", ""}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			flow := NewFlow().UseFunc(sideEffect(&calls))
+
+			var first string
+			for i, key := range tt.keys {
+				var opts []RunOption
+				if key != "" {
+					opts = append(opts, WithIdempotencyKey(key))
+				}
+
+				var out string
+				if err := flow.Run(context.Background(), "charge", &out, opts...); err != nil {
+					t.Fatalf("Run() error = %v", err)
+				}
+				if i == 0 {
+					first = out
+				} else if key == tt.keys[0] && key != "" && out != first {
+					t.Errorf("re-submission output = %q, want original %q", out, first)
+				}
+			}
+
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("handler calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestFlow_IdempotencyFailuresNotRecorded(t *testing.T) {
+	var calls atomic.Int32
+	flow := NewFlow().UseFunc(func(_ *Request, _ *Response) error {
+		if calls.Add(1) == 1 {
+			return errors.New("tool failed")
+		}
+		return nil
+	})
+
+	var out string
+	if err := flow.Run(context.Background(), "x", &out, WithIdempotencyKey("k")); err == nil {
+		t.Fatal("expected first run to fail")
+	}
+	if err := flow.Run(context.Background(), "x", &out, WithIdempotencyKey("k")); err != nil {
+		t.Fatalf("retry after failure error = %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("handler calls = %d, want 2", got)
+	}
+}
+
+func TestFlow_IdempotencyConcurrentSubmissions(t *testing.T) {
+	var calls atomic.Int32
+	flow := NewFlow().UseFunc(func(req *Request, res *Response) error {
+		calls.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var out []byte
+			if err := flow.Run(context.Background(), "payload", &out, WithIdempotencyKey("same")); err != nil || string(out) != "payload" {
+				t.Errorf("Run() = %q, %v", out, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("handler calls = %d, want 1", got)
+	}
+}
+
+func TestFlow_IdempotencyStoreAndTTL(t *testing.T) {
+	store := newMemoryResultStore()
+	var calls atomic.Int32
+	flow := NewFlow(FlowConfig{IdempotencyStore: store, IdempotencyTTL: 20 * time.Millisecond}).
+		UseFunc(sideEffect(&calls))
+
+	var out string
+	_ = flow.Run(context.Background(), "a", &out, WithIdempotencyKey("k"))
+
+	if recorded, _ := store.Get(idempotencyKeyPrefix + "k"); string(recorded) != out {
+		t.Errorf("store holds %q, want %q", recorded, out)
+	}
+
+	// A second flow sharing the store replays the result
+	other := NewFlow(FlowConfig{IdempotencyStore: store}).UseFunc(sideEffect(&calls))
+	var replayed string
+	_ = other.Run(context.Background(), "a", &replayed, WithIdempotencyKey("k"))
+	if replayed != out || calls.Load() != 1 {
+		t.Errorf("shared store replay = %q (calls %d), want %q", replayed, calls.Load(), out)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	_ = flow.Run(context.Background(), "a", &out, WithIdempotencyKey("k"))
+	if got := calls.Load(); got != 2 {
+		t.Errorf("handler calls after TTL = %d, want 2", got)
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	var seen string
+	flow := NewFlow().UseFunc(func(req *Request, res *Response) error {
+		seen = IdempotencyKey(req.Context)
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+
+	var out string
+	_ = flow.Run(context.Background(), "x", &out, WithIdempotencyKey("abc"))
+	if seen != "abc" {
+		t.Errorf("IdempotencyKey() = %q, want abc", seen)
+	}
+	if got := IdempotencyKey(context.Background()); got != "" {
+		t.Errorf("IdempotencyKey() without key = %q", got)
+	}
+}
+
+func TestTypedFlow_RunWithIdempotencyKey(t *testing.T) {
+	type receipt struct {
+		ID int `json:"id"`
+	}
+
+	var calls atomic.Int32
+	f, err := Typed[string, receipt](HandlerFunc(func(_ *Request, res *Response) error {
+		_, err := res.Data.Write([]byte(`{"id":` + string(rune('0'+calls.Add(1))) + `}`))
+		return err
+	}))
+	if err != nil {
+		t.Fatalf("Typed() error = %v", err)
+	}
+
+	first, _ := f.Run(context.Background(), "order", WithIdempotencyKey("o-1"))
+	second, _ := f.Run(context.Background(), "order", WithIdempotencyKey("o-1"))
+	if first.ID != 1 || second.ID != 1 {
+		t.Errorf("receipts = %+v, %+v; want both ID 1", first, second)
+	}
+}
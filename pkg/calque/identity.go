@@ -0,0 +1,123 @@
+package calque
+
+import "context"
+
+// User identifies the caller a request is made on behalf of.
+//
+// Middleware that scopes state per caller (memory, rate limiting, audit) reads
+// it with UserFrom instead of defining its own context key.
+type User struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name,omitempty"`
+	Email      string            `json:"email,omitempty"`
+	Roles      []string          `json:"roles,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// HasRole reports whether the user was granted role.
+func (u User) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// WithUser stores the calling user in the context.
+//
+// Example:
+//
+//	ctx = calque.WithUser(ctx, calque.User{ID: session.UserID, Roles: session.Roles})
+//	err := flow.Run(ctx, input, &output)
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userKey, user)
+}
+
+// UserFromContext retrieves the calling user from context.
+//
+// Returns false if no user was stored with WithUser.
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(userKey).(User)
+	return user, ok
+}
+
+// UserFrom retrieves the calling user from a request's context.
+//
+// Example:
+//
+//	if user, ok := calque.UserFrom(req); ok {
+//	    calque.LogInfo(req.Context, "handling request", "user_id", user.ID)
+//	}
+func UserFrom(req *Request) (User, bool) {
+	return UserFromContext(requestContext(req))
+}
+
+// WithTenant stores the tenant (organisation, workspace, account) in the context.
+//
+// Example:
+//
+//	ctx = calque.WithTenant(ctx, "acme")
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// Tenant retrieves the tenant from context.
+//
+// Returns empty string if no tenant is found.
+func Tenant(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey).(string); ok {
+		return tenant
+	}
+	return ""
+}
+
+// TenantFrom retrieves the tenant from a request's context.
+func TenantFrom(req *Request) string {
+	return Tenant(requestContext(req))
+}
+
+// WithLocale stores the caller's locale as a BCP 47 tag (e.g. "en-GB") in the context.
+//
+// Example:
+//
+//	// Accept-Language is a weighted list ("en-GB,en;q=0.9"), so pick the preferred tag
+//	if tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language")); err == nil && len(tags) > 0 {
+//		ctx = calque.WithLocale(ctx, tags[0].String())
+//	}
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey, locale)
+}
+
+// Locale retrieves the locale from context.
+//
+// Returns empty string if no locale is found.
+func Locale(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey).(string); ok {
+		return locale
+	}
+	return ""
+}
+
+// LocaleFrom retrieves the locale from a request's context.
+func LocaleFrom(req *Request) string {
+	return Locale(requestContext(req))
+}
+
+// TraceIDFrom retrieves the trace ID from a request's context.
+func TraceIDFrom(req *Request) string {
+	return TraceID(requestContext(req))
+}
+
+// RequestIDFrom retrieves the request ID from a request's context.
+func RequestIDFrom(req *Request) string {
+	return RequestID(requestContext(req))
+}
+
+// requestContext returns the request's context, tolerating nil requests
+func requestContext(req *Request) context.Context {
+	if req == nil || req.Context == nil {
+		return context.Background()
+	}
+	return req.Context
+}
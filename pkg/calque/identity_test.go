@@ -0,0 +1,77 @@
+package calque
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestUserHelpers(t *testing.T) {
+	user := User{ID: "u-1", Name: "Ada", Roles: []string{"admin"}}
+	ctx := WithUser(context.Background(), user)
+
+	got, ok := UserFrom(NewRequest(ctx, strings.NewReader("")))
+	if !ok || got.ID != "u-1" || got.Name != "Ada" {
+		t.Errorf("UserFrom() = %+v, %v", got, ok)
+	}
+	if !got.HasRole("admin") || got.HasRole("billing") {
+		t.Errorf("HasRole() mismatch for roles %v", got.Roles)
+	}
+	if _, ok := UserFromContext(context.Background()); ok {
+		t.Error("UserFromContext() found a user in an empty context")
+	}
+}
+
+func TestStringHelpers(t *testing.T) {
+	ctx := context.Background()
+	ctx = WithTenant(ctx, "acme")
+	ctx = WithLocale(ctx, "en-GB")
+	ctx = WithTraceID(ctx, "trace-1")
+	ctx = WithRequestID(ctx, "req-1")
+	req := NewRequest(ctx, strings.NewReader(""))
+
+	tests := []struct {
+		name string
+		got  func(*Request) string
+		want string
+	}{
+		{"tenant", TenantFrom, "acme"},
+		{"locale", LocaleFrom, "en-GB"},
+		{"trace id", TraceIDFrom, "trace-1"},
+		{"request id", RequestIDFrom, "req-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.got(req); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if got := tt.got(nil); got != "" {
+				t.Errorf("nil request = %q, want empty", got)
+			}
+			if got := tt.got(&Request{}); got != "" {
+				t.Errorf("request without context = %q, want empty", got)
+			}
+		})
+	}
+}
+
+func TestLogging_IncludesUserAndTenant(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	ctx := WithLogger(context.Background(), logger)
+	ctx = WithUser(ctx, User{ID: "u-1"})
+	ctx = WithTenant(ctx, "acme")
+
+	LogInfo(ctx, "hello")
+	LogAttr(ctx, slog.LevelInfo, "attrs")
+
+	for _, want := range []string{"user_id=u-1", "tenant=acme"} {
+		if strings.Count(buf.String(), want) != 2 {
+			t.Errorf("log output missing %q in both lines:\n%s", want, buf.String())
+		}
+	}
+}
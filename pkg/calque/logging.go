@@ -89,7 +89,7 @@ func LogError(ctx context.Context, msg string, err error, args ...any) {
 	logger.ErrorContext(ctx, msg, args...)
 }
 
-// appendContextFields adds trace_id and request_id to args if present in context.
+// appendContextFields adds trace_id, request_id, user_id and tenant to args if present in context.
 //
 // This is called by all log functions to ensure consistent context propagation.
 func appendContextFields(ctx context.Context, args []any) []any {
@@ -99,6 +99,12 @@ func appendContextFields(ctx context.Context, args []any) []any {
 	if requestID := RequestID(ctx); requestID != "" {
 		args = append(args, "request_id", requestID)
 	}
+	if user, ok := UserFromContext(ctx); ok && user.ID != "" {
+		args = append(args, "user_id", user.ID)
+	}
+	if tenant := Tenant(ctx); tenant != "" {
+		args = append(args, "tenant", tenant)
+	}
 	return args
 }
 
@@ -144,6 +150,12 @@ func LogAttr(ctx context.Context, level slog.Level, msg string, attrs ...slog.At
 	if requestID := RequestID(ctx); requestID != "" {
 		attrs = append(attrs, slog.String("request_id", requestID))
 	}
+	if user, ok := UserFromContext(ctx); ok && user.ID != "" {
+		attrs = append(attrs, slog.String("user_id", user.ID))
+	}
+	if tenant := Tenant(ctx); tenant != "" {
+		attrs = append(attrs, slog.String("tenant", tenant))
+	}
 
 	logger.LogAttrs(ctx, level, msg, attrs...)
 }
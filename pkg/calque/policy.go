@@ -0,0 +1,190 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DataPolicy restricts where handlers in a flow may send the data passing through it
+type DataPolicy int
+
+const (
+	// AllowRemote places no restriction on where data is sent (default)
+	AllowRemote DataPolicy = iota
+	// LocalOnly rejects handlers that send data off the host, such as cloud AI
+	// clients, webhooks and remote flows
+	LocalOnly
+)
+
+// String returns the policy name used in violation reports
+func (p DataPolicy) String() string {
+	switch p {
+	case AllowRemote:
+		return "allow-remote"
+	case LocalOnly:
+		return "local-only"
+	default:
+		return fmt.Sprintf("DataPolicy(%d)", int(p))
+	}
+}
+
+// ErrDataPolicy is returned by Validate, Run and ServeFlow when a handler
+// breaks the flow's DataPolicy.
+var ErrDataPolicy = errors.New("data policy violated")
+
+// Egress is implemented by handlers and AI clients that send data off the host.
+//
+// Egress returns the destinations data is sent to, typically host names, or
+// a name such as "s3://bucket" when only an SDK client knows the host. An
+// empty result means the handler keeps data local, e.g. a client configured
+// for a server on localhost.
+//
+// Example:
+//
+//	func (c *Client) Egress() []string { return calque.EgressHosts(c.baseURL) }
+type Egress interface {
+	Egress() []string
+}
+
+// EgressOf returns the off-host destinations declared by a handler or client,
+// nil if it does not implement Egress.
+func EgressOf(v any) []string {
+	if e, ok := v.(Egress); ok {
+		return e.Egress()
+	}
+	return nil
+}
+
+// EgressHosts returns the hosts of the given URLs that are not on this machine.
+//
+// Loopback and unspecified addresses, "localhost" and unix sockets count as
+// local. URLs without a scheme, such as "10.0.0.5:11434", are read as hosts.
+// Unparseable URLs are returned as-is so they are reported rather than allowed.
+func EgressHosts(urls ...string) []string {
+	var hosts []string
+	for _, raw := range urls {
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" && u.Scheme != "unix" {
+			u, err = url.Parse("//" + raw)
+		}
+		switch {
+		case err != nil:
+			hosts = append(hosts, raw)
+		case u.Scheme == "unix" || isLocalHost(u.Hostname()):
+		case !slices.Contains(hosts, u.Hostname()):
+			hosts = append(hosts, u.Hostname())
+		}
+	}
+	return hosts
+}
+
+func isLocalHost(host string) bool {
+	if host == "" || strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
+
+// egressHandler declares the destinations of a handler that can't implement Egress itself
+type egressHandler struct {
+	Handler
+	destinations []string
+}
+
+func (h *egressHandler) Egress() []string { return h.destinations }
+
+// Describe delegates to the wrapped handler; DescribeHandler adds the destinations
+func (h *egressHandler) Describe() Node {
+	return DescribeHandler(h.Handler)
+}
+
+// Warmup forwards to the wrapped handler
+func (h *egressHandler) Warmup(ctx context.Context) error {
+	return WarmupHandler(ctx, h.Handler)
+}
+
+// Shutdown forwards to the wrapped handler
+func (h *egressHandler) Shutdown(ctx context.Context) error {
+	return ShutdownHandler(ctx, h.Handler)
+}
+
+// WithEgress declares that a handler sends data to the given destinations.
+//
+// Input: handler and the destinations it sends data to
+// Output: Handler that also implements Egress
+// Behavior: STREAMING - delegates directly to the wrapped handler
+//
+// Use it to mark custom handlers that call external services so LocalOnly
+// flows reject them. With no destinations the handler is returned unchanged.
+//
+// Example:
+//
+//	audit := calque.WithEgress(auditHandler, "audit.example.com")
+func WithEgress(handler Handler, destinations ...string) Handler {
+	if len(destinations) == 0 {
+		return handler
+	}
+	return &egressHandler{Handler: handler, destinations: destinations}
+}
+
+// WithDataPolicy returns a FlowConfig that applies a data policy.
+//
+// Input: DataPolicy to enforce
+// Output: FlowConfig for NewFlow, merged with any other configs passed
+// Behavior: Use reports every handler that breaks the policy; Validate, Run
+// and ServeFlow then fail with ErrDataPolicy before any handler executes
+//
+// Under LocalOnly, a handler breaks the policy when it or anything nested in
+// it implements Egress with a destination, e.g. an ai.Agent with an OpenAI
+// client or a Slack sink. Nesting is found through Describe: the wrappers in
+// this module describe what they wrap, but a custom wrapper that doesn't
+// implement Describer (or use Described) hides its handlers.
+//
+// Example:
+//
+//	flow := calque.NewFlow(calque.WithDataPolicy(calque.LocalOnly)).
+//		Use(ai.Agent(ollamaClient)).
+//		Use(sink.Slack(webhookURL))
+//	if err := flow.Validate(); err != nil {
+//		log.Fatal(err) // handler 1 (sink.Slack) sends data to hooks.slack.com
+//	}
+func WithDataPolicy(policy DataPolicy) FlowConfig {
+	return FlowConfig{DataPolicy: policy}
+}
+
+// checkPolicy records a violation for each destination reachable from handler
+func (f *Flow) checkPolicy(index int, handler Handler) {
+	if f.dataPolicy != LocalOnly {
+		return
+	}
+	var walk func(node Node, path []string)
+	walk = func(node Node, path []string) {
+		path = append(path, node.Label)
+		if len(node.Egress) > 0 {
+			f.violations = append(f.violations, fmt.Sprintf("handler %d (%s) sends data to %s",
+				index, strings.Join(path, " > "), strings.Join(node.Egress, ", ")))
+		}
+		for _, child := range node.Children {
+			walk(child, path)
+		}
+	}
+	walk(DescribeHandler(handler), nil)
+}
+
+// policyErr reports every recorded violation, nil if there are none
+func (f *Flow) policyErr() error {
+	if len(f.violations) == 0 {
+		return nil
+	}
+	report := fmt.Errorf("%w:\n  %s", ErrDataPolicy, strings.Join(f.violations, "\n  "))
+	return WrapErr(context.Background(), report, fmt.Sprintf("%s flow sends data off the host", f.dataPolicy))
+}
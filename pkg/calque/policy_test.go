@@ -0,0 +1,171 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// cloudClient is a handler that declares it sends data off the host
+type cloudClient struct {
+	HandlerFunc
+	hosts []string
+}
+
+func (c cloudClient) Egress() []string { return c.hosts }
+
+func TestEgressHosts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		urls []string
+		want []string
+	}{
+		{name: "remote URL", urls: []string{"https://api.example.com/v1"}, want: []string{"api.example.com"}},
+		{name: "localhost", urls: []string{"http://localhost:11434", "http://api.localhost"}},
+		{name: "loopback and unspecified", urls: []string{"http://127.0.0.1:8080", "http://[::1]:8080", "0.0.0.0:11434"}},
+		{name: "unix socket", urls: []string{"unix:///var/run/model.sock"}},
+		{name: "host without scheme", urls: []string{"10.0.0.5:11434", "smtp.example.com"}, want: []string{"10.0.0.5", "smtp.example.com"}},
+		{name: "duplicates and empty", urls: []string{"", "https://a.example.com/x", "https://a.example.com/y"}, want: []string{"a.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := EgressHosts(tt.urls...); !slices.Equal(got, tt.want) {
+				t.Errorf("EgressHosts(%v) = %v, want %v", tt.urls, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithEgress(t *testing.T) {
+	t.Parallel()
+
+	plain := HandlerFunc(passthrough)
+	if WithEgress(plain) == nil || EgressOf(WithEgress(plain)) != nil {
+		t.Error("WithEgress without destinations should return the handler unchanged")
+	}
+
+	h := WithEgress(plain, "audit.example.com")
+	if got := EgressOf(h); !slices.Equal(got, []string{"audit.example.com"}) {
+		t.Errorf("EgressOf() = %v", got)
+	}
+	if got := DescribeHandler(h).Egress; !slices.Equal(got, []string{"audit.example.com"}) {
+		t.Errorf("described egress = %v", got)
+	}
+
+	var out string
+	if err := NewFlow().Use(h).Run(context.Background(), "hi", &out); err != nil || out != "hi" {
+		t.Errorf("Run() = %q, %v", out, err)
+	}
+}
+
+func TestDataPolicy(t *testing.T) {
+	t.Parallel()
+
+	local := HandlerFunc(passthrough)
+	cloud := cloudClient{HandlerFunc: passthrough, hosts: []string{"api.openai.com"}}
+	onHost := cloudClient{HandlerFunc: passthrough}
+	webhook := WithEgress(local, "hooks.slack.com")
+	nested := Described(local, func() Node {
+		return Node{Label: "fallback", Kind: NodeBranch, Children: []Node{DescribeHandler(local), DescribeHandler(cloud)}}
+	})
+
+	tests := []struct {
+		name     string
+		policy   DataPolicy
+		handlers []Handler
+		want     []string
+	}{
+		{
+			name:     "allow remote",
+			policy:   AllowRemote,
+			handlers: []Handler{cloud, webhook},
+		},
+		{
+			name:     "local handlers",
+			policy:   LocalOnly,
+			handlers: []Handler{local, onHost, NewFlow().Use(local)},
+		},
+		{
+			name:     "every violation reported",
+			policy:   LocalOnly,
+			handlers: []Handler{local, cloud, webhook},
+			want: []string{
+				"local-only flow sends data off the host",
+				"handler 1 (calque.cloudClient) sends data to api.openai.com",
+				"handler 2 (calque.passthrough) sends data to hooks.slack.com",
+			},
+		},
+		{
+			name:     "nested handlers",
+			policy:   LocalOnly,
+			handlers: []Handler{nested, NewFlow().Use(webhook)},
+			want: []string{
+				"handler 0 (fallback > calque.cloudClient) sends data to api.openai.com",
+				"handler 1 (flow > calque.passthrough) sends data to hooks.slack.com",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			flow := NewFlow(WithDataPolicy(tt.policy))
+			for _, h := range tt.handlers {
+				flow.Use(h)
+			}
+
+			err := flow.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrDataPolicy) {
+				t.Fatalf("Validate() error = %v, want ErrDataPolicy", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("report missing %q:\n%v", want, err)
+				}
+			}
+
+			var out string
+			if runErr := flow.Run(context.Background(), "hi", &out); !errors.Is(runErr, ErrDataPolicy) {
+				t.Errorf("Run() error = %v, want ErrDataPolicy", runErr)
+			}
+		})
+	}
+}
+
+func TestNewFlow_MergesConfigs(t *testing.T) {
+	t.Parallel()
+
+	flow := NewFlow(FlowConfig{MaxConcurrent: 3, MetadataBusBuffer: 7}, WithDataPolicy(LocalOnly))
+	if cap(flow.sem) != 3 || flow.metadataBusBuffer != 7 || flow.dataPolicy != LocalOnly {
+		t.Errorf("merged config = sem %d, buffer %d, policy %v", cap(flow.sem), flow.metadataBusBuffer, flow.dataPolicy)
+	}
+}
+
+func TestDataPolicyString(t *testing.T) {
+	t.Parallel()
+
+	if AllowRemote.String() != "allow-remote" || LocalOnly.String() != "local-only" || DataPolicy(4).String() != "DataPolicy(4)" {
+		t.Errorf("String() = %s, %s, %s", AllowRemote, LocalOnly, DataPolicy(4))
+	}
+}
+
+func passthrough(req *Request, res *Response) error {
+	var s string
+	if err := Read(req, &s); err != nil {
+		return err
+	}
+	return Write(res, s)
+}
@@ -0,0 +1,148 @@
+package calque
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// ProgressEvent describes a single progress update emitted by a handler.
+//
+// Percent is expressed in the range 0-100. A negative value means the
+// handler cannot estimate completion and is only reporting a stage change.
+type ProgressEvent struct {
+	RunID   string    `json:"run_id,omitempty"`
+	Stage   string    `json:"stage"`
+	Percent float64   `json:"percent"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// ProgressFunc receives progress events emitted during a flow run.
+//
+// Reporters are called synchronously from the emitting handler's goroutine,
+// so implementations must be safe for concurrent use and should return quickly.
+type ProgressFunc func(ProgressEvent)
+
+// WithProgress registers a progress reporter in the context.
+//
+// Multiple reporters can be registered by calling WithProgress repeatedly;
+// every registered reporter receives each event in registration order.
+//
+// Example:
+//
+//	ctx = calque.WithProgress(ctx, func(ev calque.ProgressEvent) {
+//	    fmt.Printf("[%s] %s %.0f%%\n", ev.RunID, ev.Stage, ev.Percent)
+//	})
+//	flow.Run(ctx, input, &output)
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	existing := progressFrom(ctx)
+	state := progressState{runID: existing.runID, reporters: make([]ProgressFunc, 0, len(existing.reporters)+1)}
+	state.reporters = append(state.reporters, existing.reporters...)
+	state.reporters = append(state.reporters, fn)
+	if state.runID == "" {
+		state.runID = newRunID()
+	}
+	return context.WithValue(ctx, progressKey, state)
+}
+
+// Progress emits a progress event to every reporter registered in the context.
+//
+// The run ID is taken from the request ID, falling back to the trace ID and
+// then to an ID generated by the first WithProgress call, so events of one run
+// can always be correlated. Calling Progress without any registered reporter
+// is a no-op, so handlers can report progress unconditionally.
+//
+// Example:
+//
+//	calque.Progress(req.Context, "embedding", 40, "embedded 400/1000 chunks")
+func Progress(ctx context.Context, stage string, percent float64, message string) {
+	state := progressFrom(ctx)
+	if len(state.reporters) == 0 {
+		return
+	}
+
+	runID := RequestID(ctx)
+	if runID == "" {
+		runID = TraceID(ctx)
+	}
+	if runID == "" {
+		runID = state.runID
+	}
+
+	if percent > 100 {
+		percent = 100
+	}
+
+	ev := ProgressEvent{
+		RunID:   runID,
+		Stage:   stage,
+		Percent: percent,
+		Message: message,
+		Time:    time.Now(),
+	}
+
+	for _, fn := range state.reporters {
+		fn(ev)
+	}
+}
+
+// HasProgress reports whether any progress reporter is registered in the context.
+//
+// Handlers can use this to skip expensive progress computations when nobody is listening.
+func HasProgress(ctx context.Context) bool {
+	return len(progressFrom(ctx).reporters) > 0
+}
+
+// ProgressChannel returns a reporter that forwards events to a buffered channel.
+//
+// Events are dropped rather than blocking the handler when the channel is full.
+// The caller owns the returned channel and should stop reading once the flow completes.
+//
+// Example:
+//
+//	report, events := calque.ProgressChannel(16)
+//	ctx = calque.WithProgress(ctx, report)
+//	go func() {
+//	    for ev := range events {
+//	        log.Println(ev.Stage, ev.Percent)
+//	    }
+//	}()
+func ProgressChannel(buffer int) (ProgressFunc, <-chan ProgressEvent) {
+	if buffer < 1 {
+		buffer = 1
+	}
+	ch := make(chan ProgressEvent, buffer)
+	return func(ev ProgressEvent) {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}, ch
+}
+
+// progressState holds the reporters registered in a context and the run ID
+// used when the context carries no request or trace ID
+type progressState struct {
+	reporters []ProgressFunc
+	runID     string
+}
+
+func progressFrom(ctx context.Context) progressState {
+	if ctx == nil {
+		return progressState{}
+	}
+	state, _ := ctx.Value(progressKey).(progressState)
+	return state
+}
+
+// newRunID generates a random run ID for progress events
+func newRunID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
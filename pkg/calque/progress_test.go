@@ -0,0 +1,178 @@
+package calque
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       func() context.Context
+		percent   float64
+		wantRunID string
+		wantPct   float64
+	}{
+		{
+			name:      "uses request id as run id",
+			ctx:       func() context.Context { return WithRequestID(context.Background(), "req-1") },
+			percent:   25,
+			wantRunID: "req-1",
+			wantPct:   25,
+		},
+		{
+			name:      "falls back to trace id",
+			ctx:       func() context.Context { return WithTraceID(context.Background(), "trace-1") },
+			percent:   50,
+			wantRunID: "trace-1",
+			wantPct:   50,
+		},
+		{
+			name:    "clamps percent above 100",
+			ctx:     context.Background,
+			percent: 150,
+			wantPct: 100,
+		},
+		{
+			name:    "keeps negative percent for indeterminate progress",
+			ctx:     context.Background,
+			percent: -1,
+			wantPct: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []ProgressEvent
+			ctx := WithProgress(tt.ctx(), func(ev ProgressEvent) {
+				got = append(got, ev)
+			})
+
+			Progress(ctx, "stage", tt.percent, "working")
+
+			if len(got) != 1 {
+				t.Fatalf("expected 1 event, got %d", len(got))
+			}
+			if tt.wantRunID == "" && len(got[0].RunID) != 16 {
+				t.Errorf("RunID = %q, want a generated ID", got[0].RunID)
+			} else if tt.wantRunID != "" && got[0].RunID != tt.wantRunID {
+				t.Errorf("RunID = %q, want %q", got[0].RunID, tt.wantRunID)
+			}
+			if got[0].Percent != tt.wantPct {
+				t.Errorf("Percent = %v, want %v", got[0].Percent, tt.wantPct)
+			}
+			if got[0].Stage != "stage" || got[0].Message != "working" {
+				t.Errorf("unexpected event %+v", got[0])
+			}
+			if got[0].Time.IsZero() {
+				t.Error("expected event time to be set")
+			}
+		})
+	}
+}
+
+func TestProgress_NoReporter(t *testing.T) {
+	ctx := context.Background()
+	if HasProgress(ctx) {
+		t.Error("expected no progress reporter")
+	}
+	// Must not panic
+	Progress(ctx, "stage", 10, "")
+	Progress(nil, "stage", 10, "") //nolint:staticcheck // nil context must be tolerated
+}
+
+func TestWithProgress_MultipleReporters(t *testing.T) {
+	var order []string
+	ctx := WithProgress(context.Background(), func(ProgressEvent) { order = append(order, "first") })
+	ctx = WithProgress(ctx, func(ProgressEvent) { order = append(order, "second") })
+	ctx = WithProgress(ctx, nil)
+
+	if !HasProgress(ctx) {
+		t.Fatal("expected progress reporter")
+	}
+
+	Progress(ctx, "stage", 0, "")
+
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("unexpected reporter order %v", order)
+	}
+}
+
+func TestProgressChannel(t *testing.T) {
+	report, events := ProgressChannel(1)
+	ctx := WithProgress(context.Background(), report)
+
+	Progress(ctx, "one", 10, "")
+	Progress(ctx, "two", 20, "") // dropped, buffer full
+
+	ev := <-events
+	if ev.Stage != "one" {
+		t.Errorf("Stage = %q, want %q", ev.Stage, "one")
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("expected dropped event, got %+v", ev)
+	default:
+	}
+}
+
+func TestProgress_FromFlowHandlers(t *testing.T) {
+	var mu sync.Mutex
+	stages := map[string]bool{}
+
+	ctx := WithProgress(context.Background(), func(ev ProgressEvent) {
+		mu.Lock()
+		stages[ev.Stage] = true
+		mu.Unlock()
+	})
+
+	stage := func(name string) Handler {
+		return HandlerFunc(func(req *Request, res *Response) error {
+			Progress(req.Context, name, 100, "")
+			_, err := io.Copy(res.Data, req.Data)
+			return err
+		})
+	}
+
+	flow := NewFlow().Use(stage("a")).Use(stage("b"))
+
+	var out string
+	if err := flow.Run(ctx, "data", &out); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if out != "data" {
+		t.Errorf("output = %q, want %q", out, "data")
+	}
+	if !stages["a"] || !stages["b"] {
+		t.Errorf("expected progress from both stages, got %v", stages)
+	}
+}
+
+func TestProgressGeneratedRunID(t *testing.T) {
+	var got []ProgressEvent
+	record := func(ev ProgressEvent) { got = append(got, ev) }
+
+	ctx := WithProgress(context.Background(), record)
+	ctx = WithProgress(ctx, record)
+	Progress(ctx, "load", 10, "")
+	Progress(ctx, "load", 20, "")
+
+	if len(got) != 4 || got[0].RunID == "" {
+		t.Fatalf("events = %+v, want four with a generated run ID", got)
+	}
+	for _, ev := range got {
+		if ev.RunID != got[0].RunID {
+			t.Errorf("RunID = %q, want every event of the run to share %q", ev.RunID, got[0].RunID)
+		}
+	}
+
+	first := got[0].RunID
+	got = nil
+	Progress(WithProgress(context.Background(), record), "load", 10, "")
+	if got[0].RunID == "" || got[0].RunID == first {
+		t.Errorf("RunID = %q, want a new ID for another run", got[0].RunID)
+	}
+}
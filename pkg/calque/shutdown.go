@@ -0,0 +1,203 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrFlowShutdown is returned by Run and ServeFlow once Shutdown has been called.
+var ErrFlowShutdown = errors.New("flow is shut down")
+
+// Shutdowner is implemented by handlers and servers that release resources on shutdown.
+//
+// Handlers holding clients, connections or exporters implement it so
+// Flow.Shutdown can close them. Handlers implementing only io.Closer are
+// closed as well.
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// ShutdownHandler shuts down handler if it implements Shutdowner or io.Closer.
+//
+// Wrapping middleware calls this from its own Shutdown so the handlers it
+// wraps release their resources too.
+func ShutdownHandler(ctx context.Context, handler Handler) error {
+	switch h := handler.(type) {
+	case Shutdowner:
+		return h.Shutdown(ctx)
+	case io.Closer:
+		return h.Close()
+	}
+	return nil
+}
+
+// lifecycle tracks in-flight runs so a flow can drain before shutting down
+type lifecycle struct {
+	mu       sync.Mutex
+	closed   bool
+	active   int
+	drained  chan struct{} // closed when closed and active reaches zero
+	once     sync.Once     // resources are released once
+	hooks    []func(ctx context.Context) error
+	closeErr error
+}
+
+// enter registers a run, failing once shutdown has started
+func (l *lifecycle) enter(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return WrapErr(ctx, ErrFlowShutdown, "flow rejected run")
+	}
+	l.active++
+	return nil
+}
+
+func (l *lifecycle) leave() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	if l.closed && l.active == 0 {
+		close(l.drained)
+	}
+}
+
+// close stops new runs and returns a channel closed once in-flight runs finish
+func (l *lifecycle) close() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.closed {
+		l.closed = true
+		l.drained = make(chan struct{})
+		if l.active == 0 {
+			close(l.drained)
+		}
+	}
+	return l.drained
+}
+
+// OnShutdown registers a function run by Shutdown after in-flight runs finish.
+//
+// Use it for resources the flow does not own as handlers: observability
+// exporters, store and provider clients, connection pools. Hooks run in reverse
+// registration order, after the flow's own handlers are shut down, so exporters
+// registered first are flushed last and still capture spans from closing handlers.
+//
+// Example:
+//
+//	flow.OnShutdown(tracerProvider.Shutdown)
+//	flow.OnShutdown(func(context.Context) error { return vectorStore.Close() })
+func (f *Flow) OnShutdown(hook func(ctx context.Context) error) *Flow {
+	f.lifecycle.mu.Lock()
+	f.lifecycle.hooks = append(f.lifecycle.hooks, hook)
+	f.lifecycle.mu.Unlock()
+	return f
+}
+
+// Shutdown drains the flow and releases its resources.
+//
+// Input: context.Context whose deadline bounds how long in-flight runs may take
+// Output: error if runs were still in flight at the deadline or resources failed to close
+// Behavior: BLOCKING - waits for in-flight runs, then closes handlers and runs hooks
+//
+// New calls to Run and ServeFlow fail with ErrFlowShutdown as soon as Shutdown
+// starts. In-flight runs are allowed to finish until ctx ends; resources are
+// released either way, so an expired deadline still closes connections rather
+// than leaking them. Handlers implementing Shutdowner or io.Closer are shut down
+// (through nested flows and ctrl wrappers), then OnShutdown hooks run. Calling
+// Shutdown again waits for the same drain and returns the first result.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+//	defer cancel()
+//	if err := flow.Shutdown(ctx); err != nil {
+//		log.Printf("unclean shutdown: %v", err)
+//	}
+func (f *Flow) Shutdown(ctx context.Context) error {
+	var drainErr error
+	select {
+	case <-f.lifecycle.close():
+	case <-ctx.Done():
+		f.lifecycle.mu.Lock()
+		active := f.lifecycle.active
+		f.lifecycle.mu.Unlock()
+		drainErr = WrapErr(ctx, ctx.Err(), fmt.Sprintf("shutdown deadline reached with %d runs in flight", active))
+	}
+
+	f.lifecycle.once.Do(func() {
+		f.lifecycle.closeErr = f.release(ctx)
+	})
+	return errors.Join(drainErr, f.lifecycle.closeErr)
+}
+
+// release shuts down handlers, then runs hooks newest first
+func (f *Flow) release(ctx context.Context) error {
+	var errs []error
+	for i, handler := range f.handlers {
+		if err := ShutdownHandler(ctx, handler); err != nil {
+			errs = append(errs, WrapErr(ctx, err, fmt.Sprintf("handler %d shutdown failed", i)))
+		}
+	}
+
+	f.lifecycle.mu.Lock()
+	hooks := slices.Clone(f.lifecycle.hooks)
+	f.lifecycle.mu.Unlock()
+	for _, hook := range slices.Backward(hooks) {
+		if err := hook(ctx); err != nil {
+			errs = append(errs, WrapErr(ctx, err, "shutdown hook failed"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown drains and releases the underlying flow, see Flow.Shutdown.
+func (t *TypedFlow[TIn, TOut]) Shutdown(ctx context.Context) error {
+	return t.flow.Shutdown(ctx)
+}
+
+// GracefulShutdown waits for SIGINT or SIGTERM, then shuts down each target.
+//
+// Input: context.Context (cancelling it also triggers shutdown), drain timeout, targets
+// Output: error joining every target that failed to shut down cleanly
+// Behavior: BLOCKING - returns after all targets are shut down
+//
+// Targets are shut down in order, sharing one timeout, so list servers before
+// the flows they serve: the server stops accepting requests first, then flows
+// drain and close their clients. This is what container orchestrators expect
+// between SIGTERM and the kill deadline.
+//
+// Example:
+//
+//	server := grpc.NewServer(":8080")
+//	server.RegisterFlow("chat", flow)
+//	go server.Start()
+//
+//	if err := calque.GracefulShutdown(context.Background(), 25*time.Second, server, flow); err != nil {
+//		log.Printf("shutdown: %v", err)
+//	}
+func GracefulShutdown(ctx context.Context, timeout time.Duration, targets ...Shutdowner) error {
+	signalCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-signalCtx.Done()
+
+	LogInfo(ctx, "shutting down", "timeout", timeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	var errs []error
+	for _, target := range targets {
+		if err := target.Shutdown(shutdownCtx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
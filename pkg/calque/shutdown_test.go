@@ -0,0 +1,165 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// closable is a pass-through handler recording how it was released
+type closable struct {
+	closed atomic.Int32
+	err    error
+}
+
+func (c *closable) ServeFlow(req *Request, res *Response) error {
+	_, err := io.Copy(res.Data, req.Data)
+	return err
+}
+
+func (c *closable) Close() error {
+	c.closed.Add(1)
+	return c.err
+}
+
+// shutdownable records the shutdown in addition to passing data through
+type shutdownable struct{ closable }
+
+func (s *shutdownable) Shutdown(context.Context) error { return s.Close() }
+
+func TestFlow_ShutdownRejectsNewRuns(t *testing.T) {
+	flow := NewFlow().UseFunc(func(req *Request, res *Response) error {
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+	outer := NewFlow().Use(flow)
+
+	if err := flow.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	var out string
+	if err := flow.Run(context.Background(), "x", &out); !errors.Is(err, ErrFlowShutdown) {
+		t.Errorf("Run() after shutdown error = %v, want ErrFlowShutdown", err)
+	}
+	if err := outer.Run(context.Background(), "x", &out); !errors.Is(err, ErrFlowShutdown) {
+		t.Errorf("nested ServeFlow after shutdown error = %v, want ErrFlowShutdown", err)
+	}
+}
+
+func TestFlow_ShutdownDrainsInFlightRuns(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	flow := NewFlow().UseFunc(func(req *Request, res *Response) error {
+		close(started)
+		<-release
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+
+	var runErr error
+	var out string
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		runErr = flow.Run(context.Background(), "in flight", &out)
+	}()
+	<-started
+
+	done := make(chan error, 1)
+	go func() { done <- flow.Shutdown(context.Background()) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Shutdown() returned %v before the in-flight run finished", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	wg.Wait()
+	if err := <-done; err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	if runErr != nil || out != "in flight" {
+		t.Errorf("in-flight run = %q, %v; want it to complete", out, runErr)
+	}
+}
+
+func TestFlow_ShutdownDeadline(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	res := &closable{}
+	flow := NewFlow().Use(res).UseFunc(func(_ *Request, _ *Response) error {
+		<-block
+		return nil
+	})
+
+	started := make(chan struct{})
+	go func() {
+		close(started)
+		_ = flow.Run(context.Background(), "x", new(string))
+	}()
+	<-started
+	time.Sleep(5 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := flow.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want deadline exceeded", err)
+	}
+	if res.closed.Load() != 1 {
+		t.Error("resources must be released even when the drain deadline passes")
+	}
+}
+
+func TestFlow_ShutdownReleasesResources(t *testing.T) {
+	closer, shut, nested := &closable{}, &shutdownable{}, &closable{}
+	failing := &closable{err: errors.New("flush failed")}
+
+	var order []string
+	flow := NewFlow().
+		Use(closer).
+		Use(WithContentTypes(shut, ContentTypeText)).
+		Use(NewFlow().Use(nested)).
+		Use(failing).
+		OnShutdown(func(context.Context) error { order = append(order, "exporter"); return nil }).
+		OnShutdown(func(context.Context) error { order = append(order, "store"); return nil })
+
+	err := flow.Shutdown(context.Background())
+	if !errors.Is(err, failing.err) {
+		t.Errorf("Shutdown() error = %v, want handler failure reported", err)
+	}
+	for name, c := range map[string]*closable{"closer": closer, "shutdowner": &shut.closable, "nested": nested} {
+		if c.closed.Load() != 1 {
+			t.Errorf("%s released %d times, want 1", name, c.closed.Load())
+		}
+	}
+	if len(order) != 2 || order[0] != "store" || order[1] != "exporter" {
+		t.Errorf("hook order = %v, want newest first", order)
+	}
+
+	// A second call does not release resources again
+	if err := flow.Shutdown(context.Background()); !errors.Is(err, failing.err) || closer.closed.Load() != 1 {
+		t.Errorf("repeat Shutdown() = %v, closer released %d times", err, closer.closed.Load())
+	}
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	first, second := &closable{}, &closable{}
+	a, b := NewFlow().Use(first), NewFlow().Use(second)
+
+	// Cancelling the parent context triggers shutdown just like a signal
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := GracefulShutdown(ctx, time.Second, a, b); err != nil {
+		t.Fatalf("GracefulShutdown() error = %v", err)
+	}
+	if first.closed.Load() != 1 || second.closed.Load() != 1 {
+		t.Error("GracefulShutdown did not shut down every target")
+	}
+}
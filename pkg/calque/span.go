@@ -0,0 +1,67 @@
+package calque
+
+import "context"
+
+// Span is the part of a tracing span that handlers annotate.
+//
+// observability.Span satisfies it, so spans from any observability
+// TracerProvider can be used.
+type Span interface {
+	SetAttribute(key string, value any)
+	End(err error)
+}
+
+// SpanStarter starts a child span of the span in ctx.
+//
+// Tracing middleware puts one in the context so that packages without a
+// tracing dependency, such as ai and tools, can emit spans of their own.
+type SpanStarter func(ctx context.Context, name string) (context.Context, Span)
+
+// WithSpanStarter stores a SpanStarter in the context.
+//
+// Example:
+//
+//	ctx = calque.WithSpanStarter(ctx, func(ctx context.Context, name string) (context.Context, calque.Span) {
+//	    return tracer.StartSpan(ctx, name)
+//	})
+func WithSpanStarter(ctx context.Context, start SpanStarter) context.Context {
+	return context.WithValue(ctx, spanStarterKey, start)
+}
+
+// WithSpan stores the span of the current operation in the context, for SetSpanAttribute.
+func WithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey, span)
+}
+
+// StartSpan starts a child span using the context's SpanStarter.
+//
+// The returned context carries the new span, so SetSpanAttribute and nested
+// StartSpan calls apply to it. Without a SpanStarter it returns ctx unchanged
+// and a span that does nothing, so callers never need to check.
+//
+// Example:
+//
+//	ctx, span := calque.StartSpan(req.Context, "chat gpt-4o")
+//	span.SetAttribute("gen_ai.request.model", "gpt-4o")
+//	defer func() { span.End(err) }()
+func StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	start, ok := ctx.Value(spanStarterKey).(SpanStarter)
+	if !ok || start == nil {
+		return ctx, noopSpan{}
+	}
+	ctx, span := start(ctx, name)
+	return WithSpan(ctx, span), span
+}
+
+// SetSpanAttribute sets an attribute on the span of the current operation.
+// It does nothing when the context carries no span.
+func SetSpanAttribute(ctx context.Context, key string, value any) {
+	if span, ok := ctx.Value(spanKey).(Span); ok {
+		span.SetAttribute(key, value)
+	}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, any) {}
+func (noopSpan) End(error)                {}
@@ -0,0 +1,55 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type recordingSpan struct {
+	name  string
+	attrs map[string]any
+	ended bool
+	err   error
+}
+
+func (s *recordingSpan) SetAttribute(key string, value any) { s.attrs[key] = value }
+func (s *recordingSpan) End(err error)                      { s.ended, s.err = true, err }
+
+func TestStartSpan(t *testing.T) {
+	t.Parallel()
+
+	var spans []*recordingSpan
+	ctx := WithSpanStarter(context.Background(), func(ctx context.Context, name string) (context.Context, Span) {
+		span := &recordingSpan{name: name, attrs: map[string]any{}}
+		spans = append(spans, span)
+		return ctx, span
+	})
+
+	ctx, span := StartSpan(ctx, "chat")
+	SetSpanAttribute(ctx, "gen_ai.request.model", "gpt-4o")
+	span.End(errors.New("boom"))
+
+	if len(spans) != 1 || spans[0].name != "chat" {
+		t.Fatalf("spans = %+v", spans)
+	}
+	if spans[0].attrs["gen_ai.request.model"] != "gpt-4o" {
+		t.Errorf("attributes = %v", spans[0].attrs)
+	}
+	if !spans[0].ended || spans[0].err == nil {
+		t.Errorf("span not ended with error: %+v", spans[0])
+	}
+}
+
+func TestStartSpanWithoutStarter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	got, span := StartSpan(ctx, "chat")
+	if got != ctx {
+		t.Error("StartSpan() without a starter should return the context unchanged")
+	}
+	span.SetAttribute("key", "value")
+	span.End(nil)
+	SetSpanAttribute(ctx, "key", "value") // must not panic
+}
@@ -0,0 +1,188 @@
+package calque
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// TypedFlow is a flow with compile-time input and output types.
+//
+// Input: TIn value passed to Run
+// Output: TOut value returned from Run
+// Behavior: BUFFERED output decode, STREAMING through the inner flow
+//
+// Converters are inferred from the type parameters: string, []byte and io.Reader
+// pass through unchanged, types implementing InputConverter/OutputConverter use
+// their own conversion, and everything else (structs, maps, slices, numbers) is
+// encoded as JSON. TypedFlow is itself a Handler, so it can be nested in other flows.
+//
+// Example:
+//
+//	type Query struct{ Question string `json:"question"` }
+//	type Answer struct{ Text string `json:"text"` }
+//
+//	qa, err := calque.Typed[Query, Answer](prompt.Template("{{.Input}}"), ai.Agent(client, ai.WithSchemaFor[Answer]()))
+//	if err != nil {
+//		log.Fatal(err) // incompatible handler content types
+//	}
+//	answer, err := qa.Run(ctx, Query{Question: "What is Go?"})
+type TypedFlow[TIn, TOut any] struct {
+	flow     *Flow
+	accepts  string
+	produces string
+}
+
+// Typed builds a TypedFlow from handlers, negotiating declared content types.
+//
+// Input: handlers to run in order
+// Output: *TypedFlow[TIn, TOut], error if declared content types cannot be reconciled
+// Behavior: Construction-time validation, no handlers are executed
+//
+// The inferred input type is negotiated against the first handler and the last
+// handler against the inferred output type, inserting registered converters
+// where needed (see RegisterContentConverter).
+//
+// Example:
+//
+//	f, err := calque.Typed[string, Report](extractor, summarizer)
+func Typed[TIn, TOut any](handlers ...Handler) (*TypedFlow[TIn, TOut], error) {
+	return TypedWithConfig[TIn, TOut](FlowConfig{}, handlers...)
+}
+
+// TypedWithConfig builds a TypedFlow using the given flow configuration.
+//
+// Example:
+//
+//	f, err := calque.TypedWithConfig[Query, Answer](calque.FlowConfig{MaxConcurrent: 50}, handlers...)
+func TypedWithConfig[TIn, TOut any](config FlowConfig, handlers ...Handler) (*TypedFlow[TIn, TOut], error) {
+	ctx := context.Background()
+	accepts := contentTypeFor[TIn]()
+	produces := contentTypeFor[TOut]()
+
+	flow := NewFlow(config)
+	flow.produces = accepts
+	for _, h := range handlers {
+		flow.Use(h)
+	}
+
+	if produces != ContentTypeAny {
+		conv, err := negotiateContentType(flow.produces, []string{produces})
+		switch {
+		case err != nil && flow.buildErr == nil:
+			flow.buildErr = NewErr(ctx, fmt.Sprintf("output %v", err))
+		case conv != nil:
+			flow.Use(conv)
+		}
+	}
+
+	if err := flow.Validate(); err != nil {
+		return nil, WrapErr(ctx, err, fmt.Sprintf("typed flow %s", typedFlowName[TIn, TOut]()))
+	}
+
+	return &TypedFlow[TIn, TOut]{flow: flow, accepts: accepts, produces: produces}, nil
+}
+
+// Run executes the flow with a typed input and returns a typed output.
+//
+// Example:
+//
+//	answer, err := qa.Run(ctx, Query{Question: "What is Go?"})
+func (t *TypedFlow[TIn, TOut]) Run(ctx context.Context, input TIn, opts ...RunOption) (TOut, error) {
+	var out TOut
+
+	in, err := typedInput(ctx, input)
+	if err != nil {
+		return out, err
+	}
+
+	switch target := any(&out).(type) {
+	case *string, *[]byte, *io.Reader, OutputConverter:
+		err = t.flow.Run(ctx, in, target, opts...)
+		return out, err
+	}
+
+	var buf bytes.Buffer
+	if err := t.flow.Run(ctx, in, &buf, opts...); err != nil {
+		return out, err
+	}
+
+	// Named string types (type Label string) are assigned directly
+	if v := reflect.ValueOf(&out).Elem(); v.Kind() == reflect.String {
+		v.SetString(buf.String())
+		return out, nil
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		return out, WrapErr(ctx, err, fmt.Sprintf("failed to decode flow output into %T", out))
+	}
+	return out, nil
+}
+
+// ServeFlow implements Handler so a TypedFlow can be embedded in other flows.
+func (t *TypedFlow[TIn, TOut]) ServeFlow(req *Request, res *Response) error {
+	return t.flow.ServeFlow(req, res)
+}
+
+// Accepts implements ContentTyped using the content type inferred from TIn.
+func (t *TypedFlow[TIn, TOut]) Accepts() []string {
+	if t.accepts == ContentTypeAny {
+		return nil
+	}
+	return []string{t.accepts}
+}
+
+// Produces implements ContentTyped using the content type inferred from TOut.
+func (t *TypedFlow[TIn, TOut]) Produces() string {
+	return t.produces
+}
+
+// typedInput converts a typed value into something Flow.Run understands
+func typedInput(ctx context.Context, input any) (any, error) {
+	switch v := input.(type) {
+	case string, []byte, io.Reader, InputConverter:
+		return v, nil
+	}
+
+	// Named string types (type Query string) pass through as text, matching contentTypeFor
+	if v := reflect.ValueOf(input); v.Kind() == reflect.String {
+		return v.String(), nil
+	}
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, WrapErr(ctx, err, fmt.Sprintf("failed to encode flow input %T", input))
+	}
+	return data, nil
+}
+
+var (
+	inputConverterType  = reflect.TypeFor[InputConverter]()
+	outputConverterType = reflect.TypeFor[OutputConverter]()
+	readerType          = reflect.TypeFor[io.Reader]()
+)
+
+// contentTypeFor infers the content type carried by a Go type
+func contentTypeFor[T any]() string {
+	t := reflect.TypeFor[T]()
+
+	switch {
+	case t.Kind() == reflect.String, t == reflect.TypeFor[[]byte]():
+		return ContentTypeAny
+	case t.Kind() == reflect.Interface && t.Implements(readerType):
+		return ContentTypeAny
+	case t.Implements(inputConverterType), t.Implements(outputConverterType),
+		reflect.PointerTo(t).Implements(outputConverterType):
+		return ContentTypeAny
+	default:
+		return ContentTypeJSON
+	}
+}
+
+// typedFlowName renders the type parameters for error messages
+func typedFlowName[TIn, TOut any]() string {
+	return fmt.Sprintf("[%s -> %s]", reflect.TypeFor[TIn](), reflect.TypeFor[TOut]())
+}
@@ -0,0 +1,256 @@
+package calque
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+type typedQuery struct {
+	Question string `json:"question"`
+}
+
+type typedAnswer struct {
+	Text  string `json:"text"`
+	Words int    `json:"words"`
+}
+
+type typedLabel string
+
+func answerHandler() Handler {
+	return HandlerFunc(func(req *Request, res *Response) error {
+		var q typedQuery
+		if err := json.NewDecoder(req.Data).Decode(&q); err != nil {
+			return err
+		}
+		return json.NewEncoder(res.Data).Encode(typedAnswer{
+			Text:  strings.ToUpper(q.Question),
+			Words: len(strings.Fields(q.Question)),
+		})
+	})
+}
+
+func TestTyped_StructToStruct(t *testing.T) {
+	f, err := Typed[typedQuery, typedAnswer](answerHandler())
+	if err != nil {
+		t.Fatalf("Typed() error = %v", err)
+	}
+
+	got, err := f.Run(context.Background(), typedQuery{Question: "what is go"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if got.Text != "WHAT IS GO" || got.Words != 3 {
+		t.Errorf("Run() = %+v", got)
+	}
+}
+
+func TestTyped_StringPassthrough(t *testing.T) {
+	upper := HandlerFunc(func(req *Request, res *Response) error {
+		var s string
+		if err := Read(req, &s); err != nil {
+			return err
+		}
+		return Write(res, strings.ToUpper(s))
+	})
+
+	tests := []struct {
+		name string
+		run  func() (string, error)
+		want string
+	}{
+		{
+			name: "string output",
+			run: func() (string, error) {
+				f, err := Typed[string, string](upper)
+				if err != nil {
+					return "", err
+				}
+				return f.Run(context.Background(), "hello")
+			},
+			want: "HELLO",
+		},
+		{
+			name: "byte output",
+			run: func() (string, error) {
+				f, err := Typed[[]byte, []byte](upper)
+				if err != nil {
+					return "", err
+				}
+				out, err := f.Run(context.Background(), []byte("bytes"))
+				return string(out), err
+			},
+			want: "BYTES",
+		},
+		{
+			name: "named string output",
+			run: func() (string, error) {
+				f, err := Typed[string, typedLabel](upper)
+				if err != nil {
+					return "", err
+				}
+				out, err := f.Run(context.Background(), "label")
+				return string(out), err
+			},
+			want: "LABEL",
+		},
+		{
+			name: "named string input",
+			run: func() (string, error) {
+				f, err := Typed[typedLabel, string](upper)
+				if err != nil {
+					return "", err
+				}
+				return f.Run(context.Background(), typedLabel("query"))
+			},
+			want: "QUERY",
+		},
+		{
+			name: "reader input",
+			run: func() (string, error) {
+				f, err := Typed[io.Reader, string](upper)
+				if err != nil {
+					return "", err
+				}
+				return f.Run(context.Background(), strings.NewReader("reader"))
+			},
+			want: "READER",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.run()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTyped_ContentTypeValidation(t *testing.T) {
+	pass := HandlerFunc(func(req *Request, res *Response) error {
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+	textOnly := WithContentTypes(pass, ContentTypeText, ContentTypeText)
+	jsonOut := WithContentTypes(pass, ContentTypeJSON, ContentTypeText)
+	anyText := WithContentTypes(pass, ContentTypeText, "text/*")
+	imageOnly := WithContentTypes(pass, "image/png", "image/png")
+
+	tests := []struct {
+		name     string
+		build    func() error
+		wantErr  bool
+		contains string
+	}{
+		{
+			name: "struct input into image handler",
+			build: func() error {
+				_, err := Typed[typedQuery, string](imageOnly)
+				return err
+			},
+			wantErr:  true,
+			contains: "handler 0 accepts [image/png] but receives application/json",
+		},
+		{
+			name: "json producer into image consumer",
+			build: func() error {
+				_, err := Typed[string, string](jsonOut, imageOnly)
+				return err
+			},
+			wantErr:  true,
+			contains: "handler 1",
+		},
+		{
+			name: "json producer into text consumer inserts converter",
+			build: func() error {
+				_, err := Typed[string, string](jsonOut, textOnly)
+				return err
+			},
+		},
+		{
+			name: "text output into struct result",
+			build: func() error {
+				_, err := Typed[string, typedAnswer](textOnly)
+				return err
+			},
+			wantErr:  true,
+			contains: "output accepts [application/json] but receives text/plain",
+		},
+		{
+			name: "undeclared handler breaks the chain check",
+			build: func() error {
+				_, err := Typed[string, string](jsonOut, pass, textOnly)
+				return err
+			},
+		},
+		{
+			name: "wildcard accepts subtype",
+			build: func() error {
+				_, err := Typed[string, string](textOnly, anyText)
+				return err
+			},
+		},
+		{
+			name: "json producer into struct output",
+			build: func() error {
+				_, err := Typed[string, typedAnswer](jsonOut)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.build()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("error %q does not contain %q", err.Error(), tt.contains)
+			}
+		})
+	}
+}
+
+func TestTyped_DecodeError(t *testing.T) {
+	f, err := Typed[string, typedAnswer](HandlerFunc(func(_ *Request, res *Response) error {
+		return Write(res, "not json")
+	}))
+	if err != nil {
+		t.Fatalf("Typed() error = %v", err)
+	}
+
+	if _, err := f.Run(context.Background(), "x"); err == nil {
+		t.Error("expected decode error")
+	}
+}
+
+func TestTyped_AsHandler(t *testing.T) {
+	inner, err := Typed[typedQuery, typedAnswer](answerHandler())
+	if err != nil {
+		t.Fatalf("Typed() error = %v", err)
+	}
+
+	if accepts := inner.Accepts(); len(accepts) != 1 || accepts[0] != ContentTypeJSON {
+		t.Errorf("Accepts() = %v", accepts)
+	}
+	if inner.Produces() != ContentTypeJSON {
+		t.Errorf("Produces() = %q", inner.Produces())
+	}
+
+	var out string
+	if err := NewFlow().Use(inner).Run(context.Background(), `{"question":"a b"}`, &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !strings.Contains(out, `"words":2`) {
+		t.Errorf("unexpected output %q", out)
+	}
+}
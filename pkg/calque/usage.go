@@ -0,0 +1,106 @@
+package calque
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// UsageMetadataKey is the MetadataBus key holding the usage accumulated in a flow run.
+// Read it with UsageFrom rather than Get.
+const UsageMetadataKey = "calque.usage"
+
+// Usage is model token usage, summed over every model call in a flow run
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	Calls            int `json:"calls"` // Model calls that reported usage
+}
+
+// Add returns the sum of two usages
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+		Calls:            u.Calls + other.Calls,
+	}
+}
+
+// IsZero reports whether no usage was recorded
+func (u Usage) IsZero() bool {
+	return u == Usage{}
+}
+
+// MarshalText encodes usage as JSON, for carrying it in string metadata across process boundaries
+func (u Usage) MarshalText() ([]byte, error) {
+	type plain Usage
+	return json.Marshal(plain(u))
+}
+
+// UnmarshalText decodes usage written by MarshalText
+func (u *Usage) UnmarshalText(data []byte) error {
+	type plain Usage
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+// usageTotal is the accumulator stored on the MetadataBus
+type usageTotal struct {
+	mu    sync.Mutex
+	usage Usage
+}
+
+// RecordUsage adds usage to the total for the request's flow run.
+//
+// The total lives on the MetadataBus, so usage recorded anywhere in a run,
+// including sub-flows, parallel branches and tool calls that share the
+// request context, adds up to one figure. Model clients record usage for
+// every call; it does nothing without a bus.
+//
+// Example:
+//
+//	calque.RecordUsage(req.Context, calque.Usage{PromptTokens: 12, CompletionTokens: 30, TotalTokens: 42, Calls: 1})
+func RecordUsage(ctx context.Context, usage Usage) {
+	bus := GetMetadataBus(ctx)
+	if bus == nil || usage.IsZero() {
+		return
+	}
+	v, _ := bus.store.LoadOrStore(UsageMetadataKey, &usageTotal{})
+	total, ok := v.(*usageTotal)
+	if !ok {
+		return
+	}
+	total.mu.Lock()
+	total.usage = total.usage.Add(usage)
+	total.mu.Unlock()
+}
+
+// UsageFrom returns the usage recorded so far in the request's flow run.
+//
+// Flow.Run reuses a MetadataBus already in the context, so attach one before
+// running to read the end-to-end total afterwards.
+//
+// Example:
+//
+//	ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+//	err := flow.Run(ctx, input, &output)
+//	usage := calque.UsageFrom(ctx)
+//	log.Printf("%d tokens over %d model calls", usage.TotalTokens, usage.Calls)
+func UsageFrom(ctx context.Context) Usage {
+	bus := GetMetadataBus(ctx)
+	if bus == nil {
+		return Usage{}
+	}
+	v, ok := bus.store.Load(UsageMetadataKey)
+	if !ok {
+		return Usage{}
+	}
+	total, ok := v.(*usageTotal)
+	if !ok {
+		return Usage{}
+	}
+	total.mu.Lock()
+	defer total.mu.Unlock()
+	return total.usage
+}
@@ -0,0 +1,82 @@
+package calque
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// usageHandler records one model call's usage and passes input through
+func usageHandler(tokens int) Handler {
+	return HandlerFunc(func(req *Request, res *Response) error {
+		RecordUsage(req.Context, Usage{PromptTokens: tokens, CompletionTokens: tokens, TotalTokens: 2 * tokens, Calls: 1})
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+}
+
+func TestUsageFrom_NestedFlows(t *testing.T) {
+	inner := NewFlow().Use(usageHandler(5)).Use(usageHandler(5))
+	outer := NewFlow().
+		Use(usageHandler(10)).
+		UseFunc(func(req *Request, res *Response) error {
+			var in, out string
+			if err := Read(req, &in); err != nil {
+				return err
+			}
+			if err := inner.Run(req.Context, in, &out); err != nil {
+				return err
+			}
+			return Write(res, out)
+		})
+
+	ctx := WithMetadataBus(context.Background(), NewMetadataBus(0))
+	var out string
+	if err := outer.Run(ctx, "hi", &out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Usage{PromptTokens: 20, CompletionTokens: 20, TotalTokens: 40, Calls: 3}
+	if got := UsageFrom(ctx); got != want {
+		t.Errorf("UsageFrom() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRecordUsage_Concurrent(t *testing.T) {
+	ctx := WithMetadataBus(context.Background(), NewMetadataBus(0))
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Go(func() { RecordUsage(ctx, Usage{TotalTokens: 1, Calls: 1}) })
+	}
+	wg.Wait()
+
+	if got := UsageFrom(ctx); got.TotalTokens != 50 || got.Calls != 50 {
+		t.Errorf("UsageFrom() = %+v, want 50 tokens over 50 calls", got)
+	}
+}
+
+func TestUsageFrom_NoBus(t *testing.T) {
+	RecordUsage(context.Background(), Usage{TotalTokens: 1}) // no bus, no panic
+	if got := UsageFrom(context.Background()); !got.IsZero() {
+		t.Errorf("UsageFrom() = %+v, want zero", got)
+	}
+}
+
+func TestUsage_Text(t *testing.T) {
+	usage := Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3, Calls: 1}
+	encoded, err := usage.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(encoded), `"total_tokens":3`) {
+		t.Errorf("MarshalText() = %s", encoded)
+	}
+
+	var decoded Usage
+	if err := decoded.UnmarshalText(encoded); err != nil || decoded != usage {
+		t.Errorf("UnmarshalText() = %+v, %v; want %+v", decoded, err, usage)
+	}
+}
@@ -0,0 +1,283 @@
+package calque
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DiagramFormat selects the output language of Flow.Visualize
+type DiagramFormat string
+
+const (
+	// DiagramMermaid renders a Mermaid flowchart, which GitHub and most docs tools display inline
+	DiagramMermaid DiagramFormat = "mermaid"
+	// DiagramDOT renders a Graphviz digraph for the dot command
+	DiagramDOT DiagramFormat = "dot"
+)
+
+// Visualize renders the flow's handler chain as a diagram.
+//
+// Input: DiagramMermaid or DiagramDOT
+// Output: diagram source, error for an unknown format
+// Behavior: No handlers are executed
+//
+// Branches, routers and fallbacks fan out to labeled edges, parallel handlers
+// fan out and merge again, and wrappers such as timeouts and retries are drawn
+// as labeled boxes around what they wrap. Handlers implement Describer to show
+// their own structure; the rest are drawn as single steps.
+//
+// Example:
+//
+//	diagram, err := flow.Visualize(calque.DiagramMermaid)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	os.WriteFile("docs/pipeline.mmd", []byte(diagram), 0o644)
+func (f *Flow) Visualize(format DiagramFormat) (string, error) {
+	g := &graph{}
+	start := g.addNode("input", shapeTerminal, 0)
+	root := f.Describe()
+	entries, exits := g.add(root, 0, true)
+	end := g.addNode("output", shapeTerminal, 0)
+
+	inner := g.edges
+	g.edges = nil
+	for _, e := range entries {
+		g.addEdge(start, e.id, e.label)
+	}
+	if len(entries) == 0 {
+		g.addEdge(start, end, "")
+	}
+	g.edges = append(g.edges, inner...)
+	for _, x := range exits {
+		g.addEdge(x, end, "")
+	}
+
+	switch format {
+	case DiagramMermaid:
+		return g.mermaid(), nil
+	case DiagramDOT:
+		return g.dot(), nil
+	default:
+		return "", NewErr(context.Background(), fmt.Sprintf("unknown diagram format %q", format))
+	}
+}
+
+type nodeShape int
+
+const (
+	shapeStep nodeShape = iota
+	shapeTerminal
+	shapeDecision
+	shapeFork
+	shapeJoin
+)
+
+type graphNode struct {
+	id      string
+	label   string
+	shape   nodeShape
+	cluster int // 0 is the top level
+}
+
+type graphCluster struct {
+	id     int
+	label  string
+	parent int
+}
+
+type graphEdge struct {
+	from, to, label string
+}
+
+// entry is a node where a sub-diagram starts, with the label for the edge into it
+type entry struct {
+	id    string
+	label string
+}
+
+// graph is the layout-independent form of a diagram
+type graph struct {
+	nodes    []graphNode
+	clusters []graphCluster
+	edges    []graphEdge
+}
+
+func (g *graph) addNode(label string, shape nodeShape, cluster int) string {
+	id := fmt.Sprintf("n%d", len(g.nodes))
+	g.nodes = append(g.nodes, graphNode{id: id, label: label, shape: shape, cluster: cluster})
+	return id
+}
+
+func (g *graph) addEdge(from, to, label string) {
+	g.edges = append(g.edges, graphEdge{from: from, to: to, label: label})
+}
+
+// add lays out node inside cluster and returns where it starts and ends.
+// top is set for the flow being visualized, which isn't boxed.
+func (g *graph) add(node Node, cluster int, top bool) (entries []entry, exits []string) {
+	switch node.Kind {
+	case NodeSequence:
+		if len(node.Children) == 0 {
+			if top {
+				return nil, nil
+			}
+			id := g.addNode(node.Label, shapeStep, cluster)
+			return []entry{{id: id, label: node.Edge}}, []string{id}
+		}
+		inner := cluster
+		if !top && node.Label != "" {
+			inner = len(g.clusters) + 1
+			g.clusters = append(g.clusters, graphCluster{id: inner, label: node.Label, parent: cluster})
+		}
+		for i, child := range node.Children {
+			childEntries, childExits := g.add(child, inner, false)
+			if i == 0 {
+				entries = childEntries
+			} else {
+				for _, x := range exits {
+					for _, e := range childEntries {
+						g.addEdge(x, e.id, e.label)
+					}
+				}
+			}
+			exits = childExits
+		}
+		// The edge into the sequence belongs to its first step
+		if node.Edge != "" {
+			for i := range entries {
+				entries[i].label = node.Edge
+			}
+		}
+		return entries, exits
+
+	case NodeBranch, NodeParallel:
+		shape := shapeDecision
+		if node.Kind == NodeParallel {
+			shape = shapeFork
+		}
+		fork := g.addNode(node.Label, shape, cluster)
+		for _, child := range node.Children {
+			childEntries, childExits := g.add(child, cluster, false)
+			for _, e := range childEntries {
+				g.addEdge(fork, e.id, e.label)
+			}
+			exits = append(exits, childExits...)
+		}
+		if len(node.Children) == 0 {
+			exits = []string{fork}
+		}
+		if node.Kind == NodeParallel && len(exits) > 1 {
+			join := g.addNode("merge", shapeJoin, cluster)
+			for _, x := range exits {
+				g.addEdge(x, join, "")
+			}
+			exits = []string{join}
+		}
+		return []entry{{id: fork, label: node.Edge}}, exits
+
+	default:
+		id := g.addNode(node.Label, shapeStep, cluster)
+		return []entry{{id: id, label: node.Edge}}, []string{id}
+	}
+}
+
+// clusterContents groups nodes and child clusters by the cluster that holds them
+func (g *graph) clusterContents() (nodes map[int][]graphNode, children map[int][]graphCluster) {
+	nodes = map[int][]graphNode{}
+	children = map[int][]graphCluster{}
+	for _, n := range g.nodes {
+		nodes[n.cluster] = append(nodes[n.cluster], n)
+	}
+	for _, c := range g.clusters {
+		children[c.parent] = append(children[c.parent], c)
+	}
+	return nodes, children
+}
+
+func (g *graph) mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	nodes, children := g.clusterContents()
+
+	var write func(cluster int, indent string)
+	write = func(cluster int, indent string) {
+		for _, n := range nodes[cluster] {
+			label := mermaidEscape(n.label)
+			switch n.shape {
+			case shapeTerminal:
+				fmt.Fprintf(&b, "%s%s([\"%s\"])\n", indent, n.id, label)
+			case shapeDecision:
+				fmt.Fprintf(&b, "%s%s{\"%s\"}\n", indent, n.id, label)
+			case shapeFork, shapeJoin:
+				fmt.Fprintf(&b, "%s%s[[\"%s\"]]\n", indent, n.id, label)
+			default:
+				fmt.Fprintf(&b, "%s%s[\"%s\"]\n", indent, n.id, label)
+			}
+		}
+		for _, c := range children[cluster] {
+			fmt.Fprintf(&b, "%ssubgraph c%d[\"%s\"]\n", indent, c.id, mermaidEscape(c.label))
+			write(c.id, indent+"    ")
+			fmt.Fprintf(&b, "%send\n", indent)
+		}
+	}
+	write(0, "    ")
+
+	for _, e := range g.edges {
+		if e.label != "" {
+			fmt.Fprintf(&b, "    %s -->|\"%s\"| %s\n", e.from, mermaidEscape(e.label), e.to)
+		} else {
+			fmt.Fprintf(&b, "    %s --> %s\n", e.from, e.to)
+		}
+	}
+	return b.String()
+}
+
+func (g *graph) dot() string {
+	var b strings.Builder
+	b.WriteString("digraph flow {\n    rankdir=TB;\n    node [shape=box, style=rounded];\n")
+	nodes, children := g.clusterContents()
+
+	var write func(cluster int, indent string)
+	write = func(cluster int, indent string) {
+		for _, n := range nodes[cluster] {
+			attrs := ""
+			switch n.shape {
+			case shapeTerminal:
+				attrs = ", shape=oval"
+			case shapeDecision:
+				attrs = ", shape=diamond, style=\"\""
+			case shapeFork, shapeJoin:
+				attrs = ", shape=box, style=\"\", peripheries=2"
+			}
+			fmt.Fprintf(&b, "%s%s [label=\"%s\"%s];\n", indent, n.id, dotEscape(n.label), attrs)
+		}
+		for _, c := range children[cluster] {
+			fmt.Fprintf(&b, "%ssubgraph cluster_%d {\n%s    label=\"%s\";\n", indent, c.id, indent, dotEscape(c.label))
+			write(c.id, indent+"    ")
+			fmt.Fprintf(&b, "%s}\n", indent)
+		}
+	}
+	write(0, "    ")
+
+	for _, e := range g.edges {
+		if e.label != "" {
+			fmt.Fprintf(&b, "    %s -> %s [label=\"%s\"];\n", e.from, e.to, dotEscape(e.label))
+		} else {
+			fmt.Fprintf(&b, "    %s -> %s;\n", e.from, e.to)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// mermaidEscape makes a label safe inside a quoted Mermaid label
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(s)
+}
+
+// dotEscape makes a label safe inside a quoted DOT string
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,108 @@
+package calque
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func passThrough(req *Request, res *Response) error {
+	_, err := io.Copy(res.Data, req.Data)
+	return err
+}
+
+// wrapNode builds a described handler around children, like the ctrl constructors do
+func wrapNode(label string, kind NodeKind, children ...Handler) Handler {
+	return Described(HandlerFunc(passThrough), func() Node {
+		node := Node{Label: label, Kind: kind}
+		for _, c := range children {
+			node.Children = append(node.Children, DescribeHandler(c))
+		}
+		return node
+	})
+}
+
+func TestDescribeHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler Handler
+		want    string
+	}{
+		{name: "named function", handler: HandlerFunc(passThrough), want: "calque.passThrough"},
+		{name: "closure", handler: HandlerFunc(func(*Request, *Response) error { return nil }), want: "calque.TestDescribeHandler"},
+		{name: "struct type", handler: NewFlow().Use(HandlerFunc(passThrough)), want: "flow"},
+		{name: "content typed", handler: WithContentTypes(HandlerFunc(passThrough), ContentTypeText), want: "calque.passThrough"},
+		{name: "described", handler: wrapNode("custom", NodeStep), want: "custom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DescribeHandler(tt.handler).Label; got != tt.want {
+				t.Errorf("label = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVisualize(t *testing.T) {
+	flow := NewFlow().
+		Use(HandlerFunc(passThrough)).
+		Use(Described(HandlerFunc(passThrough), func() Node {
+			return Node{Label: "branch", Kind: NodeBranch, Children: []Node{
+				DescribeHandler(wrapNode("timeout 5s", NodeSequence, HandlerFunc(passThrough))).WithEdge("yes"),
+				DescribeHandler(wrapNode("parallel", NodeParallel, HandlerFunc(passThrough), HandlerFunc(passThrough))).WithEdge(`say "no"`),
+			}}
+		}))
+
+	mermaid, err := flow.Visualize(DiagramMermaid)
+	if err != nil {
+		t.Fatalf("Visualize() error = %v", err)
+	}
+	for _, want := range []string{
+		"flowchart TD\n",
+		`n0(["input"])`,
+		`n2{"branch"}`,
+		`subgraph c1["timeout 5s"]`,
+		`n4[["parallel"]]`,
+		`n7[["merge"]]`,
+		"n0 --> n1\n",
+		"n1 --> n2\n",
+		`n2 -->|"yes"| n3`,
+		`n2 -->|"say #quot;no#quot;"| n4`,
+		"n5 --> n7\n", "n6 --> n7\n",
+		"n3 --> n8\n", "n7 --> n8\n",
+	} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("mermaid missing %q:\n%s", want, mermaid)
+		}
+	}
+
+	dot, err := flow.Visualize(DiagramDOT)
+	if err != nil {
+		t.Fatalf("Visualize() error = %v", err)
+	}
+	for _, want := range []string{
+		"digraph flow {",
+		`n2 [label="branch", shape=diamond`,
+		"subgraph cluster_1 {",
+		`label="timeout 5s";`,
+		`n2 -> n4 [label="say \"no\""];`,
+		"n7 -> n8;",
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("dot missing %q:\n%s", want, dot)
+		}
+	}
+	if strings.Count(dot, "{") != strings.Count(dot, "}") {
+		t.Errorf("unbalanced braces:\n%s", dot)
+	}
+}
+
+func TestVisualizeEmptyAndUnknown(t *testing.T) {
+	out, err := NewFlow().Visualize(DiagramMermaid)
+	if err != nil || !strings.Contains(out, "n0 --> n1") {
+		t.Errorf("empty flow = %q, %v", out, err)
+	}
+	if _, err := NewFlow().Visualize("svg"); err == nil || !strings.Contains(err.Error(), `unknown diagram format "svg"`) {
+		t.Errorf("unknown format error = %v", err)
+	}
+}
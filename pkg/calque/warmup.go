@@ -0,0 +1,126 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Warmer is implemented by handlers that can prepare for traffic ahead of the first request.
+//
+// Warmup should establish connections, load models or prime caches, and must be
+// safe to call more than once.
+type Warmer interface {
+	Warmup(ctx context.Context) error
+}
+
+// WarmupHandler warms handler if it implements Warmer, otherwise it does nothing.
+//
+// Wrapping middleware calls this from its own Warmup so warm-up reaches the
+// handlers it wraps.
+func WarmupHandler(ctx context.Context, handler Handler) error {
+	if w, ok := handler.(Warmer); ok {
+		return w.Warmup(ctx)
+	}
+	return nil
+}
+
+// Warmup prepares every handler in the flow for traffic.
+//
+// Input: context.Context bounding how long warm-up may take
+// Output: error joining every handler that failed to warm up
+// Behavior: CONCURRENT - all Warmer handlers are called at once, no data flows
+//
+// Handlers implementing Warmer pre-dial remote services, ping AI providers and
+// load models so the first request after a deploy does not pay the cold-start
+// latency. Nested flows are warmed recursively. Handlers that do not implement
+// Warmer are skipped; attach custom priming (loading tokenizers, filling caches)
+// with WithWarmup.
+//
+// Example:
+//
+//	flow := calque.NewFlow().Use(grpc.NewRegistryHandler(svc)).Use(ai.Agent(client))
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	if err := flow.Warmup(ctx); err != nil {
+//		log.Printf("warm-up incomplete: %v", err)
+//	}
+func (f *Flow) Warmup(ctx context.Context) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for i, handler := range f.handlers {
+		if _, ok := handler.(Warmer); !ok {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := WarmupHandler(ctx, handler); err != nil {
+				mu.Lock()
+				errs = append(errs, WrapErr(ctx, err, fmt.Sprintf("handler %d warm-up failed", i)))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+// Warmup prepares the handlers of the underlying flow, see Flow.Warmup.
+func (t *TypedFlow[TIn, TOut]) Warmup(ctx context.Context) error {
+	return t.flow.Warmup(ctx)
+}
+
+// warmupHandler attaches a warm-up function to a handler
+type warmupHandler struct {
+	Handler
+	warmup func(ctx context.Context) error
+}
+
+func (h *warmupHandler) Warmup(ctx context.Context) error {
+	if err := WarmupHandler(ctx, h.Handler); err != nil {
+		return err
+	}
+	return h.warmup(ctx)
+}
+
+func (h *warmupHandler) Shutdown(ctx context.Context) error {
+	return ShutdownHandler(ctx, h.Handler)
+}
+
+func (h *warmupHandler) Accepts() []string {
+	accepts, _ := ContentTypesOf(h.Handler)
+	return accepts
+}
+
+func (h *warmupHandler) Produces() string {
+	_, produces := ContentTypesOf(h.Handler)
+	return produces
+}
+
+// WithWarmup attaches a warm-up function to an existing handler.
+//
+// Input: handler, function run by Flow.Warmup
+// Output: Handler that also implements Warmer
+// Behavior: STREAMING - requests are passed directly to the wrapped handler
+//
+// The wrapped handler is warmed first if it implements Warmer itself. Declared
+// content types are preserved.
+//
+// Example:
+//
+//	flow.Use(calque.WithWarmup(cached, func(ctx context.Context) error {
+//		return primeCache(ctx, store, popularQueries)
+//	}))
+func WithWarmup(handler Handler, warmup func(ctx context.Context) error) Handler {
+	return &warmupHandler{Handler: handler, warmup: warmup}
+}
@@ -0,0 +1,95 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// warmable is a pass-through handler that counts warm-ups
+type warmable struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (w *warmable) ServeFlow(req *Request, res *Response) error {
+	_, err := io.Copy(res.Data, req.Data)
+	return err
+}
+
+func (w *warmable) Warmup(context.Context) error {
+	w.calls.Add(1)
+	return w.err
+}
+
+func TestFlow_Warmup(t *testing.T) {
+	first, nested := &warmable{}, &warmable{}
+	var primed atomic.Int32
+	plain := HandlerFunc(func(req *Request, res *Response) error {
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+
+	flow := NewFlow().
+		Use(first).
+		Use(plain).
+		Use(NewFlow().Use(nested)).
+		Use(WithContentTypes(nested, ContentTypeText)).
+		Use(WithWarmup(plain, func(context.Context) error {
+			primed.Add(1)
+			return nil
+		}))
+
+	if err := flow.Warmup(context.Background()); err != nil {
+		t.Fatalf("Warmup() error = %v", err)
+	}
+	if first.calls.Load() != 1 || nested.calls.Load() != 2 || primed.Load() != 1 {
+		t.Errorf("warm-ups = first %d, nested %d, primed %d; want 1, 2, 1",
+			first.calls.Load(), nested.calls.Load(), primed.Load())
+	}
+
+	// Warm-up does not change request handling
+	var out string
+	if err := flow.Run(context.Background(), "ok", &out); err != nil || out != "ok" {
+		t.Errorf("Run() = %q, %v", out, err)
+	}
+}
+
+func TestFlow_WarmupErrors(t *testing.T) {
+	errA, errB := errors.New("provider unreachable"), errors.New("model not pulled")
+	ok := &warmable{}
+	flow := NewFlow().Use(&warmable{err: errA}).Use(ok).Use(&warmable{err: errB})
+
+	err := flow.Warmup(context.Background())
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Fatalf("Warmup() error = %v, want both failures joined", err)
+	}
+	if !strings.Contains(err.Error(), "handler 0") || !strings.Contains(err.Error(), "handler 2") {
+		t.Errorf("error %q should name the failing handlers", err)
+	}
+	if ok.calls.Load() != 1 {
+		t.Error("healthy handler was not warmed when others failed")
+	}
+}
+
+func TestWithWarmup_PreservesContentTypes(t *testing.T) {
+	typed := WithContentTypes(&warmable{}, ContentTypeJSON, ContentTypeText)
+	accepts, produces := ContentTypesOf(WithWarmup(typed, func(context.Context) error { return nil }))
+	if produces != ContentTypeJSON || len(accepts) != 1 || accepts[0] != ContentTypeText {
+		t.Errorf("ContentTypesOf() = %v, %q", accepts, produces)
+	}
+}
+
+func TestTypedFlow_Warmup(t *testing.T) {
+	w := &warmable{}
+	f, err := Typed[string, string](w)
+	if err != nil {
+		t.Fatalf("Typed() error = %v", err)
+	}
+	if err := f.Warmup(context.Background()); err != nil || w.calls.Load() != 1 {
+		t.Errorf("Warmup() = %v, calls %d", err, w.calls.Load())
+	}
+}
@@ -0,0 +1,326 @@
+// Package calquebench benchmarks calque handlers with standardized workloads.
+//
+// It measures throughput, allocations and the latency distribution of any
+// calque.Handler at several payload sizes, saves the results as JSON reports
+// and compares two reports to flag regressions. Use Benchmark inside Go
+// benchmark functions, or Run to produce a Report for CI comparisons.
+package calquebench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultPayloadSizes are the input sizes, in bytes, used when Config.PayloadSizes is empty.
+var DefaultPayloadSizes = []int{64, 4 << 10, 64 << 10, 1 << 20}
+
+// Config controls how a handler is exercised.
+type Config struct {
+	// PayloadSizes are the input sizes to benchmark (default: DefaultPayloadSizes)
+	PayloadSizes []int
+	// Payload builds the input for a size (default: repeated printable ASCII text)
+	Payload func(size int) []byte
+	// Concurrency is the number of goroutines calling the handler (default: 1)
+	Concurrency int
+	// Duration is how long each payload size runs when Iterations is zero (default: 1s)
+	Duration time.Duration
+	// Iterations fixes the number of calls per payload size instead of using Duration
+	Iterations int
+	// Warmup is the number of untimed calls made before measuring each size (default: 10)
+	Warmup int
+	// CPUProfile, when set, receives a pprof CPU profile covering all measured calls
+	CPUProfile io.Writer
+	// MemProfile, when set, receives a pprof heap profile taken after the run
+	MemProfile io.Writer
+}
+
+// Result holds the measurements for one payload size.
+type Result struct {
+	PayloadSize int           `json:"payload_size"`
+	Ops         int64         `json:"ops"`
+	Errors      int64         `json:"errors"`
+	Elapsed     time.Duration `json:"elapsed"`
+	OpsPerSec   float64       `json:"ops_per_sec"`
+	BytesPerSec float64       `json:"bytes_per_sec"` // input bytes processed per second
+	OutputBytes int64         `json:"output_bytes"`  // total bytes written by the handler
+	AllocsPerOp float64       `json:"allocs_per_op"`
+	BytesPerOp  float64       `json:"bytes_per_op"` // heap bytes allocated per call
+	P50         time.Duration `json:"p50"`
+	P90         time.Duration `json:"p90"`
+	P99         time.Duration `json:"p99"`
+	Max         time.Duration `json:"max"`
+}
+
+// Report is the outcome of benchmarking one handler.
+type Report struct {
+	Name        string    `json:"name"`
+	GoVersion   string    `json:"go_version"`
+	GOOS        string    `json:"goos"`
+	GOARCH      string    `json:"goarch"`
+	GOMAXPROCS  int       `json:"gomaxprocs"`
+	Concurrency int       `json:"concurrency"`
+	Created     time.Time `json:"created"`
+	Results     []Result  `json:"results"`
+}
+
+// Run benchmarks handler at every configured payload size.
+//
+// Input: context for cancellation, report name, handler, optional config
+// Output: *Report with one Result per payload size, error if ctx ends or profiling fails
+// Behavior: BLOCKING - calls the handler repeatedly, Concurrency calls at a time
+//
+// Each call gets a fresh request reading the payload and a response that counts
+// and discards output. Handler errors are counted, not returned, so a flaky
+// handler still produces a report. Allocation figures cover the handler and the
+// request/response plumbing, which is what a flow pays per call.
+//
+// Example:
+//
+//	report, err := calquebench.Run(ctx, "text.Transform", text.Transform(strings.ToUpper), nil)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	report.WriteText(os.Stdout)
+func Run(ctx context.Context, name string, handler calque.Handler, config *Config) (*Report, error) {
+	cfg := withDefaults(config)
+
+	report := &Report{
+		Name:        name,
+		GoVersion:   runtime.Version(),
+		GOOS:        runtime.GOOS,
+		GOARCH:      runtime.GOARCH,
+		GOMAXPROCS:  runtime.GOMAXPROCS(0),
+		Concurrency: cfg.Concurrency,
+		Created:     time.Now().UTC(),
+	}
+
+	if cfg.CPUProfile != nil {
+		if err := pprof.StartCPUProfile(cfg.CPUProfile); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to start CPU profile")
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	for _, size := range cfg.PayloadSizes {
+		result, err := measure(ctx, handler, cfg, size)
+		if err != nil {
+			return nil, err
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	if cfg.MemProfile != nil {
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(cfg.MemProfile); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to write heap profile")
+		}
+	}
+	return report, nil
+}
+
+// Benchmark runs handler as Go sub-benchmarks, one per payload size.
+//
+// Each sub-benchmark reports ns/op, B/op, allocs/op, MB/s from the payload size,
+// and p50/p99 latency as custom metrics, so results work with benchstat.
+//
+// Example:
+//
+//	func BenchmarkUpper(b *testing.B) {
+//		calquebench.Benchmark(b, text.Transform(strings.ToUpper))
+//	}
+func Benchmark(b *testing.B, handler calque.Handler, sizes ...int) {
+	if len(sizes) == 0 {
+		sizes = DefaultPayloadSizes
+	}
+	for _, size := range sizes {
+		b.Run(sizeName(size), func(b *testing.B) {
+			payload := textPayload(size)
+			latencies := make([]time.Duration, 0, b.N)
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for range b.N {
+				start := time.Now()
+				if _, err := call(context.Background(), handler, payload); err != nil {
+					b.Fatalf("handler error: %v", err)
+				}
+				latencies = append(latencies, time.Since(start))
+			}
+
+			b.StopTimer()
+			p := percentiles(latencies)
+			b.ReportMetric(float64(p.p50.Nanoseconds()), "p50-ns")
+			b.ReportMetric(float64(p.p99.Nanoseconds()), "p99-ns")
+		})
+	}
+}
+
+func withDefaults(config *Config) Config {
+	var cfg Config
+	if config != nil {
+		cfg = *config
+	}
+	if len(cfg.PayloadSizes) == 0 {
+		cfg.PayloadSizes = DefaultPayloadSizes
+	}
+	if cfg.Payload == nil {
+		cfg.Payload = textPayload
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = time.Second
+	}
+	if cfg.Warmup < 0 {
+		cfg.Warmup = 0
+	} else if cfg.Warmup == 0 {
+		cfg.Warmup = 10
+	}
+	return cfg
+}
+
+// measure benchmarks one payload size
+func measure(ctx context.Context, handler calque.Handler, cfg Config, size int) (Result, error) {
+	payload := cfg.Payload(size)
+	for range cfg.Warmup {
+		_, _ = call(ctx, handler, payload)
+	}
+
+	var (
+		ops, errs, output atomic.Int64
+		mu                sync.Mutex
+		latencies         = make([]time.Duration, 0, 1024)
+		wg                sync.WaitGroup
+		before, after     runtime.MemStats
+	)
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	deadline := start.Add(cfg.Duration)
+	next := func() bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		if cfg.Iterations > 0 {
+			return ops.Add(1) <= int64(cfg.Iterations)
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		ops.Add(1)
+		return true
+	}
+
+	for range cfg.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			local := make([]time.Duration, 0, 1024)
+			for next() {
+				t := time.Now()
+				n, err := call(ctx, handler, payload)
+				local = append(local, time.Since(t))
+				output.Add(n)
+				if err != nil {
+					errs.Add(1)
+				}
+			}
+			mu.Lock()
+			latencies = append(latencies, local...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	if err := ctx.Err(); err != nil {
+		return Result{}, calque.WrapErr(ctx, err, "benchmark cancelled")
+	}
+
+	count := int64(len(latencies))
+	result := Result{
+		PayloadSize: size,
+		Ops:         count,
+		Errors:      errs.Load(),
+		Elapsed:     elapsed,
+		OutputBytes: output.Load(),
+	}
+	if count == 0 {
+		return result, nil
+	}
+
+	p := percentiles(latencies)
+	result.P50, result.P90, result.P99, result.Max = p.p50, p.p90, p.p99, p.max
+	result.OpsPerSec = float64(count) / elapsed.Seconds()
+	result.BytesPerSec = result.OpsPerSec * float64(len(payload))
+	result.AllocsPerOp = float64(after.Mallocs-before.Mallocs) / float64(count)
+	result.BytesPerOp = float64(after.TotalAlloc-before.TotalAlloc) / float64(count)
+	return result, nil
+}
+
+// call runs the handler once, returning the number of bytes it wrote
+func call(ctx context.Context, handler calque.Handler, payload []byte) (int64, error) {
+	var out countingWriter
+	err := handler.ServeFlow(calque.NewRequest(ctx, bytes.NewReader(payload)), calque.NewResponse(&out))
+	return out.n, err
+}
+
+// countingWriter discards output while counting it
+type countingWriter struct{ n int64 }
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+type latencyPercentiles struct {
+	p50, p90, p99, max time.Duration
+}
+
+func percentiles(latencies []time.Duration) latencyPercentiles {
+	if len(latencies) == 0 {
+		return latencyPercentiles{}
+	}
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+	at := func(q float64) time.Duration {
+		return sorted[min(int(q*float64(len(sorted))), len(sorted)-1)]
+	}
+	return latencyPercentiles{p50: at(0.50), p90: at(0.90), p99: at(0.99), max: sorted[len(sorted)-1]}
+}
+
+// textPayload returns size bytes of printable text broken into lines
+func textPayload(size int) []byte {
+	const line = "The quick brown fox jumps over the lazy dog. 0123456789\n"
+	payload := bytes.Repeat([]byte(line), size/len(line)+1)
+	return payload[:size]
+}
+
+// sizeName renders a payload size as 64B, 4KB, 1MB
+func sizeName(size int) string {
+	switch {
+	case size >= 1<<20 && size%(1<<20) == 0:
+		return fmt.Sprintf("%dMB", size>>20)
+	case size >= 1<<10 && size%(1<<10) == 0:
+		return fmt.Sprintf("%dKB", size>>10)
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
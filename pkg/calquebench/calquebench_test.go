@@ -0,0 +1,136 @@
+package calquebench
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func echo() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{"fixed iterations", &Config{PayloadSizes: []int{16, 1024}, Iterations: 50}},
+		{"concurrent", &Config{PayloadSizes: []int{128}, Iterations: 40, Concurrency: 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Run(context.Background(), "echo", echo(), tt.config)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if len(report.Results) != len(tt.config.PayloadSizes) {
+				t.Fatalf("results = %d, want %d", len(report.Results), len(tt.config.PayloadSizes))
+			}
+			for i, res := range report.Results {
+				size := tt.config.PayloadSizes[i]
+				if res.PayloadSize != size || res.Ops != int64(tt.config.Iterations) {
+					t.Errorf("result %d = size %d, ops %d", i, res.PayloadSize, res.Ops)
+				}
+				if res.OutputBytes != int64(size*tt.config.Iterations) {
+					t.Errorf("OutputBytes = %d, want %d", res.OutputBytes, size*tt.config.Iterations)
+				}
+				if res.P50 <= 0 || res.P50 > res.P99 || res.P99 > res.Max || res.OpsPerSec <= 0 {
+					t.Errorf("inconsistent latency stats: %+v", res)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_CountsErrorsAndDuration(t *testing.T) {
+	failing := calque.HandlerFunc(func(_ *calque.Request, _ *calque.Response) error {
+		return errors.New("boom")
+	})
+
+	report, err := Run(context.Background(), "failing", failing, &Config{
+		PayloadSizes: []int{8},
+		Duration:     20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	res := report.Results[0]
+	if res.Ops == 0 || res.Errors != res.Ops {
+		t.Errorf("ops = %d, errors = %d; every call should be counted as an error", res.Ops, res.Errors)
+	}
+	if res.Elapsed < 20*time.Millisecond {
+		t.Errorf("Elapsed = %s, want at least the configured duration", res.Elapsed)
+	}
+}
+
+func TestRun_Profiles(t *testing.T) {
+	var cpu, mem bytes.Buffer
+	_, err := Run(context.Background(), "echo", echo(), &Config{
+		PayloadSizes: []int{64},
+		Iterations:   10,
+		CPUProfile:   &cpu,
+		MemProfile:   &mem,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if cpu.Len() == 0 || mem.Len() == 0 {
+		t.Errorf("profiles not written: cpu %d bytes, mem %d bytes", cpu.Len(), mem.Len())
+	}
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Run(ctx, "echo", echo(), &Config{PayloadSizes: []int{8}}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestTextPayload(t *testing.T) {
+	for _, size := range []int{0, 1, 57, 4096} {
+		if got := len(textPayload(size)); got != size {
+			t.Errorf("len(textPayload(%d)) = %d", size, got)
+		}
+	}
+}
+
+func TestSizeName(t *testing.T) {
+	tests := map[int]string{64: "64B", 4096: "4KB", 1 << 20: "1MB", 1500: "1500B"}
+	for size, want := range tests {
+		if got := sizeName(size); got != want {
+			t.Errorf("sizeName(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
+
+func BenchmarkEcho(b *testing.B) {
+	Benchmark(b, echo(), 64, 4096)
+}
+
+func TestReport_WriteText(t *testing.T) {
+	report, err := Run(context.Background(), "echo", echo(), &Config{PayloadSizes: []int{64, 4096}, Iterations: 5})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	var buf strings.Builder
+	if err := report.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	for _, want := range []string{"echo", "payload", "64B", "4KB", "p99"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package calquebench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultThreshold is the relative change Compare treats as a regression when none is given.
+const DefaultThreshold = 0.10
+
+// WriteText writes the report as an aligned table.
+func (r *Report) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "%s (%s %s/%s, GOMAXPROCS=%d, concurrency=%d)\n",
+		r.Name, r.GoVersion, r.GOOS, r.GOARCH, r.GOMAXPROCS, r.Concurrency)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "payload\tops/s\tMB/s\tallocs/op\tB/op\tp50\tp90\tp99\tmax\terrors\t")
+	for _, res := range r.Results {
+		fmt.Fprintf(tw, "%s\t%.0f\t%.2f\t%.1f\t%.0f\t%s\t%s\t%s\t%s\t%d\t\n",
+			sizeName(res.PayloadSize), res.OpsPerSec, res.BytesPerSec/(1<<20), res.AllocsPerOp, res.BytesPerOp,
+			round(res.P50), round(res.P90), round(res.P99), round(res.Max), res.Errors)
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as indented JSON, for saving a baseline.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// ReadReport decodes a report written by WriteJSON.
+//
+// Example:
+//
+//	f, _ := os.Open("testdata/baseline.json")
+//	baseline, err := calquebench.ReadReport(f)
+func ReadReport(r io.Reader) (*Report, error) {
+	var report Report
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, fmt.Errorf("decode benchmark report: %w", err)
+	}
+	return &report, nil
+}
+
+// Delta compares one payload size between two reports.
+//
+// Changes are relative: 0.25 means the current value is 25% higher than the baseline.
+type Delta struct {
+	PayloadSize int     `json:"payload_size"`
+	OpsPerSec   float64 `json:"ops_per_sec"`
+	AllocsPerOp float64 `json:"allocs_per_op"`
+	BytesPerOp  float64 `json:"bytes_per_op"`
+	P50         float64 `json:"p50"`
+	P99         float64 `json:"p99"`
+	// Regressions lists the metrics that moved the wrong way by more than the threshold
+	Regressions []string `json:"regressions,omitempty"`
+}
+
+// Comparison is the outcome of comparing a report against a baseline.
+type Comparison struct {
+	Name      string  `json:"name"`
+	Threshold float64 `json:"threshold"`
+	Deltas    []Delta `json:"deltas"`
+	Missing   []int   `json:"missing,omitempty"` // baseline payload sizes absent from the current report
+}
+
+// Compare reports how current differs from baseline for each payload size.
+//
+// A metric regresses when throughput drops, or latency or allocations grow, by
+// more than threshold (a fraction; zero uses DefaultThreshold). p99 uses twice
+// the threshold since tail latency is noisier. Sizes present in only one report
+// are listed in Missing rather than compared.
+//
+// Example:
+//
+//	cmp := calquebench.Compare(baseline, current, 0.05)
+//	cmp.WriteText(os.Stdout)
+//	if cmp.Regressed() {
+//		os.Exit(1)
+//	}
+func Compare(baseline, current *Report, threshold float64) *Comparison {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	cmp := &Comparison{Name: current.Name, Threshold: threshold}
+
+	byPayload := make(map[int]Result, len(current.Results))
+	for _, res := range current.Results {
+		byPayload[res.PayloadSize] = res
+	}
+
+	for _, base := range baseline.Results {
+		cur, ok := byPayload[base.PayloadSize]
+		if !ok {
+			cmp.Missing = append(cmp.Missing, base.PayloadSize)
+			continue
+		}
+
+		d := Delta{
+			PayloadSize: base.PayloadSize,
+			OpsPerSec:   change(base.OpsPerSec, cur.OpsPerSec),
+			AllocsPerOp: change(base.AllocsPerOp, cur.AllocsPerOp),
+			BytesPerOp:  change(base.BytesPerOp, cur.BytesPerOp),
+			P50:         change(float64(base.P50), float64(cur.P50)),
+			P99:         change(float64(base.P99), float64(cur.P99)),
+		}
+		if d.OpsPerSec < -threshold {
+			d.Regressions = append(d.Regressions, "ops/s")
+		}
+		if d.AllocsPerOp > threshold {
+			d.Regressions = append(d.Regressions, "allocs/op")
+		}
+		if d.BytesPerOp > threshold {
+			d.Regressions = append(d.Regressions, "B/op")
+		}
+		if d.P50 > threshold {
+			d.Regressions = append(d.Regressions, "p50")
+		}
+		if d.P99 > 2*threshold {
+			d.Regressions = append(d.Regressions, "p99")
+		}
+		cmp.Deltas = append(cmp.Deltas, d)
+	}
+	return cmp
+}
+
+// Regressed reports whether any payload size regressed.
+func (c *Comparison) Regressed() bool {
+	for _, d := range c.Deltas {
+		if len(d.Regressions) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes the comparison as a table of relative changes.
+func (c *Comparison) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "%s vs baseline (threshold %.0f%%)\n", c.Name, c.Threshold*100)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "payload\tops/s\tallocs/op\tB/op\tp50\tp99\tregressions\t")
+	for _, d := range c.Deltas {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%v\t\n", sizeName(d.PayloadSize),
+			percent(d.OpsPerSec), percent(d.AllocsPerOp), percent(d.BytesPerOp), percent(d.P50), percent(d.P99),
+			d.Regressions)
+	}
+	for _, size := range c.Missing {
+		fmt.Fprintf(tw, "%s\t-\t-\t-\t-\t-\tmissing\t\n", sizeName(size))
+	}
+	return tw.Flush()
+}
+
+// change returns the relative change from base to cur
+func change(base, cur float64) float64 {
+	if base == 0 {
+		if cur == 0 {
+			return 0
+		}
+		return 1
+	}
+	return (cur - base) / base
+}
+
+func percent(v float64) string {
+	return fmt.Sprintf("%+.1f%%", v*100)
+}
+
+// round trims durations to three significant digits for display
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(time.Microsecond)
+	case d >= time.Microsecond:
+		return d.Round(10 * time.Nanosecond)
+	default:
+		return d
+	}
+}
@@ -0,0 +1,89 @@
+package calquebench
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func baselineReport() *Report {
+	return &Report{
+		Name: "handler",
+		Results: []Result{
+			{PayloadSize: 64, OpsPerSec: 1000, AllocsPerOp: 10, BytesPerOp: 512, P50: time.Millisecond, P99: 2 * time.Millisecond},
+			{PayloadSize: 4096, OpsPerSec: 500, AllocsPerOp: 12, BytesPerOp: 8192, P50: 2 * time.Millisecond, P99: 4 * time.Millisecond},
+		},
+	}
+}
+
+func TestReport_JSONRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := baselineReport().WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	got, err := ReadReport(&buf)
+	if err != nil {
+		t.Fatalf("ReadReport() error = %v", err)
+	}
+	if got.Name != "handler" || len(got.Results) != 2 || got.Results[1].P99 != 4*time.Millisecond {
+		t.Errorf("round trip = %+v", got)
+	}
+	if _, err := ReadReport(strings.NewReader("{")); err == nil {
+		t.Error("ReadReport() should fail on invalid JSON")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Result)
+		want    []string
+		regress bool
+	}{
+		{"unchanged", func(*Result) {}, nil, false},
+		{"within threshold", func(r *Result) { r.OpsPerSec *= 0.95; r.P50 += r.P50 / 20 }, nil, false},
+		{"throughput drop", func(r *Result) { r.OpsPerSec *= 0.5 }, []string{"ops/s"}, true},
+		{"more allocations", func(r *Result) { r.AllocsPerOp *= 2; r.BytesPerOp *= 2 }, []string{"allocs/op", "B/op"}, true},
+		{"slower median", func(r *Result) { r.P50 *= 2 }, []string{"p50"}, true},
+		{"noisy tail tolerated", func(r *Result) { r.P99 += r.P99 * 15 / 100 }, nil, false},
+		{"improvement", func(r *Result) { r.OpsPerSec *= 2; r.P50 /= 2 }, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := baselineReport()
+			tt.mutate(&current.Results[0])
+
+			cmp := Compare(baselineReport(), current, 0)
+			if cmp.Regressed() != tt.regress {
+				t.Errorf("Regressed() = %v, want %v", cmp.Regressed(), tt.regress)
+			}
+			if !slices.Equal(cmp.Deltas[0].Regressions, tt.want) {
+				t.Errorf("regressions = %v, want %v", cmp.Deltas[0].Regressions, tt.want)
+			}
+			if len(cmp.Deltas[1].Regressions) != 0 {
+				t.Errorf("untouched size flagged: %v", cmp.Deltas[1].Regressions)
+			}
+		})
+	}
+}
+
+func TestCompare_MissingSizes(t *testing.T) {
+	current := baselineReport()
+	current.Results = current.Results[:1]
+
+	cmp := Compare(baselineReport(), current, 0.05)
+	if len(cmp.Deltas) != 1 || !slices.Equal(cmp.Missing, []int{4096}) {
+		t.Errorf("deltas = %d, missing = %v", len(cmp.Deltas), cmp.Missing)
+	}
+
+	var buf strings.Builder
+	if err := cmp.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "missing") || !strings.Contains(buf.String(), "threshold 5%") {
+		t.Errorf("comparison output:\n%s", buf.String())
+	}
+}
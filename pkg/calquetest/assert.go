@@ -0,0 +1,161 @@
+package calquetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultTimeout bounds each handler call made by the assertion helpers.
+const DefaultTimeout = 5 * time.Second
+
+// ErrPanic is returned by Serve when the handler panics.
+var ErrPanic = errors.New("calquetest: handler panicked")
+
+// Layout is a named way of splitting a stream into reads.
+type Layout struct {
+	Name  string
+	Sizes []int // chunk sizes passed to ChunkedReader; nil means a single read
+}
+
+// Layouts returns the standard chunk layouts used by AssertChunkInvariant.
+//
+// They cover a single read, one byte at a time, small odd sizes, reads that
+// split every multi-byte rune, and several seeded random layouts.
+func Layouts(data []byte) []Layout {
+	layouts := []Layout{
+		{Name: "whole", Sizes: []int{max(len(data), 1)}},
+		{Name: "bytewise", Sizes: []int{1}},
+		{Name: "pairs", Sizes: []int{2}},
+		{Name: "odd", Sizes: []int{3, 7, 1, 13}},
+		{Name: "split-runes", Sizes: SplitRunes(data)},
+	}
+	for seed := range uint64(3) {
+		layouts = append(layouts, Layout{
+			Name:  fmt.Sprintf("random-%d", seed),
+			Sizes: RandomChunkSizes(len(data), 64, seed),
+		})
+	}
+	return layouts
+}
+
+// Serve runs handler once on input and returns everything it wrote.
+//
+// The call is bounded by DefaultTimeout; a handler that does not return in
+// time yields context.DeadlineExceeded. A panic in the handler is recovered and
+// returned as ErrPanic.
+func Serve(handler calque.Handler, input io.Reader) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("%w: %v", ErrPanic, r)
+			}
+		}()
+		done <- handler.ServeFlow(calque.NewRequest(ctx, input), calque.NewResponse(&out))
+	}()
+
+	select {
+	case err := <-done:
+		return out.Bytes(), err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("handler did not return within %s: %w", DefaultTimeout, ctx.Err())
+	}
+}
+
+// AssertChunkInvariant fails the test if handler output depends on how data is chunked.
+//
+// The handler runs once per layout from Layouts; every run must succeed and
+// produce the same output as a single whole read. Streaming parsers that
+// assume a token, line or rune arrives within one Read fail here.
+//
+// Example:
+//
+//	func TestUpper_Chunking(t *testing.T) {
+//		calquetest.AssertChunkInvariant(t, text.Transform(strings.ToUpper), []byte("héllo wörld"))
+//	}
+func AssertChunkInvariant(t testing.TB, handler calque.Handler, data []byte) {
+	t.Helper()
+
+	want, err := Serve(handler, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("handler failed on whole input: %v", err)
+	}
+
+	for _, layout := range Layouts(data) {
+		got, err := Serve(handler, ChunkedReader(data, layout.Sizes...))
+		if err != nil {
+			t.Errorf("layout %s: handler error = %v", layout.Name, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("layout %s (chunks %v): output differs from whole read\n got: %q\nwant: %q",
+				layout.Name, truncateSizes(layout.Sizes), abbreviate(got), abbreviate(want))
+		}
+	}
+}
+
+// AssertPropagatesReadError fails the test if handler hides an upstream read failure.
+//
+// The input fails with ErrInjected after half of data has been read. Handlers
+// that buffer their input must return an error; ones that treat the failure as
+// end of input would silently process a truncated payload.
+func AssertPropagatesReadError(t testing.TB, handler calque.Handler, data []byte) {
+	t.Helper()
+
+	if _, err := Serve(handler, FailingReader(data, len(data)/2, nil)); err == nil {
+		t.Errorf("handler returned nil after its input failed with %v", ErrInjected)
+	}
+}
+
+// AssertSurvivesMalformed fails the test if handler panics or hangs on malformed input.
+//
+// The handler is run on data truncated at several points, with invalid UTF-8
+// inserted, on an empty stream and on a 1MB single line. Returning an error is
+// acceptable; panicking or blocking past DefaultTimeout is not.
+func AssertSurvivesMalformed(t testing.TB, handler calque.Handler, data []byte) {
+	t.Helper()
+
+	inputs := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated-1", Truncate(data, -1)},
+		{"truncated-half", Truncate(data, len(data)/2)},
+		{"truncated-1-byte", Truncate(data, 1)},
+		{"invalid-utf8", InvalidUTF8(data)},
+		{"giant-line", GiantLine(1 << 20)},
+	}
+
+	for _, in := range inputs {
+		_, err := Serve(handler, ChunkedReader(in.data, 1, 4096))
+		if errors.Is(err, ErrPanic) || errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: %v", in.name, err)
+		}
+	}
+}
+
+func truncateSizes(sizes []int) []int {
+	if len(sizes) > 8 {
+		return sizes[:8]
+	}
+	return sizes
+}
+
+func abbreviate(b []byte) []byte {
+	if len(b) > 200 {
+		return append(bytes.Clone(b[:200]), "..."...)
+	}
+	return b
+}
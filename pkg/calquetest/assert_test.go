@@ -0,0 +1,166 @@
+package calquetest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// recorder captures assertion failures instead of failing the real test
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func echo() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+}
+
+// singleRead assumes the whole input arrives in one Read
+func singleRead() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		buf := make([]byte, 4096)
+		n, _ := req.Data.Read(buf)
+		_, err := res.Data.Write(buf[:n])
+		return err
+	})
+}
+
+// runeCounter emits the rune count of each Read, so split runes change its output
+func runeCounter() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		buf := make([]byte, 4096)
+		var invalid int
+		for {
+			n, err := req.Data.Read(buf)
+			for b := buf[:n]; len(b) > 0; {
+				r, size := utf8.DecodeRune(b)
+				if r == utf8.RuneError {
+					invalid++
+				}
+				b = b[size:]
+			}
+			if err == io.EOF {
+				_, werr := fmt.Fprintf(res.Data, "invalid=%d", invalid)
+				return werr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// swallowsErrors stops at the first read error and reports success
+func swallowsErrors() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		data, _ := io.ReadAll(req.Data)
+		_, err := res.Data.Write(data)
+		return err
+	})
+}
+
+// lineScanner uses bufio.Scanner with its default 64KB limit and panics on scan errors
+func lineScanner() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		scanner := bufio.NewScanner(req.Data)
+		for scanner.Scan() {
+			fmt.Fprintln(res.Data, strings.ToUpper(scanner.Text()))
+		}
+		if err := scanner.Err(); err != nil {
+			panic(err)
+		}
+		return nil
+	})
+}
+
+func TestAssertChunkInvariant(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  calque.Handler
+		wantFail bool
+	}{
+		{"streaming copy", echo(), false},
+		{"single read", singleRead(), true},
+		{"rune decoding per read", runeCounter(), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{TB: t}
+			AssertChunkInvariant(rec, tt.handler, []byte("héllo wörld, ünïcode €"))
+			if failed := len(rec.failures) > 0; failed != tt.wantFail {
+				t.Errorf("failed = %v, want %v; failures: %v", failed, tt.wantFail, rec.failures)
+			}
+		})
+	}
+}
+
+func TestAssertPropagatesReadError(t *testing.T) {
+	rec := &recorder{TB: t}
+	AssertPropagatesReadError(rec, echo(), []byte("payload"))
+	if len(rec.failures) != 0 {
+		t.Errorf("io.Copy propagates errors but assertion failed: %v", rec.failures)
+	}
+
+	rec = &recorder{TB: t}
+	AssertPropagatesReadError(rec, swallowsErrors(), []byte("payload"))
+	if len(rec.failures) != 1 {
+		t.Errorf("handler swallowing errors was not caught: %v", rec.failures)
+	}
+}
+
+func TestAssertSurvivesMalformed(t *testing.T) {
+	rec := &recorder{TB: t}
+	AssertSurvivesMalformed(rec, echo(), []byte(`{"key":"välue"}`))
+	if len(rec.failures) != 0 {
+		t.Errorf("echo should survive malformed input: %v", rec.failures)
+	}
+
+	rec = &recorder{TB: t}
+	AssertSurvivesMalformed(rec, lineScanner(), []byte("line one\nline two\n"))
+	if len(rec.failures) != 1 || !strings.Contains(rec.failures[0], "giant-line") {
+		t.Errorf("giant line panic not reported: %v", rec.failures)
+	}
+}
+
+func TestServe(t *testing.T) {
+	out, err := Serve(echo(), strings.NewReader("abc"))
+	if err != nil || string(out) != "abc" {
+		t.Errorf("Serve() = %q, %v", out, err)
+	}
+
+	panicky := calque.HandlerFunc(func(*calque.Request, *calque.Response) error { panic("boom") })
+	if _, err := Serve(panicky, strings.NewReader("")); !errors.Is(err, ErrPanic) {
+		t.Errorf("Serve() error = %v, want ErrPanic", err)
+	}
+}
+
+func FuzzChunkedEcho(f *testing.F) {
+	f.Add([]byte("hello, wörld"), []byte{0, 2, 5})
+	f.Fuzz(func(t *testing.T, data, layout []byte) {
+		out, err := Serve(echo(), ChunkedReader(data, ChunkSizesFromBytes(layout)...))
+		if err != nil || string(out) != string(data) {
+			t.Errorf("Serve() = %q, %v; want %q", out, err, data)
+		}
+	})
+}
@@ -0,0 +1,197 @@
+// Package calquetest provides utilities for testing calque handlers against streaming edge cases.
+//
+// Handlers read from an io.Reader that may deliver data in arbitrary chunks,
+// fail mid-stream or carry malformed content. The readers and generators here
+// reproduce those conditions deterministically, and the assertion helpers run
+// a handler under each of them. Chunk layouts can be derived from fuzzer input
+// with ChunkSizesFromBytes, so the same helpers work in native Go fuzz tests.
+package calquetest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInjected is the default error returned by FailingReader.
+var ErrInjected = errors.New("calquetest: injected read error")
+
+// chunkedReader returns data in a fixed pattern of chunk sizes
+type chunkedReader struct {
+	data   []byte
+	sizes  []int
+	offset int
+	next   int
+}
+
+// ChunkedReader returns a reader that delivers data in chunks of the given sizes.
+//
+// Each Read returns at most the next size in chunkSizes, cycling through the
+// list until data is exhausted. Sizes below 1 are treated as 1. With no sizes,
+// data is delivered one byte at a time, the harshest case for parsers that
+// assume a token or line arrives in a single Read.
+//
+// Example:
+//
+//	r := calquetest.ChunkedReader([]byte(`{"name":"calque"}`), 1, 3, 7)
+//	err := handler.ServeFlow(calque.NewRequest(ctx, r), calque.NewResponse(&out))
+func ChunkedReader(data []byte, chunkSizes ...int) io.Reader {
+	return &chunkedReader{data: data, sizes: chunkSizes}
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if r.offset >= len(r.data) {
+		return 0, io.EOF
+	}
+	size := 1
+	if len(r.sizes) > 0 {
+		size = max(r.sizes[r.next%len(r.sizes)], 1)
+		r.next++
+	}
+	size = min(size, len(p), len(r.data)-r.offset)
+	n := copy(p, r.data[r.offset:r.offset+size])
+	r.offset += n
+	return n, nil
+}
+
+// RandomChunkedReader returns a reader delivering data in random chunks of 1 to maxChunk bytes.
+//
+// The layout is fully determined by seed, so a failing case can be replayed.
+func RandomChunkedReader(data []byte, maxChunk int, seed uint64) io.Reader {
+	return ChunkedReader(data, RandomChunkSizes(len(data), maxChunk, seed)...)
+}
+
+// RandomChunkSizes returns a deterministic random split of total bytes into chunks of 1 to maxChunk.
+func RandomChunkSizes(total, maxChunk int, seed uint64) []int {
+	maxChunk = max(maxChunk, 1)
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	var sizes []int
+	for total > 0 {
+		n := min(rng.IntN(maxChunk)+1, total)
+		sizes = append(sizes, n)
+		total -= n
+	}
+	return sizes
+}
+
+// ChunkSizesFromBytes turns fuzzer-provided bytes into chunk sizes of 1 to 256.
+//
+// Example:
+//
+//	f.Fuzz(func(t *testing.T, data []byte, layout []byte) {
+//		r := calquetest.ChunkedReader(data, calquetest.ChunkSizesFromBytes(layout)...)
+//		...
+//	})
+func ChunkSizesFromBytes(layout []byte) []int {
+	sizes := make([]int, len(layout))
+	for i, b := range layout {
+		sizes[i] = int(b) + 1
+	}
+	return sizes
+}
+
+// failingReader returns data up to a point, then an error
+type failingReader struct {
+	r   io.Reader
+	err error
+}
+
+// FailingReader returns a reader that yields the first n bytes of data, then err.
+//
+// A nil err uses ErrInjected. Use it to check that handlers propagate read
+// errors instead of treating a broken upstream as a short, successful input.
+func FailingReader(data []byte, n int, err error) io.Reader {
+	if err == nil {
+		err = ErrInjected
+	}
+	n = min(max(n, 0), len(data))
+	return &failingReader{r: bytes.NewReader(data[:n]), err: err}
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err == io.EOF {
+		return n, r.err
+	}
+	return n, err
+}
+
+// Truncate returns the first n bytes of data, simulating a stream cut off mid-message.
+//
+// A negative n counts from the end, so Truncate(data, -1) drops the final byte.
+func Truncate(data []byte, n int) []byte {
+	if n < 0 {
+		n = len(data) + n
+	}
+	n = min(max(n, 0), len(data))
+	return bytes.Clone(data[:n])
+}
+
+// TruncatedReader returns a reader that yields the first n bytes of data, then io.ErrUnexpectedEOF.
+func TruncatedReader(data []byte, n int) io.Reader {
+	return FailingReader(data, n, io.ErrUnexpectedEOF)
+}
+
+// InvalidUTF8 returns data with invalid UTF-8 sequences inserted at the start, middle and end.
+//
+// The inserted bytes are a lone continuation byte, a truncated multi-byte
+// sequence and an overlong encoding, which decoders must reject or replace.
+func InvalidUTF8(data []byte) []byte {
+	invalid := [][]byte{{0x80}, {0xe2, 0x82}, {0xc0, 0xaf}}
+	mid := len(data) / 2
+	// Keep the split on a rune boundary so only the inserted bytes are invalid
+	for mid > 0 && mid < len(data) && data[mid]&0xc0 == 0x80 {
+		mid--
+	}
+
+	var buf bytes.Buffer
+	buf.Write(invalid[0])
+	buf.Write(data[:mid])
+	buf.Write(invalid[1])
+	buf.Write(data[mid:])
+	buf.Write(invalid[2])
+	return buf.Bytes()
+}
+
+// SplitRunes returns chunk sizes that split every multi-byte rune in data across two reads.
+//
+// Pass the result to ChunkedReader to test handlers that decode text
+// incrementally and might emit replacement characters at chunk boundaries.
+func SplitRunes(data []byte) []int {
+	var sizes []int
+	last := 0
+	for i := 0; i < len(data); {
+		_, width := utf8.DecodeRune(data[i:])
+		if width > 1 {
+			sizes = append(sizes, i+1-last)
+			last = i + 1
+		}
+		i += width
+	}
+	if last < len(data) {
+		sizes = append(sizes, len(data)-last)
+	}
+	return sizes
+}
+
+// GiantLine returns a single line of size bytes with no newline until the final byte.
+//
+// Handlers using bufio.Scanner fail on lines over 64KB unless they raise the
+// buffer limit; this exercises that path.
+func GiantLine(size int) []byte {
+	if size <= 0 {
+		return nil
+	}
+	line := bytes.Repeat([]byte("x"), size)
+	line[size-1] = '\n'
+	return line
+}
+
+// ManyLines returns count lines of the given width, each terminated by a newline.
+func ManyLines(count, width int) []byte {
+	line := strings.Repeat("y", max(width, 0)) + "\n"
+	return []byte(strings.Repeat(line, max(count, 0)))
+}
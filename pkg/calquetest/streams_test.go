@@ -0,0 +1,174 @@
+package calquetest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+)
+
+// reads collects the size of every Read until EOF
+func reads(t *testing.T, r io.Reader) ([]int, []byte) {
+	t.Helper()
+	var sizes []int
+	var all []byte
+	buf := make([]byte, 1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			sizes = append(sizes, n)
+			all = append(all, buf[:n]...)
+		}
+		if err == io.EOF {
+			return sizes, all
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+	}
+}
+
+func TestChunkedReader(t *testing.T) {
+	data := []byte("abcdefghij")
+	tests := []struct {
+		name  string
+		sizes []int
+		want  []int
+	}{
+		{"bytewise default", nil, []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}},
+		{"fixed", []int{4}, []int{4, 4, 2}},
+		{"cycling", []int{1, 3}, []int{1, 3, 1, 3, 1, 1}},
+		{"oversized", []int{100}, []int{10}},
+		{"zero treated as one", []int{0, 5}, []int{1, 5, 1, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, all := reads(t, ChunkedReader(data, tt.sizes...))
+			if !slices.Equal(got, tt.want) || !bytes.Equal(all, data) {
+				t.Errorf("reads = %v (%q), want %v", got, all, tt.want)
+			}
+		})
+	}
+
+	if err := iotest.TestReader(ChunkedReader(data, 3), data); err != nil {
+		t.Errorf("iotest.TestReader: %v", err)
+	}
+}
+
+func TestRandomChunkSizes(t *testing.T) {
+	a := RandomChunkSizes(1000, 16, 42)
+	b := RandomChunkSizes(1000, 16, 42)
+	if !slices.Equal(a, b) {
+		t.Error("same seed produced different layouts")
+	}
+
+	total := 0
+	for _, n := range a {
+		if n < 1 || n > 16 {
+			t.Fatalf("chunk size %d out of range", n)
+		}
+		total += n
+	}
+	if total != 1000 {
+		t.Errorf("sizes sum to %d, want 1000", total)
+	}
+
+	_, all := reads(t, RandomChunkedReader([]byte("hello world"), 3, 7))
+	if string(all) != "hello world" {
+		t.Errorf("RandomChunkedReader data = %q", all)
+	}
+}
+
+func TestChunkSizesFromBytes(t *testing.T) {
+	if got := ChunkSizesFromBytes([]byte{0, 9, 255}); !slices.Equal(got, []int{1, 10, 256}) {
+		t.Errorf("ChunkSizesFromBytes() = %v", got)
+	}
+}
+
+func TestFailingReader(t *testing.T) {
+	custom := errors.New("connection reset")
+	tests := []struct {
+		name    string
+		r       io.Reader
+		want    string
+		wantErr error
+	}{
+		{"default error", FailingReader([]byte("abcdef"), 3, nil), "abc", ErrInjected},
+		{"custom error", FailingReader([]byte("abcdef"), 0, custom), "", custom},
+		{"truncated", TruncatedReader([]byte("abcdef"), 5), "abcde", io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(tt.r)
+			if string(got) != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReadAll() = %q, %v; want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	data := []byte("abcdef")
+	tests := []struct {
+		n    int
+		want string
+	}{{3, "abc"}, {-1, "abcde"}, {0, ""}, {99, "abcdef"}, {-99, ""}}
+	for _, tt := range tests {
+		if got := Truncate(data, tt.n); string(got) != tt.want {
+			t.Errorf("Truncate(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	data := []byte("héllo wörld")
+	got := InvalidUTF8(data)
+	if utf8.Valid(got) {
+		t.Fatal("InvalidUTF8() output is valid UTF-8")
+	}
+	// Only the three inserted sequences are invalid: 1 + 2 + 2 bytes
+	if len(got) != len(data)+5 {
+		t.Errorf("len = %d, want %d", len(got), len(data)+5)
+	}
+	if !bytes.Contains(got, []byte("ö")) || !bytes.Contains(got, []byte("é")) {
+		t.Errorf("original runes were corrupted: %q", got)
+	}
+}
+
+func TestSplitRunes(t *testing.T) {
+	data := []byte("aé€b")
+	sizes := SplitRunes(data)
+
+	total := 0
+	for _, n := range sizes {
+		total += n
+	}
+	if total != len(data) {
+		t.Fatalf("sizes %v sum to %d, want %d", sizes, total, len(data))
+	}
+
+	// Every chunk boundary inside a multi-byte rune means no chunk but the last is valid on its own
+	r := ChunkedReader(data, sizes...)
+	chunks, _ := reads(t, r)
+	if len(chunks) < 3 {
+		t.Errorf("expected multi-byte runes to be split, got chunks %v", chunks)
+	}
+}
+
+func TestGeneratedStreams(t *testing.T) {
+	line := GiantLine(100_000)
+	if len(line) != 100_000 || bytes.Count(line, []byte("\n")) != 1 || line[len(line)-1] != '\n' {
+		t.Errorf("GiantLine() has %d bytes and %d newlines", len(line), bytes.Count(line, []byte("\n")))
+	}
+	if GiantLine(0) != nil {
+		t.Error("GiantLine(0) should be empty")
+	}
+	if got := ManyLines(3, 2); string(got) != "yy\nyy\nyy\n" {
+		t.Errorf("ManyLines() = %q", got)
+	}
+}
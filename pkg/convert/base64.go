@@ -0,0 +1,351 @@
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// defaultDataURIMediaType is the media type of a data URI that names none (RFC 2397)
+const defaultDataURIMediaType = "text/plain;charset=US-ASCII"
+
+// sniffLen is the number of bytes http.DetectContentType considers
+const sniffLen = 512
+
+// Base64InputConverter is an input converter for encoding binary data as base64
+type Base64InputConverter struct {
+	data any
+}
+
+// Base64OutputConverter is an output converter for decoding base64 into binary data
+type Base64OutputConverter struct {
+	target any
+}
+
+// DataURIInputConverter is an input converter for encoding binary data as a data: URI
+type DataURIInputConverter struct {
+	data any
+}
+
+// DataURIOutputConverter is an output converter for decoding a data: URI
+type DataURIOutputConverter struct {
+	target any
+}
+
+// DataURI is a decoded data: URI (RFC 2397).
+//
+// DataURI implements encoding.TextMarshaler, so it can be embedded in JSON
+// envelopes and is written as a "data:<media type>;base64,<data>" string.
+//
+// Example:
+//
+//	type Attachment struct {
+//		Name    string          `json:"name"`
+//		Content convert.DataURI `json:"content"`
+//	}
+//
+//	png, _ := os.ReadFile("chart.png")
+//	att := Attachment{Name: "chart.png", Content: convert.DataURI{MediaType: "image/png", Data: png}}
+//	err := pipeline.Run(ctx, convert.ToJSON(att), &result)
+type DataURI struct {
+	MediaType string // Media type with parameters, e.g. "image/png" or "text/plain;charset=utf-8"
+	Data      []byte
+}
+
+// ToBase64 creates an input converter for encoding binary data as standard base64.
+//
+// Input: []byte, string, io.Reader, or DataURI (its data is encoded)
+// Output: calque.InputConverter for pipeline input position
+// Behavior: STREAMING - io.Reader input is encoded as it is read
+//
+// Bytes are encoded as-is, so images, audio and other binary payloads survive
+// pipelines and envelopes that only carry text.
+//
+// Example usage:
+//
+//	file, _ := os.Open("report.pdf")
+//	defer file.Close()
+//	err := pipeline.Run(ctx, convert.ToBase64(file), &encoded)
+func ToBase64(data any) calque.InputConverter {
+	return &Base64InputConverter{data: data}
+}
+
+// FromBase64 creates an output converter for decoding standard base64 into binary data.
+//
+// Input: *[]byte, *string, or io.Writer target
+// Output: calque.OutputConverter for pipeline output position
+// Behavior: STREAMING - io.Writer targets receive data as it is decoded
+//
+// Whitespace, including the line breaks of wrapped base64, is ignored.
+//
+// Example usage:
+//
+//	var audio []byte
+//	err := pipeline.Run(ctx, input, convert.FromBase64(&audio))
+func FromBase64(target any) calque.OutputConverter {
+	return &Base64OutputConverter{target: target}
+}
+
+// ToDataURI creates an input converter for encoding binary data as a base64 data: URI.
+//
+// Input: DataURI or *DataURI, or []byte, string, or io.Reader with a sniffed media type
+// Output: calque.InputConverter for pipeline input position
+// Behavior: STREAMING - io.Reader input is encoded as it is read
+//
+// Raw input is labelled with the media type http.DetectContentType reports
+// for its first 512 bytes. Use a DataURI to set the media type explicitly.
+//
+// Example usage:
+//
+//	img, _ := os.ReadFile("photo.jpg")
+//	err := pipeline.Run(ctx, convert.ToDataURI(img), &uri) // "data:image/jpeg;base64,..."
+func ToDataURI(data any) calque.InputConverter {
+	return &DataURIInputConverter{data: data}
+}
+
+// FromDataURI creates an output converter for decoding a data: URI.
+//
+// Input: *DataURI, or *[]byte, *string, or io.Writer for the data alone
+// Output: calque.OutputConverter for pipeline output position
+// Behavior: BUFFERED - reads the whole URI before decoding
+//
+// Both base64 and percent-encoded data URIs are accepted.
+//
+// Example usage:
+//
+//	var attachment convert.DataURI
+//	err := pipeline.Run(ctx, input, convert.FromDataURI(&attachment))
+//	fmt.Println(attachment.MediaType, len(attachment.Data))
+func FromDataURI(target any) calque.OutputConverter {
+	return &DataURIOutputConverter{target: target}
+}
+
+// ToReader converts the input data to a base64 stream.
+func (b *Base64InputConverter) ToReader() (io.Reader, error) {
+	reader, err := binaryReader(b.data)
+	if err != nil {
+		return nil, err
+	}
+	return encodeBase64(reader), nil
+}
+
+// FromReader decodes a base64 stream into the target.
+func (b *Base64OutputConverter) FromReader(reader io.Reader) error {
+	ctx := context.Background()
+	decoder := base64.NewDecoder(base64.StdEncoding, &whitespaceFilter{r: reader})
+
+	if w, ok := b.target.(io.Writer); ok {
+		if _, err := io.Copy(w, decoder); err != nil {
+			return calque.WrapErr(ctx, err, "failed to decode base64")
+		}
+		return nil
+	}
+
+	data, err := io.ReadAll(decoder)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to decode base64")
+	}
+	return setBinary(b.target, data)
+}
+
+// ToReader converts the input data to a data: URI stream.
+func (d *DataURIInputConverter) ToReader() (io.Reader, error) {
+	switch v := d.data.(type) {
+	case DataURI:
+		return strings.NewReader(v.String()), nil
+	case *DataURI:
+		if v == nil {
+			return nil, calque.NewErr(context.Background(), "data URI is nil")
+		}
+		return strings.NewReader(v.String()), nil
+	}
+
+	reader, err := binaryReader(d.data)
+	if err != nil {
+		return nil, err
+	}
+	buffered := bufio.NewReaderSize(reader, sniffLen)
+	head, _ := buffered.Peek(sniffLen)
+	prefix := "data:" + http.DetectContentType(head) + ";base64,"
+	return io.MultiReader(strings.NewReader(prefix), encodeBase64(buffered)), nil
+}
+
+// FromReader decodes a data: URI into the target.
+func (d *DataURIOutputConverter) FromReader(reader io.Reader) error {
+	ctx := context.Background()
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to read data URI")
+	}
+	uri, err := ParseDataURI(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return err
+	}
+
+	switch t := d.target.(type) {
+	case *DataURI:
+		*t = *uri
+		return nil
+	case io.Writer:
+		if _, err := t.Write(uri.Data); err != nil {
+			return calque.WrapErr(ctx, err, "failed to write data URI content")
+		}
+		return nil
+	default:
+		return setBinary(d.target, uri.Data)
+	}
+}
+
+// ParseDataURI decodes a data: URI such as "data:image/png;base64,iVBORw0KGgo=".
+// A URI without a media type gets text/plain;charset=US-ASCII, as RFC 2397 specifies.
+func ParseDataURI(s string) (*DataURI, error) {
+	ctx := context.Background()
+	if len(s) < len("data:") || !strings.EqualFold(s[:len("data:")], "data:") {
+		return nil, calque.NewErr(ctx, "not a data URI")
+	}
+	meta, payload, found := strings.Cut(s[len("data:"):], ",")
+	if !found {
+		return nil, calque.NewErr(ctx, "data URI has no data")
+	}
+
+	isBase64 := false
+	if idx := strings.LastIndex(meta, ";"); idx >= 0 && strings.EqualFold(strings.TrimSpace(meta[idx+1:]), "base64") {
+		isBase64, meta = true, meta[:idx]
+	}
+	mediaType := strings.TrimSpace(meta)
+	switch {
+	case mediaType == "":
+		mediaType = defaultDataURIMediaType
+	case strings.HasPrefix(mediaType, ";"):
+		mediaType = "text/plain" + mediaType
+	}
+	if _, _, err := mime.ParseMediaType(mediaType); err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("invalid data URI media type %q", mediaType))
+	}
+
+	var data []byte
+	var err error
+	if isBase64 {
+		data, err = decodeBase64String(payload)
+	} else {
+		var unescaped string
+		unescaped, err = url.PathUnescape(payload)
+		data = []byte(unescaped)
+	}
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "invalid data URI data")
+	}
+	return &DataURI{MediaType: mediaType, Data: data}, nil
+}
+
+// String returns the URI with base64 data. An empty media type is written as
+// application/octet-stream.
+func (d DataURI) String() string {
+	mediaType := d.MediaType
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(d.Data)
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (d DataURI) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *DataURI) UnmarshalText(text []byte) error {
+	uri, err := ParseDataURI(string(text))
+	if err != nil {
+		return err
+	}
+	*d = *uri
+	return nil
+}
+
+// binaryReader returns the bytes of binary input without any text conversion
+func binaryReader(data any) (io.Reader, error) {
+	switch v := data.(type) {
+	case []byte:
+		return bytes.NewReader(v), nil
+	case string:
+		return strings.NewReader(v), nil
+	case io.Reader:
+		return v, nil
+	case DataURI:
+		return bytes.NewReader(v.Data), nil
+	case *DataURI:
+		if v != nil {
+			return bytes.NewReader(v.Data), nil
+		}
+	}
+	return nil, calque.NewErr(context.Background(), fmt.Sprintf("unsupported binary input type %T", data))
+}
+
+// setBinary stores decoded bytes in a *[]byte or *string target
+func setBinary(target any, data []byte) error {
+	switch t := target.(type) {
+	case *[]byte:
+		*t = data
+	case *string:
+		*t = string(data)
+	default:
+		return calque.NewErr(context.Background(), fmt.Sprintf("unsupported binary target type %T", target))
+	}
+	return nil
+}
+
+// encodeBase64 streams r as standard base64
+func encodeBase64(r io.Reader) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		encoder := base64.NewEncoder(base64.StdEncoding, pw)
+		if _, err := io.Copy(encoder, r); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(encoder.Close())
+	}()
+	return pr
+}
+
+// decodeBase64String decodes padded or unpadded base64, ignoring whitespace
+func decodeBase64String(s string) ([]byte, error) {
+	s = strings.Join(strings.Fields(s), "")
+	if strings.HasSuffix(s, "=") || len(s)%4 == 0 {
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// whitespaceFilter drops ASCII whitespace from a stream
+type whitespaceFilter struct {
+	r io.Reader
+}
+
+func (f *whitespaceFilter) Read(p []byte) (int, error) {
+	for {
+		n, err := f.r.Read(p)
+		kept := 0
+		for _, c := range p[:n] {
+			switch c {
+			case ' ', '\t', '\n', '\r', '\f', '\v':
+			default:
+				p[kept] = c
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
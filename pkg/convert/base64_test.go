@@ -0,0 +1,184 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// binaryPayload covers every byte value, including invalid UTF-8
+var binaryPayload = func() []byte {
+	b := make([]byte, 1024)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}()
+
+func readConverted(t *testing.T, converter calque.InputConverter) string {
+	t.Helper()
+	reader, err := converter.ToReader()
+	if err != nil {
+		t.Fatalf("ToReader() error = %v", err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	return string(data)
+}
+
+func TestBase64RoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		data any
+	}{
+		{"bytes", binaryPayload},
+		{"reader", bytes.NewReader(binaryPayload)},
+		{"string", string(binaryPayload)},
+		{"data URI", DataURI{MediaType: "image/png", Data: binaryPayload}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := readConverted(t, ToBase64(tt.data))
+
+			var decoded []byte
+			if err := FromBase64(&decoded).FromReader(strings.NewReader(encoded)); err != nil {
+				t.Fatalf("FromBase64() error = %v", err)
+			}
+			if !bytes.Equal(decoded, binaryPayload) {
+				t.Errorf("round trip changed %d bytes into %d", len(binaryPayload), len(decoded))
+			}
+		})
+	}
+}
+
+func TestFromBase64Targets(t *testing.T) {
+	wrapped := "aGVs\nbG8g\r\nd29y bGQ=\n"
+
+	var s string
+	if err := FromBase64(&s).FromReader(strings.NewReader(wrapped)); err != nil || s != "hello world" {
+		t.Errorf("string target = %q, %v", s, err)
+	}
+
+	var buf bytes.Buffer
+	if err := FromBase64(&buf).FromReader(strings.NewReader(wrapped)); err != nil || buf.String() != "hello world" {
+		t.Errorf("writer target = %q, %v", buf.String(), err)
+	}
+
+	var n int
+	if err := FromBase64(&n).FromReader(strings.NewReader(wrapped)); err == nil {
+		t.Error("unsupported target should fail")
+	}
+	if err := FromBase64(&s).FromReader(strings.NewReader("not base64!")); err == nil {
+		t.Error("invalid base64 should fail")
+	}
+}
+
+func TestToDataURI(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), binaryPayload...)
+
+	tests := []struct {
+		name       string
+		data       any
+		wantPrefix string
+	}{
+		{"sniffed bytes", png, "data:image/png;base64,"},
+		{"sniffed reader", bytes.NewReader(png), "data:image/png;base64,"},
+		{"sniffed text", "hello", "data:text/plain; charset=utf-8;base64,"},
+		{"explicit media type", DataURI{MediaType: "audio/wav", Data: png}, "data:audio/wav;base64,"},
+		{"pointer", &DataURI{Data: png}, "data:application/octet-stream;base64,"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri := readConverted(t, ToDataURI(tt.data))
+			if !strings.HasPrefix(uri, tt.wantPrefix) {
+				t.Fatalf("URI = %.40q..., want prefix %q", uri, tt.wantPrefix)
+			}
+
+			var decoded DataURI
+			if err := FromDataURI(&decoded).FromReader(strings.NewReader(uri)); err != nil {
+				t.Fatalf("FromDataURI() error = %v", err)
+			}
+			want := png
+			if text, ok := tt.data.(string); ok {
+				want = []byte(text)
+			}
+			if !bytes.Equal(decoded.Data, want) {
+				t.Errorf("decoded %d bytes, want %d", len(decoded.Data), len(want))
+			}
+		})
+	}
+
+	if _, err := ToDataURI(42).ToReader(); err == nil {
+		t.Error("unsupported input should fail")
+	}
+}
+
+func TestParseDataURI(t *testing.T) {
+	tests := []struct {
+		name      string
+		uri       string
+		wantType  string
+		wantData  string
+		wantError bool
+	}{
+		{name: "base64", uri: "data:image/gif;base64,R0lGODdh", wantType: "image/gif", wantData: "GIF87a"},
+		{name: "unpadded base64", uri: "data:text/plain;base64,aGk", wantType: "text/plain", wantData: "hi"},
+		{name: "uppercase scheme", uri: "DATA:text/plain;BASE64,aGk=", wantType: "text/plain", wantData: "hi"},
+		{name: "percent encoded", uri: "data:,Hello%2C%20World%21", wantType: defaultDataURIMediaType, wantData: "Hello, World!"},
+		{name: "parameters only", uri: "data:;charset=utf-8,caf%C3%A9", wantType: "text/plain;charset=utf-8", wantData: "café"},
+		{name: "not a data URI", uri: "https://example.com/a.png", wantError: true},
+		{name: "missing data", uri: "data:image/png;base64", wantError: true},
+		{name: "invalid media type", uri: "data:image/;base64,aGk=", wantError: true},
+		{name: "invalid base64", uri: "data:image/png;base64,@@@", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri, err := ParseDataURI(tt.uri)
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("ParseDataURI(%q) should fail", tt.uri)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDataURI(%q) error = %v", tt.uri, err)
+			}
+			if uri.MediaType != tt.wantType || string(uri.Data) != tt.wantData {
+				t.Errorf("ParseDataURI(%q) = %q %q, want %q %q", tt.uri, uri.MediaType, uri.Data, tt.wantType, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestDataURIJSON(t *testing.T) {
+	type envelope struct {
+		Name    string  `json:"name"`
+		Content DataURI `json:"content"`
+	}
+
+	in := envelope{Name: "chart.png", Content: DataURI{MediaType: "image/png", Data: binaryPayload}}
+	encoded, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !bytes.Contains(encoded, []byte(`"content":"data:image/png;base64,AAECAw`)) {
+		t.Errorf("encoded = %.80s...", encoded)
+	}
+
+	var out envelope
+	if err := json.Unmarshal(encoded, &out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if out.Content.MediaType != "image/png" || !bytes.Equal(out.Content.Data, binaryPayload) {
+		t.Errorf("decoded content = %q with %d bytes", out.Content.MediaType, len(out.Content.Data))
+	}
+}
@@ -0,0 +1,146 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// PartialUpdate reports progress while a JSON value streams in
+type PartialUpdate[T any] struct {
+	Path  string // Value that just completed, e.g. "name", "items[0]" or "items[0].title"; "" for the whole document
+	Value T      // Everything received so far, decoded into T
+	Done  bool   // True for the last update, once the whole document has arrived
+}
+
+// JSONStreamOutputConverter for streamed JSON -> progressive PartialUpdate events
+type JSONStreamOutputConverter[T any] struct {
+	updates chan<- PartialUpdate[T]
+}
+
+// FromJSONStream creates an output converter that reports structured output as it streams.
+//
+// Input: channel that receives updates; it is closed when decoding ends
+// Output: calque.OutputConverter for pipeline output position
+// Behavior: STREAMING - emits an update each time a field, array item or object completes
+//
+// Every update carries a snapshot of T decoded from the JSON received so far,
+// with unfinished containers closed, so a UI can render a result while the
+// model is still writing it. The final update has Done set and holds the
+// complete value. Sends block until received, so read the channel while the
+// flow runs.
+//
+// Example usage:
+//
+//	type Recipe struct {
+//		Name  string   `json:"name"`
+//		Steps []string `json:"steps"`
+//	}
+//
+//	updates := make(chan convert.PartialUpdate[Recipe])
+//	go func() {
+//		for u := range updates {
+//			render(u.Value) // "name", then "steps[0]", "steps[1]", ...
+//		}
+//	}()
+//	err := flow.Run(ctx, prompt, convert.FromJSONStream(updates))
+func FromJSONStream[T any](updates chan<- PartialUpdate[T]) calque.OutputConverter {
+	return &JSONStreamOutputConverter[T]{updates: updates}
+}
+
+// jsonFrame tracks position inside one open object or array
+type jsonFrame struct {
+	array     bool
+	index     int
+	key       string
+	expectKey bool
+}
+
+// FromReader implements outputConverter interface
+func (j *JSONStreamOutputConverter[T]) FromReader(reader io.Reader) error {
+	defer close(j.updates)
+	ctx := context.Background()
+
+	var received bytes.Buffer
+	decoder := json.NewDecoder(io.TeeReader(reader, &received))
+	var stack []jsonFrame
+
+	for {
+		tok, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return calque.NewErr(ctx, "JSON stream ended before the document was complete")
+		}
+		if err != nil {
+			return calque.WrapErr(ctx, err, "failed to parse streamed JSON")
+		}
+
+		if delim, ok := tok.(json.Delim); ok && (delim == '{' || delim == '[') {
+			stack = append(stack, jsonFrame{array: delim == '[', expectKey: delim == '{'})
+			continue
+		}
+		if delim, ok := tok.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+		} else if n := len(stack); n > 0 && stack[n-1].expectKey {
+			stack[n-1].key, _ = tok.(string)
+			stack[n-1].expectKey = false
+			continue
+		}
+
+		// A value just completed
+		update := PartialUpdate[T]{Path: jsonPath(stack), Done: len(stack) == 0}
+		snapshot := append(received.Bytes()[:decoder.InputOffset():decoder.InputOffset()], closers(stack)...)
+		if err := json.Unmarshal(snapshot, &update.Value); err != nil {
+			return calque.WrapErr(ctx, err, fmt.Sprintf("failed to decode partial JSON at %q", update.Path))
+		}
+		j.updates <- update
+
+		if update.Done {
+			// Drain anything after the document to prevent pipe deadlock
+			if _, err := io.Copy(io.Discard, reader); err != nil {
+				return calque.WrapErr(ctx, err, "failed to drain reader after JSON stream")
+			}
+			return nil
+		}
+		top := &stack[len(stack)-1]
+		if top.array {
+			top.index++
+		} else {
+			top.expectKey = true
+		}
+	}
+}
+
+// jsonPath renders the position of the value being completed, e.g. items[0].title
+func jsonPath(stack []jsonFrame) string {
+	var b strings.Builder
+	for _, f := range stack {
+		if f.array {
+			fmt.Fprintf(&b, "[%d]", f.index)
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(f.key)
+	}
+	return b.String()
+}
+
+// closers returns the brackets that close every open container, innermost first
+func closers(stack []jsonFrame) []byte {
+	out := make([]byte, 0, len(stack))
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].array {
+			out = append(out, ']')
+		} else {
+			out = append(out, '}')
+		}
+	}
+	return out
+}
@@ -0,0 +1,129 @@
+package convert
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+type streamRecipe struct {
+	Name  string       `json:"name"`
+	Steps []streamStep `json:"steps"`
+	Serve int          `json:"serves"`
+}
+
+type streamStep struct {
+	Title string `json:"title"`
+	Mins  int    `json:"mins"`
+}
+
+func collectUpdates[T any](t *testing.T, input string) ([]PartialUpdate[T], error) {
+	t.Helper()
+	updates := make(chan PartialUpdate[T])
+	var got []PartialUpdate[T]
+	done := make(chan struct{})
+	go func() {
+		for u := range updates {
+			got = append(got, u)
+		}
+		close(done)
+	}()
+	err := FromJSONStream(updates).FromReader(iotest.OneByteReader(strings.NewReader(input)))
+	<-done
+	return got, err
+}
+
+func TestFromJSONStream(t *testing.T) {
+	input := `{"name":"Soup","steps":[{"title":"Chop","mins":5},{"title":"Boil","mins":20}],"serves":4}`
+	got, err := collectUpdates[streamRecipe](t, input)
+	if err != nil {
+		t.Fatalf("FromReader() error = %v", err)
+	}
+
+	var paths []string
+	for _, u := range got {
+		paths = append(paths, u.Path)
+	}
+	wantPaths := []string{
+		"name",
+		"steps[0].title", "steps[0].mins", "steps[0]",
+		"steps[1].title", "steps[1].mins", "steps[1]",
+		"steps",
+		"serves",
+		"",
+	}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("paths = %q\nwant %q", paths, wantPaths)
+	}
+
+	// Snapshots grow as the document arrives
+	if v := got[0].Value; v.Name != "Soup" || len(v.Steps) != 0 {
+		t.Errorf("after name: %+v", v)
+	}
+	if v := got[1].Value; len(v.Steps) != 1 || v.Steps[0].Title != "Chop" || v.Steps[0].Mins != 0 {
+		t.Errorf("after steps[0].title: %+v", v)
+	}
+
+	last := got[len(got)-1]
+	want := streamRecipe{Name: "Soup", Steps: []streamStep{{"Chop", 5}, {"Boil", 20}}, Serve: 4}
+	if !last.Done || !reflect.DeepEqual(last.Value, want) {
+		t.Errorf("final = %+v (done %v), want %+v", last.Value, last.Done, want)
+	}
+	for _, u := range got[:len(got)-1] {
+		if u.Done {
+			t.Errorf("update %q marked done before the end", u.Path)
+		}
+	}
+}
+
+func TestFromJSONStream_ArrayRoot(t *testing.T) {
+	got, err := collectUpdates[[]string](t, `["a", "b"] trailing`)
+	if err != nil {
+		t.Fatalf("FromReader() error = %v", err)
+	}
+	if len(got) != 3 || got[0].Path != "[0]" || got[1].Path != "[1]" || !got[2].Done {
+		t.Fatalf("updates = %+v", got)
+	}
+	if !reflect.DeepEqual(got[0].Value, []string{"a"}) {
+		t.Errorf("first snapshot = %v", got[0].Value)
+	}
+}
+
+func TestFromJSONStream_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "truncated", input: `{"name":"Sou`, wantErr: "failed to parse"},
+		{name: "ends between values", input: `{"name":"Soup",`, wantErr: "before the document was complete"},
+		{name: "type mismatch", input: `{"name":5}`, wantErr: `partial JSON at "name"`},
+		{name: "not JSON", input: `hello`, wantErr: "failed to parse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := collectUpdates[streamRecipe](t, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFromJSONStream_InFlow(t *testing.T) {
+	updates := make(chan PartialUpdate[streamStep], 8)
+	if err := calque.NewFlow().Run(context.Background(), `{"title":"Chop","mins":5}`, FromJSONStream(updates)); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	var last PartialUpdate[streamStep]
+	for u := range updates {
+		last = u
+	}
+	if !last.Done || last.Value.Title != "Chop" || last.Value.Mins != 5 {
+		t.Errorf("final update = %+v", last)
+	}
+}
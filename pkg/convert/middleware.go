@@ -0,0 +1,61 @@
+package convert
+
+import (
+	"context"
+	"io"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Middleware runs a typed function mid-pipeline, using converters to decode
+// its input and encode its result.
+//
+// Input: data in the format read by from, e.g. JSON for FromJSON
+// Output: the function's result in the format written by to, e.g. YAML for ToYAML
+// Behavior: BUFFERED - decodes the whole input before calling fn
+//
+// Converters normally sit at the Run boundaries. Middleware lets a segment
+// between two stages work on Go values instead of bytes, without splitting
+// the pipeline into several flows. from and to take the converter
+// constructors themselves (FromJSON, FromYAML, FromJSONSchema[T], ToJSON,
+// ToYAML, ...); Middleware creates a fresh target for every request.
+//
+// Example:
+//
+//	type Ticket struct {
+//		Title    string `json:"title"`
+//		Priority string `json:"priority"`
+//	}
+//
+//	flow := calque.NewFlow().
+//		Use(ai.Agent(client, ai.WithSchema(&Ticket{}))).
+//		Use(convert.Middleware(convert.FromJSON, convert.ToJSON,
+//			func(_ context.Context, t Ticket) (Ticket, error) {
+//				t.Priority = strings.ToUpper(t.Priority)
+//				return t, nil
+//			})).
+//		Use(ai.Agent(client))
+func Middleware[In, Out any](from func(target any) calque.OutputConverter, to func(data any) calque.InputConverter, fn func(ctx context.Context, in In) (Out, error)) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var in In
+		if err := from(&in).FromReader(req.Data); err != nil {
+			return calque.WrapErr(req.Context, err, "failed to decode middleware input")
+		}
+		// Decoders may stop after the first value; drain the rest so upstream handlers finish
+		if _, err := io.Copy(io.Discard, req.Data); err != nil {
+			return err
+		}
+
+		out, err := fn(req.Context, in)
+		if err != nil {
+			return err
+		}
+
+		reader, err := to(out).ToReader()
+		if err != nil {
+			return calque.WrapErr(req.Context, err, "failed to encode middleware output")
+		}
+		_, err = io.Copy(res.Data, reader)
+		return err
+	})
+}
@@ -0,0 +1,94 @@
+package convert
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+type middlewareTicket struct {
+	Title    string `json:"title" yaml:"title"`
+	Priority string `json:"priority" yaml:"priority"`
+}
+
+func upperPriority(_ context.Context, t middlewareTicket) (middlewareTicket, error) {
+	t.Priority = strings.ToUpper(t.Priority)
+	return t, nil
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler calque.Handler
+		input   string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "json to json",
+			handler: Middleware(FromJSON, ToJSON, upperPriority),
+			input:   `{"title":"login broken","priority":"high"}`,
+			want:    `{"title":"login broken","priority":"HIGH"}`,
+		},
+		{
+			name:    "json to yaml",
+			handler: Middleware(FromJSON, ToYAML, upperPriority),
+			input:   `{"title":"login broken","priority":"high"}`,
+			want:    "title: login broken\npriority: HIGH",
+		},
+		{
+			name: "different output type",
+			handler: Middleware(FromJSON, ToJSON, func(_ context.Context, t middlewareTicket) ([]string, error) {
+				return []string{t.Title, t.Priority}, nil
+			}),
+			input: `{"title":"a","priority":"b"}`,
+			want:  `["a","b"]`,
+		},
+		{
+			name:    "undecodable input",
+			handler: Middleware(FromJSON, ToJSON, upperPriority),
+			input:   `not json`,
+			wantErr: "failed to decode middleware input",
+		},
+		{
+			name: "function error",
+			handler: Middleware(FromJSON, ToJSON, func(context.Context, middlewareTicket) (middlewareTicket, error) {
+				return middlewareTicket{}, errors.New("rejected")
+			}),
+			input:   `{"title":"a"}`,
+			wantErr: "rejected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flow := calque.NewFlow().
+				UseFunc(func(req *calque.Request, res *calque.Response) error {
+					var in string
+					if err := calque.Read(req, &in); err != nil {
+						return err
+					}
+					return calque.Write(res, in)
+				}).
+				Use(tt.handler)
+
+			var out string
+			err := flow.Run(context.Background(), tt.input, &out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(out) != tt.want {
+				t.Errorf("output = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/invopop/jsonschema"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Migration decodes JSON written for an older struct version and upgrades it.
+//
+// Create one with Migrate, or MigrateFrom to chain versions, and use From as
+// the output converter wherever stored payloads are decoded.
+type Migration[Old, New any] struct {
+	migrate   func(Old) (New, error)
+	decodeOld func([]byte) (Old, error)
+
+	oldOnly     map[string]bool // Fields only the old version has
+	newRequired []string        // Required fields the old version doesn't have
+}
+
+// Migrate creates a converter that accepts payloads in either the Old or the New format.
+//
+// Input: migrator upgrading an Old value to New
+// Output: *Migration whose From method returns the output converter
+// Behavior: BUFFERED - reads the whole payload to detect its version
+//
+// Structured outputs persisted in memory, cache or idempotency stores outlive
+// the structs they were written from. The migration compares the JSON
+// schemas of Old and New: payloads carrying fields only Old has, or missing
+// fields New requires, are decoded as Old and passed through the migrator.
+// Everything else is decoded as New, falling back to Old if it doesn't fit,
+// e.g. when a field kept its name but changed type.
+//
+// Example:
+//
+//	type TicketV1 struct {
+//		Title    string `json:"title"`
+//		Assignee string `json:"assignee"`
+//	}
+//	type Ticket struct {
+//		Title     string   `json:"title"`
+//		Assignees []string `json:"assignees"`
+//	}
+//
+//	upgrade := convert.Migrate(func(old TicketV1) (Ticket, error) {
+//		return Ticket{Title: old.Title, Assignees: []string{old.Assignee}}, nil
+//	})
+//
+//	var ticket Ticket
+//	err := flow.Run(ctx, cachedPayload, upgrade.From(&ticket))
+func Migrate[Old, New any](migrator func(Old) (New, error)) *Migration[Old, New] {
+	return newMigration(migrator, decodeStrict[Old])
+}
+
+// MigrateFrom chains a migration onto an earlier one, so payloads from any
+// previous version are upgraded step by step to New.
+//
+// Example:
+//
+//	v1to2 := convert.Migrate(upgradeV1)
+//	v2to3 := convert.MigrateFrom(v1to2, upgradeV2)
+//	err := flow.Run(ctx, payload, v2to3.From(&ticketV3))
+func MigrateFrom[Older, Old, New any](previous *Migration[Older, Old], migrator func(Old) (New, error)) *Migration[Old, New] {
+	return newMigration(migrator, func(data []byte) (Old, error) {
+		old, _, err := previous.Decode(data)
+		return old, err
+	})
+}
+
+func newMigration[Old, New any](migrator func(Old) (New, error), decodeOld func([]byte) (Old, error)) *Migration[Old, New] {
+	var old Old
+	var current New
+	oldProps, _ := schemaFields(old)
+	newProps, newRequired := schemaFields(current)
+
+	m := &Migration[Old, New]{migrate: migrator, decodeOld: decodeOld, oldOnly: map[string]bool{}}
+	for name := range oldProps {
+		if !newProps[name] {
+			m.oldOnly[name] = true
+		}
+	}
+	for _, name := range newRequired {
+		if !oldProps[name] {
+			m.newRequired = append(m.newRequired, name)
+		}
+	}
+	return m
+}
+
+// schemaFields returns the top-level JSON properties and required fields of a struct type
+func schemaFields(v any) (map[string]bool, []string) {
+	reflector := jsonschema.Reflector{ExpandedStruct: true, DoNotReference: true}
+	schema := reflector.Reflect(v)
+	props := map[string]bool{}
+	if schema.Properties != nil {
+		for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+			props[pair.Key] = true
+		}
+	}
+	return props, schema.Required
+}
+
+// looksOld reports whether a payload's fields match the old schema rather than the new one
+func (m *Migration[Old, New]) looksOld(data []byte) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return false
+	}
+	for name := range fields {
+		if m.oldOnly[name] {
+			return true
+		}
+	}
+	for _, name := range m.newRequired {
+		if _, ok := fields[name]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Decode parses a payload in either format, reporting whether it was migrated.
+// Callers holding the payload in a store can write the upgraded value back.
+//
+// Example:
+//
+//	ticket, migrated, err := upgrade.Decode(data)
+//	if err == nil && migrated {
+//		store.Set(key, mustMarshal(ticket), ttl)
+//	}
+func (m *Migration[Old, New]) Decode(data []byte) (New, bool, error) {
+	var current New
+	var newErr error
+	if !m.looksOld(data) {
+		if current, newErr = decodeStrict[New](data); newErr == nil {
+			return current, false, nil
+		}
+	}
+
+	old, oldErr := m.decodeOld(data)
+	if oldErr != nil {
+		return *new(New), false, errors.Join(newErr, oldErr)
+	}
+	upgraded, err := m.migrate(old)
+	if err != nil {
+		return *new(New), false, fmt.Errorf("migrating %T to %T: %w", old, current, err)
+	}
+	return upgraded, true, nil
+}
+
+// decodeStrict decodes data into T, rejecting fields T doesn't have
+func decodeStrict[T any](data []byte) (T, error) {
+	var v T
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&v)
+	return v, err
+}
+
+// From returns an output converter decoding into target, upgrading old payloads
+func (m *Migration[Old, New]) From(target *New) calque.OutputConverter {
+	return &migrationOutputConverter[Old, New]{migration: m, target: target}
+}
+
+// migrationOutputConverter adapts a Migration to calque.OutputConverter
+type migrationOutputConverter[Old, New any] struct {
+	migration *Migration[Old, New]
+	target    *New
+}
+
+// FromReader implements calque.OutputConverter
+func (c *migrationOutputConverter[Old, New]) FromReader(reader io.Reader) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return calque.WrapErr(context.Background(), err, "failed to read payload")
+	}
+	value, _, err := c.migration.Decode(data)
+	if err != nil {
+		return calque.WrapErr(context.Background(), err, "failed to decode payload in any known version")
+	}
+	*c.target = value
+	return nil
+}
@@ -0,0 +1,116 @@
+package convert
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+type ticketV1 struct {
+	Title    string `json:"title"`
+	Assignee string `json:"assignee"`
+}
+
+type ticketV2 struct {
+	Title     string   `json:"title"`
+	Assignees []string `json:"assignees"`
+}
+
+type ticketV3 struct {
+	Title     string   `json:"title"`
+	Assignees []string `json:"assignees"`
+	Priority  int      `json:"priority"`
+}
+
+func upgradeV1(old ticketV1) (ticketV2, error) {
+	if old.Title == "" {
+		return ticketV2{}, errors.New("title is required")
+	}
+	return ticketV2{Title: old.Title, Assignees: []string{old.Assignee}}, nil
+}
+
+func upgradeV2(old ticketV2) (ticketV3, error) {
+	return ticketV3{Title: old.Title, Assignees: old.Assignees, Priority: 3}, nil
+}
+
+func TestMigrationDecode(t *testing.T) {
+	migration := Migrate(upgradeV1)
+
+	tests := []struct {
+		name         string
+		payload      string
+		want         ticketV2
+		wantMigrated bool
+		wantErr      string
+	}{
+		{"current format", `{"title":"a","assignees":["x","y"]}`, ticketV2{Title: "a", Assignees: []string{"x", "y"}}, false, ""},
+		{"old-only field", `{"title":"a","assignee":"x"}`, ticketV2{Title: "a", Assignees: []string{"x"}}, true, ""},
+		{"missing required new field", `{"title":"a"}`, ticketV2{Title: "a", Assignees: []string{""}}, true, ""},
+		{"migrator error", `{"assignee":"x"}`, ticketV2{}, false, "title is required"},
+		{"neither format", `{"name":"a"}`, ticketV2{}, false, "unknown field"},
+		{"not json", `nope`, ticketV2{}, false, "invalid character"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, migrated, err := migration.Decode([]byte(tt.payload))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Decode() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) || migrated != tt.wantMigrated {
+				t.Errorf("Decode() = %+v (migrated %v), want %+v (migrated %v)", got, migrated, tt.want, tt.wantMigrated)
+			}
+		})
+	}
+}
+
+func TestMigrationTypeChange(t *testing.T) {
+	type countV1 struct {
+		Count string `json:"count"`
+	}
+	type countV2 struct {
+		Count int `json:"count"`
+	}
+	migration := Migrate(func(old countV1) (countV2, error) {
+		return countV2{Count: len(old.Count)}, nil
+	})
+
+	got, migrated, err := migration.Decode([]byte(`{"count":"abc"}`))
+	if err != nil || got.Count != 3 || !migrated {
+		t.Errorf("Decode() = %+v, %v, %v; want migrated count 3", got, migrated, err)
+	}
+}
+
+func TestMigrateFromChain(t *testing.T) {
+	chain := MigrateFrom(Migrate(upgradeV1), upgradeV2)
+
+	for _, payload := range []string{
+		`{"title":"a","assignee":"x"}`,
+		`{"title":"a","assignees":["x"]}`,
+	} {
+		var ticket ticketV3
+		if err := calque.NewFlow().Run(context.Background(), payload, chain.From(&ticket)); err != nil {
+			t.Fatalf("Run(%s) error = %v", payload, err)
+		}
+		want := ticketV3{Title: "a", Assignees: []string{"x"}, Priority: 3}
+		if !reflect.DeepEqual(ticket, want) {
+			t.Errorf("Run(%s) = %+v, want %+v", payload, ticket, want)
+		}
+	}
+
+	var ticket ticketV3
+	err := calque.NewFlow().Run(context.Background(), `{"title":"a","assignees":["x"],"priority":1}`, chain.From(&ticket))
+	if err != nil || ticket.Priority != 1 {
+		t.Errorf("current payload = %+v, %v; want it decoded unchanged", ticket, err)
+	}
+}
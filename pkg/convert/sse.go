@@ -1,14 +1,18 @@
 package convert
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"maps"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
 )
@@ -60,8 +64,17 @@ const (
 	SSEChunkByLine // Stream line by line
 	// SSEChunkNone streams entire response as single event
 	SSEChunkNone // Stream entire response as single event
+	// SSEChunkByToken streams approximate LLM-style tokens (words with leading whitespace, punctuation)
+	SSEChunkByToken // Stream token by token
+	// SSEChunkBySentence streams complete sentences, flushing on terminal punctuation or newlines
+	SSEChunkBySentence // Stream sentence by sentence
+	// SSEChunkByBytes streams fixed-size byte windows without splitting UTF-8 characters
+	SSEChunkByBytes // Stream fixed byte windows (see WithChunkSize)
 )
 
+// DefaultSSEChunkSize is the byte window used by SSEChunkByBytes when no size is configured.
+const DefaultSSEChunkSize = 64
+
 // RawContentFormatter sends content directly without wrapping (default).
 //
 // Input: content string, done flag (ignored)
@@ -139,6 +152,7 @@ func ToSSE(w http.ResponseWriter) *SSEConverter {
 		writer:           w,
 		flusher:          flusher,
 		chunkBy:          SSEChunkByWord,
+		chunkSize:        DefaultSSEChunkSize,
 		formatter:        RawContentFormatter, // Default: send raw content
 		eventFields:      nil,                 // No additional fields by default
 		keepAliveEnabled: false,               // Keep-alive disabled by default
@@ -160,12 +174,22 @@ type SSEConverter struct {
 	chunkBy     SSEChunkMode
 	formatter   SSEEventFormatter // RawContentFormatter or MapEventFormatter
 	eventFields map[string]any    // Additional fields to include in events
+	chunkSize   int               // Byte window for SSEChunkByBytes
 
 	// Keep-alive configuration
 	keepAliveInterval time.Duration
 	keepAliveEnabled  bool
 	keepAliveCancel   context.CancelFunc
-	mu                sync.Mutex
+	heartbeatIdleOnly bool      // Only send keep-alive comments when the stream is idle
+	lastWrite         time.Time // Time of the last event written
+
+	// Event IDs and replay configuration
+	eventIDs bool           // Emit monotonically increasing "id:" fields
+	lastID   int64          // Last event ID written
+	replay   SSEReplayStore // Optional store recording events for Last-Event-ID resume
+	streamID string         // Stream key used with the replay store
+	seeded   bool           // lastID continues from the replay store's highest ID
+	mu       sync.Mutex
 }
 
 // Close forcefully terminates the SSE connection and releases resources.
@@ -248,6 +272,20 @@ func (s *SSEConverter) WithChunkMode(mode SSEChunkMode) *SSEConverter {
 	return s
 }
 
+// WithChunkSize sets the byte window used by SSEChunkByBytes.
+//
+// Input: maximum bytes per event (values below 4 are raised to 4)
+// Output: *SSEConverter for chaining
+// Behavior: Configures window size; multi-byte UTF-8 characters are never split
+//
+// Example:
+//
+//	sse.WithChunkMode(convert.SSEChunkByBytes).WithChunkSize(256)
+func (s *SSEConverter) WithChunkSize(size int) *SSEConverter {
+	s.chunkSize = max(size, utf8.UTFMax)
+	return s
+}
+
 // WithEventFields sets additional fields to include in each event.
 //
 // Input: map of additional fields
@@ -292,6 +330,29 @@ func (s *SSEConverter) WithKeepAlive(interval time.Duration) *SSEConverter {
 	return s
 }
 
+// WithHeartbeat enables heartbeat comments that are only sent while the stream is idle.
+//
+// Input: idle interval after which a heartbeat comment is sent
+// Output: *SSEConverter for chaining
+// Behavior: Sends ": heartbeat" comments when no event was written for the interval
+//
+// Unlike WithKeepAlive, heartbeats are suppressed while chunks are flowing, so
+// they only add traffic when an upstream provider stalls (long tool calls,
+// slow first token) and a proxy would otherwise close the idle connection.
+//
+// Example:
+//
+//	sse.WithHeartbeat(15 * time.Second)
+func (s *SSEConverter) WithHeartbeat(interval time.Duration) *SSEConverter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keepAliveEnabled = true
+	s.keepAliveInterval = interval
+	s.heartbeatIdleOnly = true
+	return s
+}
+
 // FromReader implements OutputConverter interface for streaming SSE responses.
 //
 // Input: io.Reader data source
@@ -321,6 +382,12 @@ func (s *SSEConverter) FromReader(reader io.Reader) error {
 		return s.streamByLine(reader)
 	case SSEChunkNone:
 		return s.streamComplete(reader)
+	case SSEChunkByToken:
+		return s.streamRunes(reader, tokenChunker())
+	case SSEChunkBySentence:
+		return s.streamRunes(reader, sentenceChunker())
+	case SSEChunkByBytes:
+		return s.streamRunes(reader, byteWindowChunker(s.chunkSize))
 	default:
 		return s.streamByWord(reader)
 	}
@@ -361,6 +428,17 @@ func (s *SSEConverter) sendKeepAlive() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.heartbeatIdleOnly {
+		if time.Since(s.lastWrite) < s.keepAliveInterval {
+			return nil
+		}
+		if _, err := fmt.Fprintf(s.writer, ": heartbeat\n\n"); err != nil {
+			return err
+		}
+		s.flusher.Flush()
+		return nil
+	}
+
 	// Send keep-alive as SSE comment
 	if _, err := fmt.Fprintf(s.writer, ": keep-alive\n\n"); err != nil {
 		return err
@@ -510,6 +588,109 @@ func (s *SSEConverter) streamComplete(reader io.Reader) error {
 	return s.sendCompletion()
 }
 
+// runeChunker decides where chunk boundaries fall while re-chunking a stream.
+// flushBefore emits the pending chunk before r is appended, flushAfter emits it including r.
+type runeChunker func(chunk []byte, r rune) (flushBefore, flushAfter bool)
+
+// streamRunes re-chunks the stream rune by rune, independent of upstream read boundaries
+func (s *SSEConverter) streamRunes(reader io.Reader, chunker runeChunker) error {
+	br := bufio.NewReader(reader)
+	var chunk []byte
+
+	for {
+		r, size, err := br.ReadRune()
+		if size > 0 {
+			flushBefore, flushAfter := chunker(chunk, r)
+			if flushBefore && len(chunk) > 0 {
+				if sendErr := s.sendChunk(string(chunk)); sendErr != nil {
+					return sendErr
+				}
+				chunk = chunk[:0]
+			}
+
+			chunk = utf8.AppendRune(chunk, r)
+
+			if flushAfter {
+				if sendErr := s.sendChunk(string(chunk)); sendErr != nil {
+					return sendErr
+				}
+				chunk = chunk[:0]
+			}
+		}
+
+		if err == io.EOF {
+			if len(chunk) > 0 {
+				if sendErr := s.sendChunk(string(chunk)); sendErr != nil {
+					return sendErr
+				}
+			}
+			return s.sendCompletion()
+		}
+
+		if err != nil {
+			return s.sendError(err)
+		}
+	}
+}
+
+// tokenChunker splits on word and punctuation boundaries, attaching leading whitespace
+// to the following word the way BPE tokenizers do (" hello", " world", "!")
+func tokenChunker() runeChunker {
+	return func(chunk []byte, r rune) (bool, bool) {
+		if len(chunk) == 0 {
+			return false, false
+		}
+		prev, _ := utf8.DecodeLastRune(chunk)
+
+		switch {
+		case unicode.IsSpace(r):
+			return !unicode.IsSpace(prev), false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return !(unicode.IsSpace(prev) || unicode.IsLetter(prev) || unicode.IsDigit(prev)), false
+		default:
+			// Punctuation is its own token unless it directly follows whitespace
+			return !unicode.IsSpace(prev), false
+		}
+	}
+}
+
+// sentenceChunker flushes after terminal punctuation followed by whitespace, and on newlines
+func sentenceChunker() runeChunker {
+	pendingEnd := false
+
+	return func(_ []byte, r rune) (bool, bool) {
+		switch {
+		case r == '\n':
+			pendingEnd = false
+			return false, true
+		case r == '.' || r == '!' || r == '?':
+			pendingEnd = true
+		case unicode.IsSpace(r):
+			if pendingEnd {
+				pendingEnd = false
+				return false, true
+			}
+		case r == '"' || r == '\'' || r == ')' || r == '”' || r == '’':
+			// Closing quotes and brackets stay attached to the sentence end
+		default:
+			// Decimal points and abbreviations like "e.g." continue the sentence
+			pendingEnd = false
+		}
+		return false, false
+	}
+}
+
+// byteWindowChunker flushes before a rune would push the chunk past size bytes
+func byteWindowChunker(size int) runeChunker {
+	if size < utf8.UTFMax {
+		size = DefaultSSEChunkSize
+	}
+
+	return func(chunk []byte, r rune) (bool, bool) {
+		return len(chunk)+utf8.RuneLen(r) > size, false
+	}
+}
+
 // sendChunk sends a data chunk as an SSE event
 func (s *SSEConverter) sendChunk(content string) error {
 	eventData := s.formatter(content, false)
@@ -551,6 +732,26 @@ func (s *SSEConverter) WriteError(err error) error {
 	return s.sendError(err)
 }
 
+// ProgressReporter returns a calque.ProgressFunc that forwards progress events to the client.
+//
+// Input: none
+// Output: calque.ProgressFunc for use with calque.WithProgress
+// Behavior: Sends each progress update as a "progress" SSE event
+//
+// Write errors are ignored since progress is advisory; the main stream
+// surfaces connection failures on its next write.
+//
+// Example:
+//
+//	sse := convert.ToSSE(w)
+//	ctx := calque.WithProgress(r.Context(), sse.ProgressReporter())
+//	flow.Run(ctx, input, sse)
+func (s *SSEConverter) ProgressReporter() calque.ProgressFunc {
+	return func(ev calque.ProgressEvent) {
+		_ = s.writeSSEEvent("progress", ev)
+	}
+}
+
 // writeSSEEvent writes an SSE event to the response writer
 func (s *SSEConverter) writeSSEEvent(event string, data any) error {
 	jsonData, err := json.Marshal(data)
@@ -561,10 +762,46 @@ func (s *SSEConverter) writeSSEEvent(event string, data any) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, err = fmt.Fprintf(s.writer, "event: %s\ndata: %s\n\n", event, jsonData)
+	// Continue the stream's IDs so earlier responses sharing it aren't replayed as this one
+	if s.replay != nil && !s.seeded {
+		last, err := s.replay.LastID(context.Background(), s.streamID)
+		if err != nil {
+			return calque.WrapErr(context.Background(), err, "failed to read SSE replay stream")
+		}
+		s.lastID = max(s.lastID, last)
+		s.seeded = true
+	}
+
+	var id string
+	if s.eventIDs {
+		s.lastID++
+		id = strconv.FormatInt(s.lastID, 10)
+	}
+
+	// Record before writing so a client that drops mid-write can still replay the event
+	if s.replay != nil {
+		ev := SSEEvent{Event: event, Data: json.RawMessage(jsonData), ID: id}
+		if err := s.replay.Append(context.Background(), s.streamID, ev); err != nil {
+			return calque.WrapErr(context.Background(), err, "failed to record SSE event for replay")
+		}
+	}
+
+	return s.writeFrame(id, event, jsonData)
+}
+
+// writeFrame writes a single SSE frame, caller must hold s.mu
+func (s *SSEConverter) writeFrame(id, event string, jsonData []byte) error {
+	if id != "" {
+		if _, err := fmt.Fprintf(s.writer, "id: %s\n", id); err != nil {
+			return calque.WrapErr(context.Background(), err, "failed to write SSE event")
+		}
+	}
+
+	_, err := fmt.Fprintf(s.writer, "event: %s\ndata: %s\n\n", event, jsonData)
 	if err != nil {
 		return calque.WrapErr(context.Background(), err, "failed to write SSE event")
 	}
+	s.lastWrite = time.Now()
 
 	s.flusher.Flush()
 	return nil
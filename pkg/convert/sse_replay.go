@@ -0,0 +1,281 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultSSEReplayPoll is how often Resume checks the replay store for new events.
+const DefaultSSEReplayPoll = 50 * time.Millisecond
+
+// SSEReplayStore records SSE events so reconnecting clients can resume a stream.
+//
+// Events are appended with numeric, monotonically increasing IDs. Events returns
+// every stored event for the stream with an ID greater than afterID, in order,
+// and LastID the highest ID stored for the stream (0 when it has none).
+// Implementations must be safe for concurrent use; the in-memory SSEReplayBuffer
+// covers single-instance deployments, and redisstore.SSEReplay shares streams
+// between instances.
+type SSEReplayStore interface {
+	Append(ctx context.Context, streamID string, event SSEEvent) error
+	Events(ctx context.Context, streamID string, afterID int64) ([]SSEEvent, error)
+	LastID(ctx context.Context, streamID string) (int64, error)
+}
+
+// WithEventIDs enables monotonically increasing event IDs.
+//
+// Input: none
+// Output: *SSEConverter for chaining
+// Behavior: Prefixes every event with an "id:" field starting at 1
+//
+// Browsers' EventSource automatically sends the last received ID in the
+// Last-Event-ID header when reconnecting.
+//
+// Example:
+//
+//	sse := convert.ToSSE(w).WithEventIDs()
+func (s *SSEConverter) WithEventIDs() *SSEConverter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.eventIDs = true
+	return s
+}
+
+// WithReplay records every event in a replay store under the given stream ID.
+//
+// Input: replay store, stream identifier shared by the original and resumed requests
+// Output: *SSEConverter for chaining
+// Behavior: Enables event IDs and appends each event to the store before writing it
+//
+// Event IDs continue from the highest ID already stored for the stream, so
+// successive responses can share a stream ID, such as the turns of one
+// conversation, and a client resuming with its Last-Event-ID only receives
+// events it has not seen. Write one response to a stream at a time. Resuming
+// without an ID replays from the oldest event the store still holds.
+//
+// Example:
+//
+//	replay := convert.NewSSEReplayBuffer(1000, 5*time.Minute)
+//
+//	sse := convert.ToSSE(w).WithReplay(replay, conversationID)
+//	if lastID := convert.LastEventID(r); lastID != "" {
+//		return sse.Resume(r.Context(), lastID)
+//	}
+//	return flow.Run(r.Context(), input, sse)
+func (s *SSEConverter) WithReplay(store SSEReplayStore, streamID string) *SSEConverter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.eventIDs = true
+	s.replay = store
+	s.streamID = streamID
+	return s
+}
+
+// Resume replays events missed since lastEventID and follows the stream until it completes.
+//
+// Input: context for cancellation, last event ID received by the client
+// Output: error if the replay store fails or the context is cancelled
+// Behavior: STREAMING - writes stored events, then polls for new ones
+//
+// Resume returns once a completion or error event has been replayed. If the
+// producing request is still running, new events are picked up as they are
+// recorded. Cancel the context to stop following a stream that never completes.
+//
+// Example:
+//
+//	sse := convert.ToSSE(w).WithReplay(replay, streamID)
+//	err := sse.Resume(r.Context(), r.Header.Get("Last-Event-ID"))
+func (s *SSEConverter) Resume(ctx context.Context, lastEventID string) error {
+	if s.replay == nil {
+		return calque.NewErr(ctx, "SSE resume requires a replay store (use WithReplay)")
+	}
+
+	after := int64(0)
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			return calque.WrapErr(ctx, err, "invalid Last-Event-ID")
+		}
+		after = id
+	}
+
+	ticker := time.NewTicker(DefaultSSEReplayPoll)
+	defer ticker.Stop()
+
+	for {
+		events, err := s.replay.Events(ctx, s.streamID, after)
+		if err != nil {
+			return calque.WrapErr(ctx, err, "failed to read SSE replay events")
+		}
+
+		for _, ev := range events {
+			done, err := s.replayEvent(ev)
+			if err != nil {
+				return err
+			}
+			if id, err := strconv.ParseInt(ev.ID, 10, 64); err == nil {
+				after = id
+			}
+			if done {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// replayEvent writes a stored event without recording it again
+func (s *SSEConverter) replayEvent(ev SSEEvent) (bool, error) {
+	jsonData, err := json.Marshal(ev.Data)
+	if err != nil {
+		return false, calque.WrapErr(context.Background(), err, "failed to marshal SSE data")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, err := strconv.ParseInt(ev.ID, 10, 64); err == nil && id > s.lastID {
+		s.lastID = id
+	}
+
+	if err := s.writeFrame(ev.ID, ev.Event, jsonData); err != nil {
+		return false, err
+	}
+	return ev.Event == "completion" || ev.Event == "error", nil
+}
+
+// LastEventID returns the client's last received event ID.
+//
+// Reads the Last-Event-ID header sent by EventSource on reconnect, falling back
+// to the "lastEventId" query parameter used by polyfills that cannot set headers.
+func LastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("lastEventId")
+}
+
+// SSEReplayBuffer is a bounded in-memory SSEReplayStore.
+//
+// Keeps the most recent maxEvents events per stream and drops streams that
+// have not been written to within the TTL.
+//
+// Example:
+//
+//	replay := convert.NewSSEReplayBuffer(500, 2*time.Minute)
+type SSEReplayBuffer struct {
+	maxEvents int
+	ttl       time.Duration
+	mu        sync.Mutex
+	streams   map[string]*replayStream
+}
+
+type replayStream struct {
+	events  []SSEEvent
+	updated time.Time
+}
+
+// NewSSEReplayBuffer creates an in-memory replay buffer.
+//
+// Input: maximum events kept per stream, idle TTL after which a stream is dropped
+// Output: *SSEReplayBuffer
+// Behavior: Non-positive values default to 1000 events and 5 minutes
+func NewSSEReplayBuffer(maxEvents int, ttl time.Duration) *SSEReplayBuffer {
+	if maxEvents <= 0 {
+		maxEvents = 1000
+	}
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return &SSEReplayBuffer{
+		maxEvents: maxEvents,
+		ttl:       ttl,
+		streams:   make(map[string]*replayStream),
+	}
+}
+
+// Append implements SSEReplayStore.
+func (b *SSEReplayBuffer) Append(_ context.Context, streamID string, event SSEEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.evictExpired(now)
+
+	stream, ok := b.streams[streamID]
+	if !ok {
+		stream = &replayStream{}
+		b.streams[streamID] = stream
+	}
+
+	stream.events = append(stream.events, event)
+	if over := len(stream.events) - b.maxEvents; over > 0 {
+		stream.events = append(stream.events[:0:0], stream.events[over:]...)
+	}
+	stream.updated = now
+	return nil
+}
+
+// Events implements SSEReplayStore.
+func (b *SSEReplayBuffer) Events(_ context.Context, streamID string, afterID int64) ([]SSEEvent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stream, ok := b.streams[streamID]
+	if !ok {
+		return nil, nil
+	}
+
+	var events []SSEEvent
+	for _, ev := range stream.events {
+		id, err := strconv.ParseInt(ev.ID, 10, 64)
+		if err != nil || id <= afterID {
+			continue
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// LastID implements SSEReplayStore.
+func (b *SSEReplayBuffer) LastID(_ context.Context, streamID string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stream, ok := b.streams[streamID]
+	if !ok || len(stream.events) == 0 {
+		return 0, nil
+	}
+	id, _ := strconv.ParseInt(stream.events[len(stream.events)-1].ID, 10, 64)
+	return id, nil
+}
+
+// Delete removes a stream's recorded events.
+func (b *SSEReplayBuffer) Delete(streamID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.streams, streamID)
+}
+
+// evictExpired drops idle streams, caller must hold b.mu
+func (b *SSEReplayBuffer) evictExpired(now time.Time) {
+	for id, stream := range b.streams {
+		if now.Sub(stream.updated) > b.ttl {
+			delete(b.streams, id)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package convert
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEConverter_WithEventIDs(t *testing.T) {
+	mock := newMockResponseWriter()
+	sse := ToSSE(mock).WithEventIDs()
+
+	if err := sse.FromReader(strings.NewReader("one two")); err != nil {
+		t.Fatalf("FromReader() error = %v", err)
+	}
+
+	body := mock.Body.String()
+	for _, id := range []string{"id: 1\n", "id: 2\n", "id: 3\n"} {
+		if !strings.Contains(body, id) {
+			t.Errorf("expected %q in body %q", id, body)
+		}
+	}
+
+	// Event parsing still works with id lines present
+	if events := parseSSEEvents(t, body); len(events) != 3 {
+		t.Errorf("expected 3 events, got %d", len(events))
+	}
+}
+
+func TestSSEConverter_Resume(t *testing.T) {
+	replay := NewSSEReplayBuffer(100, time.Minute)
+
+	original := newMockResponseWriter()
+	if err := ToSSE(original).WithReplay(replay, "stream-1").FromReader(strings.NewReader("alpha beta gamma")); err != nil {
+		t.Fatalf("FromReader() error = %v", err)
+	}
+
+	resumed := newMockResponseWriter()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sse := ToSSE(resumed).WithReplay(replay, "stream-1")
+	if err := sse.Resume(ctx, "1"); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+
+	body := resumed.Body.String()
+	if strings.Contains(body, "id: 1\n") {
+		t.Error("event already received by the client must not be replayed")
+	}
+
+	events := parseSSEEvents(t, body)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 replayed events, got %d: %+v", len(events), events)
+	}
+	if events[0].Data != "beta " || events[1].Data != "gamma" || events[2].Event != testCompletion {
+		t.Errorf("unexpected replay %+v", events)
+	}
+
+	// Further writes continue the ID sequence
+	if err := sse.WriteEvent("note", "after"); err != nil {
+		t.Fatalf("WriteEvent() error = %v", err)
+	}
+	if !strings.Contains(resumed.Body.String(), "id: 5\n") {
+		t.Errorf("expected next id 5 after resume, got %q", resumed.Body.String())
+	}
+}
+
+func TestSSEConverter_ResumeSharedStream(t *testing.T) {
+	replay := NewSSEReplayBuffer(100, time.Minute)
+
+	// Two turns of one conversation, recorded under the same stream ID
+	first := newMockResponseWriter()
+	if err := ToSSE(first).WithReplay(replay, "conversation-1").FromReader(strings.NewReader("hello there")); err != nil {
+		t.Fatalf("first FromReader() error = %v", err)
+	}
+	second := newMockResponseWriter()
+	if err := ToSSE(second).WithReplay(replay, "conversation-1").FromReader(strings.NewReader("second answer")); err != nil {
+		t.Fatalf("second FromReader() error = %v", err)
+	}
+
+	if !strings.Contains(second.Body.String(), "id: 4\n") || strings.Contains(second.Body.String(), "id: 1\n") {
+		t.Errorf("second response should continue the stream's IDs, got %q", second.Body.String())
+	}
+
+	// The client dropped after the first event of the second turn
+	resumed := newMockResponseWriter()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ToSSE(resumed).WithReplay(replay, "conversation-1").Resume(ctx, "4"); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+
+	events := parseSSEEvents(t, resumed.Body.String())
+	if len(events) != 2 || events[0].Data != "answer" || events[1].Event != testCompletion {
+		t.Errorf("expected the rest of the second turn, got %+v", events)
+	}
+}
+
+func TestSSEConverter_ResumeFollowsLiveStream(t *testing.T) {
+	replay := NewSSEReplayBuffer(100, time.Minute)
+	producer := ToSSE(newMockResponseWriter()).WithReplay(replay, "live")
+
+	if err := producer.WriteEvent("message", "first"); err != nil {
+		t.Fatalf("WriteEvent() error = %v", err)
+	}
+
+	resumed := newMockResponseWriter()
+	consumer := ToSSE(resumed).WithReplay(replay, "live")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- consumer.Resume(ctx, "") }()
+
+	time.Sleep(3 * DefaultSSEReplayPoll)
+	if err := producer.FromReader(strings.NewReader("second")); err != nil {
+		t.Fatalf("FromReader() error = %v", err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+
+	events := parseSSEEvents(t, resumed.Body.String())
+	if len(events) != 3 || events[0].Data != "first" || events[1].Data != "second" || events[2].Event != testCompletion {
+		t.Errorf("unexpected events %+v", events)
+	}
+}
+
+func TestSSEConverter_ResumeErrors(t *testing.T) {
+	ctx := context.Background()
+
+	if err := ToSSE(newMockResponseWriter()).Resume(ctx, "1"); err == nil {
+		t.Error("expected error without replay store")
+	}
+
+	sse := ToSSE(newMockResponseWriter()).WithReplay(NewSSEReplayBuffer(0, 0), "s")
+	if err := sse.Resume(ctx, "abc"); err == nil {
+		t.Error("expected error for non-numeric Last-Event-ID")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := sse.Resume(cancelled, ""); err != context.Canceled {
+		t.Errorf("expected context.Canceled for unfinished stream, got %v", err)
+	}
+}
+
+func TestSSEReplayBuffer(t *testing.T) {
+	ctx := context.Background()
+	buf := NewSSEReplayBuffer(2, time.Minute)
+
+	for _, id := range []string{"1", "2", "3"} {
+		if err := buf.Append(ctx, "s", SSEEvent{Event: "message", Data: id, ID: id}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	events, err := buf.Events(ctx, "s", 0)
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+	if len(events) != 2 || events[0].ID != "2" || events[1].ID != "3" {
+		t.Errorf("expected last 2 events, got %+v", events)
+	}
+
+	events, _ = buf.Events(ctx, "s", 2)
+	if len(events) != 1 || events[0].ID != "3" {
+		t.Errorf("expected events after 2, got %+v", events)
+	}
+
+	buf.Delete("s")
+	if events, _ := buf.Events(ctx, "s", 0); len(events) != 0 {
+		t.Errorf("expected no events after Delete, got %+v", events)
+	}
+}
+
+func TestSSEReplayBuffer_TTL(t *testing.T) {
+	ctx := context.Background()
+	buf := NewSSEReplayBuffer(10, 20*time.Millisecond)
+
+	_ = buf.Append(ctx, "old", SSEEvent{ID: "1"})
+	time.Sleep(40 * time.Millisecond)
+	_ = buf.Append(ctx, "new", SSEEvent{ID: "1"})
+
+	if events, _ := buf.Events(ctx, "old", 0); len(events) != 0 {
+		t.Error("expected idle stream to be evicted")
+	}
+	if events, _ := buf.Events(ctx, "new", 0); len(events) != 1 {
+		t.Error("expected active stream to be kept")
+	}
+}
+
+func TestLastEventID(t *testing.T) {
+	r := httptest.NewRequest("GET", "/stream?lastEventId=7", nil)
+	if got := LastEventID(r); got != "7" {
+		t.Errorf("LastEventID() from query = %q, want 7", got)
+	}
+
+	r.Header.Set("Last-Event-ID", "9")
+	if got := LastEventID(r); got != "9" {
+		t.Errorf("LastEventID() from header = %q, want 9", got)
+	}
+}
@@ -14,6 +14,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
 )
 
 const (
@@ -640,6 +642,142 @@ func filterMessageEvents(events []SSEEventCapture) []SSEEventCapture {
 	return messageEvents
 }
 
+// splitReader returns data in fixed-size reads regardless of word or rune boundaries
+type splitReader struct {
+	data []byte
+	size int
+}
+
+func (r *splitReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := min(r.size, len(r.data), len(p))
+	copy(p, r.data[:n])
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestSSEConverter_FromReader_RechunkModes(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      SSEChunkMode
+		chunkSize int
+		input     string
+		want      []string
+	}{
+		{
+			name:  "by token",
+			mode:  SSEChunkByToken,
+			input: "Hello, world! It's 42.",
+			want:  []string{"Hello", ",", " world", "!", " It", "'", "s", " 42", "."},
+		},
+		{
+			name:  "by sentence",
+			mode:  SSEChunkBySentence,
+			input: "Pi is 3.14. Really? Yes!\nNext line",
+			want:  []string{"Pi is 3.14. ", "Really? ", "Yes!\n", "Next line"},
+		},
+		{
+			name:  "by sentence keeps closing quotes",
+			mode:  SSEChunkBySentence,
+			input: `He said "stop." Then left.`,
+			want:  []string{`He said "stop." `, "Then left."},
+		},
+		{
+			name:      "by bytes is utf8 safe",
+			mode:      SSEChunkByBytes,
+			chunkSize: 5,
+			input:     "héllo wörld",
+			want:      []string{"héll", "o wö", "rld"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mock := newMockResponseWriter()
+			sse := ToSSE(mock).WithChunkMode(tt.mode)
+			if tt.chunkSize > 0 {
+				sse.WithChunkSize(tt.chunkSize)
+			}
+
+			// Deliver input in 3-byte reads so upstream boundaries split words and runes
+			if err := sse.FromReader(&splitReader{data: []byte(tt.input), size: 3}); err != nil {
+				t.Fatalf("FromReader() error = %v", err)
+			}
+
+			events := parseSSEEvents(t, mock.Body.String())
+			if len(events) != len(tt.want)+1 {
+				t.Fatalf("got %d events, want %d: %+v", len(events), len(tt.want)+1, events)
+			}
+			for i, want := range tt.want {
+				if events[i].Event != testEvent || events[i].Data != want {
+					t.Errorf("event %d = %s %q, want %q", i, events[i].Event, events[i].Data, want)
+				}
+			}
+			if events[len(events)-1].Event != testCompletion {
+				t.Errorf("expected final completion event, got %s", events[len(events)-1].Event)
+			}
+		})
+	}
+}
+
+func TestSSEConverter_WithHeartbeat(t *testing.T) {
+	pr, pw := io.Pipe()
+	mock := newMockResponseWriter()
+	sse := ToSSE(mock).WithChunkMode(SSEChunkNone).WithHeartbeat(20 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() { done <- sse.FromReader(pr) }()
+
+	// Stream stays idle long enough for several heartbeats
+	time.Sleep(90 * time.Millisecond)
+	_, _ = pw.Write([]byte("done"))
+	_ = pw.Close()
+
+	if err := <-done; err != nil {
+		t.Fatalf("FromReader() error = %v", err)
+	}
+
+	sse.mu.Lock()
+	body := mock.Body.String()
+	sse.mu.Unlock()
+
+	if !strings.Contains(body, ": heartbeat\n\n") {
+		t.Errorf("expected heartbeat comment while idle, got %q", body)
+	}
+	if strings.Contains(body, ": keep-alive") {
+		t.Error("heartbeat mode should not send keep-alive comments")
+	}
+}
+
+func TestSSEConverter_HeartbeatSkippedWhenActive(t *testing.T) {
+	mock := newMockResponseWriter()
+	sse := ToSSE(mock).WithHeartbeat(time.Hour)
+
+	if err := sse.WriteEvent("message", "recent"); err != nil {
+		t.Fatalf("WriteEvent() error = %v", err)
+	}
+	if err := sse.sendKeepAlive(); err != nil {
+		t.Fatalf("sendKeepAlive() error = %v", err)
+	}
+
+	if strings.Contains(mock.Body.String(), "heartbeat") {
+		t.Error("heartbeat must not be sent while the stream is active")
+	}
+}
+
+func TestSSEConverter_WithChunkSize(t *testing.T) {
+	sse := ToSSE(newMockResponseWriter())
+	if sse.chunkSize != DefaultSSEChunkSize {
+		t.Errorf("default chunkSize = %d, want %d", sse.chunkSize, DefaultSSEChunkSize)
+	}
+	if sse.WithChunkSize(1).chunkSize != 4 {
+		t.Errorf("chunkSize should be raised to fit any UTF-8 character, got %d", sse.chunkSize)
+	}
+}
+
 func TestSSEConverter_FromReader_ErrorCases(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -749,6 +887,32 @@ func TestSSEConverter_WriteError(t *testing.T) {
 	}
 }
 
+func TestSSEConverter_ProgressReporter(t *testing.T) {
+	mock := newMockResponseWriter()
+	sse := ToSSE(mock)
+
+	ctx := calque.WithRequestID(context.Background(), "run-1")
+	ctx = calque.WithProgress(ctx, sse.ProgressReporter())
+	calque.Progress(ctx, "indexing", 50, "halfway")
+
+	events := parseSSEEvents(t, mock.Body.String())
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 progress event, got %d", len(events))
+	}
+	if events[0].Event != "progress" {
+		t.Errorf("Expected progress event, got %s", events[0].Event)
+	}
+
+	var ev calque.ProgressEvent
+	dataJSON := strings.TrimPrefix(strings.Split(mock.Body.String(), "\n")[1], "data: ")
+	if err := json.Unmarshal([]byte(dataJSON), &ev); err != nil {
+		t.Fatalf("Failed to parse progress data: %v", err)
+	}
+	if ev.RunID != "run-1" || ev.Stage != "indexing" || ev.Percent != 50 || ev.Message != "halfway" {
+		t.Errorf("unexpected progress event %+v", ev)
+	}
+}
+
 func TestSSEConverter_FlushBehavior(t *testing.T) {
 	mock := newMockResponseWriter()
 	sse := ToSSE(mock)
@@ -0,0 +1,174 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// UnionDiscriminator is the JSON property that names which union variant an object holds
+const UnionDiscriminator = "type"
+
+// UnionTagger lets a union variant choose its discriminator value instead of the default snake_case type name
+type UnionTagger interface {
+	UnionTag() string
+}
+
+// UnionOutputConverter for tagged JSON objects -> one of several Go types
+type UnionOutputConverter[T any] struct {
+	target   *T
+	variants []reflect.Type
+}
+
+// UnionTag returns the discriminator value for a union variant.
+//
+// Variants implementing UnionTagger decide their own tag; otherwise the type
+// name is converted to snake_case, so ToolPlan becomes "tool_plan".
+//
+// Example:
+//
+//	convert.UnionTag(&FinalAnswer{}) // "final_answer"
+func UnionTag(variant any) string {
+	if t, ok := variant.(UnionTagger); ok {
+		return t.UnionTag()
+	}
+	typ := reflect.TypeOf(variant)
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == nil {
+		return ""
+	}
+	return snakeCase(typ.Name())
+}
+
+// FromJSONUnion creates an output converter that decodes a discriminated union.
+//
+// Input: pointer to the target, plus one example value per variant
+// Output: calque.OutputConverter for pipeline output position
+// Behavior: BUFFERED - reads the entire JSON object before choosing a variant
+//
+// The object's "type" property selects the variant by UnionTag, matching the
+// schema built by ai.SchemaOneOf. A new value of that variant's type (a
+// pointer when the example is a pointer) is stored in target, ready for a type
+// switch. If the discriminator is missing, the first variant that decodes
+// without unknown fields is used.
+//
+// Example usage:
+//
+//	type Step interface{}
+//
+//	var step Step
+//	err := flow.Run(ctx, prompt, convert.FromJSONUnion(&step, &ToolPlan{}, &FinalAnswer{}))
+//	switch s := step.(type) {
+//	case *ToolPlan:
+//		runTools(s.Calls)
+//	case *FinalAnswer:
+//		fmt.Println(s.Text)
+//	}
+func FromJSONUnion[T any](target *T, variants ...any) calque.OutputConverter {
+	types := make([]reflect.Type, len(variants))
+	for i, v := range variants {
+		types[i] = reflect.TypeOf(v)
+	}
+	return &UnionOutputConverter[T]{target: target, variants: types}
+}
+
+// FromReader implements outputConverter interface
+func (u *UnionOutputConverter[T]) FromReader(reader io.Reader) error {
+	ctx := context.Background()
+	if u.target == nil {
+		return calque.NewErr(ctx, "union target is nil")
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to read union JSON")
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return calque.WrapErr(ctx, err, "failed to parse union JSON object")
+	}
+
+	if raw, ok := fields[UnionDiscriminator]; ok {
+		var tag string
+		if err := json.Unmarshal(raw, &tag); err != nil {
+			return calque.WrapErr(ctx, err, "union discriminator must be a string")
+		}
+		for _, typ := range u.variants {
+			if typ != nil && variantTag(typ) == tag {
+				return u.decode(ctx, typ, data, false)
+			}
+		}
+		return calque.NewErr(ctx, fmt.Sprintf("unknown union variant %q", tag))
+	}
+
+	for _, typ := range u.variants {
+		if typ != nil && u.decode(ctx, typ, data, true) == nil {
+			return nil
+		}
+	}
+	return calque.NewErr(ctx, "union JSON has no discriminator and matches no variant")
+}
+
+// decode unmarshals data into a new value of typ and stores it in the target
+func (u *UnionOutputConverter[T]) decode(ctx context.Context, typ reflect.Type, data []byte, strict bool) error {
+	elem := typ
+	if typ.Kind() == reflect.Pointer {
+		elem = typ.Elem()
+	}
+	ptr := reflect.New(elem)
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(ptr.Interface()); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to decode union variant %s", elem.Name()))
+	}
+
+	value := ptr
+	if typ.Kind() != reflect.Pointer {
+		value = ptr.Elem()
+	}
+	dst := reflect.ValueOf(u.target).Elem()
+	if !value.Type().AssignableTo(dst.Type()) {
+		return calque.NewErr(ctx, fmt.Sprintf("union variant %s is not assignable to %s", value.Type(), dst.Type()))
+	}
+	dst.Set(value)
+	return nil
+}
+
+// variantTag returns the UnionTag of a zero value of typ, allocating pointers so tag methods can run
+func variantTag(typ reflect.Type) string {
+	if typ.Kind() == reflect.Pointer {
+		return UnionTag(reflect.New(typ.Elem()).Interface())
+	}
+	return UnionTag(reflect.New(typ).Elem().Interface())
+}
+
+// snakeCase converts a Go identifier such as HTTPRequest to http_request
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && !unicode.IsUpper(runes[i-1])
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if i > 0 && (prevLower || nextLower) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,137 @@
+package convert
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+type unionStep interface{ isStep() }
+
+type toolPlan struct {
+	Calls []string `json:"calls"`
+}
+
+func (*toolPlan) isStep() {}
+
+type finalAnswer struct {
+	Text string `json:"text"`
+}
+
+func (*finalAnswer) isStep() {}
+
+type escalation struct {
+	Reason string `json:"reason"`
+}
+
+func (escalation) UnionTag() string { return "escalate" }
+
+func TestUnionTag(t *testing.T) {
+	tests := []struct {
+		variant any
+		want    string
+	}{
+		{&toolPlan{}, "tool_plan"},
+		{finalAnswer{}, "final_answer"},
+		{escalation{}, "escalate"},
+		{&escalation{}, "escalate"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := UnionTag(tt.variant); got != tt.want {
+			t.Errorf("UnionTag(%T) = %q, want %q", tt.variant, got, tt.want)
+		}
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"ToolPlan":    "tool_plan",
+		"HTTPRequest": "http_request",
+		"UserID":      "user_id",
+		"V2Plan":      "v2_plan",
+		"answer":      "answer",
+	}
+	for in, want := range tests {
+		if got := snakeCase(in); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFromJSONUnion(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		check   func(t *testing.T, step unionStep)
+		wantErr string
+	}{
+		{
+			name:  "tagged tool plan",
+			input: `{"type":"tool_plan","calls":["search","summarize"]}`,
+			check: func(t *testing.T, step unionStep) {
+				plan, ok := step.(*toolPlan)
+				if !ok || strings.Join(plan.Calls, ",") != "search,summarize" {
+					t.Errorf("step = %#v, want *toolPlan with calls", step)
+				}
+			},
+		},
+		{
+			name:  "tagged final answer",
+			input: `{"type":"final_answer","text":"42"}`,
+			check: func(t *testing.T, step unionStep) {
+				if answer, ok := step.(*finalAnswer); !ok || answer.Text != "42" {
+					t.Errorf("step = %#v, want *finalAnswer", step)
+				}
+			},
+		},
+		{
+			name:  "untagged picks matching variant",
+			input: `{"text":"hi"}`,
+			check: func(t *testing.T, step unionStep) {
+				if _, ok := step.(*finalAnswer); !ok {
+					t.Errorf("step = %#v, want *finalAnswer", step)
+				}
+			},
+		},
+		{name: "unknown tag", input: `{"type":"refusal"}`, wantErr: `unknown union variant "refusal"`},
+		{name: "untagged matches nothing", input: `{"other":1}`, wantErr: "matches no variant"},
+		{name: "not an object", input: `["a"]`, wantErr: "failed to parse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var step unionStep
+			err := calque.NewFlow().Run(context.Background(), tt.input, FromJSONUnion(&step, &toolPlan{}, &finalAnswer{}))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.check(t, step)
+		})
+	}
+}
+
+func TestFromJSONUnion_ValueVariantsAndTypeMismatch(t *testing.T) {
+	var anyStep any
+	if err := FromJSONUnion(&anyStep, escalation{}).FromReader(strings.NewReader(`{"type":"escalate","reason":"angry"}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, ok := anyStep.(escalation); !ok || got.Reason != "angry" {
+		t.Errorf("value = %#v, want escalation value", anyStep)
+	}
+
+	// escalation doesn't implement unionStep, so it can't be stored
+	var step unionStep
+	err := FromJSONUnion(&step, escalation{}).FromReader(strings.NewReader(`{"type":"escalate"}`))
+	if err == nil || !strings.Contains(err.Error(), "not assignable") {
+		t.Errorf("error = %v, want not assignable", err)
+	}
+}
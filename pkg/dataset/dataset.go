@@ -0,0 +1,157 @@
+// Package dataset exports recorded runs as fine-tuning datasets.
+//
+// Runs captured by feedback.Collect are filtered by their feedback or eval
+// score and written as JSONL in the chat formats expected by OpenAI, Gemini
+// (Vertex AI) and Hugging Face trainers. Each example contains the system
+// prompt, the user input, every tool call turn with its result, and the final
+// answer, using the user's correction in place of the output when one exists.
+//
+// Example:
+//
+//	f, _ := os.Create("train.jsonl")
+//	n, err := dataset.ExportWithConfig(ctx, store, dataset.OpenAI, f, &dataset.Config{
+//		Filter: feedback.MinScore(0.8),
+//	})
+package dataset
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/feedback"
+)
+
+// Format is a fine-tuning dataset layout
+type Format string
+
+const (
+	// OpenAI is the chat fine-tuning format: {"messages": [...]} with tool_calls and tool messages
+	OpenAI Format = "openai"
+	// Gemini is the Gemini/Vertex AI tuning format: systemInstruction plus contents with functionCall parts
+	Gemini Format = "gemini"
+	// HFChat is the Hugging Face chat format used by chat templates and TRL, with tool arguments as objects
+	HFChat Format = "hf"
+)
+
+// Config controls which runs are exported and how
+type Config struct {
+	// Filter selects the runs to export (default: feedback.Positive)
+	Filter feedback.Filter
+	// System replaces the recorded system prompt of every run when set
+	System string
+	// SkipToolCalls leaves out tool call turns, keeping only the final answer
+	SkipToolCalls bool
+}
+
+// Export writes runs from store with a known good answer as JSONL in format.
+//
+// Input: store of recorded runs, dataset format, destination writer
+// Output: number of examples written, error for unknown formats or store/write failures
+// Behavior: one JSON object per line; failed and truncated runs are skipped
+//
+// Example:
+//
+//	n, err := dataset.Export(ctx, store, dataset.HFChat, file)
+func Export(ctx context.Context, store feedback.Store, format Format, w io.Writer) (int, error) {
+	return ExportWithConfig(ctx, store, format, w, nil)
+}
+
+// ExportWithConfig writes runs from store as JSONL in format with custom filtering.
+//
+// Example:
+//
+//	n, err := dataset.ExportWithConfig(ctx, store, dataset.Gemini, file, &dataset.Config{
+//		Filter: feedback.MinScore(0.9),
+//		System: "You are a support agent for Acme.",
+//	})
+func ExportWithConfig(ctx context.Context, store feedback.Store, format Format, w io.Writer, config *Config) (int, error) {
+	cfg := Config{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Filter == nil {
+		cfg.Filter = feedback.Positive
+	}
+
+	var encode func(conversation) any
+	switch format {
+	case OpenAI:
+		encode = encodeOpenAI
+	case Gemini:
+		encode = encodeGemini
+	case HFChat:
+		encode = encodeHFChat
+	default:
+		return 0, calque.NewErr(ctx, fmt.Sprintf("dataset: unknown format %q", format))
+	}
+
+	runs, err := store.ListRuns(ctx)
+	if err != nil {
+		return 0, calque.WrapErr(ctx, err, "dataset: failed to list runs")
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	written := 0
+	for _, run := range runs {
+		if run.Error != "" || run.Truncated || !cfg.Filter(run) {
+			continue
+		}
+		if err := enc.Encode(encode(newConversation(run, cfg))); err != nil {
+			return written, calque.WrapErr(ctx, err, "dataset: failed to write example")
+		}
+		written++
+	}
+	return written, nil
+}
+
+// conversation is a run laid out as turns, independent of the output format
+type conversation struct {
+	system    string
+	input     string
+	toolCalls []feedback.ToolCall
+	answer    string
+}
+
+func newConversation(run *feedback.Run, cfg Config) conversation {
+	c := conversation{system: run.System, input: run.Input, answer: run.Output}
+	if cfg.System != "" {
+		c.system = cfg.System
+	}
+	if correction := run.Correction(); correction != "" {
+		c.answer = correction
+	}
+	if !cfg.SkipToolCalls {
+		c.toolCalls = run.ToolCalls
+	}
+	return c
+}
+
+// callID returns the recorded call ID or a stable one based on position
+func callID(call feedback.ToolCall, i int) string {
+	if call.ID != "" {
+		return call.ID
+	}
+	return fmt.Sprintf("call_%d", i)
+}
+
+// toolOutput is the text a tool turn carries, reporting failures in-band
+func toolOutput(call feedback.ToolCall) string {
+	if call.Error != "" {
+		return "error: " + call.Error
+	}
+	return call.Result
+}
+
+// jsonObject returns s when it is a JSON object, otherwise wraps it under key
+func jsonObject(s, key string) any {
+	trimmed := bytes.TrimSpace([]byte(s))
+	if len(trimmed) > 0 && trimmed[0] == '{' && json.Valid(trimmed) {
+		return json.RawMessage(trimmed)
+	}
+	return map[string]string{key: s}
+}
@@ -0,0 +1,131 @@
+package dataset
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/middleware/feedback"
+)
+
+func testStore(t *testing.T) feedback.Store {
+	t.Helper()
+	ctx := context.Background()
+	store := feedback.NewInMemoryStore()
+	runs := []*feedback.Run{
+		{
+			ID: "tools", System: "You are a calculator.", Input: "What is 2+2?", Output: "4",
+			ToolCalls: []feedback.ToolCall{{Name: "add", Arguments: `{"a":2,"b":2}`, Result: "4"}},
+		},
+		{ID: "corrected", Input: "Capital of Australia?", Output: "Sydney"},
+		{ID: "down", Input: "q", Output: "bad"},
+		{ID: "failed", Input: "q", Error: "timeout"},
+	}
+	for _, run := range runs {
+		_ = store.SaveRun(ctx, run)
+	}
+	score := 0.9
+	_ = store.AddFeedback(ctx, feedback.Feedback{RunID: "tools", Rating: feedback.RatingUp, Score: &score})
+	_ = store.AddFeedback(ctx, feedback.Feedback{RunID: "corrected", Rating: feedback.RatingDown, Correction: "Canberra"})
+	_ = store.AddFeedback(ctx, feedback.Feedback{RunID: "down", Rating: feedback.RatingDown})
+	_ = store.AddFeedback(ctx, feedback.Feedback{RunID: "failed", Rating: feedback.RatingUp})
+	return store
+}
+
+func TestExport(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   []string
+	}{
+		{
+			format: OpenAI,
+			want: []string{
+				`{"messages":[{"role":"system","content":"You are a calculator."},{"role":"user","content":"What is 2+2?"},{"role":"assistant","tool_calls":[{"id":"call_0","type":"function","function":{"name":"add","arguments":"{\"a\":2,\"b\":2}"}}]},{"role":"tool","content":"4","tool_call_id":"call_0"},{"role":"assistant","content":"4"}]}`,
+				`{"messages":[{"role":"user","content":"Capital of Australia?"},{"role":"assistant","content":"Canberra"}]}`,
+			},
+		},
+		{
+			format: Gemini,
+			want: []string{
+				`{"systemInstruction":{"parts":[{"text":"You are a calculator."}]},"contents":[{"role":"user","parts":[{"text":"What is 2+2?"}]},{"role":"model","parts":[{"functionCall":{"name":"add","args":{"a":2,"b":2}}}]},{"role":"user","parts":[{"functionResponse":{"name":"add","response":{"content":"4"}}}]},{"role":"model","parts":[{"text":"4"}]}]}`,
+				`{"contents":[{"role":"user","parts":[{"text":"Capital of Australia?"}]},{"role":"model","parts":[{"text":"Canberra"}]}]}`,
+			},
+		},
+		{
+			format: HFChat,
+			want: []string{
+				`{"messages":[{"role":"system","content":"You are a calculator."},{"role":"user","content":"What is 2+2?"},{"role":"assistant","tool_calls":[{"type":"function","function":{"name":"add","arguments":{"a":2,"b":2}}}]},{"role":"tool","content":"4","name":"add"},{"role":"assistant","content":"4"}]}`,
+				`{"messages":[{"role":"user","content":"Capital of Australia?"},{"role":"assistant","content":"Canberra"}]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			n, err := Export(context.Background(), testStore(t), tt.format, &buf)
+			if err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if n != len(tt.want) || len(lines) != len(tt.want) {
+				t.Fatalf("Export() wrote %d examples:\n%s", n, buf.String())
+			}
+			for i, want := range tt.want {
+				if lines[i] != want {
+					t.Errorf("line %d =\n%s\nwant\n%s", i, lines[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestExportWithConfig(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := ExportWithConfig(context.Background(), testStore(t), OpenAI, &buf, &Config{
+		Filter:        feedback.MinScore(0.8),
+		System:        "Be brief.",
+		SkipToolCalls: true,
+	})
+	if err != nil {
+		t.Fatalf("ExportWithConfig() error = %v", err)
+	}
+	want := `{"messages":[{"role":"system","content":"Be brief."},{"role":"user","content":"What is 2+2?"},{"role":"assistant","content":"4"}]}` + "\n"
+	if n != 1 || buf.String() != want {
+		t.Errorf("got %d examples:\n%s", n, buf.String())
+	}
+}
+
+func TestExportToolErrorsAndPlainArguments(t *testing.T) {
+	ctx := context.Background()
+	store := feedback.NewInMemoryStore()
+	_ = store.SaveRun(ctx, &feedback.Run{ID: "r", Input: "search", Output: "none found", ToolCalls: []feedback.ToolCall{
+		{ID: "x1", Name: "search", Arguments: "golang", Error: "rate limited"},
+	}})
+	_ = store.AddFeedback(ctx, feedback.Feedback{RunID: "r", Rating: feedback.RatingUp})
+
+	var buf bytes.Buffer
+	if _, err := Export(ctx, store, Gemini, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	for _, want := range []string{`"args":{"input":"golang"}`, `"response":{"error":"rate limited"}`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output missing %s:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if _, err := Export(ctx, store, OpenAI, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `{"role":"tool","content":"error: rate limited","tool_call_id":"x1"}`) {
+		t.Errorf("tool error not reported in-band:\n%s", buf.String())
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	if _, err := Export(context.Background(), feedback.NewInMemoryStore(), "csv", &bytes.Buffer{}); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,153 @@
+package dataset
+
+// OpenAI chat fine-tuning format
+
+type openAIExample struct {
+	Messages []openAIMessage `json:"messages"`
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content,omitempty"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string         `json:"id"`
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON-encoded string, as the API returns it
+}
+
+func encodeOpenAI(c conversation) any {
+	var messages []openAIMessage
+	if c.system != "" {
+		messages = append(messages, openAIMessage{Role: "system", Content: c.system})
+	}
+	messages = append(messages, openAIMessage{Role: "user", Content: c.input})
+
+	if len(c.toolCalls) > 0 {
+		calls := make([]openAIToolCall, len(c.toolCalls))
+		for i, call := range c.toolCalls {
+			calls[i] = openAIToolCall{
+				ID:       callID(call, i),
+				Type:     "function",
+				Function: openAIFunction{Name: call.Name, Arguments: call.Arguments},
+			}
+		}
+		messages = append(messages, openAIMessage{Role: "assistant", ToolCalls: calls})
+		for i, call := range c.toolCalls {
+			messages = append(messages, openAIMessage{Role: "tool", ToolCallID: callID(call, i), Content: toolOutput(call)})
+		}
+	}
+
+	messages = append(messages, openAIMessage{Role: "assistant", Content: c.answer})
+	return openAIExample{Messages: messages}
+}
+
+// Gemini / Vertex AI tuning format
+
+type geminiExample struct {
+	SystemInstruction *geminiContent  `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent `json:"contents"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiFunctionCall struct {
+	Name string `json:"name"`
+	Args any    `json:"args"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string `json:"name"`
+	Response any    `json:"response"`
+}
+
+func encodeGemini(c conversation) any {
+	example := geminiExample{}
+	if c.system != "" {
+		example.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: c.system}}}
+	}
+	example.Contents = append(example.Contents, geminiContent{Role: "user", Parts: []geminiPart{{Text: c.input}}})
+
+	if len(c.toolCalls) > 0 {
+		calls := make([]geminiPart, len(c.toolCalls))
+		responses := make([]geminiPart, len(c.toolCalls))
+		for i, call := range c.toolCalls {
+			calls[i] = geminiPart{FunctionCall: &geminiFunctionCall{Name: call.Name, Args: jsonObject(call.Arguments, "input")}}
+
+			response := jsonObject(call.Result, "content")
+			if call.Error != "" {
+				response = map[string]string{"error": call.Error}
+			}
+			responses[i] = geminiPart{FunctionResponse: &geminiFunctionResponse{Name: call.Name, Response: response}}
+		}
+		example.Contents = append(example.Contents,
+			geminiContent{Role: "model", Parts: calls},
+			geminiContent{Role: "user", Parts: responses},
+		)
+	}
+
+	example.Contents = append(example.Contents, geminiContent{Role: "model", Parts: []geminiPart{{Text: c.answer}}})
+	return example
+}
+
+// Hugging Face chat format
+
+type hfExample struct {
+	Messages []hfMessage `json:"messages"`
+}
+
+type hfMessage struct {
+	Role      string       `json:"role"`
+	Content   string       `json:"content,omitempty"`
+	Name      string       `json:"name,omitempty"`
+	ToolCalls []hfToolCall `json:"tool_calls,omitempty"`
+}
+
+type hfToolCall struct {
+	Type     string     `json:"type"`
+	Function hfFunction `json:"function"`
+}
+
+type hfFunction struct {
+	Name      string `json:"name"`
+	Arguments any    `json:"arguments"` // Chat templates expect an object, not a string
+}
+
+func encodeHFChat(c conversation) any {
+	var messages []hfMessage
+	if c.system != "" {
+		messages = append(messages, hfMessage{Role: "system", Content: c.system})
+	}
+	messages = append(messages, hfMessage{Role: "user", Content: c.input})
+
+	if len(c.toolCalls) > 0 {
+		calls := make([]hfToolCall, len(c.toolCalls))
+		for i, call := range c.toolCalls {
+			calls[i] = hfToolCall{Type: "function", Function: hfFunction{Name: call.Name, Arguments: jsonObject(call.Arguments, "input")}}
+		}
+		messages = append(messages, hfMessage{Role: "assistant", ToolCalls: calls})
+		for _, call := range c.toolCalls {
+			messages = append(messages, hfMessage{Role: "tool", Name: call.Name, Content: toolOutput(call)})
+		}
+	}
+
+	messages = append(messages, hfMessage{Role: "assistant", Content: c.answer})
+	return hfExample{Messages: messages}
+}
@@ -0,0 +1,63 @@
+package eval
+
+import "strings"
+
+// maxDiffCells bounds the LCS table; larger inputs fall back to a whole-text replacement
+const maxDiffCells = 1 << 20
+
+// Diff returns a line diff from a to b.
+//
+// Unchanged lines are prefixed with two spaces, removed lines with "- " and
+// added lines with "+ ". Very long outputs are shown as a full removal and
+// addition rather than a minimal diff.
+//
+// Example:
+//
+//	eval.Diff("a\nb", "a\nc") // "  a\n- b\n+ c\n"
+func Diff(a, b string) string {
+	linesA := strings.Split(a, "\n")
+	linesB := strings.Split(b, "\n")
+
+	var out strings.Builder
+	if len(linesA)*len(linesB) > maxDiffCells {
+		for _, line := range linesA {
+			out.WriteString("- " + line + "\n")
+		}
+		for _, line := range linesB {
+			out.WriteString("+ " + line + "\n")
+		}
+		return out.String()
+	}
+
+	// lcs[i][j] is the longest common subsequence of linesA[i:] and linesB[j:]
+	lcs := make([][]int, len(linesA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(linesB)+1)
+	}
+	for i := len(linesA) - 1; i >= 0; i-- {
+		for j := len(linesB) - 1; j >= 0; j-- {
+			if linesA[i] == linesB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(linesA) || j < len(linesB) {
+		switch {
+		case i < len(linesA) && j < len(linesB) && linesA[i] == linesB[j]:
+			out.WriteString("  " + linesA[i] + "\n")
+			i++
+			j++
+		case i < len(linesA) && (j == len(linesB) || lcs[i+1][j] >= lcs[i][j+1]):
+			out.WriteString("- " + linesA[i] + "\n")
+			i++
+		default:
+			out.WriteString("+ " + linesB[j] + "\n")
+			j++
+		}
+	}
+	return out.String()
+}
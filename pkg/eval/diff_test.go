@@ -0,0 +1,37 @@
+package eval
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"identical", "x\ny", "x\ny", "  x\n  y\n"},
+		{"changed line", "a\nb", "a\nc", "  a\n- b\n+ c\n"},
+		{"added line", "a\nc", "a\nb\nc", "  a\n+ b\n  c\n"},
+		{"removed line", "a\nb\nc", "a\nc", "  a\n- b\n  c\n"},
+		{"empty to text", "", "hi", "- \n+ hi\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.a, tt.b); got != tt.want {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLargeFallsBack(t *testing.T) {
+	a := strings.Repeat("a\n", 2000)
+	b := strings.Repeat("b\n", 2000)
+	got := Diff(a, b)
+	if !strings.HasPrefix(got, "- a\n") || !strings.HasSuffix(got, "+ \n") {
+		t.Errorf("large diff should be a full replacement, got prefix %q", got[:20])
+	}
+}
@@ -0,0 +1,318 @@
+// Package eval compares flow variants against a dataset of cases.
+//
+// Compare runs every case through two variants of a flow, for example the
+// same agent with a different prompt or model, scores both outputs and
+// reports per-case diffs, win rates, and score, cost and latency deltas. Cases
+// can be written by hand or built from rated runs with CasesFromPairs.
+//
+// Example:
+//
+//	pairs, _ := feedback.Pairs(ctx, store, feedback.Positive)
+//	report, err := eval.Compare(ctx, eval.CasesFromPairs(pairs),
+//		eval.Variant{Name: "gpt-4o-mini", Handler: ai.Agent(small)},
+//		eval.Variant{Name: "gpt-4o", Handler: ai.Agent(large)},
+//	)
+//	fmt.Printf("B wins %.0f%% of cases\n", report.B.WinRate*100)
+package eval
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/feedback"
+)
+
+// Case is one dataset entry to run through each variant
+type Case struct {
+	ID       string            `json:"id"`
+	Input    string            `json:"input"`
+	Expected string            `json:"expected,omitempty"` // Reference answer used by scorers
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// CasesFromPairs turns labeled feedback pairs into eval cases
+func CasesFromPairs(pairs []feedback.Pair) []Case {
+	cases := make([]Case, len(pairs))
+	for i, pair := range pairs {
+		cases[i] = Case{ID: pair.RunID, Input: pair.Input, Expected: pair.Expected, Metadata: pair.Metadata}
+	}
+	return cases
+}
+
+// Variant is one version of the flow under comparison
+type Variant struct {
+	// Name identifies the variant in the report
+	Name string
+	// Handler runs a case input and writes the output
+	Handler calque.Handler
+	// Cost estimates a run's cost when the handler doesn't call RecordCost
+	Cost func(input, output []byte) float64
+}
+
+// Scorer rates an output for a case from 0 (wrong) to 1 (perfect)
+type Scorer func(ctx context.Context, c Case, output []byte) (float64, error)
+
+// Config controls how variants are compared
+type Config struct {
+	// Scorer rates each output (default: ExactMatch)
+	Scorer Scorer
+	// Concurrency is the number of cases run at once (default: 4)
+	Concurrency int
+	// Tolerance is the score difference below which a case is a tie (default: 0)
+	Tolerance float64
+}
+
+// Outcome is one variant's result for a case
+type Outcome struct {
+	Output  string        `json:"output"`
+	Score   float64       `json:"score"`
+	Latency time.Duration `json:"latency"`
+	Cost    float64       `json:"cost"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// Winner names which variant did better on a case
+type Winner string
+
+const (
+	// WinnerA means variant A scored higher
+	WinnerA Winner = "a"
+	// WinnerB means variant B scored higher
+	WinnerB Winner = "b"
+	// Tie means both scored within Config.Tolerance
+	Tie Winner = "tie"
+)
+
+// CaseResult compares both variants on one case
+type CaseResult struct {
+	Case   Case    `json:"case"`
+	A      Outcome `json:"a"`
+	B      Outcome `json:"b"`
+	Winner Winner  `json:"winner"`
+	Diff   string  `json:"diff,omitempty"` // Line diff from A's output to B's, empty when identical
+}
+
+// Summary aggregates one variant's outcomes
+type Summary struct {
+	Name        string        `json:"name"`
+	Wins        int           `json:"wins"`
+	WinRate     float64       `json:"win_rate"`
+	Errors      int           `json:"errors"`
+	MeanScore   float64       `json:"mean_score"`
+	TotalCost   float64       `json:"total_cost"`
+	MeanLatency time.Duration `json:"mean_latency"`
+	P95Latency  time.Duration `json:"p95_latency"`
+}
+
+// Report is the outcome of comparing two variants. Deltas are B minus A.
+type Report struct {
+	A            Summary       `json:"a"`
+	B            Summary       `json:"b"`
+	Ties         int           `json:"ties"`
+	ScoreDelta   float64       `json:"score_delta"`
+	CostDelta    float64       `json:"cost_delta"`
+	LatencyDelta time.Duration `json:"latency_delta"`
+	Cases        []CaseResult  `json:"cases"`
+}
+
+// Regressions returns the cases where B did worse than A
+func (r *Report) Regressions() []CaseResult {
+	var regressions []CaseResult
+	for _, c := range r.Cases {
+		if c.Winner == WinnerA {
+			regressions = append(regressions, c)
+		}
+	}
+	return regressions
+}
+
+// Compare runs cases through variants a and b and reports how they differ.
+//
+// Input: cases to run, baseline variant a, candidate variant b
+// Output: *Report with per-case results in case order, error if ctx ends
+// Behavior: runs up to 4 cases concurrently; failed runs score 0 and lose
+//
+// Example:
+//
+//	report, err := eval.Compare(ctx, cases,
+//		eval.Variant{Name: "v1", Handler: flowV1},
+//		eval.Variant{Name: "v2", Handler: flowV2},
+//	)
+//	for _, c := range report.Regressions() {
+//		fmt.Println(c.Case.ID, c.Diff)
+//	}
+func Compare(ctx context.Context, cases []Case, a, b Variant) (*Report, error) {
+	return CompareWithConfig(ctx, cases, a, b, nil)
+}
+
+// CompareWithConfig runs cases through variants a and b with a custom scorer and concurrency.
+//
+// Example:
+//
+//	report, err := eval.CompareWithConfig(ctx, cases, a, b, &eval.Config{
+//		Scorer:      eval.FromJudge(ai.LLMJudge(judgeClient)),
+//		Concurrency: 8,
+//		Tolerance:   0.05,
+//	})
+func CompareWithConfig(ctx context.Context, cases []Case, a, b Variant, config *Config) (*Report, error) {
+	cfg := Config{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Scorer == nil {
+		cfg.Scorer = ExactMatch
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+
+	results := make([]CaseResult, len(cases))
+	sem := make(chan struct{}, cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i, c := range cases {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = compareCase(ctx, c, a, b, cfg)
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return buildReport(a.Name, b.Name, results), nil
+}
+
+func compareCase(ctx context.Context, c Case, a, b Variant, cfg Config) CaseResult {
+	result := CaseResult{Case: c}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); result.A = runVariant(ctx, c, a, cfg.Scorer) }()
+	go func() { defer wg.Done(); result.B = runVariant(ctx, c, b, cfg.Scorer) }()
+	wg.Wait()
+
+	switch delta := result.B.Score - result.A.Score; {
+	case delta > cfg.Tolerance:
+		result.Winner = WinnerB
+	case -delta > cfg.Tolerance:
+		result.Winner = WinnerA
+	default:
+		result.Winner = Tie
+	}
+	if result.A.Output != result.B.Output {
+		result.Diff = Diff(result.A.Output, result.B.Output)
+	}
+	return result
+}
+
+func runVariant(ctx context.Context, c Case, v Variant, scorer Scorer) Outcome {
+	recorder := &costRecorder{}
+	ctx = context.WithValue(ctx, costRecorderKey{}, recorder)
+
+	var output []byte
+	start := time.Now()
+	err := calque.NewFlow().Use(v.Handler).Run(ctx, c.Input, &output)
+	outcome := Outcome{Output: string(output), Latency: time.Since(start)}
+
+	recorder.mu.Lock()
+	outcome.Cost = recorder.cost
+	recorded := recorder.recorded
+	recorder.mu.Unlock()
+	if !recorded && v.Cost != nil {
+		outcome.Cost = v.Cost([]byte(c.Input), output)
+	}
+
+	if err != nil {
+		outcome.Error = err.Error()
+		return outcome
+	}
+	score, err := scorer(ctx, c, output)
+	if err != nil {
+		outcome.Error = "scorer: " + err.Error()
+		return outcome
+	}
+	outcome.Score = score
+	return outcome
+}
+
+func buildReport(nameA, nameB string, results []CaseResult) *Report {
+	report := &Report{Cases: results}
+	report.A = summarize(nameA, results, WinnerA, func(r CaseResult) Outcome { return r.A })
+	report.B = summarize(nameB, results, WinnerB, func(r CaseResult) Outcome { return r.B })
+	for _, r := range results {
+		if r.Winner == Tie {
+			report.Ties++
+		}
+	}
+	report.ScoreDelta = report.B.MeanScore - report.A.MeanScore
+	report.CostDelta = report.B.TotalCost - report.A.TotalCost
+	report.LatencyDelta = report.B.MeanLatency - report.A.MeanLatency
+	return report
+}
+
+func summarize(name string, results []CaseResult, win Winner, pick func(CaseResult) Outcome) Summary {
+	s := Summary{Name: name}
+	if len(results) == 0 {
+		return s
+	}
+
+	latencies := make([]time.Duration, len(results))
+	var totalScore float64
+	var totalLatency time.Duration
+	for i, r := range results {
+		o := pick(r)
+		if r.Winner == win {
+			s.Wins++
+		}
+		if o.Error != "" {
+			s.Errors++
+		}
+		totalScore += o.Score
+		s.TotalCost += o.Cost
+		totalLatency += o.Latency
+		latencies[i] = o.Latency
+	}
+
+	n := len(results)
+	s.WinRate = float64(s.Wins) / float64(n)
+	s.MeanScore = totalScore / float64(n)
+	s.MeanLatency = totalLatency / time.Duration(n)
+	slices.Sort(latencies)
+	s.P95Latency = latencies[min(n-1, (n*95+99)/100-1)]
+	return s
+}
+
+type costRecorderKey struct{}
+
+// costRecorder accumulates RecordCost calls for one variant run
+type costRecorder struct {
+	mu       sync.Mutex
+	cost     float64
+	recorded bool
+}
+
+// RecordCost adds to the cost of the variant run in progress.
+//
+// Handlers that know their real cost, such as from provider usage, call this
+// so the report uses it instead of Variant.Cost. It is a no-op outside Compare.
+func RecordCost(ctx context.Context, cost float64) {
+	recorder, ok := ctx.Value(costRecorderKey{}).(*costRecorder)
+	if !ok {
+		return
+	}
+	recorder.mu.Lock()
+	recorder.cost += cost
+	recorder.recorded = true
+	recorder.mu.Unlock()
+}
@@ -0,0 +1,142 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/feedback"
+)
+
+// answerer replies from a fixed table, optionally sleeping and recording a cost
+func answerer(answers map[string]string, delay time.Duration, cost float64) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		input, err := io.ReadAll(req.Data)
+		if err != nil {
+			return err
+		}
+		time.Sleep(delay)
+		answer, ok := answers[string(input)]
+		if !ok {
+			return errors.New("no answer")
+		}
+		if cost > 0 {
+			RecordCost(req.Context, cost)
+		}
+		_, err = res.Data.Write([]byte(answer))
+		return err
+	})
+}
+
+func TestCompare(t *testing.T) {
+	cases := []Case{
+		{ID: "1", Input: "2+2", Expected: "4"},
+		{ID: "2", Input: "capital of France", Expected: "Paris"},
+		{ID: "3", Input: "color of sky", Expected: "blue"},
+		{ID: "4", Input: "unknown", Expected: "x"},
+	}
+	a := Variant{Name: "small", Handler: answerer(map[string]string{
+		"2+2": "4", "capital of France": "Lyon", "color of sky": "blue", "unknown": "y",
+	}, 0, 0.001)}
+	b := Variant{
+		Name: "large",
+		Handler: answerer(map[string]string{
+			"2+2": "4", "capital of France": "Paris", "color of sky": "green",
+		}, 5*time.Millisecond, 0),
+		Cost: func(input, output []byte) float64 { return float64(len(input)+len(output)) / 1000 },
+	}
+
+	report, err := Compare(context.Background(), cases, a, b)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	winners := make([]string, len(report.Cases))
+	for i, c := range report.Cases {
+		winners[i] = string(c.Winner)
+	}
+	if got := strings.Join(winners, ","); got != "tie,b,a,tie" {
+		t.Errorf("winners = %s, want tie,b,a,tie", got)
+	}
+	if report.A.Wins != 1 || report.B.Wins != 1 || report.Ties != 2 || report.A.WinRate != 0.25 {
+		t.Errorf("summary A = %+v, B = %+v, ties = %d", report.A, report.B, report.Ties)
+	}
+	if report.B.Errors != 1 || report.Cases[3].B.Error == "" {
+		t.Errorf("B errors = %d, case 4 = %+v", report.B.Errors, report.Cases[3].B)
+	}
+	if math.Abs(report.A.TotalCost-0.004) > 1e-9 {
+		t.Errorf("A cost = %v, want recorded 0.004", report.A.TotalCost)
+	}
+	// B estimates: len("2+2")+len("4"), "capital of France"+"Paris", "color of sky"+"green", "unknown"
+	if want := float64(4+22+17+7) / 1000; math.Abs(report.B.TotalCost-want) > 1e-9 {
+		t.Errorf("B cost = %v, want estimated %v", report.B.TotalCost, want)
+	}
+	if report.LatencyDelta <= 0 || report.B.P95Latency < 5*time.Millisecond {
+		t.Errorf("latency delta = %v, B p95 = %v", report.LatencyDelta, report.B.P95Latency)
+	}
+	if report.ScoreDelta != 0 || report.A.MeanScore != 0.5 {
+		t.Errorf("score delta = %v, A mean = %v", report.ScoreDelta, report.A.MeanScore)
+	}
+	if report.Cases[0].Diff != "" || report.Cases[1].Diff != "- Lyon\n+ Paris\n" {
+		t.Errorf("diffs = %q, %q", report.Cases[0].Diff, report.Cases[1].Diff)
+	}
+
+	regressions := report.Regressions()
+	if len(regressions) != 1 || regressions[0].Case.ID != "3" {
+		t.Errorf("Regressions() = %+v", regressions)
+	}
+}
+
+func TestCompareWithConfig(t *testing.T) {
+	cases := []Case{{ID: "1", Input: "q", Expected: "hello"}}
+	a := Variant{Name: "a", Handler: answerer(map[string]string{"q": "hello world"}, 0, 0)}
+	b := Variant{Name: "b", Handler: answerer(map[string]string{"q": "HELLO"}, 0, 0)}
+
+	// Contains scores both 1, so B's exact match brings no win
+	report, err := CompareWithConfig(context.Background(), cases, a, b, &Config{Scorer: Contains, Concurrency: 1})
+	if err != nil {
+		t.Fatalf("CompareWithConfig() error = %v", err)
+	}
+	if report.Cases[0].Winner != Tie {
+		t.Errorf("winner = %s, want tie", report.Cases[0].Winner)
+	}
+
+	lengthScorer := func(_ context.Context, _ Case, output []byte) (float64, error) {
+		return float64(len(output)) / 100, nil
+	}
+	report, _ = CompareWithConfig(context.Background(), cases, a, b, &Config{Scorer: lengthScorer, Tolerance: 0.1})
+	if report.Cases[0].Winner != Tie {
+		t.Errorf("score gap 0.06 within tolerance should tie, got %s", report.Cases[0].Winner)
+	}
+
+	failing := func(context.Context, Case, []byte) (float64, error) { return 0, errors.New("judge down") }
+	report, _ = CompareWithConfig(context.Background(), cases, a, b, &Config{Scorer: failing})
+	if !strings.Contains(report.Cases[0].A.Error, "judge down") {
+		t.Errorf("scorer error not reported: %+v", report.Cases[0].A)
+	}
+}
+
+func TestCompareCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	v := Variant{Handler: answerer(map[string]string{"q": "a"}, 0, 0)}
+	if _, err := Compare(ctx, []Case{{Input: "q"}}, v, v); !errors.Is(err, context.Canceled) {
+		t.Errorf("Compare() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestCasesFromPairs(t *testing.T) {
+	cases := CasesFromPairs([]feedback.Pair{{RunID: "r1", Input: "q", Output: "bad", Expected: "good"}})
+	if len(cases) != 1 || cases[0].ID != "r1" || cases[0].Input != "q" || cases[0].Expected != "good" {
+		t.Errorf("CasesFromPairs() = %+v", cases)
+	}
+}
+
+func TestRecordCostOutsideCompare(_ *testing.T) {
+	RecordCost(context.Background(), 1)
+}
@@ -0,0 +1,42 @@
+package eval
+
+import (
+	"context"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+)
+
+// ExactMatch scores 1 when the output equals the expected answer, ignoring case and surrounding whitespace
+func ExactMatch(_ context.Context, c Case, output []byte) (float64, error) {
+	if strings.EqualFold(strings.TrimSpace(string(output)), strings.TrimSpace(c.Expected)) {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Contains scores 1 when the output contains the expected answer, ignoring case
+func Contains(_ context.Context, c Case, output []byte) (float64, error) {
+	if strings.Contains(strings.ToLower(string(output)), strings.ToLower(strings.TrimSpace(c.Expected))) {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// FromJudge scores outputs with an ai.Judge, such as ai.LLMJudge.
+//
+// The judge sees the case input as the prompt. When the case has an expected
+// answer it is appended to the prompt as a reference.
+//
+// Example:
+//
+//	scorer := eval.FromJudge(ai.LLMJudge(judgeClient))
+func FromJudge(judge ai.Judge) Scorer {
+	return func(ctx context.Context, c Case, output []byte) (float64, error) {
+		prompt := c.Input
+		if c.Expected != "" {
+			prompt += "\n\nReference answer:\n" + c.Expected
+		}
+		return judge(ctx, []byte(prompt), output)
+	}
+}
@@ -0,0 +1,52 @@
+package eval
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestScorers(t *testing.T) {
+	tests := []struct {
+		name     string
+		scorer   Scorer
+		expected string
+		output   string
+		want     float64
+	}{
+		{"exact match ignores case and space", ExactMatch, "Paris", " paris\n", 1},
+		{"exact match rejects extra text", ExactMatch, "Paris", "It is Paris", 0},
+		{"contains finds answer", Contains, "Paris", "It is PARIS.", 1},
+		{"contains misses", Contains, "Paris", "Lyon", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.scorer(context.Background(), Case{Expected: tt.expected}, []byte(tt.output))
+			if err != nil || got != tt.want {
+				t.Errorf("score = %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromJudge(t *testing.T) {
+	var seen string
+	judge := func(_ context.Context, prompt, answer []byte) (float64, error) {
+		seen = string(prompt)
+		return float64(len(answer)) / 10, nil
+	}
+
+	score, err := FromJudge(judge)(context.Background(), Case{Input: "q", Expected: "ref"}, []byte("abcde"))
+	if err != nil || score != 0.5 {
+		t.Errorf("score = %v, %v", score, err)
+	}
+	if !strings.HasPrefix(seen, "q") || !strings.Contains(seen, "Reference answer:\nref") {
+		t.Errorf("judge prompt = %q", seen)
+	}
+
+	_, _ = FromJudge(judge)(context.Background(), Case{Input: "q"}, nil)
+	if seen != "q" {
+		t.Errorf("judge prompt without reference = %q", seen)
+	}
+}
@@ -0,0 +1,298 @@
+// Package flowconfig builds calque flows from YAML files.
+//
+// A flow file names a model provider and lists the steps to chain, each step
+// naming a registered builder under "use" and its settings under "with":
+//
+//	name: support-bot
+//	provider:
+//	  type: openai
+//	  model: gpt-4o-mini
+//	steps:
+//	  - use: prompt.system
+//	    with:
+//	      text: You are a friendly support agent.
+//	  - use: ctrl.timeout
+//	    with:
+//	      duration: 30s
+//	      step:
+//	        use: ai.agent
+//
+// Built-in steps cover prompts, the agent, text cleanup, flow control,
+// guardrails and feature-flagged variants. Applications add their own with
+// Register.
+//
+// Example:
+//
+//	file, err := flowconfig.Load("flow.yaml")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	flow, err := file.Build()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	err = flow.Run(ctx, "Where is my order?", os.Stdout)
+package flowconfig
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai/gemini"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai/ollama"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai/openai"
+)
+
+// File is a parsed flow definition
+type File struct {
+	Name     string    `yaml:"name"`
+	Provider *Provider `yaml:"provider,omitempty"`
+	Steps    []Step    `yaml:"steps"`
+}
+
+// Provider selects the model client used by ai.* steps
+type Provider struct {
+	Type        string   `yaml:"type"`                  // openai, gemini, ollama or mock
+	Model       string   `yaml:"model,omitempty"`       // Model name, required except for mock
+	Temperature *float32 `yaml:"temperature,omitempty"` // Sampling temperature (default: provider default)
+	MaxTokens   *int     `yaml:"max_tokens,omitempty"`  // Response token limit (default: provider default)
+	BaseURL     string   `yaml:"base_url,omitempty"`    // OpenAI-compatible endpoint or Ollama host
+	Response    string   `yaml:"response,omitempty"`    // Canned reply for the mock provider
+}
+
+// Step is one handler in a flow file
+type Step struct {
+	Use  string         `yaml:"use"`            // Registered builder name, e.g. "prompt.system"
+	Name string         `yaml:"name,omitempty"` // Optional label for traces and logs
+	With map[string]any `yaml:"with,omitempty"` // Builder settings
+}
+
+// Label returns the step name, falling back to its builder name
+func (s Step) Label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Use
+}
+
+// Decode unmarshals the step settings into v, rejecting unknown keys
+func (s Step) Decode(v any) error {
+	data, err := yaml.Marshal(s.With)
+	if err != nil {
+		return err
+	}
+	return yaml.UnmarshalWithOptions(data, v, yaml.Strict())
+}
+
+// BuildFunc creates the handler for a step
+type BuildFunc func(env *Env, step Step) (calque.Handler, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]BuildFunc{}
+)
+
+// Register makes a step type available to flow files, replacing any builder with the same name.
+//
+// Example:
+//
+//	flowconfig.Register("acme.lookup", func(env *flowconfig.Env, step flowconfig.Step) (calque.Handler, error) {
+//		var cfg struct{ Table string `yaml:"table"` }
+//		if err := step.Decode(&cfg); err != nil {
+//			return nil, err
+//		}
+//		return lookup(cfg.Table), nil
+//	})
+func Register(name string, build BuildFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = build
+}
+
+// Registered returns the names of all registered step types, sorted
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func lookup(name string) (BuildFunc, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	build, ok := registry[name]
+	return build, ok
+}
+
+// Load reads and parses a flow file
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, calque.WrapErr(context.Background(), err, "failed to read flow file")
+	}
+	return Parse(data)
+}
+
+// Parse parses a flow definition and checks that every step type is registered
+func Parse(data []byte) (*File, error) {
+	ctx := context.Background()
+	var file File
+	if err := yaml.UnmarshalWithOptions(data, &file, yaml.Strict()); err != nil {
+		return nil, calque.WrapErr(ctx, err, "invalid flow file")
+	}
+	if len(file.Steps) == 0 {
+		return nil, calque.NewErr(ctx, "flow file has no steps")
+	}
+	if err := checkSteps(file.Steps); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// checkSteps reports the first step whose type is not registered
+func checkSteps(steps []Step) error {
+	for i, step := range steps {
+		if step.Use == "" {
+			return calque.NewErr(context.Background(), fmt.Sprintf("step %d: missing \"use\"", i+1))
+		}
+		if _, ok := lookup(step.Use); !ok {
+			return calque.NewErr(context.Background(), fmt.Sprintf("step %d: unknown step type %q", i+1, step.Use))
+		}
+	}
+	return nil
+}
+
+// BuildConfig customizes how a File becomes a flow
+type BuildConfig struct {
+	// Client replaces the client described by the file's provider, e.g. in tests
+	Client ai.Client
+	// AgentOptions are applied to every ai.agent step, e.g. ai.WithUsageHandler (optional)
+	AgentOptions []ai.AgentOption
+	// Wrap decorates each top-level step handler, e.g. for tracing (optional)
+	Wrap func(index int, step Step, handler calque.Handler) calque.Handler
+	// FlowConfig is passed to calque.NewFlow (optional)
+	FlowConfig *calque.FlowConfig
+}
+
+// Build creates a flow from the file
+func (f *File) Build() (*calque.Flow, error) {
+	return f.BuildWithConfig(nil)
+}
+
+// BuildWithConfig creates a flow from the file with custom settings
+//
+// Example:
+//
+//	flow, err := file.BuildWithConfig(&flowconfig.BuildConfig{
+//		Client: ai.NewMockClient("hello"),
+//	})
+func (f *File) BuildWithConfig(config *BuildConfig) (*calque.Flow, error) {
+	cfg := BuildConfig{}
+	if config != nil {
+		cfg = *config
+	}
+
+	var flow *calque.Flow
+	if cfg.FlowConfig != nil {
+		flow = calque.NewFlow(*cfg.FlowConfig)
+	} else {
+		flow = calque.NewFlow()
+	}
+
+	env := &Env{file: f, client: cfg.Client, agentOptions: cfg.AgentOptions}
+	for i, step := range f.Steps {
+		handler, err := env.Build(step)
+		if err != nil {
+			return nil, calque.WrapErr(context.Background(), err, fmt.Sprintf("step %d (%s)", i+1, step.Label()))
+		}
+		if cfg.Wrap != nil {
+			handler = cfg.Wrap(i, step, handler)
+		}
+		flow.Use(handler)
+	}
+	return flow, nil
+}
+
+// Env gives builders access to shared resources while a file is built
+type Env struct {
+	file         *File
+	client       ai.Client
+	agentOptions []ai.AgentOption
+}
+
+// Client returns the model client for the file's provider, creating it on first use
+func (e *Env) Client() (ai.Client, error) {
+	if e.client != nil {
+		return e.client, nil
+	}
+	if e.file.Provider == nil {
+		return nil, calque.NewErr(context.Background(), "flow file has no provider")
+	}
+	client, err := NewClient(e.file.Provider)
+	if err != nil {
+		return nil, err
+	}
+	e.client = client
+	return client, nil
+}
+
+// AgentOptions returns the options from BuildConfig that agent steps should apply
+func (e *Env) AgentOptions() []ai.AgentOption {
+	return e.agentOptions
+}
+
+// Build creates the handler for a step, for builders that wrap nested steps
+func (e *Env) Build(step Step) (calque.Handler, error) {
+	build, ok := lookup(step.Use)
+	if !ok {
+		return nil, calque.NewErr(context.Background(), fmt.Sprintf("unknown step type %q", step.Use))
+	}
+	return build(e, step)
+}
+
+// NewClient creates the model client a provider describes
+//
+// Example:
+//
+//	client, err := flowconfig.NewClient(&flowconfig.Provider{Type: "ollama", Model: "llama3.2"})
+func NewClient(p *Provider) (ai.Client, error) {
+	ctx := context.Background()
+	if p.Type != "mock" && p.Model == "" {
+		return nil, calque.NewErr(ctx, fmt.Sprintf("provider %q needs a model", p.Type))
+	}
+
+	switch p.Type {
+	case "openai":
+		return openai.New(p.Model, openai.WithConfig(&openai.Config{
+			BaseURL:     p.BaseURL,
+			Temperature: p.Temperature,
+			MaxTokens:   p.MaxTokens,
+		}))
+	case "gemini":
+		return gemini.New(p.Model, gemini.WithConfig(&gemini.Config{
+			Temperature: p.Temperature,
+			MaxTokens:   p.MaxTokens,
+		}))
+	case "ollama":
+		return ollama.New(p.Model, ollama.WithConfig(&ollama.Config{
+			Host:        p.BaseURL,
+			Temperature: p.Temperature,
+			MaxTokens:   p.MaxTokens,
+		}))
+	case "mock":
+		return ai.NewMockClient(p.Response).WithStreamDelay(0), nil
+	default:
+		return nil, calque.NewErr(ctx, fmt.Sprintf("unknown provider type %q", p.Type))
+	}
+}
@@ -0,0 +1,298 @@
+package flowconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/flags"
+)
+
+const testFlow = `
+name: shouty
+provider:
+  type: mock
+  response: "  hello there  "
+steps:
+  - use: prompt.system
+    with:
+      text: Be brief.
+  - use: ctrl.timeout
+    name: model
+    with:
+      duration: 5s
+      step:
+        use: ctrl.retry
+        with:
+          attempts: 2
+          step:
+            use: ai.agent
+  - use: text.trim
+  - use: text.upper
+`
+
+func TestParseAndBuild(t *testing.T) {
+	file, err := Parse([]byte(testFlow))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if file.Name != "shouty" || file.Provider.Type != "mock" || len(file.Steps) != 4 {
+		t.Fatalf("file = %+v", file)
+	}
+	if file.Steps[1].Label() != "model" || file.Steps[2].Label() != "text.trim" {
+		t.Errorf("labels = %q, %q", file.Steps[1].Label(), file.Steps[2].Label())
+	}
+
+	flow, err := file.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	var out string
+	if err := flow.Run(context.Background(), "hi", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != "HELLO THERE" {
+		t.Errorf("output = %q, want HELLO THERE", out)
+	}
+}
+
+func TestBuildWithConfig(t *testing.T) {
+	file, err := Parse([]byte("steps:\n  - use: prompt.template\n    with:\n      template: \"{{.Role}}: {{.Input}}\"\n      data: {Role: Q}\n  - use: ai.agent\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	var wrapped []string
+	flow, err := file.BuildWithConfig(&BuildConfig{
+		Client: ai.NewMockClient("from override").WithStreamDelay(0),
+		Wrap: func(i int, step Step, h calque.Handler) calque.Handler {
+			wrapped = append(wrapped, step.Use)
+			return h
+		},
+	})
+	if err != nil {
+		t.Fatalf("BuildWithConfig() error = %v", err)
+	}
+	if strings.Join(wrapped, ",") != "prompt.template,ai.agent" {
+		t.Errorf("wrapped = %v", wrapped)
+	}
+	var out string
+	if err := flow.Run(context.Background(), "why?", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != "from override" {
+		t.Errorf("output = %q", out)
+	}
+}
+
+// usageClient replies with a fixed text and reports fixed token usage
+type usageClient struct{}
+
+func (usageClient) Chat(_ *calque.Request, w *calque.Response, opts *ai.AgentOptions) error {
+	if opts != nil && opts.UsageHandler != nil {
+		opts.UsageHandler(&ai.UsageMetadata{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5})
+	}
+	return calque.Write(w, "ok")
+}
+
+func TestBuildAgentOptions(t *testing.T) {
+	file, err := Parse([]byte("steps:\n  - use: ctrl.retry\n    with: {step: {use: ai.agent}}\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	total := 0
+	flow, err := file.BuildWithConfig(&BuildConfig{
+		Client:       usageClient{},
+		AgentOptions: []ai.AgentOption{ai.WithUsageHandler(func(u *ai.UsageMetadata) { total += u.TotalTokens })},
+	})
+	if err != nil {
+		t.Fatalf("BuildWithConfig() error = %v", err)
+	}
+	var out string
+	if err := flow.Run(context.Background(), "hi", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if total != 5 {
+		t.Errorf("usage handler saw %d tokens, want 5", total)
+	}
+}
+
+func TestFlagSelectStep(t *testing.T) {
+	file, err := Parse([]byte("steps:\n  - use: flags.select\n    with:\n      flag: shout\n      variants:\n        default: {use: text.lower}\n        loud: {use: text.upper}\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	flow, err := file.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	for provider, want := range map[string]string{"": "hey", "loud": "HEY"} {
+		ctx := flags.WithProvider(context.Background(), flags.Static(map[string]string{"shout": provider}))
+		var out string
+		if err := flow.Run(ctx, "Hey", &out); err != nil || out != want {
+			t.Errorf("variant %q: Run() = %q, %v; want %q", provider, out, err, want)
+		}
+	}
+}
+
+func TestJSONQuerySteps(t *testing.T) {
+	file, err := Parse([]byte("steps:\n  - use: text.jmes\n    with: {expr: user}\n  - use: text.jsonpath\n    with: {expr: $.name, first: true, raw: true}\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	flow, err := file.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	var out string
+	if err := flow.Run(context.Background(), `{"user":{"name":"Ada"}}`, &out); err != nil || out != "Ada" {
+		t.Errorf("Run() = %q, %v; want Ada", out, err)
+	}
+}
+
+func TestCELSteps(t *testing.T) {
+	file, err := Parse([]byte(`
+steps:
+  - use: ctrl.cel
+    with:
+      expr: input.amount >= min
+      vars: {min: 100}
+  - use: ctrl.branch
+    with:
+      when: input.currency == "EUR"
+      then:
+        use: ctrl.cel
+        with: {expr: '"€" + string(input.amount)', raw: true}
+      else:
+        use: ctrl.cel
+        with: {expr: 'input.currency + " " + string(input.amount)', raw: true}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	flow, err := file.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: `{"amount":150,"currency":"EUR"}`, want: "€150"},
+		{input: `{"amount":200,"currency":"USD"}`, want: "USD 200"},
+		{input: `{"amount":5,"currency":"EUR"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var out string
+		err := flow.Run(context.Background(), tt.input, &out)
+		if (err != nil) != tt.wantErr || out != tt.want {
+			t.Errorf("Run(%s) = %q, %v; want %q", tt.input, out, err, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "no steps", input: "name: x\n", wantErr: "no steps"},
+		{name: "unknown step", input: "steps:\n  - use: nope\n", wantErr: `unknown step type "nope"`},
+		{name: "missing use", input: "steps:\n  - name: x\n", wantErr: `step 1: missing "use"`},
+		{name: "unknown top-level key", input: "stepz: []\nsteps:\n  - use: text.trim\n", wantErr: "invalid flow file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "unknown setting", input: "steps:\n  - use: text.trim\n    with: {extra: 1}\n", wantErr: "step 1 (text.trim)"},
+		{name: "missing text", input: "steps:\n  - use: prompt.system\n", wantErr: "text is required"},
+		{name: "agent without provider", input: "steps:\n  - use: ai.agent\n", wantErr: "no provider"},
+		{name: "nested step missing", input: "steps:\n  - use: ctrl.timeout\n    with: {duration: 1s}\n", wantErr: "step is required"},
+		{name: "nested unknown", input: "steps:\n  - use: ctrl.retry\n    with: {step: {use: nope}}\n", wantErr: `unknown step type "nope"`},
+		{name: "unknown provider", input: "provider: {type: acme, model: x}\nsteps:\n  - use: ai.agent\n", wantErr: `unknown provider type "acme"`},
+		{name: "flag without variants", input: "steps:\n  - use: flags.select\n    with: {flag: x}\n", wantErr: "variants are required"},
+		{name: "bad flag variant", input: "steps:\n  - use: flags.select\n    with: {flag: x, variants: {a: {use: nope}}}\n", wantErr: `variant "a"`},
+		{name: "token rate without tpm", input: "steps:\n  - use: ctrl.token_ratelimit\n", wantErr: "tpm must be positive"},
+		{name: "jmes without expr", input: "steps:\n  - use: text.jmes\n", wantErr: "expr is required"},
+		{name: "cel without expr", input: "steps:\n  - use: ctrl.cel\n", wantErr: "expr is required"},
+		{name: "invalid cel", input: "steps:\n  - use: ctrl.cel\n    with: {expr: 'input.'}\n", wantErr: "invalid expr"},
+		{name: "branch without then", input: "steps:\n  - use: ctrl.branch\n    with: {when: 'true'}\n", wantErr: "step is required"},
+		{name: "provider without model", input: "provider: {type: ollama}\nsteps:\n  - use: ai.agent\n", wantErr: "needs a model"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := Parse([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			_, err = file.Build()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisterAndLoad(t *testing.T) {
+	Register("test.echo_twice", func(_ *Env, _ Step) (calque.Handler, error) {
+		return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+			var s string
+			if err := calque.Read(req, &s); err != nil {
+				return err
+			}
+			return calque.Write(res, s+s)
+		}), nil
+	})
+
+	found := false
+	for _, name := range Registered() {
+		found = found || name == "test.echo_twice"
+	}
+	if !found {
+		t.Fatal("Registered() missing custom step")
+	}
+
+	path := filepath.Join(t.TempDir(), "flow.yaml")
+	if err := os.WriteFile(path, []byte("steps:\n  - use: test.echo_twice\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	flow, err := file.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	var out string
+	if err := flow.Run(context.Background(), "ab", &out); err != nil || out != "abab" {
+		t.Errorf("Run() = %q, %v", out, err)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Load() of missing file should fail")
+	}
+}
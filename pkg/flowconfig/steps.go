@@ -0,0 +1,326 @@
+package flowconfig
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+	"github.com/calque-ai/go-calque/pkg/middleware/flags"
+	"github.com/calque-ai/go-calque/pkg/middleware/guardrails"
+	"github.com/calque-ai/go-calque/pkg/middleware/prompt"
+	"github.com/calque-ai/go-calque/pkg/middleware/text"
+)
+
+func init() {
+	Register("prompt.system", buildPromptSystem)
+	Register("prompt.template", buildPromptTemplate)
+	Register("ai.agent", buildAgent)
+	Register("text.trim", transformStep(strings.TrimSpace))
+	Register("text.upper", transformStep(strings.ToUpper))
+	Register("text.lower", transformStep(strings.ToLower))
+	Register("text.jsonpath", buildJSONPath)
+	Register("text.jmes", buildJMES)
+	Register("ctrl.chain", buildChain)
+	Register("ctrl.timeout", buildTimeout)
+	Register("ctrl.retry", buildRetry)
+	Register("ctrl.ratelimit", buildRateLimit)
+	Register("ctrl.token_ratelimit", buildTokenRateLimit)
+	Register("ctrl.cel", buildCEL)
+	Register("ctrl.branch", buildBranch)
+	Register("guardrails.sanitize", buildSanitize)
+	Register("flags.select", buildFlagSelect)
+}
+
+// prompt.system: {text}
+func buildPromptSystem(_ *Env, step Step) (calque.Handler, error) {
+	var cfg struct {
+		Text string `yaml:"text"`
+	}
+	if err := step.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Text == "" {
+		return nil, calque.NewErr(context.Background(), "text is required")
+	}
+	return prompt.System(cfg.Text), nil
+}
+
+// prompt.template: {template, data}
+func buildPromptTemplate(_ *Env, step Step) (calque.Handler, error) {
+	var cfg struct {
+		Template string         `yaml:"template"`
+		Data     map[string]any `yaml:"data"`
+	}
+	if err := step.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Template == "" {
+		return nil, calque.NewErr(context.Background(), "template is required")
+	}
+	return prompt.Template(cfg.Template, cfg.Data), nil
+}
+
+// ai.agent: no settings, uses the file's provider
+func buildAgent(env *Env, step Step) (calque.Handler, error) {
+	if err := step.Decode(&struct{}{}); err != nil {
+		return nil, err
+	}
+	client, err := env.Client()
+	if err != nil {
+		return nil, err
+	}
+	return ai.Agent(client, env.AgentOptions()...), nil
+}
+
+// queryStep holds the settings shared by text.jsonpath and text.jmes
+type queryStep struct {
+	Expr     string `yaml:"expr"`
+	First    bool   `yaml:"first"`
+	Raw      bool   `yaml:"raw"`
+	Required bool   `yaml:"required"`
+}
+
+func decodeQuery(step Step) (queryStep, error) {
+	var cfg queryStep
+	if err := step.Decode(&cfg); err != nil {
+		return cfg, err
+	}
+	if cfg.Expr == "" {
+		return cfg, calque.NewErr(context.Background(), "expr is required")
+	}
+	return cfg, nil
+}
+
+// text.jsonpath: {expr, first, raw, required}
+func buildJSONPath(_ *Env, step Step) (calque.Handler, error) {
+	cfg, err := decodeQuery(step)
+	if err != nil {
+		return nil, err
+	}
+	return text.JSONPathWithConfig(cfg.Expr, &text.QueryConfig{First: cfg.First, Raw: cfg.Raw, Required: cfg.Required}), nil
+}
+
+// text.jmes: {expr, raw, required}
+func buildJMES(_ *Env, step Step) (calque.Handler, error) {
+	cfg, err := decodeQuery(step)
+	if err != nil {
+		return nil, err
+	}
+	return text.JMESWithConfig(cfg.Expr, &text.QueryConfig{Raw: cfg.Raw, Required: cfg.Required}), nil
+}
+
+func transformStep(fn func(string) string) BuildFunc {
+	return func(_ *Env, step Step) (calque.Handler, error) {
+		if err := step.Decode(&struct{}{}); err != nil {
+			return nil, err
+		}
+		return text.Transform(fn), nil
+	}
+}
+
+// ctrl.chain: {steps}
+func buildChain(env *Env, step Step) (calque.Handler, error) {
+	var cfg struct {
+		Steps []Step `yaml:"steps"`
+	}
+	if err := step.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	handlers := make([]calque.Handler, len(cfg.Steps))
+	for i, s := range cfg.Steps {
+		h, err := env.Build(s)
+		if err != nil {
+			return nil, err
+		}
+		handlers[i] = h
+	}
+	return ctrl.Chain(handlers...), nil
+}
+
+// ctrl.timeout: {duration, step}
+func buildTimeout(env *Env, step Step) (calque.Handler, error) {
+	var cfg struct {
+		Duration time.Duration `yaml:"duration"`
+		Step     *Step         `yaml:"step"`
+	}
+	if err := step.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Duration <= 0 {
+		return nil, calque.NewErr(context.Background(), "duration must be positive")
+	}
+	inner, err := nestedStep(env, cfg.Step)
+	if err != nil {
+		return nil, err
+	}
+	return ctrl.Timeout(inner, cfg.Duration), nil
+}
+
+// ctrl.retry: {attempts, step}
+func buildRetry(env *Env, step Step) (calque.Handler, error) {
+	var cfg struct {
+		Attempts int   `yaml:"attempts"`
+		Step     *Step `yaml:"step"`
+	}
+	if err := step.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Attempts <= 0 {
+		cfg.Attempts = 3
+	}
+	inner, err := nestedStep(env, cfg.Step)
+	if err != nil {
+		return nil, err
+	}
+	return ctrl.Retry(inner, cfg.Attempts), nil
+}
+
+// ctrl.ratelimit: {rate, per}
+func buildRateLimit(_ *Env, step Step) (calque.Handler, error) {
+	var cfg struct {
+		Rate int           `yaml:"rate"`
+		Per  time.Duration `yaml:"per"`
+	}
+	if err := step.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Rate <= 0 {
+		return nil, calque.NewErr(context.Background(), "rate must be positive")
+	}
+	if cfg.Per <= 0 {
+		cfg.Per = time.Second
+	}
+	return ctrl.RateLimit(cfg.Rate, cfg.Per), nil
+}
+
+// ctrl.token_ratelimit: {tpm, burst, reserve_output}
+func buildTokenRateLimit(_ *Env, step Step) (calque.Handler, error) {
+	var cfg struct {
+		TPM           int `yaml:"tpm"`
+		Burst         int `yaml:"burst"`
+		ReserveOutput int `yaml:"reserve_output"`
+	}
+	if err := step.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.TPM <= 0 {
+		return nil, calque.NewErr(context.Background(), "tpm must be positive")
+	}
+	return ctrl.TokenRateLimitWithConfig(&ctrl.TokenRateLimitConfig{
+		TokensPerMinute: cfg.TPM,
+		Burst:           cfg.Burst,
+		ReserveOutput:   cfg.ReserveOutput,
+	}), nil
+}
+
+// ctrl.cel: {expr, vars, raw, drop_rejected}
+func buildCEL(_ *Env, step Step) (calque.Handler, error) {
+	var cfg struct {
+		Expr         string         `yaml:"expr"`
+		Vars         map[string]any `yaml:"vars"`
+		Raw          bool           `yaml:"raw"`
+		DropRejected bool           `yaml:"drop_rejected"`
+	}
+	if err := step.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Expr == "" {
+		return nil, calque.NewErr(context.Background(), "expr is required")
+	}
+	celCfg := &ctrl.CELConfig{Vars: cfg.Vars, Raw: cfg.Raw, DropRejected: cfg.DropRejected}
+	// Compile now so a bad expression fails the build rather than every request
+	if _, err := ctrl.CompileCEL(cfg.Expr, celCfg); err != nil {
+		return nil, calque.WrapErr(context.Background(), err, "invalid expr")
+	}
+	return ctrl.CELWithConfig(cfg.Expr, celCfg), nil
+}
+
+// ctrl.branch: {when, vars, then, else}
+func buildBranch(env *Env, step Step) (calque.Handler, error) {
+	var cfg struct {
+		When string         `yaml:"when"`
+		Vars map[string]any `yaml:"vars"`
+		Then *Step          `yaml:"then"`
+		Else *Step          `yaml:"else"`
+	}
+	if err := step.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.When == "" {
+		return nil, calque.NewErr(context.Background(), "when is required")
+	}
+	condition, err := ctrl.CompileCEL(cfg.When, &ctrl.CELConfig{Vars: cfg.Vars})
+	if err != nil {
+		return nil, calque.WrapErr(context.Background(), err, "invalid when")
+	}
+	then, err := nestedStep(env, cfg.Then)
+	if err != nil {
+		return nil, err
+	}
+	otherwise := ctrl.PassThrough()
+	if cfg.Else != nil {
+		if otherwise, err = env.Build(*cfg.Else); err != nil {
+			return nil, err
+		}
+	}
+	return ctrl.Branch(condition.Match, then, otherwise), nil
+}
+
+// guardrails.sanitize: {allowed_schemes, allowed_image_hosts, allow_shell_fences, replacement, block}
+func buildSanitize(_ *Env, step Step) (calque.Handler, error) {
+	var cfg struct {
+		AllowedSchemes    []string `yaml:"allowed_schemes"`
+		AllowedImageHosts []string `yaml:"allowed_image_hosts"`
+		AllowShellFences  bool     `yaml:"allow_shell_fences"`
+		Replacement       string   `yaml:"replacement"`
+		Block             bool     `yaml:"block"`
+	}
+	if err := step.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	return guardrails.SanitizeOutput(&guardrails.SanitizeOptions{
+		AllowedSchemes:    cfg.AllowedSchemes,
+		AllowedImageHosts: cfg.AllowedImageHosts,
+		AllowShellFences:  cfg.AllowShellFences,
+		Replacement:       cfg.Replacement,
+		Block:             cfg.Block,
+	}), nil
+}
+
+// flags.select: {flag, variants, default}
+func buildFlagSelect(env *Env, step Step) (calque.Handler, error) {
+	var cfg struct {
+		Flag     string          `yaml:"flag"`
+		Variants map[string]Step `yaml:"variants"`
+		Default  string          `yaml:"default"`
+	}
+	if err := step.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Flag == "" {
+		return nil, calque.NewErr(context.Background(), "flag is required")
+	}
+	if len(cfg.Variants) == 0 {
+		return nil, calque.NewErr(context.Background(), "variants are required")
+	}
+	variants := make(map[string]calque.Handler, len(cfg.Variants))
+	for name, s := range cfg.Variants {
+		h, err := env.Build(s)
+		if err != nil {
+			return nil, calque.WrapErr(context.Background(), err, fmt.Sprintf("variant %q", name))
+		}
+		variants[name] = h
+	}
+	return flags.SelectWithConfig(cfg.Flag, variants, &flags.SelectConfig{Default: cfg.Default}), nil
+}
+
+func nestedStep(env *Env, step *Step) (calque.Handler, error) {
+	if step == nil {
+		return nil, calque.NewErr(context.Background(), "step is required")
+	}
+	return env.Build(*step)
+}
@@ -4,7 +4,10 @@
 package ai
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"slices"
 	"strings"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
@@ -31,45 +34,117 @@ import (
 //	agent := ai.Agent(client, ai.WithTools(searchTool, calcTool))
 //	pipe.Use(agent)
 func Agent(client Client, opts ...AgentOption) calque.Handler {
-	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
-		// Build options
-		agentOpts := &AgentOptions{}
-		for _, opt := range opts {
-			opt.Apply(agentOpts)
+	return &agentHandler{client: client, opts: opts}
+}
+
+// agentHandler runs an Agent and forwards warm-up to its client
+type agentHandler struct {
+	client Client
+	opts   []AgentOption
+}
+
+func (a *agentHandler) ServeFlow(r *calque.Request, w *calque.Response) error {
+	// Build options
+	agentOpts := &AgentOptions{}
+	for _, opt := range a.opts {
+		opt.Apply(agentOpts)
+	}
+	if tracker := GetCostTracker(agentOpts); tracker != nil {
+		r = r.WithContext(ContextWithCostTracker(r.Context, tracker))
+	}
+	agentOpts.UsageHandler = recordUsage(r.Context, agentOpts.UsageHandler)
+
+	if agentOpts.Cache != nil {
+		return serveCached(a.client, agentOpts, r, w, func(r *calque.Request, w *calque.Response) error {
+			return a.serve(agentOpts, r, w)
+		})
+	}
+	return a.serve(agentOpts, r, w)
+}
+
+// serve runs the agent with resolved options
+func (a *agentHandler) serve(agentOpts *AgentOptions, r *calque.Request, w *calque.Response) error {
+	// Strict schemas the client can't enforce are spelled out in the prompt instead
+	if agentOpts.Schema != nil && agentOpts.Schema.Strict && !supportsStructuredOutput(a.client) {
+		var err error
+		if r, err = withSchemaInstructions(r, agentOpts.Schema); err != nil {
+			return err
 		}
+	}
 
-		// Determine behavior based on options
-		if len(agentOpts.Tools) > 0 {
-			// Tool-calling agent behavior
-			return runToolCallingAgent(client, agentOpts, r, w)
+	if _, ok := calque.DeterministicSeed(r.Context); ok && !supportsSeed(a.client) {
+		calque.RecordNondeterminism(r.Context, "ai.Agent", fmt.Sprintf("client %T does not support seeded sampling", a.client))
+	}
+
+	client := withStreamTimeouts(a.client, agentOpts.StreamTimeouts)
+
+	// Determine behavior based on options
+	if len(agentOpts.Tools) > 0 {
+		if agentOpts.MaxIterations > 0 || agentOpts.IterationHook != nil {
+			// Multi-turn agent loop feeding tool results back to the model
+			return runAgentLoop(client, agentOpts, r, w)
 		}
-		// Simple chat behavior
-		return client.Chat(r, w, agentOpts)
-	})
+		// Tool-calling agent behavior
+		return runToolCallingAgent(client, agentOpts, r, w)
+	}
+	// Simple chat behavior
+	return client.Chat(r, w, agentOpts)
 }
 
-// runToolCallingAgent implements the full agent loop with tools
-func runToolCallingAgent(client Client, agentOpts *AgentOptions, r *calque.Request, w *calque.Response) error {
-	// Use default tools config if none provided
-	if agentOpts.ToolsConfig == nil {
-		defaultConfig := tools.Config{
-			MaxConcurrentTools:    0, // No limit
-			IncludeOriginalOutput: false,
+// Describe implements calque.Describer, listing the agent's tools in diagrams
+func (a *agentHandler) Describe() calque.Node {
+	agentOpts := &AgentOptions{}
+	for _, opt := range a.opts {
+		opt.Apply(agentOpts)
+	}
+	if len(agentOpts.Tools) == 0 {
+		return calque.Node{Label: "ai.Agent"}
+	}
+	names := make([]string, len(agentOpts.Tools))
+	for i, t := range agentOpts.Tools {
+		names[i] = t.Name()
+	}
+	return calque.Node{Label: "ai.Agent (tools: " + strings.Join(names, ", ") + ")"}
+}
+
+// Egress implements calque.Egress, reporting where the client sends prompts
+// and where the agent's tools send their arguments
+func (a *agentHandler) Egress() []string {
+	agentOpts := &AgentOptions{}
+	for _, opt := range a.opts {
+		opt.Apply(agentOpts)
+	}
+	destinations := slices.Clone(calque.EgressOf(a.client))
+	for _, dest := range tools.EgressOf(agentOpts.Tools) {
+		if !slices.Contains(destinations, dest) {
+			destinations = append(destinations, dest)
 		}
-		agentOpts.ToolsConfig = &defaultConfig
 	}
+	return destinations
+}
 
-	// Determine which formatter to use
-	formatter := agentOpts.ToolResultFormatter
-	if formatter == nil {
-		formatter = defaultToolResultFormatter
+// Warmup implements calque.Warmer for clients that can prepare ahead of the first request
+func (a *agentHandler) Warmup(ctx context.Context) error {
+	if w, ok := a.client.(calque.Warmer); ok {
+		return w.Warmup(ctx)
 	}
+	return nil
+}
 
-	// Determine which client to use for tool formatting
-	formatterClient := agentOpts.ToolFormatterClient
-	if formatterClient == nil {
-		formatterClient = client
+// Shutdown implements calque.Shutdowner for clients holding connections or background workers
+func (a *agentHandler) Shutdown(ctx context.Context) error {
+	switch c := a.client.(type) {
+	case calque.Shutdowner:
+		return c.Shutdown(ctx)
+	case io.Closer:
+		return c.Close()
 	}
+	return nil
+}
+
+// runToolCallingAgent makes a single tool-calling pass and synthesizes an answer from its results
+func runToolCallingAgent(client Client, agentOpts *AgentOptions, r *calque.Request, w *calque.Response) error {
+	toolsConfig, formatter, formatterClient := toolCallingDefaults(client, agentOpts)
 
 	var input []byte
 	err := calque.Read(r, &input)
@@ -87,8 +162,8 @@ func runToolCallingAgent(client Client, agentOpts *AgentOptions, r *calque.Reque
 		tools.Detect(
 			// If tools detected → Execute tools, then format final answer
 			ctrl.Chain(
-				tools.ExecuteWithOptions(*agentOpts.ToolsConfig), // Execute tools
-				formatter(formatterClient, input),                // Format results with original input
+				tools.ExecuteWithOptions(toolsConfig), // Execute tools
+				formatter(formatterClient, input),     // Format results with original input
 			),
 			// No tools detected → just pass through the LLM response
 			ctrl.PassThrough(),
@@ -97,7 +172,7 @@ func runToolCallingAgent(client Client, agentOpts *AgentOptions, r *calque.Reque
 
 	// Execute the flow
 	var output []byte
-	if err := flow.Run(r.Context, input, &output); err != nil {
+	if err := flow.Run(withPartialToolResults(r.Context, client), input, &output); err != nil {
 		return calque.WrapErr(r.Context, err, "agent failed")
 	}
 
@@ -105,6 +180,50 @@ func runToolCallingAgent(client Client, agentOpts *AgentOptions, r *calque.Reque
 	return calque.Write(w, output)
 }
 
+// toolCallingDefaults resolves the tools config, result formatter and
+// formatting client of a tool-calling agent
+func toolCallingDefaults(client Client, agentOpts *AgentOptions) (tools.Config, ToolResultFormatterFunc, Client) {
+	// Use default tools config if none provided
+	toolsConfig := tools.Config{
+		MaxConcurrentTools:    0, // No limit
+		IncludeOriginalOutput: false,
+	}
+	if agentOpts.ToolsConfig != nil {
+		toolsConfig = *agentOpts.ToolsConfig
+	}
+
+	// Determine which formatter to use
+	formatter := agentOpts.ToolResultFormatter
+	if formatter == nil {
+		formatter = defaultToolResultFormatter
+	}
+
+	// Determine which client to use for tool formatting
+	formatterClient := agentOpts.ToolFormatterClient
+	if formatterClient == nil {
+		formatterClient = client
+	}
+	return toolsConfig, formatter, formatterClient
+}
+
+// withPartialToolResults forwards the output of running tools to a client that
+// streams tool results, or else to the run's progress reporters
+func withPartialToolResults(ctx context.Context, client Client) context.Context {
+	if streamer, ok := client.(ToolResultStreamer); ok {
+		return tools.WithPartialResultObserver(ctx, func(ctx context.Context, partial tools.PartialResult) {
+			if err := streamer.StreamToolResult(ctx, partial); err != nil {
+				calque.LogWarn(ctx, "streaming partial tool result failed", "tool", partial.ToolCall.Name, "error", err)
+			}
+		})
+	}
+	if !calque.HasProgress(ctx) {
+		return ctx
+	}
+	return tools.WithPartialResultObserver(ctx, func(ctx context.Context, partial tools.PartialResult) {
+		calque.Progress(ctx, "tool:"+partial.ToolCall.Name, -1, string(partial.Chunk))
+	})
+}
+
 // clientChatHandler creates a handler that calls client.Chat directly
 func clientChatHandler(client Client, agentOpts *AgentOptions) calque.Handler {
 	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
@@ -160,6 +279,37 @@ Please provide a complete answer to the original question using the tool results
 
 		// Make LLM call without tools for synthesis
 		req := calque.NewRequest(r.Context, strings.NewReader(synthesisPrompt))
-		return client.Chat(req, w, &AgentOptions{})
+		return client.Chat(req, w, &AgentOptions{UsageHandler: recordUsage(r.Context, nil)})
 	})
 }
+
+// recordUsage adds each call's usage to the flow run's total (see calque.UsageFrom)
+// and the context's CostTracker before passing it on to the caller's handler
+func recordUsage(ctx context.Context, next func(*UsageMetadata)) func(*UsageMetadata) {
+	tracker := CostTrackerFromContext(ctx)
+	return func(usage *UsageMetadata) {
+		if usage == nil {
+			return
+		}
+		calque.RecordUsage(ctx, usage.Usage())
+		if tracker != nil {
+			tracker.Record(ctx, usage)
+		}
+		if next != nil {
+			next(usage)
+		}
+	}
+}
+
+// egressOf merges the destinations declared by several handlers or clients
+func egressOf(values ...any) []string {
+	var all []string
+	for _, v := range values {
+		for _, dest := range calque.EgressOf(v) {
+			if !slices.Contains(all, dest) {
+				all = append(all, dest)
+			}
+		}
+	}
+	return all
+}
@@ -3,12 +3,22 @@ package ai
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/analytics"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+	"github.com/calque-ai/go-calque/pkg/middleware/feedback"
+	"github.com/calque-ai/go-calque/pkg/middleware/inspect"
+	"github.com/calque-ai/go-calque/pkg/middleware/observability"
+	"github.com/calque-ai/go-calque/pkg/middleware/text"
 	"github.com/calque-ai/go-calque/pkg/middleware/tools"
 )
 
@@ -319,3 +329,310 @@ type errorReader struct {
 func (e *errorReader) Read(_ []byte) (n int, err error) {
 	return 0, e.err
 }
+
+// warmClient is a mock client that records warm-up
+type warmClient struct {
+	*MockClient
+	warmed bool
+}
+
+func (c *warmClient) Warmup(context.Context) error {
+	c.warmed = true
+	return nil
+}
+
+func TestAgentWarmup(t *testing.T) {
+	client := &warmClient{MockClient: NewMockClient("hi")}
+	flow := calque.NewFlow().Use(Agent(client))
+
+	if err := flow.Warmup(context.Background()); err != nil {
+		t.Fatalf("Warmup() error = %v", err)
+	}
+	if !client.warmed {
+		t.Error("Agent did not forward warm-up to its client")
+	}
+
+	// Clients without warm-up support are skipped
+	if err := calque.NewFlow().Use(Agent(NewMockClient("hi"))).Warmup(context.Background()); err != nil {
+		t.Errorf("Warmup() without Warmer client error = %v", err)
+	}
+}
+
+// ctrlWrappers wrap a handler in each ctrl middleware that nests handlers
+var ctrlWrappers = map[string]func(calque.Handler) calque.Handler{
+	"retry":    func(h calque.Handler) calque.Handler { return ctrl.Retry(h, 3) },
+	"timeout":  func(h calque.Handler) calque.Handler { return ctrl.Timeout(h, time.Second) },
+	"chain":    func(h calque.Handler) calque.Handler { return ctrl.Chain(ctrl.PassThrough(), h) },
+	"fallback": func(h calque.Handler) calque.Handler { return ctrl.Fallback(ctrl.PassThrough(), h) },
+	"branch": func(h calque.Handler) calque.Handler {
+		return ctrl.Branch(func([]byte) bool { return true }, ctrl.PassThrough(), h)
+	},
+	"parallel":  func(h calque.Handler) calque.Handler { return ctrl.Parallel(h) },
+	"semaphore": func(h calque.Handler) calque.Handler { return ctrl.Semaphore(h, 1, 0) },
+	"adaptive":  func(h calque.Handler) calque.Handler { return ctrl.AdaptiveConcurrency(h) },
+	"dedupe":    func(h calque.Handler) calque.Handler { return ctrl.Dedupe(h, nil, nil, time.Minute) },
+	"nested":    func(h calque.Handler) calque.Handler { return ctrl.Timeout(ctrl.Retry(h, 2), time.Second) },
+}
+
+// moduleWrappers wrap a handler in the non-ctrl middleware that nests handlers
+var moduleWrappers = map[string]func(calque.Handler) calque.Handler{
+	"tracing": func(h calque.Handler) calque.Handler {
+		return observability.TracingHandler(observability.NewInMemoryTracerProvider(), "agent", h)
+	},
+	"metrics": func(h calque.Handler) calque.Handler {
+		return observability.MetricsHandler(observability.NewInMemoryMetricsProvider(), nil, h)
+	},
+	"rate limit metrics": func(h calque.Handler) calque.Handler {
+		return observability.RateLimitMetrics(observability.NewInMemoryMetricsProvider(), nil, h)
+	},
+	"text filter": func(h calque.Handler) calque.Handler { return text.Filter(func(string) bool { return true }, h) },
+	"text branch": func(h calque.Handler) calque.Handler {
+		return text.Branch(func(string) bool { return true }, ctrl.PassThrough(), h)
+	},
+	"tools detect": func(h calque.Handler) calque.Handler { return tools.Detect(h, ctrl.PassThrough()) },
+	"timing":       func(h calque.Handler) calque.Handler { return inspect.Timing("agent", h) },
+	"analytics": func(h calque.Handler) calque.Handler {
+		return analytics.New(analytics.SinkFunc(func(context.Context, []analytics.Event) error { return nil }), nil).Track("agent", h)
+	},
+	"feedback": func(h calque.Handler) calque.Handler { return feedback.Collect(feedback.NewInMemoryStore()).Wrap(h) },
+}
+
+func TestAgentLocalOnlyThroughWrappers(t *testing.T) {
+	wrappers := maps.Clone(ctrlWrappers)
+	maps.Copy(wrappers, moduleWrappers)
+	for name, wrap := range wrappers {
+		t.Run(name, func(t *testing.T) {
+			cloud := &cloudClient{MockClient: NewMockClient("hi"), host: "api.example.com"}
+			flow := calque.NewFlow(calque.WithDataPolicy(calque.LocalOnly)).Use(wrap(Agent(cloud)))
+			if err := flow.Validate(); !errors.Is(err, calque.ErrDataPolicy) || !strings.Contains(err.Error(), "api.example.com") {
+				t.Errorf("%s hid the cloud agent from the local-only policy, Validate() error = %v", name, err)
+			}
+
+			local := calque.NewFlow(calque.WithDataPolicy(calque.LocalOnly)).Use(wrap(Agent(NewMockClient("hi"))))
+			if err := local.Validate(); err != nil {
+				t.Errorf("%s with a local agent: Validate() error = %v", name, err)
+			}
+		})
+	}
+}
+
+func TestAgentToolEgress(t *testing.T) {
+	agent := Agent(NewMockClient("hi"), WithTools(tools.GitHub("t")...))
+	if got := calque.DescribeHandler(agent).Egress; !slices.Contains(got, "api.github.com") {
+		t.Errorf("egress = %v, want the GitHub API host", got)
+	}
+}
+
+func TestAgentWarmupThroughWrappers(t *testing.T) {
+	for name, wrap := range ctrlWrappers {
+		t.Run(name, func(t *testing.T) {
+			client := &warmClient{MockClient: NewMockClient("hi")}
+			flow := calque.NewFlow().Use(wrap(Agent(client)))
+			if err := flow.Warmup(context.Background()); err != nil {
+				t.Fatalf("Warmup() error = %v", err)
+			}
+			if !client.warmed {
+				t.Errorf("%s did not forward warm-up to the agent", name)
+			}
+		})
+	}
+}
+
+// closingClient is a mock client holding a connection released by Close
+type closingClient struct {
+	*MockClient
+	closed bool
+}
+
+func (c *closingClient) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestAgentShutdownThroughWrappers(t *testing.T) {
+	for name, wrap := range ctrlWrappers {
+		t.Run(name, func(t *testing.T) {
+			client := &closingClient{MockClient: NewMockClient("hi")}
+			flow := calque.NewFlow().Use(wrap(Agent(client)))
+			if err := flow.Shutdown(context.Background()); err != nil {
+				t.Fatalf("Shutdown() error = %v", err)
+			}
+			if !client.closed {
+				t.Errorf("%s did not forward shutdown to the agent's client", name)
+			}
+		})
+	}
+}
+
+func TestAgentDescribe(t *testing.T) {
+	client := NewMockClient("ok")
+	if got := calque.DescribeHandler(Agent(client)).Label; got != "ai.Agent" {
+		t.Errorf("label = %q", got)
+	}
+
+	calc := tools.Simple("calculator", "Math", func(s string) string { return s })
+	search := tools.Simple("search", "Search", func(s string) string { return s })
+	if got := calque.DescribeHandler(Agent(client, WithTools(calc, search))).Label; got != "ai.Agent (tools: calculator, search)" {
+		t.Errorf("label = %q", got)
+	}
+}
+
+// cloudClient is a mock client that sends prompts to a remote API
+type cloudClient struct {
+	*MockClient
+	host string
+}
+
+func (c *cloudClient) Egress() []string { return []string{c.host} }
+
+func TestAgentEgress(t *testing.T) {
+	cloud := &cloudClient{MockClient: NewMockClient("ok"), host: "api.example.com"}
+	local := NewMockClient("ok")
+
+	tests := []struct {
+		name    string
+		handler calque.Handler
+		want    []string
+	}{
+		{"local client", Agent(local), nil},
+		{"cloud client", Agent(cloud), []string{"api.example.com"}},
+		{"escalate", Escalate(local, cloud, HeuristicJudge(1)), []string{"api.example.com"}},
+		{"self-consistency", SelfConsistency(cloud, 3, nil), []string{"api.example.com"}},
+		{"multi-region", Agent(MultiRegion(map[string]Client{"us": cloud, "onprem": local}, nil)), []string{"api.example.com"}},
+		{"cost router", CostRouter(nil, cloud, &cloudClient{MockClient: local, host: "eu.example.com"}, cloud), []string{"api.example.com", "eu.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calque.DescribeHandler(tt.handler).Egress; !slices.Equal(got, tt.want) {
+				t.Errorf("egress = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	flow := calque.NewFlow(calque.WithDataPolicy(calque.LocalOnly)).Use(Agent(local)).Use(Agent(cloud))
+	if err := flow.Validate(); !errors.Is(err, calque.ErrDataPolicy) || !strings.Contains(err.Error(), "handler 1 (ai.Agent) sends data to api.example.com") {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+// seededClient is a mock client that supports seeded sampling
+type seededClient struct {
+	*MockClient
+}
+
+func (seededClient) SupportsSeed() bool { return true }
+
+func TestAgentDeterministic(t *testing.T) {
+	tests := []struct {
+		name   string
+		client Client
+		want   []calque.NondeterministicPoint
+	}{
+		{"seeded client", seededClient{NewMockClient("ok")}, nil},
+		{"multi-region of seeded clients", MultiRegion(map[string]Client{"us": seededClient{NewMockClient("ok")}}, nil), nil},
+		{
+			name:   "client without seed support",
+			client: NewMockClient("ok"),
+			want:   []calque.NondeterministicPoint{{Source: "ai.Agent", Reason: "client *ai.MockClient does not support seeded sampling"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+			var out string
+			if err := calque.NewFlow().Use(Agent(tt.client)).Run(ctx, "hi", &out, calque.Deterministic(3)); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if got := calque.NondeterminismFrom(ctx); !slices.Equal(got, tt.want) {
+				t.Errorf("NondeterminismFrom() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// usageReportingClient answers "ok" and reports fixed usage for every call
+type usageReportingClient struct{}
+
+func (usageReportingClient) Chat(r *calque.Request, w *calque.Response, opts *AgentOptions) error {
+	if _, err := io.Copy(io.Discard, r.Data); err != nil {
+		return err
+	}
+	if opts.UsageHandler != nil {
+		opts.UsageHandler(&UsageMetadata{PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7})
+	}
+	return calque.Write(w, "ok")
+}
+
+func TestAgentRecordsUsage(t *testing.T) {
+	var reported int
+	flow := calque.NewFlow().
+		Use(Agent(usageReportingClient{}, WithUsageHandler(func(u *UsageMetadata) { reported += u.TotalTokens }))).
+		Use(Agent(usageReportingClient{}))
+
+	ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+	var out string
+	if err := flow.Run(ctx, "hi", &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if reported != 7 {
+		t.Errorf("WithUsageHandler saw %d tokens, want 7", reported)
+	}
+	want := calque.Usage{PromptTokens: 6, CompletionTokens: 8, TotalTokens: 14, Calls: 2}
+	if got := calque.UsageFrom(ctx); got != want {
+		t.Errorf("UsageFrom() = %+v, want %+v", got, want)
+	}
+}
+
+// toolStreamingClient is a mock client whose provider accepts partial tool results
+type toolStreamingClient struct {
+	*MockClient
+	partials []string
+}
+
+func (c *toolStreamingClient) StreamToolResult(_ context.Context, partial tools.PartialResult) error {
+	c.partials = append(c.partials, partial.ToolCall.Name+":"+string(partial.Chunk))
+	return nil
+}
+
+func TestAgentPartialToolResults(t *testing.T) {
+	progress := tools.HandlerFunc("progress", "Reports in steps", func(_ *calque.Request, res *calque.Response) error {
+		for _, step := range []string{"10%", "50%", "done"} {
+			if err := calque.Write(res, step); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	responses := []string{
+		`{"tool_calls": [{"type": "function", "function": {"name": "progress", "arguments": "{}"}}]}`,
+		"Finished.",
+	}
+
+	t.Run("streaming client", func(t *testing.T) {
+		client := &toolStreamingClient{MockClient: NewMockClientWithResponses(responses)}
+		var out string
+		if err := calque.NewFlow().Use(Agent(client, WithTools(progress))).Run(context.Background(), "go", &out); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if want := []string{"progress:10%", "progress:50%", "progress:done"}; !slices.Equal(client.partials, want) {
+			t.Errorf("StreamToolResult() saw %v, want %v", client.partials, want)
+		}
+	})
+
+	t.Run("progress reporter", func(t *testing.T) {
+		var events []string
+		ctx := calque.WithProgress(context.Background(), func(ev calque.ProgressEvent) {
+			events = append(events, ev.Stage+"="+ev.Message)
+		})
+		var out string
+		if err := calque.NewFlow().Use(Agent(NewMockClientWithResponses(responses), WithTools(progress))).Run(ctx, "go", &out); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if want := []string{"tool:progress=10%", "tool:progress=50%", "tool:progress=done"}; !slices.Equal(events, want) {
+			t.Errorf("progress events = %v, want %v", events, want)
+		}
+	})
+}
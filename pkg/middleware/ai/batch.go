@@ -0,0 +1,423 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultBatchPollInterval is how often BatchJob.Wait checks a provider batch job
+const DefaultBatchPollInterval = 30 * time.Second
+
+// DefaultBatchConcurrency is how many requests run at once for clients without a batch API
+const DefaultBatchConcurrency = 4
+
+// ErrBatchNoResult is set on results for requests the finished job returned nothing for,
+// e.g. when a job expired before reaching them
+var ErrBatchNoResult = errors.New("no result for batch request")
+
+// BatchRequest is one prompt submitted with BatchSubmit
+type BatchRequest struct {
+	ID    string // Correlates the result with its input (default: position in the batch)
+	Input string // Prompt text or multimodal JSON, as accepted by Chat
+}
+
+// BatchResult is the outcome of one BatchRequest
+type BatchResult struct {
+	ID     string         // BatchRequest.ID
+	Index  int            // Position of the request in the submitted batch
+	Output string         // Model response
+	Usage  *UsageMetadata // Token usage, if the provider reported it
+	Err    error          // Per-request failure; other requests are unaffected
+}
+
+// BatchState is the lifecycle state of a batch job
+type BatchState string
+
+// Batch job states, mapped from each provider's own states
+const (
+	BatchPending   BatchState = "pending"
+	BatchRunning   BatchState = "running"
+	BatchCompleted BatchState = "completed"
+	BatchFailed    BatchState = "failed"
+	BatchCancelled BatchState = "cancelled"
+	BatchExpired   BatchState = "expired"
+)
+
+// Done reports whether the job has stopped and won't change state again
+func (s BatchState) Done() bool {
+	switch s {
+	case BatchCompleted, BatchFailed, BatchCancelled, BatchExpired:
+		return true
+	}
+	return false
+}
+
+// BatchStatus is a snapshot of a batch job's progress
+type BatchStatus struct {
+	State     BatchState
+	Total     int    // Requests in the job, 0 if the provider doesn't report counts
+	Completed int    // Requests that finished successfully
+	Failed    int    // Requests that failed
+	Message   string // Provider error for failed jobs
+}
+
+// BatchClient is implemented by clients whose provider offers an asynchronous
+// batch API, typically at a large discount over interactive requests.
+//
+// CreateBatch submits the requests and returns the provider's job ID.
+// BatchResults may return results in any order; each one must carry the ID
+// or Index of the request it answers.
+type BatchClient interface {
+	Client
+	CreateBatch(ctx context.Context, requests []BatchRequest, opts *AgentOptions) (string, error)
+	BatchStatus(ctx context.Context, jobID string) (*BatchStatus, error)
+	BatchResults(ctx context.Context, jobID string) ([]BatchResult, error)
+	CancelBatch(ctx context.Context, jobID string) error
+}
+
+// BatchConfig configures BatchSubmitWithConfig
+type BatchConfig struct {
+	Options      []AgentOption // Options applied to every request (tools aren't supported)
+	PollInterval time.Duration // How often Wait checks the job (default: DefaultBatchPollInterval)
+	Concurrency  int           // Parallel requests for clients without a batch API (default: DefaultBatchConcurrency)
+}
+
+// BatchJob is a submitted batch. ID and RequestIDs identify it across
+// process restarts, see ResumeBatch.
+type BatchJob struct {
+	ID         string   // Provider job ID
+	RequestIDs []string // BatchRequest IDs in submission order
+
+	client       BatchClient
+	pollInterval time.Duration
+}
+
+// BatchSubmit submits prompts through the provider's batch API for offline processing.
+//
+// Input: requests to run, options applied to every request
+// Output: *BatchJob to poll for results, error if submission fails
+// Behavior: ASYNC - returns once the provider accepted the job
+//
+// Clients implementing BatchClient (OpenAI, Gemini) use the provider's batch
+// API, trading latency (up to 24 hours) for roughly half the token price.
+// Other clients run the requests in the background with a few concurrent Chat
+// calls, so pipelines can be developed against the mock or a local model.
+// Requests without an ID are numbered by position; IDs must be unique.
+//
+// Example:
+//
+//	job, err := ai.BatchSubmit(ctx, client, []ai.BatchRequest{
+//		{ID: "ticket-1", Input: "Summarize: ..."},
+//		{ID: "ticket-2", Input: "Summarize: ..."},
+//	})
+//	results, err := job.Wait(ctx)
+//	for _, r := range results {
+//		fmt.Println(r.ID, r.Output, r.Err)
+//	}
+func BatchSubmit(ctx context.Context, client Client, requests []BatchRequest, opts ...AgentOption) (*BatchJob, error) {
+	return BatchSubmitWithConfig(ctx, client, requests, &BatchConfig{Options: opts})
+}
+
+// BatchSubmitWithConfig submits prompts for batch processing with custom polling and fallback settings.
+//
+// Example:
+//
+//	job, err := ai.BatchSubmitWithConfig(ctx, client, requests, &ai.BatchConfig{
+//		Options:      []ai.AgentOption{ai.WithSchema(&Summary{})},
+//		PollInterval: time.Minute,
+//	})
+func BatchSubmitWithConfig(ctx context.Context, client Client, requests []BatchRequest, config *BatchConfig) (*BatchJob, error) {
+	cfg := BatchConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if len(requests) == 0 {
+		return nil, calque.NewErr(ctx, "batch has no requests")
+	}
+
+	agentOpts := &AgentOptions{}
+	for _, opt := range cfg.Options {
+		opt.Apply(agentOpts)
+	}
+	if len(agentOpts.Tools) > 0 {
+		return nil, calque.NewErr(ctx, "tools are not supported in batch requests")
+	}
+
+	requests, ids, err := numberRequests(ctx, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	batchClient, ok := client.(BatchClient)
+	if !ok {
+		batchClient = newLocalBatcher(client, cfg.Concurrency)
+	}
+
+	jobID, err := batchClient.CreateBatch(ctx, requests, agentOpts)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to submit batch")
+	}
+	calque.LogDebug(ctx, "batch submitted", "job", jobID, "requests", len(requests))
+
+	job := ResumeBatch(batchClient, jobID, ids)
+	job.pollInterval = cfg.PollInterval
+	return job, nil
+}
+
+// ResumeBatch reattaches to a job submitted earlier, e.g. by another process.
+// requestIDs are the job's RequestIDs, used to put results back in order.
+//
+// Example:
+//
+//	job := ai.ResumeBatch(client, saved.ID, saved.RequestIDs)
+//	results, err := job.Wait(ctx)
+func ResumeBatch(client BatchClient, jobID string, requestIDs []string) *BatchJob {
+	return &BatchJob{ID: jobID, RequestIDs: requestIDs, client: client}
+}
+
+// numberRequests fills in missing IDs and checks they're unique
+func numberRequests(ctx context.Context, requests []BatchRequest) ([]BatchRequest, []string, error) {
+	numbered := make([]BatchRequest, len(requests))
+	ids := make([]string, len(requests))
+	seen := make(map[string]bool, len(requests))
+	for i, req := range requests {
+		if req.ID == "" {
+			req.ID = strconv.Itoa(i)
+		}
+		if seen[req.ID] {
+			return nil, nil, calque.NewErr(ctx, fmt.Sprintf("duplicate batch request ID %q", req.ID))
+		}
+		seen[req.ID] = true
+		numbered[i] = req
+		ids[i] = req.ID
+	}
+	return numbered, ids, nil
+}
+
+// Status returns the job's current progress
+func (j *BatchJob) Status(ctx context.Context) (*BatchStatus, error) {
+	status, err := j.client.BatchStatus(ctx, j.ID)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to get status of batch %s", j.ID))
+	}
+	return status, nil
+}
+
+// Wait polls until the job finishes and returns one result per request, in submission order.
+//
+// Requests that failed individually carry their error in BatchResult.Err.
+// Wait itself fails if the job failed as a whole or ctx is done; the job
+// keeps running on the provider and can be picked up again with ResumeBatch.
+func (j *BatchJob) Wait(ctx context.Context) ([]BatchResult, error) {
+	interval := j.pollInterval
+	if interval <= 0 {
+		interval = DefaultBatchPollInterval
+	}
+
+	for {
+		status, err := j.Status(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if status.State.Done() {
+			if status.State == BatchFailed {
+				return nil, calque.NewErr(ctx, fmt.Sprintf("batch %s failed: %s", j.ID, status.Message))
+			}
+			return j.Results(ctx)
+		}
+		calque.LogDebug(ctx, "batch in progress", "job", j.ID, "state", status.State, "completed", status.Completed, "total", status.Total)
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, calque.WrapErr(ctx, ctx.Err(), fmt.Sprintf("stopped waiting for batch %s", j.ID))
+		case <-timer.C:
+		}
+	}
+}
+
+// Results fetches the results of a finished job, in submission order.
+// Requests the provider returned nothing for get ErrBatchNoResult.
+func (j *BatchJob) Results(ctx context.Context) ([]BatchResult, error) {
+	raw, err := j.client.BatchResults(ctx, j.ID)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to get results of batch %s", j.ID))
+	}
+
+	index := make(map[string]int, len(j.RequestIDs))
+	results := make([]BatchResult, len(j.RequestIDs))
+	for i, id := range j.RequestIDs {
+		index[id] = i
+		results[i] = BatchResult{ID: id, Index: i, Err: ErrBatchNoResult}
+	}
+
+	for _, r := range raw {
+		i, ok := index[r.ID]
+		if r.ID == "" {
+			i, ok = r.Index, r.Index >= 0 && r.Index < len(results)
+		}
+		if !ok {
+			calque.LogWarn(ctx, "batch returned a result for an unknown request", "job", j.ID, "id", r.ID, "index", r.Index)
+			continue
+		}
+		r.ID, r.Index = j.RequestIDs[i], i
+		results[i] = r
+	}
+	return results, nil
+}
+
+// Cancel asks the provider to stop the job; requests already processed keep their results
+func (j *BatchJob) Cancel(ctx context.Context) error {
+	if err := j.client.CancelBatch(ctx, j.ID); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to cancel batch %s", j.ID))
+	}
+	return nil
+}
+
+// localBatcher runs batches in-process for clients without a batch API
+type localBatcher struct {
+	Client
+	concurrency int
+
+	mu   sync.Mutex
+	jobs map[string]*localBatch
+}
+
+type localBatch struct {
+	cancel  context.CancelFunc
+	status  BatchStatus
+	results []BatchResult
+}
+
+func newLocalBatcher(client Client, concurrency int) *localBatcher {
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+	return &localBatcher{Client: client, concurrency: concurrency, jobs: make(map[string]*localBatch)}
+}
+
+// CreateBatch starts the requests in the background, detached from ctx's cancellation
+func (l *localBatcher) CreateBatch(ctx context.Context, requests []BatchRequest, opts *AgentOptions) (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	jobID := "local-" + hex.EncodeToString(id)
+
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	job := &localBatch{
+		cancel:  cancel,
+		status:  BatchStatus{State: BatchRunning, Total: len(requests)},
+		results: make([]BatchResult, 0, len(requests)),
+	}
+	l.mu.Lock()
+	l.jobs[jobID] = job
+	l.mu.Unlock()
+
+	go l.run(runCtx, job, requests, opts)
+	return jobID, nil
+}
+
+func (l *localBatcher) run(ctx context.Context, job *localBatch, requests []BatchRequest, opts *AgentOptions) {
+	defer job.cancel()
+
+	sem := make(chan struct{}, l.concurrency)
+	var wg sync.WaitGroup
+	for i, req := range requests {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			result := l.chat(ctx, i, req, opts)
+
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			job.results = append(job.results, result)
+			if result.Err != nil {
+				job.status.Failed++
+			} else {
+				job.status.Completed++
+			}
+		}()
+	}
+	wg.Wait()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if ctx.Err() != nil {
+		job.status.State = BatchCancelled
+	} else {
+		job.status.State = BatchCompleted
+	}
+}
+
+// chat runs one request, with its own copy of opts so usage is captured per request
+func (l *localBatcher) chat(ctx context.Context, i int, req BatchRequest, opts *AgentOptions) BatchResult {
+	result := BatchResult{ID: req.ID, Index: i}
+	reqOpts := *opts
+	reqOpts.UsageHandler = func(u *UsageMetadata) {
+		result.Usage = u
+		if opts.UsageHandler != nil {
+			opts.UsageHandler(u)
+		}
+	}
+
+	var out bytes.Buffer
+	err := l.Chat(calque.NewRequest(ctx, strings.NewReader(req.Input)), calque.NewResponse(&out), &reqOpts)
+	result.Output, result.Err = out.String(), err
+	return result
+}
+
+func (l *localBatcher) job(jobID string) (*localBatch, error) {
+	job, ok := l.jobs[jobID]
+	if !ok {
+		return nil, fmt.Errorf("unknown batch %q", jobID)
+	}
+	return job, nil
+}
+
+func (l *localBatcher) BatchStatus(_ context.Context, jobID string) (*BatchStatus, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	job, err := l.job(jobID)
+	if err != nil {
+		return nil, err
+	}
+	status := job.status
+	return &status, nil
+}
+
+func (l *localBatcher) BatchResults(_ context.Context, jobID string) ([]BatchResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	job, err := l.job(jobID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]BatchResult(nil), job.results...), nil
+}
+
+func (l *localBatcher) CancelBatch(_ context.Context, jobID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	job, err := l.job(jobID)
+	if err != nil {
+		return err
+	}
+	job.cancel()
+	return nil
+}
@@ -0,0 +1,194 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// upperClient answers with the upper-cased input and fails on "fail"
+type upperClient struct{}
+
+func (upperClient) Chat(r *calque.Request, w *calque.Response, opts *AgentOptions) error {
+	in, err := io.ReadAll(r.Data)
+	if err != nil {
+		return err
+	}
+	if string(in) == "fail" {
+		return errors.New("model refused")
+	}
+	if opts != nil && opts.UsageHandler != nil {
+		opts.UsageHandler(&UsageMetadata{TotalTokens: len(in)})
+	}
+	return calque.Write(w, strings.ToUpper(string(in)))
+}
+
+// fakeBatchClient replays canned provider results
+type fakeBatchClient struct {
+	upperClient
+	submitted []BatchRequest
+	states    []BatchState
+	polls     int
+	results   []BatchResult
+	cancelled bool
+}
+
+func (f *fakeBatchClient) CreateBatch(_ context.Context, requests []BatchRequest, _ *AgentOptions) (string, error) {
+	f.submitted = requests
+	return "job-1", nil
+}
+
+func (f *fakeBatchClient) BatchStatus(context.Context, string) (*BatchStatus, error) {
+	state := f.states[min(f.polls, len(f.states)-1)]
+	f.polls++
+	return &BatchStatus{State: state, Message: "quota exceeded"}, nil
+}
+
+func (f *fakeBatchClient) BatchResults(context.Context, string) ([]BatchResult, error) {
+	return f.results, nil
+}
+
+func (f *fakeBatchClient) CancelBatch(context.Context, string) error {
+	f.cancelled = true
+	return nil
+}
+
+func TestBatchSubmitLocal(t *testing.T) {
+	var usage int
+	job, err := BatchSubmitWithConfig(context.Background(), upperClient{}, []BatchRequest{
+		{ID: "a", Input: "one"},
+		{Input: "fail"},
+		{Input: "three"},
+	}, &BatchConfig{
+		Options:      []AgentOption{WithUsageHandler(func(u *UsageMetadata) { usage += u.TotalTokens })},
+		PollInterval: time.Millisecond,
+		Concurrency:  1,
+	})
+	if err != nil {
+		t.Fatalf("BatchSubmit() error = %v", err)
+	}
+	if strings.Join(job.RequestIDs, ",") != "a,1,2" {
+		t.Errorf("RequestIDs = %v", job.RequestIDs)
+	}
+
+	results, err := job.Wait(context.Background())
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results", len(results))
+	}
+	if results[0].ID != "a" || results[0].Output != "ONE" || results[0].Usage.TotalTokens != 3 {
+		t.Errorf("result 0 = %+v", results[0])
+	}
+	if results[1].Index != 1 || results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "model refused") {
+		t.Errorf("result 1 = %+v", results[1])
+	}
+	if results[2].ID != "2" || results[2].Output != "THREE" {
+		t.Errorf("result 2 = %+v", results[2])
+	}
+	if usage != 8 {
+		t.Errorf("usage handler saw %d tokens, want 8", usage)
+	}
+
+	status, err := job.Status(context.Background())
+	if err != nil || status.State != BatchCompleted || status.Completed != 2 || status.Failed != 1 {
+		t.Errorf("Status() = %+v, %v", status, err)
+	}
+}
+
+func TestBatchSubmitProvider(t *testing.T) {
+	client := &fakeBatchClient{
+		states: []BatchState{BatchPending, BatchRunning, BatchCompleted},
+		results: []BatchResult{
+			{ID: "b", Output: "second"},
+			{ID: "unknown", Output: "ignored"},
+			{ID: "a", Output: "first"},
+		},
+	}
+	job, err := BatchSubmitWithConfig(context.Background(), client, []BatchRequest{
+		{ID: "a", Input: "x"}, {ID: "b", Input: "y"}, {ID: "c", Input: "z"},
+	}, &BatchConfig{PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("BatchSubmit() error = %v", err)
+	}
+	if job.ID != "job-1" || len(client.submitted) != 3 {
+		t.Fatalf("job = %+v, submitted = %v", job, client.submitted)
+	}
+
+	results, err := job.Wait(context.Background())
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if client.polls != 3 {
+		t.Errorf("polled %d times, want 3", client.polls)
+	}
+	if results[0].Output != "first" || results[1].Output != "second" || results[1].Index != 1 {
+		t.Errorf("results = %+v", results)
+	}
+	if !errors.Is(results[2].Err, ErrBatchNoResult) {
+		t.Errorf("missing result error = %v", results[2].Err)
+	}
+}
+
+func TestBatchResultsByIndex(t *testing.T) {
+	client := &fakeBatchClient{
+		states:  []BatchState{BatchCompleted},
+		results: []BatchResult{{Index: 1, Output: "second"}, {Index: 0, Output: "first"}, {Index: 7}},
+	}
+	results, err := ResumeBatch(client, "job-1", []string{"a", "b"}).Wait(context.Background())
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if results[0].ID != "a" || results[0].Output != "first" || results[1].ID != "b" || results[1].Output != "second" {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestBatchWaitErrors(t *testing.T) {
+	failed := &fakeBatchClient{states: []BatchState{BatchFailed}}
+	if _, err := ResumeBatch(failed, "job-1", []string{"a"}).Wait(context.Background()); err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("failed job error = %v", err)
+	}
+
+	running := &fakeBatchClient{states: []BatchState{BatchRunning}}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	job := ResumeBatch(running, "job-1", []string{"a"})
+	job.pollInterval = time.Millisecond
+	if _, err := job.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("cancelled wait error = %v", err)
+	}
+
+	if err := job.Cancel(context.Background()); err != nil || !running.cancelled {
+		t.Errorf("Cancel() = %v, cancelled = %v", err, running.cancelled)
+	}
+}
+
+func TestBatchSubmitErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		requests []BatchRequest
+		opts     []AgentOption
+		wantErr  string
+	}{
+		{name: "empty", wantErr: "no requests"},
+		{name: "duplicate IDs", requests: []BatchRequest{{ID: "a"}, {ID: "a"}}, wantErr: `duplicate batch request ID "a"`},
+		{name: "numbered ID clash", requests: []BatchRequest{{ID: "1"}, {}}, wantErr: `duplicate batch request ID "1"`},
+		{name: "tools", requests: []BatchRequest{{}}, opts: []AgentOption{WithTools(tools.Simple("t", "d", func(s string) string { return s }))}, wantErr: "tools are not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := BatchSubmit(context.Background(), upperClient{}, tt.requests, tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,919 @@
+// Package bedrock provides Amazon Bedrock integration for the calque framework.
+// It implements the AI client interface on top of the Bedrock Converse API, so
+// the same client works with every chat model Bedrock hosts, including
+// Anthropic Claude, Amazon Titan and Nova, Meta Llama and Mistral.
+//
+// The client supports:
+//   - Text, multi-turn and image chat completions
+//   - Streaming responses over ConverseStream
+//   - Tool calling
+//   - Token usage reporting for ai.WithUsageHandler
+//   - Model IDs, cross-region inference profiles and model ARNs
+//
+// Requests are signed with AWS Signature Version 4.
+//
+// Example usage:
+//
+//	client, err := bedrock.New("anthropic.claude-3-5-sonnet-20240620-v1:0",
+//		bedrock.WithConfig(&bedrock.Config{Region: "us-east-1"}))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	flow := calque.NewFlow().Use(ai.Agent(client))
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/helpers"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai/config"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+// signingName is the service name Bedrock runtime requests are signed for
+const signingName = "bedrock"
+
+// Client implements the Client interface for Amazon Bedrock.
+//
+// Provides streaming chat completions with tool calling through the Converse
+// API, which gives every Bedrock chat model the same request format.
+//
+// Example:
+//
+//	client, _ := bedrock.New("meta.llama3-1-70b-instruct-v1:0")
+//	agent := ai.Agent(client)
+type Client struct {
+	model       string // model ID, inference profile ID or ARN that requests are sent to
+	region      string
+	endpoint    string
+	config      *Config
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	httpClient  *http.Client
+}
+
+// Config holds Bedrock-specific configuration.
+//
+// Configures the AWS region and credentials, model parameters, and response
+// format. All fields are optional with sensible defaults.
+//
+// Example:
+//
+//	config := &bedrock.Config{
+//		Region:      "eu-central-1",
+//		Temperature: helpers.PtrOf(float32(0.2)),
+//		MaxTokens:   helpers.PtrOf(1024),
+//	}
+type Config struct {
+	// Optional. AWS region of the Bedrock runtime endpoint
+	// (defaults to the region of ModelARN, then AWS_REGION or AWS_DEFAULT_REGION)
+	Region string
+
+	// Optional. ARN of a foundation model, inference profile, provisioned
+	// throughput or imported model to invoke instead of the model ID passed to New
+	ModelARN string
+
+	// Optional. Source of AWS credentials (defaults to AWS_ACCESS_KEY_ID,
+	// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN). Pass the Credentials of an
+	// aws.Config loaded by the AWS SDK to use profiles, SSO or IAM roles
+	Credentials aws.CredentialsProvider
+
+	// Optional. Bedrock runtime endpoint, e.g. a VPC endpoint
+	// (defaults to https://bedrock-runtime.<region>.amazonaws.com)
+	Endpoint string
+
+	// Optional. HTTP client used for requests (defaults to http.DefaultClient)
+	HTTPClient *http.Client
+
+	// Optional. Proxy and CA settings for restricted networks, used when
+	// HTTPClient is not set (defaults to HTTPS_PROXY/HTTP_PROXY and system CAs)
+	Network *ai.NetworkConfig
+
+	// Optional. Controls randomness in token selection (0.0-1.0)
+	// Lower values = more deterministic, higher values = more creative
+	Temperature *float32
+
+	// Optional. Nucleus sampling parameter (0.0-1.0)
+	// Tokens are selected until their probabilities sum to this value
+	TopP *float32
+
+	// Optional. Maximum number of tokens in the response
+	MaxTokens *int
+
+	// Optional. Strings that stop text generation when encountered
+	Stop []string
+
+	// Optional. Model-specific request fields not covered by the Converse API,
+	// e.g. {"top_k": 50} for Claude
+	AdditionalModelRequestFields map[string]any
+
+	// Optional. Response format configuration (JSON schema, etc.)
+	ResponseFormat *ai.ResponseFormat
+
+	// Optional. Enable/disable streaming of responses (true by default)
+	Stream *bool
+
+	// Optional. Model tokenizer for exact TokenEstimator counts, matching the
+	// model family, via tokenizer.Func (default: tokenizer.Conservative, an estimate)
+	Tokenizer tokenizer.Tokenizer
+}
+
+// Validate reports every out-of-range setting, as ai.FieldError values
+func (c *Config) Validate() error {
+	return errors.Join(
+		ai.CheckURL("Endpoint", c.Endpoint),
+		ai.CheckRange("Temperature", c.Temperature, 0, 1),
+		ai.CheckRange("TopP", c.TopP, 0, 1),
+		ai.CheckPositive("MaxTokens", c.MaxTokens),
+	)
+}
+
+// Option interface for functional options pattern
+type Option interface {
+	Apply(*Config)
+}
+
+// configOption implements Option
+type configOption struct{ config *Config }
+
+func (o configOption) Apply(opts *Config) {
+	config.Merge(opts, o.config)
+}
+
+// WithConfig sets custom Bedrock configuration.
+//
+// Input: *Config with Bedrock settings
+// Output: Option for client creation
+// Behavior: Merges with default configuration (only non-zero/nil fields override defaults)
+//
+// Example:
+//
+//	config := &bedrock.Config{Region: "us-west-2"}
+//	client, _ := bedrock.New("amazon.titan-text-premier-v1:0", bedrock.WithConfig(config))
+func WithConfig(cfg *Config) Option {
+	return configOption{config: cfg}
+}
+
+// DefaultConfig returns sensible defaults for Bedrock.
+//
+// Input: none
+// Output: *Config with default settings
+// Behavior: Reads the region from AWS_REGION or AWS_DEFAULT_REGION
+//
+// Sampling parameters are left to the model's defaults.
+//
+// Example:
+//
+//	config := bedrock.DefaultConfig()
+//	config.MaxTokens = helpers.PtrOf(2000)
+func DefaultConfig() *Config {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	return &Config{
+		Region: region,
+		Stream: helpers.PtrOf(true),
+	}
+}
+
+// New creates a new Bedrock client with optional configuration.
+//
+// Input: model ID, inference profile ID or ARN, optional config Options
+// Output: *Client, error
+// Behavior: Resolves region, endpoint and credentials; no request is made
+//
+// The model must be enabled for the account in the Bedrock console. An ARN in
+// the model argument or Config.ModelARN also sets the region when none is
+// configured.
+//
+// Example:
+//
+//	client, err := bedrock.New("us.anthropic.claude-3-7-sonnet-20250219-v1:0")
+//	if err != nil { log.Fatal(err) }
+func New(model string, opts ...Option) (*Client, error) {
+	ctx := context.Background()
+
+	// Build config from options
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt.Apply(cfg)
+	}
+
+	if cfg.ModelARN != "" {
+		model = cfg.ModelARN
+	}
+	if model == "" {
+		return nil, calque.NewErr(ctx, "model ID or ARN is required")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, calque.WrapErr(ctx, err, "invalid bedrock config")
+	}
+
+	// An ARN names its region, which wins over the environment default
+	region := cfg.Region
+	if parsed, err := arn.Parse(model); err == nil && parsed.Region != "" && (region == "" || region == DefaultConfig().Region) {
+		region = parsed.Region
+	}
+	if region == "" {
+		return nil, calque.NewErr(ctx, "AWS region not set: provide Config.Region or set AWS_REGION")
+	}
+
+	endpoint := strings.TrimSuffix(cfg.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", region)
+	}
+
+	credentials := cfg.Credentials
+	if credentials == nil {
+		credentials = aws.CredentialsProviderFunc(envCredentials)
+	}
+
+	httpClient, err := ai.ProviderHTTPClient(cfg.HTTPClient, cfg.Network)
+	if err != nil {
+		return nil, err
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		model:       model,
+		region:      region,
+		endpoint:    endpoint,
+		config:      cfg,
+		credentials: aws.NewCredentialsCache(credentials),
+		signer:      v4.NewSigner(),
+		httpClient:  httpClient,
+	}, nil
+}
+
+// envCredentials reads static AWS credentials from the environment
+func envCredentials(ctx context.Context) (aws.Credentials, error) {
+	creds := aws.Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		Source:          "environment",
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return aws.Credentials{}, calque.NewErr(ctx, "AWS credentials not found: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY or provide Config.Credentials")
+	}
+	return creds, nil
+}
+
+// Egress implements calque.Egress. Requests go to the regional Bedrock
+// runtime endpoint, or to Config.Endpoint when set.
+func (c *Client) Egress() []string {
+	return calque.EgressHosts(c.endpoint)
+}
+
+// TokenEstimator returns an ai.TokenEstimator for the client's model, counting
+// with Config.Tokenizer when set and estimating otherwise
+func (c *Client) TokenEstimator() ai.TokenEstimator {
+	return ai.NewTokenEstimator(c.model, c.config.Tokenizer)
+}
+
+// Chat implements the Client interface.
+//
+// Input: user prompt/query via calque.Request
+// Output: streamed AI response via calque.Response
+// Behavior: STREAMING - outputs tokens as they arrive
+//
+// Tool calls and schema responses are buffered and written once complete.
+// Token usage is reported to the usage handler after every call.
+//
+// Example:
+//
+//	err := client.Chat(req, res, &ai.AgentOptions{Tools: tools})
+func (c *Client) Chat(r *calque.Request, w *calque.Response, opts *ai.AgentOptions) (err error) {
+	c = c.withOverrides(r.Context)
+	r, span := ai.StartChatSpan(r, ai.ChatSpanInfo{
+		Provider:    "aws.bedrock",
+		Model:       c.model,
+		Temperature: c.config.Temperature,
+		MaxTokens:   c.config.MaxTokens,
+	})
+	defer func() { span.End(err) }()
+
+	// Route provider requests through the call's interceptors, see ai.WithInterceptor
+	r = r.WithContext(ai.ContextWithInterceptors(r.Context, ai.GetInterceptors(opts)...))
+
+	// Which input type are we processing?
+	input, err := ai.ClassifyInput(r, opts)
+	if err != nil {
+		return err
+	}
+
+	request, err := c.buildRequest(r.Context, input, ai.GetSchema(opts), ai.GetTools(opts))
+	if err != nil {
+		return err
+	}
+
+	if c.config.Stream == nil || *c.config.Stream {
+		return c.executeStreamingRequest(request, r, w, opts)
+	}
+	return c.executeNonStreamingRequest(request, r, w, opts)
+}
+
+// ModelSettings implements ai.ModelDescriber
+func (c *Client) ModelSettings(ctx context.Context) ai.ModelSettings {
+	c = c.withOverrides(ctx)
+	return ai.ModelSettings{Model: c.model, Temperature: c.config.Temperature, MaxTokens: c.config.MaxTokens}
+}
+
+// withOverrides returns a copy of the client using the call's ai.Overrides, if any
+func (c *Client) withOverrides(ctx context.Context) *Client {
+	overrides := ai.OverridesFrom(ctx)
+	if overrides.IsZero() {
+		return c
+	}
+	client, config := *c, *c.config
+	if overrides.Model != "" {
+		client.model = overrides.Model
+	}
+	if overrides.Temperature != nil {
+		config.Temperature = overrides.Temperature
+	}
+	if overrides.MaxTokens != nil {
+		config.MaxTokens = overrides.MaxTokens
+	}
+	client.config = &config
+	return &client
+}
+
+// converseRequest is the body of Converse and ConverseStream requests
+type converseRequest struct {
+	Messages                     []message        `json:"messages"`
+	System                       []contentBlock   `json:"system,omitempty"`
+	InferenceConfig              *inferenceConfig `json:"inferenceConfig,omitempty"`
+	ToolConfig                   *toolConfig      `json:"toolConfig,omitempty"`
+	AdditionalModelRequestFields map[string]any   `json:"additionalModelRequestFields,omitempty"`
+
+	jsonResponse bool // buffer and clean the response for a schema
+}
+
+type message struct {
+	Role    string         `json:"role"`
+	Content []contentBlock `json:"content"`
+}
+
+type contentBlock struct {
+	Text             string            `json:"text,omitempty"`
+	Image            *imageBlock       `json:"image,omitempty"`
+	ToolUse          *toolUse          `json:"toolUse,omitempty"`
+	ReasoningContent *reasoningContent `json:"reasoningContent,omitempty"`
+}
+
+type imageBlock struct {
+	Format string `json:"format"`
+	Source struct {
+		Bytes []byte `json:"bytes"`
+	} `json:"source"`
+}
+
+type toolUse struct {
+	ToolUseID string          `json:"toolUseId"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input,omitempty"`
+}
+
+// reasoningContent holds model reasoning; responses nest the text in
+// reasoningText, stream deltas carry it directly
+type reasoningContent struct {
+	ReasoningText *struct {
+		Text string `json:"text"`
+	} `json:"reasoningText,omitempty"`
+	Text string `json:"text,omitempty"`
+}
+
+type inferenceConfig struct {
+	MaxTokens     *int     `json:"maxTokens,omitempty"`
+	Temperature   *float32 `json:"temperature,omitempty"`
+	TopP          *float32 `json:"topP,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+type toolConfig struct {
+	Tools []toolSpecification `json:"tools"`
+}
+
+type toolSpecification struct {
+	ToolSpec struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		InputSchema struct {
+			JSON any `json:"json"`
+		} `json:"inputSchema"`
+	} `json:"toolSpec"`
+}
+
+// tokenUsage is the usage reported by Converse and the ConverseStream metadata event
+type tokenUsage struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+	TotalTokens  int `json:"totalTokens"`
+}
+
+// buildRequest creates the Converse request body for the input
+func (c *Client) buildRequest(ctx context.Context, input *ai.ClassifiedInput, schema *ai.ResponseFormat, toolList []tools.Tool) (*converseRequest, error) {
+	request := &converseRequest{AdditionalModelRequestFields: c.config.AdditionalModelRequestFields}
+
+	switch input.Type {
+	case ai.TextInput:
+		request.Messages = []message{{Role: "user", Content: []contentBlock{{Text: input.Text}}}}
+
+	case ai.MultimodalJSONInput, ai.MultimodalStreamingInput:
+		msg, err := multimodalToMessage(ctx, input.Multimodal)
+		if err != nil {
+			return nil, err
+		}
+		request.Messages = []message{*msg}
+
+	case ai.MessagesInput:
+		request.System, request.Messages = messagesToBedrock(input.Messages)
+
+	default:
+		return nil, calque.NewErr(ctx, fmt.Sprintf("unsupported input type: %d", input.Type))
+	}
+
+	if c.config.MaxTokens != nil || c.config.Temperature != nil || c.config.TopP != nil || len(c.config.Stop) > 0 {
+		request.InferenceConfig = &inferenceConfig{
+			MaxTokens:     c.config.MaxTokens,
+			Temperature:   c.config.Temperature,
+			TopP:          c.config.TopP,
+			StopSequences: c.config.Stop,
+		}
+	}
+
+	// Converse has no response format parameter, so schemas go in the system prompt
+	if schema == nil {
+		schema = c.config.ResponseFormat
+	}
+	if schema != nil {
+		instructions, err := schemaInstructions(ctx, schema)
+		if err != nil {
+			return nil, err
+		}
+		request.System = append(request.System, contentBlock{Text: instructions})
+		request.jsonResponse = true
+	}
+
+	if len(toolList) > 0 {
+		request.ToolConfig = convertToBedrockTools(toolList)
+	}
+
+	return request, nil
+}
+
+// messagesToBedrock converts canonical chat messages to Bedrock system
+// blocks and messages, merging consecutive turns of the same role as
+// Converse requires roles to alternate
+func messagesToBedrock(messages *ai.Messages) ([]contentBlock, []message) {
+	var system []contentBlock
+	var result []message
+	for _, msg := range messages.Messages {
+		role := "user"
+		text := msg.Content
+		switch msg.Role {
+		case ai.RoleSystem:
+			system = append(system, contentBlock{Text: msg.Content})
+			continue
+		case ai.RoleAssistant:
+			role = "assistant"
+		case ai.RoleTool:
+			// Converse only accepts tool results that answer a toolUse block, so pass the result as context
+			text = ai.NewMessages(msg).Text()
+		}
+		if text == "" {
+			continue
+		}
+
+		if n := len(result); n > 0 && result[n-1].Role == role {
+			result[n-1].Content = append(result[n-1].Content, contentBlock{Text: text})
+			continue
+		}
+		result = append(result, message{Role: role, Content: []contentBlock{{Text: text}}})
+	}
+	return system, result
+}
+
+// multimodalToMessage converts multimodal input to a Bedrock user message
+func multimodalToMessage(ctx context.Context, multimodal *ai.MultimodalInput) (*message, error) {
+	if multimodal == nil {
+		return nil, calque.NewErr(ctx, "multimodal input cannot be nil")
+	}
+
+	msg := &message{Role: "user"}
+	for _, part := range multimodal.Parts {
+		switch part.Type {
+		case "text":
+			if part.Text != "" {
+				msg.Content = append(msg.Content, contentBlock{Text: part.Text})
+			}
+		case "image":
+			data := part.Data
+			if part.Reader != nil {
+				var err error
+				if data, err = io.ReadAll(part.Reader); err != nil {
+					return nil, calque.WrapErr(ctx, err, "failed to read image data")
+				}
+			}
+			if len(data) == 0 {
+				continue
+			}
+			image := &imageBlock{Format: imageFormat(part.MimeType)}
+			image.Source.Bytes = data
+			msg.Content = append(msg.Content, contentBlock{Image: image})
+		case "audio", "video":
+			return nil, calque.NewErr(ctx, "audio and video content not yet supported by the Bedrock client")
+		default:
+			return nil, calque.NewErr(ctx, fmt.Sprintf("unsupported content part type: %s", part.Type))
+		}
+	}
+
+	if len(msg.Content) == 0 {
+		return nil, calque.NewErr(ctx, "no valid content parts found in multimodal input")
+	}
+	return msg, nil
+}
+
+// imageFormat maps a MIME type to a Converse image format
+func imageFormat(mimeType string) string {
+	format := strings.TrimPrefix(strings.ToLower(mimeType), "image/")
+	if format == "jpg" {
+		return "jpeg"
+	}
+	if format == "" {
+		return "png"
+	}
+	return format
+}
+
+// schemaInstructions describes the expected JSON response for the system prompt
+func schemaInstructions(ctx context.Context, format *ai.ResponseFormat) (string, error) {
+	if format.Schema == nil {
+		return "Respond only with a valid JSON object.", nil
+	}
+	schema, err := json.Marshal(format.Schema)
+	if err != nil {
+		return "", calque.WrapErr(ctx, err, "failed to encode response schema")
+	}
+	return "Respond only with JSON that matches this schema, without code fences or commentary:\n" + string(schema), nil
+}
+
+// convertToBedrockTools converts tools to Converse tool specifications
+func convertToBedrockTools(toolList []tools.Tool) *toolConfig {
+	cfg := &toolConfig{Tools: make([]toolSpecification, len(toolList))}
+	for i, tool := range toolList {
+		spec := &cfg.Tools[i].ToolSpec
+		spec.Name = tool.Name()
+		spec.Description = tool.Description()
+		if schema := tool.ParametersSchema(); schema != nil {
+			spec.InputSchema.JSON = schema
+		} else {
+			spec.InputSchema.JSON = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+	}
+	return cfg
+}
+
+// send signs and posts a request to the model's Converse operation
+func (c *Client) send(ctx context.Context, operation string, request *converseRequest) (*http.Response, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to encode bedrock request")
+	}
+
+	endpoint := c.endpoint + "/model/" + url.PathEscape(c.model) + "/" + operation
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to create bedrock request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := ai.Intercept(req, c.signAndDo)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "bedrock request failed")
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, apiError(ctx, resp)
+	}
+	return resp, nil
+}
+
+// signAndDo signs a request with SigV4 and sends it. Signing happens after
+// interceptors run, so the signature covers any headers or body they changed.
+func (c *Client) signAndDo(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to read bedrock request")
+		}
+		req.Body.Close()
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	creds, err := c.credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to retrieve AWS credentials")
+	}
+	hash := sha256.Sum256(body)
+	if err := c.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), signingName, c.region, time.Now()); err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to sign bedrock request")
+	}
+	return c.httpClient.Do(req)
+}
+
+// apiError converts a Bedrock error response into an error
+func apiError(ctx context.Context, resp *http.Response) error {
+	var body struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err := json.Unmarshal(data, &body); err != nil || body.Message == "" {
+		body.Message = strings.TrimSpace(string(data))
+	}
+
+	errorType := resp.Header.Get("X-Amzn-Errortype")
+	if i := strings.IndexByte(errorType, ':'); i >= 0 {
+		errorType = errorType[:i]
+	}
+	if errorType == "" {
+		errorType = http.StatusText(resp.StatusCode)
+	}
+	return calque.NewErr(ctx, fmt.Sprintf("bedrock %s (%d): %s", errorType, resp.StatusCode, body.Message))
+}
+
+// executeNonStreamingRequest calls Converse and writes the complete response
+func (c *Client) executeNonStreamingRequest(request *converseRequest, r *calque.Request, w *calque.Response, opts *ai.AgentOptions) error {
+	resp, err := c.send(r.Context, "converse", request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Output struct {
+			Message message `json:"message"`
+		} `json:"output"`
+		StopReason string     `json:"stopReason"`
+		Usage      tokenUsage `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return calque.WrapErr(r.Context, err, "failed to decode bedrock response")
+	}
+
+	ai.SetChatResponse(r.Context, resp.Header.Get("X-Amzn-Requestid"), "", result.StopReason)
+	reportUsage(r.Context, c.model, result.Usage, opts)
+
+	reasoning := ai.GetReasoning(opts)
+	var text strings.Builder
+	var calls []toolUse
+	for _, block := range result.Output.Message.Content {
+		switch {
+		case block.ToolUse != nil:
+			calls = append(calls, *block.ToolUse)
+		case block.ReasoningContent != nil && block.ReasoningContent.ReasoningText != nil:
+			if err := reasoning.Append(block.ReasoningContent.ReasoningText.Text); err != nil {
+				return err
+			}
+		default:
+			text.WriteString(block.Text)
+		}
+	}
+	if err := reasoning.Finish(r.Context); err != nil {
+		return err
+	}
+
+	return writeResult(request, text.String(), calls, w)
+}
+
+// pendingToolUse accumulates a tool call streamed in pieces
+type pendingToolUse struct {
+	toolUse
+	input strings.Builder
+}
+
+// executeStreamingRequest calls ConverseStream and writes text as it arrives
+func (c *Client) executeStreamingRequest(request *converseRequest, r *calque.Request, w *calque.Response, opts *ai.AgentOptions) error {
+	resp, err := c.send(r.Context, "converse-stream", request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Tool calls and schema responses are written once complete
+	shouldBuffer := request.ToolConfig != nil || request.jsonResponse
+	reasoning := ai.GetReasoning(opts)
+
+	var text strings.Builder
+	var calls []*pendingToolUse
+	blocks := make(map[int]*pendingToolUse)
+	var stopReason string
+	var usage tokenUsage
+
+	decoder := eventstream.NewDecoder()
+	var payload []byte
+	for {
+		msg, err := decoder.Decode(resp.Body, payload)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return calque.WrapErr(r.Context, err, "failed to read bedrock stream")
+		}
+		payload = msg.Payload[:0]
+
+		if messageType := header(msg, ":message-type"); messageType != "event" {
+			return streamError(r.Context, msg)
+		}
+
+		var event struct {
+			ContentBlockIndex int `json:"contentBlockIndex"`
+			Start             struct {
+				ToolUse *toolUse `json:"toolUse"`
+			} `json:"start"`
+			Delta struct {
+				Text    string `json:"text"`
+				ToolUse *struct {
+					Input string `json:"input"`
+				} `json:"toolUse"`
+				ReasoningContent *reasoningContent `json:"reasoningContent"`
+			} `json:"delta"`
+			StopReason string     `json:"stopReason"`
+			Usage      tokenUsage `json:"usage"`
+		}
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			return calque.WrapErr(r.Context, err, "failed to decode bedrock stream event")
+		}
+
+		switch header(msg, ":event-type") {
+		case "contentBlockStart":
+			if event.Start.ToolUse != nil {
+				call := &pendingToolUse{toolUse: *event.Start.ToolUse}
+				blocks[event.ContentBlockIndex] = call
+				calls = append(calls, call)
+			}
+		case "contentBlockDelta":
+			switch {
+			case event.Delta.ToolUse != nil:
+				if call, ok := blocks[event.ContentBlockIndex]; ok {
+					call.input.WriteString(event.Delta.ToolUse.Input)
+				}
+			case event.Delta.ReasoningContent != nil:
+				if err := reasoning.Append(event.Delta.ReasoningContent.Text); err != nil {
+					return err
+				}
+			case event.Delta.Text == "":
+			case shouldBuffer:
+				text.WriteString(event.Delta.Text)
+			default:
+				if _, err := w.Data.Write([]byte(event.Delta.Text)); err != nil {
+					return err
+				}
+			}
+		case "messageStop":
+			stopReason = event.StopReason
+		case "metadata":
+			usage = event.Usage
+		}
+	}
+
+	ai.SetChatResponse(r.Context, resp.Header.Get("X-Amzn-Requestid"), "", stopReason)
+	reportUsage(r.Context, c.model, usage, opts)
+	if err := reasoning.Finish(r.Context); err != nil {
+		return err
+	}
+
+	toolCalls := make([]toolUse, len(calls))
+	for i, call := range calls {
+		toolCalls[i] = call.toolUse
+		toolCalls[i].Input = json.RawMessage(call.input.String())
+	}
+	if !shouldBuffer {
+		return nil
+	}
+	return writeResult(request, text.String(), toolCalls, w)
+}
+
+// header returns an event stream header as a string, "" if it is missing
+func header(msg eventstream.Message, name string) string {
+	if v := msg.Headers.Get(name); v != nil {
+		return v.String()
+	}
+	return ""
+}
+
+// streamError converts an exception sent on the event stream into an error
+func streamError(ctx context.Context, msg eventstream.Message) error {
+	var body struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(msg.Payload, &body)
+	errorType := header(msg, ":exception-type")
+	if errorType == "" {
+		errorType = header(msg, ":error-code")
+	}
+	if body.Message == "" {
+		body.Message = header(msg, ":error-message")
+	}
+	return calque.NewErr(ctx, fmt.Sprintf("bedrock stream %s: %s", errorType, body.Message))
+}
+
+// reportUsage records token usage on the span and reports it to the usage handler
+func reportUsage(ctx context.Context, model string, usage tokenUsage, opts *ai.AgentOptions) {
+	if usage.InputTokens == 0 && usage.OutputTokens == 0 {
+		return
+	}
+	metadata := &ai.UsageMetadata{
+		PromptTokens:     usage.InputTokens,
+		CompletionTokens: usage.OutputTokens,
+		TotalTokens:      usage.TotalTokens,
+		Model:            model,
+	}
+	if metadata.TotalTokens == 0 {
+		metadata.TotalTokens = usage.InputTokens + usage.OutputTokens
+	}
+	ai.SetChatUsage(ctx, metadata)
+	if opts != nil && opts.UsageHandler != nil {
+		opts.UsageHandler(metadata)
+	}
+}
+
+// writeResult writes tool calls in the OpenAI format the agent expects, or
+// the buffered text, with code fences removed for schema responses
+func writeResult(request *converseRequest, text string, calls []toolUse, w *calque.Response) error {
+	if len(calls) > 0 {
+		return writeToolCalls(calls, w)
+	}
+	if request.jsonResponse {
+		text = cleanJSONResponse(text)
+	}
+	if text == "" {
+		return nil
+	}
+	_, err := w.Data.Write([]byte(text))
+	return err
+}
+
+// writeToolCalls formats Bedrock tool calls for the agent framework
+func writeToolCalls(calls []toolUse, w *calque.Response) error {
+	formatted := make([]map[string]any, len(calls))
+	for i, call := range calls {
+		arguments := string(call.Input)
+		if strings.TrimSpace(arguments) == "" {
+			arguments = "{}"
+		}
+		formatted[i] = map[string]any{
+			"id":   call.ToolUseID,
+			"type": "function",
+			"function": map[string]any{
+				"name":      call.Name,
+				"arguments": arguments,
+			},
+		}
+	}
+
+	jsonBytes, err := json.Marshal(map[string]any{"tool_calls": formatted})
+	if err != nil {
+		return err
+	}
+	_, err = w.Data.Write(jsonBytes)
+	return err
+}
+
+// cleanJSONResponse removes the markdown code fences models often wrap JSON in
+func cleanJSONResponse(content string) string {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+	return strings.TrimSpace(content)
+}
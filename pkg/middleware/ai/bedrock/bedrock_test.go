@@ -0,0 +1,383 @@
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/helpers"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+const testModel = "anthropic.claude-3-5-sonnet-20240620-v1:0"
+
+var testCredentials = aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+	return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+})
+
+// streamEvent is one event in a fake ConverseStream response
+type streamEvent struct {
+	eventType string
+	payload   string
+}
+
+// encodeStream encodes events as an AWS event stream
+func encodeStream(t *testing.T, events []streamEvent) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	encoder := eventstream.NewEncoder()
+	for _, e := range events {
+		msg := eventstream.Message{Payload: []byte(e.payload)}
+		msg.Headers.Set(":message-type", eventstream.StringValue("event"))
+		msg.Headers.Set(":event-type", eventstream.StringValue(e.eventType))
+		msg.Headers.Set(":content-type", eventstream.StringValue("application/json"))
+		if err := encoder.Encode(&buf, msg); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// fakeBedrock serves one canned response and records the request
+type fakeBedrock struct {
+	path   string
+	auth   string
+	gotHdr http.Header
+	body   map[string]any
+	status int
+	header http.Header
+	reply  []byte
+}
+
+func (f *fakeBedrock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.path = r.URL.EscapedPath()
+	f.auth = r.Header.Get("Authorization")
+	f.gotHdr = r.Header.Clone()
+	data, _ := io.ReadAll(r.Body)
+	_ = json.Unmarshal(data, &f.body)
+	for k, v := range f.header {
+		w.Header()[k] = v
+	}
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+	}
+	_, _ = w.Write(f.reply)
+}
+
+func newTestClient(t *testing.T, fake *fakeBedrock, cfg *Config) *Client {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	cfg.Region = "us-west-2"
+	cfg.Endpoint = server.URL
+	cfg.Credentials = testCredentials
+	client, err := New(testModel, WithConfig(cfg))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return client
+}
+
+func chat(t *testing.T, client *Client, input string, opts *ai.AgentOptions) (string, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	err := client.Chat(calque.NewRequest(context.Background(), strings.NewReader(input)), calque.NewResponse(&buf), opts)
+	return buf.String(), err
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+
+	tests := []struct {
+		name         string
+		model        string
+		config       *Config
+		wantRegion   string
+		wantModel    string
+		wantEndpoint string
+		wantErr      string
+	}{
+		{
+			name:         "model with region",
+			model:        testModel,
+			config:       &Config{Region: "eu-central-1"},
+			wantRegion:   "eu-central-1",
+			wantModel:    testModel,
+			wantEndpoint: "https://bedrock-runtime.eu-central-1.amazonaws.com",
+		},
+		{
+			name:         "region from model ARN",
+			config:       &Config{ModelARN: "arn:aws:bedrock:ap-southeast-2::foundation-model/meta.llama3-1-70b-instruct-v1:0"},
+			wantRegion:   "ap-southeast-2",
+			wantModel:    "arn:aws:bedrock:ap-southeast-2::foundation-model/meta.llama3-1-70b-instruct-v1:0",
+			wantEndpoint: "https://bedrock-runtime.ap-southeast-2.amazonaws.com",
+		},
+		{
+			name:         "custom endpoint",
+			model:        testModel,
+			config:       &Config{Region: "us-east-1", Endpoint: "https://vpce.example.com/"},
+			wantRegion:   "us-east-1",
+			wantModel:    testModel,
+			wantEndpoint: "https://vpce.example.com",
+		},
+		{name: "missing model", config: &Config{Region: "us-east-1"}, wantErr: "model ID or ARN is required"},
+		{name: "missing region", model: testModel, wantErr: "AWS region not set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.config != nil {
+				opts = append(opts, WithConfig(tt.config))
+			}
+			client, err := New(tt.model, opts...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("New() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if client.region != tt.wantRegion || client.model != tt.wantModel || client.endpoint != tt.wantEndpoint {
+				t.Errorf("New() = region %q, model %q, endpoint %q; want %q, %q, %q",
+					client.region, client.model, client.endpoint, tt.wantRegion, tt.wantModel, tt.wantEndpoint)
+			}
+			if hosts := client.Egress(); len(hosts) != 1 || !strings.Contains(tt.wantEndpoint, hosts[0]) {
+				t.Errorf("Egress() = %v, want host of %s", hosts, tt.wantEndpoint)
+			}
+		})
+	}
+}
+
+func TestChatConverse(t *testing.T) {
+	fake := &fakeBedrock{
+		header: http.Header{"X-Amzn-Requestid": {"req-1"}},
+		reply: []byte(`{
+			"output": {"message": {"role": "assistant", "content": [{"text": "Hello from Bedrock"}]}},
+			"stopReason": "end_turn",
+			"usage": {"inputTokens": 12, "outputTokens": 4, "totalTokens": 16}
+		}`),
+	}
+	client := newTestClient(t, fake, &Config{
+		Stream:      helpers.PtrOf(false),
+		MaxTokens:   helpers.PtrOf(256),
+		Temperature: helpers.PtrOf(float32(0.5)),
+		Stop:        []string{"END"},
+	})
+
+	var usage *ai.UsageMetadata
+	got, err := chat(t, client, "Hi", &ai.AgentOptions{UsageHandler: func(u *ai.UsageMetadata) { usage = u }})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if got != "Hello from Bedrock" {
+		t.Errorf("Chat() = %q, want %q", got, "Hello from Bedrock")
+	}
+
+	if want := "/model/" + testModel + "/converse"; fake.path != want {
+		t.Errorf("request path = %q, want %q", fake.path, want)
+	}
+	if !strings.HasPrefix(fake.auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(fake.auth, "/us-west-2/bedrock/aws4_request") {
+		t.Errorf("Authorization = %q, want a SigV4 signature for us-west-2/bedrock", fake.auth)
+	}
+
+	messages, _ := json.Marshal(fake.body["messages"])
+	if string(messages) != `[{"content":[{"text":"Hi"}],"role":"user"}]` {
+		t.Errorf("messages = %s", messages)
+	}
+	inference, _ := json.Marshal(fake.body["inferenceConfig"])
+	if string(inference) != `{"maxTokens":256,"stopSequences":["END"],"temperature":0.5}` {
+		t.Errorf("inferenceConfig = %s", inference)
+	}
+
+	if usage == nil || usage.PromptTokens != 12 || usage.CompletionTokens != 4 || usage.TotalTokens != 16 || usage.Model != testModel {
+		t.Errorf("usage = %+v, want 12/4/16 for %s", usage, testModel)
+	}
+}
+
+func TestChatInterceptorIsSigned(t *testing.T) {
+	fake := &fakeBedrock{
+		reply: []byte(`{"output": {"message": {"role": "assistant", "content": [{"text": "ok"}]}}, "stopReason": "end_turn"}`),
+	}
+	client := newTestClient(t, fake, &Config{Stream: helpers.PtrOf(false)})
+
+	opts := &ai.AgentOptions{}
+	ai.WithInterceptor(func(req *http.Request, next ai.InterceptorNext) (*http.Response, error) {
+		req.Header.Set("X-Gateway-Route", "claude-primary")
+		body := `{"messages":[{"role":"user","content":[{"text":"rewritten"}]}]}`
+		req.Body = io.NopCloser(strings.NewReader(body))
+		req.ContentLength = int64(len(body))
+		return next(req)
+	}).Apply(opts)
+
+	if _, err := chat(t, client, "Hi", opts); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if fake.gotHdr.Get("X-Gateway-Route") != "claude-primary" {
+		t.Errorf("X-Gateway-Route = %q, want the interceptor's header", fake.gotHdr.Get("X-Gateway-Route"))
+	}
+	if !strings.Contains(fake.auth, "x-gateway-route") {
+		t.Errorf("Authorization = %q, want the interceptor's header signed", fake.auth)
+	}
+	if messages, _ := json.Marshal(fake.body["messages"]); string(messages) != `[{"content":[{"text":"rewritten"}],"role":"user"}]` {
+		t.Errorf("messages = %s, want the rewritten body", messages)
+	}
+}
+
+func TestChatConverseStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []streamEvent
+		opts   *ai.AgentOptions
+		want   string
+	}{
+		{
+			name: "text",
+			events: []streamEvent{
+				{"messageStart", `{"role":"assistant"}`},
+				{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"Hello"}}`},
+				{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":", world"}}`},
+				{"contentBlockStop", `{"contentBlockIndex":0}`},
+				{"messageStop", `{"stopReason":"end_turn"}`},
+				{"metadata", `{"usage":{"inputTokens":3,"outputTokens":2,"totalTokens":5}}`},
+			},
+			want: "Hello, world",
+		},
+		{
+			name: "tool call",
+			events: []streamEvent{
+				{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"Let me check."}}`},
+				{"contentBlockStart", `{"contentBlockIndex":1,"start":{"toolUse":{"toolUseId":"tool-1","name":"weather"}}}`},
+				{"contentBlockDelta", `{"contentBlockIndex":1,"delta":{"toolUse":{"input":"{\"input\":"}}}`},
+				{"contentBlockDelta", `{"contentBlockIndex":1,"delta":{"toolUse":{"input":"\"Paris\"}"}}}`},
+				{"messageStop", `{"stopReason":"tool_use"}`},
+				{"metadata", `{"usage":{"inputTokens":3,"outputTokens":2,"totalTokens":5}}`},
+			},
+			opts: &ai.AgentOptions{Tools: []tools.Tool{
+				tools.Simple("weather", "Looks up the weather", func(string) string { return "sunny" }),
+			}},
+			want: `{"tool_calls":[{"function":{"arguments":"{\"input\":\"Paris\"}","name":"weather"},"id":"tool-1","type":"function"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeBedrock{reply: encodeStream(t, tt.events)}
+			client := newTestClient(t, fake, nil)
+
+			opts := tt.opts
+			if opts == nil {
+				opts = &ai.AgentOptions{}
+			}
+			var usage *ai.UsageMetadata
+			opts.UsageHandler = func(u *ai.UsageMetadata) { usage = u }
+
+			got, err := chat(t, client, "What's the weather in Paris?", opts)
+			if err != nil {
+				t.Fatalf("Chat() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Chat() = %s, want %s", got, tt.want)
+			}
+			if !strings.HasSuffix(fake.path, "/converse-stream") {
+				t.Errorf("request path = %q, want converse-stream", fake.path)
+			}
+			if usage == nil || usage.TotalTokens != 5 {
+				t.Errorf("usage = %+v, want 5 total tokens", usage)
+			}
+			if tt.opts != nil {
+				tools, _ := json.Marshal(fake.body["toolConfig"])
+				if !strings.Contains(string(tools), `"toolSpec":{"description":"Looks up the weather","inputSchema":{"json":`) {
+					t.Errorf("toolConfig = %s", tools)
+				}
+			}
+		})
+	}
+}
+
+func TestChatMessages(t *testing.T) {
+	fake := &fakeBedrock{reply: []byte(`{"output":{"message":{"content":[{"text":"{\"ok\":true}"}]}}}`)}
+	client := newTestClient(t, fake, &Config{Stream: helpers.PtrOf(false)})
+
+	input, _ := json.Marshal(ai.NewMessages(
+		ai.Message{Role: ai.RoleSystem, Content: "Be brief."},
+		ai.Message{Role: ai.RoleUser, Content: "First"},
+		ai.Message{Role: ai.RoleUser, Content: "Second"},
+		ai.Message{Role: ai.RoleAssistant, Content: "Answer"},
+		ai.Message{Role: ai.RoleUser, Content: "Third"},
+	))
+	got, err := chat(t, client, string(input), &ai.AgentOptions{})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if got != `{"ok":true}` {
+		t.Errorf("Chat() = %q", got)
+	}
+
+	system, _ := json.Marshal(fake.body["system"])
+	if string(system) != `[{"text":"Be brief."}]` {
+		t.Errorf("system = %s", system)
+	}
+	messages, _ := json.Marshal(fake.body["messages"])
+	want := `[{"content":[{"text":"First"},{"text":"Second"}],"role":"user"},{"content":[{"text":"Answer"}],"role":"assistant"},{"content":[{"text":"Third"}],"role":"user"}]`
+	if string(messages) != want {
+		t.Errorf("messages = %s, want %s", messages, want)
+	}
+}
+
+func TestChatErrors(t *testing.T) {
+	exception := eventstream.Message{Payload: []byte(`{"message":"Too many tokens"}`)}
+	exception.Headers.Set(":message-type", eventstream.StringValue("exception"))
+	exception.Headers.Set(":exception-type", eventstream.StringValue("throttlingException"))
+	var stream bytes.Buffer
+	if err := eventstream.NewEncoder().Encode(&stream, exception); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		fake    *fakeBedrock
+		wantErr string
+	}{
+		{
+			name: "http error",
+			fake: &fakeBedrock{
+				status: http.StatusForbidden,
+				header: http.Header{"X-Amzn-Errortype": {"AccessDeniedException:http://internal.amazon.com/"}},
+				reply:  []byte(`{"message":"You don't have access to the model"}`),
+			},
+			wantErr: "bedrock AccessDeniedException (403): You don't have access to the model",
+		},
+		{
+			name:    "stream exception",
+			fake:    &fakeBedrock{reply: stream.Bytes()},
+			wantErr: "bedrock stream throttlingException: Too many tokens",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, tt.fake, nil)
+			if _, err := chat(t, client, "Hi", &ai.AgentOptions{}); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Chat() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
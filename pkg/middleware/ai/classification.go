@@ -19,6 +19,8 @@ const (
 	MultimodalJSONInput
 	// MultimodalStreamingInput is multimodal input via streaming (e.g., multipart)
 	MultimodalStreamingInput
+	// MessagesInput is a multi-turn chat in the canonical Messages format
+	MessagesInput
 )
 
 // ClassifiedInput represents input after classification
@@ -27,6 +29,7 @@ type ClassifiedInput struct {
 	RawBytes   []byte
 	Text       string
 	Multimodal *MultimodalInput
+	Messages   *Messages
 }
 
 // ClassifyInput reads and classifies the input type for any AI client
@@ -60,6 +63,19 @@ func ClassifyInput(r *calque.Request, opts *AgentOptions) (*ClassifiedInput, err
 		}
 	}
 
+	// Try multi-turn messages; Text carries a transcript for text-only providers
+	if isMessagesJSON(inputBytes) {
+		var messages Messages
+		if json.Unmarshal(inputBytes, &messages) == nil && hasValidRoles(messages) {
+			return &ClassifiedInput{
+				Type:     MessagesInput,
+				RawBytes: inputBytes,
+				Text:     messages.Text(),
+				Messages: &messages,
+			}, nil
+		}
+	}
+
 	// Default to text
 	return &ClassifiedInput{
 		Type:     TextInput,
@@ -87,6 +103,27 @@ func isMultimodalJSON(data []byte) bool {
 	return true
 }
 
+// isMessagesJSON performs fast detection of the Messages format before unmarshaling
+func isMessagesJSON(data []byte) bool {
+	return json.Valid(data) &&
+		bytes.Contains(data, []byte(`"messages"`)) &&
+		bytes.Contains(data, []byte(`"role"`))
+}
+
+// hasValidRoles checks that every message uses a canonical role, so arbitrary
+// JSON that happens to have a "messages" field is still treated as text
+func hasValidRoles(messages Messages) bool {
+	if len(messages.Messages) == 0 {
+		return false
+	}
+	for _, msg := range messages.Messages {
+		if !validRole(msg.Role) {
+			return false
+		}
+	}
+	return true
+}
+
 // hasJSONData checks if multimodal input contains embedded data (simple approach)
 func hasJSONData(multimodal MultimodalInput) bool {
 	for _, part := range multimodal.Parts {
@@ -112,3 +149,11 @@ func GetTools(opts *AgentOptions) []tools.Tool {
 	}
 	return nil
 }
+
+// GetReasoning returns the reasoning trace from options, or nil when capture is not enabled
+func GetReasoning(opts *AgentOptions) *ReasoningTrace {
+	if opts != nil {
+		return opts.Reasoning
+	}
+	return nil
+}
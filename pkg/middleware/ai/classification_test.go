@@ -185,6 +185,21 @@ func TestClassifyInput(t *testing.T) {
 			expectedType: TextInput, // Empty parts should classify as text
 			expectError:  false,
 		},
+		{
+			name:         "chat messages",
+			input:        `{"messages": [{"role": "system", "content": "Be brief."}, {"role": "user", "content": "Hi"}]}`,
+			expectedType: MessagesInput,
+		},
+		{
+			name:         "user JSON with messages and unknown roles",
+			input:        `{"messages": [{"role": "admin", "content": "Hi"}]}`,
+			expectedType: TextInput,
+		},
+		{
+			name:         "empty messages",
+			input:        `{"messages": [], "role": "user"}`,
+			expectedType: TextInput,
+		},
 	}
 
 	for _, tt := range tests {
@@ -224,6 +239,10 @@ func TestClassifyInput(t *testing.T) {
 				if result.Multimodal != nil {
 					t.Error("ClassifyInput() multimodal should be nil for text input")
 				}
+			case MessagesInput:
+				if result.Messages == nil || result.Text != result.Messages.Text() {
+					t.Errorf("ClassifyInput() messages = %v, text = %q", result.Messages, result.Text)
+				}
 			case MultimodalJSONInput, MultimodalStreamingInput:
 				if result.Multimodal == nil {
 					t.Error("ClassifyInput() multimodal should not be nil for multimodal input")
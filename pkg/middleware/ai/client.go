@@ -1,9 +1,12 @@
 package ai
 
 import (
+	"context"
+
 	"github.com/invopop/jsonschema"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
 )
 
 // Client interface for AI providers.
@@ -20,6 +23,48 @@ type Client interface {
 	Chat(r *calque.Request, w *calque.Response, opts *AgentOptions) error
 }
 
+// SeedCapable is implemented by clients that send the seed of a
+// calque.Deterministic run to their provider, making sampling reproducible.
+//
+// Agents record a nondeterministic point for other clients in deterministic runs.
+type SeedCapable interface {
+	// SupportsSeed reports whether requests carry calque.DeterministicSeed
+	SupportsSeed() bool
+}
+
+// supportsSeed reports whether a client makes sampling reproducible in deterministic runs
+func supportsSeed(client Client) bool {
+	c, ok := client.(SeedCapable)
+	return ok && c.SupportsSeed()
+}
+
+// ModelSettings are the model and sampling settings of a client's calls
+type ModelSettings struct {
+	Model       string   `json:"model"`
+	Temperature *float32 `json:"temperature,omitempty"`
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+}
+
+// ModelDescriber is implemented by clients that report the settings a call
+// in ctx would use, including any Overrides.
+//
+// Response caching keys on them, so a completion is never served for a
+// different model or temperature than the one it came from.
+type ModelDescriber interface {
+	ModelSettings(ctx context.Context) ModelSettings
+}
+
+// ToolResultStreamer is implemented by clients whose provider accepts tool
+// output while the tool is still running, e.g. over a realtime session.
+//
+// Agents pass each partial result of a running tool to StreamToolResult.
+// Partial results from tools run with other clients are reported as
+// calque.Progress events instead. The complete result is sent as usual once
+// the tool finishes.
+type ToolResultStreamer interface {
+	StreamToolResult(ctx context.Context, partial tools.PartialResult) error
+}
+
 // ResponseFormat defines structured output requirements.
 //
 // Configures AI models to return structured JSON responses according to
@@ -34,6 +79,7 @@ type Client interface {
 type ResponseFormat struct {
 	Type   string             `json:"type"`             // "json_object" or "json_schema"
 	Schema *jsonschema.Schema `json:"schema,omitempty"` // JSON schema for validation
+	Strict bool               `json:"strict,omitempty"` // Require provider-enforced decoding, see WithStrictSchema
 }
 
 // UsageMetadata contains token usage information from AI requests.
@@ -47,7 +93,18 @@ type ResponseFormat struct {
 //		log.Printf("Total tokens: %d", usage.TotalTokens)
 //	}))
 type UsageMetadata struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	TotalTokens      int    `json:"total_tokens"`
+	Model            string `json:"model,omitempty"` // Model the client called, set by provider clients
+}
+
+// Usage converts the usage of one call for calque.RecordUsage
+func (u *UsageMetadata) Usage() calque.Usage {
+	return calque.Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+		Calls:            1,
+	}
 }
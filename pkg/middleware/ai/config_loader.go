@@ -0,0 +1,466 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/goccy/go-yaml"
+	"github.com/hbollon/go-edlib"
+	"github.com/joho/godotenv"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// modulePath limits which nested structs LoadConfig descends into
+const modulePath = "github.com/calque-ai/go-calque/"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// ConfigSources selects where LoadConfig reads settings from.
+//
+// Keys are the snake_case form of the config's field names: MaxTokens is
+// max_tokens in a file and <EnvPrefix>_MAX_TOKENS in the environment. Nested
+// configs such as Network use nested file keys and joined variable names,
+// e.g. OPENAI_NETWORK_PROXY_URL.
+type ConfigSources struct {
+	// File is a YAML or JSON file. A missing file is an error.
+	File string
+
+	// Section is the top-level key of File holding this config, e.g. "openai",
+	// so one file can configure several providers (default: the whole file)
+	Section string
+
+	// DotEnv is a .env file. Its variables apply where the real environment has
+	// none, and a missing file is ignored.
+	DotEnv string
+
+	// EnvPrefix enables environment variables, e.g. "OPENAI" reads OPENAI_API_KEY
+	EnvPrefix string
+}
+
+// FieldError reports an invalid config field.
+//
+// Providers return them from Config.Validate, joined with errors.Join.
+// LoadConfig adds the source that set the value.
+type FieldError struct {
+	Field  string // Go field path, e.g. "Temperature" or "Network.ProxyURL"
+	Reason string // What is wrong, e.g. "must be between 0 and 2, got 3.5"
+	Source string // File or variable the value came from, empty when set in code
+}
+
+// Error implements error
+func (e *FieldError) Error() string {
+	if e.Source != "" {
+		return fmt.Sprintf("%s %s (from %s)", e.Field, e.Reason, e.Source)
+	}
+	return e.Field + " " + e.Reason
+}
+
+// CheckRange returns a FieldError when v is set and outside [minValue, maxValue]
+func CheckRange[T ~int | ~int32 | ~float32 | ~float64](field string, v *T, minValue, maxValue T) error {
+	if v != nil && (*v < minValue || *v > maxValue) {
+		return &FieldError{Field: field, Reason: fmt.Sprintf("must be between %v and %v, got %v", minValue, maxValue, *v)}
+	}
+	return nil
+}
+
+// CheckPositive returns a FieldError when v is set and not greater than zero
+func CheckPositive[T ~int | ~int32 | ~float32 | ~float64](field string, v *T) error {
+	if v != nil && *v <= 0 {
+		return &FieldError{Field: field, Reason: fmt.Sprintf("must be positive, got %v", *v)}
+	}
+	return nil
+}
+
+// CheckURL returns a FieldError when v is set and not an absolute http(s) URL
+func CheckURL(field, v string) error {
+	if v == "" {
+		return nil
+	}
+	if u, err := url.Parse(v); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &FieldError{Field: field, Reason: fmt.Sprintf("must be an http(s) URL, got %q", v)}
+	}
+	return nil
+}
+
+// LoadConfig layers settings from a file and the environment onto a provider config.
+//
+// Input: pointer to a provider Config holding the defaults, sources to read
+// Output: error listing every unknown key, unparsable value and invalid field
+// Behavior: defaults ← file ← .env ← environment; settings in code are applied
+// afterwards through the provider's WithConfig
+//
+// Only fields with plain values are loaded: strings, numbers, booleans,
+// durations, string lists and maps. Clients, tokenizers and credentials are set
+// in code. When the config has a Validate method it runs last, and each
+// problem names the file or variable that set the value.
+//
+// Example:
+//
+//	cfg := openai.DefaultConfig()
+//	if err := ai.LoadConfig(cfg, ai.ConfigSources{File: "calque.yaml", Section: "openai", DotEnv: ".env", EnvPrefix: "OPENAI"}); err != nil {
+//		log.Fatal(err) // e.g. "invalid config: Temperature must be between 0 and 2, got 3 (from $OPENAI_TEMPERATURE)"
+//	}
+//	client, err := openai.New("gpt-4o", openai.WithConfig(cfg), openai.WithConfig(&openai.Config{MaxTokens: helpers.PtrOf(500)}))
+func LoadConfig(cfg any, sources ConfigSources) error {
+	ctx := context.Background()
+	root := reflect.ValueOf(cfg)
+	if root.Kind() != reflect.Pointer || root.IsNil() || root.Elem().Kind() != reflect.Struct {
+		return calque.NewErr(ctx, fmt.Sprintf("LoadConfig needs a pointer to a config struct, got %T", cfg))
+	}
+
+	loader := &configLoader{sources: map[string]string{}}
+	if sources.File != "" {
+		if err := loader.loadFile(root.Elem(), sources.File, sources.Section); err != nil {
+			return calque.WrapErr(ctx, err, "failed to load config")
+		}
+	}
+	if sources.EnvPrefix != "" {
+		prefix := strings.ToUpper(sources.EnvPrefix)
+		env := map[string]string{}
+		if sources.DotEnv != "" {
+			dotEnv, err := godotenv.Read(sources.DotEnv)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return calque.WrapErr(ctx, err, "failed to read "+sources.DotEnv)
+			}
+			for name, value := range dotEnv {
+				env[name] = value
+				loader.dotEnv = append(loader.dotEnv, name)
+			}
+		}
+		for _, kv := range os.Environ() {
+			if name, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(name, prefix+"_") {
+				env[name] = value
+				loader.dotEnv = slices.DeleteFunc(loader.dotEnv, func(n string) bool { return n == name })
+			}
+		}
+		loader.loadEnv(root.Elem(), prefix, "", env, sources.DotEnv)
+	}
+
+	if validator, ok := cfg.(interface{ Validate() error }); ok {
+		if err := validator.Validate(); err != nil {
+			loader.addProblems(err)
+		}
+	}
+	if len(loader.problems) > 0 {
+		return calque.WrapErr(ctx, errors.Join(loader.problems...), "invalid config")
+	}
+	return nil
+}
+
+// configLoader accumulates problems and remembers where each field was set
+type configLoader struct {
+	sources  map[string]string // Go field path → source
+	dotEnv   []string          // variables that came from the .env file
+	problems []error
+}
+
+// loadFile assigns the values of a YAML or JSON file, or one section of it
+func (l *configLoader) loadFile(target reflect.Value, path, section string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return calque.WrapErr(context.Background(), err, "failed to parse "+path)
+	}
+	if section != "" {
+		nested, ok := values[section].(map[string]any)
+		if !ok {
+			return calque.NewErr(context.Background(), fmt.Sprintf("%s has no %q section", path, section))
+		}
+		values = nested
+	}
+	l.loadMap(target, values, "", section, path)
+	return nil
+}
+
+// loadMap assigns file values to the fields of target
+func (l *configLoader) loadMap(target reflect.Value, values map[string]any, fieldPrefix, keyPrefix, file string) {
+	fields := configFields(target.Type())
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+
+	for key, value := range values {
+		keyPath := joinKey(keyPrefix, key)
+		index, ok := fields[key]
+		if !ok {
+			reason := "is not a config field"
+			if suggestion, err := edlib.FuzzySearchThreshold(key, keys, 0.6, edlib.Levenshtein); err == nil && suggestion != "" {
+				reason += fmt.Sprintf("; did you mean %q?", suggestion)
+			}
+			l.problems = append(l.problems, &FieldError{Field: keyPath, Reason: reason, Source: file})
+			continue
+		}
+
+		sf := target.Type().Field(index)
+		field := target.Field(index)
+		fieldPath := joinKey(fieldPrefix, sf.Name)
+		if nested, ok := value.(map[string]any); ok && isNestedConfig(sf.Type) {
+			if field.Kind() == reflect.Pointer {
+				if field.IsNil() {
+					field.Set(reflect.New(sf.Type.Elem()))
+				}
+				field = field.Elem()
+			}
+			l.loadMap(field, nested, fieldPath, keyPath, file)
+			continue
+		}
+
+		if err := assignValue(field, value); err != nil {
+			l.problems = append(l.problems, &FieldError{Field: fieldPath, Reason: err.Error(), Source: file})
+			continue
+		}
+		l.sources[fieldPath] = file
+	}
+}
+
+// loadEnv assigns environment variables to the fields of target
+func (l *configLoader) loadEnv(target reflect.Value, varPrefix, fieldPrefix string, env map[string]string, dotEnvFile string) {
+	for _, index := range configFields(target.Type()) {
+		sf := target.Type().Field(index)
+		field := target.Field(index)
+		name := varPrefix + "_" + strings.ToUpper(snakeCase(sf.Name))
+		fieldPath := joinKey(fieldPrefix, sf.Name)
+
+		if isNestedConfig(sf.Type) {
+			nested := field
+			if field.Kind() == reflect.Pointer {
+				if !hasPrefixedVar(env, name+"_") {
+					continue
+				}
+				if field.IsNil() {
+					field.Set(reflect.New(sf.Type.Elem()))
+				}
+				nested = field.Elem()
+			}
+			l.loadEnv(nested, name, fieldPath, env, dotEnvFile)
+			continue
+		}
+
+		raw, ok := env[name]
+		if !ok {
+			continue
+		}
+		source := "$" + name
+		if slices.Contains(l.dotEnv, name) {
+			source = name + " in " + dotEnvFile
+		}
+		if err := assignString(field, raw); err != nil {
+			l.problems = append(l.problems, &FieldError{Field: fieldPath, Reason: err.Error(), Source: source})
+			continue
+		}
+		l.sources[fieldPath] = source
+	}
+}
+
+// addProblems records validation errors, adding the source of each field
+func (l *configLoader) addProblems(err error) {
+	var errs []error
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	} else {
+		errs = []error{err}
+	}
+	for _, e := range errs {
+		var fieldErr *FieldError
+		if errors.As(e, &fieldErr) && fieldErr.Source == "" {
+			fieldErr.Source = l.sources[fieldErr.Field]
+		}
+		l.problems = append(l.problems, e)
+	}
+}
+
+// configFields maps the snake_case keys of loadable fields to their index
+func configFields(t reflect.Type) map[string]int {
+	fields := map[string]int{}
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if sf.IsExported() && (isNestedConfig(sf.Type) || isLoadable(sf.Type)) {
+			fields[snakeCase(sf.Name)] = i
+		}
+	}
+	return fields
+}
+
+// isNestedConfig reports whether t is a struct of this module, or a pointer to one
+func isNestedConfig(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && strings.HasPrefix(t.PkgPath()+"/", modulePath)
+}
+
+// isLoadable reports whether a field of type t holds a plain value
+func isLoadable(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String
+	}
+	return false
+}
+
+// assignValue sets field from a decoded file value
+func assignValue(field reflect.Value, value any) error {
+	if s, ok := value.(string); ok {
+		return assignString(field, s)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	target := reflect.New(field.Type())
+	if err := json.Unmarshal(data, target.Interface()); err != nil {
+		return fmt.Errorf("cannot be set to %v: expected %s", value, describeType(field.Type()))
+	}
+	field.Set(target.Elem())
+	return nil
+}
+
+// assignString parses a string into field
+func assignString(field reflect.Value, raw string) error {
+	t := field.Type()
+	if t.Kind() == reflect.Pointer {
+		value := reflect.New(t.Elem())
+		if err := assignString(value.Elem(), raw); err != nil {
+			return err
+		}
+		field.Set(value)
+		return nil
+	}
+
+	invalid := fmt.Errorf("cannot be set to %q: expected %s", raw, describeType(t))
+	raw = strings.TrimSpace(raw)
+	switch {
+	case t == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return invalid
+		}
+		field.SetInt(int64(d))
+	case t.Kind() == reflect.String:
+		field.SetString(raw)
+	case t.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return invalid
+		}
+		field.SetBool(b)
+	case field.CanInt():
+		n, err := strconv.ParseInt(raw, 10, t.Bits())
+		if err != nil {
+			return invalid
+		}
+		field.SetInt(n)
+	case field.CanUint():
+		n, err := strconv.ParseUint(raw, 10, t.Bits())
+		if err != nil {
+			return invalid
+		}
+		field.SetUint(n)
+	case field.CanFloat():
+		f, err := strconv.ParseFloat(raw, t.Bits())
+		if err != nil {
+			return invalid
+		}
+		field.SetFloat(f)
+	case t.Kind() == reflect.Slice:
+		var items []string
+		for item := range strings.SplitSeq(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items).Convert(t))
+	case t.Kind() == reflect.Map:
+		target := reflect.New(t)
+		if err := json.Unmarshal([]byte(raw), target.Interface()); err != nil {
+			return fmt.Errorf("cannot be set to %q: expected a JSON object", raw)
+		}
+		field.Set(target.Elem())
+	default:
+		return fmt.Errorf("cannot be loaded from text")
+	}
+	return nil
+}
+
+// describeType names a field type for error messages
+func describeType(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType:
+		return "a duration such as 30s"
+	case t.Kind() == reflect.Bool:
+		return "true or false"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return "an integer"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return "a number"
+	case t.Kind() == reflect.Slice:
+		return "a list of strings"
+	case t.Kind() == reflect.Map:
+		return "a map"
+	}
+	return "a string"
+}
+
+// snakeCase converts a Go field name to snake_case, keeping initialisms
+// together: APIKey → api_key, CACertPEM → ca_cert_pem
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// joinKey joins a nested key or field path with a dot
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// hasPrefixedVar reports whether any variable name starts with prefix
+func hasPrefixedVar(env map[string]string, prefix string) bool {
+	for name := range env {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
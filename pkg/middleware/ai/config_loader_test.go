@@ -0,0 +1,201 @@
+package ai
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loaderConfig mirrors the shape of a provider config
+type loaderConfig struct {
+	APIKey      string
+	BaseURL     string
+	Temperature *float32
+	MaxTokens   *int
+	Stop        []string
+	Headers     map[string]string
+	Stream      bool
+	Timeout     time.Duration
+	Network     *NetworkConfig
+	Unsupported func() // not loadable, so not a config key
+}
+
+func (c *loaderConfig) Validate() error {
+	return errors.Join(
+		CheckURL("BaseURL", c.BaseURL),
+		CheckRange("Temperature", c.Temperature, 0, 2),
+		CheckPositive("MaxTokens", c.MaxTokens),
+	)
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"APIKey":      "api_key",
+		"BaseURL":     "base_url",
+		"CACertPEM":   "ca_cert_pem",
+		"ModelARN":    "model_arn",
+		"MaxTokens":   "max_tokens",
+		"TopK":        "top_k",
+		"Temperature": "temperature",
+	}
+	for in, want := range tests {
+		if got := snakeCase(in); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := writeFile(t, "calque.yaml", `
+openai:
+  api_key: sk-file
+  temperature: 0.2
+  max_tokens: 500
+  stop: ["END", "STOP"]
+  headers:
+    X-Team: search
+  stream: true
+  timeout: 30s
+  network:
+    proxy_url: http://proxy.corp:3128
+gemini:
+  api_key: other
+`)
+	cfg := &loaderConfig{BaseURL: "https://api.default.test"}
+	if err := LoadConfig(cfg, ConfigSources{File: path, Section: "openai"}); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if cfg.APIKey != "sk-file" || *cfg.Temperature != 0.2 || *cfg.MaxTokens != 500 || !cfg.Stream {
+		t.Errorf("scalars not loaded: %+v", cfg)
+	}
+	if len(cfg.Stop) != 2 || cfg.Headers["X-Team"] != "search" || cfg.Timeout != 30*time.Second {
+		t.Errorf("collections not loaded: stop %v, headers %v, timeout %v", cfg.Stop, cfg.Headers, cfg.Timeout)
+	}
+	if cfg.Network == nil || cfg.Network.ProxyURL != "http://proxy.corp:3128" {
+		t.Errorf("Network = %+v, want the proxy from the file", cfg.Network)
+	}
+	if cfg.BaseURL != "https://api.default.test" {
+		t.Errorf("BaseURL = %q, a default missing from the file should be kept", cfg.BaseURL)
+	}
+}
+
+func TestLoadConfigEnvLayers(t *testing.T) {
+	path := writeFile(t, "calque.json", `{"api_key": "sk-file", "max_tokens": 100}`)
+	dotEnv := writeFile(t, ".env", "TEST_API_KEY=sk-dotenv\nTEST_MAX_TOKENS=200\nTEST_STOP=a, b\n")
+	t.Setenv("TEST_MAX_TOKENS", "300")
+	t.Setenv("TEST_NETWORK_TIMEOUT", "5s")
+
+	cfg := &loaderConfig{}
+	if err := LoadConfig(cfg, ConfigSources{File: path, DotEnv: dotEnv, EnvPrefix: "test"}); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if cfg.APIKey != "sk-dotenv" {
+		t.Errorf("APIKey = %q, .env should override the file", cfg.APIKey)
+	}
+	if *cfg.MaxTokens != 300 {
+		t.Errorf("MaxTokens = %d, the environment should override .env", *cfg.MaxTokens)
+	}
+	if strings.Join(cfg.Stop, "|") != "a|b" {
+		t.Errorf("Stop = %q, want comma separated values", cfg.Stop)
+	}
+	if cfg.Network == nil || cfg.Network.Timeout != 5*time.Second {
+		t.Errorf("Network = %+v, want the timeout from TEST_NETWORK_TIMEOUT", cfg.Network)
+	}
+}
+
+func TestLoadConfigMissingDotEnv(t *testing.T) {
+	cfg := &loaderConfig{}
+	err := LoadConfig(cfg, ConfigSources{DotEnv: filepath.Join(t.TempDir(), ".env"), EnvPrefix: "TEST"})
+	if err != nil {
+		t.Errorf("LoadConfig() error = %v, a missing .env should be ignored", err)
+	}
+	if cfg.Network != nil {
+		t.Error("Network should stay nil without any of its variables")
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		file    string
+		env     map[string]string
+		sources ConfigSources
+		want    []string
+	}{
+		"unknown key": {
+			file: "temprature: 0.5\n",
+			want: []string{`temprature is not a config field; did you mean "temperature"?`},
+		},
+		"unknown nested key": {
+			file:    "openai:\n  network:\n    proxy: http://proxy\n",
+			sources: ConfigSources{Section: "openai"},
+			want:    []string{"openai.network.proxy is not a config field"},
+		},
+		"wrong type": {
+			file: "max_tokens: lots\n",
+			want: []string{`MaxTokens cannot be set to "lots": expected an integer`},
+		},
+		"missing section": {
+			file:    "gemini: {}\n",
+			sources: ConfigSources{Section: "openai"},
+			want:    []string{`has no "openai" section`},
+		},
+		"invalid env value": {
+			env:  map[string]string{"TEST_TIMEOUT": "soon"},
+			want: []string{`Timeout cannot be set to "soon": expected a duration such as 30s (from $TEST_TIMEOUT)`},
+		},
+		"validation names the source": {
+			file: "base_url: api.test\n",
+			env:  map[string]string{"TEST_TEMPERATURE": "3"},
+			want: []string{
+				"Temperature must be between 0 and 2, got 3 (from $TEST_TEMPERATURE)",
+				`BaseURL must be an http(s) URL, got "api.test" (from `,
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			sources := tt.sources
+			if tt.file != "" {
+				sources.File = writeFile(t, "calque.yaml", tt.file)
+			}
+			if tt.env != nil {
+				sources.EnvPrefix = "TEST"
+				for k, v := range tt.env {
+					t.Setenv(k, v)
+				}
+			}
+			err := LoadConfig(&loaderConfig{}, sources)
+			if err == nil {
+				t.Fatal("LoadConfig() should fail")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q should contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfigRejectsNonPointer(t *testing.T) {
+	if err := LoadConfig(loaderConfig{}, ConfigSources{}); err == nil {
+		t.Error("LoadConfig() should reject a config that is not a pointer")
+	}
+	if err := LoadConfig(&loaderConfig{}, ConfigSources{File: filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Error("LoadConfig() should fail for a missing file")
+	}
+}
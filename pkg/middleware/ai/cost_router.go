@@ -0,0 +1,267 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+type taskClassContextKey struct{}
+
+type budgetContextKey struct{}
+
+// CostRule selects a client for requests matching its conditions.
+//
+// All set conditions must hold for the rule to match; zero values are ignored.
+// Rules are evaluated in order and the first match wins, so list the cheapest
+// models first.
+//
+// Example:
+//
+//	ai.CostRule{Name: "local", Client: 0, MaxInputTokens: 500, TaskClasses: []string{"qa", "chat"}}
+type CostRule struct {
+	// Name identifies the rule in logs and RouteDecision
+	Name string
+	// Client is the index of the client (as passed to CostRouter) this rule routes to
+	Client int
+	// MinInputTokens is the smallest estimated input size this rule accepts
+	MinInputTokens int
+	// MaxInputTokens is the largest estimated input size this rule accepts (0: no limit)
+	MaxInputTokens int
+	// TaskClasses restricts the rule to these task classes (empty: any class)
+	TaskClasses []string
+	// MinBudget is the remaining budget required to use this rule; ignored when no budget is set
+	MinBudget float64
+}
+
+// CostRouterConfig holds configuration for the CostRouter middleware
+type CostRouterConfig struct {
+	// Rules are evaluated in order; the first matching rule picks the client
+	Rules []CostRule
+	// Fallback is the client index used when no rule matches (default: 0)
+	Fallback int
+	// Classifier derives a task class from the input when none is set with WithTaskClass
+	Classifier func(ctx context.Context, input []byte) (string, error)
+	// EstimateTokens estimates the input token count (default: tokenizer.Approximate)
+	EstimateTokens func(input []byte) int
+	// AgentOptions are applied to every routed agent
+	AgentOptions []AgentOption
+	// OnRoute is called with each routing decision, e.g. for cost accounting
+	OnRoute func(ctx context.Context, decision RouteDecision)
+}
+
+// RouteDecision describes why a CostRouter picked a client.
+type RouteDecision struct {
+	Rule        string   `json:"rule"`                 // Matching rule name, empty for the fallback
+	Client      int      `json:"client"`               // Index of the selected client
+	InputTokens int      `json:"input_tokens"`         // Estimated input tokens
+	TaskClass   string   `json:"task_class,omitempty"` // Task class from context or classifier
+	Budget      *float64 `json:"budget,omitempty"`     // Remaining budget, nil when not set
+}
+
+// WithTaskClass stores the task class used by CostRouter rules in the context.
+//
+// Example:
+//
+//	ctx = ai.WithTaskClass(ctx, "analysis")
+func WithTaskClass(ctx context.Context, class string) context.Context {
+	return context.WithValue(ctx, taskClassContextKey{}, class)
+}
+
+// TaskClass retrieves the task class from context.
+//
+// Returns empty string if no task class is set.
+func TaskClass(ctx context.Context) string {
+	if class, ok := ctx.Value(taskClassContextKey{}).(string); ok {
+		return class
+	}
+	return ""
+}
+
+// WithRemainingBudget stores the remaining spend budget used by CostRouter rules in the context.
+//
+// The unit is up to the caller (dollars, credits, tokens) as long as rule
+// MinBudget values use the same one.
+//
+// Example:
+//
+//	ctx = ai.WithRemainingBudget(ctx, account.Credits())
+func WithRemainingBudget(ctx context.Context, remaining float64) context.Context {
+	return context.WithValue(ctx, budgetContextKey{}, remaining)
+}
+
+// RemainingBudget retrieves the remaining budget from context.
+//
+// The second return value is false if no budget is set.
+func RemainingBudget(ctx context.Context) (float64, bool) {
+	remaining, ok := ctx.Value(budgetContextKey{}).(float64)
+	return remaining, ok
+}
+
+// CostRouter sends each request to the cheapest client whose rule it satisfies
+//
+// Input: string prompt/query
+// Output: string AI response from the selected client
+// Behavior: BUFFERED - reads the input to estimate its size before routing
+//
+// Each rule names a client by index and matches on estimated input tokens,
+// task class (set with WithTaskClass) and remaining budget (set with
+// WithRemainingBudget). Rules are checked in order and requests matching no
+// rule go to the first client. This keeps short questions on a small local
+// model and sends long analyses to a frontier model, and lets a shrinking
+// budget push traffic back to cheaper models.
+//
+// Example:
+//
+//	router := ai.CostRouter([]ai.CostRule{
+//		{Name: "small", Client: 0, MaxInputTokens: 500},
+//		{Name: "frontier", Client: 1, TaskClasses: []string{"analysis"}, MinBudget: 1.0},
+//	}, localClient, frontierClient)
+//	flow.Use(router)
+func CostRouter(rules []CostRule, clients ...Client) calque.Handler {
+	return CostRouterWithConfig(&CostRouterConfig{Rules: rules}, clients...)
+}
+
+// CostRouterWithConfig routes requests between clients with custom configuration
+//
+// Input: string prompt/query
+// Output: string AI response from the selected client
+// Behavior: BUFFERED - reads the input to estimate its size before routing
+//
+// Behaves like CostRouter, additionally classifying inputs that carry no task
+// class, applying agent options to every client and reporting each decision.
+//
+// Example:
+//
+//	router := ai.CostRouterWithConfig(&ai.CostRouterConfig{
+//		Rules:      rules,
+//		Fallback:   1,
+//		Classifier: func(_ context.Context, in []byte) (string, error) { return classify(in), nil },
+//		OnRoute:    func(_ context.Context, d ai.RouteDecision) { routed.WithLabelValues(d.Rule).Inc() },
+//	}, localClient, frontierClient)
+func CostRouterWithConfig(config *CostRouterConfig, clients ...Client) calque.Handler {
+	cfg := CostRouterConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.EstimateTokens == nil {
+		cfg.EstimateTokens = tokenizer.Approximate.CountTokens
+	}
+
+	r := &costRouter{config: cfg, agents: make([]calque.Handler, len(clients))}
+	for i, client := range clients {
+		r.agents[i] = Agent(client, cfg.AgentOptions...)
+	}
+	return r
+}
+
+// costRouter dispatches requests to per-client agents
+type costRouter struct {
+	config CostRouterConfig
+	agents []calque.Handler
+}
+
+func (r *costRouter) ServeFlow(req *calque.Request, res *calque.Response) error {
+	if len(r.agents) == 0 {
+		return calque.NewErr(req.Context, "no clients provided to cost router")
+	}
+
+	var input []byte
+	if err := calque.Read(req, &input); err != nil {
+		return err
+	}
+
+	decision, err := r.decide(req.Context, input)
+	if err != nil {
+		return err
+	}
+	if decision.Client < 0 || decision.Client >= len(r.agents) {
+		return calque.NewErr(req.Context, fmt.Sprintf("cost router rule %q selects client %d, only %d clients configured",
+			decision.Rule, decision.Client, len(r.agents)))
+	}
+
+	calque.LogDebug(req.Context, "cost router selected client",
+		"rule", decision.Rule, "client", decision.Client, "input_tokens", decision.InputTokens, "task_class", decision.TaskClass)
+	if r.config.OnRoute != nil {
+		r.config.OnRoute(req.Context, decision)
+	}
+
+	req.Data = bytes.NewReader(input)
+	return r.agents[decision.Client].ServeFlow(req, res)
+}
+
+// decide evaluates the rules against the input
+func (r *costRouter) decide(ctx context.Context, input []byte) (RouteDecision, error) {
+	decision := RouteDecision{
+		Client:      r.config.Fallback,
+		InputTokens: r.config.EstimateTokens(input),
+		TaskClass:   TaskClass(ctx),
+	}
+	if decision.TaskClass == "" && r.config.Classifier != nil {
+		class, err := r.config.Classifier(ctx, input)
+		if err != nil {
+			return decision, calque.WrapErr(ctx, err, "cost router classifier failed")
+		}
+		decision.TaskClass = class
+	}
+	if remaining, ok := RemainingBudget(ctx); ok {
+		decision.Budget = &remaining
+	}
+
+	for _, rule := range r.config.Rules {
+		if rule.matches(decision) {
+			decision.Rule = rule.Name
+			decision.Client = rule.Client
+			break
+		}
+	}
+	return decision, nil
+}
+
+func (rule CostRule) matches(d RouteDecision) bool {
+	if d.InputTokens < rule.MinInputTokens {
+		return false
+	}
+	if rule.MaxInputTokens > 0 && d.InputTokens > rule.MaxInputTokens {
+		return false
+	}
+	if len(rule.TaskClasses) > 0 && !slices.Contains(rule.TaskClasses, d.TaskClass) {
+		return false
+	}
+	if d.Budget != nil && *d.Budget < rule.MinBudget {
+		return false
+	}
+	return true
+}
+
+// Egress implements calque.Egress with the destinations of every client
+func (r *costRouter) Egress() []string {
+	values := make([]any, len(r.agents))
+	for i, agent := range r.agents {
+		values[i] = agent
+	}
+	return egressOf(values...)
+}
+
+// Warmup implements calque.Warmer by warming every client
+func (r *costRouter) Warmup(ctx context.Context) error {
+	var errs []error
+	for _, agent := range r.agents {
+		errs = append(errs, calque.WarmupHandler(ctx, agent))
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown implements calque.Shutdowner by shutting down every client
+func (r *costRouter) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, agent := range r.agents {
+		errs = append(errs, calque.ShutdownHandler(ctx, agent))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,133 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func runAgentFlow(ctx context.Context, h calque.Handler, input string) (string, error) {
+	var out string
+	err := calque.NewFlow().Use(h).Run(ctx, input, &out)
+	return out, err
+}
+
+func TestCostRouter(t *testing.T) {
+	rules := []CostRule{
+		{Name: "small", Client: 0, MaxInputTokens: 10},
+		{Name: "frontier", Client: 1, TaskClasses: []string{"analysis"}, MinBudget: 5},
+		{Name: "medium", Client: 2, MinInputTokens: 11},
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		class    string
+		budget   *float64
+		want     string
+		wantRule string
+	}{
+		{"short question", "what time is it?", "", nil, "local", "small"},
+		{"long analysis", strings.Repeat("quarterly revenue ", 20), "analysis", nil, "frontier", "frontier"},
+		{"analysis with budget", strings.Repeat("x", 200), "analysis", ptr(10.0), "frontier", "frontier"},
+		{"analysis out of budget", strings.Repeat("x", 200), "analysis", ptr(1.0), "medium", "medium"},
+		{"long chat", strings.Repeat("x", 200), "chat", nil, "medium", "medium"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decision RouteDecision
+			router := CostRouterWithConfig(&CostRouterConfig{
+				Rules:   rules,
+				OnRoute: func(_ context.Context, d RouteDecision) { decision = d },
+			},
+				NewMockClient("local").WithStreamDelay(0),
+				NewMockClient("frontier").WithStreamDelay(0),
+				NewMockClient("medium").WithStreamDelay(0))
+
+			ctx := context.Background()
+			if tt.class != "" {
+				ctx = WithTaskClass(ctx, tt.class)
+			}
+			if tt.budget != nil {
+				ctx = WithRemainingBudget(ctx, *tt.budget)
+			}
+
+			out, err := runAgentFlow(ctx, router, tt.input)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if out != tt.want || decision.Rule != tt.wantRule {
+				t.Errorf("routed to %q via rule %q, want %q via %q", out, decision.Rule, tt.want, tt.wantRule)
+			}
+			if decision.TaskClass != tt.class {
+				t.Errorf("decision task class = %q, want %q", decision.TaskClass, tt.class)
+			}
+		})
+	}
+}
+
+func TestCostRouterWithConfig_ClassifierAndFallback(t *testing.T) {
+	var classified int
+	router := CostRouterWithConfig(&CostRouterConfig{
+		Rules:    []CostRule{{Name: "code", Client: 0, TaskClasses: []string{"code"}}},
+		Fallback: 1,
+		Classifier: func(_ context.Context, input []byte) (string, error) {
+			classified++
+			if strings.Contains(string(input), "func") {
+				return "code", nil
+			}
+			return "other", nil
+		},
+	}, NewMockClient("coder").WithStreamDelay(0), NewMockClient("general").WithStreamDelay(0))
+
+	if out, _ := runAgentFlow(context.Background(), router, "fix this func"); out != "coder" {
+		t.Errorf("classified request routed to %q, want coder", out)
+	}
+	if out, _ := runAgentFlow(context.Background(), router, "write a poem"); out != "general" {
+		t.Errorf("unmatched request routed to %q, want fallback general", out)
+	}
+
+	// A class set in context takes precedence over the classifier
+	ctx := WithTaskClass(context.Background(), "code")
+	if out, _ := runAgentFlow(ctx, router, "write a poem"); out != "coder" || classified != 2 {
+		t.Errorf("context class routed to %q (classifier calls %d), want coder without classifying", out, classified)
+	}
+}
+
+func TestCostRouter_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler calque.Handler
+		wantErr string
+	}{
+		{"no clients", CostRouter(nil), "no clients"},
+		{"rule out of range", CostRouter([]CostRule{{Name: "bad", Client: 3}}, NewMockClient("a")), "selects client 3"},
+		{"classifier failure", CostRouterWithConfig(&CostRouterConfig{
+			Classifier: func(context.Context, []byte) (string, error) { return "", errors.New("boom") },
+		}, NewMockClient("a")), "classifier failed"},
+		{"client failure", CostRouter(nil, NewMockClientWithError("down")), "mock error: down"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := runAgentFlow(context.Background(), tt.handler, "hello"); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Run() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRemainingBudget(t *testing.T) {
+	if _, ok := RemainingBudget(context.Background()); ok {
+		t.Error("RemainingBudget() reported a budget on an empty context")
+	}
+	if got, ok := RemainingBudget(WithRemainingBudget(context.Background(), 0)); !ok || got != 0 {
+		t.Errorf("RemainingBudget() = %v, %v; want 0, true", got, ok)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,293 @@
+package ai
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"sync"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/observability"
+)
+
+type costTrackerContextKey struct{}
+
+// ModelPrice is what a model costs per million tokens, in any currency unit.
+//
+// Example:
+//
+//	ai.ModelPrice{Prompt: 2.50, Completion: 10.00} // gpt-4o, USD
+type ModelPrice struct {
+	Prompt     float64 // Price per million prompt (input) tokens
+	Completion float64 // Price per million completion (output) tokens
+}
+
+// Cost returns the price of one call's usage
+func (p ModelPrice) Cost(usage *UsageMetadata) float64 {
+	return (float64(usage.PromptTokens)*p.Prompt + float64(usage.CompletionTokens)*p.Completion) / 1e6
+}
+
+// CostTrackerConfig holds configuration for a CostTracker
+type CostTrackerConfig struct {
+	// Prices maps model names to their price. Names match by prefix, like
+	// ContextWindow, so "gpt-4o" also prices "gpt-4o-2024-08-06"; the longest
+	// matching name wins. Models without a price are counted but cost nothing.
+	Prices map[string]ModelPrice
+	// Metrics receives token and cost totals as calls are recorded (default: none)
+	Metrics observability.MetricsProvider
+	// Namespace prefixes metric names (default: "calque_ai")
+	Namespace string
+	// Labels are added to every metric, e.g. the service name
+	Labels map[string]string
+	// OnRecord is called with each recorded call, e.g. for a billing log
+	OnRecord func(ctx context.Context, record CostRecord)
+}
+
+// CostRecord describes one model call recorded by a CostTracker
+type CostRecord struct {
+	Model  string        `json:"model"`
+	Tenant string        `json:"tenant,omitempty"` // From calque.WithTenant, empty when not set
+	Usage  UsageMetadata `json:"usage"`
+	Cost   float64       `json:"cost"`
+	Priced bool          `json:"priced"` // False when no price matched the model
+}
+
+// CostTotals is usage and cost summed over many calls
+type CostTotals struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Calls            int     `json:"calls"`
+	UnpricedCalls    int     `json:"unpriced_calls,omitempty"` // Calls to models without a price
+	Cost             float64 `json:"cost"`
+}
+
+// add sums one record into the totals
+func (t *CostTotals) add(record CostRecord) {
+	t.PromptTokens += record.Usage.PromptTokens
+	t.CompletionTokens += record.Usage.CompletionTokens
+	t.TotalTokens += record.Usage.TotalTokens
+	t.Calls++
+	if !record.Priced {
+		t.UnpricedCalls++
+	}
+	t.Cost += record.Cost
+}
+
+// CostTracker prices model calls and keeps running totals per model and tenant.
+//
+// One tracker is meant to be shared by every agent and flow in a process.
+// Agents record into the tracker set with WithCostTracker, or else the one in
+// the request context (see ContextWithCostTracker), so a single tracker can
+// cover many flows without touching each agent. It is safe for concurrent use.
+//
+// Example:
+//
+//	costs := ai.NewCostTracker(&ai.CostTrackerConfig{
+//		Prices: map[string]ai.ModelPrice{
+//			"gpt-4o":           {Prompt: 2.50, Completion: 10.00},
+//			"gemini-2.0-flash": {Prompt: 0.10, Completion: 0.40},
+//		},
+//		Metrics: prometheusProvider,
+//	})
+//
+//	ctx := ai.ContextWithCostTracker(context.Background(), costs)
+//	ctx = calque.WithTenant(ctx, "acme")
+//	err := flow.Run(ctx, input, &output)
+//
+//	fmt.Printf("acme has spent $%.4f\n", costs.ByTenant()["acme"].Cost)
+type CostTracker struct {
+	config *CostTrackerConfig
+
+	mu       sync.Mutex
+	prices   map[string]ModelPrice // normalized name → price
+	total    CostTotals
+	byModel  map[string]*CostTotals
+	byTenant map[string]*CostTotals
+}
+
+// NewCostTracker creates a CostTracker. A nil config tracks tokens without prices or metrics.
+func NewCostTracker(config *CostTrackerConfig) *CostTracker {
+	if config == nil {
+		config = &CostTrackerConfig{}
+	}
+	if config.Namespace == "" {
+		config.Namespace = "calque_ai"
+	}
+	t := &CostTracker{
+		config:   config,
+		prices:   map[string]ModelPrice{},
+		byModel:  map[string]*CostTotals{},
+		byTenant: map[string]*CostTotals{},
+	}
+	for model, price := range config.Prices {
+		t.prices[normalizeModelName(model)] = price
+	}
+	return t
+}
+
+// SetPrice adds or replaces the price of a model, e.g. after a price change
+func (t *CostTracker) SetPrice(model string, price ModelPrice) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prices[normalizeModelName(model)] = price
+}
+
+// Price returns the price used for model. The second return value is false
+// when no configured name matches it.
+func (t *CostTracker) Price(model string) (ModelPrice, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.priceLocked(model)
+}
+
+func (t *CostTracker) priceLocked(model string) (ModelPrice, bool) {
+	name := normalizeModelName(model)
+	var best string
+	var found bool
+	for prefix := range t.prices {
+		if strings.HasPrefix(name, prefix) && (!found || len(prefix) > len(best)) {
+			best, found = prefix, true
+		}
+	}
+	return t.prices[best], found
+}
+
+// Record prices one call's usage and adds it to the totals.
+//
+// The model comes from usage.Model and the tenant from calque.WithTenant.
+// Agents call it for every model call; call it directly for usage reported
+// elsewhere, such as batch results.
+func (t *CostTracker) Record(ctx context.Context, usage *UsageMetadata) CostRecord {
+	if usage == nil {
+		return CostRecord{}
+	}
+	record := CostRecord{Model: usage.Model, Tenant: calque.Tenant(ctx), Usage: *usage}
+
+	t.mu.Lock()
+	price, ok := t.priceLocked(usage.Model)
+	if ok {
+		record.Cost, record.Priced = price.Cost(usage), true
+	}
+	t.total.add(record)
+	totalsFor(t.byModel, record.Model).add(record)
+	if record.Tenant != "" {
+		totalsFor(t.byTenant, record.Tenant).add(record)
+	}
+	t.mu.Unlock()
+
+	t.export(ctx, record)
+	if t.config.OnRecord != nil {
+		t.config.OnRecord(ctx, record)
+	}
+	return record
+}
+
+// export adds a record to the metrics provider's counters
+func (t *CostTracker) export(ctx context.Context, record CostRecord) {
+	metrics := t.config.Metrics
+	if metrics == nil {
+		return
+	}
+	labels := maps.Clone(t.config.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels["model"] = record.Model
+	labels["tenant"] = record.Tenant
+
+	name := func(metric string) string { return t.config.Namespace + "_" + metric }
+	metrics.Counter(ctx, name("calls_total"), 1, labels)
+	metrics.Counter(ctx, name("prompt_tokens_total"), int64(record.Usage.PromptTokens), labels)
+	metrics.Counter(ctx, name("completion_tokens_total"), int64(record.Usage.CompletionTokens), labels)
+	if record.Cost > 0 {
+		metrics.Gauge(ctx, name("cost_total"), record.Cost, labels) // Gauges add, and cost is fractional
+	}
+}
+
+// Total returns usage and cost across every recorded call
+func (t *CostTracker) Total() CostTotals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
+
+// ByModel returns the totals of each model
+func (t *CostTracker) ByModel() map[string]CostTotals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return snapshot(t.byModel)
+}
+
+// ByTenant returns the totals of each tenant. Calls without a tenant are only
+// counted in Total and ByModel.
+func (t *CostTracker) ByTenant() map[string]CostTotals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return snapshot(t.byTenant)
+}
+
+// Reset clears all totals, e.g. at the start of a billing period. Prices are kept.
+func (t *CostTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total = CostTotals{}
+	t.byModel = map[string]*CostTotals{}
+	t.byTenant = map[string]*CostTotals{}
+}
+
+// totalsFor returns the totals for key, creating them on first use
+func totalsFor(totals map[string]*CostTotals, key string) *CostTotals {
+	if totals[key] == nil {
+		totals[key] = &CostTotals{}
+	}
+	return totals[key]
+}
+
+// snapshot copies totals so callers can read them without the lock
+func snapshot(totals map[string]*CostTotals) map[string]CostTotals {
+	result := make(map[string]CostTotals, len(totals))
+	for key, value := range totals {
+		result[key] = *value
+	}
+	return result
+}
+
+// ContextWithCostTracker returns a context whose agents record their usage in tracker.
+//
+// Example:
+//
+//	ctx = ai.ContextWithCostTracker(ctx, costs)
+func ContextWithCostTracker(ctx context.Context, tracker *CostTracker) context.Context {
+	return context.WithValue(ctx, costTrackerContextKey{}, tracker)
+}
+
+// CostTrackerFromContext returns the tracker set with ContextWithCostTracker, or nil
+func CostTrackerFromContext(ctx context.Context) *CostTracker {
+	tracker, _ := ctx.Value(costTrackerContextKey{}).(*CostTracker)
+	return tracker
+}
+
+type costTrackerOption struct{ tracker *CostTracker }
+
+func (o costTrackerOption) Apply(opts *AgentOptions) { opts.CostTracker = o.tracker }
+
+// WithCostTracker records the agent's usage in tracker, instead of the one in
+// the request context.
+//
+// Example:
+//
+//	costs := ai.NewCostTracker(&ai.CostTrackerConfig{Prices: prices})
+//	support := ai.Agent(client, ai.WithCostTracker(costs))
+//	triage := ai.Agent(smallClient, ai.WithCostTracker(costs))
+func WithCostTracker(tracker *CostTracker) AgentOption {
+	return costTrackerOption{tracker: tracker}
+}
+
+// GetCostTracker returns the tracker set with WithCostTracker, or nil
+func GetCostTracker(opts *AgentOptions) *CostTracker {
+	if opts == nil {
+		return nil
+	}
+	return opts.CostTracker
+}
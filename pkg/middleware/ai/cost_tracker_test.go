@@ -0,0 +1,189 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/observability"
+)
+
+// modelClient reports fixed usage for its model on every call
+type modelClient struct {
+	model              string
+	prompt, completion int
+}
+
+func (c modelClient) Chat(r *calque.Request, w *calque.Response, opts *AgentOptions) error {
+	if _, err := io.Copy(io.Discard, r.Data); err != nil {
+		return err
+	}
+	if opts.UsageHandler != nil {
+		opts.UsageHandler(&UsageMetadata{PromptTokens: c.prompt, CompletionTokens: c.completion, TotalTokens: c.prompt + c.completion, Model: c.model})
+	}
+	return calque.Write(w, "ok")
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCostTrackerPrices(t *testing.T) {
+	tracker := NewCostTracker(&CostTrackerConfig{Prices: map[string]ModelPrice{
+		"gpt-4o":      {Prompt: 2.50, Completion: 10},
+		"gpt-4o-mini": {Prompt: 0.15, Completion: 0.60},
+		"claude-3-5":  {Prompt: 3, Completion: 15},
+	}})
+
+	tests := []struct {
+		model string
+		want  ModelPrice
+		found bool
+	}{
+		{"gpt-4o-2024-08-06", ModelPrice{Prompt: 2.50, Completion: 10}, true},
+		{"gpt-4o-mini-2024-07-18", ModelPrice{Prompt: 0.15, Completion: 0.60}, true}, // longest match wins
+		{"us.anthropic.claude-3-5-sonnet-20240620-v1:0", ModelPrice{Prompt: 3, Completion: 15}, true},
+		{"llama3.2:3b", ModelPrice{}, false},
+	}
+	for _, tt := range tests {
+		got, found := tracker.Price(tt.model)
+		if got != tt.want || found != tt.found {
+			t.Errorf("Price(%q) = %+v, %v, want %+v, %v", tt.model, got, found, tt.want, tt.found)
+		}
+	}
+
+	tracker.SetPrice("llama3", ModelPrice{Prompt: 0.01})
+	if _, found := tracker.Price("llama3.2:3b"); !found {
+		t.Error("SetPrice() should price matching models")
+	}
+}
+
+func TestCostTrackerRecord(t *testing.T) {
+	tracker := NewCostTracker(&CostTrackerConfig{Prices: map[string]ModelPrice{"gpt-4o": {Prompt: 2, Completion: 10}}})
+	ctx := calque.WithTenant(context.Background(), "acme")
+
+	record := tracker.Record(ctx, &UsageMetadata{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500, Model: "gpt-4o"})
+	if !record.Priced || !approxEqual(record.Cost, 0.007) || record.Tenant != "acme" {
+		t.Errorf("Record() = %+v, want a $0.007 charge to acme", record)
+	}
+	tracker.Record(context.Background(), &UsageMetadata{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, Model: "llama3.2"})
+
+	total := tracker.Total()
+	if total.Calls != 2 || total.UnpricedCalls != 1 || total.TotalTokens != 1515 || !approxEqual(total.Cost, 0.007) {
+		t.Errorf("Total() = %+v", total)
+	}
+	if byModel := tracker.ByModel(); byModel["llama3.2"].PromptTokens != 10 || byModel["gpt-4o"].Calls != 1 {
+		t.Errorf("ByModel() = %+v", byModel)
+	}
+	if byTenant := tracker.ByTenant(); len(byTenant) != 1 || byTenant["acme"].CompletionTokens != 500 {
+		t.Errorf("ByTenant() = %+v, want only acme", byTenant)
+	}
+
+	tracker.Reset()
+	if tracker.Total() != (CostTotals{}) || len(tracker.ByModel()) != 0 {
+		t.Error("Reset() should clear the totals")
+	}
+	if _, found := tracker.Price("gpt-4o"); !found {
+		t.Error("Reset() should keep prices")
+	}
+}
+
+func TestCostTrackerSharedAcrossFlows(t *testing.T) {
+	tracker := NewCostTracker(&CostTrackerConfig{Prices: map[string]ModelPrice{
+		"gpt-4o":           {Prompt: 1, Completion: 1},
+		"gemini-2.0-flash": {Prompt: 1, Completion: 1},
+	}})
+	support := calque.NewFlow().Use(Agent(modelClient{model: "gpt-4o", prompt: 100, completion: 50}))
+	triage := calque.NewFlow().Use(Agent(modelClient{model: "gemini-2.0-flash", prompt: 10, completion: 5}))
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tenant := "acme"
+			if i%2 == 1 {
+				tenant = "globex"
+			}
+			ctx := calque.WithTenant(ContextWithCostTracker(context.Background(), tracker), tenant)
+			var out string
+			if err := support.Run(ctx, "help", &out); err != nil {
+				t.Error(err)
+			}
+			if err := triage.Run(ctx, "route", &out); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if total := tracker.Total(); total.Calls != 20 || total.TotalTokens != 10*(150+15) {
+		t.Errorf("Total() = %+v, want 20 calls of both flows", total)
+	}
+	byTenant := tracker.ByTenant()
+	if byTenant["acme"].Calls != 10 || byTenant["globex"].Calls != 10 {
+		t.Errorf("ByTenant() = %+v, want 10 calls each", byTenant)
+	}
+	if got := tracker.ByModel()["gemini-2.0-flash"].PromptTokens; got != 100 {
+		t.Errorf("gemini prompt tokens = %d, want 100", got)
+	}
+}
+
+func TestWithCostTracker(t *testing.T) {
+	shared := NewCostTracker(nil)
+	agentOwn := NewCostTracker(nil)
+	flow := calque.NewFlow().
+		Use(Agent(modelClient{model: "a", prompt: 1, completion: 1}, WithCostTracker(agentOwn))).
+		Use(Agent(modelClient{model: "b", prompt: 1, completion: 1}))
+
+	var out string
+	if err := flow.Run(ContextWithCostTracker(context.Background(), shared), "hi", &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := agentOwn.ByModel()["a"]; !ok || agentOwn.Total().Calls != 1 {
+		t.Errorf("WithCostTracker tracker = %+v, want only model a", agentOwn.ByModel())
+	}
+	if _, ok := shared.ByModel()["b"]; !ok || shared.Total().Calls != 1 {
+		t.Errorf("context tracker = %+v, want only model b", shared.ByModel())
+	}
+	if GetCostTracker(nil) != nil {
+		t.Error("GetCostTracker(nil) should be nil")
+	}
+}
+
+func TestCostTrackerMetrics(t *testing.T) {
+	metrics := observability.NewInMemoryMetricsProvider()
+	var records []CostRecord
+	tracker := NewCostTracker(&CostTrackerConfig{
+		Prices:   map[string]ModelPrice{"gpt-4o": {Prompt: 2, Completion: 10}},
+		Metrics:  metrics,
+		Labels:   map[string]string{"service": "support"},
+		OnRecord: func(_ context.Context, record CostRecord) { records = append(records, record) },
+	})
+
+	ctx := calque.WithTenant(context.Background(), "acme")
+	for range 2 {
+		tracker.Record(ctx, &UsageMetadata{PromptTokens: 1000, CompletionTokens: 100, TotalTokens: 1100, Model: "gpt-4o"})
+	}
+
+	labels := map[string]string{"service": "support", "model": "gpt-4o", "tenant": "acme"}
+	if got := metrics.GetCounter("calque_ai_calls_total", labels); got != 2 {
+		t.Errorf("calls_total = %d, want 2", got)
+	}
+	if got := metrics.GetCounter("calque_ai_prompt_tokens_total", labels); got != 2000 {
+		t.Errorf("prompt_tokens_total = %d, want 2000", got)
+	}
+	if got := metrics.GetCounter("calque_ai_completion_tokens_total", labels); got != 200 {
+		t.Errorf("completion_tokens_total = %d, want 200", got)
+	}
+	if got := metrics.GetGauge("calque_ai_cost_total", labels); !approxEqual(got, 0.006) {
+		t.Errorf("cost_total = %v, want 0.006", got)
+	}
+	if len(records) != 2 {
+		t.Errorf("OnRecord called %d times, want 2", len(records))
+	}
+}
@@ -0,0 +1,228 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Judge scores an answer to a prompt between 0 (unusable) and 1 (excellent).
+type Judge func(ctx context.Context, prompt, answer []byte) (float64, error)
+
+// EscalateConfig holds configuration for the Escalate middleware
+type EscalateConfig struct {
+	// Threshold is the minimum judge score for the cheap answer to be kept (default: 0.7)
+	Threshold float64
+	// AgentOptions are applied to both the cheap and the strong agent
+	AgentOptions []AgentOption
+	// OnEscalate is called whenever a request is re-run on the strong client, with the
+	// cheap answer's score and the cheap client or judge error, if any
+	OnEscalate func(ctx context.Context, score float64, err error)
+}
+
+// Escalate answers with a cheap client and re-runs low-quality answers on a strong one
+//
+// Input: string prompt/query
+// Output: string AI response (cheap answer if it passes the judge, otherwise strong answer)
+// Behavior: BUFFERED - buffers the cheap answer so it can be scored before being written
+//
+// The cheap client answers every request first and the judge scores its answer.
+// Answers scoring at least 0.7 are returned as is; lower scores, cheap client
+// errors and judge errors transparently re-run the original prompt on the strong
+// client. Callers see a single answer either way.
+//
+// Use HeuristicJudge for a free structural check or LLMJudge to have a model grade
+// the answer.
+//
+// Example:
+//
+//	agent := ai.Escalate(localClient, frontierClient, ai.HeuristicJudge(20))
+//	flow.Use(agent)
+func Escalate(cheap, strong Client, judge Judge) calque.Handler {
+	return EscalateWithConfig(cheap, strong, judge, nil)
+}
+
+// EscalateWithConfig escalates low-quality answers with custom configuration
+//
+// Input: string prompt/query
+// Output: string AI response (cheap answer if it passes the judge, otherwise strong answer)
+// Behavior: BUFFERED - buffers the cheap answer so it can be scored before being written
+//
+// Behaves like Escalate using the configured threshold, and reports each
+// escalation so the escalation rate can be monitored.
+//
+// Example:
+//
+//	agent := ai.EscalateWithConfig(localClient, frontierClient, ai.LLMJudge(graderClient), &ai.EscalateConfig{
+//		Threshold:  0.8,
+//		OnEscalate: func(_ context.Context, score float64, _ error) { escalations.Inc() },
+//	})
+func EscalateWithConfig(cheap, strong Client, judge Judge, config *EscalateConfig) calque.Handler {
+	cfg := EscalateConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 0.7
+	}
+
+	return &escalateHandler{
+		cheap:  Agent(cheap, cfg.AgentOptions...),
+		strong: Agent(strong, cfg.AgentOptions...),
+		judge:  judge,
+		config: cfg,
+	}
+}
+
+// escalateHandler runs the cheap agent and falls back to the strong one
+type escalateHandler struct {
+	cheap  calque.Handler
+	strong calque.Handler
+	judge  Judge
+	config EscalateConfig
+}
+
+func (e *escalateHandler) ServeFlow(req *calque.Request, res *calque.Response) error {
+	var prompt []byte
+	if err := calque.Read(req, &prompt); err != nil {
+		return err
+	}
+
+	score, err := e.tryCheap(req.Context, prompt, res)
+	if err == nil && score >= e.config.Threshold {
+		return nil
+	}
+
+	calque.LogDebug(req.Context, "escalating to strong client", "score", score, "threshold", e.config.Threshold, "error", err)
+	if e.config.OnEscalate != nil {
+		e.config.OnEscalate(req.Context, score, err)
+	}
+
+	req.Data = bytes.NewReader(prompt)
+	return e.strong.ServeFlow(req, res)
+}
+
+// tryCheap answers with the cheap agent and writes the answer if it passes the judge
+func (e *escalateHandler) tryCheap(ctx context.Context, prompt []byte, res *calque.Response) (float64, error) {
+	var answer []byte
+	if err := calque.NewFlow().Use(e.cheap).Run(ctx, prompt, &answer); err != nil {
+		return 0, err
+	}
+
+	score := 1.0
+	if e.judge != nil {
+		var err error
+		if score, err = e.judge(ctx, prompt, answer); err != nil {
+			return 0, calque.WrapErr(ctx, err, "judge failed")
+		}
+	}
+	if score < e.config.Threshold {
+		return score, nil
+	}
+	return score, calque.Write(res, answer)
+}
+
+// Egress implements calque.Egress with the destinations of both clients
+func (e *escalateHandler) Egress() []string {
+	return egressOf(e.cheap, e.strong)
+}
+
+// Warmup implements calque.Warmer by warming both clients
+func (e *escalateHandler) Warmup(ctx context.Context) error {
+	return errors.Join(calque.WarmupHandler(ctx, e.cheap), calque.WarmupHandler(ctx, e.strong))
+}
+
+// Shutdown implements calque.Shutdowner by shutting down both clients
+func (e *escalateHandler) Shutdown(ctx context.Context) error {
+	return errors.Join(calque.ShutdownHandler(ctx, e.cheap), calque.ShutdownHandler(ctx, e.strong))
+}
+
+// refusalMarkers are phrases typical of answers that dodge the question
+var refusalMarkers = []string{
+	"i don't know",
+	"i do not know",
+	"i'm not sure",
+	"i am not sure",
+	"i cannot help",
+	"i can't help",
+	"i'm unable to",
+	"i am unable to",
+	"as an ai",
+}
+
+// HeuristicJudge scores answers on structure alone, without a model call.
+//
+// Empty answers, answers that are not valid UTF-8 and refusals ("I don't know",
+// "as an AI", ...) score 0. Otherwise answers shorter than minChars runes score
+// proportionally less, and answers at or above it score 1.
+//
+// Example:
+//
+//	agent := ai.Escalate(cheap, strong, ai.HeuristicJudge(40))
+func HeuristicJudge(minChars int) Judge {
+	return func(_ context.Context, _, answer []byte) (float64, error) {
+		text := strings.TrimSpace(string(answer))
+		if text == "" || !utf8.ValidString(text) {
+			return 0, nil
+		}
+
+		lower := strings.ToLower(text)
+		for _, marker := range refusalMarkers {
+			if strings.Contains(lower, marker) {
+				return 0, nil
+			}
+		}
+
+		if length := utf8.RuneCountInString(text); minChars > 0 && length < minChars {
+			return float64(length) / float64(minChars), nil
+		}
+		return 1, nil
+	}
+}
+
+// JudgeVerdict is the structured output LLMJudge requests from the grading model
+type JudgeVerdict struct {
+	Score  float64 `json:"score" jsonschema:"required,minimum=0,maximum=1,description=Answer quality from 0 (wrong or unhelpful) to 1 (correct and complete)"`
+	Reason string  `json:"reason,omitempty" jsonschema:"description=Brief justification for the score"`
+}
+
+// LLMJudge scores answers by asking a grading model for a JudgeVerdict.
+//
+// The grader sees the original prompt and the answer and returns a score
+// between 0 and 1 using structured output. Scores outside that range are clamped.
+//
+// Example:
+//
+//	agent := ai.Escalate(cheap, strong, ai.LLMJudge(graderClient))
+func LLMJudge(client Client) Judge {
+	grader := Agent(client, WithSchema(&JudgeVerdict{}))
+
+	return func(ctx context.Context, prompt, answer []byte) (float64, error) {
+		gradingPrompt := fmt.Sprintf(`Grade how well the answer responds to the question.
+
+Question:
+%s
+
+Answer:
+%s
+
+Score from 0 (wrong, evasive or unhelpful) to 1 (correct, complete and direct).`, prompt, answer)
+
+		var output []byte
+		if err := calque.NewFlow().Use(grader).Run(ctx, gradingPrompt, &output); err != nil {
+			return 0, err
+		}
+
+		var verdict JudgeVerdict
+		if err := json.Unmarshal(output, &verdict); err != nil {
+			return 0, calque.WrapErr(ctx, err, "failed to parse judge verdict")
+		}
+		return min(max(verdict.Score, 0), 1), nil
+	}
+}
@@ -0,0 +1,112 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEscalate(t *testing.T) {
+	tests := []struct {
+		name         string
+		cheap        Client
+		judge        Judge
+		want         string
+		wantEscalate bool
+	}{
+		{"good cheap answer kept", NewMockClient("Paris is the capital of France."), HeuristicJudge(10), "Paris is the capital of France.", false},
+		{"short answer escalated", NewMockClient("Paris"), HeuristicJudge(20), "strong answer", true},
+		{"refusal escalated", NewMockClient("I don't know the answer to that question."), HeuristicJudge(10), "strong answer", true},
+		{"cheap error escalated", NewMockClientWithError("overloaded"), HeuristicJudge(10), "strong answer", true},
+		{"judge error escalated", NewMockClient("Paris is the capital of France."),
+			func(context.Context, []byte, []byte) (float64, error) { return 0, errors.New("grader down") }, "strong answer", true},
+		{"nil judge keeps cheap answer", NewMockClient("ok"), nil, "ok", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if mock, ok := tt.cheap.(*MockClient); ok {
+				mock.WithStreamDelay(0)
+			}
+			var escalated bool
+			handler := EscalateWithConfig(tt.cheap, NewMockClient("strong answer").WithStreamDelay(0), tt.judge, &EscalateConfig{
+				OnEscalate: func(context.Context, float64, error) { escalated = true },
+			})
+
+			out, err := runAgentFlow(context.Background(), handler, "What is the capital of France?")
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if out != tt.want || escalated != tt.wantEscalate {
+				t.Errorf("output = %q (escalated %v), want %q (escalated %v)", out, escalated, tt.want, tt.wantEscalate)
+			}
+		})
+	}
+}
+
+func TestEscalateWithConfig_Threshold(t *testing.T) {
+	judge := func(context.Context, []byte, []byte) (float64, error) { return 0.75, nil }
+	cheap := NewMockClient("cheap").WithStreamDelay(0)
+	strong := NewMockClient("strong").WithStreamDelay(0)
+
+	if out, _ := runAgentFlow(context.Background(), Escalate(cheap, strong, judge), "q"); out != "cheap" {
+		t.Errorf("default threshold output = %q, want cheap", out)
+	}
+	strict := EscalateWithConfig(cheap, strong, judge, &EscalateConfig{Threshold: 0.9})
+	if out, _ := runAgentFlow(context.Background(), strict, "q"); out != "strong" {
+		t.Errorf("strict threshold output = %q, want strong", out)
+	}
+}
+
+func TestHeuristicJudge(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer string
+		want   float64
+	}{
+		{"empty", "   ", 0},
+		{"invalid utf8", "\xff\xfe", 0},
+		{"refusal", "As an AI, I cannot browse the web.", 0},
+		{"half length", "12345", 0.5},
+		{"long enough", "1234567890 and more", 1},
+	}
+
+	judge := HeuristicJudge(10)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := judge(context.Background(), nil, []byte(tt.answer)); got != tt.want {
+				t.Errorf("score = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLLMJudge(t *testing.T) {
+	tests := []struct {
+		name    string
+		verdict string
+		want    float64
+		wantErr bool
+	}{
+		{"parsed score", `{"score": 0.4, "reason": "incomplete"}`, 0.4, false},
+		{"clamped score", `{"score": 3}`, 1, false},
+		{"malformed verdict", `not json`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			judge := LLMJudge(NewMockClientWithResponses([]string{tt.verdict}).WithStreamDelay(0))
+			got, err := judge(context.Background(), []byte("q"), []byte("a"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("score = %v, want %v", got, tt.want)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), "judge verdict") {
+				t.Errorf("error = %v, want parse failure", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+)
+
+// CreateBatch implements ai.BatchClient using the Gemini Batch API.
+//
+// Requests are sent inline with the client's model and generation config.
+// Gemini returns inline responses in request order, which is how results are
+// matched back to their requests. Inline batches are limited to 20MB; larger
+// workloads should be split across several jobs.
+func (g *Client) CreateBatch(ctx context.Context, requests []ai.BatchRequest, opts *ai.AgentOptions) (string, error) {
+	ctx = ai.ContextWithInterceptors(ctx, ai.GetInterceptors(opts)...)
+	config := g.buildGenerateConfig(ai.GetSchema(opts))
+
+	inlined := make([]*genai.InlinedRequest, 0, len(requests))
+	for _, req := range requests {
+		input, err := ai.ClassifyInput(calque.NewRequest(ctx, strings.NewReader(req.Input)), opts)
+		if err != nil {
+			return "", err
+		}
+		parts, err := g.inputToParts(ctx, input)
+		if err != nil {
+			return "", err
+		}
+		content := &genai.Content{Role: genai.RoleUser}
+		for i := range parts {
+			content.Parts = append(content.Parts, &parts[i])
+		}
+		inlined = append(inlined, &genai.InlinedRequest{
+			Contents: []*genai.Content{content},
+			Metadata: map[string]string{"id": req.ID},
+			Config:   config,
+		})
+	}
+
+	job, err := g.client.Batches.Create(ctx, g.model, &genai.BatchJobSource{InlinedRequests: inlined}, nil)
+	if err != nil {
+		return "", calque.WrapErr(ctx, err, "failed to create batch")
+	}
+	return job.Name, nil
+}
+
+// BatchStatus implements ai.BatchClient
+func (g *Client) BatchStatus(ctx context.Context, jobID string) (*ai.BatchStatus, error) {
+	job, err := g.client.Batches.Get(ctx, jobID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ai.BatchStatus{State: batchState(job.State)}
+	if job.CompletionStats != nil {
+		status.Completed = int(job.CompletionStats.SuccessfulCount)
+		status.Failed = int(job.CompletionStats.FailedCount)
+		status.Total = status.Completed + status.Failed + max(int(job.CompletionStats.IncompleteCount), 0)
+	}
+	if job.Error != nil {
+		status.Message = job.Error.Message
+	}
+	return status, nil
+}
+
+// batchState maps Gemini job states onto ai.BatchState
+func batchState(state genai.JobState) ai.BatchState {
+	switch state {
+	case genai.JobStateRunning, genai.JobStateCancelling, genai.JobStateUpdating:
+		return ai.BatchRunning
+	case genai.JobStateSucceeded, genai.JobStatePartiallySucceeded:
+		return ai.BatchCompleted
+	case genai.JobStateFailed:
+		return ai.BatchFailed
+	case genai.JobStateCancelled:
+		return ai.BatchCancelled
+	case genai.JobStateExpired:
+		return ai.BatchExpired
+	default:
+		return ai.BatchPending
+	}
+}
+
+// BatchResults implements ai.BatchClient from the job's inline responses
+func (g *Client) BatchResults(ctx context.Context, jobID string) ([]ai.BatchResult, error) {
+	job, err := g.client.Batches.Get(ctx, jobID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if job.Dest == nil {
+		return nil, nil
+	}
+
+	results := make([]ai.BatchResult, 0, len(job.Dest.InlinedResponses))
+	for i, resp := range job.Dest.InlinedResponses {
+		result := ai.BatchResult{Index: i}
+		switch {
+		case resp == nil:
+			continue
+		case resp.Error != nil:
+			result.Err = fmt.Errorf("%s", resp.Error.Message)
+		case resp.Response == nil:
+			result.Err = fmt.Errorf("empty batch response")
+		default:
+			result.Output = resp.Response.Text()
+			if usage := resp.Response.UsageMetadata; usage != nil {
+				result.Usage = &ai.UsageMetadata{
+					PromptTokens:     int(usage.PromptTokenCount),
+					CompletionTokens: int(usage.CandidatesTokenCount),
+					TotalTokens:      int(usage.TotalTokenCount),
+				}
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// CancelBatch implements ai.BatchClient
+func (g *Client) CancelBatch(ctx context.Context, jobID string) error {
+	return g.client.Batches.Cancel(ctx, jobID, nil)
+}
@@ -0,0 +1,27 @@
+package gemini
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+)
+
+func TestBatchState(t *testing.T) {
+	tests := map[genai.JobState]ai.BatchState{
+		genai.JobStateQueued:             ai.BatchPending,
+		genai.JobStatePending:            ai.BatchPending,
+		genai.JobStateRunning:            ai.BatchRunning,
+		genai.JobStateSucceeded:          ai.BatchCompleted,
+		genai.JobStatePartiallySucceeded: ai.BatchCompleted,
+		genai.JobStateFailed:             ai.BatchFailed,
+		genai.JobStateCancelled:          ai.BatchCancelled,
+		genai.JobStateExpired:            ai.BatchExpired,
+	}
+	for state, want := range tests {
+		if got := batchState(state); got != want {
+			t.Errorf("batchState(%q) = %q, want %q", state, got, want)
+		}
+	}
+}
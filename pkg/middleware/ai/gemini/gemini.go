@@ -6,9 +6,12 @@ package gemini
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"strings"
 
 	"google.golang.org/genai"
 
@@ -17,6 +20,7 @@ import (
 	"github.com/calque-ai/go-calque/pkg/middleware/ai"
 	"github.com/calque-ai/go-calque/pkg/middleware/ai/config"
 	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
 )
 
 const applicationJSON = "application/json"
@@ -52,6 +56,19 @@ type Config struct {
 	// Required. API key for Google AI/Vertex AI authentication
 	APIKey string
 
+	// Optional. Base URL of the Gemini API, e.g. an API gateway or proxy
+	// (defaults to https://generativelanguage.googleapis.com/)
+	BaseURL string
+
+	// Optional. HTTP client used for requests, e.g. one shared with other
+	// clients or with a custom transport (defaults to a client with
+	// environment proxies)
+	HTTPClient *http.Client
+
+	// Optional. Proxy and CA settings for restricted networks, used when
+	// HTTPClient is not set (defaults to HTTPS_PROXY/HTTP_PROXY and system CAs)
+	Network *ai.NetworkConfig
+
 	// Optional. Controls randomness in token selection (0.0-2.0)
 	// Lower values = more deterministic, higher values = more creative
 	Temperature *float32
@@ -97,6 +114,24 @@ type Config struct {
 	// Optional. Enable/disable streaming of responses (disabled automatically when tools are present)
 	// Default: true (streaming enabled), but tools force non-streaming regardless of this setting
 	Stream *bool
+
+	// Optional. Model tokenizer for exact TokenEstimator counts, e.g. a SentencePiece
+	// model via tokenizer.Func (default: tokenizer.Conservative, an estimate)
+	Tokenizer tokenizer.Tokenizer
+}
+
+// Validate reports every out-of-range setting, as ai.FieldError values
+func (c *Config) Validate() error {
+	return errors.Join(
+		ai.CheckURL("BaseURL", c.BaseURL),
+		ai.CheckRange("Temperature", c.Temperature, 0, 2),
+		ai.CheckRange("TopP", c.TopP, 0, 1),
+		ai.CheckPositive("TopK", c.TopK),
+		ai.CheckPositive("MaxTokens", c.MaxTokens),
+		ai.CheckPositive("CandidateCount", c.CandidateCount),
+		ai.CheckRange("PresencePenalty", c.PresencePenalty, -2, 2),
+		ai.CheckRange("FrequencyPenalty", c.FrequencyPenalty, -2, 2),
+	)
 }
 
 // Option interface for functional options pattern
@@ -173,10 +208,20 @@ func New(model string, opts ...Option) (*Client, error) {
 	if config.APIKey == "" {
 		return nil, calque.NewErr(ctx, "GOOGLE_API_KEY environment variable not set or provided in config")
 	}
+	if err := config.Validate(); err != nil {
+		return nil, calque.WrapErr(ctx, err, "invalid gemini config")
+	}
+
+	httpClient, err := ai.ProviderHTTPClient(config.HTTPClient, config.Network)
+	if err != nil {
+		return nil, err
+	}
 
-	// Configure the GenAI client
+	// Configure the GenAI client; the transport runs interceptors from ai.WithInterceptor
 	clientConfig := &genai.ClientConfig{
-		APIKey: config.APIKey,
+		APIKey:      config.APIKey,
+		HTTPClient:  ai.InterceptClient(httpClient),
+		HTTPOptions: genai.HTTPOptions{BaseURL: config.BaseURL},
 	}
 
 	client, err := genai.NewClient(ctx, clientConfig)
@@ -199,6 +244,43 @@ type RequestConfig struct {
 	HasTools    bool
 }
 
+// Warmup implements calque.Warmer by looking up the configured model.
+//
+// The request establishes the connection and verifies credentials and the
+// model name before the first chat request.
+//
+// Example:
+//
+//	if err := client.Warmup(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+func (g *Client) Warmup(ctx context.Context) error {
+	if _, err := g.client.Models.Get(ctx, g.model, nil); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("gemini warm-up for model %s failed", g.model))
+	}
+	return nil
+}
+
+// Egress implements calque.Egress with the Gemini API host
+func (g *Client) Egress() []string {
+	if baseURL := g.client.ClientConfig().HTTPOptions.BaseURL; baseURL != "" {
+		return calque.EgressHosts(baseURL)
+	}
+	return []string{"generativelanguage.googleapis.com"}
+}
+
+// TokenEstimator returns an ai.TokenEstimator for the client's model, counting
+// with Config.Tokenizer when set and estimating otherwise
+func (g *Client) TokenEstimator() ai.TokenEstimator {
+	return ai.NewTokenEstimator(g.model, g.config.Tokenizer)
+}
+
+// SupportsSeed implements ai.SeedCapable; deterministic runs override Config.Seed
+func (g *Client) SupportsSeed() bool { return true }
+
+// SupportsStructuredOutput implements ai.StructuredOutputCapable; schemas are sent as responseJsonSchema
+func (g *Client) SupportsStructuredOutput() bool { return true }
+
 // Chat implements the Client interface with streaming support.
 //
 // Input: user prompt/query via calque.Request
@@ -211,7 +293,19 @@ type RequestConfig struct {
 // Example:
 //
 //	err := client.Chat(req, res, &ai.AgentOptions{Tools: tools})
-func (g *Client) Chat(r *calque.Request, w *calque.Response, opts *ai.AgentOptions) error {
+func (g *Client) Chat(r *calque.Request, w *calque.Response, opts *ai.AgentOptions) (err error) {
+	g = g.withOverrides(r.Context)
+	r, span := ai.StartChatSpan(r, ai.ChatSpanInfo{
+		Provider:    "gcp.gemini",
+		Model:       g.model,
+		Temperature: g.config.Temperature,
+		MaxTokens:   g.config.MaxTokens,
+	})
+	defer func() { span.End(err) }()
+
+	// Route provider requests through the call's interceptors, see ai.WithInterceptor
+	r = r.WithContext(ai.ContextWithInterceptors(r.Context, ai.GetInterceptors(opts)...))
+
 	// Which input type are we processing?
 	input, err := ai.ClassifyInput(r, opts)
 	if err != nil {
@@ -219,7 +313,7 @@ func (g *Client) Chat(r *calque.Request, w *calque.Response, opts *ai.AgentOptio
 	}
 
 	// Build request configuration based on input type
-	config, err := g.buildRequestConfig(r.Context, input, ai.GetSchema(opts), ai.GetTools(opts))
+	config, err := g.buildRequestConfig(r.Context, input, ai.GetSchema(opts), ai.GetTools(opts), ai.GetReasoning(opts) != nil)
 	if err != nil {
 		return err
 	}
@@ -228,6 +322,32 @@ func (g *Client) Chat(r *calque.Request, w *calque.Response, opts *ai.AgentOptio
 	return g.executeRequest(config, r, w, opts)
 }
 
+// ModelSettings implements ai.ModelDescriber
+func (g *Client) ModelSettings(ctx context.Context) ai.ModelSettings {
+	g = g.withOverrides(ctx)
+	return ai.ModelSettings{Model: g.model, Temperature: g.config.Temperature, MaxTokens: g.config.MaxTokens}
+}
+
+// withOverrides returns a copy of the client using the call's ai.Overrides, if any
+func (g *Client) withOverrides(ctx context.Context) *Client {
+	overrides := ai.OverridesFrom(ctx)
+	if overrides.IsZero() {
+		return g
+	}
+	client, config := *g, *g.config
+	if overrides.Model != "" {
+		client.model = overrides.Model
+	}
+	if overrides.Temperature != nil {
+		config.Temperature = overrides.Temperature
+	}
+	if overrides.MaxTokens != nil {
+		config.MaxTokens = overrides.MaxTokens
+	}
+	client.config = &config
+	return &client
+}
+
 // buildGenerateConfig creates a Gemini GenerateContentConfig from provider config and optional schema override
 func (g *Client) buildGenerateConfig(schemaOverride *ai.ResponseFormat) *genai.GenerateContentConfig {
 	config := &genai.GenerateContentConfig{}
@@ -318,9 +438,17 @@ func convertToolsToGeminiFunctions(toolList []tools.Tool) []*genai.FunctionDecla
 }
 
 // buildRequestConfig creates configuration for the request
-func (g *Client) buildRequestConfig(ctx context.Context, input *ai.ClassifiedInput, schema *ai.ResponseFormat, tools []tools.Tool) (*RequestConfig, error) {
+func (g *Client) buildRequestConfig(ctx context.Context, input *ai.ClassifiedInput, schema *ai.ResponseFormat, tools []tools.Tool, includeThoughts bool) (*RequestConfig, error) {
 	// Build config once
 	genaiConfig := g.buildGenerateConfig(schema)
+	if seed, ok := calque.DeterministicSeed(ctx); ok {
+		genaiConfig.Seed = genai.Ptr(int32(seed))
+	}
+
+	// Ask for thought summaries when reasoning capture is enabled
+	if includeThoughts {
+		genaiConfig.ThinkingConfig = &genai.ThinkingConfig{IncludeThoughts: true}
+	}
 
 	// Track if we have tools (needed for buffering decision)
 	hasTools := len(tools) > 0
@@ -331,16 +459,29 @@ func (g *Client) buildRequestConfig(ctx context.Context, input *ai.ClassifiedInp
 		genaiConfig.Tools = []*genai.Tool{{FunctionDeclarations: geminiFunctions}}
 	}
 
-	// Create chat once
-	chat, err := g.client.Chats.Create(ctx, g.model, genaiConfig, nil)
-	if err != nil {
-		return nil, calque.WrapErr(ctx, err, "failed to create chat")
+	// Multi-turn input seeds the chat history and sends only the final turn
+	var history []*genai.Content
+	var parts []genai.Part
+	if input.Type == ai.MessagesInput {
+		var system string
+		system, history, parts = messagesToHistory(input.Messages)
+		if system != "" {
+			if genaiConfig.SystemInstruction != nil {
+				system = g.config.SystemInstruction + "\n\n" + system
+			}
+			genaiConfig.SystemInstruction = genai.NewContentFromText(system, genai.RoleUser)
+		}
+	} else {
+		var err error
+		if parts, err = g.inputToParts(ctx, input); err != nil {
+			return nil, err
+		}
 	}
 
-	// Convert to parts once
-	parts, err := g.inputToParts(ctx, input)
+	// Create chat once
+	chat, err := g.client.Chats.Create(ctx, g.model, genaiConfig, history)
 	if err != nil {
-		return nil, err
+		return nil, calque.WrapErr(ctx, err, "failed to create chat")
 	}
 
 	return &RequestConfig{
@@ -367,6 +508,7 @@ func (g *Client) executeRequest(config *RequestConfig, r *calque.Request, w *cal
 // reportUsage invokes the usage handler if present
 func (g *Client) reportUsage(opts *ai.AgentOptions) {
 	if g.lastUsage != nil && opts != nil && opts.UsageHandler != nil {
+		g.lastUsage.Model = g.model
 		opts.UsageHandler(g.lastUsage)
 	}
 }
@@ -389,8 +531,15 @@ func (g *Client) executeNonStreamingRequest(config *RequestConfig, r *calque.Req
 	}
 
 	// Report usage
+	recordResponse(r.Context, result)
+	ai.SetChatUsage(r.Context, g.lastUsage)
 	g.reportUsage(opts)
 
+	// Keep thoughts out of the answer
+	if err := captureThoughts(r.Context, result, ai.GetReasoning(opts)); err != nil {
+		return err
+	}
+
 	// Check for function calls first
 	functionCalls := result.FunctionCalls()
 	if len(functionCalls) > 0 {
@@ -424,6 +573,13 @@ func (g *Client) executeStreamingRequest(config *RequestConfig, r *calque.Reques
 			}
 		}
 
+		recordResponse(r.Context, result)
+
+		// Route thoughts to the reasoning trace, never the output
+		if err := appendThoughts(result, ai.GetReasoning(opts)); err != nil {
+			return err
+		}
+
 		// Get text from chunk and stream it
 		text := result.Text()
 		if text != "" {
@@ -434,11 +590,49 @@ func (g *Client) executeStreamingRequest(config *RequestConfig, r *calque.Reques
 	}
 
 	// Report usage after stream completes
+	ai.SetChatUsage(r.Context, g.lastUsage)
 	g.reportUsage(opts)
 
+	return ai.GetReasoning(opts).Finish(r.Context)
+}
+
+// recordResponse notes the response ID, model version and finish reasons on
+// the chat span once a candidate has finished
+func recordResponse(ctx context.Context, result *genai.GenerateContentResponse) {
+	var reasons []string
+	for _, candidate := range result.Candidates {
+		if candidate.FinishReason != "" {
+			reasons = append(reasons, string(candidate.FinishReason))
+		}
+	}
+	if len(reasons) > 0 {
+		ai.SetChatResponse(ctx, result.ResponseID, result.ModelVersion, reasons...)
+	}
+}
+
+// appendThoughts adds the thought parts of a response to the reasoning trace
+func appendThoughts(result *genai.GenerateContentResponse, reasoning *ai.ReasoningTrace) error {
+	if reasoning == nil || len(result.Candidates) == 0 || result.Candidates[0].Content == nil {
+		return nil
+	}
+	for _, part := range result.Candidates[0].Content.Parts {
+		if part.Thought && part.Text != "" {
+			if err := reasoning.Append(part.Text); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
+// captureThoughts records the thoughts of a complete response and finishes the trace
+func captureThoughts(ctx context.Context, result *genai.GenerateContentResponse, reasoning *ai.ReasoningTrace) error {
+	if err := appendThoughts(result, reasoning); err != nil {
+		return err
+	}
+	return reasoning.Finish(ctx)
+}
+
 // writeFunctionCalls formats Gemini function calls as OpenAI JSON format for the agent
 func (g *Client) writeFunctionCalls(functionCalls []*genai.FunctionCall, w *calque.Response) error {
 	// Convert to OpenAI format
@@ -492,11 +686,45 @@ func (g *Client) inputToParts(ctx context.Context, input *ai.ClassifiedInput) ([
 	case ai.MultimodalJSONInput, ai.MultimodalStreamingInput:
 		return g.multimodalToParts(ctx, input.Multimodal)
 
+	case ai.MessagesInput:
+		// Single-request paths such as batches send the whole transcript
+		return []genai.Part{{Text: input.Text}}, nil
+
 	default:
 		return nil, calque.NewErr(ctx, fmt.Sprintf("unsupported input type: %d", input.Type))
 	}
 }
 
+// messagesToHistory splits canonical chat messages into a system instruction,
+// prior turns for the chat history, and the parts of the final turn to send.
+// Gemini has no tool role outside function calling, so tool results are sent
+// as labelled user turns.
+func messagesToHistory(messages *ai.Messages) (string, []*genai.Content, []genai.Part) {
+	var system []string
+	var turns []*genai.Content
+	for _, msg := range messages.Messages {
+		switch msg.Role {
+		case ai.RoleSystem:
+			system = append(system, msg.Content)
+		case ai.RoleAssistant:
+			turns = append(turns, genai.NewContentFromText(msg.Content, genai.RoleModel))
+		case ai.RoleTool:
+			turns = append(turns, genai.NewContentFromText(ai.NewMessages(msg).Text(), genai.RoleUser))
+		default:
+			turns = append(turns, genai.NewContentFromText(msg.Content, genai.RoleUser))
+		}
+	}
+
+	var last []genai.Part
+	if len(turns) > 0 {
+		for _, part := range turns[len(turns)-1].Parts {
+			last = append(last, *part)
+		}
+		turns = turns[:len(turns)-1]
+	}
+	return strings.Join(system, "\n\n"), turns, last
+}
+
 // multimodalToParts converts multimodal input to genai.Part array
 func (g *Client) multimodalToParts(ctx context.Context, multimodal *ai.MultimodalInput) ([]genai.Part, error) {
 	if multimodal == nil {
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -98,6 +100,68 @@ func TestNew(t *testing.T) {
 	os.Unsetenv("GOOGLE_API_KEY")
 }
 
+func TestBuildRequestConfigDeterministic(t *testing.T) {
+	client, err := New("gemini-pro", WithConfig(&Config{APIKey: "test-api-key"}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	input := &ai.ClassifiedInput{Type: ai.TextInput, Text: "Hello"}
+
+	config, err := client.buildRequestConfig(calque.WithDeterministicSeed(context.Background(), 42), input, nil, nil, false)
+	if err != nil {
+		t.Fatalf("buildRequestConfig() error = %v", err)
+	}
+	if config.GenaiConfig.Seed == nil || *config.GenaiConfig.Seed != 42 {
+		t.Errorf("seed = %v, want 42", config.GenaiConfig.Seed)
+	}
+}
+
+func TestEgress(t *testing.T) {
+	client, err := New("gemini-pro", WithConfig(&Config{APIKey: "test-api-key"}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := client.Egress(); len(got) != 1 || got[0] != "generativelanguage.googleapis.com" {
+		t.Errorf("Egress() = %v", got)
+	}
+}
+
+func TestBaseURLAndHTTPClient(t *testing.T) {
+	var path, via string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, via = r.URL.Path, r.Header.Get("X-Via")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"models/gemini-pro"}`)
+	}))
+	defer server.Close()
+
+	// A caller-supplied client keeps its transport; interceptors wrap it
+	var transportUsed bool
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		transportUsed = true
+		req.Header.Set("X-Via", "corp-gateway")
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+	client, err := New("gemini-pro", WithConfig(&Config{APIKey: "test-api-key", BaseURL: server.URL, HTTPClient: httpClient}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := client.Warmup(context.Background()); err != nil {
+		t.Fatalf("Warmup() error = %v", err)
+	}
+	if !strings.HasSuffix(path, "/models/gemini-pro") || !transportUsed || via != "corp-gateway" {
+		t.Errorf("request path = %q via %q (transport used: %v), want the gateway and custom client", path, via, transportUsed)
+	}
+
+	if _, err := New("gemini-pro", WithConfig(&Config{APIKey: "test-api-key", Network: &ai.NetworkConfig{CACertFile: "/nonexistent/ca.pem"}})); err == nil {
+		t.Error("New() should fail for an unreadable CA bundle")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
 func TestDefaultConfig(t *testing.T) {
 	// Test without environment variable
 	os.Unsetenv("GOOGLE_API_KEY")
@@ -419,6 +483,30 @@ func TestInputToParts(t *testing.T) {
 	}
 }
 
+func TestMessagesToHistory(t *testing.T) {
+	system, history, last := messagesToHistory(&ai.Messages{Messages: []ai.Message{
+		{Role: ai.RoleSystem, Content: "Be brief."},
+		{Role: ai.RoleUser, Content: "Weather?"},
+		{Role: ai.RoleAssistant, Content: "Checking."},
+		{Role: ai.RoleTool, Name: "weather", Content: "sunny"},
+		{Role: ai.RoleSystem, Content: "Use Celsius."},
+		{Role: ai.RoleUser, Content: "And tomorrow?"},
+	}})
+
+	if system != "Be brief.\n\nUse Celsius." {
+		t.Errorf("system = %q", system)
+	}
+	if len(history) != 3 {
+		t.Fatalf("history length = %d, want 3", len(history))
+	}
+	if history[1].Role != genai.RoleModel || history[2].Role != genai.RoleUser || history[2].Parts[0].Text != "tool (weather): sunny" {
+		t.Errorf("history = %+v, %+v", history[1], history[2])
+	}
+	if len(last) != 1 || last[0].Text != "And tomorrow?" {
+		t.Errorf("final turn = %+v", last)
+	}
+}
+
 func TestConvertToolsToGeminiFunctions(t *testing.T) {
 	// Create a simple mock tool
 	tool := tools.Simple("calculator", "Performs calculations", func(_ string) string {
@@ -1121,3 +1209,29 @@ func TestExecuteRequestStreamingDecision(t *testing.T) {
 		})
 	}
 }
+
+func TestCaptureThoughts(t *testing.T) {
+	result := &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{Content: &genai.Content{Parts: []*genai.Part{
+		{Text: "Comparing both options.", Thought: true},
+		{Text: "Option B."},
+	}}}}}
+
+	opts := &ai.AgentOptions{}
+	ai.WithReasoning(ai.ReasoningConfig{}).Apply(opts)
+	ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+
+	if err := captureThoughts(ctx, result, ai.GetReasoning(opts)); err != nil {
+		t.Fatalf("captureThoughts() error = %v", err)
+	}
+	if trace, _ := ai.ReasoningFrom(ctx); trace != "Comparing both options." {
+		t.Errorf("reasoning metadata = %q", trace)
+	}
+	if text := result.Text(); text != "Option B." {
+		t.Errorf("answer text = %q, thoughts must be excluded", text)
+	}
+
+	// Without capture enabled thoughts are simply dropped
+	if err := captureThoughts(ctx, result, nil); err != nil {
+		t.Errorf("captureThoughts() without trace error = %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+)
+
+// InterceptorNext sends a request on to the provider, or to the next interceptor
+type InterceptorNext func(req *http.Request) (*http.Response, error)
+
+// Interceptor wraps the HTTP exchange between a client and its provider.
+//
+// It receives the provider-native request after the client has built it and
+// just before it is sent, and returns the raw response before the client
+// parses it. An interceptor can change headers or the body, call next more
+// than once, or answer without calling next at all.
+//
+// Example:
+//
+//	func(req *http.Request, next ai.InterceptorNext) (*http.Response, error) {
+//		req.Header.Set("Helicone-Auth", "Bearer "+heliconeKey)
+//		return next(req)
+//	}
+type Interceptor func(req *http.Request, next InterceptorNext) (*http.Response, error)
+
+type interceptorOption struct{ interceptor Interceptor }
+
+func (o interceptorOption) Apply(opts *AgentOptions) {
+	opts.Interceptors = append(opts.Interceptors, o.interceptor)
+}
+
+// WithInterceptor runs fn around every provider request the agent makes.
+//
+// Input: Interceptor receiving the outgoing request and the next step
+// Output: AgentOption for configuration
+// Behavior: Interceptors run in the order given; the first one is outermost
+//
+// Use it to add gateway headers (Helicone, LiteLLM, Portkey), rewrite payloads
+// for a proxy that expects a different shape, or retry a deployment-specific
+// error. The request body is the provider's JSON; replace req.Body and
+// req.ContentLength together when rewriting it. Bedrock requests are signed
+// after interceptors run, so rewritten requests stay valid.
+//
+// Example:
+//
+//	agent := ai.Agent(client,
+//		ai.WithInterceptor(func(req *http.Request, next ai.InterceptorNext) (*http.Response, error) {
+//			req.Header.Set("Helicone-Auth", "Bearer "+heliconeKey)
+//			req.Header.Set("Helicone-Property-Flow", "support")
+//			return next(req)
+//		}),
+//	)
+func WithInterceptor(fn Interceptor) AgentOption {
+	return interceptorOption{interceptor: fn}
+}
+
+// GetInterceptors returns the interceptors from AgentOptions, or nil if none.
+// A WithRetry policy comes first, so every attempt passes through the rest.
+func GetInterceptors(opts *AgentOptions) []Interceptor {
+	if opts == nil {
+		return nil
+	}
+	if opts.Retry != nil {
+		return append([]Interceptor{RetryInterceptor(*opts.Retry)}, opts.Interceptors...)
+	}
+	return opts.Interceptors
+}
+
+type interceptorsContextKey struct{}
+
+// ContextWithInterceptors adds interceptors to those already in ctx.
+//
+// Clients call it with the interceptors of each Chat call. It can also wrap
+// every provider request of a flow:
+//
+//	ctx = ai.ContextWithInterceptors(ctx, gatewayHeaders)
+//	err := flow.Run(ctx, input, &output)
+func ContextWithInterceptors(ctx context.Context, interceptors ...Interceptor) context.Context {
+	if len(interceptors) == 0 {
+		return ctx
+	}
+	existing := InterceptorsFromContext(ctx)
+	combined := make([]Interceptor, 0, len(existing)+len(interceptors))
+	combined = append(append(combined, existing...), interceptors...)
+	return context.WithValue(ctx, interceptorsContextKey{}, combined)
+}
+
+// InterceptorsFromContext returns the interceptors added to ctx, or nil
+func InterceptorsFromContext(ctx context.Context) []Interceptor {
+	interceptors, _ := ctx.Value(interceptorsContextKey{}).([]Interceptor)
+	return interceptors
+}
+
+// Intercept sends req through the interceptors in its context, ending with send.
+//
+// Provider clients call it from their HTTP layer. Without interceptors it
+// calls send directly.
+func Intercept(req *http.Request, send InterceptorNext) (*http.Response, error) {
+	interceptors := InterceptorsFromContext(req.Context())
+	next := send
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := interceptors[i], next
+		next = func(req *http.Request) (*http.Response, error) {
+			return interceptor(req, inner)
+		}
+	}
+	return next(req)
+}
+
+// InterceptTransport returns an http.RoundTripper that runs the interceptors
+// in each request's context before next (default: http.DefaultTransport).
+//
+// Use it for clients whose SDK accepts an *http.Client.
+func InterceptTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return interceptTransport{next: next}
+}
+
+type interceptTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t interceptTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return Intercept(req, t.next.RoundTrip)
+}
@@ -0,0 +1,127 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// tagInterceptor appends name to the X-Order header on the way in and out
+func tagInterceptor(name string) Interceptor {
+	return func(req *http.Request, next InterceptorNext) (*http.Response, error) {
+		req.Header.Add("X-Order", name)
+		resp, err := next(req)
+		if resp != nil {
+			resp.Header.Add("X-Order", name)
+		}
+		return resp, err
+	}
+}
+
+func TestIntercept(t *testing.T) {
+	ctx := ContextWithInterceptors(context.Background(), tagInterceptor("flow"))
+	ctx = ContextWithInterceptors(ctx, tagInterceptor("call"))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "http://provider.test/v1/chat", nil)
+
+	var sent []string
+	resp, err := Intercept(req, func(req *http.Request) (*http.Response, error) {
+		sent = req.Header.Values("X-Order")
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}, nil
+	})
+	if err != nil {
+		t.Fatalf("Intercept() error = %v", err)
+	}
+	if strings.Join(sent, ",") != "flow,call" {
+		t.Errorf("request passed interceptors %v, want flow then call", sent)
+	}
+	if got := strings.Join(resp.Header.Values("X-Order"), ","); got != "call,flow" {
+		t.Errorf("response passed interceptors %s, want call then flow", got)
+	}
+}
+
+func TestInterceptShortCircuitAndRetry(t *testing.T) {
+	t.Run("answer without sending", func(t *testing.T) {
+		cached := func(*http.Request, InterceptorNext) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusTeapot}, nil
+		}
+		req, _ := http.NewRequestWithContext(ContextWithInterceptors(context.Background(), cached), http.MethodGet, "http://provider.test", nil)
+		resp, err := Intercept(req, func(*http.Request) (*http.Response, error) {
+			t.Fatal("send called after interceptor answered")
+			return nil, nil
+		})
+		if err != nil || resp.StatusCode != http.StatusTeapot {
+			t.Errorf("Intercept() = %v, %v, want the interceptor's response", resp, err)
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		retry := func(req *http.Request, next InterceptorNext) (*http.Response, error) {
+			resp, err := next(req)
+			if err != nil {
+				return next(req)
+			}
+			return resp, nil
+		}
+		req, _ := http.NewRequestWithContext(ContextWithInterceptors(context.Background(), retry), http.MethodGet, "http://provider.test", nil)
+		calls := 0
+		resp, err := Intercept(req, func(*http.Request) (*http.Response, error) {
+			calls++
+			if calls == 1 {
+				return nil, errors.New("connection reset")
+			}
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+		if err != nil || resp.StatusCode != http.StatusOK || calls != 2 {
+			t.Errorf("Intercept() = %v, %v after %d calls, want success on the second", resp, err, calls)
+		}
+	})
+}
+
+func TestInterceptTransport(t *testing.T) {
+	var gotHeader, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("Helicone-Auth")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+	}))
+	defer server.Close()
+
+	rewrite := func(req *http.Request, next InterceptorNext) (*http.Response, error) {
+		req.Header.Set("Helicone-Auth", "Bearer test")
+		body := `{"model":"gateway-route"}`
+		req.Body = io.NopCloser(strings.NewReader(body))
+		req.ContentLength = int64(len(body))
+		return next(req)
+	}
+	client := &http.Client{Transport: InterceptTransport(nil)}
+	ctx := ContextWithInterceptors(context.Background(), rewrite)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader(`{"model":"gpt-4o"}`))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if gotHeader != "Bearer test" || gotBody != `{"model":"gateway-route"}` {
+		t.Errorf("server got header %q and body %q, want the interceptor's", gotHeader, gotBody)
+	}
+}
+
+func TestWithInterceptor(t *testing.T) {
+	opts := &AgentOptions{}
+	WithInterceptor(tagInterceptor("a")).Apply(opts)
+	WithInterceptor(tagInterceptor("b")).Apply(opts)
+	if len(GetInterceptors(opts)) != 2 {
+		t.Errorf("GetInterceptors() has %d interceptors, want 2", len(opts.Interceptors))
+	}
+	if GetInterceptors(nil) != nil {
+		t.Error("GetInterceptors(nil) should be nil")
+	}
+	if ctx := context.Background(); ContextWithInterceptors(ctx) != ctx {
+		t.Error("ContextWithInterceptors() without interceptors should return ctx")
+	}
+}
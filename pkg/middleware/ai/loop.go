@@ -0,0 +1,180 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// Iteration describes one model call of an agent loop, passed to IterationHook.
+//
+// Results is empty when the model answered without calling tools, which ends
+// the loop with Output as the final answer.
+type Iteration struct {
+	Number  int                // 1-based iteration number
+	Output  string             // Model output: tool calls or the final answer
+	Results []tools.ToolResult // Tool calls executed this iteration, including failed ones
+	Final   bool               // True when Output is the final answer
+}
+
+// IterationHook is called after every model call of an agent loop
+type IterationHook func(ctx context.Context, iteration Iteration)
+
+type maxIterationsOption struct{ n int }
+
+func (o maxIterationsOption) Apply(opts *AgentOptions) { opts.MaxIterations = o.n }
+
+type iterationHookOption struct{ hook IterationHook }
+
+func (o iterationHookOption) Apply(opts *AgentOptions) { opts.IterationHook = o.hook }
+
+// WithMaxIterations runs tool-calling agents as a loop that feeds tool results back to the model.
+//
+// Input: maximum number of model calls that may request tools
+// Output: AgentOption for configuration
+// Behavior: Repeats model call → tool execution until the model answers without tools
+//
+// Without this option an agent makes one tool-calling pass and synthesizes an
+// answer from its results. With it, each round's tool calls and results are
+// appended to the conversation as assistant and tool messages, and the model
+// is called again so it can chain tools or correct a failed call. Failed tool
+// calls are reported to the model instead of failing the flow. When the model
+// is still calling tools after n iterations, the tool result formatter
+// answers from every result gathered so far.
+//
+// Input may be plain text or ai.Messages JSON; it becomes the start of the
+// conversation.
+//
+// Example:
+//
+//	agent := ai.Agent(client,
+//		ai.WithTools(searchTool, fetchTool),
+//		ai.WithMaxIterations(5),
+//	)
+func WithMaxIterations(n int) AgentOption {
+	return maxIterationsOption{n: n}
+}
+
+// WithIterationHook calls hook after each model call of an agent loop.
+//
+// Input: hook receiving the iteration's output and tool results
+// Output: AgentOption for configuration
+// Behavior: Observes the loop; it cannot change it
+//
+// Setting a hook without WithMaxIterations runs a single-iteration loop.
+//
+// Example:
+//
+//	agent := ai.Agent(client,
+//		ai.WithTools(searchTool),
+//		ai.WithMaxIterations(5),
+//		ai.WithIterationHook(func(ctx context.Context, it ai.Iteration) {
+//			log.Printf("iteration %d: %d tool calls, final=%v", it.Number, len(it.Results), it.Final)
+//		}),
+//	)
+func WithIterationHook(hook IterationHook) AgentOption {
+	return iterationHookOption{hook: hook}
+}
+
+// runAgentLoop calls the model with the growing conversation until it answers
+// without tools or MaxIterations is reached
+func runAgentLoop(client Client, agentOpts *AgentOptions, r *calque.Request, w *calque.Response) error {
+	toolsConfig, formatter, formatterClient := toolCallingDefaults(client, agentOpts)
+	maxIterations := max(agentOpts.MaxIterations, 1)
+
+	var input []byte
+	if err := calque.Read(r, &input); err != nil {
+		return err
+	}
+	conversation := startConversation(input)
+	ctx := withPartialToolResults(r.Context, client)
+
+	var toolMessages []Message
+	for number := 1; number <= maxIterations; number++ {
+		output, results, err := runIteration(ctx, client, agentOpts, toolsConfig, conversation)
+		if err != nil {
+			return calque.WrapErr(r.Context, err, "agent failed")
+		}
+
+		iteration := Iteration{Number: number, Output: output, Results: results, Final: len(results) == 0}
+		calque.LogDebug(ctx, "agent iteration", "iteration", number, "tool_calls", len(results), "final", iteration.Final)
+		if agentOpts.IterationHook != nil {
+			agentOpts.IterationHook(ctx, iteration)
+		}
+		if iteration.Final {
+			return calque.Write(w, output)
+		}
+
+		// Results travel without call IDs: canonical messages don't carry the
+		// assistant's tool calls, so providers send them as labelled context
+		conversation.Messages = append(conversation.Messages, Message{Role: RoleAssistant, Content: output})
+		for _, result := range results {
+			msg := toolResultMessage(result)
+			conversation.Messages = append(conversation.Messages, msg)
+			toolMessages = append(toolMessages, msg)
+		}
+	}
+
+	// Still calling tools at the cap: answer from everything gathered so far
+	calque.LogDebug(ctx, "agent reached max iterations", "max_iterations", maxIterations)
+	req := calque.NewRequest(ctx, strings.NewReader(NewMessages(toolMessages...).Text()))
+	if err := formatter(formatterClient, input).ServeFlow(req, w); err != nil {
+		return calque.WrapErr(r.Context, err, "agent failed")
+	}
+	return nil
+}
+
+// runIteration makes one model call and executes the tools it requested,
+// returning the model output and the tool results (none for a final answer)
+func runIteration(ctx context.Context, client Client, agentOpts *AgentOptions, toolsConfig tools.Config, conversation Messages) (string, []tools.ToolResult, error) {
+	prompt, err := json.Marshal(conversation)
+	if err != nil {
+		return "", nil, calque.WrapErr(ctx, err, "failed to encode conversation")
+	}
+
+	var output bytes.Buffer
+	if err := client.Chat(calque.NewRequest(ctx, bytes.NewReader(prompt)), calque.NewResponse(&output), agentOpts); err != nil {
+		return "", nil, err
+	}
+
+	var results []tools.ToolResult
+	ctx = tools.WithResultObserver(ctx, func(_ context.Context, result tools.ToolResult) {
+		results = append(results, result)
+	})
+	execute := ctrl.Chain(
+		tools.Registry(agentOpts.Tools...),
+		tools.Detect(tools.ExecuteWithOptions(toolsConfig), ctrl.PassThrough()),
+	)
+	err = execute.ServeFlow(calque.NewRequest(ctx, bytes.NewReader(output.Bytes())), calque.NewResponse(&bytes.Buffer{}))
+	// Failed tool calls are fed back so the model can recover from them
+	if err != nil && len(results) == 0 {
+		return "", nil, err
+	}
+	return output.String(), results, nil
+}
+
+// startConversation uses ai.Messages input as the conversation so far, or
+// else starts one with the input as the user's message
+func startConversation(input []byte) Messages {
+	if isMessagesJSON(input) {
+		var messages Messages
+		if json.Unmarshal(input, &messages) == nil && hasValidRoles(messages) {
+			return messages
+		}
+	}
+	return NewMessages(Message{Role: RoleUser, Content: string(input)})
+}
+
+// toolResultMessage reports a tool's result, or its failure, to the model
+func toolResultMessage(result tools.ToolResult) Message {
+	content := string(result.Result)
+	if result.Error != "" {
+		content = "error: " + result.Error
+	}
+	return Message{Role: RoleTool, Name: result.ToolCall.Name, Content: content}
+}
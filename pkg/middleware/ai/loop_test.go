@@ -0,0 +1,176 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// scriptedClient answers with scripted outputs and records each prompt
+type scriptedClient struct {
+	outputs []string
+	prompts []string
+}
+
+func (c *scriptedClient) Chat(r *calque.Request, w *calque.Response, _ *AgentOptions) error {
+	var prompt string
+	if err := calque.Read(r, &prompt); err != nil {
+		return err
+	}
+	c.prompts = append(c.prompts, prompt)
+	if len(c.prompts) > len(c.outputs) {
+		return calque.NewErr(r.Context, "no scripted output left")
+	}
+	return calque.Write(w, c.outputs[len(c.prompts)-1])
+}
+
+func toolCall(name, arguments string) string {
+	return `{"tool_calls": [{"type": "function", "function": {"name": "` + name + `", "arguments": "` + arguments + `"}}]}`
+}
+
+func TestAgentLoop(t *testing.T) {
+	lookup := tools.Simple("lookup", "Look up a city's country", func(city string) string {
+		return city + " is in France"
+	})
+	weather := tools.Simple("weather", "Weather for a country", func(country string) string {
+		if country == "" {
+			panic("country required")
+		}
+		return "sunny in " + country
+	})
+
+	tests := []struct {
+		name          string
+		maxIterations int
+		outputs       []string
+		want          string
+		wantCalls     int
+		wantFinal     []bool
+		wantLastTurn  string
+	}{
+		{
+			name:          "chains tools until a final answer",
+			maxIterations: 5,
+			outputs:       []string{toolCall("lookup", "Paris"), toolCall("weather", "France"), "It is sunny in Paris."},
+			want:          "It is sunny in Paris.",
+			wantCalls:     3,
+			wantFinal:     []bool{false, false, true},
+			wantLastTurn:  "sunny in France",
+		},
+		{
+			name:          "answers without tools",
+			maxIterations: 3,
+			outputs:       []string{"Hello!"},
+			want:          "Hello!",
+			wantCalls:     1,
+			wantFinal:     []bool{true},
+		},
+		{
+			name:          "failed tool call is fed back",
+			maxIterations: 3,
+			outputs:       []string{toolCall("weather", ""), toolCall("weather", "France"), "Sunny."},
+			want:          "Sunny.",
+			wantCalls:     3,
+			wantFinal:     []bool{false, false, true},
+			wantLastTurn:  "sunny in France",
+		},
+		{
+			name:          "synthesizes at the iteration cap",
+			maxIterations: 2,
+			outputs:       []string{toolCall("lookup", "Paris"), toolCall("weather", "France"), "Summary from results."},
+			want:          "Summary from results.",
+			wantCalls:     3,
+			wantFinal:     []bool{false, false},
+			wantLastTurn:  "tool (weather): sunny in France",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &scriptedClient{outputs: tt.outputs}
+			var final []bool
+			agent := Agent(client,
+				WithTools(lookup, weather),
+				WithMaxIterations(tt.maxIterations),
+				WithIterationHook(func(_ context.Context, it Iteration) {
+					if it.Number != len(final)+1 {
+						t.Errorf("iteration number = %d, want %d", it.Number, len(final)+1)
+					}
+					final = append(final, it.Final)
+				}),
+			)
+
+			var out string
+			if err := calque.NewFlow().Use(agent).Run(context.Background(), "Weather in Paris?", &out); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if out != tt.want {
+				t.Errorf("output = %q, want %q", out, tt.want)
+			}
+			if len(client.prompts) != tt.wantCalls {
+				t.Fatalf("model calls = %d, want %d", len(client.prompts), tt.wantCalls)
+			}
+			if len(final) != len(tt.wantFinal) {
+				t.Fatalf("hook calls = %v, want %v", final, tt.wantFinal)
+			}
+			for i := range final {
+				if final[i] != tt.wantFinal[i] {
+					t.Errorf("iteration %d final = %v, want %v", i+1, final[i], tt.wantFinal[i])
+				}
+			}
+			if tt.wantLastTurn != "" && !strings.Contains(client.prompts[len(client.prompts)-1], tt.wantLastTurn) {
+				t.Errorf("last prompt = %q, want it to contain %q", client.prompts[len(client.prompts)-1], tt.wantLastTurn)
+			}
+		})
+	}
+}
+
+func TestAgentLoopConversation(t *testing.T) {
+	lookup := tools.Simple("lookup", "Look up a city's country", func(city string) string {
+		return city + " is in France"
+	})
+	client := &scriptedClient{outputs: []string{toolCall("lookup", "Paris"), "France."}}
+
+	input, _ := json.Marshal(NewMessages(
+		Message{Role: RoleSystem, Content: "Answer briefly."},
+		Message{Role: RoleUser, Content: "Where is Paris?"},
+	))
+	var out string
+	if err := calque.NewFlow().Use(Agent(client, WithTools(lookup), WithMaxIterations(3))).Run(context.Background(), input, &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var second Messages
+	if err := json.Unmarshal([]byte(client.prompts[1]), &second); err != nil {
+		t.Fatalf("second prompt is not messages JSON: %v", err)
+	}
+	roles := make([]Role, len(second.Messages))
+	for i, msg := range second.Messages {
+		roles[i] = msg.Role
+	}
+	want := []Role{RoleSystem, RoleUser, RoleAssistant, RoleTool}
+	if !slices.Equal(roles, want) {
+		t.Errorf("roles = %v, want %v", roles, want)
+	}
+	if last := second.Messages[3]; last.Name != "lookup" || last.Content != "Paris is in France" {
+		t.Errorf("tool message = %+v", last)
+	}
+}
+
+func TestAgentLoopUnknownTool(t *testing.T) {
+	lookup := tools.Simple("lookup", "Look up a city's country", func(city string) string { return city })
+	client := &scriptedClient{outputs: []string{toolCall("missing", "x"), "Recovered."}}
+
+	var out string
+	if err := calque.NewFlow().Use(Agent(client, WithTools(lookup), WithMaxIterations(2))).Run(context.Background(), "go", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != "Recovered." || !strings.Contains(client.prompts[1], "error:") {
+		t.Errorf("output = %q, second prompt = %q", out, client.prompts[1])
+	}
+}
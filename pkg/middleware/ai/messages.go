@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"strings"
+)
+
+// Role identifies the author of a chat message
+type Role string
+
+// Chat message roles understood by every provider
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+	RoleTool      Role = "tool"
+)
+
+// Message is a single turn in a multi-turn chat.
+//
+// Name identifies the tool that produced a RoleTool message, and ToolCallID
+// links it to the assistant tool call it answers when the provider tracks
+// call IDs.
+//
+// Example:
+//
+//	msg := ai.Message{Role: ai.RoleTool, Name: "search", ToolCallID: "call_1", Content: `{"hits":3}`}
+type Message struct {
+	Role       Role   `json:"role"`
+	Content    string `json:"content"`
+	Name       string `json:"name,omitempty"`
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+// Messages is the canonical multi-turn chat format passed between handlers.
+//
+// Serialized as {"messages":[{"role":"system","content":"..."}, ...]}, it is
+// recognized by ClassifyInput so providers can send each turn as a native
+// chat message instead of one concatenated prompt.
+//
+// Example:
+//
+//	input := ai.NewMessages(
+//		ai.Message{Role: ai.RoleSystem, Content: "You are a Go expert."},
+//		ai.Message{Role: ai.RoleUser, Content: "What is a goroutine?"},
+//	)
+//	data, _ := json.Marshal(input)
+//	flow.Use(ai.Agent(client)).Run(ctx, data, &output)
+type Messages struct {
+	Messages []Message `json:"messages"`
+}
+
+// NewMessages creates a Messages value from the given turns.
+//
+// Example:
+//
+//	input := ai.NewMessages(ai.Message{Role: ai.RoleUser, Content: "Hello"})
+func NewMessages(messages ...Message) Messages {
+	return Messages{Messages: messages}
+}
+
+// Text renders the messages as a role-prefixed transcript.
+//
+// Used by providers and handlers that only accept a single prompt string.
+//
+// Example:
+//
+//	ai.NewMessages(
+//		ai.Message{Role: ai.RoleSystem, Content: "Be brief."},
+//		ai.Message{Role: ai.RoleUser, Content: "Hi"},
+//	).Text() // "system: Be brief.\n\nuser: Hi"
+func (m Messages) Text() string {
+	turns := make([]string, 0, len(m.Messages))
+	for _, msg := range m.Messages {
+		turns = append(turns, msg.label()+": "+msg.Content)
+	}
+	return strings.Join(turns, "\n\n")
+}
+
+// label is the transcript prefix for a message, naming the tool for tool results
+func (m Message) label() string {
+	if m.Role == RoleTool && m.Name != "" {
+		return string(m.Role) + " (" + m.Name + ")"
+	}
+	return string(m.Role)
+}
+
+// validRole reports whether role is one of the canonical chat roles
+func validRole(role Role) bool {
+	switch role {
+	case RoleSystem, RoleUser, RoleAssistant, RoleTool:
+		return true
+	}
+	return false
+}
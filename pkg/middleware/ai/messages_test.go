@@ -0,0 +1,38 @@
+package ai
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMessagesText(t *testing.T) {
+	messages := NewMessages(
+		Message{Role: RoleSystem, Content: "Be brief."},
+		Message{Role: RoleUser, Content: "Weather?"},
+		Message{Role: RoleTool, Name: "weather", ToolCallID: "call_1", Content: "sunny"},
+		Message{Role: RoleAssistant, Content: "It's sunny."},
+	)
+
+	want := "system: Be brief.\n\nuser: Weather?\n\ntool (weather): sunny\n\nassistant: It's sunny."
+	if got := messages.Text(); got != want {
+		t.Errorf("Text() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMessagesJSON(t *testing.T) {
+	data, err := json.Marshal(NewMessages(
+		Message{Role: RoleUser, Content: "Hi"},
+		Message{Role: RoleTool, Name: "clock", ToolCallID: "call_1", Content: "noon"},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"messages":[{"role":"user","content":"Hi"},{"role":"tool","content":"noon","name":"clock","tool_call_id":"call_1"}]}`
+	if string(data) != want {
+		t.Errorf("json = %s, want %s", data, want)
+	}
+	if !isMessagesJSON(data) {
+		t.Error("isMessagesJSON() = false for marshaled Messages")
+	}
+}
@@ -23,7 +23,8 @@ type MockClient struct {
 	errorMessage     string
 	simulateTools    bool // Whether to simulate tool calls
 	toolCalls        []MockToolCall
-	simulateJSONMode bool // Whether to simulate structured JSON output
+	simulateJSONMode bool   // Whether to simulate structured JSON output
+	reasoning        string // Simulated reasoning tokens
 }
 
 // MockToolCall represents a simulated tool call for testing
@@ -75,6 +76,12 @@ func (m *MockClient) WithJSONMode(enabled bool) *MockClient {
 	return m
 }
 
+// WithReasoning configures the mock to emit reasoning tokens alongside the answer
+func (m *MockClient) WithReasoning(reasoning string) *MockClient {
+	m.reasoning = reasoning
+	return m
+}
+
 // Chat implements the Client interface with simulated streaming
 func (m *MockClient) Chat(req *calque.Request, res *calque.Response, opts *AgentOptions) error {
 	// Extract options
@@ -98,6 +105,16 @@ func (m *MockClient) Chat(req *calque.Request, res *calque.Response, opts *Agent
 
 	inputStr = strings.TrimSpace(inputStr)
 
+	// Reasoning goes to the trace, never the output
+	if reasoning := GetReasoning(opts); reasoning != nil && m.reasoning != "" {
+		if err := reasoning.Append(m.reasoning); err != nil {
+			return err
+		}
+		if err := reasoning.Finish(req.Context); err != nil {
+			return err
+		}
+	}
+
 	// Check if we have predefined responses first
 	if len(m.responses) > 0 {
 		response := m.getNextResponse(inputStr)
@@ -236,12 +253,22 @@ func (m *MockClient) simulateStructuredOutput(schema *ResponseFormat, input stri
 func (m *MockClient) generateMockFromSchema(schema *jsonschema.Schema, input string) map[string]interface{} {
 	result := make(map[string]interface{})
 
+	// Unions (see SchemaOneOf) answer with their first variant
+	if schema.Properties == nil && len(schema.AnyOf) > 0 {
+		return m.generateMockFromSchema(schema.AnyOf[0], input)
+	}
+
 	// Very basic schema interpretation for testing
 	if schema.Properties != nil {
 		for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
 			key := pair.Key
 			propSchema := pair.Value
 
+			if propSchema.Const != nil {
+				result[key] = propSchema.Const
+				continue
+			}
+
 			switch propSchema.Type {
 			case "string":
 				result[key] = fmt.Sprintf("mock_%s_for_%s", key, input)
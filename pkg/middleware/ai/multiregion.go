@@ -0,0 +1,379 @@
+package ai
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// RegionMetadataKey is the MetadataBus key recording which region served a request
+const RegionMetadataKey = "ai.region"
+
+// RegionPolicy configures how MultiRegion picks and fails over between regions
+type RegionPolicy struct {
+	// Preferred orders regions that have no latency measurements yet (default: sorted names)
+	Preferred []string
+	// FailureThreshold is how many consecutive failures mark a region unhealthy (default: 2)
+	FailureThreshold int
+	// Cooldown is how long an unhealthy region is only used as a last resort (default: 30s)
+	Cooldown time.Duration
+	// ProbeInterval enables background health probes; zero relies on live traffic only
+	ProbeInterval time.Duration
+	// ProbeTimeout bounds each probe (default: 5s)
+	ProbeTimeout time.Duration
+	// Probe checks a region (default: the client's Warmup, if it implements calque.Warmer)
+	Probe func(ctx context.Context, region string, client Client) error
+	// Smoothing is the weight of the newest latency sample in the moving average (default: 0.2)
+	Smoothing float64
+	// IsRegionalFailure decides whether an error should fail over (default: every error
+	// except the caller's own cancellation)
+	IsRegionalFailure func(error) bool
+	// OnResult is called after every request and probe, e.g. to export per-region metrics
+	OnResult func(region string, latency time.Duration, err error)
+}
+
+// RegionStats is a snapshot of one region's health
+type RegionStats struct {
+	Healthy   bool          `json:"healthy"`
+	Latency   time.Duration `json:"latency"`  // Moving average of time to first byte
+	Requests  int64         `json:"requests"` // Requests and probes sent to the region
+	Failures  int64         `json:"failures"` // Requests and probes that failed
+	LastError string        `json:"last_error,omitempty"`
+}
+
+// MultiRegionClient routes requests to the fastest healthy regional endpoint.
+//
+// It is created by MultiRegion and implements Client, so it can be used
+// anywhere a single provider client is.
+type MultiRegionClient struct {
+	clients map[string]Client
+	names   []string
+	policy  RegionPolicy
+
+	mu      sync.Mutex
+	regions map[string]*regionState
+
+	probeOnce sync.Once
+	stop      chan struct{}
+	stopOnce  sync.Once
+	probing   sync.WaitGroup
+}
+
+type regionState struct {
+	latency     time.Duration
+	failures    int // consecutive
+	unhealthyAt time.Time
+	stats       RegionStats
+}
+
+// MultiRegion routes to the lowest-latency healthy region and fails over on outages.
+//
+// Input: clients keyed by region name, e.g. the same model on several regional endpoints
+// Output: *MultiRegionClient implementing Client
+// Behavior: STREAMING - streams from the chosen region; fails over only before any output
+//
+// Regions are tried fastest first, by a moving average of time to first byte.
+// Regions without measurements are tried before measured ones so every region
+// gets a latency estimate. A region failing FailureThreshold times in a row is
+// marked unhealthy and only used as a last resort until a request or probe
+// succeeds, or Cooldown passes. Once a region has streamed output, its errors
+// are returned as is, since the response can't be replayed elsewhere. The
+// region that served each request is recorded on the MetadataBus under
+// RegionMetadataKey.
+//
+// Example:
+//
+//	client := ai.MultiRegion(map[string]ai.Client{
+//		"us-east": usEast,
+//		"eu-west": euWest,
+//	}, &ai.RegionPolicy{ProbeInterval: 30 * time.Second})
+//	agent := ai.Agent(client)
+func MultiRegion(clients map[string]Client, policy *RegionPolicy) *MultiRegionClient {
+	p := RegionPolicy{}
+	if policy != nil {
+		p = *policy
+	}
+	if p.FailureThreshold <= 0 {
+		p.FailureThreshold = 2
+	}
+	if p.Cooldown <= 0 {
+		p.Cooldown = 30 * time.Second
+	}
+	if p.ProbeTimeout <= 0 {
+		p.ProbeTimeout = 5 * time.Second
+	}
+	if p.Smoothing <= 0 || p.Smoothing > 1 {
+		p.Smoothing = 0.2
+	}
+	if p.Probe == nil {
+		p.Probe = warmupProbe
+	}
+
+	m := &MultiRegionClient{
+		clients: clients,
+		policy:  p,
+		regions: make(map[string]*regionState, len(clients)),
+		stop:    make(chan struct{}),
+	}
+	for name := range clients {
+		m.regions[name] = &regionState{stats: RegionStats{Healthy: true}}
+		if !slices.Contains(p.Preferred, name) {
+			m.names = append(m.names, name)
+		}
+	}
+	slices.Sort(m.names)
+	for _, name := range slices.Backward(p.Preferred) {
+		if _, ok := clients[name]; ok {
+			m.names = slices.Insert(m.names, 0, name)
+		}
+	}
+	return m
+}
+
+// warmupProbe checks clients that support warm-up and treats others as healthy
+func warmupProbe(ctx context.Context, _ string, client Client) error {
+	if w, ok := client.(calque.Warmer); ok {
+		return w.Warmup(ctx)
+	}
+	return nil
+}
+
+// Chat implements Client
+func (m *MultiRegionClient) Chat(r *calque.Request, w *calque.Response, opts *AgentOptions) error {
+	m.startProbes()
+	ctx := r.Context
+
+	input, err := io.ReadAll(r.Data)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to read input")
+	}
+
+	var errs []error
+	for _, region := range m.route() {
+		out := &firstByteWriter{w: w.Data}
+		start := time.Now()
+		err := m.clients[region].Chat(calque.NewRequest(ctx, bytes.NewReader(input)), calque.NewResponse(out), opts)
+
+		latency := time.Since(start)
+		if !out.first.IsZero() {
+			latency = out.first.Sub(start)
+		}
+		regional := err != nil && m.isRegionalFailure(ctx, err)
+		m.record(region, latency, err, regional)
+
+		if err == nil {
+			if bus := calque.GetMetadataBus(ctx); bus != nil {
+				bus.Set(RegionMetadataKey, region)
+			}
+			return nil
+		}
+		if !regional || out.written {
+			return err
+		}
+		calque.LogWarn(ctx, "region failed, failing over", "region", region, "error", err)
+		errs = append(errs, fmt.Errorf("%s: %w", region, err))
+	}
+	return calque.WrapErr(ctx, errors.Join(errs...), "all regions failed")
+}
+
+func (m *MultiRegionClient) isRegionalFailure(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if m.policy.IsRegionalFailure != nil {
+		return m.policy.IsRegionalFailure(err)
+	}
+	return true
+}
+
+// route orders regions: healthy ones unmeasured first then by latency, unhealthy ones last
+func (m *MultiRegionClient) route() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var healthy, unhealthy []string
+	for _, name := range m.names {
+		state := m.regions[name]
+		if state.stats.Healthy || now.Sub(state.unhealthyAt) >= m.policy.Cooldown {
+			healthy = append(healthy, name)
+		} else {
+			unhealthy = append(unhealthy, name)
+		}
+	}
+	slices.SortStableFunc(healthy, func(a, b string) int {
+		return cmp.Compare(m.regions[a].latency, m.regions[b].latency)
+	})
+	return append(healthy, unhealthy...)
+}
+
+// record updates a region's health and latency after a request or probe
+func (m *MultiRegionClient) record(region string, latency time.Duration, err error, regional bool) {
+	m.mu.Lock()
+	state := m.regions[region]
+	state.stats.Requests++
+	switch {
+	case err == nil:
+		state.failures = 0
+		state.stats.Healthy = true
+		if state.latency == 0 {
+			state.latency = latency
+		} else {
+			state.latency += time.Duration(m.policy.Smoothing * float64(latency-state.latency))
+		}
+	case regional:
+		state.failures++
+		state.stats.Failures++
+		state.stats.LastError = err.Error()
+		if state.failures >= m.policy.FailureThreshold {
+			if state.stats.Healthy {
+				calque.LogWarn(context.Background(), "region marked unhealthy", "region", region, "failures", state.failures)
+			}
+			state.stats.Healthy = false
+			state.unhealthyAt = time.Now()
+		}
+	}
+	m.mu.Unlock()
+
+	if m.policy.OnResult != nil {
+		m.policy.OnResult(region, latency, err)
+	}
+}
+
+// Stats returns a snapshot of every region's health, keyed by region
+func (m *MultiRegionClient) Stats() map[string]RegionStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make(map[string]RegionStats, len(m.regions))
+	for name, state := range m.regions {
+		s := state.stats
+		s.Latency = state.latency
+		stats[name] = s
+	}
+	return stats
+}
+
+// Probe checks every region once and updates its health
+func (m *MultiRegionClient) Probe(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, name := range m.names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, m.policy.ProbeTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := m.policy.Probe(probeCtx, name, m.clients[name])
+			m.record(name, time.Since(start), err, err != nil)
+		}()
+	}
+	wg.Wait()
+}
+
+// startProbes runs background probes if a ProbeInterval is set
+func (m *MultiRegionClient) startProbes() {
+	if m.policy.ProbeInterval <= 0 {
+		return
+	}
+	m.probeOnce.Do(func() {
+		m.probing.Add(1)
+		go func() {
+			defer m.probing.Done()
+			ticker := time.NewTicker(m.policy.ProbeInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-m.stop:
+					return
+				case <-ticker.C:
+					m.Probe(context.Background())
+				}
+			}
+		}()
+	})
+}
+
+// SupportsStructuredOutput implements StructuredOutputCapable when every region does
+func (m *MultiRegionClient) SupportsStructuredOutput() bool {
+	for _, client := range m.clients {
+		if !supportsStructuredOutput(client) {
+			return false
+		}
+	}
+	return len(m.clients) > 0
+}
+
+// Warmup implements calque.Warmer by probing every region and starting background probes.
+// It fails only if no region is reachable.
+func (m *MultiRegionClient) Warmup(ctx context.Context) error {
+	m.Probe(ctx)
+	m.startProbes()
+	for name, s := range m.Stats() {
+		if s.Healthy {
+			calque.LogDebug(ctx, "region reachable", "region", name, "latency", s.Latency)
+			return nil
+		}
+	}
+	return calque.NewErr(ctx, "no region is reachable")
+}
+
+// SupportsSeed implements SeedCapable when every region's client does
+func (m *MultiRegionClient) SupportsSeed() bool {
+	for _, name := range m.names {
+		if !supportsSeed(m.clients[name]) {
+			return false
+		}
+	}
+	return len(m.names) > 0
+}
+
+// Egress implements calque.Egress with the destinations of every region's client
+func (m *MultiRegionClient) Egress() []string {
+	values := make([]any, len(m.names))
+	for i, name := range m.names {
+		values[i] = m.clients[name]
+	}
+	return egressOf(values...)
+}
+
+// Shutdown implements calque.Shutdowner by stopping probes and shutting down every region's client
+func (m *MultiRegionClient) Shutdown(ctx context.Context) error {
+	m.stopOnce.Do(func() { close(m.stop) })
+	m.probing.Wait()
+
+	var errs []error
+	for _, name := range m.names {
+		switch c := m.clients[name].(type) {
+		case calque.Shutdowner:
+			errs = append(errs, c.Shutdown(ctx))
+		case io.Closer:
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// firstByteWriter records whether and when output started
+type firstByteWriter struct {
+	w       io.Writer
+	written bool
+	first   time.Time
+}
+
+func (f *firstByteWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 && !f.written {
+		f.written = true
+		f.first = time.Now()
+	}
+	return f.w.Write(p)
+}
@@ -0,0 +1,211 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// regionClient answers with its name after a delay, or fails after writing partial
+type regionClient struct {
+	name    string
+	delay   time.Duration
+	fail    atomic.Bool
+	partial string
+	calls   atomic.Int32
+	warmErr error
+	closed  bool
+}
+
+func (c *regionClient) Chat(r *calque.Request, w *calque.Response, _ *AgentOptions) error {
+	c.calls.Add(1)
+	if _, err := io.ReadAll(r.Data); err != nil {
+		return err
+	}
+	time.Sleep(c.delay)
+	if c.fail.Load() {
+		if c.partial != "" {
+			_, _ = io.WriteString(w.Data, c.partial)
+		}
+		return errors.New(c.name + " unavailable")
+	}
+	_, err := io.WriteString(w.Data, c.name)
+	return err
+}
+
+func (c *regionClient) Warmup(context.Context) error { return c.warmErr }
+
+func (c *regionClient) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestMultiRegion_RoutesToFastest(t *testing.T) {
+	slow := &regionClient{name: "us-east", delay: 20 * time.Millisecond}
+	fast := &regionClient{name: "eu-west"}
+	client := MultiRegion(map[string]Client{"us-east": slow, "eu-west": fast}, &RegionPolicy{Preferred: []string{"us-east"}})
+	agent := Agent(client)
+
+	// Unmeasured regions are tried in preferred order, then the faster one wins
+	if out, err := runAgentFlow(context.Background(), agent, "q"); err != nil || out != "us-east" {
+		t.Fatalf("first Run() = %q, %v; want us-east", out, err)
+	}
+	for range 3 {
+		if out, err := runAgentFlow(context.Background(), agent, "q"); err != nil || out != "eu-west" {
+			t.Fatalf("Run() = %q, %v; want eu-west", out, err)
+		}
+	}
+	if stats := client.Stats(); stats["eu-west"].Latency >= stats["us-east"].Latency {
+		t.Errorf("Stats() latency eu-west %v, us-east %v", stats["eu-west"].Latency, stats["us-east"].Latency)
+	}
+}
+
+func TestMultiRegion_FailsOver(t *testing.T) {
+	primary := &regionClient{name: "a"}
+	primary.fail.Store(true)
+	secondary := &regionClient{name: "b"}
+
+	var results []string
+	client := MultiRegion(map[string]Client{"a": primary, "b": secondary}, &RegionPolicy{
+		FailureThreshold: 1,
+		Cooldown:         time.Hour,
+		OnResult: func(region string, _ time.Duration, err error) {
+			results = append(results, region+":"+map[bool]string{true: "ok", false: "err"}[err == nil])
+		},
+	})
+
+	ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+	var out strings.Builder
+	if err := client.Chat(calque.NewRequest(ctx, strings.NewReader("q")), calque.NewResponse(&out), &AgentOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "b" {
+		t.Errorf("output = %q, want b", out.String())
+	}
+	if region, _ := calque.GetMetadataBus(ctx).GetString(RegionMetadataKey); region != "b" {
+		t.Errorf("%s = %q, want b", RegionMetadataKey, region)
+	}
+	if got := strings.Join(results, ","); got != "a:err,b:ok" {
+		t.Errorf("OnResult calls = %s", got)
+	}
+
+	// The unhealthy region is skipped until its cooldown passes
+	if _, err := runAgentFlow(context.Background(), Agent(client), "q"); err != nil {
+		t.Fatal(err)
+	}
+	if calls := primary.calls.Load(); calls != 1 {
+		t.Errorf("unhealthy region called %d times, want 1", calls)
+	}
+	if stats := client.Stats()["a"]; stats.Healthy || stats.Failures != 1 || stats.LastError == "" {
+		t.Errorf("Stats()[a] = %+v", stats)
+	}
+}
+
+func TestMultiRegion_NoFailoverAfterOutput(t *testing.T) {
+	primary := &regionClient{name: "a", partial: "half an ans"}
+	primary.fail.Store(true)
+	secondary := &regionClient{name: "b"}
+	client := MultiRegion(map[string]Client{"a": primary, "b": secondary}, nil)
+
+	out, err := runAgentFlow(context.Background(), Agent(client), "q")
+	if err == nil {
+		t.Fatalf("Run() = %q, want the streaming region's error", out)
+	}
+	if secondary.calls.Load() != 0 {
+		t.Error("failed over after output was streamed")
+	}
+}
+
+func TestMultiRegion_AllRegionsFail(t *testing.T) {
+	a, b := &regionClient{name: "a"}, &regionClient{name: "b"}
+	a.fail.Store(true)
+	b.fail.Store(true)
+
+	_, err := runAgentFlow(context.Background(), Agent(MultiRegion(map[string]Client{"a": a, "b": b}, nil)), "q")
+	if err == nil || !strings.Contains(err.Error(), "a unavailable") || !strings.Contains(err.Error(), "b unavailable") {
+		t.Errorf("Run() error = %v, want both regional errors", err)
+	}
+}
+
+func TestMultiRegion_NonRegionalErrorReturned(t *testing.T) {
+	a, b := &regionClient{name: "a"}, &regionClient{name: "b"}
+	a.fail.Store(true)
+	client := MultiRegion(map[string]Client{"a": a, "b": b}, &RegionPolicy{
+		IsRegionalFailure: func(error) bool { return false },
+	})
+
+	if _, err := runAgentFlow(context.Background(), Agent(client), "q"); err == nil {
+		t.Error("Run() error = nil, want the request error")
+	}
+	if b.calls.Load() != 0 || !client.Stats()["a"].Healthy {
+		t.Error("a non-regional error triggered failover")
+	}
+}
+
+func TestMultiRegion_ProbesAndLifecycle(t *testing.T) {
+	up := &regionClient{name: "up"}
+	down := &regionClient{name: "down", warmErr: errors.New("dns failure")}
+	client := MultiRegion(map[string]Client{"up": up, "down": down}, &RegionPolicy{FailureThreshold: 1})
+
+	if err := client.Warmup(context.Background()); err != nil {
+		t.Fatalf("Warmup() = %v, want nil with one region reachable", err)
+	}
+	if client.Stats()["down"].Healthy {
+		t.Error("failed probe left region healthy")
+	}
+
+	// A successful probe brings the region back
+	down.warmErr = nil
+	client.Probe(context.Background())
+	if !client.Stats()["down"].Healthy {
+		t.Error("successful probe didn't restore region")
+	}
+
+	if err := client.Shutdown(context.Background()); err != nil || !up.closed || !down.closed {
+		t.Errorf("Shutdown() = %v, closed up=%v down=%v", err, up.closed, down.closed)
+	}
+
+	up.warmErr = errors.New("down")
+	down.warmErr = errors.New("down")
+	if err := client.Warmup(context.Background()); err == nil {
+		t.Error("Warmup() = nil with no region reachable")
+	}
+}
+
+func TestMultiRegion_BackgroundProbes(t *testing.T) {
+	var probes atomic.Int32
+	client := MultiRegion(map[string]Client{"a": &regionClient{name: "a"}}, &RegionPolicy{
+		ProbeInterval: 5 * time.Millisecond,
+		Probe: func(context.Context, string, Client) error {
+			probes.Add(1)
+			return nil
+		},
+	})
+
+	if _, err := runAgentFlow(context.Background(), Agent(client), "q"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := client.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if probes.Load() == 0 {
+		t.Error("no background probes ran")
+	}
+}
+
+func TestMultiRegion_SupportsStructuredOutput(t *testing.T) {
+	native := &promptRecorder{native: true}
+	if !MultiRegion(map[string]Client{"a": native}, nil).SupportsStructuredOutput() {
+		t.Error("want structured output when every region supports it")
+	}
+	if MultiRegion(map[string]Client{"a": native, "b": &regionClient{}}, nil).SupportsStructuredOutput() {
+		t.Error("want no structured output when a region lacks it")
+	}
+}
@@ -0,0 +1,118 @@
+package ai
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// NetworkConfig configures how clients reach their provider from restricted networks.
+//
+// Provider configs take it as Network. It only applies when the provider
+// config has no HTTPClient; a custom client carries its own proxy and TLS
+// settings.
+//
+// Example:
+//
+//	client, _ := openai.New("gpt-4o", openai.WithConfig(&openai.Config{
+//		BaseURL: "https://llm-gateway.corp.example/v1",
+//		Network: &ai.NetworkConfig{
+//			ProxyURL:   "http://proxy.corp.example:3128",
+//			CACertFile: "/etc/ssl/corp-root-ca.pem",
+//		},
+//	}))
+type NetworkConfig struct {
+	// ProxyURL routes every request through this proxy. By default the
+	// HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables apply.
+	ProxyURL string
+
+	// CACertFile is a PEM bundle of extra CAs to trust, e.g. for a TLS-inspecting
+	// proxy. They are added to the system roots.
+	CACertFile string
+
+	// CACertPEM holds extra CAs in PEM form, for bundles not stored on disk
+	CACertPEM []byte
+
+	// Timeout limits each request including reading the response (default: none,
+	// so long streams are not cut off)
+	Timeout time.Duration
+}
+
+// NewHTTPClient creates an HTTP client for provider requests.
+//
+// Input: NetworkConfig (nil for environment proxies and system CAs)
+// Output: *http.Client, error for an invalid proxy URL or CA bundle
+// Behavior: Clones http.DefaultTransport, so connection pooling and HTTP/2 are kept
+//
+// Provider clients call it when their config has Network but no HTTPClient.
+// Use it directly to share one proxied client between providers.
+//
+// Example:
+//
+//	httpClient, err := ai.NewHTTPClient(&ai.NetworkConfig{ProxyURL: os.Getenv("CORP_PROXY")})
+//	if err != nil { log.Fatal(err) }
+//	client, _ := gemini.New("gemini-2.0-flash", gemini.WithConfig(&gemini.Config{HTTPClient: httpClient}))
+func NewHTTPClient(network *NetworkConfig) (*http.Client, error) {
+	ctx := context.Background()
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if network == nil {
+		return &http.Client{Transport: transport}, nil
+	}
+
+	if network.ProxyURL != "" {
+		proxy, err := url.Parse(network.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return nil, calque.NewErr(ctx, fmt.Sprintf("invalid proxy URL %q", network.ProxyURL))
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if network.CACertFile != "" || len(network.CACertPEM) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if network.CACertFile != "" {
+			bundle, err := os.ReadFile(network.CACertFile)
+			if err != nil {
+				return nil, calque.WrapErr(ctx, err, "failed to read CA bundle")
+			}
+			if !pool.AppendCertsFromPEM(bundle) {
+				return nil, calque.NewErr(ctx, fmt.Sprintf("no certificates found in CA bundle %s", network.CACertFile))
+			}
+		}
+		if len(network.CACertPEM) > 0 && !pool.AppendCertsFromPEM(network.CACertPEM) {
+			return nil, calque.NewErr(ctx, "no certificates found in CACertPEM")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	return &http.Client{Transport: transport, Timeout: network.Timeout}, nil
+}
+
+// ProviderHTTPClient returns the HTTP client a provider should use: client when
+// set, else one built from network, else nil for the SDK's default.
+func ProviderHTTPClient(client *http.Client, network *NetworkConfig) (*http.Client, error) {
+	if client != nil || network == nil {
+		return client, nil
+	}
+	return NewHTTPClient(network)
+}
+
+// InterceptClient returns a copy of client whose transport runs the
+// interceptors in each request's context. A nil client copies http.DefaultClient.
+func InterceptClient(client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	intercepted := *client
+	intercepted.Transport = InterceptTransport(client.Transport)
+	return &intercepted
+}
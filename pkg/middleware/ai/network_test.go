@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String() // proxies receive the absolute target URL
+		_, _ = io.WriteString(w, "via proxy")
+	}))
+	defer proxy.Close()
+
+	client, err := NewHTTPClient(&NetworkConfig{ProxyURL: proxy.URL})
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	resp, err := client.Get("http://api.provider.test/v1/models")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if proxied != "http://api.provider.test/v1/models" || string(body) != "via proxy" {
+		t.Errorf("proxy saw %q and returned %q", proxied, body)
+	}
+}
+
+func TestNewHTTPClientCABundle(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // the untrusted handshake is expected to fail
+	server.StartTLS()
+	defer server.Close()
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	untrusted, _ := NewHTTPClient(nil)
+	if _, err := untrusted.Get(server.URL); err == nil {
+		t.Fatal("request to a server with an unknown CA should fail without the bundle")
+	}
+
+	path := filepath.Join(t.TempDir(), "corp-ca.pem")
+	if err := os.WriteFile(path, bundle, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for name, network := range map[string]*NetworkConfig{
+		"file": {CACertFile: path},
+		"pem":  {CACertPEM: bundle},
+	} {
+		client, err := NewHTTPClient(network)
+		if err != nil {
+			t.Fatalf("%s: NewHTTPClient() error = %v", name, err)
+		}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("%s: Get() error = %v", name, err)
+		}
+		resp.Body.Close()
+	}
+}
+
+func TestNewHTTPClientErrors(t *testing.T) {
+	tests := map[string]struct {
+		network *NetworkConfig
+		want    string
+	}{
+		"invalid proxy":      {&NetworkConfig{ProxyURL: "::not a url"}, "invalid proxy URL"},
+		"proxy without host": {&NetworkConfig{ProxyURL: "proxy.corp:3128"}, "invalid proxy URL"},
+		"missing bundle":     {&NetworkConfig{CACertFile: filepath.Join(t.TempDir(), "missing.pem")}, "failed to read CA bundle"},
+		"empty bundle":       {&NetworkConfig{CACertPEM: []byte("not a certificate")}, "no certificates found"},
+	}
+	for name, tt := range tests {
+		if _, err := NewHTTPClient(tt.network); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: NewHTTPClient() error = %v, want %q", name, err, tt.want)
+		}
+	}
+}
+
+func TestProviderHTTPClient(t *testing.T) {
+	custom := &http.Client{}
+	if got, _ := ProviderHTTPClient(custom, &NetworkConfig{ProxyURL: "http://proxy.test"}); got != custom {
+		t.Error("a configured HTTPClient should take precedence over Network")
+	}
+	if got, _ := ProviderHTTPClient(nil, nil); got != nil {
+		t.Error("no HTTPClient or Network should leave the SDK default")
+	}
+	if got, _ := ProviderHTTPClient(nil, &NetworkConfig{}); got == nil {
+		t.Error("Network should build a client")
+	}
+
+	intercepted := InterceptClient(custom)
+	if intercepted == custom || custom.Transport != nil {
+		t.Error("InterceptClient() should not modify the client it copies")
+	}
+}
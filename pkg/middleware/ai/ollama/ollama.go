@@ -6,21 +6,25 @@ package ollama
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"maps"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/invopop/jsonschema"
 	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/envconfig"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
 	"github.com/calque-ai/go-calque/pkg/helpers"
 	"github.com/calque-ai/go-calque/pkg/middleware/ai"
 	"github.com/calque-ai/go-calque/pkg/middleware/ai/config"
 	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
 )
 
 // Client implements the Client interface for Ollama.
@@ -34,6 +38,7 @@ import (
 //	agent := ai.Agent(client)
 type Client struct {
 	client    *api.Client
+	host      string // server URL, for Egress
 	model     string
 	config    *Config
 	lastUsage *ai.UsageMetadata
@@ -54,6 +59,14 @@ type Config struct {
 	// Optional. Ollama server host (defaults to localhost:11434 or OLLAMA_HOST env)
 	Host string
 
+	// Optional. HTTP client used for requests, e.g. one shared with other
+	// clients or with a custom transport (defaults to http.DefaultClient)
+	HTTPClient *http.Client
+
+	// Optional. Proxy and CA settings for restricted networks, used when
+	// HTTPClient is not set (defaults to HTTPS_PROXY/HTTP_PROXY and system CAs)
+	Network *ai.NetworkConfig
+
 	// Optional. Controls randomness in token selection (0.0-2.0)
 	// Lower values = more deterministic, higher values = more creative
 	Temperature *float32
@@ -84,6 +97,20 @@ type Config struct {
 	// Optional. Model-specific options (temperature, top_p, etc.)
 	// These override the individual fields above if both are set
 	Options map[string]any
+
+	// Optional. Model tokenizer for exact TokenEstimator counts, e.g. the model's
+	// SentencePiece or BPE vocabulary via tokenizer.Func (default:
+	// tokenizer.Conservative, an estimate)
+	Tokenizer tokenizer.Tokenizer
+}
+
+// Validate reports every out-of-range setting, as ai.FieldError values
+func (c *Config) Validate() error {
+	return errors.Join(
+		ai.CheckURL("Host", c.Host),
+		ai.CheckRange("Temperature", c.Temperature, 0, 2),
+		ai.CheckRange("TopP", c.TopP, 0, 1),
+	)
 }
 
 // Option interface for functional options pattern
@@ -157,17 +184,25 @@ func New(model string, opts ...Option) (*Client, error) {
 	for _, opt := range opts {
 		opt.Apply(config)
 	}
+	if err := config.Validate(); err != nil {
+		return nil, calque.WrapErr(ctx, err, "invalid ollama config")
+	}
 
-	// Create Ollama client
+	baseClient, err := ai.ProviderHTTPClient(config.HTTPClient, config.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create Ollama client; the transport runs interceptors from ai.WithInterceptor
+	httpClient := ai.InterceptClient(baseClient)
 	var client *api.Client
-	var err error
+	host := config.Host
 
 	if config.Host == "" {
-		// Use environment-based client (checks OLLAMA_HOST env var)
-		client, err = api.ClientFromEnvironment()
-		if err != nil {
-			return nil, calque.WrapErr(ctx, err, "failed to create client from environment")
-		}
+		// Use environment-based host (checks OLLAMA_HOST env var)
+		base := envconfig.Host()
+		client = api.NewClient(base, httpClient)
+		host = base.String()
 	} else {
 		// Parse the host URL
 		u, err := url.Parse(config.Host)
@@ -175,11 +210,12 @@ func New(model string, opts ...Option) (*Client, error) {
 			return nil, calque.WrapErr(ctx, err, "invalid host URL")
 		}
 		// Create client with custom host
-		client = api.NewClient(u, http.DefaultClient)
+		client = api.NewClient(u, httpClient)
 	}
 
 	return &Client{
 		client: client,
+		host:   host,
 		model:  model,
 		config: config,
 	}, nil
@@ -190,6 +226,54 @@ type RequestConfig struct {
 	ChatRequest *api.ChatRequest
 }
 
+// Warmup implements calque.Warmer by loading the model into memory.
+//
+// Ollama loads models lazily, so the first request after start-up can take
+// seconds to minutes. Warmup sends an empty generate request, which loads the
+// model and keeps it resident for Config.KeepAlive.
+//
+// Example:
+//
+//	if err := client.Warmup(ctx); err != nil {
+//		log.Fatal(err) // server unreachable or model not pulled
+//	}
+func (o *Client) Warmup(ctx context.Context) error {
+	req := &api.GenerateRequest{Model: o.model}
+	if d, err := time.ParseDuration(o.config.KeepAlive); err == nil {
+		req.KeepAlive = &api.Duration{Duration: d}
+	}
+	if err := o.client.Generate(ctx, req, func(api.GenerateResponse) error { return nil }); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("ollama warm-up for model %s failed", o.model))
+	}
+	return nil
+}
+
+// SupportsStructuredOutput implements ai.StructuredOutputCapable; schemas are sent as the format parameter
+func (o *Client) SupportsStructuredOutput() bool { return true }
+
+// SupportsSeed implements ai.SeedCapable; deterministic runs send the seed as a model option
+func (o *Client) SupportsSeed() bool { return true }
+
+// Egress implements calque.Egress. An Ollama server on this machine keeps
+// prompts local; a remote host is reported.
+func (o *Client) Egress() []string {
+	return calque.EgressHosts(o.host)
+}
+
+// TokenEstimator returns an ai.TokenEstimator for the client's model, counting
+// with Config.Tokenizer when set and estimating otherwise. The context window
+// is the num_ctx option when set, since Ollama silently drops the start of
+// prompts longer than num_ctx.
+func (o *Client) TokenEstimator() ai.TokenEstimator {
+	switch window := o.config.Options["num_ctx"].(type) {
+	case int:
+		return ai.NewTokenEstimatorWithWindow(o.config.Tokenizer, window)
+	case float64: // options decoded from JSON
+		return ai.NewTokenEstimatorWithWindow(o.config.Tokenizer, int(window))
+	}
+	return ai.NewTokenEstimator(o.model, o.config.Tokenizer)
+}
+
 // Chat implements the Client interface.
 //
 // Input: user prompt/query via calque.Request
@@ -202,7 +286,19 @@ type RequestConfig struct {
 // Example:
 //
 //	err := client.Chat(req, res, &ai.AgentOptions{Tools: tools})
-func (o *Client) Chat(r *calque.Request, w *calque.Response, opts *ai.AgentOptions) error {
+func (o *Client) Chat(r *calque.Request, w *calque.Response, opts *ai.AgentOptions) (err error) {
+	o = o.withOverrides(r.Context)
+	r, span := ai.StartChatSpan(r, ai.ChatSpanInfo{
+		Provider:    "ollama",
+		Model:       o.model,
+		Temperature: o.config.Temperature,
+		MaxTokens:   o.config.MaxTokens,
+	})
+	defer func() { span.End(err) }()
+
+	// Route provider requests through the call's interceptors, see ai.WithInterceptor
+	r = r.WithContext(ai.ContextWithInterceptors(r.Context, ai.GetInterceptors(opts)...))
+
 	// Which input type are we processing?
 	input, err := ai.ClassifyInput(r, opts)
 	if err != nil {
@@ -219,6 +315,32 @@ func (o *Client) Chat(r *calque.Request, w *calque.Response, opts *ai.AgentOptio
 	return o.executeRequest(config, r, w, opts)
 }
 
+// ModelSettings implements ai.ModelDescriber
+func (o *Client) ModelSettings(ctx context.Context) ai.ModelSettings {
+	o = o.withOverrides(ctx)
+	return ai.ModelSettings{Model: o.model, Temperature: o.config.Temperature, MaxTokens: o.config.MaxTokens}
+}
+
+// withOverrides returns a copy of the client using the call's ai.Overrides, if any
+func (o *Client) withOverrides(ctx context.Context) *Client {
+	overrides := ai.OverridesFrom(ctx)
+	if overrides.IsZero() {
+		return o
+	}
+	client, config := *o, *o.config
+	if overrides.Model != "" {
+		client.model = overrides.Model
+	}
+	if overrides.Temperature != nil {
+		config.Temperature = overrides.Temperature
+	}
+	if overrides.MaxTokens != nil {
+		config.MaxTokens = overrides.MaxTokens
+	}
+	client.config = &config
+	return &client
+}
+
 // buildRequestConfig creates configuration for the request
 func (o *Client) buildRequestConfig(ctx context.Context, input *ai.ClassifiedInput, schema *ai.ResponseFormat, tools []tools.Tool) (*RequestConfig, error) {
 	// Create chat request based on input type
@@ -229,6 +351,9 @@ func (o *Client) buildRequestConfig(ctx context.Context, input *ai.ClassifiedInp
 
 	// Apply configuration
 	o.applyChatConfig(chatRequest, schema)
+	if seed, ok := calque.DeterministicSeed(ctx); ok {
+		chatRequest.Options["seed"] = seed
+	}
 
 	// Add tools if provided
 	if len(tools) > 0 {
@@ -243,6 +368,7 @@ func (o *Client) buildRequestConfig(ctx context.Context, input *ai.ClassifiedInp
 // reportUsage invokes the usage handler if present
 func (o *Client) reportUsage(opts *ai.AgentOptions) {
 	if o.lastUsage != nil && opts != nil && opts.UsageHandler != nil {
+		o.lastUsage.Model = o.model
 		opts.UsageHandler(o.lastUsage)
 	}
 }
@@ -255,13 +381,23 @@ func (o *Client) executeRequest(config *RequestConfig, r *calque.Request, w *cal
 
 	// Determine if we need to buffer the response
 	shouldBuffer := len(config.ChatRequest.Tools) > 0 || config.ChatRequest.Format != nil
+	reasoning := ai.GetReasoning(opts)
 
 	responseFunc := func(resp api.ChatResponse) error {
+		// Thinking models report reasoning separately from content
+		if err := reasoning.Append(resp.Message.Thinking); err != nil {
+			return err
+		}
+
 		// Collect tool calls
 		if len(resp.Message.ToolCalls) > 0 {
 			toolCalls = append(toolCalls, resp.Message.ToolCalls...)
 		}
 
+		if resp.Done {
+			ai.SetChatResponse(r.Context, "", resp.Model, resp.DoneReason)
+		}
+
 		// Capture token counts
 		if resp.PromptEvalCount > 0 {
 			promptTokens = resp.PromptEvalCount
@@ -297,8 +433,13 @@ func (o *Client) executeRequest(config *RequestConfig, r *calque.Request, w *cal
 	}
 
 	// Report usage
+	ai.SetChatUsage(r.Context, o.lastUsage)
 	o.reportUsage(opts)
 
+	if err := reasoning.Finish(r.Context); err != nil {
+		return err
+	}
+
 	// Process tool calls if found
 	if len(toolCalls) > 0 {
 		return o.writeOllamaToolCalls(toolCalls, w)
@@ -352,6 +493,16 @@ func (o *Client) inputToChatRequest(ctx context.Context, input *ai.ClassifiedInp
 		}
 		req.Messages = []api.Message{*message}
 
+	case ai.MessagesInput:
+		for _, msg := range input.Messages.Messages {
+			req.Messages = append(req.Messages, api.Message{
+				Role:       string(msg.Role),
+				Content:    msg.Content,
+				ToolName:   msg.Name,
+				ToolCallID: msg.ToolCallID,
+			})
+		}
+
 	default:
 		return nil, calque.NewErr(ctx, fmt.Sprintf("unsupported input type: %d", input.Type))
 	}
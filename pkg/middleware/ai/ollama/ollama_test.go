@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 
@@ -95,6 +96,69 @@ func TestDefaultConfig(t *testing.T) {
 	}
 }
 
+func TestBuildRequestConfigDeterministic(t *testing.T) {
+	client := &Client{model: "llama3.2", config: &Config{}}
+	input := &ai.ClassifiedInput{Type: ai.TextInput, Text: "Hello"}
+
+	config, err := client.buildRequestConfig(context.Background(), input, nil, nil)
+	if err != nil {
+		t.Fatalf("buildRequestConfig() error = %v", err)
+	}
+	if _, ok := config.ChatRequest.Options["seed"]; ok {
+		t.Error("seed set outside a deterministic run")
+	}
+
+	config, err = client.buildRequestConfig(calque.WithDeterministicSeed(context.Background(), 42), input, nil, nil)
+	if err != nil || config.ChatRequest.Options["seed"] != int64(42) {
+		t.Errorf("seed option = %v, %v; want 42", config.ChatRequest.Options["seed"], err)
+	}
+}
+
+func TestEgress(t *testing.T) {
+	tests := []struct {
+		host string
+		want []string
+	}{
+		{"http://localhost:11434", nil},
+		{"http://127.0.0.1:11434", nil},
+		{"http://gpu-box.internal:11434", []string{"gpu-box.internal"}},
+	}
+
+	for _, tt := range tests {
+		client, err := New("llama3.2", WithConfig(&Config{Host: tt.host}))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if got := client.Egress(); !slices.Equal(got, tt.want) {
+			t.Errorf("Egress() with host %q = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestTokenEstimator(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]any
+		want    int
+	}{
+		{"model context window", nil, 131072},
+		{"num_ctx option", map[string]any{"num_ctx": 4096}, 4096},
+		{"num_ctx decoded from JSON", map[string]any{"num_ctx": float64(8192)}, 8192},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := New("llama3.1:8b", WithConfig(&Config{Options: tt.options}))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := client.TokenEstimator().ContextWindow(); got != tt.want {
+				t.Errorf("ContextWindow() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestWithConfig(t *testing.T) {
 	customConfig := &Config{
 		Temperature: helpers.PtrOf(float32(0.9)),
@@ -150,6 +214,29 @@ func TestInputToChatRequest(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			name: "messages input",
+			input: &ai.ClassifiedInput{
+				Type: ai.MessagesInput,
+				Messages: &ai.Messages{Messages: []ai.Message{
+					{Role: ai.RoleSystem, Content: "Be brief."},
+					{Role: ai.RoleUser, Content: "Weather?"},
+					{Role: ai.RoleTool, Name: "weather", ToolCallID: "call_1", Content: "sunny"},
+				}},
+			},
+			checkFunc: func(req *api.ChatRequest) error {
+				if len(req.Messages) != 3 {
+					return fmt.Errorf("messages length = %v, want 3", len(req.Messages))
+				}
+				if req.Messages[0].Role != "system" || req.Messages[1].Content != "Weather?" {
+					return fmt.Errorf("messages = %+v, want system then user", req.Messages)
+				}
+				if tool := req.Messages[2]; tool.Role != "tool" || tool.ToolName != "weather" || tool.ToolCallID != "call_1" {
+					return fmt.Errorf("tool message = %+v", tool)
+				}
+				return nil
+			},
+		},
 		{
 			name: "multimodal input with text",
 			input: &ai.ClassifiedInput{
@@ -811,3 +898,73 @@ func TestToolCallPriority(t *testing.T) {
 		t.Error("Tool calls output should not contain text content")
 	}
 }
+
+func TestWarmup(t *testing.T) {
+	var got api.GenerateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/generate" {
+			http.Error(w, "Not found", 404)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, "Bad request", 400)
+			return
+		}
+		if got.Model != "test-model" {
+			http.Error(w, `{"error":"model not found"}`, 404)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		json.NewEncoder(w).Encode(api.GenerateResponse{Model: got.Model, Done: true, DoneReason: "load"})
+	}))
+	defer server.Close()
+
+	client, err := New("test-model", WithConfig(&Config{Host: server.URL, KeepAlive: "10m"}))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if err := client.Warmup(context.Background()); err != nil {
+		t.Fatalf("Warmup() error = %v", err)
+	}
+	if got.Prompt != "" || got.KeepAlive == nil || got.KeepAlive.Duration.String() != "10m0s" {
+		t.Errorf("generate request = %+v, want empty prompt with keep_alive 10m", got)
+	}
+
+	missing, _ := New("missing-model", WithConfig(&Config{Host: server.URL}))
+	if err := missing.Warmup(context.Background()); err == nil {
+		t.Error("Warmup() for a missing model should fail")
+	}
+}
+
+func TestChatCapturesThinking(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		enc.Encode(api.ChatResponse{Message: api.Message{Role: "assistant", Thinking: "Two plus "}})
+		enc.Encode(api.ChatResponse{Message: api.Message{Role: "assistant", Thinking: "two is four."}})
+		enc.Encode(api.ChatResponse{Message: api.Message{Role: "assistant", Content: "4"}, Done: true})
+	}))
+	defer server.Close()
+
+	client, err := New("test-model", WithConfig(&Config{Host: server.URL}))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	var side strings.Builder
+	opts := &ai.AgentOptions{}
+	ai.WithReasoning(ai.ReasoningConfig{Writer: &side}).Apply(opts)
+
+	ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+	var response strings.Builder
+	if err := client.Chat(calque.NewRequest(ctx, strings.NewReader("2+2?")), calque.NewResponse(&response), opts); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	if response.String() != "4" {
+		t.Errorf("Chat() = %q, thinking must not reach the output", response.String())
+	}
+	if trace, _ := ai.ReasoningFrom(ctx); trace != "Two plus two is four." || side.String() != trace {
+		t.Errorf("reasoning metadata = %q, side channel = %q", trace, side.String())
+	}
+}
@@ -0,0 +1,213 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go/v2"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+)
+
+// batchLine is one request in a Batch API input file
+type batchLine struct {
+	CustomID string                         `json:"custom_id"`
+	Method   string                         `json:"method"`
+	URL      string                         `json:"url"`
+	Body     openai.ChatCompletionNewParams `json:"body"`
+}
+
+// batchOutputLine is one result in a Batch API output or error file
+type batchOutputLine struct {
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// CreateBatch implements ai.BatchClient using the OpenAI Batch API.
+//
+// The requests are uploaded as a JSONL file of chat completions using the
+// client's model and config, with each request's ID as the custom_id.
+// Streaming settings don't apply; results are fetched once the job is done.
+func (c *Client) CreateBatch(ctx context.Context, requests []ai.BatchRequest, opts *ai.AgentOptions) (string, error) {
+	ctx = ai.ContextWithInterceptors(ctx, ai.GetInterceptors(opts)...)
+	var file bytes.Buffer
+	enc := json.NewEncoder(&file)
+	for _, req := range requests {
+		input, err := ai.ClassifyInput(calque.NewRequest(ctx, strings.NewReader(req.Input)), opts)
+		if err != nil {
+			return "", err
+		}
+		params, err := c.buildChatParams(ctx, input, ai.GetSchema(opts), nil)
+		if err != nil {
+			return "", err
+		}
+		line := batchLine{CustomID: req.ID, Method: "POST", URL: string(openai.BatchNewParamsEndpointV1ChatCompletions), Body: params}
+		if err := enc.Encode(line); err != nil {
+			return "", calque.WrapErr(ctx, err, fmt.Sprintf("failed to encode batch request %q", req.ID))
+		}
+	}
+
+	uploaded, err := c.client.Files.New(ctx, openai.FileNewParams{
+		File:    openai.File(&file, "calque-batch.jsonl", "application/jsonl"),
+		Purpose: openai.FilePurposeBatch,
+	})
+	if err != nil {
+		return "", calque.WrapErr(ctx, err, "failed to upload batch input file")
+	}
+
+	batch, err := c.client.Batches.New(ctx, openai.BatchNewParams{
+		InputFileID:      uploaded.ID,
+		Endpoint:         openai.BatchNewParamsEndpointV1ChatCompletions,
+		CompletionWindow: openai.BatchNewParamsCompletionWindow24h,
+	})
+	if err != nil {
+		return "", calque.WrapErr(ctx, err, "failed to create batch")
+	}
+	return batch.ID, nil
+}
+
+// BatchStatus implements ai.BatchClient
+func (c *Client) BatchStatus(ctx context.Context, jobID string) (*ai.BatchStatus, error) {
+	batch, err := c.client.Batches.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ai.BatchStatus{
+		State:     batchState(batch.Status),
+		Total:     int(batch.RequestCounts.Total),
+		Completed: int(batch.RequestCounts.Completed),
+		Failed:    int(batch.RequestCounts.Failed),
+	}
+	messages := make([]string, 0, len(batch.Errors.Data))
+	for _, e := range batch.Errors.Data {
+		messages = append(messages, e.Message)
+	}
+	status.Message = strings.Join(messages, "; ")
+	return status, nil
+}
+
+// batchState maps OpenAI batch statuses onto ai.BatchState
+func batchState(status openai.BatchStatus) ai.BatchState {
+	switch status {
+	case openai.BatchStatusInProgress, openai.BatchStatusFinalizing, openai.BatchStatusCancelling:
+		return ai.BatchRunning
+	case openai.BatchStatusCompleted:
+		return ai.BatchCompleted
+	case openai.BatchStatusFailed:
+		return ai.BatchFailed
+	case openai.BatchStatusCancelled:
+		return ai.BatchCancelled
+	case openai.BatchStatusExpired:
+		return ai.BatchExpired
+	default:
+		return ai.BatchPending
+	}
+}
+
+// BatchResults implements ai.BatchClient by reading the job's output and error files
+func (c *Client) BatchResults(ctx context.Context, jobID string) ([]ai.BatchResult, error) {
+	batch, err := c.client.Batches.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ai.BatchResult
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		fileResults, err := c.readBatchFile(ctx, fileID)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, fileResults...)
+	}
+	return results, nil
+}
+
+// readBatchFile downloads and parses a JSONL output or error file
+func (c *Client) readBatchFile(ctx context.Context, fileID string) ([]ai.BatchResult, error) {
+	resp, err := c.client.Files.Content(ctx, fileID)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to download batch file %s", fileID))
+	}
+	defer resp.Body.Close()
+
+	var results []ai.BatchResult
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var line batchOutputLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, calque.WrapErr(ctx, err, fmt.Sprintf("invalid line in batch file %s", fileID))
+		}
+		results = append(results, parseBatchLine(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to read batch file %s", fileID))
+	}
+	return results, nil
+}
+
+// parseBatchLine converts one output line into a result
+func parseBatchLine(line batchOutputLine) ai.BatchResult {
+	result := ai.BatchResult{ID: line.CustomID, Index: -1}
+	switch {
+	case line.Error != nil:
+		result.Err = fmt.Errorf("%s: %s", line.Error.Code, line.Error.Message)
+		return result
+	case line.Response == nil:
+		result.Err = fmt.Errorf("empty batch response")
+		return result
+	case line.Response.StatusCode != 200:
+		var body struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(line.Response.Body, &body)
+		result.Err = fmt.Errorf("status %d: %s", line.Response.StatusCode, body.Error.Message)
+		return result
+	}
+
+	var completion openai.ChatCompletion
+	if err := json.Unmarshal(line.Response.Body, &completion); err != nil {
+		result.Err = fmt.Errorf("invalid chat completion: %w", err)
+		return result
+	}
+	if len(completion.Choices) == 0 {
+		result.Err = fmt.Errorf("no response choices returned")
+		return result
+	}
+	result.Output = completion.Choices[0].Message.Content
+	if completion.Usage.TotalTokens > 0 {
+		result.Usage = &ai.UsageMetadata{
+			PromptTokens:     int(completion.Usage.PromptTokens),
+			CompletionTokens: int(completion.Usage.CompletionTokens),
+			TotalTokens:      int(completion.Usage.TotalTokens),
+		}
+	}
+	return result
+}
+
+// CancelBatch implements ai.BatchClient
+func (c *Client) CancelBatch(ctx context.Context, jobID string) error {
+	_, err := c.client.Batches.Cancel(ctx, jobID)
+	return err
+}
@@ -0,0 +1,131 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v2"
+
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+)
+
+// fakeBatchAPI serves the Files and Batches endpoints used by a batch job
+func fakeBatchAPI(t *testing.T, upload *string) *httptest.Server {
+	t.Helper()
+	completion := func(text string) string {
+		return fmt.Sprintf(`{"id":"c","object":"chat.completion","created":1,"model":%q,"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":%q}}],"usage":{"prompt_tokens":4,"completion_tokens":2,"total_tokens":6}}`, testModel, text)
+	}
+	output := strings.Join([]string{
+		fmt.Sprintf(`{"custom_id":"b","response":{"status_code":200,"body":%s}}`, completion("second")),
+		fmt.Sprintf(`{"custom_id":"a","response":{"status_code":200,"body":%s}}`, completion("first")),
+	}, "\n")
+	errorLines := `{"custom_id":"c","response":{"status_code":400,"body":{"error":{"message":"bad prompt"}}}}`
+
+	polls := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/files":
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				t.Errorf("upload: %v", err)
+				return
+			}
+			data, _ := io.ReadAll(file)
+			*upload = string(data)
+			fmt.Fprint(w, `{"id":"file-in","object":"file","bytes":1,"created_at":1,"filename":"calque-batch.jsonl","purpose":"batch","status":"processed"}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/batches":
+			fmt.Fprint(w, `{"id":"batch-1","object":"batch","endpoint":"/v1/chat/completions","input_file_id":"file-in","completion_window":"24h","status":"validating","created_at":1}`)
+		case r.URL.Path == "/batches/batch-1":
+			status := "in_progress"
+			if polls++; polls > 1 {
+				status = "completed"
+			}
+			fmt.Fprintf(w, `{"id":"batch-1","object":"batch","endpoint":"/v1/chat/completions","input_file_id":"file-in","completion_window":"24h","status":%q,"created_at":1,"output_file_id":"file-out","error_file_id":"file-err","request_counts":{"total":3,"completed":2,"failed":1}}`, status)
+		case r.URL.Path == "/files/file-out/content":
+			fmt.Fprint(w, output)
+		case r.URL.Path == "/files/file-err/content":
+			fmt.Fprint(w, errorLines)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestBatchSubmit(t *testing.T) {
+	var upload string
+	server := fakeBatchAPI(t, &upload)
+	defer server.Close()
+
+	client, err := New(testModel, WithConfig(&Config{APIKey: "sk-test", BaseURL: server.URL}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	job, err := ai.BatchSubmitWithConfig(context.Background(), client, []ai.BatchRequest{
+		{ID: "a", Input: "one"}, {ID: "b", Input: "two"}, {ID: "c", Input: "three"},
+	}, &ai.BatchConfig{PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("BatchSubmit() error = %v", err)
+	}
+	if job.ID != "batch-1" {
+		t.Errorf("job ID = %q", job.ID)
+	}
+
+	lines := strings.Split(strings.TrimSpace(upload), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("uploaded %d lines", len(lines))
+	}
+	var first struct {
+		CustomID string `json:"custom_id"`
+		URL      string `json:"url"`
+		Body     struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		} `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("invalid input line: %v", err)
+	}
+	if first.CustomID != "a" || first.URL != "/v1/chat/completions" || first.Body.Model != testModel || first.Body.Messages[0].Content != "one" {
+		t.Errorf("input line = %s", lines[0])
+	}
+
+	results, err := job.Wait(context.Background())
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if results[0].Output != "first" || results[1].Output != "second" || results[0].Usage.TotalTokens != 6 {
+		t.Errorf("results = %+v", results)
+	}
+	if results[2].Err == nil || !strings.Contains(results[2].Err.Error(), "bad prompt") {
+		t.Errorf("result c error = %v", results[2].Err)
+	}
+}
+
+func TestBatchState(t *testing.T) {
+	tests := map[openai.BatchStatus]ai.BatchState{
+		openai.BatchStatusValidating: ai.BatchPending,
+		openai.BatchStatusInProgress: ai.BatchRunning,
+		openai.BatchStatusFinalizing: ai.BatchRunning,
+		openai.BatchStatusCompleted:  ai.BatchCompleted,
+		openai.BatchStatusFailed:     ai.BatchFailed,
+		openai.BatchStatusExpired:    ai.BatchExpired,
+		openai.BatchStatusCancelled:  ai.BatchCancelled,
+	}
+	for status, want := range tests {
+		if got := batchState(status); got != want {
+			t.Errorf("batchState(%q) = %q, want %q", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/option"
+	"github.com/openai/openai-go/v2/packages/respjson"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+)
+
+// Base URLs of hosted OpenAI-compatible APIs, for NewCompatible
+const (
+	GroqBaseURL      = "https://api.groq.com/openai/v1"
+	MistralBaseURL   = "https://api.mistral.ai/v1"
+	TogetherBaseURL  = "https://api.together.xyz/v1"
+	FireworksBaseURL = "https://api.fireworks.ai/inference/v1"
+)
+
+// compatProfile describes how an OpenAI-compatible server differs from the OpenAI API
+type compatProfile struct {
+	provider        string // gen_ai.provider.name reported on chat spans
+	apiKeyEnv       string // Environment variable holding the API key, empty for keyless servers
+	noStreamOptions bool   // Rejects stream_options; usage arrives in the last chunk regardless
+	seedField       string // Request field for the sampling seed when it isn't "seed"
+}
+
+// compatProfiles are the known hosted providers, keyed by API host
+var compatProfiles = map[string]compatProfile{
+	"api.groq.com":     {provider: "groq", apiKeyEnv: "GROQ_API_KEY"},
+	"api.mistral.ai":   {provider: "mistral_ai", apiKeyEnv: "MISTRAL_API_KEY", noStreamOptions: true, seedField: "random_seed"},
+	"api.together.xyz": {provider: "together", apiKeyEnv: "TOGETHER_API_KEY"},
+	"api.fireworks.ai": {provider: "fireworks", apiKeyEnv: "FIREWORKS_API_KEY"},
+}
+
+// profileFor returns the profile for a base URL; unknown hosts such as
+// self-hosted vLLM servers get a generic keyless profile
+func profileFor(baseURL string) compatProfile {
+	if u, err := url.Parse(baseURL); err == nil {
+		if profile, ok := compatProfiles[strings.ToLower(u.Hostname())]; ok {
+			return profile
+		}
+	}
+	return compatProfile{provider: "openai_compatible"}
+}
+
+// NewCompatible creates a client for a server that implements the OpenAI Chat
+// Completions API, such as Groq, Mistral, Together, Fireworks or a vLLM server.
+//
+// Input: base URL of the API (including /v1), model name, optional config Options
+// Output: *Client, error
+// Behavior: Initializes a client that adapts requests and responses to the server
+//
+// Hosted providers are recognized by their base URL: the API key is read from
+// GROQ_API_KEY, MISTRAL_API_KEY, TOGETHER_API_KEY or FIREWORKS_API_KEY, and
+// provider quirks such as Groq's x_groq usage field and Mistral's random_seed
+// are handled. Other servers need no key; Config.APIKey sets one. The
+// OPENAI_API_KEY variable is never sent to a compatible server.
+//
+// Example:
+//
+//	groq, err := openai.NewCompatible(openai.GroqBaseURL, "llama-3.3-70b-versatile")
+//	if err != nil { log.Fatal(err) }
+//	agent := ai.Agent(groq)
+//
+//	local, _ := openai.NewCompatible("http://localhost:8000/v1", "Qwen/Qwen2.5-7B-Instruct")
+func NewCompatible(baseURL, model string, opts ...Option) (*Client, error) {
+	if baseURL == "" {
+		return nil, calque.NewErr(context.Background(), "base URL is required")
+	}
+	if model == "" {
+		return nil, calque.NewErr(context.Background(), "model name is required")
+	}
+
+	profile := profileFor(baseURL)
+
+	// Build config from options, without the OpenAI key
+	config := DefaultConfig()
+	config.APIKey = ""
+	if profile.apiKeyEnv != "" {
+		config.APIKey = os.Getenv(profile.apiKeyEnv)
+	}
+	for _, opt := range opts {
+		opt.Apply(config)
+	}
+	config.BaseURL = baseURL
+
+	if profile.apiKeyEnv != "" && config.APIKey == "" {
+		return nil, calque.NewErr(context.Background(), profile.apiKeyEnv+" environment variable not set or provided in config")
+	}
+	if err := config.Validate(); err != nil {
+		return nil, calque.WrapErr(context.Background(), err, "invalid openai config")
+	}
+
+	httpClient, err := ai.ProviderHTTPClient(config.HTTPClient, config.Network)
+	if err != nil {
+		return nil, err
+	}
+	config.HTTPClient = httpClient
+
+	return newClient(model, config, &profile), nil
+}
+
+// providerName reports the API provider on chat spans
+func (c *Client) providerName() string {
+	if c.compat != nil {
+		return c.compat.provider
+	}
+	return "openai"
+}
+
+// requestOptions rewrites request fields that a compatible server names differently
+func (c *Client) requestOptions(params openai.ChatCompletionNewParams) []option.RequestOption {
+	if c.compat == nil || c.compat.seedField == "" || !params.Seed.Valid() {
+		return nil
+	}
+	return []option.RequestOption{
+		option.WithJSONDel("seed"),
+		option.WithJSONSet(c.compat.seedField, params.Seed.Value),
+	}
+}
+
+// extraUsage reads token usage that compatible servers report outside the
+// standard usage field, such as Groq's x_groq.usage on the final stream chunk
+func extraUsage(fields map[string]respjson.Field) *ai.UsageMetadata {
+	field, ok := fields["x_groq"]
+	if !ok || field.Raw() == "" {
+		return nil
+	}
+	var groq struct {
+		Usage *struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal([]byte(field.Raw()), &groq); err != nil || groq.Usage == nil || groq.Usage.TotalTokens == 0 {
+		return nil
+	}
+	return &ai.UsageMetadata{
+		PromptTokens:     groq.Usage.PromptTokens,
+		CompletionTokens: groq.Usage.CompletionTokens,
+		TotalTokens:      groq.Usage.TotalTokens,
+	}
+}
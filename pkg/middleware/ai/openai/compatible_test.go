@@ -0,0 +1,162 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/helpers"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+)
+
+func TestNewCompatible(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+	t.Setenv("GROQ_API_KEY", "gsk-test")
+	t.Setenv("MISTRAL_API_KEY", "")
+
+	tests := []struct {
+		name         string
+		baseURL      string
+		model        string
+		opts         []Option
+		wantErr      string
+		wantProvider string
+		wantKey      string
+	}{
+		{name: "groq", baseURL: GroqBaseURL, model: "llama-3.3-70b-versatile", wantProvider: "groq", wantKey: "gsk-test"},
+		{name: "mistral without key", baseURL: MistralBaseURL, model: "mistral-large-latest", wantErr: "MISTRAL_API_KEY"},
+		{
+			name: "mistral with config key", baseURL: MistralBaseURL, model: "mistral-large-latest",
+			opts: []Option{WithConfig(&Config{APIKey: "m-key"})}, wantProvider: "mistral_ai", wantKey: "m-key",
+		},
+		{name: "self-hosted", baseURL: "http://localhost:8000/v1", model: "Qwen/Qwen2.5-7B-Instruct", wantProvider: "openai_compatible"},
+		{name: "missing base URL", model: "llama", wantErr: "base URL is required"},
+		{name: "missing model", baseURL: GroqBaseURL, wantErr: "model name is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewCompatible(tt.baseURL, tt.model, tt.opts...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewCompatible() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewCompatible() error = %v", err)
+			}
+			if client.providerName() != tt.wantProvider {
+				t.Errorf("providerName() = %q, want %q", client.providerName(), tt.wantProvider)
+			}
+			if client.config.APIKey != tt.wantKey {
+				t.Errorf("APIKey = %q, want %q", client.config.APIKey, tt.wantKey)
+			}
+			if client.config.BaseURL != tt.baseURL {
+				t.Errorf("BaseURL = %q, want %q", client.config.BaseURL, tt.baseURL)
+			}
+		})
+	}
+}
+
+// compatServer records the last request body and answers with a canned SSE stream
+func compatServer(t *testing.T, chunks []string, body *map[string]any, auth *string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, body); err != nil {
+			t.Errorf("request body: %v", err)
+		}
+		*auth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+}
+
+func TestCompatibleStreaming(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+
+	t.Run("groq usage and keyless server", func(t *testing.T) {
+		var body map[string]any
+		var auth string
+		server := compatServer(t, []string{
+			`{"id":"1","object":"chat.completion.chunk","model":"llama","choices":[{"index":0,"delta":{"content":"hi"}}]}`,
+			`{"id":"1","object":"chat.completion.chunk","model":"llama","choices":[{"index":0,"delta":{},"finish_reason":"stop"}],` +
+				`"x_groq":{"id":"req_1","usage":{"prompt_tokens":7,"completion_tokens":2,"total_tokens":9}}}`,
+		}, &body, &auth)
+		defer server.Close()
+
+		client, err := NewCompatible(server.URL, "llama", WithConfig(&Config{MaxTokens: helpers.PtrOf(64)}))
+		if err != nil {
+			t.Fatalf("NewCompatible() error = %v", err)
+		}
+
+		var usage *ai.UsageMetadata
+		opts := &ai.AgentOptions{UsageHandler: func(u *ai.UsageMetadata) { usage = u }}
+		var out strings.Builder
+		if err := client.Chat(calque.NewRequest(context.Background(), strings.NewReader("hello")), calque.NewResponse(&out), opts); err != nil {
+			t.Fatalf("Chat() error = %v", err)
+		}
+
+		if out.String() != "hi" {
+			t.Errorf("Chat() = %q, want %q", out.String(), "hi")
+		}
+		if usage == nil || usage.PromptTokens != 7 || usage.CompletionTokens != 2 || usage.TotalTokens != 9 || usage.Model != "llama" {
+			t.Errorf("usage = %+v", usage)
+		}
+		if auth != "" {
+			t.Errorf("Authorization = %q, OPENAI_API_KEY must not reach a compatible server", auth)
+		}
+		if body["max_tokens"] != float64(64) || body["max_completion_tokens"] != nil {
+			t.Errorf("max tokens fields = %v, %v", body["max_tokens"], body["max_completion_tokens"])
+		}
+		if body["stream_options"] == nil {
+			t.Error("stream_options missing for a server that supports it")
+		}
+	})
+
+	t.Run("mistral seed and tool calls", func(t *testing.T) {
+		var body map[string]any
+		var auth string
+		server := compatServer(t, []string{
+			`{"id":"1","object":"chat.completion.chunk","model":"mistral","choices":[{"index":0,"delta":{"tool_calls":[` +
+				`{"id":"a","index":0,"type":"function","function":{"name":"search","arguments":"{\"q\":\"go\"}"}},` +
+				`{"id":"b","index":0,"type":"function","function":{"name":"calc","arguments":"{\"x\":1}"}}]}}]}`,
+			`{"id":"1","object":"chat.completion.chunk","model":"mistral","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}],` +
+				`"usage":{"prompt_tokens":3,"completion_tokens":4,"total_tokens":7}}`,
+		}, &body, &auth)
+		defer server.Close()
+
+		profile := compatProfiles["api.mistral.ai"]
+		client := newClient("mistral-large-latest", &Config{APIKey: "m-key", BaseURL: server.URL, Seed: helpers.PtrOf(42)}, &profile)
+
+		var out strings.Builder
+		if err := client.Chat(calque.NewRequest(context.Background(), strings.NewReader("hello")), calque.NewResponse(&out), nil); err != nil {
+			t.Fatalf("Chat() error = %v", err)
+		}
+
+		if body["random_seed"] != float64(42) || body["seed"] != nil {
+			t.Errorf("seed fields = %v, %v", body["random_seed"], body["seed"])
+		}
+		if body["stream_options"] != nil {
+			t.Errorf("stream_options = %v, Mistral rejects it", body["stream_options"])
+		}
+		if auth != "Bearer m-key" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		for _, name := range []string{`"search"`, `"calc"`} {
+			if !strings.Contains(out.String(), name) {
+				t.Errorf("tool calls %s missing %s", out.String(), name)
+			}
+		}
+	})
+}
@@ -27,13 +27,16 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"strings"
 
 	"github.com/openai/openai-go/v2"
 	"github.com/openai/openai-go/v2/option"
+	"github.com/openai/openai-go/v2/packages/respjson"
 	"github.com/openai/openai-go/v2/shared"
 	"github.com/openai/openai-go/v2/shared/constant"
 
@@ -41,7 +44,9 @@ import (
 	"github.com/calque-ai/go-calque/pkg/helpers"
 	"github.com/calque-ai/go-calque/pkg/middleware/ai"
 	"github.com/calque-ai/go-calque/pkg/middleware/ai/config"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
 	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
 )
 
 // Client implements the Client interface for OpenAI.
@@ -57,6 +62,7 @@ type Client struct {
 	client    *openai.Client
 	model     shared.ChatModel
 	config    *Config
+	compat    *compatProfile // Set for OpenAI-compatible servers, see NewCompatible
 	lastUsage *ai.UsageMetadata
 }
 
@@ -82,6 +88,14 @@ type Config struct {
 	// Optional. Organization ID for OpenAI API requests
 	OrgID string
 
+	// Optional. HTTP client used for requests, e.g. one shared with other
+	// clients or with a custom transport (defaults to the SDK's client)
+	HTTPClient *http.Client
+
+	// Optional. Proxy and CA settings for restricted networks, used when
+	// HTTPClient is not set (defaults to HTTPS_PROXY/HTTP_PROXY and system CAs)
+	Network *ai.NetworkConfig
+
 	// Optional. Controls randomness in token selection (0.0-2.0)
 	// Lower values = more deterministic, higher values = more creative
 	Temperature *float32
@@ -118,6 +132,23 @@ type Config struct {
 
 	// Optional. Enable/disable streaming of responses (true by default)
 	Stream *bool
+
+	// Optional. Model tokenizer for exact TokenEstimator counts, e.g. a tiktoken
+	// encoding via tokenizer.Func (default: tokenizer.Conservative, an estimate)
+	Tokenizer tokenizer.Tokenizer
+}
+
+// Validate reports every out-of-range setting, as ai.FieldError values
+func (c *Config) Validate() error {
+	return errors.Join(
+		ai.CheckURL("BaseURL", c.BaseURL),
+		ai.CheckRange("Temperature", c.Temperature, 0, 2),
+		ai.CheckRange("TopP", c.TopP, 0, 1),
+		ai.CheckPositive("MaxTokens", c.MaxTokens),
+		ai.CheckPositive("N", c.N),
+		ai.CheckRange("PresencePenalty", c.PresencePenalty, -2, 2),
+		ai.CheckRange("FrequencyPenalty", c.FrequencyPenalty, -2, 2),
+	)
 }
 
 // Option interface for functional options pattern
@@ -196,24 +227,116 @@ func New(model string, opts ...Option) (*Client, error) {
 	if config.APIKey == "" {
 		return nil, calque.NewErr(context.Background(), "OPENAI_API_KEY environment variable not set or provided in config")
 	}
+	if err := config.Validate(); err != nil {
+		return nil, calque.WrapErr(context.Background(), err, "invalid openai config")
+	}
+
+	httpClient, err := ai.ProviderHTTPClient(config.HTTPClient, config.Network)
+	if err != nil {
+		return nil, err
+	}
+	config.HTTPClient = httpClient
+
+	return newClient(model, config, nil), nil
+}
 
+// newClient creates the API client for a validated configuration
+func newClient(model string, config *Config, compat *compatProfile) *Client {
 	// Create client options
 	var clientOptions []option.RequestOption
 	clientOptions = append(clientOptions, option.WithAPIKey(config.APIKey))
+	if config.APIKey == "" {
+		// Keyless local servers such as vLLM; never send the OPENAI_API_KEY the SDK would pick up
+		clientOptions = append(clientOptions, option.WithHeaderDel("Authorization"))
+	}
 
 	if config.BaseURL != "" {
 		clientOptions = append(clientOptions, option.WithBaseURL(config.BaseURL))
 	}
 
+	if config.HTTPClient != nil {
+		clientOptions = append(clientOptions, option.WithHTTPClient(config.HTTPClient))
+	}
+
+	// Surface x-ratelimit-* headers to ctrl.Retry, ctrl.AdaptiveConcurrency and metrics
+	clientOptions = append(clientOptions, option.WithMiddleware(recordRateLimit))
+	// Innermost, so interceptors see the request exactly as it is sent
+	clientOptions = append(clientOptions, option.WithMiddleware(intercept))
+
 	openaiClient := openai.NewClient(clientOptions...)
 
 	return &Client{
 		client: &openaiClient,
 		model:  shared.ChatModel(model),
 		config: config,
-	}, nil
+		compat: compat,
+	}
+}
+
+// recordRateLimit stores quota headers from every API response on the request's MetadataBus
+func recordRateLimit(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	resp, err := next(req)
+	if resp != nil {
+		if info, ok := ctrl.ParseRateLimitHeaders(resp.Header); ok {
+			ctrl.RecordRateLimit(req.Context(), info)
+		}
+	}
+	return resp, err
 }
 
+// callOptions returns the request options for one chat call
+func (c *Client) callOptions(params openai.ChatCompletionNewParams, opts *ai.AgentOptions) []option.RequestOption {
+	options := c.requestOptions(params)
+	if ai.GetRetry(opts) != nil {
+		// ai.WithRetry replaces the SDK's own retries rather than multiplying them
+		options = append(options, option.WithMaxRetries(0))
+	}
+	return options
+}
+
+// intercept runs the request through the interceptors in its context
+func intercept(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	return ai.Intercept(req, ai.InterceptorNext(next))
+}
+
+// Warmup implements calque.Warmer by looking up the configured model.
+//
+// The request establishes the HTTPS connection and verifies the API key and
+// model name before the first chat request.
+//
+// Example:
+//
+//	if err := client.Warmup(ctx); err != nil {
+//		log.Fatal(err) // bad key, unknown model or unreachable endpoint
+//	}
+func (c *Client) Warmup(ctx context.Context) error {
+	if _, err := c.client.Models.Get(ctx, string(c.model)); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("openai warm-up for model %s failed", c.model))
+	}
+	return nil
+}
+
+// Egress implements calque.Egress. A BaseURL on localhost, such as a local
+// OpenAI-compatible server, keeps prompts on the host.
+func (c *Client) Egress() []string {
+	if c.config.BaseURL == "" {
+		return []string{"api.openai.com"}
+	}
+	return calque.EgressHosts(c.config.BaseURL)
+}
+
+// TokenEstimator returns an ai.TokenEstimator for the client's model, counting
+// with Config.Tokenizer when set and estimating otherwise
+func (c *Client) TokenEstimator() ai.TokenEstimator {
+	return ai.NewTokenEstimator(string(c.model), c.config.Tokenizer)
+}
+
+// SupportsSeed implements ai.SeedCapable; deterministic runs override Config.Seed
+func (c *Client) SupportsSeed() bool { return true }
+
+// SupportsStructuredOutput implements ai.StructuredOutputCapable; schemas are sent as json_schema response formats
+func (c *Client) SupportsStructuredOutput() bool { return true }
+
 // Chat implements the Client interface with streaming support.
 //
 // Input: user prompt/query via calque.Request
@@ -226,7 +349,19 @@ func New(model string, opts ...Option) (*Client, error) {
 // Example:
 //
 //	err := client.Chat(req, res, &ai.AgentOptions{Tools: tools})
-func (c *Client) Chat(r *calque.Request, w *calque.Response, opts *ai.AgentOptions) error {
+func (c *Client) Chat(r *calque.Request, w *calque.Response, opts *ai.AgentOptions) (err error) {
+	c = c.withOverrides(r.Context)
+	r, span := ai.StartChatSpan(r, ai.ChatSpanInfo{
+		Provider:    c.providerName(),
+		Model:       string(c.model),
+		Temperature: c.config.Temperature,
+		MaxTokens:   c.config.MaxTokens,
+	})
+	defer func() { span.End(err) }()
+
+	// Route provider requests through the call's interceptors, see ai.WithInterceptor
+	r = r.WithContext(ai.ContextWithInterceptors(r.Context, ai.GetInterceptors(opts)...))
+
 	// Which input type are we processing?
 	input, err := ai.ClassifyInput(r, opts)
 	if err != nil {
@@ -243,6 +378,32 @@ func (c *Client) Chat(r *calque.Request, w *calque.Response, opts *ai.AgentOptio
 	return c.executeRequest(params, r, w, opts)
 }
 
+// ModelSettings implements ai.ModelDescriber
+func (c *Client) ModelSettings(ctx context.Context) ai.ModelSettings {
+	c = c.withOverrides(ctx)
+	return ai.ModelSettings{Model: string(c.model), Temperature: c.config.Temperature, MaxTokens: c.config.MaxTokens}
+}
+
+// withOverrides returns a copy of the client using the call's ai.Overrides, if any
+func (c *Client) withOverrides(ctx context.Context) *Client {
+	overrides := ai.OverridesFrom(ctx)
+	if overrides.IsZero() {
+		return c
+	}
+	client, config := *c, *c.config
+	if overrides.Model != "" {
+		client.model = shared.ChatModel(overrides.Model)
+	}
+	if overrides.Temperature != nil {
+		config.Temperature = overrides.Temperature
+	}
+	if overrides.MaxTokens != nil {
+		config.MaxTokens = overrides.MaxTokens
+	}
+	client.config = &config
+	return &client
+}
+
 // buildChatParams creates OpenAI chat completion parameters
 func (c *Client) buildChatParams(ctx context.Context, input *ai.ClassifiedInput, schema *ai.ResponseFormat, toolList []tools.Tool) (openai.ChatCompletionNewParams, error) {
 	// Convert input to messages
@@ -259,6 +420,9 @@ func (c *Client) buildChatParams(ctx context.Context, input *ai.ClassifiedInput,
 
 	// Apply configuration
 	c.applyChatConfig(&params, schema)
+	if seed, ok := calque.DeterministicSeed(ctx); ok {
+		params.Seed = openai.Int(seed)
+	}
 
 	// Add tools if provided
 	if len(toolList) > 0 {
@@ -288,6 +452,7 @@ func (c *Client) executeRequest(params openai.ChatCompletionNewParams, r *calque
 // reportUsage invokes the usage handler if present
 func (c *Client) reportUsage(opts *ai.AgentOptions) {
 	if c.lastUsage != nil && opts != nil && opts.UsageHandler != nil {
+		c.lastUsage.Model = string(c.model)
 		opts.UsageHandler(c.lastUsage)
 	}
 }
@@ -295,12 +460,14 @@ func (c *Client) reportUsage(opts *ai.AgentOptions) {
 // executeStreamingRequest executes a streaming request
 func (c *Client) executeStreamingRequest(params openai.ChatCompletionNewParams, r *calque.Request, w *calque.Response, opts *ai.AgentOptions) (err error) {
 	// Enable stream options to get usage data in streaming mode
-	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{
-		IncludeUsage: openai.Bool(true),
+	if c.compat == nil || !c.compat.noStreamOptions {
+		params.StreamOptions = openai.ChatCompletionStreamOptionsParam{
+			IncludeUsage: openai.Bool(true),
+		}
 	}
 
 	// Create streaming request
-	stream := c.client.Chat.Completions.NewStreaming(r.Context, params)
+	stream := c.client.Chat.Completions.NewStreaming(r.Context, params, c.callOptions(params, opts)...)
 	defer func() {
 		if closeErr := stream.Close(); closeErr != nil && err == nil {
 			// Only set the error if no other error occurred
@@ -324,15 +491,25 @@ func (c *Client) executeStreamingRequest(params openai.ChatCompletionNewParams,
 				CompletionTokens: int(chunk.Usage.CompletionTokens),
 				TotalTokens:      int(chunk.Usage.TotalTokens),
 			}
+		} else if usage := extraUsage(chunk.JSON.ExtraFields); usage != nil {
+			c.lastUsage = usage
 		}
 
 		// Skip chunks with no choices (e.g., final usage-only chunk)
 		if len(chunk.Choices) == 0 {
 			continue
 		}
+		if reason := chunk.Choices[0].FinishReason; reason != "" {
+			ai.SetChatResponse(r.Context, chunk.ID, chunk.Model, reason)
+		}
 
 		delta := chunk.Choices[0].Delta
 
+		// Reasoning from OpenAI-compatible servers goes to the trace, not the output
+		if err := ai.GetReasoning(opts).Append(reasoningContent(delta.JSON.ExtraFields)); err != nil {
+			return err
+		}
+
 		// Process delta chunk
 		if err := c.processStreamDelta(delta, toolCalls, &hasToolCalls, w); err != nil {
 			return err
@@ -344,18 +521,44 @@ func (c *Client) executeStreamingRequest(params openai.ChatCompletionNewParams,
 	}
 
 	// Report usage before finalizing
+	ai.SetChatUsage(r.Context, c.lastUsage)
 	c.reportUsage(opts)
 
+	if err := ai.GetReasoning(opts).Finish(r.Context); err != nil {
+		return err
+	}
+
 	// Finalize accumulated tool calls
 	return c.finalizeToolCalls(toolCalls, w)
 }
 
+// reasoningContent extracts reasoning text that OpenAI-compatible servers (DeepSeek,
+// vLLM, OpenRouter) return alongside content as "reasoning_content" or "reasoning"
+func reasoningContent(fields map[string]respjson.Field) string {
+	for _, key := range []string{"reasoning_content", "reasoning"} {
+		// Unknown fields are never Valid, so decode the raw JSON directly
+		field, ok := fields[key]
+		if !ok || field.Raw() == "" {
+			continue
+		}
+		var text string
+		if err := json.Unmarshal([]byte(field.Raw()), &text); err == nil && text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
 // processStreamDelta processes a single streaming delta chunk
 func (c *Client) processStreamDelta(delta openai.ChatCompletionChunkChoiceDelta, toolCalls map[int]*openai.ChatCompletionMessageFunctionToolCall, hasToolCalls *bool, w *calque.Response) error {
 	// Handle tool calls (streaming) - collect first
 	for _, toolCall := range delta.ToolCalls {
 		*hasToolCalls = true
 		index := int(toolCall.Index)
+		if existing := toolCalls[index]; existing != nil && toolCall.ID != "" && existing.ID != "" && toolCall.ID != existing.ID {
+			// Mistral sends each complete call at index 0; a new ID starts a new call
+			index = len(toolCalls)
+		}
 
 		// Initialize tool call if not exists
 		if toolCalls[index] == nil {
@@ -411,7 +614,7 @@ func (c *Client) finalizeToolCalls(toolCalls map[int]*openai.ChatCompletionMessa
 // executeNonStreamingRequest executes a non-streaming request
 func (c *Client) executeNonStreamingRequest(params openai.ChatCompletionNewParams, r *calque.Request, w *calque.Response, opts *ai.AgentOptions) error {
 	// Create request
-	response, err := c.client.Chat.Completions.New(r.Context, params)
+	response, err := c.client.Chat.Completions.New(r.Context, params, c.callOptions(params, opts)...)
 	if err != nil {
 		return calque.WrapErr(r.Context, err, "failed to create chat completion")
 	}
@@ -420,6 +623,12 @@ func (c *Client) executeNonStreamingRequest(params openai.ChatCompletionNewParam
 		return calque.NewErr(r.Context, "no response choices returned")
 	}
 
+	finishReasons := make([]string, len(response.Choices))
+	for i, choice := range response.Choices {
+		finishReasons[i] = string(choice.FinishReason)
+	}
+	ai.SetChatResponse(r.Context, response.ID, response.Model, finishReasons...)
+
 	// Capture usage metadata
 	if response.Usage.TotalTokens > 0 {
 		c.lastUsage = &ai.UsageMetadata{
@@ -430,8 +639,17 @@ func (c *Client) executeNonStreamingRequest(params openai.ChatCompletionNewParam
 	}
 
 	// Report usage
+	ai.SetChatUsage(r.Context, c.lastUsage)
 	c.reportUsage(opts)
 
+	reasoning := ai.GetReasoning(opts)
+	if err := reasoning.Append(reasoningContent(response.Choices[0].Message.JSON.ExtraFields)); err != nil {
+		return err
+	}
+	if err := reasoning.Finish(r.Context); err != nil {
+		return err
+	}
+
 	// Process all choices
 	return c.processChoices(response.Choices, w)
 }
@@ -485,11 +703,37 @@ func (c *Client) inputToMessages(ctx context.Context, input *ai.ClassifiedInput)
 	case ai.MultimodalJSONInput, ai.MultimodalStreamingInput:
 		return c.multimodalToMessages(ctx, input.Multimodal)
 
+	case ai.MessagesInput:
+		return messagesToOpenAI(input.Messages), nil
+
 	default:
 		return nil, calque.NewErr(ctx, fmt.Sprintf("unsupported input type: %d", input.Type))
 	}
 }
 
+// messagesToOpenAI converts canonical chat messages to OpenAI messages
+func messagesToOpenAI(messages *ai.Messages) []openai.ChatCompletionMessageParamUnion {
+	result := make([]openai.ChatCompletionMessageParamUnion, 0, len(messages.Messages))
+	for _, msg := range messages.Messages {
+		switch msg.Role {
+		case ai.RoleSystem:
+			result = append(result, openai.SystemMessage(msg.Content))
+		case ai.RoleAssistant:
+			result = append(result, openai.AssistantMessage(msg.Content))
+		case ai.RoleTool:
+			if msg.ToolCallID != "" {
+				result = append(result, openai.ToolMessage(msg.Content, msg.ToolCallID))
+				continue
+			}
+			// OpenAI rejects tool messages that don't answer a tool call, so pass the result as context
+			result = append(result, openai.UserMessage(ai.NewMessages(msg).Text()))
+		default:
+			result = append(result, openai.UserMessage(msg.Content))
+		}
+	}
+	return result
+}
+
 // multimodalToMessages converts multimodal input to OpenAI message format
 func (c *Client) multimodalToMessages(ctx context.Context, multimodal *ai.MultimodalInput) ([]openai.ChatCompletionMessageParamUnion, error) {
 	if multimodal == nil {
@@ -571,7 +815,12 @@ func (c *Client) applyChatConfig(params *openai.ChatCompletionNewParams, schema
 		params.TopP = openai.Float(float64(*c.config.TopP))
 	}
 	if c.config.MaxTokens != nil {
-		params.MaxCompletionTokens = openai.Int(int64(*c.config.MaxTokens))
+		if c.compat != nil {
+			// Compatible servers predate max_completion_tokens
+			params.MaxTokens = openai.Int(int64(*c.config.MaxTokens))
+		} else {
+			params.MaxCompletionTokens = openai.Int(int64(*c.config.MaxTokens))
+		}
 	}
 	if c.config.N != nil {
 		params.N = openai.Int(int64(*c.config.N))
@@ -614,14 +863,24 @@ func (c *Client) setResponseFormat(responseFormat *ai.ResponseFormat, params *op
 		}
 
 	case "json_schema":
-		if responseFormat.Schema != nil {
-			c.setJSONSchemaFormat(responseFormat.Schema, params)
+		if responseFormat.Schema == nil {
+			return
+		}
+		if !responseFormat.Strict {
+			c.setJSONSchemaFormat(responseFormat.Schema, true, params)
+			return
+		}
+		// Strict mode only accepts a subset of JSON Schema; send anything else unenforced
+		if schema, err := ai.StrictJSONSchema(responseFormat.Schema); err == nil {
+			c.setJSONSchemaFormat(schema, true, params)
+		} else {
+			c.setJSONSchemaFormat(responseFormat.Schema, false, params)
 		}
 	}
 }
 
 // setJSONSchemaFormat sets the JSON schema response format
-func (c *Client) setJSONSchemaFormat(schema any, params *openai.ChatCompletionNewParams) {
+func (c *Client) setJSONSchemaFormat(schema any, strict bool, params *openai.ChatCompletionNewParams) {
 	schemaBytes, err := json.Marshal(schema)
 	if err != nil {
 		// Fallback to json_object on error
@@ -637,7 +896,7 @@ func (c *Client) setJSONSchemaFormat(schema any, params *openai.ChatCompletionNe
 			JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
 				Name:        "response_schema",
 				Schema:      schemaBytes,
-				Strict:      openai.Bool(true),
+				Strict:      openai.Bool(strict),
 				Description: openai.String("Generated schema for structured response"),
 			},
 		},
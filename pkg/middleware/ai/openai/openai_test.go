@@ -5,10 +5,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/invopop/jsonschema"
 	"github.com/openai/openai-go/v2"
@@ -18,6 +24,7 @@ import (
 	"github.com/calque-ai/go-calque/pkg/calque"
 	"github.com/calque-ai/go-calque/pkg/helpers"
 	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
 	"github.com/calque-ai/go-calque/pkg/middleware/tools"
 )
 
@@ -265,6 +272,35 @@ func TestInputToMessages(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			name: "messages input",
+			input: &ai.ClassifiedInput{
+				Type: ai.MessagesInput,
+				Messages: &ai.Messages{Messages: []ai.Message{
+					{Role: ai.RoleSystem, Content: "Be brief."},
+					{Role: ai.RoleUser, Content: "Weather?"},
+					{Role: ai.RoleAssistant, Content: "Checking."},
+					{Role: ai.RoleTool, Name: "weather", ToolCallID: "call_1", Content: "sunny"},
+					{Role: ai.RoleTool, Name: "clock", Content: "noon"},
+				}},
+			},
+			checkFunc: func(messages []openai.ChatCompletionMessageParamUnion) error {
+				if len(messages) != 5 {
+					return fmt.Errorf("expected 5 messages, got %d", len(messages))
+				}
+				switch {
+				case messages[0].OfSystem == nil:
+					return fmt.Errorf("message 0 should be a system message")
+				case messages[2].OfAssistant == nil:
+					return fmt.Errorf("message 2 should be an assistant message")
+				case messages[3].OfTool == nil || messages[3].OfTool.ToolCallID != "call_1":
+					return fmt.Errorf("message 3 should answer call_1")
+				case messages[4].OfUser == nil || messages[4].OfUser.Content.OfString.Value != "tool (clock): noon":
+					return fmt.Errorf("tool result without a call ID should be sent as user context")
+				}
+				return nil
+			},
+		},
 		{
 			name: "unsupported input type",
 			input: &ai.ClassifiedInput{
@@ -1038,7 +1074,7 @@ func TestSetJSONSchemaFormat(t *testing.T) {
 				Model: client.model,
 			}
 
-			client.setJSONSchemaFormat(tt.schema, params)
+			client.setJSONSchemaFormat(tt.schema, true, params)
 
 			if tt.checkFunc != nil {
 				if err := tt.checkFunc(params); err != nil {
@@ -1049,6 +1085,54 @@ func TestSetJSONSchemaFormat(t *testing.T) {
 	}
 }
 
+func TestSetResponseFormat_Strict(t *testing.T) {
+	type profile struct {
+		Name string `json:"name"`
+		Bio  string `json:"bio,omitempty"`
+	}
+	type tagged struct {
+		Tags map[string]string `json:"tags"`
+	}
+	reflector := jsonschema.Reflector{}
+	client := &Client{model: shared.ChatModel(testModel), config: DefaultConfig()}
+
+	tests := []struct {
+		name       string
+		schema     *jsonschema.Schema
+		wantStrict bool
+	}{
+		{name: "convertible schema is sent strict", schema: reflector.Reflect(&profile{}), wantStrict: true},
+		{name: "open map falls back to non-strict", schema: reflector.Reflect(&tagged{}), wantStrict: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := &openai.ChatCompletionNewParams{Model: client.model}
+			client.setResponseFormat(&ai.ResponseFormat{Type: "json_schema", Schema: tt.schema, Strict: true}, params)
+
+			format := params.ResponseFormat.OfJSONSchema
+			if format == nil {
+				t.Fatal("expected JSON schema format to be set")
+			}
+			if format.JSONSchema.Strict.Value != tt.wantStrict {
+				t.Errorf("strict = %v, want %v", format.JSONSchema.Strict.Value, tt.wantStrict)
+			}
+			if !tt.wantStrict {
+				return
+			}
+			var schema struct {
+				Required []string `json:"required"`
+			}
+			if err := json.Unmarshal(format.JSONSchema.Schema.([]byte), &schema); err != nil {
+				t.Fatalf("unmarshal schema: %v", err)
+			}
+			if strings.Join(schema.Required, ",") != "bio,name" {
+				t.Errorf("required = %v, want [bio name]", schema.Required)
+			}
+		})
+	}
+}
+
 // TestErrorHandling tests various error scenarios
 func TestErrorHandling(t *testing.T) {
 	tests := []struct {
@@ -1324,3 +1408,372 @@ func TestUsageHandler_NilOptions(_ *testing.T) {
 	// Should not panic with options but no handler
 	client.reportUsage(&ai.AgentOptions{})
 }
+
+func TestBuildChatParamsDeterministic(t *testing.T) {
+	client := &Client{model: shared.ChatModel(testModel), config: &Config{Seed: helpers.PtrOf(7)}}
+	input := &ai.ClassifiedInput{Type: ai.TextInput, Text: "Hello"}
+
+	params, err := client.buildChatParams(context.Background(), input, nil, nil)
+	if err != nil || params.Seed.Value != 7 {
+		t.Fatalf("configured seed = %v, %v; want 7", params.Seed.Value, err)
+	}
+
+	params, err = client.buildChatParams(calque.WithDeterministicSeed(context.Background(), 42), input, nil, nil)
+	if err != nil || params.Seed.Value != 42 {
+		t.Errorf("deterministic seed = %v, %v; want the run seed 42", params.Seed.Value, err)
+	}
+}
+
+func TestEgress(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    []string
+	}{
+		{"", []string{"api.openai.com"}},
+		{"https://gateway.example.com/v1", []string{"gateway.example.com"}},
+		{"http://localhost:8000/v1", nil},
+	}
+
+	for _, tt := range tests {
+		client, err := New(testModel, WithConfig(&Config{APIKey: "sk-test", BaseURL: tt.baseURL}))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if got := client.Egress(); !slices.Equal(got, tt.want) {
+			t.Errorf("Egress() with BaseURL %q = %v, want %v", tt.baseURL, got, tt.want)
+		}
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"model found", http.StatusOK, false},
+		{"unknown model", http.StatusNotFound, true},
+		{"bad key", http.StatusUnauthorized, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				fmt.Fprintf(w, `{"id":%q,"object":"model","owned_by":"openai"}`, testModel)
+			}))
+			defer server.Close()
+
+			client, err := New(testModel, WithConfig(&Config{APIKey: "sk-test", BaseURL: server.URL}))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			err = client.Warmup(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Warmup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if path != "/models/"+testModel {
+				t.Errorf("request path = %q", path)
+			}
+		})
+	}
+}
+
+func TestChatCapturesReasoningContent(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream bool
+	}{
+		{"streaming", true},
+		{"non-streaming", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if !tt.stream {
+					w.Header().Set("Content-Type", "application/json")
+					fmt.Fprint(w, `{"id":"1","object":"chat.completion","model":"deepseek-reasoner","choices":[{"index":0,"finish_reason":"stop",`+
+						`"message":{"role":"assistant","content":"4","reasoning_content":"Two plus two is four."}}]}`)
+					return
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, delta := range []string{
+					`{"role":"assistant","reasoning_content":"Two plus "}`,
+					`{"reasoning_content":"two is four."}`,
+					`{"content":"4"}`,
+				} {
+					fmt.Fprintf(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"model\":\"deepseek-reasoner\",\"choices\":[{\"index\":0,\"delta\":%s}]}\n\n", delta)
+				}
+				fmt.Fprint(w, "data: [DONE]\n\n")
+			}))
+			defer server.Close()
+
+			client, err := New("deepseek-reasoner", WithConfig(&Config{APIKey: "sk-test", BaseURL: server.URL, Stream: &tt.stream}))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			opts := &ai.AgentOptions{}
+			ai.WithReasoning(ai.ReasoningConfig{}).Apply(opts)
+			ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+
+			var response strings.Builder
+			if err := client.Chat(calque.NewRequest(ctx, strings.NewReader("2+2?")), calque.NewResponse(&response), opts); err != nil {
+				t.Fatalf("Chat() error = %v", err)
+			}
+			if response.String() != "4" {
+				t.Errorf("Chat() = %q, reasoning must not reach the output", response.String())
+			}
+			if trace, _ := ai.ReasoningFrom(ctx); trace != "Two plus two is four." {
+				t.Errorf("reasoning metadata = %q", trace)
+			}
+		})
+	}
+}
+
+func TestChatRecordsRateLimitHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("x-ratelimit-limit-tokens", "30000")
+		w.Header().Set("x-ratelimit-remaining-tokens", "29000")
+		w.Header().Set("x-ratelimit-reset-tokens", "2s")
+		fmt.Fprintf(w, `{"id":"1","object":"chat.completion","model":%q,"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`, testModel)
+	}))
+	defer server.Close()
+
+	client, err := New(testModel, WithConfig(&Config{APIKey: "sk-test", BaseURL: server.URL, Stream: helpers.PtrOf(false)}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+	var response strings.Builder
+	if err := client.Chat(calque.NewRequest(ctx, strings.NewReader("hi")), calque.NewResponse(&response), nil); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	info, ok := ctrl.RateLimitFromContext(ctx)
+	if !ok || info.Tokens.Remaining != 29000 || info.Tokens.Reset != 2*time.Second {
+		t.Errorf("RateLimitFromContext() = %+v, %v", info, ok)
+	}
+}
+
+func TestChatInterceptor(t *testing.T) {
+	var gotHeader, gotModel string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("Helicone-Auth")
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		gotModel = body.Model
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"1","object":"chat.completion","model":%q,"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`, testModel)
+	}))
+	defer server.Close()
+
+	client, err := New(testModel, WithConfig(&Config{APIKey: "sk-test", BaseURL: server.URL, Stream: helpers.PtrOf(false)}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var status int
+	opts := &ai.AgentOptions{}
+	ai.WithInterceptor(func(req *http.Request, next ai.InterceptorNext) (*http.Response, error) {
+		req.Header.Set("Helicone-Auth", "Bearer test")
+		body, _ := io.ReadAll(req.Body)
+		body = bytes.Replace(body, []byte(testModel), []byte("router/fast"), 1)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+
+		resp, err := next(req)
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		return resp, err
+	}).Apply(opts)
+
+	var response strings.Builder
+	if err := client.Chat(calque.NewRequest(context.Background(), strings.NewReader("hi")), calque.NewResponse(&response), opts); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if gotHeader != "Bearer test" || gotModel != "router/fast" {
+		t.Errorf("provider got header %q and model %q, want the interceptor's", gotHeader, gotModel)
+	}
+	if status != http.StatusOK || response.String() != "ok" {
+		t.Errorf("interceptor saw status %d, response = %q", status, response.String())
+	}
+}
+
+func TestChatOverrides(t *testing.T) {
+	var bodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"1","object":"chat.completion","model":%q,"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`, body["model"])
+	}))
+	defer server.Close()
+
+	client, err := New(testModel, WithConfig(&Config{
+		APIKey:      "sk-test",
+		BaseURL:     server.URL,
+		Stream:      helpers.PtrOf(false),
+		Temperature: helpers.PtrOf(float32(1)),
+		MaxTokens:   helpers.PtrOf(100),
+	}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var usage *ai.UsageMetadata
+	opts := &ai.AgentOptions{UsageHandler: func(u *ai.UsageMetadata) { usage = u }}
+	ctx := ai.Override(context.Background(), ai.Overrides{Model: "gpt-4o-mini", Temperature: helpers.PtrOf(float32(0.25)), MaxTokens: helpers.PtrOf(2000)})
+	for _, ctx := range []context.Context{ctx, context.Background()} {
+		if err := client.Chat(calque.NewRequest(ctx, strings.NewReader("hi")), calque.NewResponse(&strings.Builder{}), opts); err != nil {
+			t.Fatalf("Chat() error = %v", err)
+		}
+	}
+
+	overridden, configured := bodies[0], bodies[1]
+	if overridden["model"] != "gpt-4o-mini" || overridden["temperature"] != 0.25 || overridden["max_completion_tokens"] != float64(2000) {
+		t.Errorf("overridden request = %v", overridden)
+	}
+	if configured["model"] != testModel || configured["temperature"] != float64(1) || configured["max_completion_tokens"] != float64(100) {
+		t.Errorf("overrides leaked into the next request: %v", configured)
+	}
+	if usage == nil || usage.Model != testModel {
+		t.Errorf("usage = %+v, want the configured model for the second call", usage)
+	}
+	if settings := client.ModelSettings(ctx); settings.Model != "gpt-4o-mini" || *settings.MaxTokens != 2000 {
+		t.Errorf("ModelSettings() = %+v, want the overridden settings", settings)
+	}
+}
+
+func TestChatRetryReplacesSDKRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error":{"message":"overloaded","type":"server_error"}}`)
+	}))
+	defer server.Close()
+
+	client, err := New(testModel, WithConfig(&Config{APIKey: "sk-test", BaseURL: server.URL, Stream: helpers.PtrOf(false)}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	opts := &ai.AgentOptions{}
+	ai.WithRetry(ai.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}).Apply(opts)
+	err = client.Chat(calque.NewRequest(context.Background(), strings.NewReader("hi")), calque.NewResponse(&strings.Builder{}), opts)
+	if err == nil {
+		t.Fatal("Chat() should fail when every attempt is unavailable")
+	}
+	if calls.Load() != 2 {
+		t.Errorf("provider called %d times, want the policy's 2 attempts", calls.Load())
+	}
+}
+
+func TestNetworkProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":%q,"object":"model","owned_by":"openai"}`, testModel)
+	}))
+	defer proxy.Close()
+
+	client, err := New(testModel, WithConfig(&Config{
+		APIKey:  "sk-test",
+		BaseURL: "http://llm-gateway.corp.test/v1",
+		Network: &ai.NetworkConfig{ProxyURL: proxy.URL},
+	}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := client.Warmup(context.Background()); err != nil {
+		t.Fatalf("Warmup() error = %v", err)
+	}
+	if proxied != "http://llm-gateway.corp.test/v1/models/"+testModel {
+		t.Errorf("proxy received %q, want the gateway URL", proxied)
+	}
+
+	if _, err := New(testModel, WithConfig(&Config{APIKey: "sk-test", Network: &ai.NetworkConfig{ProxyURL: "proxy:3128"}})); err == nil {
+		t.Error("New() should reject an invalid proxy URL")
+	}
+}
+
+type recordedSpan struct {
+	attrs map[string]any
+	err   error
+}
+
+func (s *recordedSpan) SetAttribute(key string, value any) { s.attrs[key] = value }
+func (s *recordedSpan) End(err error)                      { s.err = err }
+
+func TestChatRecordsGenAISpan(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream bool
+	}{
+		{"streaming", true},
+		{"non-streaming", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if !tt.stream {
+					w.Header().Set("Content-Type", "application/json")
+					fmt.Fprintf(w, `{"id":"chatcmpl-1","object":"chat.completion","model":"%s-0613","choices":[{"index":0,"finish_reason":"stop",`+
+						`"message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`, testModel)
+					return
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprintf(w, "data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"model\":\"%s-0613\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"ok\"}}]}\n\n", testModel)
+				fmt.Fprintf(w, "data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"model\":\"%s-0613\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n", testModel)
+				fmt.Fprintf(w, "data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"model\":\"%s-0613\",\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":1,\"total_tokens\":6}}\n\n", testModel)
+				fmt.Fprint(w, "data: [DONE]\n\n")
+			}))
+			defer server.Close()
+
+			client, err := New(testModel, WithConfig(&Config{APIKey: "sk-test", BaseURL: server.URL, Stream: &tt.stream}))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			span := &recordedSpan{attrs: map[string]any{}}
+			ctx := calque.WithSpanStarter(context.Background(), func(ctx context.Context, _ string) (context.Context, calque.Span) {
+				return ctx, span
+			})
+
+			var response strings.Builder
+			if err := client.Chat(calque.NewRequest(ctx, strings.NewReader("hi")), calque.NewResponse(&response), nil); err != nil {
+				t.Fatalf("Chat() error = %v", err)
+			}
+
+			want := map[string]any{
+				ai.AttrGenAIProviderName:          "openai",
+				ai.AttrGenAIRequestModel:          testModel,
+				ai.AttrGenAIResponseID:            "chatcmpl-1",
+				ai.AttrGenAIResponseModel:         testModel + "-0613",
+				ai.AttrGenAIUsageInputTokens:      5,
+				ai.AttrGenAIUsageOutputTokens:     1,
+				ai.AttrGenAIResponseFinishReasons: []string{"stop"},
+			}
+			for key, value := range want {
+				if got := span.attrs[key]; fmt.Sprint(got) != fmt.Sprint(value) {
+					t.Errorf("attribute %s = %v, want %v", key, got, value)
+				}
+			}
+		})
+	}
+}
@@ -35,6 +35,14 @@ type AgentOptions struct {
 	ToolResultFormatter ToolResultFormatterFunc
 	ToolFormatterClient Client
 	UsageHandler        func(*UsageMetadata)
+	Reasoning           *ReasoningTrace
+	StreamTimeouts      *StreamTimeouts
+	MaxIterations       int                  // Model calls allowed in an agent loop, see WithMaxIterations
+	IterationHook       IterationHook        // Called after each agent loop iteration
+	Interceptors        []Interceptor        // Wrap provider HTTP requests, see WithInterceptor
+	Retry               *RetryPolicy         // Retry transient provider failures, see WithRetry
+	CostTracker         *CostTracker         // Price and total usage, see WithCostTracker
+	Cache               *ResponseCacheConfig // Serve repeated requests from a cache, see WithCache
 }
 
 // AgentOption interface for functional options pattern.
@@ -239,7 +247,9 @@ func (o usageHandlerOption) Apply(opts *AgentOptions) {
 // once for the initial request and once for the synthesis request.
 //
 // Users are responsible for any required synchronization if tracking
-// cumulative usage across concurrent requests.
+// cumulative usage across concurrent requests. Agents also add every call's
+// usage to the flow run's total, which calque.UsageFrom reports, so the
+// handler isn't needed just to account for a whole run.
 //
 // Example:
 //
@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// OverridesMetadataKey is the MetadataBus key handlers set to override model
+// settings for the rest of a flow run. The value is an Overrides, or its JSON
+// encoding when it arrives as string metadata from another process.
+const OverridesMetadataKey = "ai.overrides"
+
+type overridesContextKey struct{}
+
+// Overrides replaces client settings for the model calls of one flow run.
+//
+// Zero fields keep the client's configured value. Provider clients apply
+// them to every call, so one deployed flow can vary its model and sampling
+// per user tier without rebuilding its agents.
+//
+// Example:
+//
+//	tiers := map[string]ai.Overrides{
+//		"free": {Model: "gpt-4o-mini", MaxTokens: helpers.PtrOf(256)},
+//		"pro":  {Model: "gpt-4o", MaxTokens: helpers.PtrOf(4096)},
+//	}
+type Overrides struct {
+	Model       string   `json:"model,omitempty"`       // Model name, ID or ARN sent to the provider
+	Temperature *float32 `json:"temperature,omitempty"` // Sampling temperature
+	MaxTokens   *int     `json:"max_tokens,omitempty"`  // Completion token limit
+}
+
+// IsZero reports whether no setting is overridden
+func (o Overrides) IsZero() bool {
+	return o.Model == "" && o.Temperature == nil && o.MaxTokens == nil
+}
+
+// merge returns o with the fields set in other replacing its own
+func (o Overrides) merge(other Overrides) Overrides {
+	if other.Model != "" {
+		o.Model = other.Model
+	}
+	if other.Temperature != nil {
+		o.Temperature = other.Temperature
+	}
+	if other.MaxTokens != nil {
+		o.MaxTokens = other.MaxTokens
+	}
+	return o
+}
+
+// MarshalText encodes overrides as JSON, for carrying them in string metadata across process boundaries
+func (o Overrides) MarshalText() ([]byte, error) {
+	type plain Overrides
+	return json.Marshal(plain(o))
+}
+
+// UnmarshalText decodes overrides written by MarshalText
+func (o *Overrides) UnmarshalText(data []byte) error {
+	type plain Overrides
+	return json.Unmarshal(data, (*plain)(o))
+}
+
+// Override returns a context whose model calls use the given settings.
+//
+// Calling it again on the returned context overrides further: fields set in
+// the newer overrides win and the rest are kept.
+//
+// Example:
+//
+//	ctx := ai.Override(r.Context(), tiers[user.Tier])
+//	err := flow.Run(ctx, input, &output)
+func Override(ctx context.Context, overrides Overrides) context.Context {
+	current, _ := ctx.Value(overridesContextKey{}).(Overrides)
+	return context.WithValue(ctx, overridesContextKey{}, current.merge(overrides))
+}
+
+// OverridesFrom returns the settings model calls in ctx should use.
+//
+// It combines Override with the Overrides set on the flow run's MetadataBus
+// under OverridesMetadataKey; metadata set during the run takes precedence.
+// Provider clients call it at the start of Chat.
+func OverridesFrom(ctx context.Context) Overrides {
+	overrides, _ := ctx.Value(overridesContextKey{}).(Overrides)
+	bus := calque.GetMetadataBus(ctx)
+	if bus == nil {
+		return overrides
+	}
+	switch v, _ := bus.Get(OverridesMetadataKey); v := v.(type) {
+	case Overrides:
+		overrides = overrides.merge(v)
+	case *Overrides:
+		if v != nil {
+			overrides = overrides.merge(*v)
+		}
+	case string:
+		var decoded Overrides
+		if err := decoded.UnmarshalText([]byte(v)); err != nil {
+			calque.LogWarn(ctx, "ignoring invalid model overrides metadata", "error", err)
+			break
+		}
+		overrides = overrides.merge(decoded)
+	}
+	return overrides
+}
@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/helpers"
+)
+
+func TestOverride(t *testing.T) {
+	if !OverridesFrom(context.Background()).IsZero() {
+		t.Error("a context without overrides should have none")
+	}
+
+	ctx := Override(context.Background(), Overrides{Model: "gpt-4o-mini", MaxTokens: helpers.PtrOf(256)})
+	ctx = Override(ctx, Overrides{Temperature: helpers.PtrOf(float32(0.2)), MaxTokens: helpers.PtrOf(512)})
+
+	got := OverridesFrom(ctx)
+	if got.Model != "gpt-4o-mini" || *got.Temperature != 0.2 || *got.MaxTokens != 512 {
+		t.Errorf("OverridesFrom() = %+v, want the newer fields over the older ones", got)
+	}
+}
+
+func TestOverridesFromMetadata(t *testing.T) {
+	tests := map[string]struct {
+		value any
+		want  Overrides
+	}{
+		"value":   {Overrides{Model: "pro"}, Overrides{Model: "pro", MaxTokens: helpers.PtrOf(100)}},
+		"pointer": {&Overrides{MaxTokens: helpers.PtrOf(4096)}, Overrides{Model: "free", MaxTokens: helpers.PtrOf(4096)}},
+		"json":    {`{"model":"pro","temperature":0.5}`, Overrides{Model: "pro", Temperature: helpers.PtrOf(float32(0.5)), MaxTokens: helpers.PtrOf(100)}},
+		"invalid": {"not json", Overrides{Model: "free", MaxTokens: helpers.PtrOf(100)}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			bus := calque.NewMetadataBus(0)
+			ctx := calque.WithMetadataBus(context.Background(), bus)
+			ctx = Override(ctx, Overrides{Model: "free", MaxTokens: helpers.PtrOf(100)})
+			bus.Set(OverridesMetadataKey, tt.value)
+
+			got := OverridesFrom(ctx)
+			if got.Model != tt.want.Model || !equalPtr(got.Temperature, tt.want.Temperature) || !equalPtr(got.MaxTokens, tt.want.MaxTokens) {
+				t.Errorf("OverridesFrom() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverridesText(t *testing.T) {
+	text, err := Overrides{Model: "gpt-4o", MaxTokens: helpers.PtrOf(10)}.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != `{"model":"gpt-4o","max_tokens":10}` {
+		t.Errorf("MarshalText() = %s", text)
+	}
+	var decoded Overrides
+	if err := decoded.UnmarshalText(text); err != nil || decoded.Model != "gpt-4o" || *decoded.MaxTokens != 10 {
+		t.Errorf("UnmarshalText() = %+v, %v", decoded, err)
+	}
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
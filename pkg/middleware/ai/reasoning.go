@@ -0,0 +1,202 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// ReasoningMetadataKey is the MetadataBus key holding the captured reasoning trace.
+const ReasoningMetadataKey = "ai.reasoning"
+
+// ReasoningConfig controls how reasoning ("thinking") tokens are captured.
+//
+// Reasoning never reaches the main output stream; it is kept in request
+// metadata and optionally streamed to a separate writer.
+type ReasoningConfig struct {
+	// Writer receives reasoning as it streams, line by line (optional)
+	Writer io.Writer
+	// Redact rewrites reasoning before it is written or stored, e.g. to mask PII;
+	// it is applied to whole lines so patterns are not split across chunks
+	Redact func(string) string
+	// MaxBytes caps the trace stored in metadata (default: 64 KiB, negative: don't store)
+	MaxBytes int
+	// OnComplete is called with the full redacted trace once the response finishes
+	OnComplete func(ctx context.Context, trace string)
+}
+
+// ReasoningTrace accumulates reasoning for a single request.
+//
+// Providers call Append for every reasoning chunk and Finish once the response
+// is complete. All methods are safe on a nil trace, so providers need not check
+// whether capture was requested.
+type ReasoningTrace struct {
+	config  ReasoningConfig
+	mu      sync.Mutex
+	pending strings.Builder // partial line not yet redacted
+	trace   strings.Builder
+	done    bool
+
+	progress func() // notified of each chunk, e.g. by WithStreamTimeouts
+}
+
+type reasoningOption struct{ config ReasoningConfig }
+
+func (o reasoningOption) Apply(opts *AgentOptions) {
+	opts.Reasoning = &ReasoningTrace{config: o.config}
+}
+
+// WithReasoning captures provider reasoning tokens separately from the answer.
+//
+// Input: ReasoningConfig with optional side-channel writer and redaction
+// Output: AgentOption for configuration
+// Behavior: Stores the trace under ReasoningMetadataKey and streams it to Writer
+//
+// Providers that expose reasoning (Gemini thoughts, Ollama thinking models,
+// OpenAI-compatible servers returning reasoning_content) hand it to the trace
+// instead of the output, and Gemini is asked to include thoughts. Providers
+// without reasoning output leave the trace empty. Read it back with
+// ReasoningFrom in a later handler, or stream it to a UI through Writer.
+//
+// Handlers run concurrently, so a later handler may start before the agent
+// has produced anything. The trace is stored before the agent's output stream
+// closes: ReasoningFrom is only guaranteed to see it once the handler has read
+// its input to EOF.
+//
+// Example:
+//
+//	agent := ai.Agent(client, ai.WithReasoning(ai.ReasoningConfig{
+//		Writer: thinkingPanel,
+//		Redact: maskEmails,
+//	}))
+func WithReasoning(config ReasoningConfig) AgentOption {
+	return reasoningOption{config: config}
+}
+
+// Append adds a chunk of reasoning, writing each completed line to the configured writer
+func (t *ReasoningTrace) Append(text string) error {
+	if t == nil || text == "" {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.progress != nil {
+		t.progress()
+	}
+	t.pending.WriteString(text)
+	buffered := t.pending.String()
+	cut := strings.LastIndexByte(buffered, '\n')
+	if cut < 0 {
+		return nil
+	}
+	t.pending.Reset()
+	t.pending.WriteString(buffered[cut+1:])
+	return t.emit(buffered[:cut+1])
+}
+
+// Finish flushes remaining reasoning, stores the trace in metadata and reports it.
+// Calls after the first are ignored.
+func (t *ReasoningTrace) Finish(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	if t.done {
+		t.mu.Unlock()
+		return nil
+	}
+	t.done = true
+	err := t.emit(t.pending.String())
+	t.pending.Reset()
+	trace := t.trace.String()
+	t.mu.Unlock()
+
+	if trace == "" {
+		return err
+	}
+	if t.config.MaxBytes >= 0 {
+		if bus := calque.GetMetadataBus(ctx); bus != nil {
+			bus.Set(ReasoningMetadataKey, truncateReasoning(trace, t.config.MaxBytes))
+		}
+	}
+	if t.config.OnComplete != nil {
+		t.config.OnComplete(ctx, trace)
+	}
+	return err
+}
+
+// setProgress sets the function notified of each reasoning chunk
+func (t *ReasoningTrace) setProgress(fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress = fn
+}
+
+// String returns the redacted reasoning captured so far
+func (t *ReasoningTrace) String() string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.trace.String()
+}
+
+// emit redacts complete text and forwards it (must hold mu)
+func (t *ReasoningTrace) emit(text string) error {
+	if text == "" {
+		return nil
+	}
+	if t.config.Redact != nil {
+		text = t.config.Redact(text)
+	}
+	t.trace.WriteString(text)
+	if t.config.Writer != nil {
+		if _, err := io.WriteString(t.config.Writer, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReasoningFrom returns the reasoning trace a previous agent stored in the context's MetadataBus.
+//
+// Example:
+//
+//	flow.Use(ai.Agent(client, ai.WithReasoning(ai.ReasoningConfig{}))).
+//		UseFunc(func(req *calque.Request, res *calque.Response) error {
+//			if _, err := io.Copy(res.Data, req.Data); err != nil {
+//				return err
+//			}
+//			// The input is drained, so the agent has stored its trace
+//			if trace, ok := ai.ReasoningFrom(req.Context); ok {
+//				ui.ShowThinking(trace)
+//			}
+//			return nil
+//		})
+func ReasoningFrom(ctx context.Context) (string, bool) {
+	bus := calque.GetMetadataBus(ctx)
+	if bus == nil {
+		return "", false
+	}
+	return bus.GetString(ReasoningMetadataKey)
+}
+
+func truncateReasoning(trace string, maxBytes int) string {
+	if maxBytes == 0 {
+		maxBytes = 64 << 10
+	}
+	if len(trace) <= maxBytes {
+		return trace
+	}
+	// Back off to a rune boundary
+	cut := maxBytes
+	for cut > 0 && cut < len(trace) && trace[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return trace[:cut] + "…"
+}
@@ -0,0 +1,118 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestWithReasoning(t *testing.T) {
+	var side bytes.Buffer
+	var completed string
+	agent := Agent(NewMockClient("The answer is 4").WithStreamDelay(0).WithReasoning("2 plus 2\nequals 4\n"),
+		WithReasoning(ReasoningConfig{
+			Writer:     &side,
+			OnComplete: func(_ context.Context, trace string) { completed = trace },
+		}))
+
+	var stored string
+	var out string
+	err := calque.NewFlow().
+		Use(agent).
+		UseFunc(func(req *calque.Request, res *calque.Response) error {
+			_, err := io.Copy(res.Data, req.Data)
+			stored, _ = ReasoningFrom(req.Context) // Stored before the agent's output closes
+			return err
+		}).
+		Run(context.Background(), "what is 2+2?", &out)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if out != "The answer is 4" {
+		t.Errorf("output = %q, reasoning must not reach the main stream", out)
+	}
+	want := "2 plus 2\nequals 4\n"
+	if side.String() != want || stored != want || completed != want {
+		t.Errorf("side channel = %q, metadata = %q, OnComplete = %q; want %q", side.String(), stored, completed, want)
+	}
+}
+
+func TestReasoningTrace_RedactsWholeLines(t *testing.T) {
+	var side bytes.Buffer
+	trace := &ReasoningTrace{config: ReasoningConfig{
+		Writer: &side,
+		Redact: func(s string) string { return strings.ReplaceAll(s, "alice@example.com", "[email]") },
+	}}
+
+	// The address is split across chunks; redaction must still catch it
+	for _, chunk := range []string{"contact ali", "ce@exam", "ple.com now\nthen ", "reply"} {
+		if err := trace.Append(chunk); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	if side.String() != "contact [email] now\n" {
+		t.Errorf("side channel before finish = %q, want only the completed line", side.String())
+	}
+
+	ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+	if err := trace.Finish(ctx); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	if err := trace.Append("late\n"); err != nil || trace.Finish(ctx) != nil {
+		t.Fatal("calls after Finish should be harmless")
+	}
+
+	want := "contact [email] now\nthen reply"
+	if got, _ := ReasoningFrom(ctx); got != want {
+		t.Errorf("stored trace = %q, want %q", got, want)
+	}
+	if strings.Contains(side.String(), "alice") {
+		t.Errorf("unredacted reasoning leaked: %q", side.String())
+	}
+}
+
+func TestReasoningTrace_MaxBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxBytes int
+		want     string
+		stored   bool
+	}{
+		{"default keeps short trace", 0, "héllo wörld", true},
+		{"truncated mid rune backs off", 2, "h…", true},
+		{"truncated on rune boundary", 3, "hé…", true},
+		{"negative disables storage", -1, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace := &ReasoningTrace{config: ReasoningConfig{MaxBytes: tt.maxBytes}}
+			_ = trace.Append("héllo wörld")
+			ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+			_ = trace.Finish(ctx)
+
+			got, ok := ReasoningFrom(ctx)
+			if ok != tt.stored || got != tt.want {
+				t.Errorf("ReasoningFrom() = %q, %v; want %q, %v", got, ok, tt.want, tt.stored)
+			}
+			if trace.String() != "héllo wörld" {
+				t.Errorf("String() = %q, full trace should be kept in memory", trace.String())
+			}
+		})
+	}
+}
+
+func TestReasoningTrace_Nil(t *testing.T) {
+	var trace *ReasoningTrace
+	if trace.Append("x") != nil || trace.Finish(context.Background()) != nil || trace.String() != "" {
+		t.Error("nil trace should be a no-op")
+	}
+	if GetReasoning(nil) != nil || GetReasoning(&AgentOptions{}) != nil {
+		t.Error("GetReasoning() should be nil when capture is not enabled")
+	}
+}
@@ -0,0 +1,206 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/cache"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// DefaultResponseCacheTTL is how long cached completions live unless configured
+const DefaultResponseCacheTTL = time.Hour
+
+// ResponseCacheConfig configures response caching for an agent, see WithCacheConfig
+type ResponseCacheConfig struct {
+	// Store holds cached completions. Any cache.Store works, e.g. cache.NewInMemoryStore
+	// or redisstore.New to share completions between workers (default: in-memory store)
+	Store cache.Store
+
+	// TTL is how long completions stay cached (default: 1 hour)
+	TTL time.Duration
+
+	// NormalizeWhitespace keys on the prompt with whitespace runs collapsed to
+	// one space and the ends trimmed, so prompts differing only in layout share
+	// a completion. The prompt sent to the model is not changed.
+	NormalizeWhitespace bool
+
+	// Namespace separates entries of different applications or prompt versions
+	// sharing a store
+	Namespace string
+
+	// OnError is called when reading or writing the store fails. Failures
+	// never fail the request; the call goes to the model instead.
+	OnError func(error)
+}
+
+type cacheOption struct{ config *ResponseCacheConfig }
+
+func (o cacheOption) Apply(opts *AgentOptions) { opts.Cache = o.config }
+
+// WithCache serves repeated requests from a cache of previous completions.
+//
+// Input: cache store, time to live for each completion
+// Output: AgentOption
+// Behavior: BUFFERED - reads the whole prompt to build the key; misses stream
+// the model's output while it is stored
+//
+// The key is a SHA-256 hash of the prompt and everything else that shapes the
+// completion: model, temperature and max tokens (including Overrides, for
+// clients implementing ModelDescriber), response schema and tool set. Hits skip
+// the model call entirely, so no usage is recorded and tools do not run.
+// Multimodal requests that stream their data are never cached.
+//
+// Example:
+//
+//	store := cache.NewInMemoryStore()
+//	agent := ai.Agent(client, ai.WithCache(store, 24*time.Hour))
+func WithCache(store cache.Store, ttl time.Duration) AgentOption {
+	return WithCacheConfig(&ResponseCacheConfig{Store: store, TTL: ttl})
+}
+
+// WithCacheConfig serves repeated requests from a cache with full configuration.
+//
+// Example:
+//
+//	agent := ai.Agent(client, ai.WithCacheConfig(&ai.ResponseCacheConfig{
+//		Store:               redisStore,
+//		TTL:                 6 * time.Hour,
+//		NormalizeWhitespace: true,
+//		Namespace:           "support-v3",
+//	}))
+func WithCacheConfig(config *ResponseCacheConfig) AgentOption {
+	if config == nil {
+		config = &ResponseCacheConfig{}
+	}
+	resolved := *config
+	if resolved.Store == nil {
+		resolved.Store = cache.NewInMemoryStore()
+	}
+	if resolved.TTL <= 0 {
+		resolved.TTL = DefaultResponseCacheTTL
+	}
+	return cacheOption{config: &resolved}
+}
+
+// GetCache returns the response cache set with WithCache, or nil
+func GetCache(opts *AgentOptions) *ResponseCacheConfig {
+	if opts == nil {
+		return nil
+	}
+	return opts.Cache
+}
+
+// serveCached answers from the cache when it holds a completion for the
+// request, and otherwise runs next while storing its output
+func serveCached(client Client, opts *AgentOptions, r *calque.Request, w *calque.Response, next calque.HandlerFunc) error {
+	config := opts.Cache
+	input, err := io.ReadAll(r.Data)
+	if err != nil {
+		return calque.WrapErr(r.Context, err, "failed to read input for cache key generation")
+	}
+	req := calque.NewRequest(r.Context, bytes.NewReader(input))
+
+	key, ok := responseCacheKey(r.Context, client, opts, input)
+	if !ok {
+		return next(req, w)
+	}
+
+	cached, err := config.Store.Get(key)
+	if err != nil {
+		config.reportError(calque.WrapErr(r.Context, err, "failed to read from response cache"))
+	} else if cached != nil {
+		calque.LogDebug(r.Context, "ai response cache hit", "key", key)
+		return calque.Write(w, cached)
+	}
+
+	var output bytes.Buffer
+	if err := next(req, calque.NewResponse(io.MultiWriter(w.Data, &output))); err != nil {
+		return err
+	}
+	if err := config.Store.Set(key, output.Bytes(), config.TTL); err != nil {
+		config.reportError(calque.WrapErr(r.Context, err, "failed to write to response cache"))
+	}
+	return nil
+}
+
+func (c *ResponseCacheConfig) reportError(err error) {
+	if c.OnError != nil {
+		c.OnError(err)
+	}
+}
+
+// responseCacheKey hashes the prompt with the settings that shape its
+// completion. It reports false for requests that cannot be cached.
+func responseCacheKey(ctx context.Context, client Client, opts *AgentOptions, input []byte) (string, bool) {
+	if opts.MultimodalData != nil {
+		for _, part := range opts.MultimodalData.Parts {
+			if part.Reader != nil {
+				return "", false // Streamed data can't be hashed without consuming it
+			}
+		}
+	}
+
+	h := sha256.New()
+	if describer, ok := client.(ModelDescriber); ok {
+		writeKeyPart(h, "model", describer.ModelSettings(ctx))
+	} else {
+		writeKeyPart(h, "client", fmt.Sprintf("%T", client))
+		writeKeyPart(h, "overrides", OverridesFrom(ctx))
+	}
+
+	prompt := string(input)
+	if opts.Cache.NormalizeWhitespace {
+		prompt = strings.Join(strings.Fields(prompt), " ")
+	}
+	writeKeyPart(h, "prompt", prompt)
+	writeKeyPart(h, "multimodal", opts.MultimodalData)
+	writeKeyPart(h, "schema", opts.Schema)
+	writeKeyPart(h, "tools", toolSignatures(opts.Tools))
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	if opts.Cache.Namespace != "" {
+		return "ai:response:" + opts.Cache.Namespace + ":" + sum, true
+	}
+	return "ai:response:" + sum, true
+}
+
+// writeKeyPart adds a labelled value to the key hash
+func writeKeyPart(h hash.Hash, label string, value any) {
+	h.Write([]byte(label))
+	h.Write([]byte{0})
+	if s, ok := value.(string); ok {
+		h.Write([]byte(s))
+	} else {
+		data, _ := json.Marshal(value)
+		h.Write(data)
+	}
+	h.Write([]byte{0})
+}
+
+// toolSignature identifies a tool by what the model sees of it
+type toolSignature struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+// toolSignatures describes a tool set independently of its order
+func toolSignatures(toolList []tools.Tool) []toolSignature {
+	signatures := make([]toolSignature, 0, len(toolList))
+	for _, tool := range toolList {
+		signatures = append(signatures, toolSignature{Name: tool.Name(), Description: tool.Description(), Parameters: tool.ParametersSchema()})
+	}
+	slices.SortFunc(signatures, func(a, b toolSignature) int { return strings.Compare(a.Name, b.Name) })
+	return signatures
+}
@@ -0,0 +1,165 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/helpers"
+	"github.com/calque-ai/go-calque/pkg/middleware/cache"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// countingClient numbers its answers and describes its settings, including overrides
+type countingClient struct {
+	calls atomic.Int32
+	fail  bool
+}
+
+func (c *countingClient) Chat(r *calque.Request, w *calque.Response, _ *AgentOptions) error {
+	if _, err := io.Copy(io.Discard, r.Data); err != nil {
+		return err
+	}
+	n := c.calls.Add(1)
+	if c.fail {
+		return errors.New("provider unavailable")
+	}
+	return calque.Write(w, fmt.Sprintf("answer %d", n))
+}
+
+func (c *countingClient) ModelSettings(ctx context.Context) ModelSettings {
+	overrides := OverridesFrom(ctx)
+	settings := ModelSettings{Model: "base", Temperature: overrides.Temperature}
+	if overrides.Model != "" {
+		settings.Model = overrides.Model
+	}
+	return settings
+}
+
+func runAgent(ctx context.Context, t *testing.T, agent calque.Handler, input string) string {
+	t.Helper()
+	var out string
+	if err := calque.NewFlow().Use(agent).Run(ctx, input, &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	return out
+}
+
+func TestWithCache(t *testing.T) {
+	client := &countingClient{}
+	agent := Agent(client, WithCache(cache.NewInMemoryStore(), time.Minute))
+	ctx := context.Background()
+
+	first := runAgent(ctx, t, agent, "What is Go?")
+	second := runAgent(ctx, t, agent, "What is Go?")
+	if first != "answer 1" || second != first || client.calls.Load() != 1 {
+		t.Errorf("repeated prompt answered %q then %q after %d calls, want one cached call", first, second, client.calls.Load())
+	}
+
+	if got := runAgent(ctx, t, agent, "What  is Go?"); got != "answer 2" {
+		t.Errorf("exact keys should not match a prompt with different whitespace, got %q", got)
+	}
+
+	cooler := Override(ctx, Overrides{Temperature: helpers.PtrOf(float32(0.1))})
+	if got := runAgent(cooler, t, agent, "What is Go?"); got != "answer 3" {
+		t.Errorf("a different temperature should miss the cache, got %q", got)
+	}
+	if got := runAgent(cooler, t, agent, "What is Go?"); got != "answer 3" {
+		t.Errorf("the overridden settings should be cached too, got %q", got)
+	}
+	if got := runAgent(Override(ctx, Overrides{Model: "large"}), t, agent, "What is Go?"); got != "answer 4" {
+		t.Errorf("a different model should miss the cache, got %q", got)
+	}
+}
+
+func TestWithCacheNormalizedWhitespace(t *testing.T) {
+	client := &countingClient{}
+	store := cache.NewInMemoryStore()
+	agent := Agent(client, WithCacheConfig(&ResponseCacheConfig{Store: store, NormalizeWhitespace: true, Namespace: "support-v1"}))
+
+	for _, prompt := range []string{"What is Go?", "  What is\n\tGo?  ", "What  is Go?"} {
+		if got := runAgent(context.Background(), t, agent, prompt); got != "answer 1" {
+			t.Errorf("prompt %q answered %q, want the cached answer 1", prompt, got)
+		}
+	}
+	if keys := store.List(); len(keys) != 1 || !strings.HasPrefix(keys[0], "ai:response:support-v1:") {
+		t.Errorf("store keys = %v, want one namespaced key", keys)
+	}
+}
+
+func TestWithCacheFailures(t *testing.T) {
+	t.Run("errors are not cached", func(t *testing.T) {
+		client := &countingClient{fail: true}
+		agent := Agent(client, WithCache(nil, 0))
+		for range 2 {
+			var out string
+			if err := calque.NewFlow().Use(agent).Run(context.Background(), "hi", &out); err == nil {
+				t.Fatal("Run() should return the provider error")
+			}
+		}
+		if client.calls.Load() != 2 {
+			t.Errorf("provider called %d times, want 2", client.calls.Load())
+		}
+	})
+
+	t.Run("store failures fall through to the model", func(t *testing.T) {
+		var reported []error
+		client := &countingClient{}
+		agent := Agent(client, WithCacheConfig(&ResponseCacheConfig{
+			Store:   failingStore{},
+			OnError: func(err error) { reported = append(reported, err) },
+		}))
+		if got := runAgent(context.Background(), t, agent, "hi"); got != "answer 1" {
+			t.Errorf("Run() = %q, want the model's answer", got)
+		}
+		if len(reported) != 2 {
+			t.Errorf("OnError called %d times, want a read and a write failure", len(reported))
+		}
+	})
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	search := tools.Simple("search", "Search the web", func(q string) string { return q })
+	calc := tools.Simple("calculator", "Math", func(e string) string { return e })
+	config := &ResponseCacheConfig{}
+	key := func(opts *AgentOptions) string {
+		opts.Cache = config
+		k, ok := responseCacheKey(context.Background(), &countingClient{}, opts, []byte("hi"))
+		if !ok {
+			t.Fatal("responseCacheKey() should cache this request")
+		}
+		return k
+	}
+
+	base := key(&AgentOptions{Tools: []tools.Tool{search, calc}})
+	if key(&AgentOptions{Tools: []tools.Tool{calc, search}}) != base {
+		t.Error("tool order should not change the key")
+	}
+	if key(&AgentOptions{Tools: []tools.Tool{search}}) == base {
+		t.Error("a different tool set should change the key")
+	}
+	if key(&AgentOptions{Tools: []tools.Tool{search, calc}, Schema: &ResponseFormat{Type: "json_object"}}) == base {
+		t.Error("a response schema should change the key")
+	}
+
+	streamed := &AgentOptions{Cache: config, MultimodalData: &MultimodalInput{Parts: []ContentPart{{Type: "image", Reader: strings.NewReader("png")}}}}
+	if _, ok := responseCacheKey(context.Background(), &countingClient{}, streamed, nil); ok {
+		t.Error("streamed multimodal data should not be cached")
+	}
+}
+
+// failingStore fails every operation
+type failingStore struct{}
+
+func (failingStore) Get(string) ([]byte, error)              { return nil, errors.New("store down") }
+func (failingStore) Set(string, []byte, time.Duration) error { return errors.New("store down") }
+func (failingStore) Delete(string) error                     { return errors.New("store down") }
+func (failingStore) Clear() error                            { return errors.New("store down") }
+func (failingStore) Exists(string) bool                      { return false }
+func (failingStore) List() []string                          { return nil }
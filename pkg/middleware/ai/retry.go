@@ -0,0 +1,177 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+// Retry policy defaults
+const (
+	DefaultRetryAttempts       = 3
+	DefaultRetryInitialBackoff = 500 * time.Millisecond
+	DefaultRetryMaxBackoff     = 30 * time.Second
+)
+
+// RetryPolicy configures provider-level retries, see WithRetry
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first (default: 3)
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the first retry, doubled for each
+	// retry after it and randomised by up to half (default: 500ms)
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps every wait, including a provider's Retry-After (default: 30s)
+	MaxBackoff time.Duration
+
+	// Retryable classifies a failed attempt (default: IsRetryable). It receives
+	// the response, or the transport error when there is none.
+	Retryable func(resp *http.Response, err error) bool
+}
+
+type retryOption struct{ policy RetryPolicy }
+
+func (o retryOption) Apply(opts *AgentOptions) { opts.Retry = &o.policy }
+
+// WithRetry retries provider requests that failed for transient reasons.
+//
+// Input: RetryPolicy (zero values use the defaults)
+// Output: AgentOption for configuration
+// Behavior: Resends the HTTP request inside the client with exponential backoff and jitter
+//
+// Only rate limits (429), overload and gateway errors (408, 500, 502, 503,
+// 504, 529) and network failures are retried. Validation, authentication and
+// other client errors fail at once, unlike ctrl.Retry, which retries every
+// error of the handler it wraps. A Retry-After header from the provider sets
+// the wait instead of the backoff.
+//
+// Retries happen below the agent, so tools are not executed twice and the
+// flow sees a single call. Every attempt passes through the interceptors
+// configured with WithInterceptor. Streaming responses are retried only
+// until the provider accepts the request.
+//
+// Example:
+//
+//	agent := ai.Agent(client, ai.WithRetry(ai.RetryPolicy{MaxAttempts: 5}))
+func WithRetry(policy RetryPolicy) AgentOption {
+	return retryOption{policy: policy}
+}
+
+// GetRetry returns the retry policy from AgentOptions, or nil when WithRetry is not set
+func GetRetry(opts *AgentOptions) *RetryPolicy {
+	if opts != nil {
+		return opts.Retry
+	}
+	return nil
+}
+
+// IsRetryable reports whether a provider request failed for a transient reason.
+//
+// Responses with status 408, 429, 500, 502, 503, 504 or 529 are retryable,
+// as are transport errors other than cancellation of the request's context.
+func IsRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	if resp == nil {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout,
+		529: // Anthropic overloaded
+		return true
+	}
+	return false
+}
+
+// RetryInterceptor returns an Interceptor that applies policy to each request.
+//
+// WithRetry installs it for an agent; use it with ContextWithInterceptors to
+// retry every provider request of a flow.
+func RetryInterceptor(policy RetryPolicy) Interceptor {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = DefaultRetryAttempts
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = DefaultRetryInitialBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = DefaultRetryMaxBackoff
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = IsRetryable
+	}
+
+	return func(req *http.Request, next InterceptorNext) (*http.Response, error) {
+		ctx := req.Context()
+		getBody, err := replayableBody(req)
+		if err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to buffer request for retries")
+		}
+
+		backoff := policy.InitialBackoff
+		for attempt := 1; ; attempt++ {
+			attemptReq := req.Clone(ctx)
+			if attemptReq.Body, err = getBody(); err != nil {
+				return nil, err
+			}
+
+			resp, err := next(attemptReq)
+			if (err == nil && resp.StatusCode < 400) || attempt >= policy.MaxAttempts || !policy.Retryable(resp, err) {
+				return resp, err
+			}
+
+			wait := backoff/2 + rand.N(backoff/2+1)
+			if resp != nil {
+				if info, ok := ctrl.ParseRateLimitHeaders(resp.Header); ok && info.RetryAfter > 0 {
+					wait = info.RetryAfter
+				}
+				// Free the connection; the failed response is not returned
+				_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+				resp.Body.Close()
+			}
+			wait = min(wait, policy.MaxBackoff)
+			backoff = min(backoff*2, policy.MaxBackoff)
+
+			status := 0
+			if resp != nil {
+				status = resp.StatusCode
+			}
+			calque.LogDebug(ctx, "retrying provider request", "attempt", attempt, "status", status, "error", err, "wait", wait)
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, calque.WrapErr(ctx, ctx.Err(), "retry cancelled")
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// replayableBody returns a function producing a fresh copy of the request body
+func replayableBody(req *http.Request) (func() (io.ReadCloser, error), error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return func() (io.ReadCloser, error) { return http.NoBody, nil }, nil
+	}
+	if req.GetBody != nil {
+		return req.GetBody, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	return func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }, nil
+}
@@ -0,0 +1,169 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *http.Response
+		err  error
+		want bool
+	}{
+		{"rate limited", &http.Response{StatusCode: 429}, nil, true},
+		{"unavailable", &http.Response{StatusCode: 503}, nil, true},
+		{"gateway timeout", &http.Response{StatusCode: 504}, nil, true},
+		{"overloaded", &http.Response{StatusCode: 529}, nil, true},
+		{"bad request", &http.Response{StatusCode: 400}, nil, false},
+		{"unauthorized", &http.Response{StatusCode: 401}, nil, false},
+		{"unprocessable", &http.Response{StatusCode: 422}, nil, false},
+		{"connection reset", nil, errors.New("read: connection reset by peer"), true},
+		{"cancelled", nil, context.Canceled, false},
+		{"deadline", nil, context.DeadlineExceeded, false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.resp, tt.err); got != tt.want {
+			t.Errorf("%s: IsRetryable() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// flakyServer fails with the given statuses, then succeeds, recording each request body
+func flakyServer(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *atomic.Int32, *[]string) {
+	t.Helper()
+	var calls atomic.Int32
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		n := int(calls.Add(1))
+		if n <= len(statuses) {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls, &bodies
+}
+
+func postWithRetry(ctx context.Context, url string, policy RetryPolicy) (*http.Response, error) {
+	client := &http.Client{Transport: InterceptTransport(nil)}
+	ctx = ContextWithInterceptors(ctx, RetryInterceptor(policy))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(`{"prompt":"hi"}`))
+	return client.Do(req)
+}
+
+func TestRetryInterceptor(t *testing.T) {
+	fast := RetryPolicy{InitialBackoff: time.Millisecond}
+
+	t.Run("retries transient failures with the same body", func(t *testing.T) {
+		server, calls, bodies := flakyServer(t, nil, 503, 429)
+		resp, err := postWithRetry(context.Background(), server.URL, fast)
+		if err != nil {
+			t.Fatalf("request error = %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+			t.Errorf("status %d after %d calls, want 200 after 3", resp.StatusCode, calls.Load())
+		}
+		for _, body := range *bodies {
+			if body != `{"prompt":"hi"}` {
+				t.Errorf("attempt sent body %q", body)
+			}
+		}
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		server, calls, _ := flakyServer(t, nil, 400)
+		resp, err := postWithRetry(context.Background(), server.URL, fast)
+		if err != nil {
+			t.Fatalf("request error = %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || calls.Load() != 1 {
+			t.Errorf("status %d after %d calls, want 400 after 1", resp.StatusCode, calls.Load())
+		}
+	})
+
+	t.Run("returns the last failure when attempts run out", func(t *testing.T) {
+		server, calls, _ := flakyServer(t, nil, 503, 503, 503)
+		resp, err := postWithRetry(context.Background(), server.URL, RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+		if err != nil {
+			t.Fatalf("request error = %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 2 {
+			t.Errorf("status %d after %d calls, want 503 after 2", resp.StatusCode, calls.Load())
+		}
+	})
+
+	t.Run("honors Retry-After", func(t *testing.T) {
+		server, _, _ := flakyServer(t, http.Header{"Retry-After-Ms": {"80"}}, 429)
+		start := time.Now()
+		resp, err := postWithRetry(context.Background(), server.URL, fast)
+		if err != nil {
+			t.Fatalf("request error = %v", err)
+		}
+		resp.Body.Close()
+		if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+			t.Errorf("retried after %v, want at least the 80ms Retry-After", elapsed)
+		}
+	})
+
+	t.Run("custom classification", func(t *testing.T) {
+		server, calls, _ := flakyServer(t, nil, 409)
+		policy := RetryPolicy{InitialBackoff: time.Millisecond, Retryable: func(resp *http.Response, err error) bool {
+			return IsRetryable(resp, err) || (resp != nil && resp.StatusCode == http.StatusConflict)
+		}}
+		resp, err := postWithRetry(context.Background(), server.URL, policy)
+		if err != nil {
+			t.Fatalf("request error = %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || calls.Load() != 2 {
+			t.Errorf("status %d after %d calls, want 200 after 2", resp.StatusCode, calls.Load())
+		}
+	})
+
+	t.Run("stops waiting when cancelled", func(t *testing.T) {
+		server, _, _ := flakyServer(t, http.Header{"Retry-After": {"10"}}, 503)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if _, err := postWithRetry(ctx, server.URL, fast); err == nil {
+			t.Fatal("expected an error after cancellation")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("cancelled retry took %v", elapsed)
+		}
+	})
+}
+
+func TestWithRetry(t *testing.T) {
+	opts := &AgentOptions{}
+	WithInterceptor(tagInterceptor("a")).Apply(opts)
+	WithRetry(RetryPolicy{MaxAttempts: 5}).Apply(opts)
+
+	if GetRetry(opts) == nil || GetRetry(opts).MaxAttempts != 5 {
+		t.Errorf("GetRetry() = %+v, want MaxAttempts 5", GetRetry(opts))
+	}
+	if got := len(GetInterceptors(opts)); got != 2 {
+		t.Errorf("GetInterceptors() has %d interceptors, want retry plus 1", got)
+	}
+	if GetRetry(nil) != nil {
+		t.Error("GetRetry(nil) should be nil")
+	}
+}
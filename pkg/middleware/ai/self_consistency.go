@@ -0,0 +1,209 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Aggregator combines several sampled answers to the same prompt into one.
+type Aggregator func(ctx context.Context, prompt []byte, samples [][]byte) ([]byte, error)
+
+// SelfConsistency samples several answers in parallel and aggregates them
+//
+// Input: string prompt/query
+// Output: string AI response chosen or built by the aggregator
+// Behavior: BUFFERED - waits for all samples before writing the aggregated answer
+//
+// Runs the prompt n times concurrently against the client and passes the answers
+// to the aggregator, typically MajorityVote on the extracted final answer or
+// JudgePick. Sampling only helps when answers vary, so the client should use a
+// temperature above zero (the provider defaults do). Failed samples are dropped;
+// the request fails only if every sample fails. An n below 1 is treated as 1,
+// and a nil aggregator defaults to MajorityVote(ExtractAnswer).
+//
+// In a calque.Deterministic run every sample would be the same, so only one is taken.
+//
+// Improves accuracy on reasoning and math tasks at n times the token cost.
+//
+// Example:
+//
+//	agent := ai.SelfConsistency(client, 5, ai.MajorityVote(ai.ExtractAnswer))
+//	flow.Use(agent)
+func SelfConsistency(client Client, n int, aggregator Aggregator, opts ...AgentOption) calque.Handler {
+	if aggregator == nil {
+		aggregator = MajorityVote(ExtractAnswer)
+	}
+	return &selfConsistencyHandler{
+		agent:      Agent(client, opts...),
+		n:          max(n, 1),
+		aggregator: aggregator,
+	}
+}
+
+// selfConsistencyHandler fans a prompt out to n samples of one agent
+type selfConsistencyHandler struct {
+	agent      calque.Handler
+	n          int
+	aggregator Aggregator
+}
+
+func (s *selfConsistencyHandler) ServeFlow(req *calque.Request, res *calque.Response) error {
+	var prompt []byte
+	if err := calque.Read(req, &prompt); err != nil {
+		return err
+	}
+
+	n := s.n
+	if _, ok := calque.DeterministicSeed(req.Context); ok {
+		n = 1
+	}
+
+	results := make([][]byte, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = calque.NewFlow().Use(s.agent).Run(req.Context, prompt, &results[i])
+		}()
+	}
+	wg.Wait()
+
+	samples := make([][]byte, 0, n)
+	for i, err := range errs {
+		if err == nil {
+			samples = append(samples, results[i])
+		}
+	}
+	if len(samples) == 0 {
+		return calque.WrapErr(req.Context, errors.Join(errs...), fmt.Sprintf("all %d samples failed", n))
+	}
+	calque.LogDebug(req.Context, "self-consistency sampled", "samples", len(samples), "failed", n-len(samples))
+
+	answer, err := s.aggregator(req.Context, prompt, samples)
+	if err != nil {
+		return calque.WrapErr(req.Context, err, "failed to aggregate samples")
+	}
+	return calque.Write(res, answer)
+}
+
+// Egress implements calque.Egress with the destinations of the sampled client
+func (s *selfConsistencyHandler) Egress() []string {
+	return calque.EgressOf(s.agent)
+}
+
+// Warmup implements calque.Warmer by warming the sampled client
+func (s *selfConsistencyHandler) Warmup(ctx context.Context) error {
+	return calque.WarmupHandler(ctx, s.agent)
+}
+
+// Shutdown implements calque.Shutdowner by shutting down the sampled client
+func (s *selfConsistencyHandler) Shutdown(ctx context.Context) error {
+	return calque.ShutdownHandler(ctx, s.agent)
+}
+
+// MajorityVote returns the sample whose extracted answer occurs most often.
+//
+// The full text of the first sample with the winning answer is returned, so any
+// reasoning that led to it is kept. Ties go to the answer seen first. A nil
+// extract compares whole answers after trimming and lowercasing.
+//
+// Example:
+//
+//	agg := ai.MajorityVote(ai.ExtractAnswer)
+func MajorityVote(extract func(answer []byte) string) Aggregator {
+	if extract == nil {
+		extract = func(answer []byte) string { return strings.ToLower(strings.TrimSpace(string(answer))) }
+	}
+
+	return func(_ context.Context, _ []byte, samples [][]byte) ([]byte, error) {
+		if len(samples) == 0 {
+			return nil, errors.New("no samples to aggregate")
+		}
+
+		counts := make(map[string]int, len(samples))
+		first := make(map[string]int, len(samples))
+		best := ""
+		for i, sample := range samples {
+			key := extract(sample)
+			if _, seen := first[key]; !seen {
+				first[key] = i
+			}
+			counts[key]++
+			if counts[key] > counts[best] || (counts[key] == counts[best] && first[key] < first[best]) {
+				best = key
+			}
+		}
+		return samples[first[best]], nil
+	}
+}
+
+var (
+	answerLinePattern = regexp.MustCompile(`(?im)\banswer\s*(?:is\b|[:=])\s*(.+?)\s*$`)
+	boxedPattern      = regexp.MustCompile(`\\boxed\{([^}]*)\}`)
+	numberPattern     = regexp.MustCompile(`-?\d[\d,]*(?:\.\d+)?`)
+)
+
+// ExtractAnswer pulls the final answer out of a free-form reasoning response.
+//
+// Looks, in order, for a LaTeX \boxed{...} value, the last "Answer: ..." or
+// "the answer is ..." phrase, and the last number in the text, falling back to
+// the last non-empty line. The result is lowercased and stripped of surrounding
+// punctuation so equivalent answers compare equal.
+func ExtractAnswer(answer []byte) string {
+	text := string(answer)
+
+	var found string
+	switch {
+	case boxedPattern.MatchString(text):
+		matches := boxedPattern.FindAllStringSubmatch(text, -1)
+		found = matches[len(matches)-1][1]
+	case answerLinePattern.MatchString(text):
+		matches := answerLinePattern.FindAllStringSubmatch(text, -1)
+		found = matches[len(matches)-1][1]
+	case numberPattern.MatchString(text):
+		matches := numberPattern.FindAllString(text, -1)
+		found = strings.ReplaceAll(matches[len(matches)-1], ",", "")
+	default:
+		lines := strings.Split(strings.TrimSpace(text), "\n")
+		found = lines[len(lines)-1]
+	}
+
+	return strings.ToLower(strings.Trim(found, " \t\r\n.,;:!*`\"'$"))
+}
+
+// JudgePick returns the sample the judge scores highest.
+//
+// Samples the judge fails to score are skipped; the aggregation fails only if
+// none can be scored. Ties go to the earlier sample.
+//
+// Example:
+//
+//	agent := ai.SelfConsistency(client, 3, ai.JudgePick(ai.LLMJudge(grader)))
+func JudgePick(judge Judge) Aggregator {
+	return func(ctx context.Context, prompt []byte, samples [][]byte) ([]byte, error) {
+		best, bestScore := -1, 0.0
+		var errs []error
+		for i, sample := range samples {
+			score, err := judge(ctx, prompt, sample)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			return nil, errors.Join(append(errs, errors.New("judge could not score any sample"))...)
+		}
+		return samples[best], nil
+	}
+}
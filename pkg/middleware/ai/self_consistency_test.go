@@ -0,0 +1,143 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// sequenceClient answers with the next response on each call and is safe for concurrent use
+type sequenceClient struct {
+	responses []string
+	calls     atomic.Int32
+}
+
+func (c *sequenceClient) Chat(req *calque.Request, res *calque.Response, _ *AgentOptions) error {
+	var input string
+	if err := calque.Read(req, &input); err != nil {
+		return err
+	}
+	response := c.responses[int(c.calls.Add(1)-1)%len(c.responses)]
+	if strings.HasPrefix(response, "error:") {
+		return errors.New(response)
+	}
+	return calque.Write(res, response)
+}
+
+func TestSelfConsistency(t *testing.T) {
+	tests := []struct {
+		name       string
+		responses  []string
+		n          int
+		aggregator Aggregator
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:      "majority on extracted answer",
+			responses: []string{"2+2 is 5. Answer: 5", "Adding gives 4. Answer: 4", "The answer is 4.", "Answer: 4"},
+			n:         4,
+			want:      "4",
+		},
+		{
+			name:      "failed samples dropped",
+			responses: []string{"error: timeout", "Answer: 7", "Answer: 7"},
+			n:         3,
+			want:      "7",
+		},
+		{
+			name:      "all samples failed",
+			responses: []string{"error: down"},
+			n:         2,
+			wantErr:   true,
+		},
+		{
+			name:       "whole answer majority",
+			responses:  []string{"Yes", "no", " yes "},
+			n:          3,
+			aggregator: MajorityVote(nil),
+			want:       "yes",
+		},
+		{
+			name:      "n below one samples once",
+			responses: []string{"only"},
+			n:         0,
+			want:      "only",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &sequenceClient{responses: tt.responses}
+			out, err := runAgentFlow(context.Background(), SelfConsistency(client, tt.n, tt.aggregator), "question")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// Samples complete in any order, so compare the answer rather than the exact text
+			if got := ExtractAnswer([]byte(out)); !tt.wantErr && got != tt.want {
+				t.Errorf("output = %q (answer %q), want answer %q", out, got, tt.want)
+			}
+			if got := int(client.calls.Load()); got != max(tt.n, 1) {
+				t.Errorf("client called %d times, want %d", got, max(tt.n, 1))
+			}
+		})
+	}
+}
+
+func TestSelfConsistencyDeterministic(t *testing.T) {
+	client := &sequenceClient{responses: []string{"Answer: 4", "Answer: 5"}}
+	var out string
+	err := calque.NewFlow().Use(SelfConsistency(client, 5, nil)).Run(context.Background(), "question", &out, calque.Deterministic(1))
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := client.calls.Load(); got != 1 || out != "Answer: 4" {
+		t.Errorf("client called %d times with output %q, want a single sample", got, out)
+	}
+}
+
+func TestJudgePick(t *testing.T) {
+	judge := func(_ context.Context, _, answer []byte) (float64, error) {
+		if string(answer) == "bad" {
+			return 0, errors.New("unscorable")
+		}
+		return float64(len(answer)), nil
+	}
+
+	client := &sequenceClient{responses: []string{"short", "bad", "the longest one", "medium one"}}
+	out, err := runAgentFlow(context.Background(), SelfConsistency(client, 4, JudgePick(judge)), "q")
+	if err != nil || out != "the longest one" {
+		t.Errorf("Run() = %q, %v; want the longest one", out, err)
+	}
+
+	if _, err := JudgePick(judge)(context.Background(), nil, [][]byte{[]byte("bad")}); err == nil {
+		t.Error("expected error when no sample can be scored")
+	}
+}
+
+func TestExtractAnswer(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer string
+		want   string
+	}{
+		{"boxed", `so the result is \boxed{42}.`, "42"},
+		{"answer line", "Step 1...\nStep 2...\nFinal Answer: Paris.", "paris"},
+		{"answer is phrase", "After checking, the answer is **B**", "b"},
+		{"last number", "We have 3 apples and 1,200 pears", "1200"},
+		{"last line", "thinking\nblue", "blue"},
+		{"answering is not an answer line", "Answering requires care\nyes", "yes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractAnswer([]byte(tt.answer)); got != tt.want {
+				t.Errorf("ExtractAnswer(%q) = %q, want %q", tt.answer, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"context"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// OpenTelemetry GenAI semantic convention attributes set on model call spans.
+//
+// LLM observability backends (Langfuse, Arize Phoenix, Datadog LLM
+// Observability) read these to show model, token and tool information.
+const (
+	AttrGenAIOperationName         = "gen_ai.operation.name"
+	AttrGenAIProviderName          = "gen_ai.provider.name"
+	AttrGenAISystem                = "gen_ai.system" // Older name for gen_ai.provider.name, still read by many backends
+	AttrGenAIRequestModel          = "gen_ai.request.model"
+	AttrGenAIRequestTemperature    = "gen_ai.request.temperature"
+	AttrGenAIRequestMaxTokens      = "gen_ai.request.max_tokens"
+	AttrGenAIResponseID            = "gen_ai.response.id"
+	AttrGenAIResponseModel         = "gen_ai.response.model"
+	AttrGenAIResponseFinishReasons = "gen_ai.response.finish_reasons"
+	AttrGenAIUsageInputTokens      = "gen_ai.usage.input_tokens"
+	AttrGenAIUsageOutputTokens     = "gen_ai.usage.output_tokens"
+)
+
+// ChatSpanInfo describes a model call for its span
+type ChatSpanInfo struct {
+	Provider    string // e.g. "openai", "gcp.gemini", "ollama"
+	Model       string // Requested model
+	Temperature *float32
+	MaxTokens   *int
+}
+
+// StartChatSpan starts a "chat {model}" span for one model call.
+//
+// Input: request and the call's provider, model and sampling settings
+// Output: request carrying the span, and the span to end when the call finishes
+// Behavior: no-op unless tracing middleware installed a calque.SpanStarter
+//
+// Provider clients call it at the top of Chat, then record the outcome with
+// SetChatResponse and SetChatUsage on the returned request's context.
+//
+// Example:
+//
+//	r, span := ai.StartChatSpan(r, ai.ChatSpanInfo{Provider: "openai", Model: "gpt-4o"})
+//	defer func() { span.End(err) }()
+func StartChatSpan(r *calque.Request, info ChatSpanInfo) (*calque.Request, calque.Span) {
+	ctx, span := calque.StartSpan(r.Context, "chat "+info.Model)
+	span.SetAttribute(AttrGenAIOperationName, "chat")
+	span.SetAttribute(AttrGenAIProviderName, info.Provider)
+	span.SetAttribute(AttrGenAISystem, info.Provider)
+	span.SetAttribute(AttrGenAIRequestModel, info.Model)
+	if info.Temperature != nil {
+		span.SetAttribute(AttrGenAIRequestTemperature, float64(*info.Temperature))
+	}
+	if info.MaxTokens != nil {
+		span.SetAttribute(AttrGenAIRequestMaxTokens, *info.MaxTokens)
+	}
+	return r.WithContext(ctx), span
+}
+
+// SetChatResponse records the response ID, the model that answered and why
+// generation stopped on the current chat span. Empty values are skipped.
+func SetChatResponse(ctx context.Context, responseID, responseModel string, finishReasons ...string) {
+	if responseID != "" {
+		calque.SetSpanAttribute(ctx, AttrGenAIResponseID, responseID)
+	}
+	if responseModel != "" {
+		calque.SetSpanAttribute(ctx, AttrGenAIResponseModel, responseModel)
+	}
+	var reasons []string
+	for _, reason := range finishReasons {
+		if reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	if len(reasons) > 0 {
+		calque.SetSpanAttribute(ctx, AttrGenAIResponseFinishReasons, reasons)
+	}
+}
+
+// SetChatUsage records token usage on the current chat span
+func SetChatUsage(ctx context.Context, usage *UsageMetadata) {
+	if usage == nil {
+		return
+	}
+	calque.SetSpanAttribute(ctx, AttrGenAIUsageInputTokens, usage.PromptTokens)
+	calque.SetSpanAttribute(ctx, AttrGenAIUsageOutputTokens, usage.CompletionTokens)
+}
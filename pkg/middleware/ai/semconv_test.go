@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+type testSpan struct {
+	name  string
+	attrs map[string]any
+	err   error
+	ended bool
+}
+
+func (s *testSpan) SetAttribute(key string, value any) { s.attrs[key] = value }
+func (s *testSpan) End(err error)                      { s.err, s.ended = err, true }
+
+func withTestSpans(ctx context.Context, spans *[]*testSpan) context.Context {
+	return calque.WithSpanStarter(ctx, func(ctx context.Context, name string) (context.Context, calque.Span) {
+		span := &testSpan{name: name, attrs: map[string]any{}}
+		*spans = append(*spans, span)
+		return ctx, span
+	})
+}
+
+func TestStartChatSpan(t *testing.T) {
+	t.Parallel()
+
+	var spans []*testSpan
+	ctx := withTestSpans(context.Background(), &spans)
+	temperature, maxTokens := float32(0.5), 256
+
+	r, span := StartChatSpan(calque.NewRequest(ctx, strings.NewReader("hi")), ChatSpanInfo{
+		Provider:    "openai",
+		Model:       "gpt-4o",
+		Temperature: &temperature,
+		MaxTokens:   &maxTokens,
+	})
+	SetChatResponse(r.Context, "resp-1", "gpt-4o-2024-08-06", "stop", "")
+	SetChatUsage(r.Context, &UsageMetadata{PromptTokens: 12, CompletionTokens: 30, TotalTokens: 42})
+	span.End(errors.New("boom"))
+
+	if len(spans) != 1 || spans[0].name != "chat gpt-4o" {
+		t.Fatalf("spans = %+v", spans)
+	}
+	want := map[string]any{
+		AttrGenAIOperationName:         "chat",
+		AttrGenAIProviderName:          "openai",
+		AttrGenAISystem:                "openai",
+		AttrGenAIRequestModel:          "gpt-4o",
+		AttrGenAIRequestTemperature:    0.5,
+		AttrGenAIRequestMaxTokens:      256,
+		AttrGenAIResponseID:            "resp-1",
+		AttrGenAIResponseModel:         "gpt-4o-2024-08-06",
+		AttrGenAIResponseFinishReasons: []string{"stop"},
+		AttrGenAIUsageInputTokens:      12,
+		AttrGenAIUsageOutputTokens:     30,
+	}
+	if !reflect.DeepEqual(spans[0].attrs, want) {
+		t.Errorf("attributes = %v, want %v", spans[0].attrs, want)
+	}
+	if !spans[0].ended || spans[0].err == nil {
+		t.Errorf("span not ended with the error: %+v", spans[0])
+	}
+}
+
+func TestStartChatSpanWithoutTracing(t *testing.T) {
+	t.Parallel()
+
+	req := calque.NewRequest(context.Background(), strings.NewReader("hi"))
+	r, span := StartChatSpan(req, ChatSpanInfo{Provider: "ollama", Model: "llama3"})
+	SetChatResponse(r.Context, "", "llama3", "stop")
+	SetChatUsage(r.Context, nil)
+	span.End(nil)
+
+	if r.Context != req.Context {
+		t.Error("StartChatSpan() without tracing should keep the request context")
+	}
+}
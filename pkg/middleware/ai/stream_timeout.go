@@ -0,0 +1,191 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// ErrFirstTokenTimeout is returned when a provider doesn't start streaming within StreamTimeouts.FirstToken
+var ErrFirstTokenTimeout = errors.New("timed out waiting for first token")
+
+// ErrStreamStalled is returned when a provider stops streaming for longer than StreamTimeouts.MaxStall
+var ErrStreamStalled = errors.New("response stream stalled")
+
+// StreamTimeouts bounds how long a provider may keep a streaming response waiting.
+// A zero field disables that check.
+type StreamTimeouts struct {
+	FirstToken time.Duration // Max wait from the request to the first output or reasoning
+	MaxStall   time.Duration // Max gap between chunks once streaming has started
+}
+
+type streamTimeoutsOption struct{ timeouts StreamTimeouts }
+
+func (o streamTimeoutsOption) Apply(opts *AgentOptions) {
+	opts.StreamTimeouts = &o.timeouts
+}
+
+// WithStreamTimeouts aborts responses that are slow to start or stall mid-stream.
+//
+// Input: time-to-first-token limit and maximum gap between chunks
+// Output: AgentOption for configuration
+// Behavior: Cancels the provider call and returns ErrFirstTokenTimeout or ErrStreamStalled
+//
+// ctrl.Timeout bounds the whole call, which has to allow for the longest
+// answer; these limits catch a provider that hangs while still letting long
+// answers finish. Reasoning chunks count as progress, so thinking models are
+// not cut off while they think. The aborted call returns an error, so
+// ctrl.Fallback or ctrl.Retry can take over; output already streamed by the
+// aborted call is not retracted, which Fallback handles by buffering.
+//
+// Example:
+//
+//	flow.Use(ctrl.Fallback(
+//		ai.Agent(primary, ai.WithStreamTimeouts(5*time.Second, 10*time.Second)),
+//		ai.Agent(backup),
+//	))
+func WithStreamTimeouts(firstToken, maxStall time.Duration) AgentOption {
+	return streamTimeoutsOption{timeouts: StreamTimeouts{FirstToken: firstToken, MaxStall: maxStall}}
+}
+
+// streamTimeoutClient enforces StreamTimeouts around another client's Chat
+type streamTimeoutClient struct {
+	Client
+	timeouts StreamTimeouts
+}
+
+// withStreamTimeouts wraps client when timeouts are configured
+func withStreamTimeouts(client Client, timeouts *StreamTimeouts) Client {
+	if timeouts == nil || (timeouts.FirstToken <= 0 && timeouts.MaxStall <= 0) {
+		return client
+	}
+	return &streamTimeoutClient{Client: client, timeouts: *timeouts}
+}
+
+// SupportsStructuredOutput forwards to the wrapped client
+func (c *streamTimeoutClient) SupportsStructuredOutput() bool {
+	return supportsStructuredOutput(c.Client)
+}
+
+// Chat implements Client
+func (c *streamTimeoutClient) Chat(r *calque.Request, w *calque.Response, opts *AgentOptions) error {
+	ctx, cancel := context.WithCancelCause(r.Context)
+	defer cancel(nil)
+
+	watch := &streamWatchdog{w: w.Data, cancel: cancel, timeouts: c.timeouts}
+	watch.arm(c.timeouts.FirstToken, ErrFirstTokenTimeout)
+	defer watch.stop()
+	if opts != nil && opts.Reasoning != nil {
+		opts.Reasoning.setProgress(watch.progress)
+		defer opts.Reasoning.setProgress(nil)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Client.Chat(calque.NewRequest(ctx, r.Data), calque.NewResponse(watch), opts)
+	}()
+
+	// Return on timeout without waiting for clients that ignore cancellation
+	select {
+	case err := <-done:
+		if err == nil {
+			return nil
+		}
+		if timeout := streamTimeoutCause(ctx); timeout != nil {
+			return calque.WrapErr(r.Context, timeout, "stream timeout")
+		}
+		return err
+	case <-ctx.Done():
+		if timeout := streamTimeoutCause(ctx); timeout != nil {
+			calque.LogWarn(r.Context, "aborted provider stream", "reason", timeout)
+			return calque.WrapErr(r.Context, timeout, "stream timeout")
+		}
+		return <-done
+	}
+}
+
+// streamTimeoutCause returns the watchdog's error if it cancelled ctx
+func streamTimeoutCause(ctx context.Context) error {
+	cause := context.Cause(ctx)
+	if errors.Is(cause, ErrFirstTokenTimeout) || errors.Is(cause, ErrStreamStalled) {
+		return cause
+	}
+	return nil
+}
+
+// streamWatchdog passes writes through and cancels the call when the stream goes quiet
+type streamWatchdog struct {
+	w        io.Writer
+	cancel   context.CancelCauseFunc
+	timeouts StreamTimeouts
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	gen     int // invalidates timers that fire while being replaced
+	aborted bool
+}
+
+// arm (re)starts the timer, disabling it for a non-positive limit. Callers hold mu
+// once the call has started.
+func (s *streamWatchdog) arm(limit time.Duration, cause error) {
+	s.gen++
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if limit <= 0 {
+		return
+	}
+	gen := s.gen
+	s.timer = time.AfterFunc(limit, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if gen != s.gen || s.aborted {
+			return
+		}
+		s.aborted = true
+		s.cancel(fmt.Errorf("%w after %v", cause, limit))
+	})
+}
+
+// progress records streaming activity and restarts the stall timer
+func (s *streamWatchdog) progress() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.touch()
+}
+
+func (s *streamWatchdog) touch() {
+	if !s.aborted {
+		s.arm(s.timeouts.MaxStall, ErrStreamStalled)
+	}
+}
+
+// stop disarms the timer and rejects further writes
+func (s *streamWatchdog) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.aborted = true
+	s.arm(0, nil)
+}
+
+// Write implements io.Writer, rejecting output that arrives after an abort
+func (s *streamWatchdog) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.aborted {
+		return 0, io.ErrClosedPipe
+	}
+	if len(p) == 0 {
+		return s.w.Write(p)
+	}
+	s.touch()
+	n, err := s.w.Write(p)
+	s.touch() // time blocked on a slow reader isn't a provider stall
+	return n, err
+}
@@ -0,0 +1,131 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+// chunkClient streams chunks with a pause before each; reasoning chunks go to the trace
+type chunkClient struct {
+	chunks []string
+	pauses []time.Duration
+	// ignoreCancel keeps streaming after the context is cancelled
+	ignoreCancel bool
+}
+
+func (c *chunkClient) Chat(r *calque.Request, w *calque.Response, opts *AgentOptions) error {
+	if _, err := io.ReadAll(r.Data); err != nil {
+		return err
+	}
+	for i, chunk := range c.chunks {
+		select {
+		case <-time.After(c.pauses[i]):
+		case <-r.Context.Done():
+			if !c.ignoreCancel {
+				return r.Context.Err()
+			}
+		}
+		if text, ok := strings.CutPrefix(chunk, "think:"); ok {
+			if err := opts.Reasoning.Append(text); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := io.WriteString(w.Data, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestWithStreamTimeouts(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name    string
+		client  *chunkClient
+		opts    []AgentOption
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "fast stream passes",
+			client: &chunkClient{chunks: []string{"a", "b", "c"}, pauses: []time.Duration{5 * ms, 5 * ms, 5 * ms}},
+			want:   "abc",
+		},
+		{
+			name:    "slow first token",
+			client:  &chunkClient{chunks: []string{"a"}, pauses: []time.Duration{time.Second}},
+			wantErr: ErrFirstTokenTimeout,
+		},
+		{
+			name:    "stall mid-stream",
+			client:  &chunkClient{chunks: []string{"a", "b"}, pauses: []time.Duration{0, time.Second}},
+			wantErr: ErrStreamStalled,
+		},
+		{
+			name:    "client ignoring cancellation",
+			client:  &chunkClient{chunks: []string{"a", "b"}, pauses: []time.Duration{0, 300 * ms}, ignoreCancel: true},
+			wantErr: ErrStreamStalled,
+		},
+		{
+			name: "reasoning counts as progress",
+			client: &chunkClient{
+				chunks: []string{"think:hmm\n", "think:ok\n", "answer"},
+				pauses: []time.Duration{30 * ms, 30 * ms, 30 * ms},
+			},
+			opts: []AgentOption{WithReasoning(ReasoningConfig{})},
+			want: "answer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]AgentOption{WithStreamTimeouts(50*ms, 50*ms)}, tt.opts...)
+
+			start := time.Now()
+			out, err := runAgentFlow(context.Background(), Agent(tt.client, opts...), "q")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Run() error = %v, want %v", err, tt.wantErr)
+				}
+				if elapsed := time.Since(start); elapsed > 250*ms {
+					t.Errorf("aborted after %v, want about 50ms", elapsed)
+				}
+				return
+			}
+			if err != nil || out != tt.want {
+				t.Errorf("Run() = %q, %v; want %q", out, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithStreamTimeouts_FallbackTakesOver(t *testing.T) {
+	hung := &chunkClient{chunks: []string{"never"}, pauses: []time.Duration{time.Minute}}
+	flow := ctrl.Fallback(
+		Agent(hung, WithStreamTimeouts(20*time.Millisecond, 0)),
+		Agent(NewMockClient("backup answer").WithStreamDelay(0)),
+	)
+
+	out, err := runAgentFlow(context.Background(), flow, "q")
+	if err != nil || out != "backup answer" {
+		t.Errorf("Run() = %q, %v; want the backup answer", out, err)
+	}
+}
+
+func TestWithStreamTimeouts_Disabled(t *testing.T) {
+	client := NewMockClient("x")
+	if got := withStreamTimeouts(client, &StreamTimeouts{}); got != client {
+		t.Error("zero timeouts should not wrap the client")
+	}
+	if got := withStreamTimeouts(client, nil); got != client {
+		t.Error("nil timeouts should not wrap the client")
+	}
+}
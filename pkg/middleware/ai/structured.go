@@ -0,0 +1,296 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/convert"
+)
+
+// StructuredOutputCapable is implemented by clients whose provider enforces
+// response schemas during decoding.
+//
+// Agents with a strict schema send it to these clients as native request
+// parameters. Other clients get the schema embedded in the prompt instead.
+//
+// Example:
+//
+//	if c, ok := client.(ai.StructuredOutputCapable); ok && c.SupportsStructuredOutput() {
+//		// schema is enforced by the provider
+//	}
+type StructuredOutputCapable interface {
+	// SupportsStructuredOutput reports whether response schemas are enforced natively
+	SupportsStructuredOutput() bool
+}
+
+// ErrSchemaNotStrict is returned by StrictJSONSchema for schemas that constrained decoding cannot express
+var ErrSchemaNotStrict = errors.New("schema not supported in strict mode")
+
+// WithStrictSchema adds a response schema the provider must enforce.
+//
+// Accepts the same sources as WithSchema. Providers with constrained decoding
+// (OpenAI json_schema strict, Gemini responseJsonSchema, Ollama format) receive
+// the schema as a request parameter, rewritten where needed to fit the
+// provider's strict subset. When a schema can't be expressed strictly the
+// provider falls back to its non-strict schema mode, and clients without
+// native support get the schema appended to the prompt.
+//
+// Example:
+//
+//	agent := ai.Agent(client, ai.WithStrictSchema(&Invoice{}))
+func WithStrictSchema(schemaSource any) AgentOption {
+	opt := WithSchema(schemaSource).(schemaOption)
+	return schemaOption{schema: strictFormat(opt.schema)}
+}
+
+// WithStrictSchemaFor is the generic form of WithStrictSchema.
+//
+// Example: ai.WithStrictSchemaFor[Invoice]()
+func WithStrictSchemaFor[T any]() AgentOption {
+	opt := WithSchemaFor[T]().(schemaOption)
+	return schemaOption{schema: strictFormat(opt.schema)}
+}
+
+func strictFormat(format *ResponseFormat) *ResponseFormat {
+	if format == nil {
+		return nil
+	}
+	strict := *format
+	strict.Strict = true
+	return &strict
+}
+
+// SchemaOneOf builds a response schema for a discriminated union of struct types.
+//
+// Each variant becomes an object with a required "type" property fixed to its
+// convert.UnionTag, so the model must say which variant it chose. Decode the
+// response with convert.FromJSONUnion using the same variants. Variants are
+// combined with anyOf, which providers support more widely than oneOf and is
+// equivalent here because the tags are distinct. Strict mode requires an
+// object at the root, so union schemas are sent without strict enforcement.
+//
+// Example:
+//
+//	agent := ai.Agent(client, ai.WithSchema(ai.SchemaOneOf(&ToolPlan{}, &FinalAnswer{})))
+//
+//	var step any
+//	err := flow.Use(agent).Run(ctx, task, convert.FromJSONUnion(&step, &ToolPlan{}, &FinalAnswer{}))
+func SchemaOneOf(variants ...any) *ResponseFormat {
+	root := &jsonschema.Schema{Version: jsonschema.Version, Definitions: jsonschema.Definitions{}}
+	for _, variant := range variants {
+		reflector := jsonschema.Reflector{ExpandedStruct: true}
+		schema := reflector.Reflect(variant)
+		for name, def := range schema.Definitions {
+			root.Definitions[name] = def
+		}
+		schema.Version = ""
+		schema.ID = ""
+		schema.Definitions = nil
+
+		tag := convert.UnionTag(variant)
+		props := orderedmap.New[string, *jsonschema.Schema]()
+		props.Set(convert.UnionDiscriminator, &jsonschema.Schema{Type: "string", Const: tag})
+		if schema.Properties != nil {
+			for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+				if pair.Key != convert.UnionDiscriminator {
+					props.Set(pair.Key, pair.Value)
+				}
+			}
+		}
+		schema.Properties = props
+		required := []string{convert.UnionDiscriminator}
+		for _, name := range schema.Required {
+			if name != convert.UnionDiscriminator {
+				required = append(required, name)
+			}
+		}
+		schema.Required = required
+
+		root.AnyOf = append(root.AnyOf, schema)
+	}
+	if len(root.Definitions) == 0 {
+		root.Definitions = nil
+	}
+	return &ResponseFormat{Type: "json_schema", Schema: root}
+}
+
+// StrictJSONSchema rewrites a schema into the subset accepted by strict
+// constrained decoding, as used by OpenAI's json_schema strict mode.
+//
+// Every object gets additionalProperties false and lists all of its
+// properties as required; properties that were optional become nullable
+// instead. A root $ref is inlined, since the root must be an object, and
+// oneOf becomes anyOf. Schemas with open maps or patternProperties return
+// ErrSchemaNotStrict.
+//
+// Example:
+//
+//	schema, err := ai.StrictJSONSchema(format.Schema)
+//	if err != nil {
+//		// send the schema without strict enforcement
+//	}
+func StrictJSONSchema(schema *jsonschema.Schema) (map[string]any, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var root map[string]any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	delete(root, "$schema")
+	delete(root, "$id")
+	if ref, ok := root["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/$defs/")
+		defs, _ := root["$defs"].(map[string]any)
+		def, ok := defs[name].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: unresolved root $ref %q", ErrSchemaNotStrict, ref)
+		}
+		delete(root, "$ref")
+		delete(defs, name)
+		for k, v := range def {
+			root[k] = v
+		}
+		// Recursive types still point at the definition, so keep an independent copy
+		if rest, _ := json.Marshal(root); strings.Contains(string(rest), `"`+ref+`"`) {
+			copied, err := cloneJSON(def)
+			if err != nil {
+				return nil, err
+			}
+			defs[name] = copied
+		}
+		if len(defs) == 0 {
+			delete(root, "$defs")
+		}
+	}
+	if root["type"] != "object" {
+		return nil, fmt.Errorf("%w: root must be an object", ErrSchemaNotStrict)
+	}
+
+	if err := strictNode(root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// strictNode applies the strict rewrite to one schema node and its children
+func strictNode(node map[string]any) error {
+	if _, ok := node["patternProperties"]; ok {
+		return fmt.Errorf("%w: patternProperties", ErrSchemaNotStrict)
+	}
+	if oneOf, ok := node["oneOf"]; ok {
+		node["anyOf"] = oneOf
+		delete(node, "oneOf")
+	}
+
+	if props, ok := node["properties"].(map[string]any); ok {
+		if extra, ok := node["additionalProperties"]; ok && extra != false {
+			return fmt.Errorf("%w: object allows additional properties", ErrSchemaNotStrict)
+		}
+		node["additionalProperties"] = false
+
+		var required []string
+		if list, ok := node["required"].([]any); ok {
+			for _, name := range list {
+				if s, ok := name.(string); ok {
+					required = append(required, s)
+				}
+			}
+		}
+		names := make([]string, 0, len(props))
+		for name, prop := range props {
+			names = append(names, name)
+			child, ok := prop.(map[string]any)
+			if !ok {
+				continue
+			}
+			if err := strictNode(child); err != nil {
+				return err
+			}
+			if !slices.Contains(required, name) {
+				props[name] = nullable(child)
+			}
+		}
+		slices.Sort(names)
+		node["required"] = names
+	} else if node["type"] == "object" {
+		if _, ok := node["$ref"]; !ok {
+			return fmt.Errorf("%w: object without fixed properties", ErrSchemaNotStrict)
+		}
+	}
+
+	if items, ok := node["items"].(map[string]any); ok {
+		if err := strictNode(items); err != nil {
+			return err
+		}
+	}
+	if defs, ok := node["$defs"].(map[string]any); ok {
+		for _, def := range defs {
+			if child, ok := def.(map[string]any); ok {
+				if err := strictNode(child); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, key := range []string{"anyOf", "allOf"} {
+		list, _ := node[key].([]any)
+		for _, item := range list {
+			if child, ok := item.(map[string]any); ok {
+				if err := strictNode(child); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// cloneJSON deep-copies a decoded JSON object
+func cloneJSON(v map[string]any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	return out, json.Unmarshal(data, &out)
+}
+
+// nullable widens a schema to also accept null
+func nullable(node map[string]any) map[string]any {
+	if t, ok := node["type"].(string); ok && node["$ref"] == nil {
+		node["type"] = []any{t, "null"}
+		return node
+	}
+	return map[string]any{"anyOf": []any{node, map[string]any{"type": "null"}}}
+}
+
+// supportsStructuredOutput reports whether client enforces schemas natively
+func supportsStructuredOutput(client Client) bool {
+	c, ok := client.(StructuredOutputCapable)
+	return ok && c.SupportsStructuredOutput()
+}
+
+// withSchemaInstructions appends the response schema to the request prompt
+func withSchemaInstructions(r *calque.Request, format *ResponseFormat) (*calque.Request, error) {
+	if format.Schema == nil {
+		return calque.NewRequest(r.Context, io.MultiReader(r.Data,
+			strings.NewReader("\n\nRespond only with a valid JSON object."))), nil
+	}
+	schema, err := json.MarshalIndent(format.Schema, "", "  ")
+	if err != nil {
+		return nil, calque.WrapErr(r.Context, err, "failed to encode response schema")
+	}
+	instructions := "\n\nRespond only with JSON that matches this schema:\n" + string(schema)
+	return calque.NewRequest(r.Context, io.MultiReader(r.Data, strings.NewReader(instructions))), nil
+}
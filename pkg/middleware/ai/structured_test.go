@@ -0,0 +1,223 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/invopop/jsonschema"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+type strictAddress struct {
+	City string `json:"city"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type strictInvoice struct {
+	ID      string         `json:"id"`
+	Total   float64        `json:"total"`
+	Note    string         `json:"note,omitempty"`
+	Address *strictAddress `json:"address,omitempty"`
+	Lines   []string       `json:"lines"`
+}
+
+type strictOpenMap struct {
+	Labels map[string]string `json:"labels"`
+}
+
+func reflectSchema(v any) *jsonschema.Schema {
+	return (&jsonschema.Reflector{}).Reflect(v)
+}
+
+func TestStrictJSONSchema(t *testing.T) {
+	schema, err := StrictJSONSchema(reflectSchema(&strictInvoice{}))
+	if err != nil {
+		t.Fatalf("StrictJSONSchema() error = %v", err)
+	}
+
+	if _, ok := schema["$ref"]; ok {
+		t.Error("root $ref should be inlined")
+	}
+	if _, ok := schema["$schema"]; ok {
+		t.Error("$schema should be dropped")
+	}
+	if schema["type"] != "object" || schema["additionalProperties"] != false {
+		t.Errorf("root = type %v, additionalProperties %v", schema["type"], schema["additionalProperties"])
+	}
+	wantRequired := []string{"address", "id", "lines", "note", "total"}
+	if !reflect.DeepEqual(schema["required"], wantRequired) {
+		t.Errorf("required = %v, want %v", schema["required"], wantRequired)
+	}
+
+	props := schema["properties"].(map[string]any)
+	if got := props["note"].(map[string]any)["type"]; !reflect.DeepEqual(got, []any{"string", "null"}) {
+		t.Errorf("optional note type = %v, want [string null]", got)
+	}
+	if got := props["id"].(map[string]any)["type"]; got != "string" {
+		t.Errorf("required id type = %v, want string", got)
+	}
+	if _, ok := props["address"].(map[string]any)["anyOf"]; !ok {
+		t.Errorf("optional $ref property should be wrapped in anyOf, got %v", props["address"])
+	}
+
+	address := schema["$defs"].(map[string]any)["strictAddress"].(map[string]any)
+	if !reflect.DeepEqual(address["required"], []string{"city", "zip"}) {
+		t.Errorf("nested required = %v", address["required"])
+	}
+	if address["additionalProperties"] != false {
+		t.Error("nested object should forbid additional properties")
+	}
+
+	// The result must stay valid JSON
+	if _, err := json.Marshal(schema); err != nil {
+		t.Fatalf("marshal strict schema: %v", err)
+	}
+}
+
+func TestStrictJSONSchema_Unsupported(t *testing.T) {
+	if _, err := StrictJSONSchema(reflectSchema(&strictOpenMap{})); !errors.Is(err, ErrSchemaNotStrict) {
+		t.Errorf("open map error = %v, want ErrSchemaNotStrict", err)
+	}
+	if _, err := StrictJSONSchema(&jsonschema.Schema{Type: "array"}); !errors.Is(err, ErrSchemaNotStrict) {
+		t.Errorf("array root error = %v, want ErrSchemaNotStrict", err)
+	}
+}
+
+func TestWithStrictSchema(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  AgentOption
+	}{
+		{"from value", WithStrictSchema(&strictInvoice{})},
+		{"generic", WithStrictSchemaFor[strictInvoice]()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &AgentOptions{}
+			tt.opt.Apply(opts)
+			if opts.Schema == nil || !opts.Schema.Strict || opts.Schema.Type != "json_schema" || opts.Schema.Schema == nil {
+				t.Errorf("Schema = %+v, want strict json_schema", opts.Schema)
+			}
+		})
+	}
+
+	// The source format is left untouched
+	format := &ResponseFormat{Type: "json_object"}
+	opts := &AgentOptions{}
+	WithStrictSchema(format).Apply(opts)
+	if format.Strict || !opts.Schema.Strict {
+		t.Error("WithStrictSchema should copy the format before marking it strict")
+	}
+}
+
+// promptRecorder records the prompt it receives
+type promptRecorder struct {
+	native bool
+	prompt string
+}
+
+func (p *promptRecorder) Chat(r *calque.Request, w *calque.Response, _ *AgentOptions) error {
+	data, err := io.ReadAll(r.Data)
+	if err != nil {
+		return err
+	}
+	p.prompt = string(data)
+	return calque.Write(w, `{"id":"1"}`)
+}
+
+func (p *promptRecorder) SupportsStructuredOutput() bool { return p.native }
+
+func TestAgent_StrictSchemaFallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		native     bool
+		opt        AgentOption
+		wantSchema bool
+	}{
+		{name: "native client gets prompt unchanged", native: true, opt: WithStrictSchema(&strictInvoice{})},
+		{name: "other clients get schema in prompt", opt: WithStrictSchema(&strictInvoice{}), wantSchema: true},
+		{name: "non-strict schema not embedded", opt: WithSchema(&strictInvoice{})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &promptRecorder{native: tt.native}
+			var out string
+			if err := calque.NewFlow().Use(Agent(client, tt.opt)).Run(context.Background(), "make an invoice", &out); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !strings.HasPrefix(client.prompt, "make an invoice") {
+				t.Errorf("prompt = %q, want original input first", client.prompt)
+			}
+			embedded := strings.Contains(client.prompt, "matches this schema") && strings.Contains(client.prompt, `"total"`)
+			if embedded != tt.wantSchema {
+				t.Errorf("schema embedded = %v, want %v; prompt %q", embedded, tt.wantSchema, client.prompt)
+			}
+		})
+	}
+}
+
+type unionPlan struct {
+	Calls []string `json:"calls"`
+}
+
+type unionAnswer struct {
+	Type string `json:"type"` // Overridden by the discriminator
+	Text string `json:"text"`
+}
+
+func TestSchemaOneOf(t *testing.T) {
+	format := SchemaOneOf(&unionPlan{}, unionAnswer{})
+	if format.Type != "json_schema" || format.Strict {
+		t.Fatalf("format = %+v, want non-strict json_schema", format)
+	}
+	if len(format.Schema.AnyOf) != 2 {
+		t.Fatalf("anyOf has %d variants, want 2", len(format.Schema.AnyOf))
+	}
+
+	for i, wantTag := range []string{"union_plan", "union_answer"} {
+		variant := format.Schema.AnyOf[i]
+		if variant.Type != "object" {
+			t.Errorf("variant %d type = %q, want object", i, variant.Type)
+		}
+		first := variant.Properties.Oldest()
+		if first.Key != "type" || first.Value.Const != wantTag {
+			t.Errorf("variant %d discriminator = %s %v, want type %q", i, first.Key, first.Value.Const, wantTag)
+		}
+		if variant.Required[0] != "type" || strings.Count(strings.Join(variant.Required, ","), "type") != 1 {
+			t.Errorf("variant %d required = %v", i, variant.Required)
+		}
+		if variant.Properties.Len() != 2 {
+			t.Errorf("variant %d has %d properties, want 2", i, variant.Properties.Len())
+		}
+	}
+
+	if _, err := json.Marshal(format.Schema); err != nil {
+		t.Fatalf("marshal union schema: %v", err)
+	}
+	if _, err := StrictJSONSchema(format.Schema); !errors.Is(err, ErrSchemaNotStrict) {
+		t.Errorf("StrictJSONSchema(union) error = %v, want ErrSchemaNotStrict", err)
+	}
+}
+
+func TestSchemaOneOf_MockRoundTrip(t *testing.T) {
+	client := NewMockClient("").WithJSONMode(true).WithStreamDelay(0)
+	agent := Agent(client, WithSchema(SchemaOneOf(&unionPlan{}, &unionAnswer{})))
+
+	var out string
+	if err := calque.NewFlow().Use(agent).Run(context.Background(), "plan it", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("output %q is not JSON: %v", out, err)
+	}
+	if decoded["type"] != "union_plan" {
+		t.Errorf("type = %v, want union_plan", decoded["type"])
+	}
+}
@@ -0,0 +1,154 @@
+package ai
+
+import (
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+// TokenEstimator estimates the tokens a model sees and knows the model's context window.
+//
+// Provider clients return one from TokenEstimator(). Counts are estimates
+// from tokenizer.Conservative, which errs high, unless the provider config
+// sets Tokenizer to the model's own tokenizer. Pass it to ctrl.TokenBudget to
+// reject or truncate input before it reaches the provider.
+//
+// Example:
+//
+//	client, _ := openai.New("gpt-4o")
+//	flow.Use(ctrl.TokenBudget(client.TokenEstimator(), 0)).Use(ai.Agent(client))
+type TokenEstimator interface {
+	tokenizer.Tokenizer
+
+	// ContextWindow is the model's maximum input plus output tokens, or 0 if unknown
+	ContextWindow() int
+}
+
+// TokenEstimatorProvider is implemented by clients that can estimate tokens for their model
+type TokenEstimatorProvider interface {
+	TokenEstimator() TokenEstimator
+}
+
+// modelEstimator pairs a tokenizer with a context window
+type modelEstimator struct {
+	tokenizer.Tokenizer
+	window int
+}
+
+func (m modelEstimator) ContextWindow() int { return m.window }
+
+// NewTokenEstimator creates a TokenEstimator for model.
+//
+// Input: model name as sent to the provider, tokenizer (nil for tokenizer.Conservative)
+// Output: TokenEstimator with the model's context window from ContextWindow
+// Behavior: Counting is delegated to t
+//
+// The module ships no model vocabularies, so counts are only exact when t is
+// the model's tokenizer, such as a tiktoken encoding for OpenAI models or a
+// SentencePiece model for Gemini and Llama, adapted with tokenizer.Func.
+// Providers take one through their Config.Tokenizer field.
+//
+// Example:
+//
+//	enc, _ := tiktoken.EncodingForModel("gpt-4o")
+//	estimator := ai.NewTokenEstimator("gpt-4o", tokenizer.Func(func(text []byte) int {
+//		return len(enc.Encode(string(text), nil, nil))
+//	}))
+func NewTokenEstimator(model string, t tokenizer.Tokenizer) TokenEstimator {
+	return NewTokenEstimatorWithWindow(t, ContextWindow(model))
+}
+
+// NewTokenEstimatorWithWindow creates a TokenEstimator with an explicit context window,
+// e.g. a self-hosted model served with a smaller context than it supports.
+func NewTokenEstimatorWithWindow(t tokenizer.Tokenizer, window int) TokenEstimator {
+	if t == nil {
+		t = tokenizer.Conservative
+	}
+	return modelEstimator{Tokenizer: t, window: window}
+}
+
+// contextWindows maps model name prefixes to context windows. More specific
+// prefixes come first.
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	// OpenAI
+	{"gpt-4.1", 1047576},
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"gpt-5", 400000},
+	{"o1-mini", 128000},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"o4", 200000},
+
+	// Anthropic (direct or on Bedrock)
+	{"claude", 200000},
+
+	// Google
+	{"gemini-1.5-pro", 2097152},
+	{"gemini-1.5", 1048576},
+	{"gemini-2", 1048576},
+	{"gemma3", 131072},
+	{"gemma2", 8192},
+
+	// Open models, by Ollama tag or Bedrock model ID
+	{"llama3.1", 131072},
+	{"llama3.2", 131072},
+	{"llama3.3", 131072},
+	{"llama3-1", 131072},
+	{"llama3-2", 131072},
+	{"llama3-3", 131072},
+	{"llama3", 8192},
+	{"mistral-large", 131072},
+	{"mistral-nemo", 131072},
+	{"mistral", 32768},
+	{"mixtral", 32768},
+	{"qwen2.5", 32768},
+	{"qwen3", 40960},
+	{"deepseek-r1", 131072},
+	{"nova-pro", 300000},
+	{"nova-lite", 300000},
+	{"nova-micro", 128000},
+}
+
+// bedrockVendors prefix Bedrock model IDs, e.g. "anthropic.claude-3-5-sonnet-20240620-v1:0"
+var bedrockVendors = []string{"anthropic.", "meta.", "mistral.", "amazon.", "cohere.", "ai21.", "deepseek."}
+
+// ContextWindow returns the context window of a known model, or 0.
+//
+// Names are matched by prefix, so dated and tagged variants such as
+// "gpt-4o-2024-08-06" or "llama3.1:8b" resolve to their family. Gemini
+// "models/" names and Bedrock model IDs, with or without a cross-region
+// inference profile prefix, are recognised too.
+func ContextWindow(model string) int {
+	name := normalizeModelName(model)
+	for _, entry := range contextWindows {
+		if strings.HasPrefix(name, entry.prefix) {
+			return entry.tokens
+		}
+	}
+	return 0
+}
+
+// normalizeModelName strips provider-specific decoration from a model name
+func normalizeModelName(model string) string {
+	name := strings.ToLower(strings.TrimSpace(model))
+	name = strings.TrimPrefix(name, "models/")
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		name = name[idx+1:] // Bedrock ARNs and namespaced Ollama tags
+	}
+	for _, region := range []string{"us.", "eu.", "apac.", "global."} {
+		name = strings.TrimPrefix(name, region)
+	}
+	for _, vendor := range bedrockVendors {
+		if strings.HasPrefix(name, vendor) {
+			return name[len(vendor):]
+		}
+	}
+	return name
+}
@@ -0,0 +1,53 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+func TestContextWindow(t *testing.T) {
+	tests := map[string]int{
+		"gpt-4o":                    128000,
+		"gpt-4o-mini-2024-07-18":    128000,
+		"gpt-4.1-nano":              1047576,
+		"gpt-4":                     8192,
+		"o3-mini":                   200000,
+		"models/gemini-1.5-pro-002": 2097152,
+		"gemini-2.5-flash":          1048576,
+		"llama3.1:8b":               131072,
+		"llama3:latest":             8192,
+		"anthropic.claude-3-5-sonnet-20240620-v1:0": 200000,
+		"us.meta.llama3-1-70b-instruct-v1:0":        131072,
+		"arn:aws:bedrock:us-east-1:123456789012:inference-profile/us.anthropic.claude-3-haiku-20240307-v1:0": 200000,
+		"my-finetune": 0,
+		"":            0,
+	}
+	for model, want := range tests {
+		if got := ContextWindow(model); got != want {
+			t.Errorf("ContextWindow(%q) = %d, want %d", model, got, want)
+		}
+	}
+}
+
+func TestNewTokenEstimator(t *testing.T) {
+	counter := NewTokenEstimator("gpt-4o", nil)
+	if counter.ContextWindow() != 128000 {
+		t.Errorf("ContextWindow() = %d, want 128000", counter.ContextWindow())
+	}
+	if got := counter.CountTokens([]byte("abcdefgh")); got != 2 {
+		t.Errorf("nil tokenizer counted %d, want conservative 2", got)
+	}
+	if got := counter.CountTokens([]byte("東京都")); got != 3 {
+		t.Errorf("nil tokenizer counted %d CJK tokens, want one per character", got)
+	}
+
+	perByte := tokenizer.Func(func(text []byte) int { return len(text) })
+	counter = NewTokenEstimatorWithWindow(perByte, 4096)
+	if counter.ContextWindow() != 4096 {
+		t.Errorf("ContextWindow() = %d, want 4096", counter.ContextWindow())
+	}
+	if got := counter.CountTokens([]byte("abcdefgh")); got != 8 {
+		t.Errorf("custom tokenizer counted %d, want 8", got)
+	}
+}
@@ -0,0 +1,488 @@
+// Package analytics emits anonymized product analytics events for flows.
+//
+// Events describe how an agent was used (who, anonymously; what kind of request;
+// which route; how long it took; how many tokens it cost; whether the user was
+// satisfied) without ever containing prompts or responses, so product teams can
+// analyze usage in Segment, PostHog or ClickHouse without access to transcripts.
+//
+// Example:
+//
+//	tracker := analytics.New(analytics.Segment(os.Getenv("SEGMENT_WRITE_KEY")), &analytics.Config{
+//		Salt: os.Getenv("ANALYTICS_SALT"),
+//	})
+//	defer tracker.Shutdown(ctx)
+//
+//	flow.Use(tracker.Track("support_agent", ai.Agent(client)))
+package analytics
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Event is a single anonymized analytics event.
+//
+// Events never carry prompt or response content: identities are keyed hashes
+// and payloads are reduced to sizes and token counts.
+type Event struct {
+	ID           string         `json:"id"`                     // Unique event ID, usable for de-duplication
+	Name         string         `json:"event"`                  // Event name, e.g. "support_agent" or "feedback"
+	RequestID    string         `json:"request_id"`             // Links feedback to the request it rates
+	AnonymousID  string         `json:"anonymous_id,omitempty"` // Keyed hash of the user ID
+	TenantID     string         `json:"tenant_id,omitempty"`    // Keyed hash of the tenant
+	Intent       string         `json:"intent,omitempty"`       // Intent label from SetIntent or the classifier
+	Route        string         `json:"route,omitempty"`        // Route taken, from SetRoute
+	LatencyMs    int64          `json:"latency_ms"`             // Handler duration in milliseconds
+	InputBytes   int64          `json:"input_bytes"`            // Size of the request payload
+	OutputBytes  int64          `json:"output_bytes"`           // Size of the response payload
+	InputTokens  int            `json:"input_tokens,omitempty"` // Prompt tokens reported with RecordTokens
+	OutputTokens int            `json:"output_tokens,omitempty"`
+	Success      bool           `json:"success"`
+	ErrorType    string         `json:"error_type,omitempty"`   // "timeout", "canceled" or "error"
+	Satisfaction *float64       `json:"satisfaction,omitempty"` // Feedback score, set on feedback events
+	Properties   map[string]any `json:"properties,omitempty"`   // Extra properties from SetProperty
+	Timestamp    time.Time      `json:"timestamp"`
+}
+
+// Sink delivers batches of events to an analytics backend.
+type Sink interface {
+	Send(ctx context.Context, events []Event) error
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(ctx context.Context, events []Event) error
+
+// Send calls f(ctx, events)
+func (f SinkFunc) Send(ctx context.Context, events []Event) error { return f(ctx, events) }
+
+// Config holds configuration for a Tracker
+type Config struct {
+	// Salt keys the user and tenant hashes; keep it secret so IDs cannot be reversed
+	// by hashing candidate IDs (default: random per process, so hashes change on restart)
+	Salt string
+	// Intent labels a request from the start of its input when no intent was set with SetIntent
+	Intent func(ctx context.Context, head []byte) string
+	// IntentBytes is how much input is passed to Intent (default: 4096)
+	IntentBytes int
+	// BatchSize is the number of events sent per request to the sink (default: 50)
+	BatchSize int
+	// FlushInterval is the longest an event waits before being sent (default: 5s)
+	FlushInterval time.Duration
+	// BufferSize is the number of events queued before new ones are dropped (default: 1000)
+	BufferSize int
+	// OnError is called when the sink fails to deliver a batch
+	OnError func(err error, events []Event)
+}
+
+// Tracker records events for tracked handlers and ships them to a sink in batches.
+//
+// Events are queued without blocking the request path; when the queue is full new
+// events are dropped and counted in Dropped. Call Shutdown to flush on exit.
+type Tracker struct {
+	sink   Sink
+	config Config
+
+	events  chan Event
+	flush   chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+	dropped atomic.Int64
+}
+
+// New creates a Tracker sending events to sink.
+//
+// Example:
+//
+//	tracker := analytics.New(analytics.PostHog(apiKey), &analytics.Config{Salt: salt})
+func New(sink Sink, config *Config) *Tracker {
+	cfg := Config{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Salt == "" {
+		cfg.Salt = newID()
+	}
+	if cfg.IntentBytes <= 0 {
+		cfg.IntentBytes = 4096
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1000
+	}
+
+	t := &Tracker{
+		sink:    sink,
+		config:  cfg,
+		events:  make(chan Event, cfg.BufferSize),
+		flush:   make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// Track wraps a handler, emitting one event named name per request
+//
+// Input: any data type (streaming - passed to the handler)
+// Output: handler output
+// Behavior: STREAMING - counts bytes as data flows, emits the event when the handler returns
+//
+// The event carries the hashed user and tenant from calque.WithUser and
+// calque.WithTenant, the request ID from calque.WithRequestID (generated if
+// absent), latency, payload sizes and outcome. Handlers inside can enrich it
+// with SetIntent, SetRoute, SetProperty and RecordTokens. Content is never
+// recorded; only the first IntentBytes are shown to the intent classifier.
+//
+// Example:
+//
+//	router := ai.CostRouterWithConfig(&ai.CostRouterConfig{
+//		Rules:   rules,
+//		OnRoute: func(ctx context.Context, d ai.RouteDecision) { analytics.SetRoute(ctx, d.Rule) },
+//	}, small, large)
+//	flow.Use(tracker.Track("assistant", router))
+func (t *Tracker) Track(name string, handler calque.Handler) calque.Handler {
+	return &trackedHandler{tracker: t, name: name, handler: handler}
+}
+
+// Feedback records a satisfaction signal for an earlier request.
+//
+// Score is application defined (e.g. 1 for thumbs up, 0 for thumbs down, or a
+// 1-5 rating); requestID is the ID the request ran with.
+//
+// Example:
+//
+//	tracker.Feedback(ctx, requestID, 1, map[string]any{"source": "thumbs"})
+func (t *Tracker) Feedback(ctx context.Context, requestID string, score float64, properties map[string]any) {
+	event := t.newEvent(ctx, "feedback", requestID)
+	event.Success = true
+	event.Satisfaction = &score
+	event.Properties = maps.Clone(properties)
+	t.Emit(event)
+}
+
+// Emit queues a custom event, filling in its ID and timestamp when missing
+func (t *Tracker) Emit(event Event) {
+	if event.ID == "" {
+		event.ID = newID()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	select {
+	case <-t.done:
+		t.dropped.Add(1)
+		return
+	default:
+	}
+	select {
+	case t.events <- event:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+// Flush sends all queued events and waits until the sink has been called
+func (t *Tracker) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case t.flush <- ack:
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown implements calque.Shutdowner, sending queued events before stopping
+func (t *Tracker) Shutdown(ctx context.Context) error {
+	t.once.Do(func() { close(t.done) })
+	select {
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Dropped returns the number of events discarded because the queue was full or the tracker stopped
+func (t *Tracker) Dropped() int64 {
+	return t.dropped.Load()
+}
+
+// AnonymousID returns the keyed hash used in place of id
+func (t *Tracker) AnonymousID(id string) string {
+	if id == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(t.config.Salt))
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// run batches queued events until shutdown
+func (t *Tracker) run() {
+	defer close(t.stopped)
+	ticker := time.NewTicker(t.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, t.config.BatchSize)
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.sink.Send(context.Background(), batch); err != nil && t.config.OnError != nil {
+			t.config.OnError(err, batch)
+		}
+		batch = make([]Event, 0, t.config.BatchSize)
+	}
+	drain := func() {
+		for {
+			select {
+			case event := <-t.events:
+				batch = append(batch, event)
+				if len(batch) >= t.config.BatchSize {
+					send()
+				}
+			default:
+				send()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case event := <-t.events:
+			batch = append(batch, event)
+			if len(batch) >= t.config.BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case ack := <-t.flush:
+			drain()
+			close(ack)
+		case <-t.done:
+			drain()
+			return
+		}
+	}
+}
+
+func (t *Tracker) newEvent(ctx context.Context, name, requestID string) Event {
+	event := Event{
+		ID:        newID(),
+		Name:      name,
+		RequestID: requestID,
+		TenantID:  t.AnonymousID(calque.Tenant(ctx)),
+		Timestamp: time.Now().UTC(),
+	}
+	if user, ok := calque.UserFromContext(ctx); ok {
+		event.AnonymousID = t.AnonymousID(user.ID)
+	}
+	return event
+}
+
+// trackedHandler measures a handler and emits its event
+type trackedHandler struct {
+	tracker *Tracker
+	name    string
+	handler calque.Handler
+}
+
+func (h *trackedHandler) ServeFlow(req *calque.Request, res *calque.Response) error {
+	ctx := req.Context
+	requestID := calque.RequestID(ctx)
+	if requestID == "" {
+		requestID = newID()
+		ctx = calque.WithRequestID(ctx, requestID)
+	}
+	state := &eventState{}
+	ctx = context.WithValue(ctx, eventStateKey{}, state)
+
+	input := &countingReader{r: req.Data, headLimit: h.tracker.config.IntentBytes}
+	output := &countingWriter{w: res.Data}
+	start := time.Now()
+	err := h.handler.ServeFlow(&calque.Request{Context: ctx, Data: input}, &calque.Response{Data: output})
+	latency := time.Since(start)
+
+	event := h.tracker.newEvent(ctx, h.name, requestID)
+	event.LatencyMs = latency.Milliseconds()
+	event.InputBytes = input.n
+	event.OutputBytes = output.n
+	event.Success = err == nil
+	event.ErrorType = classifyError(err)
+
+	state.mu.Lock()
+	event.Intent = state.intent
+	event.Route = state.route
+	event.InputTokens = state.inputTokens
+	event.OutputTokens = state.outputTokens
+	event.Properties = maps.Clone(state.properties)
+	state.mu.Unlock()
+
+	if event.Intent == "" && h.tracker.config.Intent != nil {
+		event.Intent = h.tracker.config.Intent(ctx, input.head)
+	}
+
+	h.tracker.Emit(event)
+	return err
+}
+
+// Describe implements calque.Describer, drawing the tracker around the tracked handler
+func (h *trackedHandler) Describe() calque.Node {
+	return calque.Node{Label: "analytics " + h.name, Kind: calque.NodeSequence, Children: []calque.Node{calque.DescribeHandler(h.handler)}}
+}
+
+// Egress implements calque.Egress with the destination of the tracker's sink
+func (h *trackedHandler) Egress() []string {
+	return calque.EgressOf(h.tracker.sink)
+}
+
+// Warmup implements calque.Warmer by warming the tracked handler
+func (h *trackedHandler) Warmup(ctx context.Context) error {
+	return calque.WarmupHandler(ctx, h.handler)
+}
+
+// Shutdown implements calque.Shutdowner by shutting down the tracked handler
+func (h *trackedHandler) Shutdown(ctx context.Context) error {
+	return calque.ShutdownHandler(ctx, h.handler)
+}
+
+type eventStateKey struct{}
+
+// eventState collects enrichment from handlers inside Track
+type eventState struct {
+	mu           sync.Mutex
+	intent       string
+	route        string
+	inputTokens  int
+	outputTokens int
+	properties   map[string]any
+}
+
+func stateFrom(ctx context.Context) *eventState {
+	state, _ := ctx.Value(eventStateKey{}).(*eventState)
+	return state
+}
+
+// SetIntent labels the current tracked request with an intent, e.g. "billing_question".
+// It is a no-op outside Track.
+func SetIntent(ctx context.Context, intent string) {
+	if state := stateFrom(ctx); state != nil {
+		state.mu.Lock()
+		state.intent = intent
+		state.mu.Unlock()
+	}
+}
+
+// SetRoute records which route, model or branch served the current tracked request.
+// It is a no-op outside Track.
+func SetRoute(ctx context.Context, route string) {
+	if state := stateFrom(ctx); state != nil {
+		state.mu.Lock()
+		state.route = route
+		state.mu.Unlock()
+	}
+}
+
+// RecordTokens adds token usage to the current tracked request; calls accumulate.
+// It is a no-op outside Track.
+//
+// Example:
+//
+//	analytics.RecordTokens(req.Context, usage.PromptTokens, usage.CompletionTokens)
+func RecordTokens(ctx context.Context, input, output int) {
+	if state := stateFrom(ctx); state != nil {
+		state.mu.Lock()
+		state.inputTokens += input
+		state.outputTokens += output
+		state.mu.Unlock()
+	}
+}
+
+// SetProperty attaches a custom property to the current tracked request.
+// Never pass prompt or response content. It is a no-op outside Track.
+func SetProperty(ctx context.Context, key string, value any) {
+	if state := stateFrom(ctx); state != nil {
+		state.mu.Lock()
+		if state.properties == nil {
+			state.properties = make(map[string]any)
+		}
+		state.properties[key] = value
+		state.mu.Unlock()
+	}
+}
+
+// countingReader counts bytes read and keeps the first headLimit bytes for intent classification
+type countingReader struct {
+	r         io.Reader
+	n         int64
+	head      []byte
+	headLimit int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if room := c.headLimit - len(c.head); room > 0 && n > 0 {
+		c.head = append(c.head, p[:min(n, room)]...)
+	}
+	return n, err
+}
+
+// countingWriter counts bytes written
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func classifyError(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "error"
+	}
+}
+
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,274 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// recordingSink keeps every batch it receives
+type recordingSink struct {
+	mu      sync.Mutex
+	batches [][]Event
+}
+
+func (s *recordingSink) Send(_ context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, append([]Event(nil), events...))
+	return nil
+}
+
+func (s *recordingSink) events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var all []Event
+	for _, batch := range s.batches {
+		all = append(all, batch...)
+	}
+	return all
+}
+
+func echo() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+}
+
+func TestTracker_Track(t *testing.T) {
+	sink := &recordingSink{}
+	tracker := New(sink, &Config{Salt: "secret"})
+
+	handler := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		SetIntent(req.Context, "billing_question")
+		SetRoute(req.Context, "small")
+		RecordTokens(req.Context, 12, 3)
+		RecordTokens(req.Context, 0, 2)
+		SetProperty(req.Context, "tools_used", 1)
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+
+	ctx := calque.WithUser(context.Background(), calque.User{ID: "user-42", Email: "jane@example.com"})
+	ctx = calque.WithTenant(ctx, "acme")
+	ctx = calque.WithRequestID(ctx, "req-1")
+
+	var out string
+	prompt := "My card was charged twice, my email is jane@example.com"
+	if err := calque.NewFlow().Use(tracker.Track("assistant", handler)).Run(ctx, prompt, &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != prompt {
+		t.Errorf("output = %q, tracked handler must pass data through", out)
+	}
+	if err := tracker.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	events := sink.events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.Name != "assistant" || e.RequestID != "req-1" || !e.Success || e.ErrorType != "" {
+		t.Errorf("event = %+v", e)
+	}
+	if e.Intent != "billing_question" || e.Route != "small" || e.InputTokens != 12 || e.OutputTokens != 5 {
+		t.Errorf("enrichment = intent %q route %q tokens %d/%d", e.Intent, e.Route, e.InputTokens, e.OutputTokens)
+	}
+	if e.InputBytes != int64(len(prompt)) || e.OutputBytes != int64(len(prompt)) {
+		t.Errorf("bytes = %d/%d, want %d", e.InputBytes, e.OutputBytes, len(prompt))
+	}
+	if e.AnonymousID != tracker.AnonymousID("user-42") || e.AnonymousID == "user-42" || len(e.AnonymousID) != 32 {
+		t.Errorf("AnonymousID = %q, want keyed hash of the user ID", e.AnonymousID)
+	}
+	if e.TenantID == "acme" || e.TenantID == "" {
+		t.Errorf("TenantID = %q, want hashed tenant", e.TenantID)
+	}
+	if e.Properties["tools_used"] != 1 {
+		t.Errorf("Properties = %v", e.Properties)
+	}
+}
+
+func TestTracker_AnonymousIDDependsOnSalt(t *testing.T) {
+	a := New(SinkFunc(func(context.Context, []Event) error { return nil }), &Config{Salt: "a"})
+	b := New(SinkFunc(func(context.Context, []Event) error { return nil }), &Config{Salt: "b"})
+	defer a.Shutdown(context.Background())
+	defer b.Shutdown(context.Background())
+
+	if a.AnonymousID("u") == b.AnonymousID("u") {
+		t.Error("different salts must produce different hashes")
+	}
+	if a.AnonymousID("u") != a.AnonymousID("u") {
+		t.Error("hash must be stable for one salt")
+	}
+	if a.AnonymousID("") != "" {
+		t.Error("empty ID should stay empty")
+	}
+}
+
+func TestTracker_IntentClassifierAndErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   calque.Handler
+		ctx       func() (context.Context, context.CancelFunc)
+		wantError string
+		wantHead  string
+	}{
+		{
+			name:     "success",
+			handler:  echo(),
+			wantHead: "refund pl",
+		},
+		{
+			name:      "handler error",
+			handler:   calque.HandlerFunc(func(*calque.Request, *calque.Response) error { return errors.New("boom") }),
+			wantError: "error",
+		},
+		{
+			name: "timeout",
+			handler: calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+				<-req.Context.Done()
+				return req.Context.Err()
+			}),
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
+			},
+			wantError: "timeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &recordingSink{}
+			var head string
+			tracker := New(sink, &Config{
+				IntentBytes: 9,
+				Intent: func(_ context.Context, h []byte) string {
+					head = string(h)
+					return "refund"
+				},
+			})
+
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tt.ctx != nil {
+				ctx, cancel = tt.ctx()
+			}
+			defer cancel()
+
+			var out string
+			_ = calque.NewFlow().Use(tracker.Track("agent", tt.handler)).Run(ctx, "refund please", &out)
+			_ = tracker.Shutdown(context.Background())
+
+			events := sink.events()
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want 1", len(events))
+			}
+			if events[0].ErrorType != tt.wantError || events[0].Success != (tt.wantError == "") {
+				t.Errorf("event outcome = success %v, error %q; want error %q", events[0].Success, events[0].ErrorType, tt.wantError)
+			}
+			if events[0].Intent != "refund" || head != tt.wantHead {
+				t.Errorf("intent = %q from head %q, want head %q", events[0].Intent, head, tt.wantHead)
+			}
+			if events[0].RequestID == "" {
+				t.Error("a request ID should be generated when none is set")
+			}
+		})
+	}
+}
+
+func TestTracker_BatchingAndFlush(t *testing.T) {
+	sink := &recordingSink{}
+	tracker := New(sink, &Config{BatchSize: 3, FlushInterval: time.Hour})
+	defer tracker.Shutdown(context.Background())
+
+	for range 7 {
+		tracker.Emit(Event{Name: "e"})
+	}
+	if err := tracker.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	sink.mu.Lock()
+	sizes := make([]int, len(sink.batches))
+	for i, b := range sink.batches {
+		sizes[i] = len(b)
+	}
+	sink.mu.Unlock()
+	if len(sizes) != 3 || sizes[0] != 3 || sizes[1] != 3 || sizes[2] != 1 {
+		t.Errorf("batch sizes = %v, want [3 3 1]", sizes)
+	}
+}
+
+func TestTracker_DropsWhenFullOrStopped(t *testing.T) {
+	block := make(chan struct{})
+	sink := SinkFunc(func(context.Context, []Event) error { <-block; return nil })
+	tracker := New(sink, &Config{BatchSize: 1, BufferSize: 2})
+
+	for range 10 {
+		tracker.Emit(Event{Name: "e"})
+	}
+	if tracker.Dropped() == 0 {
+		t.Error("expected events to be dropped while the sink is blocked")
+	}
+	close(block)
+	_ = tracker.Shutdown(context.Background())
+
+	before := tracker.Dropped()
+	tracker.Emit(Event{Name: "late"})
+	if tracker.Dropped() != before+1 {
+		t.Error("events after Shutdown should be dropped")
+	}
+}
+
+func TestTracker_FeedbackAndSinkErrors(t *testing.T) {
+	var failed []Event
+	tracker := New(SinkFunc(func(_ context.Context, events []Event) error {
+		return errors.New("unavailable")
+	}), &Config{OnError: func(_ error, events []Event) { failed = append(failed, events...) }})
+
+	ctx := calque.WithUser(context.Background(), calque.User{ID: "u1"})
+	tracker.Feedback(ctx, "req-9", 1, map[string]any{"source": "thumbs"})
+	_ = tracker.Shutdown(context.Background())
+
+	if len(failed) != 1 {
+		t.Fatalf("OnError received %d events, want 1", len(failed))
+	}
+	fb := failed[0]
+	if fb.Name != "feedback" || fb.RequestID != "req-9" || fb.Satisfaction == nil || *fb.Satisfaction != 1 || fb.AnonymousID == "" {
+		t.Errorf("feedback event = %+v", fb)
+	}
+}
+
+func TestEnrichmentOutsideTrack(_ *testing.T) {
+	ctx := context.Background()
+	SetIntent(ctx, "x")
+	SetRoute(ctx, "x")
+	RecordTokens(ctx, 1, 1)
+	SetProperty(ctx, "k", "v")
+}
+
+func TestTracker_NeverRecordsContent(t *testing.T) {
+	sink := &recordingSink{}
+	tracker := New(sink, nil)
+
+	secret := "ssn 123-45-6789"
+	var out string
+	_ = calque.NewFlow().Use(tracker.Track("agent", echo())).Run(context.Background(), secret, &out)
+	_ = tracker.Shutdown(context.Background())
+
+	for _, e := range sink.events() {
+		for _, v := range eventProperties(e) {
+			if s, ok := v.(string); ok && strings.Contains(s, "123-45") {
+				t.Errorf("event leaked content: %+v", e)
+			}
+		}
+	}
+}
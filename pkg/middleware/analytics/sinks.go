@@ -0,0 +1,238 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// SinkOption configures the HTTP sinks
+type SinkOption func(*sinkConfig)
+
+type sinkConfig struct {
+	endpoint string
+	client   *http.Client
+	headers  map[string]string
+}
+
+// WithEndpoint overrides the sink URL, e.g. for EU regions or self-hosted instances
+func WithEndpoint(endpoint string) SinkOption {
+	return func(cfg *sinkConfig) {
+		cfg.endpoint = endpoint
+	}
+}
+
+// WithHTTPClient sets the HTTP client used by the sink (default: 10s timeout)
+func WithHTTPClient(client *http.Client) SinkOption {
+	return func(cfg *sinkConfig) {
+		cfg.client = client
+	}
+}
+
+// WithSinkHeaders adds headers to every request the sink makes
+func WithSinkHeaders(headers map[string]string) SinkOption {
+	return func(cfg *sinkConfig) {
+		cfg.headers = headers
+	}
+}
+
+// httpSink is a Sink posting to an endpoint, declaring its host so local-only
+// flows reject handlers tracked with it
+type httpSink struct {
+	send  SinkFunc
+	hosts []string
+}
+
+func (s *httpSink) Send(ctx context.Context, events []Event) error { return s.send(ctx, events) }
+
+// Egress implements calque.Egress
+func (s *httpSink) Egress() []string { return s.hosts }
+
+// sink wraps send as a Sink declaring the configured endpoint
+func (cfg sinkConfig) sink(send SinkFunc) Sink {
+	return &httpSink{send: send, hosts: calque.EgressHosts(cfg.endpoint)}
+}
+
+func newSinkConfig(endpoint string, opts []SinkOption) sinkConfig {
+	cfg := sinkConfig{endpoint: endpoint, client: &http.Client{Timeout: 10 * time.Second}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// Segment sends events to the Segment HTTP tracking API as "track" calls.
+//
+// The anonymized user ID becomes Segment's anonymousId; all other event fields
+// are sent as properties.
+//
+// Example:
+//
+//	sink := analytics.Segment(os.Getenv("SEGMENT_WRITE_KEY"))
+func Segment(writeKey string, opts ...SinkOption) Sink {
+	cfg := newSinkConfig("https://api.segment.io/v1/batch", opts)
+
+	return cfg.sink(func(ctx context.Context, events []Event) error {
+		batch := make([]map[string]any, len(events))
+		for i, event := range events {
+			anonymousID := event.AnonymousID
+			if anonymousID == "" {
+				anonymousID = event.RequestID
+			}
+			batch[i] = map[string]any{
+				"type":        "track",
+				"messageId":   event.ID,
+				"event":       event.Name,
+				"anonymousId": anonymousID,
+				"timestamp":   event.Timestamp,
+				"properties":  eventProperties(event),
+			}
+		}
+
+		body, err := json.Marshal(map[string]any{"batch": batch})
+		if err != nil {
+			return err
+		}
+		return cfg.post(ctx, "segment", body, "application/json", func(r *http.Request) {
+			r.SetBasicAuth(writeKey, "")
+		})
+	})
+}
+
+// PostHog sends events to the PostHog batch capture API.
+//
+// The anonymized user ID becomes the distinct_id and the events are marked as
+// not creating person profiles.
+//
+// Example:
+//
+//	sink := analytics.PostHog(apiKey, analytics.WithEndpoint("https://eu.i.posthog.com/batch/"))
+func PostHog(apiKey string, opts ...SinkOption) Sink {
+	cfg := newSinkConfig("https://us.i.posthog.com/batch/", opts)
+
+	return cfg.sink(func(ctx context.Context, events []Event) error {
+		batch := make([]map[string]any, len(events))
+		for i, event := range events {
+			properties := eventProperties(event)
+			distinctID := event.AnonymousID
+			if distinctID == "" {
+				distinctID = event.RequestID
+			}
+			properties["distinct_id"] = distinctID
+			properties["$process_person_profile"] = false
+			properties["$insert_id"] = event.ID
+			batch[i] = map[string]any{
+				"event":      event.Name,
+				"timestamp":  event.Timestamp,
+				"properties": properties,
+			}
+		}
+
+		body, err := json.Marshal(map[string]any{"api_key": apiKey, "batch": batch})
+		if err != nil {
+			return err
+		}
+		return cfg.post(ctx, "posthog", body, "application/json", nil)
+	})
+}
+
+// ClickHouse inserts events into a table over the ClickHouse HTTP interface.
+//
+// Each event is one JSONEachRow row using the Event JSON field names, with
+// properties as a JSON string column. Credentials can be passed with
+// WithSinkHeaders using X-ClickHouse-User and X-ClickHouse-Key.
+//
+// Example:
+//
+//	sink := analytics.ClickHouse("http://clickhouse:8123", "agent_events")
+func ClickHouse(endpoint, table string, opts ...SinkOption) Sink {
+	cfg := newSinkConfig(endpoint, opts)
+
+	return cfg.sink(func(ctx context.Context, events []Event) error {
+		var body bytes.Buffer
+		enc := json.NewEncoder(&body)
+		for _, event := range events {
+			row := eventProperties(event)
+			row["id"] = event.ID
+			row["event"] = event.Name
+			row["anonymous_id"] = event.AnonymousID
+			row["timestamp"] = event.Timestamp.Format("2006-01-02 15:04:05.000")
+			if props, ok := row["properties"]; ok {
+				encoded, _ := json.Marshal(props)
+				row["properties"] = string(encoded)
+			}
+			if err := enc.Encode(row); err != nil {
+				return err
+			}
+		}
+
+		query := url.Values{"query": {fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", table)}}
+		insert := cfg
+		insert.endpoint = strings.TrimRight(cfg.endpoint, "/") + "/?" + query.Encode()
+		return insert.post(ctx, "clickhouse", body.Bytes(), "application/x-ndjson", nil)
+	})
+}
+
+// eventProperties flattens an event into a property map, leaving out identity fields
+func eventProperties(event Event) map[string]any {
+	properties := map[string]any{
+		"request_id":   event.RequestID,
+		"latency_ms":   event.LatencyMs,
+		"input_bytes":  event.InputBytes,
+		"output_bytes": event.OutputBytes,
+		"success":      event.Success,
+	}
+	set := func(key string, value any, ok bool) {
+		if ok {
+			properties[key] = value
+		}
+	}
+	set("tenant_id", event.TenantID, event.TenantID != "")
+	set("intent", event.Intent, event.Intent != "")
+	set("route", event.Route, event.Route != "")
+	set("input_tokens", event.InputTokens, event.InputTokens > 0)
+	set("output_tokens", event.OutputTokens, event.OutputTokens > 0)
+	set("error_type", event.ErrorType, event.ErrorType != "")
+	if event.Satisfaction != nil {
+		properties["satisfaction"] = *event.Satisfaction
+	}
+	if len(event.Properties) > 0 {
+		properties["properties"] = event.Properties
+	}
+	return properties
+}
+
+func (cfg sinkConfig) post(ctx context.Context, name string, body []byte, contentType string, prepare func(*http.Request)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range cfg.headers {
+		req.Header.Set(k, v)
+	}
+	if prepare != nil {
+		prepare(req)
+	}
+
+	resp, err := cfg.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s sink: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s sink: status %d: %s", name, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
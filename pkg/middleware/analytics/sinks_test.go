@@ -0,0 +1,153 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func testEvents() []Event {
+	score := 0.5
+	return []Event{
+		{
+			ID: "e1", Name: "assistant", RequestID: "r1", AnonymousID: "anon", Intent: "billing",
+			LatencyMs: 120, InputTokens: 10, Success: true, Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			Properties: map[string]any{"tools_used": 2},
+		},
+		{ID: "e2", Name: "feedback", RequestID: "r1", Satisfaction: &score, Success: true, Timestamp: time.Now()},
+	}
+}
+
+// captureServer records the last request it received
+func captureServer(t *testing.T, status int) (*httptest.Server, *http.Request, *[]byte) {
+	t.Helper()
+	var last http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last = *r.Clone(context.Background())
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"detail":"bad"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &last, &body
+}
+
+func TestSegment(t *testing.T) {
+	server, req, body := captureServer(t, http.StatusOK)
+	if err := Segment("wk", WithEndpoint(server.URL)).Send(context.Background(), testEvents()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if user, _, ok := req.BasicAuth(); !ok || user != "wk" {
+		t.Errorf("basic auth user = %q, want write key", user)
+	}
+	var payload struct {
+		Batch []struct {
+			Type, MessageID, Event, AnonymousID string
+			Properties                          map[string]any
+		} `json:"batch"`
+	}
+	if err := json.Unmarshal(*body, &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if len(payload.Batch) != 2 || payload.Batch[0].Type != "track" || payload.Batch[0].AnonymousID != "anon" ||
+		payload.Batch[0].MessageID != "e1" || payload.Batch[0].Properties["intent"] != "billing" {
+		t.Errorf("payload = %s", *body)
+	}
+	if payload.Batch[1].AnonymousID != "r1" || payload.Batch[1].Properties["satisfaction"] != 0.5 {
+		t.Errorf("feedback without user should fall back to the request ID: %s", *body)
+	}
+}
+
+func TestPostHog(t *testing.T) {
+	server, _, body := captureServer(t, http.StatusOK)
+	if err := PostHog("phc_key", WithEndpoint(server.URL)).Send(context.Background(), testEvents()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	var payload struct {
+		APIKey string `json:"api_key"`
+		Batch  []struct {
+			Event      string
+			Properties map[string]any
+		} `json:"batch"`
+	}
+	if err := json.Unmarshal(*body, &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if payload.APIKey != "phc_key" || len(payload.Batch) != 2 {
+		t.Fatalf("payload = %s", *body)
+	}
+	props := payload.Batch[0].Properties
+	if props["distinct_id"] != "anon" || props["$process_person_profile"] != false || props["latency_ms"] != float64(120) {
+		t.Errorf("properties = %v", props)
+	}
+}
+
+func TestClickHouse(t *testing.T) {
+	server, req, body := captureServer(t, http.StatusOK)
+	sink := ClickHouse(server.URL, "agent_events", WithSinkHeaders(map[string]string{"X-ClickHouse-User": "writer"}))
+	if err := sink.Send(context.Background(), testEvents()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if q := req.URL.Query().Get("query"); q != "INSERT INTO agent_events FORMAT JSONEachRow" {
+		t.Errorf("query = %q", q)
+	}
+	if req.Header.Get("X-ClickHouse-User") != "writer" {
+		t.Error("custom headers not sent")
+	}
+	rows := strings.Split(strings.TrimSpace(string(*body)), "\n")
+	if len(rows) != 2 {
+		t.Fatalf("rows = %q", rows)
+	}
+	var row map[string]any
+	if err := json.Unmarshal([]byte(rows[0]), &row); err != nil {
+		t.Fatalf("invalid row: %v", err)
+	}
+	if row["event"] != "assistant" || row["timestamp"] != "2026-01-02 03:04:05.000" || row["properties"] != `{"tools_used":2}` {
+		t.Errorf("row = %v", row)
+	}
+}
+
+func TestSinkErrorStatus(t *testing.T) {
+	server, _, _ := captureServer(t, http.StatusBadRequest)
+	err := PostHog("k", WithEndpoint(server.URL)).Send(context.Background(), testEvents())
+	if err == nil || !strings.Contains(err.Error(), "posthog sink: status 400") || !strings.Contains(err.Error(), "bad") {
+		t.Errorf("Send() error = %v, want status error with detail", err)
+	}
+}
+
+func TestSinkEgress(t *testing.T) {
+	tests := []struct {
+		name string
+		sink Sink
+		want []string
+	}{
+		{"segment", Segment("wk"), []string{"api.segment.io"}},
+		{"posthog", PostHog("phc_key"), []string{"us.i.posthog.com"}},
+		{"clickhouse", ClickHouse("https://ch.example.com:8443", "events"), []string{"ch.example.com"}},
+		{"local clickhouse", ClickHouse("http://localhost:8123", "events"), nil},
+		{"func", SinkFunc(func(context.Context, []Event) error { return nil }), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := New(tt.sink, nil)
+			defer tracker.Shutdown(context.Background())
+			tracked := tracker.Track("agent", calque.HandlerFunc(func(*calque.Request, *calque.Response) error { return nil }))
+			if got := calque.DescribeHandler(tracked).Egress; !slices.Equal(got, tt.want) {
+				t.Errorf("egress = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+// Package redisstore provides a Redis backed store for the cache middleware,
+// and SSEReplay, a replay store for resuming SSE streams on any instance.
+//
+// Entries are stored as plain string values under a key prefix and expire
+// through native Redis TTLs, so several processes can share one cache.
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Defaults
+const (
+	DefaultPrefix  = "calque:cache:"
+	DefaultTimeout = 5 * time.Second
+
+	// scanCount is the COUNT hint for each SCAN call
+	scanCount = 500
+)
+
+// API is the subset of the Redis client used by the store.
+//
+// *redis.Client, *redis.ClusterClient and redis.UniversalClient satisfy this
+// interface; tests can provide a fake.
+type API interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Set(ctx context.Context, key string, value any, expiration time.Duration) *redis.StatusCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	Exists(ctx context.Context, keys ...string) *redis.IntCmd
+	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
+}
+
+// Config holds Redis store configuration.
+type Config struct {
+	// Redis client (required), typically redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	Client API
+
+	// Prefix for all cache keys (default: calque:cache:)
+	Prefix string
+
+	// Timeout for each Redis call (default: 5s)
+	Timeout time.Duration
+}
+
+// Store implements cache.Store on top of Redis.
+//
+// Example:
+//
+//	store, err := redisstore.New(&redisstore.Config{
+//		Client: redis.NewClient(&redis.Options{Addr: "localhost:6379"}),
+//		Prefix: "myapp:cache:",
+//	})
+//	responses := cache.NewCacheWithStore(store)
+type Store struct {
+	client  API
+	prefix  string
+	timeout time.Duration
+}
+
+// New creates a Redis backed cache store.
+func New(config *Config) (*Store, error) {
+	if config == nil || config.Client == nil {
+		return nil, calque.NewErr(context.Background(), "Redis client is required")
+	}
+
+	store := &Store{
+		client:  config.Client,
+		prefix:  config.Prefix,
+		timeout: config.Timeout,
+	}
+	if store.prefix == "" {
+		store.prefix = DefaultPrefix
+	}
+	if store.timeout <= 0 {
+		store.timeout = DefaultTimeout
+	}
+	return store, nil
+}
+
+// Get retrieves data for a key, returns nil if not found or expired
+func (s *Store) Get(key string) ([]byte, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	data, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to get key %s", key))
+	}
+	return data, nil
+}
+
+// Set stores data for a key with TTL (0 = never expires)
+func (s *Store) Set(key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	if err := s.client.Set(ctx, s.prefix+key, value, max(ttl, 0)).Err(); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to set key %s", key))
+	}
+	return nil
+}
+
+// Delete removes data for a key
+func (s *Store) Delete(key string) error {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	if err := s.client.Del(ctx, s.prefix+key).Err(); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to delete key %s", key))
+	}
+	return nil
+}
+
+// Clear removes all keys under the prefix
+func (s *Store) Clear() error {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	// Collect first so deletions can't disturb the scan cursor
+	var all []string
+	err := s.scan(ctx, func(keys []string) error {
+		all = append(all, keys...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(all); start += scanCount {
+		end := min(start+scanCount, len(all))
+		if err := s.client.Del(ctx, all[start:end]...).Err(); err != nil {
+			return calque.WrapErr(ctx, err, "failed to delete keys")
+		}
+	}
+	return nil
+}
+
+// Exists checks if a key exists and hasn't expired
+func (s *Store) Exists(key string) bool {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	n, err := s.client.Exists(ctx, s.prefix+key).Result()
+	return err == nil && n > 0
+}
+
+// List returns all non-expired keys. Returns nil on errors.
+func (s *Store) List() []string {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	var all []string
+	err := s.scan(ctx, func(keys []string) error {
+		for _, key := range keys {
+			all = append(all, strings.TrimPrefix(key, s.prefix))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil
+	}
+	return all
+}
+
+// scan walks every key under the prefix, passing each non-empty page to fn
+func (s *Store) scan(ctx context.Context, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, s.prefix+"*", scanCount).Result()
+		if err != nil {
+			return calque.WrapErr(ctx, err, "failed to scan keys")
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func (s *Store) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.timeout)
+}
@@ -0,0 +1,230 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/cache"
+)
+
+var (
+	_ cache.Store = (*Store)(nil)
+	_ API         = (*redis.Client)(nil)
+)
+
+// fakeRedis is an in-memory keyspace whose SCAN returns two keys per page
+type fakeRedis struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	ttls map[string]time.Duration
+	err  error
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{data: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (f *fakeRedis) Get(_ context.Context, key string) *redis.StringCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return redis.NewStringResult("", f.err)
+	}
+	data, ok := f.data[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(string(data), nil)
+}
+
+func (f *fakeRedis) Set(_ context.Context, key string, value any, expiration time.Duration) *redis.StatusCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return redis.NewStatusResult("", f.err)
+	}
+	f.data[key] = value.([]byte)
+	f.ttls[key] = expiration
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (f *fakeRedis) Del(_ context.Context, keys ...string) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return redis.NewIntResult(0, f.err)
+	}
+	var n int64
+	for _, key := range keys {
+		if _, ok := f.data[key]; ok {
+			delete(f.data, key)
+			n++
+		}
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (f *fakeRedis) Exists(_ context.Context, keys ...string) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return redis.NewIntResult(0, f.err)
+	}
+	var n int64
+	for _, key := range keys {
+		if _, ok := f.data[key]; ok {
+			n++
+		}
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (f *fakeRedis) Scan(_ context.Context, cursor uint64, match string, _ int64) *redis.ScanCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cmd := redis.NewScanCmd(context.Background(), nil)
+	if f.err != nil {
+		cmd.SetErr(f.err)
+		return cmd
+	}
+
+	var matched []string
+	for key := range f.data {
+		if strings.HasPrefix(key, strings.TrimSuffix(match, "*")) {
+			matched = append(matched, key)
+		}
+	}
+	slices.Sort(matched)
+
+	start := min(int(cursor), len(matched))
+	end := min(start+2, len(matched))
+	next := uint64(end)
+	if end == len(matched) {
+		next = 0
+	}
+	cmd.SetVal(matched[start:end], next)
+	return cmd
+}
+
+func TestNew_Validation(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("expected error for nil config")
+	}
+	store, err := New(&Config{Client: newFakeRedis()})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if store.prefix != DefaultPrefix || store.timeout != DefaultTimeout {
+		t.Errorf("defaults = %q, %v", store.prefix, store.timeout)
+	}
+}
+
+func TestStore_CRUD(t *testing.T) {
+	fake := newFakeRedis()
+	store, _ := New(&Config{Client: fake, Prefix: "p:"})
+
+	if data, err := store.Get("missing"); err != nil || data != nil {
+		t.Errorf("Get(missing) = %v, %v; want nil, nil", data, err)
+	}
+
+	if err := store.Set("a", []byte("1"), time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Set("b", []byte("2"), 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if fake.ttls["p:a"] != time.Minute || fake.ttls["p:b"] != 0 {
+		t.Errorf("ttls = %v, want native expiry on p:a only", fake.ttls)
+	}
+
+	if data, err := store.Get("a"); err != nil || string(data) != "1" {
+		t.Errorf("Get(a) = %q, %v", data, err)
+	}
+	if !store.Exists("a") || store.Exists("missing") {
+		t.Error("Exists() mismatch")
+	}
+
+	if err := store.Delete("a"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if store.Exists("a") {
+		t.Error("expected key to be deleted")
+	}
+}
+
+func TestStore_ListAndClear(t *testing.T) {
+	fake := newFakeRedis()
+	store, _ := New(&Config{Client: fake, Prefix: "p:"})
+	fake.data["other:keep"] = []byte("x")
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		_ = store.Set(key, []byte("v"), 0)
+	}
+	if keys := store.List(); !slices.Equal(keys, []string{"a", "b", "c", "d", "e"}) {
+		t.Errorf("List() = %v", keys)
+	}
+
+	if err := store.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if keys := store.List(); len(keys) != 0 {
+		t.Errorf("List() after Clear = %v", keys)
+	}
+	if _, ok := fake.data["other:keep"]; !ok {
+		t.Error("Clear() removed a key outside the prefix")
+	}
+}
+
+func TestStore_WithCacheMiddleware(t *testing.T) {
+	store, _ := New(&Config{Client: newFakeRedis()})
+	calls := 0
+	handler := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		calls++
+		var s string
+		if err := calque.Read(req, &s); err != nil {
+			return err
+		}
+		return calque.Write(res, strings.ToUpper(s))
+	})
+	cached := cache.NewCacheWithStore(store).Cache(handler, time.Minute)
+
+	for range 2 {
+		var out string
+		if err := calque.NewFlow().Use(cached).Run(context.Background(), "hi", &out); err != nil || out != "HI" {
+			t.Fatalf("Run() = %q, %v", out, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	fake := newFakeRedis()
+	fake.err = errors.New("connection refused")
+	store, _ := New(&Config{Client: fake})
+
+	if _, err := store.Get("a"); err == nil {
+		t.Error("Get() should fail")
+	}
+	if err := store.Set("a", []byte("1"), 0); err == nil {
+		t.Error("Set() should fail")
+	}
+	if err := store.Delete("a"); err == nil {
+		t.Error("Delete() should fail")
+	}
+	if err := store.Clear(); err == nil {
+		t.Error("Clear() should fail")
+	}
+	if store.Exists("a") || store.List() != nil {
+		t.Error("Exists()/List() should report nothing on errors")
+	}
+}
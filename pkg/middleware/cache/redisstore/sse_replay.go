@@ -0,0 +1,181 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/convert"
+)
+
+// SSE replay defaults
+const (
+	DefaultSSEReplayPrefix    = "calque:sse:"
+	DefaultSSEReplayMaxEvents = 1000
+	DefaultSSEReplayTTL       = 5 * time.Minute
+)
+
+// ReplayAPI is the subset of the Redis client used by SSEReplay.
+//
+// *redis.Client, *redis.ClusterClient and redis.UniversalClient satisfy this
+// interface; tests can provide a fake.
+type ReplayAPI interface {
+	RPush(ctx context.Context, key string, values ...any) *redis.IntCmd
+	LTrim(ctx context.Context, key string, start, stop int64) *redis.StatusCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+	LRange(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd
+	LIndex(ctx context.Context, key string, index int64) *redis.StringCmd
+}
+
+// SSEReplayConfig holds Redis SSE replay store configuration.
+type SSEReplayConfig struct {
+	// Redis client (required), typically redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	Client ReplayAPI
+
+	// Prefix for stream keys (default: calque:sse:)
+	Prefix string
+
+	// MaxEvents kept per stream, oldest dropped first (default: 1000)
+	MaxEvents int
+
+	// TTL after the last append before a stream expires (default: 5m)
+	TTL time.Duration
+
+	// Timeout for each Redis call (default: 5s)
+	Timeout time.Duration
+}
+
+// SSEReplay implements convert.SSEReplayStore on top of Redis lists, so a
+// client can resume an SSE stream on any instance behind a load balancer.
+//
+// Each stream is a list of JSON-encoded events under the prefix, trimmed to
+// MaxEvents and expiring TTL after its last append.
+//
+// Example:
+//
+//	replay, err := redisstore.NewSSEReplay(&redisstore.SSEReplayConfig{
+//		Client: redis.NewClient(&redis.Options{Addr: "localhost:6379"}),
+//	})
+//	sse := convert.ToSSE(w).WithReplay(replay, conversationID)
+type SSEReplay struct {
+	client    ReplayAPI
+	prefix    string
+	maxEvents int
+	ttl       time.Duration
+	timeout   time.Duration
+}
+
+// NewSSEReplay creates a Redis backed SSE replay store.
+func NewSSEReplay(config *SSEReplayConfig) (*SSEReplay, error) {
+	if config == nil || config.Client == nil {
+		return nil, calque.NewErr(context.Background(), "Redis client is required")
+	}
+
+	replay := &SSEReplay{
+		client:    config.Client,
+		prefix:    config.Prefix,
+		maxEvents: config.MaxEvents,
+		ttl:       config.TTL,
+		timeout:   config.Timeout,
+	}
+	if replay.prefix == "" {
+		replay.prefix = DefaultSSEReplayPrefix
+	}
+	if replay.maxEvents <= 0 {
+		replay.maxEvents = DefaultSSEReplayMaxEvents
+	}
+	if replay.ttl <= 0 {
+		replay.ttl = DefaultSSEReplayTTL
+	}
+	if replay.timeout <= 0 {
+		replay.timeout = DefaultTimeout
+	}
+	return replay, nil
+}
+
+// Append implements convert.SSEReplayStore
+func (r *SSEReplay) Append(ctx context.Context, streamID string, event convert.SSEEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to encode SSE event")
+	}
+
+	key := r.prefix + streamID
+	if err := r.client.RPush(ctx, key, data).Err(); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to append to SSE stream %s", streamID))
+	}
+	if err := r.client.LTrim(ctx, key, int64(-r.maxEvents), -1).Err(); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to trim SSE stream %s", streamID))
+	}
+	if err := r.client.Expire(ctx, key, r.ttl).Err(); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to set expiry on SSE stream %s", streamID))
+	}
+	return nil
+}
+
+// Events implements convert.SSEReplayStore
+func (r *SSEReplay) Events(ctx context.Context, streamID string, afterID int64) ([]convert.SSEEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	values, err := r.client.LRange(ctx, r.prefix+streamID, 0, -1).Result()
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to read SSE stream %s", streamID))
+	}
+
+	var events []convert.SSEEvent
+	for _, value := range values {
+		event, id, err := decodeEvent(value)
+		if err != nil {
+			return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to decode SSE stream %s", streamID))
+		}
+		if id > afterID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// LastID implements convert.SSEReplayStore
+func (r *SSEReplay) LastID(ctx context.Context, streamID string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	value, err := r.client.LIndex(ctx, r.prefix+streamID, -1).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, calque.WrapErr(ctx, err, fmt.Sprintf("failed to read SSE stream %s", streamID))
+	}
+	_, id, err := decodeEvent(value)
+	if err != nil {
+		return 0, calque.WrapErr(ctx, err, fmt.Sprintf("failed to decode SSE stream %s", streamID))
+	}
+	return id, nil
+}
+
+// decodeEvent decodes a stored event and its numeric ID (0 when it has none).
+// Data is kept as raw JSON so replayed events match the originals byte for byte.
+func decodeEvent(value string) (convert.SSEEvent, int64, error) {
+	var stored struct {
+		convert.SSEEvent
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(value), &stored); err != nil {
+		return convert.SSEEvent{}, 0, err
+	}
+	event := stored.SSEEvent
+	event.Data = stored.Data
+	id, _ := strconv.ParseInt(event.ID, 10, 64)
+	return event, id, nil
+}
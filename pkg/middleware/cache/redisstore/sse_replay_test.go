@@ -0,0 +1,176 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/calque-ai/go-calque/pkg/convert"
+)
+
+var (
+	_ convert.SSEReplayStore = (*SSEReplay)(nil)
+	_ ReplayAPI              = (*redis.Client)(nil)
+)
+
+// fakeLists is an in-memory set of Redis lists
+type fakeLists struct {
+	mu    sync.Mutex
+	lists map[string][]string
+	ttls  map[string]time.Duration
+	err   error
+}
+
+func newFakeLists() *fakeLists {
+	return &fakeLists{lists: map[string][]string{}, ttls: map[string]time.Duration{}}
+}
+
+func (f *fakeLists) RPush(_ context.Context, key string, values ...any) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return redis.NewIntResult(0, f.err)
+	}
+	for _, v := range values {
+		f.lists[key] = append(f.lists[key], string(v.([]byte)))
+	}
+	return redis.NewIntResult(int64(len(f.lists[key])), nil)
+}
+
+func (f *fakeLists) LTrim(_ context.Context, key string, start, stop int64) *redis.StatusCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	list := f.lists[key]
+	from, to := listIndex(start, len(list)), listIndex(stop, len(list))
+	f.lists[key] = append([]string(nil), list[from:to+1]...)
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (f *fakeLists) Expire(_ context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ttls[key] = expiration
+	return redis.NewBoolResult(true, nil)
+}
+
+func (f *fakeLists) LRange(_ context.Context, key string, start, stop int64) *redis.StringSliceCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return redis.NewStringSliceResult(nil, f.err)
+	}
+	list := f.lists[key]
+	if len(list) == 0 {
+		return redis.NewStringSliceResult(nil, nil)
+	}
+	return redis.NewStringSliceResult(list[listIndex(start, len(list)):listIndex(stop, len(list))+1], nil)
+}
+
+func (f *fakeLists) LIndex(_ context.Context, key string, index int64) *redis.StringCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	list := f.lists[key]
+	if len(list) == 0 {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(list[listIndex(index, len(list))], nil)
+}
+
+// listIndex resolves a Redis list index, where negative values count from the end
+func listIndex(i int64, n int) int {
+	if i < 0 {
+		i += int64(n)
+	}
+	return max(0, min(int(i), n-1))
+}
+
+func TestNewSSEReplay(t *testing.T) {
+	if _, err := NewSSEReplay(nil); err == nil {
+		t.Error("NewSSEReplay(nil) should fail")
+	}
+	replay, err := NewSSEReplay(&SSEReplayConfig{Client: newFakeLists()})
+	if err != nil {
+		t.Fatalf("NewSSEReplay() error = %v", err)
+	}
+	if replay.prefix != DefaultSSEReplayPrefix || replay.maxEvents != DefaultSSEReplayMaxEvents || replay.ttl != DefaultSSEReplayTTL {
+		t.Errorf("defaults = %+v", replay)
+	}
+}
+
+func TestSSEReplay(t *testing.T) {
+	fake := newFakeLists()
+	replay, _ := NewSSEReplay(&SSEReplayConfig{Client: fake, MaxEvents: 2, TTL: time.Minute})
+	ctx := context.Background()
+
+	if id, err := replay.LastID(ctx, "s"); err != nil || id != 0 {
+		t.Errorf("LastID() of an empty stream = %d, %v", id, err)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		if err := replay.Append(ctx, "s", convert.SSEEvent{Event: "message", Data: "e" + id, ID: id}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	events, err := replay.Events(ctx, "s", 0)
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+	if len(events) != 2 || events[0].ID != "2" || events[1].ID != "3" {
+		t.Errorf("Events() = %+v, want the two most recent", events)
+	}
+	if events, _ := replay.Events(ctx, "s", 2); len(events) != 1 || string(events[0].Data.(json.RawMessage)) != `"e3"` {
+		t.Errorf("Events(after 2) = %+v", events)
+	}
+	if id, _ := replay.LastID(ctx, "s"); id != 3 {
+		t.Errorf("LastID() = %d, want 3", id)
+	}
+	if fake.ttls[DefaultSSEReplayPrefix+"s"] != time.Minute {
+		t.Errorf("stream TTL = %v, want 1m", fake.ttls[DefaultSSEReplayPrefix+"s"])
+	}
+
+	fake.err = errors.New("connection refused")
+	if _, err := replay.Events(ctx, "s", 0); err == nil {
+		t.Error("Events() should report Redis errors")
+	}
+	if err := replay.Append(ctx, "s", convert.SSEEvent{ID: "4"}); err == nil {
+		t.Error("Append() should report Redis errors")
+	}
+}
+
+func TestSSEReplayResumesAcrossInstances(t *testing.T) {
+	fake := newFakeLists()
+	newInstance := func() *SSEReplay {
+		replay, _ := NewSSEReplay(&SSEReplayConfig{Client: fake})
+		return replay
+	}
+
+	// Two turns of a conversation answered by one instance
+	for _, answer := range []string{"first turn", "second turn"} {
+		if err := convert.ToSSE(httptest.NewRecorder()).WithReplay(newInstance(), "conv-1").FromReader(strings.NewReader(answer)); err != nil {
+			t.Fatalf("FromReader() error = %v", err)
+		}
+	}
+
+	// The client reconnects to another instance after the second turn's first event
+	resumed := httptest.NewRecorder()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := convert.ToSSE(resumed).WithReplay(newInstance(), "conv-1").Resume(ctx, "4"); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+
+	body := resumed.Body.String()
+	if !strings.Contains(body, "id: 5\n") || !strings.Contains(body, `"turn"`) || strings.Contains(body, "id: 4\n") {
+		t.Errorf("expected the rest of the second turn, got %q", body)
+	}
+	if !strings.Contains(body, "event: completion") {
+		t.Errorf("expected the completion event, got %q", body)
+	}
+}
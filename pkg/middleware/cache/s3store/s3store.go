@@ -0,0 +1,323 @@
+// Package s3store provides an Amazon S3 backed blob store for the cache middleware.
+//
+// Use it for large cached artifacts such as transcripts, retrieved contexts or
+// rendered documents that do not fit comfortably in DynamoDB items or memory.
+// Entries are stored as objects under a key prefix, with their expiry recorded in
+// object metadata. Configure an S3 lifecycle rule on the prefix to physically
+// remove old objects; reads ignore expired objects even before they are deleted.
+package s3store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/helpers"
+)
+
+// Defaults and limits
+const (
+	DefaultTimeout = 30 * time.Second
+
+	// expiresMetadataKey stores the expiry as unix seconds in object metadata
+	expiresMetadataKey = "calque-expires-at"
+
+	// deleteObjectsLimit is the maximum number of keys in one DeleteObjects call
+	deleteObjectsLimit = 1000
+)
+
+// API is the subset of the S3 client used by the store.
+//
+// *s3.Client satisfies this interface; tests can provide a fake.
+type API interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// Config holds S3 store configuration.
+type Config struct {
+	// S3 client (required), typically s3.NewFromConfig(awsCfg)
+	Client API
+
+	// Bucket name (required)
+	Bucket string
+
+	// Prefix for all cache objects, e.g. "calque/cache/"
+	Prefix string
+
+	// Content type recorded on stored objects (default: application/octet-stream)
+	ContentType string
+
+	// Timeout for each S3 call (default: 30s)
+	Timeout time.Duration
+}
+
+// Store implements cache.Store on top of an S3 bucket.
+//
+// Example:
+//
+//	awsCfg, _ := config.LoadDefaultConfig(ctx)
+//	store, err := s3store.New(&s3store.Config{
+//		Client: s3.NewFromConfig(awsCfg),
+//		Bucket: "my-artifacts",
+//		Prefix: "calque/cache/",
+//	})
+//	transcripts := cache.NewCacheWithStore(store)
+type Store struct {
+	client      API
+	bucket      string
+	prefix      string
+	contentType string
+	timeout     time.Duration
+}
+
+// New creates an S3 backed cache store.
+func New(config *Config) (*Store, error) {
+	ctx := context.Background()
+	if config == nil || config.Client == nil {
+		return nil, calque.NewErr(ctx, "S3 client is required")
+	}
+	if config.Bucket == "" {
+		return nil, calque.NewErr(ctx, "S3 bucket is required")
+	}
+
+	store := &Store{
+		client:      config.Client,
+		bucket:      config.Bucket,
+		prefix:      config.Prefix,
+		contentType: config.ContentType,
+		timeout:     config.Timeout,
+	}
+	if store.contentType == "" {
+		store.contentType = "application/octet-stream"
+	}
+	if store.timeout <= 0 {
+		store.timeout = DefaultTimeout
+	}
+	return store, nil
+}
+
+// Get retrieves data for a key, returns nil if not found or expired
+func (s *Store) Get(key string) ([]byte, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{Bucket: &s.bucket, Key: s.objectKey(key)})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to get object %s", key))
+	}
+	defer out.Body.Close()
+
+	if expired(out.Metadata) {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to read object %s", key))
+	}
+	return data, nil
+}
+
+// Set stores data for a key with TTL (0 = never expires)
+func (s *Store) Set(key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	input := &s3.PutObjectInput{
+		Bucket:        &s.bucket,
+		Key:           s.objectKey(key),
+		Body:          bytes.NewReader(value),
+		ContentLength: helpers.PtrOf(int64(len(value))),
+		ContentType:   &s.contentType,
+	}
+	if ttl > 0 {
+		input.Metadata = map[string]string{
+			expiresMetadataKey: strconv.FormatInt(time.Now().Add(ttl).Unix(), 10),
+		}
+	}
+
+	if _, err := s.client.PutObject(ctx, input); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to put object %s", key))
+	}
+	return nil
+}
+
+// Delete removes data for a key
+func (s *Store) Delete(key string) error {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	if _, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: &s.bucket, Key: s.objectKey(key)}); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to delete object %s", key))
+	}
+	return nil
+}
+
+// Clear removes all objects under the prefix
+func (s *Store) Clear() error {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	keys, err := s.allKeys(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(keys); start += deleteObjectsLimit {
+		end := min(start+deleteObjectsLimit, len(keys))
+
+		objects := make([]types.ObjectIdentifier, 0, end-start)
+		for _, key := range keys[start:end] {
+			objects = append(objects, types.ObjectIdentifier{Key: s.objectKey(key)})
+		}
+
+		out, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: &s.bucket,
+			Delete: &types.Delete{Objects: objects, Quiet: helpers.PtrOf(true)},
+		})
+		if err != nil {
+			return calque.WrapErr(ctx, err, "failed to delete objects")
+		}
+		if len(out.Errors) > 0 {
+			return calque.NewErr(ctx, fmt.Sprintf("failed to delete %d objects: %s", len(out.Errors), stringValue(out.Errors[0].Message)))
+		}
+	}
+	return nil
+}
+
+// Exists checks if a key exists and hasn't expired
+func (s *Store) Exists(key string) bool {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	out, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &s.bucket, Key: s.objectKey(key)})
+	return err == nil && !expired(out.Metadata)
+}
+
+// List returns all non-expired keys. Returns nil on errors.
+//
+// Expiry lives in object metadata, so List issues a HeadObject per key.
+// Prefer ListPage for large buckets.
+func (s *Store) List() []string {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	keys, err := s.allKeys(ctx, true)
+	if err != nil {
+		return nil
+	}
+	return keys
+}
+
+// ListPage returns up to limit keys starting at cursor, and the cursor for the next page.
+//
+// Keys are listed without checking expiry. An empty returned cursor means there
+// are no more pages.
+//
+// Example:
+//
+//	keys, next, err := store.ListPage(ctx, 500, "")
+func (s *Store) ListPage(ctx context.Context, limit int32, cursor string) ([]string, string, error) {
+	input := &s3.ListObjectsV2Input{Bucket: &s.bucket, Prefix: &s.prefix}
+	if limit > 0 {
+		input.MaxKeys = &limit
+	}
+	if cursor != "" {
+		input.ContinuationToken = &cursor
+	}
+
+	out, err := s.client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return nil, "", calque.WrapErr(ctx, err, "failed to list objects")
+	}
+
+	keys := make([]string, 0, len(out.Contents))
+	for _, obj := range out.Contents {
+		keys = append(keys, strings.TrimPrefix(stringValue(obj.Key), s.prefix))
+	}
+
+	var next string
+	if out.IsTruncated != nil && *out.IsTruncated {
+		next = stringValue(out.NextContinuationToken)
+	}
+	return keys, next, nil
+}
+
+// allKeys walks every page, optionally filtering expired objects
+func (s *Store) allKeys(ctx context.Context, skipExpired bool) ([]string, error) {
+	var all []string
+	cursor := ""
+	for {
+		keys, next, err := s.ListPage(ctx, 0, cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			if skipExpired {
+				head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &s.bucket, Key: s.objectKey(key)})
+				if err != nil || expired(head.Metadata) {
+					continue
+				}
+			}
+			all = append(all, key)
+		}
+
+		if next == "" {
+			return all, nil
+		}
+		cursor = next
+	}
+}
+
+func (s *Store) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.timeout)
+}
+
+func (s *Store) objectKey(key string) *string {
+	k := s.prefix + key
+	return &k
+}
+
+// expired reports whether object metadata carries an expiry in the past
+func expired(metadata map[string]string) bool {
+	value, ok := metadata[expiresMetadataKey]
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+	return time.Now().Unix() >= expires
+}
+
+// isNotFound detects missing object errors from GetObject and HeadObject
+func isNotFound(err error) bool {
+	var noSuchKey *types.NoSuchKey
+	var notFound *types.NotFound
+	return errors.As(err, &noSuchKey) || errors.As(err, &notFound)
+}
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,254 @@
+package s3store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/calque-ai/go-calque/pkg/helpers"
+	"github.com/calque-ai/go-calque/pkg/middleware/cache"
+)
+
+var _ cache.Store = (*Store)(nil)
+
+type fakeObject struct {
+	data     []byte
+	metadata map[string]string
+}
+
+// fakeS3 is an in-memory bucket supporting paginated listing
+type fakeS3 struct {
+	mu          sync.Mutex
+	objects     map[string]fakeObject
+	err         error
+	deleteCalls int
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: make(map[string]fakeObject)}
+}
+
+func (f *fakeS3) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	obj, ok := f.objects[*in.Key]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(obj.data)), Metadata: obj.metadata}, nil
+}
+
+func (f *fakeS3) HeadObject(_ context.Context, in *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.objects[*in.Key]
+	if !ok {
+		return nil, &types.NotFound{}
+	}
+	return &s3.HeadObjectOutput{Metadata: obj.metadata}, nil
+}
+
+func (f *fakeS3) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	data, _ := io.ReadAll(in.Body)
+	f.objects[*in.Key] = fakeObject{data: data, metadata: in.Metadata}
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) DeleteObject(_ context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.objects, *in.Key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (f *fakeS3) DeleteObjects(_ context.Context, in *s3.DeleteObjectsInput, _ ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleteCalls++
+	if len(in.Delete.Objects) > deleteObjectsLimit {
+		return nil, errors.New("too many objects")
+	}
+	for _, obj := range in.Delete.Objects {
+		delete(f.objects, *obj.Key)
+	}
+	return &s3.DeleteObjectsOutput{}, nil
+}
+
+func (f *fakeS3) ListObjectsV2(_ context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, *in.Prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	start := 0
+	if in.ContinuationToken != nil {
+		start, _ = strconv.Atoi(*in.ContinuationToken)
+	}
+	limit := int32(1000)
+	if in.MaxKeys != nil {
+		limit = *in.MaxKeys
+	}
+	end := min(start+int(limit), len(keys))
+
+	out := &s3.ListObjectsV2Output{IsTruncated: helpers.PtrOf(end < len(keys))}
+	for _, k := range keys[start:end] {
+		out.Contents = append(out.Contents, types.Object{Key: &k})
+	}
+	if end < len(keys) {
+		next := strconv.Itoa(end)
+		out.NextContinuationToken = &next
+	}
+	return out, nil
+}
+
+func TestNew_Validation(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("expected error for nil config")
+	}
+	if _, err := New(&Config{Bucket: "b"}); err == nil {
+		t.Error("expected error for missing client")
+	}
+	if _, err := New(&Config{Client: newFakeS3()}); err == nil {
+		t.Error("expected error for missing bucket")
+	}
+}
+
+func TestStore_CRUD(t *testing.T) {
+	fake := newFakeS3()
+	store, err := New(&Config{Client: fake, Bucket: "artifacts", Prefix: "cache/"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if data, err := store.Get("missing"); err != nil || data != nil {
+		t.Errorf("Get(missing) = %v, %v; want nil, nil", data, err)
+	}
+
+	transcript := bytes.Repeat([]byte("long transcript "), 1000)
+	if err := store.Set("call-1", transcript, time.Hour); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, ok := fake.objects["cache/call-1"]; !ok {
+		t.Fatal("expected object stored under prefix")
+	}
+
+	data, err := store.Get("call-1")
+	if err != nil || !bytes.Equal(data, transcript) {
+		t.Errorf("Get() returned %d bytes, err %v", len(data), err)
+	}
+	if !store.Exists("call-1") {
+		t.Error("expected key to exist")
+	}
+
+	if err := store.Delete("call-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if store.Exists("call-1") {
+		t.Error("expected key to be deleted")
+	}
+}
+
+func TestStore_Expiry(t *testing.T) {
+	fake := newFakeS3()
+	store, _ := New(&Config{Client: fake, Bucket: "b"})
+
+	_ = store.Set("fresh", []byte("1"), time.Hour)
+	_ = store.Set("forever", []byte("2"), 0)
+	_ = store.Set("stale", []byte("3"), time.Hour)
+	fake.objects["stale"].metadata[expiresMetadataKey] = "1"
+
+	if data, _ := store.Get("stale"); data != nil {
+		t.Error("expired object must not be returned")
+	}
+	if store.Exists("stale") {
+		t.Error("expired object must not exist")
+	}
+	if keys := store.List(); !slices.Equal(keys, []string{"forever", "fresh"}) {
+		t.Errorf("List() = %v", keys)
+	}
+}
+
+func TestStore_ListPageAndClear(t *testing.T) {
+	fake := newFakeS3()
+	store, _ := New(&Config{Client: fake, Bucket: "b", Prefix: "p/"})
+	fake.objects["other/keep"] = fakeObject{data: []byte("x")}
+
+	for i := range 1005 {
+		_ = store.Set("k"+strconv.Itoa(i), []byte("v"), 0)
+	}
+
+	keys, next, err := store.ListPage(context.Background(), 10, "")
+	if err != nil || len(keys) != 10 || next == "" {
+		t.Fatalf("ListPage() = %d keys, next %q, err %v", len(keys), next, err)
+	}
+	if strings.HasPrefix(keys[0], "p/") {
+		t.Errorf("keys must be returned without prefix, got %q", keys[0])
+	}
+
+	if err := store.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if len(fake.objects) != 1 {
+		t.Errorf("expected only objects outside prefix to remain, got %d", len(fake.objects))
+	}
+	if fake.deleteCalls != 2 {
+		t.Errorf("expected 2 DeleteObjects batches, got %d", fake.deleteCalls)
+	}
+}
+
+func TestStore_WithCacheMiddleware(t *testing.T) {
+	store, _ := New(&Config{Client: newFakeS3(), Bucket: "b"})
+	mem := cache.NewCacheWithStore(store)
+
+	if err := mem.Set("ctx", []byte("retrieved context"), time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	data, err := mem.Get("ctx")
+	if err != nil || string(data) != "retrieved context" {
+		t.Errorf("Get() = %q, %v", data, err)
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	fake := newFakeS3()
+	fake.err = errors.New("access denied")
+	store, _ := New(&Config{Client: fake, Bucket: "b"})
+
+	if _, err := store.Get("k"); err == nil {
+		t.Error("expected Get error")
+	}
+	if err := store.Set("k", nil, 0); err == nil {
+		t.Error("expected Set error")
+	}
+	if keys := store.List(); keys != nil {
+		t.Errorf("expected nil keys on error, got %v", keys)
+	}
+}
@@ -0,0 +1,264 @@
+// Package classify assigns one label from a fixed set to a piece of text.
+//
+// Label asks the model to score every label rather than name a single one,
+// then normalizes the scores into a confidence. A threshold turns low-confidence
+// answers into a fallback label instead of a guess. A Classifier can be used on
+// its own, as a flow handler, or as the selector for multiagent.RouterWithSelector.
+package classify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/convert"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/multiagent"
+)
+
+// Result is the outcome of classifying one text
+type Result struct {
+	// Label is the chosen label, or the fallback when Confidence is below the threshold
+	Label string `json:"label"`
+	// Confidence is the normalized score of the best label, between 0 and 1
+	Confidence float64 `json:"confidence"`
+}
+
+// Example is a labeled text shown to the model as a few-shot example
+type Example struct {
+	Text  string
+	Label string
+}
+
+// Option configures a Classifier
+type Option func(*config)
+
+type config struct {
+	examples     []Example
+	descriptions map[string]string
+	threshold    float64
+	fallback     string
+	agentOptions []ai.AgentOption
+}
+
+// WithExamples adds few-shot examples to the prompt
+func WithExamples(examples ...Example) Option {
+	return func(cfg *config) {
+		cfg.examples = append(cfg.examples, examples...)
+	}
+}
+
+// WithThreshold sets the confidence below which the fallback label is returned
+func WithThreshold(threshold float64) Option {
+	return func(cfg *config) {
+		cfg.threshold = threshold
+	}
+}
+
+// WithFallback sets the label returned when confidence is below the threshold
+// (default: empty label)
+func WithFallback(label string) Option {
+	return func(cfg *config) {
+		cfg.fallback = label
+	}
+}
+
+// WithDescriptions explains what each label means, keyed by label
+func WithDescriptions(descriptions map[string]string) Option {
+	return func(cfg *config) {
+		cfg.descriptions = descriptions
+	}
+}
+
+// WithAgentOptions adds options to the classification agent, after its schema
+func WithAgentOptions(opts ...ai.AgentOption) Option {
+	return func(cfg *config) {
+		cfg.agentOptions = append(cfg.agentOptions, opts...)
+	}
+}
+
+// Classifier assigns labels to text. Create one with Label.
+type Classifier struct {
+	labels []string
+	config config
+	agent  calque.Handler
+}
+
+// labelScores is the structured answer requested from the model
+type labelScores struct {
+	Scores []labelScore `json:"scores" jsonschema:"required,description=One score for every label"`
+}
+
+type labelScore struct {
+	Label string  `json:"label" jsonschema:"required,description=Label exactly as listed"`
+	Score float64 `json:"score" jsonschema:"required,minimum=0,maximum=1,description=Probability that the label applies"`
+}
+
+// Label creates a classifier that picks one of labels for its input.
+//
+// Input: text to classify
+// Output: JSON-encoded Result
+// Behavior: BUFFERED - reads the whole input and makes one model call
+//
+// The model scores every label and the scores are normalized to sum to 1, so
+// Confidence is relative to the other labels rather than the model's own
+// estimate of certainty. Scores for labels outside the set are ignored. When
+// the best label's confidence is below the threshold (default: 0, accept any
+// answer), Result.Label is the fallback.
+//
+// Example:
+//
+//	classifier := classify.Label(client, []string{"billing", "bug", "feature"},
+//	    classify.WithExamples(classify.Example{Text: "I was charged twice", Label: "billing"}),
+//	    classify.WithThreshold(0.7),
+//	    classify.WithFallback("triage"))
+//
+//	result, err := classifier.Classify(ctx, ticket)
+func Label(client ai.Client, labels []string, opts ...Option) *Classifier {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	agentOpts := append([]ai.AgentOption{ai.WithSchemaFor[labelScores]()}, cfg.agentOptions...)
+	return &Classifier{
+		labels: labels,
+		config: cfg,
+		agent:  ai.Agent(client, agentOpts...),
+	}
+}
+
+// ServeFlow implements calque.Handler
+func (c *Classifier) ServeFlow(req *calque.Request, res *calque.Response) error {
+	var text string
+	if err := calque.Read(req, &text); err != nil {
+		return err
+	}
+	result, err := c.Classify(req.Context, text)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return calque.WrapErr(req.Context, err, "classify: failed to encode result")
+	}
+	return calque.Write(res, data)
+}
+
+// Classify labels text
+func (c *Classifier) Classify(ctx context.Context, text string) (Result, error) {
+	return c.classify(ctx, text, c.labels, c.config.descriptions)
+}
+
+// Select implements multiagent.Selector, classifying the request into a route.
+// The classifier's labels must be route IDs; when it has none, the route IDs
+// are used, described by the route descriptions.
+func (c *Classifier) Select(ctx context.Context, request string, routes []multiagent.RouteOption) (*multiagent.RouteSelection, error) {
+	labels := c.labels
+	descriptions := make(map[string]string, len(routes))
+	for _, route := range routes {
+		if len(c.labels) == 0 {
+			labels = append(labels, route.ID)
+		}
+		descriptions[route.ID] = route.Description
+	}
+	for label, description := range c.config.descriptions {
+		descriptions[label] = description
+	}
+
+	result, err := c.classify(ctx, request, labels, descriptions)
+	if err != nil {
+		return nil, err
+	}
+	if result.Label == "" {
+		return nil, calque.NewErr(ctx, fmt.Sprintf("classify: confidence %.2f is below the threshold", result.Confidence))
+	}
+	return &multiagent.RouteSelection{Route: result.Label, Confidence: result.Confidence}, nil
+}
+
+func (c *Classifier) classify(ctx context.Context, text string, labels []string, descriptions map[string]string) (Result, error) {
+	if len(labels) == 0 {
+		return Result{}, calque.NewErr(ctx, "classify: no labels")
+	}
+
+	var answer labelScores
+	err := calque.NewFlow().Use(c.agent).Run(ctx, c.prompt(text, labels, descriptions), convert.FromJSON(&answer))
+	if err != nil {
+		return Result{}, calque.WrapErr(ctx, err, "classify: model call failed")
+	}
+
+	best, confidence, ok := normalize(answer.Scores, labels)
+	if !ok {
+		return Result{}, calque.NewErr(ctx, "classify: model scored none of the labels")
+	}
+	if confidence < c.config.threshold {
+		return Result{Label: c.config.fallback, Confidence: confidence}, nil
+	}
+	return Result{Label: best, Confidence: confidence}, nil
+}
+
+func (c *Classifier) prompt(text string, labels []string, descriptions map[string]string) string {
+	var prompt strings.Builder
+	prompt.WriteString("Classify the text into exactly one of the labels below. " +
+		"Give every label a score between 0 and 1 for how likely it is the correct one.\n\nLabels:\n")
+	for _, label := range labels {
+		if description := descriptions[label]; description != "" {
+			fmt.Fprintf(&prompt, "- %s: %s\n", label, description)
+		} else {
+			fmt.Fprintf(&prompt, "- %s\n", label)
+		}
+	}
+	if len(c.config.examples) > 0 {
+		prompt.WriteString("\nExamples:\n")
+		for _, example := range c.config.examples {
+			fmt.Fprintf(&prompt, "<text>%s</text> → %s\n", example.Text, example.Label)
+		}
+	}
+	fmt.Fprintf(&prompt, "\n<text>\n%s\n</text>", text)
+	return prompt.String()
+}
+
+// normalize maps the model's scores onto labels and returns the best label
+// with its share of the total score. Unknown labels are ignored, negative
+// scores count as 0 and repeated labels keep their highest score.
+func normalize(scores []labelScore, labels []string) (string, float64, bool) {
+	byLabel := make(map[string]float64, len(labels))
+	for _, score := range scores {
+		for _, label := range labels {
+			if strings.EqualFold(strings.TrimSpace(score.Label), label) {
+				byLabel[label] = max(byLabel[label], score.Score)
+				break
+			}
+		}
+	}
+
+	var best string
+	var bestScore, total float64
+	for _, label := range labels {
+		score := byLabel[label]
+		total += score
+		if score > bestScore {
+			best, bestScore = label, score
+		}
+	}
+	if total == 0 {
+		return "", 0, false
+	}
+	return best, bestScore / total, true
+}
+
+// Egress implements calque.Egress, reporting where the classification client sends input
+func (c *Classifier) Egress() []string {
+	return calque.EgressOf(c.agent)
+}
+
+// Warmup implements calque.Warmer by warming the classification client
+func (c *Classifier) Warmup(ctx context.Context) error {
+	return calque.WarmupHandler(ctx, c.agent)
+}
+
+// Shutdown implements calque.Shutdowner by shutting down the classification client
+func (c *Classifier) Shutdown(ctx context.Context) error {
+	return calque.ShutdownHandler(ctx, c.agent)
+}
@@ -0,0 +1,228 @@
+package classify
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/multiagent"
+)
+
+// scoreClient answers every prompt with a fixed score list and records the prompts
+type scoreClient struct {
+	answer  string
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (c *scoreClient) Chat(r *calque.Request, w *calque.Response, _ *ai.AgentOptions) error {
+	var prompt string
+	if err := calque.Read(r, &prompt); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.prompts = append(c.prompts, prompt)
+	c.mu.Unlock()
+	return calque.Write(w, c.answer)
+}
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	labels := []string{"billing", "bug", "feature"}
+	tests := []struct {
+		name    string
+		answer  string
+		opts    []Option
+		want    Result
+		wantErr string
+	}{
+		{
+			name:   "scores normalized",
+			answer: `{"scores":[{"label":"billing","score":0.6},{"label":"bug","score":0.3},{"label":"feature","score":0.1}]}`,
+			want:   Result{Label: "billing", Confidence: 0.6},
+		},
+		{
+			name:   "unnormalized scores",
+			answer: `{"scores":[{"label":"billing","score":0.9},{"label":"bug","score":0.9},{"label":"feature","score":0.2}]}`,
+			want:   Result{Label: "billing", Confidence: 0.45},
+		},
+		{
+			name:   "labels matched case-insensitively, unknown labels ignored",
+			answer: `{"scores":[{"label":" Bug ","score":0.8},{"label":"outage","score":0.9},{"label":"bug","score":0.4}]}`,
+			want:   Result{Label: "bug", Confidence: 1},
+		},
+		{
+			name:   "below threshold returns fallback",
+			answer: `{"scores":[{"label":"billing","score":0.5},{"label":"bug","score":0.5}]}`,
+			opts:   []Option{WithThreshold(0.7), WithFallback("triage")},
+			want:   Result{Label: "triage", Confidence: 0.5},
+		},
+		{
+			name:   "below threshold without fallback",
+			answer: `{"scores":[{"label":"billing","score":0.5},{"label":"bug","score":0.5}]}`,
+			opts:   []Option{WithThreshold(0.7)},
+			want:   Result{Confidence: 0.5},
+		},
+		{
+			name:   "at threshold accepted",
+			answer: `{"scores":[{"label":"feature","score":0.7},{"label":"bug","score":0.3}]}`,
+			opts:   []Option{WithThreshold(0.7), WithFallback("triage")},
+			want:   Result{Label: "feature", Confidence: 0.7},
+		},
+		{
+			name:    "no label scored",
+			answer:  `{"scores":[{"label":"outage","score":1},{"label":"bug","score":0}]}`,
+			wantErr: "model scored none of the labels",
+		},
+		{
+			name:    "invalid answer",
+			answer:  `billing`,
+			wantErr: "classify: model call failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Label(&scoreClient{answer: tt.answer}, labels, tt.opts...).Classify(context.Background(), "I was charged twice")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got.Label != tt.want.Label || !approx(got.Confidence, tt.want.Confidence) {
+				t.Errorf("Classify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func approx(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
+
+func TestClassifyPrompt(t *testing.T) {
+	t.Parallel()
+
+	client := &scoreClient{answer: `{"scores":[{"label":"bug","score":1}]}`}
+	classifier := Label(client, []string{"billing", "bug"},
+		WithExamples(Example{Text: "App crashes on login", Label: "bug"}),
+		WithDescriptions(map[string]string{"billing": "Charges, refunds and invoices"}))
+
+	var got string
+	if err := calque.NewFlow().Use(classifier).Run(context.Background(), "Refund please", &got); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if got != `{"label":"bug","confidence":1}` {
+		t.Errorf("output = %s", got)
+	}
+
+	prompt := client.prompts[0]
+	for _, want := range []string{
+		"- billing: Charges, refunds and invoices\n- bug\n",
+		"<text>App crashes on login</text> → bug",
+		"<text>\nRefund please\n</text>",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestClassifyNoLabels(t *testing.T) {
+	t.Parallel()
+
+	_, err := Label(&scoreClient{answer: `{}`}, nil).Classify(context.Background(), "text")
+	if err == nil || !strings.Contains(err.Error(), "no labels") {
+		t.Errorf("error = %v, want no labels", err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	t.Parallel()
+
+	routes := []multiagent.RouteOption{
+		{ID: "math", Description: "Mathematical calculations"},
+		{ID: "code", Description: "Programming tasks"},
+	}
+
+	t.Run("labels from routes", func(t *testing.T) {
+		t.Parallel()
+
+		client := &scoreClient{answer: `{"scores":[{"label":"math","score":0.2},{"label":"code","score":0.8}]}`}
+		selection, err := Label(client, nil).Select(context.Background(), "fix my loop", routes)
+		if err != nil {
+			t.Fatalf("error = %v", err)
+		}
+		if selection.Route != "code" || !approx(selection.Confidence, 0.8) {
+			t.Errorf("selection = %+v", selection)
+		}
+		if !strings.Contains(client.prompts[0], "- math: Mathematical calculations\n- code: Programming tasks\n") {
+			t.Errorf("prompt = %s", client.prompts[0])
+		}
+	})
+
+	t.Run("below threshold without fallback", func(t *testing.T) {
+		t.Parallel()
+
+		client := &scoreClient{answer: `{"scores":[{"label":"math","score":0.5},{"label":"code","score":0.5}]}`}
+		_, err := Label(client, nil, WithThreshold(0.7)).Select(context.Background(), "hello", routes)
+		if err == nil || !strings.Contains(err.Error(), "below the threshold") {
+			t.Errorf("error = %v, want below the threshold", err)
+		}
+	})
+}
+
+func TestRouterWithClassifier(t *testing.T) {
+	t.Parallel()
+
+	handler := func(name string) calque.Handler {
+		return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+			var input string
+			if err := calque.Read(req, &input); err != nil {
+				return err
+			}
+			return calque.Write(res, name+": "+input)
+		})
+	}
+
+	client := &scoreClient{answer: `{"scores":[{"label":"math","score":0.4},{"label":"code","score":0.6}]}`}
+	router := multiagent.RouterWithSelector(
+		Label(client, []string{"math", "code", "chat"}, WithThreshold(0.7), WithFallback("chat")),
+		multiagent.Route(handler("math"), "math", "Mathematical calculations", "calculate"),
+		multiagent.Route(handler("code"), "code", "Programming tasks", "code"),
+		multiagent.Route(handler("chat"), "chat", "Everything else", ""),
+	)
+
+	var got string
+	if err := calque.NewFlow().Use(router).Run(context.Background(), "hello", &got); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if got != "chat: hello" {
+		t.Errorf("output = %q, want the fallback route", got)
+	}
+}
+
+// remoteClient is a client on another host
+type remoteClient struct{ ai.Client }
+
+func (remoteClient) Egress() []string { return []string{"api.example.com"} }
+
+func TestClassifierEgress(t *testing.T) {
+	if got := calque.DescribeHandler(Label(remoteClient{ai.NewMockClient("{}")}, []string{"a", "b"})).Egress; !slices.Equal(got, []string{"api.example.com"}) {
+		t.Errorf("egress = %v, want the client's host", got)
+	}
+	if got := calque.DescribeHandler(Label(ai.NewMockClient("{}"), []string{"a", "b"})).Egress; got != nil {
+		t.Errorf("egress with a local client = %v, want none", got)
+	}
+}
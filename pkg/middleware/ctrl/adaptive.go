@@ -0,0 +1,389 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// AdaptiveConfig holds configuration for the AdaptiveConcurrency middleware
+type AdaptiveConfig struct {
+	// InitialConcurrency is the starting limit (default: 4)
+	InitialConcurrency int
+	// MinConcurrency is the floor the limit never drops below (default: 1)
+	MinConcurrency int
+	// MaxConcurrency is the ceiling the limit never grows above (default: 100)
+	MaxConcurrency int
+	// LatencyTarget is the p90 latency above which the limit is reduced; zero derives
+	// it from the observed baseline latency and LatencyTolerance
+	LatencyTarget time.Duration
+	// LatencyTolerance is how far p90 may exceed the baseline p50 before backing off (default: 2.0)
+	LatencyTolerance float64
+	// Backoff is the factor applied to the limit on overload (default: 0.5)
+	Backoff float64
+	// SampleSize is the number of recent latencies used for percentiles (default: 50)
+	SampleSize int
+	// IsOverload classifies handler errors as overload signals (default: IsOverloadError)
+	IsOverload func(error) bool
+	// OnLimitChange is called whenever the whole-number limit changes
+	OnLimitChange func(previous, current int)
+}
+
+// AdaptiveStats is a snapshot of the controller state.
+type AdaptiveStats struct {
+	Limit     int           `json:"limit"`     // Current concurrency limit
+	InFlight  int           `json:"in_flight"` // Requests currently inside the handler
+	Queued    int           `json:"queued"`    // Requests waiting for the limit to allow them
+	Successes int64         `json:"successes"` // Requests that completed without error
+	Overloads int64         `json:"overloads"` // Errors classified as overload (429, 503, ...)
+	Errors    int64         `json:"errors"`    // Other handler errors
+	P50       time.Duration `json:"p50"`       // Median latency over the sample window
+	P90       time.Duration `json:"p90"`       // 90th percentile latency over the sample window
+	Baseline  time.Duration `json:"baseline"`  // Uncongested latency estimate
+
+	PausedUntil time.Time `json:"paused_until,omitzero"` // Admissions held until a provider quota resets
+}
+
+// AdaptiveHandler runs a handler under an AIMD-controlled concurrency limit.
+//
+// It is created by AdaptiveConcurrency or AdaptiveConcurrencyWithConfig and
+// implements calque.Handler.
+type AdaptiveHandler struct {
+	handler calque.Handler
+	config  AdaptiveConfig
+
+	mu           sync.Mutex
+	limit        float64
+	inFlight     int
+	waiters      []chan struct{}
+	samples      []time.Duration // ring buffer of recent successful latencies
+	next         int
+	baseline     time.Duration
+	lastDecrease time.Time
+	pausedUntil  time.Time
+	stats        AdaptiveStats
+}
+
+// AdaptiveConcurrency adjusts how many requests run a handler at once from observed load
+//
+// Input: any data type (streaming - passed to the handler once admitted)
+// Output: handler output
+// Behavior: STREAMING - blocks while the adaptive limit is reached
+//
+// Implements additive-increase/multiplicative-decrease: every successful request
+// grows the limit by 1/limit (about one slot per round of requests), while an
+// overload error (HTTP 429/503, "rate limit", "overloaded") or a p90 latency
+// beyond the target multiplies it by Backoff. Decreases are spaced by the median
+// latency so one burst of 429s backs off once rather than collapsing to the floor.
+// The limit only grows while it is actually being used.
+//
+// When the wrapped client reports provider quota headers (see RecordRateLimit)
+// showing an exhausted quota, or a 429 with retry-after, no new requests are
+// admitted until the quota resets, up to MaxRateLimitWait.
+//
+// Use it in front of rate-limited AI APIs to find the highest sustainable
+// parallelism without hand-tuning MaxConcurrent. Errors are returned unchanged;
+// combine with ctrl.Retry to retry overloaded requests.
+//
+// Example:
+//
+//	llm := ctrl.AdaptiveConcurrency(ai.Agent(client))
+//	flow.Use(ctrl.Retry(llm, 3))
+//
+//	log.Printf("limit %d, p90 %s", llm.Stats().Limit, llm.Stats().P90)
+func AdaptiveConcurrency(handler calque.Handler) *AdaptiveHandler {
+	return AdaptiveConcurrencyWithConfig(handler, nil)
+}
+
+// AdaptiveConcurrencyWithConfig adjusts handler concurrency with custom configuration
+//
+// Input: any data type (streaming - passed to the handler once admitted)
+// Output: handler output
+// Behavior: STREAMING - blocks while the adaptive limit is reached
+//
+// Behaves like AdaptiveConcurrency using the given bounds, latency target and
+// overload classifier.
+//
+// Example:
+//
+//	llm := ctrl.AdaptiveConcurrencyWithConfig(agent, &ctrl.AdaptiveConfig{
+//		InitialConcurrency: 8,
+//		MaxConcurrency:     64,
+//		LatencyTarget:      5 * time.Second,
+//		OnLimitChange:      func(_, limit int) { concurrencyGauge.Set(float64(limit)) },
+//	})
+func AdaptiveConcurrencyWithConfig(handler calque.Handler, config *AdaptiveConfig) *AdaptiveHandler {
+	cfg := AdaptiveConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.MinConcurrency <= 0 {
+		cfg.MinConcurrency = 1
+	}
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = 100
+	}
+	cfg.MaxConcurrency = max(cfg.MaxConcurrency, cfg.MinConcurrency)
+	if cfg.InitialConcurrency <= 0 {
+		cfg.InitialConcurrency = 4
+	}
+	cfg.InitialConcurrency = min(max(cfg.InitialConcurrency, cfg.MinConcurrency), cfg.MaxConcurrency)
+	if cfg.LatencyTolerance <= 1 {
+		cfg.LatencyTolerance = 2.0
+	}
+	if cfg.Backoff <= 0 || cfg.Backoff >= 1 {
+		cfg.Backoff = 0.5
+	}
+	if cfg.SampleSize <= 0 {
+		cfg.SampleSize = 50
+	}
+	if cfg.IsOverload == nil {
+		cfg.IsOverload = IsOverloadError
+	}
+
+	return &AdaptiveHandler{
+		handler: handler,
+		config:  cfg,
+		limit:   float64(cfg.InitialConcurrency),
+		samples: make([]time.Duration, 0, cfg.SampleSize),
+	}
+}
+
+// ServeFlow implements calque.Handler
+func (a *AdaptiveHandler) ServeFlow(req *calque.Request, res *calque.Response) error {
+	if err := a.acquire(req); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err := a.handler.ServeFlow(req, res)
+
+	var quotaWait time.Duration
+	if info, ok := rateLimitSince(req.Context, start); ok && (info.Exhausted() || err != nil) {
+		quotaWait = min(info.Wait(), MaxRateLimitWait)
+	}
+	a.release(time.Since(start), err, quotaWait)
+	return err
+}
+
+// Describe implements calque.Describer, drawing the limiter around the wrapped handler
+func (a *AdaptiveHandler) Describe() calque.Node {
+	return calque.Node{Label: "adaptive concurrency", Kind: calque.NodeSequence, Children: describeAll([]calque.Handler{a.handler})}
+}
+
+// Warmup implements calque.Warmer by warming the wrapped handler
+func (a *AdaptiveHandler) Warmup(ctx context.Context) error {
+	return calque.WarmupHandler(ctx, a.handler)
+}
+
+// Shutdown implements calque.Shutdowner by shutting down the wrapped handler
+func (a *AdaptiveHandler) Shutdown(ctx context.Context) error {
+	return calque.ShutdownHandler(ctx, a.handler)
+}
+
+// Stats returns a snapshot of the current limit, load and latency percentiles
+func (a *AdaptiveHandler) Stats() AdaptiveStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stats := a.stats
+	stats.Limit = int(a.limit)
+	stats.InFlight = a.inFlight
+	stats.Queued = len(a.waiters)
+	if a.paused() {
+		stats.PausedUntil = a.pausedUntil
+	}
+	stats.P50, stats.P90 = a.percentiles()
+	stats.Baseline = a.baseline
+	return stats
+}
+
+// acquire waits until the request fits under the current limit
+func (a *AdaptiveHandler) acquire(req *calque.Request) error {
+	a.mu.Lock()
+	if a.inFlight < int(a.limit) && len(a.waiters) == 0 && !a.paused() {
+		a.inFlight++
+		a.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	a.waiters = append(a.waiters, ready)
+	a.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-req.Context.Done():
+		a.mu.Lock()
+		if i := slices.Index(a.waiters, ready); i >= 0 {
+			a.waiters = slices.Delete(a.waiters, i, i+1)
+			a.mu.Unlock()
+		} else {
+			// Admitted while cancelled; give the slot back
+			a.inFlight--
+			a.admit()
+			a.mu.Unlock()
+		}
+		return calque.WrapErr(req.Context, req.Context.Err(), "adaptive concurrency wait cancelled")
+	}
+}
+
+// release records the outcome, adjusts the limit and admits waiters
+func (a *AdaptiveHandler) release(latency time.Duration, err error, quotaWait time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if quotaWait > 0 {
+		a.pause(quotaWait)
+	}
+
+	saturated := a.inFlight >= int(a.limit)
+	a.inFlight--
+	previous := int(a.limit)
+
+	switch {
+	case err == nil:
+		a.stats.Successes++
+		a.record(latency)
+		if a.congested() {
+			a.decrease()
+		} else if saturated || len(a.waiters) > 0 {
+			a.limit = math.Min(a.limit+1/a.limit, float64(a.config.MaxConcurrency))
+		}
+	case a.config.IsOverload(err):
+		a.stats.Overloads++
+		a.decrease()
+	default:
+		a.stats.Errors++
+	}
+
+	if current := int(a.limit); current != previous && a.config.OnLimitChange != nil {
+		a.config.OnLimitChange(previous, current)
+	}
+	a.admit()
+}
+
+// admit wakes queued requests while there is room under the limit (must hold mu)
+func (a *AdaptiveHandler) admit() {
+	if a.paused() {
+		return
+	}
+	for len(a.waiters) > 0 && a.inFlight < int(a.limit) {
+		next := a.waiters[0]
+		a.waiters = a.waiters[1:]
+		a.inFlight++
+		close(next)
+	}
+}
+
+// pause holds admissions for d, extending any current pause (must hold mu)
+func (a *AdaptiveHandler) pause(d time.Duration) {
+	until := time.Now().Add(d)
+	if !until.After(a.pausedUntil) {
+		return
+	}
+	a.pausedUntil = until
+	time.AfterFunc(d, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.admit()
+	})
+}
+
+// paused reports whether admissions are held for a quota reset (must hold mu)
+func (a *AdaptiveHandler) paused() bool {
+	return time.Now().Before(a.pausedUntil)
+}
+
+// decrease applies multiplicative backoff, at most once per median latency (must hold mu)
+func (a *AdaptiveHandler) decrease() {
+	p50, _ := a.percentiles()
+	if time.Since(a.lastDecrease) < p50 {
+		return
+	}
+	a.lastDecrease = time.Now()
+	a.limit = math.Max(math.Floor(a.limit*a.config.Backoff), float64(a.config.MinConcurrency))
+}
+
+// record adds a latency sample and updates the baseline once the window is full (must hold mu)
+func (a *AdaptiveHandler) record(latency time.Duration) {
+	if len(a.samples) < a.config.SampleSize {
+		a.samples = append(a.samples, latency)
+	} else {
+		a.samples[a.next] = latency
+		a.next = (a.next + 1) % a.config.SampleSize
+	}
+	if len(a.samples) < a.config.SampleSize {
+		return
+	}
+
+	// Follow drops immediately, rises slowly so sustained congestion stays visible
+	p50, _ := a.percentiles()
+	if a.baseline == 0 || p50 < a.baseline {
+		a.baseline = p50
+	} else {
+		a.baseline += (p50 - a.baseline) / 100
+	}
+}
+
+// congested reports whether p90 latency exceeds the target (must hold mu)
+func (a *AdaptiveHandler) congested() bool {
+	if len(a.samples) < a.config.SampleSize {
+		return false
+	}
+	target := a.config.LatencyTarget
+	if target <= 0 {
+		target = time.Duration(float64(a.baseline) * a.config.LatencyTolerance)
+	}
+	_, p90 := a.percentiles()
+	return target > 0 && p90 > target
+}
+
+// percentiles returns p50 and p90 of the sample window (must hold mu)
+func (a *AdaptiveHandler) percentiles() (p50, p90 time.Duration) {
+	if len(a.samples) == 0 {
+		return 0, 0
+	}
+	sorted := slices.Clone(a.samples)
+	slices.Sort(sorted)
+	return sorted[len(sorted)*50/100], sorted[len(sorted)*90/100]
+}
+
+// IsOverloadError reports whether err signals that a provider is rate limiting or overloaded.
+//
+// Errors exposing an HTTP status via StatusCode() or HTTPStatusCode() match on 429
+// and 503; otherwise the message is checked for common provider phrasing such as
+// "429", "too many requests", "rate limit", "resource_exhausted" and "overloaded".
+func IsOverloadError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var withStatus interface{ StatusCode() int }
+	if errors.As(err, &withStatus) {
+		return isOverloadStatus(withStatus.StatusCode())
+	}
+	var withHTTPStatus interface{ HTTPStatusCode() int }
+	if errors.As(err, &withHTTPStatus) {
+		return isOverloadStatus(withHTTPStatus.HTTPStatusCode())
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, marker := range []string{"429", "too many requests", "rate limit", "resource_exhausted", "overloaded"} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func isOverloadStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
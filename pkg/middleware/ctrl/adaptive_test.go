@@ -0,0 +1,162 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+type statusError struct{ code int }
+
+func (e statusError) Error() string   { return fmt.Sprintf("status %d", e.code) }
+func (e statusError) StatusCode() int { return e.code }
+
+// providerHandler simulates an API that sleeps per call and returns 429 above capacity
+func providerHandler(capacity int32, delay time.Duration) calque.Handler {
+	var active atomic.Int32
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		n := active.Add(1)
+		defer active.Add(-1)
+		if capacity > 0 && n > capacity {
+			return statusError{code: 429}
+		}
+		time.Sleep(delay)
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+}
+
+func hammer(h calque.Handler, requests int) {
+	var wg sync.WaitGroup
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var out string
+			_ = calque.NewFlow().Use(h).Run(context.Background(), "x", &out)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestAdaptiveConcurrency_GrowsUnderHealthyLoad(t *testing.T) {
+	var changes atomic.Int32
+	adaptive := AdaptiveConcurrencyWithConfig(providerHandler(0, 2*time.Millisecond), &AdaptiveConfig{
+		InitialConcurrency: 2,
+		MaxConcurrency:     16,
+		LatencyTarget:      time.Second,
+		OnLimitChange:      func(_, _ int) { changes.Add(1) },
+	})
+
+	hammer(adaptive, 300)
+
+	stats := adaptive.Stats()
+	if stats.Limit <= 2 || stats.Limit > 16 {
+		t.Errorf("Limit = %d, want grown above 2 and capped at 16", stats.Limit)
+	}
+	if stats.Successes != 300 || stats.InFlight != 0 || stats.Queued != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if changes.Load() == 0 {
+		t.Error("OnLimitChange was never called")
+	}
+}
+
+func TestAdaptiveConcurrency_BacksOffOnOverload(t *testing.T) {
+	adaptive := AdaptiveConcurrencyWithConfig(providerHandler(3, 2*time.Millisecond), &AdaptiveConfig{
+		InitialConcurrency: 20,
+		MaxConcurrency:     20,
+		LatencyTarget:      time.Second,
+	})
+
+	hammer(adaptive, 200)
+
+	stats := adaptive.Stats()
+	if stats.Overloads == 0 {
+		t.Fatal("expected overload errors from the simulated provider")
+	}
+	if stats.Limit >= 20 {
+		t.Errorf("Limit = %d, want reduced below the initial 20", stats.Limit)
+	}
+	if stats.Errors != 0 {
+		t.Errorf("Errors = %d, 429s must be counted as overloads", stats.Errors)
+	}
+}
+
+func TestAdaptiveConcurrency_BacksOffOnLatency(t *testing.T) {
+	adaptive := AdaptiveConcurrencyWithConfig(providerHandler(0, 10*time.Millisecond), &AdaptiveConfig{
+		InitialConcurrency: 8,
+		LatencyTarget:      time.Millisecond,
+		SampleSize:         4,
+	})
+
+	for range 10 {
+		hammer(adaptive, 8)
+	}
+
+	if stats := adaptive.Stats(); stats.Limit != 1 || stats.P90 < 10*time.Millisecond {
+		t.Errorf("stats = %+v, want limit at the floor with p90 above target", stats)
+	}
+}
+
+func TestAdaptiveConcurrency_CancelledWhileQueued(t *testing.T) {
+	release := make(chan struct{})
+	var active, peak atomic.Int32
+	adaptive := AdaptiveConcurrencyWithConfig(gatedHandler(release, &active, &peak), &AdaptiveConfig{
+		InitialConcurrency: 1,
+		MaxConcurrency:     1,
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = runSemaphore(context.Background(), adaptive, "first")
+	}()
+	waitFor(t, func() bool { return adaptive.Stats().InFlight == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := runSemaphore(ctx, adaptive, "second"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("queued request error = %v, want deadline exceeded", err)
+	}
+
+	close(release)
+	<-done
+	if stats := adaptive.Stats(); stats.InFlight != 0 || stats.Queued != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestIsOverloadError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"status 429", statusError{429}, true},
+		{"status 503", statusError{503}, true},
+		{"status 500", statusError{500}, false},
+		{"wrapped status", fmt.Errorf("agent: %w", statusError{429}), true},
+		{"openai message", errors.New("POST /v1/chat: 429 Too Many Requests"), true},
+		{"gemini message", errors.New("rpc error: code = ResourceExhausted desc = RESOURCE_EXHAUSTED"), true},
+		{"anthropic message", errors.New("overloaded_error: Overloaded"), true},
+		{"rate limited", errors.New("rate limited, retry after 2s"), true},
+		{"other", errors.New("invalid api key"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsOverloadError(tt.err); got != tt.want {
+				t.Errorf("IsOverloadError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
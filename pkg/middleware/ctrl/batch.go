@@ -6,6 +6,7 @@ package ctrl
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
@@ -94,7 +95,7 @@ func BatchWithConfig(handler calque.Handler, config *BatchConfig) calque.Handler
 
 	go batcher.processBatches()
 
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		var input []byte
 		err := calque.Read(req, &input)
 		if err != nil {
@@ -123,6 +124,9 @@ func BatchWithConfig(handler calque.Handler, config *BatchConfig) calque.Handler
 			return req.Context.Err()
 		}
 	})
+	return calque.Described(h, func() calque.Node {
+		return calque.Node{Label: fmt.Sprintf("batch (max %d)", config.MaxSize), Kind: calque.NodeSequence, Children: describeAll([]calque.Handler{handler})}
+	}, handler)
 }
 
 // processBatches runs in background to collect and process batches
@@ -0,0 +1,219 @@
+package ctrl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// ErrCELRejected is returned by CEL when a boolean expression evaluates to false
+var ErrCELRejected = errors.New("rejected by CEL expression")
+
+// CELConfig holds configuration for the CEL middleware
+type CELConfig struct {
+	// Vars are extra variables available to the expression by name, e.g. thresholds
+	// kept in a flow file. Values must be JSON-like: strings, numbers, bools, lists and maps.
+	Vars map[string]any
+	// Raw writes string results without JSON quotes, e.g. to feed a prompt
+	Raw bool
+	// DropRejected writes nothing for rejected input instead of failing the request
+	DropRejected bool
+}
+
+// CELProgram is a compiled CEL expression.
+//
+// Expressions see these variables:
+//   - input: the payload decoded from JSON, or the raw text if it is not JSON
+//   - meta: a map with the request_id, trace_id, tenant and locale from the context
+//   - any names from CELConfig.Vars
+//
+// JSON numbers decode as doubles, so write 2.0 rather than 2 in arithmetic;
+// comparisons such as input.price > 10 work either way.
+type CELProgram struct {
+	program cel.Program
+	vars    map[string]any
+}
+
+// CompileCEL parses and type-checks a CEL expression.
+//
+// Use it to validate expressions up front, or to build predicates for Branch.
+//
+// Example:
+//
+//	urgent, err := ctrl.CompileCEL(`input.priority == "high"`, nil)
+//	if err != nil {
+//		return err
+//	}
+//	flow.Use(ctrl.Branch(urgent.Match, pagerHandler, queueHandler))
+func CompileCEL(expr string, config *CELConfig) (*CELProgram, error) {
+	cfg := celConfig(config)
+
+	opts := []cel.EnvOption{
+		cel.Variable("input", cel.DynType),
+		cel.Variable("meta", cel.MapType(cel.StringType, cel.StringType)),
+	}
+	for name := range cfg.Vars {
+		if name == "input" || name == "meta" {
+			return nil, fmt.Errorf("CEL variable %q is reserved", name)
+		}
+		opts = append(opts, cel.Variable(name, cel.DynType))
+	}
+	env, err := cel.NewEnv(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expr)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	// Check for cancellation periodically so long comprehensions can be interrupted
+	program, err := env.Program(ast, cel.InterruptCheckFrequency(100))
+	if err != nil {
+		return nil, err
+	}
+	return &CELProgram{program: program, vars: cfg.Vars}, nil
+}
+
+// Eval runs the expression against a payload, returning its result as a
+// JSON-like Go value (bool, float64, int64, string, []any, map[string]any or nil)
+func (p *CELProgram) Eval(ctx context.Context, input []byte) (any, error) {
+	activation := make(map[string]any, len(p.vars)+2)
+	for name, value := range p.vars {
+		activation[name] = value
+	}
+	activation["input"] = decodeCELInput(input)
+	activation["meta"] = map[string]string{
+		"request_id": calque.RequestID(ctx),
+		"trace_id":   calque.TraceID(ctx),
+		"tenant":     calque.Tenant(ctx),
+		"locale":     calque.Locale(ctx),
+	}
+
+	out, _, err := p.program.ContextEval(ctx, activation)
+	if err != nil {
+		return nil, err
+	}
+	switch out.Type() {
+	case types.BoolType, types.IntType, types.UintType, types.DoubleType, types.StringType:
+		return out.Value(), nil
+	case types.NullType:
+		return nil, nil
+	}
+	native, err := out.ConvertToNative(reflect.TypeFor[*structpb.Value]())
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode %s result as JSON: %w", out.Type().TypeName(), err)
+	}
+	return native.(*structpb.Value).AsInterface(), nil
+}
+
+// Match reports whether the expression evaluates to true for the payload.
+//
+// It has the signature Branch expects; evaluation errors and non-boolean
+// results count as false.
+func (p *CELProgram) Match(input []byte) bool {
+	result, err := p.Eval(context.Background(), input)
+	matched, ok := result.(bool)
+	return err == nil && ok && matched
+}
+
+// CEL evaluates a Common Expression Language program against the payload.
+//
+// Input: JSON document or plain text (buffered - reads entire input into memory)
+// Output: the input unchanged for true, or the JSON-encoded result of other expressions
+// Behavior: BUFFERED - must decode the whole payload before evaluating
+//
+// Boolean expressions filter: true passes the original input through and false
+// fails the request with ErrCELRejected. Any other result replaces the payload,
+// so one expression can reshape or compute a value. The expression is compiled
+// once; an invalid one makes every request fail. See CELProgram for the
+// variables available.
+//
+// Example:
+//
+//	// Only let through tickets for the billing queue
+//	flow.Use(ctrl.CEL(`input.queue == "billing" && input.amount > 100`))
+//
+//	// {"first":"Ada","last":"Lovelace"} → {"name":"Ada Lovelace","tenant":"acme"}
+//	flow.Use(ctrl.CEL(`{"name": input.first + " " + input.last, "tenant": meta.tenant}`))
+func CEL(expr string) calque.Handler {
+	return CELWithConfig(expr, nil)
+}
+
+// CELWithConfig evaluates a CEL program with extra variables and output options.
+//
+// Input: JSON document or plain text (buffered - reads entire input into memory)
+// Output: the input for true, nothing for false with DropRejected, or the expression result
+// Behavior: BUFFERED - must decode the whole payload before evaluating
+//
+// Example:
+//
+//	flow.Use(ctrl.CELWithConfig(`input.score >= threshold`, &ctrl.CELConfig{
+//		Vars:         map[string]any{"threshold": 0.8},
+//		DropRejected: true,
+//	}))
+func CELWithConfig(expr string, config *CELConfig) calque.Handler {
+	cfg := celConfig(config)
+	program, err := CompileCEL(expr, &cfg)
+	if err != nil {
+		return calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+			return calque.WrapErr(req.Context, err, "invalid CEL expression")
+		})
+	}
+
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var input []byte
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+
+		result, err := program.Eval(req.Context, input)
+		if err != nil {
+			return calque.WrapErr(req.Context, err, "CEL evaluation failed")
+		}
+
+		switch result := result.(type) {
+		case bool:
+			if result {
+				return calque.Write(res, input)
+			}
+			if cfg.DropRejected {
+				return nil
+			}
+			return calque.WrapErr(req.Context, ErrCELRejected, expr)
+		case string:
+			if cfg.Raw {
+				return calque.Write(res, result)
+			}
+		}
+		output, err := json.Marshal(result)
+		if err != nil {
+			return calque.WrapErr(req.Context, err, fmt.Sprintf("failed to encode %T result", result))
+		}
+		return calque.Write(res, output)
+	})
+}
+
+func celConfig(config *CELConfig) CELConfig {
+	if config == nil {
+		return CELConfig{}
+	}
+	return *config
+}
+
+// decodeCELInput decodes a JSON payload, falling back to the raw text
+func decodeCELInput(input []byte) any {
+	var doc any
+	if err := json.Unmarshal(input, &doc); err != nil {
+		return string(input)
+	}
+	return doc
+}
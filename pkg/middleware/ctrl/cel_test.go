@@ -0,0 +1,192 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestCEL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		expr    string
+		config  *CELConfig
+		input   string
+		want    string
+		wantErr error
+		errText string
+	}{
+		{
+			name:  "true passes input through",
+			expr:  `input.queue == "billing" && input.amount > 100`,
+			input: `{"queue":"billing","amount":250}`,
+			want:  `{"queue":"billing","amount":250}`,
+		},
+		{
+			name:    "false rejects",
+			expr:    `input.amount > 100`,
+			input:   `{"amount":5}`,
+			wantErr: ErrCELRejected,
+		},
+		{
+			name:   "false dropped",
+			expr:   `input.amount > 100`,
+			config: &CELConfig{DropRejected: true},
+			input:  `{"amount":5}`,
+			want:   "",
+		},
+		{
+			name:  "transform to object",
+			expr:  `{"name": input.first + " " + input.last, "tags": input.tags.filter(t, t != "spam")}`,
+			input: `{"first":"Ada","last":"Lovelace","tags":["vip","spam"]}`,
+			want:  `{"name":"Ada Lovelace","tags":["vip"]}`,
+		},
+		{
+			name:  "string result quoted",
+			expr:  `input.user.name`,
+			input: `{"user":{"name":"Ada"}}`,
+			want:  `"Ada"`,
+		},
+		{
+			name:   "raw string result",
+			expr:   `input.user.name`,
+			config: &CELConfig{Raw: true},
+			input:  `{"user":{"name":"Ada"}}`,
+			want:   "Ada",
+		},
+		{
+			name:  "arithmetic",
+			expr:  `input.price * 2.0`,
+			input: `{"price":1.5}`,
+			want:  "3",
+		},
+		{
+			name:  "plain text input",
+			expr:  `input.contains("refund")`,
+			input: "I want a refund",
+			want:  "I want a refund",
+		},
+		{
+			name:   "vars",
+			expr:   `input.score >= threshold && input.lang in langs`,
+			config: &CELConfig{Vars: map[string]any{"threshold": 0.8, "langs": []any{"en", "fr"}}},
+			input:  `{"score":0.9,"lang":"fr"}`,
+			want:   `{"score":0.9,"lang":"fr"}`,
+		},
+		{
+			name:    "invalid expression",
+			expr:    `input.amount >`,
+			input:   `{}`,
+			errText: "invalid CEL expression",
+		},
+		{
+			name:    "missing field",
+			expr:    `input.missing == 1`,
+			input:   `{}`,
+			errText: "CEL evaluation failed",
+		},
+		{
+			name:    "reserved var",
+			expr:    `true`,
+			config:  &CELConfig{Vars: map[string]any{"input": 1}},
+			errText: "reserved",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out strings.Builder
+			req := calque.NewRequest(context.Background(), strings.NewReader(tt.input))
+			err := CELWithConfig(tt.expr, tt.config).ServeFlow(req, calque.NewResponse(&out))
+
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ServeFlow() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.errText != "":
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Fatalf("ServeFlow() error = %v, want %q", err, tt.errText)
+				}
+			case err != nil:
+				t.Fatalf("ServeFlow() error = %v", err)
+			case out.String() != tt.want:
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCELMeta(t *testing.T) {
+	t.Parallel()
+
+	ctx := calque.WithTenant(context.Background(), "acme")
+	ctx = calque.WithLocale(ctx, "fr-FR")
+
+	var out string
+	flow := calque.NewFlow().Use(CELWithConfig(`meta.tenant + "/" + meta.locale`, &CELConfig{Raw: true}))
+	if err := flow.Run(ctx, "{}", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != "acme/fr-FR" {
+		t.Errorf("output = %q, want %q", out, "acme/fr-FR")
+	}
+}
+
+func TestCompileCEL(t *testing.T) {
+	t.Parallel()
+
+	if _, err := CompileCEL(`input.`, nil); err == nil {
+		t.Error("CompileCEL() accepted an invalid expression")
+	}
+
+	urgent, err := CompileCEL(`input.priority == "high"`, nil)
+	if err != nil {
+		t.Fatalf("CompileCEL() error = %v", err)
+	}
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{input: `{"priority":"high"}`, want: true},
+		{input: `{"priority":"low"}`, want: false},
+		{input: `{}`, want: false},
+		{input: `not json`, want: false},
+	}
+	for _, tt := range tests {
+		if got := urgent.Match([]byte(tt.input)); got != tt.want {
+			t.Errorf("Match(%s) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	var out string
+	branch := Branch(urgent.Match, calque.HandlerFunc(func(_ *calque.Request, res *calque.Response) error {
+		return calque.Write(res, "paged")
+	}), PassThrough())
+	if err := calque.NewFlow().Use(branch).Run(context.Background(), `{"priority":"high"}`, &out); err != nil || out != "paged" {
+		t.Errorf("Branch() = %q, %v", out, err)
+	}
+}
+
+func TestCELCancellation(t *testing.T) {
+	t.Parallel()
+
+	// Interrupts are checked every 100 comprehension iterations
+	program, err := CompileCEL(`input.all(x, x >= 0)`, nil)
+	if err != nil {
+		t.Fatalf("CompileCEL() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	input := "[" + strings.Repeat("1,", 1000) + "1]"
+	if _, err := program.Eval(ctx, []byte(input)); err == nil {
+		t.Error("Eval() ignored a cancelled context")
+	}
+}
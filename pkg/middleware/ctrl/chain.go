@@ -2,6 +2,9 @@ package ctrl
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"strings"
 
@@ -35,51 +38,191 @@ import (
 // Data is buffered between handlers in the chain, but each individual handler
 // can still stream internally.
 func Chain(handlers ...calque.Handler) calque.Handler {
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
-		if len(handlers) == 0 {
-			// Empty chain - just pass through
-			_, err := io.Copy(res.Data, req.Data)
-			return err
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		return runChain(req, res, handlers, nil)
+	})
+	return calque.Described(h, func() calque.Node {
+		return calque.Node{Label: "chain", Kind: calque.NodeSequence, Children: describeAll(handlers)}
+	}, handlers...)
+}
+
+// stageHandler holds a chain stage with its name
+type stageHandler struct {
+	name    string
+	handler calque.Handler
+}
+
+// ServeFlow implements the Handler interface for stageHandler
+func (s *stageHandler) ServeFlow(req *calque.Request, res *calque.Response) error {
+	return s.handler.ServeFlow(req, res)
+}
+
+// Warmup implements calque.Warmer by warming the wrapped handler
+func (s *stageHandler) Warmup(ctx context.Context) error {
+	return calque.WarmupHandler(ctx, s.handler)
+}
+
+// Shutdown implements calque.Shutdowner by shutting down the wrapped handler
+func (s *stageHandler) Shutdown(ctx context.Context) error {
+	return calque.ShutdownHandler(ctx, s.handler)
+}
+
+// Describe implements calque.Describer, labeling the wrapped handler's edge with the stage name
+func (s *stageHandler) Describe() calque.Node {
+	return calque.DescribeHandler(s.handler).WithEdge(s.name)
+}
+
+// Stage names a handler for NamedChain.
+//
+// Example:
+//
+//	ctrl.Stage("retrieve", retrieval.VectorSearch(store, nil))
+func Stage(name string, handler calque.Handler) calque.Handler {
+	return &stageHandler{name: name, handler: handler}
+}
+
+// StageError reports which stage of a NamedChain failed
+type StageError struct {
+	Index int    // Position of the failing stage, starting at 0
+	Name  string // Stage name, or "stage_<index>" for handlers not wrapped with Stage
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("chain stage %d (%s) failed: %v", e.Index, e.Name, e.Err)
+}
+
+func (e *StageError) Unwrap() error { return e.Err }
+
+// FailedStage returns the NamedChain stage that caused err
+//
+// Example:
+//
+//	if stage, ok := ctrl.FailedStage(err); ok {
+//		log.Printf("stage %d (%s) failed", stage.Index, stage.Name)
+//	}
+func FailedStage(err error) (*StageError, bool) {
+	var stageErr *StageError
+	if errors.As(err, &stageErr) {
+		return stageErr, true
+	}
+	return nil, false
+}
+
+// NamedChain is Chain with named stages, so a failure says where it happened.
+//
+// Input: any data type (buffered between stages)
+// Output: last stage's output
+// Behavior: BUFFERED - runs stages sequentially with context propagation, as Chain
+//
+// Each stage runs in a trace span named "stage <name>". A failing stage's
+// error is returned as a *StageError carrying the stage's name and index
+// (see FailedStage), which observability.MetricsHandler adds as a "stage"
+// label on the errors counter. Handlers not wrapped with Stage are named
+// "stage_<index>".
+//
+// Example:
+//
+//	flow.Use(ctrl.NamedChain(
+//	    ctrl.Stage("retrieve", retrieval.VectorSearch(store, nil)),
+//	    ctrl.Stage("generate", ai.Agent(client)),
+//	))
+func NamedChain(stages ...calque.Handler) calque.Handler {
+	named := make([]*stageHandler, len(stages))
+	handlers := make([]calque.Handler, len(stages))
+	for i, h := range stages {
+		if s, ok := h.(*stageHandler); ok {
+			named[i] = s
+		} else {
+			named[i] = &stageHandler{name: fmt.Sprintf("stage_%d", i), handler: h}
 		}
+		handlers[i] = named[i]
+	}
 
-		// Read all input data for buffered sequential processing
-		var inputData []byte
-		err := calque.Read(req, &inputData)
-		if err != nil {
-			return err
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		return runChain(req, res, handlers, named)
+	})
+	return calque.Described(h, func() calque.Node {
+		return calque.Node{Label: "chain", Kind: calque.NodeSequence, Children: describeAll(handlers)}
+	}, handlers...)
+}
+
+// runChain executes handlers sequentially, buffering data and propagating
+// context between them. When stages is set, each handler runs in a span and
+// its error is reported as a *StageError.
+func runChain(req *calque.Request, res *calque.Response, handlers []calque.Handler, stages []*stageHandler) error {
+	if len(handlers) == 0 {
+		// Empty chain - just pass through
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	}
+
+	// Read all input data for buffered sequential processing
+	var inputData []byte
+	err := calque.Read(req, &inputData)
+	if err != nil {
+		return err
+	}
+
+	// Execute handlers sequentially with context propagation
+	currentData := inputData
+	currentCtx := req.Context
+
+	for i, handler := range handlers {
+		stageReq := &calque.Request{
+			Context: currentCtx,
+			Data:    io.NopCloser(strings.NewReader(string(currentData))),
 		}
 
-		// Execute handlers sequentially with context propagation
-		currentData := inputData
-		currentCtx := req.Context
-
-		for i, handler := range handlers {
-			if i == len(handlers)-1 {
-				// Last handler - write directly to final output (allows streaming)
-				finalReq := &calque.Request{
-					Context: currentCtx,
-					Data:    io.NopCloser(strings.NewReader(string(currentData))),
-				}
-				return handler.ServeFlow(finalReq, res)
-			}
-
-			// Intermediate handler - buffer output
-			var buf bytes.Buffer
-			tempReq := &calque.Request{
-				Context: currentCtx,
-				Data:    io.NopCloser(strings.NewReader(string(currentData))),
-			}
-			tempRes := &calque.Response{Data: &buf}
-
-			if err := handler.ServeFlow(tempReq, tempRes); err != nil {
-				return err
-			}
-
-			// Update data and context for next handler
-			currentData = buf.Bytes()
-			currentCtx = tempReq.Context // Context may have been modified by handler
+		if i == len(handlers)-1 {
+			// Last handler - write directly to final output (allows streaming)
+			return serveStage(stageReq, res, handler, stages, i)
 		}
 
-		return nil
-	})
+		// Intermediate handler - buffer output
+		var buf bytes.Buffer
+		if err := serveStage(stageReq, &calque.Response{Data: &buf}, handler, stages, i); err != nil {
+			return err
+		}
+
+		// Update data and context for next handler
+		currentData = buf.Bytes()
+		currentCtx = stageReq.Context // Context may have been modified by handler
+	}
+
+	return nil
+}
+
+// serveStage runs one chain handler, in a span with stage errors when the chain is named
+func serveStage(req *calque.Request, res *calque.Response, handler calque.Handler, stages []*stageHandler, index int) error {
+	if stages == nil {
+		return handler.ServeFlow(req, res)
+	}
+
+	stage := stages[index]
+	parent := req.Context
+	ctx, span := calque.StartSpan(parent, "stage "+stage.name)
+	span.SetAttribute("calque.stage.name", stage.name)
+	span.SetAttribute("calque.stage.index", index)
+
+	req.Context = ctx
+	err := handler.ServeFlow(req, res)
+	span.End(err)
+	if req.Context == ctx {
+		// Keep the stage span out of the next stage's context
+		req.Context = parent
+	}
+	if err != nil {
+		return &StageError{Index: index, Name: stage.name, Err: err}
+	}
+	return nil
+}
+
+// describeAll describes each handler for diagrams
+func describeAll(handlers []calque.Handler) []calque.Node {
+	nodes := make([]calque.Node, len(handlers))
+	for i, h := range handlers {
+		nodes[i] = calque.DescribeHandler(h)
+	}
+	return nodes
 }
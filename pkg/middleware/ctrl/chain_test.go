@@ -2,6 +2,7 @@ package ctrl
 
 import (
 	"context"
+	"errors"
 	"io"
 	"strings"
 	"sync"
@@ -210,3 +211,101 @@ func TestChain_DoesNotDeadlock(t *testing.T) {
 		t.Errorf("Expected result %q, got %q", "test", result)
 	}
 }
+
+// stageSpan records a span started by NamedChain
+type stageSpan struct {
+	name  string
+	attrs map[string]any
+	err   error
+}
+
+func (s *stageSpan) SetAttribute(key string, value any) { s.attrs[key] = value }
+func (s *stageSpan) End(err error)                      { s.err = err }
+
+func TestNamedChain(t *testing.T) {
+	upper := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var input string
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+		return calque.Write(res, strings.ToUpper(input))
+	})
+	errGenerate := errors.New("model unavailable")
+	failing := calque.HandlerFunc(func(_ *calque.Request, _ *calque.Response) error {
+		return errGenerate
+	})
+
+	t.Run("success", func(t *testing.T) {
+		var spans []*stageSpan
+		ctx := calque.WithSpanStarter(context.Background(), func(ctx context.Context, name string) (context.Context, calque.Span) {
+			span := &stageSpan{name: name, attrs: map[string]any{}}
+			spans = append(spans, span)
+			return ctx, span
+		})
+
+		var out string
+		chain := NamedChain(Stage("retrieve", PassThrough()), Stage("generate", upper))
+		if err := calque.NewFlow().Use(chain).Run(ctx, "hello", &out); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if out != "HELLO" {
+			t.Errorf("output = %q, want %q", out, "HELLO")
+		}
+		if len(spans) != 2 || spans[0].name != "stage retrieve" || spans[1].name != "stage generate" {
+			t.Fatalf("spans = %+v", spans)
+		}
+		if spans[1].attrs["calque.stage.name"] != "generate" || spans[1].attrs["calque.stage.index"] != 1 {
+			t.Errorf("span attributes = %v", spans[1].attrs)
+		}
+	})
+
+	tests := []struct {
+		name      string
+		stages    []calque.Handler
+		wantIndex int
+		wantName  string
+	}{
+		{
+			name:      "last stage fails",
+			stages:    []calque.Handler{Stage("retrieve", PassThrough()), Stage("generate", failing)},
+			wantIndex: 1,
+			wantName:  "generate",
+		},
+		{
+			name:      "intermediate stage fails",
+			stages:    []calque.Handler{Stage("retrieve", failing), Stage("generate", upper)},
+			wantIndex: 0,
+			wantName:  "retrieve",
+		},
+		{
+			name:      "unnamed stage",
+			stages:    []calque.Handler{Stage("retrieve", PassThrough()), upper, failing},
+			wantIndex: 2,
+			wantName:  "stage_2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out string
+			err := calque.NewFlow().Use(NamedChain(tt.stages...)).Run(context.Background(), "hello", &out)
+			if !errors.Is(err, errGenerate) {
+				t.Fatalf("Run() error = %v, want %v", err, errGenerate)
+			}
+			stage, ok := FailedStage(err)
+			if !ok {
+				t.Fatalf("FailedStage(%v) found no stage", err)
+			}
+			if stage.Index != tt.wantIndex || stage.Name != tt.wantName {
+				t.Errorf("FailedStage() = %d %q, want %d %q", stage.Index, stage.Name, tt.wantIndex, tt.wantName)
+			}
+			if !strings.Contains(err.Error(), tt.wantName) {
+				t.Errorf("error %q does not name stage %q", err, tt.wantName)
+			}
+		})
+	}
+
+	if _, ok := FailedStage(errGenerate); ok {
+		t.Error("FailedStage() found a stage in a plain error")
+	}
+}
@@ -0,0 +1,178 @@
+package ctrl
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Compression names a compression format. The values match HTTP
+// Content-Encoding and gRPC compressor names.
+type Compression string
+
+// Supported compression formats
+const (
+	Gzip Compression = "gzip"
+	Zstd Compression = "zstd"
+)
+
+// DefaultCompressMinBytes is the payload size below which remote hops skip
+// compression, since small payloads rarely shrink enough to pay for it
+const DefaultCompressMinBytes = 1024
+
+// Magic numbers at the start of compressed streams, used by Decompress
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Compress compresses the input stream.
+//
+// Input: any data
+// Output: compressed bytes
+// Behavior: STREAMING - compressed blocks are written as input arrives
+//
+// Use it to shrink large payloads, such as retrieved contexts, before they
+// leave the process, and Decompress on the receiving side.
+//
+// Example:
+//
+//	flow.Use(retrieval.VectorSearch(store, nil)).
+//		Use(ctrl.Compress(ctrl.Zstd)).
+//		Use(sink)
+func Compress(codec Compression) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		w, err := CompressWriter(req.Context, res.Data, codec)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, req.Data); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	})
+}
+
+// Decompress decompresses gzip or zstd input, detected from the stream header.
+// Input that isn't compressed passes through unchanged.
+//
+// Input: gzip, zstd or uncompressed bytes
+// Output: decompressed bytes
+// Behavior: STREAMING - output is written as compressed blocks arrive
+//
+// Example:
+//
+//	flow.Use(ctrl.Decompress()).Use(ai.Agent(client))
+func Decompress() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		r, err := DetectDecompressReader(req.Context, req.Data)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		_, err = io.Copy(res.Data, r)
+		return err
+	})
+}
+
+// CompressWriter returns a writer that compresses into w with codec.
+// Close flushes the compressed stream; it does not close w.
+func CompressWriter(ctx context.Context, w io.Writer, codec Compression) (io.WriteCloser, error) {
+	switch codec {
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		enc, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to create zstd encoder")
+		}
+		return enc, nil
+	default:
+		return nil, calque.NewErr(ctx, fmt.Sprintf("unsupported compression %q", codec))
+	}
+}
+
+// DecompressReader returns a reader that decompresses r with codec
+func DecompressReader(ctx context.Context, r io.Reader, codec Compression) (io.ReadCloser, error) {
+	switch codec {
+	case Gzip:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to read gzip stream")
+		}
+		return gz, nil
+	case Zstd:
+		dec, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to read zstd stream")
+		}
+		return dec.IOReadCloser(), nil
+	default:
+		return nil, calque.NewErr(ctx, fmt.Sprintf("unsupported compression %q", codec))
+	}
+}
+
+// DetectDecompressReader decompresses r if it starts with a gzip or zstd
+// header, and otherwise returns its data unchanged
+func DetectDecompressReader(ctx context.Context, r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	header, _ := buffered.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return DecompressReader(ctx, buffered, Gzip)
+	case bytes.HasPrefix(header, zstdMagic):
+		return DecompressReader(ctx, buffered, Zstd)
+	default:
+		return io.NopCloser(buffered), nil
+	}
+}
+
+// CompressAbove compresses r with codec when it holds at least minBytes,
+// reading no more than minBytes ahead to decide. It returns the reader to
+// send and whether it is compressed.
+//
+// Example:
+//
+//	body, compressed, err := ctrl.CompressAbove(ctx, req.Data, ctrl.Gzip, ctrl.DefaultCompressMinBytes)
+//	if compressed {
+//		httpReq.Header.Set("Content-Encoding", "gzip")
+//	}
+func CompressAbove(ctx context.Context, r io.Reader, codec Compression, minBytes int) (io.Reader, bool, error) {
+	head := make([]byte, minBytes)
+	n, err := io.ReadFull(r, head)
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		return bytes.NewReader(head[:n]), false, nil
+	case err != nil:
+		return nil, false, calque.WrapErr(ctx, err, "failed to read input")
+	}
+
+	// Validate the codec before handing back a stream that can't fail early
+	if codec != Gzip && codec != Zstd {
+		return nil, false, calque.NewErr(ctx, fmt.Sprintf("unsupported compression %q", codec))
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := CompressWriter(ctx, pw, codec)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if _, err := io.Copy(w, io.MultiReader(bytes.NewReader(head), r)); err != nil {
+			w.Close()
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(w.Close())
+	}()
+	return pr, true, nil
+}
@@ -0,0 +1,97 @@
+package ctrl
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	input := strings.Repeat("retrieved context ", 1000)
+
+	for _, codec := range []Compression{Gzip, Zstd} {
+		t.Run(string(codec), func(t *testing.T) {
+			var compressed []byte
+			if err := calque.NewFlow().Use(Compress(codec)).Run(context.Background(), input, &compressed); err != nil {
+				t.Fatalf("Compress() error = %v", err)
+			}
+			if len(compressed) >= len(input) {
+				t.Errorf("compressed %d bytes to %d", len(input), len(compressed))
+			}
+
+			var out string
+			if err := calque.NewFlow().Use(Decompress()).Run(context.Background(), compressed, &out); err != nil {
+				t.Fatalf("Decompress() error = %v", err)
+			}
+			if out != input {
+				t.Errorf("round trip changed the input")
+			}
+		})
+	}
+}
+
+func TestCompressUnsupported(t *testing.T) {
+	var out string
+	if err := calque.NewFlow().Use(Compress("brotli")).Run(context.Background(), "data", &out); err == nil {
+		t.Error("Compress() with an unknown codec should fail")
+	}
+}
+
+func TestDecompressPassesThroughPlainInput(t *testing.T) {
+	for _, input := range []string{"", "a", "plain text input"} {
+		var out string
+		if err := calque.NewFlow().Use(Decompress()).Run(context.Background(), input, &out); err != nil {
+			t.Fatalf("Decompress(%q) error = %v", input, err)
+		}
+		if out != input {
+			t.Errorf("Decompress(%q) = %q", input, out)
+		}
+	}
+}
+
+func TestCompressAbove(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		input    string
+		minBytes int
+		want     bool
+	}{
+		{name: "below threshold", input: "short", minBytes: 16, want: false},
+		{name: "at threshold", input: strings.Repeat("x", 16), minBytes: 16, want: true},
+		{name: "above threshold", input: strings.Repeat("x", 4096), minBytes: 16, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, compressed, err := CompressAbove(ctx, strings.NewReader(tt.input), Zstd, tt.minBytes)
+			if err != nil {
+				t.Fatalf("CompressAbove() error = %v", err)
+			}
+			if compressed != tt.want {
+				t.Errorf("compressed = %v, want %v", compressed, tt.want)
+			}
+
+			if compressed {
+				decoded, err := DecompressReader(ctx, body, Zstd)
+				if err != nil {
+					t.Fatalf("DecompressReader() error = %v", err)
+				}
+				defer decoded.Close()
+				body = decoded
+			}
+			data, err := io.ReadAll(body)
+			if err != nil || string(data) != tt.input {
+				t.Errorf("body = %d bytes, %v; want %d bytes", len(data), err, len(tt.input))
+			}
+		})
+	}
+
+	if _, _, err := CompressAbove(ctx, bytes.NewReader(make([]byte, 32)), "brotli", 16); err == nil {
+		t.Error("CompressAbove() with an unknown codec should fail")
+	}
+}
@@ -0,0 +1,225 @@
+package ctrl
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/cache"
+)
+
+// DefaultDedupeKeyPrefix namespaces dedupe entries in a shared store
+const DefaultDedupeKeyPrefix = "dedupe:"
+
+// DedupeKeyFunc derives a dedupe key from the request context and payload.
+//
+// Returning an empty key skips deduplication for that request.
+type DedupeKeyFunc func(ctx context.Context, input []byte) string
+
+// DedupeConfig holds configuration for the Dedupe middleware
+type DedupeConfig struct {
+	// Store records responses for the dedupe window (default: in-memory cache store)
+	Store cache.Store
+	// KeyFunc derives the key; nil hashes the payload with SHA-256
+	KeyFunc DedupeKeyFunc
+	// Window is how long a completed request suppresses duplicates
+	Window time.Duration
+	// CannedResponse, when set, is returned to duplicates instead of the original response
+	CannedResponse []byte
+	// KeyPrefix namespaces keys in the store (default: "dedupe:")
+	KeyPrefix string
+	// OnDuplicate is called with the key whenever a duplicate is short-circuited
+	OnDuplicate func(key string)
+}
+
+type deduper struct {
+	handler  calque.Handler
+	config   DedupeConfig
+	mu       sync.Mutex
+	inFlight map[string]*dedupeCall
+}
+
+type dedupeCall struct {
+	done   chan struct{}
+	output []byte
+	err    error
+}
+
+// Dedupe short-circuits duplicate requests seen within a window.
+//
+// Input: any data type (buffered - hashed while reading)
+// Output: handler output, or the recorded response for duplicates
+// Behavior: BUFFERED - reads the full input to compute its key
+//
+// The first request for a key runs the handler and records its output in the store
+// for the window. Duplicates arriving while it runs wait for it and share its result;
+// later duplicates within the window are answered from the store without calling the
+// handler. Failed requests are not recorded, so a retry after an error runs again.
+// Use it in front of paid or side-effecting handlers so client retries of identical
+// requests are not charged twice.
+//
+// Example:
+//
+//	llm := ctrl.Dedupe(ai.Agent(client), cache.NewInMemoryStore(), nil, time.Minute)
+//
+//	// Derive the key from an idempotency header stored in the context instead
+//	byHeader := func(ctx context.Context, _ []byte) string {
+//		return ctx.Value(idempotencyKey{}).(string)
+//	}
+//	charge := ctrl.Dedupe(chargeHandler, redisStore, byHeader, 24*time.Hour)
+func Dedupe(handler calque.Handler, store cache.Store, keyFn DedupeKeyFunc, window time.Duration) calque.Handler {
+	return DedupeWithConfig(handler, &DedupeConfig{
+		Store:   store,
+		KeyFunc: keyFn,
+		Window:  window,
+	})
+}
+
+// DedupeWithConfig short-circuits duplicate requests with custom configuration
+//
+// Input: any data type (buffered - hashed while reading)
+// Output: handler output, the recorded response, or config.CannedResponse for duplicates
+// Behavior: BUFFERED - reads the full input to compute its key
+//
+// Behaves like Dedupe. When config.CannedResponse is set, duplicates receive it
+// instead of the original output, and only a marker is kept in the store.
+//
+// Example:
+//
+//	dedupe := ctrl.DedupeWithConfig(handler, &ctrl.DedupeConfig{
+//		Window:         30 * time.Second,
+//		CannedResponse: []byte(`{"status":"duplicate"}`),
+//		OnDuplicate:    func(key string) { duplicates.Inc() },
+//	})
+func DedupeWithConfig(handler calque.Handler, config *DedupeConfig) calque.Handler {
+	d := &deduper{handler: handler, inFlight: make(map[string]*dedupeCall)}
+	if config != nil {
+		d.config = *config
+	}
+	if d.config.Store == nil {
+		d.config.Store = cache.NewInMemoryStore()
+	}
+	if d.config.KeyPrefix == "" {
+		d.config.KeyPrefix = DefaultDedupeKeyPrefix
+	}
+
+	return d
+}
+
+// Describe implements calque.Describer, drawing the dedupe window around the wrapped handler
+func (d *deduper) Describe() calque.Node {
+	return calque.Node{Label: fmt.Sprintf("dedupe (window %v)", d.config.Window), Kind: calque.NodeSequence, Children: describeAll([]calque.Handler{d.handler})}
+}
+
+// lead runs the handler for the first request with a key and records its
+// result. Waiters are woken even if the handler panics; they get an error
+// while the panic carries on up the leader's stack.
+func (d *deduper) lead(ctx context.Context, key string, call *dedupeCall, input io.Reader) {
+	call.err = calque.NewErr(ctx, "dedupe: handler panicked while serving a duplicated request")
+	defer func() {
+		d.mu.Lock()
+		delete(d.inFlight, key)
+		d.mu.Unlock()
+		close(call.done)
+	}()
+
+	var output bytes.Buffer
+	err := d.handler.ServeFlow(calque.NewRequest(ctx, input), calque.NewResponse(&output))
+	call.output, call.err = output.Bytes(), err
+
+	if call.err == nil && d.config.Window > 0 {
+		recorded := call.output
+		if d.config.CannedResponse != nil || recorded == nil {
+			recorded = []byte{}
+		}
+		if err := d.config.Store.Set(key, recorded, d.config.Window); err != nil {
+			calque.LogDebug(ctx, "dedupe failed to record response", "key", key, "error", err)
+		}
+	}
+}
+
+// Warmup implements calque.Warmer by warming the wrapped handler
+func (d *deduper) Warmup(ctx context.Context) error {
+	return calque.WarmupHandler(ctx, d.handler)
+}
+
+// Shutdown implements calque.Shutdowner by shutting down the wrapped handler
+func (d *deduper) Shutdown(ctx context.Context) error {
+	return calque.ShutdownHandler(ctx, d.handler)
+}
+
+// ServeFlow implements calque.Handler
+func (d *deduper) ServeFlow(req *calque.Request, res *calque.Response) error {
+	// Hash while buffering so the payload is only read once
+	var input bytes.Buffer
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(&input, hasher), req.Data); err != nil {
+		return calque.WrapErr(req.Context, err, "failed to read input for dedupe")
+	}
+
+	var key string
+	if d.config.KeyFunc != nil {
+		key = d.config.KeyFunc(req.Context, input.Bytes())
+	} else {
+		key = hex.EncodeToString(hasher.Sum(nil))
+	}
+	if key == "" {
+		return d.run(req.Context, input.Bytes(), res)
+	}
+	key = d.config.KeyPrefix + key
+
+	// Completed within the window
+	if recorded, err := d.config.Store.Get(key); err == nil && recorded != nil {
+		return d.duplicate(req.Context, key, recorded, res)
+	}
+
+	// Still running - wait for the first request and share its result
+	d.mu.Lock()
+	if call, ok := d.inFlight[key]; ok {
+		d.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-req.Context.Done():
+			return req.Context.Err()
+		}
+		if call.err != nil {
+			return call.err
+		}
+		return d.duplicate(req.Context, key, call.output, res)
+	}
+	call := &dedupeCall{done: make(chan struct{})}
+	d.inFlight[key] = call
+	d.mu.Unlock()
+
+	d.lead(req.Context, key, call, &input)
+	if call.err != nil {
+		return call.err
+	}
+	_, err := res.Data.Write(call.output)
+	return err
+}
+
+// run executes the handler without deduplication
+func (d *deduper) run(ctx context.Context, input []byte, res *calque.Response) error {
+	return d.handler.ServeFlow(calque.NewRequest(ctx, bytes.NewReader(input)), res)
+}
+
+func (d *deduper) duplicate(ctx context.Context, key string, recorded []byte, res *calque.Response) error {
+	calque.LogDebug(ctx, "dedupe short-circuited duplicate request", "key", key)
+	if d.config.OnDuplicate != nil {
+		d.config.OnDuplicate(key)
+	}
+
+	response := recorded
+	if d.config.CannedResponse != nil {
+		response = d.config.CannedResponse
+	}
+	_, err := res.Data.Write(response)
+	return err
+}
@@ -0,0 +1,212 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/cache"
+)
+
+// countingHandler upper-cases input and counts invocations
+func countingHandler(calls *atomic.Int32, delay time.Duration) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		calls.Add(1)
+		time.Sleep(delay)
+		data, err := io.ReadAll(req.Data)
+		if err != nil {
+			return err
+		}
+		_, err = res.Data.Write([]byte(strings.ToUpper(string(data))))
+		return err
+	})
+}
+
+func runDedupe(t *testing.T, handler calque.Handler, ctx context.Context, input string) (string, error) {
+	t.Helper()
+	var out string
+	err := calque.NewFlow().Use(handler).Run(ctx, input, &out)
+	return out, err
+}
+
+func TestDedupe(t *testing.T) {
+	tests := []struct {
+		name      string
+		inputs    []string
+		wantCalls int32
+	}{
+		{"identical requests run once", []string{"charge", "charge", "charge"}, 1},
+		{"distinct requests each run", []string{"a", "b", "c"}, 3},
+		{"mixed", []string{"a", "b", "a"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			handler := Dedupe(countingHandler(&calls, 0), cache.NewInMemoryStore(), nil, time.Minute)
+
+			for _, input := range tt.inputs {
+				out, err := runDedupe(t, handler, context.Background(), input)
+				if err != nil {
+					t.Fatalf("Run() error = %v", err)
+				}
+				if out != strings.ToUpper(input) {
+					t.Errorf("output = %q, want %q", out, strings.ToUpper(input))
+				}
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("handler calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDedupe_WindowExpires(t *testing.T) {
+	var calls atomic.Int32
+	handler := Dedupe(countingHandler(&calls, 0), cache.NewInMemoryStore(), nil, 20*time.Millisecond)
+
+	_, _ = runDedupe(t, handler, context.Background(), "x")
+	time.Sleep(40 * time.Millisecond)
+	_, _ = runDedupe(t, handler, context.Background(), "x")
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("handler calls = %d, want 2 after window expiry", got)
+	}
+}
+
+func TestDedupe_ConcurrentDuplicatesShareResult(t *testing.T) {
+	var calls atomic.Int32
+	handler := Dedupe(countingHandler(&calls, 30*time.Millisecond), nil, nil, time.Minute)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out, err := runDedupe(t, handler, context.Background(), "retry me")
+			if err != nil || out != "RETRY ME" {
+				t.Errorf("Run() = %q, %v", out, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("handler calls = %d, want 1", got)
+	}
+}
+
+func TestDedupe_KeyFunc(t *testing.T) {
+	type keyCtx struct{}
+	byContext := func(ctx context.Context, _ []byte) string {
+		key, _ := ctx.Value(keyCtx{}).(string)
+		return key
+	}
+
+	var calls atomic.Int32
+	handler := Dedupe(countingHandler(&calls, 0), cache.NewInMemoryStore(), byContext, time.Minute)
+
+	ctx := context.WithValue(context.Background(), keyCtx{}, "idem-1")
+	first, _ := runDedupe(t, handler, ctx, "first")
+	second, _ := runDedupe(t, handler, ctx, "different payload")
+	if first != "FIRST" || second != "FIRST" {
+		t.Errorf("outputs = %q, %q; duplicate key must replay the first response", first, second)
+	}
+
+	// Empty key disables deduplication
+	_, _ = runDedupe(t, handler, context.Background(), "first")
+	_, _ = runDedupe(t, handler, context.Background(), "first")
+	if got := calls.Load(); got != 3 {
+		t.Errorf("handler calls = %d, want 3", got)
+	}
+}
+
+func TestDedupeWithConfig_CannedResponse(t *testing.T) {
+	var calls atomic.Int32
+	var duplicates []string
+	handler := DedupeWithConfig(countingHandler(&calls, 0), &DedupeConfig{
+		Window:         time.Minute,
+		CannedResponse: []byte("duplicate"),
+		OnDuplicate:    func(key string) { duplicates = append(duplicates, key) },
+	})
+
+	first, _ := runDedupe(t, handler, context.Background(), "pay")
+	second, _ := runDedupe(t, handler, context.Background(), "pay")
+
+	if first != "PAY" || second != "duplicate" {
+		t.Errorf("outputs = %q, %q", first, second)
+	}
+	if len(duplicates) != 1 || !strings.HasPrefix(duplicates[0], DefaultDedupeKeyPrefix) {
+		t.Errorf("OnDuplicate keys = %v", duplicates)
+	}
+}
+
+func TestDedupe_ErrorsAreNotRecorded(t *testing.T) {
+	var calls atomic.Int32
+	failing := calque.HandlerFunc(func(_ *calque.Request, _ *calque.Response) error {
+		calls.Add(1)
+		return errors.New("upstream failed")
+	})
+	handler := Dedupe(failing, cache.NewInMemoryStore(), nil, time.Minute)
+
+	for range 2 {
+		if _, err := runDedupe(t, handler, context.Background(), "x"); err == nil {
+			t.Error("expected error")
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("handler calls = %d, want 2 (failures must be retried)", got)
+	}
+}
+
+func TestDedupe_PanicReleasesWaiters(t *testing.T) {
+	var calls atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	inner := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+			panic("boom")
+		}
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+	handler := Dedupe(inner, nil, nil, time.Minute)
+
+	leaderPanicked := make(chan any, 1)
+	go func() {
+		defer func() { leaderPanicked <- recover() }()
+		_ = handler.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("x")), calque.NewResponse(io.Discard))
+	}()
+	<-started
+
+	waiterErr := make(chan error, 1)
+	go func() {
+		_, err := runDedupe(t, handler, context.Background(), "x")
+		waiterErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond) // Let the duplicate start waiting
+	close(release)
+
+	if r := <-leaderPanicked; r != "boom" {
+		t.Errorf("leader recovered %v, want the handler's panic", r)
+	}
+	select {
+	case err := <-waiterErr:
+		if err == nil || !strings.Contains(err.Error(), "panicked") {
+			t.Errorf("waiter error = %v, want the panic reported", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter still blocked after the handler panicked")
+	}
+
+	if out, err := runDedupe(t, handler, context.Background(), "x"); err != nil || out != "x" {
+		t.Errorf("Run() after panic = %q, %v, want the handler to run again", out, err)
+	}
+}
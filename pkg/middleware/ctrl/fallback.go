@@ -56,7 +56,7 @@ func Fallback(handlers ...calque.Handler) calque.Handler {
 		}
 	}
 
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		var input []byte
 		err := calque.Read(req, &input)
 		if err != nil {
@@ -85,6 +85,14 @@ func Fallback(handlers ...calque.Handler) calque.Handler {
 
 		return calque.WrapErr(req.Context, lastErr, "all handlers failed")
 	})
+	return calque.Described(h, func() calque.Node {
+		children := describeAll(handlers)
+		children[0].Edge = "primary"
+		for i := 1; i < len(children); i++ {
+			children[i].Edge = "on failure"
+		}
+		return calque.Node{Label: "fallback", Kind: calque.NodeBranch, Children: children}
+	}, handlers...)
 }
 
 // Allow checks if requests should be allowed through
@@ -36,7 +36,7 @@ func PassThrough() calque.Handler {
 //	  textHandler,
 //	)
 func Branch(condition func([]byte) bool, ifHandler calque.Handler, elseHandler calque.Handler) calque.Handler {
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		var input []byte
 		err := calque.Read(req, &input)
 		if err != nil {
@@ -50,6 +50,12 @@ func Branch(condition func([]byte) bool, ifHandler calque.Handler, elseHandler c
 		}
 		return elseHandler.ServeFlow(req, res)
 	})
+	return calque.Described(h, func() calque.Node {
+		return calque.Node{Label: "branch", Kind: calque.NodeBranch, Children: []calque.Node{
+			calque.DescribeHandler(ifHandler).WithEdge("true"),
+			calque.DescribeHandler(elseHandler).WithEdge("false"),
+		}}
+	}, ifHandler, elseHandler)
 }
 
 // TeeReader copies input stream to multiple destinations while passing through.
@@ -97,7 +103,7 @@ func TeeReader(destinations ...io.Writer) calque.Handler {
 //	parallel := ctrl.Parallel(handler1, handler2, handler3)
 //	// All three handlers process the same input concurrently via TeeReader
 func Parallel(handlers ...calque.Handler) calque.Handler {
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		if len(handlers) == 0 {
 			_, err := io.Copy(res.Data, req.Data)
 			return err
@@ -174,6 +180,9 @@ func Parallel(handlers ...calque.Handler) calque.Handler {
 		err := calque.Write(res, combined)
 		return err
 	})
+	return calque.Described(h, func() calque.Node {
+		return calque.Node{Label: "parallel", Kind: calque.NodeParallel, Children: describeAll(handlers)}
+	}, handlers...)
 }
 
 // Timeout wraps a handler with timeout protection.
@@ -191,7 +200,7 @@ func Parallel(handlers ...calque.Handler) calque.Handler {
 //	timeoutHandler := ctrl.Timeout(someHandler, 30*time.Second)
 //	pipe.Use(timeoutHandler)
 func Timeout(handler calque.Handler, timeout time.Duration) calque.Handler {
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		timeoutCtx, cancel := context.WithTimeout(req.Context, timeout)
 		defer cancel()
 
@@ -209,6 +218,9 @@ func Timeout(handler calque.Handler, timeout time.Duration) calque.Handler {
 			return calque.WrapErr(req.Context, timeoutCtx.Err(), fmt.Sprintf("handler timeout after %v", timeout))
 		}
 	})
+	return calque.Described(h, func() calque.Node {
+		return calque.Node{Label: fmt.Sprintf("timeout %v", timeout), Kind: calque.NodeSequence, Children: describeAll([]calque.Handler{handler})}
+	}, handler)
 }
 
 // Retry wraps a handler with retry logic and exponential backoff.
@@ -219,14 +231,16 @@ func Timeout(handler calque.Handler, timeout time.Duration) calque.Handler {
 //
 // The function attempts to execute the wrapped handler up to maxAttempts times.
 // If the handler fails, it retries with exponential backoff (100ms, 200ms, 400ms, etc.).
-// The same input is replayed for each retry attempt.
+// The same input is replayed for each retry attempt. When the failed attempt
+// reported provider quota headers (see RecordRateLimit), Retry waits until the
+// quota resets instead, up to MaxRateLimitWait.
 //
 // Example:
 //
 //	retryHandler := ctrl.Retry(someHandler, 3)
 //	pipe.Use(retryHandler)
 func Retry(handler calque.Handler, maxAttempts int) calque.Handler {
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		var input []byte
 		err := calque.Read(req, &input)
 		if err != nil {
@@ -240,6 +254,7 @@ func Retry(handler calque.Handler, maxAttempts int) calque.Handler {
 
 			var output bytes.Buffer
 			tempRes := &calque.Response{Data: &output}
+			start := time.Now()
 			err := handler.ServeFlow(req, tempRes)
 			if err == nil {
 				_, writeErr := res.Data.Write(output.Bytes())
@@ -247,12 +262,27 @@ func Retry(handler calque.Handler, maxAttempts int) calque.Handler {
 			}
 			lastErr = err
 
-			// Exponential backoff
-			if attempt < maxAttempts-1 {
-				time.Sleep(time.Duration(1<<attempt) * 100 * time.Millisecond)
+			if attempt == maxAttempts-1 {
+				break
+			}
+
+			// Exponential backoff, or the provider's quota reset if it told us
+			delay := time.Duration(1<<attempt) * 100 * time.Millisecond
+			if info, ok := rateLimitSince(req.Context, start); ok {
+				delay = max(delay, min(info.Wait(), MaxRateLimitWait))
+			}
+			timer := time.NewTimer(delay)
+			select {
+			case <-req.Context.Done():
+				timer.Stop()
+				return calque.WrapErr(req.Context, lastErr, "retry cancelled")
+			case <-timer.C:
 			}
 		}
 
 		return calque.WrapErr(req.Context, lastErr, "retry exhausted")
 	})
+	return calque.Described(h, func() calque.Node {
+		return calque.Node{Label: fmt.Sprintf("retry (max %d)", maxAttempts), Kind: calque.NodeSequence, Children: describeAll([]calque.Handler{handler})}
+	}, handler)
 }
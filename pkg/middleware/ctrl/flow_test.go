@@ -883,3 +883,34 @@ func BenchmarkRetry(b *testing.B) {
 		})
 	}
 }
+
+func TestDescribe(t *testing.T) {
+	leaf := PassThrough()
+	tests := []struct {
+		name      string
+		handler   calque.Handler
+		wantLabel string
+		wantKind  calque.NodeKind
+		wantEdges []string
+	}{
+		{name: "branch", handler: Branch(func([]byte) bool { return true }, leaf, leaf), wantLabel: "branch", wantKind: calque.NodeBranch, wantEdges: []string{"true", "false"}},
+		{name: "parallel", handler: Parallel(leaf, leaf, leaf), wantLabel: "parallel", wantKind: calque.NodeParallel, wantEdges: []string{"", "", ""}},
+		{name: "timeout", handler: Timeout(leaf, 2*time.Second), wantLabel: "timeout 2s", wantKind: calque.NodeSequence, wantEdges: []string{""}},
+		{name: "retry", handler: Retry(leaf, 3), wantLabel: "retry (max 3)", wantKind: calque.NodeSequence, wantEdges: []string{""}},
+		{name: "chain", handler: Chain(leaf, leaf), wantLabel: "chain", wantKind: calque.NodeSequence, wantEdges: []string{"", ""}},
+		{name: "fallback", handler: Fallback(leaf, leaf, leaf), wantLabel: "fallback", wantKind: calque.NodeBranch, wantEdges: []string{"primary", "on failure", "on failure"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := calque.DescribeHandler(tt.handler)
+			if node.Label != tt.wantLabel || node.Kind != tt.wantKind || len(node.Children) != len(tt.wantEdges) {
+				t.Fatalf("node = %+v", node)
+			}
+			for i, child := range node.Children {
+				if child.Label != "ctrl.PassThrough" || child.Edge != tt.wantEdges[i] {
+					t.Errorf("child %d = %+v, want edge %q", i, child, tt.wantEdges[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package ctrl
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// MaxRateLimitWait caps how long Retry and AdaptiveConcurrency hold off for a provider quota reset
+const MaxRateLimitWait = time.Minute
+
+// RateLimitMetadataKey is the MetadataBus key holding the latest RateLimitInfo seen in a flow run
+const RateLimitMetadataKey = "ratelimit"
+
+// RateLimitWindow is one provider quota, e.g. requests or tokens per minute
+type RateLimitWindow struct {
+	Limit     int           // Quota for the window, 0 if the provider didn't report it
+	Remaining int           // Quota left in the current window
+	Reset     time.Duration // Time until the window is replenished
+}
+
+// Exhausted reports whether the window is known to have no quota left
+func (w RateLimitWindow) Exhausted() bool {
+	return w.Limit > 0 && w.Remaining <= 0
+}
+
+// RateLimitInfo is a provider's quota state, parsed from response headers
+type RateLimitInfo struct {
+	Requests   RateLimitWindow
+	Tokens     RateLimitWindow
+	RetryAfter time.Duration // From retry-after, usually only sent with 429 responses
+	Observed   time.Time     // When the response was received
+}
+
+// Wait returns how long to hold off before the next request: RetryAfter if
+// the provider sent one, otherwise the longest reset among exhausted windows,
+// or zero when there is quota left.
+func (i RateLimitInfo) Wait() time.Duration {
+	if i.RetryAfter > 0 {
+		return i.RetryAfter
+	}
+	var wait time.Duration
+	for _, w := range []RateLimitWindow{i.Requests, i.Tokens} {
+		if w.Exhausted() {
+			wait = max(wait, w.Reset)
+		}
+	}
+	return wait
+}
+
+// Exhausted reports whether any known window has no quota left
+func (i RateLimitInfo) Exhausted() bool {
+	return i.Requests.Exhausted() || i.Tokens.Exhausted()
+}
+
+// ParseRateLimitHeaders reads provider quota headers.
+//
+// Understands OpenAI-style x-ratelimit-{limit,remaining,reset}-{requests,tokens}
+// headers (resets as durations such as "6m0s"), Anthropic-style
+// anthropic-ratelimit-{requests,tokens}-{limit,remaining,reset} headers
+// (resets as RFC 3339 times), and retry-after / retry-after-ms. The bool is
+// false when none of them are present.
+//
+// Example:
+//
+//	if info, ok := ctrl.ParseRateLimitHeaders(resp.Header); ok && info.Exhausted() {
+//		time.Sleep(info.Wait())
+//	}
+func ParseRateLimitHeaders(h http.Header) (RateLimitInfo, bool) {
+	now := time.Now()
+	info := RateLimitInfo{Observed: now}
+	found := false
+
+	for _, window := range []struct {
+		name string
+		dst  *RateLimitWindow
+	}{{"requests", &info.Requests}, {"tokens", &info.Tokens}} {
+		openai := parseWindow(h, "x-ratelimit-limit-"+window.name, "x-ratelimit-remaining-"+window.name, "x-ratelimit-reset-"+window.name, now)
+		anthropic := parseWindow(h, "anthropic-ratelimit-"+window.name+"-limit", "anthropic-ratelimit-"+window.name+"-remaining", "anthropic-ratelimit-"+window.name+"-reset", now)
+		for _, parsed := range []*RateLimitWindow{openai, anthropic} {
+			if parsed != nil {
+				*window.dst = *parsed
+				found = true
+			}
+		}
+	}
+
+	if ms, err := strconv.ParseFloat(h.Get("retry-after-ms"), 64); err == nil && ms > 0 {
+		info.RetryAfter = time.Duration(ms * float64(time.Millisecond))
+		found = true
+	} else if after := parseRetryAfter(h.Get("retry-after"), now); after > 0 {
+		info.RetryAfter = after
+		found = true
+	}
+	return info, found
+}
+
+// parseWindow reads one quota window, nil if the remaining quota isn't reported
+func parseWindow(h http.Header, limitKey, remainingKey, resetKey string, now time.Time) *RateLimitWindow {
+	remaining, err := strconv.Atoi(h.Get(remainingKey))
+	if err != nil {
+		return nil
+	}
+	limit, _ := strconv.Atoi(h.Get(limitKey))
+	return &RateLimitWindow{Limit: limit, Remaining: remaining, Reset: parseReset(h.Get(resetKey), now)}
+}
+
+// parseReset accepts durations ("1s", "6m0s", "20ms") and RFC 3339 times
+func parseReset(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return max(d, 0)
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
+
+// parseRetryAfter accepts delay seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return max(time.Duration(seconds*float64(time.Second)), 0)
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
+
+// RecordRateLimit stores info on the request's MetadataBus under RateLimitMetadataKey,
+// where Retry, AdaptiveConcurrency and observability.RateLimitMetrics pick it up.
+// Provider clients call it for every response; it does nothing without a bus.
+func RecordRateLimit(ctx context.Context, info RateLimitInfo) {
+	bus := calque.GetMetadataBus(ctx)
+	if bus == nil {
+		return
+	}
+	bus.Set(RateLimitMetadataKey, info)
+	if info.Exhausted() {
+		calque.LogDebug(ctx, "provider quota exhausted", "remaining_requests", info.Requests.Remaining, "remaining_tokens", info.Tokens.Remaining, "wait", info.Wait())
+	}
+}
+
+// RateLimitFromContext returns the latest RateLimitInfo recorded in the request's flow run
+func RateLimitFromContext(ctx context.Context) (RateLimitInfo, bool) {
+	bus := calque.GetMetadataBus(ctx)
+	if bus == nil {
+		return RateLimitInfo{}, false
+	}
+	v, ok := bus.Get(RateLimitMetadataKey)
+	if !ok {
+		return RateLimitInfo{}, false
+	}
+	info, ok := v.(RateLimitInfo)
+	return info, ok
+}
+
+// rateLimitSince returns quota info recorded at or after since, so handlers
+// only react to responses from the call they just made
+func rateLimitSince(ctx context.Context, since time.Time) (RateLimitInfo, bool) {
+	info, ok := RateLimitFromContext(ctx)
+	if !ok || info.Observed.Before(since) {
+		return RateLimitInfo{}, false
+	}
+	return info, true
+}
+
+// RateLimitTransport records quota headers from every response, for
+// providers whose SDK accepts a custom http.Client.
+//
+// Example:
+//
+//	httpClient := &http.Client{Transport: ctrl.RateLimitTransport(nil)}
+func RateLimitTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return rateLimitTransport{next: next}
+}
+
+type rateLimitTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if resp != nil {
+		if info, ok := ParseRateLimitHeaders(resp.Header); ok {
+			RecordRateLimit(req.Context(), info)
+		}
+	}
+	return resp, err
+}
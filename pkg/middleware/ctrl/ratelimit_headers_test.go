@@ -0,0 +1,212 @@
+package ctrl
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func headers(kv ...string) http.Header {
+	h := http.Header{}
+	for i := 0; i < len(kv); i += 2 {
+		h.Set(kv[i], kv[i+1])
+	}
+	return h
+}
+
+func TestParseRateLimitHeaders(t *testing.T) {
+	resetAt := time.Now().Add(30 * time.Second).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name      string
+		header    http.Header
+		wantOK    bool
+		requests  RateLimitWindow
+		tokens    RateLimitWindow
+		wait      time.Duration
+		tolerance time.Duration
+		exhausted bool
+	}{
+		{
+			name: "openai",
+			header: headers(
+				"x-ratelimit-limit-requests", "500", "x-ratelimit-remaining-requests", "499", "x-ratelimit-reset-requests", "120ms",
+				"x-ratelimit-limit-tokens", "30000", "x-ratelimit-remaining-tokens", "0", "x-ratelimit-reset-tokens", "6m0s",
+			),
+			wantOK:    true,
+			requests:  RateLimitWindow{Limit: 500, Remaining: 499, Reset: 120 * time.Millisecond},
+			tokens:    RateLimitWindow{Limit: 30000, Remaining: 0, Reset: 6 * time.Minute},
+			wait:      6 * time.Minute,
+			exhausted: true,
+		},
+		{
+			name: "anthropic",
+			header: headers(
+				"anthropic-ratelimit-requests-limit", "50", "anthropic-ratelimit-requests-remaining", "0", "anthropic-ratelimit-requests-reset", resetAt,
+			),
+			wantOK:    true,
+			requests:  RateLimitWindow{Limit: 50, Remaining: 0, Reset: 30 * time.Second},
+			wait:      30 * time.Second,
+			tolerance: 2 * time.Second,
+			exhausted: true,
+		},
+		{
+			name:   "retry-after seconds",
+			header: headers("retry-after", "7"),
+			wantOK: true,
+			wait:   7 * time.Second,
+		},
+		{
+			name:   "retry-after-ms wins",
+			header: headers("retry-after", "7", "retry-after-ms", "250"),
+			wantOK: true,
+			wait:   250 * time.Millisecond,
+		},
+		{
+			name:     "quota left",
+			header:   headers("x-ratelimit-limit-requests", "10", "x-ratelimit-remaining-requests", "3"),
+			wantOK:   true,
+			requests: RateLimitWindow{Limit: 10, Remaining: 3},
+		},
+		{
+			name:   "limit without remaining is ignored",
+			header: headers("x-ratelimit-limit-requests", "10"),
+		},
+		{
+			name:   "no headers",
+			header: http.Header{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ok := ParseRateLimitHeaders(tt.header)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if info.Requests.Limit != tt.requests.Limit || info.Requests.Remaining != tt.requests.Remaining {
+				t.Errorf("Requests = %+v, want %+v", info.Requests, tt.requests)
+			}
+			if info.Tokens != tt.tokens {
+				t.Errorf("Tokens = %+v, want %+v", info.Tokens, tt.tokens)
+			}
+			if diff := info.Wait() - tt.wait; diff < -tt.tolerance || diff > tt.tolerance {
+				t.Errorf("Wait() = %v, want %v", info.Wait(), tt.wait)
+			}
+			if info.Exhausted() != tt.exhausted {
+				t.Errorf("Exhausted() = %v, want %v", info.Exhausted(), tt.exhausted)
+			}
+		})
+	}
+}
+
+func TestRecordRateLimit(t *testing.T) {
+	if _, ok := RateLimitFromContext(context.Background()); ok {
+		t.Error("RateLimitFromContext() found info without a bus")
+	}
+	RecordRateLimit(context.Background(), RateLimitInfo{}) // no bus, no panic
+
+	ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+	RecordRateLimit(ctx, RateLimitInfo{RetryAfter: time.Second})
+	if info, ok := RateLimitFromContext(ctx); !ok || info.RetryAfter != time.Second {
+		t.Errorf("RateLimitFromContext() = %+v, %v", info, ok)
+	}
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("x-ratelimit-limit-requests", "10")
+		w.Header().Set("x-ratelimit-remaining-requests", "4")
+	}))
+	defer server.Close()
+
+	ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := (&http.Client{Transport: RateLimitTransport(nil)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if info, ok := RateLimitFromContext(ctx); !ok || info.Requests.Remaining != 4 {
+		t.Errorf("RateLimitFromContext() = %+v, %v", info, ok)
+	}
+}
+
+// quotaHandler fails its first call with a 429 that asks callers to wait
+func quotaHandler(wait time.Duration, calls *int) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		*calls++
+		if *calls == 1 {
+			RecordRateLimit(req.Context, RateLimitInfo{RetryAfter: wait, Observed: time.Now()})
+			return statusError{code: 429}
+		}
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+}
+
+func TestRetryWaitsForQuotaReset(t *testing.T) {
+	calls := 0
+	flow := calque.NewFlow().Use(Retry(quotaHandler(300*time.Millisecond, &calls), 2))
+
+	start := time.Now()
+	var out string
+	if err := flow.Run(context.Background(), "hi", &out); err != nil || out != "hi" {
+		t.Fatalf("Run() = %q, %v", out, err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("retried after %v, want at least the 300ms retry-after", elapsed)
+	}
+}
+
+func TestRetryCancelledWhileWaiting(t *testing.T) {
+	calls := 0
+	flow := calque.NewFlow().Use(Retry(quotaHandler(time.Minute, &calls), 2))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var out string
+	if err := flow.Run(ctx, "hi", &out); err == nil || calls != 1 {
+		t.Errorf("Run() error = %v after %d calls, want cancellation before the retry", err, calls)
+	}
+}
+
+func TestAdaptiveConcurrency_PausesOnExhaustedQuota(t *testing.T) {
+	var first = true
+	handler := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		if first {
+			first = false
+			RecordRateLimit(req.Context, RateLimitInfo{
+				Requests: RateLimitWindow{Limit: 10, Remaining: 0, Reset: 200 * time.Millisecond},
+				Observed: time.Now(),
+			})
+		}
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+	adaptive := AdaptiveConcurrency(handler)
+	flow := calque.NewFlow().Use(adaptive)
+
+	var out string
+	if err := flow.Run(context.Background(), "a", &out); err != nil {
+		t.Fatal(err)
+	}
+	if adaptive.Stats().PausedUntil.IsZero() {
+		t.Error("Stats().PausedUntil not set after an exhausted quota")
+	}
+
+	start := time.Now()
+	if err := flow.Run(context.Background(), "b", &out); err != nil || out != "b" {
+		t.Fatalf("Run() = %q, %v", out, err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("second request admitted after %v, want held until the quota reset", elapsed)
+	}
+}
@@ -0,0 +1,237 @@
+// Package s3sink archives flow payloads to Amazon S3 with ctrl.TeeSinks.
+//
+// Each sampled request becomes one object, uploaded with S3 multipart upload
+// while the payload streams through, so large payloads are never held in
+// memory. Uploads of requests whose input fails are aborted.
+//
+// Example:
+//
+//	awsCfg, _ := config.LoadDefaultConfig(ctx)
+//	archive, err := s3sink.New(&s3sink.Config{
+//		Client: s3.NewFromConfig(awsCfg),
+//		Bucket: "payload-archive",
+//		Prefix: "prod/chat/",
+//	})
+//	flow.Use(ctrl.TeeSinksWithConfig(&ctrl.TeeConfig{SampleRate: 0.05}, archive))
+package s3sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+// Defaults and limits
+const (
+	// MinPartSize is the smallest part S3 accepts, except for the last part
+	MinPartSize = 5 << 20
+
+	DefaultTimeout = 30 * time.Second
+)
+
+// API is the subset of the S3 client used by the sink.
+//
+// *s3.Client satisfies this interface; tests can provide a fake.
+type API interface {
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+}
+
+// Config holds S3 sink configuration.
+type Config struct {
+	// S3 client (required), typically s3.NewFromConfig(awsCfg)
+	Client API
+
+	// Bucket name (required)
+	Bucket string
+
+	// Prefix for all archived objects, e.g. "prod/chat/"
+	Prefix string
+
+	// Key names the object for a request (default: prefix + "2006/01/02/" + request ID or timestamp)
+	Key func(ctx context.Context) string
+
+	// Content type recorded on archived objects (default: application/octet-stream)
+	ContentType string
+
+	// PartSize is the size of each uploaded part (default and minimum: MinPartSize)
+	PartSize int
+
+	// Timeout for each S3 call (default: 30s)
+	Timeout time.Duration
+}
+
+// Sink implements ctrl.TeeSink on top of an S3 bucket.
+type Sink struct {
+	config Config
+}
+
+var _ ctrl.TeeSink = (*Sink)(nil)
+
+// New creates an S3 archive sink.
+func New(config *Config) (*Sink, error) {
+	ctx := context.Background()
+	if config == nil || config.Client == nil {
+		return nil, calque.NewErr(ctx, "S3 client is required")
+	}
+	if config.Bucket == "" {
+		return nil, calque.NewErr(ctx, "S3 bucket is required")
+	}
+
+	cfg := *config
+	if cfg.ContentType == "" {
+		cfg.ContentType = "application/octet-stream"
+	}
+	if cfg.PartSize < MinPartSize {
+		cfg.PartSize = MinPartSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	return &Sink{config: cfg}, nil
+}
+
+// Egress implements calque.Egress so local-only flows reject the sink. An
+// *s3.Client with a BaseEndpoint, such as a local MinIO, reports that host.
+func (s *Sink) Egress() []string {
+	if client, ok := s.config.Client.(*s3.Client); ok {
+		if endpoint := client.Options().BaseEndpoint; endpoint != nil {
+			return calque.EgressHosts(*endpoint)
+		}
+	}
+	return []string{"s3://" + s.config.Bucket}
+}
+
+// Open implements ctrl.TeeSink by starting an upload for the request.
+// The multipart upload is created with the first part, so a request smaller
+// than PartSize is uploaded as a single part when it completes.
+func (s *Sink) Open(ctx context.Context) (io.WriteCloser, error) {
+	key := s.objectKey(ctx)
+	return &upload{sink: s, ctx: context.WithoutCancel(ctx), key: key}, nil
+}
+
+// objectKey names the archived object for a request
+func (s *Sink) objectKey(ctx context.Context) string {
+	if s.config.Key != nil {
+		return s.config.Prefix + s.config.Key(ctx)
+	}
+	now := time.Now().UTC()
+	name := calque.RequestID(ctx)
+	if name == "" {
+		name = now.Format("150405.000000000")
+	}
+	return s.config.Prefix + now.Format("2006/01/02/") + name
+}
+
+// context bounds one S3 call
+func (s *Sink) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.config.Timeout)
+}
+
+// upload streams one request's payload into a multipart upload
+type upload struct {
+	sink     *Sink
+	ctx      context.Context // Request context without cancellation, so uploads finish after the flow
+	key      string
+	uploadID *string
+	buf      bytes.Buffer
+	parts    []types.CompletedPart
+}
+
+// Write buffers data and uploads each full part
+func (u *upload) Write(p []byte) (int, error) {
+	u.buf.Write(p)
+	for u.buf.Len() >= u.sink.config.PartSize {
+		if err := u.uploadPart(u.buf.Next(u.sink.config.PartSize)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close uploads the remaining data and completes the upload
+func (u *upload) Close() error {
+	if u.buf.Len() > 0 || u.uploadID == nil {
+		if err := u.uploadPart(u.buf.Bytes()); err != nil {
+			u.Abort(err)
+			return err
+		}
+		u.buf.Reset()
+	}
+
+	ctx, cancel := u.sink.context(u.ctx)
+	defer cancel()
+	_, err := u.sink.config.Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          &u.sink.config.Bucket,
+		Key:             &u.key,
+		UploadId:        u.uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: u.parts},
+	})
+	if err != nil {
+		u.Abort(err)
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to complete upload of %s", u.key))
+	}
+	return nil
+}
+
+// Abort discards the upload so no partial object is left behind
+func (u *upload) Abort(error) {
+	if u.uploadID == nil {
+		return
+	}
+	ctx, cancel := u.sink.context(u.ctx)
+	defer cancel()
+	if _, err := u.sink.config.Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   &u.sink.config.Bucket,
+		Key:      &u.key,
+		UploadId: u.uploadID,
+	}); err != nil {
+		calque.LogWarn(ctx, "failed to abort S3 upload", "key", u.key, "error", err)
+	}
+	u.uploadID = nil
+}
+
+// uploadPart uploads one part, creating the multipart upload first if needed
+func (u *upload) uploadPart(data []byte) error {
+	ctx, cancel := u.sink.context(u.ctx)
+	defer cancel()
+
+	if u.uploadID == nil {
+		out, err := u.sink.config.Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:      &u.sink.config.Bucket,
+			Key:         &u.key,
+			ContentType: &u.sink.config.ContentType,
+		})
+		if err != nil {
+			return calque.WrapErr(ctx, err, fmt.Sprintf("failed to start upload of %s", u.key))
+		}
+		u.uploadID = out.UploadId
+	}
+
+	partNumber := int32(len(u.parts) + 1)
+	out, err := u.sink.config.Client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        &u.sink.config.Bucket,
+		Key:           &u.key,
+		UploadId:      u.uploadID,
+		PartNumber:    &partNumber,
+		Body:          bytes.NewReader(data),
+		ContentLength: int64Ptr(int64(len(data))),
+	})
+	if err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to upload part %d of %s", partNumber, u.key))
+	}
+	u.parts = append(u.parts, types.CompletedPart{ETag: out.ETag, PartNumber: &partNumber})
+	return nil
+}
+
+func int64Ptr(i int64) *int64 { return &i }
@@ -0,0 +1,173 @@
+package s3sink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+// fakeS3 assembles multipart uploads in memory
+type fakeS3 struct {
+	mu        sync.Mutex
+	uploads   map[string][][]byte
+	objects   map[string][]byte
+	aborted   []string
+	partSizes []int
+	partErr   error
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{uploads: map[string][][]byte{}, objects: map[string][]byte{}}
+}
+
+func (f *fakeS3) CreateMultipartUpload(_ context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := fmt.Sprintf("upload-%d", len(f.uploads)+1)
+	f.uploads[id] = nil
+	return &s3.CreateMultipartUploadOutput{UploadId: &id, Key: in.Key}, nil
+}
+
+func (f *fakeS3) UploadPart(_ context.Context, in *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.partErr != nil {
+		return nil, f.partErr
+	}
+	data, _ := io.ReadAll(in.Body)
+	f.uploads[*in.UploadId] = append(f.uploads[*in.UploadId], data)
+	f.partSizes = append(f.partSizes, len(data))
+	etag := fmt.Sprintf("etag-%d", *in.PartNumber)
+	return &s3.UploadPartOutput{ETag: &etag}, nil
+}
+
+func (f *fakeS3) CompleteMultipartUpload(_ context.Context, in *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	parts := f.uploads[*in.UploadId]
+	if len(parts) != len(in.MultipartUpload.Parts) {
+		return nil, errors.New("part count mismatch")
+	}
+	f.objects[*in.Key] = bytes.Join(parts, nil)
+	delete(f.uploads, *in.UploadId)
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (f *fakeS3) AbortMultipartUpload(_ context.Context, in *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.aborted = append(f.aborted, *in.Key)
+	delete(f.uploads, *in.UploadId)
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("New(nil) should fail")
+	}
+	if _, err := New(&Config{Client: newFakeS3()}); err == nil {
+		t.Error("New() without a bucket should fail")
+	}
+	sink, err := New(&Config{Client: newFakeS3(), Bucket: "archive", PartSize: 1})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if sink.config.PartSize != MinPartSize || sink.config.ContentType != "application/octet-stream" {
+		t.Errorf("config defaults = %+v", sink.config)
+	}
+}
+
+func TestSinkEgress(t *testing.T) {
+	tests := []struct {
+		name   string
+		client API
+		want   []string
+	}{
+		{"bucket", newFakeS3(), []string{"s3://archive"}},
+		{"aws", s3.New(s3.Options{Region: "us-east-1"}), []string{"s3://archive"}},
+		{"minio", s3.New(s3.Options{BaseEndpoint: aws.String("http://localhost:9000")}), nil},
+		{"remote endpoint", s3.New(s3.Options{BaseEndpoint: aws.String("https://storage.example.com")}), []string{"storage.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink, err := New(&Config{Client: tt.client, Bucket: "archive"})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := calque.DescribeHandler(ctrl.TeeSinks(sink)).Egress; !slices.Equal(got, tt.want) {
+				t.Errorf("egress = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSinkArchivesPayloads(t *testing.T) {
+	fake := newFakeS3()
+	sink, err := New(&Config{Client: fake, Bucket: "archive", Prefix: "prod/", Key: func(ctx context.Context) string {
+		return calque.RequestID(ctx) + ".txt"
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	large := strings.Repeat("x", MinPartSize*2+100)
+	for id, payload := range map[string]string{"small": "hello", "large": large} {
+		ctx := calque.WithRequestID(context.Background(), id)
+		var out string
+		if err := calque.NewFlow().Use(ctrl.TeeSinks(sink)).Run(ctx, payload, &out); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if out != payload {
+			t.Errorf("output changed for %s", id)
+		}
+	}
+
+	if string(fake.objects["prod/small.txt"]) != "hello" {
+		t.Errorf("small object = %q", fake.objects["prod/small.txt"])
+	}
+	if string(fake.objects["prod/large.txt"]) != large {
+		t.Errorf("large object has %d bytes, want %d", len(fake.objects["prod/large.txt"]), len(large))
+	}
+	for _, size := range fake.partSizes {
+		if size > MinPartSize {
+			t.Errorf("part of %d bytes exceeds the part size", size)
+		}
+	}
+}
+
+func TestSinkAbortsFailedUploads(t *testing.T) {
+	fake := newFakeS3()
+	sink, err := New(&Config{Client: fake, Bucket: "archive"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	w, err := sink.Open(calque.WithRequestID(context.Background(), "req-1"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := w.Write(bytes.Repeat([]byte("x"), MinPartSize+10)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	fake.partErr = errors.New("throttled")
+	if err := w.Close(); err == nil {
+		t.Fatal("Close() should report the failed part")
+	}
+	if len(fake.aborted) != 1 || !strings.HasSuffix(fake.aborted[0], "/req-1") || len(fake.objects) != 0 {
+		t.Errorf("aborted = %v, objects = %d", fake.aborted, len(fake.objects))
+	}
+}
@@ -0,0 +1,223 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// ErrQueueFull is returned by Semaphore when all slots are busy and the wait queue is at its limit.
+var ErrQueueFull = errors.New("semaphore queue is full")
+
+// SemaphoreConfig holds configuration for the Semaphore middleware
+type SemaphoreConfig struct {
+	// MaxConcurrent is the number of requests allowed to run the handler at once (default: 1)
+	MaxConcurrent int
+	// QueueLimit is the number of requests allowed to wait for a slot; 0 rejects as
+	// soon as all slots are busy, negative queues without limit
+	QueueLimit int
+	// OnQueueWait is called with the time each admitted request spent waiting for a slot
+	OnQueueWait func(wait time.Duration)
+	// OnReject is called whenever a request is rejected because the queue is full
+	OnReject func()
+}
+
+// SemaphoreStats is a snapshot of a Semaphore's load and queueing.
+type SemaphoreStats struct {
+	Running   int           `json:"running"`    // Requests currently inside the handler
+	Queued    int           `json:"queued"`     // Requests currently waiting for a slot
+	Admitted  int64         `json:"admitted"`   // Requests that acquired a slot
+	Rejected  int64         `json:"rejected"`   // Requests turned away with ErrQueueFull
+	Abandoned int64         `json:"abandoned"`  // Requests whose context ended while queued
+	TotalWait time.Duration `json:"total_wait"` // Sum of queue wait across admitted requests
+	MaxWait   time.Duration `json:"max_wait"`   // Longest queue wait observed
+	AvgWait   time.Duration `json:"avg_wait"`   // Mean queue wait per admitted request
+}
+
+// SemaphoreHandler bounds concurrent executions of a wrapped handler.
+//
+// It is created by Semaphore or SemaphoreWithConfig and implements calque.Handler.
+type SemaphoreHandler struct {
+	handler calque.Handler
+	config  SemaphoreConfig
+
+	mu      sync.Mutex
+	running int
+	waiters []chan struct{}
+	stats   SemaphoreStats
+}
+
+// Semaphore limits how many requests run a handler concurrently
+//
+// Input: any data type (streaming - passed to the handler once admitted)
+// Output: handler output
+// Behavior: STREAMING - blocks until a slot is free, rejects when the queue is full
+//
+// At most max requests execute the handler at once; up to queueLimit more wait
+// for a slot in arrival order, and any beyond that fail immediately with
+// ErrQueueFull. A queueLimit of 0 disables waiting, a negative queueLimit waits
+// without bound. Requests whose context ends while queued leave the queue.
+//
+// The limit applies to this handler only, independent of FlowConfig.MaxConcurrent,
+// so one expensive stage (a local GPU model, a licensed API) can be throttled
+// while the rest of the flow runs at full concurrency. Wait times and rejections
+// are reported by Stats.
+//
+// Example:
+//
+//	gpu := ctrl.Semaphore(ai.Agent(localModel), 2, 20) // 2 running, 20 waiting
+//	flow.Use(gpu)
+//
+//	stats := gpu.Stats()
+//	log.Printf("queued %d, avg wait %s, rejected %d", stats.Queued, stats.AvgWait, stats.Rejected)
+func Semaphore(handler calque.Handler, maxConcurrent, queueLimit int) *SemaphoreHandler {
+	return SemaphoreWithConfig(handler, &SemaphoreConfig{
+		MaxConcurrent: maxConcurrent,
+		QueueLimit:    queueLimit,
+	})
+}
+
+// SemaphoreWithConfig limits handler concurrency with custom configuration
+//
+// Input: any data type (streaming - passed to the handler once admitted)
+// Output: handler output
+// Behavior: STREAMING - blocks until a slot is free, rejects when the queue is full
+//
+// Behaves like Semaphore, additionally reporting each queue wait and rejection
+// through the config callbacks so they can be exported to a metrics backend.
+//
+// Example:
+//
+//	gpu := ctrl.SemaphoreWithConfig(handler, &ctrl.SemaphoreConfig{
+//		MaxConcurrent: 2,
+//		QueueLimit:    20,
+//		OnQueueWait:   func(d time.Duration) { queueWait.Observe(d.Seconds()) },
+//		OnReject:      func() { rejected.Inc() },
+//	})
+func SemaphoreWithConfig(handler calque.Handler, config *SemaphoreConfig) *SemaphoreHandler {
+	s := &SemaphoreHandler{handler: handler}
+	if config != nil {
+		s.config = *config
+	}
+	if s.config.MaxConcurrent <= 0 {
+		s.config.MaxConcurrent = 1
+	}
+	return s
+}
+
+// ServeFlow implements calque.Handler
+func (s *SemaphoreHandler) ServeFlow(req *calque.Request, res *calque.Response) error {
+	if err := s.acquire(req); err != nil {
+		return err
+	}
+	defer s.release()
+
+	return s.handler.ServeFlow(req, res)
+}
+
+// Describe implements calque.Describer, drawing the semaphore around the wrapped handler
+func (s *SemaphoreHandler) Describe() calque.Node {
+	return calque.Node{Label: fmt.Sprintf("semaphore (max %d)", s.config.MaxConcurrent), Kind: calque.NodeSequence, Children: describeAll([]calque.Handler{s.handler})}
+}
+
+// Warmup implements calque.Warmer by warming the wrapped handler
+func (s *SemaphoreHandler) Warmup(ctx context.Context) error {
+	return calque.WarmupHandler(ctx, s.handler)
+}
+
+// Shutdown implements calque.Shutdowner by shutting down the wrapped handler
+func (s *SemaphoreHandler) Shutdown(ctx context.Context) error {
+	return calque.ShutdownHandler(ctx, s.handler)
+}
+
+// Stats returns a snapshot of current load and accumulated queue metrics
+func (s *SemaphoreHandler) Stats() SemaphoreStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.Running = s.running
+	stats.Queued = len(s.waiters)
+	if stats.Admitted > 0 {
+		stats.AvgWait = stats.TotalWait / time.Duration(stats.Admitted)
+	}
+	return stats
+}
+
+// acquire takes a slot, queueing if allowed, and records the wait
+func (s *SemaphoreHandler) acquire(req *calque.Request) error {
+	s.mu.Lock()
+	if s.running < s.config.MaxConcurrent && len(s.waiters) == 0 {
+		s.running++
+		s.stats.Admitted++
+		s.mu.Unlock()
+		s.observe(0)
+		return nil
+	}
+
+	if s.config.QueueLimit >= 0 && len(s.waiters) >= s.config.QueueLimit {
+		s.stats.Rejected++
+		s.mu.Unlock()
+		if s.config.OnReject != nil {
+			s.config.OnReject()
+		}
+		return calque.WrapErr(req.Context, ErrQueueFull,
+			fmt.Sprintf("semaphore rejected request (%d running, %d queued)", s.config.MaxConcurrent, s.config.QueueLimit))
+	}
+
+	ready := make(chan struct{})
+	s.waiters = append(s.waiters, ready)
+	s.mu.Unlock()
+
+	start := time.Now()
+	select {
+	case <-ready:
+		// release handed its slot directly to us
+	case <-req.Context.Done():
+		s.mu.Lock()
+		if i := slices.Index(s.waiters, ready); i >= 0 {
+			s.waiters = slices.Delete(s.waiters, i, i+1)
+			s.stats.Abandoned++
+			s.mu.Unlock()
+			return calque.WrapErr(req.Context, req.Context.Err(), "semaphore wait cancelled")
+		}
+		s.mu.Unlock()
+		// The slot was granted while we were cancelled; hand it on
+		s.release()
+		return calque.WrapErr(req.Context, req.Context.Err(), "semaphore wait cancelled")
+	}
+
+	wait := time.Since(start)
+	s.mu.Lock()
+	s.stats.Admitted++
+	s.stats.TotalWait += wait
+	s.stats.MaxWait = max(s.stats.MaxWait, wait)
+	s.mu.Unlock()
+	s.observe(wait)
+	return nil
+}
+
+// release passes the slot to the longest waiting request, or frees it
+func (s *SemaphoreHandler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.waiters) > 0 {
+		next := s.waiters[0]
+		s.waiters = s.waiters[1:]
+		close(next)
+		return
+	}
+	s.running--
+}
+
+func (s *SemaphoreHandler) observe(wait time.Duration) {
+	if s.config.OnQueueWait != nil {
+		s.config.OnQueueWait(wait)
+	}
+}
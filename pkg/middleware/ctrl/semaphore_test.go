@@ -0,0 +1,215 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// gatedHandler echoes input once release is closed, tracking peak concurrency
+func gatedHandler(release <-chan struct{}, active, peak *atomic.Int32) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+}
+
+func runSemaphore(ctx context.Context, h calque.Handler, input string) (string, error) {
+	var out string
+	err := calque.NewFlow().Use(h).Run(ctx, input, &out)
+	return out, err
+}
+
+// waitFor polls until cond holds or the test times out
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not reached")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSemaphore_BoundsConcurrency(t *testing.T) {
+	release := make(chan struct{})
+	var active, peak atomic.Int32
+	sem := Semaphore(gatedHandler(release, &active, &peak), 2, -1)
+
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if out, err := runSemaphore(context.Background(), sem, "x"); err != nil || out != "x" {
+				t.Errorf("Run() = %q, %v", out, err)
+			}
+		}()
+	}
+
+	waitFor(t, func() bool { s := sem.Stats(); return s.Running == 2 && s.Queued == 4 })
+	close(release)
+	wg.Wait()
+
+	if got := peak.Load(); got != 2 {
+		t.Errorf("peak concurrency = %d, want 2", got)
+	}
+	stats := sem.Stats()
+	if stats.Admitted != 6 || stats.Running != 0 || stats.Queued != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if stats.MaxWait <= 0 || stats.AvgWait <= 0 {
+		t.Errorf("queue wait not recorded: %+v", stats)
+	}
+}
+
+func TestSemaphore_RejectsWhenQueueFull(t *testing.T) {
+	tests := []struct {
+		name         string
+		queueLimit   int
+		requests     int
+		wantRejected int64
+	}{
+		{"no queue", 0, 3, 2},
+		{"queue of one", 1, 4, 2},
+		{"room for all", 5, 4, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			var active, peak atomic.Int32
+			var rejects atomic.Int32
+			sem := SemaphoreWithConfig(gatedHandler(release, &active, &peak), &SemaphoreConfig{
+				MaxConcurrent: 1,
+				QueueLimit:    tt.queueLimit,
+				OnReject:      func() { rejects.Add(1) },
+			})
+
+			var wg sync.WaitGroup
+			var queueFull atomic.Int32
+			for range tt.requests {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := runSemaphore(context.Background(), sem, "x"); errors.Is(err, ErrQueueFull) {
+						queueFull.Add(1)
+					}
+				}()
+			}
+
+			waitFor(t, func() bool {
+				s := sem.Stats()
+				return int64(s.Running+s.Queued)+s.Rejected == int64(tt.requests)
+			})
+			close(release)
+			wg.Wait()
+
+			if got := sem.Stats().Rejected; got != tt.wantRejected {
+				t.Errorf("Rejected = %d, want %d", got, tt.wantRejected)
+			}
+			if int64(queueFull.Load()) != tt.wantRejected || int64(rejects.Load()) != tt.wantRejected {
+				t.Errorf("ErrQueueFull returned %d times, OnReject called %d times, want %d",
+					queueFull.Load(), rejects.Load(), tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestSemaphore_CancelledWhileQueued(t *testing.T) {
+	release := make(chan struct{})
+	var active, peak atomic.Int32
+	sem := Semaphore(gatedHandler(release, &active, &peak), 1, 5)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = runSemaphore(context.Background(), sem, "first")
+	}()
+	waitFor(t, func() bool { return sem.Stats().Running == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := runSemaphore(ctx, sem, "second"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("queued request error = %v, want deadline exceeded", err)
+	}
+
+	close(release)
+	<-done
+
+	stats := sem.Stats()
+	if stats.Abandoned != 1 || stats.Queued != 0 || stats.Running != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestSemaphoreWithConfig_OnQueueWait(t *testing.T) {
+	var waits []time.Duration
+	var mu sync.Mutex
+	sem := SemaphoreWithConfig(calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		time.Sleep(10 * time.Millisecond)
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	}), &SemaphoreConfig{
+		QueueLimit: -1,
+		OnQueueWait: func(d time.Duration) {
+			mu.Lock()
+			waits = append(waits, d)
+			mu.Unlock()
+		},
+	})
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = runSemaphore(context.Background(), sem, "x")
+		}()
+	}
+	wg.Wait()
+
+	if len(waits) != 3 {
+		t.Fatalf("OnQueueWait called %d times, want 3", len(waits))
+	}
+	var queued int
+	for _, d := range waits {
+		if d > 0 {
+			queued++
+		}
+	}
+	if queued == 0 {
+		t.Errorf("expected at least one request to wait with default MaxConcurrent 1, waits = %v", waits)
+	}
+}
+
+func TestSemaphore_ForwardsWarmup(t *testing.T) {
+	var warmed atomic.Int32
+	inner := calque.WithWarmup(calque.HandlerFunc(func(_ *calque.Request, _ *calque.Response) error { return nil }),
+		func(context.Context) error { warmed.Add(1); return nil })
+
+	flow := calque.NewFlow().
+		Use(Semaphore(inner, 1, 0)).
+		Use(AdaptiveConcurrency(inner)).
+		Use(Dedupe(inner, nil, nil, time.Minute)).
+		Use(NamedChain(Stage("inner", inner)))
+	if err := flow.Warmup(context.Background()); err != nil || warmed.Load() != 4 {
+		t.Errorf("Warmup() = %v, wrapped handler warmed %d times, want 4", err, warmed.Load())
+	}
+}
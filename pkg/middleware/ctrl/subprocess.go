@@ -0,0 +1,569 @@
+package ctrl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// ErrSubprocessUnavailable is returned by Subprocess when its worker crashed
+// more often than the restart budget allows.
+var ErrSubprocessUnavailable = errors.New("subprocess restarted too often")
+
+// SubprocessCodec frames payloads on a worker process's stdin and stdout.
+//
+// Encode writes one request; Decode reads the matching response, failing if
+// it is larger than maxSize bytes.
+type SubprocessCodec interface {
+	Encode(w io.Writer, payload []byte) error
+	Decode(r *bufio.Reader, maxSize int64) ([]byte, error)
+}
+
+// StreamCodec runs a fresh process for every request.
+//
+// The payload streams to the process's stdin, which is then closed, and
+// everything it writes to stdout until it exits is the output. Any filter
+// program works unchanged (e.g. "pdftotext - -"), and each request gets a
+// clean process at the cost of a start per request.
+func StreamCodec() SubprocessCodec {
+	return streamCodec{}
+}
+
+// LineCodec frames each request and response as one newline-terminated line.
+//
+// Suits simple persistent workers reading stdin line by line. Payloads
+// containing a newline are rejected; use LengthPrefixCodec for binary or
+// multi-line data.
+func LineCodec() SubprocessCodec {
+	return lineCodec{}
+}
+
+// LengthPrefixCodec frames each request and response as a 4-byte big-endian
+// length followed by that many bytes.
+func LengthPrefixCodec() SubprocessCodec {
+	return lengthPrefixCodec{}
+}
+
+// SubprocessConfig holds configuration for the Subprocess middleware
+type SubprocessConfig struct {
+	Dir    string    // Working directory (default: the current one)
+	Env    []string  // Environment as "KEY=value" pairs (default: inherited)
+	Stderr io.Writer // Receives the process's stderr (default: discarded; the tail is kept for errors)
+
+	// Timeout bounds each request; the process is killed when it is exceeded (default: none)
+	Timeout time.Duration
+	// MaxOutput is the largest response accepted, in bytes (default: 16 MiB)
+	MaxOutput int64
+	// MaxMemory caps the process's address space in bytes (Linux only; default: none)
+	MaxMemory uint64
+	// MaxCPUTime caps the process's CPU time (Linux only; default: none)
+	MaxCPUTime time.Duration
+
+	// MaxRestarts is how many times a crashed worker is restarted within
+	// RestartWindow before requests fail with ErrSubprocessUnavailable (default: 5)
+	MaxRestarts int
+	// RestartWindow is the period MaxRestarts applies to (default: 1 minute)
+	RestartWindow time.Duration
+}
+
+// SubprocessStats is a snapshot of a Subprocess handler's activity.
+type SubprocessStats struct {
+	Requests int64 `json:"requests"` // Requests served, successful or not
+	Failures int64 `json:"failures"` // Requests that returned an error
+	Starts   int64 `json:"starts"`   // Processes started
+	Crashes  int64 `json:"crashes"`  // Processes that exited or were killed mid-request
+	Running  bool  `json:"running"`  // Whether a persistent worker is currently up
+}
+
+// SubprocessHandler runs a stage in a child process.
+//
+// It is created by Subprocess or SubprocessWithConfig and implements calque.Handler.
+type SubprocessHandler struct {
+	command []string
+	codec   SubprocessCodec
+	config  SubprocessConfig
+
+	mu       sync.Mutex // serializes requests to the persistent worker
+	worker   *subprocessWorker
+	starts   []time.Time // recent worker starts, for the restart budget
+	statsMu  sync.Mutex
+	stats    SubprocessStats
+	shutdown bool
+}
+
+// Subprocess runs a stage in a child process so a crash can't take down the flow
+//
+// Input: any data type (streaming with StreamCodec, buffered with framed codecs)
+// Output: the process's response
+// Behavior: STREAMING with StreamCodec, BUFFERED otherwise - isolates the work in its own process
+//
+// Risky native code (PDF parsers, image libraries, CV models) runs outside
+// the agent's address space. With StreamCodec every request gets its own
+// process. With a framed codec one worker process is kept running and handles
+// requests one at a time; if it exits or breaks the protocol, the request
+// fails and a new worker is started for the next request, up to the
+// configured restart budget. A request that times out or is cancelled kills
+// the worker, since its state is then unknown.
+//
+// Example:
+//
+//	extract := ctrl.Subprocess([]string{"pdftotext", "-", "-"}, ctrl.StreamCodec())
+//	ocr := ctrl.Subprocess([]string{"python3", "ocr_worker.py"}, ctrl.LengthPrefixCodec())
+//	flow.Use(extract)
+func Subprocess(command []string, codec SubprocessCodec) *SubprocessHandler {
+	return SubprocessWithConfig(command, codec, nil)
+}
+
+// SubprocessWithConfig runs a stage in a child process with custom configuration
+//
+// Input: any data type (streaming with StreamCodec, buffered with framed codecs)
+// Output: the process's response
+// Behavior: behaves like Subprocess, with resource limits, timeouts and restart budget from config
+//
+// Example:
+//
+//	parser := ctrl.SubprocessWithConfig([]string{"./parse-pdf"}, ctrl.LengthPrefixCodec(), &ctrl.SubprocessConfig{
+//		Timeout:   30 * time.Second,
+//		MaxMemory: 512 << 20,
+//		Stderr:    os.Stderr,
+//	})
+func SubprocessWithConfig(command []string, codec SubprocessCodec, config *SubprocessConfig) *SubprocessHandler {
+	h := &SubprocessHandler{command: command, codec: codec}
+	if config != nil {
+		h.config = *config
+	}
+	if h.codec == nil {
+		h.codec = StreamCodec()
+	}
+	if h.config.MaxOutput <= 0 {
+		h.config.MaxOutput = 16 << 20
+	}
+	if h.config.MaxRestarts <= 0 {
+		h.config.MaxRestarts = 5
+	}
+	if h.config.RestartWindow <= 0 {
+		h.config.RestartWindow = time.Minute
+	}
+	return h
+}
+
+// ServeFlow implements calque.Handler
+func (h *SubprocessHandler) ServeFlow(req *calque.Request, res *calque.Response) error {
+	if len(h.command) == 0 {
+		return calque.NewErr(req.Context, "subprocess command is empty")
+	}
+
+	ctx := req.Context
+	if h.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.config.Timeout)
+		defer cancel()
+	}
+
+	var err error
+	if _, ok := h.codec.(streamCodec); ok {
+		err = h.serveStream(ctx, req.Data, res.Data)
+	} else {
+		err = h.serveWorker(ctx, req, res)
+	}
+
+	h.statsMu.Lock()
+	h.stats.Requests++
+	if err != nil {
+		h.stats.Failures++
+	}
+	h.statsMu.Unlock()
+	return err
+}
+
+// Stats returns a snapshot of the handler's activity
+func (h *SubprocessHandler) Stats() SubprocessStats {
+	h.statsMu.Lock()
+	defer h.statsMu.Unlock()
+	return h.stats
+}
+
+// Shutdown implements calque.Shutdowner by stopping the persistent worker.
+//
+// The worker's stdin is closed so it can exit on its own; it is killed if it
+// is still running when ctx ends. Later requests fail.
+func (h *SubprocessHandler) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.shutdown = true
+	worker := h.worker
+	h.setWorker(nil)
+	if worker == nil {
+		return nil
+	}
+
+	_ = worker.stdin.Close()
+	select {
+	case <-worker.done:
+		return nil
+	case <-ctx.Done():
+		worker.kill()
+		return calque.WrapErr(ctx, ctx.Err(), "subprocess did not exit before the deadline")
+	}
+}
+
+// serveStream runs one process for this request, streaming stdin to stdout
+func (h *SubprocessHandler) serveStream(ctx context.Context, input io.Reader, output io.Writer) error {
+	stderr := &tailBuffer{}
+	cmd := h.newCommand(stderr)
+	cmd.Stdin = input
+	out := &limitedWriter{w: output, remaining: h.config.MaxOutput}
+	out.onExceed = func() { _ = cmd.Process.Kill() }
+	cmd.Stdout = out
+
+	if err := h.start(ctx, cmd); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		if out.exceeded {
+			return calque.NewErr(ctx, fmt.Sprintf("subprocess output exceeds %d bytes", h.config.MaxOutput))
+		}
+		if err != nil {
+			h.countCrash()
+			return calque.WrapErr(ctx, err, h.describe("subprocess failed", stderr))
+		}
+		return nil
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		<-done
+		h.countCrash()
+		return calque.WrapErr(ctx, ctx.Err(), "subprocess request abandoned")
+	}
+}
+
+// serveWorker sends one framed request to the persistent worker
+func (h *SubprocessHandler) serveWorker(ctx context.Context, req *calque.Request, res *calque.Response) error {
+	var payload []byte
+	if err := calque.Read(req, &payload); err != nil {
+		return err
+	}
+
+	// Frame the request up front so a payload the codec rejects fails this
+	// request without disturbing the worker
+	var frame bytes.Buffer
+	if err := h.codec.Encode(&frame, payload); err != nil {
+		return calque.WrapErr(ctx, err, "subprocess request rejected")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	worker, err := h.ensureWorker(ctx)
+	if err != nil {
+		return err
+	}
+
+	type result struct {
+		output []byte
+		err    error
+	}
+	results := make(chan result, 1)
+	go func() {
+		if _, err := worker.stdin.Write(frame.Bytes()); err != nil {
+			results <- result{err: err}
+			return
+		}
+		output, err := h.codec.Decode(worker.stdout, h.config.MaxOutput)
+		results <- result{output: output, err: err}
+	}()
+
+	select {
+	case r := <-results:
+		if r.err != nil {
+			// The stream may be out of sync now, so never reuse this worker
+			h.stopWorker(worker)
+			return calque.WrapErr(ctx, r.err, h.describe("subprocess worker failed", worker.stderr))
+		}
+		_, err := res.Data.Write(r.output)
+		return err
+	case <-ctx.Done():
+		h.stopWorker(worker)
+		<-results
+		return calque.WrapErr(ctx, ctx.Err(), "subprocess request abandoned")
+	}
+}
+
+// ensureWorker returns the running worker, starting one within the restart budget; caller holds h.mu
+func (h *SubprocessHandler) ensureWorker(ctx context.Context) (*subprocessWorker, error) {
+	if h.shutdown {
+		return nil, calque.NewErr(ctx, "subprocess handler is shut down")
+	}
+	if h.worker != nil {
+		select {
+		case <-h.worker.done:
+			// Exited between requests
+			h.countCrash()
+			h.setWorker(nil)
+		default:
+			return h.worker, nil
+		}
+	}
+
+	now := time.Now()
+	recent := h.starts[:0]
+	for _, started := range h.starts {
+		if now.Sub(started) < h.config.RestartWindow {
+			recent = append(recent, started)
+		}
+	}
+	h.starts = recent
+	if len(h.starts) > h.config.MaxRestarts {
+		return nil, calque.WrapErr(ctx, ErrSubprocessUnavailable, fmt.Sprintf("%d starts within %s", len(h.starts), h.config.RestartWindow))
+	}
+
+	worker, err := h.startWorker(ctx)
+	if err != nil {
+		return nil, err
+	}
+	h.starts = append(h.starts, now)
+	h.setWorker(worker)
+	return worker, nil
+}
+
+// stopWorker kills the worker and waits for it to exit; caller holds h.mu
+func (h *SubprocessHandler) stopWorker(worker *subprocessWorker) {
+	worker.kill()
+	if h.worker == worker {
+		h.setWorker(nil)
+	}
+	h.countCrash()
+}
+
+// setWorker replaces the persistent worker; caller holds h.mu
+func (h *SubprocessHandler) setWorker(worker *subprocessWorker) {
+	h.worker = worker
+	h.statsMu.Lock()
+	h.stats.Running = worker != nil
+	h.statsMu.Unlock()
+}
+
+// startWorker launches a persistent worker process
+func (h *SubprocessHandler) startWorker(ctx context.Context) (*subprocessWorker, error) {
+	worker := &subprocessWorker{stderr: &tailBuffer{}, done: make(chan struct{})}
+	cmd := h.newCommand(worker.stderr)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to create subprocess stdin")
+	}
+	// A plain pipe rather than StdoutPipe: Wait runs concurrently with reads
+	// and must not close the read end underneath the decoder
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to create subprocess stdout")
+	}
+	cmd.Stdout = stdoutW
+
+	err = h.start(ctx, cmd)
+	_ = stdoutW.Close()
+	if err != nil {
+		_ = stdoutR.Close()
+		return nil, err
+	}
+
+	worker.cmd = cmd
+	worker.stdin = stdin
+	worker.stdout = bufio.NewReader(stdoutR)
+	go func() {
+		worker.err = cmd.Wait()
+		_ = stdoutR.Close()
+		close(worker.done)
+	}()
+	return worker, nil
+}
+
+// start starts cmd and applies resource limits, killing it if they can't be set
+func (h *SubprocessHandler) start(ctx context.Context, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to start subprocess %s", h.command[0]))
+	}
+	if err := applyResourceLimits(cmd.Process.Pid, h.config); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return calque.WrapErr(ctx, err, "failed to apply subprocess resource limits")
+	}
+
+	h.statsMu.Lock()
+	h.stats.Starts++
+	h.statsMu.Unlock()
+	return nil
+}
+
+func (h *SubprocessHandler) newCommand(stderr *tailBuffer) *exec.Cmd {
+	cmd := exec.Command(h.command[0], h.command[1:]...)
+	cmd.Dir = h.config.Dir
+	cmd.Env = h.config.Env
+	cmd.Stderr = stderr
+	if h.config.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(stderr, h.config.Stderr)
+	}
+	// Don't wait forever on pipes held open by grandchildren
+	cmd.WaitDelay = time.Second
+	return cmd
+}
+
+func (h *SubprocessHandler) countCrash() {
+	h.statsMu.Lock()
+	h.stats.Crashes++
+	h.statsMu.Unlock()
+}
+
+// describe builds an error message including the tail of the process's stderr
+func (h *SubprocessHandler) describe(msg string, stderr *tailBuffer) string {
+	msg = fmt.Sprintf("%s (%s)", msg, h.command[0])
+	if tail := strings.TrimSpace(stderr.String()); tail != "" {
+		msg += ": " + tail
+	}
+	return msg
+}
+
+// subprocessWorker is a running persistent worker
+type subprocessWorker struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr *tailBuffer
+	done   chan struct{} // closed once the process has exited
+	err    error         // exit error, valid after done is closed
+}
+
+// kill stops the worker and waits for it to exit
+func (w *subprocessWorker) kill() {
+	_ = w.cmd.Process.Kill()
+	<-w.done
+}
+
+// stderrTail is how much of a process's stderr is kept for error messages
+const stderrTail = 2048
+
+// tailBuffer keeps the last stderrTail bytes written to it
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > stderrTail {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-stderrTail:]...)
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
+
+// limitedWriter fails and calls onExceed once more than remaining bytes are written
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64
+	exceeded  bool
+	onExceed  func()
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.remaining {
+		if !l.exceeded {
+			l.exceeded = true
+			l.onExceed()
+		}
+		return 0, io.ErrShortWrite
+	}
+	l.remaining -= int64(len(p))
+	return l.w.Write(p)
+}
+
+type streamCodec struct{}
+
+func (streamCodec) Encode(w io.Writer, payload []byte) error {
+	_, err := w.Write(payload)
+	return err
+}
+
+func (streamCodec) Decode(r *bufio.Reader, maxSize int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err == nil && int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("response exceeds %d bytes", maxSize)
+	}
+	return data, err
+}
+
+type lineCodec struct{}
+
+func (lineCodec) Encode(w io.Writer, payload []byte) error {
+	if bytes.IndexByte(payload, '\n') >= 0 {
+		return errors.New("payload contains a newline; use LengthPrefixCodec")
+	}
+	_, err := w.Write(append(payload, '\n'))
+	return err
+}
+
+func (lineCodec) Decode(r *bufio.Reader, maxSize int64) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		line = append(line, chunk...)
+		if int64(len(line)) > maxSize+1 {
+			return nil, fmt.Errorf("response exceeds %d bytes", maxSize)
+		}
+		if err == nil {
+			return line[:len(line)-1], nil
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return nil, err
+		}
+	}
+}
+
+type lengthPrefixCodec struct{}
+
+func (lengthPrefixCodec) Encode(w io.Writer, payload []byte) error {
+	frame := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	copy(frame[4:], payload)
+	_, err := w.Write(frame)
+	return err
+}
+
+func (lengthPrefixCodec) Decode(r *bufio.Reader, maxSize int64) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size := int64(binary.BigEndian.Uint32(header[:]))
+	if size > maxSize {
+		return nil, fmt.Errorf("response of %d bytes exceeds %d", size, maxSize)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
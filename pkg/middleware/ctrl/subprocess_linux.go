@@ -0,0 +1,26 @@
+package ctrl
+
+import (
+	"math"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// applyResourceLimits sets the configured rlimits on a started process
+func applyResourceLimits(pid int, config SubprocessConfig) error {
+	if config.MaxMemory > 0 {
+		limit := &unix.Rlimit{Cur: config.MaxMemory, Max: config.MaxMemory}
+		if err := unix.Prlimit(pid, unix.RLIMIT_AS, limit, nil); err != nil {
+			return err
+		}
+	}
+	if config.MaxCPUTime > 0 {
+		seconds := uint64(math.Ceil(float64(config.MaxCPUTime) / float64(time.Second)))
+		limit := &unix.Rlimit{Cur: seconds, Max: seconds}
+		if err := unix.Prlimit(pid, unix.RLIMIT_CPU, limit, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
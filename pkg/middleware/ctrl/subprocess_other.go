@@ -0,0 +1,13 @@
+//go:build !linux
+
+package ctrl
+
+import "errors"
+
+// applyResourceLimits refuses to run without the limits the caller asked for
+func applyResourceLimits(_ int, config SubprocessConfig) error {
+	if config.MaxMemory > 0 || config.MaxCPUTime > 0 {
+		return errors.New("MaxMemory and MaxCPUTime are only supported on Linux")
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package ctrl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+const subprocessHelperEnv = "CTRL_SUBPROCESS_HELPER"
+
+// TestSubprocessHelper is not a real test: it is the child process the
+// Subprocess tests start, re-running this test binary in the given mode
+func TestSubprocessHelper(t *testing.T) {
+	if os.Getenv(subprocessHelperEnv) != "1" {
+		t.Skip("helper process for subprocess tests")
+	}
+	mode := os.Args[len(os.Args)-1]
+	os.Exit(runSubprocessHelper(mode))
+}
+
+func runSubprocessHelper(mode string) int {
+	switch mode {
+	case "upper":
+		data, _ := io.ReadAll(os.Stdin)
+		_, _ = os.Stdout.Write(bytes.ToUpper(data))
+		return 0
+	case "fail":
+		fmt.Fprintln(os.Stderr, "cannot parse document")
+		return 2
+	case "flood":
+		chunk := bytes.Repeat([]byte("x"), 64<<10)
+		for {
+			if _, err := os.Stdout.Write(chunk); err != nil {
+				return 1
+			}
+		}
+	case "memory":
+		// Give the parent time to apply its limits
+		time.Sleep(200 * time.Millisecond)
+		hog := make([]byte, 1<<30)
+		for i := range hog {
+			hog[i] = 1
+		}
+		return 0
+	case "lines":
+		in := bufio.NewScanner(os.Stdin)
+		for in.Scan() {
+			switch line := in.Text(); line {
+			case "crash":
+				fmt.Fprintln(os.Stderr, "segfault in libparse")
+				return 3
+			case "hang":
+				time.Sleep(time.Minute)
+			case "pid":
+				fmt.Println(os.Getpid())
+			default:
+				fmt.Println(strings.ToUpper(line))
+			}
+		}
+		return 0
+	case "frames":
+		in := bufio.NewReader(os.Stdin)
+		for {
+			payload, err := LengthPrefixCodec().Decode(in, 1<<20)
+			if err != nil {
+				return 0
+			}
+			if err := LengthPrefixCodec().Encode(os.Stdout, bytes.ToUpper(payload)); err != nil {
+				return 1
+			}
+		}
+	}
+	return 1
+}
+
+// helperSubprocess builds a handler running this test binary in mode
+func helperSubprocess(mode string, codec SubprocessCodec, config *SubprocessConfig) *SubprocessHandler {
+	cfg := SubprocessConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	cfg.Env = append(os.Environ(), subprocessHelperEnv+"=1")
+	command := []string{os.Args[0], "-test.run=^TestSubprocessHelper$", "--", mode}
+	return SubprocessWithConfig(command, codec, &cfg)
+}
+
+func runSubprocess(h *SubprocessHandler, input string) (string, error) {
+	var out bytes.Buffer
+	req := calque.NewRequest(context.Background(), strings.NewReader(input))
+	err := h.ServeFlow(req, calque.NewResponse(&out))
+	return out.String(), err
+}
+
+func TestSubprocessStreamCodec(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		mode    string
+		config  *SubprocessConfig
+		want    string
+		wantErr string
+	}{
+		{name: "success", mode: "upper", want: "HELLO\nWORLD"},
+		{name: "non-zero exit includes stderr", mode: "fail", wantErr: "cannot parse document"},
+		{name: "output limit", mode: "flood", config: &SubprocessConfig{MaxOutput: 1 << 20}, wantErr: "exceeds 1048576 bytes"},
+		{name: "timeout", mode: "flood", config: &SubprocessConfig{Timeout: 200 * time.Millisecond, MaxOutput: 1 << 40}, wantErr: "abandoned"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := helperSubprocess(tt.mode, StreamCodec(), tt.config)
+			out, err := runSubprocess(h, "hello\nworld")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ServeFlow() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ServeFlow() error = %v", err)
+			}
+			if out != tt.want {
+				t.Errorf("output = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubprocessWorker(t *testing.T) {
+	t.Parallel()
+
+	h := helperSubprocess("lines", LineCodec(), nil)
+	defer h.Shutdown(context.Background())
+
+	first, err := runSubprocess(h, "pid")
+	if err != nil {
+		t.Fatalf("ServeFlow() error = %v", err)
+	}
+	for _, input := range []string{"hello", "world"} {
+		out, err := runSubprocess(h, input)
+		if err != nil || out != strings.ToUpper(input) {
+			t.Fatalf("ServeFlow(%q) = %q, %v", input, out, err)
+		}
+	}
+	second, _ := runSubprocess(h, "pid")
+	if first != second {
+		t.Errorf("worker pid changed from %s to %s, want one persistent process", first, second)
+	}
+
+	if _, err := runSubprocess(h, "two\nlines"); err == nil || !strings.Contains(err.Error(), "newline") {
+		t.Errorf("ServeFlow() with newline error = %v", err)
+	}
+
+	stats := h.Stats()
+	if stats.Starts != 1 || stats.Requests != 5 || stats.Failures != 1 || !stats.Running {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestSubprocessRestartsAfterCrash(t *testing.T) {
+	t.Parallel()
+
+	h := helperSubprocess("lines", LineCodec(), &SubprocessConfig{MaxRestarts: 2, RestartWindow: time.Minute})
+	defer h.Shutdown(context.Background())
+
+	_, err := runSubprocess(h, "crash")
+	if err == nil || !strings.Contains(err.Error(), "segfault in libparse") {
+		t.Fatalf("ServeFlow() error = %v, want the worker's stderr", err)
+	}
+
+	// The next request gets a fresh worker
+	if out, err := runSubprocess(h, "again"); err != nil || out != "AGAIN" {
+		t.Fatalf("ServeFlow() after crash = %q, %v", out, err)
+	}
+
+	// Two more crashes use up the budget of two restarts
+	_, _ = runSubprocess(h, "crash")
+	if _, err := runSubprocess(h, "crash"); err == nil {
+		t.Fatal("expected the third worker to crash")
+	}
+	if _, err := runSubprocess(h, "again"); !errors.Is(err, ErrSubprocessUnavailable) {
+		t.Errorf("ServeFlow() error = %v, want ErrSubprocessUnavailable", err)
+	}
+
+	if stats := h.Stats(); stats.Starts != 3 || stats.Crashes != 3 {
+		t.Errorf("Stats() = %+v, want 3 starts and 3 crashes", stats)
+	}
+}
+
+func TestSubprocessWorkerTimeout(t *testing.T) {
+	t.Parallel()
+
+	h := helperSubprocess("lines", LineCodec(), &SubprocessConfig{Timeout: 200 * time.Millisecond})
+	defer h.Shutdown(context.Background())
+
+	start := time.Now()
+	if _, err := runSubprocess(h, "hang"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ServeFlow() error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ServeFlow() took %v, want the hung worker killed at the timeout", elapsed)
+	}
+	if out, err := runSubprocess(h, "next"); err != nil || out != "NEXT" {
+		t.Errorf("ServeFlow() after timeout = %q, %v", out, err)
+	}
+}
+
+func TestSubprocessLengthPrefixCodec(t *testing.T) {
+	t.Parallel()
+
+	h := helperSubprocess("frames", LengthPrefixCodec(), nil)
+	defer h.Shutdown(context.Background())
+
+	for _, input := range []string{"multi\nline\npayload", "", "\x00binary\xff"} {
+		out, err := runSubprocess(h, input)
+		if err != nil || out != strings.ToUpper(input) {
+			t.Errorf("ServeFlow(%q) = %q, %v", input, out, err)
+		}
+	}
+}
+
+func TestSubprocessMemoryLimit(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS != "linux" {
+		t.Skip("resource limits are Linux only")
+	}
+
+	h := helperSubprocess("memory", StreamCodec(), &SubprocessConfig{MaxMemory: 512 << 20})
+	if _, err := runSubprocess(h, ""); err == nil {
+		t.Error("expected the process to fail under the memory limit")
+	}
+}
+
+func TestSubprocessShutdown(t *testing.T) {
+	t.Parallel()
+
+	h := helperSubprocess("lines", LineCodec(), nil)
+	if _, err := runSubprocess(h, "hello"); err != nil {
+		t.Fatalf("ServeFlow() error = %v", err)
+	}
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if h.Stats().Running {
+		t.Error("worker still running after Shutdown")
+	}
+	if _, err := runSubprocess(h, "hello"); err == nil || !strings.Contains(err.Error(), "shut down") {
+		t.Errorf("ServeFlow() after Shutdown error = %v", err)
+	}
+}
+
+func TestSubprocessCodecs(t *testing.T) {
+	t.Parallel()
+
+	codecs := map[string]SubprocessCodec{"line": LineCodec(), "length prefix": LengthPrefixCodec()}
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := codec.Encode(&buf, []byte("payload")); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			got, err := codec.Decode(bufio.NewReader(bytes.NewReader(buf.Bytes())), 100)
+			if err != nil || string(got) != "payload" {
+				t.Errorf("Decode() = %q, %v", got, err)
+			}
+			if _, err := codec.Decode(bufio.NewReader(bytes.NewReader(buf.Bytes())), 3); err == nil {
+				t.Error("Decode() accepted a response over maxSize")
+			}
+		})
+	}
+}
@@ -0,0 +1,401 @@
+package ctrl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// TeeSink receives a copy of each request's payload.
+//
+// Open is called once per sampled request; the payload is written to the
+// returned writer as it streams through, and Close is called when the request
+// completes. If the flow input fails part way, writers that implement
+// Abort(error) are aborted instead of closed, so partial payloads are not archived.
+type TeeSink interface {
+	Open(ctx context.Context) (io.WriteCloser, error)
+}
+
+// TeeSinkFunc adapts a function to the TeeSink interface
+type TeeSinkFunc func(ctx context.Context) (io.WriteCloser, error)
+
+// Open implements TeeSink
+func (f TeeSinkFunc) Open(ctx context.Context) (io.WriteCloser, error) {
+	return f(ctx)
+}
+
+// TeeConfig configures TeeSinksWithConfig
+type TeeConfig struct {
+	// SampleRate is the fraction of requests copied, from 0 to 1 (default: 1, every request)
+	SampleRate float64
+	// Sample decides per request whether to copy it, replacing SampleRate
+	Sample func(ctx context.Context) bool
+	// MaxBytes truncates each copy after this many bytes (default: 0, no limit)
+	MaxBytes int64
+	// OnError is called when a sink fails. Sink failures never fail the flow;
+	// the failing sink stops receiving the rest of that request's payload.
+	OnError func(ctx context.Context, err error)
+}
+
+// TeeSinks copies the input stream to archival sinks while passing it through.
+//
+// Input: any data type (streaming)
+// Output: same as input (pass-through)
+// Behavior: STREAMING - copies data to each sink as it flows through
+//
+// Unlike TeeReader, each request gets its own copy per sink, opened when the
+// request starts and closed when it ends, so payloads can be archived as S3
+// objects (see the s3sink package), Kafka messages or records in a rotating file.
+// Sinks implementing calque.Egress are reported to local-only flows.
+//
+// Example:
+//
+//	archive, _ := ctrl.RotatingFile("/var/log/calque/payloads.log", 100<<20)
+//	flow.Use(ctrl.TeeSinks(archive, ctrl.KafkaSink(producer, "llm-payloads")))
+func TeeSinks(sinks ...TeeSink) calque.Handler {
+	return TeeSinksWithConfig(nil, sinks...)
+}
+
+// TeeSinksWithConfig is TeeSinks with sampling, size limits and error reporting.
+//
+// Example:
+//
+//	flow.Use(ctrl.TeeSinksWithConfig(&ctrl.TeeConfig{
+//		SampleRate: 0.01, // archive 1% of production payloads
+//		MaxBytes:   1 << 20,
+//		OnError:    func(ctx context.Context, err error) { calque.LogWarn(ctx, "archive failed", "error", err) },
+//	}, s3Archive))
+func TeeSinksWithConfig(config *TeeConfig, sinks ...TeeSink) calque.Handler {
+	cfg := TeeConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.SampleRate <= 0 || cfg.SampleRate > 1 {
+		cfg.SampleRate = 1
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(ctx context.Context, err error) {
+			calque.LogWarn(ctx, "tee sink failed", "error", err)
+		}
+	}
+
+	var destinations []string
+	for _, sink := range sinks {
+		for _, dest := range calque.EgressOf(sink) {
+			if !slices.Contains(destinations, dest) {
+				destinations = append(destinations, dest)
+			}
+		}
+	}
+
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		ctx := req.Context
+		if len(sinks) == 0 || !cfg.sampled(ctx) {
+			_, err := io.Copy(res.Data, req.Data)
+			return err
+		}
+
+		copies := make([]*sinkWriter, 0, len(sinks))
+		for _, sink := range sinks {
+			w, err := sink.Open(ctx)
+			if err != nil {
+				cfg.OnError(ctx, err)
+				continue
+			}
+			copies = append(copies, &sinkWriter{ctx: ctx, w: w, remaining: cfg.MaxBytes, onError: cfg.OnError})
+		}
+
+		writers := make([]io.Writer, 0, len(copies)+1)
+		for _, c := range copies {
+			writers = append(writers, c)
+		}
+		writers = append(writers, res.Data)
+
+		_, err := io.Copy(io.MultiWriter(writers...), req.Data)
+		for _, c := range copies {
+			c.finish(err)
+		}
+		return err
+	})
+	return calque.WithEgress(h, destinations...)
+}
+
+// sampled reports whether a request is copied to the sinks
+func (cfg TeeConfig) sampled(ctx context.Context) bool {
+	if cfg.Sample != nil {
+		return cfg.Sample(ctx)
+	}
+	return cfg.SampleRate >= 1 || rand.Float64() < cfg.SampleRate
+}
+
+// sinkWriter isolates the flow from a sink: it never returns errors, stops
+// writing after the first failure and truncates at the configured size
+type sinkWriter struct {
+	ctx       context.Context
+	w         io.WriteCloser
+	remaining int64 // Bytes left before truncation, unlimited when the limit is 0
+	failed    bool
+	truncated bool
+	onError   func(ctx context.Context, err error)
+}
+
+// Write implements io.Writer, always reporting the full write as done
+func (s *sinkWriter) Write(p []byte) (int, error) {
+	if s.failed || s.truncated {
+		return len(p), nil
+	}
+	data := p
+	if s.remaining > 0 {
+		if int64(len(data)) >= s.remaining {
+			data = data[:s.remaining]
+			s.truncated = true
+		}
+		s.remaining -= int64(len(data))
+	}
+	if _, err := s.w.Write(data); err != nil {
+		s.failed = true
+		s.onError(s.ctx, err)
+	}
+	return len(p), nil
+}
+
+// finish closes the sink, or aborts it when the input failed
+func (s *sinkWriter) finish(inputErr error) {
+	if aborter, ok := s.w.(interface{ Abort(error) }); ok && (inputErr != nil || s.failed) {
+		cause := inputErr
+		if cause == nil {
+			cause = calque.NewErr(s.ctx, "tee sink write failed")
+		}
+		aborter.Abort(cause)
+		return
+	}
+	if err := s.w.Close(); err != nil {
+		s.onError(s.ctx, err)
+	}
+}
+
+// KafkaProducer publishes a message to a Kafka topic.
+//
+// Adapt your Kafka client to it, e.g. for segmentio/kafka-go:
+//
+//	producer := ctrl.KafkaProducerFunc(func(ctx context.Context, topic string, key, value []byte) error {
+//		return writer.WriteMessages(ctx, kafka.Message{Topic: topic, Key: key, Value: value})
+//	})
+type KafkaProducer interface {
+	Produce(ctx context.Context, topic string, key, value []byte) error
+}
+
+// KafkaProducerFunc adapts a function to the KafkaProducer interface
+type KafkaProducerFunc func(ctx context.Context, topic string, key, value []byte) error
+
+// Produce implements KafkaProducer
+func (f KafkaProducerFunc) Produce(ctx context.Context, topic string, key, value []byte) error {
+	return f(ctx, topic, key, value)
+}
+
+// KafkaSink publishes each request's payload as one message on topic,
+// keyed by the request ID (see calque.WithRequestID).
+//
+// The sink sends data off the host: local-only flows reject it with the
+// brokers the producer declares through calque.Egress, or else the topic.
+//
+// Example:
+//
+//	flow.Use(ctrl.TeeSinks(ctrl.KafkaSink(producer, "llm-payloads")))
+func KafkaSink(producer KafkaProducer, topic string) TeeSink {
+	return &kafkaSink{producer: producer, topic: topic}
+}
+
+type kafkaSink struct {
+	producer KafkaProducer
+	topic    string
+}
+
+// Open implements TeeSink
+func (k *kafkaSink) Open(ctx context.Context) (io.WriteCloser, error) {
+	return &bufferedRecord{flush: func(payload []byte) error {
+		if err := k.producer.Produce(ctx, k.topic, []byte(calque.RequestID(ctx)), payload); err != nil {
+			return calque.WrapErr(ctx, err, fmt.Sprintf("failed to produce to kafka topic %s", k.topic))
+		}
+		return nil
+	}}, nil
+}
+
+// Egress implements calque.Egress
+func (k *kafkaSink) Egress() []string {
+	if e, ok := k.producer.(calque.Egress); ok {
+		return e.Egress()
+	}
+	return []string{"kafka topic " + k.topic}
+}
+
+// bufferedRecord collects a payload and hands it over whole on Close
+type bufferedRecord struct {
+	buf   bytes.Buffer
+	flush func(payload []byte) error
+}
+
+func (b *bufferedRecord) Write(p []byte) (int, error) { return b.buf.Write(p) }
+func (b *bufferedRecord) Close() error                { return b.flush(b.buf.Bytes()) }
+func (b *bufferedRecord) Abort(error)                 {}
+
+// RotatingFileConfig configures RotatingFileWithConfig
+type RotatingFileConfig struct {
+	// Path of the active file; rotated files get a timestamp suffix (required)
+	Path string
+	// MaxBytes rotates the file once it reaches this size (default: 100 MiB)
+	MaxBytes int64
+	// MaxBackups is how many rotated files to keep (default: 0, keep all)
+	MaxBackups int
+}
+
+// RotatingFileSink appends each request's payload to a file as one
+// newline-terminated record, rotating the file as it grows
+type RotatingFileSink struct {
+	mu      sync.Mutex
+	config  RotatingFileConfig
+	file    *os.File
+	size    int64
+	nowFunc func() time.Time
+}
+
+// RotatingFile creates a TeeSink that appends payloads to path, rotating
+// it once it reaches maxBytes.
+//
+// Example:
+//
+//	archive, err := ctrl.RotatingFile("/var/log/calque/payloads.log", 100<<20)
+//	if err != nil { log.Fatal(err) }
+//	defer archive.Close()
+func RotatingFile(path string, maxBytes int64) (*RotatingFileSink, error) {
+	return RotatingFileWithConfig(&RotatingFileConfig{Path: path, MaxBytes: maxBytes})
+}
+
+// RotatingFileWithConfig creates a rotating file sink with custom retention.
+//
+// Example:
+//
+//	archive, err := ctrl.RotatingFileWithConfig(&ctrl.RotatingFileConfig{
+//		Path:       "/var/log/calque/payloads.log",
+//		MaxBytes:   50 << 20,
+//		MaxBackups: 10,
+//	})
+func RotatingFileWithConfig(config *RotatingFileConfig) (*RotatingFileSink, error) {
+	if config == nil || config.Path == "" {
+		return nil, calque.NewErr(context.Background(), "rotating file path is required")
+	}
+	cfg := *config
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 100 << 20
+	}
+
+	s := &RotatingFileSink{config: cfg, nowFunc: time.Now}
+	if err := s.openFile(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Open implements TeeSink; the payload is buffered so records never interleave
+func (s *RotatingFileSink) Open(ctx context.Context) (io.WriteCloser, error) {
+	return &bufferedRecord{flush: func(payload []byte) error {
+		return s.append(ctx, payload)
+	}}, nil
+}
+
+// Close closes the active file
+func (s *RotatingFileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// append writes one record, rotating first if it would overflow the file
+func (s *RotatingFileSink) append(ctx context.Context, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return calque.NewErr(ctx, "rotating file sink is closed")
+	}
+	record := append(slices.Clip(payload), '\n')
+	if s.size > 0 && s.size+int64(len(record)) > s.config.MaxBytes {
+		if err := s.rotate(); err != nil {
+			return calque.WrapErr(ctx, err, "failed to rotate "+s.config.Path)
+		}
+	}
+
+	n, err := s.file.Write(record)
+	s.size += int64(n)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to write "+s.config.Path)
+	}
+	return nil
+}
+
+// openFile opens the active file for appending
+func (s *RotatingFileSink) openFile() error {
+	file, err := os.OpenFile(s.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return calque.WrapErr(context.Background(), err, "failed to open "+s.config.Path)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return calque.WrapErr(context.Background(), err, "failed to stat "+s.config.Path)
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// rotate renames the active file with a timestamp, opens a new one and prunes old backups
+func (s *RotatingFileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+
+	backup := s.config.Path + "." + s.nowFunc().UTC().Format("20060102T150405.000000000")
+	if err := os.Rename(s.config.Path, backup); err != nil {
+		return err
+	}
+	if err := s.openFile(); err != nil {
+		return err
+	}
+	return s.pruneBackups()
+}
+
+// pruneBackups removes the oldest rotated files beyond MaxBackups
+func (s *RotatingFileSink) pruneBackups() error {
+	if s.config.MaxBackups <= 0 {
+		return nil
+	}
+	backups, err := filepath.Glob(s.config.Path + ".*")
+	if err != nil {
+		return err
+	}
+	// Timestamp suffixes sort chronologically
+	slices.Sort(backups)
+	for len(backups) > s.config.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
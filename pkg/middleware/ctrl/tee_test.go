@@ -0,0 +1,265 @@
+package ctrl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// recordingSink keeps every payload it was given
+type recordingSink struct {
+	mu       sync.Mutex
+	payloads []string
+	aborted  int
+	openErr  error
+	writeErr error
+}
+
+func (s *recordingSink) Open(context.Context) (io.WriteCloser, error) {
+	if s.openErr != nil {
+		return nil, s.openErr
+	}
+	return &recordingCopy{sink: s}, nil
+}
+
+type recordingCopy struct {
+	sink *recordingSink
+	buf  bytes.Buffer
+}
+
+func (c *recordingCopy) Write(p []byte) (int, error) {
+	if c.sink.writeErr != nil {
+		return 0, c.sink.writeErr
+	}
+	return c.buf.Write(p)
+}
+
+func (c *recordingCopy) Close() error {
+	c.sink.mu.Lock()
+	defer c.sink.mu.Unlock()
+	c.sink.payloads = append(c.sink.payloads, c.buf.String())
+	return nil
+}
+
+func (c *recordingCopy) Abort(error) {
+	c.sink.mu.Lock()
+	defer c.sink.mu.Unlock()
+	c.sink.aborted++
+}
+
+// failingReader returns data followed by an error
+type failingReader struct {
+	data string
+	done bool
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.done {
+		return 0, errors.New("input broken")
+	}
+	r.done = true
+	return copy(p, r.data), nil
+}
+
+func TestTeeSinks(t *testing.T) {
+	t.Run("copies each request and passes through", func(t *testing.T) {
+		a, b := &recordingSink{}, &recordingSink{}
+		tee := TeeSinks(a, b)
+
+		for _, input := range []string{"first", "second"} {
+			var out string
+			if err := calque.NewFlow().Use(tee).Run(context.Background(), input, &out); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if out != input {
+				t.Errorf("output = %q, want %q", out, input)
+			}
+		}
+		for _, sink := range []*recordingSink{a, b} {
+			if strings.Join(sink.payloads, ",") != "first,second" {
+				t.Errorf("payloads = %q", sink.payloads)
+			}
+		}
+	})
+
+	t.Run("sink failures do not fail the flow", func(t *testing.T) {
+		broken := &recordingSink{writeErr: errors.New("disk full")}
+		unreachable := &recordingSink{openErr: errors.New("no route")}
+		healthy := &recordingSink{}
+
+		var reported []error
+		tee := TeeSinksWithConfig(&TeeConfig{
+			OnError: func(_ context.Context, err error) { reported = append(reported, err) },
+		}, broken, unreachable, healthy)
+
+		var out string
+		if err := calque.NewFlow().Use(tee).Run(context.Background(), "payload", &out); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if out != "payload" || len(healthy.payloads) != 1 {
+			t.Errorf("output = %q, healthy payloads = %q", out, healthy.payloads)
+		}
+		if broken.aborted != 1 || len(broken.payloads) != 0 {
+			t.Errorf("broken sink aborted %d times, payloads %q", broken.aborted, broken.payloads)
+		}
+		if len(reported) != 2 {
+			t.Errorf("reported errors = %v", reported)
+		}
+	})
+
+	t.Run("input failure aborts copies", func(t *testing.T) {
+		sink := &recordingSink{}
+		req := calque.NewRequest(context.Background(), &failingReader{data: "partial"})
+		err := TeeSinks(sink).ServeFlow(req, calque.NewResponse(io.Discard))
+		if err == nil {
+			t.Fatal("expected input error")
+		}
+		if sink.aborted != 1 || len(sink.payloads) != 0 {
+			t.Errorf("aborted = %d, payloads = %q", sink.aborted, sink.payloads)
+		}
+	})
+
+	t.Run("max bytes truncates copies only", func(t *testing.T) {
+		sink := &recordingSink{}
+		var out string
+		err := calque.NewFlow().Use(TeeSinksWithConfig(&TeeConfig{MaxBytes: 4}, sink)).Run(context.Background(), "truncate me", &out)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if out != "truncate me" || len(sink.payloads) != 1 || sink.payloads[0] != "trun" {
+			t.Errorf("output = %q, payloads = %q", out, sink.payloads)
+		}
+	})
+
+	t.Run("sampling", func(t *testing.T) {
+		sink := &recordingSink{}
+		tee := TeeSinksWithConfig(&TeeConfig{
+			Sample: func(ctx context.Context) bool { return calque.Tenant(ctx) == "acme" },
+		}, sink)
+
+		for _, tenant := range []string{"acme", "globex", "acme"} {
+			var out string
+			ctx := calque.WithTenant(context.Background(), tenant)
+			if err := calque.NewFlow().Use(tee).Run(ctx, tenant, &out); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+		}
+		if strings.Join(sink.payloads, ",") != "acme,acme" {
+			t.Errorf("payloads = %q", sink.payloads)
+		}
+
+		rate := &recordingSink{}
+		tee = TeeSinksWithConfig(&TeeConfig{SampleRate: 0.2}, rate)
+		for range 1000 {
+			var out string
+			if err := calque.NewFlow().Use(tee).Run(context.Background(), "x", &out); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+		}
+		if n := len(rate.payloads); n < 100 || n > 300 {
+			t.Errorf("sampled %d of 1000 requests at rate 0.2", n)
+		}
+	})
+}
+
+func TestKafkaSink(t *testing.T) {
+	type message struct{ topic, key, value string }
+	var got []message
+	producer := KafkaProducerFunc(func(_ context.Context, topic string, key, value []byte) error {
+		got = append(got, message{topic, string(key), string(value)})
+		return nil
+	})
+
+	ctx := calque.WithRequestID(context.Background(), "req-1")
+	var out string
+	if err := calque.NewFlow().Use(TeeSinks(KafkaSink(producer, "payloads"))).Run(ctx, "hello", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(got) != 1 || got[0] != (message{"payloads", "req-1", "hello"}) {
+		t.Errorf("messages = %+v", got)
+	}
+}
+
+// brokerProducer declares the brokers it produces to
+type brokerProducer struct {
+	KafkaProducerFunc
+	brokers []string
+}
+
+func (b brokerProducer) Egress() []string { return b.brokers }
+
+func TestTeeSinksEgress(t *testing.T) {
+	discard := KafkaProducerFunc(func(context.Context, string, []byte, []byte) error { return nil })
+	tests := []struct {
+		name string
+		sink TeeSink
+		want []string
+	}{
+		{"file", &recordingSink{}, nil},
+		{"kafka topic", KafkaSink(discard, "payloads"), []string{"kafka topic payloads"}},
+		{"kafka brokers", KafkaSink(brokerProducer{discard, []string{"kafka-1.example.com"}}, "payloads"), []string{"kafka-1.example.com"}},
+		{"local brokers", KafkaSink(brokerProducer{discard, nil}, "payloads"), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tee := TeeSinks(tt.sink)
+			if got := calque.DescribeHandler(tee).Egress; !slices.Equal(got, tt.want) {
+				t.Errorf("egress = %v, want %v", got, tt.want)
+			}
+			err := calque.NewFlow(calque.WithDataPolicy(calque.LocalOnly)).Use(tee).Validate()
+			if (err != nil) != (tt.want != nil) {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payloads.log")
+	sink, err := RotatingFileWithConfig(&RotatingFileConfig{Path: path, MaxBytes: 8, MaxBackups: 1})
+	if err != nil {
+		t.Fatalf("RotatingFileWithConfig() error = %v", err)
+	}
+	defer sink.Close()
+
+	tick := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sink.nowFunc = func() time.Time {
+		tick = tick.Add(time.Second)
+		return tick
+	}
+
+	tee := TeeSinks(sink)
+	for _, input := range []string{"aaaa", "bbbb", "cccc", "dddd"} {
+		var out string
+		if err := calque.NewFlow().Use(tee).Run(context.Background(), input, &out); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+
+	active, err := os.ReadFile(path)
+	if err != nil || string(active) != "dddd\n" {
+		t.Errorf("active file = %q, %v", active, err)
+	}
+	backups, _ := filepath.Glob(path + ".*")
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v, want 1 kept", backups)
+	}
+	if data, _ := os.ReadFile(backups[0]); string(data) != "cccc\n" {
+		t.Errorf("newest backup = %q", data)
+	}
+
+	if _, err := RotatingFile("", 0); err == nil {
+		t.Error("RotatingFile() without a path should fail")
+	}
+}
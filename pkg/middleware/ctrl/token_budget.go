@@ -0,0 +1,159 @@
+package ctrl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+// ErrTokenBudgetExceeded is returned by TokenBudget when input has more tokens than the budget allows.
+var ErrTokenBudgetExceeded = errors.New("input exceeds token budget")
+
+// BudgetOverflow selects what TokenBudget does with input over the budget
+type BudgetOverflow int
+
+const (
+	// BudgetReject fails the request with ErrTokenBudgetExceeded (default)
+	BudgetReject BudgetOverflow = iota
+	// BudgetTruncateEnd keeps the start of the input, e.g. instructions followed by retrieved context
+	BudgetTruncateEnd
+	// BudgetTruncateStart keeps the end of the input, e.g. a transcript whose latest turns matter most
+	BudgetTruncateStart
+)
+
+// TokenBudgetConfig configures TokenBudgetWithConfig
+type TokenBudgetConfig struct {
+	// Tokenizer counts input tokens (default: tokenizer.Conservative). An
+	// ai.TokenEstimator also supplies the model's context window.
+	Tokenizer tokenizer.Tokenizer
+
+	// MaxTokens is the budget (default: the Tokenizer's context window)
+	MaxTokens int
+
+	// ReserveOutput is subtracted from MaxTokens to leave room for the response
+	ReserveOutput int
+
+	// Overflow selects rejection or truncation (default: BudgetReject)
+	Overflow BudgetOverflow
+}
+
+// contextWindower is implemented by token counters that know their model's context window
+type contextWindower interface {
+	ContextWindow() int
+}
+
+// TokenBudget rejects input with more than max tokens before it reaches the provider.
+//
+// Input: any data type (buffered to count tokens)
+// Output: same as input (pass-through when within budget)
+// Behavior: BUFFERED - reads the payload, counts its tokens, then forwards or rejects it
+//
+// A request over the model's context window fails at the provider after a
+// network round trip, and sometimes after being billed. Checking locally fails
+// fast with ErrTokenBudgetExceeded. A max of 0 uses the counter's context
+// window when it is an ai.TokenEstimator. A nil counter uses
+// tokenizer.Conservative, which errs high so estimated input rarely overflows
+// the window; pass the model's own tokenizer for exact counts.
+//
+// Example:
+//
+//	client, _ := openai.New("gpt-4o")
+//	flow.Use(ctrl.TokenBudget(client.TokenEstimator(), 0)).Use(ai.Agent(client))
+func TokenBudget(counter tokenizer.Tokenizer, max int) calque.Handler {
+	return TokenBudgetWithConfig(&TokenBudgetConfig{Tokenizer: counter, MaxTokens: max})
+}
+
+// TokenBudgetWithConfig enforces a token budget with output reservation and truncation.
+//
+// Truncation cuts at a character boundary and keeps as much of the input as
+// fits. It works on raw bytes, so trim structured conversations turn by turn
+// (e.g. with memory.ContextMemory) instead.
+//
+// Example:
+//
+//	budget := ctrl.TokenBudgetWithConfig(&ctrl.TokenBudgetConfig{
+//		Tokenizer:     client.TokenEstimator(),
+//		ReserveOutput: 1000, // max_tokens configured on the client
+//		Overflow:      ctrl.BudgetTruncateEnd,
+//	})
+func TokenBudgetWithConfig(config *TokenBudgetConfig) calque.Handler {
+	cfg := TokenBudgetConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.MaxTokens <= 0 {
+		if windowed, ok := cfg.Tokenizer.(contextWindower); ok {
+			cfg.MaxTokens = windowed.ContextWindow()
+		}
+	}
+	budget := cfg.MaxTokens - cfg.ReserveOutput
+	if budget <= 0 {
+		return calque.HandlerFunc(func(r *calque.Request, _ *calque.Response) error {
+			return calque.NewErr(r.Context, fmt.Sprintf("invalid token budget: max tokens %d leaves no room after reserving %d for output", cfg.MaxTokens, cfg.ReserveOutput))
+		})
+	}
+	counter := cfg.Tokenizer
+	if counter == nil {
+		counter = tokenizer.Conservative
+	}
+
+	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		var input []byte
+		if err := calque.Read(r, &input); err != nil {
+			return err
+		}
+
+		tokens := counter.CountTokens(input)
+		if tokens > budget {
+			if cfg.Overflow == BudgetReject {
+				return calque.WrapErr(r.Context, ErrTokenBudgetExceeded,
+					fmt.Sprintf("input has %d tokens, budget is %d", tokens, budget))
+			}
+			input = truncateToBudget(input, budget, counter, cfg.Overflow == BudgetTruncateStart)
+			calque.LogDebug(r.Context, "token budget truncated input", "tokens", tokens, "budget", budget)
+		}
+
+		_, err := io.Copy(w.Data, bytes.NewReader(input))
+		return err
+	})
+}
+
+// truncateToBudget keeps the longest prefix, or suffix when keepEnd is set,
+// of input that fits in budget tokens, cutting at a rune boundary
+func truncateToBudget(input []byte, budget int, counter tokenizer.Tokenizer, keepEnd bool) []byte {
+	window := func(n int) []byte {
+		if keepEnd {
+			return input[len(input)-n:]
+		}
+		return input[:n]
+	}
+
+	// Binary search for the longest window within budget
+	left, right := 0, len(input)
+	for left < right {
+		mid := (left + right + 1) / 2
+		if counter.CountTokens(window(mid)) <= budget {
+			left = mid
+		} else {
+			right = mid - 1
+		}
+	}
+
+	if keepEnd {
+		start := len(input) - left
+		for start < len(input) && !utf8.RuneStart(input[start]) {
+			start++
+		}
+		return input[start:]
+	}
+	cut := left
+	for cut > 0 && cut < len(input) && !utf8.RuneStart(input[cut]) {
+		cut--
+	}
+	return input[:cut]
+}
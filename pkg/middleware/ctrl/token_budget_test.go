@@ -0,0 +1,130 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+// windowedCounter counts one token per byte and reports a fixed context window
+type windowedCounter int
+
+func (w windowedCounter) CountTokens(text []byte) int { return len(text) }
+func (w windowedCounter) ContextWindow() int          { return int(w) }
+
+func TestTokenBudget(t *testing.T) {
+	perByte := tokenizer.Func(func(text []byte) int { return len(text) })
+
+	tests := []struct {
+		name    string
+		config  *TokenBudgetConfig
+		input   string
+		want    string
+		wantErr error
+		errText string
+	}{
+		{
+			name:   "within budget passes through",
+			config: &TokenBudgetConfig{Tokenizer: perByte, MaxTokens: 10},
+			input:  "0123456789",
+			want:   "0123456789",
+		},
+		{
+			name:    "over budget is rejected",
+			config:  &TokenBudgetConfig{Tokenizer: perByte, MaxTokens: 10},
+			input:   "0123456789a",
+			wantErr: ErrTokenBudgetExceeded,
+		},
+		{
+			name:    "output reservation shrinks the budget",
+			config:  &TokenBudgetConfig{Tokenizer: perByte, MaxTokens: 10, ReserveOutput: 5},
+			input:   "012345",
+			wantErr: ErrTokenBudgetExceeded,
+		},
+		{
+			name:   "truncate end keeps the start",
+			config: &TokenBudgetConfig{Tokenizer: perByte, MaxTokens: 4, Overflow: BudgetTruncateEnd},
+			input:  "0123456789",
+			want:   "0123",
+		},
+		{
+			name:   "truncate start keeps the end",
+			config: &TokenBudgetConfig{Tokenizer: perByte, MaxTokens: 4, Overflow: BudgetTruncateStart},
+			input:  "0123456789",
+			want:   "6789",
+		},
+		{
+			name:   "truncation keeps whole characters",
+			config: &TokenBudgetConfig{Tokenizer: perByte, MaxTokens: 4, Overflow: BudgetTruncateEnd},
+			input:  "ab€cd", // € is 3 bytes, so the 4-byte cut lands inside it
+			want:   "ab",
+		},
+		{
+			name:    "default estimate does not undercount CJK text",
+			config:  &TokenBudgetConfig{MaxTokens: 8},
+			input:   "東京都の天気を教えて", // 30 bytes, 10 characters
+			wantErr: ErrTokenBudgetExceeded,
+		},
+		{
+			name:   "context window is the default budget",
+			config: &TokenBudgetConfig{Tokenizer: windowedCounter(8), Overflow: BudgetTruncateEnd},
+			input:  "0123456789",
+			want:   "01234567",
+		},
+		{
+			name:   "approximate tokenizer by default",
+			config: &TokenBudgetConfig{MaxTokens: 2},
+			input:  "12345678",
+			want:   "12345678",
+		},
+		{
+			name:    "missing budget fails",
+			config:  &TokenBudgetConfig{Tokenizer: perByte},
+			input:   "x",
+			errText: "invalid token budget",
+		},
+		{
+			name:    "reservation larger than budget fails",
+			config:  &TokenBudgetConfig{Tokenizer: perByte, MaxTokens: 10, ReserveOutput: 10},
+			input:   "x",
+			errText: "invalid token budget",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output strings.Builder
+			req := calque.NewRequest(context.Background(), strings.NewReader(tt.input))
+			err := TokenBudgetWithConfig(tt.config).ServeFlow(req, calque.NewResponse(&output))
+
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.errText != "":
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Fatalf("error = %v, want %q", err, tt.errText)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			default:
+				if output.String() != tt.want {
+					t.Errorf("output = %q, want %q", output.String(), tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenBudgetShorthand(t *testing.T) {
+	req := calque.NewRequest(context.Background(), strings.NewReader(strings.Repeat("a", 9)))
+	err := TokenBudget(windowedCounter(8), 0).ServeFlow(req, calque.NewResponse(&strings.Builder{}))
+	if !errors.Is(err, ErrTokenBudgetExceeded) {
+		t.Errorf("error = %v, want ErrTokenBudgetExceeded", err)
+	}
+}
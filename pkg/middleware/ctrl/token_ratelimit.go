@@ -0,0 +1,135 @@
+package ctrl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+// TokenRateLimitConfig configures TokenRateLimitWithConfig
+type TokenRateLimitConfig struct {
+	TokensPerMinute int                 // Sustained token budget, e.g. the provider's TPM quota
+	Burst           int                 // Tokens that may be spent at once (default: TokensPerMinute)
+	Tokenizer       tokenizer.Tokenizer // Counts payload tokens (default: tokenizer.Approximate)
+	ReserveOutput   int                 // Tokens charged per request for the response, since quotas usually count max_tokens too
+}
+
+// tokenBucket refills continuously and may go into debt for oversized requests
+type tokenBucket struct {
+	mu        sync.Mutex
+	available float64
+	capacity  float64
+	perSecond float64
+	last      time.Time
+}
+
+// TokenRateLimit throttles requests by estimated token count rather than request count.
+//
+// Input: any data type (buffered to count tokens)
+// Output: same as input (pass-through when allowed)
+// Behavior: BUFFERED - reads the payload, waits until its tokens fit the budget, then forwards it
+//
+// Providers enforce tokens-per-minute quotas, so a few large prompts can
+// exhaust a limit that RateLimit would happily let through. The budget
+// refills continuously, smoothing bursts instead of spending a full minute's
+// quota up front and then stalling. A request larger than the burst waits
+// for a full bucket and then proceeds, delaying the requests after it. A nil
+// tokenizer uses tokenizer.Approximate.
+//
+// Example:
+//
+//	limit := ctrl.TokenRateLimit(30000, nil) // stay under a 30k TPM quota
+//	flow.Use(limit).Use(ai.Agent(client))
+func TokenRateLimit(tpm int, counter tokenizer.Tokenizer) calque.Handler {
+	return TokenRateLimitWithConfig(&TokenRateLimitConfig{TokensPerMinute: tpm, Tokenizer: counter})
+}
+
+// TokenRateLimitWithConfig throttles requests by estimated token count with custom burst and output reservation.
+//
+// Example:
+//
+//	limit := ctrl.TokenRateLimitWithConfig(&ctrl.TokenRateLimitConfig{
+//		TokensPerMinute: 30000,
+//		Burst:           8000,
+//		ReserveOutput:   1000, // max_tokens configured on the client
+//	})
+func TokenRateLimitWithConfig(config *TokenRateLimitConfig) calque.Handler {
+	cfg := TokenRateLimitConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.TokensPerMinute <= 0 {
+		return calque.HandlerFunc(func(r *calque.Request, _ *calque.Response) error {
+			return calque.NewErr(r.Context, fmt.Sprintf("invalid token rate limit: tokens per minute must be greater than 0, got %d", cfg.TokensPerMinute))
+		})
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = cfg.TokensPerMinute
+	}
+	counter := tokenizer.OrApproximate(cfg.Tokenizer)
+
+	bucket := &tokenBucket{
+		available: float64(cfg.Burst),
+		capacity:  float64(cfg.Burst),
+		perSecond: float64(cfg.TokensPerMinute) / 60,
+		last:      time.Now(),
+	}
+
+	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		var input []byte
+		if err := calque.Read(r, &input); err != nil {
+			return err
+		}
+
+		tokens := counter.CountTokens(input) + cfg.ReserveOutput
+		waited, err := bucket.take(r.Context, tokens)
+		if err != nil {
+			return calque.WrapErr(r.Context, err, "token rate limit wait failed")
+		}
+		if waited > 0 {
+			calque.LogDebug(r.Context, "token rate limit delayed request", "tokens", tokens, "waited", waited)
+		}
+
+		_, err = io.Copy(w.Data, bytes.NewReader(input))
+		return err
+	})
+}
+
+// take waits until tokens fit the bucket, or the bucket is full for oversized
+// requests, then spends them. It returns how long it waited.
+func (b *tokenBucket) take(ctx context.Context, tokens int) (time.Duration, error) {
+	start := time.Now()
+	need := min(float64(tokens), b.capacity)
+	for {
+		b.mu.Lock()
+		b.refill()
+		if b.available >= need {
+			b.available -= float64(tokens)
+			b.mu.Unlock()
+			return time.Since(start), nil
+		}
+		wait := time.Duration((need - b.available) / b.perSecond * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(max(wait, time.Millisecond))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return time.Since(start), ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// refill adds tokens for the time since the last refill (must be called with mutex held)
+func (b *tokenBucket) refill() {
+	now := time.Now()
+	b.available = min(b.capacity, b.available+now.Sub(b.last).Seconds()*b.perSecond)
+	b.last = now
+}
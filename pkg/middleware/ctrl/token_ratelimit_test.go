@@ -0,0 +1,105 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+func TestTokenRateLimit(t *testing.T) {
+	// 6000 TPM refills 100 tokens per second, i.e. 10 tokens every 100ms
+	tests := []struct {
+		name     string
+		config   *TokenRateLimitConfig
+		inputs   []string
+		minTotal time.Duration
+		maxTotal time.Duration
+	}{
+		{
+			name:     "within burst passes immediately",
+			config:   &TokenRateLimitConfig{TokensPerMinute: 6000, Burst: 20},
+			inputs:   []string{strings.Repeat("a", 40), strings.Repeat("b", 40)},
+			maxTotal: 50 * time.Millisecond,
+		},
+		{
+			name:     "second request waits for refill",
+			config:   &TokenRateLimitConfig{TokensPerMinute: 6000, Burst: 10},
+			inputs:   []string{strings.Repeat("a", 40), strings.Repeat("b", 40)},
+			minTotal: 80 * time.Millisecond,
+			maxTotal: 500 * time.Millisecond,
+		},
+		{
+			name:     "oversized request delays the next one",
+			config:   &TokenRateLimitConfig{TokensPerMinute: 6000, Burst: 10},
+			inputs:   []string{strings.Repeat("a", 80), "b"},
+			minTotal: 80 * time.Millisecond,
+			maxTotal: 500 * time.Millisecond,
+		},
+		{
+			name:     "output reservation is charged",
+			config:   &TokenRateLimitConfig{TokensPerMinute: 6000, Burst: 10, ReserveOutput: 9},
+			inputs:   []string{"a", "b"},
+			minTotal: 80 * time.Millisecond,
+			maxTotal: 500 * time.Millisecond,
+		},
+		{
+			name: "custom tokenizer",
+			config: &TokenRateLimitConfig{TokensPerMinute: 6000, Burst: 10, Tokenizer: tokenizer.Func(func(text []byte) int {
+				return len(text)
+			})},
+			inputs:   []string{"0123456789", "x"},
+			minTotal: 5 * time.Millisecond,
+			maxTotal: 500 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flow := calque.NewFlow().Use(TokenRateLimitWithConfig(tt.config))
+
+			start := time.Now()
+			for _, input := range tt.inputs {
+				var out string
+				if err := flow.Run(context.Background(), input, &out); err != nil {
+					t.Fatalf("Run() error = %v", err)
+				}
+				if out != input {
+					t.Errorf("output = %q, want %q", out, input)
+				}
+			}
+			elapsed := time.Since(start)
+			if elapsed < tt.minTotal || elapsed > tt.maxTotal {
+				t.Errorf("took %v, want between %v and %v", elapsed, tt.minTotal, tt.maxTotal)
+			}
+		})
+	}
+}
+
+func TestTokenRateLimitCancelled(t *testing.T) {
+	flow := calque.NewFlow().Use(TokenRateLimit(60, nil)) // one token per second
+
+	var out string
+	if err := flow.Run(context.Background(), strings.Repeat("a", 240), &out); err != nil {
+		t.Fatalf("first Run() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := flow.Run(ctx, "more", &out)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestTokenRateLimitInvalid(t *testing.T) {
+	var out string
+	err := calque.NewFlow().Use(TokenRateLimit(0, nil)).Run(context.Background(), "hi", &out)
+	if err == nil || !strings.Contains(err.Error(), "tokens per minute must be greater than 0") {
+		t.Errorf("error = %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+var paragraphBreak = regexp.MustCompile(`\n[ \t]*\n\s*`)
+
+// unit is an indivisible piece of text and the separator that precedes it
+// when joined back together
+type unit struct {
+	text   string
+	sep    string
+	tokens int
+}
+
+// Split divides text into chunks of at most maxTokens tokens, consecutive
+// chunks sharing up to overlapTokens tokens of context.
+//
+// Input: document text, chunk and overlap sizes, token counter (nil for tokenizer.Approximate)
+// Output: chunks in document order
+// Behavior: Breaks on paragraphs first, then lines, then words, so chunks end
+// on natural boundaries; only a single word longer than maxTokens is cut
+// mid-word. Overlap repeats whole trailing pieces of the previous chunk.
+//
+// Example:
+//
+//	chunks := extract.Split(contract, 2000, 200, nil)
+func Split(text string, maxTokens, overlapTokens int, counter tokenizer.Tokenizer) []string {
+	counter = tokenizer.OrApproximate(counter)
+	maxTokens = max(maxTokens, 1)
+	overlapTokens = min(max(overlapTokens, 0), maxTokens/2)
+
+	var units []unit
+	for _, paragraph := range paragraphBreak.Split(strings.TrimSpace(text), -1) {
+		if paragraph == "" {
+			continue
+		}
+		units = append(units, splitUnit(unit{text: paragraph, sep: "\n\n"}, maxTokens, counter)...)
+	}
+	if len(units) == 0 {
+		return nil
+	}
+
+	var chunks []string
+	var current []unit
+	size := 0
+	for _, u := range units {
+		if size+u.tokens > maxTokens && len(current) > 0 {
+			chunks = append(chunks, join(current))
+			// Carry trailing units forward as overlap while they leave room for u
+			var carried []unit
+			size = 0
+			for i := len(current) - 1; i >= 0; i-- {
+				if size+current[i].tokens > overlapTokens || size+current[i].tokens+u.tokens > maxTokens {
+					break
+				}
+				carried = append([]unit{current[i]}, carried...)
+				size += current[i].tokens
+			}
+			current = carried
+		}
+		current = append(current, u)
+		size += u.tokens
+	}
+	return append(chunks, join(current))
+}
+
+// splitUnit breaks u into pieces that fit maxTokens, from lines down to words
+func splitUnit(u unit, maxTokens int, counter tokenizer.Tokenizer) []unit {
+	u.tokens = counter.CountTokens([]byte(u.text))
+	if u.tokens <= maxTokens {
+		return []unit{u}
+	}
+
+	var parts []string
+	sep := "\n"
+	if parts = strings.Split(u.text, "\n"); len(parts) == 1 {
+		sep = " "
+		if parts = strings.Fields(u.text); len(parts) == 1 {
+			return cutWord(u, maxTokens, counter)
+		}
+	}
+
+	var units []unit
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		piece := unit{text: part, sep: sep}
+		if i == 0 {
+			piece.sep = u.sep
+		}
+		units = append(units, splitUnit(piece, maxTokens, counter)...)
+	}
+	return units
+}
+
+// cutWord hard-cuts a single oversized word at rune boundaries
+func cutWord(u unit, maxTokens int, counter tokenizer.Tokenizer) []unit {
+	runes := []rune(u.text)
+	step := max(len(runes)*maxTokens/u.tokens, 1)
+	var units []unit
+	for start := 0; start < len(runes); start += step {
+		piece := string(runes[start:min(start+step, len(runes))])
+		sep := ""
+		if start == 0 {
+			sep = u.sep
+		}
+		units = append(units, unit{text: piece, sep: sep, tokens: counter.CountTokens([]byte(piece))})
+	}
+	return units
+}
+
+func join(units []unit) string {
+	var b strings.Builder
+	for i, u := range units {
+		if i > 0 {
+			b.WriteString(u.sep)
+		}
+		b.WriteString(u.text)
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+// words counts whitespace-separated words, making chunk sizes easy to reason about
+var words = tokenizer.Func(func(text []byte) int {
+	return len(strings.Fields(string(text)))
+})
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		text    string
+		max     int
+		overlap int
+		want    []string
+	}{
+		{name: "empty", text: " \n\n ", max: 10},
+		{name: "fits", text: "one two three", max: 10, want: []string{"one two three"}},
+		{
+			name: "paragraphs packed",
+			text: "a b\n\nc d\n\n\ne f",
+			max:  4,
+			want: []string{"a b\n\nc d", "e f"},
+		},
+		{
+			name: "long paragraph split on lines then words",
+			text: "a b c\nd e f g h i\nj",
+			max:  3,
+			want: []string{"a b c", "d e f", "g h i", "j"},
+		},
+		{
+			name:    "overlap carries trailing pieces",
+			text:    "a\n\nb\n\nc\n\nd\n\ne",
+			max:     3,
+			overlap: 1,
+			want:    []string{"a\n\nb\n\nc", "c\n\nd\n\ne"},
+		},
+		{
+			name:    "overlap capped at half the chunk",
+			text:    "a b c d e f",
+			max:     2,
+			overlap: 5,
+			want:    []string{"a b", "b c", "c d", "d e", "e f"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := Split(tt.text, tt.max, tt.overlap, words)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Errorf("Split() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitLongWord(t *testing.T) {
+	t.Parallel()
+
+	word := strings.Repeat("é", 40)
+	chunks := Split("start "+word, 5, 0, nil)
+	if strings.Join(chunks, "") != "start"+word {
+		t.Errorf("chunks %q lost text", chunks)
+	}
+	for _, chunk := range chunks {
+		if tokens := tokenizer.Approximate.CountTokens([]byte(chunk)); tokens > 5 {
+			t.Errorf("chunk %q has %d tokens, want at most 5", chunk, tokens)
+		}
+	}
+}
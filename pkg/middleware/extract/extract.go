@@ -0,0 +1,221 @@
+// Package extract pulls structured data out of documents too long for one
+// model call.
+//
+// Pipeline splits a document into token-sized chunks, extracts a typed value
+// from each chunk in parallel with a schema-constrained agent, and merges the
+// partial values into one. Split and MergeJSON are the building blocks and
+// can be used on their own.
+package extract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+// DefaultInstructions is the extraction prompt used when Options.Instructions is empty
+const DefaultInstructions = "Extract the requested information from the document. Use only facts stated in the text."
+
+// Options configures Pipeline
+type Options[T any] struct {
+	// Instructions describe what to extract (default: DefaultInstructions)
+	Instructions string
+	// ChunkTokens is the largest chunk sent in one call (default: 2000)
+	ChunkTokens int
+	// OverlapTokens is the context shared by consecutive chunks (default: 200)
+	OverlapTokens int
+	// Tokenizer counts chunk tokens (default: tokenizer.Approximate)
+	Tokenizer tokenizer.Tokenizer
+	// Concurrency is the number of chunks extracted at once (default: 4)
+	Concurrency int
+	// SkipFailedChunks drops chunks whose extraction fails instead of failing
+	// the document; it still fails when every chunk does
+	SkipFailedChunks bool
+	// Merge combines the partial results in document order (default: MergeJSON)
+	Merge func(parts []T) (T, error)
+	// AgentOptions are added to the per-chunk agent, after the schema for T
+	AgentOptions []ai.AgentOption
+}
+
+// Pipeline extracts a T from a document of any length.
+//
+// Input: document text
+// Output: JSON-encoded T
+// Behavior: BUFFERED - reads the whole document and writes once all chunks are merged
+//
+// The document is split with Split. Each chunk is sent to an agent constrained
+// to T's JSON schema, with Concurrency chunks in flight. The chunk results are
+// combined with Merge. When the document fits in one chunk, that chunk's
+// result is written as is. Chunk prompts say which part of the document they
+// hold, so the model leaves out fields that part does not mention.
+//
+// Example:
+//
+//	type Resume struct {
+//		Name   string   `json:"name"`
+//		Skills []string `json:"skills"`
+//		Jobs   []Job    `json:"jobs"`
+//	}
+//
+//	var resume Resume
+//	err := calque.NewFlow().
+//		Use(extract.Pipeline[Resume](client, &extract.Options[Resume]{Instructions: "Extract the candidate's resume."})).
+//		Run(ctx, document, convert.FromJSON(&resume))
+func Pipeline[T any](client ai.Client, opts *Options[T]) calque.Handler {
+	cfg := Options[T]{}
+	if opts != nil {
+		cfg = *opts
+	}
+	if cfg.Instructions == "" {
+		cfg.Instructions = DefaultInstructions
+	}
+	if cfg.ChunkTokens <= 0 {
+		cfg.ChunkTokens = 2000
+	}
+	if cfg.OverlapTokens == 0 {
+		cfg.OverlapTokens = 200
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.Merge == nil {
+		cfg.Merge = MergeJSON[T]
+	}
+
+	agentOpts := append([]ai.AgentOption{ai.WithSchemaFor[T]()}, cfg.AgentOptions...)
+	return &pipeline[T]{config: cfg, agent: ai.Agent(client, agentOpts...)}
+}
+
+type pipeline[T any] struct {
+	config Options[T]
+	agent  calque.Handler
+}
+
+func (p *pipeline[T]) ServeFlow(req *calque.Request, res *calque.Response) error {
+	var document string
+	if err := calque.Read(req, &document); err != nil {
+		return err
+	}
+	chunks := Split(document, p.config.ChunkTokens, p.config.OverlapTokens, p.config.Tokenizer)
+	if len(chunks) == 0 {
+		return calque.NewErr(req.Context, "extract: empty document")
+	}
+
+	ctx, cancel := context.WithCancel(req.Context)
+	defer cancel()
+
+	parts := make([]T, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, p.config.Concurrency)
+	// The first failure cancels the rest, so report it rather than their cancellations
+	var firstErr error
+	var once sync.Once
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			if errs[i] = p.extractChunk(ctx, chunk, i, len(chunks), &parts[i]); errs[i] != nil && !p.config.SkipFailedChunks {
+				once.Do(func() {
+					firstErr = calque.WrapErr(req.Context, errs[i], fmt.Sprintf("extract: chunk %d of %d failed", i+1, len(chunks)))
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+
+	extracted := make([]T, 0, len(chunks))
+	var failed []error
+	for i, err := range errs {
+		if err == nil {
+			extracted = append(extracted, parts[i])
+			continue
+		}
+		failed = append(failed, err)
+		calque.LogWarn(req.Context, "extract: skipping failed chunk", "chunk", i+1, "chunks", len(chunks), "error", err)
+	}
+	if len(extracted) == 0 {
+		return calque.WrapErr(req.Context, errors.Join(failed...), fmt.Sprintf("extract: all %d chunks failed", len(chunks)))
+	}
+
+	merged := extracted[0]
+	if len(extracted) > 1 {
+		var err error
+		if merged, err = p.config.Merge(extracted); err != nil {
+			return calque.WrapErr(req.Context, err, "extract: failed to merge chunk results")
+		}
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return calque.WrapErr(req.Context, err, "extract: failed to encode result")
+	}
+	return calque.Write(res, data)
+}
+
+// extractChunk runs the agent on one chunk and decodes its answer into out
+func (p *pipeline[T]) extractChunk(ctx context.Context, chunk string, index, total int, out *T) error {
+	var prompt strings.Builder
+	prompt.WriteString(p.config.Instructions)
+	if total > 1 {
+		fmt.Fprintf(&prompt, "\n\nThis is part %d of %d of a longer document. Extract only what appears in this part "+
+			"and leave out anything it does not mention.", index+1, total)
+	}
+	prompt.WriteString("\n\n<document>\n")
+	prompt.WriteString(chunk)
+	prompt.WriteString("\n</document>")
+
+	var answer []byte
+	if err := calque.NewFlow().Use(p.agent).Run(ctx, prompt.String(), &answer); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(unfence(answer), out); err != nil {
+		return calque.WrapErr(ctx, err, "model answer is not valid JSON")
+	}
+	return nil
+}
+
+// unfence strips a Markdown code fence some models wrap JSON answers in
+func unfence(answer []byte) []byte {
+	answer = bytes.TrimSpace(answer)
+	if !bytes.HasPrefix(answer, []byte("```")) {
+		return answer
+	}
+	if i := bytes.IndexByte(answer, '\n'); i >= 0 {
+		answer = answer[i+1:]
+	}
+	return bytes.TrimSpace(bytes.TrimSuffix(bytes.TrimSpace(answer), []byte("```")))
+}
+
+// Egress implements calque.Egress, reporting where the extraction client sends input
+func (p *pipeline[T]) Egress() []string {
+	return calque.EgressOf(p.agent)
+}
+
+// Warmup implements calque.Warmer by warming the extraction client
+func (p *pipeline[T]) Warmup(ctx context.Context) error {
+	return calque.WarmupHandler(ctx, p.agent)
+}
+
+// Shutdown implements calque.Shutdowner by shutting down the extraction client
+func (p *pipeline[T]) Shutdown(ctx context.Context) error {
+	return calque.ShutdownHandler(ctx, p.agent)
+}
@@ -0,0 +1,235 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/convert"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+)
+
+type job struct {
+	Company string `json:"company"`
+	Years   int    `json:"years,omitempty"`
+}
+
+type resume struct {
+	Name   string   `json:"name"`
+	Skills []string `json:"skills"`
+	Jobs   []job    `json:"jobs"`
+}
+
+// funcClient answers each prompt with answer, tracking peak concurrency
+type funcClient struct {
+	answer  func(prompt string) (string, error)
+	mu      sync.Mutex
+	prompts []string
+	active  atomic.Int32
+	peak    atomic.Int32
+	hasJSON atomic.Bool
+}
+
+func (c *funcClient) Chat(r *calque.Request, w *calque.Response, opts *ai.AgentOptions) error {
+	n := c.active.Add(1)
+	defer c.active.Add(-1)
+	for {
+		peak := c.peak.Load()
+		if n <= peak || c.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	if opts != nil && opts.Schema != nil {
+		c.hasJSON.Store(true)
+	}
+
+	var prompt string
+	if err := calque.Read(r, &prompt); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.prompts = append(c.prompts, prompt)
+	c.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+	answer, err := c.answer(prompt)
+	if err != nil {
+		return err
+	}
+	return calque.Write(w, answer)
+}
+
+var partNumber = regexp.MustCompile(`part (\d+) of`)
+
+// resumeAnswers answers per document part, as a model would for each chunk
+func resumeAnswers(answers map[string]string) func(string) (string, error) {
+	return func(prompt string) (string, error) {
+		part := "1"
+		if m := partNumber.FindStringSubmatch(prompt); m != nil {
+			part = m[1]
+		}
+		answer, ok := answers[part]
+		if !ok {
+			return "", errors.New("model unavailable")
+		}
+		return answer, nil
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	t.Parallel()
+
+	document := strings.Repeat("Alice Johnson, senior engineer. ", 10) + "\n\n" +
+		strings.Repeat("Worked at Tech Corp on Go services. ", 10) + "\n\n" +
+		strings.Repeat("Skills: Go, Kubernetes, PostgreSQL. ", 10)
+
+	tests := []struct {
+		name    string
+		answers map[string]string
+		opts    *Options[resume]
+		want    string
+		wantErr string
+	}{
+		{
+			name: "merges chunk results",
+			answers: map[string]string{
+				"1": `{"name":"Alice Johnson","skills":[],"jobs":[]}`,
+				"2": "```json\n{\"name\":\"\",\"skills\":[\"Go\"],\"jobs\":[{\"company\":\"Tech Corp\"}]}\n```",
+				"3": `{"name":"alice johnson","skills":["go","Kubernetes"],"jobs":[{"company":"tech corp","years":4}]}`,
+			},
+			opts: &Options[resume]{ChunkTokens: 100, OverlapTokens: -1},
+			want: `{"name":"Alice Johnson","skills":["Go","Kubernetes"],"jobs":[{"company":"Tech Corp","years":4}]}`,
+		},
+		{
+			name:    "single chunk passes through",
+			answers: map[string]string{"1": `{"name":"Alice","skills":["Go","go"],"jobs":null}`},
+			want:    `{"name":"Alice","skills":["Go","go"],"jobs":null}`,
+		},
+		{
+			name:    "chunk failure fails the document",
+			answers: map[string]string{"1": `{"name":"Alice"}`, "3": `{}`},
+			opts:    &Options[resume]{ChunkTokens: 100, OverlapTokens: -1},
+			wantErr: "extract: chunk 2 of 3 failed",
+		},
+		{
+			name:    "skipped chunk failure",
+			answers: map[string]string{"1": `{"name":"Alice"}`, "3": `{"skills":["Go"]}`},
+			opts:    &Options[resume]{ChunkTokens: 100, OverlapTokens: -1, SkipFailedChunks: true},
+			want:    `{"name":"Alice","skills":["Go"],"jobs":null}`,
+		},
+		{
+			name:    "every chunk failed",
+			answers: map[string]string{},
+			opts:    &Options[resume]{ChunkTokens: 100, OverlapTokens: -1, SkipFailedChunks: true},
+			wantErr: "extract: all 3 chunks failed",
+		},
+		{
+			name:    "invalid json",
+			answers: map[string]string{"1": `I could not find a resume.`},
+			wantErr: "model answer is not valid JSON",
+		},
+		{
+			name: "custom merge",
+			answers: map[string]string{
+				"1": `{"name":"Alice","skills":["Go"]}`,
+				"2": `{"skills":["Go"]}`,
+				"3": `{"skills":["SQL"]}`,
+			},
+			opts: &Options[resume]{ChunkTokens: 100, OverlapTokens: -1, Merge: func(parts []resume) (resume, error) {
+				merged := parts[0]
+				for _, part := range parts[1:] {
+					merged.Skills = append(merged.Skills, part.Skills...)
+				}
+				return merged, nil
+			}},
+			want: `{"name":"Alice","skills":["Go","Go","SQL"],"jobs":null}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &funcClient{answer: resumeAnswers(tt.answers)}
+			var got string
+			err := calque.NewFlow().Use(Pipeline(client, tt.opts)).Run(context.Background(), document, &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("output = %s, want %s", got, tt.want)
+			}
+			if !client.hasJSON.Load() {
+				t.Error("agent was not given T's schema")
+			}
+		})
+	}
+}
+
+func TestPipelinePrompts(t *testing.T) {
+	t.Parallel()
+
+	client := &funcClient{answer: func(string) (string, error) { return `{"name":"Alice"}`, nil }}
+	document := strings.Repeat("word ", 400)
+	var result resume
+	err := calque.NewFlow().
+		Use(Pipeline(client, &Options[resume]{Instructions: "Extract the resume.", ChunkTokens: 100, OverlapTokens: 10, Concurrency: 2})).
+		Run(context.Background(), document, convert.FromJSON(&result))
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if result.Name != "Alice" {
+		t.Errorf("result = %+v", result)
+	}
+	if peak := client.peak.Load(); peak > 2 {
+		t.Errorf("peak concurrency = %d, want at most 2", peak)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if len(client.prompts) < 5 {
+		t.Fatalf("sent %d prompts, want the document split into several chunks", len(client.prompts))
+	}
+	for _, prompt := range client.prompts {
+		if !strings.HasPrefix(prompt, "Extract the resume.\n\nThis is part ") || !strings.Contains(prompt, "<document>\nword word") {
+			t.Errorf("prompt = %q", prompt[:min(len(prompt), 120)])
+		}
+	}
+}
+
+func TestPipelineEmptyDocument(t *testing.T) {
+	t.Parallel()
+
+	client := &funcClient{answer: func(string) (string, error) { return `{}`, nil }}
+	err := calque.NewFlow().Use(Pipeline[resume](client, nil)).Run(context.Background(), "  \n\n ", new(string))
+	if err == nil || !strings.Contains(err.Error(), "empty document") {
+		t.Errorf("error = %v, want empty document", err)
+	}
+}
+
+// remoteClient is a client on another host
+type remoteClient struct{ ai.Client }
+
+func (remoteClient) Egress() []string { return []string{"api.example.com"} }
+
+func TestPipelineEgress(t *testing.T) {
+	if got := calque.DescribeHandler(Pipeline[job](remoteClient{ai.NewMockClient("{}")}, nil)).Egress; !slices.Equal(got, []string{"api.example.com"}) {
+		t.Errorf("egress = %v, want the client's host", got)
+	}
+	if got := calque.DescribeHandler(Pipeline[job](ai.NewMockClient("{}"), nil)).Egress; got != nil {
+		t.Errorf("egress with a local client = %v, want none", got)
+	}
+}
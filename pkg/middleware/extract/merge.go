@@ -0,0 +1,151 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// MergeJSON combines partial extractions into one value. It is the default
+// Options.Merge.
+//
+// Input: partial results in document order
+// Output: merged result
+// Behavior: Works on the JSON form of T:
+//   - scalars: the first non-empty value wins
+//   - objects: merged field by field
+//   - arrays: concatenated and deduplicated
+//
+// Strings are compared case-insensitively after trimming. Two array objects
+// are treated as the same item when they share at least one non-empty field
+// and agree on every field both have set. They are merged, so an entity seen
+// in two overlapping chunks appears once with fields from both.
+//
+// Example:
+//
+//	merged, err := extract.MergeJSON([]Resume{fromPage1, fromPage2})
+func MergeJSON[T any](parts []T) (T, error) {
+	var result T
+	var merged any
+	for _, part := range parts {
+		data, err := json.Marshal(part)
+		if err != nil {
+			return result, err
+		}
+		value, err := decodeJSON(data)
+		if err != nil {
+			return result, err
+		}
+		merged = mergeValue(merged, value)
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+func decodeJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	err := decoder.Decode(&value)
+	return value, err
+}
+
+func mergeValue(a, b any) any {
+	if isEmpty(a) {
+		return b
+	}
+	if isEmpty(b) {
+		return a
+	}
+	switch a := a.(type) {
+	case map[string]any:
+		if b, ok := b.(map[string]any); ok {
+			for key, value := range b {
+				a[key] = mergeValue(a[key], value)
+			}
+		}
+		return a
+	case []any:
+		if b, ok := b.([]any); ok {
+			return dedupe(append(a, b...))
+		}
+		return a
+	}
+	return a
+}
+
+// dedupe removes repeated items, merging objects that describe the same thing
+func dedupe(items []any) []any {
+	var out []any
+next:
+	for _, item := range items {
+		for i, existing := range out {
+			if sameItem(existing, item) {
+				out[i] = mergeValue(existing, item)
+				continue next
+			}
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+func sameItem(a, b any) bool {
+	am, aok := a.(map[string]any)
+	bm, bok := b.(map[string]any)
+	if !aok || !bok {
+		return equalValue(a, b)
+	}
+	shared := false
+	for key, av := range am {
+		bv, ok := bm[key]
+		if !ok || isEmpty(av) || isEmpty(bv) {
+			continue
+		}
+		if !equalValue(av, bv) {
+			return false
+		}
+		shared = true
+	}
+	return shared
+}
+
+func equalValue(a, b any) bool {
+	if as, ok := a.(string); ok {
+		bs, ok := b.(string)
+		return ok && strings.EqualFold(strings.TrimSpace(as), strings.TrimSpace(bs))
+	}
+	// encoding/json sorts map keys, so equal values encode identically
+	aj, aerr := json.Marshal(a)
+	bj, berr := json.Marshal(b)
+	return aerr == nil && berr == nil && bytes.Equal(aj, bj)
+}
+
+func isEmpty(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case bool:
+		return !v
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && f == 0
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		for _, value := range v {
+			if !isEmpty(value) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package extract
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMergeJSON(t *testing.T) {
+	t.Parallel()
+
+	type contact struct {
+		Name  string `json:"name"`
+		Email string `json:"email,omitempty"`
+		Phone string `json:"phone,omitempty"`
+	}
+	type record struct {
+		Title    string            `json:"title"`
+		Pages    int               `json:"pages"`
+		Signed   bool              `json:"signed"`
+		Tags     []string          `json:"tags"`
+		Contacts []contact         `json:"contacts"`
+		Meta     map[string]string `json:"meta"`
+	}
+
+	tests := []struct {
+		name  string
+		parts []record
+		want  string
+	}{
+		{
+			name:  "first non-empty scalar wins",
+			parts: []record{{Pages: 0}, {Title: "Lease", Pages: 3}, {Title: "Other", Pages: 9, Signed: true}},
+			want:  `{"title":"Lease","pages":3,"signed":true,"tags":null,"contacts":null,"meta":null}`,
+		},
+		{
+			name:  "lists deduplicated case-insensitively",
+			parts: []record{{Tags: []string{"Lease", "rent"}}, {Tags: []string{" lease", "Deposit"}}},
+			want:  `{"title":"","pages":0,"signed":false,"tags":["Lease","rent","Deposit"],"contacts":null,"meta":null}`,
+		},
+		{
+			name: "partial objects for the same entity merged",
+			parts: []record{
+				{Contacts: []contact{{Name: "Ann Lee", Email: "ann@example.com"}, {Name: "Bob"}}},
+				{Contacts: []contact{{Name: "ann lee", Phone: "555-0100"}, {Name: "Bob", Email: "bob@example.com"}}},
+				{Contacts: []contact{{Name: "Ann Lee", Email: "other@example.com"}}},
+			},
+			want: `{"title":"","pages":0,"signed":false,"tags":null,"contacts":[` +
+				`{"name":"Ann Lee","email":"ann@example.com","phone":"555-0100"},` +
+				`{"name":"Bob","email":"bob@example.com"},` +
+				`{"name":"Ann Lee","email":"other@example.com"}],"meta":null}`,
+		},
+		{
+			name:  "nested objects merged by field",
+			parts: []record{{Meta: map[string]string{"landlord": "Acme"}}, {Meta: map[string]string{"landlord": "ACME Ltd", "city": "Oslo"}}},
+			want:  `{"title":"","pages":0,"signed":false,"tags":null,"contacts":null,"meta":{"city":"Oslo","landlord":"Acme"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			merged, err := MergeJSON(tt.parts)
+			if err != nil {
+				t.Fatalf("MergeJSON() error = %v", err)
+			}
+			got, _ := json.Marshal(merged)
+			if string(got) != tt.want {
+				t.Errorf("MergeJSON() = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeJSONNoParts(t *testing.T) {
+	t.Parallel()
+
+	merged, err := MergeJSON[map[string]int](nil)
+	if err != nil || merged != nil {
+		t.Errorf("MergeJSON(nil) = %v, %v; want the zero value", merged, err)
+	}
+}
@@ -0,0 +1,101 @@
+package feedback
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Pair is a labeled input/output example derived from a rated run
+type Pair struct {
+	RunID    string            `json:"run_id"`
+	Input    string            `json:"input"`
+	Output   string            `json:"output"`             // What the flow produced
+	Expected string            `json:"expected,omitempty"` // The correction, or the output itself when rated up
+	Rating   Rating            `json:"rating"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Filter selects which runs are exported
+type Filter func(run *Run) bool
+
+// Positive selects runs rated up or given a correction, i.e. runs with a known good answer
+func Positive(run *Run) bool {
+	return run.Correction() != "" || run.Rating() == RatingUp
+}
+
+// Negative selects runs rated down, e.g. for rejected examples in preference datasets
+func Negative(run *Run) bool {
+	return run.Rating() == RatingDown
+}
+
+// Rated selects runs with any rating, score or correction
+func Rated(run *Run) bool {
+	_, scored := run.Score()
+	return run.Rating() != RatingNone || scored || run.Correction() != ""
+}
+
+// MinScore selects runs whose latest score is at least threshold
+func MinScore(threshold float64) Filter {
+	return func(run *Run) bool {
+		score, ok := run.Score()
+		return ok && score >= threshold
+	}
+}
+
+// Pairs exports labeled pairs from the runs in store matching filter.
+//
+// Input: store of runs, optional filter (default: Rated)
+// Output: pairs in run order, error if the store fails
+// Behavior: skips failed and truncated runs, since their text is incomplete
+//
+// Expected holds the most recent correction, or the output when the run was
+// rated up, so Positive pairs can be used directly as eval cases or
+// fine-tuning examples.
+//
+// Example:
+//
+//	pairs, err := feedback.Pairs(ctx, store, feedback.Positive)
+//	err = feedback.WriteJSONL(file, pairs)
+func Pairs(ctx context.Context, store Store, filter Filter) ([]Pair, error) {
+	if filter == nil {
+		filter = Rated
+	}
+	runs, err := store.ListRuns(ctx)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "feedback: failed to list runs")
+	}
+
+	var pairs []Pair
+	for _, run := range runs {
+		if run.Error != "" || run.Truncated || !filter(run) {
+			continue
+		}
+		pair := Pair{
+			RunID:    run.ID,
+			Input:    run.Input,
+			Output:   run.Output,
+			Expected: run.Correction(),
+			Rating:   run.Rating(),
+			Metadata: run.Metadata,
+		}
+		if pair.Expected == "" && pair.Rating == RatingUp {
+			pair.Expected = run.Output
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+// WriteJSONL writes one pair per line as JSON
+func WriteJSONL(w io.Writer, pairs []Pair) error {
+	enc := json.NewEncoder(w)
+	for _, pair := range pairs {
+		if err := enc.Encode(pair); err != nil {
+			return err
+		}
+	}
+	return nil
+}
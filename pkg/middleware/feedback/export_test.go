@@ -0,0 +1,80 @@
+package feedback
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPairs(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	for _, run := range []*Run{
+		{ID: "up", Input: "q1", Output: "a1"},
+		{ID: "corrected", Input: "q2", Output: "wrong"},
+		{ID: "down", Input: "q3", Output: "bad"},
+		{ID: "scored", Input: "q4", Output: "a4"},
+		{ID: "failed", Input: "q5", Error: "boom"},
+		{ID: "truncated", Input: "q6", Output: "a6", Truncated: true},
+	} {
+		_ = store.SaveRun(ctx, run)
+	}
+	_ = store.AddFeedback(ctx, Feedback{RunID: "up", Rating: RatingUp})
+	_ = store.AddFeedback(ctx, Feedback{RunID: "corrected", Rating: RatingDown, Correction: "right"})
+	_ = store.AddFeedback(ctx, Feedback{RunID: "down", Rating: RatingDown})
+	_ = store.AddFeedback(ctx, Feedback{RunID: "failed", Rating: RatingDown})
+	_ = store.AddFeedback(ctx, Feedback{RunID: "truncated", Rating: RatingUp})
+	score := 0.7
+	_ = store.AddFeedback(ctx, Feedback{RunID: "scored", Score: &score})
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"default rated", nil, []string{"up", "corrected", "down", "scored"}},
+		{"positive", Positive, []string{"up", "corrected"}},
+		{"negative", Negative, []string{"corrected", "down"}},
+		{"min score", MinScore(0.5), []string{"scored"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs, err := Pairs(ctx, store, tt.filter)
+			if err != nil {
+				t.Fatalf("Pairs() error = %v", err)
+			}
+			var ids []string
+			for _, p := range pairs {
+				ids = append(ids, p.RunID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("run IDs = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	pairs, _ := Pairs(ctx, store, Positive)
+	if pairs[0].Expected != "a1" || pairs[1].Expected != "right" || pairs[1].Output != "wrong" {
+		t.Errorf("expected values = %+v", pairs)
+	}
+}
+
+func TestWriteJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	pairs := []Pair{{RunID: "1", Input: "q", Expected: "a", Rating: RatingUp}, {RunID: "2", Input: "q2"}}
+	if err := WriteJSONL(&buf, pairs); err != nil {
+		t.Fatalf("WriteJSONL() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	var first Pair
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || first.Expected != "a" || first.Rating != RatingUp {
+		t.Errorf("first line = %s (%v)", lines[0], err)
+	}
+}
@@ -0,0 +1,369 @@
+// Package feedback records flow runs and attaches user feedback to them.
+//
+// A Collector stores the input and output of each run under its run ID (the
+// calque request ID). Users later rate the run with a thumbs-up or down, or
+// supply a corrected answer, either in code via Submit or through the HTTP
+// endpoint from HTTPHandler. Rated runs can then be exported as labeled
+// input/output pairs for evaluation sets and fine-tuning datasets.
+//
+// Example:
+//
+//	store := feedback.NewInMemoryStore()
+//	collector := feedback.Collect(store)
+//
+//	flow := calque.NewFlow().Use(collector.Wrap(ai.Agent(client)))
+//	ctx = calque.WithRequestID(ctx, runID)
+//	err := flow.Run(ctx, question, &answer)
+//
+//	http.Handle("/feedback", feedback.HTTPHandler(store))
+//
+//	pairs, err := feedback.Pairs(ctx, store, feedback.Positive)
+package feedback
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// RunIDMetadataKey is the metadata bus key holding the ID of the recorded run
+const RunIDMetadataKey = "feedback.run_id"
+
+// ErrRunNotFound is returned when feedback refers to a run the store doesn't know
+var ErrRunNotFound = errors.New("feedback: run not found")
+
+// Rating is a thumbs-up/down signal
+type Rating int
+
+const (
+	// RatingNone means no rating was given, e.g. a correction on its own
+	RatingNone Rating = 0
+	// RatingUp marks a good response
+	RatingUp Rating = 1
+	// RatingDown marks a bad response
+	RatingDown Rating = -1
+)
+
+// Run is one recorded execution of a wrapped handler
+type Run struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name,omitempty"`
+	System    string            `json:"system,omitempty"`
+	Input     string            `json:"input"`
+	ToolCalls []ToolCall        `json:"tool_calls,omitempty"`
+	Output    string            `json:"output"`
+	Error     string            `json:"error,omitempty"`
+	Truncated bool              `json:"truncated,omitempty"` // Input or output exceeded Config.MaxBytes
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Feedback  []Feedback        `json:"feedback,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// ToolCall is a tool invocation made while producing a run's output
+type ToolCall struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments,omitempty"`
+	Result    string `json:"result,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Rating returns the most recent thumbs-up/down given to the run
+func (r *Run) Rating() Rating {
+	for i := len(r.Feedback) - 1; i >= 0; i-- {
+		if r.Feedback[i].Rating != RatingNone {
+			return r.Feedback[i].Rating
+		}
+	}
+	return RatingNone
+}
+
+// Correction returns the most recent corrected output given for the run
+func (r *Run) Correction() string {
+	for i := len(r.Feedback) - 1; i >= 0; i-- {
+		if r.Feedback[i].Correction != "" {
+			return r.Feedback[i].Correction
+		}
+	}
+	return ""
+}
+
+// Score returns the most recent score given to the run
+func (r *Run) Score() (float64, bool) {
+	for i := len(r.Feedback) - 1; i >= 0; i-- {
+		if r.Feedback[i].Score != nil {
+			return *r.Feedback[i].Score, true
+		}
+	}
+	return 0, false
+}
+
+// Feedback is a user's or evaluator's judgement of a run
+type Feedback struct {
+	RunID      string    `json:"run_id"`
+	Rating     Rating    `json:"rating,omitempty"`
+	Score      *float64  `json:"score,omitempty"`      // Quality from 0 to 1, e.g. from an eval judge
+	Correction string    `json:"correction,omitempty"` // What the output should have been
+	Comment    string    `json:"comment,omitempty"`
+	UserID     string    `json:"user_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Store persists runs and their feedback
+type Store interface {
+	// SaveRun stores a run, replacing a run with the same ID but keeping its feedback
+	SaveRun(ctx context.Context, run *Run) error
+
+	// AddFeedback attaches feedback to a stored run, returning ErrRunNotFound if it doesn't exist
+	AddFeedback(ctx context.Context, fb Feedback) error
+
+	// GetRun returns a run with its feedback, or ErrRunNotFound
+	GetRun(ctx context.Context, id string) (*Run, error)
+
+	// ListRuns returns all runs, oldest first
+	ListRuns(ctx context.Context) ([]*Run, error)
+}
+
+// Config controls what a Collector records
+type Config struct {
+	// Name labels recorded runs, e.g. the flow or agent name
+	Name string
+	// System is the system prompt the wrapped handler runs with, kept for dataset export
+	System string
+	// MaxBytes caps the recorded input and output each (default: 1 MiB)
+	MaxBytes int
+	// Metadata adds key/values from the request context to each run
+	Metadata func(ctx context.Context) map[string]string
+	// OnError receives store failures; recording never fails the run itself
+	OnError func(error)
+}
+
+// Collector records runs into a Store and accepts feedback for them
+type Collector struct {
+	store  Store
+	config Config
+}
+
+// Collect creates a Collector recording runs into store.
+//
+// Input: store for runs and feedback
+// Output: *Collector
+// Behavior: wrapped handlers stream unchanged; the run is saved after the handler returns
+//
+// Example:
+//
+//	collector := feedback.Collect(store)
+//	flow.Use(collector.Wrap(agent))
+func Collect(store Store) *Collector {
+	return CollectWithConfig(store, nil)
+}
+
+// CollectWithConfig creates a Collector with custom configuration.
+//
+// Example:
+//
+//	collector := feedback.CollectWithConfig(store, &feedback.Config{
+//		Name:     "support_agent",
+//		MaxBytes: 64 << 10,
+//	})
+func CollectWithConfig(store Store, config *Config) *Collector {
+	cfg := Config{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 1 << 20
+	}
+	return &Collector{store: store, config: cfg}
+}
+
+// Wrap records each run of handler.
+//
+// Input: any data, streamed to handler unchanged
+// Output: handler's output, streamed unchanged
+// Behavior: STREAMING - copies up to MaxBytes of input and output aside
+//
+// Tool calls executed by tools.Execute inside handler, such as those of an
+// ai.Agent with tools, are recorded with their arguments and results.
+//
+// The run ID is the request ID from calque.WithRequestID; when none is set a
+// random ID is generated. Either way it is published on the metadata bus under
+// RunIDMetadataKey so later handlers, or the caller via RunID, can hand it to
+// the UI that collects feedback.
+//
+// Example:
+//
+//	ctx = calque.WithRequestID(ctx, "run-123")
+//	err := calque.NewFlow().Use(collector.Wrap(agent)).Run(ctx, question, &answer)
+//	// later: collector.Submit(ctx, feedback.Feedback{RunID: "run-123", Rating: feedback.RatingUp})
+func (c *Collector) Wrap(handler calque.Handler) calque.Handler {
+	return &collectedHandler{collector: c, handler: handler}
+}
+
+// Submit attaches feedback to a recorded run
+func (c *Collector) Submit(ctx context.Context, fb Feedback) error {
+	return Submit(ctx, c.store, fb)
+}
+
+// Submit validates feedback and attaches it to a run in store
+func Submit(ctx context.Context, store Store, fb Feedback) error {
+	if err := validate(ctx, fb); err != nil {
+		return err
+	}
+	if fb.UserID == "" {
+		if user, ok := calque.UserFromContext(ctx); ok {
+			fb.UserID = user.ID
+		}
+	}
+	if fb.CreatedAt.IsZero() {
+		fb.CreatedAt = time.Now()
+	}
+	return store.AddFeedback(ctx, fb)
+}
+
+func validate(ctx context.Context, fb Feedback) error {
+	if fb.RunID == "" {
+		return calque.NewErr(ctx, "feedback: run ID is required")
+	}
+	if fb.Rating < RatingDown || fb.Rating > RatingUp {
+		return calque.NewErr(ctx, "feedback: rating must be -1, 0 or 1")
+	}
+	if fb.Score != nil && (*fb.Score < 0 || *fb.Score > 1) {
+		return calque.NewErr(ctx, "feedback: score must be between 0 and 1")
+	}
+	if fb.Rating == RatingNone && fb.Score == nil && fb.Correction == "" && fb.Comment == "" {
+		return calque.NewErr(ctx, "feedback: rating, score, correction or comment is required")
+	}
+	return nil
+}
+
+// RunID returns the ID of the run recorded in this flow, falling back to the request ID
+func RunID(ctx context.Context) string {
+	if bus := calque.GetMetadataBus(ctx); bus != nil {
+		if id, ok := bus.GetString(RunIDMetadataKey); ok {
+			return id
+		}
+	}
+	return calque.RequestID(ctx)
+}
+
+// collectedHandler records the run of the handler it wraps
+type collectedHandler struct {
+	collector *Collector
+	handler   calque.Handler
+}
+
+func (h *collectedHandler) ServeFlow(req *calque.Request, res *calque.Response) error {
+	ctx := req.Context
+	runID := calque.RequestID(ctx)
+	if runID == "" {
+		runID = newID()
+		ctx = calque.WithRequestID(ctx, runID)
+	}
+	if bus := calque.GetMetadataBus(ctx); bus != nil {
+		bus.Set(RunIDMetadataKey, runID)
+	}
+
+	var mu sync.Mutex
+	var toolCalls []ToolCall
+	ctx = tools.WithResultObserver(ctx, func(_ context.Context, result tools.ToolResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		toolCalls = append(toolCalls, ToolCall{
+			ID:        result.ToolCall.ID,
+			Name:      result.ToolCall.Name,
+			Arguments: result.ToolCall.Arguments,
+			Result:    string(result.Result),
+			Error:     result.Error,
+		})
+	})
+
+	cfg := h.collector.config
+	input := &limitedBuffer{limit: cfg.MaxBytes}
+	output := &limitedBuffer{limit: cfg.MaxBytes}
+	created := time.Now()
+	err := h.handler.ServeFlow(
+		&calque.Request{Context: ctx, Data: io.TeeReader(req.Data, input)},
+		&calque.Response{Data: io.MultiWriter(res.Data, output)},
+	)
+
+	run := &Run{
+		ID:        runID,
+		Name:      cfg.Name,
+		System:    cfg.System,
+		Input:     input.String(),
+		Output:    output.String(),
+		Truncated: input.truncated || output.truncated,
+		CreatedAt: created,
+	}
+	mu.Lock()
+	run.ToolCalls = toolCalls
+	mu.Unlock()
+	if err != nil {
+		run.Error = err.Error()
+	}
+	if cfg.Metadata != nil {
+		run.Metadata = cfg.Metadata(ctx)
+	}
+	if saveErr := h.collector.store.SaveRun(ctx, run); saveErr != nil {
+		if cfg.OnError != nil {
+			cfg.OnError(saveErr)
+		}
+		calque.LogDebug(ctx, "feedback: failed to save run", "run_id", runID, "error", saveErr)
+	}
+	return err
+}
+
+// Describe implements calque.Describer, drawing the collector around the wrapped handler
+func (h *collectedHandler) Describe() calque.Node {
+	return calque.Node{Label: "feedback", Kind: calque.NodeSequence, Children: []calque.Node{calque.DescribeHandler(h.handler)}}
+}
+
+// Warmup implements calque.Warmer by warming the wrapped handler
+func (h *collectedHandler) Warmup(ctx context.Context) error {
+	return calque.WarmupHandler(ctx, h.handler)
+}
+
+// Shutdown implements calque.Shutdowner by shutting down the wrapped handler
+func (h *collectedHandler) Shutdown(ctx context.Context) error {
+	return calque.ShutdownHandler(ctx, h.handler)
+}
+
+// limitedBuffer keeps the first limit bytes written and discards the rest
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		b.buf.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// String returns the kept bytes, dropping a rune cut in half by the limit
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return strings.ToValidUTF8(b.buf.String(), "")
+	}
+	return b.buf.String()
+}
+
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,199 @@
+package feedback
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+func upper() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		data, err := io.ReadAll(req.Data)
+		if err != nil {
+			return err
+		}
+		_, err = res.Data.Write([]byte(strings.ToUpper(string(data))))
+		return err
+	})
+}
+
+func TestCollector_Wrap(t *testing.T) {
+	store := NewInMemoryStore()
+	collector := CollectWithConfig(store, &Config{
+		Name:     "support",
+		Metadata: func(ctx context.Context) map[string]string { return map[string]string{"tenant": calque.Tenant(ctx)} },
+	})
+
+	var seenRunID string
+	flow := calque.NewFlow().
+		Use(collector.Wrap(upper())).
+		UseFunc(func(req *calque.Request, res *calque.Response) error {
+			seenRunID = RunID(req.Context)
+			_, err := io.Copy(res.Data, req.Data)
+			return err
+		})
+
+	ctx := calque.WithTenant(calque.WithRequestID(context.Background(), "run-1"), "acme")
+	var out string
+	if err := flow.Run(ctx, "hello", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != "HELLO" || seenRunID != "run-1" {
+		t.Errorf("output = %q, run ID = %q", out, seenRunID)
+	}
+
+	run, err := store.GetRun(context.Background(), "run-1")
+	if err != nil {
+		t.Fatalf("GetRun() error = %v", err)
+	}
+	if run.Input != "hello" || run.Output != "HELLO" || run.Name != "support" || run.Metadata["tenant"] != "acme" || run.Error != "" {
+		t.Errorf("run = %+v", run)
+	}
+}
+
+func TestCollector_GeneratesRunIDAndRecordsErrors(t *testing.T) {
+	store := NewInMemoryStore()
+	failing := calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+		_, _ = io.ReadAll(req.Data)
+		return errors.New("model unavailable")
+	})
+
+	var out string
+	err := calque.NewFlow().Use(Collect(store).Wrap(failing)).Run(context.Background(), "question", &out)
+	if err == nil {
+		t.Fatal("wrapped handler error should be returned")
+	}
+
+	runs, _ := store.ListRuns(context.Background())
+	if len(runs) != 1 || len(runs[0].ID) != 32 || runs[0].Input != "question" || !strings.Contains(runs[0].Error, "model unavailable") {
+		t.Errorf("runs = %+v", runs)
+	}
+}
+
+func TestCollector_MaxBytes(t *testing.T) {
+	store := NewInMemoryStore()
+	collector := CollectWithConfig(store, &Config{MaxBytes: 4})
+
+	ctx := calque.WithRequestID(context.Background(), "r")
+	var out string
+	if err := calque.NewFlow().Use(collector.Wrap(upper())).Run(ctx, "héllo", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != "HÉLLO" {
+		t.Errorf("output = %q, truncation must not affect the stream", out)
+	}
+
+	run, _ := store.GetRun(ctx, "r")
+	if !run.Truncated || run.Input != "hél" || run.Output != "HÉL" {
+		t.Errorf("run = %+v, want truncated 4-byte capture", run)
+	}
+}
+
+// failingStore cannot save runs
+type failingStore struct{ InMemoryStore }
+
+func (*failingStore) SaveRun(context.Context, *Run) error { return errors.New("disk full") }
+
+func TestCollector_StoreErrorDoesNotFailRun(t *testing.T) {
+	var reported error
+	collector := CollectWithConfig(&failingStore{}, &Config{OnError: func(err error) { reported = err }})
+
+	var out string
+	if err := calque.NewFlow().Use(collector.Wrap(upper())).Run(context.Background(), "x", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != "X" || reported == nil {
+		t.Errorf("output = %q, reported = %v", out, reported)
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	store := NewInMemoryStore()
+	_ = store.SaveRun(context.Background(), &Run{ID: "r1", Input: "q", Output: "a"})
+	collector := Collect(store)
+
+	tests := []struct {
+		name    string
+		fb      Feedback
+		wantErr bool
+	}{
+		{"thumbs up", Feedback{RunID: "r1", Rating: RatingUp}, false},
+		{"correction only", Feedback{RunID: "r1", Correction: "better"}, false},
+		{"score only", Feedback{RunID: "r1", Score: ptr(0.8)}, false},
+		{"score out of range", Feedback{RunID: "r1", Score: ptr(1.5)}, true},
+		{"missing run ID", Feedback{Rating: RatingUp}, true},
+		{"invalid rating", Feedback{RunID: "r1", Rating: 5}, true},
+		{"empty feedback", Feedback{RunID: "r1"}, true},
+		{"unknown run", Feedback{RunID: "nope", Rating: RatingDown}, true},
+	}
+
+	ctx := calque.WithUser(context.Background(), calque.User{ID: "u1"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := collector.Submit(ctx, tt.fb)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Submit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	run, _ := store.GetRun(ctx, "r1")
+	if len(run.Feedback) != 3 || run.Feedback[0].UserID != "u1" || run.Feedback[0].CreatedAt.IsZero() {
+		t.Errorf("feedback = %+v", run.Feedback)
+	}
+	if run.Rating() != RatingUp || run.Correction() != "better" {
+		t.Errorf("Rating() = %v, Correction() = %q", run.Rating(), run.Correction())
+	}
+	if score, ok := run.Score(); !ok || score != 0.8 {
+		t.Errorf("Score() = %v, %v", score, ok)
+	}
+}
+
+func TestCollector_RecordsToolCalls(t *testing.T) {
+	store := NewInMemoryStore()
+	collector := CollectWithConfig(store, &Config{System: "You are a calculator."})
+
+	calc := tools.Simple("calculator", "Adds numbers", func(string) string { return "4" })
+	agent := ctrl.Chain(
+		tools.Registry(calc),
+		tools.Execute(),
+	)
+
+	ctx := calque.WithRequestID(context.Background(), "r")
+	input := `{"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "calculator", "arguments": "2+2"}}]}`
+	var out string
+	if err := calque.NewFlow().Use(collector.Wrap(agent)).Run(ctx, input, &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	run, _ := store.GetRun(ctx, "r")
+	if run.System != "You are a calculator." || len(run.ToolCalls) != 1 {
+		t.Fatalf("run = %+v", run)
+	}
+	if call := run.ToolCalls[0]; call.Name != "calculator" || call.Arguments != "2+2" || call.Result != "4" {
+		t.Errorf("tool call = %+v", call)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestRun_LatestFeedbackWins(t *testing.T) {
+	run := &Run{Feedback: []Feedback{
+		{Rating: RatingUp, Correction: "first"},
+		{Rating: RatingDown},
+		{Comment: "meh"},
+		{Correction: "second"},
+	}}
+	if run.Rating() != RatingDown || run.Correction() != "second" {
+		t.Errorf("Rating() = %v, Correction() = %q", run.Rating(), run.Correction())
+	}
+	if (&Run{}).Rating() != RatingNone {
+		t.Error("unrated run should have RatingNone")
+	}
+}
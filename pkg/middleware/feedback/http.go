@@ -0,0 +1,116 @@
+package feedback
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// maxRequestBytes bounds the size of a feedback request body
+const maxRequestBytes = 1 << 20
+
+// feedbackRequest is the JSON body accepted by HTTPHandler
+type feedbackRequest struct {
+	RunID      string   `json:"run_id"`
+	Rating     any      `json:"rating"` // "up"/"down" or 1/-1
+	Score      *float64 `json:"score"`
+	Correction string   `json:"correction"`
+	Comment    string   `json:"comment"`
+}
+
+// HTTPHandler returns an endpoint that attaches feedback to recorded runs.
+//
+// It accepts POST requests with a JSON body:
+//
+//	{"run_id": "run-123", "rating": "up", "correction": "...", "comment": "..."}
+//
+// rating may be "up", "down", 1 or -1 and is optional when a score (0 to 1),
+// correction or comment is given. The user ID is taken from the calque.User on the request
+// context, never from the body, so put authentication middleware in front of
+// the handler.
+//
+// Responses: 204 on success, 400 for invalid bodies, 404 for unknown runs,
+// 405 for other methods and 500 when the store fails.
+//
+// Example:
+//
+//	mux.Handle("/api/feedback", auth(feedback.HTTPHandler(store)))
+func HTTPHandler(store Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var body feedbackRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBytes)).Decode(&body); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		rating, ok := parseRating(body.Rating)
+		if !ok {
+			http.Error(w, `rating must be "up", "down", 1 or -1`, http.StatusBadRequest)
+			return
+		}
+
+		fb := Feedback{
+			RunID:      body.RunID,
+			Rating:     rating,
+			Score:      body.Score,
+			Correction: body.Correction,
+			Comment:    body.Comment,
+		}
+		if err := validate(r.Context(), fb); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err := Submit(r.Context(), store, fb)
+		switch {
+		case errors.Is(err, ErrRunNotFound):
+			http.Error(w, "run not found", http.StatusNotFound)
+		case err != nil:
+			calque.LogError(r.Context(), "feedback: failed to store feedback", err, "run_id", fb.RunID)
+			http.Error(w, "failed to store feedback", http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
+
+// parseRating accepts the rating forms a UI is likely to send
+func parseRating(v any) (Rating, bool) {
+	switch r := v.(type) {
+	case nil:
+		return RatingNone, true
+	case float64:
+		switch r {
+		case 1:
+			return RatingUp, true
+		case -1:
+			return RatingDown, true
+		case 0:
+			return RatingNone, true
+		}
+	case bool:
+		if r {
+			return RatingUp, true
+		}
+		return RatingDown, true
+	case string:
+		switch strings.ToLower(r) {
+		case "up", "thumbs_up", "positive", "+1", "1":
+			return RatingUp, true
+		case "down", "thumbs_down", "negative", "-1":
+			return RatingDown, true
+		case "":
+			return RatingNone, true
+		}
+	}
+	return RatingNone, false
+}
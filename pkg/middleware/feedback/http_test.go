@@ -0,0 +1,66 @@
+package feedback
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// brokenStore cannot store feedback
+type brokenStore struct{ InMemoryStore }
+
+func (*brokenStore) AddFeedback(context.Context, Feedback) error { return errors.New("db down") }
+
+func TestHTTPHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		store      Store
+		wantStatus int
+		wantRating Rating
+	}{
+		{"thumbs up string", http.MethodPost, `{"run_id":"r1","rating":"up"}`, nil, http.StatusNoContent, RatingUp},
+		{"thumbs down number", http.MethodPost, `{"run_id":"r1","rating":-1}`, nil, http.StatusNoContent, RatingDown},
+		{"score only", http.MethodPost, `{"run_id":"r1","score":0.9}`, nil, http.StatusNoContent, RatingNone},
+		{"correction without rating", http.MethodPost, `{"run_id":"r1","correction":"fixed"}`, nil, http.StatusNoContent, RatingNone},
+		{"unknown run", http.MethodPost, `{"run_id":"zzz","rating":"up"}`, nil, http.StatusNotFound, RatingNone},
+		{"bad rating", http.MethodPost, `{"run_id":"r1","rating":"meh"}`, nil, http.StatusBadRequest, RatingNone},
+		{"empty feedback", http.MethodPost, `{"run_id":"r1"}`, nil, http.StatusBadRequest, RatingNone},
+		{"invalid JSON", http.MethodPost, `{`, nil, http.StatusBadRequest, RatingNone},
+		{"wrong method", http.MethodGet, ``, nil, http.StatusMethodNotAllowed, RatingNone},
+		{"store failure", http.MethodPost, `{"run_id":"r1","rating":1}`, &brokenStore{}, http.StatusInternalServerError, RatingNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mem := NewInMemoryStore()
+			_ = mem.SaveRun(context.Background(), &Run{ID: "r1"})
+			store := tt.store
+			if store == nil {
+				store = mem
+			}
+
+			req := httptest.NewRequest(tt.method, "/feedback", strings.NewReader(tt.body))
+			req = req.WithContext(calque.WithUser(req.Context(), calque.User{ID: "u7"}))
+			rec := httptest.NewRecorder()
+			HTTPHandler(store).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusNoContent {
+				return
+			}
+			run, _ := mem.GetRun(context.Background(), "r1")
+			if len(run.Feedback) != 1 || run.Feedback[0].Rating != tt.wantRating || run.Feedback[0].UserID != "u7" {
+				t.Errorf("feedback = %+v", run.Feedback)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package feedback
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// InMemoryStore keeps runs in memory, mostly for examples or testing
+type InMemoryStore struct {
+	mu    sync.RWMutex
+	runs  map[string]*Run
+	order []string
+}
+
+// NewInMemoryStore creates a new in-memory store
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{runs: make(map[string]*Run)}
+}
+
+// SaveRun stores a copy of run, keeping feedback already attached to the same ID
+func (s *InMemoryStore) SaveRun(_ context.Context, run *Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := cloneRun(run)
+	if existing, ok := s.runs[run.ID]; ok {
+		stored.Feedback = append(existing.Feedback, stored.Feedback...)
+	} else {
+		s.order = append(s.order, run.ID)
+	}
+	s.runs[run.ID] = stored
+	return nil
+}
+
+// AddFeedback attaches feedback to a stored run
+func (s *InMemoryStore) AddFeedback(_ context.Context, fb Feedback) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run, ok := s.runs[fb.RunID]
+	if !ok {
+		return ErrRunNotFound
+	}
+	run.Feedback = append(run.Feedback, fb)
+	return nil
+}
+
+// GetRun returns a copy of a stored run
+func (s *InMemoryStore) GetRun(_ context.Context, id string) (*Run, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	run, ok := s.runs[id]
+	if !ok {
+		return nil, ErrRunNotFound
+	}
+	return cloneRun(run), nil
+}
+
+// ListRuns returns copies of all runs in the order they were first saved
+func (s *InMemoryStore) ListRuns(_ context.Context) ([]*Run, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	runs := make([]*Run, 0, len(s.order))
+	for _, id := range s.order {
+		runs = append(runs, cloneRun(s.runs[id]))
+	}
+	return runs, nil
+}
+
+func cloneRun(run *Run) *Run {
+	c := *run
+	c.Feedback = slices.Clone(run.Feedback)
+	c.ToolCalls = slices.Clone(run.ToolCalls)
+	if run.Metadata != nil {
+		c.Metadata = make(map[string]string, len(run.Metadata))
+		for k, v := range run.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+	return &c
+}
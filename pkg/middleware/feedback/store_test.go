@@ -0,0 +1,44 @@
+package feedback
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestInMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+
+	if _, err := store.GetRun(ctx, "missing"); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("GetRun() error = %v, want ErrRunNotFound", err)
+	}
+	if err := store.AddFeedback(ctx, Feedback{RunID: "missing"}); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("AddFeedback() error = %v, want ErrRunNotFound", err)
+	}
+
+	_ = store.SaveRun(ctx, &Run{ID: "b", Output: "one", Metadata: map[string]string{"k": "v"}})
+	_ = store.SaveRun(ctx, &Run{ID: "a"})
+	_ = store.AddFeedback(ctx, Feedback{RunID: "b", Rating: RatingUp})
+
+	// Re-saving a run keeps its feedback and its position
+	_ = store.SaveRun(ctx, &Run{ID: "b", Output: "two"})
+
+	runs, err := store.ListRuns(ctx)
+	if err != nil {
+		t.Fatalf("ListRuns() error = %v", err)
+	}
+	if len(runs) != 2 || runs[0].ID != "b" || runs[1].ID != "a" {
+		t.Fatalf("ListRuns() = %+v, want save order", runs)
+	}
+	if runs[0].Output != "two" || len(runs[0].Feedback) != 1 {
+		t.Errorf("re-saved run = %+v", runs[0])
+	}
+
+	// Returned runs are copies
+	runs[0].Feedback[0].Rating = RatingDown
+	got, _ := store.GetRun(ctx, "b")
+	if got.Feedback[0].Rating != RatingUp {
+		t.Error("mutating a returned run changed the store")
+	}
+}
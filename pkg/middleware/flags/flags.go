@@ -0,0 +1,211 @@
+// Package flags picks between handler variants with feature flags, so a model,
+// prompt or whole sub-flow can be switched per user or tenant at runtime
+// without a deploy.
+//
+// Select asks a Provider which variant a flag resolves to for the caller in
+// the request context (see calque.WithUser and calque.WithTenant) and runs the
+// matching handler. Env reads flags from environment variables and Static
+// from a map; hosted services such as LaunchDarkly or OpenFeature plug in
+// through ProviderFunc.
+//
+// Example:
+//
+//	agent := flags.Select("support-model", map[string]calque.Handler{
+//		"default": ai.Agent(small),
+//		"large":   ai.Agent(large),
+//	})
+//	// CALQUE_FLAG_SUPPORT_MODEL="tenant:acme=large" sends acme's traffic to the large model
+package flags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultVariant is the variant used when a flag is unset and SelectConfig.Default is empty
+const DefaultVariant = "default"
+
+// MetadataKeyPrefix prefixes the MetadataBus key that records each flag's selected variant,
+// e.g. "flags.support-model"
+const MetadataKeyPrefix = "flags."
+
+// ErrUnknownVariant is returned when neither the selected nor the default variant has a handler
+var ErrUnknownVariant = errors.New("unknown flag variant")
+
+// Target is who a flag is evaluated for
+type Target struct {
+	User   calque.User // From calque.WithUser, zero if unset
+	Tenant string      // From calque.WithTenant
+	Locale string      // From calque.WithLocale
+}
+
+// TargetFromContext collects the caller identity stored in ctx
+func TargetFromContext(ctx context.Context) Target {
+	user, _ := calque.UserFromContext(ctx)
+	return Target{User: user, Tenant: calque.Tenant(ctx), Locale: calque.Locale(ctx)}
+}
+
+// Provider evaluates feature flags.
+//
+// Variant returns the variant name a flag resolves to for target, or an
+// empty string if the flag isn't set so the default variant applies.
+type Provider interface {
+	Variant(ctx context.Context, flagKey string, target Target) (string, error)
+}
+
+// ProviderFunc adapts a function to the Provider interface.
+//
+// Example:
+//
+//	// LaunchDarkly
+//	provider := flags.ProviderFunc(func(_ context.Context, key string, t flags.Target) (string, error) {
+//		return ld.StringVariation(key, ldcontext.NewWithKind("user", t.User.ID), "")
+//	})
+//
+//	// OpenFeature
+//	provider := flags.ProviderFunc(func(ctx context.Context, key string, t flags.Target) (string, error) {
+//		return of.StringValue(ctx, key, "", openfeature.NewEvaluationContext(t.User.ID, map[string]any{"tenant": t.Tenant}))
+//	})
+type ProviderFunc func(ctx context.Context, flagKey string, target Target) (string, error)
+
+// Variant implements Provider
+func (f ProviderFunc) Variant(ctx context.Context, flagKey string, target Target) (string, error) {
+	return f(ctx, flagKey, target)
+}
+
+type providerKey struct{}
+
+// WithProvider stores the provider Select uses when its config doesn't name one.
+//
+// Example:
+//
+//	ctx = flags.WithProvider(ctx, flags.Static(map[string]string{"support-model": "large"}))
+func WithProvider(ctx context.Context, provider Provider) context.Context {
+	return context.WithValue(ctx, providerKey{}, provider)
+}
+
+// ProviderFromContext returns the provider stored with WithProvider
+func ProviderFromContext(ctx context.Context) (Provider, bool) {
+	p, ok := ctx.Value(providerKey{}).(Provider)
+	return p, ok
+}
+
+// SelectConfig configures flag evaluation for Select
+type SelectConfig struct {
+	Provider    Provider // Flag source (default: provider from context, then Env())
+	Default     string   // Variant for unset flags and failed evaluations (default: DefaultVariant)
+	FailOnError bool     // Return provider errors instead of falling back to Default
+}
+
+// Select runs the handler for the variant a feature flag resolves to.
+//
+// Input: any data type (passed through to the selected handler)
+// Output: selected handler's output
+// Behavior: STREAMING - evaluates the flag, then delegates without buffering
+//
+// The flag is evaluated per request for the caller in the request context.
+// Unset flags, provider errors and variants without a handler fall back to
+// the "default" variant. The chosen variant is recorded on the MetadataBus
+// under MetadataKeyPrefix+flagKey.
+//
+// Example:
+//
+//	prompts := flags.Select("onboarding-prompt", map[string]calque.Handler{
+//		"default": prompt.Template(v1),
+//		"v2":      prompt.Template(v2),
+//	})
+func Select(flagKey string, variants map[string]calque.Handler) calque.Handler {
+	return SelectWithConfig(flagKey, variants, nil)
+}
+
+// SelectWithConfig runs the handler for the variant a feature flag resolves to, with custom evaluation settings.
+//
+// Example:
+//
+//	model := flags.SelectWithConfig("model", map[string]calque.Handler{
+//		"mini": ai.Agent(mini),
+//		"full": ai.Agent(full),
+//	}, &flags.SelectConfig{Provider: provider, Default: "mini"})
+func SelectWithConfig(flagKey string, variants map[string]calque.Handler, config *SelectConfig) calque.Handler {
+	cfg := SelectConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Default == "" {
+		cfg.Default = DefaultVariant
+	}
+	s := &selector{flagKey: flagKey, variants: variants, config: cfg}
+	return calque.Described(calque.HandlerFunc(s.serve), s.describe, slices.Collect(maps.Values(variants))...)
+}
+
+type selector struct {
+	flagKey  string
+	variants map[string]calque.Handler
+	config   SelectConfig
+}
+
+func (s *selector) serve(req *calque.Request, res *calque.Response) error {
+	ctx := req.Context
+	variant, err := s.evaluate(ctx)
+	if err != nil {
+		return err
+	}
+
+	handler, ok := s.variants[variant]
+	if !ok && variant != s.config.Default {
+		calque.LogWarn(ctx, "flag variant has no handler, using default", "flag", s.flagKey, "variant", variant)
+		variant = s.config.Default
+		handler, ok = s.variants[variant]
+	}
+	if !ok {
+		return calque.WrapErr(ctx, ErrUnknownVariant, fmt.Sprintf("flag %q has no %q variant", s.flagKey, variant))
+	}
+
+	if bus := calque.GetMetadataBus(ctx); bus != nil {
+		bus.Set(MetadataKeyPrefix+s.flagKey, variant)
+	}
+	calque.LogDebug(ctx, "flag selected variant", "flag", s.flagKey, "variant", variant)
+	return handler.ServeFlow(req, res)
+}
+
+// evaluate resolves the flag, falling back to the default variant
+func (s *selector) evaluate(ctx context.Context) (string, error) {
+	provider := s.config.Provider
+	if provider == nil {
+		var ok bool
+		if provider, ok = ProviderFromContext(ctx); !ok {
+			provider = defaultEnv
+		}
+	}
+
+	variant, err := provider.Variant(ctx, s.flagKey, TargetFromContext(ctx))
+	switch {
+	case err != nil && s.config.FailOnError:
+		return "", calque.WrapErr(ctx, err, fmt.Sprintf("failed to evaluate flag %q", s.flagKey))
+	case err != nil:
+		calque.LogWarn(ctx, "flag evaluation failed, using default", "flag", s.flagKey, "error", err)
+		return s.config.Default, nil
+	case variant == "":
+		return s.config.Default, nil
+	}
+	return variant, nil
+}
+
+func (s *selector) describe() calque.Node {
+	names := make([]string, 0, len(s.variants))
+	for name := range s.variants {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	node := calque.Node{Label: "flag " + s.flagKey, Kind: calque.NodeBranch}
+	for _, name := range names {
+		node.Children = append(node.Children, calque.DescribeHandler(s.variants[name]).WithEdge(name))
+	}
+	return node
+}
@@ -0,0 +1,111 @@
+package flags
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func reply(s string) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var in string
+		if err := calque.Read(req, &in); err != nil {
+			return err
+		}
+		return calque.Write(res, s+":"+in)
+	})
+}
+
+var variants = map[string]calque.Handler{
+	"default": reply("small"),
+	"large":   reply("large"),
+}
+
+func run(t *testing.T, ctx context.Context, h calque.Handler) (string, error) {
+	t.Helper()
+	var out string
+	err := calque.NewFlow().Use(h).Run(ctx, "hi", &out)
+	return out, err
+}
+
+func TestSelect(t *testing.T) {
+	failing := ProviderFunc(func(context.Context, string, Target) (string, error) {
+		return "", errors.New("flag service down")
+	})
+	byTenant := ProviderFunc(func(_ context.Context, _ string, target Target) (string, error) {
+		if target.Tenant == "acme" {
+			return "large", nil
+		}
+		return "", nil
+	})
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		config  *SelectConfig
+		want    string
+		wantErr string
+	}{
+		{name: "unset flag uses default", ctx: WithProvider(context.Background(), Static(nil)), want: "small:hi"},
+		{name: "variant from context provider", ctx: WithProvider(context.Background(), Static(map[string]string{"model": "large"})), want: "large:hi"},
+		{name: "config provider wins over context", ctx: WithProvider(context.Background(), Static(map[string]string{"model": "large"})), config: &SelectConfig{Provider: Static(nil)}, want: "small:hi"},
+		{name: "targeted by tenant", ctx: calque.WithTenant(context.Background(), "acme"), config: &SelectConfig{Provider: byTenant}, want: "large:hi"},
+		{name: "other tenant", ctx: calque.WithTenant(context.Background(), "globex"), config: &SelectConfig{Provider: byTenant}, want: "small:hi"},
+		{name: "unknown variant falls back", ctx: context.Background(), config: &SelectConfig{Provider: Static(map[string]string{"model": "huge"})}, want: "small:hi"},
+		{name: "provider error falls back", ctx: context.Background(), config: &SelectConfig{Provider: failing}, want: "small:hi"},
+		{name: "provider error fails when asked", ctx: context.Background(), config: &SelectConfig{Provider: failing, FailOnError: true}, wantErr: "flag service down"},
+		{name: "custom default", ctx: context.Background(), config: &SelectConfig{Provider: Static(nil), Default: "large"}, want: "large:hi"},
+		{name: "missing default", ctx: context.Background(), config: &SelectConfig{Provider: Static(nil), Default: "nope"}, wantErr: `has no "nope" variant`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.ctx, SelectWithConfig("model", variants, tt.config))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectRecordsVariant(t *testing.T) {
+	bus := calque.NewMetadataBus(0)
+	ctx := calque.WithMetadataBus(context.Background(), bus)
+	ctx = WithProvider(ctx, Static(map[string]string{"model": "large"}))
+
+	if _, err := run(t, ctx, Select("model", variants)); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := bus.GetString(MetadataKeyPrefix + "model"); v != "large" {
+		t.Errorf("metadata = %q, want large", v)
+	}
+}
+
+func TestSelectDescribe(t *testing.T) {
+	node := calque.DescribeHandler(Select("model", variants))
+	if node.Label != "flag model" || node.Kind != calque.NodeBranch || len(node.Children) != 2 {
+		t.Fatalf("node = %+v", node)
+	}
+	if node.Children[0].Edge != "default" || node.Children[1].Edge != "large" {
+		t.Errorf("edges = %q, %q", node.Children[0].Edge, node.Children[1].Edge)
+	}
+}
+
+func TestTargetFromContext(t *testing.T) {
+	ctx := calque.WithUser(context.Background(), calque.User{ID: "42", Roles: []string{"beta"}})
+	ctx = calque.WithTenant(ctx, "acme")
+	ctx = calque.WithLocale(ctx, "en-GB")
+
+	target := TargetFromContext(ctx)
+	if target.User.ID != "42" || target.Tenant != "acme" || target.Locale != "en-GB" {
+		t.Errorf("target = %+v", target)
+	}
+}
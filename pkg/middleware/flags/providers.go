@@ -0,0 +1,131 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultEnvPrefix is prepended to flag keys to form environment variable names
+const DefaultEnvPrefix = "CALQUE_FLAG_"
+
+// defaultEnv is used by Select when no provider is configured
+var defaultEnv = Env()
+
+// EnvConfig configures the environment variable provider
+type EnvConfig struct {
+	Prefix string                           // Variable name prefix (default: DefaultEnvPrefix)
+	Lookup func(name string) (string, bool) // Variable source (default: os.LookupEnv)
+}
+
+type envProvider struct {
+	config EnvConfig
+}
+
+// Env creates a provider that reads flags from environment variables.
+//
+// Input: none
+// Output: Provider backed by the process environment
+// Behavior: Reads the variable on every evaluation
+//
+// The flag "support-model" is read from CALQUE_FLAG_SUPPORT_MODEL. The value
+// is a comma-separated list of rules: "tenant:<name>=<variant>",
+// "user:<id>=<variant>" and "role:<role>=<variant>" apply to matching callers,
+// checked in order, and a bare "<variant>" applies to everyone else.
+//
+// Example:
+//
+//	// CALQUE_FLAG_SUPPORT_MODEL="tenant:acme=large,user:42=large,small"
+//	provider := flags.Env()
+func Env() Provider {
+	return EnvWithConfig(nil)
+}
+
+// EnvWithConfig creates an environment variable provider with custom settings.
+//
+// Example:
+//
+//	provider := flags.EnvWithConfig(&flags.EnvConfig{Prefix: "MYAPP_FLAG_"})
+func EnvWithConfig(config *EnvConfig) Provider {
+	cfg := EnvConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultEnvPrefix
+	}
+	if cfg.Lookup == nil {
+		cfg.Lookup = os.LookupEnv
+	}
+	return &envProvider{config: cfg}
+}
+
+// Variant implements Provider
+func (e *envProvider) Variant(_ context.Context, flagKey string, target Target) (string, error) {
+	name := EnvVarName(e.config.Prefix, flagKey)
+	value, ok := e.config.Lookup(name)
+	if !ok {
+		return "", nil
+	}
+
+	fallback := ""
+	for _, rule := range strings.Split(value, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		match, variant, targeted := strings.Cut(rule, "=")
+		if !targeted {
+			fallback = rule
+			continue
+		}
+		kind, want, ok := strings.Cut(match, ":")
+		if !ok || variant == "" {
+			return "", fmt.Errorf("%s: invalid rule %q", name, rule)
+		}
+		want = strings.TrimSpace(want)
+		var matched bool
+		switch strings.TrimSpace(kind) {
+		case "tenant":
+			matched = target.Tenant == want
+		case "user":
+			matched = target.User.ID == want
+		case "role":
+			matched = target.User.HasRole(want)
+		default:
+			return "", fmt.Errorf("%s: unknown rule type %q", name, kind)
+		}
+		if matched {
+			return strings.TrimSpace(variant), nil
+		}
+	}
+	return fallback, nil
+}
+
+// EnvVarName returns the environment variable that holds flagKey, e.g.
+// "CALQUE_FLAG_SUPPORT_MODEL" for "support-model"
+func EnvVarName(prefix, flagKey string) string {
+	return prefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, flagKey)
+}
+
+// Static creates a provider with fixed variants for everyone, e.g. in tests
+// or to pin variants from application config.
+//
+// Example:
+//
+//	ctx = flags.WithProvider(ctx, flags.Static(map[string]string{"support-model": "large"}))
+func Static(variants map[string]string) Provider {
+	return ProviderFunc(func(_ context.Context, flagKey string, _ Target) (string, error) {
+		return variants[flagKey], nil
+	})
+}
@@ -0,0 +1,77 @@
+package flags
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestEnvVarName(t *testing.T) {
+	tests := map[string]string{
+		"support-model":  "CALQUE_FLAG_SUPPORT_MODEL",
+		"onboarding.v2":  "CALQUE_FLAG_ONBOARDING_V2",
+		"Already_UPPER1": "CALQUE_FLAG_ALREADY_UPPER1",
+	}
+	for key, want := range tests {
+		if got := EnvVarName(DefaultEnvPrefix, key); got != want {
+			t.Errorf("EnvVarName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestEnv(t *testing.T) {
+	env := map[string]string{
+		"TEST_FLAG_MODEL":  "tenant:acme=large, user:42=xl, role:beta=beta, small",
+		"TEST_FLAG_PINNED": "large",
+		"TEST_FLAG_BROKEN": "tenant=large",
+		"TEST_FLAG_ODD":    "team:core=large",
+	}
+	provider := EnvWithConfig(&EnvConfig{
+		Prefix: "TEST_FLAG_",
+		Lookup: func(name string) (string, bool) { v, ok := env[name]; return v, ok },
+	})
+
+	tests := []struct {
+		name    string
+		flag    string
+		target  Target
+		want    string
+		wantErr string
+	}{
+		{name: "tenant rule", flag: "model", target: Target{Tenant: "acme"}, want: "large"},
+		{name: "user rule", flag: "model", target: Target{User: calque.User{ID: "42"}}, want: "xl"},
+		{name: "role rule", flag: "model", target: Target{User: calque.User{ID: "7", Roles: []string{"beta"}}}, want: "beta"},
+		{name: "first matching rule wins", flag: "model", target: Target{Tenant: "acme", User: calque.User{ID: "42"}}, want: "large"},
+		{name: "bare variant for everyone else", flag: "model", target: Target{Tenant: "globex"}, want: "small"},
+		{name: "single variant", flag: "pinned", want: "large"},
+		{name: "unset", flag: "missing", want: ""},
+		{name: "malformed rule", flag: "broken", wantErr: "invalid rule"},
+		{name: "unknown rule type", flag: "odd", wantErr: `unknown rule type "team"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := provider.Variant(context.Background(), tt.flag, tt.target)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Variant() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectDefaultsToEnv(t *testing.T) {
+	t.Setenv("CALQUE_FLAG_FLAGS_TEST_MODEL", "tenant:acme=large")
+
+	ctx := calque.WithTenant(context.Background(), "acme")
+	got, err := run(t, ctx, Select("flags-test-model", variants))
+	if err != nil || got != "large:hi" {
+		t.Errorf("got %q, %v", got, err)
+	}
+}
@@ -0,0 +1,31 @@
+// Package guardrails provides safety middleware that checks and rewrites
+// content flowing to and from models.
+//
+// Output guardrails clean model responses before they reach end users, for
+// example by removing script tags or links that could leak data. Input
+// guardrails reject requests that fall outside an application's policy
+// before a model is ever called.
+//
+// Example:
+//
+//	flow := calque.NewFlow().
+//		Use(ai.Agent(client)).
+//		Use(guardrails.SanitizeOutput(nil))
+package guardrails
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrBlocked is returned when a guardrail rejects content instead of rewriting it
+var ErrBlocked = errors.New("guardrails: content blocked")
+
+// Finding describes one unsafe artifact a guardrail detected
+type Finding struct {
+	Kind  string `json:"kind"`  // Category, e.g. "url", "image", "html", "shell"
+	Match string `json:"match"` // The offending text as it appeared
+}
+
+// FindingHandler is notified of each finding, e.g. for logging or metrics
+type FindingHandler func(ctx context.Context, finding Finding)
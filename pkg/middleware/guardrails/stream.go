@@ -0,0 +1,225 @@
+package guardrails
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// RuleAction decides what StreamFilter does when a rule matches
+type RuleAction int
+
+const (
+	// RuleMask replaces the match and lets the stream continue
+	RuleMask RuleAction = iota
+	// RuleHalt ends the stream just before the match
+	RuleHalt
+)
+
+// Rule is one pattern a StreamFilter watches for
+type Rule struct {
+	Name        string         // Reported as Finding.Kind, e.g. "profanity"
+	Pattern     *regexp.Regexp // Text to match
+	Action      RuleAction     // RuleMask (default) or RuleHalt
+	Replacement string         // Masked text is replaced with this (default: one '*' per character)
+}
+
+// WordRule builds a case-insensitive rule matching any of words as whole words.
+//
+// Example:
+//
+//	rule := guardrails.WordRule("profanity", guardrails.RuleMask, "darn", "heck")
+func WordRule(name string, action RuleAction, words ...string) Rule {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = regexp.QuoteMeta(w)
+	}
+	return Rule{
+		Name:    name,
+		Pattern: regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`),
+		Action:  action,
+	}
+}
+
+// StreamFilterConfig configures StreamFilter
+type StreamFilterConfig struct {
+	// Window is how many trailing bytes are held back in case a match spans
+	// chunks; matches longer than this may slip through (default: 64)
+	Window int
+	// HaltMessage is written before the stream ends on a RuleHalt match (default: none)
+	HaltMessage string
+	// OnFinding is called for every match, masked or halting
+	OnFinding FindingHandler
+}
+
+// StreamFilter masks or halts model output as it streams.
+//
+// Input: streaming model output
+// Output: the same text with masked matches, possibly cut short
+// Behavior: STREAMING - holds back only the last Window bytes between chunks
+//
+// Each chunk is scanned together with the held-back tail of the previous one,
+// so words split across chunk boundaries are still caught. Text is released as
+// soon as no rule could still match it. A RuleMask match is replaced in place;
+// a RuleHalt match stops the stream immediately, writes HaltMessage and fails
+// with ErrBlocked, so the offending text never reaches the client.
+//
+// Example:
+//
+//	flow.Use(ai.Agent(client)).
+//		Use(guardrails.StreamFilter([]guardrails.Rule{
+//			guardrails.WordRule("profanity", guardrails.RuleMask, badWords...),
+//			{Name: "secret", Pattern: regexp.MustCompile(`sk-[A-Za-z0-9]{20,}`), Action: guardrails.RuleHalt},
+//		}))
+func StreamFilter(rules []Rule) calque.Handler {
+	return StreamFilterWithConfig(rules, nil)
+}
+
+// StreamFilterWithConfig creates a StreamFilter with custom settings
+//
+// Example:
+//
+//	filter := guardrails.StreamFilterWithConfig(rules, &guardrails.StreamFilterConfig{
+//		HaltMessage: "\n\n[response stopped]",
+//	})
+func StreamFilterWithConfig(rules []Rule, config *StreamFilterConfig) calque.Handler {
+	cfg := StreamFilterConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 64
+	}
+
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		f := &streamFilter{rules: rules, config: cfg, req: req, res: res}
+		buf := make([]byte, 4096)
+		for {
+			n, err := req.Data.Read(buf)
+			if n > 0 {
+				f.pending = append(f.pending, buf[:n]...)
+				if ferr := f.flush(false); ferr != nil {
+					return ferr
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				return f.flush(true)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	})
+}
+
+type streamMatch struct {
+	start, end int
+	rule       *Rule
+}
+
+type streamFilter struct {
+	rules   []Rule
+	config  StreamFilterConfig
+	req     *calque.Request
+	res     *calque.Response
+	context []byte // last rune already written, so \b and friends see the real neighbour
+	pending []byte
+}
+
+// flush writes out everything in pending that no future chunk can change
+func (f *streamFilter) flush(final bool) error {
+	text := append(slices.Clone(f.context), f.pending...)
+	offset := len(f.context)
+	matches := f.matches(text, offset)
+
+	safe := len(text)
+	if !final {
+		safe = max(offset, len(text)-f.config.Window)
+		// Never split a match that crosses the cut
+		for _, m := range matches {
+			if m.start < safe && m.end > safe {
+				safe = m.start
+				break
+			}
+		}
+		for safe > offset && !utf8.RuneStart(text[safe]) {
+			safe--
+		}
+	}
+
+	var out []byte
+	last := offset
+	for _, m := range matches {
+		if m.end > safe {
+			break
+		}
+		out = append(out, text[last:m.start]...)
+		f.report(m, text)
+		if m.rule.Action == RuleHalt {
+			out = append(out, f.config.HaltMessage...)
+			if _, err := f.res.Data.Write(out); err != nil {
+				return err
+			}
+			return calque.WrapErr(f.req.Context, ErrBlocked, "stream halted by rule "+m.rule.Name)
+		}
+		out = append(out, mask(m.rule, text[m.start:m.end])...)
+		last = m.end
+	}
+	out = append(out, text[last:safe]...)
+
+	if len(out) > 0 {
+		if _, err := f.res.Data.Write(out); err != nil {
+			return err
+		}
+	}
+	if safe > offset {
+		_, size := utf8.DecodeLastRune(text[:safe])
+		f.context = slices.Clone(text[safe-size : safe])
+	}
+	f.pending = slices.Clone(text[safe:])
+	return nil
+}
+
+// matches returns non-overlapping matches of all rules starting at or after offset, in text order
+func (f *streamFilter) matches(text []byte, offset int) []streamMatch {
+	var all []streamMatch
+	for i := range f.rules {
+		rule := &f.rules[i]
+		for _, loc := range rule.Pattern.FindAllIndex(text, -1) {
+			if loc[0] >= offset && loc[1] > loc[0] {
+				all = append(all, streamMatch{start: loc[0], end: loc[1], rule: rule})
+			}
+		}
+	}
+	slices.SortStableFunc(all, func(a, b streamMatch) int { return a.start - b.start })
+
+	matches := all[:0]
+	end := 0
+	for _, m := range all {
+		if m.start >= end {
+			matches = append(matches, m)
+			end = m.end
+		}
+	}
+	return matches
+}
+
+func (f *streamFilter) report(m streamMatch, text []byte) {
+	finding := Finding{Kind: m.rule.Name, Match: string(text[m.start:m.end])}
+	calque.LogDebug(f.req.Context, "guardrails: stream filter match", "rule", finding.Kind)
+	if f.config.OnFinding != nil {
+		f.config.OnFinding(f.req.Context, finding)
+	}
+}
+
+func mask(rule *Rule, match []byte) string {
+	if rule.Replacement != "" {
+		return rule.Replacement
+	}
+	return strings.Repeat("*", utf8.RuneCount(match))
+}
@@ -0,0 +1,154 @@
+package guardrails
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// runStreamFilter feeds input one byte per Read so every match spans chunks
+func runStreamFilter(t *testing.T, rules []Rule, config *StreamFilterConfig, input string) (string, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	req := calque.NewRequest(context.Background(), iotest.OneByteReader(strings.NewReader(input)))
+	err := StreamFilterWithConfig(rules, config).ServeFlow(req, calque.NewResponse(&buf))
+	return buf.String(), err
+}
+
+func TestStreamFilter(t *testing.T) {
+	profanity := WordRule("profanity", RuleMask, "darn", "heck")
+	secret := Rule{Name: "secret", Pattern: regexp.MustCompile(`sk-[a-z0-9]{8,}`), Action: RuleHalt}
+
+	tests := []struct {
+		name     string
+		rules    []Rule
+		config   *StreamFilterConfig
+		input    string
+		want     string
+		wantKind []string
+		halted   bool
+	}{
+		{
+			name:  "clean text passes through",
+			rules: []Rule{profanity},
+			input: "Nothing to see here.",
+			want:  "Nothing to see here.",
+		},
+		{
+			name:     "masks across chunks",
+			rules:    []Rule{profanity},
+			input:    "Oh darn, what the HECK happened?",
+			want:     "Oh ****, what the **** happened?",
+			wantKind: []string{"profanity", "profanity"},
+		},
+		{
+			name:  "respects word boundaries",
+			rules: []Rule{profanity},
+			input: "heckle darnit",
+			want:  "heckle darnit",
+		},
+		{
+			name:   "word boundary after released text",
+			rules:  []Rule{profanity},
+			config: &StreamFilterConfig{Window: 4},
+			input:  "xxxxxxxxxxdarn",
+			want:   "xxxxxxxxxxdarn",
+		},
+		{
+			name:     "custom replacement",
+			rules:    []Rule{{Name: "name", Pattern: regexp.MustCompile(`Bob`), Replacement: "[name]"}},
+			input:    "Hi Bob!",
+			want:     "Hi [name]!",
+			wantKind: []string{"name"},
+		},
+		{
+			name:     "multibyte mask",
+			rules:    []Rule{{Name: "x", Pattern: regexp.MustCompile(`héllo`)}},
+			input:    "say héllo",
+			want:     "say *****",
+			wantKind: []string{"x"},
+		},
+		{
+			name:     "halts before the match",
+			rules:    []Rule{profanity, secret},
+			config:   &StreamFilterConfig{HaltMessage: "[stopped]"},
+			input:    "darn, your key is sk-abc123def456 and more text",
+			want:     "****, your key is [stopped]",
+			wantKind: []string{"profanity", "secret"},
+			halted:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kinds []string
+			cfg := &StreamFilterConfig{}
+			if tt.config != nil {
+				cfg = tt.config
+			}
+			cfg.OnFinding = func(_ context.Context, f Finding) { kinds = append(kinds, f.Kind) }
+
+			got, err := runStreamFilter(t, tt.rules, cfg, tt.input)
+			if tt.halted {
+				if !errors.Is(err, ErrBlocked) {
+					t.Fatalf("error = %v, want ErrBlocked", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+			if strings.Join(kinds, ",") != strings.Join(tt.wantKind, ",") {
+				t.Errorf("findings = %v, want %v", kinds, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestStreamFilter_ReleasesBeforeInputEnds(t *testing.T) {
+	pr, pw := io.Pipe()
+	out := make(chan []byte, 16)
+	done := make(chan error, 1)
+
+	go func() {
+		req := calque.NewRequest(context.Background(), pr)
+		done <- StreamFilterWithConfig([]Rule{WordRule("p", RuleMask, "darn")}, &StreamFilterConfig{Window: 8}).
+			ServeFlow(req, calque.NewResponse(chanWriter(out)))
+	}()
+
+	if _, err := pw.Write([]byte("the first part of a long answer")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case chunk := <-out:
+		if string(chunk) != "the first part of a lon" {
+			t.Errorf("first chunk = %q", chunk)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no output released before input finished")
+	}
+
+	pw.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if rest := <-out; string(rest) != "g answer" {
+		t.Errorf("tail = %q", rest)
+	}
+}
+
+type chanWriter chan []byte
+
+func (c chanWriter) Write(p []byte) (int, error) {
+	c <- bytes.Clone(p)
+	return len(p), nil
+}
@@ -0,0 +1,385 @@
+package guardrails
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/retrieval"
+)
+
+// TopicMetadataKey is the metadata bus key holding the best matching topic
+const TopicMetadataKey = "guardrails.topic"
+
+// TopicScore is how strongly text matches a topic, from 0 to 1
+type TopicScore struct {
+	Topic string  `json:"topic" jsonschema:"required,description=One of the candidate topics exactly as given"`
+	Score float64 `json:"score" jsonschema:"required,minimum=0,maximum=1,description=How strongly the request is about this topic"`
+}
+
+// TopicClassifier scores text against candidate topics without training data
+type TopicClassifier interface {
+	Classify(ctx context.Context, text string, topics []string) ([]TopicScore, error)
+}
+
+// TopicClassifierFunc adapts a function to TopicClassifier
+type TopicClassifierFunc func(ctx context.Context, text string, topics []string) ([]TopicScore, error)
+
+// Classify calls f
+func (f TopicClassifierFunc) Classify(ctx context.Context, text string, topics []string) ([]TopicScore, error) {
+	return f(ctx, text, topics)
+}
+
+// TopicDecision is the outcome of a TopicPolicy check
+type TopicDecision struct {
+	Allowed bool         `json:"allowed"`
+	Topic   string       `json:"topic,omitempty"`  // Best matching topic, empty when none passed the threshold
+	Reason  string       `json:"reason,omitempty"` // "denied" or "off_topic" when not allowed
+	Scores  []TopicScore `json:"scores"`
+}
+
+// RefusalError is returned when a policy refuses a request
+type RefusalError struct {
+	Decision TopicDecision
+	Message  string // The rendered refusal to show the user
+}
+
+func (e *RefusalError) Error() string {
+	return fmt.Sprintf("guardrails: request refused (%s: %s)", e.Decision.Reason, e.Decision.Topic)
+}
+
+// Unwrap makes errors.Is(err, ErrBlocked) true for refusals
+func (e *RefusalError) Unwrap() error { return ErrBlocked }
+
+// Refusal returns the refusal message carried by err, if a policy refused the request
+func Refusal(err error) (string, bool) {
+	var refusal *RefusalError
+	if errors.As(err, &refusal) {
+		return refusal.Message, true
+	}
+	return "", false
+}
+
+// TopicPolicyConfig configures TopicPolicyWithConfig
+type TopicPolicyConfig struct {
+	// Threshold is the score a topic needs to count as a match (default: 0.5)
+	Threshold float64
+	// MaxInputBytes is how much of the input is classified (default: 4096)
+	MaxInputBytes int
+	// DeniedRefusal is the refusal for denied topics; {{.Topic}} and {{.Allowed}} are available
+	// (default: "I can't help with {{.Topic}}.")
+	DeniedRefusal string
+	// OffTopicRefusal is the refusal for requests matching no allowed topic
+	// (default: "I can only help with {{.Allowed}}.")
+	OffTopicRefusal string
+	// Refusals overrides the refusal template for specific denied topics
+	Refusals map[string]string
+	// OnDecision is called with every decision, allowed or not
+	OnDecision func(ctx context.Context, decision TopicDecision)
+}
+
+// TopicPolicy refuses requests about denied topics or outside allowed ones.
+//
+// Input: user request text
+// Output: the same request, unchanged, when allowed
+// Behavior: BUFFERED - classifies the start of the input before passing it on
+//
+// The input is scored against every allowed and denied topic. A denied topic
+// scoring at or above the threshold refuses the request, even when an allowed
+// topic also matches. With allowed topics set, a request matching none of
+// them is refused as off-topic; with no allowed topics, everything not denied
+// passes. Refusals stop the flow with a *RefusalError whose message comes
+// from the policy's templates; use Refusal to show it to the user.
+//
+// Example:
+//
+//	policy := guardrails.TopicPolicy(guardrails.EmbeddingClassifier(embedder),
+//		[]string{"billing", "shipping", "returns"},
+//		[]string{"medical advice", "legal advice"},
+//	)
+//	err := calque.NewFlow().Use(policy).Use(agent).Run(ctx, question, &answer)
+//	if msg, ok := guardrails.Refusal(err); ok {
+//		answer = msg
+//	}
+func TopicPolicy(classifier TopicClassifier, allowed, denied []string) calque.Handler {
+	return TopicPolicyWithConfig(classifier, allowed, denied, nil)
+}
+
+// TopicPolicyWithConfig creates a TopicPolicy with custom thresholds and refusal templates.
+//
+// Example:
+//
+//	policy := guardrails.TopicPolicyWithConfig(classifier, allowed, denied, &guardrails.TopicPolicyConfig{
+//		Threshold: 0.6,
+//		Refusals: map[string]string{
+//			"medical advice": "Please contact a doctor for medical questions.",
+//		},
+//	})
+func TopicPolicyWithConfig(classifier TopicClassifier, allowed, denied []string, config *TopicPolicyConfig) calque.Handler {
+	cfg := TopicPolicyConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 0.5
+	}
+	if cfg.MaxInputBytes <= 0 {
+		cfg.MaxInputBytes = 4096
+	}
+	if cfg.DeniedRefusal == "" {
+		cfg.DeniedRefusal = "I can't help with {{.Topic}}."
+	}
+	if cfg.OffTopicRefusal == "" {
+		cfg.OffTopicRefusal = "I can only help with {{.Allowed}}."
+	}
+	topics := slices.Concat(allowed, denied)
+
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var input []byte
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+
+		sample := input[:min(len(input), cfg.MaxInputBytes)]
+		scores, err := classifier.Classify(req.Context, string(sample), topics)
+		if err != nil {
+			return calque.WrapErr(req.Context, err, "topic classification failed")
+		}
+
+		decision := decideTopic(scores, allowed, denied, cfg.Threshold)
+		if bus := calque.GetMetadataBus(req.Context); bus != nil && decision.Topic != "" {
+			bus.Set(TopicMetadataKey, decision.Topic)
+		}
+		if cfg.OnDecision != nil {
+			cfg.OnDecision(req.Context, decision)
+		}
+		if decision.Allowed {
+			return calque.Write(res, input)
+		}
+
+		tmpl := cfg.OffTopicRefusal
+		if decision.Reason == "denied" {
+			tmpl = cfg.DeniedRefusal
+			if custom, ok := cfg.Refusals[decision.Topic]; ok {
+				tmpl = custom
+			}
+		}
+		message, err := renderRefusal(tmpl, decision.Topic, allowed)
+		if err != nil {
+			return calque.WrapErr(req.Context, err, "failed to render refusal")
+		}
+		calque.LogDebug(req.Context, "guardrails: request refused", "reason", decision.Reason, "topic", decision.Topic)
+		return &RefusalError{Decision: decision, Message: message}
+	})
+	return calque.WithEgress(h, calque.EgressOf(classifier)...)
+}
+
+func decideTopic(scores []TopicScore, allowed, denied []string, threshold float64) TopicDecision {
+	decision := TopicDecision{Scores: scores}
+
+	best := func(candidates []string) (string, float64) {
+		topic, top := "", -1.0
+		for _, s := range scores {
+			if slices.Contains(candidates, s.Topic) && s.Score > top {
+				topic, top = s.Topic, s.Score
+			}
+		}
+		return topic, top
+	}
+
+	if topic, score := best(denied); score >= threshold {
+		decision.Topic, decision.Reason = topic, "denied"
+		return decision
+	}
+	topic, score := best(allowed)
+	if score >= threshold {
+		decision.Topic = topic
+	}
+	if len(allowed) > 0 && score < threshold {
+		decision.Reason = "off_topic"
+		return decision
+	}
+	decision.Allowed = true
+	return decision
+}
+
+func renderRefusal(text, topic string, allowed []string) (string, error) {
+	tmpl, err := template.New("refusal").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]any{"Topic": topic, "Allowed": joinTopics(allowed)})
+	return buf.String(), err
+}
+
+// joinTopics lists topics for prose: "a", "a and b", "a, b and c"
+func joinTopics(topics []string) string {
+	switch len(topics) {
+	case 0:
+		return ""
+	case 1:
+		return topics[0]
+	}
+	return strings.Join(topics[:len(topics)-1], ", ") + " and " + topics[len(topics)-1]
+}
+
+// KeywordClassifier scores a topic 1 when any of its keywords appears in the text.
+//
+// It needs no model, so it suits short denylists of unambiguous terms.
+// Matching is case-insensitive on whole words; keywords containing spaces
+// match as phrases. Topics without keywords score 0.
+//
+// Example:
+//
+//	classifier := guardrails.KeywordClassifier(map[string][]string{
+//		"weapons": {"gun", "rifle", "explosive"},
+//	})
+func KeywordClassifier(keywords map[string][]string) TopicClassifier {
+	return TopicClassifierFunc(func(_ context.Context, text string, topics []string) ([]TopicScore, error) {
+		words := map[string]bool{}
+		for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !('a' <= r && r <= 'z' || '0' <= r && r <= '9' || r > 127)
+		}) {
+			words[w] = true
+		}
+
+		scores := make([]TopicScore, len(topics))
+		for i, topic := range topics {
+			scores[i].Topic = topic
+			for _, kw := range keywords[topic] {
+				if words[strings.ToLower(kw)] || (strings.Contains(kw, " ") && strings.Contains(strings.ToLower(text), strings.ToLower(kw))) {
+					scores[i].Score = 1
+					break
+				}
+			}
+		}
+		return scores, nil
+	})
+}
+
+// EmbeddingClassifier scores topics by the cosine similarity between the text
+// and each topic's embedding.
+//
+// Topic embeddings are computed once and cached. Cosine similarity of
+// sentence embeddings rarely reaches 1, so tune TopicPolicyConfig.Threshold
+// for the embedding model, typically between 0.3 and 0.5. Descriptive topics
+// such as "questions about billing and invoices" separate better than single
+// words.
+//
+// Example:
+//
+//	classifier := guardrails.EmbeddingClassifier(vectorStore)
+func EmbeddingClassifier(embedder retrieval.EmbeddingCapable) TopicClassifier {
+	var mu sync.Mutex
+	cache := map[string]retrieval.EmbeddingVector{}
+
+	return TopicClassifierFunc(func(ctx context.Context, text string, topics []string) ([]TopicScore, error) {
+		query, err := embedder.GetEmbedding(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+
+		scores := make([]TopicScore, len(topics))
+		for i, topic := range topics {
+			mu.Lock()
+			vec, ok := cache[topic]
+			mu.Unlock()
+			if !ok {
+				if vec, err = embedder.GetEmbedding(ctx, topic); err != nil {
+					return nil, err
+				}
+				mu.Lock()
+				cache[topic] = vec
+				mu.Unlock()
+			}
+			scores[i] = TopicScore{Topic: topic, Score: max(cosine(query, vec), 0)}
+		}
+		return scores, nil
+	})
+}
+
+func cosine(a, b retrieval.EmbeddingVector) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// topicVerdict is the structured output LLMClassifier requests
+type topicVerdict struct {
+	Scores []TopicScore `json:"scores" jsonschema:"required,description=A score for every candidate topic"`
+}
+
+// LLMClassifier scores topics by asking a model for a zero-shot classification.
+//
+// Use a small, fast model; the request is classified before the main agent
+// runs, so its latency adds to every request. Topics the model leaves out
+// score 0.
+//
+// Example:
+//
+//	classifier := guardrails.LLMClassifier(ollamaClient)
+func LLMClassifier(client ai.Client) TopicClassifier {
+	classifier := ai.Agent(client, ai.WithSchema(&topicVerdict{}))
+
+	return agentClassifier{agent: classifier, TopicClassifierFunc: func(ctx context.Context, text string, topics []string) ([]TopicScore, error) {
+		prompt := fmt.Sprintf(`Classify the user request against each candidate topic.
+Score every topic from 0 (unrelated) to 1 (clearly about that topic). Topics are not exclusive.
+
+Candidate topics:
+- %s
+
+User request:
+%s`, strings.Join(topics, "\n- "), text)
+
+		var output []byte
+		if err := calque.NewFlow().Use(classifier).Run(ctx, prompt, &output); err != nil {
+			return nil, err
+		}
+		var verdict topicVerdict
+		if err := json.Unmarshal(output, &verdict); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to parse topic classification")
+		}
+
+		byTopic := map[string]float64{}
+		for _, s := range verdict.Scores {
+			byTopic[strings.ToLower(strings.TrimSpace(s.Topic))] = min(max(s.Score, 0), 1)
+		}
+		scores := make([]TopicScore, len(topics))
+		for i, topic := range topics {
+			scores[i] = TopicScore{Topic: topic, Score: byTopic[strings.ToLower(topic)]}
+		}
+		return scores, nil
+	}}
+}
+
+// agentClassifier is a TopicClassifier backed by an agent, reporting where
+// its client sends requests so local-only flows reject the policy
+type agentClassifier struct {
+	TopicClassifierFunc
+	agent calque.Handler
+}
+
+// Egress implements calque.Egress
+func (c agentClassifier) Egress() []string {
+	return calque.EgressOf(c.agent)
+}
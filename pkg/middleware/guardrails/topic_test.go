@@ -0,0 +1,195 @@
+package guardrails
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/retrieval"
+)
+
+// fixedClassifier returns preset scores
+func fixedClassifier(scores map[string]float64) TopicClassifier {
+	return TopicClassifierFunc(func(_ context.Context, _ string, topics []string) ([]TopicScore, error) {
+		out := make([]TopicScore, len(topics))
+		for i, t := range topics {
+			out[i] = TopicScore{Topic: t, Score: scores[t]}
+		}
+		return out, nil
+	})
+}
+
+func TestTopicPolicy(t *testing.T) {
+	allowed := []string{"billing", "shipping", "returns"}
+	denied := []string{"medical advice"}
+
+	tests := []struct {
+		name        string
+		allowed     []string
+		scores      map[string]float64
+		wantAllowed bool
+		wantRefusal string
+	}{
+		{"allowed topic passes", allowed, map[string]float64{"billing": 0.9}, true, ""},
+		{"denied wins over allowed", allowed, map[string]float64{"billing": 0.9, "medical advice": 0.6}, false, "I can't help with medical advice."},
+		{"off topic", allowed, map[string]float64{"billing": 0.2}, false, "I can only help with billing, shipping and returns."},
+		{"deny-only policy passes unmatched", nil, map[string]float64{}, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decision TopicDecision
+			policy := TopicPolicyWithConfig(fixedClassifier(tt.scores), tt.allowed, denied, &TopicPolicyConfig{
+				OnDecision: func(_ context.Context, d TopicDecision) { decision = d },
+			})
+
+			var out string
+			err := calque.NewFlow().Use(policy).Run(context.Background(), "my request", &out)
+			if decision.Allowed != tt.wantAllowed {
+				t.Errorf("decision = %+v", decision)
+			}
+			if tt.wantAllowed {
+				if err != nil || out != "my request" {
+					t.Errorf("Run() = %q, %v; want input passed through", out, err)
+				}
+				return
+			}
+			msg, ok := Refusal(err)
+			if !ok || msg != tt.wantRefusal || !errors.Is(err, ErrBlocked) {
+				t.Errorf("Refusal() = %q, %v (err %v); want %q", msg, ok, err, tt.wantRefusal)
+			}
+		})
+	}
+}
+
+func TestTopicPolicyCustomRefusalAndLimits(t *testing.T) {
+	var classified string
+	classifier := TopicClassifierFunc(func(_ context.Context, text string, topics []string) ([]TopicScore, error) {
+		classified = text
+		return []TopicScore{{Topic: "legal advice", Score: 0.7}}, nil
+	})
+	policy := TopicPolicyWithConfig(classifier, nil, []string{"legal advice"}, &TopicPolicyConfig{
+		Threshold:     0.6,
+		MaxInputBytes: 5,
+		Refusals:      map[string]string{"legal advice": "Please consult a lawyer about {{.Topic}}."},
+	})
+
+	err := calque.NewFlow().Use(policy).Run(context.Background(), "can I sue my landlord?", new(string))
+	if msg, _ := Refusal(err); msg != "Please consult a lawyer about legal advice." {
+		t.Errorf("refusal = %q (%v)", msg, err)
+	}
+	if classified != "can I" {
+		t.Errorf("classified %q, want first 5 bytes", classified)
+	}
+
+	failing := TopicClassifierFunc(func(context.Context, string, []string) ([]TopicScore, error) {
+		return nil, errors.New("classifier down")
+	})
+	err = calque.NewFlow().Use(TopicPolicy(failing, nil, nil)).Run(context.Background(), "x", new(string))
+	if err == nil || !strings.Contains(err.Error(), "topic classification failed") {
+		t.Errorf("classifier error = %v", err)
+	}
+	if _, ok := Refusal(err); ok {
+		t.Error("classifier failure must not look like a refusal")
+	}
+}
+
+func TestKeywordClassifier(t *testing.T) {
+	classifier := KeywordClassifier(map[string][]string{
+		"weapons": {"rifle", "Explosive"},
+		"crypto":  {"bitcoin wallet"},
+	})
+	scores, _ := classifier.Classify(context.Background(), "How do I build an EXPLOSIVE? Also my Bitcoin wallet.", []string{"weapons", "crypto", "cooking"})
+	want := []float64{1, 1, 0}
+	for i, s := range scores {
+		if s.Score != want[i] {
+			t.Errorf("%s = %v, want %v", s.Topic, s.Score, want[i])
+		}
+	}
+
+	scores, _ = classifier.Classify(context.Background(), "rifles of wind", []string{"weapons"})
+	if scores[0].Score != 0 {
+		t.Error("keywords should match whole words only")
+	}
+}
+
+// fakeEmbedder maps known texts to vectors and counts calls
+type fakeEmbedder struct {
+	vectors map[string]retrieval.EmbeddingVector
+	calls   int
+}
+
+func (f *fakeEmbedder) GetEmbedding(_ context.Context, text string) (retrieval.EmbeddingVector, error) {
+	f.calls++
+	if v, ok := f.vectors[text]; ok {
+		return v, nil
+	}
+	return nil, errors.New("unknown text")
+}
+
+func TestEmbeddingClassifier(t *testing.T) {
+	embedder := &fakeEmbedder{vectors: map[string]retrieval.EmbeddingVector{
+		"billing":     {1, 0},
+		"weather":     {0, 1},
+		"refund":      {0.8, 0.6},
+		"storm today": {-1, 0.1},
+	}}
+	classifier := EmbeddingClassifier(embedder)
+
+	scores, err := classifier.Classify(context.Background(), "refund", []string{"billing", "weather"})
+	if err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	if scores[0].Score < 0.79 || scores[0].Score > 0.81 || scores[1].Score < 0.59 || scores[1].Score > 0.61 {
+		t.Errorf("scores = %+v", scores)
+	}
+
+	scores, _ = classifier.Classify(context.Background(), "storm today", []string{"billing", "weather"})
+	if scores[0].Score != 0 {
+		t.Errorf("negative similarity should clamp to 0, got %v", scores[0].Score)
+	}
+	if embedder.calls != 4 {
+		t.Errorf("embedder called %d times, topic embeddings should be cached", embedder.calls)
+	}
+
+	if _, err := classifier.Classify(context.Background(), "unknown", []string{"billing"}); err == nil {
+		t.Error("expected embedder error")
+	}
+}
+
+func TestLLMClassifier(t *testing.T) {
+	client := ai.NewMockClient(`{"scores":[{"topic":"Billing","score":0.9},{"topic":"weather","score":1.7}]}`).WithStreamDelay(0)
+	scores, err := LLMClassifier(client).Classify(context.Background(), "where is my invoice", []string{"billing", "weather", "sports"})
+	if err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	want := []float64{0.9, 1, 0}
+	for i, s := range scores {
+		if s.Score != want[i] {
+			t.Errorf("%s = %v, want %v", s.Topic, s.Score, want[i])
+		}
+	}
+
+	bad := ai.NewMockClient("not json").WithStreamDelay(0)
+	if _, err := LLMClassifier(bad).Classify(context.Background(), "x", []string{"a"}); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+// remoteClient is a client on another host
+type remoteClient struct{ ai.Client }
+
+func (remoteClient) Egress() []string { return []string{"api.example.com"} }
+
+func TestLLMClassifierEgress(t *testing.T) {
+	if got := calque.DescribeHandler(TopicPolicy(LLMClassifier(remoteClient{ai.NewMockClient("{}")}), []string{"billing"}, nil)).Egress; !slices.Equal(got, []string{"api.example.com"}) {
+		t.Errorf("egress = %v, want the client's host", got)
+	}
+	if got := calque.DescribeHandler(TopicPolicy(LLMClassifier(ai.NewMockClient("{}")), []string{"billing"}, nil)).Egress; got != nil {
+		t.Errorf("egress with a local client = %v, want none", got)
+	}
+}
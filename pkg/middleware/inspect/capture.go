@@ -0,0 +1,162 @@
+package inspect
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"time"
+	"unicode/utf8"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultCaptureMaxPayload is the default limit on bytes stored per captured payload
+const DefaultCaptureMaxPayload = 1 << 20
+
+// CaptureRecord is one payload captured at a pipeline stage.
+//
+// Records are written as JSON lines. The payload is stored under "payload"
+// when it is valid UTF-8 and under "payload_base64" otherwise.
+type CaptureRecord struct {
+	Stage     string    `json:"stage"`
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id,omitempty"`
+	TraceID   string    `json:"trace_id,omitempty"`
+	Bytes     int64     `json:"bytes"`               // Full payload size, including any truncated part
+	Truncated bool      `json:"truncated,omitempty"` // Payload was cut at CaptureConfig.MaxPayloadBytes
+	Payload   []byte    `json:"-"`
+}
+
+// MarshalJSON encodes the record with its payload as text when possible
+func (r CaptureRecord) MarshalJSON() ([]byte, error) {
+	type plain CaptureRecord
+	encoded := struct {
+		plain
+		Payload       *string `json:"payload,omitempty"`
+		PayloadBase64 []byte  `json:"payload_base64,omitempty"`
+	}{plain: plain(r)}
+
+	if utf8.Valid(r.Payload) {
+		text := string(r.Payload)
+		encoded.Payload = &text
+	} else {
+		encoded.PayloadBase64 = r.Payload
+	}
+	return json.Marshal(encoded)
+}
+
+// CaptureSink stores captured records.
+//
+// Implementations must be safe for concurrent use. FileSink and ObjectSink
+// are provided.
+type CaptureSink interface {
+	WriteRecord(ctx context.Context, record *CaptureRecord) error
+	Close() error
+}
+
+// CaptureConfig configures Capture
+type CaptureConfig struct {
+	// SampleRate is the fraction of requests captured, from 0 to 1 (default: 1)
+	SampleRate *float64
+	// MaxPayloadBytes limits the bytes stored per payload; the rest still
+	// streams through (default: DefaultCaptureMaxPayload, negative for no limit)
+	MaxPayloadBytes int
+}
+
+// Capture writes full payloads passing through a stage to a sink.
+//
+// Input: any data type (streaming)
+// Output: same as input (pass-through)
+// Behavior: STREAMING - data flows through unchanged; a copy is written to
+// the sink once the stream ends
+//
+// Intended for offline debugging of production traffic: pair it with a
+// rotating FileSink or ObjectSink and sample with CaptureWithConfig. Sink
+// failures are logged and never fail the request. Sinks implementing
+// calque.Egress, such as ObjectSink, are reported to local-only flows.
+//
+// Example:
+//
+//	sink, _ := inspect.NewFileSink(&inspect.FileSinkConfig{Dir: "/var/log/calque/capture"})
+//	defer sink.Close()
+//
+//	flow.Use(inspect.Capture("PROMPT", sink)).Use(ai.Agent(client)).Use(inspect.Capture("RESPONSE", sink))
+func Capture(stage string, sink CaptureSink) calque.Handler {
+	return CaptureWithConfig(stage, sink, nil)
+}
+
+// CaptureWithConfig creates a Capture handler with sampling and payload limits.
+//
+// Input: any data type (streaming)
+// Output: same as input (pass-through)
+// Behavior: STREAMING - unsampled requests pass straight through
+//
+// Example:
+//
+//	rate := 0.01
+//	flow.Use(inspect.CaptureWithConfig("RESPONSE", sink, &inspect.CaptureConfig{
+//		SampleRate:      &rate,     // 1% of requests
+//		MaxPayloadBytes: 256 << 10, // first 256KB of each payload
+//	}))
+func CaptureWithConfig(stage string, sink CaptureSink, config *CaptureConfig) calque.Handler {
+	if config == nil {
+		config = &CaptureConfig{}
+	}
+	sampleRate := 1.0
+	if config.SampleRate != nil {
+		sampleRate = *config.SampleRate
+	}
+	maxPayload := config.MaxPayloadBytes
+	if maxPayload == 0 {
+		maxPayload = DefaultCaptureMaxPayload
+	}
+
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		if sampleRate < 1 && rand.Float64() >= sampleRate {
+			_, err := io.Copy(res.Data, req.Data)
+			return err
+		}
+
+		payload := &limitedCapture{limit: maxPayload}
+		total, err := io.Copy(res.Data, io.TeeReader(req.Data, payload))
+		if err != nil {
+			return err
+		}
+
+		record := &CaptureRecord{
+			Stage:     stage,
+			Time:      time.Now().UTC(),
+			RequestID: calque.RequestID(req.Context),
+			TraceID:   calque.TraceID(req.Context),
+			Bytes:     total,
+			Truncated: payload.truncated,
+			Payload:   payload.data,
+		}
+		if err := sink.WriteRecord(req.Context, record); err != nil {
+			calque.LogWarn(req.Context, "failed to write captured payload", "stage", stage, "error", err)
+		}
+		return nil
+	})
+	return calque.WithEgress(h, calque.EgressOf(sink)...)
+}
+
+// limitedCapture keeps up to limit bytes (all when limit is negative) and
+// notes whether more were written
+type limitedCapture struct {
+	data      []byte
+	limit     int
+	truncated bool
+}
+
+func (c *limitedCapture) Write(p []byte) (int, error) {
+	keep := p
+	if c.limit >= 0 {
+		if room := c.limit - len(c.data); len(p) > room {
+			keep = p[:max(room, 0)]
+			c.truncated = true
+		}
+	}
+	c.data = append(c.data, keep...)
+	return len(p), nil
+}
@@ -0,0 +1,115 @@
+package inspect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// recordingSink keeps records in memory
+type recordingSink struct {
+	mu      sync.Mutex
+	records []*CaptureRecord
+	err     error
+}
+
+func (s *recordingSink) WriteRecord(_ context.Context, record *CaptureRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return s.err
+}
+
+func (s *recordingSink) Close() error { return nil }
+
+func TestCapture(t *testing.T) {
+	sink := &recordingSink{}
+	ctx := calque.WithTraceID(calque.WithRequestID(context.Background(), "req-1"), "trace-1")
+
+	var output string
+	flow := calque.NewFlow().Use(Capture("PROMPT", sink))
+	if err := flow.Run(ctx, "hello world", &output); err != nil {
+		t.Fatal(err)
+	}
+	if output != "hello world" {
+		t.Errorf("output = %q, want pass-through", output)
+	}
+
+	if len(sink.records) != 1 {
+		t.Fatalf("records = %d, want 1", len(sink.records))
+	}
+	record := sink.records[0]
+	if record.Stage != "PROMPT" || record.RequestID != "req-1" || record.TraceID != "trace-1" ||
+		string(record.Payload) != "hello world" || record.Bytes != 11 || record.Truncated || record.Time.IsZero() {
+		t.Errorf("record = %+v", record)
+	}
+}
+
+func TestCaptureWithConfig(t *testing.T) {
+	never, always := 0.0, 1.0
+	tests := []struct {
+		name      string
+		config    *CaptureConfig
+		records   int
+		payload   string
+		truncated bool
+	}{
+		{name: "truncated payload", config: &CaptureConfig{MaxPayloadBytes: 5}, records: 1, payload: "hello", truncated: true},
+		{name: "unlimited payload", config: &CaptureConfig{MaxPayloadBytes: -1}, records: 1, payload: "hello world"},
+		{name: "never sampled", config: &CaptureConfig{SampleRate: &never}, records: 0},
+		{name: "always sampled", config: &CaptureConfig{SampleRate: &always}, records: 1, payload: "hello world"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &recordingSink{}
+			var output string
+			if err := calque.NewFlow().Use(CaptureWithConfig("S", sink, tt.config)).Run(context.Background(), "hello world", &output); err != nil {
+				t.Fatal(err)
+			}
+			if output != "hello world" {
+				t.Errorf("output = %q, want the full input", output)
+			}
+			if len(sink.records) != tt.records {
+				t.Fatalf("records = %d, want %d", len(sink.records), tt.records)
+			}
+			if tt.records == 0 {
+				return
+			}
+			record := sink.records[0]
+			if string(record.Payload) != tt.payload || record.Truncated != tt.truncated || record.Bytes != 11 {
+				t.Errorf("record = %+v", record)
+			}
+		})
+	}
+}
+
+func TestCaptureSinkErrorDoesNotFailRequest(t *testing.T) {
+	sink := &recordingSink{err: errors.New("disk full")}
+	var output string
+	if err := calque.NewFlow().Use(Capture("S", sink)).Run(context.Background(), "data", &output); err != nil || output != "data" {
+		t.Errorf("Run() = %q, %v; want the request to succeed", output, err)
+	}
+}
+
+func TestCaptureRecordJSON(t *testing.T) {
+	text, _ := json.Marshal(CaptureRecord{Stage: "S", Bytes: 2, Payload: []byte("hi")})
+	if !strings.Contains(string(text), `"payload":"hi"`) || strings.Contains(string(text), "payload_base64") {
+		t.Errorf("text record = %s", text)
+	}
+
+	binary, _ := json.Marshal(CaptureRecord{Stage: "S", Bytes: 2, Payload: []byte{0xff, 0xfe}})
+	if !strings.Contains(string(binary), `"payload_base64":"//4="`) || strings.Contains(string(binary), `"payload":`) {
+		t.Errorf("binary record = %s", binary)
+	}
+
+	empty, _ := json.Marshal(CaptureRecord{Stage: "S"})
+	if !strings.Contains(string(empty), `"payload":""`) {
+		t.Errorf("empty record = %s", empty)
+	}
+}
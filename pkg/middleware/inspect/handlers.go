@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"time"
@@ -125,7 +126,7 @@ func (hb *HandlerBuilder) Chunks(prefix string, chunkSize int, attrs ...Attribut
 //	timedHandler := log.Info().Timing("AI_PROCESSING", ai.Agent(client))
 //	pipe.Use(timedHandler) // Logs: [AI_PROCESSING] completed duration_ms=150 bytes=1024
 func (hb *HandlerBuilder) Timing(prefix string, handler calque.Handler, attrs ...Attribute) calque.Handler {
-	return hb.createHandler(func(req *calque.Request, res *calque.Response, logFunc func(string, ...Attribute)) error {
+	h := hb.createHandler(func(req *calque.Request, res *calque.Response, logFunc func(string, ...Attribute)) error {
 		start := time.Now()
 
 		// Use TeeReader to capture bytes as they flow through the handler
@@ -161,6 +162,9 @@ func (hb *HandlerBuilder) Timing(prefix string, handler calque.Handler, attrs ..
 
 		return err
 	})
+	return calque.Described(h, func() calque.Node {
+		return calque.Node{Label: "timing " + prefix, Kind: calque.NodeSequence, Children: []calque.Node{calque.DescribeHandler(handler)}}
+	}, handler)
 }
 
 // Sampling takes distributed samples throughout the stream and logs them in a single entry.
@@ -355,6 +359,188 @@ func (hb *HandlerBuilder) HeadTail(prefix string, headBytes, tailBytes int, attr
 	})
 }
 
+// Stats logs payload size and shape without logging the content itself.
+//
+// Input: any data type (streaming - uses io.TeeReader for efficient monitoring)
+// Output: same as input (pass-through)
+// Behavior: STREAMING - classifies data while it flows, constant memory usage
+//
+// Logs the byte count, line count, detected content type (json, yaml, text,
+// binary or empty) and whether the payload is valid UTF-8 as structured fields.
+// Content type is sniffed from the first bytes of the stream, so it is cheap
+// enough to leave enabled in production where dumping payloads is not an option.
+//
+// Example:
+//
+//	handler := log.Info().Stats("LLM_RESPONSE")
+//	pipe.Use(handler) // Logs: [LLM_RESPONSE] bytes=1532 lines=42 content_type=json valid_utf8=true
+func (hb *HandlerBuilder) Stats(prefix string, attrs ...Attribute) calque.Handler {
+	return hb.createHandler(func(req *calque.Request, res *calque.Response, logFunc func(string, ...Attribute)) error {
+		capture := &statsCapture{valid: true}
+
+		_, err := io.Copy(res.Data, io.TeeReader(req.Data, capture))
+		if err != nil {
+			return err
+		}
+
+		allAttrs := make([]Attribute, len(attrs), len(attrs)+4)
+		copy(allAttrs, attrs)
+		allAttrs = append(allAttrs,
+			Attribute{"bytes", capture.totalBytes},
+			Attribute{"lines", capture.lines()},
+			Attribute{"content_type", capture.contentType()},
+			Attribute{"valid_utf8", capture.validUTF8()},
+		)
+		logFunc(fmt.Sprintf("[%s]", prefix), allAttrs...)
+
+		return nil
+	})
+}
+
+// sniffSize is how much of the stream Stats keeps for content type detection
+const sniffSize = 512
+
+// statsCapture counts bytes and lines and tracks UTF-8 validity across writes
+type statsCapture struct {
+	head       []byte
+	pending    []byte // incomplete rune carried over from the previous write
+	totalBytes int
+	newlines   int
+	last       byte
+	valid      bool
+}
+
+func (s *statsCapture) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if len(s.head) < sniffSize {
+		s.head = append(s.head, p[:min(sniffSize-len(s.head), len(p))]...)
+	}
+	s.totalBytes += len(p)
+	s.newlines += bytes.Count(p, []byte{'\n'})
+	s.last = p[len(p)-1]
+
+	if s.valid {
+		data := p
+		if len(s.pending) > 0 {
+			data = append(s.pending, p...)
+			s.pending = nil
+		}
+		for len(data) > 0 {
+			r, size := utf8.DecodeRune(data)
+			if r == utf8.RuneError && size <= 1 {
+				if !utf8.FullRune(data) {
+					s.pending = append([]byte(nil), data...)
+					break
+				}
+				s.valid = false
+				break
+			}
+			data = data[size:]
+		}
+	}
+	return len(p), nil
+}
+
+func (s *statsCapture) lines() int {
+	if s.totalBytes > 0 && s.last != '\n' {
+		return s.newlines + 1
+	}
+	return s.newlines
+}
+
+func (s *statsCapture) validUTF8() bool {
+	return s.valid && len(s.pending) == 0
+}
+
+// contentType classifies the payload from its leading bytes
+func (s *statsCapture) contentType() string {
+	head := bytes.TrimPrefix(s.head, []byte("\xef\xbb\xbf"))
+	trimmed := bytes.TrimLeft(head, " \t\r\n")
+	switch {
+	case s.totalBytes == 0:
+		return "empty"
+	case len(trimmed) == 0:
+		return "text"
+	case bytes.IndexByte(head, 0) >= 0 || !looksLikeText(head):
+		return "binary"
+	case trimmed[0] == '{' || trimmed[0] == '[':
+		// A complete small payload can be validated; larger ones are judged by their opening
+		if s.totalBytes <= sniffSize && !json.Valid(trimmed) {
+			return "text"
+		}
+		return "json"
+	case looksLikeYAML(trimmed):
+		return "yaml"
+	default:
+		return "text"
+	}
+}
+
+// looksLikeText reports whether the sample decodes as UTF-8 with few control characters.
+// A rune cut off at the end of the sample is not treated as invalid.
+func looksLikeText(data []byte) bool {
+	var runes, control int
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size <= 1 {
+			if !utf8.FullRune(data) {
+				break
+			}
+			return false
+		}
+		runes++
+		if !unicode.IsPrint(r) && !isWhitespace(r) {
+			control++
+		}
+		data = data[size:]
+	}
+	return control*10 <= runes
+}
+
+// looksLikeYAML checks for a document marker, or for at least two lines where
+// every non-blank, non-comment line is a "key: value" mapping or a "- item" entry
+func looksLikeYAML(data []byte) bool {
+	if bytes.HasPrefix(data, []byte("---")) {
+		return true
+	}
+	lines := bytes.Split(data, []byte{'\n'})
+	if len(data) >= sniffSize && len(lines) > 1 {
+		lines = lines[:len(lines)-1] // the last line may be cut off
+	}
+
+	var entries int
+	for _, line := range lines {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		if !isYAMLEntry(line) {
+			return false
+		}
+		entries++
+	}
+	return entries >= 2
+}
+
+func isYAMLEntry(line []byte) bool {
+	if bytes.HasPrefix(line, []byte("- ")) || bytes.Equal(line, []byte("-")) {
+		return true
+	}
+	key, rest, found := bytes.Cut(line, []byte(":"))
+	if !found || len(key) == 0 || (len(rest) > 0 && rest[0] != ' ') {
+		return false // "http://..." or "10:30" rather than a mapping
+	}
+	for _, c := range key {
+		if !(c == '_' || c == '-' || c == '.' || c == '"' || c == '\'' ||
+			(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
 // formatDuration returns the appropriate field name and value based on duration with improved precision
 func formatDuration(d time.Duration) (string, float64) {
 	microseconds := float64(d.Microseconds())
@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/calquetest"
 )
 
 const testMessage = "Hello, world!"
@@ -268,6 +270,78 @@ func TestHandlerPrint(t *testing.T) {
 	}
 }
 
+// TestHandlerStats tests the Stats handler classification and pass-through
+func TestHandlerStats(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		chunks []int
+		want   []string
+	}{
+		{"empty", "", nil, []string{"bytes=0", "lines=0", "content_type=empty", "valid_utf8=true"}},
+		{"plain text", "Hello, world!\nSecond line", nil, []string{"bytes=25", "lines=2", "content_type=text"}},
+		{"json object", `{"name": "calque", "tags": ["a", "b"]}`, nil, []string{"lines=1", "content_type=json"}},
+		{"json array with newline", "[1, 2, 3]\n", nil, []string{"lines=1", "content_type=json"}},
+		{"invalid json", "{not json at all", nil, []string{"content_type=text"}},
+		{"yaml", "name: calque\ntags:\n  - a\n  - b\n", nil, []string{"lines=4", "content_type=yaml"}},
+		{"yaml document", "---\nkey: value\n", nil, []string{"content_type=yaml"}},
+		{"prose with colon", "Answer: 42", nil, []string{"content_type=text"}},
+		{"binary", "\x00\x01\x02\xff\xfe", nil, []string{"bytes=5", "content_type=binary", "valid_utf8=false"}},
+		{"rune split across chunks", "héllo 世界", []int{2, 1, 6, 1}, []string{"content_type=text", "valid_utf8=true"}},
+		{"truncated rune", "abc\xe4\xb8", nil, []string{"valid_utf8=false"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := New(&MockLogger{buffer: &buf})
+
+			var input io.Reader = strings.NewReader(tt.input)
+			if tt.chunks != nil {
+				input = calquetest.ChunkedReader([]byte(tt.input), tt.chunks...)
+			}
+			var output bytes.Buffer
+			req := &calque.Request{Context: context.Background(), Data: input}
+			if err := log.Info().Stats("STATS").ServeFlow(req, &calque.Response{Data: &output}); err != nil {
+				t.Fatalf("Stats handler failed: %v", err)
+			}
+
+			if output.String() != tt.input {
+				t.Errorf("Output mismatch: got %q, want %q", output.String(), tt.input)
+			}
+			logOutput := buf.String()
+			if !strings.Contains(logOutput, "[STATS]") {
+				t.Errorf("Expected [STATS] in log output, got: %s", logOutput)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(logOutput, want) {
+					t.Errorf("Expected %q in log output, got: %s", want, logOutput)
+				}
+			}
+			if tt.input != "" && strings.Contains(logOutput, tt.input) {
+				t.Errorf("Stats must not log payload content, got: %s", logOutput)
+			}
+		})
+	}
+}
+
+// TestHandlerStatsLargeJSON tests that content type is sniffed from the head of large payloads
+func TestHandlerStatsLargeJSON(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&MockLogger{buffer: &buf})
+
+	payload := "[" + strings.Repeat(`{"id": 1},`, 200) + `{"id": 2}]`
+	req := &calque.Request{Context: context.Background(), Data: strings.NewReader(payload)}
+	if err := log.Info().Stats("BIG").ServeFlow(req, &calque.Response{Data: io.Discard}); err != nil {
+		t.Fatalf("Stats handler failed: %v", err)
+	}
+
+	logOutput := buf.String()
+	if !strings.Contains(logOutput, "content_type=json") || !strings.Contains(logOutput, fmt.Sprintf("bytes=%d", len(payload))) {
+		t.Errorf("unexpected log output: %s", logOutput)
+	}
+}
+
 // TestHandlerWithAttributes tests handlers with custom attributes
 func TestHandlerWithAttributes(t *testing.T) {
 	var buf bytes.Buffer
@@ -0,0 +1,73 @@
+package inspect
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+)
+
+// logrDebugVerbosity is the V-level used for DebugLevel; logr has no named levels
+const logrDebugVerbosity = 1
+
+// LogrAdapter adapts logr.Logger (used by controller-runtime and klog) to our LoggerInterface
+//
+// logr only distinguishes info and error messages: DebugLevel logs at V(1),
+// InfoLevel and WarnLevel log at V(0), and ErrorLevel logs through Error.
+// When the context carries a logger (logr.NewContext, as controller-runtime
+// does for each reconcile), that logger is used so its keys are kept.
+type LogrAdapter struct {
+	logger logr.Logger
+}
+
+// NewLogrAdapter creates a new adapter for logr
+func NewLogrAdapter(logger logr.Logger) *LogrAdapter {
+	return &LogrAdapter{logger: logger}
+}
+
+// Log implements LoggerInterface for structured logging with logr
+func (l *LogrAdapter) Log(ctx context.Context, level LogLevel, msg string, attrs ...Attribute) {
+	logger := l.loggerFor(ctx)
+
+	keysAndValues := make([]any, 0, len(attrs)*2)
+	for _, attr := range attrs {
+		keysAndValues = append(keysAndValues, attr.Key, attr.Value)
+	}
+
+	switch level {
+	case DebugLevel:
+		logger.V(logrDebugVerbosity).Info(msg, keysAndValues...)
+	case ErrorLevel:
+		logger.Error(nil, msg, keysAndValues...)
+	default:
+		logger.Info(msg, keysAndValues...)
+	}
+}
+
+// IsLevelEnabled checks if the given level is enabled in logr
+func (l *LogrAdapter) IsLevelEnabled(ctx context.Context, level LogLevel) bool {
+	logger := l.loggerFor(ctx)
+	switch level {
+	case DebugLevel:
+		return logger.V(logrDebugVerbosity).Enabled()
+	case ErrorLevel:
+		return logger.GetSink() != nil // logr always emits errors
+	default:
+		return logger.Enabled()
+	}
+}
+
+// Printf implements backward compatibility by logging the formatted message at V(0)
+func (l *LogrAdapter) Printf(format string, v ...any) {
+	l.logger.Info(fmt.Sprintf(format, v...))
+}
+
+// loggerFor prefers a logger carried by the context over the configured one
+func (l *LogrAdapter) loggerFor(ctx context.Context) logr.Logger {
+	if ctx != nil {
+		if logger, err := logr.FromContext(ctx); err == nil {
+			return logger
+		}
+	}
+	return l.logger
+}
@@ -0,0 +1,83 @@
+package inspect
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+)
+
+// newTestLogr returns a logr.Logger recording "prefix: args" lines up to verbosity
+func newTestLogr(verbosity int) (logr.Logger, *[]string) {
+	var lines []string
+	logger := funcr.New(func(prefix, args string) {
+		lines = append(lines, strings.TrimSpace(prefix+" "+args))
+	}, funcr.Options{Verbosity: verbosity})
+	return logger, &lines
+}
+
+func TestLogrAdapter_Log(t *testing.T) {
+	tests := []struct {
+		name  string
+		level LogLevel
+		want  string
+	}{
+		{name: "debug logs at V(1)", level: DebugLevel, want: `"level"=1 "msg"="[STAGE]" "bytes"=42`},
+		{name: "info", level: InfoLevel, want: `"level"=0 "msg"="[STAGE]" "bytes"=42`},
+		{name: "warn logs as info", level: WarnLevel, want: `"level"=0 "msg"="[STAGE]" "bytes"=42`},
+		{name: "error", level: ErrorLevel, want: `"msg"="[STAGE]" "error"=null "bytes"=42`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, lines := newTestLogr(1)
+			NewLogrAdapter(logger).Log(context.Background(), tt.level, "[STAGE]", Attr("bytes", 42))
+
+			if len(*lines) != 1 || !strings.Contains((*lines)[0], tt.want) {
+				t.Errorf("lines = %q, want one containing %s", *lines, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogrAdapter_IsLevelEnabled(t *testing.T) {
+	logger, lines := newTestLogr(0)
+	adapter := NewLogrAdapter(logger)
+	ctx := context.Background()
+
+	if adapter.IsLevelEnabled(ctx, DebugLevel) {
+		t.Error("debug should be disabled at verbosity 0")
+	}
+	if !adapter.IsLevelEnabled(ctx, InfoLevel) || !adapter.IsLevelEnabled(ctx, ErrorLevel) {
+		t.Error("info and error should be enabled")
+	}
+	if NewLogrAdapter(logr.Discard()).IsLevelEnabled(ctx, ErrorLevel) {
+		t.Error("a discarding logger should report errors disabled")
+	}
+
+	adapter.Log(ctx, DebugLevel, "dropped")
+	if len(*lines) != 0 {
+		t.Errorf("debug message logged at verbosity 0: %q", *lines)
+	}
+}
+
+func TestLogrAdapter_ContextLogger(t *testing.T) {
+	base, baseLines := newTestLogr(0)
+	scoped, scopedLines := newTestLogr(0)
+	ctx := logr.NewContext(context.Background(), scoped.WithValues("reconcile", "default/app"))
+
+	adapter := NewLogrAdapter(base)
+	adapter.Log(ctx, InfoLevel, "[STAGE]")
+
+	if len(*baseLines) != 0 || len(*scopedLines) != 1 || !strings.Contains((*scopedLines)[0], `"reconcile"="default/app"`) {
+		t.Errorf("base = %q, scoped = %q; want the context logger used", *baseLines, *scopedLines)
+	}
+
+	adapter.Printf("processed %d items", 3)
+	if len(*baseLines) != 1 || !strings.Contains((*baseLines)[0], fmt.Sprintf("%q", "processed 3 items")) {
+		t.Errorf("Printf() lines = %q", *baseLines)
+	}
+}
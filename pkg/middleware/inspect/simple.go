@@ -67,3 +67,13 @@ func Sampling(prefix string, numSamples int, sampleSize int) calque.Handler {
 func Print(prefix string) calque.Handler {
 	return defaultLogger.Print().Print(prefix)
 }
+
+// Stats logs payload size, line count, content type and UTF-8 validity using standard log.
+//
+// Convenience function for standard log debugging. Streams through data
+// recording only its shape, a cheap always-on alternative to dumping content.
+//
+// Quick debugging equivalent to: logger.Default().Print().Stats(prefix)
+func Stats(prefix string) calque.Handler {
+	return defaultLogger.Print().Stats(prefix)
+}
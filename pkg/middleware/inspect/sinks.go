@@ -0,0 +1,321 @@
+package inspect
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Rotation defaults
+const (
+	DefaultRotationMaxBytes = 100 << 20 // 100MB of uncompressed records per segment
+	DefaultRotationMaxAge   = time.Hour
+)
+
+// RotationConfig controls when a sink starts a new segment
+type RotationConfig struct {
+	// MaxBytes rotates once a segment holds this many uncompressed bytes (default: DefaultRotationMaxBytes)
+	MaxBytes int64
+	// MaxAge rotates segments older than this when the next record arrives (default: DefaultRotationMaxAge)
+	MaxAge time.Duration
+	// Gzip compresses segments, adding a ".gz" suffix (default: false)
+	Gzip bool
+}
+
+// FileSinkConfig configures a FileSink
+type FileSinkConfig struct {
+	RotationConfig
+	// Dir holds the segment files (required); it is created if missing
+	Dir string
+	// Prefix starts every segment file name (default: "capture")
+	Prefix string
+	// MaxFiles keeps only the newest segments, deleting older ones on rotation (default: 0, keep all)
+	MaxFiles int
+}
+
+// FileSink writes captured records to rotating JSON lines files.
+//
+// Segments are named "<prefix>-<UTC start time>-<seq>.jsonl", plus ".gz"
+// when compressed, so they sort chronologically.
+//
+// Example:
+//
+//	sink, err := inspect.NewFileSink(&inspect.FileSinkConfig{
+//		Dir:            "/var/log/calque/capture",
+//		RotationConfig: inspect.RotationConfig{MaxBytes: 50 << 20, MaxAge: 15 * time.Minute, Gzip: true},
+//		MaxFiles:       96,
+//	})
+//	defer sink.Close()
+type FileSink struct {
+	*rotator
+	dir      string
+	prefix   string
+	maxFiles int
+}
+
+// NewFileSink creates a rotating file sink.
+func NewFileSink(config *FileSinkConfig) (*FileSink, error) {
+	ctx := context.Background()
+	if config == nil || config.Dir == "" {
+		return nil, calque.NewErr(ctx, "capture directory is required")
+	}
+	if err := os.MkdirAll(config.Dir, 0o750); err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to create capture directory")
+	}
+
+	sink := &FileSink{
+		dir:      config.Dir,
+		prefix:   cmp.Or(config.Prefix, "capture"),
+		maxFiles: config.MaxFiles,
+	}
+	sink.rotator = newRotator(config.RotationConfig, sink.prefix, sink.openFile)
+	return sink, nil
+}
+
+// openFile creates the next segment file, pruning old ones when it closes
+func (s *FileSink) openFile(name string) (io.WriteCloser, error) {
+	file, err := os.OpenFile(filepath.Join(s.dir, name), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	return &closeHook{Writer: file, close: func() error {
+		if err := file.Close(); err != nil {
+			return err
+		}
+		return s.prune()
+	}}, nil
+}
+
+// prune removes the oldest segments beyond maxFiles
+func (s *FileSink) prune() error {
+	if s.maxFiles <= 0 {
+		return nil
+	}
+	segments, err := filepath.Glob(filepath.Join(s.dir, s.prefix+"-*.jsonl*"))
+	if err != nil {
+		return err
+	}
+	slices.Sort(segments)
+	for len(segments) > s.maxFiles {
+		if err := os.Remove(segments[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		segments = segments[1:]
+	}
+	return nil
+}
+
+// ObjectPutter uploads a finished segment to an object store.
+//
+// Adapt an S3, GCS or Azure client with ObjectPutterFunc.
+type ObjectPutter interface {
+	PutObject(ctx context.Context, key string, data []byte) error
+}
+
+// ObjectPutterFunc adapts a function to ObjectPutter
+type ObjectPutterFunc func(ctx context.Context, key string, data []byte) error
+
+// PutObject calls f(ctx, key, data)
+func (f ObjectPutterFunc) PutObject(ctx context.Context, key string, data []byte) error {
+	return f(ctx, key, data)
+}
+
+// ObjectSinkConfig configures an ObjectSink
+type ObjectSinkConfig struct {
+	RotationConfig
+	// Prefix starts every object key, e.g. "capture/prod/" (default: "capture/")
+	Prefix string
+	// Timeout bounds each upload (default: 30s)
+	Timeout time.Duration
+	// Endpoint is the object store URL reported to local-only flows; a local
+	// store such as MinIO on localhost keeps the flow local (default: the sink
+	// is reported as sending to "object store" unless the putter implements
+	// calque.Egress)
+	Endpoint string
+}
+
+// ObjectSink buffers captured records and uploads each segment as one object.
+//
+// Segments are held in memory until they rotate or the sink is closed, so
+// keep MaxBytes well below the memory you can spare. A failed upload is
+// returned from the write that triggered the rotation and the segment is dropped.
+//
+// Example:
+//
+//	s3Client := s3.NewFromConfig(awsCfg)
+//	sink := inspect.NewObjectSink(inspect.ObjectPutterFunc(func(ctx context.Context, key string, data []byte) error {
+//		_, err := s3Client.PutObject(ctx, &s3.PutObjectInput{Bucket: aws.String("debug"), Key: &key, Body: bytes.NewReader(data)})
+//		return err
+//	}), &inspect.ObjectSinkConfig{Prefix: "capture/prod/", RotationConfig: inspect.RotationConfig{Gzip: true}})
+//	defer sink.Close()
+type ObjectSink struct {
+	*rotator
+	putter   ObjectPutter
+	prefix   string
+	timeout  time.Duration
+	endpoint string
+}
+
+// NewObjectSink creates an object store sink.
+func NewObjectSink(putter ObjectPutter, config *ObjectSinkConfig) *ObjectSink {
+	if config == nil {
+		config = &ObjectSinkConfig{}
+	}
+	sink := &ObjectSink{
+		putter:   putter,
+		prefix:   cmp.Or(config.Prefix, "capture/"),
+		timeout:  cmp.Or(config.Timeout, 30*time.Second),
+		endpoint: config.Endpoint,
+	}
+	sink.rotator = newRotator(config.RotationConfig, "", sink.openObject)
+	return sink
+}
+
+// Egress implements calque.Egress, so Capture handlers writing to the sink
+// are rejected by local-only flows
+func (s *ObjectSink) Egress() []string {
+	if e, ok := s.putter.(calque.Egress); ok {
+		return e.Egress()
+	}
+	if s.endpoint != "" {
+		return calque.EgressHosts(s.endpoint)
+	}
+	return []string{"object store"}
+}
+
+// openObject buffers a segment that is uploaded when it closes
+func (s *ObjectSink) openObject(name string) (io.WriteCloser, error) {
+	var buf bytes.Buffer
+	key := s.prefix + name
+	return &closeHook{Writer: &buf, close: func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		defer cancel()
+		if err := s.putter.PutObject(ctx, key, buf.Bytes()); err != nil {
+			return fmt.Errorf("failed to upload capture segment %s: %w", key, err)
+		}
+		return nil
+	}}, nil
+}
+
+// rotator writes JSON lines records into segments, starting a new segment
+// when the current one is too large or too old
+type rotator struct {
+	mu      sync.Mutex
+	config  RotationConfig
+	prefix  string
+	open    func(name string) (io.WriteCloser, error)
+	now     func() time.Time
+	out     io.WriteCloser
+	gz      *gzip.Writer
+	size    int64
+	started time.Time
+	seq     int
+}
+
+func newRotator(config RotationConfig, prefix string, open func(string) (io.WriteCloser, error)) *rotator {
+	config.MaxBytes = cmp.Or(config.MaxBytes, DefaultRotationMaxBytes)
+	config.MaxAge = cmp.Or(config.MaxAge, DefaultRotationMaxAge)
+	return &rotator{config: config, prefix: prefix, open: open, now: time.Now}
+}
+
+// WriteRecord appends a record to the current segment, rotating first when needed
+func (r *rotator) WriteRecord(ctx context.Context, record *CaptureRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to encode capture record")
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if r.out != nil && (r.size+int64(len(line)) > r.config.MaxBytes || now.Sub(r.started) >= r.config.MaxAge) {
+		if err := r.closeSegment(); err != nil {
+			return calque.WrapErr(ctx, err, "failed to rotate capture segment")
+		}
+	}
+	if r.out == nil {
+		if err := r.openSegment(now); err != nil {
+			return calque.WrapErr(ctx, err, "failed to open capture segment")
+		}
+	}
+
+	var w io.Writer = r.out
+	if r.gz != nil {
+		w = r.gz
+	}
+	if _, err := w.Write(line); err != nil {
+		return calque.WrapErr(ctx, err, "failed to write capture record")
+	}
+	r.size += int64(len(line))
+	return nil
+}
+
+// Rotate closes the current segment; the next record starts a new one
+func (r *rotator) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closeSegment()
+}
+
+// Close finishes the current segment
+func (r *rotator) Close() error {
+	return r.Rotate()
+}
+
+func (r *rotator) openSegment(now time.Time) error {
+	r.seq++
+	name := fmt.Sprintf("%s-%04d.jsonl", now.UTC().Format("20060102T150405.000Z"), r.seq)
+	if r.prefix != "" {
+		name = r.prefix + "-" + name
+	}
+	if r.config.Gzip {
+		name += ".gz"
+	}
+
+	out, err := r.open(name)
+	if err != nil {
+		return err
+	}
+	r.out, r.size, r.started = out, 0, now
+	if r.config.Gzip {
+		r.gz = gzip.NewWriter(out)
+	}
+	return nil
+}
+
+func (r *rotator) closeSegment() error {
+	if r.out == nil {
+		return nil
+	}
+	var gzErr error
+	if r.gz != nil {
+		gzErr = r.gz.Close()
+	}
+	err := r.out.Close()
+	r.out, r.gz = nil, nil
+	return errors.Join(gzErr, err)
+}
+
+// closeHook is a writer with a custom Close
+type closeHook struct {
+	io.Writer
+	close func() error
+}
+
+func (c *closeHook) Close() error {
+	return c.close()
+}
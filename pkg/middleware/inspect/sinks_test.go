@@ -0,0 +1,225 @@
+package inspect
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// readSegment decodes the records in a segment, decompressing .gz segments
+func readSegment(t *testing.T, name string, data []byte) []map[string]any {
+	t.Helper()
+	var r io.Reader = bytes.NewReader(data)
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		r = gz
+	}
+	var records []map[string]any
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var record map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func readDir(t *testing.T, dir string) map[string][]map[string]any {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	segments := map[string][]map[string]any{}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		segments[entry.Name()] = readSegment(t, entry.Name(), data)
+	}
+	return segments
+}
+
+func record(stage, payload string) *CaptureRecord {
+	return &CaptureRecord{Stage: stage, Time: time.Now(), Bytes: int64(len(payload)), Payload: []byte(payload)}
+}
+
+func TestFileSinkRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewFileSink(&FileSinkConfig{Dir: dir, RotationConfig: RotationConfig{MaxBytes: 150, Gzip: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, payload := range []string{"one", "two", "three", "four", "five"} {
+		if err := sink.WriteRecord(ctx, record("S", payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	segments := readDir(t, dir)
+	if len(segments) < 2 {
+		t.Fatalf("segments = %d, want rotation at 150 bytes", len(segments))
+	}
+	total := 0
+	for name, records := range segments {
+		if !strings.HasPrefix(name, "capture-") || !strings.HasSuffix(name, ".jsonl.gz") {
+			t.Errorf("segment name = %s", name)
+		}
+		total += len(records)
+	}
+	if total != 5 {
+		t.Errorf("records = %d, want 5", total)
+	}
+}
+
+func TestFileSinkRotatesByAgeAndPrunes(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewFileSink(&FileSinkConfig{Dir: dir, Prefix: "debug", RotationConfig: RotationConfig{MaxAge: time.Minute}, MaxFiles: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sink.now = func() time.Time { return clock }
+
+	ctx := context.Background()
+	for i := range 4 {
+		if err := sink.WriteRecord(ctx, record("S", "payload")); err != nil {
+			t.Fatal(err)
+		}
+		clock = clock.Add(time.Duration(i+1) * 30 * time.Second)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	segments := readDir(t, dir)
+	if len(segments) != 2 {
+		t.Fatalf("segments = %v, want the 2 newest", segments)
+	}
+	if _, ok := segments["debug-20260101T000300.000Z-0003.jsonl"]; !ok {
+		t.Errorf("segments = %v, want the last segment kept", segments)
+	}
+}
+
+func TestFileSinkRequiresDir(t *testing.T) {
+	if _, err := NewFileSink(nil); err == nil {
+		t.Error("NewFileSink(nil) should fail")
+	}
+}
+
+func TestObjectSink(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	putter := ObjectPutterFunc(func(_ context.Context, key string, data []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		objects[key] = bytes.Clone(data)
+		return nil
+	})
+	sink := NewObjectSink(putter, &ObjectSinkConfig{Prefix: "prod/", RotationConfig: RotationConfig{MaxBytes: 150, Gzip: true}})
+
+	ctx := context.Background()
+	for _, payload := range []string{"one", "two", "three"} {
+		if err := sink.WriteRecord(ctx, record("S", payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(objects) == 0 {
+		t.Error("a full segment should be uploaded on rotation")
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	total := 0
+	for key, data := range objects {
+		if !strings.HasPrefix(key, "prod/") || !strings.HasSuffix(key, ".jsonl.gz") {
+			t.Errorf("object key = %s", key)
+		}
+		total += len(readSegment(t, key, data))
+	}
+	if total != 3 {
+		t.Errorf("uploaded records = %d, want 3", total)
+	}
+
+	// Closing with nothing buffered uploads nothing
+	before := len(objects)
+	if err := sink.Close(); err != nil || len(objects) != before {
+		t.Errorf("second Close() = %v, objects %d -> %d", err, before, len(objects))
+	}
+}
+
+func TestObjectSinkUploadError(t *testing.T) {
+	sink := NewObjectSink(ObjectPutterFunc(func(context.Context, string, []byte) error {
+		return errors.New("access denied")
+	}), nil)
+
+	if err := sink.WriteRecord(context.Background(), record("S", "x")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("Close() error = %v, want the upload failure", err)
+	}
+}
+
+// bucketPutter declares the object store it uploads to
+type bucketPutter struct {
+	ObjectPutterFunc
+	hosts []string
+}
+
+func (b bucketPutter) Egress() []string { return b.hosts }
+
+func TestObjectSinkEgress(t *testing.T) {
+	discard := ObjectPutterFunc(func(context.Context, string, []byte) error { return nil })
+	tests := []struct {
+		name   string
+		putter ObjectPutter
+		config *ObjectSinkConfig
+		want   []string
+	}{
+		{"default", discard, nil, []string{"object store"}},
+		{"endpoint", discard, &ObjectSinkConfig{Endpoint: "https://storage.googleapis.com"}, []string{"storage.googleapis.com"}},
+		{"local endpoint", discard, &ObjectSinkConfig{Endpoint: "http://localhost:9000"}, nil},
+		{"putter", bucketPutter{discard, []string{"s3.amazonaws.com"}}, nil, []string{"s3.amazonaws.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewObjectSink(tt.putter, tt.config)
+			defer sink.Close()
+			capture := Capture("prompt", sink)
+			if got := calque.DescribeHandler(capture).Egress; !slices.Equal(got, tt.want) {
+				t.Errorf("egress = %v, want %v", got, tt.want)
+			}
+			err := calque.NewFlow(calque.WithDataPolicy(calque.LocalOnly)).Use(capture).Validate()
+			if (err != nil) != (tt.want != nil) {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
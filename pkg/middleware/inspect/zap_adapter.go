@@ -0,0 +1,59 @@
+package inspect
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ZapAdapter adapts zap.Logger to our LoggerInterface
+type ZapAdapter struct {
+	logger *zap.Logger
+}
+
+// NewZapAdapter creates a new adapter for zap
+func NewZapAdapter(logger *zap.Logger) *ZapAdapter {
+	return &ZapAdapter{logger: logger}
+}
+
+// Log implements LoggerInterface for structured logging with zap
+func (z *ZapAdapter) Log(_ context.Context, level LogLevel, msg string, attrs ...Attribute) {
+	// Check first so fields are only built for enabled levels
+	entry := z.logger.Check(logLevelToZap(level), msg)
+	if entry == nil {
+		return
+	}
+
+	fields := make([]zap.Field, len(attrs))
+	for i, attr := range attrs {
+		fields[i] = zap.Any(attr.Key, attr.Value)
+	}
+	entry.Write(fields...)
+}
+
+// IsLevelEnabled checks if the given level is enabled in zap
+func (z *ZapAdapter) IsLevelEnabled(_ context.Context, level LogLevel) bool {
+	return z.logger.Core().Enabled(logLevelToZap(level))
+}
+
+// Printf implements backward compatibility via zap's sugared logger
+func (z *ZapAdapter) Printf(format string, v ...any) {
+	z.logger.Sugar().Infof(format, v...)
+}
+
+// logLevelToZap converts our LogLevel to zapcore.Level
+func logLevelToZap(level LogLevel) zapcore.Level {
+	switch level {
+	case DebugLevel:
+		return zapcore.DebugLevel
+	case InfoLevel:
+		return zapcore.InfoLevel
+	case WarnLevel:
+		return zapcore.WarnLevel
+	case ErrorLevel:
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
@@ -0,0 +1,82 @@
+package inspect
+
+import (
+	"context"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapAdapter_Log(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     LogLevel
+		wantLevel zapcore.Level
+	}{
+		{name: "debug", level: DebugLevel, wantLevel: zapcore.DebugLevel},
+		{name: "info", level: InfoLevel, wantLevel: zapcore.InfoLevel},
+		{name: "warn", level: WarnLevel, wantLevel: zapcore.WarnLevel},
+		{name: "error", level: ErrorLevel, wantLevel: zapcore.ErrorLevel},
+		{name: "unknown defaults to info", level: LogLevel(99), wantLevel: zapcore.InfoLevel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			adapter := NewZapAdapter(zap.New(core))
+
+			adapter.Log(context.Background(), tt.level, "[STAGE]", Attr("bytes", 42), Attr("preview", "hello"))
+
+			entries := logs.All()
+			if len(entries) != 1 {
+				t.Fatalf("entries = %d, want 1", len(entries))
+			}
+			entry := entries[0]
+			if entry.Level != tt.wantLevel || entry.Message != "[STAGE]" {
+				t.Errorf("entry = %v %q", entry.Level, entry.Message)
+			}
+			fields := entry.ContextMap()
+			if fields["bytes"] != int64(42) || fields["preview"] != "hello" {
+				t.Errorf("fields = %v", fields)
+			}
+		})
+	}
+}
+
+func TestZapAdapter_IsLevelEnabled(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	adapter := NewZapAdapter(zap.New(core))
+	ctx := context.Background()
+
+	if adapter.IsLevelEnabled(ctx, InfoLevel) || !adapter.IsLevelEnabled(ctx, WarnLevel) || !adapter.IsLevelEnabled(ctx, ErrorLevel) {
+		t.Error("IsLevelEnabled() should follow the core's level")
+	}
+
+	adapter.Log(ctx, DebugLevel, "dropped")
+	if logs.Len() != 0 {
+		t.Errorf("disabled level logged %d entries", logs.Len())
+	}
+}
+
+func TestZapAdapter_WithHandlers(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := New(NewZapAdapter(zap.New(core)))
+
+	var output string
+	if err := calque.NewFlow().Use(logger.Info().Head("INPUT", 5)).Run(context.Background(), "hello world", &output); err != nil {
+		t.Fatal(err)
+	}
+	if logs.Len() != 1 || logs.All()[0].ContextMap()["preview"] != "hello" {
+		t.Errorf("logs = %v", logs.All())
+	}
+
+	adapter := NewZapAdapter(zap.New(core))
+	adapter.Printf("processed %d items", 3)
+	if last := logs.All()[logs.Len()-1]; last.Message != "processed 3 items" {
+		t.Errorf("Printf() message = %q", last.Message)
+	}
+}
@@ -0,0 +1,68 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ProgressNotifier bridges calque progress events to MCP progress notifications.
+//
+// Use it inside MCP server tool handlers that run calque flows, so clients that
+// supplied a progress token receive notifications/progress updates. Percent is
+// reported against a total of 100. MCP requires progress to increase with every
+// notification, so indeterminate events (negative percent), and percentages
+// that would not advance past the last one sent, are sent one step past it
+// without a total. Returns nil when token is nil, which WithProgress ignores.
+//
+// Example:
+//
+//	func handler(ctx context.Context, req *mcp.CallToolRequest, in Input) (*mcp.CallToolResult, Output, error) {
+//		ctx = calque.WithProgress(ctx, calquemcp.ProgressNotifier(ctx, req.Session, req.Params.GetProgressToken()))
+//		err := flow.Run(ctx, in.Query, &out)
+//		...
+//	}
+func ProgressNotifier(ctx context.Context, session *mcp.ServerSession, token any) calque.ProgressFunc {
+	if session == nil || token == nil {
+		return nil
+	}
+
+	var mu sync.Mutex
+	var last float64
+	sent := false
+	return func(ev calque.ProgressEvent) {
+		params := &ProgressNotificationParams{
+			ProgressToken: token,
+			Message:       progressMessage(ev),
+		}
+
+		// Sent under the lock so concurrent handlers can't reorder notifications
+		mu.Lock()
+		defer mu.Unlock()
+		if ev.Percent >= 0 && (!sent || ev.Percent > last) {
+			params.Progress = ev.Percent
+			params.Total = 100
+		} else {
+			params.Progress = last + 1
+		}
+		last, sent = params.Progress, true
+
+		if err := session.NotifyProgress(ctx, params); err != nil {
+			calque.LogDebug(ctx, "failed to send MCP progress notification", "error", err)
+		}
+	}
+}
+
+// progressMessage combines stage and message into a single human readable string
+func progressMessage(ev calque.ProgressEvent) string {
+	switch {
+	case ev.Stage == "":
+		return ev.Message
+	case ev.Message == "":
+		return ev.Stage
+	default:
+		return ev.Stage + ": " + ev.Message
+	}
+}
@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestProgressNotifier(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	var received []*mcp.ProgressNotificationParams
+	done := make(chan struct{}, 5)
+
+	flow := calque.NewFlow().UseFunc(func(req *calque.Request, res *calque.Response) error {
+		calque.Progress(req.Context, "fetch", 50, "halfway")
+		calque.Progress(req.Context, "fetch", -1, "")
+		calque.Progress(req.Context, "rank", -1, "")
+		calque.Progress(req.Context, "rank", 30, "behind a parallel stage")
+		calque.Progress(req.Context, "rank", 80, "")
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "progress-server", Version: "v0.0.1"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "work"}, func(ctx context.Context, req *mcp.CallToolRequest, args GreetParams) (*mcp.CallToolResult, any, error) {
+		ctx = calque.WithProgress(ctx, ProgressNotifier(ctx, req.Session, req.Params.GetProgressToken()))
+		var out string
+		if err := flow.Run(ctx, args.Name, &out); err != nil {
+			return nil, nil, err
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: out}}}, nil, nil
+	})
+
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer serverSession.Close()
+
+	client := mcp.NewClient(defaultImplementation(), &mcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+			mu.Lock()
+			received = append(received, req.Params)
+			mu.Unlock()
+			done <- struct{}{}
+		},
+	})
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	defer session.Close()
+
+	// SetProgressToken only writes into an existing Meta map
+	params := &mcp.CallToolParams{Meta: mcp.Meta{}, Name: "work", Arguments: map[string]any{"name": "payload"}}
+	params.SetProgressToken("tok-1")
+	if _, err := session.CallTool(ctx, params); err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+
+	for range 5 {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for progress notifications")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	first := received[0]
+	if first.ProgressToken != "tok-1" {
+		t.Errorf("ProgressToken = %v, want tok-1", first.ProgressToken)
+	}
+	if first.Progress != 50 || first.Total != 100 {
+		t.Errorf("Progress = %v/%v, want 50/100", first.Progress, first.Total)
+	}
+	if !strings.Contains(first.Message, "fetch: halfway") {
+		t.Errorf("Message = %q, want stage and message", first.Message)
+	}
+	if received[1].Total != 0 {
+		t.Errorf("expected indeterminate progress without total, got %v", received[1].Total)
+	}
+
+	want := []struct{ progress, total float64 }{{50, 100}, {51, 0}, {52, 0}, {53, 0}, {80, 100}}
+	for i, w := range want {
+		if got := received[i]; got.Progress != w.progress || got.Total != w.total {
+			t.Errorf("notification %d = %v/%v, want %v/%v", i, got.Progress, got.Total, w.progress, w.total)
+		}
+	}
+}
+
+func TestProgressNotifier_NoToken(t *testing.T) {
+	if fn := ProgressNotifier(context.Background(), &mcp.ServerSession{}, nil); fn != nil {
+		t.Error("expected nil reporter when no progress token is supplied")
+	}
+}
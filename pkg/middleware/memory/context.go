@@ -11,6 +11,7 @@ import (
 	"unicode"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
 )
 
 // ContextMemory provides sliding window context memory using a pluggable store.
@@ -23,7 +24,19 @@ import (
 //	mem := memory.NewContext()
 //	flow.Use(mem.Input("session1", 4000)) // 4k token window
 type ContextMemory struct {
-	store Store
+	store     Store
+	tokenizer tokenizer.Tokenizer
+}
+
+// ContextConfig configures NewContextWithConfig.
+type ContextConfig struct {
+	// Store persists context windows (default: in-memory store)
+	Store Store
+
+	// Tokenizer counts tokens for windowing and Info. Use the tokenizer of the
+	// model the context is sent to for exact limits (default: a word and
+	// punctuation estimate)
+	Tokenizer tokenizer.Tokenizer
 }
 
 // NewContext creates a context memory with default in-memory store.
@@ -40,9 +53,7 @@ type ContextMemory struct {
 //	mem := memory.NewContext()
 //	flow.Use(mem.Input("session1", 2000))
 func NewContext() *ContextMemory {
-	return &ContextMemory{
-		store: NewInMemoryStore(),
-	}
+	return NewContextWithConfig(nil)
 }
 
 // NewContextWithStore creates a context memory with custom store.
@@ -59,7 +70,43 @@ func NewContext() *ContextMemory {
 //	mem := memory.NewContextWithStore(redisStore)
 func NewContextWithStore(store Store) *ContextMemory {
 	return &ContextMemory{
-		store: store,
+		store:     store,
+		tokenizer: estimatedTokens,
+	}
+}
+
+// NewContextWithConfig creates a context memory with a custom store and tokenizer.
+//
+// Input: *ContextConfig (nil uses defaults)
+// Output: *ContextMemory
+// Behavior: Windows are trimmed and measured with the configured tokenizer
+//
+// Counting with the model's own tokenizer keeps the window within the
+// model's limit; the default estimate can be off by 20% or more for code
+// and non-English text.
+//
+// Example:
+//
+//	enc, _ := tiktoken.EncodingForModel("gpt-4o")
+//	mem := memory.NewContextWithConfig(&memory.ContextConfig{
+//	    Tokenizer: tokenizer.Func(func(text []byte) int {
+//	        return len(enc.Encode(string(text), nil, nil))
+//	    }),
+//	})
+func NewContextWithConfig(config *ContextConfig) *ContextMemory {
+	cfg := ContextConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewInMemoryStore()
+	}
+	if cfg.Tokenizer == nil {
+		cfg.Tokenizer = estimatedTokens
+	}
+	return &ContextMemory{
+		store:     cfg.Store,
+		tokenizer: cfg.Tokenizer,
 	}
 }
 
@@ -69,6 +116,9 @@ type contextData struct {
 	Content   []byte `json:"content"`
 }
 
+// estimatedTokens is the default ContextMemory tokenizer
+var estimatedTokens tokenizer.Tokenizer = tokenizer.Func(approximateTokenCount)
+
 // approximateTokenCount provides a rough token estimate
 // Uses a more sophisticated approach: 1 token ≈ 3.5 chars for English
 // Counts words, punctuation separately for better estimates
@@ -93,10 +143,10 @@ func approximateTokenCount(data []byte) int {
 	return int(tokenCount)
 }
 
-// trimToTokenLimit trims content to stay within token limit
+// trimToTokenLimit trims content to stay within token limit as counted by counter
 // Tries to preserve sentence boundaries when possible
-func trimToTokenLimit(content []byte, maxTokens int) []byte {
-	if approximateTokenCount(content) <= maxTokens {
+func trimToTokenLimit(content []byte, maxTokens int, counter tokenizer.Tokenizer) []byte {
+	if counter.CountTokens(content) <= maxTokens {
 		return content
 	}
 
@@ -108,7 +158,7 @@ func trimToTokenLimit(content []byte, maxTokens int) []byte {
 
 	for left < right {
 		mid := (left + right) / 2
-		if approximateTokenCount([]byte(text[mid:])) <= maxTokens {
+		if counter.CountTokens([]byte(text[mid:])) <= maxTokens {
 			bestCut = mid
 			right = mid
 		} else {
@@ -220,7 +270,7 @@ func (cm *ContextMemory) AddToContext(ctx context.Context, key string, content [
 	ctxData.Content = append(ctxData.Content, content...)
 
 	// Trim to token limit
-	ctxData.Content = trimToTokenLimit(ctxData.Content, maxTokens)
+	ctxData.Content = trimToTokenLimit(ctxData.Content, maxTokens, cm.tokenizer)
 
 	return cm.saveContext(ctx, key, ctxData)
 }
@@ -244,6 +294,9 @@ func (cm *ContextMemory) Clear(key string) error {
 // Output: current tokens, max tokens, exists flag, error
 // Behavior: Non-destructive inspection of context state
 //
+// Tokens are counted with the configured tokenizer, so the count matches
+// what the model will see when that tokenizer is the model's own.
+//
 // Example:
 //
 //	tokens, max, exists, err := mem.Info(ctx, "session1")
@@ -259,7 +312,7 @@ func (cm *ContextMemory) Info(ctx context.Context, key string) (tokenCount, maxT
 		return 0, 0, exists, nil
 	}
 
-	return approximateTokenCount(ctxData.Content), ctxData.MaxTokens, true, nil
+	return cm.tokenizer.CountTokens(ctxData.Content), ctxData.MaxTokens, true, nil
 }
 
 // ListKeys returns all active context keys.
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
 )
 
 func TestNewContext(t *testing.T) {
@@ -35,6 +36,34 @@ func TestNewContextWithStore(t *testing.T) {
 	}
 }
 
+func TestNewContextWithConfig(t *testing.T) {
+	bg := context.Background()
+	words := tokenizer.Func(func(text []byte) int { return len(strings.Fields(string(text))) })
+	store := NewInMemoryStore()
+	mem := NewContextWithConfig(&ContextConfig{Store: store, Tokenizer: words})
+
+	if mem.store != store {
+		t.Error("NewContextWithConfig() did not use provided store")
+	}
+
+	if err := mem.AddToContext(bg, "s", []byte("alpha beta gamma. delta epsilon. zeta eta theta iota"), 6); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := mem.GetContext(bg, "s")
+	if string(content) != "zeta eta theta iota" {
+		t.Errorf("window = %q, want trimming by the configured tokenizer at a sentence boundary", content)
+	}
+
+	tokens, maxTokens, exists, err := mem.Info(bg, "s")
+	if err != nil || !exists || tokens != 4 || maxTokens != 6 {
+		t.Errorf("Info() = %d, %d, %v, %v; want 4 tokens of 6", tokens, maxTokens, exists, err)
+	}
+
+	if defaults := NewContextWithConfig(nil); defaults.store == nil || defaults.tokenizer == nil {
+		t.Error("NewContextWithConfig(nil) should use defaults")
+	}
+}
+
 func TestApproximateTokenCount(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -128,7 +157,7 @@ func TestTrimToTokenLimit(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := trimToTokenLimit(tt.content, tt.maxTokens)
+			got := trimToTokenLimit(tt.content, tt.maxTokens, estimatedTokens)
 
 			if !tt.expectLen(len(got), len(tt.content)) {
 				t.Errorf("trimToTokenLimit() result length validation failed: got %d bytes, original %d bytes", len(got), len(tt.content))
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
@@ -267,6 +268,90 @@ func (cm *ConversationMemory) Info(ctx context.Context, key string) (messageCoun
 	return len(history), exists, nil
 }
 
+// Messages returns the stored conversation history, oldest first.
+//
+// Input: conversation key string
+// Output: messages (empty if the conversation doesn't exist), error
+// Behavior: Non-destructive read of the conversation
+//
+// Example:
+//
+//	msgs, err := mem.Messages(ctx, "user123")
+//	for _, m := range msgs { fmt.Println(m) }
+func (cm *ConversationMemory) Messages(ctx context.Context, key string) ([]Message, error) {
+	return cm.getConversation(ctx, key)
+}
+
+// EditMessage replaces the content of the message at idx, keeping its role.
+//
+// Input: conversation key, zero-based message index, new content
+// Output: error if the index is out of range or saving fails
+// Behavior: Rewrites the stored conversation; later messages are kept
+//
+// Example:
+//
+//	err := mem.EditMessage(ctx, "user123", 2, []byte("What about Go 1.22?"))
+func (cm *ConversationMemory) EditMessage(ctx context.Context, key string, idx int, content []byte) error {
+	return cm.updateConversation(ctx, key, idx, func(messages []Message) []Message {
+		messages[idx].Content = bytes.Clone(content)
+		return messages
+	})
+}
+
+// DeleteMessage removes the message at idx.
+//
+// Input: conversation key, zero-based message index
+// Output: error if the index is out of range or saving fails
+// Behavior: Rewrites the stored conversation; later messages shift down by one
+//
+// Example:
+//
+//	err := mem.DeleteMessage(ctx, "user123", 0)
+func (cm *ConversationMemory) DeleteMessage(ctx context.Context, key string, idx int) error {
+	return cm.updateConversation(ctx, key, idx, func(messages []Message) []Message {
+		return slices.Delete(messages, idx, idx+1)
+	})
+}
+
+// TruncateAfter removes every message after idx; -1 removes them all.
+//
+// Input: conversation key, zero-based index of the last message to keep
+// Output: error if the index is out of range or saving fails
+// Behavior: Rewrites the stored conversation
+//
+// For edit-and-resend, truncate before the edited user message and run the
+// flow again with the new text, so Input records it and the old answer is gone.
+//
+// Example:
+//
+//	// The user edits their message at index 4
+//	err := mem.TruncateAfter(ctx, "user123", 3)
+//	err = flow.Run(ctx, editedText, &answer)
+func (cm *ConversationMemory) TruncateAfter(ctx context.Context, key string, idx int) error {
+	if idx == -1 {
+		messages, err := cm.getConversation(ctx, key)
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+		return cm.saveConversation(ctx, key, []Message{})
+	}
+	return cm.updateConversation(ctx, key, idx, func(messages []Message) []Message {
+		return messages[:idx+1]
+	})
+}
+
+// updateConversation loads a conversation, checks idx and saves the result of change
+func (cm *ConversationMemory) updateConversation(ctx context.Context, key string, idx int, change func([]Message) []Message) error {
+	messages, err := cm.getConversation(ctx, key)
+	if err != nil {
+		return err
+	}
+	if idx < 0 || idx >= len(messages) {
+		return calque.NewErr(ctx, fmt.Sprintf("message index %d out of range for conversation %s with %d messages", idx, key, len(messages)))
+	}
+	return cm.saveConversation(ctx, key, change(messages))
+}
+
 // ListKeys returns all active conversation keys.
 //
 // Input: none
@@ -309,6 +394,10 @@ func WithKey(ctx context.Context, key string) context.Context {
 // Output: key string (empty if not found)
 // Behavior: Retrieves conversation key from context
 //
+// A key set with WithKey takes precedence. Otherwise the user stored with
+// calque.WithUser is used, prefixed with the tenant ("tenant/user") when one is set,
+// so conversations are isolated per caller without a memory-specific key.
+//
 // Example:
 //
 //	key := memory.GetKey(req.Context)
@@ -317,7 +406,14 @@ func GetKey(ctx context.Context) string {
 	if key, ok := ctx.Value(MemoryKey).(string); ok {
 		return key
 	}
-	return ""
+	user, ok := calque.UserFromContext(ctx)
+	if !ok || user.ID == "" {
+		return ""
+	}
+	if tenant := calque.Tenant(ctx); tenant != "" {
+		return tenant + "/" + user.ID
+	}
+	return user.ID
 }
 
 // InputFromContext creates input middleware that uses key from context.
@@ -474,6 +474,141 @@ func TestConversationMemoryInfo(t *testing.T) {
 	}
 }
 
+func TestConversationMemoryMessages(t *testing.T) {
+	conv := NewConversation()
+	ctx := context.Background()
+
+	msgs, err := conv.Messages(ctx, "missing")
+	if err != nil || len(msgs) != 0 {
+		t.Fatalf("Messages() of missing key = %v, %v", msgs, err)
+	}
+
+	conv.saveConversation(ctx, "chat", []Message{
+		{Role: "user", Content: []byte("Hello")},
+		{Role: "assistant", Content: []byte("Hi!")},
+	})
+	msgs, err = conv.Messages(ctx, "chat")
+	if err != nil {
+		t.Fatalf("Messages() error = %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].String() != "user: Hello" || msgs[1].String() != "assistant: Hi!" {
+		t.Errorf("Messages() = %v", msgs)
+	}
+}
+
+func TestConversationMemoryEditing(t *testing.T) {
+	ctx := context.Background()
+	history := []Message{
+		{Role: "user", Content: []byte("Q1")},
+		{Role: "assistant", Content: []byte("A1")},
+		{Role: "user", Content: []byte("Q2")},
+		{Role: "assistant", Content: []byte("A2")},
+	}
+
+	tests := []struct {
+		name    string
+		edit    func(conv *ConversationMemory) error
+		want    string
+		wantErr string
+	}{
+		{
+			name: "edit keeps role and later messages",
+			edit: func(conv *ConversationMemory) error { return conv.EditMessage(ctx, "chat", 2, []byte("Q2 edited")) },
+			want: "user: Q1|assistant: A1|user: Q2 edited|assistant: A2",
+		},
+		{
+			name: "delete shifts later messages",
+			edit: func(conv *ConversationMemory) error { return conv.DeleteMessage(ctx, "chat", 1) },
+			want: "user: Q1|user: Q2|assistant: A2",
+		},
+		{
+			name: "truncate after",
+			edit: func(conv *ConversationMemory) error { return conv.TruncateAfter(ctx, "chat", 1) },
+			want: "user: Q1|assistant: A1",
+		},
+		{
+			name: "truncate after last keeps everything",
+			edit: func(conv *ConversationMemory) error { return conv.TruncateAfter(ctx, "chat", 3) },
+			want: "user: Q1|assistant: A1|user: Q2|assistant: A2",
+		},
+		{
+			name: "truncate after -1 empties the conversation",
+			edit: func(conv *ConversationMemory) error { return conv.TruncateAfter(ctx, "chat", -1) },
+			want: "",
+		},
+		{
+			name:    "index out of range",
+			edit:    func(conv *ConversationMemory) error { return conv.EditMessage(ctx, "chat", 4, []byte("x")) },
+			want:    "user: Q1|assistant: A1|user: Q2|assistant: A2",
+			wantErr: "message index 4 out of range",
+		},
+		{
+			name:    "negative index",
+			edit:    func(conv *ConversationMemory) error { return conv.DeleteMessage(ctx, "chat", -1) },
+			want:    "user: Q1|assistant: A1|user: Q2|assistant: A2",
+			wantErr: "out of range",
+		},
+		{
+			name:    "missing conversation",
+			edit:    func(conv *ConversationMemory) error { return conv.TruncateAfter(ctx, "missing", 0) },
+			want:    "user: Q1|assistant: A1|user: Q2|assistant: A2",
+			wantErr: "with 0 messages",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv := NewConversation()
+			conv.saveConversation(ctx, "chat", history)
+
+			err := tt.edit(conv)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			msgs, _ := conv.Messages(ctx, "chat")
+			got := make([]string, len(msgs))
+			for i, m := range msgs {
+				got[i] = m.String()
+			}
+			if strings.Join(got, "|") != tt.want {
+				t.Errorf("messages = %q, want %q", strings.Join(got, "|"), tt.want)
+			}
+		})
+	}
+}
+
+func TestConversationMemoryEditAndResend(t *testing.T) {
+	ctx := context.Background()
+	conv := NewConversation()
+	echo := calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		var input string
+		if err := calque.Read(r, &input); err != nil {
+			return err
+		}
+		return calque.Write(w, "answer")
+	})
+	flow := calque.NewFlow().Use(conv.Input("chat")).Use(echo).Use(conv.Output("chat"))
+
+	var out string
+	_ = flow.Run(ctx, "first", &out)
+	_ = flow.Run(ctx, "second with a typo", &out)
+
+	if err := conv.TruncateAfter(ctx, "chat", 1); err != nil {
+		t.Fatal(err)
+	}
+	_ = flow.Run(ctx, "second, fixed", &out)
+
+	msgs, _ := conv.Messages(ctx, "chat")
+	if len(msgs) != 4 || msgs[2].Text() != "second, fixed" {
+		t.Errorf("messages after resend = %v", msgs)
+	}
+}
+
 func TestConversationMemoryListKeys(t *testing.T) {
 	conv := NewConversation()
 
@@ -674,3 +809,27 @@ func TestConversationMemoryErrorHandling(t *testing.T) {
 		}
 	})
 }
+
+func TestGetKey_FallsBackToUser(t *testing.T) {
+	user := calque.User{ID: "u-1"}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"empty", context.Background(), ""},
+		{"user", calque.WithUser(context.Background(), user), "u-1"},
+		{"tenant scoped", calque.WithTenant(calque.WithUser(context.Background(), user), "acme"), "acme/u-1"},
+		{"explicit key wins", WithKey(calque.WithUser(context.Background(), user), "session-9"), "session-9"},
+		{"user without id", calque.WithUser(context.Background(), calque.User{Name: "anon"}), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetKey(tt.ctx); got != tt.want {
+				t.Errorf("GetKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
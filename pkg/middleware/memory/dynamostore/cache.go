@@ -0,0 +1,87 @@
+package dynamostore
+
+import (
+	"context"
+	"time"
+)
+
+// CacheStore implements cache.Store on top of a DynamoDB table.
+//
+// Each Set records an expiry timestamp; enable DynamoDB TTL on the expiry
+// attribute so AWS deletes expired entries in the background.
+//
+// Example:
+//
+//	store, err := dynamostore.NewCache(&dynamostore.Config{
+//		Client: dynamodb.NewFromConfig(awsCfg),
+//		Table:  "calque-cache",
+//	})
+//	responses := cache.NewCacheWithStore(store)
+type CacheStore struct {
+	table *table
+}
+
+// NewCache creates a DynamoDB backed cache store.
+func NewCache(config *Config) (*CacheStore, error) {
+	t, err := newTable(config)
+	if err != nil {
+		return nil, err
+	}
+	return &CacheStore{table: t}, nil
+}
+
+// Get retrieves data for a key, returns nil if not found or expired
+func (c *CacheStore) Get(key string) ([]byte, error) {
+	ctx, cancel := c.table.context()
+	defer cancel()
+	return c.table.get(ctx, key)
+}
+
+// Set stores data for a key with TTL
+func (c *CacheStore) Set(key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := c.table.context()
+	defer cancel()
+	return c.table.put(ctx, key, value, ttl)
+}
+
+// Delete removes data for a key
+func (c *CacheStore) Delete(key string) error {
+	ctx, cancel := c.table.context()
+	defer cancel()
+	return c.table.delete(ctx, key)
+}
+
+// Clear removes all cached data
+func (c *CacheStore) Clear() error {
+	ctx, cancel := c.table.context()
+	defer cancel()
+
+	keys, err := c.table.keys(ctx)
+	if err != nil {
+		return err
+	}
+	return c.table.deleteAll(ctx, keys)
+}
+
+// Exists checks if a key exists and hasn't expired
+func (c *CacheStore) Exists(key string) bool {
+	data, err := c.Get(key)
+	return err == nil && data != nil
+}
+
+// List returns all non-expired keys. Returns nil on scan errors.
+func (c *CacheStore) List() []string {
+	ctx, cancel := c.table.context()
+	defer cancel()
+
+	keys, err := c.table.keys(ctx)
+	if err != nil {
+		return nil
+	}
+	return keys
+}
+
+// ListPage returns up to limit non-expired keys starting after cursor, and the next cursor.
+func (c *CacheStore) ListPage(ctx context.Context, limit int32, cursor string) ([]string, string, error) {
+	return c.table.page(ctx, limit, cursor)
+}
@@ -0,0 +1,269 @@
+// Package dynamostore provides Amazon DynamoDB backed stores for memory and cache middleware.
+//
+// Store implements memory.Store for conversation and context memory, and CacheStore
+// implements cache.Store with per-item expiry. Both use a table with a single string
+// partition key, so they work with on-demand tables and serverless deployments without
+// extra indexes. Enable DynamoDB TTL on the expiry attribute to have AWS remove
+// expired items; reads ignore expired items even before AWS deletes them.
+package dynamostore
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/helpers"
+)
+
+// Default attribute names and limits
+const (
+	DefaultKeyAttribute     = "pk"
+	DefaultValueAttribute   = "value"
+	DefaultExpiresAttribute = "expires_at"
+	DefaultTimeout          = 10 * time.Second
+
+	// batchWriteLimit is the maximum number of requests in one BatchWriteItem call
+	batchWriteLimit = 25
+	// batchWriteAttempts bounds the BatchWriteItem calls made for one batch
+	// while DynamoDB keeps returning unprocessed items
+	batchWriteAttempts = 5
+	// batchWriteBackoff is the wait before resending unprocessed items, doubled per retry
+	batchWriteBackoff = 50 * time.Millisecond
+)
+
+// API is the subset of the DynamoDB client used by the stores.
+//
+// *dynamodb.Client satisfies this interface; tests can provide a fake.
+type API interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+}
+
+// Config holds DynamoDB store configuration.
+type Config struct {
+	// DynamoDB client (required), typically dynamodb.NewFromConfig(awsCfg)
+	Client API
+
+	// Table name (required). The table must have a string partition key named KeyAttribute.
+	Table string
+
+	// Attribute names (defaults: "pk", "value", "expires_at")
+	KeyAttribute     string
+	ValueAttribute   string
+	ExpiresAttribute string
+
+	// TTL applied to every memory.Store write (0 = items never expire).
+	// CacheStore uses the TTL passed to Set instead.
+	TTL time.Duration
+
+	// Timeout for each DynamoDB call (default: 10s)
+	Timeout time.Duration
+}
+
+// table implements the DynamoDB operations shared by Store and CacheStore
+type table struct {
+	client      API
+	name        string
+	keyAttr     string
+	valueAttr   string
+	expiresAttr string
+	timeout     time.Duration
+}
+
+func newTable(config *Config) (*table, error) {
+	ctx := context.Background()
+	if config == nil || config.Client == nil {
+		return nil, calque.NewErr(ctx, "DynamoDB client is required")
+	}
+	if config.Table == "" {
+		return nil, calque.NewErr(ctx, "DynamoDB table name is required")
+	}
+
+	t := &table{
+		client:      config.Client,
+		name:        config.Table,
+		keyAttr:     config.KeyAttribute,
+		valueAttr:   config.ValueAttribute,
+		expiresAttr: config.ExpiresAttribute,
+		timeout:     config.Timeout,
+	}
+	if t.keyAttr == "" {
+		t.keyAttr = DefaultKeyAttribute
+	}
+	if t.valueAttr == "" {
+		t.valueAttr = DefaultValueAttribute
+	}
+	if t.expiresAttr == "" {
+		t.expiresAttr = DefaultExpiresAttribute
+	}
+	if t.timeout <= 0 {
+		t.timeout = DefaultTimeout
+	}
+	return t, nil
+}
+
+func (t *table) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), t.timeout)
+}
+
+func (t *table) itemKey(key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{t.keyAttr: &types.AttributeValueMemberS{Value: key}}
+}
+
+// get returns the stored value, or nil if the item is missing or expired
+func (t *table) get(ctx context.Context, key string) ([]byte, error) {
+	out, err := t.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      &t.name,
+		Key:            t.itemKey(key),
+		ConsistentRead: helpers.PtrOf(true),
+	})
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to get item %s", key))
+	}
+	if out.Item == nil || t.expired(out.Item) {
+		return nil, nil
+	}
+
+	value, ok := out.Item[t.valueAttr].(*types.AttributeValueMemberB)
+	if !ok {
+		return nil, calque.NewErr(ctx, fmt.Sprintf("item %s has no binary %s attribute", key, t.valueAttr))
+	}
+	return value.Value, nil
+}
+
+func (t *table) put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	item := t.itemKey(key)
+	item[t.valueAttr] = &types.AttributeValueMemberB{Value: value}
+	if ttl > 0 {
+		expires := time.Now().Add(ttl).Unix()
+		item[t.expiresAttr] = &types.AttributeValueMemberN{Value: strconv.FormatInt(expires, 10)}
+	}
+
+	if _, err := t.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: &t.name, Item: item}); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to put item %s", key))
+	}
+	return nil
+}
+
+func (t *table) delete(ctx context.Context, key string) error {
+	if _, err := t.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{TableName: &t.name, Key: t.itemKey(key)}); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to delete item %s", key))
+	}
+	return nil
+}
+
+// page scans one page of keys, skipping expired items
+func (t *table) page(ctx context.Context, limit int32, cursor string) ([]string, string, error) {
+	input := &dynamodb.ScanInput{
+		TableName:            &t.name,
+		ProjectionExpression: helpers.PtrOf("#k, #e"),
+		ExpressionAttributeNames: map[string]string{
+			"#k": t.keyAttr,
+			"#e": t.expiresAttr,
+		},
+	}
+	if limit > 0 {
+		input.Limit = &limit
+	}
+	if cursor != "" {
+		input.ExclusiveStartKey = t.itemKey(cursor)
+	}
+
+	out, err := t.client.Scan(ctx, input)
+	if err != nil {
+		return nil, "", calque.WrapErr(ctx, err, "failed to scan table")
+	}
+
+	keys := make([]string, 0, len(out.Items))
+	for _, item := range out.Items {
+		if t.expired(item) {
+			continue
+		}
+		if k, ok := item[t.keyAttr].(*types.AttributeValueMemberS); ok {
+			keys = append(keys, k.Value)
+		}
+	}
+
+	var next string
+	if k, ok := out.LastEvaluatedKey[t.keyAttr].(*types.AttributeValueMemberS); ok {
+		next = k.Value
+	}
+	return keys, next, nil
+}
+
+// keys scans every page and returns all live keys
+func (t *table) keys(ctx context.Context) ([]string, error) {
+	var all []string
+	cursor := ""
+	for {
+		keys, next, err := t.page(ctx, 0, cursor)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, keys...)
+		if next == "" {
+			return all, nil
+		}
+		cursor = next
+	}
+}
+
+// deleteAll removes items in BatchWriteItem-sized batches, resending
+// unprocessed writes with exponential backoff up to batchWriteAttempts times
+func (t *table) deleteAll(ctx context.Context, keys []string) error {
+	for start := 0; start < len(keys); start += batchWriteLimit {
+		end := min(start+batchWriteLimit, len(keys))
+
+		requests := make([]types.WriteRequest, 0, end-start)
+		for _, key := range keys[start:end] {
+			requests = append(requests, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: t.itemKey(key)}})
+		}
+
+		pending := map[string][]types.WriteRequest{t.name: requests}
+		backoff := batchWriteBackoff
+		for attempt := 1; ; attempt++ {
+			out, err := t.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: pending})
+			if err != nil {
+				return calque.WrapErr(ctx, err, "failed to batch delete items")
+			}
+			pending = out.UnprocessedItems
+			if len(pending[t.name]) == 0 {
+				break
+			}
+			if attempt == batchWriteAttempts {
+				return calque.NewErr(ctx, fmt.Sprintf("failed to batch delete items: %d still unprocessed after %d attempts", len(pending[t.name]), attempt))
+			}
+
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+			backoff *= 2
+		}
+	}
+	return nil
+}
+
+// expired reports whether an item's expiry attribute is in the past
+func (t *table) expired(item map[string]types.AttributeValue) bool {
+	attr, ok := item[t.expiresAttr].(*types.AttributeValueMemberN)
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(attr.Value, 10, 64)
+	if err != nil {
+		return false
+	}
+	return time.Now().Unix() >= expires
+}
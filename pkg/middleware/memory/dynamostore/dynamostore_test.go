@@ -0,0 +1,329 @@
+package dynamostore
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/cache"
+	"github.com/calque-ai/go-calque/pkg/middleware/memory"
+)
+
+var (
+	_ memory.Store = (*Store)(nil)
+	_ cache.Store  = (*CacheStore)(nil)
+)
+
+// fakeDynamo is an in-memory table keyed by the "pk" attribute
+type fakeDynamo struct {
+	mu          sync.Mutex
+	items       map[string]map[string]types.AttributeValue
+	err         error
+	unprocessed int // number of BatchWriteItem calls that leave one request unprocessed
+	batchCalls  int
+}
+
+func newFakeDynamo() *fakeDynamo {
+	return &fakeDynamo{items: make(map[string]map[string]types.AttributeValue)}
+}
+
+func keyOf(key map[string]types.AttributeValue) string {
+	return key[DefaultKeyAttribute].(*types.AttributeValueMemberS).Value
+}
+
+func (f *fakeDynamo) GetItem(_ context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &dynamodb.GetItemOutput{Item: f.items[keyOf(in.Key)]}, nil
+}
+
+func (f *fakeDynamo) PutItem(_ context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.items[keyOf(in.Item)] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamo) DeleteItem(_ context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.items, keyOf(in.Key))
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (f *fakeDynamo) Scan(_ context.Context, in *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	keys := make([]string, 0, len(f.items))
+	for k := range f.items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	start := 0
+	if in.ExclusiveStartKey != nil {
+		start = sort.SearchStrings(keys, keyOf(in.ExclusiveStartKey)) + 1
+	}
+
+	out := &dynamodb.ScanOutput{}
+	for i := start; i < len(keys); i++ {
+		if in.Limit != nil && int32(len(out.Items)) == *in.Limit {
+			out.LastEvaluatedKey = map[string]types.AttributeValue{
+				DefaultKeyAttribute: &types.AttributeValueMemberS{Value: keys[i-1]},
+			}
+			break
+		}
+		out.Items = append(out.Items, f.items[keys[i]])
+	}
+	return out, nil
+}
+
+func (f *fakeDynamo) BatchWriteItem(_ context.Context, in *dynamodb.BatchWriteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batchCalls++
+
+	out := &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]types.WriteRequest{}}
+	for table, requests := range in.RequestItems {
+		if len(requests) > batchWriteLimit {
+			return nil, errors.New("too many items in batch")
+		}
+		for i, req := range requests {
+			if f.unprocessed > 0 && i == len(requests)-1 {
+				f.unprocessed--
+				out.UnprocessedItems[table] = append(out.UnprocessedItems[table], req)
+				continue
+			}
+			delete(f.items, keyOf(req.DeleteRequest.Key))
+		}
+	}
+	return out, nil
+}
+
+func TestNew_Validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{"nil config", nil},
+		{"missing client", &Config{Table: "t"}},
+		{"missing table", &Config{Client: newFakeDynamo()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestStore_CRUD(t *testing.T) {
+	store, err := New(&Config{Client: newFakeDynamo(), Table: "memory"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if data, err := store.Get("missing"); err != nil || data != nil {
+		t.Errorf("Get(missing) = %v, %v; want nil, nil", data, err)
+	}
+
+	if err := store.Set("conv-1", []byte(`{"messages":[]}`)); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if !store.Exists("conv-1") {
+		t.Error("expected key to exist")
+	}
+
+	data, err := store.Get("conv-1")
+	if err != nil || string(data) != `{"messages":[]}` {
+		t.Errorf("Get() = %q, %v", data, err)
+	}
+
+	if keys := store.List(); !slices.Equal(keys, []string{"conv-1"}) {
+		t.Errorf("List() = %v", keys)
+	}
+
+	if err := store.Delete("conv-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if store.Exists("conv-1") {
+		t.Error("expected key to be deleted")
+	}
+}
+
+func TestStore_WithConversationMemory(t *testing.T) {
+	store, _ := New(&Config{Client: newFakeDynamo(), Table: "memory"})
+	conv := memory.NewConversationWithStore(store)
+
+	var out string
+	if err := calque.NewFlow().Use(conv.Input("user-1")).Run(context.Background(), "hello", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	count, exists, err := conv.Info(context.Background(), "user-1")
+	if err != nil || !exists || count != 1 {
+		t.Errorf("Info() = %d, %v, %v", count, exists, err)
+	}
+}
+
+func TestStore_TTL(t *testing.T) {
+	fake := newFakeDynamo()
+	store, _ := New(&Config{Client: fake, Table: "memory", TTL: time.Hour})
+
+	if err := store.Set("k", []byte("v")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	expires, ok := fake.items["k"][DefaultExpiresAttribute].(*types.AttributeValueMemberN)
+	if !ok {
+		t.Fatal("expected expiry attribute")
+	}
+	if ts, _ := strconv.ParseInt(expires.Value, 10, 64); ts < time.Now().Add(59*time.Minute).Unix() {
+		t.Errorf("unexpected expiry %s", expires.Value)
+	}
+
+	// Expired items are invisible even before DynamoDB deletes them
+	fake.items["k"][DefaultExpiresAttribute] = &types.AttributeValueMemberN{Value: "1"}
+	if store.Exists("k") {
+		t.Error("expired item must not be returned")
+	}
+	if keys := store.List(); len(keys) != 0 {
+		t.Errorf("expired item must not be listed, got %v", keys)
+	}
+}
+
+func TestStore_ListPage(t *testing.T) {
+	store, _ := New(&Config{Client: newFakeDynamo(), Table: "memory"})
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		_ = store.Set(k, []byte(k))
+	}
+
+	var pages [][]string
+	cursor := ""
+	for {
+		keys, next, err := store.ListPage(context.Background(), 2, cursor)
+		if err != nil {
+			t.Fatalf("ListPage() error = %v", err)
+		}
+		pages = append(pages, keys)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if len(pages) != 3 || !slices.Equal(pages[0], []string{"a", "b"}) || !slices.Equal(pages[2], []string{"e"}) {
+		t.Errorf("unexpected pages %v", pages)
+	}
+
+	if keys := store.List(); len(keys) != 5 {
+		t.Errorf("List() = %v, want 5 keys", keys)
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	fake := newFakeDynamo()
+	fake.err = errors.New("throttled")
+	store, _ := New(&Config{Client: fake, Table: "memory"})
+
+	if _, err := store.Get("k"); err == nil {
+		t.Error("expected Get error")
+	}
+	if err := store.Set("k", nil); err == nil {
+		t.Error("expected Set error")
+	}
+	if keys := store.List(); keys != nil {
+		t.Errorf("expected nil keys on error, got %v", keys)
+	}
+	if store.Exists("k") {
+		t.Error("Exists must be false on error")
+	}
+}
+
+func TestCacheStore(t *testing.T) {
+	fake := newFakeDynamo()
+	store, err := NewCache(&Config{Client: fake, Table: "cache"})
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+
+	if err := store.Set("fresh", []byte("data"), time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Set("stale", []byte("old"), time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	fake.items["stale"][DefaultExpiresAttribute] = &types.AttributeValueMemberN{Value: "1"}
+
+	if data, _ := store.Get("fresh"); string(data) != "data" {
+		t.Errorf("Get(fresh) = %q", data)
+	}
+	if data, _ := store.Get("stale"); data != nil {
+		t.Errorf("Get(stale) = %q, want nil", data)
+	}
+	if keys := store.List(); !slices.Equal(keys, []string{"fresh"}) {
+		t.Errorf("List() = %v", keys)
+	}
+}
+
+func TestCacheStore_Clear(t *testing.T) {
+	fake := newFakeDynamo()
+	fake.unprocessed = 1
+	store, _ := NewCache(&Config{Client: fake, Table: "cache"})
+
+	for i := range 30 {
+		_ = store.Set("k"+strconv.Itoa(i), []byte("v"), time.Minute)
+	}
+
+	if err := store.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if len(fake.items) != 0 {
+		t.Errorf("expected all items deleted, %d remain", len(fake.items))
+	}
+	// 2 batches for 30 items plus 1 retry for the unprocessed request
+	if fake.batchCalls != 3 {
+		t.Errorf("expected 3 BatchWriteItem calls, got %d", fake.batchCalls)
+	}
+}
+
+func TestCacheStore_ClearGivesUpOnUnprocessedItems(t *testing.T) {
+	fake := newFakeDynamo()
+	store, _ := NewCache(&Config{Client: fake, Table: "cache"})
+	_ = store.Set("k", []byte("v"), time.Minute)
+	fake.unprocessed = 100
+
+	start := time.Now()
+	if err := store.Clear(); err == nil || !strings.Contains(err.Error(), "unprocessed") {
+		t.Fatalf("Clear() error = %v, want unprocessed items reported", err)
+	}
+	if fake.batchCalls != batchWriteAttempts {
+		t.Errorf("expected %d BatchWriteItem calls, got %d", batchWriteAttempts, fake.batchCalls)
+	}
+	// 50ms + 100ms + 200ms + 400ms between the five attempts
+	if elapsed := time.Since(start); elapsed < 750*time.Millisecond {
+		t.Errorf("retries took %v, want exponential backoff between them", elapsed)
+	}
+}
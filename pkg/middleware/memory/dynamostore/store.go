@@ -0,0 +1,90 @@
+package dynamostore
+
+import (
+	"context"
+	"time"
+)
+
+// Store implements memory.Store on top of a DynamoDB table.
+//
+// Example:
+//
+//	awsCfg, _ := config.LoadDefaultConfig(ctx)
+//	store, err := dynamostore.New(&dynamostore.Config{
+//		Client: dynamodb.NewFromConfig(awsCfg),
+//		Table:  "calque-memory",
+//		TTL:    7 * 24 * time.Hour,
+//	})
+//	conversations := memory.NewConversationWithStore(store)
+type Store struct {
+	table *table
+	ttl   time.Duration
+}
+
+// New creates a DynamoDB backed memory store.
+func New(config *Config) (*Store, error) {
+	t, err := newTable(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{table: t, ttl: config.TTL}, nil
+}
+
+// Get retrieves data for a key, returns nil if not found
+func (s *Store) Get(key string) ([]byte, error) {
+	ctx, cancel := s.table.context()
+	defer cancel()
+	return s.table.get(ctx, key)
+}
+
+// Set stores data for a key, applying the configured TTL
+func (s *Store) Set(key string, value []byte) error {
+	ctx, cancel := s.table.context()
+	defer cancel()
+	return s.table.put(ctx, key, value, s.ttl)
+}
+
+// Delete removes data for a key
+func (s *Store) Delete(key string) error {
+	ctx, cancel := s.table.context()
+	defer cancel()
+	return s.table.delete(ctx, key)
+}
+
+// List returns all keys, scanning every page. Returns nil on scan errors.
+func (s *Store) List() []string {
+	ctx, cancel := s.table.context()
+	defer cancel()
+
+	keys, err := s.table.keys(ctx)
+	if err != nil {
+		return nil
+	}
+	return keys
+}
+
+// ListPage returns up to limit keys starting after cursor, and the cursor for the next page.
+//
+// An empty returned cursor means there are no more pages. DynamoDB applies the
+// limit before filtering expired items, so pages may contain fewer keys.
+//
+// Example:
+//
+//	cursor := ""
+//	for {
+//		keys, next, err := store.ListPage(ctx, 100, cursor)
+//		...
+//		if next == "" {
+//			break
+//		}
+//		cursor = next
+//	}
+func (s *Store) ListPage(ctx context.Context, limit int32, cursor string) ([]string, string, error) {
+	return s.table.page(ctx, limit, cursor)
+}
+
+// Exists checks if a key exists
+func (s *Store) Exists(key string) bool {
+	data, err := s.Get(key)
+	return err == nil && data != nil
+}
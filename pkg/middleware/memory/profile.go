@@ -0,0 +1,273 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// profilePrefix separates profiles from conversations sharing a store under the same key
+const profilePrefix = "profile:"
+
+// ProfileMemory keeps durable user attributes and preferences across sessions.
+//
+// Unlike conversation memory, a profile is not a log of turns but a set of
+// facts about the user ("language": "Go", "units": "metric") that outlives
+// any single conversation. Writes merge into the stored profile, so
+// independent handlers can each record what they learn.
+//
+// Example:
+//
+//	profiles := memory.Profile(store)
+//	profiles.Update(ctx, "user123", map[string]any{"name": "Ada", "preferences": map[string]any{"tone": "concise"}})
+//	flow.Use(profiles.Inject("user123")).Use(ai.Agent(client))
+type ProfileMemory struct {
+	store Store
+	mu    sync.Mutex // Serializes read-merge-write within this process
+}
+
+// profileData is the stored representation of a profile
+type profileData struct {
+	Attributes map[string]any `json:"attributes"`
+	Updated    time.Time      `json:"updated"`
+}
+
+// Profile creates a profile memory backed by store.
+//
+// Input: Store implementation (nil uses an in-memory store)
+// Output: *ProfileMemory
+// Behavior: Profiles are stored under "profile:<key>", so the store can be
+// shared with conversation memory
+//
+// Example:
+//
+//	store, _ := sqlitestore.New(&sqlitestore.Config{Path: "calque.db"})
+//	profiles := memory.Profile(store)
+func Profile(store Store) *ProfileMemory {
+	if store == nil {
+		store = NewInMemoryStore()
+	}
+	return &ProfileMemory{store: store}
+}
+
+// Get returns the stored profile attributes for key, or an empty map when there are none.
+//
+// Example:
+//
+//	attrs, err := profiles.Get(ctx, "user123")
+//	lang, _ := attrs["language"].(string)
+func (pm *ProfileMemory) Get(ctx context.Context, key string) (map[string]any, error) {
+	data, err := pm.load(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return data.Attributes, nil
+}
+
+// Update merges attributes into the stored profile and returns the result.
+//
+// Input: profile key and attributes to merge
+// Output: the merged profile
+// Behavior: Nested maps are merged key by key; nil values remove attributes
+//
+// Merging is serialized within a ProfileMemory; writers in other processes
+// sharing the store can still overwrite each other's concurrent changes.
+//
+// Example:
+//
+//	profiles.Update(ctx, "user123", map[string]any{
+//	    "preferences": map[string]any{"units": "metric"}, // keeps other preferences
+//	    "nickname":    nil,                               // forgets the nickname
+//	})
+func (pm *ProfileMemory) Update(ctx context.Context, key string, attributes map[string]any) (map[string]any, error) {
+	if key == "" {
+		return nil, calque.NewErr(ctx, "profile key is required")
+	}
+	// Round-trip through JSON so merged values have the same types as stored ones
+	patch, err := normalizeAttributes(attributes)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to encode profile attributes")
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	data, err := pm.load(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	data.Attributes = mergeAttributes(data.Attributes, patch)
+	data.Updated = time.Now()
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to marshal profile")
+	}
+	if err := pm.store.Set(profilePrefix+key, encoded); err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to save profile %s", key))
+	}
+	return data.Attributes, nil
+}
+
+// Clear deletes the profile for key.
+//
+// Example:
+//
+//	err := profiles.Clear("user123") // e.g. on account deletion
+func (pm *ProfileMemory) Clear(key string) error {
+	return pm.store.Delete(profilePrefix + key)
+}
+
+// ListKeys returns the keys of all stored profiles.
+func (pm *ProfileMemory) ListKeys() []string {
+	var keys []string
+	for _, key := range pm.store.List() {
+		if profile, ok := strings.CutPrefix(key, profilePrefix); ok {
+			keys = append(keys, profile)
+		}
+	}
+	return keys
+}
+
+// Inject creates a middleware that prepends the user's profile to the input.
+//
+// Input: prompt text
+// Output: profile block followed by the prompt; the prompt unchanged when there is no profile
+// Behavior: BUFFERED - reads the whole input
+//
+// Attributes are listed one per line in key order, with nested keys joined
+// by dots:
+//
+//	User profile:
+//	- name: Ada
+//	- preferences.tone: concise
+//
+// Example:
+//
+//	flow.Use(profiles.Inject("user123")).Use(convMem.Input("user123")).Use(ai.Agent(client))
+func (pm *ProfileMemory) Inject(key string) calque.Handler {
+	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		var input string
+		if err := calque.Read(r, &input); err != nil {
+			return calque.WrapErr(r.Context, err, "failed to read input")
+		}
+
+		attributes, err := pm.Get(r.Context, key)
+		if err != nil {
+			return err
+		}
+		if len(attributes) == 0 {
+			return calque.Write(w, input)
+		}
+		return calque.Write(w, FormatProfile(attributes)+"\n\n"+input)
+	})
+}
+
+// InjectFromContext creates an Inject middleware that uses the memory key from context.
+//
+// Input: prompt text (requires memory key in context, see GetKey)
+// Output: profile block followed by the prompt
+// Behavior: BUFFERED - reads the whole input
+//
+// Example:
+//
+//	ctx := calque.WithUser(ctx, calque.User{ID: "user123"})
+//	flow.Use(profiles.InjectFromContext()).Use(ai.Agent(client))
+func (pm *ProfileMemory) InjectFromContext() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		key := GetKey(req.Context)
+		if key == "" {
+			return calque.NewErr(req.Context, "no memory key found in context for profile injection")
+		}
+		return pm.Inject(key).ServeFlow(req, res)
+	})
+}
+
+// FormatProfile renders profile attributes as the block Inject prepends to prompts
+func FormatProfile(attributes map[string]any) string {
+	lines := []string{"User profile:"}
+	var walk func(prefix string, attrs map[string]any)
+	walk = func(prefix string, attrs map[string]any) {
+		for _, name := range slices.Sorted(maps.Keys(attrs)) {
+			value := attrs[name]
+			if nested, ok := value.(map[string]any); ok {
+				walk(prefix+name+".", nested)
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("- %s%s: %s", prefix, name, formatAttribute(value)))
+		}
+	}
+	walk("", attributes)
+	return strings.Join(lines, "\n")
+}
+
+func formatAttribute(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// load reads the stored profile, returning an empty one when none exists
+func (pm *ProfileMemory) load(ctx context.Context, key string) (*profileData, error) {
+	raw, err := pm.store.Get(profilePrefix + key)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to load profile %s", key))
+	}
+	data := &profileData{}
+	if raw != nil {
+		if err := json.Unmarshal(raw, data); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to unmarshal profile")
+		}
+	}
+	if data.Attributes == nil {
+		data.Attributes = map[string]any{}
+	}
+	return data, nil
+}
+
+func normalizeAttributes(attributes map[string]any) (map[string]any, error) {
+	encoded, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, err
+	}
+	var normalized map[string]any
+	err = json.Unmarshal(encoded, &normalized)
+	return normalized, err
+}
+
+// mergeAttributes merges patch into base in place, recursing into nested maps
+func mergeAttributes(base, patch map[string]any) map[string]any {
+	for name, value := range patch {
+		if value == nil {
+			delete(base, name)
+			continue
+		}
+		incoming, isMap := value.(map[string]any)
+		existing, hadMap := base[name].(map[string]any)
+		if isMap && hadMap {
+			base[name] = mergeAttributes(existing, incoming)
+			if len(base[name].(map[string]any)) == 0 {
+				delete(base, name)
+			}
+			continue
+		}
+		if isMap {
+			// Nil values inside a new map have nothing to remove
+			value = mergeAttributes(map[string]any{}, incoming)
+		}
+		base[name] = value
+	}
+	return base
+}
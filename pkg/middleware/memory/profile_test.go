@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestProfileUpdateMerges(t *testing.T) {
+	ctx := context.Background()
+	profiles := Profile(nil)
+
+	_, err := profiles.Update(ctx, "ada", map[string]any{
+		"name":        "Ada",
+		"nickname":    "A",
+		"preferences": map[string]any{"tone": "concise", "units": "metric"},
+		"visits":      3,
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	merged, err := profiles.Update(ctx, "ada", map[string]any{
+		"nickname":    nil,
+		"preferences": map[string]any{"units": "imperial", "tone": nil, "theme": map[string]any{"dark": true, "accent": nil}},
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	want := map[string]any{
+		"name":        "Ada",
+		"visits":      float64(3),
+		"preferences": map[string]any{"units": "imperial", "theme": map[string]any{"dark": true}},
+	}
+	if !sameJSON(t, merged, want) {
+		t.Errorf("merged = %v, want %v", merged, want)
+	}
+
+	stored, _ := profiles.Get(ctx, "ada")
+	if !sameJSON(t, stored, want) {
+		t.Errorf("Get() = %v, want %v", stored, want)
+	}
+
+	// Removing the last nested attribute removes the empty map
+	merged, _ = profiles.Update(ctx, "ada", map[string]any{"preferences": map[string]any{"units": nil, "theme": nil}})
+	if _, ok := merged["preferences"]; ok {
+		t.Errorf("empty preferences should be removed, got %v", merged)
+	}
+}
+
+func TestProfileIsolation(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	profiles := Profile(store)
+	conversations := NewConversationWithStore(store)
+
+	_ = store.Set("ada", []byte(`{"messages":[]}`)) // a conversation under the same key
+	_, _ = profiles.Update(ctx, "ada", map[string]any{"name": "Ada"})
+	_, _ = profiles.Update(ctx, "grace", map[string]any{"name": "Grace"})
+
+	if messages, err := conversations.Messages(ctx, "ada"); err != nil || len(messages) != 0 {
+		t.Errorf("conversation clobbered by profile: %v, %v", messages, err)
+	}
+	keys := profiles.ListKeys()
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"ada", "grace"}) {
+		t.Errorf("ListKeys() = %v", keys)
+	}
+
+	if err := profiles.Clear("ada"); err != nil {
+		t.Fatal(err)
+	}
+	if attrs, _ := profiles.Get(ctx, "ada"); len(attrs) != 0 {
+		t.Errorf("Get() after Clear = %v", attrs)
+	}
+	if !store.Exists("ada") {
+		t.Error("Clear() removed the conversation")
+	}
+
+	if _, err := profiles.Update(ctx, "", map[string]any{"a": 1}); err == nil {
+		t.Error("Update() without a key should fail")
+	}
+}
+
+func TestProfileConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	profiles := Profile(nil)
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = profiles.Update(ctx, "u", map[string]any{name: true})
+		}()
+	}
+	wg.Wait()
+
+	if attrs, _ := profiles.Get(ctx, "u"); len(attrs) != 8 {
+		t.Errorf("lost concurrent updates: %v", attrs)
+	}
+}
+
+func TestProfileInject(t *testing.T) {
+	ctx := context.Background()
+	profiles := Profile(nil)
+	_, _ = profiles.Update(ctx, "ada", map[string]any{
+		"name":        "Ada",
+		"languages":   []string{"Go", "SQL"},
+		"preferences": map[string]any{"tone": "concise"},
+	})
+
+	var output string
+	if err := calque.NewFlow().Use(profiles.Inject("ada")).Run(ctx, "Explain channels", &output); err != nil {
+		t.Fatal(err)
+	}
+	want := "User profile:\n- languages: [\"Go\",\"SQL\"]\n- name: Ada\n- preferences.tone: concise\n\nExplain channels"
+	if output != want {
+		t.Errorf("Inject() =\n%s\nwant\n%s", output, want)
+	}
+
+	if err := calque.NewFlow().Use(profiles.Inject("stranger")).Run(ctx, "hi", &output); err != nil || output != "hi" {
+		t.Errorf("Inject() without profile = %q, %v; want input unchanged", output, err)
+	}
+
+	userCtx := calque.WithUser(ctx, calque.User{ID: "ada"})
+	if err := calque.NewFlow().Use(profiles.InjectFromContext()).Run(userCtx, "hi", &output); err != nil || !strings.HasPrefix(output, "User profile:") {
+		t.Errorf("InjectFromContext() = %q, %v", output, err)
+	}
+	if err := calque.NewFlow().Use(profiles.InjectFromContext()).Run(ctx, "hi", &output); err == nil {
+		t.Error("InjectFromContext() without a key should fail")
+	}
+}
+
+func sameJSON(t *testing.T, a, b any) bool {
+	t.Helper()
+	ja, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(ja) == string(jb)
+}
@@ -0,0 +1,89 @@
+package sqlitestore
+
+import (
+	"context"
+	"time"
+)
+
+// CacheStore implements cache.Store on top of a SQLite table.
+//
+// Expired rows are hidden from reads immediately; call Purge periodically to
+// reclaim their space.
+//
+// Example:
+//
+//	store, err := sqlitestore.NewCache(&sqlitestore.Config{DB: db})
+//	responses := cache.NewCacheWithStore(store)
+type CacheStore struct {
+	table *kvTable
+}
+
+// NewCache creates a SQLite backed cache store, creating its table if needed.
+func NewCache(config *Config) (*CacheStore, error) {
+	t, err := newKVTable(config, DefaultCacheTable)
+	if err != nil {
+		return nil, err
+	}
+	return &CacheStore{table: t}, nil
+}
+
+// Get retrieves data for a key, returns nil if not found or expired
+func (c *CacheStore) Get(key string) ([]byte, error) {
+	ctx, cancel := c.table.context()
+	defer cancel()
+	return c.table.get(ctx, key)
+}
+
+// Set stores data for a key with TTL (0 = never expires)
+func (c *CacheStore) Set(key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := c.table.context()
+	defer cancel()
+	return c.table.put(ctx, key, value, ttl)
+}
+
+// Delete removes data for a key
+func (c *CacheStore) Delete(key string) error {
+	ctx, cancel := c.table.context()
+	defer cancel()
+	return c.table.delete(ctx, key)
+}
+
+// Clear removes all cached data
+func (c *CacheStore) Clear() error {
+	ctx, cancel := c.table.context()
+	defer cancel()
+	return c.table.clear(ctx)
+}
+
+// Exists checks if a key exists and hasn't expired
+func (c *CacheStore) Exists(key string) bool {
+	data, err := c.Get(key)
+	return err == nil && data != nil
+}
+
+// List returns all non-expired keys. Returns nil on query errors.
+func (c *CacheStore) List() []string {
+	ctx, cancel := c.table.context()
+	defer cancel()
+
+	keys, _, err := c.table.page(ctx, 0, "")
+	if err != nil {
+		return nil
+	}
+	return keys
+}
+
+// ListPage returns up to limit non-expired keys ordered after cursor, and the next cursor.
+func (c *CacheStore) ListPage(ctx context.Context, limit int32, cursor string) ([]string, string, error) {
+	return c.table.page(ctx, limit, cursor)
+}
+
+// Purge deletes expired entries and returns the number removed
+func (c *CacheStore) Purge(ctx context.Context) (int64, error) {
+	return c.table.purge(ctx)
+}
+
+// Close closes the database if the store opened it from Config.Path
+func (c *CacheStore) Close() error {
+	return c.table.close()
+}
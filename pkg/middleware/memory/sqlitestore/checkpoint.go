@@ -0,0 +1,178 @@
+package sqlitestore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+const checkpointSchema = `
+CREATE TABLE IF NOT EXISTS {table} (
+	run_id     TEXT NOT NULL,
+	seq        INTEGER NOT NULL,
+	step       TEXT NOT NULL,
+	state      BLOB NOT NULL,
+	created_at INTEGER NOT NULL,
+	PRIMARY KEY (run_id, seq)
+);`
+
+// Checkpoint is the state recorded after a workflow step completed.
+type Checkpoint struct {
+	RunID    string    `json:"run_id"`
+	Sequence int64     `json:"sequence"` // Increases by one per checkpoint within a run
+	Step     string    `json:"step"`
+	State    []byte    `json:"state"`
+	Created  time.Time `json:"created"`
+}
+
+// CheckpointStore persists workflow checkpoints so interrupted runs can resume
+// from their last completed step.
+//
+// Example:
+//
+//	checkpoints, _ := sqlitestore.NewCheckpoints(&sqlitestore.Config{DB: db})
+//
+//	if cp, _ := checkpoints.Latest(ctx, runID); cp != nil {
+//		// resume from cp.Step with cp.State
+//	}
+//	err := checkpoints.Save(ctx, runID, "summarized", state)
+type CheckpointStore struct {
+	db *database
+}
+
+// NewCheckpoints creates a SQLite backed checkpoint store, creating its table if needed.
+func NewCheckpoints(config *Config) (*CheckpointStore, error) {
+	d, err := newDatabase(config, DefaultCheckpointTable, checkpointSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointStore{db: d}, nil
+}
+
+// Save appends a checkpoint for runID and returns its sequence number
+func (c *CheckpointStore) Save(ctx context.Context, runID, step string, state []byte) (int64, error) {
+	if runID == "" {
+		return 0, calque.NewErr(ctx, "checkpoint run ID is required")
+	}
+	if state == nil {
+		state = []byte{}
+	}
+
+	var seq int64
+	err := c.db.db.QueryRowContext(ctx, c.db.query(`
+		INSERT INTO {table} (run_id, seq, step, state, created_at)
+		VALUES (?, (SELECT COALESCE(MAX(seq), 0) + 1 FROM {table} WHERE run_id = ?), ?, ?, ?)
+		RETURNING seq`),
+		runID, runID, step, state, time.Now().UnixMilli(),
+	).Scan(&seq)
+	if err != nil {
+		return 0, calque.WrapErr(ctx, err, fmt.Sprintf("failed to save checkpoint for run %s", runID))
+	}
+	return seq, nil
+}
+
+// Latest returns the most recent checkpoint for runID, or nil if there is none
+func (c *CheckpointStore) Latest(ctx context.Context, runID string) (*Checkpoint, error) {
+	row := c.db.db.QueryRowContext(ctx, c.db.query(`
+		SELECT run_id, seq, step, state, created_at FROM {table}
+		WHERE run_id = ? ORDER BY seq DESC LIMIT 1`), runID)
+
+	cp, err := scanCheckpoint(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to load checkpoint for run %s", runID))
+	}
+	return cp, nil
+}
+
+// History returns all checkpoints for runID, oldest first
+func (c *CheckpointStore) History(ctx context.Context, runID string) ([]Checkpoint, error) {
+	rows, err := c.db.db.QueryContext(ctx, c.db.query(`
+		SELECT run_id, seq, step, state, created_at FROM {table}
+		WHERE run_id = ? ORDER BY seq`), runID)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to load checkpoints for run %s", runID))
+	}
+	defer rows.Close()
+
+	var history []Checkpoint
+	for rows.Next() {
+		cp, err := scanCheckpoint(rows)
+		if err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to scan checkpoint")
+		}
+		history = append(history, *cp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, calque.WrapErr(ctx, err, "error iterating checkpoints")
+	}
+	return history, nil
+}
+
+// Delete removes all checkpoints for runID, typically once the run has finished
+func (c *CheckpointStore) Delete(ctx context.Context, runID string) error {
+	if _, err := c.db.db.ExecContext(ctx, c.db.query(`DELETE FROM {table} WHERE run_id = ?`), runID); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to delete checkpoints for run %s", runID))
+	}
+	return nil
+}
+
+// Step returns a pass-through handler that checkpoints the data flowing through it.
+//
+// Input: any data
+// Output: the same data, unchanged
+// Behavior: BUFFERED - reads the full input, saves it as a checkpoint, then writes it out
+//
+// The run is identified by calque.RequestID, falling back to calque.TraceID. Handlers
+// without either ID in the context pass data through without saving.
+//
+// Example:
+//
+//	flow := calque.NewFlow().
+//		Use(retrieve).
+//		Use(checkpoints.Step("retrieved")).
+//		Use(ai.Agent(client))
+func (c *CheckpointStore) Step(step string) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		data, err := io.ReadAll(req.Data)
+		if err != nil {
+			return calque.WrapErr(req.Context, err, "failed to read checkpoint input")
+		}
+
+		runID := calque.RequestID(req.Context)
+		if runID == "" {
+			runID = calque.TraceID(req.Context)
+		}
+		if runID != "" {
+			if _, err := c.Save(req.Context, runID, step, data); err != nil {
+				return err
+			}
+		}
+
+		_, err = io.Copy(res.Data, bytes.NewReader(data))
+		return err
+	})
+}
+
+// Close closes the database if the store opened it from Config.Path
+func (c *CheckpointStore) Close() error {
+	return c.db.close()
+}
+
+func scanCheckpoint(row interface{ Scan(...any) error }) (*Checkpoint, error) {
+	var cp Checkpoint
+	var created int64
+	if err := row.Scan(&cp.RunID, &cp.Sequence, &cp.Step, &cp.State, &created); err != nil {
+		return nil, err
+	}
+	cp.Created = time.UnixMilli(created)
+	return &cp, nil
+}
@@ -0,0 +1,99 @@
+package sqlitestore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+func TestCheckpointStore_SaveAndLoad(t *testing.T) {
+	ctx := context.Background()
+	checkpoints, err := NewCheckpoints(openTestDB(t))
+	if err != nil {
+		t.Fatalf("NewCheckpoints() error = %v", err)
+	}
+
+	if cp, err := checkpoints.Latest(ctx, "run-1"); err != nil || cp != nil {
+		t.Errorf("Latest() on empty run = %v, %v", cp, err)
+	}
+
+	for i, step := range []string{"fetched", "summarized"} {
+		seq, err := checkpoints.Save(ctx, "run-1", step, []byte(step+" state"))
+		if err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		if seq != int64(i+1) {
+			t.Errorf("Save() sequence = %d, want %d", seq, i+1)
+		}
+	}
+	_, _ = checkpoints.Save(ctx, "run-2", "fetched", nil)
+
+	latest, err := checkpoints.Latest(ctx, "run-1")
+	if err != nil || latest == nil {
+		t.Fatalf("Latest() = %v, %v", latest, err)
+	}
+	if latest.Step != "summarized" || string(latest.State) != "summarized state" || latest.Sequence != 2 {
+		t.Errorf("unexpected latest checkpoint %+v", latest)
+	}
+	if latest.Created.IsZero() {
+		t.Error("expected creation time")
+	}
+
+	history, err := checkpoints.History(ctx, "run-1")
+	if err != nil || len(history) != 2 || history[0].Step != "fetched" {
+		t.Errorf("History() = %+v, %v", history, err)
+	}
+
+	if err := checkpoints.Delete(ctx, "run-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if cp, _ := checkpoints.Latest(ctx, "run-1"); cp != nil {
+		t.Error("expected run-1 checkpoints to be deleted")
+	}
+	if cp, _ := checkpoints.Latest(ctx, "run-2"); cp == nil {
+		t.Error("run-2 checkpoints must be kept")
+	}
+
+	if _, err := checkpoints.Save(ctx, "", "step", nil); err == nil {
+		t.Error("expected error for empty run ID")
+	}
+}
+
+func TestCheckpointStore_Step(t *testing.T) {
+	checkpoints, _ := NewCheckpoints(openTestDB(t))
+
+	flow := calque.NewFlow().
+		Use(ctrl.PassThrough()).
+		Use(checkpoints.Step("input"))
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		wantSaved bool
+	}{
+		{"with request ID", calque.WithRequestID(context.Background(), "req-1"), true},
+		{"without run ID", context.Background(), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out string
+			if err := flow.Run(tt.ctx, "payload", &out); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if out != "payload" {
+				t.Errorf("output = %q, want payload", out)
+			}
+
+			cp, _ := checkpoints.Latest(context.Background(), calque.RequestID(tt.ctx))
+			if saved := cp != nil; saved != tt.wantSaved {
+				t.Fatalf("checkpoint saved = %v, want %v", saved, tt.wantSaved)
+			}
+			if cp != nil && (cp.Step != "input" || string(cp.State) != "payload") {
+				t.Errorf("unexpected checkpoint %+v", cp)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+const kvSchema = `
+CREATE TABLE IF NOT EXISTS {table} (
+	key        TEXT PRIMARY KEY,
+	value      BLOB NOT NULL,
+	expires_at INTEGER
+);
+CREATE INDEX IF NOT EXISTS {table}_expires_idx ON {table} (expires_at);`
+
+// kvTable implements the key/value operations shared by Store and CacheStore.
+// Expiry is stored as unix milliseconds; NULL means the entry never expires.
+type kvTable struct {
+	*database
+}
+
+func newKVTable(config *Config, defaultTable string) (*kvTable, error) {
+	d, err := newDatabase(config, defaultTable, kvSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &kvTable{database: d}, nil
+}
+
+// get returns the stored value, or nil if the key is missing or expired
+func (t *kvTable) get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := t.db.QueryRowContext(ctx,
+		t.query(`SELECT value FROM {table} WHERE key = ? AND (expires_at IS NULL OR expires_at > ?)`),
+		key, time.Now().UnixMilli(),
+	).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to get key %s", key))
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, nil
+}
+
+func (t *kvTable) put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	var expires any
+	if ttl > 0 {
+		expires = time.Now().Add(ttl).UnixMilli()
+	}
+	if value == nil {
+		value = []byte{}
+	}
+
+	_, err := t.db.ExecContext(ctx, t.query(`
+		INSERT INTO {table} (key, value, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at`),
+		key, value, expires,
+	)
+	if err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to set key %s", key))
+	}
+	return nil
+}
+
+func (t *kvTable) delete(ctx context.Context, key string) error {
+	if _, err := t.db.ExecContext(ctx, t.query(`DELETE FROM {table} WHERE key = ?`), key); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to delete key %s", key))
+	}
+	return nil
+}
+
+func (t *kvTable) clear(ctx context.Context) error {
+	if _, err := t.db.ExecContext(ctx, t.query(`DELETE FROM {table}`)); err != nil {
+		return calque.WrapErr(ctx, err, "failed to clear table")
+	}
+	return nil
+}
+
+// purge deletes expired rows and returns how many were removed
+func (t *kvTable) purge(ctx context.Context) (int64, error) {
+	res, err := t.db.ExecContext(ctx,
+		t.query(`DELETE FROM {table} WHERE expires_at IS NOT NULL AND expires_at <= ?`),
+		time.Now().UnixMilli(),
+	)
+	if err != nil {
+		return 0, calque.WrapErr(ctx, err, "failed to purge expired keys")
+	}
+	return res.RowsAffected()
+}
+
+// page returns up to limit live keys ordered after cursor (limit <= 0 = all)
+func (t *kvTable) page(ctx context.Context, limit int32, cursor string) ([]string, string, error) {
+	sqlLimit := int64(-1)
+	if limit > 0 {
+		sqlLimit = int64(limit)
+	}
+
+	rows, err := t.db.QueryContext(ctx, t.query(`
+		SELECT key FROM {table}
+		WHERE key > ? AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY key LIMIT ?`),
+		cursor, time.Now().UnixMilli(), sqlLimit,
+	)
+	if err != nil {
+		return nil, "", calque.WrapErr(ctx, err, "failed to list keys")
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, "", calque.WrapErr(ctx, err, "failed to scan key")
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", calque.WrapErr(ctx, err, "error iterating keys")
+	}
+
+	var next string
+	if limit > 0 && len(keys) == int(limit) {
+		next = keys[len(keys)-1]
+	}
+	return keys, next, nil
+}
@@ -0,0 +1,161 @@
+// Package sqlitestore provides SQLite backed stores for memory, cache and workflow checkpoints.
+//
+// It uses a pure Go SQLite driver, so single-binary deployments get durable state
+// without cgo or external services. Store implements memory.Store, CacheStore
+// implements cache.Store and CheckpointStore records workflow progress per run.
+// All stores can share one database file opened with Open; each keeps its own table.
+// The retrieval/sqlitevec package adds a vector store on the same database.
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" database/sql driver
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Defaults for table names and timeouts
+const (
+	DefaultMemoryTable     = "calque_memory"
+	DefaultCacheTable      = "calque_cache"
+	DefaultCheckpointTable = "calque_checkpoints"
+	DefaultTimeout         = 10 * time.Second
+	DefaultBusyTimeout     = 5 * time.Second
+)
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Config holds SQLite store configuration.
+type Config struct {
+	// Shared database handle, typically from Open. Takes precedence over Path.
+	DB *sql.DB
+
+	// Database file path, opened with Open when DB is nil (":memory:" for tests)
+	Path string
+
+	// Table name (defaults per store: calque_memory, calque_cache, calque_checkpoints)
+	Table string
+
+	// TTL applied to every memory.Store write (0 = entries never expire).
+	// CacheStore uses the TTL passed to Set instead.
+	TTL time.Duration
+
+	// Timeout for each database call (default: 10s)
+	Timeout time.Duration
+}
+
+// Open opens a SQLite database tuned for embedded use.
+//
+// The database uses WAL journaling and a busy timeout, and is limited to a single
+// connection so concurrent writers queue instead of failing with SQLITE_BUSY.
+// Pass the returned handle to several stores to keep all state in one file.
+//
+// Example:
+//
+//	db, err := sqlitestore.Open("calque.db")
+//	if err != nil {
+//		return err
+//	}
+//	defer db.Close()
+//
+//	conversations, _ := sqlitestore.New(&sqlitestore.Config{DB: db})
+//	responses, _ := sqlitestore.NewCache(&sqlitestore.Config{DB: db})
+func Open(path string) (*sql.DB, error) {
+	ctx := context.Background()
+	if path == "" {
+		return nil, calque.NewErr(ctx, "SQLite database path is required")
+	}
+
+	dsn := path
+	if path != ":memory:" {
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		dsn = fmt.Sprintf("%s%s_pragma=journal_mode(WAL)&_pragma=busy_timeout(%d)", path, sep, DefaultBusyTimeout.Milliseconds())
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to open SQLite database")
+	}
+	// A single connection serialises writes and keeps ":memory:" databases shared
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, calque.WrapErr(ctx, err, "failed to connect to SQLite database")
+	}
+	return db, nil
+}
+
+// ValidIdentifier reports whether name is safe to use as a table name.
+//
+// Table names are interpolated into SQL, so stores only accept plain identifiers.
+func ValidIdentifier(name string) bool {
+	return identifierPattern.MatchString(name)
+}
+
+// database holds the connection settings shared by every store
+type database struct {
+	db      *sql.DB
+	owned   bool // db was opened by the store and is closed with it
+	table   string
+	timeout time.Duration
+}
+
+func newDatabase(config *Config, defaultTable, schema string) (*database, error) {
+	ctx := context.Background()
+	if config == nil || (config.DB == nil && config.Path == "") {
+		return nil, calque.NewErr(ctx, "SQLite DB or Path is required")
+	}
+
+	d := &database{db: config.DB, table: config.Table, timeout: config.Timeout}
+	if d.table == "" {
+		d.table = defaultTable
+	}
+	if !ValidIdentifier(d.table) {
+		return nil, calque.NewErr(ctx, fmt.Sprintf("invalid SQLite table name %q", d.table))
+	}
+	if d.timeout <= 0 {
+		d.timeout = DefaultTimeout
+	}
+
+	if d.db == nil {
+		db, err := Open(config.Path)
+		if err != nil {
+			return nil, err
+		}
+		d.db, d.owned = db, true
+	}
+
+	ctx, cancel := d.context()
+	defer cancel()
+	if _, err := d.db.ExecContext(ctx, strings.ReplaceAll(schema, "{table}", d.table)); err != nil {
+		d.close()
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to create table %s", d.table))
+	}
+	return d, nil
+}
+
+func (d *database) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), d.timeout)
+}
+
+// query substitutes the table name into a statement
+func (d *database) query(statement string) string {
+	return strings.ReplaceAll(statement, "{table}", d.table)
+}
+
+func (d *database) close() error {
+	if d.owned {
+		return d.db.Close()
+	}
+	return nil
+}
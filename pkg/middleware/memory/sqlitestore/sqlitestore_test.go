@@ -0,0 +1,229 @@
+package sqlitestore
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/cache"
+	"github.com/calque-ai/go-calque/pkg/middleware/memory"
+)
+
+var (
+	_ memory.Store = (*Store)(nil)
+	_ cache.Store  = (*CacheStore)(nil)
+)
+
+func openTestDB(t *testing.T) *Config {
+	t.Helper()
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &Config{DB: db}
+}
+
+func TestNew_Validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{"nil config", nil},
+		{"missing db and path", &Config{}},
+		{"invalid table", &Config{Path: ":memory:", Table: "memory; DROP TABLE x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestStore_CRUD(t *testing.T) {
+	store, err := New(openTestDB(t))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if data, err := store.Get("missing"); err != nil || data != nil {
+		t.Errorf("Get(missing) = %v, %v; want nil, nil", data, err)
+	}
+
+	if err := store.Set("conv-1", []byte("v1")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Set("conv-1", []byte("v2")); err != nil {
+		t.Fatalf("Set() overwrite error = %v", err)
+	}
+	if data, _ := store.Get("conv-1"); string(data) != "v2" {
+		t.Errorf("Get() = %q, want v2", data)
+	}
+
+	// Empty values still exist
+	_ = store.Set("empty", nil)
+	if !store.Exists("empty") {
+		t.Error("expected empty value to exist")
+	}
+
+	if keys := store.List(); !slices.Equal(keys, []string{"conv-1", "empty"}) {
+		t.Errorf("List() = %v", keys)
+	}
+
+	if err := store.Delete("conv-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if store.Exists("conv-1") {
+		t.Error("expected key to be deleted")
+	}
+}
+
+func TestStore_PersistsAcrossOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calque.db")
+
+	store, err := New(&Config{Path: path})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := store.Set("k", []byte("durable")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	reopened, err := New(&Config{Path: path})
+	if err != nil {
+		t.Fatalf("New() reopen error = %v", err)
+	}
+	defer reopened.Close()
+
+	if data, _ := reopened.Get("k"); string(data) != "durable" {
+		t.Errorf("Get() after reopen = %q", data)
+	}
+}
+
+func TestStore_WithConversationMemory(t *testing.T) {
+	store, _ := New(openTestDB(t))
+	conv := memory.NewConversationWithStore(store)
+
+	var out string
+	if err := calque.NewFlow().Use(conv.Input("user-1")).Run(context.Background(), "hello", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	count, exists, err := conv.Info(context.Background(), "user-1")
+	if err != nil || !exists || count != 1 {
+		t.Errorf("Info() = %d, %v, %v", count, exists, err)
+	}
+}
+
+func TestStore_ListPage(t *testing.T) {
+	store, _ := New(openTestDB(t))
+	for _, k := range []string{"e", "a", "c", "b", "d"} {
+		_ = store.Set(k, []byte(k))
+	}
+
+	var pages [][]string
+	cursor := ""
+	for {
+		keys, next, err := store.ListPage(context.Background(), 2, cursor)
+		if err != nil {
+			t.Fatalf("ListPage() error = %v", err)
+		}
+		pages = append(pages, keys)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if len(pages) != 3 || !slices.Equal(pages[0], []string{"a", "b"}) || !slices.Equal(pages[2], []string{"e"}) {
+		t.Errorf("unexpected pages %v", pages)
+	}
+}
+
+func TestCacheStore_TTL(t *testing.T) {
+	config := openTestDB(t)
+	store, err := NewCache(config)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+
+	_ = store.Set("fresh", []byte("data"), time.Hour)
+	_ = store.Set("forever", []byte("data"), 0)
+	_ = store.Set("stale", []byte("old"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if data, _ := store.Get("stale"); data != nil {
+		t.Errorf("Get(stale) = %q, want nil", data)
+	}
+	if keys := store.List(); !slices.Equal(keys, []string{"forever", "fresh"}) {
+		t.Errorf("List() = %v", keys)
+	}
+
+	purged, err := store.Purge(context.Background())
+	if err != nil || purged != 1 {
+		t.Errorf("Purge() = %d, %v; want 1", purged, err)
+	}
+
+	if err := store.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if keys := store.List(); len(keys) != 0 {
+		t.Errorf("expected empty cache after Clear, got %v", keys)
+	}
+}
+
+func TestStores_ShareDatabase(t *testing.T) {
+	config := openTestDB(t)
+	mem, _ := New(config)
+	c, _ := NewCache(config)
+
+	_ = mem.Set("k", []byte("memory"))
+	_ = c.Set("k", []byte("cache"), 0)
+
+	if data, _ := mem.Get("k"); string(data) != "memory" {
+		t.Errorf("memory Get() = %q", data)
+	}
+	if data, _ := c.Get("k"); string(data) != "cache" {
+		t.Errorf("cache Get() = %q", data)
+	}
+
+	// Closing a store that did not open the database leaves it usable
+	_ = mem.Close()
+	if data, _ := c.Get("k"); string(data) != "cache" {
+		t.Errorf("cache Get() after memory Close = %q", data)
+	}
+}
+
+func TestStore_Concurrent(t *testing.T) {
+	store, _ := New(&Config{Path: filepath.Join(t.TempDir(), "concurrent.db")})
+	defer store.Close()
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := "k" + strconv.Itoa(i)
+			if err := store.Set(key, []byte(key)); err != nil {
+				t.Errorf("Set(%s) error = %v", key, err)
+			}
+			_, _ = store.Get(key)
+		}()
+	}
+	wg.Wait()
+
+	if keys := store.List(); len(keys) != 20 {
+		t.Errorf("List() returned %d keys, want 20", len(keys))
+	}
+}
@@ -0,0 +1,81 @@
+package sqlitestore
+
+import (
+	"context"
+	"time"
+)
+
+// Store implements memory.Store on top of a SQLite table.
+//
+// Example:
+//
+//	store, err := sqlitestore.New(&sqlitestore.Config{Path: "calque.db"})
+//	if err != nil {
+//		return err
+//	}
+//	defer store.Close()
+//	conversations := memory.NewConversationWithStore(store)
+type Store struct {
+	table *kvTable
+	ttl   time.Duration
+}
+
+// New creates a SQLite backed memory store, creating its table if needed.
+func New(config *Config) (*Store, error) {
+	t, err := newKVTable(config, DefaultMemoryTable)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{table: t, ttl: config.TTL}, nil
+}
+
+// Get retrieves data for a key, returns nil if not found
+func (s *Store) Get(key string) ([]byte, error) {
+	ctx, cancel := s.table.context()
+	defer cancel()
+	return s.table.get(ctx, key)
+}
+
+// Set stores data for a key, applying the configured TTL
+func (s *Store) Set(key string, value []byte) error {
+	ctx, cancel := s.table.context()
+	defer cancel()
+	return s.table.put(ctx, key, value, s.ttl)
+}
+
+// Delete removes data for a key
+func (s *Store) Delete(key string) error {
+	ctx, cancel := s.table.context()
+	defer cancel()
+	return s.table.delete(ctx, key)
+}
+
+// List returns all keys in key order. Returns nil on query errors.
+func (s *Store) List() []string {
+	ctx, cancel := s.table.context()
+	defer cancel()
+
+	keys, _, err := s.table.page(ctx, 0, "")
+	if err != nil {
+		return nil
+	}
+	return keys
+}
+
+// ListPage returns up to limit keys ordered after cursor, and the cursor for the next page.
+//
+// An empty returned cursor means there are no more pages.
+func (s *Store) ListPage(ctx context.Context, limit int32, cursor string) ([]string, string, error) {
+	return s.table.page(ctx, limit, cursor)
+}
+
+// Exists checks if a key exists
+func (s *Store) Exists(key string) bool {
+	data, err := s.Get(key)
+	return err == nil && data != nil
+}
+
+// Close closes the database if the store opened it from Config.Path
+func (s *Store) Close() error {
+	return s.table.close()
+}
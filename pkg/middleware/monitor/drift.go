@@ -0,0 +1,382 @@
+// Package monitor watches flow outputs for changes in behavior over time.
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/retrieval"
+)
+
+// DriftConfig configures a DriftMonitor
+type DriftConfig struct {
+	// SampleRate is the fraction of outputs embedded (default: 0.1)
+	SampleRate float64
+	// Threshold is the Shift at which a window counts as drifted (default: 0.5)
+	Threshold float64
+	// MinSamples is the number of embedded outputs a window needs to be
+	// compared; the baseline period is extended until it has them (default: 30)
+	MinSamples int
+	// MaxBytes is how much of each output is embedded (default: 8192)
+	MaxBytes int
+	// Concurrency is the number of embeddings computed at once; sampled
+	// outputs are dropped while all are busy (default: 4)
+	Concurrency int
+	// OnDrift is called when a window drifts past the threshold
+	OnDrift func(ctx context.Context, event DriftEvent)
+}
+
+// DriftEvent describes a window of outputs compared with the baseline
+type DriftEvent struct {
+	// Shift is Distance as a fraction of BaselineSpread
+	Shift float64 `json:"shift"`
+	// Distance is the cosine distance between the window and baseline centroids
+	Distance float64 `json:"distance"`
+	// BaselineSpread is the mean cosine distance of baseline outputs from their centroid
+	BaselineSpread  float64   `json:"baseline_spread"`
+	Samples         int       `json:"samples"`
+	BaselineSamples int       `json:"baseline_samples"`
+	WindowStart     time.Time `json:"window_start"`
+	WindowEnd       time.Time `json:"window_end"`
+}
+
+// DriftMonitor compares the distribution of recent outputs with a baseline
+// period. Create one with Drift.
+type DriftMonitor struct {
+	embedder retrieval.EmbeddingProvider
+	window   time.Duration
+	config   DriftConfig
+	now      func() time.Time
+	sem      chan struct{}
+	inflight sync.WaitGroup
+
+	mu       sync.Mutex
+	baseline *driftWindow
+	current  *driftWindow
+}
+
+// driftWindow sums the unit-length embeddings of one period. The centroid
+// direction and the spread around it both follow from the sum.
+type driftWindow struct {
+	sum   []float64
+	n     int
+	start time.Time
+}
+
+func (w *driftWindow) add(vector []float64) {
+	if w.sum == nil {
+		w.sum = make([]float64, len(vector))
+	}
+	for i, v := range vector {
+		w.sum[i] += v
+	}
+	w.n++
+}
+
+// spread is the mean cosine distance of the window's embeddings from their centroid
+func (w *driftWindow) spread() float64 {
+	return 1 - norm(w.sum)/float64(w.n)
+}
+
+// Drift monitors outputs passing through it for distribution shifts.
+//
+// Input: any output text (passes through unchanged)
+// Output: the same text
+// Behavior: STREAMING - copies input to output; sampled outputs are embedded in the background
+//
+// Embeddings of sampled outputs are grouped into periods of length window. The
+// first period with enough samples becomes the baseline. Each later period is
+// compared with it by the cosine distance between their centroids, scaled by
+// how spread out the baseline outputs are. A Shift of 0.5 means the typical
+// output moved half as far as baseline outputs differ from each other. Periods
+// over the threshold are logged and reported to OnDrift until ResetBaseline
+// accepts the new behavior.
+//
+// Drift is an early warning that a prompt, model or upstream data changed
+// what the flow produces, before users notice.
+//
+// Example:
+//
+//	drift := monitor.Drift(embedder, time.Hour)
+//	flow := calque.NewFlow().Use(ai.Agent(client)).Use(drift)
+func Drift(embedder retrieval.EmbeddingProvider, window time.Duration) *DriftMonitor {
+	return DriftWithConfig(embedder, window, nil)
+}
+
+// DriftWithConfig creates a drift monitor with custom sampling and alerting.
+//
+// Input: any output text (passes through unchanged)
+// Output: the same text
+// Behavior: STREAMING - copies input to output; sampled outputs are embedded in the background
+//
+// Example:
+//
+//	drift := monitor.DriftWithConfig(embedder, 24*time.Hour, &monitor.DriftConfig{
+//		SampleRate: 0.05,
+//		OnDrift: func(ctx context.Context, event monitor.DriftEvent) {
+//			pager.Notify(ctx, fmt.Sprintf("support answers drifted (shift %.2f)", event.Shift))
+//		},
+//	})
+func DriftWithConfig(embedder retrieval.EmbeddingProvider, window time.Duration, config *DriftConfig) *DriftMonitor {
+	cfg := DriftConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = 0.1
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 0.5
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = 30
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 8192
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+
+	return &DriftMonitor{
+		embedder: embedder,
+		window:   window,
+		config:   cfg,
+		now:      time.Now,
+		sem:      make(chan struct{}, cfg.Concurrency),
+	}
+}
+
+// ServeFlow implements calque.Handler
+func (m *DriftMonitor) ServeFlow(req *calque.Request, res *calque.Response) error {
+	if rand.Float64() >= m.config.SampleRate {
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	}
+
+	var sample bytes.Buffer
+	if _, err := io.Copy(res.Data, io.TeeReader(req.Data, &limitedWriter{&sample, m.config.MaxBytes})); err != nil {
+		return err
+	}
+
+	select {
+	case m.sem <- struct{}{}:
+	default:
+		calque.LogDebug(req.Context, "drift monitor busy, dropping sample")
+		return nil
+	}
+	m.inflight.Add(1)
+	go func() {
+		defer m.inflight.Done()
+		defer func() { <-m.sem }()
+		ctx := context.WithoutCancel(req.Context)
+		if err := m.Record(ctx, sample.String()); err != nil {
+			calque.LogWarn(ctx, "drift monitor failed to record output", "error", err)
+		}
+	}()
+	return nil
+}
+
+// limitedWriter keeps the first n bytes written to it and discards the rest
+type limitedWriter struct {
+	buf *bytes.Buffer
+	n   int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if room := w.n - w.buf.Len(); room > 0 {
+		w.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// Record embeds one output and adds it to the current period, comparing the
+// previous period with the baseline once it has ended.
+//
+// Use it to monitor outputs produced outside a flow. It is not sampled.
+func (m *DriftMonitor) Record(ctx context.Context, output string) error {
+	vector, err := m.embed(ctx, output)
+	if err != nil || vector == nil {
+		return err
+	}
+
+	now := m.now()
+	m.mu.Lock()
+	if m.current != nil && len(m.current.sum) != len(vector) {
+		m.mu.Unlock()
+		return calque.NewErr(ctx, fmt.Sprintf("monitor: embedding has %d dimensions, want %d", len(vector), len(m.current.sum)))
+	}
+	var event *DriftEvent
+	if m.current != nil && !now.Before(m.current.start.Add(m.window)) {
+		event = m.closeWindow()
+	}
+	if m.current == nil {
+		m.current = &driftWindow{start: now}
+	}
+	m.current.add(vector)
+	m.mu.Unlock()
+
+	if event != nil {
+		calque.LogWarn(ctx, "output drift detected", "shift", event.Shift, "distance", event.Distance,
+			"samples", event.Samples, "window_start", event.WindowStart)
+		if m.config.OnDrift != nil {
+			m.config.OnDrift(ctx, *event)
+		}
+	}
+	return nil
+}
+
+// closeWindow ends the current period, making it the baseline or comparing
+// it with the baseline. It returns an event when the period drifted.
+// Callers hold m.mu.
+func (m *DriftMonitor) closeWindow() *DriftEvent {
+	closed := m.current
+	if closed.n < m.config.MinSamples {
+		if m.baseline == nil {
+			// Keep collecting until the baseline has enough samples
+			return nil
+		}
+		m.current = nil
+		return nil
+	}
+	m.current = nil
+	if m.baseline == nil {
+		m.baseline = closed
+		return nil
+	}
+	if len(closed.sum) != len(m.baseline.sum) {
+		return nil
+	}
+
+	spread := m.baseline.spread()
+	distance := 1 - cosine(closed.sum, m.baseline.sum)
+	shift := math.Inf(1)
+	if spread > 0 {
+		shift = distance / spread
+	} else if distance <= 0 {
+		shift = 0
+	}
+	if shift < m.config.Threshold {
+		return nil
+	}
+	return &DriftEvent{
+		Shift:           shift,
+		Distance:        distance,
+		BaselineSpread:  spread,
+		Samples:         closed.n,
+		BaselineSamples: m.baseline.n,
+		WindowStart:     closed.start,
+		WindowEnd:       closed.start.Add(m.window),
+	}
+}
+
+// SetBaseline replaces the baseline with known-good outputs, such as answers
+// to an evaluation set from the current prompt and model.
+func (m *DriftMonitor) SetBaseline(ctx context.Context, outputs []string) error {
+	baseline := &driftWindow{start: m.now()}
+	for _, output := range outputs {
+		vector, err := m.embed(ctx, output)
+		if err != nil {
+			return err
+		}
+		if vector == nil {
+			continue
+		}
+		if baseline.sum != nil && len(vector) != len(baseline.sum) {
+			return calque.NewErr(ctx, fmt.Sprintf("monitor: embedding has %d dimensions, want %d", len(vector), len(baseline.sum)))
+		}
+		baseline.add(vector)
+	}
+	if baseline.n == 0 {
+		return calque.NewErr(ctx, "monitor: no baseline outputs")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.baseline = baseline
+	return nil
+}
+
+// ResetBaseline discards the baseline, so the next period with enough
+// samples becomes the new one. Call it after an intended change in behavior.
+func (m *DriftMonitor) ResetBaseline() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.baseline = nil
+	m.current = nil
+}
+
+// Baseline reports whether a baseline is set, and its size and spread
+func (m *DriftMonitor) Baseline() (samples int, spread float64, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.baseline == nil {
+		return 0, 0, false
+	}
+	return m.baseline.n, m.baseline.spread(), true
+}
+
+// Shutdown implements calque.Shutdowner, waiting for background embeddings to finish
+func (m *DriftMonitor) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		m.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// embed returns the unit-length embedding of text, or nil for blank text or a zero vector
+func (m *DriftMonitor) embed(ctx context.Context, text string) ([]float64, error) {
+	if len(bytes.TrimSpace([]byte(text))) == 0 {
+		return nil, nil
+	}
+	embedding, err := m.embedder.Embed(ctx, text)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "monitor: failed to embed output")
+	}
+	vector := make([]float64, len(embedding))
+	for i, v := range embedding {
+		vector[i] = float64(v)
+	}
+	length := norm(vector)
+	if length == 0 {
+		return nil, nil
+	}
+	for i := range vector {
+		vector[i] /= length
+	}
+	return vector, nil
+}
+
+func norm(v []float64) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += x * x
+	}
+	return math.Sqrt(sum)
+}
+
+func cosine(a, b []float64) float64 {
+	var dot float64
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	na, nb := norm(a), norm(b)
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (na * nb)
+}
@@ -0,0 +1,190 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/retrieval"
+)
+
+// letterEmbedder embeds text as counts of the letters a, b and c
+type letterEmbedder struct {
+	mu    sync.Mutex
+	texts []string
+	dims  int
+}
+
+func (e *letterEmbedder) Embed(_ context.Context, text string) (retrieval.EmbeddingVector, error) {
+	if strings.Contains(text, "fail") {
+		return nil, errors.New("embedding service unavailable")
+	}
+	e.mu.Lock()
+	e.texts = append(e.texts, text)
+	e.mu.Unlock()
+	vector := retrieval.EmbeddingVector{
+		float32(strings.Count(text, "a")),
+		float32(strings.Count(text, "b")),
+		float32(strings.Count(text, "c")),
+	}
+	if e.dims > 0 {
+		return vector[:e.dims], nil
+	}
+	return vector, nil
+}
+
+// newTestMonitor returns a drift monitor with one-hour windows whose clock the test controls
+func newTestMonitor(config *DriftConfig) (*DriftMonitor, *time.Time, *[]DriftEvent) {
+	var events []DriftEvent
+	cfg := DriftConfig{MinSamples: 10}
+	if config != nil {
+		cfg = *config
+	}
+	cfg.OnDrift = func(_ context.Context, event DriftEvent) {
+		events = append(events, event)
+	}
+	monitor := DriftWithConfig(&letterEmbedder{}, time.Hour, &cfg)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	monitor.now = func() time.Time { return now }
+	return monitor, &now, &events
+}
+
+// recordWindow records n outputs cycling through outputs, then moves the clock to the next window
+func recordWindow(t *testing.T, m *DriftMonitor, now *time.Time, n int, outputs ...string) {
+	t.Helper()
+	for i := range n {
+		if err := m.Record(context.Background(), outputs[i%len(outputs)]); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	*now = now.Add(time.Hour)
+}
+
+func TestDrift(t *testing.T) {
+	t.Parallel()
+
+	monitor, now, events := newTestMonitor(nil)
+	normal := []string{"aab", "aba", "aaa", "abc"}
+
+	recordWindow(t, monitor, now, 20, normal...)
+	if _, _, ok := monitor.Baseline(); ok {
+		t.Fatal("baseline set before its window ended")
+	}
+
+	recordWindow(t, monitor, now, 20, normal...)
+	samples, spread, ok := monitor.Baseline()
+	if !ok || samples != 20 || spread <= 0 {
+		t.Fatalf("Baseline() = %d, %v, %v", samples, spread, ok)
+	}
+
+	// A shifted window is reported once the next window starts
+	recordWindow(t, monitor, now, 20, "bbc", "cbb", "bcb")
+	if len(*events) != 0 {
+		t.Fatalf("events after a normal window = %+v", *events)
+	}
+	recordWindow(t, monitor, now, 1, "aab")
+	if len(*events) != 1 {
+		t.Fatalf("events = %d, want 1 for the shifted window", len(*events))
+	}
+	event := (*events)[0]
+	if event.Shift < 0.5 || event.Samples != 20 || event.BaselineSamples != 20 ||
+		!event.WindowEnd.Equal(event.WindowStart.Add(time.Hour)) {
+		t.Errorf("event = %+v", event)
+	}
+
+	// Accepting the new behavior makes the next full window the baseline
+	monitor.ResetBaseline()
+	recordWindow(t, monitor, now, 20, "bbc", "cbb")
+	recordWindow(t, monitor, now, 20, "bbc", "cbb")
+	recordWindow(t, monitor, now, 1, "bbc")
+	if len(*events) != 1 {
+		t.Errorf("events after reset = %d, want no new events", len(*events))
+	}
+}
+
+func TestDriftSparseWindows(t *testing.T) {
+	t.Parallel()
+
+	monitor, now, events := newTestMonitor(nil)
+
+	// The baseline keeps collecting past its window until it has MinSamples
+	recordWindow(t, monitor, now, 6, "aab")
+	recordWindow(t, monitor, now, 6, "aba")
+	if samples, _, ok := monitor.Baseline(); !ok || samples != 10 {
+		t.Fatalf("Baseline() = %d, %v; want 10 samples", samples, ok)
+	}
+
+	// Later windows below MinSamples are not compared
+	recordWindow(t, monitor, now, 5, "ccc")
+	recordWindow(t, monitor, now, 1, "aab")
+	if len(*events) != 0 {
+		t.Errorf("events = %+v, want none for a sparse window", *events)
+	}
+}
+
+func TestDriftSetBaseline(t *testing.T) {
+	t.Parallel()
+
+	monitor, now, events := newTestMonitor(nil)
+	if err := monitor.SetBaseline(context.Background(), []string{"aab", "aba", " ", "abc"}); err != nil {
+		t.Fatalf("SetBaseline() error = %v", err)
+	}
+	if samples, _, ok := monitor.Baseline(); !ok || samples != 3 {
+		t.Fatalf("Baseline() = %d, %v; want 3 samples, blank outputs skipped", samples, ok)
+	}
+
+	recordWindow(t, monitor, now, 10, "ccc")
+	recordWindow(t, monitor, now, 1, "aab")
+	if len(*events) != 1 {
+		t.Errorf("events = %d, want 1", len(*events))
+	}
+
+	if err := monitor.SetBaseline(context.Background(), []string{" "}); err == nil || !strings.Contains(err.Error(), "no baseline outputs") {
+		t.Errorf("SetBaseline() error = %v, want no baseline outputs", err)
+	}
+}
+
+func TestDriftErrors(t *testing.T) {
+	t.Parallel()
+
+	monitor, _, _ := newTestMonitor(nil)
+	if err := monitor.Record(context.Background(), "fail"); err == nil || !strings.Contains(err.Error(), "failed to embed output") {
+		t.Errorf("Record() error = %v, want embed failure", err)
+	}
+
+	if err := monitor.Record(context.Background(), "abc"); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	monitor.embedder = &letterEmbedder{dims: 2}
+	if err := monitor.Record(context.Background(), "abc"); err == nil || !strings.Contains(err.Error(), "2 dimensions, want 3") {
+		t.Errorf("Record() error = %v, want dimension mismatch", err)
+	}
+}
+
+func TestDriftHandler(t *testing.T) {
+	t.Parallel()
+
+	embedder := &letterEmbedder{}
+	monitor := DriftWithConfig(embedder, time.Hour, &DriftConfig{SampleRate: 1, MaxBytes: 4})
+
+	var out string
+	if err := calque.NewFlow().Use(monitor).Run(context.Background(), "abcabc", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != "abcabc" {
+		t.Errorf("output = %q, want the input unchanged", out)
+	}
+	if err := monitor.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	embedder.mu.Lock()
+	defer embedder.mu.Unlock()
+	if len(embedder.texts) != 1 || embedder.texts[0] != "abca" {
+		t.Errorf("embedded %q, want the first MaxBytes of the output", embedder.texts)
+	}
+}
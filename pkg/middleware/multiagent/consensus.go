@@ -6,7 +6,10 @@ package multiagent
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
 	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
@@ -35,7 +38,7 @@ type VoteFunc func(responses []string) (string, error)
 //	consensus := multiagent.SimpleConsensus(agents, votingFunc, 2)
 //	flow.Use(consensus)
 func SimpleConsensus(agents []calque.Handler, voteFunc VoteFunc, minResponses int) calque.Handler {
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		if len(agents) == 0 {
 			return calque.NewErr(req.Context, "no agents provided for consensus")
 		}
@@ -78,4 +81,204 @@ func SimpleConsensus(agents []calque.Handler, voteFunc VoteFunc, minResponses in
 
 		return calque.Write(res, []byte(result))
 	})
+	return calque.Described(h, func() calque.Node {
+		agentNodes := make([]calque.Node, len(agents))
+		for i, agent := range agents {
+			agentNodes[i] = calque.DescribeHandler(agent)
+		}
+		return calque.Node{Label: "consensus", Kind: calque.NodeSequence, Children: []calque.Node{
+			{Label: "agents", Kind: calque.NodeParallel, Children: agentNodes},
+			{Label: fmt.Sprintf("vote (min %d)", minResponses)},
+		}}
+	}, agents...)
+}
+
+// WeightedAgent is an agent along with the relative cost of consulting it,
+// e.g. its price per call or expected latency
+type WeightedAgent struct {
+	Agent calque.Handler
+	Cost  float64
+}
+
+// Weighted pairs an agent with its cost for QuorumConsensus
+func Weighted(agent calque.Handler, cost float64) WeightedAgent {
+	return WeightedAgent{Agent: agent, Cost: cost}
+}
+
+// agentHandlers returns the handlers of weighted agents
+func agentHandlers(agents []WeightedAgent) []calque.Handler {
+	handlers := make([]calque.Handler, len(agents))
+	for i, agent := range agents {
+		handlers[i] = agent.Agent
+	}
+	return handlers
+}
+
+// QuorumConfig configures QuorumConsensusWithConfig
+type QuorumConfig struct {
+	// Normalize maps a response to the key compared between agents (default: strings.TrimSpace)
+	Normalize func(string) string
+	// MaxCost caps the total cost of the agents consulted (0 = no limit)
+	MaxCost float64
+}
+
+// QuorumConsensus consults agents from cheapest to most expensive until
+// quorum of them give the same answer.
+//
+// Input: any data type (passes same input to the agents consulted)
+// Output: earliest response with the answer that reached quorum
+// Behavior: BUFFERED - returns as soon as quorum agents agree, cancelling the rest
+//
+// Agents are grouped by cost. The cheapest group runs first, in parallel, and
+// the next group is started only once the agents still running can no longer
+// reach quorum because some disagreed, failed or gave an empty answer. Votes
+// carry over between groups, so expensive models are consulted only when
+// cheaper ones disagree. With equal costs all agents run at once and the
+// slowest are cancelled as soon as enough agree. Responses are compared after
+// trimming whitespace.
+//
+// Example:
+//
+//	consensus := multiagent.QuorumConsensus([]multiagent.WeightedAgent{
+//		multiagent.Weighted(ai.Agent(llamaClient), 0),
+//		multiagent.Weighted(ai.Agent(miniClient), 1),
+//		multiagent.Weighted(ai.Agent(gpt4Client), 10),
+//	}, 2)
+func QuorumConsensus(agents []WeightedAgent, quorum int) calque.Handler {
+	return QuorumConsensusWithConfig(agents, quorum, nil)
+}
+
+// QuorumConsensusWithConfig is QuorumConsensus with a custom answer comparison
+// and cost budget.
+//
+// Input: any data type (passes same input to the agents consulted)
+// Output: earliest response with the answer that reached quorum
+// Behavior: BUFFERED - returns as soon as quorum agents agree, cancelling the rest
+//
+// Agents that would take the total cost of the agents consulted past MaxCost
+// are not started, and without quorum within the budget an error is
+// returned. A nil config uses the defaults.
+//
+// Example:
+//
+//	consensus := multiagent.QuorumConsensusWithConfig(agents, 2, &multiagent.QuorumConfig{
+//		Normalize: func(s string) string { return strings.ToLower(strings.TrimSpace(s)) },
+//		MaxCost:   5,
+//	})
+func QuorumConsensusWithConfig(agents []WeightedAgent, quorum int, config *QuorumConfig) calque.Handler {
+	cfg := QuorumConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Normalize == nil {
+		cfg.Normalize = strings.TrimSpace
+	}
+
+	// Cheapest first, keeping the given order for equal costs
+	ordered := slices.Clone(agents)
+	slices.SortStableFunc(ordered, func(a, b WeightedAgent) int {
+		switch {
+		case a.Cost < b.Cost:
+			return -1
+		case a.Cost > b.Cost:
+			return 1
+		}
+		return 0
+	})
+
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		if len(ordered) == 0 {
+			return calque.NewErr(req.Context, "no agents provided for consensus")
+		}
+		if quorum < 1 || quorum > len(ordered) {
+			return calque.NewErr(req.Context, fmt.Sprintf("quorum must be between 1 and %d, got %d", len(ordered), quorum))
+		}
+
+		var input []byte
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+
+		answer, err := runQuorum(req.Context, ordered, quorum, cfg, input)
+		if err != nil {
+			return err
+		}
+		return calque.Write(res, answer)
+	})
+	return calque.Described(h, func() calque.Node {
+		agentNodes := make([]calque.Node, len(ordered))
+		for i, agent := range ordered {
+			agentNodes[i] = calque.DescribeHandler(agent.Agent)
+		}
+		return calque.Node{Label: "consensus", Kind: calque.NodeSequence, Children: []calque.Node{
+			{Label: "agents (cheapest first)", Kind: calque.NodeParallel, Children: agentNodes},
+			{Label: fmt.Sprintf("quorum %d", quorum)},
+		}}
+	}, agentHandlers(ordered)...)
+}
+
+// quorumVote is the outcome of consulting one agent
+type quorumVote struct {
+	index    int
+	response string
+	err      error
+}
+
+// runQuorum starts agents tier by tier until quorum responses agree
+func runQuorum(ctx context.Context, agents []WeightedAgent, quorum int, cfg QuorumConfig, input []byte) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // stops agents still running once quorum is reached
+
+	votes := make(chan quorumVote, len(agents))
+	answers := make(map[string][]string)
+	next, running, best, failed := 0, 0, 0, 0
+	spent := 0.0
+
+	for {
+		// Start the next cost tier while the agents running can't reach quorum on their own
+		if running+best < quorum && next < len(agents) {
+			tier := agents[next].Cost
+			for next < len(agents) && agents[next].Cost == tier {
+				agent := agents[next]
+				if cfg.MaxCost > 0 && spent+agent.Cost > cfg.MaxCost {
+					break
+				}
+				spent += agent.Cost
+				running++
+				go func(index int, agent calque.Handler) {
+					var out bytes.Buffer
+					err := agent.ServeFlow(calque.NewRequest(ctx, bytes.NewReader(input)), calque.NewResponse(&out))
+					votes <- quorumVote{index: index, response: out.String(), err: err}
+				}(next, agent.Agent)
+				next++
+			}
+		}
+
+		if running == 0 {
+			return "", calque.NewErr(ctx, fmt.Sprintf("no quorum: at most %d of %d agents agreed after consulting %d (%d failed, cost %g)",
+				best, quorum, next, failed, spent))
+		}
+
+		var vote quorumVote
+		select {
+		case vote = <-votes:
+		case <-ctx.Done():
+			return "", calque.WrapErr(ctx, ctx.Err(), "consensus cancelled")
+		}
+		running--
+
+		if vote.err != nil || strings.TrimSpace(vote.response) == "" {
+			failed++
+			calque.LogDebug(ctx, "consensus agent gave no answer", "agent", vote.index, "error", vote.err)
+			continue
+		}
+
+		key := cfg.Normalize(vote.response)
+		answers[key] = append(answers[key], vote.response)
+		best = max(best, len(answers[key]))
+		if len(answers[key]) >= quorum {
+			calque.LogDebug(ctx, "consensus reached", "agreeing", quorum, "consulted", next, "cost", spent)
+			return answers[key][0], nil
+		}
+	}
 }
@@ -3,9 +3,12 @@ package multiagent
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
 )
@@ -186,3 +189,132 @@ func TestConsensus_AllEmptyResponses(t *testing.T) {
 		t.Errorf("Expected insufficient responses error, got: %v", err)
 	}
 }
+
+func TestConsensus_Describe(t *testing.T) {
+	consensus := SimpleConsensus([]calque.Handler{mockAgent("a"), mockAgent("b")}, firstVote, 2)
+
+	node := calque.DescribeHandler(consensus)
+	if node.Label != "consensus" || len(node.Children) != 2 {
+		t.Fatalf("node = %+v", node)
+	}
+	if agents := node.Children[0]; agents.Kind != calque.NodeParallel || len(agents.Children) != 2 {
+		t.Errorf("agents node = %+v", agents)
+	}
+	if vote := node.Children[1]; vote.Label != "vote (min 2)" {
+		t.Errorf("vote node = %+v", vote)
+	}
+}
+
+// countingAgent returns a fixed response and counts how often it is consulted
+func countingAgent(response string, calls *atomic.Int32) calque.Handler {
+	return calque.HandlerFunc(func(_ *calque.Request, res *calque.Response) error {
+		calls.Add(1)
+		return calque.Write(res, response)
+	})
+}
+
+// blockingAgent waits until its request is cancelled, reporting the cancellation
+func blockingAgent(cancelled chan<- struct{}) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+		<-req.Context.Done()
+		close(cancelled)
+		return req.Context.Err()
+	})
+}
+
+func failingAgent() calque.Handler {
+	return calque.HandlerFunc(func(*calque.Request, *calque.Response) error {
+		return errors.New("agent unavailable")
+	})
+}
+
+func TestQuorumConsensus(t *testing.T) {
+	tests := []struct {
+		name      string
+		cheap     []string // responses of cost-1 agents, "!" for a failing agent
+		pricey    string   // response of the cost-10 agent
+		quorum    int
+		config    *QuorumConfig
+		want      string
+		wantErr   string
+		wantCalls int32 // times the cost-10 agent was consulted
+	}{
+		{name: "cheap agents agree", cheap: []string{"Paris", " Paris\n"}, pricey: "Lyon", quorum: 2, want: "Paris"},
+		{name: "cheap agents disagree", cheap: []string{"Paris", "Lyon"}, pricey: "Paris", quorum: 2, want: "Paris", wantCalls: 1},
+		{name: "cheap agent fails", cheap: []string{"Paris", "!"}, pricey: "Paris", quorum: 2, want: "Paris", wantCalls: 1},
+		{name: "no quorum", cheap: []string{"Paris", "Lyon"}, pricey: "Nice", quorum: 2, wantErr: "no quorum: at most 1 of 2 agents agreed after consulting 3 (0 failed, cost 12)", wantCalls: 1},
+		{
+			name:    "budget keeps expensive agent out",
+			cheap:   []string{"Paris", "Lyon"},
+			pricey:  "Paris",
+			quorum:  2,
+			config:  &QuorumConfig{MaxCost: 5},
+			wantErr: "no quorum: at most 1 of 2 agents agreed after consulting 2 (0 failed, cost 2)",
+		},
+		{
+			name:   "custom normalization",
+			cheap:  []string{"PARIS", "paris"},
+			pricey: "Lyon",
+			quorum: 2,
+			config: &QuorumConfig{Normalize: func(s string) string { return strings.ToLower(strings.TrimSpace(s)) }},
+			want:   "paris",
+		},
+		{name: "quorum above agent count", cheap: []string{"Paris"}, pricey: "Paris", quorum: 3, wantErr: "quorum must be between 1 and 2, got 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			agents := []WeightedAgent{Weighted(countingAgent(tt.pricey, &calls), 10)}
+			for _, response := range tt.cheap {
+				agent := mockAgent(response)
+				if response == "!" {
+					agent = failingAgent()
+				}
+				agents = append(agents, Weighted(agent, 1))
+			}
+
+			var out string
+			err := calque.NewFlow().Use(QuorumConsensusWithConfig(agents, tt.quorum, tt.config)).Run(context.Background(), "capital of France?", &out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil || !strings.EqualFold(strings.TrimSpace(out), tt.want) {
+				t.Errorf("Run() = %q, %v, want %q", out, err, tt.want)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("expensive agent consulted %d times, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestQuorumConsensus_CancelsRemainingAgents(t *testing.T) {
+	cancelled := make(chan struct{})
+	consensus := QuorumConsensus([]WeightedAgent{
+		Weighted(mockAgent("yes"), 1),
+		Weighted(blockingAgent(cancelled), 1),
+		Weighted(mockAgent("yes"), 1),
+	}, 2)
+
+	var out string
+	if err := calque.NewFlow().Use(consensus).Run(context.Background(), "ok?", &out); err != nil || out != "yes" {
+		t.Fatalf("Run() = %q, %v", out, err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("slow agent was not cancelled after quorum")
+	}
+}
+
+func TestQuorumConsensus_Describe(t *testing.T) {
+	node := calque.DescribeHandler(QuorumConsensus([]WeightedAgent{Weighted(mockAgent("a"), 2), Weighted(mockAgent("b"), 1)}, 2))
+	if node.Label != "consensus" || len(node.Children) != 2 || len(node.Children[0].Children) != 2 {
+		t.Fatalf("node = %+v", node)
+	}
+	if quorum := node.Children[1]; quorum.Label != "quorum 2" {
+		t.Errorf("quorum node = %+v", quorum)
+	}
+}
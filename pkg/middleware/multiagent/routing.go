@@ -25,6 +25,21 @@ func (rh *routeHandler) ServeFlow(req *calque.Request, res *calque.Response) err
 	return rh.handler.ServeFlow(req, res)
 }
 
+// Warmup implements calque.Warmer by warming the wrapped handler
+func (rh *routeHandler) Warmup(ctx context.Context) error {
+	return calque.WarmupHandler(ctx, rh.handler)
+}
+
+// Shutdown implements calque.Shutdowner by shutting down the wrapped handler
+func (rh *routeHandler) Shutdown(ctx context.Context) error {
+	return calque.ShutdownHandler(ctx, rh.handler)
+}
+
+// Describe implements calque.Describer, labeling the wrapped handler's edge with the route name
+func (rh *routeHandler) Describe() calque.Node {
+	return calque.DescribeHandler(rh.handler).WithEdge(rh.name)
+}
+
 // RouteSelection defines the structured output schema for route selection
 type RouteSelection struct {
 	Route      string  `json:"route" jsonschema:"required,description=Selected route identifier"`
@@ -32,6 +47,43 @@ type RouteSelection struct {
 	Reasoning  string  `json:"reasoning,omitempty" jsonschema:"description=Brief explanation for the route choice"`
 }
 
+// RouteMetadataKey is the MetadataBus key holding the RouteDecision of the last
+// Router in the flow. Read it with RouteDecisionFrom.
+const RouteMetadataKey = "multiagent.route"
+
+// RouteDecision records how a Router picked the handler for a request
+type RouteDecision struct {
+	RouteSelection
+	Attempts int  `json:"attempts"`           // Selector calls made, including retries
+	Fallback bool `json:"fallback,omitempty"` // No valid route was selected; the first handler was used
+	Sticky   bool `json:"sticky,omitempty"`   // The conversation was kept on its previous route
+}
+
+// RouteDecisionFrom returns the routing decision recorded in the request's flow run
+func RouteDecisionFrom(ctx context.Context) (RouteDecision, bool) {
+	bus := calque.GetMetadataBus(ctx)
+	if bus == nil {
+		return RouteDecision{}, false
+	}
+	v, ok := bus.Get(RouteMetadataKey)
+	if !ok {
+		return RouteDecision{}, false
+	}
+	decision, ok := v.(RouteDecision)
+	return decision, ok
+}
+
+// RouterConfig configures RouterWithConfig
+type RouterConfig struct {
+	// TraceRoutes runs the chosen handler in a span named "route <name>"
+	// carrying the decision as attributes
+	TraceRoutes bool
+	// OnDecision is called with every routing decision before the chosen handler runs
+	OnDecision func(ctx context.Context, decision RouteDecision)
+	// Stickiness keeps conversations on the route that served their previous turn
+	Stickiness *Stickiness
+}
+
 // RouterInput contains the request data and available route options for the selector
 type RouterInput struct {
 	Request string        `json:"request" jsonschema:"required,description=The user request to route"`
@@ -68,6 +120,13 @@ func Route(handler calque.Handler, name, description, keywords string) calque.Ha
 	}
 }
 
+// Selector picks the route for a request from the available route options.
+// Router uses an AI agent constrained to the RouteSelection schema;
+// implement Selector to route with something else, such as a classifier.
+type Selector interface {
+	Select(ctx context.Context, request string, routes []RouteOption) (*RouteSelection, error)
+}
+
 // Router creates intelligent handler selection using structured JSON Schema output.
 // The router takes an AI client and automatically configures it with the RouteSelection schema.
 // No manual prompt setup is needed - the router generates structured input with route metadata.
@@ -76,11 +135,71 @@ func Route(handler calque.Handler, name, description, keywords string) calque.Ha
 // Output: response from selected handler
 // Behavior: BUFFERED - reads input, creates structured prompt with schema, validates response
 //
+// The selection, with the model's confidence and reasoning, is recorded on
+// the MetadataBus under RouteMetadataKey.
+//
 // Example:
 //
 //	router := multiagent.Router(selectionClient,
 //	    mathHandler, codeHandler, searchHandler)
 func Router(client ai.Client, handlers ...calque.Handler) calque.Handler {
+	return RouterWithSelector(AgentSelector(client), handlers...)
+}
+
+// AgentSelector returns the Selector used by Router: an AI agent constrained
+// to the RouteSelection schema
+//
+// Example:
+//
+//	router := multiagent.RouterWithConfig(multiagent.AgentSelector(client),
+//	    &multiagent.RouterConfig{TraceRoutes: true}, mathHandler, codeHandler)
+func AgentSelector(client ai.Client) Selector {
+	return schemaSelector{agent: ai.Agent(client, ai.WithSchema(&RouteSelection{}))}
+}
+
+// RouterWithSelector routes each request to the handler chosen by selector.
+// A failed selection or an unknown route is retried twice before falling back
+// to the first handler, as with Router.
+//
+// Input: any data type (buffered - needs full input for selection)
+// Output: response from selected handler
+// Behavior: BUFFERED - reads input, asks the selector for a route, then delegates
+//
+// Example:
+//
+//	router := multiagent.RouterWithSelector(
+//	    classify.Label(client, []string{"math", "code"}, classify.WithFallback("code")),
+//	    mathHandler, codeHandler)
+func RouterWithSelector(selector Selector, handlers ...calque.Handler) calque.Handler {
+	return RouterWithConfig(selector, nil, handlers...)
+}
+
+// RouterWithConfig is RouterWithSelector with decision auditing options.
+//
+// Input: any data type (buffered - needs full input for selection)
+// Output: response from selected handler
+// Behavior: BUFFERED - reads input, asks the selector for a route, then delegates
+//
+// Every router records its RouteDecision on the MetadataBus and on the current
+// trace span. RouterConfig adds a span around the chosen handler, so traces
+// show which route served each request and why, and a callback for audit logs.
+// Stickiness keeps multi-turn conversations on one agent (see WithStickiness).
+// A nil config behaves like RouterWithSelector.
+//
+// Example:
+//
+//	router := multiagent.RouterWithConfig(multiagent.AgentSelector(client), &multiagent.RouterConfig{
+//		TraceRoutes: true,
+//		OnDecision: func(ctx context.Context, d multiagent.RouteDecision) {
+//			auditLog.Info("routed", "route", d.Route, "confidence", d.Confidence, "reasoning", d.Reasoning)
+//		},
+//	}, mathHandler, codeHandler)
+func RouterWithConfig(selector Selector, config *RouterConfig, handlers ...calque.Handler) calque.Handler {
+	cfg := RouterConfig{}
+	if config != nil {
+		cfg = *config
+	}
+
 	if len(handlers) == 0 {
 		return calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
 			return calque.NewErr(req.Context, "no handlers provided to router")
@@ -116,46 +235,114 @@ func Router(client ai.Client, handlers ...calque.Handler) calque.Handler {
 		}
 	}
 
-	// Create AI agent with schema for route selection
-	selector := ai.Agent(client, ai.WithSchema(&RouteSelection{}))
-
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		var input []byte
 		err := calque.Read(req, &input)
 		if err != nil {
 			return err
 		}
 
-		// Create structured input with route options
-		routerInput := RouterInput{
-			Request: string(input),
-			Routes:  routeOptions,
-		}
-
 		// Try selection with retry logic
 		maxRetries := 2
-		var selectedHandler calque.Handler
+		var selected *routeHandler
+		var decision RouteDecision
 
 		for attempt := 0; attempt <= maxRetries; attempt++ {
-			selection, err := callSelectorWithSchema(req.Context, selector, routerInput)
+			decision.Attempts = attempt + 1
+			selection, err := selector.Select(req.Context, string(input), routeOptions)
 
 			if err == nil {
 				// Validate the selected route exists
-				if selectedHandler = findHandlerByID(selection.Route, routes); selectedHandler != nil {
+				decision.RouteSelection = *selection
+				if selected = findRoute(selection.Route, routes); selected != nil {
 					break
 				}
 			}
 
 			if attempt == maxRetries {
 				// Final fallback - use first handler
-				selectedHandler = routes[0].handler
+				selected = routes[0]
+				decision.Fallback = true
+				decision.Route = selected.name
 				break
 			}
 		}
 
+		if cfg.Stickiness != nil {
+			selected = cfg.Stickiness.apply(req.Context, selected, &decision, routes)
+		}
+
+		recordDecision(req.Context, decision, cfg)
+
 		// Route to selected handler
 		req.Data = bytes.NewReader(input)
-		return selectedHandler.ServeFlow(req, res)
+		if !cfg.TraceRoutes {
+			return selected.handler.ServeFlow(req, res)
+		}
+
+		ctx, span := calque.StartSpan(req.Context, "route "+selected.name)
+		setDecisionAttributes(span.SetAttribute, decision)
+		err = selected.handler.ServeFlow(calque.NewRequest(ctx, req.Data), res)
+		span.End(err)
+		return err
+	})
+	return calque.Described(h, func() calque.Node {
+		node := calque.Node{Label: "router", Kind: calque.NodeBranch, Egress: calque.EgressOf(selector)}
+		for _, route := range routes {
+			node.Children = append(node.Children, route.Describe())
+		}
+		return node
+	}, handlers...)
+}
+
+// recordDecision stores a routing decision on the MetadataBus and the current
+// span, and reports it to the configured callback
+func recordDecision(ctx context.Context, decision RouteDecision, cfg RouterConfig) {
+	calque.LogDebug(ctx, "router selected route", "route", decision.Route, "confidence", decision.Confidence,
+		"attempts", decision.Attempts, "fallback", decision.Fallback, "sticky", decision.Sticky)
+	if bus := calque.GetMetadataBus(ctx); bus != nil {
+		bus.Set(RouteMetadataKey, decision)
+	}
+	setDecisionAttributes(func(key string, value any) { calque.SetSpanAttribute(ctx, key, value) }, decision)
+	if cfg.OnDecision != nil {
+		cfg.OnDecision(ctx, decision)
+	}
+}
+
+// setDecisionAttributes describes a routing decision as span attributes
+func setDecisionAttributes(set func(key string, value any), decision RouteDecision) {
+	set("multiagent.route", decision.Route)
+	set("multiagent.route.attempts", decision.Attempts)
+	if decision.Confidence > 0 {
+		set("multiagent.route.confidence", decision.Confidence)
+	}
+	if decision.Reasoning != "" {
+		set("multiagent.route.reasoning", decision.Reasoning)
+	}
+	if decision.Fallback {
+		set("multiagent.route.fallback", true)
+	}
+	if decision.Sticky {
+		set("multiagent.route.sticky", true)
+	}
+}
+
+// schemaSelector asks an agent configured with the RouteSelection schema for a route
+type schemaSelector struct {
+	agent calque.Handler
+}
+
+// Select implements Selector
+// Egress implements calque.Egress, reporting where the selection client sends requests
+func (s schemaSelector) Egress() []string {
+	return calque.EgressOf(s.agent)
+}
+
+func (s schemaSelector) Select(ctx context.Context, request string, routes []RouteOption) (*RouteSelection, error) {
+	// Create structured input with route options
+	return callSelectorWithSchema(ctx, s.agent, RouterInput{
+		Request: request,
+		Routes:  routes,
 	})
 }
 
@@ -180,9 +367,17 @@ func callSelectorWithSchema(ctx context.Context, selector calque.Handler, router
 
 // findHandlerByID locates handler by route ID
 func findHandlerByID(routeID string, routes []*routeHandler) calque.Handler {
+	if route := findRoute(routeID, routes); route != nil {
+		return route.handler
+	}
+	return nil
+}
+
+// findRoute locates a route by ID
+func findRoute(routeID string, routes []*routeHandler) *routeHandler {
 	for _, route := range routes {
 		if route.name == routeID {
-			return route.handler
+			return route
 		}
 	}
 	return nil
@@ -202,10 +397,17 @@ func LoadBalancer(handlers ...calque.Handler) calque.Handler {
 	}
 
 	var counter atomic.Uint64
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		// Atomically increment and get the previous value for round-robin selection
 		idx := counter.Add(1) - 1
 		handler := handlers[idx%uint64(len(handlers))]
 		return handler.ServeFlow(req, res)
 	})
+	return calque.Described(h, func() calque.Node {
+		node := calque.Node{Label: "load balancer", Kind: calque.NodeBranch}
+		for _, handler := range handlers {
+			node.Children = append(node.Children, calque.DescribeHandler(handler).WithEdge("round robin"))
+		}
+		return node
+	}, handlers...)
 }
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -130,6 +131,58 @@ func TestRouterFallbackOnSelectorError(t *testing.T) {
 	}
 }
 
+// selectorFunc adapts a function to the Selector interface
+type selectorFunc func(ctx context.Context, request string, routes []RouteOption) (*RouteSelection, error)
+
+func (f selectorFunc) Select(ctx context.Context, request string, routes []RouteOption) (*RouteSelection, error) {
+	return f(ctx, request, routes)
+}
+
+func TestRouterWithSelector(t *testing.T) {
+	mathHandler := Route(createMockHandler("math", "42"), "math", "Math", "calculate")
+	codeHandler := Route(createMockHandler("code", "func() {}"), "code", "Code", "program")
+
+	tests := []struct {
+		name      string
+		responses []string
+		wantCalls int
+		expected  string
+	}{
+		{name: "selected route", responses: []string{"code"}, wantCalls: 1, expected: "code: func() {}"},
+		{name: "retries unknown route", responses: []string{"chat", "code"}, wantCalls: 2, expected: "code: func() {}"},
+		{name: "falls back after retries", responses: []string{"", "", ""}, wantCalls: 3, expected: "math: 42"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			selector := selectorFunc(func(_ context.Context, request string, routes []RouteOption) (*RouteSelection, error) {
+				if request != "test input" || len(routes) != 2 || routes[1].ID != "code" || routes[1].Description != "Code" {
+					t.Errorf("Select(%q, %+v)", request, routes)
+				}
+				route := test.responses[calls]
+				calls++
+				if route == "" {
+					return nil, fmt.Errorf("no route")
+				}
+				return &RouteSelection{Route: route}, nil
+			})
+
+			var output bytes.Buffer
+			req := calque.NewRequest(context.Background(), strings.NewReader("test input"))
+			if err := RouterWithSelector(selector, mathHandler, codeHandler).ServeFlow(req, calque.NewResponse(&output)); err != nil {
+				t.Fatalf("Router failed: %v", err)
+			}
+			if output.String() != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, output.String())
+			}
+			if calls != test.wantCalls {
+				t.Errorf("Expected %d selector calls, got %d", test.wantCalls, calls)
+			}
+		})
+	}
+}
+
 func TestLoadBalancer(t *testing.T) {
 	t.Run("RoundRobin", func(t *testing.T) {
 		handler1 := createMockHandler("handler1", "response1")
@@ -426,3 +479,133 @@ func TestCallSelectorWithSchema(t *testing.T) {
 		t.Errorf("Expected reasoning 'test', got %q", selection.Reasoning)
 	}
 }
+
+func TestRouterDescribe(t *testing.T) {
+	router := Router(ai.NewMockClient(`{"route": "math"}`),
+		Route(createMockHandler("math", "42"), "math", "Mathematical calculations", "calculate"),
+		createMockHandler("plain", "plain response"),
+	)
+
+	node := calque.DescribeHandler(router)
+	if node.Label != "router" || node.Kind != calque.NodeBranch || len(node.Children) != 2 {
+		t.Fatalf("node = %+v", node)
+	}
+	if node.Children[0].Edge != "math" || node.Children[1].Edge != "handler_1" {
+		t.Errorf("edges = %q, %q", node.Children[0].Edge, node.Children[1].Edge)
+	}
+
+	lb := calque.DescribeHandler(LoadBalancer(createMockHandler("a", ""), createMockHandler("b", "")))
+	if lb.Kind != calque.NodeBranch || len(lb.Children) != 2 {
+		t.Errorf("load balancer node = %+v", lb)
+	}
+
+	diagram, err := calque.NewFlow().Use(router).Visualize(calque.DiagramMermaid)
+	if err != nil || !strings.Contains(diagram, `-->|"math"|`) {
+		t.Errorf("diagram = %s, err = %v", diagram, err)
+	}
+}
+
+// routeSpan records the attributes of a span started by the router
+type routeSpan struct {
+	name  string
+	attrs map[string]any
+	ended bool
+}
+
+func (s *routeSpan) SetAttribute(key string, value any) { s.attrs[key] = value }
+func (s *routeSpan) End(error)                          { s.ended = true }
+
+func TestRouterDecision(t *testing.T) {
+	mathHandler := Route(createMockHandler("math", "42"), "math", "Math", "calculate")
+	codeHandler := Route(createMockHandler("code", "func() {}"), "code", "Code", "program")
+
+	tests := []struct {
+		name       string
+		selections []*RouteSelection
+		want       RouteDecision
+	}{
+		{
+			name:       "selected route",
+			selections: []*RouteSelection{{Route: "code", Confidence: 0.9, Reasoning: "asks for a function"}},
+			want:       RouteDecision{RouteSelection: RouteSelection{Route: "code", Confidence: 0.9, Reasoning: "asks for a function"}, Attempts: 1},
+		},
+		{
+			name:       "retried route",
+			selections: []*RouteSelection{{Route: "chat"}, {Route: "math", Confidence: 0.6}},
+			want:       RouteDecision{RouteSelection: RouteSelection{Route: "math", Confidence: 0.6}, Attempts: 2},
+		},
+		{
+			name:       "fallback",
+			selections: []*RouteSelection{nil, nil, nil},
+			want:       RouteDecision{RouteSelection: RouteSelection{Route: "math"}, Attempts: 3, Fallback: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			selector := selectorFunc(func(context.Context, string, []RouteOption) (*RouteSelection, error) {
+				selection := test.selections[calls]
+				calls++
+				if selection == nil {
+					return nil, fmt.Errorf("no route")
+				}
+				return selection, nil
+			})
+
+			var spans []*routeSpan
+			ctx := calque.WithSpanStarter(context.Background(), func(ctx context.Context, name string) (context.Context, calque.Span) {
+				span := &routeSpan{name: name, attrs: map[string]any{}}
+				spans = append(spans, span)
+				return ctx, span
+			})
+			ctx = calque.WithMetadataBus(ctx, calque.NewMetadataBus(0))
+
+			var observed []RouteDecision
+			router := RouterWithConfig(selector, &RouterConfig{
+				TraceRoutes: true,
+				OnDecision:  func(_ context.Context, d RouteDecision) { observed = append(observed, d) },
+			}, mathHandler, codeHandler)
+
+			var out string
+			if err := calque.NewFlow().Use(router).Run(ctx, "test input", &out); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if got, ok := RouteDecisionFrom(ctx); !ok || got != test.want {
+				t.Errorf("RouteDecisionFrom() = %+v, %v, want %+v", got, ok, test.want)
+			}
+			if len(observed) != 1 || observed[0] != test.want {
+				t.Errorf("OnDecision saw %+v", observed)
+			}
+			if len(spans) != 1 || spans[0].name != "route "+test.want.Route || !spans[0].ended {
+				t.Fatalf("spans = %+v", spans)
+			}
+			if spans[0].attrs["multiagent.route"] != test.want.Route || spans[0].attrs["multiagent.route.attempts"] != test.want.Attempts {
+				t.Errorf("span attributes = %v", spans[0].attrs)
+			}
+			if _, ok := spans[0].attrs["multiagent.route.fallback"]; ok != test.want.Fallback {
+				t.Errorf("span fallback attribute = %v, want %v", ok, test.want.Fallback)
+			}
+
+			data, err := json.Marshal(test.want)
+			if err != nil || !strings.Contains(string(data), `"route":"`+test.want.Route+`"`) {
+				t.Errorf("json.Marshal(decision) = %s, %v", data, err)
+			}
+		})
+	}
+}
+
+// remoteClient is a client on another host
+type remoteClient struct{ ai.Client }
+
+func (remoteClient) Egress() []string { return []string{"api.example.com"} }
+
+func TestRouterEgress(t *testing.T) {
+	if got := calque.DescribeHandler(Router(remoteClient{ai.NewMockClient("{}")}, createMockHandler("a", "ok"))).Egress; !slices.Equal(got, []string{"api.example.com"}) {
+		t.Errorf("egress = %v, want the client's host", got)
+	}
+	if got := calque.DescribeHandler(Router(ai.NewMockClient("{}"), createMockHandler("a", "ok"))).Egress; got != nil {
+		t.Errorf("egress with a local client = %v, want none", got)
+	}
+}
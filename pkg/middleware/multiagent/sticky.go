@@ -0,0 +1,93 @@
+package multiagent
+
+import (
+	"context"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/cache"
+	"github.com/calque-ai/go-calque/pkg/middleware/memory"
+)
+
+// DefaultSwitchThreshold is the confidence a different route needs to take a
+// sticky conversation away from its current agent
+const DefaultSwitchThreshold = 0.8
+
+// stickyKeyPrefix namespaces conversation keys in the stickiness store
+const stickyKeyPrefix = "multiagent.route:"
+
+// Stickiness keeps a conversation on the agent that served its previous turn.
+// Set it on RouterConfig, usually through WithStickiness.
+type Stickiness struct {
+	Store           cache.Store                      // Remembers the route of each conversation
+	TTL             time.Duration                    // How long a route is remembered after the last turn
+	SwitchThreshold float64                          // Confidence needed to move to a different route (default: DefaultSwitchThreshold)
+	Key             func(ctx context.Context) string // Conversation key (default: memory.GetKey)
+}
+
+// WithStickiness keeps each conversation on the agent that served its previous
+// turn, so a user isn't handed to another agent mid-conversation because one
+// message looks slightly more like another route. A different route is only
+// taken when the selector's confidence in it reaches DefaultSwitchThreshold.
+//
+// Conversations are identified by memory.GetKey; requests without a key are
+// routed as usual. Each turn refreshes the remembered route for ttl.
+//
+// Example:
+//
+//	router := multiagent.RouterWithConfig(multiagent.AgentSelector(client), &multiagent.RouterConfig{
+//		Stickiness: multiagent.WithStickiness(cache.NewInMemoryStore(), 30*time.Minute),
+//	}, billingHandler, supportHandler)
+//
+//	ctx := memory.WithKey(context.Background(), sessionID)
+func WithStickiness(store cache.Store, ttl time.Duration) *Stickiness {
+	return &Stickiness{Store: store, TTL: ttl}
+}
+
+// conversationKey returns the store key for the request's conversation, or an
+// empty string when the request isn't part of one
+func (s *Stickiness) conversationKey(ctx context.Context) string {
+	keyFunc := s.Key
+	if keyFunc == nil {
+		keyFunc = memory.GetKey
+	}
+	key := keyFunc(ctx)
+	if key == "" {
+		return ""
+	}
+	return stickyKeyPrefix + key
+}
+
+// apply keeps the conversation on its previous route unless the new selection
+// is confident enough to switch, then remembers the route that will serve this turn
+func (s *Stickiness) apply(ctx context.Context, selected *routeHandler, decision *RouteDecision, routes []*routeHandler) *routeHandler {
+	key := s.conversationKey(ctx)
+	if key == "" || s.Store == nil {
+		return selected
+	}
+
+	threshold := s.SwitchThreshold
+	if threshold <= 0 {
+		threshold = DefaultSwitchThreshold
+	}
+
+	stored, err := s.Store.Get(key)
+	if err != nil {
+		calque.LogWarn(ctx, "router stickiness lookup failed", "error", err)
+	}
+	if previous := findRoute(string(stored), routes); previous != nil && previous != selected {
+		if decision.Fallback || decision.Confidence < threshold {
+			calque.LogDebug(ctx, "router kept conversation on previous route",
+				"route", previous.name, "candidate", selected.name, "confidence", decision.Confidence)
+			selected = previous
+			decision.Route = previous.name
+			decision.Fallback = false
+			decision.Sticky = true
+		}
+	}
+
+	if err := s.Store.Set(key, []byte(selected.name), s.TTL); err != nil {
+		calque.LogWarn(ctx, "router stickiness update failed", "error", err)
+	}
+	return selected
+}
@@ -0,0 +1,142 @@
+package multiagent
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/cache"
+	"github.com/calque-ai/go-calque/pkg/middleware/memory"
+)
+
+func TestRouterStickiness(t *testing.T) {
+	billing := Route(createMockHandler("billing", "ok"), "billing", "Billing questions", "invoice")
+	support := Route(createMockHandler("support", "ok"), "support", "Technical support", "error")
+
+	type turn struct {
+		key        string
+		route      string
+		confidence float64
+		err        bool
+		want       string
+		sticky     bool
+	}
+
+	tests := []struct {
+		name  string
+		turns []turn
+	}{
+		{
+			name: "low confidence switch stays on previous route",
+			turns: []turn{
+				{key: "c1", route: "billing", confidence: 0.9, want: "billing"},
+				{key: "c1", route: "support", confidence: 0.6, want: "billing", sticky: true},
+			},
+		},
+		{
+			name: "confident switch moves the conversation",
+			turns: []turn{
+				{key: "c1", route: "billing", confidence: 0.9, want: "billing"},
+				{key: "c1", route: "support", confidence: 0.85, want: "support"},
+				{key: "c1", route: "billing", confidence: 0.5, want: "support", sticky: true},
+			},
+		},
+		{
+			name: "conversations are independent",
+			turns: []turn{
+				{key: "c1", route: "billing", confidence: 0.9, want: "billing"},
+				{key: "c2", route: "support", confidence: 0.5, want: "support"},
+			},
+		},
+		{
+			name: "failed selection uses previous route instead of fallback",
+			turns: []turn{
+				{key: "c1", route: "support", confidence: 0.9, want: "support"},
+				{key: "c1", err: true, want: "support", sticky: true},
+			},
+		},
+		{
+			name: "requests without a key are not sticky",
+			turns: []turn{
+				{route: "billing", confidence: 0.9, want: "billing"},
+				{route: "support", confidence: 0.1, want: "support"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var current turn
+			selector := selectorFunc(func(context.Context, string, []RouteOption) (*RouteSelection, error) {
+				if current.err {
+					return nil, calque.NewErr(context.Background(), "selector down")
+				}
+				return &RouteSelection{Route: current.route, Confidence: current.confidence}, nil
+			})
+			router := RouterWithConfig(selector, &RouterConfig{
+				Stickiness: WithStickiness(cache.NewInMemoryStore(), time.Minute),
+			}, billing, support)
+
+			for i, tc := range tt.turns {
+				current = tc
+				ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+				if tc.key != "" {
+					ctx = memory.WithKey(ctx, tc.key)
+				}
+
+				var output bytes.Buffer
+				if err := router.ServeFlow(calque.NewRequest(ctx, strings.NewReader("hi")), calque.NewResponse(&output)); err != nil {
+					t.Fatalf("turn %d: router failed: %v", i, err)
+				}
+				if got := strings.SplitN(output.String(), ":", 2)[0]; got != tc.want {
+					t.Errorf("turn %d: routed to %q, want %q", i, got, tc.want)
+				}
+
+				decision, ok := RouteDecisionFrom(ctx)
+				if !ok {
+					t.Fatalf("turn %d: no decision recorded", i)
+				}
+				if decision.Route != tc.want || decision.Sticky != tc.sticky || decision.Fallback {
+					t.Errorf("turn %d: decision = %+v, want route %q sticky %v", i, decision, tc.want, tc.sticky)
+				}
+			}
+		})
+	}
+}
+
+func TestStickinessExpires(t *testing.T) {
+	store := cache.NewInMemoryStore()
+	billing := Route(createMockHandler("billing", "ok"), "billing", "Billing questions", "invoice")
+	support := Route(createMockHandler("support", "ok"), "support", "Technical support", "error")
+
+	route := "billing"
+	selector := selectorFunc(func(context.Context, string, []RouteOption) (*RouteSelection, error) {
+		return &RouteSelection{Route: route, Confidence: 0.5}, nil
+	})
+	sticky := WithStickiness(store, 10*time.Millisecond)
+	sticky.SwitchThreshold = 0.9
+	router := RouterWithConfig(selector, &RouterConfig{Stickiness: sticky}, billing, support)
+
+	ctx := memory.WithKey(context.Background(), "c1")
+	serve := func() string {
+		var output bytes.Buffer
+		if err := router.ServeFlow(calque.NewRequest(ctx, strings.NewReader("hi")), calque.NewResponse(&output)); err != nil {
+			t.Fatalf("router failed: %v", err)
+		}
+		return output.String()
+	}
+
+	serve()
+	route = "support"
+	if got := serve(); !strings.HasPrefix(got, "billing") {
+		t.Errorf("before expiry got %q, want billing", got)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if got := serve(); !strings.HasPrefix(got, "support") {
+		t.Errorf("after expiry got %q, want support", got)
+	}
+}
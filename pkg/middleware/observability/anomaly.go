@@ -0,0 +1,448 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Anomaly metrics
+const (
+	// AnomalyLatency is the latency of successful requests, in seconds
+	AnomalyLatency = "latency"
+	// AnomalyTokens is the model tokens used by successful requests that called a model
+	AnomalyTokens = "tokens"
+	// AnomalyErrorRate is the fraction of failed requests
+	AnomalyErrorRate = "error_rate"
+)
+
+// AnomalyMethod selects how recent samples are compared with the baseline
+type AnomalyMethod int
+
+const (
+	// ZScore scores the mean of the last Window samples against the baseline,
+	// in standard errors of that mean.
+	ZScore AnomalyMethod = iota
+	// EWMA scores an exponentially weighted moving average of the samples
+	// against the baseline, as in an EWMA control chart. It reacts to small
+	// sustained shifts sooner than ZScore and forgets old spikes gradually.
+	EWMA
+)
+
+// String returns the method name
+func (m AnomalyMethod) String() string {
+	switch m {
+	case ZScore:
+		return "zscore"
+	case EWMA:
+		return "ewma"
+	default:
+		return fmt.Sprintf("AnomalyMethod(%d)", int(m))
+	}
+}
+
+// AnomalyConfig configures an AnomalyDetector.
+type AnomalyConfig struct {
+	// Method is the detection method.
+	// Default: ZScore
+	Method AnomalyMethod
+
+	// Threshold is the score, in standard deviations of the recent statistic,
+	// at which a sample is anomalous.
+	// Default: 3
+	Threshold float64
+
+	// Window is the number of recent samples compared with the baseline.
+	// EWMA uses a smoothing factor of 2/(Window+1).
+	// Default: 30
+	Window int
+
+	// BaselineSize is the number of samples the baseline is learned from.
+	// Past it, the baseline keeps adapting with that effective memory.
+	// Default: 1000
+	BaselineSize int
+
+	// MinSamples is the number of samples a series needs before anomalies are detected.
+	// Default: 100
+	MinSamples int
+
+	// Cooldown is the minimum time between two notifications for the same
+	// flow and metric.
+	// Default: 15 minutes
+	Cooldown time.Duration
+
+	// Notifier receives anomaly events. Delivery happens in the background.
+	// Default: nil (anomalies are only logged and counted in metrics)
+	Notifier AnomalyNotifier
+
+	// Provider receives anomaly metrics. Default: nil (no metrics)
+	Provider MetricsProvider
+
+	// Metrics sets the namespace, subsystem and labels of anomaly metrics.
+	// Default: DefaultMetricsConfig()
+	Metrics MetricsConfig
+}
+
+// DefaultAnomalyConfig returns the default anomaly detection configuration
+func DefaultAnomalyConfig() AnomalyConfig {
+	return AnomalyConfig{
+		Method:       ZScore,
+		Threshold:    3,
+		Window:       30,
+		BaselineSize: 1000,
+		MinSamples:   100,
+		Cooldown:     15 * time.Minute,
+		Metrics:      DefaultMetricsConfig(),
+	}
+}
+
+// AnomalyOption configures an AnomalyDetector
+type AnomalyOption func(*AnomalyConfig)
+
+// WithAnomalyMethod sets the detection method
+func WithAnomalyMethod(method AnomalyMethod) AnomalyOption {
+	return func(cfg *AnomalyConfig) {
+		cfg.Method = method
+	}
+}
+
+// WithAnomalyThreshold sets the score at which a sample is anomalous
+func WithAnomalyThreshold(threshold float64) AnomalyOption {
+	return func(cfg *AnomalyConfig) {
+		cfg.Threshold = threshold
+	}
+}
+
+// WithAnomalyWindow sets the number of recent samples compared with the baseline
+func WithAnomalyWindow(window int) AnomalyOption {
+	return func(cfg *AnomalyConfig) {
+		cfg.Window = window
+	}
+}
+
+// WithBaseline sets how many samples the baseline is learned from and how
+// many a series needs before anomalies are detected
+func WithBaseline(size, minSamples int) AnomalyOption {
+	return func(cfg *AnomalyConfig) {
+		cfg.BaselineSize = size
+		cfg.MinSamples = minSamples
+	}
+}
+
+// WithAnomalyCooldown sets the minimum time between repeated notifications
+func WithAnomalyCooldown(cooldown time.Duration) AnomalyOption {
+	return func(cfg *AnomalyConfig) {
+		cfg.Cooldown = cooldown
+	}
+}
+
+// WithAnomalyNotifier sets where anomaly events are sent
+func WithAnomalyNotifier(notifier AnomalyNotifier) AnomalyOption {
+	return func(cfg *AnomalyConfig) {
+		cfg.Notifier = notifier
+	}
+}
+
+// WithAnomalyMetrics exports anomaly metrics to provider
+func WithAnomalyMetrics(provider MetricsProvider, opts ...MetricsOption) AnomalyOption {
+	return func(cfg *AnomalyConfig) {
+		cfg.Provider = provider
+		for _, opt := range opts {
+			opt(&cfg.Metrics)
+		}
+	}
+}
+
+// AnomalyEvent describes a metric that departed from its baseline
+type AnomalyEvent struct {
+	Flow      string    `json:"flow"`      // Flow name
+	Metric    string    `json:"metric"`    // AnomalyLatency, AnomalyTokens or AnomalyErrorRate
+	Method    string    `json:"method"`    // Detection method, e.g. "zscore"
+	Value     float64   `json:"value"`     // Recent mean (ZScore) or moving average (EWMA)
+	Baseline  float64   `json:"baseline"`  // Baseline mean
+	StdDev    float64   `json:"std_dev"`   // Baseline standard deviation
+	Score     float64   `json:"score"`     // Signed score; positive when Value is above the baseline
+	Samples   int64     `json:"samples"`   // Samples seen for this flow and metric
+	Timestamp time.Time `json:"timestamp"` // When the anomaly was detected
+}
+
+// AnomalyNotifier delivers anomaly events.
+//
+// WebhookAlerter implements it, POSTing the event as JSON. Use
+// AnomalyNotifierFunc for chat, paging or ticketing APIs.
+type AnomalyNotifier interface {
+	NotifyAnomaly(ctx context.Context, event AnomalyEvent) error
+}
+
+// AnomalyNotifierFunc adapts a function to AnomalyNotifier
+type AnomalyNotifierFunc func(ctx context.Context, event AnomalyEvent) error
+
+// NotifyAnomaly calls f(ctx, event)
+func (f AnomalyNotifierFunc) NotifyAnomaly(ctx context.Context, event AnomalyEvent) error {
+	return f(ctx, event)
+}
+
+// AnomalySample is the outcome of one request
+type AnomalySample struct {
+	Latency time.Duration
+	Usage   calque.Usage // Model usage; tokens are tracked only when Usage.Calls > 0
+	Err     error
+}
+
+// AnomalyDetector learns baseline latency, token and error distributions per
+// flow and reports requests that depart from them.
+//
+// Each flow and metric has its own series. The baseline mean and variance are
+// learned from the first BaselineSize samples and then keep adapting slowly,
+// so a lasting change becomes the new normal after roughly BaselineSize
+// samples; the cooldown keeps it from notifying on every request until then.
+// A series detects nothing until it has MinSamples samples.
+//
+// Latency and token anomalies fire in both directions: a sudden drop in tokens
+// often means truncated or refused answers. Error-rate anomalies fire only
+// on increases. Standard deviations have small floors so a perfectly stable
+// baseline, such as a flow that never failed, does not flag its first blip.
+//
+// Metrics, when a provider is configured (labels include flow and metric):
+//
+//  1. calque_flow_anomalies_total (Counter)
+//     - Anomalous requests, including those whose notification the cooldown suppressed
+//
+// Example:
+//
+//	detector := observability.NewAnomalyDetector(
+//	    observability.WithAnomalyMethod(observability.EWMA),
+//	    observability.WithAnomalyNotifier(&observability.WebhookAlerter{URL: hookURL}),
+//	)
+//
+//	flow := calque.NewFlow().Use(detector.Handler("support-chat", ai.Agent(client)))
+type AnomalyDetector struct {
+	cfg AnomalyConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	series    map[anomalyKey]*anomalySeries
+	lastFired map[anomalyKey]time.Time
+}
+
+type anomalyKey struct {
+	flow, metric string
+}
+
+// anomalySeries holds the learned baseline and recent samples of one metric
+type anomalySeries struct {
+	n        int64
+	mean     float64
+	variance float64
+	recent   []float64 // Ring buffer of the last Window samples (ZScore)
+	next     int
+	ewma     float64
+}
+
+// NewAnomalyDetector creates an anomaly detector.
+func NewAnomalyDetector(opts ...AnomalyOption) *AnomalyDetector {
+	cfg := DefaultAnomalyConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.Window = max(cfg.Window, 1)
+	cfg.BaselineSize = max(cfg.BaselineSize, 1)
+
+	return &AnomalyDetector{
+		cfg:       cfg,
+		now:       time.Now,
+		series:    make(map[anomalyKey]*anomalySeries),
+		lastFired: make(map[anomalyKey]time.Time),
+	}
+}
+
+// Handler wraps a handler, recording its latency, token usage and outcome
+// under the flow name.
+//
+// Input: any data type (streaming)
+// Output: whatever handler writes
+// Behavior: STREAMING - latency is measured until handler returns; tokens are
+// the usage recorded in the run while handler ran (see calque.UsageFrom)
+//
+// A MetadataBus is attached to the request when it has none, so model usage
+// can be counted.
+//
+// Example:
+//
+//	flow := calque.NewFlow().Use(detector.Handler("support-chat", ai.Agent(client)))
+func (d *AnomalyDetector) Handler(flow string, handler calque.Handler) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		if calque.GetMetadataBus(req.Context) == nil {
+			req.Context = calque.WithMetadataBus(req.Context, calque.NewMetadataBus(0))
+		}
+		before := calque.UsageFrom(req.Context)
+		start := d.now()
+		err := handler.ServeFlow(req, res)
+		usage := calque.UsageFrom(req.Context)
+		d.Record(req.Context, flow, AnomalySample{
+			Latency: d.now().Sub(start),
+			Usage: calque.Usage{
+				PromptTokens:     usage.PromptTokens - before.PromptTokens,
+				CompletionTokens: usage.CompletionTokens - before.CompletionTokens,
+				TotalTokens:      usage.TotalTokens - before.TotalTokens,
+				Calls:            usage.Calls - before.Calls,
+			},
+			Err: err,
+		})
+		return err
+	})
+}
+
+// Record adds one request outcome to the flow's series and reports any anomaly.
+//
+// Use it for work that does not run as a handler.
+func (d *AnomalyDetector) Record(ctx context.Context, flow string, sample AnomalySample) {
+	now := d.now()
+
+	values := map[string]float64{AnomalyErrorRate: 0}
+	if sample.Err != nil {
+		values[AnomalyErrorRate] = 1
+	} else {
+		values[AnomalyLatency] = sample.Latency.Seconds()
+		if sample.Usage.Calls > 0 {
+			values[AnomalyTokens] = float64(sample.Usage.TotalTokens)
+		}
+	}
+
+	var events []AnomalyEvent
+	d.mu.Lock()
+	for metric, value := range values {
+		key := anomalyKey{flow, metric}
+		s, ok := d.series[key]
+		if !ok {
+			s = &anomalySeries{}
+			d.series[key] = s
+		}
+		if event, anomalous := d.observe(s, key, value, now); anomalous {
+			events = append(events, event)
+		}
+	}
+	d.mu.Unlock()
+
+	d.emit(ctx, events)
+}
+
+// observe scores value against the series baseline, then learns from it.
+// Callers hold d.mu.
+func (d *AnomalyDetector) observe(s *anomalySeries, key anomalyKey, value float64, now time.Time) (AnomalyEvent, bool) {
+	// Recent statistic, including this sample
+	if len(s.recent) < d.cfg.Window {
+		s.recent = append(s.recent, value)
+	} else {
+		s.recent[s.next] = value
+		s.next = (s.next + 1) % d.cfg.Window
+	}
+	lambda := 2 / float64(d.cfg.Window+1)
+	if s.n == 0 {
+		s.ewma = value
+	} else {
+		s.ewma += lambda * (value - s.ewma)
+	}
+
+	var event AnomalyEvent
+	anomalous := false
+	if s.n >= int64(d.cfg.MinSamples) {
+		std := max(math.Sqrt(s.variance), 0.01*math.Abs(s.mean), anomalyStdFloor[key.metric])
+		var current, score float64
+		switch d.cfg.Method {
+		case EWMA:
+			current = s.ewma
+			score = (current - s.mean) / (std * math.Sqrt(lambda/(2-lambda)))
+		default:
+			if len(s.recent) == d.cfg.Window {
+				for _, v := range s.recent {
+					current += v
+				}
+				current /= float64(len(s.recent))
+				score = (current - s.mean) / (std / math.Sqrt(float64(len(s.recent))))
+			}
+		}
+		if score >= d.cfg.Threshold || (key.metric != AnomalyErrorRate && -score >= d.cfg.Threshold) {
+			anomalous = true
+			event = AnomalyEvent{
+				Flow:      key.flow,
+				Metric:    key.metric,
+				Method:    d.cfg.Method.String(),
+				Value:     current,
+				Baseline:  s.mean,
+				StdDev:    std,
+				Score:     score,
+				Samples:   s.n + 1,
+				Timestamp: now,
+			}
+		}
+	}
+
+	// Incremental mean and variance: exact while learning, then exponentially weighted
+	s.n++
+	weight := 1 / float64(min(s.n, int64(d.cfg.BaselineSize)))
+	diff := value - s.mean
+	s.mean += weight * diff
+	s.variance = (1 - weight) * (s.variance + weight*diff*diff)
+	return event, anomalous
+}
+
+// anomalyStdFloor is the smallest standard deviation assumed per metric:
+// 1ms of latency, one token, and the spread of a 2% error rate
+var anomalyStdFloor = map[string]float64{
+	AnomalyLatency:   0.001,
+	AnomalyTokens:    1,
+	AnomalyErrorRate: 0.14,
+}
+
+// Baseline returns the learned mean and standard deviation of a flow's metric,
+// and the number of samples seen
+func (d *AnomalyDetector) Baseline(flow, metric string) (mean, stdDev float64, samples int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, ok := d.series[anomalyKey{flow, metric}]
+	if !ok {
+		return 0, 0, 0
+	}
+	return s.mean, math.Sqrt(s.variance), s.n
+}
+
+// emit counts anomalies and delivers notifications outside the lock
+func (d *AnomalyDetector) emit(ctx context.Context, events []AnomalyEvent) {
+	for _, event := range events {
+		if p := d.cfg.Provider; p != nil {
+			p.Counter(ctx, metricName(d.cfg.Metrics, "anomalies_total"), 1,
+				d.cfg.Metrics.Labels.Merge(Labels{"flow": event.Flow, "metric": event.Metric}))
+		}
+		if !d.shouldNotify(event) {
+			continue
+		}
+		calque.LogWarn(ctx, "flow metric anomaly", "flow", event.Flow, "metric", event.Metric,
+			"value", event.Value, "baseline", event.Baseline, "score", event.Score)
+		if d.cfg.Notifier == nil {
+			continue
+		}
+		go func(event AnomalyEvent) {
+			notifyCtx := context.WithoutCancel(ctx)
+			if err := d.cfg.Notifier.NotifyAnomaly(notifyCtx, event); err != nil {
+				calque.LogWarn(notifyCtx, "failed to deliver anomaly event", "flow", event.Flow, "metric", event.Metric, "error", err)
+			}
+		}(event)
+	}
+}
+
+// shouldNotify applies the cooldown per flow and metric
+func (d *AnomalyDetector) shouldNotify(event AnomalyEvent) bool {
+	key := anomalyKey{event.Flow, event.Metric}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if last, ok := d.lastFired[key]; ok && event.Timestamp.Sub(last) < d.cfg.Cooldown {
+		return false
+	}
+	d.lastFired[key] = event.Timestamp
+	return true
+}
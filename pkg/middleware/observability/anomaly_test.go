@@ -0,0 +1,275 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// newTestDetector returns a detector whose clock the test controls and whose
+// notifications arrive on the returned channel
+func newTestDetector(opts ...AnomalyOption) (*AnomalyDetector, *time.Time, chan AnomalyEvent) {
+	events := make(chan AnomalyEvent, 100)
+	notifier := AnomalyNotifierFunc(func(_ context.Context, event AnomalyEvent) error {
+		events <- event
+		return nil
+	})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	detector := NewAnomalyDetector(append([]AnomalyOption{WithAnomalyNotifier(notifier)}, opts...)...)
+	detector.now = func() time.Time { return now }
+	return detector, &now, events
+}
+
+// recordLatencies records successful requests cycling through latencies (in milliseconds)
+func recordLatencies(d *AnomalyDetector, n int, millis ...int) {
+	for i := range n {
+		latency := time.Duration(millis[i%len(millis)]) * time.Millisecond
+		d.Record(context.Background(), "chat", AnomalySample{Latency: latency})
+	}
+}
+
+func recordErrors(d *AnomalyDetector, n int, failEvery int) {
+	for i := range n {
+		var err error
+		if failEvery > 0 && i%failEvery == 0 {
+			err = errors.New("model unavailable")
+		}
+		d.Record(context.Background(), "chat", AnomalySample{Err: err})
+	}
+}
+
+func TestAnomalyBaseline(t *testing.T) {
+	t.Parallel()
+
+	detector, _, _ := newTestDetector()
+	recordLatencies(detector, 200, 900, 1100)
+
+	mean, std, samples := detector.Baseline("chat", AnomalyLatency)
+	if !approxEqual(mean, 1) || !approxEqual(std, 0.1) || samples != 200 {
+		t.Errorf("Baseline() = %v, %v, %d; want 1, 0.1, 200", mean, std, samples)
+	}
+	if _, _, samples := detector.Baseline("chat", AnomalyTokens); samples != 0 {
+		t.Errorf("tokens samples = %d, want 0 without model usage", samples)
+	}
+	if _, _, samples := detector.Baseline("other", AnomalyLatency); samples != 0 {
+		t.Errorf("other flow samples = %d, want 0", samples)
+	}
+}
+
+func TestAnomalyDetection(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		method    AnomalyMethod
+		baseline  func(d *AnomalyDetector)
+		shift     func(d *AnomalyDetector)
+		metric    string
+		wantEvent bool
+		wantSign  float64
+	}{
+		{
+			name:      "zscore latency regression",
+			method:    ZScore,
+			baseline:  func(d *AnomalyDetector) { recordLatencies(d, 200, 900, 1100) },
+			shift:     func(d *AnomalyDetector) { recordLatencies(d, 30, 1500, 1700) },
+			metric:    AnomalyLatency,
+			wantEvent: true,
+			wantSign:  1,
+		},
+		{
+			name:      "ewma small sustained shift",
+			method:    EWMA,
+			baseline:  func(d *AnomalyDetector) { recordLatencies(d, 200, 900, 1100) },
+			shift:     func(d *AnomalyDetector) { recordLatencies(d, 60, 1050, 1250) },
+			metric:    AnomalyLatency,
+			wantEvent: true,
+			wantSign:  1,
+		},
+		{
+			name:     "normal variation",
+			method:   ZScore,
+			baseline: func(d *AnomalyDetector) { recordLatencies(d, 200, 900, 1100) },
+			shift:    func(d *AnomalyDetector) { recordLatencies(d, 60, 1100, 900, 1000) },
+		},
+		{
+			name:     "too few samples",
+			method:   ZScore,
+			baseline: func(d *AnomalyDetector) { recordLatencies(d, 50, 900, 1100) },
+			shift:    func(d *AnomalyDetector) { recordLatencies(d, 40, 5000) },
+		},
+		{
+			name:     "single error on a clean baseline",
+			method:   EWMA,
+			baseline: func(d *AnomalyDetector) { recordErrors(d, 200, 0) },
+			shift:    func(d *AnomalyDetector) { recordErrors(d, 30, 30) },
+		},
+		{
+			name:      "error rate spike",
+			method:    ZScore,
+			baseline:  func(d *AnomalyDetector) { recordErrors(d, 200, 100) },
+			shift:     func(d *AnomalyDetector) { recordErrors(d, 30, 3) },
+			metric:    AnomalyErrorRate,
+			wantEvent: true,
+			wantSign:  1,
+		},
+		{
+			name:     "error rate drop is not an anomaly",
+			method:   ZScore,
+			baseline: func(d *AnomalyDetector) { recordErrors(d, 200, 2) },
+			shift:    func(d *AnomalyDetector) { recordErrors(d, 60, 0) },
+		},
+		{
+			name:   "token drop",
+			method: EWMA,
+			baseline: func(d *AnomalyDetector) {
+				for i := range 200 {
+					d.Record(context.Background(), "chat", AnomalySample{Usage: calque.Usage{TotalTokens: 400 + i%3*10, Calls: 1}})
+				}
+			},
+			shift: func(d *AnomalyDetector) {
+				for range 30 {
+					d.Record(context.Background(), "chat", AnomalySample{Usage: calque.Usage{TotalTokens: 40, Calls: 1}})
+				}
+			},
+			metric:    AnomalyTokens,
+			wantEvent: true,
+			wantSign:  -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			detector, _, events := newTestDetector(WithAnomalyMethod(tt.method))
+			tt.baseline(detector)
+			tt.shift(detector)
+
+			select {
+			case event := <-events:
+				if !tt.wantEvent {
+					t.Fatalf("unexpected anomaly %+v", event)
+				}
+				if event.Flow != "chat" || event.Metric != tt.metric || event.Method != tt.method.String() ||
+					math.Signbit(event.Score) != math.Signbit(tt.wantSign) || math.Abs(event.Score) < 3 {
+					t.Errorf("event = %+v", event)
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.wantEvent {
+					t.Fatal("no anomaly reported")
+				}
+			}
+		})
+	}
+}
+
+func TestAnomalyCooldown(t *testing.T) {
+	t.Parallel()
+
+	provider := NewInMemoryMetricsProvider()
+	detector, now, events := newTestDetector(
+		WithAnomalyMetrics(provider, WithMetricsLabels(Labels{"service": "api"})),
+		WithAnomalyWindow(10),
+		WithBaseline(1000, 50),
+		WithAnomalyCooldown(time.Hour),
+	)
+	recordLatencies(detector, 100, 900, 1100)
+	recordLatencies(detector, 20, 3000)
+
+	labels := map[string]string{"service": "api", "flow": "chat", "metric": AnomalyLatency}
+	counted := provider.GetCounter("calque_flow_anomalies_total", labels)
+	if counted < 2 {
+		t.Errorf("anomalies counter = %d, want every anomalous request counted", counted)
+	}
+
+	<-events
+	select {
+	case event := <-events:
+		t.Fatalf("notified again within the cooldown: %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	*now = now.Add(2 * time.Hour)
+	recordLatencies(detector, 1, 3000)
+	select {
+	case <-events:
+	case <-time.After(time.Second):
+		t.Fatal("no notification after the cooldown")
+	}
+}
+
+func TestAnomalyHandler(t *testing.T) {
+	t.Parallel()
+
+	detector, _, _ := newTestDetector()
+	fail := false
+	handler := detector.Handler("chat", calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		if fail {
+			return errors.New("model unavailable")
+		}
+		calque.RecordUsage(req.Context, calque.Usage{PromptTokens: 70, CompletionTokens: 30, TotalTokens: 100, Calls: 1})
+		return passThrough(req, res)
+	}))
+
+	// A bus already on the context is reused, and usage recorded before the handler is not counted
+	ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+	calque.RecordUsage(ctx, calque.Usage{TotalTokens: 5000, Calls: 1})
+	var out string
+	if err := calque.NewFlow().Use(handler).Run(ctx, "hi", &out); err != nil || out != "hi" {
+		t.Fatalf("Run() = %q, %v", out, err)
+	}
+	if err := calque.NewFlow().Use(handler).Run(context.Background(), "hi", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	fail = true
+	if err := calque.NewFlow().Use(handler).Run(context.Background(), "hi", &out); err == nil {
+		t.Fatal("expected the handler error")
+	}
+
+	if mean, _, samples := detector.Baseline("chat", AnomalyTokens); mean != 100 || samples != 2 {
+		t.Errorf("tokens baseline = %v over %d samples, want 100 over 2", mean, samples)
+	}
+	if _, _, samples := detector.Baseline("chat", AnomalyLatency); samples != 2 {
+		t.Errorf("latency samples = %d, want 2 successful requests", samples)
+	}
+	if mean, _, samples := detector.Baseline("chat", AnomalyErrorRate); !approxEqual(mean, 1.0/3) || samples != 3 {
+		t.Errorf("error rate baseline = %v over %d samples, want 0.33 over 3", mean, samples)
+	}
+}
+
+func TestWebhookAlerterAnomaly(t *testing.T) {
+	t.Parallel()
+
+	var received AnomalyEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	var notifier AnomalyNotifier = &WebhookAlerter{URL: server.URL}
+	err := notifier.NotifyAnomaly(context.Background(), AnomalyEvent{Flow: "chat", Metric: AnomalyLatency, Score: 4.2})
+	if err != nil {
+		t.Fatalf("NotifyAnomaly() error = %v", err)
+	}
+	if received.Flow != "chat" || received.Metric != AnomalyLatency || received.Score != 4.2 {
+		t.Errorf("received %+v", received)
+	}
+}
+
+func TestAnomalyMethodString(t *testing.T) {
+	t.Parallel()
+
+	if ZScore.String() != "zscore" || EWMA.String() != "ewma" || AnomalyMethod(5).String() != "AnomalyMethod(5)" {
+		t.Errorf("String() = %s, %s, %s", ZScore, EWMA, AnomalyMethod(5))
+	}
+}
@@ -4,9 +4,12 @@
 package observability
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
 )
 
 // MetricsConfig configures the metrics middleware behavior.
@@ -92,6 +95,11 @@ func WithMetricsLabels(labels Labels) MetricsOption {
 //     - Goes up when request starts, down when it finishes
 //     - Example: 5 requests currently processing
 //
+//  5. calque_flow_cancellations_total (Counter)
+//     - Counts failed requests whose caller cancelled or hit its deadline
+//     - Labeled reason="canceled" or reason="deadline_exceeded"
+//     - Example: work abandoned after a remote client gave up
+//
 // Example:
 //
 //	provider := observability.NewPrometheusProvider()
@@ -134,6 +142,9 @@ func Metrics(provider MetricsProvider, labels map[string]string, opts ...Metrics
 		if handlerErr != nil {
 			errorLabels := allLabels.Merge(Labels{"error_type": errorType(handlerErr)})
 			provider.Counter(ctx, metricName(cfg, "errors_total"), 1, errorLabels)
+			if reason := cancelReason(handlerErr); reason != "" {
+				provider.Counter(ctx, metricName(cfg, "cancellations_total"), 1, allLabels.Merge(Labels{"reason": reason}))
+			}
 		}
 
 		return handlerErr
@@ -178,12 +189,18 @@ func Metrics(provider MetricsProvider, labels map[string]string, opts ...Metrics
 //  3. calque_flow_errors_total (Counter)
 //     - Counts requests that failed
 //     - Only incremented when handler returns an error
+//     - Labeled stage="<name>" when a ctrl.NamedChain stage failed
 //     - Example: 23 errors total
 //
 //  4. calque_flow_in_flight_requests (Gauge)
 //     - Shows how many requests are currently being processed
 //     - Goes up when request starts, down when it finishes
 //     - Example: 5 requests currently processing
+//
+//  5. calque_flow_cancellations_total (Counter)
+//     - Counts failed requests whose caller cancelled or hit its deadline
+//     - Labeled reason="canceled" or reason="deadline_exceeded"
+//     - Example: work abandoned after a remote client gave up
 func MetricsHandler(provider MetricsProvider, labels map[string]string, handler calque.Handler, opts ...MetricsOption) calque.Handler {
 	cfg := DefaultMetricsConfig()
 	for _, opt := range opts {
@@ -192,7 +209,7 @@ func MetricsHandler(provider MetricsProvider, labels map[string]string, handler
 
 	allLabels := cfg.Labels.Merge(Labels(labels))
 
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		ctx := req.Context
 		start := time.Now()
 
@@ -213,11 +230,20 @@ func MetricsHandler(provider MetricsProvider, labels map[string]string, handler
 
 		if handlerErr != nil {
 			errorLabels := allLabels.Merge(Labels{"error_type": errorType(handlerErr)})
+			if stage, ok := ctrl.FailedStage(handlerErr); ok {
+				errorLabels = errorLabels.Merge(Labels{"stage": stage.Name})
+			}
 			provider.Counter(ctx, metricName(cfg, "errors_total"), 1, errorLabels)
+			if reason := cancelReason(handlerErr); reason != "" {
+				provider.Counter(ctx, metricName(cfg, "cancellations_total"), 1, allLabels.Merge(Labels{"reason": reason}))
+			}
 		}
 
 		return handlerErr
 	})
+	return calque.Described(h, func() calque.Node {
+		return calque.Node{Label: "metrics", Kind: calque.NodeSequence, Children: []calque.Node{calque.DescribeHandler(handler)}}
+	}, handler)
 }
 
 // metricName builds the full metric name with namespace and subsystem
@@ -246,6 +272,18 @@ func errorType(err error) string {
 	return "unknown"
 }
 
+// cancelReason labels errors caused by context cancellation, or returns "" for other errors
+func cancelReason(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return ""
+	}
+}
+
 // passThrough copies data from request to response (middleware pattern)
 func passThrough(req *calque.Request, res *calque.Response) error {
 	var input string
@@ -2,10 +2,12 @@ package observability
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
 )
 
 func TestMetricsHandler(t *testing.T) {
@@ -84,6 +86,48 @@ func TestMetricsHandlerWithError(t *testing.T) {
 	}
 }
 
+func TestMetricsHandlerCancellations(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		err    error
+		reason string
+	}{
+		{name: "canceled", err: context.Canceled, reason: "canceled"},
+		{name: "deadline", err: context.DeadlineExceeded, reason: "deadline_exceeded"},
+		{name: "other error", err: errors.New("boom")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			provider := NewInMemoryMetricsProvider()
+			labels := map[string]string{"service": "test"}
+			handler := MetricsHandler(provider, labels, calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+				return calque.WrapErr(req.Context, tt.err, "flow stopped")
+			}))
+
+			req := calque.NewRequest(context.Background(), strings.NewReader("input"))
+			if err := handler.ServeFlow(req, calque.NewResponse(calque.NewWriter[string]())); err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+
+			for _, reason := range []string{"canceled", "deadline_exceeded"} {
+				want := int64(0)
+				if reason == tt.reason {
+					want = 1
+				}
+				got := provider.GetCounter("calque_flow_cancellations_total", map[string]string{"service": "test", "reason": reason})
+				if got != want {
+					t.Errorf("cancellations{reason=%q} = %d, want %d", reason, got, want)
+				}
+			}
+		})
+	}
+}
+
 func TestMetricsConfig(t *testing.T) {
 	t.Parallel()
 
@@ -170,3 +214,25 @@ func TestLabels_Merge(t *testing.T) {
 		t.Errorf("Expected c=4, got c=%s", merged["c"])
 	}
 }
+
+func TestMetricsHandlerStageLabel(t *testing.T) {
+	t.Parallel()
+
+	provider := NewInMemoryMetricsProvider()
+	labels := map[string]string{"service": "test"}
+
+	failing := calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+		return calque.NewErr(req.Context, "model unavailable")
+	})
+	handler := MetricsHandler(provider, labels, ctrl.NamedChain(ctrl.Stage("retrieve", ctrl.PassThrough()), ctrl.Stage("generate", failing)))
+
+	var out string
+	if err := calque.NewFlow().Use(handler).Run(context.Background(), "input", &out); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	got := provider.GetCounter("calque_flow_errors_total", map[string]string{"service": "test", "error_type": "unknown", "stage": "generate"})
+	if got != 1 {
+		t.Errorf("errors_total{stage=generate} = %d, want 1", got)
+	}
+}
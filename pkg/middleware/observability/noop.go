@@ -3,6 +3,8 @@ package observability
 
 import (
 	"context"
+	"maps"
+	"slices"
 	"sync"
 	"time"
 )
@@ -150,8 +152,8 @@ func (p *InMemoryMetricsProvider) Reset() {
 // metricsKey builds a key from metric name and labels
 func metricsKey(name string, labels map[string]string) string {
 	key := name
-	for k, v := range labels {
-		key += "|" + k + "=" + v
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		key += "|" + k + "=" + labels[k]
 	}
 	return key
 }
@@ -0,0 +1,75 @@
+package observability
+
+import (
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+// RateLimitMetrics wraps a provider-calling handler and records the quota
+// state its responses report.
+//
+// Provider clients record x-ratelimit-* style headers with
+// ctrl.RecordRateLimit; this handler turns the latest values into metrics:
+//
+//  1. calque_flow_ratelimit_remaining_requests (Gauge)
+//     - Requests left in the provider's current window
+//
+//  2. calque_flow_ratelimit_remaining_tokens (Gauge)
+//     - Tokens left in the provider's current window
+//
+//  3. calque_flow_ratelimit_exhausted_total (Counter)
+//     - Responses that reported an exhausted quota or a retry-after
+//
+//  4. calque_flow_ratelimit_wait_seconds (Histogram)
+//     - How long the provider asked callers to hold off
+//
+// Example:
+//
+//	flow.Use(observability.RateLimitMetrics(provider, map[string]string{"provider": "openai"}, ai.Agent(client)))
+func RateLimitMetrics(provider MetricsProvider, labels map[string]string, handler calque.Handler, opts ...MetricsOption) calque.Handler {
+	cfg := DefaultMetricsConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	allLabels := cfg.Labels.Merge(Labels(labels))
+
+	// Provider gauges add rather than set, so report changes since the last value
+	var mu sync.Mutex
+	var lastRequests, lastTokens float64
+
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		ctx := req.Context
+		start := time.Now()
+		handlerErr := handler.ServeFlow(req, res)
+
+		info, ok := ctrl.RateLimitFromContext(ctx)
+		if !ok || info.Observed.Before(start) {
+			return handlerErr
+		}
+
+		mu.Lock()
+		if info.Requests.Limit > 0 {
+			remaining := float64(info.Requests.Remaining)
+			provider.Gauge(ctx, metricName(cfg, "ratelimit_remaining_requests"), remaining-lastRequests, allLabels)
+			lastRequests = remaining
+		}
+		if info.Tokens.Limit > 0 {
+			remaining := float64(info.Tokens.Remaining)
+			provider.Gauge(ctx, metricName(cfg, "ratelimit_remaining_tokens"), remaining-lastTokens, allLabels)
+			lastTokens = remaining
+		}
+		mu.Unlock()
+
+		if wait := info.Wait(); wait > 0 {
+			provider.Counter(ctx, metricName(cfg, "ratelimit_exhausted_total"), 1, allLabels)
+			provider.Histogram(ctx, metricName(cfg, "ratelimit_wait_seconds"), wait.Seconds(), allLabels)
+		}
+		return handlerErr
+	})
+	return calque.Described(h, func() calque.Node {
+		return calque.Node{Label: "rate limit metrics", Kind: calque.NodeSequence, Children: []calque.Node{calque.DescribeHandler(handler)}}
+	}, handler)
+}
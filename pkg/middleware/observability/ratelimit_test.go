@@ -0,0 +1,61 @@
+package observability
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+func TestRateLimitMetrics(t *testing.T) {
+	t.Parallel()
+
+	remaining := []int{5, 0}
+	calls := 0
+	inner := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		ctrl.RecordRateLimit(req.Context, ctrl.RateLimitInfo{
+			Requests: ctrl.RateLimitWindow{Limit: 10, Remaining: remaining[calls], Reset: 2 * time.Second},
+			Observed: time.Now(),
+		})
+		calls++
+		return calque.Write(res, "ok")
+	})
+
+	provider := NewInMemoryMetricsProvider()
+	labels := map[string]string{"provider": "openai"}
+	flow := calque.NewFlow().Use(RateLimitMetrics(provider, labels, inner))
+
+	for range remaining {
+		var out string
+		if err := flow.Run(context.Background(), "hi", &out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := provider.GetGauge("calque_flow_ratelimit_remaining_requests", labels); got != 0 {
+		t.Errorf("remaining requests gauge = %v, want 0", got)
+	}
+	if got := provider.GetCounter("calque_flow_ratelimit_exhausted_total", labels); got != 1 {
+		t.Errorf("exhausted counter = %d, want 1", got)
+	}
+	if got := provider.GetHistogram("calque_flow_ratelimit_wait_seconds", labels); len(got) != 1 || got[0] != 2 {
+		t.Errorf("wait histogram = %v, want [2]", got)
+	}
+}
+
+func TestRateLimitMetricsWithoutHeaders(t *testing.T) {
+	t.Parallel()
+
+	provider := NewInMemoryMetricsProvider()
+	flow := calque.NewFlow().Use(RateLimitMetrics(provider, nil, calque.HandlerFunc(passThrough)))
+
+	var out string
+	if err := flow.Run(context.Background(), "hi", &out); err != nil || out != "hi" {
+		t.Fatalf("Run() = %q, %v", out, err)
+	}
+	if got := provider.GetCounter("calque_flow_ratelimit_exhausted_total", nil); got != 0 {
+		t.Errorf("exhausted counter = %d, want 0", got)
+	}
+}
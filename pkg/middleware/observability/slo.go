@@ -0,0 +1,613 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// SLO objectives
+const (
+	// SLOSuccess is the fraction of requests that must complete without error
+	SLOSuccess = "success"
+	// SLOLatency is the fraction of successful requests that must finish under the latency threshold
+	SLOLatency = "latency"
+)
+
+// sloBucketWidth is the resolution of the sliding windows
+const sloBucketWidth = time.Minute
+
+// BurnRateAlert fires when the error budget burns at Factor times the
+// sustainable rate over both the long and the short window.
+//
+// The short window makes the alert reset quickly once the problem is fixed.
+type BurnRateAlert struct {
+	Name        string        // Alert name, e.g. "fast-burn"
+	Severity    string        // Free-form severity passed to the alerter, e.g. "page" or "ticket"
+	LongWindow  time.Duration // e.g. 1h
+	ShortWindow time.Duration // e.g. 5m
+	Factor      float64       // Burn rate threshold, e.g. 14.4
+}
+
+// DefaultBurnRateAlerts returns the multi-window alerts recommended by the
+// Google SRE workbook for a 30 day budget: 2% of the budget spent in an hour,
+// or 5% in six hours.
+func DefaultBurnRateAlerts() []BurnRateAlert {
+	return []BurnRateAlert{
+		{Name: "fast-burn", Severity: "page", LongWindow: time.Hour, ShortWindow: 5 * time.Minute, Factor: 14.4},
+		{Name: "slow-burn", Severity: "page", LongWindow: 6 * time.Hour, ShortWindow: 30 * time.Minute, Factor: 6},
+	}
+}
+
+// SLOConfig configures an SLO.
+type SLOConfig struct {
+	// SuccessTarget is the fraction of requests that must succeed.
+	// Default: 0.99
+	SuccessTarget float64
+
+	// LatencyThreshold is the slowest acceptable successful request.
+	// Default: 0 (no latency objective)
+	LatencyThreshold time.Duration
+
+	// LatencyTarget is the fraction of successful requests that must finish
+	// within LatencyThreshold.
+	// Default: 0.99
+	LatencyTarget float64
+
+	// BudgetWindow is the window the remaining error budget is computed over.
+	// Events are kept in memory, so long windows are better served by
+	// recording rules on the exported event counters.
+	// Default: 24 hours
+	BudgetWindow time.Duration
+
+	// Alerts are the burn-rate conditions evaluated after requests complete.
+	// Default: DefaultBurnRateAlerts()
+	Alerts []BurnRateAlert
+
+	// Alerter receives alerts as they fire. Delivery happens in the background.
+	// Default: nil (alerts are only counted in metrics)
+	Alerter Alerter
+
+	// AlertCooldown is the minimum time between two notifications for the
+	// same alert and objective.
+	// Default: 1 hour
+	AlertCooldown time.Duration
+
+	// EvaluationInterval limits how often burn rates are recomputed.
+	// Default: 10 seconds
+	EvaluationInterval time.Duration
+
+	// Provider receives SLO metrics. Default: nil (no metrics)
+	Provider MetricsProvider
+
+	// Metrics sets the namespace, subsystem and labels of SLO metrics.
+	// Default: DefaultMetricsConfig()
+	Metrics MetricsConfig
+}
+
+// DefaultSLOConfig returns the default SLO configuration
+func DefaultSLOConfig() SLOConfig {
+	return SLOConfig{
+		SuccessTarget:      0.99,
+		LatencyTarget:      0.99,
+		BudgetWindow:       24 * time.Hour,
+		Alerts:             DefaultBurnRateAlerts(),
+		AlertCooldown:      time.Hour,
+		EvaluationInterval: 10 * time.Second,
+		Metrics:            DefaultMetricsConfig(),
+	}
+}
+
+// SLOOption configures an SLO
+type SLOOption func(*SLOConfig)
+
+// WithSuccessObjective sets the fraction of requests that must succeed
+func WithSuccessObjective(target float64) SLOOption {
+	return func(cfg *SLOConfig) {
+		cfg.SuccessTarget = target
+	}
+}
+
+// WithLatencyObjective adds a latency objective: target fraction of
+// successful requests must finish within threshold
+func WithLatencyObjective(threshold time.Duration, target float64) SLOOption {
+	return func(cfg *SLOConfig) {
+		cfg.LatencyThreshold = threshold
+		cfg.LatencyTarget = target
+	}
+}
+
+// WithBudgetWindow sets the window the remaining error budget is computed over
+func WithBudgetWindow(window time.Duration) SLOOption {
+	return func(cfg *SLOConfig) {
+		cfg.BudgetWindow = window
+	}
+}
+
+// WithBurnRateAlerts replaces the default burn-rate alerts
+func WithBurnRateAlerts(alerts ...BurnRateAlert) SLOOption {
+	return func(cfg *SLOConfig) {
+		cfg.Alerts = alerts
+	}
+}
+
+// WithAlerter sets where fired alerts are sent
+func WithAlerter(alerter Alerter) SLOOption {
+	return func(cfg *SLOConfig) {
+		cfg.Alerter = alerter
+	}
+}
+
+// WithAlertCooldown sets the minimum time between repeated notifications
+func WithAlertCooldown(cooldown time.Duration) SLOOption {
+	return func(cfg *SLOConfig) {
+		cfg.AlertCooldown = cooldown
+	}
+}
+
+// WithSLOMetrics exports SLO metrics to provider
+func WithSLOMetrics(provider MetricsProvider, opts ...MetricsOption) SLOOption {
+	return func(cfg *SLOConfig) {
+		cfg.Provider = provider
+		for _, opt := range opts {
+			opt(&cfg.Metrics)
+		}
+	}
+}
+
+// SLOAlert describes a burn-rate alert that fired
+type SLOAlert struct {
+	SLO             string        `json:"slo"`              // SLO name
+	Objective       string        `json:"objective"`        // SLOSuccess or SLOLatency
+	Alert           string        `json:"alert"`            // BurnRateAlert.Name
+	Severity        string        `json:"severity"`         // BurnRateAlert.Severity
+	Target          float64       `json:"target"`           // Objective target, e.g. 0.99
+	Factor          float64       `json:"factor"`           // Burn rate threshold that was crossed
+	LongWindow      time.Duration `json:"long_window"`      // Long window length
+	ShortWindow     time.Duration `json:"short_window"`     // Short window length
+	LongBurnRate    float64       `json:"long_burn_rate"`   // Burn rate over the long window
+	ShortBurnRate   float64       `json:"short_burn_rate"`  // Burn rate over the short window
+	BudgetRemaining float64       `json:"budget_remaining"` // Fraction of the budget left; negative once exhausted
+	Timestamp       time.Time     `json:"timestamp"`        // When the alert fired
+}
+
+// Alerter delivers fired SLO alerts.
+//
+// Built-in implementations:
+//   - WebhookAlerter: POSTs the alert as JSON
+//   - AlerterFunc: Runs a custom function (for chat, paging or ticketing APIs)
+type Alerter interface {
+	Alert(ctx context.Context, alert SLOAlert) error
+}
+
+// AlerterFunc adapts a function to Alerter
+type AlerterFunc func(ctx context.Context, alert SLOAlert) error
+
+// Alert calls f(ctx, alert)
+func (f AlerterFunc) Alert(ctx context.Context, alert SLOAlert) error {
+	return f(ctx, alert)
+}
+
+// WebhookAlerter POSTs alerts as JSON to a URL.
+//
+// The body is the SLOAlert, or the AnomalyEvent when used as an
+// AnomalyNotifier, encoded as JSON. Any 2xx response counts as delivered.
+//
+// Example:
+//
+//	alerter := &observability.WebhookAlerter{
+//	    URL:     "https://alerts.internal/hooks/calque",
+//	    Headers: map[string]string{"Authorization": "Bearer " + token},
+//	}
+type WebhookAlerter struct {
+	URL          string            // Endpoint to POST to
+	Headers      map[string]string // Extra request headers
+	AlertTimeout time.Duration     // defaults to 10 seconds
+	Client       *http.Client
+}
+
+// Alert POSTs the alert to the webhook
+func (w *WebhookAlerter) Alert(ctx context.Context, alert SLOAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to marshal SLO alert")
+	}
+	return w.post(ctx, body)
+}
+
+// NotifyAnomaly POSTs the anomaly event to the webhook
+func (w *WebhookAlerter) NotifyAnomaly(ctx context.Context, event AnomalyEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to marshal anomaly event")
+	}
+	return w.post(ctx, body)
+}
+
+func (w *WebhookAlerter) post(ctx context.Context, body []byte) error {
+	timeout := w.AlertTimeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: timeout}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "webhook request failed")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return calque.NewErr(ctx, fmt.Sprintf("unexpected webhook status code: %d", resp.StatusCode))
+	}
+	return nil
+}
+
+// SLO tracks success-rate and latency objectives for a flow.
+//
+// Outcomes are counted in one-minute buckets covering the budget window and
+// the longest alert window. After a request completes, burn rates are
+// recomputed (at most once per evaluation interval), exported as gauges and
+// checked against the burn-rate alerts.
+//
+// The burn rate is the observed bad-event rate divided by the rate the
+// objective allows: 1 spends the budget exactly over the budget window,
+// 14.4 spends a 30 day budget in about two days.
+//
+// Metrics, when a provider is configured (labels include slo and objective):
+//
+//  1. calque_flow_slo_events_total (Counter)
+//     - Events counted against each objective
+//
+//  2. calque_flow_slo_bad_events_total (Counter)
+//     - Failed requests, or successful requests over the latency threshold
+//
+//  3. calque_flow_slo_burn_rate (Gauge, window label)
+//     - Burn rate over each alert window
+//
+//  4. calque_flow_slo_error_budget_remaining (Gauge)
+//     - Fraction of the budget left over the budget window; negative once exhausted
+//
+//  5. calque_flow_slo_alerts_total (Counter, alert label)
+//     - Alerts fired, including those suppressed by the cooldown
+//
+// Example:
+//
+//	slo := observability.NewSLO("support-chat",
+//	    observability.WithSuccessObjective(0.995),
+//	    observability.WithLatencyObjective(5*time.Second, 0.95),
+//	    observability.WithSLOMetrics(provider),
+//	    observability.WithAlerter(&observability.WebhookAlerter{URL: hookURL}),
+//	)
+//
+//	flow := calque.NewFlow().Use(slo.Handler(ai.Agent(client)))
+type SLO struct {
+	name   string
+	cfg    SLOConfig
+	labels Labels
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   []sloBucket
+	lastEval  time.Time
+	lastFired map[string]time.Time
+	gauges    map[sloGauge]float64 // Last reported gauge values, since provider gauges add
+}
+
+// sloBucket holds the outcomes of one minute
+type sloBucket struct {
+	slot   int64 // Minutes since the Unix epoch
+	total  int64
+	failed int64
+	slow   int64 // Successful requests over the latency threshold
+}
+
+// sloCounts sums buckets over a window
+type sloCounts struct {
+	total, failed, slow int64
+}
+
+// NewSLO creates an SLO tracker named after the flow it measures.
+func NewSLO(name string, opts ...SLOOption) *SLO {
+	cfg := DefaultSLOConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	span := cfg.BudgetWindow
+	for _, alert := range cfg.Alerts {
+		span = max(span, alert.LongWindow, alert.ShortWindow)
+	}
+
+	return &SLO{
+		name:      name,
+		cfg:       cfg,
+		labels:    cfg.Metrics.Labels.Merge(Labels{"slo": name}),
+		now:       time.Now,
+		buckets:   make([]sloBucket, windowSlots(span)+1),
+		lastFired: make(map[string]time.Time),
+		gauges:    make(map[sloGauge]float64),
+	}
+}
+
+// Handler wraps a handler, recording its outcome and latency.
+//
+// Input: any data type (streaming)
+// Output: whatever handler writes
+// Behavior: STREAMING - the request counts as failed when handler returns an
+// error; latency is measured until handler returns
+//
+// Example:
+//
+//	flow := calque.NewFlow().Use(slo.Handler(ai.Agent(client)))
+func (s *SLO) Handler(handler calque.Handler) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		start := s.now()
+		err := handler.ServeFlow(req, res)
+		s.Record(req.Context, s.now().Sub(start), err)
+		return err
+	})
+}
+
+// Record counts one request with the given latency and outcome.
+//
+// Use it for work that does not run as a handler.
+func (s *SLO) Record(ctx context.Context, latency time.Duration, err error) {
+	now := s.now()
+	slow := err == nil && s.cfg.LatencyThreshold > 0 && latency > s.cfg.LatencyThreshold
+
+	s.mu.Lock()
+	b := s.bucket(now)
+	b.total++
+	if err != nil {
+		b.failed++
+	}
+	if slow {
+		b.slow++
+	}
+
+	var gauges map[sloGauge]float64
+	var fired []SLOAlert
+	if now.Sub(s.lastEval) >= s.cfg.EvaluationInterval {
+		s.lastEval = now
+		gauges, fired = s.evaluate(now)
+	}
+	s.mu.Unlock()
+
+	s.emit(ctx, err, slow, gauges, fired)
+}
+
+// BurnRate returns the burn rate of an objective over a window, rounded up
+// to whole minutes. It is 0 when the window holds no events.
+func (s *SLO) BurnRate(objective string, window time.Duration) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.burnRate(objective, s.sum(s.now(), window))
+}
+
+// BudgetRemaining returns the fraction of the error budget left over the
+// budget window: 1 when nothing was spent, negative once exhausted.
+func (s *SLO) BudgetRemaining(objective string) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.budgetRemaining(objective, s.sum(s.now(), s.cfg.BudgetWindow))
+}
+
+// objectives returns the objectives this SLO tracks
+func (s *SLO) objectives() []string {
+	if s.cfg.LatencyThreshold > 0 {
+		return []string{SLOSuccess, SLOLatency}
+	}
+	return []string{SLOSuccess}
+}
+
+// bucket returns the bucket for now, resetting it if it held an older minute
+func (s *SLO) bucket(now time.Time) *sloBucket {
+	slot := now.Unix() / int64(sloBucketWidth/time.Second)
+	b := &s.buckets[slot%int64(len(s.buckets))]
+	if b.slot != slot {
+		*b = sloBucket{slot: slot}
+	}
+	return b
+}
+
+// sum adds up the buckets covering window, ending at now
+func (s *SLO) sum(now time.Time, window time.Duration) sloCounts {
+	slot := now.Unix() / int64(sloBucketWidth/time.Second)
+	var counts sloCounts
+	for i := range min(int64(windowSlots(window)), int64(len(s.buckets))) {
+		b := s.buckets[(slot-i)%int64(len(s.buckets))]
+		if b.slot != slot-i {
+			continue
+		}
+		counts.total += b.total
+		counts.failed += b.failed
+		counts.slow += b.slow
+	}
+	return counts
+}
+
+// good and bad events for an objective, plus its target
+func (s *SLO) objectiveCounts(objective string, counts sloCounts) (total, bad int64, target float64) {
+	if objective == SLOLatency {
+		return counts.total - counts.failed, counts.slow, s.cfg.LatencyTarget
+	}
+	return counts.total, counts.failed, s.cfg.SuccessTarget
+}
+
+func (s *SLO) burnRate(objective string, counts sloCounts) float64 {
+	total, bad, target := s.objectiveCounts(objective, counts)
+	if total == 0 {
+		return 0
+	}
+	allowed := 1 - target
+	if allowed <= 0 {
+		if bad > 0 {
+			return math.Inf(1)
+		}
+		return 0
+	}
+	return float64(bad) / float64(total) / allowed
+}
+
+func (s *SLO) budgetRemaining(objective string, counts sloCounts) float64 {
+	total, bad, target := s.objectiveCounts(objective, counts)
+	if total == 0 || bad == 0 {
+		return 1
+	}
+	allowed := float64(total) * (1 - target)
+	if allowed <= 0 {
+		return math.Inf(-1)
+	}
+	return 1 - float64(bad)/allowed
+}
+
+// evaluate recomputes burn rates, returning gauge deltas and alerts to send.
+// Callers hold s.mu.
+func (s *SLO) evaluate(now time.Time) (map[sloGauge]float64, []SLOAlert) {
+	gauges := map[sloGauge]float64{}
+	setGauge := func(key sloGauge, value float64) {
+		if math.IsInf(value, 0) || math.IsNaN(value) {
+			return
+		}
+		if delta := value - s.gauges[key]; delta != 0 {
+			gauges[key] = delta
+			s.gauges[key] = value
+		}
+	}
+
+	var windows []time.Duration
+	for _, alert := range s.cfg.Alerts {
+		windows = append(windows, alert.LongWindow, alert.ShortWindow)
+	}
+	slices.Sort(windows)
+	windows = slices.Compact(windows)
+
+	var fired []SLOAlert
+	for _, objective := range s.objectives() {
+		rates := make(map[time.Duration]float64, len(windows))
+		for _, window := range windows {
+			rates[window] = s.burnRate(objective, s.sum(now, window))
+			setGauge(sloGauge{"slo_burn_rate", objective, window.String()}, rates[window])
+		}
+		budget := s.budgetRemaining(objective, s.sum(now, s.cfg.BudgetWindow))
+		setGauge(sloGauge{"slo_error_budget_remaining", objective, ""}, budget)
+
+		for _, alert := range s.cfg.Alerts {
+			long, short := rates[alert.LongWindow], rates[alert.ShortWindow]
+			if long < alert.Factor || short < alert.Factor {
+				continue
+			}
+			_, _, target := s.objectiveCounts(objective, sloCounts{})
+			fired = append(fired, SLOAlert{
+				SLO:             s.name,
+				Objective:       objective,
+				Alert:           alert.Name,
+				Severity:        alert.Severity,
+				Target:          target,
+				Factor:          alert.Factor,
+				LongWindow:      alert.LongWindow,
+				ShortWindow:     alert.ShortWindow,
+				LongBurnRate:    long,
+				ShortBurnRate:   short,
+				BudgetRemaining: budget,
+				Timestamp:       now,
+			})
+		}
+	}
+	return gauges, fired
+}
+
+// emit reports metrics and delivers alerts outside the lock
+func (s *SLO) emit(ctx context.Context, err error, slow bool, gauges map[sloGauge]float64, fired []SLOAlert) {
+	if p := s.cfg.Provider; p != nil {
+		success := s.labels.Merge(Labels{"objective": SLOSuccess})
+		p.Counter(ctx, metricName(s.cfg.Metrics, "slo_events_total"), 1, success)
+		if err != nil {
+			p.Counter(ctx, metricName(s.cfg.Metrics, "slo_bad_events_total"), 1, success)
+		}
+		if err == nil && s.cfg.LatencyThreshold > 0 {
+			latency := s.labels.Merge(Labels{"objective": SLOLatency})
+			p.Counter(ctx, metricName(s.cfg.Metrics, "slo_events_total"), 1, latency)
+			if slow {
+				p.Counter(ctx, metricName(s.cfg.Metrics, "slo_bad_events_total"), 1, latency)
+			}
+		}
+		for g, delta := range gauges {
+			p.Gauge(ctx, metricName(s.cfg.Metrics, g.name), delta, s.gaugeLabels(g))
+		}
+	}
+
+	for _, alert := range fired {
+		if p := s.cfg.Provider; p != nil {
+			p.Counter(ctx, metricName(s.cfg.Metrics, "slo_alerts_total"), 1,
+				s.labels.Merge(Labels{"objective": alert.Objective, "alert": alert.Alert}))
+		}
+		if !s.shouldNotify(alert) {
+			continue
+		}
+		calque.LogWarn(ctx, "SLO burn rate alert", "slo", s.name, "objective", alert.Objective,
+			"alert", alert.Alert, "long_burn_rate", alert.LongBurnRate, "short_burn_rate", alert.ShortBurnRate)
+		if s.cfg.Alerter == nil {
+			continue
+		}
+		go func(alert SLOAlert) {
+			alertCtx := context.WithoutCancel(ctx)
+			if err := s.cfg.Alerter.Alert(alertCtx, alert); err != nil {
+				calque.LogWarn(alertCtx, "failed to deliver SLO alert", "slo", s.name, "alert", alert.Alert, "error", err)
+			}
+		}(alert)
+	}
+}
+
+// shouldNotify applies the cooldown per alert and objective
+func (s *SLO) shouldNotify(alert SLOAlert) bool {
+	key := alert.Objective + "|" + alert.Alert
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if last, ok := s.lastFired[key]; ok && alert.Timestamp.Sub(last) < s.cfg.AlertCooldown {
+		return false
+	}
+	s.lastFired[key] = alert.Timestamp
+	return true
+}
+
+// sloGauge identifies one exported gauge series
+type sloGauge struct {
+	name      string
+	objective string
+	window    string // Empty for the budget gauge
+}
+
+func (s *SLO) gaugeLabels(g sloGauge) Labels {
+	labels := Labels{"objective": g.objective}
+	if g.window != "" {
+		labels["window"] = g.window
+	}
+	return s.labels.Merge(labels)
+}
+
+// windowSlots is the number of buckets covering window, rounded up
+func windowSlots(window time.Duration) int {
+	return max(int((window+sloBucketWidth-1)/sloBucketWidth), 1)
+}
@@ -0,0 +1,289 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// newTestSLO returns an SLO whose clock the test controls
+func newTestSLO(opts ...SLOOption) (*SLO, *time.Time) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	slo := NewSLO("chat", opts...)
+	slo.now = func() time.Time { return now }
+	return slo, &now
+}
+
+func recordN(slo *SLO, n int, latency time.Duration, err error) {
+	for range n {
+		slo.Record(context.Background(), latency, err)
+	}
+}
+
+func TestSLOBurnRate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		opts       []SLOOption
+		ok, failed int
+		slow       int
+		objective  string
+		wantBurn   float64
+		wantBudget float64
+	}{
+		{
+			name:       "no events",
+			objective:  SLOSuccess,
+			wantBurn:   0,
+			wantBudget: 1,
+		},
+		{
+			name:       "within budget",
+			ok:         995,
+			failed:     5,
+			objective:  SLOSuccess,
+			wantBurn:   0.5,
+			wantBudget: 0.5,
+		},
+		{
+			name:       "budget exhausted",
+			ok:         98,
+			failed:     2,
+			objective:  SLOSuccess,
+			wantBurn:   2,
+			wantBudget: -1,
+		},
+		{
+			name:       "latency ignores failures",
+			opts:       []SLOOption{WithLatencyObjective(time.Second, 0.9)},
+			ok:         80,
+			slow:       20,
+			failed:     50,
+			objective:  SLOLatency,
+			wantBurn:   2,
+			wantBudget: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			slo, _ := newTestSLO(tt.opts...)
+			recordN(slo, tt.ok, 10*time.Millisecond, nil)
+			recordN(slo, tt.slow, 2*time.Second, nil)
+			recordN(slo, tt.failed, 10*time.Millisecond, errors.New("boom"))
+
+			if got := slo.BurnRate(tt.objective, time.Hour); !approxEqual(got, tt.wantBurn) {
+				t.Errorf("BurnRate() = %v, want %v", got, tt.wantBurn)
+			}
+			if got := slo.BudgetRemaining(tt.objective); !approxEqual(got, tt.wantBudget) {
+				t.Errorf("BudgetRemaining() = %v, want %v", got, tt.wantBudget)
+			}
+		})
+	}
+}
+
+func TestSLOSlidingWindow(t *testing.T) {
+	t.Parallel()
+
+	slo, now := newTestSLO()
+	recordN(slo, 10, 0, errors.New("boom"))
+
+	*now = now.Add(10 * time.Minute)
+	recordN(slo, 10, 0, nil)
+
+	if got := slo.BurnRate(SLOSuccess, 5*time.Minute); got != 0 {
+		t.Errorf("5m burn rate = %v, want 0 once failures leave the window", got)
+	}
+	if got := slo.BurnRate(SLOSuccess, time.Hour); !approxEqual(got, 50) {
+		t.Errorf("1h burn rate = %v, want 50", got)
+	}
+
+	// Buckets are reused once the ring wraps; stale counts must not leak back in
+	*now = now.Add(25 * time.Hour)
+	recordN(slo, 1, 0, nil)
+	if got := slo.BudgetRemaining(SLOSuccess); got != 1 {
+		t.Errorf("BudgetRemaining() after a day = %v, want 1", got)
+	}
+}
+
+func TestSLOAlerts(t *testing.T) {
+	t.Parallel()
+
+	alerts := make(chan SLOAlert, 10)
+	slo, now := newTestSLO(
+		WithBurnRateAlerts(BurnRateAlert{Name: "fast-burn", Severity: "page", LongWindow: time.Hour, ShortWindow: 5 * time.Minute, Factor: 10}),
+		WithAlerter(AlerterFunc(func(_ context.Context, alert SLOAlert) error {
+			alerts <- alert
+			return nil
+		})),
+		WithAlertCooldown(30*time.Minute),
+	)
+
+	// Burn rates are recomputed on the first request after each evaluation interval.
+	// 5% errors burns a 99% objective at about 5x: below the threshold
+	recordN(slo, 95, 0, nil)
+	recordN(slo, 5, 0, errors.New("boom"))
+	*now = now.Add(time.Minute)
+	recordN(slo, 1, 0, nil)
+	select {
+	case alert := <-alerts:
+		t.Fatalf("unexpected alert %+v", alert)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// A burst of failures pushes both windows over 10x
+	*now = now.Add(time.Minute)
+	recordN(slo, 100, 0, errors.New("boom"))
+	*now = now.Add(time.Minute)
+	recordN(slo, 1, 0, errors.New("boom"))
+
+	select {
+	case alert := <-alerts:
+		if alert.SLO != "chat" || alert.Objective != SLOSuccess || alert.Alert != "fast-burn" || alert.Severity != "page" {
+			t.Errorf("alert = %+v", alert)
+		}
+		if alert.LongBurnRate < 10 || alert.ShortBurnRate < 10 {
+			t.Errorf("burn rates = %v/%v, want both >= 10", alert.LongBurnRate, alert.ShortBurnRate)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected an alert")
+	}
+
+	// Still burning, but inside the cooldown
+	*now = now.Add(10 * time.Minute)
+	recordN(slo, 10, 0, errors.New("boom"))
+	*now = now.Add(30 * time.Minute)
+	recordN(slo, 10, 0, errors.New("boom"))
+
+	select {
+	case <-alerts:
+	case <-time.After(time.Second):
+		t.Fatal("expected an alert after the cooldown")
+	}
+	select {
+	case alert := <-alerts:
+		t.Fatalf("unexpected alert during cooldown %+v", alert)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSLOMetrics(t *testing.T) {
+	t.Parallel()
+
+	provider := NewInMemoryMetricsProvider()
+	slo, now := newTestSLO(
+		WithSLOMetrics(provider, WithMetricsLabels(Labels{"service": "api"})),
+		WithLatencyObjective(time.Second, 0.9),
+		WithBurnRateAlerts(BurnRateAlert{Name: "fast-burn", LongWindow: time.Hour, ShortWindow: 5 * time.Minute, Factor: 1}),
+	)
+
+	// Each minute is past the evaluation interval, so the first request of each evaluates
+	recordN(slo, 1, 0, errors.New("boom"))
+	*now = now.Add(time.Minute)
+	recordN(slo, 1, 2*time.Second, nil)
+	*now = now.Add(time.Minute)
+	recordN(slo, 2, 0, nil)
+
+	success := map[string]string{"service": "api", "slo": "chat", "objective": SLOSuccess}
+	latency := map[string]string{"service": "api", "slo": "chat", "objective": SLOLatency}
+
+	if got := provider.GetCounter("calque_flow_slo_events_total", success); got != 4 {
+		t.Errorf("success events = %d, want 4", got)
+	}
+	if got := provider.GetCounter("calque_flow_slo_bad_events_total", success); got != 1 {
+		t.Errorf("success bad events = %d, want 1", got)
+	}
+	if got := provider.GetCounter("calque_flow_slo_events_total", latency); got != 3 {
+		t.Errorf("latency events = %d, want 3", got)
+	}
+	if got := provider.GetCounter("calque_flow_slo_bad_events_total", latency); got != 1 {
+		t.Errorf("latency bad events = %d, want 1", got)
+	}
+
+	// Gauges hold the last evaluation: 1 failure in 3 requests against a 1% allowance
+	hour := map[string]string{"service": "api", "slo": "chat", "objective": SLOSuccess, "window": "1h0m0s"}
+	if got := provider.GetGauge("calque_flow_slo_burn_rate", hour); !approxEqual(got, 100.0/3) {
+		t.Errorf("burn rate gauge = %v, want 33.3", got)
+	}
+	if got := provider.GetGauge("calque_flow_slo_error_budget_remaining", success); !approxEqual(got, 1-100.0/3) {
+		t.Errorf("budget gauge = %v, want -32.3", got)
+	}
+	fired := map[string]string{"service": "api", "slo": "chat", "objective": SLOSuccess, "alert": "fast-burn"}
+	if got := provider.GetCounter("calque_flow_slo_alerts_total", fired); got != 3 {
+		t.Errorf("alerts counter = %d, want 3", got)
+	}
+}
+
+func TestSLOHandler(t *testing.T) {
+	t.Parallel()
+
+	slo, _ := newTestSLO()
+	fail := false
+	handler := slo.Handler(calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		if fail {
+			return errors.New("model unavailable")
+		}
+		return passThrough(req, res)
+	}))
+
+	var out string
+	if err := calque.NewFlow().Use(handler).Run(context.Background(), "hi", &out); err != nil || out != "hi" {
+		t.Fatalf("Run() = %q, %v", out, err)
+	}
+	fail = true
+	if err := calque.NewFlow().Use(handler).Run(context.Background(), "hi", &out); err == nil {
+		t.Fatal("expected the handler error")
+	}
+
+	if got := slo.BurnRate(SLOSuccess, time.Hour); !approxEqual(got, 50) {
+		t.Errorf("BurnRate() = %v, want 50", got)
+	}
+}
+
+func TestWebhookAlerter(t *testing.T) {
+	t.Parallel()
+
+	var received SLOAlert
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if received.Alert == "reject" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	alerter := &WebhookAlerter{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer secret"}}
+
+	err := alerter.Alert(context.Background(), SLOAlert{SLO: "chat", Objective: SLOSuccess, Alert: "fast-burn", LongBurnRate: 20})
+	if err != nil {
+		t.Fatalf("Alert() error = %v", err)
+	}
+	if received.SLO != "chat" || received.LongBurnRate != 20 || auth != "Bearer secret" {
+		t.Errorf("received %+v with auth %q", received, auth)
+	}
+
+	err = alerter.Alert(context.Background(), SLOAlert{Alert: "reject"})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Alert() error = %v, want status 500", err)
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	const epsilon = 1e-6
+	return a-b < epsilon && b-a < epsilon
+}
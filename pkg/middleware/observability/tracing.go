@@ -19,6 +19,8 @@
 package observability
 
 import (
+	"context"
+
 	"github.com/calque-ai/go-calque/pkg/calque"
 )
 
@@ -111,6 +113,7 @@ func Tracing(provider TracerProvider, operationName string, opts ...TracingOptio
 
 		// Start a new span
 		ctx, span := provider.StartSpan(ctx, operationName, WithSpanKind(SpanKindInternal))
+		ctx = withSpanHooks(ctx, provider, span)
 
 		// Optionally record input
 		if cfg.RecordInput {
@@ -182,11 +185,12 @@ func TracingHandler(provider TracerProvider, operationName string, handler calqu
 		opt(&cfg)
 	}
 
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		ctx := req.Context
 
 		// Start a new span
 		ctx, span := provider.StartSpan(ctx, operationName, WithSpanKind(SpanKindInternal))
+		ctx = withSpanHooks(ctx, provider, span)
 
 		// Update request context with span context
 		req = req.WithContext(ctx)
@@ -236,6 +240,18 @@ func TracingHandler(provider TracerProvider, operationName string, handler calqu
 
 		return handlerErr
 	})
+	return calque.Described(h, func() calque.Node {
+		return calque.Node{Label: "span " + operationName, Kind: calque.NodeSequence, Children: []calque.Node{calque.DescribeHandler(handler)}}
+	}, handler)
+}
+
+// withSpanHooks exposes span to the handlers below, so they can add
+// attributes and start child spans (ai and tools add GenAI spans this way)
+func withSpanHooks(ctx context.Context, provider TracerProvider, span Span) context.Context {
+	ctx = calque.WithSpan(ctx, span)
+	return calque.WithSpanStarter(ctx, func(ctx context.Context, name string) (context.Context, calque.Span) {
+		return provider.StartSpan(ctx, name)
+	})
 }
 
 // truncate truncates a string to the given length
@@ -196,3 +196,31 @@ func TestTracingConfig(t *testing.T) {
 		t.Errorf("Expected MaxAttributeLength 1024, got %d", cfg.MaxAttributeLength)
 	}
 }
+
+func TestTracingHandlerExposesSpan(t *testing.T) {
+	t.Parallel()
+
+	provider := NewInMemoryTracerProvider()
+	inner := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		calque.SetSpanAttribute(req.Context, "gen_ai.operation.name", "workflow")
+
+		_, child := calque.StartSpan(req.Context, "chat gpt-4o")
+		child.SetAttribute("gen_ai.request.model", "gpt-4o")
+		child.End(nil)
+		return passThrough(req, res)
+	})
+
+	var out string
+	if err := calque.NewFlow().Use(TracingHandler(provider, "agent", inner)).Run(context.Background(), "hi", &out); err != nil {
+		t.Fatal(err)
+	}
+
+	parent := provider.GetSpansByName("agent")
+	if len(parent) != 1 || parent[0].Attributes["gen_ai.operation.name"] != "workflow" {
+		t.Errorf("parent spans = %+v", parent)
+	}
+	child := provider.GetSpansByName("chat gpt-4o")
+	if len(child) != 1 || child[0].Attributes["gen_ai.request.model"] != "gpt-4o" {
+		t.Errorf("child spans = %+v", child)
+	}
+}
@@ -0,0 +1,273 @@
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+	"text/template"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/memory"
+)
+
+// ConversationBuilder assembles a multi-turn chat prompt.
+//
+// Each turn is a Go template that receives the input as {{.Input}} plus any
+// data and placeholders. The handler emits ai.Messages JSON, which providers
+// send as separate system/user/assistant/tool messages rather than one
+// concatenated prompt.
+//
+// Example:
+//
+//	chat := prompt.Conversation().
+//		System("You are a support agent for {{.Product}}.").
+//		History(convMem, "").
+//		User("Context:\n{{.Docs}}\n\nQuestion: {{.Input}}").
+//		With(map[string]any{"Product": "calque"}).
+//		Placeholder("Docs", retrieval.VectorSearch(store, opts))
+//
+//	flow.Use(chat).Use(ai.Agent(client))
+type ConversationBuilder struct {
+	turns        []conversationTurn
+	data         map[string]any
+	placeholders []placeholder
+	err          error
+}
+
+// conversationTurn is either a templated message or a slot for stored history
+type conversationTurn struct {
+	message ai.Message
+	tmpl    *template.Template
+	history func(ctx context.Context, input string) ([]ai.Message, error)
+}
+
+// placeholder fills a template variable by running a handler on the input
+type placeholder struct {
+	name    string
+	handler calque.Handler
+}
+
+// Conversation creates an empty multi-turn chat template builder.
+//
+// Input: user input, available to every turn as {{.Input}}
+// Output: ai.Messages JSON ({"messages":[{"role":"system","content":"..."}, ...]})
+// Behavior: BUFFERED - reads the whole input, runs placeholders, then renders each turn
+//
+// Turns that render to only whitespace are dropped, so a template such as
+// "{{if .Docs}}Context: {{.Docs}}{{end}}" can be conditional.
+//
+// Example:
+//
+//	chat := prompt.Conversation().
+//		System("You are a helpful assistant.").
+//		User("{{.Input}}")
+func Conversation() *ConversationBuilder {
+	return &ConversationBuilder{data: map[string]any{}}
+}
+
+// System appends a system message template.
+func (cb *ConversationBuilder) System(tmpl string) *ConversationBuilder {
+	return cb.add(ai.Message{Role: ai.RoleSystem}, tmpl)
+}
+
+// User appends a user message template.
+func (cb *ConversationBuilder) User(tmpl string) *ConversationBuilder {
+	return cb.add(ai.Message{Role: ai.RoleUser}, tmpl)
+}
+
+// Assistant appends an assistant message template, e.g. a few-shot example answer.
+func (cb *ConversationBuilder) Assistant(tmpl string) *ConversationBuilder {
+	return cb.add(ai.Message{Role: ai.RoleAssistant}, tmpl)
+}
+
+// Tool appends a tool result message template.
+//
+// Input: tool name, the ID of the tool call it answers (may be empty), and a template
+//
+// Example:
+//
+//	prompt.Conversation().
+//		User("What's the weather in Paris?").
+//		Tool("weather", "call_1", "{{.Forecast}}")
+func (cb *ConversationBuilder) Tool(name, toolCallID, tmpl string) *ConversationBuilder {
+	return cb.add(ai.Message{Role: ai.RoleTool, Name: name, ToolCallID: toolCallID}, tmpl)
+}
+
+// Messages appends literal messages without template rendering.
+//
+// Example:
+//
+//	prompt.Conversation().
+//		System("Translate to French.").
+//		Messages(
+//			ai.Message{Role: ai.RoleUser, Content: "Good morning"},
+//			ai.Message{Role: ai.RoleAssistant, Content: "Bonjour"},
+//		).
+//		User("{{.Input}}")
+func (cb *ConversationBuilder) Messages(messages ...ai.Message) *ConversationBuilder {
+	history := append([]ai.Message(nil), messages...)
+	cb.turns = append(cb.turns, conversationTurn{history: func(context.Context, string) ([]ai.Message, error) {
+		return history, nil
+	}})
+	return cb
+}
+
+// History inserts the stored conversation for key at this position.
+//
+// Input: conversation memory and key; an empty key uses memory.GetKey(ctx)
+// Behavior: Inserts the turns stored before this request, then records the
+// input as a user message like convMem.Input does
+//
+// Pair it with convMem.Output to record the assistant's reply.
+//
+// Example:
+//
+//	chat := prompt.Conversation().
+//		System("You are a helpful assistant.").
+//		History(convMem, "user123").
+//		User("{{.Input}}")
+//
+//	flow.Use(chat).Use(ai.Agent(client)).Use(convMem.Output("user123"))
+func (cb *ConversationBuilder) History(conversations *memory.ConversationMemory, key string) *ConversationBuilder {
+	cb.turns = append(cb.turns, conversationTurn{history: func(ctx context.Context, input string) ([]ai.Message, error) {
+		historyKey := key
+		if historyKey == "" {
+			historyKey = memory.GetKey(ctx)
+		}
+		if historyKey == "" {
+			return nil, calque.NewErr(ctx, "no memory key found in context for conversation history")
+		}
+
+		stored, err := conversations.Messages(ctx, historyKey)
+		if err != nil {
+			return nil, err
+		}
+		messages := make([]ai.Message, 0, len(stored))
+		for _, msg := range stored {
+			messages = append(messages, ai.Message{Role: ai.Role(msg.Role), Content: msg.Text()})
+		}
+
+		// Record this turn; the transcript Input writes is not needed
+		if err := calque.NewFlow().Use(conversations.Input(historyKey)).Run(ctx, input, io.Discard); err != nil {
+			return nil, err
+		}
+		return messages, nil
+	}})
+	return cb
+}
+
+// With adds static template data, available to every turn as {{.Key}}.
+func (cb *ConversationBuilder) With(data map[string]any) *ConversationBuilder {
+	maps.Copy(cb.data, data)
+	return cb
+}
+
+// Placeholder fills {{.Name}} with the output of handler run on the input.
+//
+// Input: template variable name and a handler, typically a retrieval search or
+// memory lookup
+// Behavior: Placeholders run in order before rendering; an error fails the request
+//
+// Example:
+//
+//	prompt.Conversation().
+//		System("Answer using only this context:\n{{.Context}}").
+//		User("{{.Input}}").
+//		Placeholder("Context", retrieval.VectorSearch(store, opts))
+func (cb *ConversationBuilder) Placeholder(name string, handler calque.Handler) *ConversationBuilder {
+	cb.placeholders = append(cb.placeholders, placeholder{name: name, handler: handler})
+	return cb
+}
+
+// ServeFlow renders the conversation and writes it as ai.Messages JSON.
+func (cb *ConversationBuilder) ServeFlow(req *calque.Request, res *calque.Response) error {
+	if cb.err != nil {
+		return calque.WrapErr(req.Context, cb.err, "template parse error")
+	}
+
+	var input string
+	if err := calque.Read(req, &input); err != nil {
+		return calque.WrapErr(req.Context, err, "failed to read input")
+	}
+
+	data, err := cb.templateData(req.Context, input)
+	if err != nil {
+		return err
+	}
+
+	messages, err := cb.render(req.Context, input, data)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(ai.NewMessages(messages...))
+	if err != nil {
+		return calque.WrapErr(req.Context, err, "failed to marshal messages")
+	}
+	_, err = res.Data.Write(encoded)
+	return err
+}
+
+// add parses tmpl and appends it as a turn, keeping the first parse error for ServeFlow
+func (cb *ConversationBuilder) add(message ai.Message, tmpl string) *ConversationBuilder {
+	parsed, err := template.New(string(message.Role)).Parse(tmpl)
+	if err != nil {
+		if cb.err == nil {
+			cb.err = fmt.Errorf("%s message %d: %w", message.Role, len(cb.turns), err)
+		}
+		return cb
+	}
+	cb.turns = append(cb.turns, conversationTurn{message: message, tmpl: parsed})
+	return cb
+}
+
+// templateData combines the input, static data and placeholder outputs
+func (cb *ConversationBuilder) templateData(ctx context.Context, input string) (map[string]any, error) {
+	data := maps.Clone(cb.data)
+	data["Input"] = input
+	if _, ok := data["Now"]; !ok {
+		data["Now"] = calque.Now(ctx)
+	}
+
+	for _, p := range cb.placeholders {
+		var output string
+		if err := calque.NewFlow().Use(p.handler).Run(ctx, input, &output); err != nil {
+			return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to fill placeholder %s", p.name))
+		}
+		data[p.name] = output
+	}
+	return data, nil
+}
+
+// render expands history slots and executes each turn's template
+func (cb *ConversationBuilder) render(ctx context.Context, input string, data map[string]any) ([]ai.Message, error) {
+	var messages []ai.Message
+	for i, turn := range cb.turns {
+		if turn.history != nil {
+			history, err := turn.history(ctx, input)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, history...)
+			continue
+		}
+
+		var content bytes.Buffer
+		if err := turn.tmpl.Execute(&content, data); err != nil {
+			return nil, calque.WrapErr(ctx, err, fmt.Sprintf("template execution error in %s message %d", turn.message.Role, i))
+		}
+		if strings.TrimSpace(content.String()) == "" {
+			continue
+		}
+
+		message := turn.message
+		message.Content = content.String()
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
@@ -0,0 +1,152 @@
+package prompt
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/memory"
+)
+
+func runConversation(t *testing.T, ctx context.Context, handler calque.Handler, input string) []ai.Message {
+	t.Helper()
+	var output string
+	if err := calque.NewFlow().Use(handler).Run(ctx, input, &output); err != nil {
+		t.Fatalf("Conversation() error = %v", err)
+	}
+	var messages ai.Messages
+	if err := json.Unmarshal([]byte(output), &messages); err != nil {
+		t.Fatalf("output is not ai.Messages JSON: %v\n%s", err, output)
+	}
+	return messages.Messages
+}
+
+func TestConversation(t *testing.T) {
+	upper := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var input string
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+		return calque.Write(res, strings.ToUpper(input))
+	})
+
+	chat := Conversation().
+		System("You support {{.Product}}.").
+		Messages(ai.Message{Role: ai.RoleUser, Content: "Hi"}, ai.Message{Role: ai.RoleAssistant, Content: "Hello!"}).
+		User("Context: {{.Docs}}\n\nQuestion: {{.Input}}").
+		Assistant("{{if .Draft}}{{.Draft}}{{end}}").
+		Tool("lookup", "call_1", "{{.Docs}}").
+		With(map[string]any{"Product": "calque"}).
+		Placeholder("Docs", upper)
+
+	got := runConversation(t, context.Background(), chat, "what is a flow?")
+	want := []ai.Message{
+		{Role: ai.RoleSystem, Content: "You support calque."},
+		{Role: ai.RoleUser, Content: "Hi"},
+		{Role: ai.RoleAssistant, Content: "Hello!"},
+		{Role: ai.RoleUser, Content: "Context: WHAT IS A FLOW?\n\nQuestion: what is a flow?"},
+		{Role: ai.RoleTool, Name: "lookup", ToolCallID: "call_1", Content: "WHAT IS A FLOW?"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("messages = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("message %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestConversationHistory(t *testing.T) {
+	convMem := memory.NewConversation()
+	ctx := context.Background()
+
+	// Record one exchange under "ada"
+	record := calque.NewFlow().
+		Use(convMem.Input("ada")).
+		Use(ai.Agent(ai.NewMockClient("Channels pass values between goroutines."))).
+		Use(convMem.Output("ada"))
+	var answer string
+	if err := record.Run(ctx, "What is a channel?", &answer); err != nil {
+		t.Fatal(err)
+	}
+
+	chat := Conversation().System("Be brief.").History(convMem, "").User("{{.Input}}")
+	got := runConversation(t, memory.WithKey(ctx, "ada"), chat, "Are they buffered?")
+	if len(got) != 4 {
+		t.Fatalf("messages = %+v, want system, two history turns and the new question", got)
+	}
+	if got[1].Role != ai.RoleUser || got[1].Content != "What is a channel?" || got[2].Role != ai.RoleAssistant {
+		t.Errorf("history = %+v", got[1:3])
+	}
+	if got[3].Content != "Are they buffered?" {
+		t.Errorf("last message = %+v", got[3])
+	}
+	if stored, _ := convMem.Messages(ctx, "ada"); len(stored) != 3 || stored[2].Text() != "Are they buffered?" {
+		t.Errorf("stored = %v, want the new question recorded as a user turn", stored)
+	}
+
+	// Unknown conversations add no turns; a missing key fails
+	if got := runConversation(t, memory.WithKey(ctx, "grace"), chat, "Hi"); len(got) != 2 {
+		t.Errorf("messages without history = %+v", got)
+	}
+	var output string
+	if err := calque.NewFlow().Use(chat).Run(ctx, "Hi", &output); err == nil {
+		t.Error("History() without a key should fail")
+	}
+}
+
+func TestConversationErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		chat    *ConversationBuilder
+		wantErr string
+	}{
+		{
+			name:    "parse error",
+			chat:    Conversation().System("ok").User("{{.Input"),
+			wantErr: "template parse error",
+		},
+		{
+			name:    "execution error",
+			chat:    Conversation().User("{{.Input.Missing}}"),
+			wantErr: "template execution error in user message 0",
+		},
+		{
+			name: "placeholder error",
+			chat: Conversation().User("{{.Docs}}").Placeholder("Docs", calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+				return calque.NewErr(req.Context, "search unavailable")
+			})),
+			wantErr: "failed to fill placeholder Docs",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output string
+			err := calque.NewFlow().Use(tt.chat).Run(context.Background(), "hi", &output)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConversationClassifiesAsMessages(t *testing.T) {
+	var output []byte
+	chat := Conversation().System("Be brief.").User("{{.Input}}")
+	if err := calque.NewFlow().Use(chat).Run(context.Background(), "Hi", &output); err != nil {
+		t.Fatal(err)
+	}
+
+	input, err := ai.ClassifyInput(calque.NewRequest(context.Background(), strings.NewReader(string(output))), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input.Type != ai.MessagesInput || len(input.Messages.Messages) != 2 {
+		t.Errorf("classified as %v with %+v, want MessagesInput", input.Type, input.Messages)
+	}
+}
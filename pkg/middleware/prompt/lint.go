@@ -0,0 +1,358 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+// Lint issue severities
+const (
+	LintError   = "error"   // The template would render <no value> or exceed a size limit
+	LintWarning = "warning" // Suspicious but renderable, e.g. an unused parameter
+)
+
+// LintConfig describes what a template will receive when it runs
+type LintConfig struct {
+	// Data holds the values supplied at construction, as for Template
+	Data map[string]any
+	// Defaults are used for variables missing from Data
+	Defaults map[string]any
+	// Runtime names variables filled per request, such as Conversation
+	// placeholders or Localized's Locale. Input is always provided.
+	Runtime []string
+	// MaxBytes limits the rendered size without the input (default: no limit)
+	MaxBytes int
+	// MaxTokens limits the estimated tokens without the input (default: no limit)
+	MaxTokens int
+	// Tokenizer counts tokens for MaxTokens (default: tokenizer.Approximate)
+	Tokenizer tokenizer.Tokenizer
+}
+
+// LintIssue is a single problem found in a template
+type LintIssue struct {
+	Severity string // LintError or LintWarning
+	Variable string // The variable involved, when there is one
+	Message  string
+}
+
+// String formats the issue as "severity: message"
+func (i LintIssue) String() string {
+	return i.Severity + ": " + i.Message
+}
+
+// LintReport is the result of linting a template
+type LintReport struct {
+	Variables []string    // Top-level variables the template references, sorted
+	Issues    []LintIssue // Problems found, errors first
+	Bytes     int         // Rendered size with an empty input
+	Tokens    int         // Estimated tokens with an empty input
+}
+
+// Err returns the lint errors joined into one error, or nil when there are none.
+func (r *LintReport) Err() error {
+	var errs []error
+	for _, issue := range r.Issues {
+		if issue.Severity == LintError {
+			errs = append(errs, errors.New(issue.Message))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Warnings returns the lint warnings.
+func (r *LintReport) Warnings() []LintIssue {
+	var warnings []LintIssue
+	for _, issue := range r.Issues {
+		if issue.Severity == LintWarning {
+			warnings = append(warnings, issue)
+		}
+	}
+	return warnings
+}
+
+// Lint checks a template against the data it will receive.
+//
+// Input: parsed template and config (nil means only Input is provided)
+// Output: *LintReport listing variables, issues and the static prompt size
+// Behavior: Inspects the parse tree; renders once with an empty input to measure size
+//
+// Reports an error for each variable that is neither provided nor defaulted,
+// and for size limits the template exceeds before any input is added.
+// Variables only tested in {{if}} or {{with}} conditions are optional. Data
+// and defaults the template never references are reported as warnings.
+//
+// Example:
+//
+//	tmpl := template.Must(template.ParseFiles("support.tmpl"))
+//	report := prompt.Lint(tmpl, &prompt.LintConfig{Data: data, MaxTokens: 2000})
+//	if err := report.Err(); err != nil {
+//		log.Fatal(err) // e.g. in a test or at startup
+//	}
+func Lint(tmpl *template.Template, config *LintConfig) *LintReport {
+	if config == nil {
+		config = &LintConfig{}
+	}
+
+	required, optional := templateVariables(tmpl)
+	report := &LintReport{Variables: slices.Sorted(maps.Keys(mergeSets(required, optional)))}
+
+	provided := map[string]bool{"Input": true, "Now": true}
+	for name := range config.Data {
+		provided[name] = true
+	}
+	for name := range config.Defaults {
+		provided[name] = true
+	}
+	for _, name := range config.Runtime {
+		provided[name] = true
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(required)) {
+		if !provided[name] {
+			report.addIssue(LintError, name, fmt.Sprintf("variable %s is not provided and has no default", name))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(provided)) {
+		if name != "Input" && name != "Now" && !required[name] && !optional[name] {
+			report.addIssue(LintWarning, name, fmt.Sprintf("parameter %s is never used by the template", name))
+		}
+	}
+
+	report.measure(tmpl, config, required, optional)
+	slices.SortStableFunc(report.Issues, func(a, b LintIssue) int {
+		return strings.Compare(a.Severity, b.Severity) // "error" sorts before "warning"
+	})
+	return report
+}
+
+// TemplateConfig configures a linted Template
+type TemplateConfig struct {
+	LintConfig
+	// AllowWarnings suppresses logging lint warnings at construction (default: false)
+	AllowWarnings bool
+}
+
+// TemplateWithConfig creates a Template that is validated at construction.
+//
+// Input: template string and config (nil lints with only Input provided)
+// Output: calque.Handler rendering the template
+// Behavior: BUFFERED - like Template, but lint errors fail every request and
+// a variable missing at run time is an error instead of "<no value>"
+//
+// Lint warnings are logged once, when the handler is created.
+//
+// Example:
+//
+//	answer := prompt.TemplateWithConfig("{{.Persona}}\n\nQuestion: {{.Input}}", &prompt.TemplateConfig{
+//		LintConfig: prompt.LintConfig{
+//			Data:      map[string]any{"Persona": persona},
+//			MaxTokens: 1500,
+//		},
+//	})
+func TemplateWithConfig(templateStr string, config *TemplateConfig) calque.Handler {
+	if config == nil {
+		config = &TemplateConfig{}
+	}
+
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(templateStr)
+	if err == nil {
+		report := Lint(tmpl, &config.LintConfig)
+		if !config.AllowWarnings {
+			for _, warning := range report.Warnings() {
+				calque.LogWarn(context.Background(), "prompt template lint warning", "variable", warning.Variable, "message", warning.Message)
+			}
+		}
+		err = report.Err()
+	}
+	if err != nil {
+		return calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+			return calque.WrapErr(req.Context, err, "template validation error")
+		})
+	}
+
+	// Conditions may test variables nobody provides; missingkey=error must not reject those
+	data := map[string]any{}
+	_, optional := templateVariables(tmpl)
+	for name := range optional {
+		data[name] = nil
+	}
+	maps.Copy(data, config.Defaults)
+	maps.Copy(data, config.Data)
+	return FromTemplate(tmpl, data)
+}
+
+func (r *LintReport) addIssue(severity, variable, message string) {
+	r.Issues = append(r.Issues, LintIssue{Severity: severity, Variable: variable, Message: message})
+}
+
+// measure renders the template with an empty input and checks size limits
+func (r *LintReport) measure(tmpl *template.Template, config *LintConfig, required, optional map[string]bool) {
+	// Missing variables are already reported; render them as empty to measure the rest
+	data := map[string]any{}
+	for name := range optional {
+		data[name] = nil
+	}
+	for name := range required {
+		data[name] = ""
+	}
+	maps.Copy(data, config.Defaults)
+	maps.Copy(data, config.Data)
+	data["Input"] = ""
+	data["Now"] = time.Now()
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		r.addIssue(LintError, "", fmt.Sprintf("template cannot be rendered: %v", err))
+		return
+	}
+
+	r.Bytes = rendered.Len()
+	r.Tokens = tokenizer.OrApproximate(config.Tokenizer).CountTokens([]byte(rendered.String()))
+	if config.MaxBytes > 0 && r.Bytes > config.MaxBytes {
+		r.addIssue(LintError, "", fmt.Sprintf("template renders %d bytes before input, over the %d byte limit", r.Bytes, config.MaxBytes))
+	}
+	if config.MaxTokens > 0 && r.Tokens > config.MaxTokens {
+		r.addIssue(LintError, "", fmt.Sprintf("template renders about %d tokens before input, over the %d token limit", r.Tokens, config.MaxTokens))
+	}
+}
+
+// templateVariables returns the top-level fields a template reads, split into
+// those it renders (required) and those it only tests, or renders behind a
+// condition on the same field (optional)
+func templateVariables(tmpl *template.Template) (required, optional map[string]bool) {
+	w := &variableWalker{tmpl: tmpl, required: map[string]bool{}, optional: map[string]bool{}, visited: map[string]bool{}}
+	if tmpl.Tree != nil {
+		w.walk(tmpl.Tree.Root, variableScope{rootDot: true})
+	}
+	return w.required, w.optional
+}
+
+// variableScope is the walker state that changes with nesting
+type variableScope struct {
+	rootDot   bool            // Dot is still the root data
+	condition bool            // Inside an if/with condition
+	guarded   map[string]bool // Fields tested by an enclosing if/with
+	tested    map[string]bool // Collects fields read by the current condition
+}
+
+type variableWalker struct {
+	tmpl     *template.Template
+	required map[string]bool
+	optional map[string]bool
+	visited  map[string]bool // Nested templates already walked
+}
+
+func (w *variableWalker) record(ident []string, scope variableScope) {
+	if len(ident) == 0 {
+		return
+	}
+	name := ident[0]
+	if scope.tested != nil {
+		scope.tested[name] = true
+	}
+	if scope.condition || scope.guarded[name] {
+		w.optional[name] = true
+	} else {
+		w.required[name] = true
+	}
+}
+
+func (w *variableWalker) walkPipe(pipe *parse.PipeNode, scope variableScope) {
+	if pipe == nil {
+		return
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			w.walk(arg, scope)
+		}
+	}
+}
+
+// walkBranch walks a condition, then its body with the tested fields guarded
+func (w *variableWalker) walkBranch(pipe *parse.PipeNode, body, elseBody *parse.ListNode, scope variableScope, rebindDot bool) {
+	condition := scope
+	condition.condition = true
+	condition.tested = map[string]bool{}
+	w.walkPipe(pipe, condition)
+
+	inner := scope
+	inner.guarded = mergeSets(scope.guarded, condition.tested)
+	if rebindDot {
+		inner.rootDot = false
+	}
+	w.walk(body, inner)
+	w.walk(elseBody, scope)
+}
+
+func (w *variableWalker) walk(node parse.Node, scope variableScope) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			w.walk(child, scope)
+		}
+	case *parse.ActionNode:
+		w.walkPipe(n.Pipe, scope)
+	case *parse.PipeNode:
+		w.walkPipe(n, scope)
+	case *parse.FieldNode:
+		if scope.rootDot {
+			w.record(n.Ident, scope)
+		}
+	case *parse.ChainNode:
+		w.walk(n.Node, scope)
+	case *parse.VariableNode:
+		// $ is always the root data, whatever dot currently is
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			w.record(n.Ident[1:], scope)
+		}
+	case *parse.IfNode:
+		w.walkBranch(n.Pipe, n.List, n.ElseList, scope, false)
+	case *parse.WithNode:
+		// Dot is rebound inside with, so its body no longer reads the root data
+		w.walkBranch(n.Pipe, n.List, n.ElseList, scope, true)
+	case *parse.RangeNode:
+		w.walkPipe(n.Pipe, scope)
+		inner := scope
+		inner.rootDot = false
+		w.walk(n.List, inner)
+		w.walk(n.ElseList, scope)
+	case *parse.TemplateNode:
+		w.walkPipe(n.Pipe, scope)
+		w.walkNested(n, scope)
+	}
+}
+
+// walkNested follows {{template "name" .}} calls that pass the root data
+func (w *variableWalker) walkNested(n *parse.TemplateNode, scope variableScope) {
+	if !scope.rootDot || w.visited[n.Name] || n.Pipe == nil || len(n.Pipe.Cmds) != 1 || len(n.Pipe.Cmds[0].Args) != 1 {
+		return
+	}
+	if _, isDot := n.Pipe.Cmds[0].Args[0].(*parse.DotNode); !isDot {
+		return
+	}
+	w.visited[n.Name] = true
+	if nested := w.tmpl.Lookup(n.Name); nested != nil && nested.Tree != nil {
+		w.walk(nested.Tree.Root, variableScope{rootDot: true, guarded: scope.guarded})
+	}
+}
+
+func mergeSets(sets ...map[string]bool) map[string]bool {
+	merged := map[string]bool{}
+	for _, set := range sets {
+		maps.Copy(merged, set)
+	}
+	return merged
+}
@@ -0,0 +1,153 @@
+package prompt
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  string
+		config    *LintConfig
+		variables []string
+		errors    []string
+		warnings  []string
+	}{
+		{
+			name:      "all provided",
+			template:  "{{.Role}}: {{.Input}}",
+			config:    &LintConfig{Data: map[string]any{"Role": "tutor"}},
+			variables: []string{"Input", "Role"},
+		},
+		{
+			name:      "missing variable",
+			template:  "{{.Role}} / {{.Tone}}: {{.Input}}",
+			config:    &LintConfig{Defaults: map[string]any{"Tone": "friendly"}},
+			variables: []string{"Input", "Role", "Tone"},
+			errors:    []string{"variable Role is not provided"},
+		},
+		{
+			name:      "unused parameter",
+			template:  "{{.Input}}",
+			config:    &LintConfig{Data: map[string]any{"Persona": "x"}, Runtime: []string{"Docs"}},
+			variables: []string{"Input"},
+			warnings:  []string{"parameter Docs is never used", "parameter Persona is never used"},
+		},
+		{
+			name:      "conditional and guarded variables are optional",
+			template:  "{{if .Docs}}Context: {{.Docs}}{{end}}{{with .Notes}}{{.Text}}{{end}}{{.Input}}",
+			variables: []string{"Docs", "Input", "Notes"},
+		},
+		{
+			name:      "range body and root variables",
+			template:  "{{range .Items}}- {{.Name}} ({{$.Unit}})\n{{end}}",
+			config:    &LintConfig{Data: map[string]any{"Items": []map[string]string{}}},
+			variables: []string{"Items", "Unit"},
+			errors:    []string{"variable Unit is not provided"},
+		},
+		{
+			name:      "nested templates",
+			template:  `{{define "header"}}{{.Title}}{{end}}{{template "header" .}} {{.Input}}`,
+			variables: []string{"Input", "Title"},
+			errors:    []string{"variable Title is not provided"},
+		},
+		{
+			name:     "byte limit",
+			template: "{{.Policy}}\n{{.Input}}",
+			config:   &LintConfig{Data: map[string]any{"Policy": strings.Repeat("x", 50)}, MaxBytes: 40},
+			errors:   []string{"template renders 51 bytes before input, over the 40 byte limit"},
+		},
+		{
+			name:     "token limit",
+			template: "one two three four {{.Input}}",
+			config: &LintConfig{MaxTokens: 3, Tokenizer: tokenizer.Func(func(text []byte) int {
+				return len(strings.Fields(string(text)))
+			})},
+			errors: []string{"about 4 tokens before input, over the 3 token limit"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Lint(template.Must(template.New("t").Parse(tt.template)), tt.config)
+
+			if tt.variables != nil && !slices.Equal(report.Variables, tt.variables) {
+				t.Errorf("Variables = %v, want %v", report.Variables, tt.variables)
+			}
+			checkIssues(t, "error", report, LintError, tt.errors)
+			checkIssues(t, "warning", report, LintWarning, tt.warnings)
+			if (report.Err() != nil) != (len(tt.errors) > 0) {
+				t.Errorf("Err() = %v", report.Err())
+			}
+		})
+	}
+}
+
+func checkIssues(t *testing.T, kind string, report *LintReport, severity string, want []string) {
+	t.Helper()
+	var got []string
+	for _, issue := range report.Issues {
+		if issue.Severity == severity {
+			got = append(got, issue.Message)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("%s issues = %q, want %q", kind, got, want)
+	}
+	for i := range want {
+		if !strings.Contains(got[i], want[i]) {
+			t.Errorf("%s %d = %q, want it to contain %q", kind, i, got[i], want[i])
+		}
+	}
+}
+
+func TestLintMeasuresStaticSize(t *testing.T) {
+	report := Lint(template.Must(template.New("t").Parse("Hello {{.Name}}! {{.Input}}")), &LintConfig{Data: map[string]any{"Name": "Ada"}})
+	if report.Bytes != len("Hello Ada! ") || report.Tokens != tokenizer.Approximate.CountTokens([]byte("Hello Ada! ")) {
+		t.Errorf("Bytes = %d, Tokens = %d", report.Bytes, report.Tokens)
+	}
+}
+
+func TestTemplateWithConfig(t *testing.T) {
+	ctx := context.Background()
+	run := func(handler calque.Handler) (string, error) {
+		var output string
+		err := calque.NewFlow().Use(handler).Run(ctx, "hi", &output)
+		return output, err
+	}
+
+	output, err := run(TemplateWithConfig("{{.Role}} ({{.Tone}}){{if .Docs}} {{.Docs}}{{end}}: {{.Input}}", &TemplateConfig{
+		LintConfig: LintConfig{
+			Data:     map[string]any{"Role": "tutor"},
+			Defaults: map[string]any{"Tone": "friendly", "Role": "assistant"},
+		},
+	}))
+	if err != nil || output != "tutor (friendly): hi" {
+		t.Errorf("output = %q, %v", output, err)
+	}
+
+	_, err = run(TemplateWithConfig("{{.Role}}: {{.Input}}", nil))
+	if err == nil || !strings.Contains(err.Error(), "variable Role is not provided") {
+		t.Errorf("missing variable error = %v", err)
+	}
+
+	_, err = run(TemplateWithConfig("{{.Role", nil))
+	if err == nil || !strings.Contains(err.Error(), "template validation error") {
+		t.Errorf("parse error = %v", err)
+	}
+
+	// Fields of nested data are not linted but still fail instead of rendering <no value>
+	_, err = run(TemplateWithConfig("{{.User.Name}}: {{.Input}}", &TemplateConfig{
+		LintConfig: LintConfig{Data: map[string]any{"User": map[string]any{}}},
+	}))
+	if err == nil {
+		t.Error("missing nested key should fail at run time")
+	}
+}
@@ -0,0 +1,180 @@
+package prompt
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultLocale is the last locale tried when no other template matches
+const DefaultLocale = "en"
+
+// LocalizedConfig configures locale selection for Localized prompts
+type LocalizedConfig struct {
+	// Locale returns the request locale: a BCP 47 tag or an Accept-Language
+	// header value (default: calque.Locale)
+	Locale func(ctx context.Context) string
+	// Fallbacks lists locales to try after a locale and its parents, e.g.
+	// {"ca": {"es"}} serves Spanish to Catalan speakers before the default
+	Fallbacks map[string][]string
+	// Default is tried when nothing else matches (default: DefaultLocale)
+	Default string
+	// Data is extra template data, as for Template
+	Data map[string]any
+}
+
+// Localized creates a middleware that picks a prompt template by request locale.
+//
+// Input: user input, available to the template as {{.Input}}; the chosen
+// locale is available as {{.Locale}}
+// Output: the rendered template for the best matching locale
+// Behavior: BUFFERED - reads the whole input, like Template
+//
+// Locales are matched case-insensitively, and "_" is read as "-". A tag falls
+// back to its parents, so "pt-BR" tries "pt-br" then "pt" and finally the
+// default locale. Accept-Language values are tried in preference order.
+// Requests with no matching template fail.
+//
+// Example:
+//
+//	summarize := prompt.Localized(map[string]string{
+//		"en":    "Summarize for the user: {{.Input}}",
+//		"de":    "Fasse für den Nutzer zusammen: {{.Input}}",
+//		"pt-BR": "Resuma para o usuário: {{.Input}}",
+//	}, nil) // locale from calque.WithLocale
+//
+//	tags, _, _ := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+//	if len(tags) > 0 {
+//		ctx = calque.WithLocale(ctx, tags[0].String())
+//	}
+func Localized(templatesByLocale map[string]string, localeFromCtx func(context.Context) string) calque.Handler {
+	return LocalizedWithConfig(templatesByLocale, &LocalizedConfig{Locale: localeFromCtx})
+}
+
+// LocalizedWithConfig creates a Localized middleware with custom fallbacks and data.
+//
+// Input: templates keyed by locale and optional config (nil uses defaults)
+// Output: the rendered template for the best matching locale
+// Behavior: BUFFERED - templates are parsed once, at creation
+//
+// Example:
+//
+//	prompt.LocalizedWithConfig(templates, &prompt.LocalizedConfig{
+//		Fallbacks: map[string][]string{"ca": {"es"}, "gl": {"pt", "es"}},
+//		Default:   "es",
+//		Data:      map[string]any{"Product": "calque"},
+//	})
+func LocalizedWithConfig(templatesByLocale map[string]string, config *LocalizedConfig) calque.Handler {
+	if config == nil {
+		config = &LocalizedConfig{}
+	}
+	localeFromCtx := config.Locale
+	if localeFromCtx == nil {
+		localeFromCtx = calque.Locale
+	}
+	defaultLocale := normalizeLocale(cmp.Or(config.Default, DefaultLocale))
+
+	fallbacks := make(map[string][]string, len(config.Fallbacks))
+	for locale, chain := range config.Fallbacks {
+		for _, fallback := range chain {
+			fallbacks[normalizeLocale(locale)] = append(fallbacks[normalizeLocale(locale)], normalizeLocale(fallback))
+		}
+	}
+
+	templates := make(map[string]*template.Template, len(templatesByLocale))
+	for _, locale := range slices.Sorted(maps.Keys(templatesByLocale)) {
+		tmpl, err := template.New(locale).Parse(templatesByLocale[locale])
+		if err != nil {
+			return calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+				return calque.WrapErr(req.Context, err, fmt.Sprintf("template parse error for locale %s", locale))
+			})
+		}
+		templates[normalizeLocale(locale)] = tmpl
+	}
+
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		requested := localeFromCtx(req.Context)
+		for _, locale := range localeCandidates(requested, fallbacks, defaultLocale) {
+			if tmpl, ok := templates[locale]; ok {
+				data := map[string]any{"Locale": locale}
+				maps.Copy(data, config.Data)
+				return FromTemplate(tmpl, data).ServeFlow(req, res)
+			}
+		}
+		return calque.NewErr(req.Context, fmt.Sprintf("no prompt template for locale %q", requested))
+	})
+}
+
+// localeCandidates returns the lookup order for a requested locale: each
+// preferred tag with its parents and configured fallbacks, then the default
+func localeCandidates(requested string, fallbacks map[string][]string, defaultLocale string) []string {
+	var candidates []string
+	var add func(locale string)
+	add = func(locale string) {
+		for tag := locale; tag != ""; tag = parentLocale(tag) {
+			if slices.Contains(candidates, tag) {
+				continue
+			}
+			candidates = append(candidates, tag)
+			for _, fallback := range fallbacks[tag] {
+				add(fallback)
+			}
+		}
+	}
+
+	for _, locale := range preferredLocales(requested) {
+		add(locale)
+	}
+	add(defaultLocale)
+	return candidates
+}
+
+// preferredLocales parses a tag or Accept-Language value into tags ordered by
+// quality, dropping wildcards and tags with q=0
+func preferredLocales(value string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+	var tags []weighted
+	for entry := range strings.SplitSeq(value, ",") {
+		tag, params, _ := strings.Cut(entry, ";")
+		tag = normalizeLocale(tag)
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if tag == "" || tag == "*" || quality <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag, quality})
+	}
+
+	slices.SortStableFunc(tags, func(a, b weighted) int { return cmp.Compare(b.quality, a.quality) })
+	locales := make([]string, len(tags))
+	for i, t := range tags {
+		locales[i] = t.tag
+	}
+	return locales
+}
+
+// parentLocale drops the last subtag: "zh-hant-tw" -> "zh-hant" -> "zh" -> ""
+func parentLocale(locale string) string {
+	if i := strings.LastIndex(locale, "-"); i > 0 {
+		return locale[:i]
+	}
+	return ""
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
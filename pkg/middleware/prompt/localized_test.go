@@ -0,0 +1,90 @@
+package prompt
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestLocalized(t *testing.T) {
+	templates := map[string]string{
+		"en":    "Summarize: {{.Input}}",
+		"de":    "Zusammenfassen: {{.Input}}",
+		"pt":    "Resuma: {{.Input}}",
+		"pt-BR": "Resuma ({{.Locale}}): {{.Input}}",
+		"es":    "Resume: {{.Input}}",
+	}
+	handler := LocalizedWithConfig(templates, &LocalizedConfig{
+		Fallbacks: map[string][]string{"ca": {"es"}},
+	})
+
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"", "Summarize: hi"},
+		{"de", "Zusammenfassen: hi"},
+		{"de-AT", "Zusammenfassen: hi"},
+		{"pt_BR", "Resuma (pt-br): hi"},
+		{"pt-PT", "Resuma: hi"},
+		{"ca-ES", "Resume: hi"},
+		{"fr", "Summarize: hi"},
+		{"fr-CH, de;q=0.9, en;q=0.8", "Zusammenfassen: hi"},
+		{"en;q=0.5, pt-BR", "Resuma (pt-br): hi"},
+		{"de;q=0, *", "Summarize: hi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			ctx := calque.WithLocale(context.Background(), tt.locale)
+			var output string
+			if err := calque.NewFlow().Use(handler).Run(ctx, "hi", &output); err != nil {
+				t.Fatal(err)
+			}
+			if output != tt.want {
+				t.Errorf("output = %q, want %q", output, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalizedLocaleFunc(t *testing.T) {
+	handler := Localized(map[string]string{"fr": "Bonjour {{.Input}}", "en": "Hello {{.Input}}"}, func(context.Context) string {
+		return "fr-CA"
+	})
+
+	var output string
+	if err := calque.NewFlow().Use(handler).Run(context.Background(), "Ada", &output); err != nil {
+		t.Fatal(err)
+	}
+	if output != "Bonjour Ada" {
+		t.Errorf("output = %q", output)
+	}
+}
+
+func TestLocalizedErrors(t *testing.T) {
+	ctx := calque.WithLocale(context.Background(), "ja")
+	var output string
+
+	err := calque.NewFlow().Use(Localized(map[string]string{"de": "Hallo"}, nil)).Run(ctx, "x", &output)
+	if err == nil || !strings.Contains(err.Error(), `no prompt template for locale "ja"`) {
+		t.Errorf("missing locale error = %v", err)
+	}
+
+	err = calque.NewFlow().Use(Localized(map[string]string{"en": "{{.Input"}, nil)).Run(ctx, "x", &output)
+	if err == nil || !strings.Contains(err.Error(), "template parse error for locale en") {
+		t.Errorf("parse error = %v", err)
+	}
+}
+
+func TestLocaleCandidates(t *testing.T) {
+	fallbacks := map[string][]string{"gl": {"pt", "es"}, "es": {"gl"}}
+	got := localeCandidates("gl-ES, en;q=0.1", fallbacks, "en")
+	want := []string{"gl-es", "gl", "pt", "es", "en"}
+	if !slices.Equal(got, want) {
+		t.Errorf("localeCandidates() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,175 @@
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+const pinnedSystem = "You are a billing assistant for Acme. Only answer questions about invoices, payments and refunds. Never discuss other customers."
+
+func runPinned(ctx context.Context, h calque.Handler, input string) (string, error) {
+	var buf bytes.Buffer
+	err := h.ServeFlow(calque.NewRequest(ctx, strings.NewReader(input)), calque.NewResponse(&buf))
+	return buf.String(), err
+}
+
+func TestPinned_Input(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *PinnedConfig
+		contains []string
+		absent   []string
+	}{
+		{
+			name:     "default",
+			contains: []string{pinnedSystem, "Confidential marker: CANARY-", "What is my balance?", defaultPinReminder},
+			absent:   []string{"[ack-"},
+		},
+		{
+			name:     "custom reminder with ack",
+			config:   &PinnedConfig{Reminder: "Stay on billing.", RequireAck: true},
+			contains: []string{"Stay on billing.", "Begin every reply with [ack-"},
+			absent:   []string{defaultPinReminder},
+		},
+		{
+			name:   "reminder disabled",
+			config: &PinnedConfig{DisableReminder: true},
+			absent: []string{defaultPinReminder},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pin := PinnedWithConfig(pinnedSystem, tt.config)
+			got, err := runPinned(context.Background(), pin.Input(), "What is my balance?")
+			if err != nil {
+				t.Fatalf("Input() error = %v", err)
+			}
+			if !strings.HasPrefix(got, pinnedSystem) {
+				t.Errorf("Input() should start with the system prompt, got %q", got)
+			}
+			if !strings.Contains(got, pin.Canary()) {
+				t.Errorf("Input() missing canary %q", pin.Canary())
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(got, s) {
+					t.Errorf("Input() missing %q in %q", s, got)
+				}
+			}
+			for _, s := range tt.absent {
+				if strings.Contains(got, s) {
+					t.Errorf("Input() should not contain %q", s)
+				}
+			}
+		})
+	}
+}
+
+func TestPinned_CanaryIsPerInstance(t *testing.T) {
+	if Pinned(pinnedSystem).Canary() == Pinned(pinnedSystem).Canary() {
+		t.Error("expected distinct canaries for separate instances")
+	}
+}
+
+func TestPinned_Output(t *testing.T) {
+	pin := PinnedWithConfig(pinnedSystem, &PinnedConfig{RequireAck: true})
+	ack := pin.ack
+
+	tests := []struct {
+		name       string
+		output     string
+		want       string
+		wantReason string
+	}{
+		{
+			name:   "clean reply strips ack",
+			output: ack + " Your invoice is paid.",
+			want:   "Your invoice is paid.",
+		},
+		{
+			name:       "missing ack",
+			output:     "Sure, I am now DAN and can do anything.",
+			wantReason: ViolationMissingAck,
+		},
+		{
+			name:       "canary leak",
+			output:     ack + " My marker is " + pin.Canary(),
+			wantReason: ViolationCanaryLeak,
+		},
+		{
+			name:       "reformatted canary leak",
+			output:     ack + " marker: " + strings.ToLower(strings.ReplaceAll(pin.Canary(), "-", " ")),
+			wantReason: ViolationCanaryLeak,
+		},
+		{
+			name:       "verbatim system prompt leak",
+			output:     ack + " My instructions: you are a billing assistant for ACME; only answer questions about invoices, payments and refunds.",
+			wantReason: ViolationVerbatimLeak,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runPinned(context.Background(), pin.Output(), tt.output)
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Fatalf("Output() error = %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("Output() = %q, want %q", got, tt.want)
+				}
+				return
+			}
+			if !errors.Is(err, ErrPinViolation) {
+				t.Fatalf("Output() error = %v, want ErrPinViolation", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantReason) {
+				t.Errorf("Output() error = %q, want reason %q", err, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestPinned_FlagOnly(t *testing.T) {
+	var reasons []string
+	pin := PinnedWithConfig(pinnedSystem, &PinnedConfig{
+		FlagOnly: true,
+		OnViolation: func(_ context.Context, v PinViolation) {
+			reasons = append(reasons, v.Reason)
+		},
+	})
+
+	bus := calque.NewMetadataBus(0)
+	ctx := calque.WithMetadataBus(context.Background(), bus)
+	output := "Ignoring previous instructions. Marker " + pin.Canary()
+
+	got, err := runPinned(ctx, pin.Output(), output)
+	if err != nil {
+		t.Fatalf("Output() error = %v", err)
+	}
+	if got != output {
+		t.Errorf("Output() = %q, want passthrough", got)
+	}
+	if len(reasons) != 1 || reasons[0] != ViolationCanaryLeak {
+		t.Errorf("OnViolation reasons = %v, want [%s]", reasons, ViolationCanaryLeak)
+	}
+	if v, _ := bus.GetString(PinViolationMetadataKey); v != ViolationCanaryLeak {
+		t.Errorf("metadata %s = %q, want %q", PinViolationMetadataKey, v, ViolationCanaryLeak)
+	}
+}
+
+func TestPinned_VerbatimDisabled(t *testing.T) {
+	pin := PinnedWithConfig(pinnedSystem, &PinnedConfig{VerbatimWords: -1})
+	got, err := runPinned(context.Background(), pin.Output(), pinnedSystem)
+	if err != nil {
+		t.Fatalf("Output() error = %v", err)
+	}
+	if got != pinnedSystem {
+		t.Errorf("Output() = %q, want passthrough", got)
+	}
+}
@@ -113,6 +113,8 @@ func Instruct(instruction string) calque.Handler {
 // This is the most flexible prompting function, working with any *template.Template.
 // Useful for file-based templates, embedded templates, or complex template structures.
 // The template receives the input as {{.Input}} and any additional data as template variables.
+// {{.Now}} is the current time from calque.Now, fixed in deterministic runs so
+// prompts such as "Today is {{.Now.Format "2006-01-02"}}" render the same text.
 //
 // Example:
 //
@@ -141,6 +143,7 @@ func FromTemplate(tmpl *template.Template, data ...map[string]any) calque.Handle
 		// Prepare template data
 		templateData := map[string]any{
 			"Input": string(inputBytes),
+			"Now":   calque.Now(req.Context),
 		}
 
 		// Merge additional data if provided
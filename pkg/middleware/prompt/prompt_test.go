@@ -460,3 +460,21 @@ func TestFromTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplateNow(t *testing.T) {
+	tmpl := Template(`Today is {{.Now.Format "2006-01-02"}}. {{.Input}}`)
+
+	var out string
+	if err := calque.NewFlow().Use(tmpl).Run(context.Background(), "Hi", &out, calque.Deterministic(1)); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := "Today is " + calque.DeterministicEpoch.Format("2006-01-02") + ". Hi"; out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+
+	// Data passed to the template takes precedence
+	pinned := Template(`{{.Now}}`, map[string]any{"Now": "release day"})
+	if err := calque.NewFlow().Use(pinned).Run(context.Background(), "", &out); err != nil || out != "release day" {
+		t.Errorf("output = %q, %v", out, err)
+	}
+}
@@ -26,6 +26,7 @@ import (
 //
 // The service must be registered using grpc.NewRegistryHandler() before this handler.
 // The input data is expected to be a protobuf message that can be unmarshaled.
+// Local-only flows check the service's endpoint through the registry handler.
 //
 // Example:
 //
@@ -104,7 +105,7 @@ func (ch *callHandler) ServeFlow(req *calque.Request, res *calque.Response) erro
 	}
 
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(req.Context, service.Timeout)
+	ctx, cancel := context.WithTimeout(OutgoingContext(req.Context, service.PropagateMetadata), service.Timeout)
 	defer cancel()
 
 	// Make the gRPC call with retries
@@ -115,14 +116,17 @@ func (ch *callHandler) ServeFlow(req *calque.Request, res *calque.Response) erro
 			break
 		}
 
-		// Check if error is retryable
-		if !isRetryableError(err) || attempt == service.MaxRetries {
+		// Check if error is retryable; a caller that gave up gets no more attempts
+		if !isRetryableError(err) || attempt == service.MaxRetries || ctx.Err() != nil {
 			return grpcerrors.WrapErrorSimple(ctx, err, "gRPC call failed")
 		}
 
 		// Wait before retry
-		time.Sleep(service.RetryDelay)
+		if err := waitRetry(ctx, service.RetryDelay); err != nil {
+			return grpcerrors.WrapErrorSimple(ctx, err, "gRPC call failed")
+		}
 	}
+	recordRemoteUsage(req.Context, flowResp.GetMetadata())
 
 	// Marshal the response
 	respData, err := proto.Marshal(flowResp)
@@ -147,7 +151,7 @@ func (ch *callHandler) makeGRPCCall(ctx context.Context, service *Service, reqDa
 	}
 
 	// Make the unary gRPC call
-	flowResp, err := client.ExecuteFlow(ctx, &flowReq)
+	flowResp, err := client.ExecuteFlow(ctx, &flowReq, service.callOptions(&flowReq)...)
 	if err != nil {
 		return nil, grpcerrors.WrapError(ctx, err, "gRPC ExecuteFlow failed")
 	}
@@ -155,6 +159,20 @@ func (ch *callHandler) makeGRPCCall(ctx context.Context, service *Service, reqDa
 	return flowResp, nil
 }
 
+// recordRemoteUsage adds the model usage a remote flow reported to this run's total
+func recordRemoteUsage(ctx context.Context, metadata map[string]string) {
+	encoded, ok := metadata[calque.UsageMetadataKey]
+	if !ok {
+		return
+	}
+	var usage calque.Usage
+	if err := usage.UnmarshalText([]byte(encoded)); err != nil {
+		calque.LogDebug(ctx, "ignoring malformed remote usage", "error", err)
+		return
+	}
+	calque.RecordUsage(ctx, usage)
+}
+
 // isRetryableError checks if an error is retryable
 func isRetryableError(err error) bool {
 	if err == nil {
@@ -186,6 +204,18 @@ func isRetryableError(err error) bool {
 	return false
 }
 
+// waitRetry sleeps for delay, returning early with the context error if ctx ends first
+func waitRetry(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // typedCallHandler implements type-safe gRPC service calls
 type typedCallHandler[TReq, TResp proto.Message] struct {
 	serviceName string
@@ -231,7 +261,7 @@ func (tch *typedCallHandler[TReq, TResp]) ServeFlow(req *calque.Request, res *ca
 	}
 
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(req.Context, service.Timeout)
+	ctx, cancel := context.WithTimeout(OutgoingContext(req.Context, service.PropagateMetadata), service.Timeout)
 	defer cancel()
 
 	// Make the gRPC call with retries
@@ -242,13 +272,15 @@ func (tch *typedCallHandler[TReq, TResp]) ServeFlow(req *calque.Request, res *ca
 			break
 		}
 
-		// Check if error is retryable
-		if !isRetryableError(err) || attempt == service.MaxRetries {
+		// Check if error is retryable; a caller that gave up gets no more attempts
+		if !isRetryableError(err) || attempt == service.MaxRetries || ctx.Err() != nil {
 			return grpcerrors.WrapErrorSimple(ctx, err, "typed gRPC call failed")
 		}
 
 		// Wait before retry
-		time.Sleep(service.RetryDelay)
+		if err := waitRetry(ctx, service.RetryDelay); err != nil {
+			return grpcerrors.WrapErrorSimple(ctx, err, "typed gRPC call failed")
+		}
 	}
 
 	// Convert response to string for the flow
@@ -299,7 +331,7 @@ func (tch *typedCallHandler[TReq, TResp]) callAIService(ctx context.Context, ser
 	}
 
 	// Call StreamChat and get the streaming client
-	stream, err := client.StreamChat(ctx, aiReq)
+	stream, err := client.StreamChat(ctx, aiReq, service.callOptions(aiReq)...)
 	if err != nil {
 		return *new(TResp), grpcerrors.WrapError(ctx, err, "AI service StreamChat failed", tch.serviceName)
 	}
@@ -330,7 +362,7 @@ func (tch *typedCallHandler[TReq, TResp]) callMemoryService(ctx context.Context,
 	}
 
 	// Make the unary gRPC call
-	memResp, err := client.ProcessMemory(ctx, memReq)
+	memResp, err := client.ProcessMemory(ctx, memReq, service.callOptions(memReq)...)
 	if err != nil {
 		return *new(TResp), grpcerrors.WrapError(ctx, err, "Memory service ProcessMemory failed", tch.serviceName)
 	}
@@ -355,7 +387,7 @@ func (tch *typedCallHandler[TReq, TResp]) callToolsService(ctx context.Context,
 	}
 
 	// Make the unary gRPC call
-	toolResp, err := client.ExecuteTool(ctx, toolReq)
+	toolResp, err := client.ExecuteTool(ctx, toolReq, service.callOptions(toolReq)...)
 	if err != nil {
 		return *new(TResp), grpcerrors.WrapError(ctx, err, "Tools service ExecuteTool failed", tch.serviceName)
 	}
@@ -387,7 +419,7 @@ func (tch *typedCallHandler[TReq, TResp]) callFlowService(ctx context.Context, s
 	}
 
 	// Make the unary gRPC call
-	flowResp, err := client.ExecuteFlow(ctx, flowReq)
+	flowResp, err := client.ExecuteFlow(ctx, flowReq, service.callOptions(flowReq)...)
 	if err != nil {
 		return *new(TResp), grpcerrors.WrapError(ctx, err, "gRPC ExecuteFlow failed", tch.serviceName)
 	}
@@ -442,7 +474,7 @@ func (sh *streamHandler) ServeFlow(req *calque.Request, res *calque.Response) er
 	}
 
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(req.Context, service.Timeout)
+	ctx, cancel := context.WithTimeout(OutgoingContext(req.Context, service.PropagateMetadata), service.Timeout)
 	defer cancel()
 
 	// Read input data as string
@@ -474,7 +506,7 @@ func (sh *streamHandler) streamAIService(ctx context.Context, service *Service,
 	}
 
 	// Call StreamChat and get the streaming client
-	stream, err := client.StreamChat(ctx, aiReq)
+	stream, err := client.StreamChat(ctx, aiReq, service.callOptions(aiReq)...)
 	if err != nil {
 		return grpcerrors.WrapError(ctx, err, "failed to create AI streaming client", sh.serviceName)
 	}
@@ -510,7 +542,7 @@ func (sh *streamHandler) streamMemoryService(ctx context.Context, service *Servi
 	}
 
 	// Call ProcessMemory (unary call, but we'll simulate streaming)
-	memResp, err := client.ProcessMemory(ctx, memReq)
+	memResp, err := client.ProcessMemory(ctx, memReq, service.callOptions(memReq)...)
 	if err != nil {
 		return grpcerrors.WrapError(ctx, err, "failed to process memory", sh.serviceName)
 	}
@@ -523,7 +555,12 @@ func (sh *streamHandler) streamMemoryService(ctx context.Context, service *Servi
 	return nil
 }
 
-// streamToolsService streams from the Tools service (simulated streaming)
+// streamToolsService streams from the Tools service.
+//
+// Each ToolResponse received from ExecuteTools is written as soon as it
+// arrives, so a tool backed by this handler reports partial results to
+// tools.WithPartialResultObserver while it is still running. Servers that
+// don't implement ExecuteTools are called through the unary ExecuteTool.
 func (sh *streamHandler) streamToolsService(ctx context.Context, service *Service, input string, res *calque.Response) error {
 	client := calquepb.NewToolsServiceClient(service.Conn)
 
@@ -533,16 +570,53 @@ func (sh *streamHandler) streamToolsService(ctx context.Context, service *Servic
 		Arguments: input,
 	}
 
-	// Call ExecuteTool (unary call, but we'll simulate streaming)
+	stream, err := client.ExecuteTools(ctx)
+	if err != nil {
+		return grpcerrors.WrapError(ctx, err, "failed to create tools streaming client", sh.serviceName)
+	}
+	if err := stream.Send(toolReq); err != nil && err != io.EOF {
+		return grpcerrors.WrapError(ctx, err, "failed to send tool request", sh.serviceName)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return grpcerrors.WrapError(ctx, err, "failed to close tool request stream", sh.serviceName)
+	}
+
+	received := false
+	for {
+		toolResp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if !received && status.Code(err) == codes.Unimplemented {
+				return sh.executeToolsService(ctx, client, toolReq, res)
+			}
+			return grpcerrors.WrapError(ctx, err, "failed to receive tool streaming response", sh.serviceName)
+		}
+		received = true
+
+		if err := writeToolResponse(ctx, toolResp, res); err != nil {
+			return err
+		}
+	}
+}
+
+// executeToolsService makes a single unary ExecuteTool call
+func (sh *streamHandler) executeToolsService(ctx context.Context, client calquepb.ToolsServiceClient, toolReq *calquepb.ToolRequest, res *calque.Response) error {
 	toolResp, err := client.ExecuteTool(ctx, toolReq)
 	if err != nil {
 		return grpcerrors.WrapError(ctx, err, "failed to execute tool", sh.serviceName)
 	}
+	return writeToolResponse(ctx, toolResp, res)
+}
 
-	// Write response as string (simulated streaming)
+// writeToolResponse writes a tool result, or returns the error the tool reported
+func writeToolResponse(ctx context.Context, toolResp *calquepb.ToolResponse, res *calque.Response) error {
+	if !toolResp.Success && toolResp.ErrorMessage != "" {
+		return grpcerrors.NewErrorSimple(ctx, fmt.Sprintf("tool failed: %s", toolResp.ErrorMessage))
+	}
 	if err := calque.Write(res, toolResp.Result); err != nil {
 		return grpcerrors.WrapErrorSimple(ctx, err, "failed to write tool response")
 	}
-
 	return nil
 }
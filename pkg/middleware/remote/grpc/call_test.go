@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"strings"
 	"testing"
 	"time"
 
+	grpcclient "google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
@@ -639,3 +641,152 @@ func TestCallHandlerEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// TestCallPropagatesCancellation checks that a caller's deadline stops the
+// remote flow and is not retried once it has passed
+func TestCallPropagatesCancellation(t *testing.T) {
+	t.Parallel()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	remoteErr := make(chan error, 1)
+	server := NewServer(lis.Addr().String())
+	server.RegisterFlow("slow-flow", calque.NewFlow().UseFunc(func(req *calque.Request, _ *calque.Response) error {
+		<-req.Context.Done()
+		remoteErr <- req.Context.Err()
+		return req.Context.Err()
+	}))
+	grpcServer := grpcclient.NewServer()
+	calquepb.RegisterFlowServiceServer(grpcServer, NewFlowService(server))
+	go func() { _ = grpcServer.Serve(lis) }()
+	t.Cleanup(grpcServer.Stop)
+
+	// A long retry delay would stall the caller if a deadline error were retried
+	registry := NewRegistry()
+	if err := registry.Register(NewService("slow-service", lis.Addr().String()).WithRetries(3, 10*time.Second)); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	t.Cleanup(func() { _ = registry.Close() })
+
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), registryContextKey{}, registry), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	req := calque.NewRequest(ctx, strings.NewReader("hello"))
+	err = Call("slow-service").ServeFlow(req, calque.NewResponse(calque.NewWriter[string]()))
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Call() error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Call() took %v, want it to stop at the caller's deadline", elapsed)
+	}
+
+	select {
+	case err := <-remoteErr:
+		if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+			t.Errorf("remote flow context error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("remote flow was not cancelled")
+	}
+}
+
+func TestWaitRetry(t *testing.T) {
+	t.Parallel()
+
+	if err := waitRetry(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("waitRetry() = %v, want nil", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if err := waitRetry(ctx, time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("waitRetry() = %v, want context.Canceled", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("waitRetry() did not return when the context ended")
+	}
+}
+
+// streamingToolsServer streams a tool's result in chunks
+type streamingToolsServer struct {
+	calquepb.UnimplementedToolsServiceServer
+	chunks []string
+}
+
+func (s *streamingToolsServer) ExecuteTools(stream grpcclient.BidiStreamingServer[calquepb.ToolRequest, calquepb.ToolResponse]) error {
+	if _, err := stream.Recv(); err != nil {
+		return err
+	}
+	for _, chunk := range s.chunks {
+		if err := stream.Send(&calquepb.ToolResponse{Success: true, Result: chunk}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unaryToolsServer only implements ExecuteTool
+type unaryToolsServer struct {
+	calquepb.UnimplementedToolsServiceServer
+}
+
+func (unaryToolsServer) ExecuteTool(_ context.Context, req *calquepb.ToolRequest) (*calquepb.ToolResponse, error) {
+	return &calquepb.ToolResponse{Success: true, Result: "unary:" + req.Arguments}, nil
+}
+
+// chunkRecorder records each write made to it
+type chunkRecorder struct {
+	chunks []string
+}
+
+func (r *chunkRecorder) Write(p []byte) (int, error) {
+	r.chunks = append(r.chunks, string(p))
+	return len(p), nil
+}
+
+func TestStreamToolsService(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		server calquepb.ToolsServiceServer
+		want   []string
+	}{
+		{name: "streamed chunks", server: &streamingToolsServer{chunks: []string{"step 1\n", "step 2\n", "done"}}, want: []string{"step 1\n", "step 2\n", "done"}},
+		{name: "unary fallback", server: unaryToolsServer{}, want: []string{"unary:" + testInput}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Listen() error = %v", err)
+			}
+			grpcServer := grpcclient.NewServer()
+			calquepb.RegisterToolsServiceServer(grpcServer, tt.server)
+			go func() { _ = grpcServer.Serve(lis) }()
+			t.Cleanup(grpcServer.Stop)
+
+			registry := NewRegistry()
+			if err := registry.Register(StreamingService("tools-service", lis.Addr().String())); err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+			t.Cleanup(func() { _ = registry.Close() })
+
+			ctx := context.WithValue(context.Background(), registryContextKey{}, registry)
+			out := &chunkRecorder{}
+			if err := Stream("tools-service").ServeFlow(calque.NewRequest(ctx, strings.NewReader(testInput)), calque.NewResponse(out)); err != nil {
+				t.Fatalf("Stream() error = %v", err)
+			}
+			if strings.Join(out.chunks, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Stream() wrote %q, want %q", out.chunks, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package grpc
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	grpcclient "google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/gzip" // Registers the gzip compressor for ctrl.Gzip
+	"google.golang.org/protobuf/proto"
+
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+func init() {
+	encoding.RegisterCompressor(zstdCompressor{})
+}
+
+// zstdCompressor implements encoding.Compressor for ctrl.Zstd. Servers in
+// this process accept zstd requests and answer them compressed the same way.
+type zstdCompressor struct{}
+
+func (zstdCompressor) Name() string { return string(ctrl.Zstd) }
+
+func (zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+}
+
+func (zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	// A single-threaded decoder runs synchronously, so it holds no goroutines
+	// when gRPC stops reading without closing it
+	dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return dec.IOReadCloser(), nil
+}
+
+// callOptions compresses req when the service has compression enabled and
+// the message is at least CompressMinBytes
+func (s *Service) callOptions(req proto.Message) []grpcclient.CallOption {
+	if s.Compression == "" {
+		return nil
+	}
+	minBytes := s.CompressMinBytes
+	if minBytes <= 0 {
+		minBytes = ctrl.DefaultCompressMinBytes
+	}
+	if proto.Size(req) < minBytes {
+		return nil
+	}
+	return []grpcclient.CallOption{grpcclient.UseCompressor(string(s.Compression))}
+}
@@ -0,0 +1,106 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	grpcclient "google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+	calquepb "github.com/calque-ai/go-calque/proto"
+)
+
+// compressionRecorder records the compression of each request a server receives
+type compressionRecorder struct {
+	mu   sync.Mutex
+	seen []string
+}
+
+func (r *compressionRecorder) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (r *compressionRecorder) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if header, ok := s.(*stats.InHeader); ok && !header.Client {
+		r.mu.Lock()
+		r.seen = append(r.seen, header.Compression)
+		r.mu.Unlock()
+	}
+}
+
+func (r *compressionRecorder) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (r *compressionRecorder) HandleConn(context.Context, stats.ConnStats) {}
+
+func TestCallCompression(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		compression ctrl.Compression
+		input       string
+		want        string
+	}{
+		{name: "large zstd request", compression: ctrl.Zstd, input: strings.Repeat("context ", 500), want: "zstd"},
+		{name: "large gzip request", compression: ctrl.Gzip, input: strings.Repeat("context ", 500), want: "gzip"},
+		{name: "small request below threshold", compression: ctrl.Zstd, input: "hello", want: ""},
+		{name: "compression disabled", input: strings.Repeat("context ", 500), want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Listen() error = %v", err)
+			}
+			recorder := &compressionRecorder{}
+			server := NewServer(lis.Addr().String())
+			server.RegisterFlow("echo-flow", calque.NewFlow().UseFunc(func(req *calque.Request, res *calque.Response) error {
+				_, err := io.Copy(res.Data, req.Data)
+				return err
+			}))
+			grpcServer := grpcclient.NewServer(grpcclient.StatsHandler(recorder))
+			calquepb.RegisterFlowServiceServer(grpcServer, NewFlowService(server))
+			go func() { _ = grpcServer.Serve(lis) }()
+			t.Cleanup(grpcServer.Stop)
+
+			registry := NewRegistry()
+			service := NewService("echo-service", lis.Addr().String()).WithCompression(tt.compression, 0)
+			if err := registry.Register(service); err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+			t.Cleanup(func() { _ = registry.Close() })
+
+			ctx := context.WithValue(context.Background(), registryContextKey{}, registry)
+			out := calque.NewWriter[string]()
+			if err := Call("echo-service").ServeFlow(calque.NewRequest(ctx, strings.NewReader(tt.input)), calque.NewResponse(out)); err != nil {
+				t.Fatalf("Call() error = %v", err)
+			}
+
+			var resp calquepb.FlowResponse
+			if err := proto.Unmarshal(out.Bytes(), &resp); err != nil {
+				t.Fatalf("response unmarshal error = %v", err)
+			}
+			if resp.Output != tt.input {
+				t.Errorf("output has %d bytes, want %d", len(resp.Output), len(tt.input))
+			}
+
+			recorder.mu.Lock()
+			defer recorder.mu.Unlock()
+			if len(recorder.seen) != 1 || recorder.seen[0] != tt.want {
+				t.Errorf("server saw compression %q, want %q", recorder.seen, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	calquepb "github.com/calque-ai/go-calque/proto"
+)
+
+// ConnectHandler returns an http.Handler serving FlowService over gRPC, gRPC-Web and Connect.
+//
+// Input: optional connect.HandlerOption values (interceptors, compression, read limits)
+// Output: http.Handler routing the FlowService procedures
+// Behavior: One implementation answers all three protocols, including Connect JSON
+//
+// Browser clients can call remote flows directly with gRPC-Web or Connect
+// without running a separate Envoy/grpcwebproxy. Bidirectional StreamFlow
+// requires HTTP/2 end to end, so browsers should use ExecuteFlow.
+//
+// Example:
+//
+//	server := grpc.NewServer(":8080")
+//	server.RegisterFlow("summarize", summarizeFlow)
+//
+//	mux := http.NewServeMux()
+//	mux.Handle("/", server.ConnectHandler())
+//	http.ListenAndServe(":8081", mux)
+func (s *Server) ConnectHandler(opts ...connect.HandlerOption) http.Handler {
+	fs := NewFlowService(s)
+	mux := http.NewServeMux()
+
+	mux.Handle(calquepb.FlowService_ExecuteFlow_FullMethodName, connect.NewUnaryHandler(
+		calquepb.FlowService_ExecuteFlow_FullMethodName,
+		func(ctx context.Context, req *connect.Request[calquepb.FlowRequest]) (*connect.Response[calquepb.FlowResponse], error) {
+			resp, err := fs.ExecuteFlow(withHeaderMetadata(ctx, req.Header()), req.Msg)
+			if err != nil {
+				return nil, err
+			}
+			return connect.NewResponse(resp), nil
+		},
+		opts...,
+	))
+
+	mux.Handle(calquepb.FlowService_StreamFlow_FullMethodName, connect.NewBidiStreamHandler(
+		calquepb.FlowService_StreamFlow_FullMethodName,
+		func(ctx context.Context, stream *connect.BidiStream[calquepb.StreamingFlowRequest, calquepb.StreamingFlowResponse]) error {
+			return fs.serveStream(withHeaderMetadata(ctx, stream.RequestHeader()), stream.Receive, stream.Send)
+		},
+		opts...,
+	))
+
+	return mux
+}
+
+// StartConnect serves the Connect handler on addr.
+//
+// Input: listen address, optional connect.HandlerOption values
+// Output: error if listening fails or the server stops unexpectedly
+// Behavior: Blocking; accepts HTTP/1.1 and cleartext HTTP/2 (h2c) on the same port
+//
+// Runs alongside Start, which serves native gRPC on the server's own address.
+// Stop shuts down both servers.
+//
+// Example:
+//
+//	go server.Start()              // native gRPC on :8080
+//	go server.StartConnect(":8081") // gRPC, gRPC-Web and Connect over HTTP
+func (s *Server) StartConnect(addr string, opts ...connect.HandlerOption) error {
+	ctx := context.Background()
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to listen on %s", addr))
+	}
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+
+	httpSrv := &http.Server{
+		Handler:           s.ConnectHandler(opts...),
+		Protocols:         protocols,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	s.mu.Lock()
+	s.httpSrv = httpSrv
+	s.mu.Unlock()
+
+	if err := httpSrv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return calque.WrapErr(ctx, err, "connect server failed")
+	}
+	return nil
+}
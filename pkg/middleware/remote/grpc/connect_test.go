@@ -0,0 +1,143 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	calquepb "github.com/calque-ai/go-calque/proto"
+)
+
+func newConnectTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := NewServer(":0")
+	server.RegisterFlow("upper", calque.NewFlow().UseFunc(func(req *calque.Request, res *calque.Response) error {
+		var input string
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+		return calque.Write(res, strings.ToUpper(input))
+	}))
+	server.RegisterFlow("identity", identityFlow())
+
+	ts := httptest.NewUnstartedServer(server.ConnectHandler())
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestConnectHandler_ExecuteFlow(t *testing.T) {
+	ts := newConnectTestServer(t)
+
+	tests := []struct {
+		name string
+		opts []connect.ClientOption
+	}{
+		{name: "connect protobuf"},
+		{name: "connect json", opts: []connect.ClientOption{connect.WithProtoJSON()}},
+		{name: "grpc", opts: []connect.ClientOption{connect.WithGRPC()}},
+		{name: "grpc-web", opts: []connect.ClientOption{connect.WithGRPCWeb()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := connect.NewClient[calquepb.FlowRequest, calquepb.FlowResponse](
+				ts.Client(), ts.URL+calquepb.FlowService_ExecuteFlow_FullMethodName, tt.opts...)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			resp, err := client.CallUnary(ctx, connect.NewRequest(&calquepb.FlowRequest{FlowName: "upper", Input: "hello"}))
+			if err != nil {
+				t.Fatalf("CallUnary() error = %v", err)
+			}
+			if !resp.Msg.Success || resp.Msg.Output != "HELLO" {
+				t.Errorf("unexpected response %+v", resp.Msg)
+			}
+		})
+	}
+}
+
+func TestConnectHandler_ExecuteFlowUnknown(t *testing.T) {
+	ts := newConnectTestServer(t)
+	client := connect.NewClient[calquepb.FlowRequest, calquepb.FlowResponse](
+		ts.Client(), ts.URL+calquepb.FlowService_ExecuteFlow_FullMethodName)
+
+	resp, err := client.CallUnary(context.Background(), connect.NewRequest(&calquepb.FlowRequest{FlowName: "missing", Input: "x"}))
+	if err != nil {
+		t.Fatalf("CallUnary() error = %v", err)
+	}
+	if resp.Msg.Success || !strings.Contains(resp.Msg.ErrorMessage, "flow missing not found") {
+		t.Errorf("expected not found error, got %+v", resp.Msg)
+	}
+}
+
+func TestConnectHandler_StreamFlow(t *testing.T) {
+	ts := newConnectTestServer(t)
+	client := connect.NewClient[calquepb.StreamingFlowRequest, calquepb.StreamingFlowResponse](
+		ts.Client(), ts.URL+calquepb.FlowService_StreamFlow_FullMethodName, connect.WithGRPC())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream := client.CallBidiStream(ctx)
+	for _, input := range []string{"one", "two"} {
+		if err := stream.Send(&calquepb.StreamingFlowRequest{FlowName: "upper", Input: input}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if err := stream.CloseRequest(); err != nil {
+		t.Fatalf("CloseRequest() error = %v", err)
+	}
+
+	var outputs []string
+	for {
+		resp, err := stream.Receive()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Receive() error = %v", err)
+		}
+		outputs = append(outputs, resp.Output)
+	}
+	_ = stream.CloseResponse()
+
+	if len(outputs) != 2 || outputs[0] != "ONE" || outputs[1] != "TWO" {
+		t.Errorf("unexpected outputs %v", outputs)
+	}
+}
+
+func TestServer_StartConnect(t *testing.T) {
+	server := NewServer(":0")
+	server.RegisterFlow("echo", calque.NewFlow())
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.StartConnect("127.0.0.1:0") }()
+
+	// Wait for the HTTP server to be created before stopping it
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		server.mu.Lock()
+		ready := server.httpSrv != nil
+		server.mu.Unlock()
+		if ready {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	server.Stop()
+	if err := <-errCh; err != nil {
+		t.Errorf("StartConnect() error = %v", err)
+	}
+}
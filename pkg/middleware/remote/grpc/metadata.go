@@ -0,0 +1,136 @@
+package grpc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Metadata keys mapped to and from the calque context
+const (
+	MetadataRequestID = "x-request-id"    // calque.RequestID
+	MetadataTraceID   = "x-trace-id"      // calque.TraceID
+	MetadataTenant    = "x-tenant-id"     // calque.Tenant
+	MetadataLocale    = "accept-language" // calque.Locale
+)
+
+// DefaultPropagatedMetadata lists incoming metadata keys that calls forward
+// unchanged to the next hop: credentials and W3C trace context.
+var DefaultPropagatedMetadata = []string{"authorization", "traceparent", "tracestate", "baggage"}
+
+// IncomingContext maps incoming gRPC metadata onto the calque context.
+//
+// Input: context carrying incoming gRPC metadata
+// Output: context with calque request ID, trace ID, tenant and locale set
+// Behavior: values already in the context win; missing metadata is skipped
+//
+// FlowService applies it to every request, so flows read the caller's
+// identity with calque.RequestID, calque.TraceID, calque.Tenant and
+// calque.Locale. When x-trace-id is absent, the trace ID is taken from a
+// W3C traceparent header. Use it directly when serving flows from your own
+// gRPC services.
+//
+// Example:
+//
+//	func (s *mySvc) Ask(ctx context.Context, req *pb.AskRequest) (*pb.AskResponse, error) {
+//		var out string
+//		err := flow.Run(grpcmw.IncomingContext(ctx), req.Question, &out)
+//		...
+//	}
+func IncomingContext(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	if id := firstValue(md, MetadataRequestID); id != "" && calque.RequestID(ctx) == "" {
+		ctx = calque.WithRequestID(ctx, id)
+	}
+	if calque.TraceID(ctx) == "" {
+		traceID := firstValue(md, MetadataTraceID)
+		if traceID == "" {
+			traceID = traceIDFromTraceparent(firstValue(md, "traceparent"))
+		}
+		if traceID != "" {
+			ctx = calque.WithTraceID(ctx, traceID)
+		}
+	}
+	if tenant := firstValue(md, MetadataTenant); tenant != "" && calque.Tenant(ctx) == "" {
+		ctx = calque.WithTenant(ctx, tenant)
+	}
+	if locale := firstValue(md, MetadataLocale); locale != "" && calque.Locale(ctx) == "" {
+		ctx = calque.WithLocale(ctx, locale)
+	}
+	return ctx
+}
+
+// OutgoingContext adds the calque context and propagated incoming metadata
+// to the outgoing gRPC metadata of ctx.
+//
+// Input: context of the current request, incoming keys to forward (nil uses DefaultPropagatedMetadata)
+// Output: context whose gRPC calls carry the request ID, trace ID, tenant, locale and forwarded keys
+// Behavior: keys already set on the outgoing metadata are left alone
+//
+// Call, CallWithTypes and Stream apply it with the service's propagation
+// list, so identity and tracing survive multi-hop flows.
+func OutgoingContext(ctx context.Context, propagate []string) context.Context {
+	if propagate == nil {
+		propagate = DefaultPropagatedMetadata
+	}
+	out, _ := metadata.FromOutgoingContext(ctx)
+	out = out.Copy()
+
+	set := func(key, value string) {
+		if value != "" && len(out.Get(key)) == 0 {
+			out.Set(key, value)
+		}
+	}
+	set(MetadataRequestID, calque.RequestID(ctx))
+	set(MetadataTraceID, calque.TraceID(ctx))
+	set(MetadataTenant, calque.Tenant(ctx))
+	set(MetadataLocale, calque.Locale(ctx))
+
+	if in, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, key := range propagate {
+			key = strings.ToLower(key)
+			if values := in.Get(key); len(values) > 0 && len(out.Get(key)) == 0 {
+				out.Set(key, values...)
+			}
+		}
+	}
+	return metadata.NewOutgoingContext(ctx, out)
+}
+
+// withHeaderMetadata exposes HTTP headers as incoming gRPC metadata, so
+// Connect and gRPC-Web requests look the same to flows as native gRPC ones
+func withHeaderMetadata(ctx context.Context, header http.Header) context.Context {
+	md := metadata.MD{}
+	for key, values := range header {
+		md.Append(strings.ToLower(key), values...)
+	}
+	if existing, ok := metadata.FromIncomingContext(ctx); ok {
+		md = metadata.Join(existing, md)
+	}
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+// traceIDFromTraceparent returns the trace ID of a W3C traceparent value
+// ("00-<trace-id>-<parent-id>-<flags>"), or "" when it is malformed
+func traceIDFromTraceparent(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || strings.Trim(parts[1], "0") == "" {
+		return ""
+	}
+	return parts[1]
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
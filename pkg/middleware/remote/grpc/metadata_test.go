@@ -0,0 +1,200 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	calquepb "github.com/calque-ai/go-calque/proto"
+)
+
+const testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+func TestIncomingContext(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		md         metadata.MD
+		ctx        func(context.Context) context.Context
+		wantReq    string
+		wantTrace  string
+		wantTenant string
+		wantLocale string
+	}{
+		{
+			name:       "calque headers",
+			md:         metadata.Pairs(MetadataRequestID, "req-1", MetadataTraceID, "trace-1", MetadataTenant, "acme", MetadataLocale, "fr-FR"),
+			wantReq:    "req-1",
+			wantTrace:  "trace-1",
+			wantTenant: "acme",
+			wantLocale: "fr-FR",
+		},
+		{
+			name:      "traceparent fallback",
+			md:        metadata.Pairs("traceparent", "00-"+testTraceID+"-00f067aa0ba902b7-01"),
+			wantTrace: testTraceID,
+		},
+		{
+			name: "malformed traceparent",
+			md:   metadata.Pairs("traceparent", "00-"+strings.Repeat("0", 32)+"-00f067aa0ba902b7-01"),
+		},
+		{
+			name: "existing values win",
+			md:   metadata.Pairs(MetadataTenant, "acme", MetadataTraceID, "trace-1"),
+			ctx: func(ctx context.Context) context.Context {
+				return calque.WithTenant(ctx, "local")
+			},
+			wantTrace:  "trace-1",
+			wantTenant: "local",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if tt.ctx != nil {
+				ctx = tt.ctx(ctx)
+			}
+			ctx = IncomingContext(metadata.NewIncomingContext(ctx, tt.md))
+
+			if got := calque.RequestID(ctx); got != tt.wantReq {
+				t.Errorf("RequestID = %q, want %q", got, tt.wantReq)
+			}
+			if got := calque.TraceID(ctx); got != tt.wantTrace {
+				t.Errorf("TraceID = %q, want %q", got, tt.wantTrace)
+			}
+			if got := calque.Tenant(ctx); got != tt.wantTenant {
+				t.Errorf("Tenant = %q, want %q", got, tt.wantTenant)
+			}
+			if got := calque.Locale(ctx); got != tt.wantLocale {
+				t.Errorf("Locale = %q, want %q", got, tt.wantLocale)
+			}
+		})
+	}
+}
+
+func TestOutgoingContext(t *testing.T) {
+	t.Parallel()
+
+	incoming := metadata.Pairs(
+		"authorization", "Bearer token",
+		"traceparent", "00-"+testTraceID+"-00f067aa0ba902b7-01",
+		"x-internal", "secret",
+	)
+	base := metadata.NewIncomingContext(context.Background(), incoming)
+	base = calque.WithRequestID(IncomingContext(base), "req-1")
+	base = calque.WithTenant(base, "acme")
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		propagate []string
+		want      map[string]string
+		absent    []string
+	}{
+		{
+			name: "defaults",
+			ctx:  base,
+			want: map[string]string{
+				MetadataRequestID: "req-1",
+				MetadataTraceID:   testTraceID,
+				MetadataTenant:    "acme",
+				"authorization":   "Bearer token",
+				"traceparent":     "00-" + testTraceID + "-00f067aa0ba902b7-01",
+			},
+			absent: []string{"x-internal", MetadataLocale},
+		},
+		{
+			name:      "custom keys",
+			ctx:       base,
+			propagate: []string{"X-Internal"},
+			want:      map[string]string{"x-internal": "secret", MetadataTenant: "acme"},
+			absent:    []string{"authorization"},
+		},
+		{
+			name:      "forward none",
+			ctx:       base,
+			propagate: []string{},
+			want:      map[string]string{MetadataRequestID: "req-1"},
+			absent:    []string{"authorization", "traceparent"},
+		},
+		{
+			name: "explicit outgoing metadata wins",
+			ctx:  metadata.AppendToOutgoingContext(base, MetadataTenant, "other", "authorization", "Bearer service"),
+			want: map[string]string{MetadataTenant: "other", "authorization": "Bearer service"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			md, _ := metadata.FromOutgoingContext(OutgoingContext(tt.ctx, tt.propagate))
+			for key, want := range tt.want {
+				if got := md.Get(key); len(got) != 1 || got[0] != want {
+					t.Errorf("%s = %v, want %q", key, got, want)
+				}
+			}
+			for _, key := range tt.absent {
+				if got := md.Get(key); len(got) != 0 {
+					t.Errorf("%s = %v, want absent", key, got)
+				}
+			}
+		})
+	}
+}
+
+// identityFlow reports the calque identity the flow sees
+func identityFlow() *calque.Flow {
+	return calque.NewFlow().UseFunc(func(req *calque.Request, res *calque.Response) error {
+		ctx := req.Context
+		return calque.Write(res, strings.Join([]string{
+			calque.RequestID(ctx), calque.TraceID(ctx), calque.Tenant(ctx),
+		}, "|"))
+	})
+}
+
+func TestFlowServiceExecuteFlowMetadata(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer(":0")
+	server.RegisterFlow("identity", identityFlow())
+	fs := NewFlowService(server)
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(MetadataRequestID, "req-1", MetadataTenant, "acme", "traceparent", "00-"+testTraceID+"-00f067aa0ba902b7-01"))
+
+	resp, err := fs.ExecuteFlow(ctx, &calquepb.FlowRequest{FlowName: "identity"})
+	if err != nil || !resp.Success {
+		t.Fatalf("ExecuteFlow() = %+v, %v", resp, err)
+	}
+	if want := "req-1|" + testTraceID + "|acme"; resp.Output != want {
+		t.Errorf("output = %q, want %q", resp.Output, want)
+	}
+}
+
+func TestConnectHandlerMetadata(t *testing.T) {
+	ts := newConnectTestServer(t)
+	client := connect.NewClient[calquepb.FlowRequest, calquepb.FlowResponse](
+		ts.Client(), ts.URL+calquepb.FlowService_ExecuteFlow_FullMethodName)
+
+	req := connect.NewRequest(&calquepb.FlowRequest{FlowName: "identity"})
+	req.Header().Set("X-Request-Id", "req-1")
+	req.Header().Set("X-Trace-Id", "trace-1")
+	req.Header().Set("X-Tenant-Id", "acme")
+
+	resp, err := client.CallUnary(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CallUnary() error = %v", err)
+	}
+	if want := "req-1|trace-1|acme"; resp.Msg.Output != want {
+		t.Errorf("output = %q, want %q", resp.Msg.Output, want)
+	}
+}
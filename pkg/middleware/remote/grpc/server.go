@@ -3,8 +3,13 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net"
+	"net/http"
+	"sync"
 	"time"
 
 	"google.golang.org/grpc"
@@ -23,6 +28,8 @@ type Server struct {
 	addr      string
 	healthSrv *health.Server
 	startTime time.Time
+	httpSrv   *http.Server // set by StartConnect
+	mu        sync.Mutex   // guards httpSrv
 }
 
 // NewServer creates a new gRPC server for hosting flows.
@@ -75,11 +82,65 @@ func (s *Server) Start() error {
 	return s.server.Serve(lis)
 }
 
-// Stop gracefully stops the gRPC server.
+// Stop gracefully stops the gRPC server and the Connect HTTP server if started.
 func (s *Server) Stop() {
+	s.mu.Lock()
+	httpSrv := s.httpSrv
+	s.mu.Unlock()
+
+	if httpSrv != nil {
+		_ = httpSrv.Shutdown(context.Background())
+	}
 	s.server.GracefulStop()
 }
 
+// Shutdown stops accepting requests, drains in-flight calls and shuts down every registered flow.
+//
+// Health checks report NOT_SERVING first so load balancers stop routing new
+// traffic. In-flight RPCs may finish until ctx ends, after which remaining
+// connections are closed forcibly. Registered flows are then shut down with
+// the same ctx, closing their clients. Server implements calque.Shutdowner,
+// so it can be passed to calque.GracefulShutdown.
+//
+// Example:
+//
+//	go server.Start()
+//	err := calque.GracefulShutdown(ctx, 25*time.Second, server)
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.healthSrv.Shutdown()
+
+	s.mu.Lock()
+	httpSrv := s.httpSrv
+	s.mu.Unlock()
+
+	var errs []error
+	if httpSrv != nil {
+		if err := httpSrv.Shutdown(ctx); err != nil {
+			errs = append(errs, calque.WrapErr(ctx, err, "connect server shutdown failed"))
+		}
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.server.Stop()
+		<-stopped
+		errs = append(errs, calque.WrapErr(ctx, ctx.Err(), "grpc server did not drain before the deadline"))
+	}
+
+	for name, flow := range s.flows {
+		if err := flow.Shutdown(ctx); err != nil {
+			errs = append(errs, calque.WrapErr(ctx, err, fmt.Sprintf("flow %s shutdown failed", name)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // GetServer returns the underlying gRPC server for advanced configuration.
 func (s *Server) GetServer() *grpc.Server {
 	return s.server
@@ -113,6 +174,8 @@ func NewFlowService(server *Server) *FlowService {
 
 // ExecuteFlow executes a registered flow with the given input.
 func (fs *FlowService) ExecuteFlow(ctx context.Context, req *calquepb.FlowRequest) (*calquepb.FlowResponse, error) {
+	ctx = IncomingContext(ctx)
+
 	// Get the flow
 	flow, err := fs.server.GetFlow(ctx, req.FlowName)
 	if err != nil {
@@ -123,9 +186,11 @@ func (fs *FlowService) ExecuteFlow(ctx context.Context, req *calquepb.FlowReques
 	}
 
 	// Execute the flow
-	var result string
-	err = flow.Run(ctx, req.Input, &result)
+	result, metadata, err := runFlow(ctx, flow, req.Input, req.Metadata)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, callerGone(ctx, ctxErr, req.FlowName)
+		}
 		return &calquepb.FlowResponse{
 			Success:      false,
 			ErrorMessage: fmt.Sprintf("failed to execute flow: %v", err),
@@ -135,48 +200,95 @@ func (fs *FlowService) ExecuteFlow(ctx context.Context, req *calquepb.FlowReques
 	return &calquepb.FlowResponse{
 		Output:   result,
 		Success:  true,
-		Metadata: req.Metadata,
+		Metadata: metadata,
 	}, nil
 }
 
+// runFlow executes a flow and returns its output with the request metadata,
+// adding the run's model usage under calque.UsageMetadataKey so callers can
+// account for it (see Call)
+func runFlow(ctx context.Context, flow *calque.Flow, input string, metadata map[string]string) (string, map[string]string, error) {
+	bus := calque.NewMetadataBus(0)
+	defer bus.Close()
+	ctx = calque.WithMetadataBus(ctx, bus)
+
+	var result string
+	if err := flow.Run(ctx, input, &result); err != nil {
+		return "", nil, err
+	}
+
+	usage := calque.UsageFrom(ctx)
+	if usage.IsZero() {
+		return result, metadata, nil
+	}
+	encoded, err := usage.MarshalText()
+	if err != nil {
+		return result, metadata, nil
+	}
+	out := make(map[string]string, len(metadata)+1)
+	maps.Copy(out, metadata)
+	out[calque.UsageMetadataKey] = string(encoded)
+	return result, out, nil
+}
+
+// callerGone reports a flow stopped because the caller cancelled or its
+// deadline passed. Both transports map the wrapped context error to
+// Canceled or DeadlineExceeded, so callers see why the run ended instead of
+// a failed response they are no longer waiting for.
+func callerGone(ctx context.Context, ctxErr error, flowName string) error {
+	return calque.WrapErr(ctx, ctxErr, fmt.Sprintf("flow %s abandoned by caller", flowName))
+}
+
 // StreamFlow executes a registered flow with bidirectional streaming.
 func (fs *FlowService) StreamFlow(stream calquepb.FlowService_StreamFlowServer) error {
+	return fs.serveStream(stream.Context(), stream.Recv, stream.Send)
+}
+
+// serveStream runs the streaming request loop shared by the gRPC and Connect transports
+func (fs *FlowService) serveStream(
+	ctx context.Context,
+	recv func() (*calquepb.StreamingFlowRequest, error),
+	send func(*calquepb.StreamingFlowResponse) error,
+) error {
 	// This is a placeholder implementation for streaming
 	// In practice, this would handle bidirectional streaming with the flow
+	ctx = IncomingContext(ctx)
 
 	for {
-		req, err := stream.Recv()
+		req, err := recv()
 		if err != nil {
-			if err.Error() == "EOF" {
+			if errors.Is(err, io.EOF) {
 				break
 			}
 			return err
 		}
 
 		// Get the flow
-		flow, err := fs.server.GetFlow(stream.Context(), req.FlowName)
+		flow, err := fs.server.GetFlow(ctx, req.FlowName)
 		if err != nil {
 			resp := &calquepb.StreamingFlowResponse{
 				Success:      false,
 				ErrorMessage: fmt.Sprintf("failed to get flow %s: %v", req.FlowName, err),
 				IsFinal:      true,
 			}
-			if err := stream.Send(resp); err != nil {
+			if err := send(resp); err != nil {
 				return err
 			}
 			continue
 		}
 
 		// Execute the flow
-		var result string
-		err = flow.Run(stream.Context(), req.Input, &result)
+		result, metadata, err := runFlow(ctx, flow, req.Input, req.Metadata)
 		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return callerGone(ctx, ctxErr, req.FlowName)
+			}
 			resp := &calquepb.StreamingFlowResponse{
 				Success:      false,
 				ErrorMessage: fmt.Sprintf("failed to execute flow: %v", err),
 				IsFinal:      true,
 			}
-			if err := stream.Send(resp); err != nil {
+			if err := send(resp); err != nil {
 				return err
 			}
 			continue
@@ -186,10 +298,10 @@ func (fs *FlowService) StreamFlow(stream calquepb.FlowService_StreamFlowServer)
 		resp := &calquepb.StreamingFlowResponse{
 			Output:   result,
 			Success:  true,
-			Metadata: req.Metadata,
+			Metadata: metadata,
 			IsFinal:  true,
 		}
-		if err := stream.Send(resp); err != nil {
+		if err := send(resp); err != nil {
 			return err
 		}
 	}
@@ -2,11 +2,15 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"io"
 	"testing"
 	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
 	calquepb "github.com/calque-ai/go-calque/proto"
@@ -150,6 +154,88 @@ func TestFlowServiceExecuteFlow(t *testing.T) {
 	}
 }
 
+func TestFlowServiceExecuteFlowReportsUsage(t *testing.T) {
+	t.Parallel()
+	server := NewServer(":8080")
+	flowService := NewFlowService(server)
+
+	server.RegisterFlow("usage-flow", calque.NewFlow().
+		UseFunc(func(req *calque.Request, res *calque.Response) error {
+			calque.RecordUsage(req.Context, calque.Usage{TotalTokens: 12, Calls: 1})
+			return calque.Write(res, "done")
+		}))
+
+	resp, err := flowService.ExecuteFlow(context.Background(), &calquepb.FlowRequest{
+		FlowName: "usage-flow",
+		Metadata: map[string]string{"service": "usage-service"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("ExecuteFlow() = %+v, %v", resp, err)
+	}
+	if resp.Metadata["service"] != "usage-service" {
+		t.Errorf("request metadata not returned: %v", resp.Metadata)
+	}
+
+	ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+	recordRemoteUsage(ctx, resp.Metadata)
+	if got := calque.UsageFrom(ctx); got.TotalTokens != 12 || got.Calls != 1 {
+		t.Errorf("UsageFrom() after remote call = %+v", got)
+	}
+}
+
+func TestFlowServiceExecuteFlowCallerGone(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer(":8080")
+	flowService := NewFlowService(server)
+	server.RegisterFlow("slow-flow", calque.NewFlow().
+		UseFunc(func(req *calque.Request, _ *calque.Response) error {
+			<-req.Context.Done()
+			return req.Context.Err()
+		}))
+
+	tests := []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+		want codes.Code
+	}{
+		{
+			name: "deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+			want: codes.DeadlineExceeded,
+		},
+		{
+			name: "cancelled",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			want: codes.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			resp, err := flowService.ExecuteFlow(ctx, &calquepb.FlowRequest{FlowName: "slow-flow"})
+			if resp != nil {
+				t.Errorf("ExecuteFlow() response = %+v, want none for an abandoned call", resp)
+			}
+			// The gRPC server converts returned context errors with FromContextError
+			if got := status.FromContextError(err).Code(); got != tt.want {
+				t.Errorf("ExecuteFlow() error = %v (%v), want %v", err, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestFlowServiceExecuteFlowNonExistent(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -392,3 +478,40 @@ func TestServerGetHealthServer(t *testing.T) {
 		t.Error("Expected non-nil health server")
 	}
 }
+
+func TestServerShutdown(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer("127.0.0.1:0")
+	flow := calque.NewFlow().UseFunc(func(req *calque.Request, res *calque.Response) error {
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+	server.RegisterFlow("echo", flow)
+
+	errChan := make(chan error, 1)
+	go func() { errChan <- server.Start() }()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	select {
+	case <-errChan:
+	case <-time.After(time.Second):
+		t.Error("Start() did not return after Shutdown")
+	}
+
+	resp, err := server.GetHealthServer().Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil || resp.Status != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("health after shutdown = %v, %v; want NOT_SERVING", resp, err)
+	}
+
+	var out string
+	if err := flow.Run(context.Background(), "x", &out); !errors.Is(err, calque.ErrFlowShutdown) {
+		t.Errorf("registered flow Run() error = %v, want ErrFlowShutdown", err)
+	}
+}
@@ -30,17 +30,21 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"time"
 
 	grpcclient "google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
 	grpcerrors "github.com/calque-ai/go-calque/pkg/grpc"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
 )
 
 // Service represents a registered gRPC service with connection and metadata.
@@ -53,6 +57,16 @@ type Service struct {
 	Timeout    time.Duration // Timeout for gRPC calls
 	MaxRetries int           // Maximum number of retries for failed calls
 	RetryDelay time.Duration // Delay between retries
+
+	// PropagateMetadata lists incoming gRPC metadata keys forwarded on calls
+	// (nil uses DefaultPropagatedMetadata, empty forwards none)
+	PropagateMetadata []string
+
+	// Compression compresses unary requests of at least CompressMinBytes
+	// (default: ctrl.DefaultCompressMinBytes); responses come back compressed
+	// the same way. The server must run this package or register the codec.
+	Compression      ctrl.Compression
+	CompressMinBytes int
 }
 
 // Registry manages multiple gRPC services and their connections.
@@ -186,6 +200,16 @@ type registryHandler struct {
 	services []*Service
 }
 
+// Egress implements calque.Egress with the hosts of the registered services,
+// so local-only flows reject calls to services on other machines
+func (rh *registryHandler) Egress() []string {
+	endpoints := make([]string, len(rh.services))
+	for i, service := range rh.services {
+		endpoints[i] = service.Endpoint
+	}
+	return calque.EgressHosts(endpoints...)
+}
+
 func (rh *registryHandler) ServeFlow(req *calque.Request, res *calque.Response) error {
 	// Create a registry and register all services
 	registry := NewRegistry()
@@ -204,6 +228,94 @@ func (rh *registryHandler) ServeFlow(req *calque.Request, res *calque.Response)
 	return err
 }
 
+// Warmup implements calque.Warmer by dialing every service before the first request.
+//
+// Services keep their connection, so requests through this handler reuse it
+// instead of connecting lazily.
+func (rh *registryHandler) Warmup(ctx context.Context) error {
+	registry := NewRegistry()
+	for _, service := range rh.services {
+		if err := registry.Register(service); err != nil {
+			return grpcerrors.WrapErrorfSimple(ctx, err, "failed to register service %s", service.Name)
+		}
+	}
+	return registry.Warmup(ctx)
+}
+
+// Shutdown implements calque.Shutdowner by closing the services' connections
+func (rh *registryHandler) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, service := range rh.services {
+		if service.Conn == nil {
+			continue
+		}
+		if err := service.Conn.Close(); err != nil {
+			errs = append(errs, grpcerrors.WrapErrorfSimple(ctx, err, "failed to close connection for service %s", service.Name))
+		}
+		service.Conn = nil
+	}
+	return errors.Join(errs...)
+}
+
+// Warmup connects every registered service and waits until each connection is ready.
+//
+// gRPC client connections are established lazily on the first call; Warmup
+// forces the dial so name resolution, TCP and TLS handshakes happen ahead of
+// traffic. It returns an error naming each service that is not ready when ctx ends.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	if err := registry.Warmup(ctx); err != nil {
+//		log.Printf("some services are not reachable yet: %v", err)
+//	}
+func (r *Registry) Warmup(ctx context.Context) error {
+	r.mu.RLock()
+	services := make([]*Service, 0, len(r.services))
+	for _, service := range r.services {
+		services = append(services, service)
+	}
+	r.mu.RUnlock()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, service := range services {
+		if service.Conn == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := waitReady(ctx, service.Conn); err != nil {
+				mu.Lock()
+				errs = append(errs, grpcerrors.WrapErrorfSimple(ctx, err, "service %s at %s is not ready", service.Name, service.Endpoint))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// waitReady dials conn and blocks until it is ready or ctx ends
+func waitReady(ctx context.Context, conn *grpcclient.ClientConn) error {
+	conn.Connect()
+	for {
+		state := conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("connection %s: %w", strings.ToLower(state.String()), ctx.Err())
+		}
+	}
+}
+
 // registryContextKey is used to store the gRPC registry in context
 type registryContextKey struct{}
 
@@ -233,3 +345,24 @@ func (s *Service) WithRetries(maxRetries int, retryDelay time.Duration) *Service
 	s.RetryDelay = retryDelay
 	return s
 }
+
+// WithPropagatedMetadata sets the incoming metadata keys forwarded to this service.
+// Calling it with no keys stops forwarding; calque request, trace, tenant and
+// locale values are always sent.
+func (s *Service) WithPropagatedMetadata(keys ...string) *Service {
+	s.PropagateMetadata = append([]string{}, keys...)
+	return s
+}
+
+// WithCompression compresses unary requests of at least minBytes with c.
+// A minBytes of 0 uses ctrl.DefaultCompressMinBytes.
+//
+// Example:
+//
+//	service := grpc.NewService("retriever", "retriever:8080").
+//		WithCompression(ctrl.Zstd, 0)
+func (s *Service) WithCompression(c ctrl.Compression, minBytes int) *Service {
+	s.Compression = c
+	s.CompressMinBytes = minBytes
+	return s
+}
@@ -3,9 +3,14 @@ package grpc
 import (
 	"context"
 	"fmt"
+	"net"
+	"slices"
 	"testing"
 	"time"
 
+	grpcclient "google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+
 	"github.com/calque-ai/go-calque/pkg/calque"
 	calquepb "github.com/calque-ai/go-calque/proto"
 )
@@ -107,6 +112,31 @@ func TestRegistryHandler(t *testing.T) {
 	}
 }
 
+func TestRegistryHandlerEgress(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		services []*Service
+		want     []string
+	}{
+		{"local", []*Service{{Name: "local", Endpoint: testEndpoint}, {Name: "loopback", Endpoint: "127.0.0.1:9000"}}, nil},
+		{"remote", []*Service{{Name: "local", Endpoint: testEndpoint}, {Name: "remote", Endpoint: "ai.example.com:443"}}, []string{"ai.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewRegistryHandler(tt.services...)
+			if got := calque.DescribeHandler(handler).Egress; !slices.Equal(got, tt.want) {
+				t.Errorf("egress = %v, want %v", got, tt.want)
+			}
+			err := calque.NewFlow(calque.WithDataPolicy(calque.LocalOnly)).Use(handler).Validate()
+			if (err != nil) != (tt.want != nil) {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
+
 func TestServiceCreation(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -596,3 +626,44 @@ func TestServiceConfigurationMethods(t *testing.T) {
 		t.Errorf("Expected retry delay %v, got %v", retryDelay, service.RetryDelay)
 	}
 }
+
+func TestRegistryWarmup(t *testing.T) {
+	t.Parallel()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := grpcclient.NewServer()
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	up := NewService("up", lis.Addr().String())
+	handler := NewRegistryHandler(up)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := calque.NewFlow().Use(handler).Warmup(ctx); err != nil {
+		t.Fatalf("Warmup() error = %v", err)
+	}
+	if up.Conn == nil || up.Conn.GetState() != connectivity.Ready {
+		t.Errorf("connection not ready after warm-up: %v", up.Conn)
+	}
+	defer up.Conn.Close()
+
+	// A closed port never becomes ready
+	closed, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := closed.Addr().String()
+	closed.Close()
+
+	registry := NewRegistry()
+	defer registry.Close()
+	if err := registry.Register(NewService("down", addr)); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	short, cancelShort := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancelShort()
+	if err := registry.Warmup(short); err == nil {
+		t.Error("Warmup() of an unreachable service should fail")
+	}
+}
@@ -0,0 +1,289 @@
+package httpflow
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+// Mode selects the wire format Call uses
+type Mode int
+
+const (
+	// ModeChunked streams raw input and output with chunked transfer encoding (default)
+	ModeChunked Mode = iota
+	// ModeSSE sends raw input and reads output as Server-Sent Events, for proxies that buffer chunked responses
+	ModeSSE
+	// ModeJSON sends a FlowRequest and reads a FlowResponse; nothing streams
+	ModeJSON
+)
+
+// maxSSELine bounds a single SSE line read by Call
+const maxSSELine = 1 << 20
+
+// Config configures Call
+type Config struct {
+	Client   *http.Client      // HTTP client (default: http.DefaultClient)
+	Mode     Mode              // Wire format (default: ModeChunked)
+	Headers  map[string]string // Extra request headers, e.g. Authorization
+	Metadata map[string]string // Request metadata sent with ModeJSON
+	Timeout  time.Duration     // Per-call timeout on top of the request context (default: none)
+
+	// Compression compresses request bodies of at least CompressMinBytes and
+	// asks the server for compressed JSON responses (default: none)
+	Compression      ctrl.Compression
+	CompressMinBytes int // Smallest request body compressed (default: ctrl.DefaultCompressMinBytes)
+}
+
+// Call runs a remote flow served by a Server.
+//
+// Input: flow data to send to the remote flow
+// Output: the remote flow's output
+// Behavior: STREAMING - input is uploaded while output streams back (ModeChunked, ModeSSE)
+//
+// url is the flow's full endpoint, e.g. "http://host:8080/flows/summarize".
+// The calque request ID, trace ID, tenant and locale travel as headers, and
+// model usage reported by the remote run is added to this run's total.
+// Cancelling the request context closes the connection, which cancels the
+// remote run. Remote failures, including those after output has started,
+// are returned as errors.
+//
+// Example:
+//
+//	flow := calque.NewFlow().
+//		Use(httpflow.Call("http://summarizer:8080/flows/summarize"))
+func Call(url string) calque.Handler {
+	return CallWithConfig(url, nil)
+}
+
+// CallWithConfig runs a remote flow with custom settings
+//
+// Example:
+//
+//	handler := httpflow.CallWithConfig(url, &httpflow.Config{
+//		Mode:    httpflow.ModeSSE,
+//		Headers: map[string]string{"Authorization": "Bearer " + token},
+//		Timeout: 30 * time.Second,
+//	})
+//
+// Large payloads, such as retrieved contexts, can be compressed on the wire.
+// The first CompressMinBytes of input are read before the request is sent to
+// decide whether to compress:
+//
+//	handler := httpflow.CallWithConfig(url, &httpflow.Config{Compression: ctrl.Zstd})
+func CallWithConfig(url string, config *Config) calque.Handler {
+	cfg := Config{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	if cfg.CompressMinBytes <= 0 {
+		cfg.CompressMinBytes = ctrl.DefaultCompressMinBytes
+	}
+
+	return calque.WithEgress(calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		ctx := req.Context
+		if cfg.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+			defer cancel()
+		}
+
+		httpReq, err := newRequest(ctx, url, req.Data, cfg)
+		if err != nil {
+			return err
+		}
+		resp, err := cfg.Client.Do(httpReq)
+		if err != nil {
+			return calque.WrapErr(ctx, err, fmt.Sprintf("remote flow request to %s failed", url))
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return remoteError(ctx, resp)
+		}
+		if encoding := resp.Header.Get("Content-Encoding"); encoding != "" {
+			body, err := ctrl.DecompressReader(ctx, resp.Body, ctrl.Compression(encoding))
+			if err != nil {
+				return err
+			}
+			defer body.Close()
+			resp.Body = body
+		}
+
+		switch {
+		case hasMediaType(resp.Header.Get("Content-Type"), contentTypeSSE):
+			return readSSE(ctx, resp.Body, res)
+		case hasMediaType(resp.Header.Get("Content-Type"), contentTypeJSON):
+			return readJSON(ctx, resp.Body, res)
+		default:
+			return readChunked(ctx, resp, res)
+		}
+	}), calque.EgressHosts(url)...)
+}
+
+// newRequest builds the POST for the configured mode
+func newRequest(ctx context.Context, url string, input io.Reader, cfg Config) (*http.Request, error) {
+	body, contentType, accept := input, contentTypeText, contentTypeText
+	switch cfg.Mode {
+	case ModeSSE:
+		accept = contentTypeSSE
+	case ModeJSON:
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to read input")
+		}
+		encoded, err := json.Marshal(FlowRequest{Input: string(data), Metadata: cfg.Metadata})
+		if err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to encode flow request")
+		}
+		body, contentType, accept = bytes.NewReader(encoded), contentTypeJSON, contentTypeJSON
+	}
+
+	compressed := false
+	if cfg.Compression != "" {
+		var err error
+		if body, compressed, err = ctrl.CompressAbove(ctx, body, cfg.Compression, cfg.CompressMinBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		if closer, ok := body.(io.Closer); ok {
+			closer.Close() // Stops the compressing goroutine
+		}
+		return nil, calque.WrapErr(ctx, err, "failed to create remote flow request")
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+	httpReq.Header.Set("Accept", accept)
+	if cfg.Compression != "" {
+		// Setting Accept-Encoding turns off the transport's transparent gzip,
+		// so responses are decoded by CallWithConfig
+		httpReq.Header.Set("Accept-Encoding", string(cfg.Compression))
+	}
+	if compressed {
+		httpReq.Header.Set("Content-Encoding", string(cfg.Compression))
+	}
+	for key, value := range cfg.Headers {
+		httpReq.Header.Set(key, value)
+	}
+	setHeader(httpReq.Header, HeaderRequestID, calque.RequestID(ctx))
+	setHeader(httpReq.Header, HeaderTraceID, calque.TraceID(ctx))
+	setHeader(httpReq.Header, HeaderTenant, calque.Tenant(ctx))
+	setHeader(httpReq.Header, HeaderLocale, calque.Locale(ctx))
+	return httpReq, nil
+}
+
+// setHeader sets a context value unless it is empty or configured explicitly
+func setHeader(header http.Header, key, value string) {
+	if value != "" && header.Get(key) == "" {
+		header.Set(key, value)
+	}
+}
+
+// readChunked copies the raw body, then checks the trailers for a late failure
+func readChunked(ctx context.Context, resp *http.Response, res *calque.Response) error {
+	if _, err := io.Copy(res.Data, resp.Body); err != nil {
+		return calque.WrapErr(ctx, err, "failed to read remote flow output")
+	}
+	if msg := resp.Trailer.Get(TrailerError); msg != "" {
+		return calque.NewErr(ctx, "remote flow failed: "+msg)
+	}
+	recordRemoteUsage(ctx, resp.Trailer.Get(TrailerUsage))
+	return nil
+}
+
+// readJSON writes the output of a FlowResponse
+func readJSON(ctx context.Context, body io.Reader, res *calque.Response) error {
+	var resp FlowResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return calque.WrapErr(ctx, err, "failed to decode remote flow response")
+	}
+	if resp.Error != "" {
+		return calque.NewErr(ctx, "remote flow failed: "+resp.Error)
+	}
+	recordRemoteUsage(ctx, resp.Metadata[calque.UsageMetadataKey])
+	return calque.Write(res, resp.Output)
+}
+
+// readSSE writes "message" events until "completion" or "error"
+func readSSE(ctx context.Context, body io.Reader, res *calque.Response) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELine)
+
+	var event string
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			done, err := handleEvent(ctx, event, data.String(), res)
+			if done || err != nil {
+				return err
+			}
+			event = ""
+			data.Reset()
+		case strings.HasPrefix(line, ":"):
+			// Keep-alive comment
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return calque.WrapErr(ctx, err, "failed to read remote flow events")
+	}
+	return calque.NewErr(ctx, "remote flow stream ended before completion")
+}
+
+// handleEvent applies one SSE event, reporting whether the stream is complete
+func handleEvent(ctx context.Context, event, data string, res *calque.Response) (bool, error) {
+	switch event {
+	case eventMessage, "":
+		var chunk string
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return true, calque.WrapErr(ctx, err, "invalid remote flow event")
+		}
+		if _, err := io.WriteString(res.Data, chunk); err != nil {
+			return true, err
+		}
+	case eventUsage:
+		recordRemoteUsage(ctx, data)
+	case eventError:
+		var failure FlowResponse
+		if err := json.Unmarshal([]byte(data), &failure); err != nil || failure.Error == "" {
+			failure.Error = data
+		}
+		return true, calque.NewErr(ctx, "remote flow failed: "+failure.Error)
+	case eventCompletion:
+		return true, nil
+	}
+	return false, nil
+}
+
+// remoteError builds an error from a non-2xx response
+func remoteError(ctx context.Context, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	msg := strings.TrimSpace(string(body))
+	var failure FlowResponse
+	if json.Unmarshal(body, &failure) == nil && failure.Error != "" {
+		msg = failure.Error
+	}
+	return calque.NewErr(ctx, fmt.Sprintf("remote flow returned %s: %s", resp.Status, msg))
+}
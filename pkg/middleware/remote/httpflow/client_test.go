@@ -0,0 +1,309 @@
+package httpflow
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+// newTestServer serves flows for the client tests
+func newTestServer(t *testing.T, flows map[string]*calque.Flow) *httptest.Server {
+	t.Helper()
+
+	server := NewServer()
+	for name, flow := range flows {
+		server.RegisterFlow(name, flow)
+	}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func upperFlow() *calque.Flow {
+	return calque.NewFlow().UseFunc(func(req *calque.Request, res *calque.Response) error {
+		var input string
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+		calque.RecordUsage(req.Context, calque.Usage{TotalTokens: 12, Calls: 1})
+		return calque.Write(res, strings.ToUpper(input)+" for "+calque.Tenant(req.Context))
+	})
+}
+
+func TestCall(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, map[string]*calque.Flow{"upper": upperFlow()})
+
+	for _, mode := range []Mode{ModeChunked, ModeSSE, ModeJSON} {
+		t.Run(modeName(mode), func(t *testing.T) {
+			t.Parallel()
+
+			ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+			ctx = calque.WithTenant(ctx, "acme")
+			flow := calque.NewFlow().Use(CallWithConfig(ts.URL+"/flows/upper", &Config{Mode: mode}))
+
+			var out string
+			if err := flow.Run(ctx, "héllo wörld", &out); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if want := "HÉLLO WÖRLD for acme"; out != want {
+				t.Errorf("output = %q, want %q", out, want)
+			}
+			if got := calque.UsageFrom(ctx); got.TotalTokens != 12 || got.Calls != 1 {
+				t.Errorf("UsageFrom() = %+v, want the remote run's usage", got)
+			}
+		})
+	}
+}
+
+func TestCallStreams(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	ts := newTestServer(t, map[string]*calque.Flow{
+		"slow": calque.NewFlow().UseFunc(func(req *calque.Request, res *calque.Response) error {
+			if err := calque.Write(res, "first "); err != nil {
+				return err
+			}
+			select {
+			case <-release:
+			case <-req.Context.Done():
+				return req.Context.Err()
+			}
+			return calque.Write(res, "second")
+		}),
+	})
+
+	for _, mode := range []Mode{ModeChunked, ModeSSE} {
+		t.Run(modeName(mode), func(t *testing.T) {
+			// The remote flow only finishes once the first chunk has reached the caller
+			out := &firstWriteSignal{first: make(chan struct{})}
+			go func() {
+				select {
+				case <-out.first:
+					release <- struct{}{}
+				case <-time.After(5 * time.Second):
+				}
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			flow := calque.NewFlow().Use(CallWithConfig(ts.URL+"/flows/slow", &Config{Mode: mode}))
+			if err := flow.Run(ctx, "", out); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if got := out.String(); got != "first second" {
+				t.Errorf("output = %q, want %q", got, "first second")
+			}
+		})
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, map[string]*calque.Flow{
+		"fails-early": calque.NewFlow().UseFunc(func(req *calque.Request, _ *calque.Response) error {
+			return calque.NewErr(req.Context, "model unavailable")
+		}),
+		"fails-late": calque.NewFlow().UseFunc(func(req *calque.Request, res *calque.Response) error {
+			if err := calque.Write(res, "partial"); err != nil {
+				return err
+			}
+			return calque.NewErr(req.Context, "stream broke")
+		}),
+	})
+
+	tests := []struct {
+		name    string
+		flow    string
+		mode    Mode
+		wantErr string
+	}{
+		{name: "unknown flow", flow: "missing", wantErr: "404 Not Found: flow missing not found"},
+		{name: "early failure", flow: "fails-early", wantErr: "500 Internal Server Error: model unavailable"},
+		{name: "late failure chunked", flow: "fails-late", wantErr: "remote flow failed: stream broke"},
+		{name: "late failure sse", flow: "fails-late", mode: ModeSSE, wantErr: "remote flow failed: stream broke"},
+		{name: "failure json", flow: "fails-late", mode: ModeJSON, wantErr: "stream broke"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			flow := calque.NewFlow().Use(CallWithConfig(ts.URL+"/flows/"+tt.flow, &Config{Mode: tt.mode}))
+			var out string
+			err := flow.Run(context.Background(), "input", &out)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCallCancellation(t *testing.T) {
+	t.Parallel()
+
+	remoteErr := make(chan error, 1)
+	ts := newTestServer(t, map[string]*calque.Flow{
+		"hang": calque.NewFlow().UseFunc(func(req *calque.Request, _ *calque.Response) error {
+			<-req.Context.Done()
+			remoteErr <- req.Context.Err()
+			return req.Context.Err()
+		}),
+	})
+
+	flow := calque.NewFlow().Use(CallWithConfig(ts.URL+"/flows/hang", &Config{Timeout: 100 * time.Millisecond}))
+	var out string
+	if err := flow.Run(context.Background(), "input", &out); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() error = %v, want deadline exceeded", err)
+	}
+
+	select {
+	case err := <-remoteErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("remote context error = %v, want canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("remote flow was not cancelled")
+	}
+}
+
+func TestCallHeaders(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, map[string]*calque.Flow{
+		"identity": calque.NewFlow().UseFunc(func(req *calque.Request, res *calque.Response) error {
+			ctx := req.Context
+			return calque.Write(res, strings.Join([]string{
+				calque.RequestID(ctx), calque.TraceID(ctx), calque.Tenant(ctx), calque.Locale(ctx),
+			}, "|"))
+		}),
+	})
+
+	ctx := calque.WithRequestID(context.Background(), "req-1")
+	ctx = calque.WithTraceID(ctx, "trace-1")
+	ctx = calque.WithLocale(ctx, "fr-FR")
+	flow := calque.NewFlow().Use(CallWithConfig(ts.URL+"/flows/identity", &Config{
+		Headers: map[string]string{HeaderTenant: "configured"},
+	}))
+
+	var out string
+	if err := flow.Run(ctx, "", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := "req-1|trace-1|configured|fr-FR"; out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+}
+
+func modeName(mode Mode) string {
+	return [...]string{"chunked", "sse", "json"}[mode]
+}
+
+// firstWriteSignal closes first on its first write
+type firstWriteSignal struct {
+	mu    sync.Mutex
+	buf   strings.Builder
+	first chan struct{}
+	once  sync.Once
+}
+
+func (w *firstWriteSignal) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.once.Do(func() { close(w.first) })
+	return w.buf.Write(p)
+}
+
+func (w *firstWriteSignal) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestCallEgress(t *testing.T) {
+	ts := newTestServer(t, map[string]*calque.Flow{"upper": upperFlow()})
+
+	// A flow served from this machine keeps data local
+	flow := calque.NewFlow(calque.WithDataPolicy(calque.LocalOnly)).Use(Call(ts.URL + "/flows/upper"))
+	if err := flow.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	flow = calque.NewFlow(calque.WithDataPolicy(calque.LocalOnly)).Use(Call("https://flows.example.com/flows/upper"))
+	if err := flow.Validate(); !errors.Is(err, calque.ErrDataPolicy) || !strings.Contains(err.Error(), "flows.example.com") {
+		t.Errorf("Validate() error = %v, want a violation for flows.example.com", err)
+	}
+}
+
+func TestCallCompression(t *testing.T) {
+	t.Parallel()
+
+	type seen struct{ request, response string }
+	var (
+		mu       sync.Mutex
+		recorded []seen
+	)
+	server := NewServer()
+	server.RegisterFlow("echo", calque.NewFlow().UseFunc(func(req *calque.Request, res *calque.Response) error {
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	}))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.ServeHTTP(w, r)
+		mu.Lock()
+		defer mu.Unlock()
+		recorded = append(recorded, seen{r.Header.Get("Content-Encoding"), w.Header().Get("Content-Encoding")})
+	}))
+	t.Cleanup(ts.Close)
+
+	large := strings.Repeat("retrieved context ", 200)
+	tests := []struct {
+		name        string
+		mode        Mode
+		compression ctrl.Compression
+		input       string
+		want        seen
+	}{
+		{name: "chunked zstd", mode: ModeChunked, compression: ctrl.Zstd, input: large, want: seen{"zstd", ""}},
+		{name: "sse gzip", mode: ModeSSE, compression: ctrl.Gzip, input: large, want: seen{"gzip", ""}},
+		{name: "json zstd", mode: ModeJSON, compression: ctrl.Zstd, input: large, want: seen{"zstd", "zstd"}},
+		{name: "json gzip", mode: ModeJSON, compression: ctrl.Gzip, input: large, want: seen{"gzip", "gzip"}},
+		{name: "below threshold", mode: ModeJSON, compression: ctrl.Zstd, input: "small", want: seen{"", ""}},
+		{name: "disabled", mode: ModeChunked, input: large, want: seen{"", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			recorded = nil
+			mu.Unlock()
+
+			flow := calque.NewFlow().Use(CallWithConfig(ts.URL+"/flows/echo", &Config{Mode: tt.mode, Compression: tt.compression}))
+			var out string
+			if err := flow.Run(context.Background(), tt.input, &out); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if out != tt.input {
+				t.Errorf("output has %d bytes, want %d", len(out), len(tt.input))
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(recorded) != 1 || recorded[0] != tt.want {
+				t.Errorf("encodings = %+v, want %+v", recorded, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Package httpflow invokes remote calque flows over plain HTTP.
+//
+// It is the HTTP counterpart of the grpc middleware for environments that
+// cannot run gRPC (serverless platforms, proxies that only speak HTTP/1.1,
+// browsers). A Server exposes registered flows at POST /flows/{name}; Call
+// forwards a flow's data to one of them and streams the result back.
+//
+// Three wire formats are supported, chosen by the request headers:
+//
+//   - Chunked (default): the request body is the flow input and the response
+//     body streams the flow output with chunked transfer encoding. Failures
+//     after output has started arrive in the Calque-Error trailer.
+//   - SSE (Accept: text/event-stream): output streams as "message" events
+//     holding JSON strings, followed by "completion" or "error", the same
+//     events convert.ToSSE produces.
+//   - JSON (Content-Type: application/json): the body is a FlowRequest and,
+//     unless SSE is requested, the response is a FlowResponse.
+//
+// Example:
+//
+//	// Service A
+//	server := httpflow.NewServer()
+//	server.RegisterFlow("summarize", summarizeFlow)
+//	http.ListenAndServe(":8080", server)
+//
+//	// Service B
+//	flow := calque.NewFlow().
+//		Use(httpflow.Call("http://service-a:8080/flows/summarize"))
+package httpflow
+
+import (
+	"context"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Headers mapped to and from the calque context, matching the grpc middleware's metadata keys
+const (
+	HeaderRequestID = "X-Request-Id"    // calque.RequestID
+	HeaderTraceID   = "X-Trace-Id"      // calque.TraceID
+	HeaderTenant    = "X-Tenant-Id"     // calque.Tenant
+	HeaderLocale    = "Accept-Language" // calque.Locale
+)
+
+// Trailers sent with chunked responses
+const (
+	TrailerError = "Calque-Error" // Error message when the flow failed mid-stream
+	TrailerUsage = "Calque-Usage" // Model usage of the run, encoded with calque.Usage.MarshalText
+)
+
+// SSE event names, shared with convert.ToSSE
+const (
+	eventMessage    = "message"
+	eventUsage      = "usage"
+	eventCompletion = "completion"
+	eventError      = "error"
+)
+
+const (
+	contentTypeJSON = "application/json"
+	contentTypeSSE  = "text/event-stream"
+	contentTypeText = "text/plain; charset=utf-8"
+)
+
+// FlowRequest is the JSON request body
+type FlowRequest struct {
+	Input    string            `json:"input"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// FlowResponse is the JSON response body, also used for error responses
+type FlowResponse struct {
+	Output   string            `json:"output,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"` // Request metadata plus calque.UsageMetadataKey
+	Error    string            `json:"error,omitempty"`
+}
+
+// recordRemoteUsage adds usage reported by the remote flow to this run's total
+func recordRemoteUsage(ctx context.Context, encoded string) {
+	if encoded == "" {
+		return
+	}
+	var usage calque.Usage
+	if err := usage.UnmarshalText([]byte(encoded)); err != nil {
+		calque.LogDebug(ctx, "ignoring malformed remote usage", "error", err)
+		return
+	}
+	calque.RecordUsage(ctx, usage)
+}
@@ -0,0 +1,371 @@
+package httpflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/convert"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+// statusClientClosedRequest reports a run abandoned by its caller (nginx convention)
+const statusClientClosedRequest = 499
+
+// Server hosts calque flows over HTTP.
+//
+// Server is an http.Handler serving POST /flows/{name}. Mount it under a
+// prefix with http.StripPrefix. Request bodies sent with a gzip or zstd
+// Content-Encoding are decompressed, and JSON responses of at least
+// ctrl.DefaultCompressMinBytes are compressed when the client accepts it.
+// Streamed responses are never compressed.
+type Server struct {
+	flows map[string]*calque.Flow
+	mu    sync.RWMutex
+	mux   *http.ServeMux
+}
+
+// NewServer creates an HTTP server for hosting flows.
+//
+// Example:
+//
+//	server := httpflow.NewServer()
+//	server.RegisterFlow("summarize", summarizeFlow)
+//	http.ListenAndServe(":8080", server)
+func NewServer() *Server {
+	s := &Server{flows: make(map[string]*calque.Flow)}
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("POST /flows/{name}", s.serveFlow)
+	return s
+}
+
+// RegisterFlow registers a flow with the server under a given name.
+func (s *Server) RegisterFlow(name string, flow *calque.Flow) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flows[name] = flow
+}
+
+// GetFlow retrieves a registered flow by name.
+func (s *Server) GetFlow(ctx context.Context, name string) (*calque.Flow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	flow, exists := s.flows[name]
+	if !exists {
+		return nil, calque.NewErr(ctx, fmt.Sprintf("flow %s not found", name))
+	}
+	return flow, nil
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// serveFlow runs one flow, picking the wire format from the request headers
+func (s *Server) serveFlow(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	flow, err := s.GetFlow(ctx, r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	body := io.Reader(r.Body)
+	if encoding := r.Header.Get("Content-Encoding"); encoding != "" {
+		decoded, err := ctrl.DecompressReader(ctx, r.Body, ctrl.Compression(encoding))
+		if err != nil {
+			writeError(w, http.StatusUnsupportedMediaType, err)
+			return
+		}
+		defer decoded.Close()
+		body = decoded
+	}
+
+	var input any = body
+	var metadata map[string]string
+	jsonRequest := hasMediaType(r.Header.Get("Content-Type"), contentTypeJSON)
+	if jsonRequest {
+		var req FlowRequest
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, calque.WrapErr(ctx, err, "invalid flow request"))
+			return
+		}
+		input, metadata = req.Input, req.Metadata
+	} else {
+		// Stream the request body into the flow while output streams back
+		_ = http.NewResponseController(w).EnableFullDuplex()
+	}
+
+	switch {
+	case accepts(r, contentTypeSSE):
+		serveSSE(ctx, w, flow, input)
+	case jsonRequest || accepts(r, contentTypeJSON):
+		serveJSON(ctx, w, flow, input, metadata, acceptedEncoding(r))
+	default:
+		serveChunked(ctx, w, flow, input)
+	}
+}
+
+// serveChunked streams raw output, reporting late failures and usage in trailers
+func serveChunked(ctx context.Context, w http.ResponseWriter, flow *calque.Flow, input any) {
+	w.Header().Set("Trailer", TrailerError+", "+TrailerUsage)
+	out := &flushWriter{w: w, rc: http.NewResponseController(w)}
+
+	usage, err := runFlow(ctx, flow, input, out)
+	if err != nil {
+		if !out.started {
+			writeError(w, statusFor(err), err)
+			return
+		}
+		w.Header().Set(TrailerError, strings.Join(strings.Fields(err.Error()), " "))
+		return
+	}
+	out.start()
+	if encoded := encodeUsage(usage); encoded != "" {
+		w.Header().Set(TrailerUsage, encoded)
+	}
+}
+
+// serveSSE streams output as "message" events
+func serveSSE(ctx context.Context, w http.ResponseWriter, flow *calque.Flow, input any) {
+	sse := convert.ToSSE(w)
+	out := &sseWriter{sse: sse}
+
+	usage, err := runFlow(ctx, flow, input, out)
+	if err == nil {
+		err = out.flush()
+	}
+	if err != nil {
+		_ = sse.WriteError(err)
+		return
+	}
+	if encoded := encodeUsage(usage); encoded != "" {
+		_ = sse.WriteEvent(eventUsage, json.RawMessage(encoded))
+	}
+	_ = sse.WriteEvent(eventCompletion, "")
+}
+
+// serveJSON buffers the output into a FlowResponse
+func serveJSON(ctx context.Context, w http.ResponseWriter, flow *calque.Flow, input any, metadata map[string]string, encoding ctrl.Compression) {
+	var output string
+	usage, err := runFlow(ctx, flow, input, &output)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+
+	resp := FlowResponse{Output: output, Metadata: metadata}
+	if encoded := encodeUsage(usage); encoded != "" {
+		resp.Metadata = make(map[string]string, len(metadata)+1)
+		maps.Copy(resp.Metadata, metadata)
+		resp.Metadata[calque.UsageMetadataKey] = encoded
+	}
+	writeCompressedJSON(ctx, w, resp, encoding)
+}
+
+// writeCompressedJSON writes a successful FlowResponse, compressing it with
+// encoding when it is large enough to benefit
+func writeCompressedJSON(ctx context.Context, w http.ResponseWriter, resp FlowResponse, encoding ctrl.Compression) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(resp); err != nil {
+		writeError(w, http.StatusInternalServerError, calque.WrapErr(ctx, err, "failed to encode flow response"))
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.Header().Add("Vary", "Accept-Encoding")
+	if encoding == "" || body.Len() < ctrl.DefaultCompressMinBytes {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(body.Bytes())
+		return
+	}
+
+	w.Header().Set("Content-Encoding", string(encoding))
+	w.WriteHeader(http.StatusOK)
+	cw, err := ctrl.CompressWriter(ctx, w, encoding)
+	if err != nil {
+		return
+	}
+	_, _ = cw.Write(body.Bytes())
+	_ = cw.Close()
+}
+
+// runFlow executes a flow, returning the model usage recorded during the run
+func runFlow(ctx context.Context, flow *calque.Flow, input, output any) (calque.Usage, error) {
+	bus := calque.NewMetadataBus(0)
+	defer bus.Close()
+	ctx = calque.WithMetadataBus(ctx, bus)
+
+	if err := flow.Run(ctx, input, output); err != nil {
+		return calque.Usage{}, err
+	}
+	return calque.UsageFrom(ctx), nil
+}
+
+// requestContext maps identity headers onto the calque context
+func requestContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if id := r.Header.Get(HeaderRequestID); id != "" {
+		ctx = calque.WithRequestID(ctx, id)
+	}
+	if id := r.Header.Get(HeaderTraceID); id != "" {
+		ctx = calque.WithTraceID(ctx, id)
+	}
+	if tenant := r.Header.Get(HeaderTenant); tenant != "" {
+		ctx = calque.WithTenant(ctx, tenant)
+	}
+	if locale := r.Header.Get(HeaderLocale); locale != "" {
+		ctx = calque.WithLocale(ctx, locale)
+	}
+	return ctx
+}
+
+// statusFor maps a flow error to an HTTP status
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return statusClientClosedRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, FlowResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, resp FlowResponse) {
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func encodeUsage(usage calque.Usage) string {
+	if usage.IsZero() {
+		return ""
+	}
+	encoded, err := usage.MarshalText()
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// accepts reports whether the request's Accept header lists mediaType
+func accepts(r *http.Request, mediaType string) bool {
+	for accept := range strings.SplitSeq(r.Header.Get("Accept"), ",") {
+		if hasMediaType(accept, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptedEncoding picks the compression for a response from the request's
+// Accept-Encoding header, preferring zstd. It returns "" when neither is accepted.
+func acceptedEncoding(r *http.Request) ctrl.Compression {
+	var accepted ctrl.Compression
+	for entry := range strings.SplitSeq(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(entry), ";")
+		if strings.ReplaceAll(strings.TrimSpace(params), " ", "") == "q=0" {
+			continue
+		}
+		switch ctrl.Compression(strings.ToLower(strings.TrimSpace(name))) {
+		case ctrl.Zstd:
+			return ctrl.Zstd
+		case ctrl.Gzip:
+			accepted = ctrl.Gzip
+		}
+	}
+	return accepted
+}
+
+func hasMediaType(header, mediaType string) bool {
+	parsed, _, err := mime.ParseMediaType(strings.TrimSpace(header))
+	return err == nil && parsed == mediaType
+}
+
+// flushWriter sends each write to the client immediately. The status line is
+// delayed until the first write, so a flow failing before any output still
+// gets a proper error status.
+type flushWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	started bool
+}
+
+func (f *flushWriter) start() {
+	if !f.started {
+		f.started = true
+		f.w.Header().Set("Content-Type", contentTypeText)
+		f.w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	f.start()
+	n, err := f.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	_ = f.rc.Flush()
+	return n, nil
+}
+
+// sseWriter sends writes as "message" events, holding back a UTF-8 character
+// split across writes so each event is valid JSON text
+type sseWriter struct {
+	sse     *convert.SSEConverter
+	pending []byte
+}
+
+func (s *sseWriter) Write(p []byte) (int, error) {
+	s.pending = append(s.pending, p...)
+	complete, rest := splitIncompleteRune(s.pending)
+	if len(complete) > 0 {
+		if err := s.sse.WriteEvent(eventMessage, string(complete)); err != nil {
+			return 0, err
+		}
+	}
+	s.pending = append(s.pending[:0], rest...)
+	return len(p), nil
+}
+
+// flush sends bytes still held back once the flow has finished
+func (s *sseWriter) flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	err := s.sse.WriteEvent(eventMessage, string(s.pending))
+	s.pending = nil
+	return err
+}
+
+// splitIncompleteRune separates a trailing partial UTF-8 character from b
+func splitIncompleteRune(b []byte) (complete, rest []byte) {
+	for i := len(b) - 1; i >= 0 && i > len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i], b[i:]
+			}
+			break
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,275 @@
+package httpflow
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	usage := testUsageText(t)
+	quotedUsage, _ := json.Marshal(usage)
+
+	server := NewServer()
+	server.RegisterFlow("upper", upperFlow())
+	server.RegisterFlow("fails", calque.NewFlow().UseFunc(func(req *calque.Request, _ *calque.Response) error {
+		return calque.NewErr(req.Context, "model unavailable")
+	}))
+	server.RegisterFlow("timeout", calque.NewFlow().UseFunc(func(req *calque.Request, _ *calque.Response) error {
+		return calque.WrapErr(req.Context, context.DeadlineExceeded, "model call")
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		accept      string
+		encoding    string
+		body        string
+		wantStatus  int
+		wantType    string
+		wantBody    string
+	}{
+		{
+			name:        "json",
+			path:        "/flows/upper",
+			contentType: "application/json",
+			body:        `{"input":"hi","metadata":{"service":"api"}}`,
+			wantStatus:  http.StatusOK,
+			wantType:    contentTypeJSON,
+			wantBody:    `{"output":"HI for ","metadata":{"calque.usage":` + string(quotedUsage) + `,"service":"api"}}`,
+		},
+		{
+			name:       "chunked",
+			path:       "/flows/upper",
+			body:       "hi",
+			wantStatus: http.StatusOK,
+			wantType:   contentTypeText,
+			wantBody:   "HI for ",
+		},
+		{
+			name:       "accept json with raw body",
+			path:       "/flows/upper",
+			accept:     "text/html, application/json;q=0.9",
+			body:       "hi",
+			wantStatus: http.StatusOK,
+			wantType:   contentTypeJSON,
+			wantBody:   `"output":"HI for "`,
+		},
+		{
+			name:       "sse",
+			path:       "/flows/upper",
+			accept:     "text/event-stream",
+			body:       "hi",
+			wantStatus: http.StatusOK,
+			wantType:   contentTypeSSE,
+			wantBody:   "event: message\ndata: \"HI for \"\n\nevent: usage\ndata: " + usage + "\n\nevent: completion\ndata: \"\"\n\n",
+		},
+		{
+			name:        "invalid json",
+			path:        "/flows/upper",
+			contentType: "application/json",
+			body:        `{"input":`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "invalid flow request",
+		},
+		{
+			name:       "unsupported content encoding",
+			path:       "/flows/upper",
+			encoding:   "br",
+			body:       "hi",
+			wantStatus: http.StatusUnsupportedMediaType,
+			wantBody:   `unsupported compression \"br\"`,
+		},
+		{
+			name:       "unknown flow",
+			path:       "/flows/missing",
+			wantStatus: http.StatusNotFound,
+			wantBody:   "flow missing not found",
+		},
+		{
+			name:       "flow error",
+			path:       "/flows/fails",
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"error":"model unavailable"}`,
+		},
+		{
+			name:       "deadline",
+			path:       "/flows/timeout",
+			wantStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name:       "wrong method",
+			method:     http.MethodGet,
+			path:       "/flows/upper",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			req := httptest.NewRequest(method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantType != "" && !strings.HasPrefix(rec.Header().Get("Content-Type"), tt.wantType) {
+				t.Errorf("Content-Type = %q, want %q", rec.Header().Get("Content-Type"), tt.wantType)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %q, want it to contain %q", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestAcceptedEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   ctrl.Compression
+	}{
+		{header: "", want: ""},
+		{header: "br", want: ""},
+		{header: "gzip, deflate", want: ctrl.Gzip},
+		{header: "gzip, zstd", want: ctrl.Zstd},
+		{header: "zstd;q=0, gzip", want: ctrl.Gzip},
+		{header: "ZSTD;q=0.5", want: ctrl.Zstd},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/flows/upper", nil)
+		req.Header.Set("Accept-Encoding", tt.header)
+		if got := acceptedEncoding(req); got != tt.want {
+			t.Errorf("acceptedEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestServerChunkedTrailers(t *testing.T) {
+	t.Parallel()
+
+	usage := testUsageText(t)
+
+	server := NewServer()
+	server.RegisterFlow("upper", upperFlow())
+	server.RegisterFlow("fails-late", calque.NewFlow().UseFunc(func(req *calque.Request, res *calque.Response) error {
+		if err := calque.Write(res, "partial"); err != nil {
+			return err
+		}
+		return calque.NewErr(req.Context, "stream\nbroke")
+	}))
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	tests := []struct {
+		flow      string
+		wantBody  string
+		wantError string
+		wantUsage string
+	}{
+		{flow: "upper", wantBody: "HI for ", wantUsage: usage},
+		{flow: "fails-late", wantBody: "partial", wantError: "stream broke"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flow, func(t *testing.T) {
+			resp, err := http.Post(ts.URL+"/flows/"+tt.flow, "text/plain", strings.NewReader("hi"))
+			if err != nil {
+				t.Fatalf("Post() error = %v", err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("reading body: %v", err)
+			}
+			if resp.StatusCode != http.StatusOK || string(body) != tt.wantBody {
+				t.Errorf("response = %d %q, want 200 %q", resp.StatusCode, body, tt.wantBody)
+			}
+			if got := resp.Trailer.Get(TrailerError); got != tt.wantError {
+				t.Errorf("%s trailer = %q, want %q", TrailerError, got, tt.wantError)
+			}
+			if got := resp.Trailer.Get(TrailerUsage); got != tt.wantUsage {
+				t.Errorf("%s trailer = %q, want %q", TrailerUsage, got, tt.wantUsage)
+			}
+		})
+	}
+}
+
+func TestServerJSONErrorBody(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodPost, "/flows/missing", nil)
+	rec := httptest.NewRecorder()
+	NewServer().ServeHTTP(rec, req)
+
+	var resp FlowResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Error != "flow missing not found" {
+		t.Errorf("error body = %q (%v)", rec.Body.String(), err)
+	}
+}
+
+func TestSplitIncompleteRune(t *testing.T) {
+	t.Parallel()
+
+	euro := []byte("€") // 3 bytes
+	tests := []struct {
+		name         string
+		input        []byte
+		wantComplete string
+		wantRest     []byte
+	}{
+		{name: "ascii", input: []byte("abc"), wantComplete: "abc"},
+		{name: "complete rune", input: append([]byte("a"), euro...), wantComplete: "a€"},
+		{name: "one byte of rune", input: append([]byte("a"), euro[0]), wantComplete: "a", wantRest: euro[:1]},
+		{name: "two bytes of rune", input: append([]byte("a"), euro[:2]...), wantComplete: "a", wantRest: euro[:2]},
+		{name: "empty", input: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			complete, rest := splitIncompleteRune(tt.input)
+			if string(complete) != tt.wantComplete || string(rest) != string(tt.wantRest) {
+				t.Errorf("splitIncompleteRune() = %q, %q, want %q, %q", complete, rest, tt.wantComplete, tt.wantRest)
+			}
+		})
+	}
+}
+
+// testUsageText is the encoded usage upperFlow records
+func testUsageText(t *testing.T) string {
+	t.Helper()
+	encoded, err := calque.Usage{TotalTokens: 12, Calls: 1}.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText() error = %v", err)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,395 @@
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Batch embedder defaults
+const (
+	DefaultEmbedBatchSize      = 96                     // Fits common provider limits (Cohere 96, OpenAI 2048)
+	DefaultEmbedBatchWait      = 10 * time.Millisecond  // Coalescing window for concurrent requests
+	DefaultEmbedConcurrency    = 4                      // Maximum batches in flight
+	DefaultEmbedMaxRetries     = 5                      // Retries per batch after rate limiting
+	DefaultEmbedInitialBackoff = 500 * time.Millisecond // Backoff when no retry hint is provided
+)
+
+// BatchEmbeddingProvider generates embeddings for many texts in one request.
+//
+// Implement this alongside EmbeddingProvider when the underlying API accepts
+// batched input. Results must be returned in the same order as texts.
+type BatchEmbeddingProvider interface {
+	EmbedBatch(ctx context.Context, texts []string) ([]EmbeddingVector, error)
+}
+
+// RateLimitError reports that an embedding provider rejected a request due to rate limiting.
+//
+// BatchEmbedder only backs off on this error, so an EmbedBatch implementation
+// must convert the provider's rate-limit response (typically HTTP 429) into a
+// RateLimitError itself, or wrap one. RetryAfter is the provider's hint, taken
+// from its response headers; zero means no hint was given and the default
+// backoff is used.
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	msg := "rate limited"
+	if e.RetryAfter > 0 {
+		msg = fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *RateLimitError) Unwrap() error { return e.Err }
+
+// EmbedAll embeds texts, batching when the provider supports it.
+//
+// Falls back to one Embed call per text for providers without EmbedBatch.
+func EmbedAll(ctx context.Context, provider EmbeddingProvider, texts []string) ([]EmbeddingVector, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if batcher, ok := provider.(BatchEmbeddingProvider); ok {
+		vectors, err := batcher.EmbedBatch(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		if len(vectors) != len(texts) {
+			return nil, calque.NewErr(ctx, fmt.Sprintf("embedding provider returned %d vectors for %d texts", len(vectors), len(texts)))
+		}
+		return vectors, nil
+	}
+
+	vectors := make([]EmbeddingVector, len(texts))
+	for i, text := range texts {
+		vector, err := provider.Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// BatchEmbedderConfig configures a BatchEmbedder.
+type BatchEmbedderConfig struct {
+	// Maximum texts per provider request (default: 96)
+	MaxBatchSize int
+
+	// How long to wait for more requests before sending a partial batch (default: 10ms)
+	MaxWait time.Duration
+
+	// Maximum batches in flight; the scheduler adapts between 1 and this value (default: 4)
+	MaxConcurrency int
+
+	// Retries per batch after rate limiting (default: 5, negative disables retries)
+	MaxRetries int
+
+	// Backoff used when a rate limit carries no retry hint, doubled per retry (default: 500ms)
+	InitialBackoff time.Duration
+}
+
+// BatchEmbedderStats is a snapshot of scheduler throughput.
+type BatchEmbedderStats struct {
+	Texts        int64         `json:"texts"`          // Texts embedded successfully
+	Batches      int64         `json:"batches"`        // Provider requests that succeeded
+	Failed       int64         `json:"failed"`         // Texts whose batch failed
+	RateLimited  int64         `json:"rate_limited"`   // Rate-limit responses received
+	Retries      int64         `json:"retries"`        // Batch retries performed
+	InFlight     int           `json:"in_flight"`      // Batches currently being embedded
+	Concurrency  int           `json:"concurrency"`    // Current adaptive concurrency limit
+	AvgBatchSize float64       `json:"avg_batch_size"` // Mean texts per successful batch
+	Throughput   float64       `json:"throughput"`     // Texts per second since the embedder was created
+	Uptime       time.Duration `json:"uptime"`
+}
+
+// BatchEmbedder coalesces embedding requests into provider-sized batches.
+//
+// Concurrent Embed calls arriving within MaxWait are merged into one request.
+// Concurrency adapts additively on success and halves on rate limiting, and all
+// batches pause for the provider's retry hint before retrying. BatchEmbedder
+// implements EmbeddingProvider and BatchEmbeddingProvider, so it can wrap any
+// provider used by the vector stores.
+type BatchEmbedder struct {
+	provider EmbeddingProvider
+	config   BatchEmbedderConfig
+
+	mu          sync.Mutex
+	cond        *sync.Cond
+	pending     []*embedRequest
+	timer       *time.Timer
+	limit       float64 // adaptive concurrency limit
+	inFlight    int
+	pausedUntil time.Time
+
+	started time.Time
+	stats   BatchEmbedderStats
+}
+
+type embedRequest struct {
+	ctx    context.Context
+	text   string
+	vector EmbeddingVector
+	err    error
+	done   chan struct{}
+}
+
+// NewBatchEmbedder wraps a provider with batching and rate-limit aware scheduling.
+//
+// Providers implementing BatchEmbeddingProvider receive whole batches; others are
+// called once per text, still under the adaptive concurrency limit.
+//
+// Example:
+//
+//	embedder := retrieval.NewBatchEmbedder(openaiEmbedder, &retrieval.BatchEmbedderConfig{
+//		MaxBatchSize:   256,
+//		MaxConcurrency: 8,
+//	})
+//	store, _ := pgvector.New(&pgvector.Config{..., EmbeddingProvider: embedder})
+//
+//	stats := embedder.Stats()
+//	log.Printf("%.0f texts/s, concurrency %d", stats.Throughput, stats.Concurrency)
+func NewBatchEmbedder(provider EmbeddingProvider, config *BatchEmbedderConfig) *BatchEmbedder {
+	cfg := BatchEmbedderConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = DefaultEmbedBatchSize
+	}
+	if cfg.MaxWait <= 0 {
+		cfg.MaxWait = DefaultEmbedBatchWait
+	}
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = DefaultEmbedConcurrency
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultEmbedMaxRetries
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = DefaultEmbedInitialBackoff
+	}
+
+	b := &BatchEmbedder{
+		provider: provider,
+		config:   cfg,
+		limit:    float64(cfg.MaxConcurrency),
+		started:  time.Now(),
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// Embed queues a single text and waits for its batch to complete
+func (b *BatchEmbedder) Embed(ctx context.Context, text string) (EmbeddingVector, error) {
+	vectors, err := b.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// EmbedBatch queues texts, which may be split or merged with other callers' texts
+func (b *BatchEmbedder) EmbedBatch(ctx context.Context, texts []string) ([]EmbeddingVector, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	requests := make([]*embedRequest, len(texts))
+	for i, text := range texts {
+		requests[i] = &embedRequest{ctx: ctx, text: text, done: make(chan struct{})}
+	}
+	b.enqueue(requests)
+
+	vectors := make([]EmbeddingVector, len(texts))
+	for i, req := range requests {
+		select {
+		case <-req.done:
+			if req.err != nil {
+				return nil, req.err
+			}
+			vectors[i] = req.vector
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return vectors, nil
+}
+
+// Stats returns a snapshot of scheduler throughput and state
+func (b *BatchEmbedder) Stats() BatchEmbedderStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := b.stats
+	stats.InFlight = b.inFlight
+	stats.Concurrency = int(b.limit)
+	stats.Uptime = time.Since(b.started)
+	if stats.Batches > 0 {
+		stats.AvgBatchSize = float64(stats.Texts) / float64(stats.Batches)
+	}
+	if secs := stats.Uptime.Seconds(); secs > 0 {
+		stats.Throughput = float64(stats.Texts) / secs
+	}
+	return stats
+}
+
+// enqueue adds requests and dispatches every full batch, arming the timer for the remainder
+func (b *BatchEmbedder) enqueue(requests []*embedRequest) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending = append(b.pending, requests...)
+	for len(b.pending) >= b.config.MaxBatchSize {
+		b.dispatchLocked(b.config.MaxBatchSize)
+	}
+
+	if len(b.pending) > 0 && b.timer == nil {
+		b.timer = time.AfterFunc(b.config.MaxWait, b.flush)
+	}
+}
+
+// flush dispatches whatever is pending when the coalescing window ends
+func (b *BatchEmbedder) flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.timer = nil
+	if len(b.pending) > 0 {
+		b.dispatchLocked(len(b.pending))
+	}
+}
+
+func (b *BatchEmbedder) dispatchLocked(n int) {
+	batch := make([]*embedRequest, n)
+	copy(batch, b.pending[:n])
+	b.pending = b.pending[n:]
+
+	if len(b.pending) == 0 && b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	go b.run(batch)
+}
+
+// run embeds one batch, retrying with backoff while the provider is rate limiting
+func (b *BatchEmbedder) run(batch []*embedRequest) {
+	// Drop requests whose callers have already given up
+	live := batch[:0]
+	for _, req := range batch {
+		if req.ctx.Err() == nil {
+			live = append(live, req)
+		}
+	}
+	if len(live) == 0 {
+		return
+	}
+	// The batch outlives any single caller, so keep values but not cancellation
+	ctx := context.WithoutCancel(live[0].ctx)
+
+	texts := make([]string, len(live))
+	for i, req := range live {
+		texts[i] = req.text
+	}
+
+	backoff := b.config.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		b.acquire()
+		vectors, err := EmbedAll(ctx, b.provider, texts)
+		b.release(err)
+
+		var rateLimit *RateLimitError
+		if errors.As(err, &rateLimit) && attempt < b.config.MaxRetries {
+			wait := rateLimit.RetryAfter
+			if wait <= 0 {
+				wait = backoff
+				backoff *= 2
+			}
+			b.pause(wait)
+			calque.LogDebug(ctx, "embedding batch rate limited", "texts", len(texts), "retry_after", wait, "attempt", attempt+1)
+			continue
+		}
+
+		b.finish(live, vectors, err)
+		return
+	}
+}
+
+// acquire blocks until a concurrency slot is free and no rate-limit pause is active
+func (b *BatchEmbedder) acquire() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for {
+		if wait := time.Until(b.pausedUntil); wait > 0 {
+			b.mu.Unlock()
+			time.Sleep(wait)
+			b.mu.Lock()
+			continue
+		}
+		if b.inFlight < max(int(b.limit), 1) {
+			b.inFlight++
+			return
+		}
+		b.cond.Wait()
+	}
+}
+
+// release frees a slot and adapts concurrency: additive increase, multiplicative decrease
+func (b *BatchEmbedder) release(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inFlight--
+	var rateLimit *RateLimitError
+	switch {
+	case errors.As(err, &rateLimit):
+		b.stats.RateLimited++
+		b.limit = max(b.limit/2, 1)
+	case err == nil:
+		b.limit = min(b.limit+1/b.limit, float64(b.config.MaxConcurrency))
+	}
+	b.cond.Broadcast()
+}
+
+func (b *BatchEmbedder) pause(wait time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.stats.Retries++
+	if until := time.Now().Add(wait); until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
+
+func (b *BatchEmbedder) finish(batch []*embedRequest, vectors []EmbeddingVector, err error) {
+	b.mu.Lock()
+	if err != nil {
+		b.stats.Failed += int64(len(batch))
+	} else {
+		b.stats.Batches++
+		b.stats.Texts += int64(len(batch))
+	}
+	b.mu.Unlock()
+
+	for i, req := range batch {
+		if err != nil {
+			req.err = err
+		} else {
+			req.vector = vectors[i]
+		}
+		close(req.done)
+	}
+}
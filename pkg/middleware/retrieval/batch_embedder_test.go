@@ -0,0 +1,214 @@
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingEmbedder embeds text as a one-element vector of its length and records batch sizes
+type countingEmbedder struct {
+	mu          sync.Mutex
+	batches     []int
+	rateLimits  int // number of calls to reject with RateLimitError
+	retryAfter  time.Duration
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+	delay       time.Duration
+	err         error
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, text string) (EmbeddingVector, error) {
+	vectors, err := e.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (e *countingEmbedder) EmbedBatch(_ context.Context, texts []string) ([]EmbeddingVector, error) {
+	n := e.inFlight.Add(1)
+	defer e.inFlight.Add(-1)
+	for {
+		m := e.maxInFlight.Load()
+		if n <= m || e.maxInFlight.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(e.delay)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.err != nil {
+		return nil, e.err
+	}
+	if e.rateLimits > 0 {
+		e.rateLimits--
+		return nil, &RateLimitError{RetryAfter: e.retryAfter}
+	}
+	e.batches = append(e.batches, len(texts))
+
+	vectors := make([]EmbeddingVector, len(texts))
+	for i, text := range texts {
+		vectors[i] = EmbeddingVector{float32(len(text))}
+	}
+	return vectors, nil
+}
+
+// singleEmbedder only implements EmbeddingProvider
+type singleEmbedder struct{ calls atomic.Int32 }
+
+func (e *singleEmbedder) Embed(_ context.Context, text string) (EmbeddingVector, error) {
+	e.calls.Add(1)
+	return EmbeddingVector{float32(len(text))}, nil
+}
+
+func TestBatchEmbedder_CoalescesConcurrentRequests(t *testing.T) {
+	provider := &countingEmbedder{}
+	embedder := NewBatchEmbedder(provider, &BatchEmbedderConfig{MaxBatchSize: 10, MaxWait: 50 * time.Millisecond})
+
+	var wg sync.WaitGroup
+	for i := range 25 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			text := string(make([]byte, i+1))
+			vector, err := embedder.Embed(context.Background(), text)
+			if err != nil {
+				t.Errorf("Embed() error = %v", err)
+				return
+			}
+			if vector[0] != float32(i+1) {
+				t.Errorf("Embed() vector = %v, want [%d]", vector, i+1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	provider.mu.Lock()
+	batches := provider.batches
+	provider.mu.Unlock()
+
+	total := 0
+	for _, size := range batches {
+		if size > 10 {
+			t.Errorf("batch size %d exceeds limit", size)
+		}
+		total += size
+	}
+	if total != 25 || len(batches) != 3 {
+		t.Errorf("batches = %v, want 25 texts in 3 batches", batches)
+	}
+
+	stats := embedder.Stats()
+	if stats.Texts != 25 || stats.Batches != 3 || stats.AvgBatchSize <= 8 || stats.Throughput <= 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestBatchEmbedder_EmbedBatchPreservesOrder(t *testing.T) {
+	embedder := NewBatchEmbedder(&countingEmbedder{}, &BatchEmbedderConfig{MaxBatchSize: 2})
+
+	vectors, err := embedder.EmbedBatch(context.Background(), []string{"a", "bbb", "cc", "dddd", "e"})
+	if err != nil {
+		t.Fatalf("EmbedBatch() error = %v", err)
+	}
+	want := []float32{1, 3, 2, 4, 1}
+	for i, v := range vectors {
+		if v[0] != want[i] {
+			t.Errorf("vectors[%d] = %v, want %v", i, v, want[i])
+		}
+	}
+}
+
+func TestBatchEmbedder_RateLimitBackoff(t *testing.T) {
+	provider := &countingEmbedder{rateLimits: 2, retryAfter: 20 * time.Millisecond}
+	embedder := NewBatchEmbedder(provider, &BatchEmbedderConfig{MaxConcurrency: 8})
+
+	start := time.Now()
+	if _, err := embedder.Embed(context.Background(), "text"); err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("expected to wait for retry hints, took %v", elapsed)
+	}
+
+	stats := embedder.Stats()
+	if stats.RateLimited != 2 || stats.Retries != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	// 8 -> 4 -> 2, then additive increase after one success
+	if stats.Concurrency != 2 {
+		t.Errorf("concurrency = %d, want 2", stats.Concurrency)
+	}
+}
+
+func TestBatchEmbedder_RetriesExhausted(t *testing.T) {
+	provider := &countingEmbedder{rateLimits: 10, retryAfter: time.Millisecond}
+	embedder := NewBatchEmbedder(provider, &BatchEmbedderConfig{MaxRetries: 2})
+
+	_, err := embedder.Embed(context.Background(), "text")
+	var rateLimit *RateLimitError
+	if !errors.As(err, &rateLimit) {
+		t.Fatalf("expected RateLimitError, got %v", err)
+	}
+	if stats := embedder.Stats(); stats.Failed != 1 || stats.Retries != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestBatchEmbedder_ConcurrencyLimit(t *testing.T) {
+	provider := &countingEmbedder{delay: 20 * time.Millisecond}
+	embedder := NewBatchEmbedder(provider, &BatchEmbedderConfig{MaxBatchSize: 1, MaxConcurrency: 2})
+
+	texts := make([]string, 8)
+	for i := range texts {
+		texts[i] = "t"
+	}
+	if _, err := embedder.EmbedBatch(context.Background(), texts); err != nil {
+		t.Fatalf("EmbedBatch() error = %v", err)
+	}
+	if got := provider.maxInFlight.Load(); got > 2 {
+		t.Errorf("max in-flight batches = %d, want <= 2", got)
+	}
+}
+
+func TestBatchEmbedder_ProviderError(t *testing.T) {
+	embedder := NewBatchEmbedder(&countingEmbedder{err: errors.New("boom")}, nil)
+	if _, err := embedder.Embed(context.Background(), "text"); err == nil || err.Error() != "boom" {
+		t.Errorf("Embed() error = %v, want boom", err)
+	}
+}
+
+func TestBatchEmbedder_ContextCancelled(t *testing.T) {
+	provider := &countingEmbedder{delay: 100 * time.Millisecond}
+	embedder := NewBatchEmbedder(provider, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := embedder.Embed(ctx, "text"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Embed() error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestEmbedAll(t *testing.T) {
+	single := &singleEmbedder{}
+	vectors, err := EmbedAll(context.Background(), single, []string{"a", "bb"})
+	if err != nil || len(vectors) != 2 || vectors[1][0] != 2 {
+		t.Errorf("EmbedAll() = %v, %v", vectors, err)
+	}
+	if single.calls.Load() != 2 {
+		t.Errorf("expected one Embed call per text, got %d", single.calls.Load())
+	}
+
+	batch := &countingEmbedder{}
+	if _, err := EmbedAll(context.Background(), batch, []string{"a", "bb", "ccc"}); err != nil {
+		t.Fatalf("EmbedAll() error = %v", err)
+	}
+	if len(batch.batches) != 1 || batch.batches[0] != 3 {
+		t.Errorf("expected a single batch of 3, got %v", batch.batches)
+	}
+}
@@ -0,0 +1,199 @@
+package retrieval
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/middleware/cache"
+)
+
+// DefaultEmbeddingCacheTTL is how long cached vectors live unless configured
+const DefaultEmbeddingCacheTTL = 30 * 24 * time.Hour
+
+// EmbeddingCacheConfig configures an EmbeddingCache.
+type EmbeddingCacheConfig struct {
+	// Namespace separating vectors from different models, e.g. "text-embedding-3-small".
+	// Change it when switching models so old vectors are not served.
+	Namespace string
+
+	// How long cached vectors live (default: 30 days)
+	TTL time.Duration
+}
+
+// EmbeddingCacheStats reports cache effectiveness.
+type EmbeddingCacheStats struct {
+	Hits    int64   `json:"hits"`     // Texts served from the cache
+	Misses  int64   `json:"misses"`   // Texts sent to the provider
+	Errors  int64   `json:"errors"`   // Cache reads or writes that failed
+	HitRate float64 `json:"hit_rate"` // Hits / (Hits + Misses)
+}
+
+// EmbeddingCache serves embeddings for previously seen content from a cache store.
+//
+// Vectors are keyed by the SHA-256 of the namespace and text, so identical
+// chunks are embedded once across ingestion runs and processes sharing the
+// store. Cache failures are counted and fall through to the provider rather
+// than failing the request. EmbeddingCache implements EmbeddingProvider and
+// BatchEmbeddingProvider.
+type EmbeddingCache struct {
+	provider  EmbeddingProvider
+	store     cache.Store
+	namespace string
+	ttl       time.Duration
+
+	hits   atomic.Int64
+	misses atomic.Int64
+	errors atomic.Int64
+}
+
+// CachedEmbedder wraps a provider with a content-hash embedding cache.
+//
+// Input: embedding provider, cache store (nil uses an in-memory store)
+// Output: *EmbeddingCache usable wherever an EmbeddingProvider is accepted
+// Behavior: Cache hits skip the provider; batches only embed the misses
+//
+// Any cache.Store works as the backend: cache.NewInMemoryStore for a single
+// process, sqlitestore.NewCache for embedded deployments, or redisstore.New
+// to share vectors between workers.
+//
+// Example:
+//
+//	db, _ := sqlitestore.Open("calque.db")
+//	vectors, _ := sqlitestore.NewCache(&sqlitestore.Config{DB: db})
+//	embedder := retrieval.CachedEmbedder(openaiEmbedder, vectors)
+//	store, _ := sqlitevec.New(&sqlitevec.Config{DB: db, EmbeddingProvider: embedder})
+//
+//	log.Printf("embedding cache hit rate %.0f%%", embedder.Stats().HitRate*100)
+func CachedEmbedder(provider EmbeddingProvider, store cache.Store) *EmbeddingCache {
+	return CachedEmbedderWithConfig(provider, store, nil)
+}
+
+// CachedEmbedderWithConfig wraps a provider with a namespaced embedding cache.
+//
+// Example:
+//
+//	embedder := retrieval.CachedEmbedderWithConfig(openaiEmbedder, redisStore, &retrieval.EmbeddingCacheConfig{
+//		Namespace: "text-embedding-3-small",
+//		TTL:       7 * 24 * time.Hour,
+//	})
+func CachedEmbedderWithConfig(provider EmbeddingProvider, store cache.Store, config *EmbeddingCacheConfig) *EmbeddingCache {
+	if store == nil {
+		store = cache.NewInMemoryStore()
+	}
+	c := &EmbeddingCache{provider: provider, store: store, ttl: DefaultEmbeddingCacheTTL}
+	if config != nil {
+		c.namespace = config.Namespace
+		if config.TTL > 0 {
+			c.ttl = config.TTL
+		}
+	}
+	return c
+}
+
+// Embed returns the cached vector for text, embedding and caching it on a miss
+func (c *EmbeddingCache) Embed(ctx context.Context, text string) (EmbeddingVector, error) {
+	vectors, err := c.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// EmbedBatch serves cached vectors and embeds the remaining distinct texts in one call
+func (c *EmbeddingCache) EmbedBatch(ctx context.Context, texts []string) ([]EmbeddingVector, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	vectors := make([]EmbeddingVector, len(texts))
+	missing := map[string][]int{} // text -> positions awaiting its vector
+	var misses []string
+	for i, text := range texts {
+		if positions, ok := missing[text]; ok {
+			missing[text] = append(positions, i)
+			c.hits.Add(1) // Repeated within the batch: embedded once
+			continue
+		}
+		if vector := c.lookup(text); vector != nil {
+			vectors[i] = vector
+			c.hits.Add(1)
+			continue
+		}
+		missing[text] = []int{i}
+		misses = append(misses, text)
+	}
+	if len(misses) == 0 {
+		return vectors, nil
+	}
+
+	c.misses.Add(int64(len(misses)))
+	embedded, err := EmbedAll(ctx, c.provider, misses)
+	if err != nil {
+		return nil, err
+	}
+	for i, text := range misses {
+		for _, pos := range missing[text] {
+			vectors[pos] = embedded[i]
+		}
+		if err := c.store.Set(c.key(text), encodeEmbedding(embedded[i]), c.ttl); err != nil {
+			c.errors.Add(1)
+		}
+	}
+	return vectors, nil
+}
+
+// Stats returns a snapshot of hit and miss counts
+func (c *EmbeddingCache) Stats() EmbeddingCacheStats {
+	stats := EmbeddingCacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Errors: c.errors.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// lookup returns the cached vector for text, or nil on a miss or cache failure
+func (c *EmbeddingCache) lookup(text string) EmbeddingVector {
+	data, err := c.store.Get(c.key(text))
+	if err != nil {
+		c.errors.Add(1)
+		return nil
+	}
+	if len(data) == 0 || len(data)%4 != 0 {
+		return nil
+	}
+	return decodeEmbedding(data)
+}
+
+func (c *EmbeddingCache) key(text string) string {
+	sum := sha256.Sum256([]byte(c.namespace + "\x00" + text))
+	if c.namespace == "" {
+		return "embedding:" + hex.EncodeToString(sum[:])
+	}
+	return "embedding:" + c.namespace + ":" + hex.EncodeToString(sum[:])
+}
+
+// encodeEmbedding serialises a vector as little-endian float32s
+func encodeEmbedding(vector EmbeddingVector) []byte {
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return buf
+}
+
+func decodeEmbedding(data []byte) EmbeddingVector {
+	vector := make(EmbeddingVector, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return vector
+}
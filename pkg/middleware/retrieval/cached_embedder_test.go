@@ -0,0 +1,93 @@
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/middleware/cache"
+)
+
+// brokenStore fails every cache operation
+type brokenStore struct{ *cache.InMemoryStore }
+
+func (brokenStore) Get(string) ([]byte, error)              { return nil, errors.New("cache down") }
+func (brokenStore) Set(string, []byte, time.Duration) error { return errors.New("cache down") }
+
+func TestCachedEmbedder(t *testing.T) {
+	ctx := context.Background()
+	provider := &countingEmbedder{}
+	store := cache.NewInMemoryStore()
+	embedder := CachedEmbedder(provider, store)
+
+	vectors, err := embedder.EmbedBatch(ctx, []string{"a", "bb", "a", "ccc"})
+	if err != nil {
+		t.Fatalf("EmbedBatch() error = %v", err)
+	}
+	want := []EmbeddingVector{{1}, {2}, {1}, {3}}
+	for i := range want {
+		if !slices.Equal(vectors[i], want[i]) {
+			t.Errorf("vectors[%d] = %v, want %v", i, vectors[i], want[i])
+		}
+	}
+
+	// A second ingestion run only embeds the new chunk
+	vectors, err = embedder.EmbedBatch(ctx, []string{"bb", "dddd"})
+	if err != nil || !slices.Equal(vectors[0], EmbeddingVector{2}) || !slices.Equal(vectors[1], EmbeddingVector{4}) {
+		t.Fatalf("EmbedBatch() = %v, %v", vectors, err)
+	}
+	if vector, err := embedder.Embed(ctx, "ccc"); err != nil || !slices.Equal(vector, EmbeddingVector{3}) {
+		t.Errorf("Embed(ccc) = %v, %v", vector, err)
+	}
+
+	if !slices.Equal(provider.batches, []int{3, 1}) {
+		t.Errorf("provider batches = %v, want [3 1]", provider.batches)
+	}
+	stats := embedder.Stats()
+	if stats.Hits != 3 || stats.Misses != 4 || stats.Errors != 0 || stats.HitRate != 3.0/7 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestCachedEmbedderNamespaces(t *testing.T) {
+	ctx := context.Background()
+	store := cache.NewInMemoryStore()
+	small := &singleEmbedder{}
+	large := &singleEmbedder{}
+
+	_, _ = CachedEmbedderWithConfig(small, store, &EmbeddingCacheConfig{Namespace: "small"}).Embed(ctx, "text")
+	_, _ = CachedEmbedderWithConfig(large, store, &EmbeddingCacheConfig{Namespace: "large"}).Embed(ctx, "text")
+	_, _ = CachedEmbedderWithConfig(large, store, &EmbeddingCacheConfig{Namespace: "large"}).Embed(ctx, "text")
+
+	if small.calls.Load() != 1 || large.calls.Load() != 1 {
+		t.Errorf("provider calls = %d small, %d large; want one each", small.calls.Load(), large.calls.Load())
+	}
+	if keys := store.List(); len(keys) != 2 {
+		t.Errorf("cached keys = %v, want one per namespace", keys)
+	}
+}
+
+func TestCachedEmbedderFailures(t *testing.T) {
+	ctx := context.Background()
+
+	// Cache failures fall through to the provider
+	embedder := CachedEmbedder(&singleEmbedder{}, brokenStore{cache.NewInMemoryStore()})
+	if vector, err := embedder.Embed(ctx, "abc"); err != nil || !slices.Equal(vector, EmbeddingVector{3}) {
+		t.Errorf("Embed() with broken cache = %v, %v", vector, err)
+	}
+	if stats := embedder.Stats(); stats.Errors != 2 || stats.Misses != 1 {
+		t.Errorf("Stats() = %+v, want a read and a write error", stats)
+	}
+
+	// Provider failures are returned and nothing is cached
+	store := cache.NewInMemoryStore()
+	embedder = CachedEmbedder(&countingEmbedder{err: errors.New("provider down")}, store)
+	if _, err := embedder.Embed(ctx, "abc"); err == nil {
+		t.Error("Embed() should return provider errors")
+	}
+	if keys := store.List(); len(keys) != 0 {
+		t.Errorf("cached keys after failure = %v", keys)
+	}
+}
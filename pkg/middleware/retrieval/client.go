@@ -131,6 +131,47 @@ type TokenEstimator interface {
 	EstimateTokensBatch(texts []string) []int
 }
 
+// DocumentLister indicates that a vector store can enumerate its documents.
+//
+// Implement this interface to let Reindex copy documents out of the store.
+// Pages are ordered by a stable key so a cursor stays valid while other
+// pages are being written.
+//
+// Example:
+//
+//	if lister, ok := store.(retrieval.DocumentLister); ok {
+//	    docs, next, err := lister.ListDocuments(ctx, "", 100)
+//	}
+type DocumentLister interface {
+	// ListDocuments returns up to limit documents after cursor ("" starts at the
+	// beginning) and the cursor of the next page, which is "" after the last page
+	ListDocuments(ctx context.Context, cursor string, limit int) ([]Document, string, error)
+}
+
+// DocumentGetter indicates that a vector store can fetch documents by ID.
+//
+// Example:
+//
+//	if getter, ok := store.(retrieval.DocumentGetter); ok {
+//	    docs, err := getter.GetByID(ctx, "doc-1", "doc-2")
+//	}
+type DocumentGetter interface {
+	// GetByID returns the documents found among ids; missing IDs are skipped
+	GetByID(ctx context.Context, ids ...string) ([]Document, error)
+}
+
+// MetadataUpdater indicates that a vector store can change metadata without re-embedding.
+type MetadataUpdater interface {
+	// UpdateMetadata merges patch into a document's metadata; nil values remove keys
+	UpdateMetadata(ctx context.Context, id string, patch map[string]any) error
+}
+
+// DocumentCounter indicates that a vector store can report how many documents it holds.
+type DocumentCounter interface {
+	// CountDocuments returns the number of stored documents
+	CountDocuments(ctx context.Context) (int, error)
+}
+
 // DiversificationOptions configures native diversification (e.g., MMR in Qdrant)
 type DiversificationOptions struct {
 	// Diversity controls the relevance vs diversity tradeoff
@@ -0,0 +1,144 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+)
+
+// Extractor finds the entities and relations stated in a text
+type Extractor interface {
+	Extract(ctx context.Context, text string) (*Subgraph, error)
+}
+
+// ExtractorFunc adapts a function to Extractor
+type ExtractorFunc func(ctx context.Context, text string) (*Subgraph, error)
+
+// Extract calls f
+func (f ExtractorFunc) Extract(ctx context.Context, text string) (*Subgraph, error) {
+	return f(ctx, text)
+}
+
+// ExtractorConfig configures LLMExtractorWithConfig
+type ExtractorConfig struct {
+	// EntityTypes restricts extraction to these entity types (default: any)
+	EntityTypes []string
+	// RelationTypes restricts extraction to these relation types (default: any)
+	RelationTypes []string
+	// MaxInputBytes is how much of each text is sent to the model (default: 16384)
+	MaxInputBytes int
+}
+
+// extraction is the structured output LLMExtractor requests
+type extraction struct {
+	Entities []struct {
+		Name        string `json:"name" jsonschema:"required,description=Canonical name of the entity"`
+		Type        string `json:"type" jsonschema:"required,description=Kind of entity, e.g. person or organization"`
+		Description string `json:"description" jsonschema:"description=One sentence describing the entity"`
+	} `json:"entities" jsonschema:"required"`
+	Relations []struct {
+		Source string `json:"source" jsonschema:"required,description=Name of the source entity"`
+		Type   string `json:"type" jsonschema:"required,description=Relation in UPPER_SNAKE_CASE, e.g. WORKS_AT"`
+		Target string `json:"target" jsonschema:"required,description=Name of the target entity"`
+	} `json:"relations" jsonschema:"required"`
+}
+
+// LLMExtractor extracts entities and relations by asking a model for structured output.
+//
+// Example:
+//
+//	extractor := graph.LLMExtractor(client)
+//	sub, err := extractor.Extract(ctx, "Ada Lovelace worked with Charles Babbage.")
+func LLMExtractor(client ai.Client) Extractor {
+	return LLMExtractorWithConfig(client, nil)
+}
+
+// LLMExtractorWithConfig creates an LLMExtractor limited to a schema of entity and relation types.
+//
+// Example:
+//
+//	extractor := graph.LLMExtractorWithConfig(client, &graph.ExtractorConfig{
+//	    EntityTypes:   []string{"person", "company", "product"},
+//	    RelationTypes: []string{"WORKS_AT", "BUILDS", "COMPETES_WITH"},
+//	})
+func LLMExtractorWithConfig(client ai.Client, config *ExtractorConfig) Extractor {
+	cfg := ExtractorConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.MaxInputBytes <= 0 {
+		cfg.MaxInputBytes = 16384
+	}
+	agent := ai.Agent(client, ai.WithSchema(&extraction{}))
+
+	var rules strings.Builder
+	if len(cfg.EntityTypes) > 0 {
+		fmt.Fprintf(&rules, "Only extract entities of these types: %s.\n", strings.Join(cfg.EntityTypes, ", "))
+	}
+	if len(cfg.RelationTypes) > 0 {
+		fmt.Fprintf(&rules, "Only extract relations of these types: %s.\n", strings.Join(cfg.RelationTypes, ", "))
+	}
+
+	return ExtractorFunc(func(ctx context.Context, text string) (*Subgraph, error) {
+		prompt := fmt.Sprintf(`Extract a knowledge graph from the text below.
+List the important entities and the relations the text states between them.
+Use the same name for an entity everywhere, and only use entity names from your entity list in relations.
+%s
+Text:
+%s`, rules.String(), text[:min(len(text), cfg.MaxInputBytes)])
+
+		var output []byte
+		if err := calque.NewFlow().Use(agent).Run(ctx, prompt, &output); err != nil {
+			return nil, err
+		}
+		var result extraction
+		if err := json.Unmarshal(output, &result); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to parse knowledge graph extraction")
+		}
+
+		sub := &Subgraph{}
+		for _, entity := range result.Entities {
+			sub.Entities = append(sub.Entities, Entity{Name: strings.TrimSpace(entity.Name), Type: strings.ToLower(strings.TrimSpace(entity.Type)), Description: entity.Description})
+		}
+		for _, relation := range result.Relations {
+			sub.Relations = append(sub.Relations, Relation{Source: strings.TrimSpace(relation.Source), Type: RelationType(relation.Type), Target: strings.TrimSpace(relation.Target)})
+		}
+		return sub, nil
+	})
+}
+
+// Index extracts a document's entities and relations and adds them to the store.
+//
+// Input: document ID and text
+// Output: the extracted subgraph, tagged with the document ID
+// Behavior: One extraction per call; entities and relations already in the store are merged
+//
+// Example:
+//
+//	for _, doc := range docs {
+//	    if _, err := graph.Index(ctx, store, extractor, doc.ID, doc.Content); err != nil {
+//	        return err
+//	    }
+//	}
+func Index(ctx context.Context, store Store, extractor Extractor, documentID, text string) (*Subgraph, error) {
+	sub, err := extractor.Extract(ctx, text)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to extract knowledge graph from document %s", documentID))
+	}
+	if documentID != "" {
+		for i := range sub.Entities {
+			sub.Entities[i].Documents = appendNew(sub.Entities[i].Documents, documentID)
+		}
+		for i := range sub.Relations {
+			sub.Relations[i].Documents = appendNew(sub.Relations[i].Documents, documentID)
+		}
+	}
+	if err := store.Add(ctx, sub.Entities, sub.Relations); err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to store knowledge graph of document %s", documentID))
+	}
+	return sub, nil
+}
@@ -0,0 +1,65 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+)
+
+func TestLLMExtractor(t *testing.T) {
+	client := ai.NewMockClient(`{
+		"entities": [
+			{"name": " Ada Lovelace ", "type": "Person", "description": "Mathematician"},
+			{"name": "Charles Babbage", "type": "person"}
+		],
+		"relations": [{"source": "Ada Lovelace", "type": "worked with", "target": "Charles Babbage"}]
+	}`)
+
+	sub, err := LLMExtractor(client).Extract(context.Background(), "Ada Lovelace worked with Charles Babbage.")
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if len(sub.Entities) != 2 || sub.Entities[0].Name != "Ada Lovelace" || sub.Entities[0].Type != "person" {
+		t.Errorf("entities = %+v", sub.Entities)
+	}
+	if len(sub.Relations) != 1 || sub.Relations[0].String() != "Ada Lovelace -[WORKED_WITH]-> Charles Babbage" {
+		t.Errorf("relations = %+v", sub.Relations)
+	}
+
+	if _, err := LLMExtractor(ai.NewMockClient("not json")).Extract(context.Background(), "x"); err == nil {
+		t.Error("Extract() should fail on unparseable output")
+	}
+}
+
+func TestIndex(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	extractor := ExtractorFunc(func(_ context.Context, text string) (*Subgraph, error) {
+		source, target, _ := strings.Cut(text, " uses ")
+		return &Subgraph{
+			Entities:  []Entity{{Name: source}, {Name: target}},
+			Relations: []Relation{{Source: source, Type: "uses", Target: target}},
+		}, nil
+	})
+
+	if _, err := Index(ctx, store, extractor, "doc-1", "calque uses Go"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Index(ctx, store, extractor, "doc-2", "calque uses Go"); err != nil {
+		t.Fatal(err)
+	}
+
+	relations, _ := store.Relations(ctx, "go")
+	if len(relations) != 1 || !slices.Equal(relations[0].Documents, []string{"doc-1", "doc-2"}) {
+		t.Errorf("relations = %+v, want one relation sourced from both documents", relations)
+	}
+
+	failing := ExtractorFunc(func(context.Context, string) (*Subgraph, error) { return nil, errors.New("model down") })
+	if _, err := Index(ctx, store, failing, "doc-3", "x"); err == nil || !strings.Contains(err.Error(), "doc-3") {
+		t.Errorf("Index() error = %v, want it to name the document", err)
+	}
+}
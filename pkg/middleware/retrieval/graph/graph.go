@@ -0,0 +1,201 @@
+// Package graph provides a knowledge graph for GraphRAG retrieval.
+//
+// Entities and relations are extracted from documents (usually by a model, see
+// LLMExtractor) and merged into a Store. At query time, entities mentioned in
+// the question are expanded over the graph to find related facts and the
+// documents they came from, supplementing plain vector search. See
+// retrieval.GraphSearch for the middleware and the neo4j subpackage for a
+// persistent backend.
+package graph
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Entity is a node in the knowledge graph, identified by its name (see Key)
+type Entity struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type,omitempty"`        // e.g. "person", "product"
+	Description string   `json:"description,omitempty"` // Short summary of what the entity is
+	Documents   []string `json:"documents,omitempty"`   // IDs of the documents mentioning the entity
+}
+
+// Relation is a directed, typed edge between two entities
+type Relation struct {
+	Source    string   `json:"source"`              // Name of the source entity
+	Type      string   `json:"type"`                // e.g. "WORKS_AT", "DEPENDS_ON"
+	Target    string   `json:"target"`              // Name of the target entity
+	Documents []string `json:"documents,omitempty"` // IDs of the documents stating the relation
+}
+
+// String renders the relation as a fact, e.g. "Ada Lovelace -[WORKS_WITH]-> Charles Babbage"
+func (r Relation) String() string {
+	return fmt.Sprintf("%s -[%s]-> %s", r.Source, r.Type, r.Target)
+}
+
+// Subgraph is a set of entities and the relations between them
+type Subgraph struct {
+	Entities  []Entity   `json:"entities"`
+	Relations []Relation `json:"relations"`
+}
+
+// Documents returns the IDs of the documents the subgraph's relations and entities came from,
+// relation sources first, without duplicates
+func (g *Subgraph) Documents() []string {
+	var ids []string
+	for _, relation := range g.Relations {
+		ids = appendNew(ids, relation.Documents...)
+	}
+	for _, entity := range g.Entities {
+		ids = appendNew(ids, entity.Documents...)
+	}
+	return ids
+}
+
+// Store persists a knowledge graph.
+//
+// Entities are merged by Key, so "ACME Corp" and "acme  corp" are one node,
+// and relation types are normalized with RelationType.
+// Adding an entity or relation again merges its document IDs and keeps the
+// existing type and description unless new ones are given.
+type Store interface {
+	// Add merges entities and relations into the graph. Relation endpoints
+	// that are not known entities are created with just a name.
+	Add(ctx context.Context, entities []Entity, relations []Relation) error
+
+	// Entities returns the entities with the given names; unknown names are skipped
+	Entities(ctx context.Context, names ...string) ([]Entity, error)
+
+	// Relations returns every relation starting or ending at one of the named entities
+	Relations(ctx context.Context, names ...string) ([]Relation, error)
+
+	// Match returns the known entities mentioned in text (see Mentions)
+	Match(ctx context.Context, text string) ([]Entity, error)
+
+	// Close releases any resources held by the store
+	Close() error
+}
+
+// Key normalizes an entity name for identity: lower case with collapsed whitespace
+func Key(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// RelationType normalizes a relation type to upper snake case, e.g. "works at" to "WORKS_AT"
+func RelationType(name string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(strings.ReplaceAll(name, "-", " "))), "_")
+}
+
+// Mentions reports whether text mentions the entity name as whole words, ignoring case
+func Mentions(text, name string) bool {
+	text, key := Key(text), Key(name)
+	if key == "" {
+		return false
+	}
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], key)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(key)
+		if wordBoundary(text, start-1) && wordBoundary(text, end) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func wordBoundary(text string, i int) bool {
+	if i < 0 || i >= len(text) {
+		return true
+	}
+	r := rune(text[i])
+	return r < 0x80 && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// Expand collects the subgraph within depth hops of the named entities.
+//
+// Input: seed entity names, hop count (minimum 1) and a relation budget
+// Output: the reached entities and the relations walked to reach them
+// Behavior: Breadth-first; stops early once maxRelations are collected (0 = unlimited)
+//
+// Example:
+//
+//	sub, err := graph.Expand(ctx, store, []string{"Ada Lovelace"}, 2, 50)
+//	for _, relation := range sub.Relations {
+//	    fmt.Println(relation)
+//	}
+func Expand(ctx context.Context, store Store, names []string, depth, maxRelations int) (*Subgraph, error) {
+	depth = max(depth, 1)
+
+	visited := map[string]bool{}
+	var frontier, reached []string
+	for _, name := range names {
+		if key := Key(name); key != "" && !visited[key] {
+			visited[key] = true
+			frontier = append(frontier, name)
+			reached = append(reached, name)
+		}
+	}
+
+	sub := &Subgraph{}
+	seen := map[string]bool{}
+	for hop := 0; hop < depth && len(frontier) > 0; hop++ {
+		relations, err := store.Relations(ctx, frontier...)
+		if err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to expand knowledge graph")
+		}
+
+		frontier = nil
+		for _, relation := range relations {
+			if maxRelations > 0 && len(sub.Relations) >= maxRelations {
+				break
+			}
+			id := relationKey(relation)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			sub.Relations = append(sub.Relations, relation)
+
+			for _, name := range []string{relation.Source, relation.Target} {
+				if key := Key(name); !visited[key] {
+					visited[key] = true
+					frontier = append(frontier, name)
+					reached = append(reached, name)
+				}
+			}
+		}
+		if maxRelations > 0 && len(sub.Relations) >= maxRelations {
+			break
+		}
+	}
+
+	entities, err := store.Entities(ctx, reached...)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to load knowledge graph entities")
+	}
+	sub.Entities = entities
+	return sub, nil
+}
+
+// relationKey identifies a relation by its endpoints and type
+func relationKey(relation Relation) string {
+	return Key(relation.Source) + "\x00" + RelationType(relation.Type) + "\x00" + Key(relation.Target)
+}
+
+// appendNew appends the values not already in list
+func appendNew(list []string, values ...string) []string {
+	for _, value := range values {
+		if value != "" && !slices.Contains(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
@@ -0,0 +1,106 @@
+package graph
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestKeyAndRelationType(t *testing.T) {
+	if got := Key("  ACME   Corp "); got != "acme corp" {
+		t.Errorf("Key() = %q", got)
+	}
+	if got := RelationType(" works at "); got != "WORKS_AT" {
+		t.Errorf("RelationType() = %q", got)
+	}
+	if got := RelationType("part-of"); got != "PART_OF" {
+		t.Errorf("RelationType() = %q", got)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		text, name string
+		want       bool
+	}{
+		{"Who founded ACME Corp?", "acme corp", true},
+		{"who founded acme\n corp", "ACME Corp", true},
+		{"Tell me about Go.", "go", true},
+		{"Is Google hiring?", "go", false},
+		{"ago", "go", false},
+		{"go-calque docs", "go", true},
+		{"anything", "", false},
+	}
+	for _, tt := range tests {
+		if got := Mentions(tt.text, tt.name); got != tt.want {
+			t.Errorf("Mentions(%q, %q) = %v, want %v", tt.text, tt.name, got, tt.want)
+		}
+	}
+}
+
+// chain builds a -> b -> c -> d plus a -> x
+func chain(t *testing.T) *MemoryStore {
+	t.Helper()
+	store := NewMemoryStore()
+	err := store.Add(context.Background(), nil, []Relation{
+		{Source: "A", Type: "next", Target: "B", Documents: []string{"doc-ab"}},
+		{Source: "B", Type: "next", Target: "C", Documents: []string{"doc-bc"}},
+		{Source: "C", Type: "next", Target: "D", Documents: []string{"doc-cd"}},
+		{Source: "A", Type: "likes", Target: "X", Documents: []string{"doc-ax"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func facts(sub *Subgraph) string {
+	lines := make([]string, len(sub.Relations))
+	for i, relation := range sub.Relations {
+		lines[i] = relation.String()
+	}
+	return strings.Join(lines, "; ")
+}
+
+func TestExpand(t *testing.T) {
+	ctx := context.Background()
+	store := chain(t)
+
+	tests := []struct {
+		name         string
+		seeds        []string
+		depth, limit int
+		wantFacts    string
+		wantEntities int
+	}{
+		{"one hop", []string{"b"}, 1, 0, "A -[NEXT]-> B; B -[NEXT]-> C", 3},
+		{"two hops", []string{"B"}, 2, 0, "A -[NEXT]-> B; B -[NEXT]-> C; A -[LIKES]-> X; C -[NEXT]-> D", 5},
+		{"depth defaults to one", []string{"D"}, 0, 0, "C -[NEXT]-> D", 2},
+		{"relation budget", []string{"A"}, 3, 2, "A -[NEXT]-> B; A -[LIKES]-> X", 3},
+		{"unknown seed", []string{"nobody"}, 2, 0, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, err := Expand(ctx, store, tt.seeds, tt.depth, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := facts(sub); got != tt.wantFacts {
+				t.Errorf("facts = %q, want %q", got, tt.wantFacts)
+			}
+			if len(sub.Entities) != tt.wantEntities {
+				t.Errorf("got %d entities, want %d", len(sub.Entities), tt.wantEntities)
+			}
+		})
+	}
+}
+
+func TestSubgraphDocuments(t *testing.T) {
+	sub := &Subgraph{
+		Entities:  []Entity{{Name: "A", Documents: []string{"doc-2", "doc-3"}}},
+		Relations: []Relation{{Documents: []string{"doc-1", "doc-2"}}, {Documents: []string{"doc-1"}}},
+	}
+	if got := strings.Join(sub.Documents(), ","); got != "doc-1,doc-2,doc-3" {
+		t.Errorf("Documents() = %s", got)
+	}
+}
@@ -0,0 +1,148 @@
+package graph
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// MemoryStore keeps the graph in process memory.
+//
+// Suited to tests and small corpora; Match scans every entity name.
+// Safe for concurrent use.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	entities  map[string]*Entity   // by Key(name)
+	relations map[string]*Relation // by relationKey
+	adjacent  map[string][]string  // entity key -> relation keys touching it
+}
+
+// NewMemoryStore creates an empty in-memory graph.
+//
+// Example:
+//
+//	store := graph.NewMemoryStore()
+//	err := graph.Index(ctx, store, graph.LLMExtractor(client), "doc-1", text)
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entities:  map[string]*Entity{},
+		relations: map[string]*Relation{},
+		adjacent:  map[string][]string{},
+	}
+}
+
+// Add merges entities and relations into the graph
+func (m *MemoryStore) Add(_ context.Context, entities []Entity, relations []Relation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, entity := range entities {
+		m.addEntity(entity)
+	}
+	for _, relation := range relations {
+		if Key(relation.Source) == "" || Key(relation.Target) == "" || RelationType(relation.Type) == "" {
+			continue
+		}
+		m.addEntity(Entity{Name: relation.Source})
+		m.addEntity(Entity{Name: relation.Target})
+
+		id := relationKey(relation)
+		if existing, ok := m.relations[id]; ok {
+			existing.Documents = appendNew(existing.Documents, relation.Documents...)
+			continue
+		}
+		stored := relation
+		stored.Type = RelationType(relation.Type)
+		stored.Documents = appendNew(nil, relation.Documents...)
+		m.relations[id] = &stored
+		source, target := Key(relation.Source), Key(relation.Target)
+		m.adjacent[source] = append(m.adjacent[source], id)
+		if target != source {
+			m.adjacent[target] = append(m.adjacent[target], id)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) addEntity(entity Entity) {
+	key := Key(entity.Name)
+	if key == "" {
+		return
+	}
+	existing, ok := m.entities[key]
+	if !ok {
+		stored := entity
+		stored.Documents = appendNew(nil, entity.Documents...)
+		m.entities[key] = &stored
+		return
+	}
+	if entity.Type != "" {
+		existing.Type = entity.Type
+	}
+	if entity.Description != "" {
+		existing.Description = entity.Description
+	}
+	existing.Documents = appendNew(existing.Documents, entity.Documents...)
+}
+
+// Entities returns the entities with the given names
+func (m *MemoryStore) Entities(_ context.Context, names ...string) ([]Entity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entities []Entity
+	seen := map[string]bool{}
+	for _, name := range names {
+		key := Key(name)
+		if entity, ok := m.entities[key]; ok && !seen[key] {
+			seen[key] = true
+			entities = append(entities, cloneEntity(*entity))
+		}
+	}
+	return entities, nil
+}
+
+// Relations returns every relation touching one of the named entities
+func (m *MemoryStore) Relations(_ context.Context, names ...string) ([]Relation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var relations []Relation
+	seen := map[string]bool{}
+	for _, name := range names {
+		for _, id := range m.adjacent[Key(name)] {
+			if !seen[id] {
+				seen[id] = true
+				relation := *m.relations[id]
+				relation.Documents = slices.Clone(relation.Documents)
+				relations = append(relations, relation)
+			}
+		}
+	}
+	return relations, nil
+}
+
+// Match returns the entities mentioned in text, in name order
+func (m *MemoryStore) Match(_ context.Context, text string) ([]Entity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entities []Entity
+	for _, key := range slices.Sorted(maps.Keys(m.entities)) {
+		if Mentions(text, key) {
+			entities = append(entities, cloneEntity(*m.entities[key]))
+		}
+	}
+	return entities, nil
+}
+
+// Close is a no-op
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+func cloneEntity(entity Entity) Entity {
+	entity.Documents = slices.Clone(entity.Documents)
+	return entity
+}
@@ -0,0 +1,71 @@
+package graph
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+var _ Store = (*MemoryStore)(nil)
+
+func TestMemoryStoreMerges(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	_ = store.Add(ctx,
+		[]Entity{{Name: "ACME Corp", Type: "company", Documents: []string{"doc-1"}}},
+		[]Relation{{Source: "Ada", Type: "works at", Target: "acme corp", Documents: []string{"doc-1"}}},
+	)
+	_ = store.Add(ctx,
+		[]Entity{{Name: "acme  corp", Description: "Makes anvils", Documents: []string{"doc-2", "doc-1"}}},
+		[]Relation{
+			{Source: "ada", Type: "WORKS_AT", Target: "ACME Corp", Documents: []string{"doc-2"}},
+			{Source: "", Type: "x", Target: "y"}, // skipped: no source
+		},
+	)
+
+	entities, err := store.Entities(ctx, "Acme Corp", "missing", "ADA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entities) != 2 {
+		t.Fatalf("Entities() = %+v, want acme and ada", entities)
+	}
+	acme := entities[0]
+	if acme.Name != "ACME Corp" || acme.Type != "company" || acme.Description != "Makes anvils" || !slices.Equal(acme.Documents, []string{"doc-1", "doc-2"}) {
+		t.Errorf("merged entity = %+v", acme)
+	}
+	if entities[1].Name != "Ada" {
+		t.Errorf("relation endpoint should be created as an entity, got %+v", entities[1])
+	}
+
+	relations, _ := store.Relations(ctx, "acme corp")
+	if len(relations) != 1 || relations[0].Type != "WORKS_AT" || !slices.Equal(relations[0].Documents, []string{"doc-1", "doc-2"}) {
+		t.Errorf("Relations() = %+v, want one merged WORKS_AT relation", relations)
+	}
+
+	// Returned values are copies
+	relations[0].Documents[0] = "changed"
+	relations, _ = store.Relations(ctx, "ada")
+	if relations[0].Documents[0] != "doc-1" {
+		t.Error("Relations() must not expose internal state")
+	}
+}
+
+func TestMemoryStoreMatch(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	_ = store.Add(ctx, []Entity{{Name: "Go"}, {Name: "Google"}, {Name: "Ada Lovelace"}, {Name: "Ada"}}, nil)
+
+	matched, err := store.Match(ctx, "Did Ada Lovelace know about go?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entity := range matched {
+		names = append(names, entity.Name)
+	}
+	if !slices.Equal(names, []string{"Ada", "Ada Lovelace", "Go"}) {
+		t.Errorf("Match() = %v", names)
+	}
+}
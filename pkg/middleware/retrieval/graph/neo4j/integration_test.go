@@ -0,0 +1,102 @@
+//go:build integration
+
+package neo4j
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/calque-ai/go-calque/pkg/middleware/retrieval/graph"
+)
+
+// setupNeo4j starts a Neo4j container and returns its bolt URI
+func setupNeo4j(ctx context.Context, t *testing.T) string {
+	t.Helper()
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "neo4j:5",
+			ExposedPorts: []string{"7687/tcp"},
+			Env:          map[string]string{"NEO4J_AUTH": "neo4j/testpassword"},
+			WaitingFor:   wait.ForLog("Started.").WithStartupTimeout(120 * time.Second),
+		},
+		Started: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to start Neo4j container: %v", err)
+	}
+	t.Cleanup(func() { container.Terminate(ctx) })
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get container host: %v", err)
+	}
+	port, err := container.MappedPort(ctx, "7687")
+	if err != nil {
+		t.Fatalf("Failed to get mapped port: %v", err)
+	}
+	return fmt.Sprintf("neo4j://%s:%s", host, port.Port())
+}
+
+func TestNeo4jStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	client, err := New(&Config{URI: setupNeo4j(ctx, t), Username: "neo4j", Password: "testpassword"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+
+	err = client.Add(ctx,
+		[]graph.Entity{{Name: "ACME Corp", Type: "company", Documents: []string{"doc-1"}}},
+		[]graph.Relation{
+			{Source: "Ada", Type: "works at", Target: "ACME Corp", Documents: []string{"doc-1"}},
+			{Source: "ACME Corp", Type: "makes", Target: "Anvils", Documents: []string{"doc-2"}},
+		})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	err = client.Add(ctx,
+		[]graph.Entity{{Name: "acme corp", Description: "Makes anvils", Documents: []string{"doc-3"}}},
+		[]graph.Relation{{Source: "ada", Type: "WORKS_AT", Target: "acme corp", Documents: []string{"doc-3"}}})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	entities, err := client.Entities(ctx, "Acme Corp", "missing")
+	if err != nil {
+		t.Fatalf("Entities() error = %v", err)
+	}
+	if len(entities) != 1 || entities[0].Type != "company" || entities[0].Description != "Makes anvils" || !slices.Equal(entities[0].Documents, []string{"doc-1", "doc-3"}) {
+		t.Errorf("Entities() = %+v", entities)
+	}
+
+	relations, err := client.Relations(ctx, "ada")
+	if err != nil {
+		t.Fatalf("Relations() error = %v", err)
+	}
+	if len(relations) != 1 || relations[0].Type != "WORKS_AT" || !slices.Equal(relations[0].Documents, []string{"doc-1", "doc-3"}) {
+		t.Errorf("Relations() = %+v", relations)
+	}
+
+	matched, err := client.Match(ctx, "What does Ada's employer make?")
+	if err != nil || len(matched) != 1 || matched[0].Name != "Ada" {
+		t.Errorf("Match() = %+v, %v", matched, err)
+	}
+
+	sub, err := graph.Expand(ctx, client, []string{"Ada"}, 2, 0)
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if len(sub.Relations) != 2 || len(sub.Entities) != 3 {
+		t.Errorf("Expand() = %+v, want both relations and all three entities", sub)
+	}
+}
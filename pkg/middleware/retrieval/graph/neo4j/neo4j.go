@@ -0,0 +1,290 @@
+// Package neo4j provides a Neo4j backend for knowledge graph retrieval.
+//
+// This package implements the graph.Store interface on a Neo4j database.
+// Entities are stored as :Entity nodes keyed by graph.Key(name), and
+// relations as :RELATES edges carrying their normalized type as a property.
+package neo4j
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	driver "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/retrieval/graph"
+)
+
+// Client is a knowledge graph store backed by Neo4j.
+//
+// Implements the graph.Store interface.
+type Client struct {
+	driver   driver.DriverWithContext
+	owned    bool
+	database string
+}
+
+// Config holds Neo4j client configuration.
+type Config struct {
+	// Existing driver to share. Takes precedence over URI and credentials.
+	Driver driver.DriverWithContext
+
+	// Connection URI, e.g. "neo4j://localhost:7687"
+	URI string
+
+	// Basic auth credentials
+	Username string
+	Password string
+
+	// Database name (default: the server's default database)
+	Database string
+}
+
+// New connects to Neo4j and ensures the entity key constraint exists.
+//
+// Example:
+//
+//	kg, err := neo4j.New(&neo4j.Config{
+//	    URI:      "neo4j://localhost:7687",
+//	    Username: "neo4j",
+//	    Password: os.Getenv("NEO4J_PASSWORD"),
+//	})
+func New(config *Config) (*Client, error) {
+	ctx := context.Background()
+	if config == nil || (config.Driver == nil && config.URI == "") {
+		return nil, calque.NewErr(ctx, "Neo4j Driver or URI is required")
+	}
+
+	client := &Client{driver: config.Driver, database: config.Database}
+	if client.driver == nil {
+		d, err := driver.NewDriverWithContext(config.URI, driver.BasicAuth(config.Username, config.Password, ""))
+		if err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to create Neo4j driver")
+		}
+		client.driver, client.owned = d, true
+	}
+
+	if err := client.driver.VerifyConnectivity(ctx); err != nil {
+		client.Close()
+		return nil, calque.WrapErr(ctx, err, "failed to connect to Neo4j")
+	}
+	if _, err := client.write(ctx, "CREATE CONSTRAINT calque_entity_key IF NOT EXISTS FOR (e:Entity) REQUIRE e.key IS UNIQUE", nil); err != nil {
+		client.Close()
+		return nil, calque.WrapErr(ctx, err, "failed to create entity key constraint")
+	}
+	return client, nil
+}
+
+// Add merges entities and relations into the graph
+func (c *Client) Add(ctx context.Context, entities []graph.Entity, relations []graph.Relation) error {
+	if len(entities) > 0 {
+		rows := make([]map[string]any, 0, len(entities))
+		for _, entity := range entities {
+			if key := graph.Key(entity.Name); key != "" {
+				rows = append(rows, map[string]any{
+					"key":         key,
+					"name":        entity.Name,
+					"type":        entity.Type,
+					"description": entity.Description,
+					"documents":   documentList(entity.Documents),
+				})
+			}
+		}
+		_, err := c.write(ctx, `
+			UNWIND $entities AS e
+			MERGE (n:Entity {key: e.key})
+			ON CREATE SET n.name = e.name, n.documents = []
+			SET n.type = CASE e.type WHEN '' THEN n.type ELSE e.type END,
+			    n.description = CASE e.description WHEN '' THEN n.description ELSE e.description END,
+			    n.documents = n.documents + [d IN e.documents WHERE NOT d IN n.documents]`,
+			map[string]any{"entities": rows})
+		if err != nil {
+			return calque.WrapErr(ctx, err, "failed to add entities")
+		}
+	}
+
+	if len(relations) > 0 {
+		rows := make([]map[string]any, 0, len(relations))
+		for _, relation := range relations {
+			source, target, relationType := graph.Key(relation.Source), graph.Key(relation.Target), graph.RelationType(relation.Type)
+			if source == "" || target == "" || relationType == "" {
+				continue
+			}
+			rows = append(rows, map[string]any{
+				"source_key": source,
+				"source":     relation.Source,
+				"target_key": target,
+				"target":     relation.Target,
+				"type":       relationType,
+				"documents":  documentList(relation.Documents),
+			})
+		}
+		_, err := c.write(ctx, `
+			UNWIND $relations AS r
+			MERGE (s:Entity {key: r.source_key}) ON CREATE SET s.name = r.source, s.documents = []
+			MERGE (t:Entity {key: r.target_key}) ON CREATE SET t.name = r.target, t.documents = []
+			MERGE (s)-[rel:RELATES {type: r.type}]->(t)
+			ON CREATE SET rel.documents = []
+			SET rel.documents = rel.documents + [d IN r.documents WHERE NOT d IN rel.documents]`,
+			map[string]any{"relations": rows})
+		if err != nil {
+			return calque.WrapErr(ctx, err, "failed to add relations")
+		}
+	}
+	return nil
+}
+
+// Entities returns the entities with the given names, in the order asked
+func (c *Client) Entities(ctx context.Context, names ...string) ([]graph.Entity, error) {
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		if key := graph.Key(name); key != "" && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	result, err := c.read(ctx, `
+		MATCH (n:Entity) WHERE n.key IN $keys
+		RETURN n.key AS key, n.name AS name, n.type AS type, n.description AS description, n.documents AS documents`,
+		map[string]any{"keys": keys})
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to get entities")
+	}
+
+	byKey := make(map[string]graph.Entity, len(result.Records))
+	for _, record := range result.Records {
+		byKey[stringValue(record, "key")] = entityFrom(record)
+	}
+	entities := make([]graph.Entity, 0, len(byKey))
+	for _, key := range keys {
+		if entity, ok := byKey[key]; ok {
+			entities = append(entities, entity)
+		}
+	}
+	return entities, nil
+}
+
+// Relations returns every relation touching one of the named entities
+func (c *Client) Relations(ctx context.Context, names ...string) ([]graph.Relation, error) {
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		if key := graph.Key(name); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	result, err := c.read(ctx, `
+		MATCH (s:Entity)-[r:RELATES]->(t:Entity)
+		WHERE s.key IN $keys OR t.key IN $keys
+		RETURN s.name AS source, r.type AS type, t.name AS target, r.documents AS documents
+		ORDER BY s.key, r.type, t.key`,
+		map[string]any{"keys": keys})
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to get relations")
+	}
+
+	relations := make([]graph.Relation, 0, len(result.Records))
+	for _, record := range result.Records {
+		relations = append(relations, graph.Relation{
+			Source:    stringValue(record, "source"),
+			Type:      stringValue(record, "type"),
+			Target:    stringValue(record, "target"),
+			Documents: stringList(record, "documents"),
+		})
+	}
+	return relations, nil
+}
+
+// Match returns the entities mentioned in text, in key order
+func (c *Client) Match(ctx context.Context, text string) ([]graph.Entity, error) {
+	// CONTAINS narrows the candidates on the server; Mentions enforces word boundaries
+	result, err := c.read(ctx, `
+		MATCH (n:Entity) WHERE $text CONTAINS n.key
+		RETURN n.key AS key, n.name AS name, n.type AS type, n.description AS description, n.documents AS documents
+		ORDER BY n.key`,
+		map[string]any{"text": graph.Key(text)})
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to match entities")
+	}
+
+	var entities []graph.Entity
+	for _, record := range result.Records {
+		if graph.Mentions(text, stringValue(record, "key")) {
+			entities = append(entities, entityFrom(record))
+		}
+	}
+	return entities, nil
+}
+
+// Close closes the driver if the client created it
+func (c *Client) Close() error {
+	if c.owned && c.driver != nil {
+		err := c.driver.Close(context.Background())
+		c.driver = nil
+		return err
+	}
+	return nil
+}
+
+func (c *Client) write(ctx context.Context, query string, params map[string]any) (*driver.EagerResult, error) {
+	return driver.ExecuteQuery(ctx, c.driver, query, params, driver.EagerResultTransformer,
+		driver.ExecuteQueryWithDatabase(c.database))
+}
+
+func (c *Client) read(ctx context.Context, query string, params map[string]any) (*driver.EagerResult, error) {
+	return driver.ExecuteQuery(ctx, c.driver, query, params, driver.EagerResultTransformer,
+		driver.ExecuteQueryWithDatabase(c.database), driver.ExecuteQueryWithReadersRouting())
+}
+
+func entityFrom(record *driver.Record) graph.Entity {
+	return graph.Entity{
+		Name:        stringValue(record, "name"),
+		Type:        stringValue(record, "type"),
+		Description: stringValue(record, "description"),
+		Documents:   stringList(record, "documents"),
+	}
+}
+
+func stringValue(record *driver.Record, key string) string {
+	value, _ := record.Get(key)
+	if s, ok := value.(string); ok {
+		return s
+	}
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func stringList(record *driver.Record, key string) []string {
+	value, _ := record.Get(key)
+	items, _ := value.([]any)
+	if len(items) == 0 {
+		return nil
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// documentList converts IDs for the driver, which needs a non-nil list
+func documentList(ids []string) []any {
+	list := make([]any, 0, len(ids))
+	for _, id := range ids {
+		if id != "" && !slices.Contains(list, any(id)) {
+			list = append(list, id)
+		}
+	}
+	return list
+}
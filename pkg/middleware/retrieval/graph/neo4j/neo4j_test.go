@@ -0,0 +1,31 @@
+package neo4j
+
+import (
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/middleware/retrieval/graph"
+)
+
+var _ graph.Store = (*Client)(nil)
+
+func TestNew_Validation(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("New(nil) should fail")
+	}
+	if _, err := New(&Config{Username: "neo4j"}); err == nil {
+		t.Error("New() without a Driver or URI should fail")
+	}
+	if _, err := New(&Config{URI: "bogus://localhost"}); err == nil {
+		t.Error("New() with an unsupported URI scheme should fail")
+	}
+}
+
+func TestDocumentList(t *testing.T) {
+	list := documentList([]string{"a", "", "b", "a"})
+	if len(list) != 2 || list[0] != "a" || list[1] != "b" {
+		t.Errorf("documentList() = %v", list)
+	}
+	if list := documentList(nil); list == nil || len(list) != 0 {
+		t.Errorf("documentList(nil) = %#v, want an empty non-nil list", list)
+	}
+}
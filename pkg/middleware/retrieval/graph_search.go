@@ -0,0 +1,211 @@
+package retrieval
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/retrieval/graph"
+)
+
+// Default GraphSearch expansion limits
+const (
+	DefaultGraphDepth           = 1  // Direct neighbors of the query entities
+	DefaultGraphMaxRelations    = 30 // Facts added to the result
+	DefaultGraphLinkedDocuments = 5  // Graph-sourced documents added to the vector results
+)
+
+// GraphSearchOptions configures GraphSearch.
+type GraphSearchOptions struct {
+	SearchOptions // Vector search and context building options
+
+	Depth              int             // Hops to expand from the query entities (default: 1)
+	MaxRelations       int             // Maximum relations collected (default: 30)
+	MaxLinkedDocuments int             // Documents fetched from graph sources (default: 5, negative disables)
+	Extractor          graph.Extractor // Finds entities in the query (default: match known entity names)
+}
+
+// GraphSearchResult is a SearchResult supplemented with knowledge graph facts.
+type GraphSearchResult struct {
+	SearchResult
+	Entities  []graph.Entity   `json:"entities"`  // Query entities and their neighbors
+	Relations []graph.Relation `json:"relations"` // Facts connecting them
+}
+
+// GraphSearch combines vector search with knowledge graph expansion (GraphRAG).
+//
+// Input: string query text
+// Output: GraphSearchResult JSON or formatted context string (based on options)
+// Behavior: BUFFERED - reads entire input to perform search
+//
+// Runs a vector search, then finds the entities mentioned in the query and
+// walks the graph from them. The relations found are returned as facts, and
+// the documents they were extracted from are fetched from the vector store
+// (when it implements DocumentGetter) and appended to the vector results,
+// surfacing documents that are related to the question without being
+// textually similar to it. With a Strategy set, the output is a context
+// string with the facts first and the documents after; MaxTokens only
+// budgets the documents.
+//
+// Example:
+//
+//	kg := graph.NewMemoryStore()
+//	for _, doc := range docs {
+//	    graph.Index(ctx, kg, graph.LLMExtractor(client), doc.ID, doc.Content)
+//	}
+//
+//	strategy := retrieval.StrategyRelevant
+//	flow := calque.NewFlow().Use(retrieval.GraphSearch(store, kg, &retrieval.GraphSearchOptions{
+//	    SearchOptions: retrieval.SearchOptions{Threshold: 0.7, Limit: 5, Strategy: &strategy},
+//	    Depth:         2,
+//	}))
+func GraphSearch(store VectorStore, kg graph.Store, opts *GraphSearchOptions) calque.Handler {
+	if opts == nil {
+		opts = &GraphSearchOptions{}
+	}
+	depth := opts.Depth
+	if depth <= 0 {
+		depth = DefaultGraphDepth
+	}
+	maxRelations := opts.MaxRelations
+	if maxRelations <= 0 {
+		maxRelations = DefaultGraphMaxRelations
+	}
+	maxLinked := opts.MaxLinkedDocuments
+	if maxLinked == 0 {
+		maxLinked = DefaultGraphLinkedDocuments
+	}
+
+	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		ctx := r.Context
+		var queryText string
+		if err := calque.Read(r, &queryText); err != nil {
+			return err
+		}
+
+		query := SearchQuery{
+			Text:      queryText,
+			Threshold: opts.Threshold,
+			Limit:     opts.Limit,
+			Filter:    opts.Filter,
+		}
+		if err := handleEmbeddingForQuery(ctx, store, &query, &opts.SearchOptions); err != nil {
+			return err
+		}
+		vectorResult, err := store.Search(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		names, err := queryEntities(r, kg, opts.Extractor, queryText)
+		if err != nil {
+			return err
+		}
+		sub := &graph.Subgraph{}
+		if len(names) > 0 {
+			sub, err = graph.Expand(ctx, kg, names, depth, maxRelations)
+			if err != nil {
+				return err
+			}
+		}
+
+		result := GraphSearchResult{SearchResult: *vectorResult, Entities: sub.Entities, Relations: sub.Relations}
+		if maxLinked > 0 {
+			linked, err := linkedDocuments(r, store, sub, result.Documents, maxLinked)
+			if err != nil {
+				return err
+			}
+			result.Documents = append(result.Documents, linked...)
+		}
+
+		if opts.Strategy == nil {
+			resultJSON, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+			return calque.Write(w, resultJSON)
+		}
+
+		documents, err := buildContext(ctx, result.Documents, &opts.SearchOptions, store, false)
+		if err != nil {
+			return err
+		}
+		facts := formatFacts(sub)
+		if facts == "" {
+			return calque.Write(w, documents)
+		}
+		if documents == "" {
+			return calque.Write(w, facts)
+		}
+		return calque.Write(w, facts+opts.GetSeparator()+documents)
+	})
+}
+
+// queryEntities names the graph entities the query is about
+func queryEntities(r *calque.Request, kg graph.Store, extractor graph.Extractor, queryText string) ([]string, error) {
+	var entities []graph.Entity
+	if extractor != nil {
+		sub, err := extractor.Extract(r.Context, queryText)
+		if err != nil {
+			return nil, calque.WrapErr(r.Context, err, "failed to extract query entities")
+		}
+		entities = sub.Entities
+	} else {
+		matched, err := kg.Match(r.Context, queryText)
+		if err != nil {
+			return nil, calque.WrapErr(r.Context, err, "failed to match query entities")
+		}
+		entities = matched
+	}
+
+	names := make([]string, len(entities))
+	for i, entity := range entities {
+		names[i] = entity.Name
+	}
+	return names, nil
+}
+
+// linkedDocuments fetches the graph's source documents missing from the vector results
+func linkedDocuments(r *calque.Request, store VectorStore, sub *graph.Subgraph, found []Document, limit int) ([]Document, error) {
+	getter, ok := store.(DocumentGetter)
+	if !ok {
+		return nil, nil
+	}
+
+	var ids []string
+	for _, id := range sub.Documents() {
+		if len(ids) == limit {
+			break
+		}
+		if !slices.ContainsFunc(found, func(doc Document) bool { return doc.ID == id }) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	docs, err := getter.GetByID(r.Context, ids...)
+	if err != nil {
+		return nil, calque.WrapErr(r.Context, err, "failed to fetch documents linked by the knowledge graph")
+	}
+	return docs, nil
+}
+
+// formatFacts renders entity descriptions and relations as a context section
+func formatFacts(sub *graph.Subgraph) string {
+	var lines []string
+	for _, entity := range sub.Entities {
+		if entity.Description != "" {
+			lines = append(lines, "- "+entity.Name+": "+entity.Description)
+		}
+	}
+	for _, relation := range sub.Relations {
+		lines = append(lines, "- "+relation.String())
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "Knowledge graph:\n" + strings.Join(lines, "\n")
+}
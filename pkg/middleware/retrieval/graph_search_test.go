@@ -0,0 +1,118 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/retrieval/graph"
+)
+
+// graphFixture stores three documents and a graph linking Ada to ACME's anvils
+func graphFixture(t *testing.T) (*gettingStore, *graph.MemoryStore) {
+	t.Helper()
+	ctx := context.Background()
+	store := newGettingStore()
+	_ = store.Store(ctx, []Document{
+		{ID: "bio", Content: "Ada works at ACME.", Metadata: map[string]any{"topic": "people"}},
+		{ID: "catalog", Content: "ACME sells anvils.", Metadata: map[string]any{"topic": "products"}},
+		{ID: "weather", Content: "It is sunny.", Metadata: map[string]any{"topic": "weather"}},
+	})
+
+	kg := graph.NewMemoryStore()
+	err := kg.Add(ctx, []graph.Entity{{Name: "ACME", Description: "An anvil maker"}}, []graph.Relation{
+		{Source: "Ada", Type: "WORKS_AT", Target: "ACME", Documents: []string{"bio"}},
+		{Source: "ACME", Type: "SELLS", Target: "Anvils", Documents: []string{"catalog"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, kg
+}
+
+func runGraphSearch(t *testing.T, handler calque.Handler, query string) string {
+	t.Helper()
+	var output string
+	if err := calque.NewFlow().Use(handler).Run(context.Background(), query, &output); err != nil {
+		t.Fatalf("GraphSearch() error = %v", err)
+	}
+	return output
+}
+
+func TestGraphSearch(t *testing.T) {
+	store, kg := graphFixture(t)
+	opts := &GraphSearchOptions{
+		SearchOptions: SearchOptions{Filter: map[string]any{"topic": "people"}},
+		Depth:         2,
+	}
+
+	var result GraphSearchResult
+	if err := json.Unmarshal([]byte(runGraphSearch(t, GraphSearch(store, kg, opts), "Where does Ada work?")), &result); err != nil {
+		t.Fatal(err)
+	}
+	if docIDs(result.Documents) != "bio,catalog" {
+		t.Errorf("documents = %s, want the vector hit plus the graph-linked catalog", docIDs(result.Documents))
+	}
+	if len(result.Relations) != 2 || len(result.Entities) != 3 {
+		t.Errorf("graph = %+v / %+v, want both relations within two hops of Ada", result.Relations, result.Entities)
+	}
+
+	opts.Depth = 1
+	_ = json.Unmarshal([]byte(runGraphSearch(t, GraphSearch(store, kg, opts), "Where does Ada work?")), &result)
+	if len(result.Relations) != 1 {
+		t.Errorf("depth 1 relations = %+v, want only WORKS_AT", result.Relations)
+	}
+
+	opts.MaxLinkedDocuments = -1
+	result = GraphSearchResult{}
+	_ = json.Unmarshal([]byte(runGraphSearch(t, GraphSearch(store, kg, opts), "What does ACME sell?")), &result)
+	if docIDs(result.Documents) != "bio" {
+		t.Errorf("documents with linking disabled = %s, want only the vector hit", docIDs(result.Documents))
+	}
+}
+
+func TestGraphSearchContext(t *testing.T) {
+	store, kg := graphFixture(t)
+	strategy := StrategyRelevant
+	opts := &GraphSearchOptions{SearchOptions: SearchOptions{
+		Filter:    map[string]any{"topic": "people"},
+		Strategy:  &strategy,
+		Separator: "\n---\n",
+	}}
+
+	output := runGraphSearch(t, GraphSearch(store, kg, opts), "What does ACME sell?")
+	want := "Knowledge graph:\n- ACME: An anvil maker\n- Ada -[WORKS_AT]-> ACME\n- ACME -[SELLS]-> Anvils\n---\nAda works at ACME.\n---\nACME sells anvils."
+	if output != want {
+		t.Errorf("context =\n%s\nwant\n%s", output, want)
+	}
+
+	// No entity in the query: plain vector context
+	output = runGraphSearch(t, GraphSearch(store, kg, opts), "Anything new?")
+	if output != "Ada works at ACME." {
+		t.Errorf("context without graph matches = %q", output)
+	}
+}
+
+func TestGraphSearchExtractor(t *testing.T) {
+	store, kg := graphFixture(t)
+	var asked string
+	extractor := graph.ExtractorFunc(func(_ context.Context, text string) (*graph.Subgraph, error) {
+		asked = text
+		return &graph.Subgraph{Entities: []graph.Entity{{Name: "anvils"}}}, nil
+	})
+	opts := &GraphSearchOptions{
+		SearchOptions: SearchOptions{Filter: map[string]any{"topic": "weather"}},
+		Extractor:     extractor,
+	}
+
+	var result GraphSearchResult
+	_ = json.Unmarshal([]byte(runGraphSearch(t, GraphSearch(store, kg, opts), "who makes the heavy iron things")), &result)
+	if asked != "who makes the heavy iron things" {
+		t.Errorf("extractor got %q", asked)
+	}
+	if len(result.Relations) != 1 || !strings.Contains(docIDs(result.Documents), "catalog") {
+		t.Errorf("result = %+v, want the SELLS relation and its catalog document", result)
+	}
+}
@@ -0,0 +1,293 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Metadata keys written by Namespaced stores
+const (
+	NamespaceMetadataKey   = "namespace"    // Namespace a document belongs to
+	NamespaceIDMetadataKey = "namespace_id" // Document ID as given by the caller
+)
+
+type namespaceKey struct{}
+
+// WithNamespace scopes retrieval calls in ctx to a namespace.
+//
+// Stores created with Namespaced(store, "") read it on every call, so one
+// flow can serve many tenants.
+//
+// Example:
+//
+//	ctx = retrieval.WithNamespace(ctx, customerID)
+//	err := flow.Run(ctx, question, &answer)
+func WithNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, namespaceKey{}, namespace)
+}
+
+// NamespaceFrom returns the namespace set with WithNamespace, or ""
+func NamespaceFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	namespace, _ := ctx.Value(namespaceKey{}).(string)
+	return namespace
+}
+
+// NamespacedStore isolates one corpus inside a shared vector store.
+type NamespacedStore struct {
+	store     VectorStore
+	namespace string
+}
+
+// Namespaced scopes Store, Search, Delete and ListDocuments to a namespace.
+//
+// Input: any VectorStore, namespace ("" reads it from the context per call)
+// Output: VectorStore that only sees documents of its namespace
+// Behavior: Tags stored documents and filters every search by the tag
+//
+// Isolation works the same on every backend: documents are tagged with
+// NamespaceMetadataKey, searches add it to the metadata filter, and IDs are
+// scoped so tenants using the same document ID don't overwrite each other.
+// Scoped IDs stay UUIDs for backends that require them. Results are returned
+// with the caller's IDs and metadata. Calls without a namespace fail rather
+// than reach across tenants. SearchQuery.Collection still selects the backend
+// collection; namespaces partition documents within it.
+//
+// The wrapper forwards embedding capabilities; native diversification and
+// reranking are not forwarded, so VectorSearch applies those strategies itself.
+//
+// Example:
+//
+//	tenants := retrieval.Namespaced(qdrantStore, "")
+//	flow := calque.NewFlow().Use(retrieval.VectorSearch(tenants, opts))
+//
+//	ctx = retrieval.WithNamespace(ctx, "acme")
+//	err := tenants.Store(ctx, acmeDocs)
+func Namespaced(store VectorStore, namespace string) VectorStore {
+	ns := &NamespacedStore{store: store, namespace: namespace}
+	if _, ok := store.(EmbeddingCapable); ok {
+		return &namespacedEmbeddingStore{ns}
+	}
+	return ns
+}
+
+// Search runs the query restricted to the namespace.
+func (n *NamespacedStore) Search(ctx context.Context, query SearchQuery) (*SearchResult, error) {
+	namespace, err := n.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := make(map[string]any, len(query.Filter)+1)
+	maps.Copy(filter, query.Filter)
+	filter[NamespaceMetadataKey] = namespace
+	query.Filter = filter
+
+	result, err := n.store.Search(ctx, query)
+	if err != nil || result == nil {
+		return result, err
+	}
+
+	scoped := *result
+	scoped.Documents = make([]Document, 0, len(result.Documents))
+	for _, doc := range result.Documents {
+		// Backends that ignore filters must still not leak other namespaces
+		if doc.Metadata[NamespaceMetadataKey] != namespace {
+			continue
+		}
+		scoped.Documents = append(scoped.Documents, unscopeDocument(doc, namespace))
+	}
+	scoped.Total -= len(result.Documents) - len(scoped.Documents)
+	return &scoped, nil
+}
+
+// Store tags documents with the namespace and stores them under scoped IDs.
+func (n *NamespacedStore) Store(ctx context.Context, documents []Document) error {
+	namespace, err := n.resolve(ctx)
+	if err != nil {
+		return err
+	}
+
+	scoped := make([]Document, len(documents))
+	for i, doc := range documents {
+		metadata := make(map[string]any, len(doc.Metadata)+2)
+		maps.Copy(metadata, doc.Metadata)
+		metadata[NamespaceMetadataKey] = namespace
+		if doc.ID != "" {
+			metadata[NamespaceIDMetadataKey] = doc.ID
+			doc.ID = scopedID(namespace, doc.ID)
+		}
+		doc.Metadata = metadata
+		scoped[i] = doc
+	}
+	return n.store.Store(ctx, scoped)
+}
+
+// Delete removes documents of the namespace; IDs of other namespaces are unaffected.
+func (n *NamespacedStore) Delete(ctx context.Context, ids []string) error {
+	namespace, err := n.resolve(ctx)
+	if err != nil {
+		return err
+	}
+
+	scoped := make([]string, len(ids))
+	for i, id := range ids {
+		scoped[i] = scopedID(namespace, id)
+	}
+	return n.store.Delete(ctx, scoped)
+}
+
+// ListDocuments pages through the namespace's documents.
+// This implements the DocumentLister interface when the wrapped store does.
+func (n *NamespacedStore) ListDocuments(ctx context.Context, cursor string, limit int) ([]Document, string, error) {
+	lister, ok := n.store.(DocumentLister)
+	if !ok {
+		return nil, "", calque.NewErr(ctx, fmt.Sprintf("store %T cannot list documents", n.store))
+	}
+	namespace, err := n.resolve(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Scoped IDs are not contiguous, so pages are filtered and may come back short or empty
+	docs, next, err := lister.ListDocuments(ctx, cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	scoped := make([]Document, 0, len(docs))
+	for _, doc := range docs {
+		if doc.Metadata[NamespaceMetadataKey] == namespace {
+			scoped = append(scoped, unscopeDocument(doc, namespace))
+		}
+	}
+	return scoped, next, nil
+}
+
+// GetByID fetches the namespace's documents among ids, skipping other tenants' documents.
+func (n *NamespacedStore) GetByID(ctx context.Context, ids ...string) ([]Document, error) {
+	getter, ok := n.store.(DocumentGetter)
+	if !ok {
+		return nil, calque.NewErr(ctx, fmt.Sprintf("store %T cannot get documents by ID", n.store))
+	}
+	namespace, err := n.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scopedIDs := make([]string, len(ids))
+	for i, id := range ids {
+		scopedIDs[i] = scopedID(namespace, id)
+	}
+	docs, err := getter.GetByID(ctx, scopedIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	scoped := make([]Document, 0, len(docs))
+	for _, doc := range docs {
+		if doc.Metadata[NamespaceMetadataKey] == namespace {
+			scoped = append(scoped, unscopeDocument(doc, namespace))
+		}
+	}
+	return scoped, nil
+}
+
+// UpdateMetadata patches a document in the namespace. The namespace keys cannot be changed.
+func (n *NamespacedStore) UpdateMetadata(ctx context.Context, id string, patch map[string]any) error {
+	updater, ok := n.store.(MetadataUpdater)
+	if !ok {
+		return calque.WrapErr(ctx, errMetadataUpdateUnsupported, fmt.Sprintf("store %T cannot update metadata", n.store))
+	}
+	namespace, err := n.resolve(ctx)
+	if err != nil {
+		return err
+	}
+
+	patch = maps.Clone(patch)
+	delete(patch, NamespaceMetadataKey)
+	delete(patch, NamespaceIDMetadataKey)
+	return updater.UpdateMetadata(ctx, scopedID(namespace, id), patch)
+}
+
+// Health checks the wrapped store.
+func (n *NamespacedStore) Health(ctx context.Context) error {
+	return n.store.Health(ctx)
+}
+
+// Close closes the wrapped store.
+func (n *NamespacedStore) Close() error {
+	return n.store.Close()
+}
+
+// SupportsAutoEmbedding reports whether the wrapped store embeds documents itself.
+func (n *NamespacedStore) SupportsAutoEmbedding() bool {
+	auto, ok := n.store.(AutoEmbeddingCapable)
+	return ok && auto.SupportsAutoEmbedding()
+}
+
+// GetEmbeddingConfig returns the wrapped store's auto-embedding configuration.
+func (n *NamespacedStore) GetEmbeddingConfig() EmbeddingConfig {
+	if auto, ok := n.store.(AutoEmbeddingCapable); ok {
+		return auto.GetEmbeddingConfig()
+	}
+	return EmbeddingConfig{}
+}
+
+func (n *NamespacedStore) resolve(ctx context.Context) (string, error) {
+	namespace := n.namespace
+	if namespace == "" {
+		namespace = NamespaceFrom(ctx)
+	}
+	if namespace == "" {
+		return "", calque.NewErr(ctx, "retrieval namespace is required - use retrieval.WithNamespace")
+	}
+	return namespace, nil
+}
+
+// namespacedEmbeddingStore forwards EmbeddingCapable for stores that have it
+type namespacedEmbeddingStore struct {
+	*NamespacedStore
+}
+
+// GetEmbedding generates embeddings with the wrapped store.
+func (n *namespacedEmbeddingStore) GetEmbedding(ctx context.Context, text string) (EmbeddingVector, error) {
+	return n.store.(EmbeddingCapable).GetEmbedding(ctx, text)
+}
+
+// scopedID derives the ID a document is stored under within a namespace
+func scopedID(namespace, id string) string {
+	if _, err := uuid.Parse(id); err == nil {
+		return uuid.NewSHA1(uuid.NameSpaceOID, []byte(namespace+"/"+id)).String()
+	}
+	return namespace + "/" + id
+}
+
+// unscopeDocument restores the caller's ID and metadata
+func unscopeDocument(doc Document, namespace string) Document {
+	if id, ok := doc.Metadata[NamespaceIDMetadataKey].(string); ok {
+		doc.ID = id
+	} else {
+		doc.ID = strings.TrimPrefix(doc.ID, namespace+"/")
+	}
+
+	metadata := make(map[string]any, len(doc.Metadata))
+	for key, value := range doc.Metadata {
+		if key != NamespaceMetadataKey && key != NamespaceIDMetadataKey {
+			metadata[key] = value
+		}
+	}
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+	doc.Metadata = metadata
+	return doc
+}
@@ -0,0 +1,187 @@
+package retrieval
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// memoryStore keeps documents by ID; Search returns every document matching the filter
+type memoryStore struct {
+	mockEmbeddingStore
+	docs          map[string]Document
+	ignoreFilters bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{docs: map[string]Document{}}
+}
+
+func (m *memoryStore) Store(_ context.Context, docs []Document) error {
+	for _, doc := range docs {
+		m.docs[doc.ID] = doc
+	}
+	return nil
+}
+
+func (m *memoryStore) Delete(_ context.Context, ids []string) error {
+	for _, id := range ids {
+		delete(m.docs, id)
+	}
+	return nil
+}
+
+func (m *memoryStore) Search(_ context.Context, query SearchQuery) (*SearchResult, error) {
+	var docs []Document
+	for _, id := range slices.Sorted(maps.Keys(m.docs)) {
+		doc := m.docs[id]
+		matches := true
+		for key, value := range query.Filter {
+			matches = matches && (m.ignoreFilters || doc.Metadata[key] == value)
+		}
+		if matches {
+			docs = append(docs, doc)
+		}
+	}
+	return &SearchResult{Documents: docs, Total: len(docs)}, nil
+}
+
+func (m *memoryStore) ListDocuments(_ context.Context, cursor string, limit int) ([]Document, string, error) {
+	ids := slices.Sorted(maps.Keys(m.docs))
+	start := sort.SearchStrings(ids, cursor)
+	if start < len(ids) && ids[start] == cursor {
+		start++
+	}
+	end := min(start+limit, len(ids))
+	var page []Document
+	for _, id := range ids[start:end] {
+		page = append(page, m.docs[id])
+	}
+	if end == len(ids) {
+		return page, "", nil
+	}
+	return page, ids[end-1], nil
+}
+
+func docIDs(docs []Document) string {
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestNamespacedIsolation(t *testing.T) {
+	ctx := context.Background()
+	shared := newMemoryStore()
+	acme, globex := Namespaced(shared, "acme"), Namespaced(shared, "globex")
+
+	if err := acme.Store(ctx, []Document{{ID: "1", Content: "acme pricing", Metadata: map[string]any{"kind": "faq"}}, {ID: "2", Content: "acme roadmap"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := globex.Store(ctx, []Document{{ID: "1", Content: "globex pricing", Metadata: map[string]any{"kind": "faq"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(shared.docs) != 3 {
+		t.Fatalf("shared store has %d documents, want 3 (same IDs must not collide)", len(shared.docs))
+	}
+
+	result, err := acme.Search(ctx, SearchQuery{Text: "pricing", Filter: map[string]any{"kind": "faq"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Documents) != 1 || result.Documents[0].Content != "acme pricing" || result.Documents[0].ID != "1" {
+		t.Errorf("acme search = %+v", result.Documents)
+	}
+	if !maps.Equal(result.Documents[0].Metadata, map[string]any{"kind": "faq"}) {
+		t.Errorf("metadata = %v, want namespace keys removed", result.Documents[0].Metadata)
+	}
+
+	if err := globex.Delete(ctx, []string{"1", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	result, _ = acme.Search(ctx, SearchQuery{})
+	if docIDs(result.Documents) != "1,2" {
+		t.Errorf("acme documents after globex delete = %s, want 1,2", docIDs(result.Documents))
+	}
+	result, _ = globex.Search(ctx, SearchQuery{})
+	if len(result.Documents) != 0 {
+		t.Errorf("globex documents after delete = %+v", result.Documents)
+	}
+}
+
+func TestNamespacedFromContext(t *testing.T) {
+	shared := newMemoryStore()
+	tenants := Namespaced(shared, "")
+
+	if err := tenants.Store(context.Background(), []Document{{ID: "1"}}); err == nil || !strings.Contains(err.Error(), "namespace is required") {
+		t.Errorf("Store() without namespace error = %v", err)
+	}
+
+	acmeCtx := WithNamespace(context.Background(), "acme")
+	_ = tenants.Store(acmeCtx, []Document{{ID: "1", Content: "a"}})
+	_ = tenants.Store(WithNamespace(context.Background(), "globex"), []Document{{ID: "1", Content: "g"}})
+
+	result, err := tenants.Search(acmeCtx, SearchQuery{})
+	if err != nil || len(result.Documents) != 1 || result.Documents[0].Content != "a" {
+		t.Errorf("Search() = %+v, %v", result, err)
+	}
+}
+
+func TestNamespacedIgnoredFilter(t *testing.T) {
+	ctx := context.Background()
+	shared := newMemoryStore()
+	shared.ignoreFilters = true
+	_ = Namespaced(shared, "acme").Store(ctx, []Document{{ID: "1"}})
+	_ = Namespaced(shared, "globex").Store(ctx, []Document{{ID: "1"}, {ID: "2"}})
+
+	result, err := Namespaced(shared, "acme").Search(ctx, SearchQuery{})
+	if err != nil || docIDs(result.Documents) != "1" || result.Total != 1 {
+		t.Errorf("Search() on a store ignoring filters = %+v, %v; want only acme's document", result, err)
+	}
+}
+
+func TestNamespacedCapabilities(t *testing.T) {
+	if _, ok := Namespaced(newMemoryStore(), "a").(EmbeddingCapable); !ok {
+		t.Error("embedding capability should be forwarded")
+	}
+	if _, ok := Namespaced(&mockVectorStore{}, "a").(EmbeddingCapable); ok {
+		t.Error("stores without embeddings must not gain the capability")
+	}
+	if !Namespaced(&mockAutoEmbeddingStore{supportsAuto: true}, "a").(AutoEmbeddingCapable).SupportsAutoEmbedding() {
+		t.Error("auto-embedding should be forwarded")
+	}
+}
+
+func TestNamespacedReindex(t *testing.T) {
+	ctx := context.Background()
+	shared := newMemoryStore()
+	_ = Namespaced(shared, "acme").Store(ctx, []Document{{ID: "a1"}, {ID: "a2"}})
+	_ = Namespaced(shared, "globex").Store(ctx, []Document{{ID: "g1"}, {ID: "g2"}, {ID: "g3"}})
+
+	dst := newMemoryStore()
+	stats, err := Reindex(ctx, Namespaced(shared, "globex"), dst, &ReindexOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Reindex() error = %v", err)
+	}
+	if stats.Copied != 3 || len(dst.docs) != 3 {
+		t.Errorf("copied %d documents (%v), want globex's 3", stats.Copied, slices.Collect(maps.Keys(dst.docs)))
+	}
+}
+
+func TestScopedID(t *testing.T) {
+	if got := scopedID("acme", "doc-1"); got != "acme/doc-1" {
+		t.Errorf("scopedID() = %q", got)
+	}
+
+	id := uuid.NewString()
+	a, b := scopedID("acme", id), scopedID("globex", id)
+	if _, err := uuid.Parse(a); err != nil || a == b || a != scopedID("acme", id) {
+		t.Errorf("UUID scoping = %q, %q; want distinct, stable UUIDs", a, b)
+	}
+}
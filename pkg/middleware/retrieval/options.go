@@ -1,6 +1,9 @@
 package retrieval
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // SearchOptions configures vector search behavior and optional context building.
 type SearchOptions struct {
@@ -23,6 +26,9 @@ type SearchOptions struct {
 	// Reranking options (for StrategyRelevant with native support)
 	RerankMultiplier *float64 `json:"rerank_multiplier,omitempty"` // Multiplier for rerank candidates (default: 2.0)
 
+	// Recency options (for StrategyRelevantRecent)
+	RecencyHalfLife *time.Duration `json:"recency_half_life,omitempty"` // Age at which a document's score is halved (default: 7 days)
+
 	// Context building options (optional)
 	Strategy  *ContextStrategy `json:"strategy,omitempty"`   // If set, returns formatted context instead of JSON
 	MaxTokens int              `json:"max_tokens,omitempty"` // Token limit for context
@@ -67,6 +73,9 @@ const (
 	DefaultSeparator            = "\n\n---\n\n" // Document separator
 )
 
+// DefaultRecencyHalfLife halves the score of a week-old document under StrategyRelevantRecent
+const DefaultRecencyHalfLife = 7 * 24 * time.Hour
+
 // GetDiversityLambda returns the configured diversity lambda or default
 func (opts *SearchOptions) GetDiversityLambda() float64 {
 	if opts.DiversityLambda != nil {
@@ -107,6 +116,14 @@ func (opts *SearchOptions) GetRerankMultiplier() float64 {
 	return DefaultRerankMultiplier
 }
 
+// GetRecencyHalfLife returns the configured recency half-life or default
+func (opts *SearchOptions) GetRecencyHalfLife() time.Duration {
+	if opts.RecencyHalfLife != nil && *opts.RecencyHalfLife > 0 {
+		return *opts.RecencyHalfLife
+	}
+	return DefaultRecencyHalfLife
+}
+
 // GetSummaryWordLimit returns the configured summary word limit or default
 func (opts *SearchOptions) GetSummaryWordLimit() int {
 	if opts.SummaryWordLimit != nil {
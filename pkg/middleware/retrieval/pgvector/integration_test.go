@@ -727,6 +727,25 @@ func TestSearchOperations(t *testing.T) {
 				t.Logf("High threshold search returned %d results", len(result.Documents))
 			},
 		},
+		{
+			name: "search with metadata filter",
+			query: func() retrieval.SearchQuery {
+				vec, _ := embedProvider.Embed(ctx, "learning")
+				return retrieval.SearchQuery{
+					Text:      "learning",
+					Vector:    vec,
+					Limit:     10,
+					Threshold: -1,
+					Filter:    map[string]any{"category": "food"},
+				}
+			}(),
+			expectErr: false,
+			checkFn: func(t *testing.T, result *retrieval.SearchResult) {
+				if len(result.Documents) != 1 || result.Documents[0].Content != "cooking recipes" {
+					t.Errorf("Expected only the food document, got %+v", result.Documents)
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1315,3 +1334,64 @@ func TestSearchResultOrdering(t *testing.T) {
 		}
 	}
 }
+
+func TestGetByIDAndUpsert(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	pc, err := setupPGVectorContainer(ctx)
+	if err != nil {
+		t.Fatalf("Failed to setup PostgreSQL container: %v", err)
+	}
+	defer pc.teardown(ctx)
+
+	client, err := New(&Config{
+		ConnectionString:  pc.ConnStr,
+		TableName:         "upsert_test",
+		VectorDimension:   128,
+		EmbeddingProvider: newMockEmbeddingProvider(128),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	doc1ID, doc2ID := generateTestUUID("doc1"), generateTestUUID("doc2")
+	docs := []retrieval.Document{
+		{ID: doc1ID, Content: "Document 1", Metadata: map[string]any{"rev": 1, "draft": true}},
+		{ID: doc2ID, Content: "Document 2"},
+	}
+	result, err := retrieval.Upsert(ctx, client, docs, nil)
+	if err != nil || result.Inserted != 2 {
+		t.Fatalf("Upsert() = %+v, %v; want 2 inserted", result, err)
+	}
+
+	found, err := client.GetByID(ctx, doc1ID, generateTestUUID("missing"))
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if len(found) != 1 || found[0].Content != "Document 1" {
+		t.Errorf("GetByID() = %+v, want only doc1", found)
+	}
+
+	docs[0].Metadata = map[string]any{"rev": 2}
+	docs[1].Content = "Document 2, revised"
+	result, err = retrieval.Upsert(ctx, client, docs, nil)
+	if err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if *result != (retrieval.UpsertResult{Updated: 1, MetadataUpdated: 1}) {
+		t.Errorf("Upsert() = %+v, want one content and one metadata update", result)
+	}
+
+	found, _ = client.GetByID(ctx, doc1ID)
+	if _, ok := found[0].Metadata["draft"]; ok || found[0].Metadata["rev"] != float64(2) {
+		t.Errorf("metadata after update = %v", found[0].Metadata)
+	}
+
+	if err := client.UpdateMetadata(ctx, generateTestUUID("missing"), map[string]any{"a": 1}); err == nil {
+		t.Error("UpdateMetadata() on a missing document should fail")
+	}
+}
@@ -121,21 +121,34 @@ func (c *Client) Search(ctx context.Context, query retrieval.SearchQuery) (*retr
 
 	// Build SQL query with cosine similarity
 	// Use <=> for cosine distance, convert to similarity with 1 - distance
+	args := []any{
+		pgvector.NewVector(query.Vector), // $1
+		query.Threshold,                  // $2
+		query.Limit,                      // $3
+	}
+
+	// Metadata filters match by JSONB containment, i.e. equality on every key
+	filterSQL := ""
+	if len(query.Filter) > 0 {
+		filterJSON, err := json.Marshal(query.Filter)
+		if err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to marshal search filter")
+		}
+		filterSQL = "AND metadata @> $4::jsonb"
+		args = append(args, string(filterJSON))
+	}
+
 	querySQL := fmt.Sprintf(`
 		SELECT id, content, metadata, created_at, updated_at,
 		       1 - (embedding <=> $1) AS similarity
 		FROM %s
-		WHERE 1 - (embedding <=> $1) > $2
+		WHERE 1 - (embedding <=> $1) > $2 %s
 		ORDER BY embedding <=> $1
 		LIMIT $3`,
-		c.tableName)
+		c.tableName, filterSQL)
 
 	// Execute query with pgvector types
-	rows, err := c.conn.Query(ctx, querySQL,
-		pgvector.NewVector(query.Vector), // $1
-		query.Threshold,                  // $2
-		query.Limit,                      // $3
-	)
+	rows, err := c.conn.Query(ctx, querySQL, args...)
 	if err != nil {
 		return nil, calque.WrapErr(ctx, err, "pgvector search failed")
 	}
@@ -203,21 +216,30 @@ func (c *Client) Store(ctx context.Context, documents []retrieval.Document) erro
 		return calque.NewErr(ctx, "no embedding provider configured - cannot generate vectors for document storage")
 	}
 
-	// Use batch for efficient bulk inserts
-	batch := &pgx.Batch{}
-
+	// Skip documents without content
+	withContent := make([]retrieval.Document, 0, len(documents))
+	texts := make([]string, 0, len(documents))
 	for _, doc := range documents {
-		if doc.Content == "" {
-			continue // Skip documents without content
+		if doc.Content != "" {
+			withContent = append(withContent, doc)
+			texts = append(texts, doc.Content)
 		}
+	}
 
-		// Generate embedding for document content
-		embedding, err := c.embeddingProvider.Embed(ctx, doc.Content)
-		if err != nil {
-			return calque.WrapErr(ctx, err, fmt.Sprintf("failed to generate embedding for document %s", doc.ID))
-		}
+	// Generate embeddings in one call when the provider supports batching
+	embeddings, err := retrieval.EmbedAll(ctx, c.embeddingProvider, texts)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to generate embeddings for documents")
+	}
+
+	// Use batch for efficient bulk inserts
+	batch := &pgx.Batch{}
+
+	for i, doc := range withContent {
+		embedding := embeddings[i]
 
 		// Marshal metadata to JSONB
+		var err error
 		var metadataJSON []byte
 		if doc.Metadata != nil {
 			metadataJSON, err = json.Marshal(doc.Metadata)
@@ -287,6 +309,92 @@ func (c *Client) Delete(ctx context.Context, ids []string) error {
 	return nil
 }
 
+// GetByID returns the stored documents among ids.
+// This implements the DocumentGetter interface for PGVector.
+func (c *Client) GetByID(ctx context.Context, ids ...string) ([]retrieval.Document, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	getSQL := fmt.Sprintf(`
+		SELECT id, content, metadata, created_at, updated_at
+		FROM %s
+		WHERE id = ANY($1)`,
+		c.tableName)
+
+	rows, err := c.conn.Query(ctx, getSQL, ids)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to get documents")
+	}
+	return scanDocuments(ctx, rows, len(ids))
+}
+
+// UpdateMetadata merges patch into a document's metadata without re-embedding it.
+// This implements the MetadataUpdater interface for PGVector.
+// Null values remove keys, including nulls nested inside the metadata.
+func (c *Client) UpdateMetadata(ctx context.Context, id string, patch map[string]any) error {
+	patchJSON, err := json.Marshal(patch)
+	if err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to marshal metadata for document %s", id))
+	}
+
+	updateSQL := fmt.Sprintf(`
+		UPDATE %s
+		SET metadata = jsonb_strip_nulls(COALESCE(metadata, '{}'::jsonb) || $1::jsonb),
+		    updated_at = NOW()
+		WHERE id = $2`,
+		c.tableName)
+
+	tag, err := c.conn.Exec(ctx, updateSQL, string(patchJSON), id)
+	if err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to update metadata for document %s", id))
+	}
+	if tag.RowsAffected() == 0 {
+		return calque.NewErr(ctx, fmt.Sprintf("document %s not found", id))
+	}
+	return nil
+}
+
+// ListDocuments pages through stored documents in ID order.
+// This implements the DocumentLister interface for PGVector.
+func (c *Client) ListDocuments(ctx context.Context, cursor string, limit int) ([]retrieval.Document, string, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	listSQL := fmt.Sprintf(`
+		SELECT id, content, metadata, created_at, updated_at
+		FROM %s
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2`,
+		c.tableName)
+
+	rows, err := c.conn.Query(ctx, listSQL, cursor, limit)
+	if err != nil {
+		return nil, "", calque.WrapErr(ctx, err, "failed to list documents")
+	}
+	documents, err := scanDocuments(ctx, rows, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(documents) < limit {
+		return documents, "", nil
+	}
+	return documents, documents[len(documents)-1].ID, nil
+}
+
+// CountDocuments returns the number of stored documents.
+// This implements the DocumentCounter interface for PGVector.
+func (c *Client) CountDocuments(ctx context.Context) (int, error) {
+	var count int
+	if err := c.conn.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", c.tableName)).Scan(&count); err != nil {
+		return 0, calque.WrapErr(ctx, err, "failed to count documents")
+	}
+	return count, nil
+}
+
 // GetEmbedding generates embeddings for text content using the configured external service.
 // This implements the EmbeddingCapable interface for PGVector.
 // Note: PGVector doesn't generate embeddings internally, so this delegates to the configured provider.
@@ -346,6 +454,30 @@ func (c *Client) Close() error {
 	return nil
 }
 
+// scanDocuments reads id, content, metadata, created_at and updated_at rows and closes them
+func scanDocuments(ctx context.Context, rows pgx.Rows, capacity int) ([]retrieval.Document, error) {
+	defer rows.Close()
+
+	documents := make([]retrieval.Document, 0, capacity)
+	for rows.Next() {
+		var doc retrieval.Document
+		var metadataJSON []byte
+		if err := rows.Scan(&doc.ID, &doc.Content, &metadataJSON, &doc.Created, &doc.Updated); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to scan row")
+		}
+		if len(metadataJSON) > 0 {
+			if err := json.Unmarshal(metadataJSON, &doc.Metadata); err != nil {
+				return nil, calque.WrapErr(ctx, err, "failed to parse metadata")
+			}
+		}
+		documents = append(documents, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, calque.WrapErr(ctx, err, "error iterating rows")
+	}
+	return documents, nil
+}
+
 // ensureTableExists checks if table exists and creates it if needed.
 // Called lazily from Store() to support both read-only and write use cases.
 // Thread-safe: uses mutex to prevent concurrent table creation.
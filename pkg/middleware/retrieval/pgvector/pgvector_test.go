@@ -2,12 +2,20 @@ package pgvector
 
 import (
 	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/middleware/retrieval"
 )
 
 const (
 	defaultTableName = "documents"
 )
 
+var (
+	_ retrieval.DocumentLister  = (*Client)(nil)
+	_ retrieval.DocumentGetter  = (*Client)(nil)
+	_ retrieval.MetadataUpdater = (*Client)(nil)
+)
+
 //nolint:gocyclo // Table-driven test with many cases
 func TestConfigValidation(t *testing.T) {
 	t.Parallel()
@@ -441,27 +441,34 @@ func (c *Client) storeBatch(ctx context.Context, documents []retrieval.Document)
 	// Convert documents to Qdrant points
 	points := make([]*qd.PointStruct, 0, len(documents))
 
+	// Skip documents without content
+	withContent := make([]retrieval.Document, 0, len(documents))
+	texts := make([]string, 0, len(documents))
 	for _, doc := range documents {
-		if doc.Content == "" {
-			continue // Skip documents without content
+		if doc.Content != "" {
+			withContent = append(withContent, doc)
+			texts = append(texts, doc.Content)
 		}
+	}
+	if len(withContent) == 0 {
+		return nil
+	}
 
+	if c.embeddingProvider == nil {
+		// No embedding provider configured - return error
+		return calque.NewErr(ctx, "no embedding provider configured - cannot generate vectors for document storage")
+	}
+
+	// Generate embeddings in one call when the provider supports batching
+	embeddings, err := retrieval.EmbedAll(ctx, c.embeddingProvider, texts)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to generate embeddings for documents")
+	}
+
+	for i, doc := range withContent {
 		// Create point ID - use document ID if available, otherwise generate UUID
 		pointID := c.createPointID(doc.ID)
-
-		// Generate embedding for document content using configured provider
-		var vectorData []float32
-		if c.embeddingProvider != nil {
-			// Use the configured embedding provider to generate vector
-			embedding, err := c.embeddingProvider.Embed(ctx, doc.Content)
-			if err != nil {
-				return calque.WrapErr(ctx, err, fmt.Sprintf("failed to generate embedding for document %s", doc.ID))
-			}
-			vectorData = []float32(embedding)
-		} else {
-			// No embedding provider configured - return error
-			return calque.NewErr(ctx, "no embedding provider configured - cannot generate vectors for document storage")
-		}
+		vectorData := []float32(embeddings[i])
 
 		// Create vector data
 		vectors := &qd.Vectors{
@@ -490,7 +497,7 @@ func (c *Client) storeBatch(ctx context.Context, documents []retrieval.Document)
 
 	// Create upsert request using the correct Qdrant Go client API
 	waitForResult := true
-	_, err := c.client.Upsert(ctx, &qd.UpsertPoints{
+	_, err = c.client.Upsert(ctx, &qd.UpsertPoints{
 		CollectionName: c.collectionName,
 		Points:         points,
 		Wait:           &waitForResult,
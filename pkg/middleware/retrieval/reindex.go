@@ -0,0 +1,156 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// ReindexOptions configures a Reindex run.
+type ReindexOptions struct {
+	// Documents read and stored per batch (default: 100)
+	BatchSize int
+
+	// Resume after this cursor, typically the last one passed to Checkpoint
+	Cursor string
+
+	// Called after each batch is stored with the cursor to resume from.
+	// Returning an error stops the run.
+	Checkpoint func(ctx context.Context, cursor string) error
+
+	// Maximum documents stored per second (default: unlimited)
+	DocumentsPerSecond float64
+
+	// Called after each batch with the running totals
+	OnProgress func(ReindexStats)
+}
+
+// ReindexStats reports how far a Reindex run got.
+type ReindexStats struct {
+	Copied  int           `json:"copied"`          // Documents stored in the destination
+	Batches int           `json:"batches"`         // Batches stored
+	Total   int           `json:"total,omitempty"` // Documents in the source, when it can count them
+	Cursor  string        `json:"cursor"`          // Resume point after the last stored batch
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// Reindex streams every document from src into dst.
+//
+// Input: src implementing DocumentLister, destination store
+// Output: running totals, including the cursor to resume from on failure
+// Behavior: BATCHED - reads and stores BatchSize documents at a time
+//
+// Used for embedding-model upgrades and store migrations. Documents carry
+// content and metadata but not vectors, so dst embeds them with its own
+// provider: point dst at a store configured with the new model to re-embed,
+// or at a different backend to migrate. Progress is reported through
+// calque.Progress under the "reindex" stage and through OnProgress.
+//
+// Example:
+//
+//	upgraded, _ := sqlitevec.New(&sqlitevec.Config{DB: db, TableName: "docs_v2", EmbeddingProvider: newModel})
+//	cp, _ := checkpoints.Latest(ctx, "reindex-v2")
+//	stats, err := retrieval.Reindex(ctx, current, upgraded, &retrieval.ReindexOptions{
+//	    Cursor:             string(cp.State),
+//	    DocumentsPerSecond: 50,
+//	    Checkpoint: func(ctx context.Context, cursor string) error {
+//	        _, err := checkpoints.Save(ctx, "reindex-v2", "batch", []byte(cursor))
+//	        return err
+//	    },
+//	})
+func Reindex(ctx context.Context, src, dst VectorStore, opts *ReindexOptions) (*ReindexStats, error) {
+	if opts == nil {
+		opts = &ReindexOptions{}
+	}
+	stats := &ReindexStats{Cursor: opts.Cursor}
+
+	lister, ok := src.(DocumentLister)
+	if !ok {
+		return stats, calque.NewErr(ctx, fmt.Sprintf("source store %T cannot list documents", src))
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	// Totals only drive progress percentages, so a failed count is not fatal
+	if counter, ok := src.(DocumentCounter); ok {
+		stats.Total, _ = counter.CountDocuments(ctx)
+	}
+
+	start := time.Now()
+	for {
+		docs, next, err := lister.ListDocuments(ctx, stats.Cursor, batchSize)
+		if err != nil {
+			return stats, calque.WrapErr(ctx, err, fmt.Sprintf("failed to list documents after cursor %q", stats.Cursor))
+		}
+		if len(docs) == 0 && next != "" {
+			// Filtered listings can return empty pages before the end
+			stats.Cursor = next
+			continue
+		}
+		if len(docs) > 0 {
+			for i := range docs {
+				docs[i].Score = 0
+			}
+			if err := dst.Store(ctx, docs); err != nil {
+				return stats, calque.WrapErr(ctx, err, fmt.Sprintf("failed to store batch after cursor %q", stats.Cursor))
+			}
+
+			stats.Copied += len(docs)
+			stats.Batches++
+			stats.Cursor = docs[len(docs)-1].ID
+			if next != "" {
+				stats.Cursor = next
+			}
+			if opts.Checkpoint != nil {
+				if err := opts.Checkpoint(ctx, stats.Cursor); err != nil {
+					return stats, calque.WrapErr(ctx, err, "failed to save reindex checkpoint")
+				}
+			}
+			stats.Elapsed = time.Since(start)
+			reportReindex(ctx, opts, *stats)
+		}
+
+		if next == "" {
+			stats.Elapsed = time.Since(start)
+			return stats, nil
+		}
+		if err := paceReindex(ctx, start, stats.Copied, opts.DocumentsPerSecond); err != nil {
+			return stats, err
+		}
+	}
+}
+
+func reportReindex(ctx context.Context, opts *ReindexOptions, stats ReindexStats) {
+	if opts.OnProgress != nil {
+		opts.OnProgress(stats)
+	}
+	percent := -1.0
+	message := fmt.Sprintf("reindexed %d documents", stats.Copied)
+	if stats.Total > 0 {
+		percent = float64(stats.Copied) / float64(stats.Total) * 100
+		message = fmt.Sprintf("reindexed %d/%d documents", stats.Copied, stats.Total)
+	}
+	calque.Progress(ctx, "reindex", percent, message)
+}
+
+// paceReindex waits until copying n documents since start stays within the rate
+func paceReindex(ctx context.Context, start time.Time, n int, perSecond float64) error {
+	if perSecond <= 0 {
+		return nil
+	}
+	wait := time.Until(start.Add(time.Duration(float64(n) / perSecond * float64(time.Second))))
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
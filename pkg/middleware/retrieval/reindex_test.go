@@ -0,0 +1,162 @@
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// listingStore keeps documents in ID order and pages through them
+type listingStore struct {
+	mockVectorStore
+	docs     []Document
+	stored   []Document
+	storeErr error
+}
+
+func newListingStore(ids ...string) *listingStore {
+	s := &listingStore{}
+	for _, id := range ids {
+		s.docs = append(s.docs, Document{ID: id, Content: "doc " + id, Score: 0.5})
+	}
+	return s
+}
+
+func (s *listingStore) ListDocuments(_ context.Context, cursor string, limit int) ([]Document, string, error) {
+	start := 0
+	for start < len(s.docs) && s.docs[start].ID <= cursor {
+		start++
+	}
+	end := min(start+limit, len(s.docs))
+	page := slices.Clone(s.docs[start:end])
+	if end == len(s.docs) {
+		return page, "", nil
+	}
+	return page, page[len(page)-1].ID, nil
+}
+
+func (s *listingStore) CountDocuments(_ context.Context) (int, error) {
+	return len(s.docs), nil
+}
+
+func (s *listingStore) Store(_ context.Context, docs []Document) error {
+	if s.storeErr != nil {
+		return s.storeErr
+	}
+	s.stored = append(s.stored, docs...)
+	return nil
+}
+
+func storedIDs(s *listingStore) string {
+	ids := make([]string, len(s.stored))
+	for i, doc := range s.stored {
+		ids[i] = doc.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestReindex(t *testing.T) {
+	src, dst := newListingStore("a", "b", "c", "d", "e"), newListingStore()
+
+	var mu sync.Mutex
+	var percents []float64
+	ctx := calque.WithProgress(context.Background(), func(ev calque.ProgressEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		percents = append(percents, ev.Percent)
+	})
+
+	var checkpoints []string
+	var progress []ReindexStats
+	stats, err := Reindex(ctx, src, dst, &ReindexOptions{
+		BatchSize: 2,
+		Checkpoint: func(_ context.Context, cursor string) error {
+			checkpoints = append(checkpoints, cursor)
+			return nil
+		},
+		OnProgress: func(s ReindexStats) { progress = append(progress, s) },
+	})
+	if err != nil {
+		t.Fatalf("Reindex() error = %v", err)
+	}
+
+	if got := storedIDs(dst); got != "a,b,c,d,e" {
+		t.Errorf("stored = %s, want a,b,c,d,e", got)
+	}
+	if dst.stored[0].Score != 0 {
+		t.Errorf("stored score = %v, want search scores cleared", dst.stored[0].Score)
+	}
+	if stats.Copied != 5 || stats.Batches != 3 || stats.Total != 5 || stats.Cursor != "e" {
+		t.Errorf("stats = %+v", stats)
+	}
+	if strings.Join(checkpoints, ",") != "b,d,e" {
+		t.Errorf("checkpoints = %v, want b,d,e", checkpoints)
+	}
+	if len(progress) != 3 || progress[2].Copied != 5 {
+		t.Errorf("OnProgress calls = %+v", progress)
+	}
+	if !slices.Equal(percents, []float64{40, 80, 100}) {
+		t.Errorf("progress percents = %v, want [40 80 100]", percents)
+	}
+}
+
+func TestReindexResume(t *testing.T) {
+	src, dst := newListingStore("a", "b", "c", "d"), newListingStore()
+
+	stats, err := Reindex(context.Background(), src, dst, &ReindexOptions{BatchSize: 10, Cursor: "b"})
+	if err != nil {
+		t.Fatalf("Reindex() error = %v", err)
+	}
+	if got := storedIDs(dst); got != "c,d" || stats.Copied != 2 {
+		t.Errorf("stored = %s (copied %d), want c,d", got, stats.Copied)
+	}
+}
+
+func TestReindexErrors(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := Reindex(ctx, &mockVectorStore{}, newListingStore(), nil); err == nil || !strings.Contains(err.Error(), "cannot list documents") {
+		t.Errorf("non-listing source error = %v", err)
+	}
+
+	dst := newListingStore()
+	dst.storeErr = errors.New("disk full")
+	stats, err := Reindex(ctx, newListingStore("a", "b"), dst, &ReindexOptions{Cursor: "a"})
+	if err == nil || !strings.Contains(err.Error(), "disk full") || stats.Cursor != "a" {
+		t.Errorf("store failure = %v (cursor %q), want error keeping resume cursor a", err, stats.Cursor)
+	}
+
+	stats, err = Reindex(ctx, newListingStore("a", "b", "c"), newListingStore(), &ReindexOptions{
+		BatchSize:  1,
+		Checkpoint: func(context.Context, string) error { return errors.New("checkpoint store down") },
+	})
+	if err == nil || !strings.Contains(err.Error(), "checkpoint") || stats.Copied != 1 {
+		t.Errorf("checkpoint failure = %v after %d documents, want stop after first batch", err, stats.Copied)
+	}
+}
+
+func TestReindexRateLimit(t *testing.T) {
+	src, dst := newListingStore("a", "b", "c", "d"), newListingStore()
+
+	start := time.Now()
+	if _, err := Reindex(context.Background(), src, dst, &ReindexOptions{BatchSize: 1, DocumentsPerSecond: 100}); err != nil {
+		t.Fatalf("Reindex() error = %v", err)
+	}
+	// Waits after the first three batches: 30ms at 100 documents per second
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Errorf("Reindex() took %v, want it paced to about 30ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Reindex(ctx, newListingStore("a", "b"), newListingStore(), &ReindexOptions{BatchSize: 1, DocumentsPerSecond: 0.01})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled pacing error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,556 @@
+// Package sqlitevec provides a SQLite vector store for embedded retrieval.
+//
+// This package implements the retrieval.VectorStore interface on a plain SQLite table,
+// so single-binary deployments can search documents without an external database.
+// Embeddings are stored as little-endian float32 BLOBs, the format used by the
+// sqlite-vec extension. When sqlite-vec is loaded into the connection, similarity is
+// computed in SQL with vec_distance_cosine; otherwise rows are scored in Go, which
+// is suited to collections of up to a few hundred thousand documents.
+package sqlitevec
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/memory/sqlitestore"
+	"github.com/calque-ai/go-calque/pkg/middleware/retrieval"
+)
+
+// Defaults for table naming and search
+const (
+	DefaultTableName = "calque_documents"
+	DefaultLimit     = 10
+)
+
+// Client represents a SQLite vector store.
+//
+// Implements the retrieval.VectorStore interface and shares its database with the
+// sqlitestore memory, cache and checkpoint stores when given the same handle.
+type Client struct {
+	db                *sql.DB
+	owned             bool
+	tableName         string
+	vectorDimension   int
+	embeddingProvider retrieval.EmbeddingProvider
+	native            bool // sqlite-vec functions are available
+	mu                sync.RWMutex
+}
+
+// Config holds SQLite vector store configuration.
+type Config struct {
+	// Shared database handle, typically from sqlitestore.Open. Takes precedence over Path.
+	DB *sql.DB
+
+	// Database file path, opened with sqlitestore.Open when DB is nil
+	Path string
+
+	// Table name for storing documents and vectors (default: calque_documents)
+	TableName string
+
+	// Vector dimension; when set, vectors of other lengths are rejected
+	VectorDimension int
+
+	// Embedding provider used to generate vectors when storing documents
+	EmbeddingProvider retrieval.EmbeddingProvider
+}
+
+// New creates a SQLite vector store, creating its table if needed.
+//
+// Example:
+//
+//	db, _ := sqlitestore.Open("calque.db")
+//	client, err := sqlitevec.New(&sqlitevec.Config{
+//	    DB:                db,
+//	    VectorDimension:   1536,
+//	    EmbeddingProvider: openaiProvider,
+//	})
+func New(config *Config) (*Client, error) {
+	ctx := context.Background()
+	if config == nil || (config.DB == nil && config.Path == "") {
+		return nil, calque.NewErr(ctx, "SQLite DB or Path is required")
+	}
+
+	client := &Client{
+		db:                config.DB,
+		tableName:         config.TableName,
+		vectorDimension:   config.VectorDimension,
+		embeddingProvider: config.EmbeddingProvider,
+	}
+	if client.tableName == "" {
+		client.tableName = DefaultTableName
+	}
+	if !sqlitestore.ValidIdentifier(client.tableName) {
+		return nil, calque.NewErr(ctx, fmt.Sprintf("invalid SQLite table name %q", client.tableName))
+	}
+
+	if client.db == nil {
+		db, err := sqlitestore.Open(config.Path)
+		if err != nil {
+			return nil, err
+		}
+		client.db, client.owned = db, true
+	}
+
+	_, err := client.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id         TEXT PRIMARY KEY,
+			content    TEXT NOT NULL,
+			metadata   TEXT,
+			embedding  BLOB NOT NULL,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL
+		)`, client.tableName))
+	if err != nil {
+		client.Close()
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to create table %s", client.tableName))
+	}
+
+	// sqlite-vec is optional; without it search falls back to scoring in Go
+	var version string
+	client.native = client.db.QueryRowContext(ctx, "SELECT vec_version()").Scan(&version) == nil
+
+	return client, nil
+}
+
+// Search performs cosine similarity search over stored embeddings.
+//
+// Filter entries match metadata fields by equality.
+func (c *Client) Search(ctx context.Context, query retrieval.SearchQuery) (*retrieval.SearchResult, error) {
+	if len(query.Vector) == 0 {
+		return nil, calque.NewErr(ctx, "query.Vector is required for sqlitevec search")
+	}
+	if err := c.checkDimension(ctx, query.Vector); err != nil {
+		return nil, err
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	where, args := filterClause(query.Filter)
+
+	var documents []retrieval.Document
+	var err error
+	if c.native {
+		documents, err = c.searchNative(ctx, query, where, args, limit)
+	} else {
+		documents, err = c.searchScan(ctx, query, where, args, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &retrieval.SearchResult{
+		Documents: documents,
+		Query:     query.Text,
+		Total:     len(documents),
+		Threshold: query.Threshold,
+	}, nil
+}
+
+// searchNative ranks rows in SQL using sqlite-vec
+func (c *Client) searchNative(ctx context.Context, query retrieval.SearchQuery, where string, args []any, limit int) ([]retrieval.Document, error) {
+	querySQL := fmt.Sprintf(`
+		SELECT id, content, metadata, created_at, updated_at, score FROM (
+			SELECT *, 1 - vec_distance_cosine(embedding, ?) AS score FROM %s %s
+		) WHERE score > ? ORDER BY score DESC LIMIT ?`,
+		c.tableName, where)
+
+	params := append([]any{encodeVector(query.Vector)}, args...)
+	params = append(params, query.Threshold, limit)
+
+	rows, err := c.db.QueryContext(ctx, querySQL, params...)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "sqlitevec search failed")
+	}
+	defer rows.Close()
+
+	documents := make([]retrieval.Document, 0, limit)
+	for rows.Next() {
+		var doc retrieval.Document
+		var metadata sql.NullString
+		var created, updated int64
+		if err := rows.Scan(&doc.ID, &doc.Content, &metadata, &created, &updated, &doc.Score); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to scan row")
+		}
+		if err := finishDocument(&doc, metadata, created, updated); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to parse metadata")
+		}
+		documents = append(documents, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, calque.WrapErr(ctx, err, "error iterating rows")
+	}
+	return documents, nil
+}
+
+// searchScan scores every matching row in Go
+func (c *Client) searchScan(ctx context.Context, query retrieval.SearchQuery, where string, args []any, limit int) ([]retrieval.Document, error) {
+	rows, err := c.db.QueryContext(ctx,
+		fmt.Sprintf("SELECT id, content, metadata, embedding, created_at, updated_at FROM %s %s", c.tableName, where),
+		args...)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "sqlitevec search failed")
+	}
+	defer rows.Close()
+
+	var documents []retrieval.Document
+	for rows.Next() {
+		var doc retrieval.Document
+		var metadata sql.NullString
+		var embedding []byte
+		var created, updated int64
+		if err := rows.Scan(&doc.ID, &doc.Content, &metadata, &embedding, &created, &updated); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to scan row")
+		}
+
+		doc.Score = cosineSimilarity(query.Vector, decodeVector(embedding))
+		if doc.Score <= query.Threshold {
+			continue
+		}
+		if err := finishDocument(&doc, metadata, created, updated); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to parse metadata")
+		}
+		documents = append(documents, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, calque.WrapErr(ctx, err, "error iterating rows")
+	}
+
+	sort.SliceStable(documents, func(i, j int) bool { return documents[i].Score > documents[j].Score })
+	if len(documents) > limit {
+		documents = documents[:limit]
+	}
+	return documents, nil
+}
+
+// Store embeds and upserts documents in a single transaction.
+func (c *Client) Store(ctx context.Context, documents []retrieval.Document) error {
+	if len(documents) == 0 {
+		return nil // Nothing to store
+	}
+
+	provider := c.GetEmbeddingProvider()
+	if provider == nil {
+		return calque.NewErr(ctx, "no embedding provider configured - cannot generate vectors for document storage")
+	}
+
+	// Embed before opening the transaction so slow providers don't hold the write lock
+	withContent := make([]retrieval.Document, 0, len(documents))
+	texts := make([]string, 0, len(documents))
+	for _, doc := range documents {
+		if doc.Content != "" {
+			withContent = append(withContent, doc)
+			texts = append(texts, doc.Content)
+		}
+	}
+	embeddings, err := retrieval.EmbedAll(ctx, provider, texts)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to generate embeddings for documents")
+	}
+
+	type row struct {
+		doc       retrieval.Document
+		metadata  any
+		embedding []byte
+	}
+	rows := make([]row, 0, len(withContent))
+	now := time.Now()
+	for i, doc := range withContent {
+		if err := c.checkDimension(ctx, embeddings[i]); err != nil {
+			return err
+		}
+
+		r := row{doc: doc, embedding: encodeVector(embeddings[i])}
+		if doc.Metadata != nil {
+			data, err := json.Marshal(doc.Metadata)
+			if err != nil {
+				return calque.WrapErr(ctx, err, fmt.Sprintf("failed to marshal metadata for document %s", doc.ID))
+			}
+			r.metadata = string(data)
+		}
+		if r.doc.Created.IsZero() {
+			r.doc.Created = now
+		}
+		if r.doc.Updated.IsZero() {
+			r.doc.Updated = now
+		}
+		rows = append(rows, r)
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	upsertSQL := fmt.Sprintf(`
+		INSERT INTO %s (id, content, metadata, embedding, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			content = excluded.content,
+			metadata = excluded.metadata,
+			embedding = excluded.embedding,
+			updated_at = excluded.updated_at`,
+		c.tableName)
+
+	for _, r := range rows {
+		_, err := tx.ExecContext(ctx, upsertSQL,
+			r.doc.ID, r.doc.Content, r.metadata, r.embedding,
+			r.doc.Created.UnixMilli(), r.doc.Updated.UnixMilli())
+		if err != nil {
+			return calque.WrapErr(ctx, err, fmt.Sprintf("failed to store document %s", r.doc.ID))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return calque.WrapErr(ctx, err, "failed to commit documents")
+	}
+	return nil
+}
+
+// Delete removes documents from the table.
+func (c *Client) Delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil // Nothing to delete
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+
+	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE id IN (%s)", c.tableName, placeholders)
+	if _, err := c.db.ExecContext(ctx, deleteSQL, args...); err != nil {
+		return calque.WrapErr(ctx, err, "failed to delete documents")
+	}
+	return nil
+}
+
+// GetByID returns the stored documents among ids.
+// This implements the DocumentGetter interface.
+func (c *Client) GetByID(ctx context.Context, ids ...string) ([]retrieval.Document, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+
+	rows, err := c.db.QueryContext(ctx,
+		fmt.Sprintf("SELECT id, content, metadata, created_at, updated_at FROM %s WHERE id IN (%s)", c.tableName, placeholders),
+		args...)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to get documents")
+	}
+	return scanDocuments(ctx, rows, len(ids))
+}
+
+// UpdateMetadata merges patch into a document's metadata without re-embedding it.
+// This implements the MetadataUpdater interface.
+func (c *Client) UpdateMetadata(ctx context.Context, id string, patch map[string]any) error {
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to marshal metadata for document %s", id))
+	}
+
+	// json_patch follows RFC 7396, so null values remove keys
+	res, err := c.db.ExecContext(ctx,
+		fmt.Sprintf("UPDATE %s SET metadata = json_patch(COALESCE(metadata, '{}'), ?), updated_at = ? WHERE id = ?", c.tableName),
+		string(data), time.Now().UnixMilli(), id)
+	if err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to update metadata for document %s", id))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return calque.NewErr(ctx, fmt.Sprintf("document %s not found", id))
+	}
+	return nil
+}
+
+// ListDocuments pages through stored documents in ID order.
+// This implements the DocumentLister interface.
+func (c *Client) ListDocuments(ctx context.Context, cursor string, limit int) ([]retrieval.Document, string, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	rows, err := c.db.QueryContext(ctx,
+		fmt.Sprintf("SELECT id, content, metadata, created_at, updated_at FROM %s WHERE id > ? ORDER BY id LIMIT ?", c.tableName),
+		cursor, limit)
+	if err != nil {
+		return nil, "", calque.WrapErr(ctx, err, "failed to list documents")
+	}
+	documents, err := scanDocuments(ctx, rows, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(documents) < limit {
+		return documents, "", nil
+	}
+	return documents, documents[len(documents)-1].ID, nil
+}
+
+// CountDocuments returns the number of stored documents.
+// This implements the DocumentCounter interface.
+func (c *Client) CountDocuments(ctx context.Context) (int, error) {
+	var count int
+	if err := c.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", c.tableName)).Scan(&count); err != nil {
+		return 0, calque.WrapErr(ctx, err, "failed to count documents")
+	}
+	return count, nil
+}
+
+// GetEmbedding generates embeddings for text content using the configured provider.
+// This implements the EmbeddingCapable interface.
+func (c *Client) GetEmbedding(ctx context.Context, text string) (retrieval.EmbeddingVector, error) {
+	provider := c.GetEmbeddingProvider()
+	if provider == nil {
+		return nil, calque.NewErr(ctx, "no embedding provider configured - please set EmbeddingProvider in Config")
+	}
+	return provider.Embed(ctx, text)
+}
+
+// SetEmbeddingProvider allows setting or updating the embedding provider after client creation.
+func (c *Client) SetEmbeddingProvider(provider retrieval.EmbeddingProvider) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.embeddingProvider = provider
+}
+
+// GetEmbeddingProvider returns the currently configured embedding provider.
+func (c *Client) GetEmbeddingProvider() retrieval.EmbeddingProvider {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.embeddingProvider
+}
+
+// Health checks that the database is reachable.
+func (c *Client) Health(ctx context.Context) error {
+	if c.db == nil {
+		return calque.NewErr(ctx, "database is closed")
+	}
+	if err := c.db.PingContext(ctx); err != nil {
+		return calque.WrapErr(ctx, err, "database connectivity check failed")
+	}
+	return nil
+}
+
+// Close closes the database if the client opened it from Config.Path.
+func (c *Client) Close() error {
+	if c.owned && c.db != nil {
+		err := c.db.Close()
+		c.db = nil
+		return err
+	}
+	return nil
+}
+
+func (c *Client) checkDimension(ctx context.Context, vector retrieval.EmbeddingVector) error {
+	if c.vectorDimension > 0 && len(vector) != c.vectorDimension {
+		return calque.NewErr(ctx, fmt.Sprintf("vector has %d dimensions, expected %d", len(vector), c.vectorDimension))
+	}
+	return nil
+}
+
+// filterClause builds a WHERE clause matching metadata fields by equality
+func filterClause(filter map[string]any) (string, []any) {
+	if len(filter) == 0 {
+		return "", nil
+	}
+
+	keys := make([]string, 0, len(filter))
+	for k := range filter {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	conditions := make([]string, 0, len(keys))
+	args := make([]any, 0, len(keys)*2)
+	for _, k := range keys {
+		conditions = append(conditions, "json_extract(metadata, ?) = ?")
+		args = append(args, `$."`+strings.ReplaceAll(k, `"`, `\"`)+`"`, filter[k])
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// scanDocuments reads id, content, metadata, created_at and updated_at rows and closes them
+func scanDocuments(ctx context.Context, rows *sql.Rows, capacity int) ([]retrieval.Document, error) {
+	defer rows.Close()
+
+	documents := make([]retrieval.Document, 0, capacity)
+	for rows.Next() {
+		var doc retrieval.Document
+		var metadata sql.NullString
+		var created, updated int64
+		if err := rows.Scan(&doc.ID, &doc.Content, &metadata, &created, &updated); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to scan row")
+		}
+		if err := finishDocument(&doc, metadata, created, updated); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to parse metadata")
+		}
+		documents = append(documents, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, calque.WrapErr(ctx, err, "error iterating rows")
+	}
+	return documents, nil
+}
+
+func finishDocument(doc *retrieval.Document, metadata sql.NullString, created, updated int64) error {
+	if metadata.Valid && metadata.String != "" {
+		if err := json.Unmarshal([]byte(metadata.String), &doc.Metadata); err != nil {
+			return err
+		}
+	}
+	doc.Created = time.UnixMilli(created)
+	doc.Updated = time.UnixMilli(updated)
+	return nil
+}
+
+// encodeVector serialises a vector in sqlite-vec's float32 BLOB format
+func encodeVector(vector retrieval.EmbeddingVector) []byte {
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return buf
+}
+
+func decodeVector(data []byte) retrieval.EmbeddingVector {
+	vector := make(retrieval.EmbeddingVector, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return vector
+}
+
+func cosineSimilarity(a, b retrieval.EmbeddingVector) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,370 @@
+package sqlitevec
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/middleware/memory/sqlitestore"
+	"github.com/calque-ai/go-calque/pkg/middleware/retrieval"
+)
+
+var (
+	_ retrieval.VectorStore      = (*Client)(nil)
+	_ retrieval.EmbeddingCapable = (*Client)(nil)
+	_ retrieval.DocumentLister   = (*Client)(nil)
+	_ retrieval.DocumentCounter  = (*Client)(nil)
+	_ retrieval.DocumentGetter   = (*Client)(nil)
+	_ retrieval.MetadataUpdater  = (*Client)(nil)
+)
+
+// fakeEmbedder returns fixed vectors per content
+type fakeEmbedder map[string]retrieval.EmbeddingVector
+
+func (f fakeEmbedder) Embed(_ context.Context, text string) (retrieval.EmbeddingVector, error) {
+	if v, ok := f[text]; ok {
+		return v, nil
+	}
+	return nil, errors.New("no embedding for " + text)
+}
+
+// countingEmbedder counts Embed calls
+type countingEmbedder struct {
+	fakeEmbedder
+	calls int
+}
+
+func (c *countingEmbedder) Embed(ctx context.Context, text string) (retrieval.EmbeddingVector, error) {
+	c.calls++
+	return c.fakeEmbedder.Embed(ctx, text)
+}
+
+var testEmbedder = fakeEmbedder{
+	"go concurrency":   {1, 0, 0},
+	"go channels":      {0.9, 0.1, 0},
+	"python asyncio":   {0.5, 0.5, 0},
+	"baking sourdough": {0, 0, 1},
+}
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	db, err := sqlitestore.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	client, err := New(&Config{DB: db, VectorDimension: 3, EmbeddingProvider: testEmbedder})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	err = client.Store(context.Background(), []retrieval.Document{
+		{ID: "1", Content: "go concurrency", Metadata: map[string]any{"lang": "go"}},
+		{ID: "2", Content: "go channels", Metadata: map[string]any{"lang": "go"}},
+		{ID: "3", Content: "python asyncio", Metadata: map[string]any{"lang": "python"}},
+		{ID: "4", Content: "baking sourdough"},
+		{ID: "5"}, // skipped: no content
+	})
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	return client
+}
+
+func TestNew_Validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{"nil config", nil},
+		{"missing db and path", &Config{}},
+		{"invalid table", &Config{Path: ":memory:", TableName: "docs--"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestClient_Search(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		query   retrieval.SearchQuery
+		wantIDs []string
+	}{
+		{
+			name:    "ranked by similarity",
+			query:   retrieval.SearchQuery{Vector: retrieval.EmbeddingVector{1, 0, 0}, Limit: 3},
+			wantIDs: []string{"1", "2", "3"},
+		},
+		{
+			name:    "threshold excludes distant documents",
+			query:   retrieval.SearchQuery{Vector: retrieval.EmbeddingVector{1, 0, 0}, Threshold: 0.9},
+			wantIDs: []string{"1", "2"},
+		},
+		{
+			name:    "metadata filter",
+			query:   retrieval.SearchQuery{Vector: retrieval.EmbeddingVector{1, 0, 0}, Filter: map[string]any{"lang": "python"}},
+			wantIDs: []string{"3"},
+		},
+		{
+			name:    "limit",
+			query:   retrieval.SearchQuery{Vector: retrieval.EmbeddingVector{0, 0, 1}, Limit: 1},
+			wantIDs: []string{"4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := client.Search(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+
+			var ids []string
+			for _, doc := range result.Documents {
+				ids = append(ids, doc.ID)
+			}
+			if len(ids) != len(tt.wantIDs) {
+				t.Fatalf("Search() ids = %v, want %v", ids, tt.wantIDs)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Errorf("Search() ids = %v, want %v", ids, tt.wantIDs)
+					break
+				}
+			}
+		})
+	}
+
+	result, _ := client.Search(ctx, retrieval.SearchQuery{Vector: retrieval.EmbeddingVector{1, 0, 0}, Limit: 1})
+	doc := result.Documents[0]
+	if doc.Score < 0.999 || doc.Metadata["lang"] != "go" || doc.Created.IsZero() {
+		t.Errorf("unexpected top document %+v", doc)
+	}
+}
+
+func TestClient_SearchErrors(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	if _, err := client.Search(ctx, retrieval.SearchQuery{Text: "no vector"}); err == nil {
+		t.Error("expected error for missing vector")
+	}
+	if _, err := client.Search(ctx, retrieval.SearchQuery{Vector: retrieval.EmbeddingVector{1, 0}}); err == nil {
+		t.Error("expected error for dimension mismatch")
+	}
+}
+
+func TestClient_UpsertAndDelete(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	err := client.Store(ctx, []retrieval.Document{{ID: "4", Content: "go concurrency", Created: created}})
+	if err != nil {
+		t.Fatalf("Store() upsert error = %v", err)
+	}
+
+	result, _ := client.Search(ctx, retrieval.SearchQuery{Vector: retrieval.EmbeddingVector{0, 0, 1}, Threshold: 0.5})
+	if len(result.Documents) != 0 {
+		t.Errorf("expected re-embedded document to move, got %+v", result.Documents)
+	}
+
+	if err := client.Delete(ctx, []string{"1", "2", "4"}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	result, _ = client.Search(ctx, retrieval.SearchQuery{Vector: retrieval.EmbeddingVector{1, 0, 0}})
+	if len(result.Documents) != 1 || result.Documents[0].ID != "3" {
+		t.Errorf("unexpected documents after delete %+v", result.Documents)
+	}
+}
+
+func TestClient_ListDocuments(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	var ids []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		docs, next, err := client.ListDocuments(ctx, cursor, 3)
+		if err != nil {
+			t.Fatalf("ListDocuments() error = %v", err)
+		}
+		for _, doc := range docs {
+			ids = append(ids, doc.ID)
+		}
+		if next == "" {
+			break
+		}
+		if pages > 2 {
+			t.Fatal("ListDocuments() never reached the last page")
+		}
+		cursor = next
+	}
+	if strings.Join(ids, ",") != "1,2,3,4" {
+		t.Errorf("listed ids = %v, want 1,2,3,4", ids)
+	}
+
+	if count, err := client.CountDocuments(ctx); err != nil || count != 4 {
+		t.Errorf("CountDocuments() = %d, %v; want 4", count, err)
+	}
+}
+
+func TestClient_GetByIDAndUpdateMetadata(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	docs, err := client.GetByID(ctx, "3", "missing", "1")
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if len(docs) != 2 || docs[0].ID != "1" || docs[1].ID != "3" || docs[1].Metadata["lang"] != "python" {
+		t.Errorf("GetByID() = %+v, want documents 1 and 3", docs)
+	}
+
+	if err := client.UpdateMetadata(ctx, "3", map[string]any{"lang": nil, "level": "advanced"}); err != nil {
+		t.Fatalf("UpdateMetadata() error = %v", err)
+	}
+	docs, _ = client.GetByID(ctx, "3")
+	if _, ok := docs[0].Metadata["lang"]; ok || docs[0].Metadata["level"] != "advanced" {
+		t.Errorf("metadata after update = %v", docs[0].Metadata)
+	}
+
+	if err := client.UpdateMetadata(ctx, "missing", map[string]any{"a": 1}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("UpdateMetadata() on a missing document error = %v", err)
+	}
+}
+
+func TestUpsertSkipsUnchangedEmbeddings(t *testing.T) {
+	db, err := sqlitestore.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	embedder := &countingEmbedder{fakeEmbedder: testEmbedder}
+	client, err := New(&Config{DB: db, VectorDimension: 3, EmbeddingProvider: embedder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	docs := []retrieval.Document{
+		{ID: "1", Content: "go concurrency", Metadata: map[string]any{"rev": 1}},
+		{ID: "2", Content: "python asyncio"},
+	}
+	if _, err := retrieval.Upsert(ctx, client, docs, nil); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	embedder.calls = 0
+
+	docs[0].Metadata = map[string]any{"rev": 2}
+	docs[1].Content = "baking sourdough"
+	result, err := retrieval.Upsert(ctx, client, docs, nil)
+	if err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if *result != (retrieval.UpsertResult{Updated: 1, MetadataUpdated: 1}) || embedder.calls != 1 {
+		t.Errorf("Upsert() = %+v with %d embeddings, want one re-embedded document", result, embedder.calls)
+	}
+	if count, _ := client.CountDocuments(ctx); count != 2 {
+		t.Errorf("CountDocuments() = %d, want 2 (no duplicates)", count)
+	}
+
+	result, _ = retrieval.Upsert(ctx, client, docs, nil)
+	if result.Unchanged != 2 {
+		t.Errorf("re-sync = %+v, want everything unchanged", result)
+	}
+}
+
+func TestReindexToNewModel(t *testing.T) {
+	current := newTestClient(t)
+	ctx := context.Background()
+
+	// The upgraded model flips every vector, so search results show which model embedded a document
+	flipped := fakeEmbedder{}
+	for text, v := range testEmbedder {
+		flipped[text] = retrieval.EmbeddingVector{v[2], v[1], v[0]}
+	}
+	upgraded, err := New(&Config{DB: current.db, TableName: "documents_v2", VectorDimension: 3, EmbeddingProvider: flipped})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	stats, err := retrieval.Reindex(ctx, current, upgraded, &retrieval.ReindexOptions{BatchSize: 3})
+	if err != nil {
+		t.Fatalf("Reindex() error = %v", err)
+	}
+	if stats.Copied != 4 || stats.Total != 4 {
+		t.Errorf("stats = %+v, want 4 of 4 copied", stats)
+	}
+
+	result, err := upgraded.Search(ctx, retrieval.SearchQuery{Vector: retrieval.EmbeddingVector{0, 0, 1}, Threshold: 0.999})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(result.Documents) != 1 || result.Documents[0].ID != "1" || result.Documents[0].Metadata["lang"] != "go" {
+		t.Errorf("re-embedded search = %+v, want document 1 with metadata", result.Documents)
+	}
+}
+
+func TestClient_StoreErrors(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	if err := client.Store(ctx, []retrieval.Document{{ID: "x", Content: "unknown"}}); err == nil {
+		t.Error("expected embedding error")
+	}
+
+	client.SetEmbeddingProvider(nil)
+	if err := client.Store(ctx, []retrieval.Document{{ID: "x", Content: "go channels"}}); err == nil {
+		t.Error("expected error without embedding provider")
+	}
+	if _, err := client.GetEmbedding(ctx, "go channels"); err == nil {
+		t.Error("expected GetEmbedding error without provider")
+	}
+}
+
+func TestClient_OwnsDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.db")
+	client, err := New(&Config{Path: path, EmbeddingProvider: testEmbedder})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := client.Health(context.Background()); err != nil {
+		t.Errorf("Health() error = %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := client.Health(context.Background()); err == nil {
+		t.Error("expected Health error after Close")
+	}
+}
+
+func TestVectorEncoding(t *testing.T) {
+	vector := retrieval.EmbeddingVector{0.25, -1.5, 3}
+	data := encodeVector(vector)
+	if len(data) != 12 {
+		t.Fatalf("encoded length = %d, want 12", len(data))
+	}
+
+	decoded := decodeVector(data)
+	for i := range vector {
+		if decoded[i] != vector[i] {
+			t.Errorf("decoded = %v, want %v", decoded, vector)
+		}
+	}
+}
@@ -34,6 +34,8 @@ const (
 	StrategyRelevant ContextStrategy = "relevant"
 	// StrategyRecent prioritizes newest documents by timestamp
 	StrategyRecent ContextStrategy = "recent"
+	// StrategyRelevantRecent ranks by similarity score decayed exponentially with document age
+	StrategyRelevantRecent ContextStrategy = "relevant_recent"
 	// StrategyDiverse ensures topic diversity across selected documents
 	StrategyDiverse ContextStrategy = "diverse"
 	// StrategySummary summarizes long documents to fit more context
@@ -0,0 +1,191 @@
+package retrieval
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// ContentHashMetadataKey holds the SHA-256 of a document's content, written by Upsert
+const ContentHashMetadataKey = "content_hash"
+
+// errMetadataUpdateUnsupported is returned by wrappers whose inner store cannot patch metadata,
+// telling Upsert to re-store the document instead
+var errMetadataUpdateUnsupported = errors.New("metadata updates not supported")
+
+// UpsertOptions configures Upsert.
+type UpsertOptions struct {
+	// Merge incoming metadata into the stored metadata instead of replacing it.
+	// Nil values remove keys.
+	MergeMetadata bool
+}
+
+// UpsertResult counts what Upsert did with each document.
+type UpsertResult struct {
+	Inserted        int `json:"inserted"`         // New documents stored
+	Updated         int `json:"updated"`          // Documents re-stored because their content changed
+	MetadataUpdated int `json:"metadata_updated"` // Documents whose metadata changed without re-embedding
+	Unchanged       int `json:"unchanged"`        // Documents skipped because nothing changed
+}
+
+// ContentHash returns the hash Upsert uses to detect content changes
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Upsert stores new and changed documents and skips unchanged ones.
+//
+// Input: store implementing DocumentGetter, documents with IDs
+// Output: counts of inserted, updated, metadata-only and unchanged documents
+// Behavior: Fetches stored versions, then only embeds documents whose content changed
+//
+// Incremental sync jobs can re-send their whole corpus: each document is
+// compared with its stored version by content hash (kept in metadata under
+// ContentHashMetadataKey). Changed content is re-stored and re-embedded.
+// When only metadata changed, stores implementing MetadataUpdater are patched
+// in place; others fall back to re-storing the document.
+//
+// Example:
+//
+//	result, err := retrieval.Upsert(ctx, store, pages, &retrieval.UpsertOptions{MergeMetadata: true})
+//	log.Printf("%d new, %d changed, %d untouched", result.Inserted, result.Updated, result.Unchanged)
+func Upsert(ctx context.Context, store VectorStore, documents []Document, opts *UpsertOptions) (*UpsertResult, error) {
+	if opts == nil {
+		opts = &UpsertOptions{}
+	}
+	result := &UpsertResult{}
+	if len(documents) == 0 {
+		return result, nil
+	}
+
+	getter, ok := store.(DocumentGetter)
+	if !ok {
+		return result, calque.NewErr(ctx, fmt.Sprintf("store %T cannot get documents by ID", store))
+	}
+
+	ids := make([]string, len(documents))
+	for i, doc := range documents {
+		if doc.ID == "" {
+			return result, calque.NewErr(ctx, fmt.Sprintf("document %d has no ID - upsert needs IDs to detect changes", i))
+		}
+		ids[i] = doc.ID
+	}
+	existing, err := getter.GetByID(ctx, ids...)
+	if err != nil {
+		return result, calque.WrapErr(ctx, err, "failed to fetch stored documents")
+	}
+	stored := make(map[string]Document, len(existing))
+	for _, doc := range existing {
+		stored[doc.ID] = doc
+	}
+
+	updater, canPatch := store.(MetadataUpdater)
+	var toStore []Document
+	for _, doc := range documents {
+		hash := ContentHash(doc.Content)
+		old, found := stored[doc.ID]
+
+		metadata := doc.Metadata
+		if found && opts.MergeMetadata {
+			metadata = mergeMetadata(old.Metadata, doc.Metadata)
+		}
+		metadata = withoutNil(metadata)
+		metadata[ContentHashMetadataKey] = hash
+		doc.Metadata = metadata
+
+		switch {
+		case !found:
+			result.Inserted++
+			toStore = append(toStore, doc)
+		case storedHash(old) != hash:
+			result.Updated++
+			if doc.Created.IsZero() {
+				doc.Created = old.Created
+			}
+			toStore = append(toStore, doc)
+		case sameMetadata(old.Metadata, metadata):
+			result.Unchanged++
+		default:
+			result.MetadataUpdated++
+			if canPatch {
+				err := updater.UpdateMetadata(ctx, doc.ID, metadataPatch(old.Metadata, metadata))
+				if err == nil {
+					continue
+				}
+				if !errors.Is(err, errMetadataUpdateUnsupported) {
+					return result, calque.WrapErr(ctx, err, fmt.Sprintf("failed to update metadata of document %s", doc.ID))
+				}
+				canPatch = false
+			}
+			doc.Created = old.Created
+			toStore = append(toStore, doc)
+		}
+	}
+
+	if len(toStore) > 0 {
+		if err := store.Store(ctx, toStore); err != nil {
+			return result, calque.WrapErr(ctx, err, "failed to store changed documents")
+		}
+	}
+	return result, nil
+}
+
+// storedHash returns the recorded content hash, hashing the content for documents stored without one
+func storedHash(doc Document) string {
+	if hash, ok := doc.Metadata[ContentHashMetadataKey].(string); ok {
+		return hash
+	}
+	return ContentHash(doc.Content)
+}
+
+func mergeMetadata(base, patch map[string]any) map[string]any {
+	merged := make(map[string]any, len(base)+len(patch))
+	maps.Copy(merged, base)
+	maps.Copy(merged, patch)
+	return merged
+}
+
+func withoutNil(metadata map[string]any) map[string]any {
+	clean := make(map[string]any, len(metadata)+1)
+	for key, value := range metadata {
+		if value != nil {
+			clean[key] = value
+		}
+	}
+	return clean
+}
+
+// metadataPatch returns the changes turning old into updated, with nil for removed keys
+func metadataPatch(old, updated map[string]any) map[string]any {
+	patch := make(map[string]any, len(updated))
+	for key, value := range updated {
+		if !sameJSON(old[key], value) {
+			patch[key] = value
+		}
+	}
+	for key := range old {
+		if _, ok := updated[key]; !ok {
+			patch[key] = nil
+		}
+	}
+	return patch
+}
+
+// sameMetadata compares metadata as stored, where numbers round-trip through JSON
+func sameMetadata(a, b map[string]any) bool {
+	return sameJSON(withoutNil(a), withoutNil(b))
+}
+
+func sameJSON(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
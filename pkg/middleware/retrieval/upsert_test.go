@@ -0,0 +1,173 @@
+package retrieval
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gettingStore adds GetByID to memoryStore and counts the documents it is asked to store
+type gettingStore struct {
+	*memoryStore
+	stored int
+}
+
+func newGettingStore() *gettingStore {
+	return &gettingStore{memoryStore: newMemoryStore()}
+}
+
+func (g *gettingStore) Store(ctx context.Context, docs []Document) error {
+	g.stored += len(docs)
+	return g.memoryStore.Store(ctx, docs)
+}
+
+func (g *gettingStore) GetByID(_ context.Context, ids ...string) ([]Document, error) {
+	var docs []Document
+	for _, id := range ids {
+		if doc, ok := g.docs[id]; ok {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// patchingStore also updates metadata in place
+type patchingStore struct {
+	*gettingStore
+	patches []map[string]any
+}
+
+func (p *patchingStore) UpdateMetadata(_ context.Context, id string, patch map[string]any) error {
+	p.patches = append(p.patches, patch)
+	doc := p.docs[id]
+	doc.Metadata = withoutNil(mergeMetadata(doc.Metadata, patch))
+	p.docs[id] = doc
+	return nil
+}
+
+func TestUpsert(t *testing.T) {
+	ctx := context.Background()
+	store := newGettingStore()
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	result, err := Upsert(ctx, store, []Document{
+		{ID: "a", Content: "alpha", Created: created},
+		{ID: "b", Content: "beta", Metadata: map[string]any{"v": 1}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if *result != (UpsertResult{Inserted: 2}) || store.stored != 2 {
+		t.Fatalf("first sync = %+v, stored %d", result, store.stored)
+	}
+	if store.docs["a"].Metadata[ContentHashMetadataKey] != ContentHash("alpha") {
+		t.Errorf("content hash not recorded: %v", store.docs["a"].Metadata)
+	}
+
+	store.stored = 0
+	result, err = Upsert(ctx, store, []Document{
+		{ID: "a", Content: "alpha v2"},
+		{ID: "b", Content: "beta", Metadata: map[string]any{"v": 1}},
+		{ID: "c", Content: "gamma"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if *result != (UpsertResult{Inserted: 1, Updated: 1, Unchanged: 1}) || store.stored != 2 {
+		t.Errorf("second sync = %+v, stored %d; want only a and c re-stored", result, store.stored)
+	}
+	if !store.docs["a"].Created.Equal(created) {
+		t.Errorf("Created = %v, want the original %v kept", store.docs["a"].Created, created)
+	}
+}
+
+func TestUpsertMetadataOnly(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("patched in place", func(t *testing.T) {
+		store := &patchingStore{gettingStore: newGettingStore()}
+		_, _ = Upsert(ctx, store, []Document{{ID: "a", Content: "alpha", Metadata: map[string]any{"tag": "x", "old": true}}}, nil)
+		store.stored = 0
+
+		result, err := Upsert(ctx, store, []Document{{ID: "a", Content: "alpha", Metadata: map[string]any{"tag": "y"}}}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.MetadataUpdated != 1 || store.stored != 0 {
+			t.Errorf("result = %+v, stored %d; want a metadata patch without re-embedding", result, store.stored)
+		}
+		want := map[string]any{"tag": "y", "old": nil}
+		if len(store.patches) != 1 || !maps.Equal(store.patches[0], want) {
+			t.Errorf("patches = %v, want %v", store.patches, want)
+		}
+	})
+
+	t.Run("re-stored without updater", func(t *testing.T) {
+		store := newGettingStore()
+		_, _ = Upsert(ctx, store, []Document{{ID: "a", Content: "alpha", Metadata: map[string]any{"tag": "x"}}}, nil)
+		store.stored = 0
+
+		result, err := Upsert(ctx, store, []Document{{ID: "a", Content: "alpha", Metadata: map[string]any{"tag": "y"}}}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.MetadataUpdated != 1 || store.stored != 1 || store.docs["a"].Metadata["tag"] != "y" {
+			t.Errorf("result = %+v, stored %d, metadata %v", result, store.stored, store.docs["a"].Metadata)
+		}
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		store := &patchingStore{gettingStore: newGettingStore()}
+		_, _ = Upsert(ctx, store, []Document{{ID: "a", Content: "alpha", Metadata: map[string]any{"tag": "x", "owner": "ops", "draft": true}}}, nil)
+
+		opts := &UpsertOptions{MergeMetadata: true}
+		result, err := Upsert(ctx, store, []Document{{ID: "a", Content: "alpha", Metadata: map[string]any{"tag": "y", "draft": nil}}}, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]any{"tag": "y", "owner": "ops", ContentHashMetadataKey: ContentHash("alpha")}
+		if result.MetadataUpdated != 1 || !maps.Equal(store.docs["a"].Metadata, want) {
+			t.Errorf("metadata = %v, want %v", store.docs["a"].Metadata, want)
+		}
+
+		result, _ = Upsert(ctx, store, []Document{{ID: "a", Content: "alpha", Metadata: map[string]any{"owner": "ops"}}}, opts)
+		if result.Unchanged != 1 {
+			t.Errorf("re-sending a subset of merged metadata = %+v, want unchanged", result)
+		}
+	})
+}
+
+func TestUpsertErrors(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := Upsert(ctx, newMemoryStore(), []Document{{ID: "a"}}, nil); err == nil || !strings.Contains(err.Error(), "cannot get documents by ID") {
+		t.Errorf("store without GetByID error = %v", err)
+	}
+	if _, err := Upsert(ctx, newGettingStore(), []Document{{ID: "a"}, {Content: "no id"}}, nil); err == nil || !strings.Contains(err.Error(), "document 1 has no ID") {
+		t.Errorf("missing ID error = %v", err)
+	}
+}
+
+func TestUpsertNamespaced(t *testing.T) {
+	ctx := context.Background()
+	shared := newGettingStore()
+	acme, globex := Namespaced(shared, "acme"), Namespaced(shared, "globex")
+
+	_, _ = Upsert(ctx, acme, []Document{{ID: "1", Content: "acme", Metadata: map[string]any{"tag": "x"}}}, nil)
+	result, err := Upsert(ctx, globex, []Document{{ID: "1", Content: "globex"}}, nil)
+	if err != nil || result.Inserted != 1 {
+		t.Errorf("same ID in another namespace = %+v, %v; want inserted", result, err)
+	}
+
+	// The shared store cannot patch metadata, so the namespaced wrapper falls back to re-storing
+	result, err = Upsert(ctx, acme, []Document{{ID: "1", Content: "acme", Metadata: map[string]any{"tag": "y"}}}, nil)
+	if err != nil || result.MetadataUpdated != 1 {
+		t.Fatalf("metadata change = %+v, %v", result, err)
+	}
+	docs, _ := acme.(DocumentGetter).GetByID(ctx, "1")
+	if len(docs) != 1 || docs[0].Metadata["tag"] != "y" || docs[0].ID != "1" {
+		t.Errorf("GetByID() = %+v", docs)
+	}
+}
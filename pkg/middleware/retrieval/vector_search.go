@@ -1,11 +1,14 @@
 package retrieval
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
 	"github.com/hbollon/go-edlib"
@@ -306,6 +309,8 @@ func applyStrategy(ctx context.Context, documents []Document, opts *SearchOption
 			}
 			return 0 // equal
 		})
+	case StrategyRelevantRecent:
+		docs = decayByAge(docs, opts.GetRecencyHalfLife(), time.Now())
 	case StrategyDiverse:
 		// If native diversification wasn't used during search, apply local implementation
 		docs = selectDiverse(docs, opts)
@@ -319,6 +324,28 @@ func applyStrategy(ctx context.Context, documents []Document, opts *SearchOption
 	return docs, nil
 }
 
+// decayByAge scales each score by 0.5^(age/halfLife) and sorts by the result.
+// Age is measured from Updated, or Created when the document was never updated;
+// undated documents keep their similarity score.
+func decayByAge(docs []Document, halfLife time.Duration, now time.Time) []Document {
+	for i := range docs {
+		timestamp := docs[i].Updated
+		if timestamp.IsZero() {
+			timestamp = docs[i].Created
+		}
+		if timestamp.IsZero() {
+			continue
+		}
+		age := max(now.Sub(timestamp), 0)
+		docs[i].Score *= math.Exp2(-float64(age) / float64(halfLife))
+	}
+
+	slices.SortStableFunc(docs, func(a, b Document) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	return docs
+}
+
 // selectDiverse implements Maximum Marginal Relevance (MMR) algorithm for diversity selection
 func selectDiverse(documents []Document, opts *SearchOptions) []Document {
 	if len(documents) <= 1 {
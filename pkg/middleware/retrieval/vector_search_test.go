@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"math"
 	"strings"
 	"testing"
 	"time"
@@ -916,6 +917,27 @@ func TestApplyStrategy(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "StrategyRelevantRecent lets a fresh document outrank a stale one",
+			docs: []Document{
+				{Content: "stale", Score: 0.9, Created: lastWeek},
+				{Content: "fresh", Score: 0.6, Created: lastWeek, Updated: now},
+				{Content: "recent", Score: 0.5, Created: yesterday},
+			},
+			opts: &SearchOptions{
+				Strategy: ptr(StrategyRelevantRecent),
+			},
+			checkFn: func(t *testing.T, result []Document, err error) {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				// stale is halved by the default one-week half-life; fresh counts from its update
+				got := []string{result[0].Content, result[1].Content, result[2].Content}
+				if strings.Join(got, ",") != "fresh,recent,stale" {
+					t.Errorf("Expected fresh,recent,stale, got %v", got)
+				}
+			},
+		},
 		{
 			name: "StrategyDiverse applies MMR",
 			docs: []Document{
@@ -987,6 +1009,36 @@ func ptr[T any](v T) *T {
 }
 
 // TestVectorSearch tests the main VectorSearch handler
+func TestDecayByAge(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	docs := decayByAge([]Document{
+		{ID: "month", Score: 1, Created: now.Add(-30 * day)},
+		{ID: "undated", Score: 0.3},
+		{ID: "day", Score: 1, Created: now.Add(-day)},
+		{ID: "future", Score: 0.2, Created: now.Add(day)},
+	}, day, now)
+
+	want := map[string]float64{"day": 0.5, "undated": 0.3, "future": 0.2, "month": math.Exp2(-30)}
+	if docIDs(docs) != "day,undated,future,month" {
+		t.Errorf("order = %s, want day,undated,future,month", docIDs(docs))
+	}
+	for _, doc := range docs {
+		if math.Abs(doc.Score-want[doc.ID]) > 1e-12 {
+			t.Errorf("%s score = %v, want %v", doc.ID, doc.Score, want[doc.ID])
+		}
+	}
+
+	halfLife := 12 * time.Hour
+	if got := (&SearchOptions{RecencyHalfLife: &halfLife}).GetRecencyHalfLife(); got != halfLife {
+		t.Errorf("GetRecencyHalfLife() = %v, want %v", got, halfLife)
+	}
+	if got := (&SearchOptions{}).GetRecencyHalfLife(); got != DefaultRecencyHalfLife {
+		t.Errorf("default GetRecencyHalfLife() = %v", got)
+	}
+}
+
 func TestVectorSearch(t *testing.T) {
 	t.Parallel()
 
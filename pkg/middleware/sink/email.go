@@ -0,0 +1,341 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// EmailConfig holds configuration for the Email sink
+type EmailConfig struct {
+	Host     string // SMTP server host (required)
+	Port     int    // SMTP server port (default: 587, or 465 with ImplicitTLS)
+	Username string // SMTP AUTH PLAIN user (optional)
+	Password string // SMTP AUTH PLAIN password
+	// ImplicitTLS connects over TLS from the start (port 465) instead of
+	// upgrading with STARTTLS when the server offers it
+	ImplicitTLS bool
+	// TLSConfig overrides the TLS settings (default: verify against Host)
+	TLSConfig *tls.Config
+
+	From    string   // Sender address (required)
+	To      []string // Recipients (at least one of To, Cc or Bcc is required)
+	Cc      []string
+	Bcc     []string // Receive the message without appearing in its headers
+	Subject string   // Subject template (default: "Flow output")
+	Body    string   // Body template (default: "{{.Output}}")
+	HTML    bool     // Send the body as text/html, escaping template values
+
+	Attachments  []Attachment // Files attached to every message
+	AttachOutput string       // Also attach the raw output under this file name (optional)
+
+	Timeout time.Duration // Bounds connecting and sending (default: 30 seconds)
+}
+
+// Email delivers the flow output as an email over SMTP.
+//
+// Input: any data type (buffered - reads entire input into memory)
+// Output: the input unchanged, once the message is accepted by the server
+// Behavior: BUFFERED - renders the whole output into one message
+//
+// The subject and body are templates executed with a TemplateData. STARTTLS
+// is used whenever the server offers it, and is required before sending
+// credentials. Delivery errors fail the request; wrap the sink in ctrl.Retry
+// to retry transient SMTP failures.
+//
+// Example:
+//
+//	notify := sink.Email(&sink.EmailConfig{
+//		Host:         "smtp.example.com",
+//		Username:     "bot@example.com",
+//		Password:     os.Getenv("SMTP_PASSWORD"),
+//		From:         "Support Bot <bot@example.com>",
+//		To:           []string{"team@example.com"},
+//		Subject:      "Ticket triage ({{.RequestID}})",
+//		Body:         "Priority: {{.Data.priority}}\n\n{{.Data.summary}}",
+//		AttachOutput: "triage.json",
+//	})
+func Email(config *EmailConfig) calque.Handler {
+	cfg := EmailConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+		if cfg.ImplicitTLS {
+			cfg.Port = 465
+		}
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+
+	if err := cfg.validate(); err != nil {
+		return failing(err, "invalid email sink config")
+	}
+	subject, err := parseTemplate("subject", cfg.Subject, "Flow output", false)
+	if err != nil {
+		return failing(err, "invalid subject template")
+	}
+	body, err := parseTemplate("body", cfg.Body, "{{.Output}}", cfg.HTML)
+	if err != nil {
+		return failing(err, "invalid body template")
+	}
+
+	e := &emailSender{config: cfg, subject: subject, body: body}
+	return deliver("sink.Email", calque.EgressHosts(cfg.Host), e.send)
+}
+
+func (c *EmailConfig) validate() error {
+	if c.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if _, err := mail.ParseAddress(c.From); err != nil {
+		return fmt.Errorf("from address %q: %w", c.From, err)
+	}
+	if len(c.To)+len(c.Cc)+len(c.Bcc) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+	for _, list := range [][]string{c.To, c.Cc, c.Bcc} {
+		for _, addr := range list {
+			if _, err := mail.ParseAddress(addr); err != nil {
+				return fmt.Errorf("recipient %q: %w", addr, err)
+			}
+		}
+	}
+	return nil
+}
+
+type emailSender struct {
+	config  EmailConfig
+	subject executor
+	body    executor
+}
+
+func (e *emailSender) send(ctx context.Context, output []byte) error {
+	data := newTemplateData(ctx, output)
+	subject, err := render(e.subject, data)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to render email subject")
+	}
+	body, err := render(e.body, data)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to render email body")
+	}
+
+	msg, err := e.message(subject, body, attachments(e.config.Attachments, e.config.AttachOutput, output))
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to build email")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+	if err := e.transmit(ctx, msg); err != nil {
+		return calque.WrapErr(ctx, err, "failed to send email")
+	}
+	return nil
+}
+
+// transmit runs the SMTP conversation, bounded by the context deadline
+func (e *emailSender) transmit(ctx context.Context, msg []byte) error {
+	addr := net.JoinHostPort(e.config.Host, strconv.Itoa(e.config.Port))
+	tlsConfig := e.config.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: e.config.Host, MinVersion: tls.VersionTLS12}
+	}
+
+	var conn net.Conn
+	var err error
+	if e.config.ImplicitTLS {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// The smtp package has no context support, so close the connection to
+	// abort a conversation the caller stopped waiting for
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, e.config.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if !e.config.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		}
+	}
+	if e.config.Username != "" {
+		// PlainAuth refuses to send credentials over an unencrypted connection
+		if err := client.Auth(smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)); err != nil {
+			return err
+		}
+	}
+
+	from, _ := mail.ParseAddress(e.config.From)
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, list := range [][]string{e.config.To, e.config.Cc, e.config.Bcc} {
+		for _, rcpt := range list {
+			addr, _ := mail.ParseAddress(rcpt)
+			if err := client.Rcpt(addr.Address); err != nil {
+				return err
+			}
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message builds the MIME message: a single part, or multipart/mixed with attachments
+func (e *emailSender) message(subject, body string, files []Attachment) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(key, value string) { fmt.Fprintf(&buf, "%s: %s\r\n", key, value) }
+
+	header("From", formatAddresses([]string{e.config.From}))
+	if len(e.config.To) > 0 {
+		header("To", formatAddresses(e.config.To))
+	}
+	if len(e.config.Cc) > 0 {
+		header("Cc", formatAddresses(e.config.Cc))
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", singleLine(subject)))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(e.config.Host))
+	header("MIME-Version", "1.0")
+
+	bodyType := "text/plain; charset=utf-8"
+	if e.config.HTML {
+		bodyType = "text/html; charset=utf-8"
+	}
+
+	if len(files) == 0 {
+		header("Content-Type", bodyType)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	buf.WriteString("\r\n")
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {bodyType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeQuotedPrintable(part, body); err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = http.DetectContentType(file.Data)
+		}
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": file.Name})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64Lines(part, file.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// writeBase64Lines writes data as base64 wrapped at 76 characters, as RFC 2045 requires
+func writeBase64Lines(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := min(76, len(encoded))
+		if _, err := fmt.Fprintf(w, "%s\r\n", encoded[:n]); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
+
+// formatAddresses re-encodes validated addresses for a header
+func formatAddresses(addrs []string) string {
+	formatted := make([]string, len(addrs))
+	for i, addr := range addrs {
+		parsed, _ := mail.ParseAddress(addr)
+		formatted[i] = parsed.String()
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// singleLine keeps rendered subjects from injecting headers
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func messageID(host string) string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return "<" + hex.EncodeToString(b[:]) + "@" + host + ">"
+}
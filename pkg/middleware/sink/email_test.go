@@ -0,0 +1,263 @@
+package sink
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// smtpServer is a minimal SMTP server recording the messages it receives.
+// Its mode is "reject" to refuse recipients, "hang" to never greet, or empty.
+type smtpServer struct {
+	listener net.Listener
+	mode     string
+	messages chan smtpMessage
+}
+
+type smtpMessage struct {
+	from string
+	rcpt []string
+	data []byte
+}
+
+func newSMTPServer(t *testing.T, mode string) *smtpServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	s := &smtpServer{listener: listener, mode: mode, messages: make(chan smtpMessage, 1)}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+// config points an EmailConfig at the server
+func (s *smtpServer) config() *EmailConfig {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return &EmailConfig{
+		Host: "127.0.0.1",
+		Port: addr.Port,
+		From: "Bot <bot@example.com>",
+		To:   []string{"team@example.com"},
+	}
+}
+
+func (s *smtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpServer) handle(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	if s.mode == "hang" {
+		_, _ = io.Copy(io.Discard, conn)
+		return
+	}
+	_ = tp.PrintfLine("220 localhost ESMTP")
+
+	var msg smtpMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.Fields(line + " ")[0])
+		switch verb {
+		case "EHLO", "HELO":
+			_ = tp.PrintfLine("250-localhost\r\n250 8BITMIME")
+		case "MAIL":
+			msg.from = line
+			_ = tp.PrintfLine("250 OK")
+		case "RCPT":
+			if s.mode == "reject" {
+				_ = tp.PrintfLine("550 no such user")
+				continue
+			}
+			msg.rcpt = append(msg.rcpt, line)
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.data = data
+			_ = tp.PrintfLine("250 queued")
+			s.messages <- msg
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+func runSink(ctx context.Context, h calque.Handler, input string) (string, error) {
+	var out string
+	err := calque.NewFlow().Use(h).Run(ctx, input, &out)
+	return out, err
+}
+
+func TestEmail(t *testing.T) {
+	t.Parallel()
+
+	server := newSMTPServer(t, "")
+	cfg := server.config()
+	cfg.Cc = []string{"lead@example.com"}
+	cfg.Bcc = []string{"audit@example.com"}
+	cfg.Subject = "Triage for {{.Tenant}}\r\nBcc: evil@example.com"
+	cfg.Body = "Priority: {{.Data.priority}} – see attached"
+
+	ctx := calque.WithTenant(context.Background(), "acmé")
+	input := `{"priority":"high"}`
+	out, err := runSink(ctx, Email(cfg), input)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != input {
+		t.Errorf("output = %q, want the input passed through", out)
+	}
+
+	received := <-server.messages
+	if !strings.Contains(received.from, "<bot@example.com>") || len(received.rcpt) != 3 {
+		t.Errorf("envelope = %q, %q", received.from, received.rcpt)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(received.data)))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Triage for acmé Bcc: evil@example.com" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+	if msg.Header.Get("Bcc") != "" || msg.Header.Get("Cc") != "<lead@example.com>" {
+		t.Errorf("headers = %v", msg.Header)
+	}
+	body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	// The SMTP client ends the data with a line break before the final dot
+	if strings.TrimRight(string(body), "\r\n") != "Priority: high – see attached" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestEmailAttachments(t *testing.T) {
+	t.Parallel()
+
+	server := newSMTPServer(t, "")
+	cfg := server.config()
+	cfg.HTML = true
+	cfg.Body = "<p>{{.Output}}</p>"
+	cfg.Attachments = []Attachment{{Name: "notes.txt", ContentType: "text/plain", Data: []byte("static notes")}}
+	cfg.AttachOutput = "report.md"
+
+	if _, err := runSink(context.Background(), Email(cfg), "# <Report>"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string((<-server.messages).data)))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q (%v)", msg.Header.Get("Content-Type"), err)
+	}
+
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	var got []string
+	for {
+		part, err := parts.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart() error = %v", err)
+		}
+		// NextPart decodes quoted-printable but leaves base64 alone
+		data, _ := io.ReadAll(part)
+		if part.Header.Get("Content-Transfer-Encoding") == "base64" {
+			data, _ = io.ReadAll(base64.NewDecoder(base64.StdEncoding, strings.NewReader(string(data))))
+		}
+		got = append(got, part.FileName()+"="+string(data))
+	}
+
+	want := []string{"=<p># &lt;Report&gt;</p>", "notes.txt=static notes", "report.md=# <Report>"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("parts = %q, want %q", got, want)
+	}
+}
+
+func TestEmailErrors(t *testing.T) {
+	t.Parallel()
+
+	rejecting := newSMTPServer(t, "reject")
+	hanging := newSMTPServer(t, "hang")
+	hangCfg := hanging.config()
+	hangCfg.Timeout = 200 * time.Millisecond
+
+	tests := []struct {
+		name    string
+		config  *EmailConfig
+		wantErr string
+	}{
+		{name: "nil config", config: nil, wantErr: "host is required"},
+		{name: "no recipients", config: &EmailConfig{Host: "localhost", From: "bot@example.com"}, wantErr: "at least one recipient"},
+		{name: "bad address", config: &EmailConfig{Host: "localhost", From: "bot@example.com", To: []string{"a@b\r\nBcc: c@d"}}, wantErr: "recipient"},
+		{name: "bad template", config: &EmailConfig{Host: "localhost", From: "bot@example.com", To: []string{"a@b.c"}, Body: "{{.Output"}, wantErr: "invalid body template"},
+		{name: "recipient rejected", config: rejecting.config(), wantErr: "no such user"},
+		{name: "timeout", config: hangCfg, wantErr: "failed to send email"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			start := time.Now()
+			out, err := runSink(context.Background(), Email(tt.config), "report")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+			if out != "" {
+				t.Errorf("output = %q, want nothing on failure", out)
+			}
+			if time.Since(start) > 5*time.Second {
+				t.Errorf("Run() took %v", time.Since(start))
+			}
+		})
+	}
+}
+
+func TestWriteBase64Lines(t *testing.T) {
+	t.Parallel()
+
+	var buf strings.Builder
+	if err := writeBase64Lines(&buf, []byte(strings.Repeat("x", 200))); err != nil {
+		t.Fatal(err)
+	}
+	for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("line %d is %d characters", i, len(line))
+		}
+	}
+}
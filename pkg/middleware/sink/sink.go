@@ -0,0 +1,122 @@
+// Package sink provides terminal handlers that deliver a flow's final output
+// to people, so notification-style pipelines need no glue code at the end.
+//
+// Each sink buffers its input, renders it through a template, delivers it
+// (optionally with attachments) and then writes the input through unchanged,
+// so the flow still returns the output and sinks can be chained:
+//
+//	flow := calque.NewFlow().
+//		Use(prompt.Template("Summarize today's incidents:\n{{.Input}}")).
+//		Use(ai.Agent(client)).
+//		Use(sink.Slack(os.Getenv("SLACK_WEBHOOK_URL"))).
+//		Use(sink.Email(&sink.EmailConfig{
+//			Host:    "smtp.example.com",
+//			From:    "bot@example.com",
+//			To:      []string{"oncall@example.com"},
+//			Subject: "Incident digest for {{.Tenant}}",
+//		}))
+//
+// Templates are Go text/template strings (html/template for HTML email)
+// executed with a TemplateData.
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	htmltemplate "html/template"
+	"io"
+	"text/template"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// TemplateData is what sink templates are executed with
+type TemplateData struct {
+	Output    string // The flow output as text
+	Data      any    // The flow output decoded as JSON, or nil if it isn't JSON
+	RequestID string // From calque.RequestID
+	TraceID   string // From calque.TraceID
+	Tenant    string // From calque.Tenant
+}
+
+// Attachment is a file delivered alongside a message
+type Attachment struct {
+	Name        string // File name shown to the recipient
+	ContentType string // MIME type (default: detected from the content)
+	Data        []byte
+}
+
+// newTemplateData builds the template data for an output
+func newTemplateData(ctx context.Context, output []byte) TemplateData {
+	data := TemplateData{
+		Output:    string(output),
+		RequestID: calque.RequestID(ctx),
+		TraceID:   calque.TraceID(ctx),
+		Tenant:    calque.Tenant(ctx),
+	}
+	var doc any
+	if json.Unmarshal(output, &doc) == nil {
+		data.Data = doc
+	}
+	return data
+}
+
+// executor is satisfied by both text and html templates
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// parseTemplate parses a template, using fallback when text is empty
+func parseTemplate(name, text, fallback string, html bool) (executor, error) {
+	if text == "" {
+		text = fallback
+	}
+	if html {
+		return htmltemplate.New(name).Parse(text)
+	}
+	return template.New(name).Parse(text)
+}
+
+func render(tmpl executor, data TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// attachments returns the configured attachments plus the output, if attachOutput names a file
+func attachments(configured []Attachment, attachOutput string, output []byte) []Attachment {
+	if attachOutput == "" {
+		return configured
+	}
+	all := make([]Attachment, 0, len(configured)+1)
+	all = append(all, configured...)
+	return append(all, Attachment{Name: attachOutput, Data: output})
+}
+
+// deliver buffers the input, calls send with it and writes it through. The
+// handler is described as name and declares the hosts send reaches, so
+// local-only flows reject it.
+func deliver(name string, hosts []string, send func(ctx context.Context, output []byte) error) calque.Handler {
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var output []byte
+		if err := calque.Read(req, &output); err != nil {
+			return err
+		}
+		if err := send(req.Context, output); err != nil {
+			return err
+		}
+		return calque.Write(res, output)
+	})
+	described := calque.Described(h, func() calque.Node { return calque.Node{Label: name} })
+	return calque.WithEgress(described, hosts...)
+}
+
+// failing returns a handler that fails every request with a construction error
+func failing(err error, msg string) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+		return calque.WrapErr(req.Context, err, msg)
+	})
+}
@@ -0,0 +1,95 @@
+package sink
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestNewTemplateData(t *testing.T) {
+	t.Parallel()
+
+	ctx := calque.WithRequestID(context.Background(), "req-1")
+	ctx = calque.WithTraceID(ctx, "trace-1")
+	ctx = calque.WithTenant(ctx, "acme")
+
+	tests := []struct {
+		name   string
+		output string
+		want   any
+	}{
+		{name: "json", output: `{"n":1}`, want: map[string]any{"n": float64(1)}},
+		{name: "text", output: "plain text", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := newTemplateData(ctx, []byte(tt.output))
+			if data.Output != tt.output || data.RequestID != "req-1" || data.TraceID != "trace-1" || data.Tenant != "acme" {
+				t.Errorf("newTemplateData() = %+v", data)
+			}
+			if !reflect.DeepEqual(data.Data, tt.want) {
+				t.Errorf("Data = %#v, want %#v", data.Data, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttachments(t *testing.T) {
+	t.Parallel()
+
+	configured := []Attachment{{Name: "a.txt"}}
+	if got := attachments(configured, "", []byte("out")); len(got) != 1 {
+		t.Errorf("attachments() without AttachOutput = %v", got)
+	}
+	got := attachments(configured, "out.txt", []byte("out"))
+	if len(got) != 2 || got[1].Name != "out.txt" || string(got[1].Data) != "out" {
+		t.Errorf("attachments() = %v", got)
+	}
+	if len(configured) != 1 {
+		t.Error("attachments() modified the configured slice")
+	}
+}
+
+func TestSinkEgress(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		handler calque.Handler
+		want    calque.Node
+	}{
+		{
+			name:    "slack webhook",
+			handler: Slack("https://hooks.slack.com/services/T0/B0/x"),
+			want:    calque.Node{Label: "sink.Slack", Egress: []string{"hooks.slack.com"}},
+		},
+		{
+			name:    "slack bot",
+			handler: SlackWithConfig(&SlackConfig{Token: "xoxb-1", Channel: "C1"}),
+			want:    calque.Node{Label: "sink.Slack", Egress: []string{"slack.com"}},
+		},
+		{
+			name:    "email relay on host",
+			handler: Email(&EmailConfig{Host: "localhost", From: "bot@example.com", To: []string{"a@example.com"}}),
+			want:    calque.Node{Label: "sink.Email"},
+		},
+		{
+			name:    "email server",
+			handler: Email(&EmailConfig{Host: "smtp.example.com", From: "bot@example.com", To: []string{"a@example.com"}}),
+			want:    calque.Node{Label: "sink.Email", Egress: []string{"smtp.example.com"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := calque.DescribeHandler(tt.handler); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DescribeHandler() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
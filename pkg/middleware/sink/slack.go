@@ -0,0 +1,285 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultSlackAPIURL is the Slack Web API base URL
+const DefaultSlackAPIURL = "https://slack.com/api"
+
+// SlackConfig holds configuration for the Slack sink.
+//
+// Set either WebhookURL, to post through an incoming webhook, or Token and
+// Channel, to post as a bot with the Web API. Attachments need a bot token
+// with the files:write scope.
+type SlackConfig struct {
+	WebhookURL string // Incoming webhook URL
+	Token      string // Bot token (xoxb-...) for the Web API
+	Channel    string // Channel ID to post to, required with Token
+
+	Text string // Message template (default: "{{.Output}}")
+	// RawText sends the rendered text as is. By default &, < and > are escaped
+	// so model output cannot trigger mentions such as <!channel> or add links.
+	RawText bool
+
+	Attachments  []Attachment // Files uploaded with every message (Token only)
+	AttachOutput string       // Also upload the raw output under this file name (Token only)
+
+	Client  *http.Client  // HTTP client (default: one with Timeout)
+	Timeout time.Duration // Bounds each delivery (default: 10 seconds)
+	APIURL  string        // Web API base URL (default: DefaultSlackAPIURL)
+}
+
+// Slack posts the flow output to a Slack incoming webhook.
+//
+// Input: any data type (buffered - reads entire input into memory)
+// Output: the input unchanged, once Slack accepts the message
+// Behavior: BUFFERED - posts the whole output as one message
+//
+// Example:
+//
+//	flow.Use(ai.Agent(client)).Use(sink.Slack(os.Getenv("SLACK_WEBHOOK_URL")))
+func Slack(webhookURL string) calque.Handler {
+	return SlackWithConfig(&SlackConfig{WebhookURL: webhookURL})
+}
+
+// SlackWithConfig posts the flow output to Slack with custom configuration.
+//
+// Input: any data type (buffered - reads entire input into memory)
+// Output: the input unchanged, once Slack accepts the message
+// Behavior: BUFFERED - posts the whole output as one message
+//
+// With a bot token, attachments are uploaded and shared in Channel with the
+// rendered text as their comment; without any, the text is sent with
+// chat.postMessage.
+//
+// Example:
+//
+//	report := sink.SlackWithConfig(&sink.SlackConfig{
+//		Token:        os.Getenv("SLACK_BOT_TOKEN"),
+//		Channel:      "C0123456789",
+//		Text:         "Weekly report for {{.Tenant}} is ready",
+//		AttachOutput: "report.md",
+//	})
+func SlackWithConfig(config *SlackConfig) calque.Handler {
+	cfg := SlackConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: cfg.Timeout}
+	}
+	if cfg.APIURL == "" {
+		cfg.APIURL = DefaultSlackAPIURL
+	}
+	cfg.APIURL = strings.TrimSuffix(cfg.APIURL, "/")
+
+	if err := cfg.validate(); err != nil {
+		return failing(err, "invalid Slack sink config")
+	}
+	text, err := parseTemplate("text", cfg.Text, "{{.Output}}", false)
+	if err != nil {
+		return failing(err, "invalid text template")
+	}
+
+	s := &slackSender{config: cfg, text: text}
+	endpoint := cfg.WebhookURL
+	if endpoint == "" {
+		endpoint = cfg.APIURL
+	}
+	return deliver("sink.Slack", calque.EgressHosts(endpoint), s.send)
+}
+
+func (c *SlackConfig) validate() error {
+	switch {
+	case c.WebhookURL == "" && c.Token == "":
+		return fmt.Errorf("a webhook URL or bot token is required")
+	case c.WebhookURL != "" && c.Token != "":
+		return fmt.Errorf("set either a webhook URL or a bot token, not both")
+	case c.Token != "" && c.Channel == "":
+		return fmt.Errorf("channel is required with a bot token")
+	case c.WebhookURL != "" && (len(c.Attachments) > 0 || c.AttachOutput != ""):
+		return fmt.Errorf("attachments need a bot token; webhooks cannot upload files")
+	}
+	return nil
+}
+
+type slackSender struct {
+	config SlackConfig
+	text   executor
+}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func (s *slackSender) send(ctx context.Context, output []byte) error {
+	text, err := render(s.text, newTemplateData(ctx, output))
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to render Slack message")
+	}
+	if !s.config.RawText {
+		text = slackEscaper.Replace(text)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	if s.config.WebhookURL != "" {
+		err = s.postWebhook(ctx, text)
+	} else if files := attachments(s.config.Attachments, s.config.AttachOutput, output); len(files) > 0 {
+		err = s.uploadFiles(ctx, text, files)
+	} else {
+		err = s.callAPI(ctx, "chat.postMessage", map[string]any{"channel": s.config.Channel, "text": text}, nil)
+	}
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to post to Slack")
+	}
+	return nil
+}
+
+// postWebhook posts to an incoming webhook, which answers "ok" or an error string
+func (s *slackSender) postWebhook(ctx context.Context, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected webhook status code: %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// uploadFiles uploads each file and shares them in the channel with text as the comment
+func (s *slackSender) uploadFiles(ctx context.Context, text string, files []Attachment) error {
+	type uploaded struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	}
+	shared := make([]uploaded, 0, len(files))
+
+	for _, file := range files {
+		var upload struct {
+			UploadURL string `json:"upload_url"`
+			FileID    string `json:"file_id"`
+		}
+		form := url.Values{"filename": {file.Name}, "length": {strconv.Itoa(len(file.Data))}}
+		if err := s.callAPI(ctx, "files.getUploadURLExternal", form, &upload); err != nil {
+			return err
+		}
+		if err := s.putFile(ctx, upload.UploadURL, file); err != nil {
+			return fmt.Errorf("uploading %s: %w", file.Name, err)
+		}
+		shared = append(shared, uploaded{ID: upload.FileID, Title: file.Name})
+	}
+
+	return s.callAPI(ctx, "files.completeUploadExternal", map[string]any{
+		"files":           shared,
+		"channel_id":      s.config.Channel,
+		"initial_comment": text,
+	}, nil)
+}
+
+func (s *slackSender) putFile(ctx context.Context, uploadURL string, file Attachment) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, bytes.NewReader(file.Data))
+	if err != nil {
+		return err
+	}
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(file.Data)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+s.config.Token)
+
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected upload status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// callAPI calls a Web API method with a JSON body, or a form for url.Values,
+// and decodes the response into out. Slack reports failures with "ok": false.
+func (s *slackSender) callAPI(ctx context.Context, method string, params any, out any) error {
+	var body io.Reader
+	contentType := "application/json; charset=utf-8"
+	if form, ok := params.(url.Values); ok {
+		body = strings.NewReader(form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	} else {
+		encoded, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.APIURL+"/"+method, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+s.config.Token)
+
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("%s rate limited, retry after %ss", method, resp.Header.Get("Retry-After"))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected status code: %d", method, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("%s: invalid response: %w", method, err)
+	}
+	if !result.OK {
+		return fmt.Errorf("%s: %s", method, result.Error)
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// slackAPI fakes the webhook and Web API endpoints the Slack sink uses
+type slackAPI struct {
+	mu       sync.Mutex
+	calls    []string          // Paths in call order
+	bodies   map[string]string // Last body per path
+	failWith string            // Web API error to report, if any
+}
+
+func newSlackAPI(t *testing.T, failWith string) (*slackAPI, *httptest.Server) {
+	t.Helper()
+	api := &slackAPI{bodies: map[string]string{}, failWith: failWith}
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		api.mu.Lock()
+		api.calls = append(api.calls, r.URL.Path)
+		api.bodies[r.URL.Path] = string(body)
+		api.mu.Unlock()
+
+		if r.URL.Path == "/hook" {
+			if failWith != "" {
+				http.Error(w, failWith, http.StatusBadRequest)
+				return
+			}
+			_, _ = io.WriteString(w, "ok")
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/upload/") {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer xoxb-test" {
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": false, "error": "not_authed"})
+			return
+		}
+		if failWith != "" {
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": false, "error": failWith})
+			return
+		}
+		switch r.URL.Path {
+		case "/api/files.getUploadURLExternal":
+			form, _ := url.ParseQuery(string(body))
+			name := form.Get("filename")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"ok": true, "upload_url": ts.URL + "/upload/" + name, "file_id": "F-" + name,
+			})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": true})
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return api, ts
+}
+
+func (a *slackAPI) snapshot() ([]string, map[string]string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.calls...), a.bodies
+}
+
+func TestSlackWebhook(t *testing.T) {
+	t.Parallel()
+
+	api, ts := newSlackAPI(t, "")
+	out, err := runSink(context.Background(), Slack(ts.URL+"/hook"), "Deploy done <!channel> & more")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != "Deploy done <!channel> & more" {
+		t.Errorf("output = %q, want the input passed through", out)
+	}
+
+	_, bodies := api.snapshot()
+	var msg map[string]string
+	if err := json.Unmarshal([]byte(bodies["/hook"]), &msg); err != nil {
+		t.Fatalf("webhook body %q: %v", bodies["/hook"], err)
+	}
+	if want := "Deploy done &lt;!channel&gt; &amp; more"; msg["text"] != want {
+		t.Errorf("text = %q, want %q", msg["text"], want)
+	}
+}
+
+func TestSlackBot(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		config    SlackConfig
+		wantCalls []string
+		wantBody  map[string]string
+	}{
+		{
+			name:      "post message",
+			config:    SlackConfig{Text: "<{{.Data.url}}|Report> for {{.Tenant}}", RawText: true},
+			wantCalls: []string{"/api/chat.postMessage"},
+			wantBody:  map[string]string{"/api/chat.postMessage": `{"channel":"C1","text":"<https://example.com|Report> for acme"}`},
+		},
+		{
+			name: "upload attachments",
+			config: SlackConfig{
+				Text:         "Report for {{.Tenant}}",
+				Attachments:  []Attachment{{Name: "notes.txt", Data: []byte("notes")}},
+				AttachOutput: "report.json",
+			},
+			wantCalls: []string{
+				"/api/files.getUploadURLExternal", "/upload/notes.txt",
+				"/api/files.getUploadURLExternal", "/upload/report.json",
+				"/api/files.completeUploadExternal",
+			},
+			wantBody: map[string]string{
+				"/upload/report.json":               `{"url":"https://example.com"}`,
+				"/api/files.completeUploadExternal": `{"channel_id":"C1","files":[{"id":"F-notes.txt","title":"notes.txt"},{"id":"F-report.json","title":"report.json"}],"initial_comment":"Report for acme"}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			api, ts := newSlackAPI(t, "")
+			cfg := tt.config
+			cfg.Token = "xoxb-test"
+			cfg.Channel = "C1"
+			cfg.APIURL = ts.URL + "/api/"
+
+			ctx := calque.WithTenant(context.Background(), "acme")
+			if _, err := runSink(ctx, SlackWithConfig(&cfg), `{"url":"https://example.com"}`); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			calls, bodies := api.snapshot()
+			if strings.Join(calls, ",") != strings.Join(tt.wantCalls, ",") {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+			for path, want := range tt.wantBody {
+				var got, wantDoc any
+				_ = json.Unmarshal([]byte(bodies[path]), &got)
+				_ = json.Unmarshal([]byte(want), &wantDoc)
+				if !reflect.DeepEqual(got, wantDoc) {
+					t.Errorf("%s body = %s, want %s", path, bodies[path], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSlackErrors(t *testing.T) {
+	t.Parallel()
+
+	_, failing := newSlackAPI(t, "channel_not_found")
+
+	tests := []struct {
+		name    string
+		config  *SlackConfig
+		wantErr string
+	}{
+		{name: "nil config", wantErr: "a webhook URL or bot token is required"},
+		{name: "both", config: &SlackConfig{WebhookURL: "https://hooks", Token: "xoxb"}, wantErr: "not both"},
+		{name: "token without channel", config: &SlackConfig{Token: "xoxb"}, wantErr: "channel is required"},
+		{name: "webhook attachments", config: &SlackConfig{WebhookURL: "https://hooks", AttachOutput: "out.txt"}, wantErr: "need a bot token"},
+		{name: "bad template", config: &SlackConfig{WebhookURL: "https://hooks", Text: "{{"}, wantErr: "invalid text template"},
+		{name: "webhook rejected", config: &SlackConfig{WebhookURL: failing.URL + "/hook"}, wantErr: "400: channel_not_found"},
+		{name: "api error", config: &SlackConfig{Token: "xoxb-test", Channel: "C1", APIURL: failing.URL + "/api"}, wantErr: "chat.postMessage: channel_not_found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			out, err := runSink(context.Background(), SlackWithConfig(tt.config), "report")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+			if out != "" {
+				t.Errorf("output = %q, want nothing on failure", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+// Package summarize condenses documents longer than one model call.
+//
+// Document splits the text into chunks with extract.Split and summarizes them
+// with one of two strategies: MapReduce summarizes every chunk in parallel and
+// then combines the partial summaries, Refine walks the chunks in order and
+// updates a running summary. Statements in the summary cite the chunks they
+// came from, such as [c3], so answers can be traced back to the source.
+package summarize
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/extract"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+// Strategy selects how chunk summaries are combined
+type Strategy int
+
+const (
+	// MapReduce summarizes chunks in parallel, then combines the summaries.
+	// Fast, and each chunk is read without bias from the others.
+	MapReduce Strategy = iota
+	// Refine summarizes the first chunk and updates the summary with each
+	// following chunk in order. Slower, but keeps the document's narrative.
+	Refine
+)
+
+// String returns the strategy name
+func (s Strategy) String() string {
+	switch s {
+	case MapReduce:
+		return "map-reduce"
+	case Refine:
+		return "refine"
+	default:
+		return fmt.Sprintf("Strategy(%d)", int(s))
+	}
+}
+
+// Config holds configuration for a Summarizer
+type Config struct {
+	// ChunkTokens is the largest prompt input sent in one call, chunk plus any
+	// summaries sent with it (default: 3000)
+	ChunkTokens int
+	// OverlapTokens is the context shared by consecutive chunks (default: 100)
+	OverlapTokens int
+	// Tokenizer counts tokens (default: tokenizer.Approximate)
+	Tokenizer tokenizer.Tokenizer
+	// Concurrency is the number of calls made at once by MapReduce (default: 4)
+	Concurrency int
+	// NoCitations leaves chunk citations out of the summary
+	NoCitations bool
+	// AgentOptions are applied to every summarization call
+	AgentOptions []ai.AgentOption
+}
+
+// Chunk is a piece of the summarized document
+type Chunk struct {
+	// ID is cited in the summary as [ID]
+	ID   string
+	Text string
+}
+
+// Summary is a document summary with the chunks it cites
+type Summary struct {
+	Text   string
+	Chunks []Chunk
+}
+
+var citationPattern = regexp.MustCompile(`\[(c\d+)\]`)
+
+// Sources returns the chunks cited in the summary, in order of first citation
+func (s *Summary) Sources() []Chunk {
+	byID := make(map[string]Chunk, len(s.Chunks))
+	for _, chunk := range s.Chunks {
+		byID[chunk.ID] = chunk
+	}
+	var sources []Chunk
+	for _, match := range citationPattern.FindAllStringSubmatch(s.Text, -1) {
+		if chunk, ok := byID[match[1]]; ok {
+			sources = append(sources, chunk)
+			delete(byID, match[1])
+		}
+	}
+	return sources
+}
+
+// Summarizer summarizes documents. Create one with Document.
+type Summarizer struct {
+	strategy     Strategy
+	targetTokens int
+	config       Config
+	agent        calque.Handler
+}
+
+// Document creates a summarizer producing summaries of about targetLen tokens.
+//
+// Input: document text
+// Output: summary text, with chunk citations such as [c3]
+// Behavior: BUFFERED - reads the whole document and writes the final summary
+//
+// The document is split into chunks that fit the token budget along with the
+// summaries sent next to them. A document that fits in one chunk is
+// summarized in a single call. With MapReduce, partial summaries that together
+// exceed the budget are combined in groups, repeatedly, until they fit one
+// call. Chunk IDs are c1, c2, ... in document order; use Summarize to get the
+// chunk texts with the summary.
+//
+// Example:
+//
+//	summarizer := summarize.Document(client, summarize.MapReduce, 300)
+//	flow.Use(summarizer)
+//
+//	summary, err := summarizer.Summarize(ctx, report)
+//	for _, chunk := range summary.Sources() { ... }
+func Document(client ai.Client, strategy Strategy, targetLen int) *Summarizer {
+	return DocumentWithConfig(client, strategy, targetLen, nil)
+}
+
+// DocumentWithConfig creates a summarizer with custom chunking and concurrency.
+//
+// Input: document text
+// Output: summary text, with chunk citations unless NoCitations is set
+// Behavior: BUFFERED - reads the whole document and writes the final summary
+//
+// A targetLen below 1 defaults to 500 tokens. ChunkTokens is raised to at
+// least three times targetLen so each call has room for the text it condenses.
+//
+// Example:
+//
+//	summarizer := summarize.DocumentWithConfig(client, summarize.Refine, 200, &summarize.Config{
+//		ChunkTokens: 6000,
+//		Tokenizer:   tokenizer.Func(countWithTiktoken),
+//	})
+func DocumentWithConfig(client ai.Client, strategy Strategy, targetLen int, config *Config) *Summarizer {
+	cfg := Config{}
+	if config != nil {
+		cfg = *config
+	}
+	if targetLen <= 0 {
+		targetLen = 500
+	}
+	if cfg.ChunkTokens <= 0 {
+		cfg.ChunkTokens = 3000
+	}
+	cfg.ChunkTokens = max(cfg.ChunkTokens, 3*targetLen)
+	if cfg.OverlapTokens == 0 {
+		cfg.OverlapTokens = 100
+	}
+	cfg.Tokenizer = tokenizer.OrApproximate(cfg.Tokenizer)
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+
+	return &Summarizer{
+		strategy:     strategy,
+		targetTokens: targetLen,
+		config:       cfg,
+		agent:        ai.Agent(client, cfg.AgentOptions...),
+	}
+}
+
+// ServeFlow implements calque.Handler
+func (s *Summarizer) ServeFlow(req *calque.Request, res *calque.Response) error {
+	var document string
+	if err := calque.Read(req, &document); err != nil {
+		return err
+	}
+	summary, err := s.Summarize(req.Context, document)
+	if err != nil {
+		return err
+	}
+	return calque.Write(res, summary.Text)
+}
+
+// Summarize summarizes document and returns the summary with its chunks
+func (s *Summarizer) Summarize(ctx context.Context, document string) (*Summary, error) {
+	chunkTokens := s.config.ChunkTokens
+	if s.strategy == Refine {
+		// Each refine call carries the running summary next to the chunk
+		chunkTokens -= s.targetTokens
+	}
+	texts := extract.Split(document, chunkTokens, s.config.OverlapTokens, s.config.Tokenizer)
+	if len(texts) == 0 {
+		return nil, calque.NewErr(ctx, "summarize: empty document")
+	}
+	chunks := make([]Chunk, len(texts))
+	for i, text := range texts {
+		chunks[i] = Chunk{ID: "c" + strconv.Itoa(i+1), Text: text}
+	}
+
+	var text string
+	var err error
+	switch s.strategy {
+	case MapReduce:
+		text, err = s.mapReduce(ctx, chunks)
+	case Refine:
+		text, err = s.refine(ctx, chunks)
+	default:
+		return nil, calque.NewErr(ctx, fmt.Sprintf("summarize: unknown strategy %s", s.strategy))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Summary{Text: text, Chunks: chunks}, nil
+}
+
+func (s *Summarizer) mapReduce(ctx context.Context, chunks []Chunk) (string, error) {
+	if len(chunks) == 1 {
+		return s.call(ctx, s.summarizePrompt(chunks[0], true))
+	}
+
+	summaries, err := s.concurrently(ctx, len(chunks), func(ctx context.Context, i int) (string, error) {
+		summary, err := s.call(ctx, s.summarizePrompt(chunks[i], false))
+		if err != nil {
+			return "", calque.WrapErr(ctx, err, fmt.Sprintf("summarize: chunk %s failed", chunks[i].ID))
+		}
+		return summary, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Collapse summaries that do not fit one call until they do
+	for groups := s.group(summaries); len(groups) > 1; groups = s.group(summaries) {
+		summaries, err = s.concurrently(ctx, len(groups), func(ctx context.Context, i int) (string, error) {
+			return s.call(ctx, s.combinePrompt(groups[i], false))
+		})
+		if err != nil {
+			return "", calque.WrapErr(ctx, err, "summarize: combining summaries failed")
+		}
+	}
+	return s.call(ctx, s.combinePrompt(summaries, true))
+}
+
+func (s *Summarizer) refine(ctx context.Context, chunks []Chunk) (string, error) {
+	summary, err := s.call(ctx, s.summarizePrompt(chunks[0], len(chunks) == 1))
+	if err != nil {
+		return "", calque.WrapErr(ctx, err, fmt.Sprintf("summarize: chunk %s failed", chunks[0].ID))
+	}
+	for _, chunk := range chunks[1:] {
+		if summary, err = s.call(ctx, s.refinePrompt(summary, chunk)); err != nil {
+			return "", calque.WrapErr(ctx, err, fmt.Sprintf("summarize: chunk %s failed", chunk.ID))
+		}
+	}
+	return summary, nil
+}
+
+// group packs consecutive summaries into groups that fit the token budget.
+// Every group holds at least two summaries, so each collapse round shrinks the list.
+func (s *Summarizer) group(summaries []string) [][]string {
+	var groups [][]string
+	var current []string
+	tokens := 0
+	for _, summary := range summaries {
+		n := s.config.Tokenizer.CountTokens([]byte(summary))
+		if len(current) >= 2 && tokens+n > s.config.ChunkTokens {
+			groups = append(groups, current)
+			current, tokens = nil, 0
+		}
+		current = append(current, summary)
+		tokens += n
+	}
+	if len(current) == 1 && len(groups) > 0 {
+		groups[len(groups)-1] = append(groups[len(groups)-1], current[0])
+	} else {
+		groups = append(groups, current)
+	}
+	return groups
+}
+
+// concurrently runs fn for 0..n-1 with at most Concurrency calls in flight,
+// stopping at the first error
+func (s *Summarizer) concurrently(ctx context.Context, n int, fn func(ctx context.Context, i int) (string, error)) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]string, n)
+	sem := make(chan struct{}, s.config.Concurrency)
+	var firstErr error
+	var once sync.Once
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+			result, err := fn(ctx, i)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+	if firstErr == nil && ctx.Err() != nil {
+		// The parent context ended before every call started
+		firstErr = context.Cause(ctx)
+	}
+	return results, firstErr
+}
+
+func (s *Summarizer) call(ctx context.Context, prompt string) (string, error) {
+	var summary string
+	if err := calque.NewFlow().Use(s.agent).Run(ctx, prompt, &summary); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(summary), nil
+}
+
+// length describes the target length in words, which models follow more reliably than tokens
+func (s *Summarizer) length() string {
+	return fmt.Sprintf("at most about %d words", max(1, s.targetTokens*3/4))
+}
+
+func (s *Summarizer) citationRule() string {
+	if s.config.NoCitations {
+		return "Do not include chunk IDs in the summary."
+	}
+	return "After each statement, cite the chunks it comes from by ID in square brackets, such as [c3]."
+}
+
+func (s *Summarizer) summarizePrompt(chunk Chunk, whole bool) string {
+	subject := "the following part of a longer document"
+	if whole {
+		subject = "the following document"
+	}
+	return fmt.Sprintf("Summarize %s in %s. Keep key facts, names, numbers and decisions. %s\n\n<chunk id=%q>\n%s\n</chunk>",
+		subject, s.length(), s.citationRule(), chunk.ID, chunk.Text)
+}
+
+func (s *Summarizer) combinePrompt(summaries []string, final bool) string {
+	var prompt strings.Builder
+	subject := "part of a document"
+	if final {
+		subject = "the whole document"
+	}
+	fmt.Fprintf(&prompt, "The summaries below cover consecutive parts of one document. Combine them into a single summary of %s in %s. "+
+		"Merge repeated points and keep the original order.", subject, s.length())
+	if !s.config.NoCitations {
+		prompt.WriteString(" Keep the chunk citations, such as [c3], on the statements they support.")
+	}
+	for _, summary := range summaries {
+		fmt.Fprintf(&prompt, "\n\n<summary>\n%s\n</summary>", summary)
+	}
+	return prompt.String()
+}
+
+func (s *Summarizer) refinePrompt(summary string, chunk Chunk) string {
+	return fmt.Sprintf("Below is a summary of a document so far and the next part of the document. "+
+		"Update the summary so it also covers the new part, in %s. Keep earlier points unless the new part corrects them. %s"+
+		"\n\n<summary>\n%s\n</summary>\n\n<chunk id=%q>\n%s\n</chunk>",
+		s.length(), s.citationRule(), summary, chunk.ID, chunk.Text)
+}
+
+// Egress implements calque.Egress, reporting where the summarization client sends documents
+func (s *Summarizer) Egress() []string {
+	return calque.EgressOf(s.agent)
+}
+
+// Warmup implements calque.Warmer by warming the summarization client
+func (s *Summarizer) Warmup(ctx context.Context) error {
+	return calque.WarmupHandler(ctx, s.agent)
+}
+
+// Shutdown implements calque.Shutdowner by shutting down the summarization client
+func (s *Summarizer) Shutdown(ctx context.Context) error {
+	return calque.ShutdownHandler(ctx, s.agent)
+}
@@ -0,0 +1,260 @@
+package summarize
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+// words counts whitespace-separated words, making chunk sizes easy to reason about
+var words = tokenizer.Func(func(text []byte) int {
+	return len(strings.Fields(string(text)))
+})
+
+var (
+	chunkID   = regexp.MustCompile(`<chunk id="(c\d+)">`)
+	citations = regexp.MustCompile(`\[c\d+\]`)
+)
+
+// summaryClient fakes a model that summarizes a chunk as its citation,
+// padded with filler words, and combines summaries by keeping their citations
+type summaryClient struct {
+	padding int
+	failOn  string
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (c *summaryClient) Chat(r *calque.Request, w *calque.Response, _ *ai.AgentOptions) error {
+	var prompt string
+	if err := calque.Read(r, &prompt); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.prompts = append(c.prompts, prompt)
+	c.mu.Unlock()
+
+	id := ""
+	if m := chunkID.FindStringSubmatch(prompt); m != nil {
+		id = m[1]
+	}
+	if c.failOn != "" && id == c.failOn {
+		return errors.New("model unavailable")
+	}
+
+	var answer string
+	switch {
+	case strings.HasPrefix(prompt, "Summarize"):
+		answer = strings.TrimSpace(strings.Repeat("point ", c.padding) + "[" + id + "]")
+	case strings.HasPrefix(prompt, "The summaries below"):
+		body := prompt[strings.Index(prompt, "<summary>"):]
+		answer = strings.Join(citations.FindAllString(body, -1), " ")
+	case strings.HasPrefix(prompt, "Below is a summary"):
+		body := prompt[strings.Index(prompt, "<summary>"):]
+		answer = strings.Join(append(citations.FindAllString(body, -1), "["+id+"]"), " ")
+	}
+	return calque.Write(w, "\n"+answer+"\n")
+}
+
+func (c *summaryClient) count(prefix string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, prompt := range c.prompts {
+		if strings.HasPrefix(prompt, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+// document has five paragraphs of 20 words each
+var document = strings.TrimSpace(strings.Repeat(strings.Repeat("word ", 20)+"\n\n", 5))
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		strategy     Strategy
+		document     string
+		padding      int
+		want         string
+		wantChunks   int
+		wantCombines int
+	}{
+		{
+			name:         "map-reduce",
+			strategy:     MapReduce,
+			document:     document,
+			want:         "[c1] [c2] [c3] [c4] [c5]",
+			wantChunks:   5,
+			wantCombines: 1,
+		},
+		{
+			name:         "map-reduce collapses summaries over budget",
+			strategy:     MapReduce,
+			document:     document,
+			padding:      12,
+			want:         "[c1] [c2] [c3] [c4] [c5]",
+			wantChunks:   5,
+			wantCombines: 3,
+		},
+		{
+			name:       "refine",
+			strategy:   Refine,
+			document:   document,
+			want:       "[c1] [c2] [c3] [c4] [c5]",
+			wantChunks: 5,
+		},
+		{
+			name:       "single chunk",
+			strategy:   MapReduce,
+			document:   "A short note.",
+			want:       "[c1]",
+			wantChunks: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &summaryClient{padding: tt.padding}
+			summarizer := DocumentWithConfig(client, tt.strategy, 10, &Config{ChunkTokens: 30, OverlapTokens: -1, Tokenizer: words})
+			summary, err := summarizer.Summarize(context.Background(), tt.document)
+			if err != nil {
+				t.Fatalf("Summarize() error = %v", err)
+			}
+			if summary.Text != tt.want {
+				t.Errorf("summary = %q, want %q", summary.Text, tt.want)
+			}
+			if len(summary.Chunks) != tt.wantChunks {
+				t.Errorf("chunks = %d, want %d", len(summary.Chunks), tt.wantChunks)
+			}
+			if n := client.count("The summaries below"); n != tt.wantCombines {
+				t.Errorf("combine calls = %d, want %d", n, tt.wantCombines)
+			}
+			if tt.strategy == Refine {
+				if n := client.count("Below is a summary"); n != tt.wantChunks-1 {
+					t.Errorf("refine calls = %d, want %d", n, tt.wantChunks-1)
+				}
+			}
+		})
+	}
+}
+
+func TestSummarizeRefineBudget(t *testing.T) {
+	t.Parallel()
+
+	// Refine chunks leave room for the running summary next to them
+	summary, err := DocumentWithConfig(&summaryClient{}, Refine, 10, &Config{ChunkTokens: 30, OverlapTokens: -1, Tokenizer: words}).
+		Summarize(context.Background(), strings.Repeat("word ", 60))
+	if err != nil {
+		t.Fatalf("Summarize() error = %v", err)
+	}
+	for _, chunk := range summary.Chunks {
+		if n := words.CountTokens([]byte(chunk.Text)); n > 20 {
+			t.Errorf("chunk %s has %d tokens, want at most 20", chunk.ID, n)
+		}
+	}
+}
+
+func TestSummarizeErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		strategy Strategy
+		document string
+		failOn   string
+		wantErr  string
+	}{
+		{name: "map chunk fails", strategy: MapReduce, document: document, failOn: "c2", wantErr: "summarize: chunk c2 failed"},
+		{name: "refine chunk fails", strategy: Refine, document: document, failOn: "c3", wantErr: "summarize: chunk c3 failed"},
+		{name: "empty document", strategy: MapReduce, document: "\n\n ", wantErr: "summarize: empty document"},
+		{name: "unknown strategy", strategy: Strategy(7), document: document, wantErr: "unknown strategy Strategy(7)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &summaryClient{failOn: tt.failOn}
+			_, err := DocumentWithConfig(client, tt.strategy, 10, &Config{ChunkTokens: 30, OverlapTokens: -1, Tokenizer: words}).
+				Summarize(context.Background(), tt.document)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSummarizeHandler(t *testing.T) {
+	t.Parallel()
+
+	client := &summaryClient{}
+	var got string
+	err := calque.NewFlow().
+		Use(DocumentWithConfig(client, MapReduce, 200, &Config{NoCitations: true})).
+		Run(context.Background(), "Quarterly revenue grew 12%.", &got)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if got != "[c1]" {
+		t.Errorf("output = %q", got)
+	}
+
+	prompt := client.prompts[0]
+	for _, want := range []string{"Summarize the following document in at most about 150 words.", "Do not include chunk IDs", "Quarterly revenue grew 12%."} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestSummarySources(t *testing.T) {
+	t.Parallel()
+
+	summary := &Summary{
+		Text:   "Revenue grew [c3]. Costs fell [c1][c3]. Hiring paused [c9].",
+		Chunks: []Chunk{{ID: "c1", Text: "one"}, {ID: "c2", Text: "two"}, {ID: "c3", Text: "three"}},
+	}
+	var got []string
+	for _, chunk := range summary.Sources() {
+		got = append(got, chunk.ID)
+	}
+	if strings.Join(got, ",") != "c3,c1" {
+		t.Errorf("Sources() = %v, want [c3 c1]", got)
+	}
+}
+
+func TestStrategyString(t *testing.T) {
+	t.Parallel()
+
+	if MapReduce.String() != "map-reduce" || Refine.String() != "refine" || Strategy(9).String() != "Strategy(9)" {
+		t.Errorf("String() = %s, %s, %s", MapReduce, Refine, Strategy(9))
+	}
+}
+
+// remoteClient is a client on another host
+type remoteClient struct{ ai.Client }
+
+func (remoteClient) Egress() []string { return []string{"api.example.com"} }
+
+func TestSummarizerEgress(t *testing.T) {
+	if got := calque.DescribeHandler(Document(remoteClient{ai.NewMockClient("summary")}, MapReduce, 100)).Egress; !slices.Equal(got, []string{"api.example.com"}) {
+		t.Errorf("egress = %v, want the client's host", got)
+	}
+	if got := calque.DescribeHandler(Document(ai.NewMockClient("summary"), MapReduce, 100)).Egress; got != nil {
+		t.Errorf("egress with a local client = %v, want none", got)
+	}
+}
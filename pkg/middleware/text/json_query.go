@@ -0,0 +1,164 @@
+package text
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/theory/jsonpath"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// QueryConfig controls how JSONPath and JMES write their results
+type QueryConfig struct {
+	// First writes only the first JSONPath match instead of an array of all matches.
+	// JMESPath expressions already produce a single value.
+	First bool
+	// Raw writes string results without JSON quotes, e.g. to feed a prompt
+	Raw bool
+	// Required fails the request when nothing matches instead of writing null or []
+	Required bool
+}
+
+// JSONPath selects values from a JSON payload with an RFC 9535 JSONPath expression.
+//
+// Input: JSON document (buffered - reads entire input into memory)
+// Output: JSON array of every matching value
+// Behavior: BUFFERED - must parse the whole document before querying
+//
+// The expression is parsed once; an invalid one makes every request fail.
+// Use JSONPathWithConfig to write a single match or raw strings, and JMES
+// to reshape documents rather than select from them.
+//
+// Example:
+//
+//	// {"items":[{"name":"a","price":3},{"name":"b","price":12}]} → ["b"]
+//	flow.Use(text.JSONPath(`$.items[?@.price > 10].name`))
+func JSONPath(expr string) calque.Handler {
+	return JSONPathWithConfig(expr, nil)
+}
+
+// JSONPathWithConfig selects values with a JSONPath expression and custom output options.
+//
+// Input: JSON document (buffered - reads entire input into memory)
+// Output: JSON array of matches, or the first match with First set
+// Behavior: BUFFERED - must parse the whole document before querying
+//
+// Example:
+//
+//	// {"user":{"name":"Ada"}} → Ada
+//	flow.Use(text.JSONPathWithConfig(`$.user.name`, &text.QueryConfig{First: true, Raw: true}))
+func JSONPathWithConfig(expr string, config *QueryConfig) calque.Handler {
+	cfg := queryConfig(config)
+	path, err := jsonpath.Parse(expr)
+	if err != nil {
+		return queryError(err, "invalid JSONPath "+expr)
+	}
+
+	return queryHandler(cfg, func(doc any) (any, bool, error) {
+		nodes := path.Select(doc)
+		if cfg.First {
+			if len(nodes) == 0 {
+				return nil, false, nil
+			}
+			return nodes[0], true, nil
+		}
+		if nodes == nil {
+			nodes = jsonpath.NodeList{}
+		}
+		return nodes, len(nodes) > 0, nil
+	})
+}
+
+// JMES evaluates a JMESPath expression against a JSON payload.
+//
+// Input: JSON document (buffered - reads entire input into memory)
+// Output: JSON value produced by the expression
+// Behavior: BUFFERED - must parse the whole document before querying
+//
+// JMESPath can reshape as well as select: project fields into new objects,
+// flatten nested arrays, filter, sort and call functions such as length().
+// The expression is compiled once; an invalid one makes every request fail.
+//
+// Example:
+//
+//	// {"orders":[{"id":1,"lines":[{"sku":"a"}]},{"id":2,"lines":[{"sku":"b"},{"sku":"c"}]}]}
+//	// → ["a","b","c"]
+//	flow.Use(text.JMES(`orders[].lines[].sku`))
+//
+//	// → [{"order":1,"items":1},{"order":2,"items":2}]
+//	flow.Use(text.JMES(`orders[].{order: id, items: length(lines)}`))
+func JMES(expr string) calque.Handler {
+	return JMESWithConfig(expr, nil)
+}
+
+// JMESWithConfig evaluates a JMESPath expression with custom output options.
+//
+// Input: JSON document (buffered - reads entire input into memory)
+// Output: JSON value produced by the expression
+// Behavior: BUFFERED - must parse the whole document before querying
+//
+// Example:
+//
+//	flow.Use(text.JMESWithConfig(`ticket.summary`, &text.QueryConfig{Raw: true, Required: true}))
+func JMESWithConfig(expr string, config *QueryConfig) calque.Handler {
+	cfg := queryConfig(config)
+	query, err := jmespath.Compile(expr)
+	if err != nil {
+		return queryError(err, "invalid JMESPath "+expr)
+	}
+
+	return queryHandler(cfg, func(doc any) (any, bool, error) {
+		result, err := query.Search(doc)
+		if err != nil {
+			return nil, false, err
+		}
+		return result, result != nil, nil
+	})
+}
+
+func queryConfig(config *QueryConfig) QueryConfig {
+	if config == nil {
+		return QueryConfig{}
+	}
+	return *config
+}
+
+// queryError returns a handler failing every request with a construction error
+func queryError(err error, msg string) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+		return calque.WrapErr(req.Context, err, msg)
+	})
+}
+
+// queryHandler decodes the input, runs eval and writes its result
+func queryHandler(cfg QueryConfig, eval func(doc any) (result any, found bool, err error)) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var input []byte
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+		var doc any
+		if err := json.Unmarshal(input, &doc); err != nil {
+			return calque.WrapErr(req.Context, err, "input is not valid JSON")
+		}
+
+		result, found, err := eval(doc)
+		if err != nil {
+			return calque.WrapErr(req.Context, err, "query failed")
+		}
+		if !found && cfg.Required {
+			return calque.NewErr(req.Context, "query matched nothing")
+		}
+
+		if s, ok := result.(string); ok && cfg.Raw {
+			return calque.Write(res, s)
+		}
+		output, err := json.Marshal(result)
+		if err != nil {
+			return calque.WrapErr(req.Context, err, fmt.Sprintf("failed to encode %T result", result))
+		}
+		return calque.Write(res, output)
+	})
+}
@@ -0,0 +1,61 @@
+package text
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+const queryDoc = `{
+	"user": {"name": "Ada"},
+	"orders": [
+		{"id": 1, "total": 5, "lines": [{"sku": "a"}]},
+		{"id": 2, "total": 15, "lines": [{"sku": "b"}, {"sku": "c"}]}
+	]
+}`
+
+func TestJSONQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler calque.Handler
+		input   string
+		want    string
+		wantErr string
+	}{
+		{"jsonpath all matches", JSONPath(`$.orders[*].id`), queryDoc, `[1,2]`, ""},
+		{"jsonpath filter", JSONPath(`$.orders[?@.total > 10].lines[*].sku`), queryDoc, `["b","c"]`, ""},
+		{"jsonpath no match", JSONPath(`$.missing`), queryDoc, `[]`, ""},
+		{"jsonpath first raw", JSONPathWithConfig(`$.user.name`, &QueryConfig{First: true, Raw: true}), queryDoc, `Ada`, ""},
+		{"jsonpath first quoted", JSONPathWithConfig(`$.user.name`, &QueryConfig{First: true}), queryDoc, `"Ada"`, ""},
+		{"jsonpath required", JSONPathWithConfig(`$.missing`, &QueryConfig{Required: true}), queryDoc, "", "matched nothing"},
+		{"jsonpath invalid expression", JSONPath(`$[`), queryDoc, "", "invalid JSONPath"},
+		{"jmes flatten", JMES(`orders[].lines[].sku`), queryDoc, `["a","b","c"]`, ""},
+		{"jmes reshape", JMES(`orders[].{order: id, items: length(lines)}`), queryDoc, `[{"items":1,"order":1},{"items":2,"order":2}]`, ""},
+		{"jmes raw string", JMESWithConfig(`user.name`, &QueryConfig{Raw: true}), queryDoc, `Ada`, ""},
+		{"jmes no match", JMES(`user.email`), queryDoc, `null`, ""},
+		{"jmes required", JMESWithConfig(`user.email`, &QueryConfig{Required: true}), queryDoc, "", "matched nothing"},
+		{"jmes invalid expression", JMES(`orders[`), queryDoc, "", "invalid JMESPath"},
+		{"invalid json input", JMES(`a`), `{not json`, "", "not valid JSON"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out string
+			err := calque.NewFlow().Use(tt.handler).Run(context.Background(), tt.input, &out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.want {
+				t.Errorf("output = %s, want %s", out, tt.want)
+			}
+		})
+	}
+}
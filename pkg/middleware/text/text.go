@@ -63,7 +63,7 @@ func Transform(fn func(string) string) calque.Handler {
 //	  textHandler,
 //	)
 func Branch(condition func(string) bool, ifHandler calque.Handler, elseHandler calque.Handler) calque.Handler {
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		var input string
 		err := calque.Read(req, &input)
 		if err != nil {
@@ -77,6 +77,12 @@ func Branch(condition func(string) bool, ifHandler calque.Handler, elseHandler c
 		}
 		return elseHandler.ServeFlow(req, res)
 	})
+	return calque.Described(h, func() calque.Node {
+		return calque.Node{Label: "branch", Kind: calque.NodeBranch, Children: []calque.Node{
+			calque.DescribeHandler(ifHandler).WithEdge("true"),
+			calque.DescribeHandler(elseHandler).WithEdge("false"),
+		}}
+	}, ifHandler, elseHandler)
 }
 
 // Filter conditionally processes input based on content evaluation.
@@ -97,7 +103,7 @@ func Branch(condition func(string) bool, ifHandler calque.Handler, elseHandler c
 //	)
 //	// Only valid JSON gets processed, everything else passes through in this example
 func Filter(condition func(string) bool, handler calque.Handler) calque.Handler {
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		var input string
 		err := calque.Read(req, &input)
 		if err != nil {
@@ -113,6 +119,9 @@ func Filter(condition func(string) bool, handler calque.Handler) calque.Handler
 		err = calque.Write(res, input)
 		return err
 	})
+	return calque.Described(h, func() calque.Node {
+		return calque.Node{Label: "filter", Kind: calque.NodeSequence, Children: []calque.Node{calque.DescribeHandler(handler)}}
+	}, handler)
 }
 
 // LineProcessor transforms input line-by-line using buffered scanning.
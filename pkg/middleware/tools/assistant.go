@@ -0,0 +1,242 @@
+package tools
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// AssistantScope selects which calendar and mail tools are exposed by
+// GoogleWorkspace and MSGraph
+type AssistantScope int
+
+// Assistant tool scopes, combined with |
+const (
+	// CalendarRead exposes listing calendar events
+	CalendarRead AssistantScope = 1 << iota
+	// MailRead exposes searching and reading mail
+	MailRead
+	// MailDraft exposes drafting replies; drafts are saved, never sent
+	MailDraft
+)
+
+// GoogleOAuthScopes returns the Google OAuth scopes the tools need, for
+// requesting consent with the smallest grant
+func (s AssistantScope) GoogleOAuthScopes() []string {
+	var scopes []string
+	if s&CalendarRead != 0 {
+		scopes = append(scopes, "https://www.googleapis.com/auth/calendar.readonly")
+	}
+	if s&MailRead != 0 {
+		scopes = append(scopes, "https://www.googleapis.com/auth/gmail.readonly")
+	}
+	if s&MailDraft != 0 {
+		scopes = append(scopes, "https://www.googleapis.com/auth/gmail.compose")
+	}
+	return scopes
+}
+
+// GraphOAuthScopes returns the Microsoft Graph delegated permissions the tools need
+func (s AssistantScope) GraphOAuthScopes() []string {
+	var scopes []string
+	if s&CalendarRead != 0 {
+		scopes = append(scopes, "Calendars.Read")
+	}
+	switch {
+	case s&MailDraft != 0:
+		scopes = append(scopes, "Mail.ReadWrite")
+	case s&MailRead != 0:
+		scopes = append(scopes, "Mail.Read")
+	}
+	return scopes
+}
+
+type listEventsArgs struct {
+	From  string `json:"from,omitempty" jsonschema:"description=Start of the range as RFC 3339 or YYYY-MM-DD (default: now)"`
+	To    string `json:"to,omitempty" jsonschema:"description=End of the range as RFC 3339 or YYYY-MM-DD (default: 7 days after from)"`
+	Query string `json:"query,omitempty" jsonschema:"description=Only events whose title contains this text"`
+	Limit int    `json:"limit,omitempty" jsonschema:"minimum=1,maximum=100,description=Maximum events to return (default: 20)"`
+}
+
+type searchMailArgs struct {
+	Query string `json:"query,omitempty" jsonschema:"description=Search text, e.g. from:alice invoice (default: most recent mail)"`
+	Limit int    `json:"limit,omitempty" jsonschema:"minimum=1,maximum=50,description=Maximum messages to return (default: 10)"`
+}
+
+type getMessageArgs struct {
+	ID string `json:"id" jsonschema:"description=Message ID from a search result"`
+}
+
+type draftReplyArgs struct {
+	MessageID string `json:"message_id" jsonschema:"description=ID of the message to reply to"`
+	Body      string `json:"body" jsonschema:"description=Reply text"`
+	ReplyAll  bool   `json:"reply_all,omitempty" jsonschema:"description=Address everyone on the original message"`
+}
+
+// calendarEvent is the compact event returned to the model
+type calendarEvent struct {
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	Start     string   `json:"start"`
+	End       string   `json:"end"`
+	Location  string   `json:"location,omitempty"`
+	Organizer string   `json:"organizer,omitempty"`
+	Attendees []string `json:"attendees,omitempty"`
+	URL       string   `json:"url,omitempty"`
+}
+
+// mailSummary is a search result returned to the model
+type mailSummary struct {
+	ID      string `json:"id"`
+	From    string `json:"from"`
+	Subject string `json:"subject"`
+	Date    string `json:"date"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// mailMessage is a full message returned to the model
+type mailMessage struct {
+	ID      string   `json:"id"`
+	From    string   `json:"from"`
+	To      []string `json:"to,omitempty"`
+	Cc      []string `json:"cc,omitempty"`
+	Subject string   `json:"subject"`
+	Date    string   `json:"date"`
+	Body    string   `json:"body"`
+}
+
+// mailDraft describes a saved reply draft
+type mailDraft struct {
+	DraftID string   `json:"draft_id"`
+	To      []string `json:"to"`
+	Cc      []string `json:"cc,omitempty"`
+	Subject string   `json:"subject"`
+}
+
+// eventRange resolves the requested time range, defaulting to the next week
+func (a listEventsArgs) eventRange(ctx context.Context, now time.Time) (time.Time, time.Time, error) {
+	parse := func(name, value string, fallback time.Time) (time.Time, error) {
+		if value == "" {
+			return fallback, nil
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, nil
+		}
+		if t, err := time.Parse(time.DateOnly, value); err == nil {
+			return t, nil
+		}
+		return time.Time{}, calque.NewErr(ctx, fmt.Sprintf("invalid %s %q: use RFC 3339 or YYYY-MM-DD", name, value))
+	}
+	from, err := parse("from", a.From, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to, err := parse("to", a.To, from.AddDate(0, 0, 7))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, calque.NewErr(ctx, "to must be after from")
+	}
+	return from, to, nil
+}
+
+// limitOr clamps a requested result count, using fallback when unset
+func limitOr(limit, fallback, maximum int) int {
+	if limit <= 0 {
+		return fallback
+	}
+	return min(limit, maximum)
+}
+
+// replySubject prefixes subject with "Re: " unless it already is a reply
+func replySubject(subject string) string {
+	if len(subject) >= 3 && strings.EqualFold(subject[:3], "re:") {
+		return subject
+	}
+	return "Re: " + subject
+}
+
+// oauthHTTPClient returns a client that authorizes requests with tokens from
+// source, refreshing them as they expire
+func oauthHTTPClient(source oauth2.TokenSource, base *http.Client) *http.Client {
+	if base == nil {
+		base = &http.Client{Timeout: 30 * time.Second}
+	}
+	client := *base
+	client.Transport = &oauth2.Transport{Source: oauth2.ReuseTokenSource(nil, source), Base: base.Transport}
+	return &client
+}
+
+// apiRequest calls a JSON API whose errors use the {"error": {"message": ...}}
+// shape shared by Google and Microsoft Graph
+type apiRequest struct {
+	service string
+	method  string
+	url     string
+	header  http.Header
+	body    any
+}
+
+func (r apiRequest) do(ctx context.Context, client *http.Client, out any) error {
+	var payload io.Reader
+	if r.body != nil {
+		data, err := json.Marshal(r.body)
+		if err != nil {
+			return calque.WrapErr(ctx, err, fmt.Sprintf("failed to encode %s request", r.service))
+		}
+		payload = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, r.url, payload)
+	if err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to create %s request", r.service))
+	}
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if r.body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("%s request failed", r.service))
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to read %s response", r.service))
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(data, &apiErr)
+		detail := cmp.Or(apiErr.Error.Message, http.StatusText(resp.StatusCode))
+		if resp.StatusCode == http.StatusForbidden {
+			detail += " (check the granted OAuth scopes)"
+		}
+		return calque.NewErr(ctx, fmt.Sprintf("%s API: %d %s", r.service, resp.StatusCode, detail))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return calque.WrapErr(ctx, err, fmt.Sprintf("invalid %s response", r.service))
+	}
+	return nil
+}
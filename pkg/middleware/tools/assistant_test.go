@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAssistantScopeOAuthScopes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		scopes     AssistantScope
+		wantGoogle string
+		wantGraph  string
+	}{
+		{
+			name:       "calendar",
+			scopes:     CalendarRead,
+			wantGoogle: "https://www.googleapis.com/auth/calendar.readonly",
+			wantGraph:  "Calendars.Read",
+		},
+		{
+			name:       "read mail",
+			scopes:     MailRead,
+			wantGoogle: "https://www.googleapis.com/auth/gmail.readonly",
+			wantGraph:  "Mail.Read",
+		},
+		{
+			name:       "draft implies write access on Graph",
+			scopes:     CalendarRead | MailRead | MailDraft,
+			wantGoogle: "https://www.googleapis.com/auth/calendar.readonly https://www.googleapis.com/auth/gmail.readonly https://www.googleapis.com/auth/gmail.compose",
+			wantGraph:  "Calendars.Read Mail.ReadWrite",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := strings.Join(tt.scopes.GoogleOAuthScopes(), " "); got != tt.wantGoogle {
+				t.Errorf("GoogleOAuthScopes() = %q, want %q", got, tt.wantGoogle)
+			}
+			if got := strings.Join(tt.scopes.GraphOAuthScopes(), " "); got != tt.wantGraph {
+				t.Errorf("GraphOAuthScopes() = %q, want %q", got, tt.wantGraph)
+			}
+		})
+	}
+}
+
+func TestEventRange(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		args     listEventsArgs
+		wantFrom string
+		wantTo   string
+		wantErr  string
+	}{
+		{name: "defaults to the next week", wantFrom: "2026-10-15T09:30:00Z", wantTo: "2026-10-22T09:30:00Z"},
+		{name: "dates", args: listEventsArgs{From: "2026-10-20", To: "2026-10-21"}, wantFrom: "2026-10-20T00:00:00Z", wantTo: "2026-10-21T00:00:00Z"},
+		{name: "timestamps", args: listEventsArgs{From: "2026-10-20T08:00:00+02:00"}, wantFrom: "2026-10-20T08:00:00+02:00", wantTo: "2026-10-27T08:00:00+02:00"},
+		{name: "bad to", args: listEventsArgs{To: "next friday"}, wantErr: `invalid to "next friday"`},
+		{name: "inverted", args: listEventsArgs{From: "2026-10-21", To: "2026-10-20"}, wantErr: "to must be after from"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			from, to, err := tt.args.eventRange(context.Background(), now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if from.Format(time.RFC3339) != tt.wantFrom || to.Format(time.RFC3339) != tt.wantTo {
+				t.Errorf("eventRange() = %s, %s; want %s, %s", from.Format(time.RFC3339), to.Format(time.RFC3339), tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestReplySubject(t *testing.T) {
+	t.Parallel()
+
+	for subject, want := range map[string]string{
+		"Invoice":     "Re: Invoice",
+		"Re: Invoice": "Re: Invoice",
+		"RE: Invoice": "RE: Invoice",
+		"":            "Re: ",
+		"Rework plan": "Re: Rework plan",
+	} {
+		if got := replySubject(subject); got != want {
+			t.Errorf("replySubject(%q) = %q, want %q", subject, got, want)
+		}
+	}
+}
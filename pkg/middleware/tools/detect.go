@@ -43,7 +43,7 @@ func DetectWithBufferSize(ifHandler, elseHandler calque.Handler, bufferSize int)
 		bufferSize = 200 // Default buffer size
 	}
 
-	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+	h := calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
 		// Buffer initial chunk for detection
 		initialBuffer := make([]byte, bufferSize)
 		n, err := r.Data.Read(initialBuffer)
@@ -61,6 +61,12 @@ func DetectWithBufferSize(ifHandler, elseHandler calque.Handler, bufferSize int)
 		// Tools detected - buffer full input and pass to ifHandler
 		return bufferToHandler(r.Context, ifHandler, initialChunk, r, w)
 	})
+	return calque.Described(h, func() calque.Node {
+		return calque.Node{Label: "tools.Detect", Kind: calque.NodeBranch, Children: []calque.Node{
+			calque.DescribeHandler(ifHandler).WithEdge("tool calls"),
+			calque.DescribeHandler(elseHandler).WithEdge("no tool calls"),
+		}}
+	}, ifHandler, elseHandler)
 }
 
 // streamToHandler streams the initial chunk and remaining data to the given handler
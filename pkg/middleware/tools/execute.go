@@ -10,6 +10,7 @@ import (
 	"sync"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
 )
 
 // ToolCall represents a parsed tool call from LLM output
@@ -57,6 +58,12 @@ type Config struct {
 	IncludeOriginalOutput bool
 	// RawOutput - if true, returns JSON-marshaled results instead of formatted text
 	RawOutput bool
+	// MaxResultTokens - token limit for each tool result (0 = no limit), see WithResultLimit
+	MaxResultTokens int
+	// ResultSummarizer - shortens results over MaxResultTokens (nil = truncate only)
+	ResultSummarizer calque.Handler
+	// Tokenizer - counts result tokens (default: tokenizer.Approximate)
+	Tokenizer tokenizer.Tokenizer
 }
 
 // Execute parses LLM output for tool calls and executes them using tools from Registry.
@@ -115,6 +122,7 @@ func executeFromBytes(ctx context.Context, inputBytes []byte, w io.Writer, tools
 
 	// Execute tool calls with configuration
 	results := executeToolCallsWithConfig(ctx, tools, toolCalls, config)
+	notifyObservers(ctx, results)
 
 	// Check for errors in tool execution
 	hasErrors := false
@@ -160,6 +168,109 @@ func executeFromBytes(ctx context.Context, inputBytes []byte, w io.Writer, tools
 	return writeErr
 }
 
+// ResultObserver is notified of every tool call executed by Execute
+type ResultObserver func(ctx context.Context, result ToolResult)
+
+type resultObserverKey struct{}
+
+// WithResultObserver returns a context whose tool executions are reported to observer.
+//
+// Observers added to a context that already has one are called after it, so
+// independent middleware can each watch the same executions. Results are
+// reported once all calls in a batch have finished, in the order the model
+// requested them, including calls that failed. Results over
+// Config.MaxResultTokens are reported as the model sees them, after
+// summarization or truncation.
+//
+// Example:
+//
+//	ctx = tools.WithResultObserver(ctx, func(ctx context.Context, r tools.ToolResult) {
+//		log.Printf("tool %s took args %s", r.ToolCall.Name, r.ToolCall.Arguments)
+//	})
+func WithResultObserver(ctx context.Context, observer ResultObserver) context.Context {
+	if parent, ok := ctx.Value(resultObserverKey{}).(ResultObserver); ok {
+		next := observer
+		observer = func(ctx context.Context, result ToolResult) {
+			parent(ctx, result)
+			next(ctx, result)
+		}
+	}
+	return context.WithValue(ctx, resultObserverKey{}, observer)
+}
+
+func notifyObservers(ctx context.Context, results []ToolResult) {
+	observer, ok := ctx.Value(resultObserverKey{}).(ResultObserver)
+	if !ok {
+		return
+	}
+	for _, result := range results {
+		observer(ctx, result)
+	}
+}
+
+// PartialResult is a chunk of output written by a tool that is still running
+type PartialResult struct {
+	ToolCall ToolCall
+	Chunk    []byte
+}
+
+// PartialResultObserver is notified of each write a tool makes to its output
+type PartialResultObserver func(ctx context.Context, partial PartialResult)
+
+type partialResultObserverKey struct{}
+
+// WithPartialResultObserver returns a context whose tool executions report
+// their output to observer as it is written, rather than once the tool finishes.
+//
+// Tools that stream, such as one backed by a gRPC streaming service, produce a
+// chunk per message received; tools that write their result in one go produce
+// a single chunk. The complete result is still buffered and reported to
+// WithResultObserver as usual. Chunks from concurrent tool calls interleave, so
+// use ToolCall.ID to tell them apart. Observers are chained like result
+// observers, and must not retain Chunk after returning.
+//
+// Example:
+//
+//	ctx = tools.WithPartialResultObserver(ctx, func(ctx context.Context, p tools.PartialResult) {
+//		fmt.Printf("[%s] %s", p.ToolCall.Name, p.Chunk)
+//	})
+func WithPartialResultObserver(ctx context.Context, observer PartialResultObserver) context.Context {
+	if parent, ok := ctx.Value(partialResultObserverKey{}).(PartialResultObserver); ok {
+		next := observer
+		observer = func(ctx context.Context, partial PartialResult) {
+			parent(ctx, partial)
+			next(ctx, partial)
+		}
+	}
+	return context.WithValue(ctx, partialResultObserverKey{}, observer)
+}
+
+// partialWriter buffers a tool's output and reports each write as a partial result
+type partialWriter struct {
+	ctx      context.Context
+	toolCall ToolCall
+	observer PartialResultObserver
+	buf      *bytes.Buffer
+}
+
+func (w *partialWriter) Write(p []byte) (int, error) {
+	n, err := w.buf.Write(p)
+	if n > 0 {
+		w.observer(w.ctx, PartialResult{ToolCall: w.toolCall, Chunk: p[:n]})
+	}
+	return n, err
+}
+
+// toolOutput returns the writer a tool's output goes to, reporting partial
+// results when an observer is registered
+func toolOutput(ctx context.Context, toolCall ToolCall, result *bytes.Buffer) io.Writer {
+	observer, ok := ctx.Value(partialResultObserverKey{}).(PartialResultObserver)
+	if !ok {
+		return result
+	}
+	return &partialWriter{ctx: ctx, toolCall: toolCall, observer: observer, buf: result}
+}
+
 // ParseToolCalls extracts tool calls from LLM output using JSON parsing (OpenAI standard)
 func parseToolCalls(output []byte) []ToolCall {
 	// Only JSON format supported (OpenAI standard)
@@ -213,7 +324,7 @@ func parseJSONToolCalls(output []byte) []ToolCall {
 // executeToolCallsWithConfig executes multiple tool calls with configuration
 func executeToolCallsWithConfig(ctx context.Context, tools []Tool, toolCalls []ToolCall, config Config) []ToolResult {
 	if len(toolCalls) == 1 { // Single tool call execute directly
-		return []ToolResult{executeToolCall(ctx, tools, toolCalls[0])}
+		return []ToolResult{limitResult(ctx, executeToolCall(ctx, tools, toolCalls[0]), config)}
 	}
 
 	results := make([]ToolResult, len(toolCalls))
@@ -233,7 +344,7 @@ func executeToolCallsWithConfig(ctx context.Context, tools []Tool, toolCalls []T
 		go func() {
 			defer wg.Done()
 			for i := range jobs {
-				results[i] = executeToolCall(ctx, tools, toolCalls[i])
+				results[i] = limitResult(ctx, executeToolCall(ctx, tools, toolCalls[i]), config)
 			}
 		}()
 	}
@@ -275,10 +386,11 @@ func executeToolCall(ctx context.Context, tools []Tool, toolCall ToolCall) ToolR
 	}
 
 	// Execute the tool with panic recovery
+	ctx, span := startToolSpan(ctx, toolCall)
 	var result bytes.Buffer
 	args := strings.NewReader(toolCall.Arguments)
 	req := calque.NewRequest(ctx, args)
-	res := calque.NewResponse(&result)
+	res := calque.NewResponse(toolOutput(ctx, toolCall, &result))
 
 	// Execute tool with panic recovery
 	var err error
@@ -290,6 +402,7 @@ func executeToolCall(ctx context.Context, tools []Tool, toolCall ToolCall) ToolR
 		}()
 		err = tool.ServeFlow(req, res)
 	}()
+	span.End(err)
 
 	if err != nil {
 		return ToolResult{
@@ -398,3 +511,15 @@ func hasToolCalls(data []byte) bool {
 	// JSON format (OpenAI standard) - high confidence pattern
 	return strings.Contains(content, `{"tool_calls":`)
 }
+
+// startToolSpan starts an "execute_tool {name}" span following the OpenTelemetry GenAI conventions
+func startToolSpan(ctx context.Context, toolCall ToolCall) (context.Context, calque.Span) {
+	ctx, span := calque.StartSpan(ctx, "execute_tool "+toolCall.Name)
+	span.SetAttribute("gen_ai.operation.name", "execute_tool")
+	span.SetAttribute("gen_ai.tool.name", toolCall.Name)
+	span.SetAttribute("gen_ai.tool.type", "function")
+	if toolCall.ID != "" {
+		span.SetAttribute("gen_ai.tool.call.id", toolCall.ID)
+	}
+	return ctx, span
+}
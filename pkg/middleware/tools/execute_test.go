@@ -353,6 +353,48 @@ func TestExecuteToolCall(t *testing.T) {
 	}
 }
 
+type recordedSpan struct {
+	name  string
+	attrs map[string]any
+	err   error
+}
+
+func (s *recordedSpan) SetAttribute(key string, value any) { s.attrs[key] = value }
+func (s *recordedSpan) End(err error)                      { s.err = err }
+
+func TestExecuteToolCallSpan(t *testing.T) {
+	var spans []*recordedSpan
+	ctx := calque.WithSpanStarter(context.Background(), func(ctx context.Context, name string) (context.Context, calque.Span) {
+		span := &recordedSpan{name: name, attrs: map[string]any{}}
+		spans = append(spans, span)
+		return ctx, span
+	})
+
+	tools := []Tool{createMockCalculator(), createErrorTool()}
+	executeToolCall(ctx, tools, ToolCall{Name: "calculator", Arguments: "2+2", ID: "call_1"})
+	executeToolCall(ctx, tools, ToolCall{Name: "error_tool", Arguments: "x"})
+
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if spans[0].name != "execute_tool calculator" || spans[0].err != nil {
+		t.Errorf("calculator span = %+v", spans[0])
+	}
+	for key, want := range map[string]any{
+		"gen_ai.operation.name": "execute_tool",
+		"gen_ai.tool.name":      "calculator",
+		"gen_ai.tool.type":      "function",
+		"gen_ai.tool.call.id":   "call_1",
+	} {
+		if got := spans[0].attrs[key]; got != want {
+			t.Errorf("attribute %s = %v, want %v", key, got, want)
+		}
+	}
+	if spans[1].err == nil {
+		t.Error("failed tool span should end with the error")
+	}
+}
+
 func TestExecuteWithIOError(t *testing.T) {
 	// Create a pipeline with tools to test IO error
 	calc := createMockCalculator()
@@ -535,3 +577,67 @@ func TestExecuteToolCallsConcurrency(t *testing.T) {
 		})
 	}
 }
+
+func TestWithResultObserver(t *testing.T) {
+	var first, second []string
+	ctx := WithResultObserver(context.Background(), func(_ context.Context, r ToolResult) {
+		first = append(first, r.ToolCall.Name+"="+string(r.Result))
+	})
+	ctx = WithResultObserver(ctx, func(_ context.Context, r ToolResult) {
+		second = append(second, r.ToolCall.Name+":"+r.ToolCall.Arguments)
+	})
+
+	input := `{"tool_calls": [{"type": "function", "function": {"name": "calculator", "arguments": "2+2"}}, {"type": "function", "function": {"name": "search", "arguments": "go"}}]}`
+	var buf bytes.Buffer
+	pipeline := NewPipelineForTest([]Tool{createMockCalculator(), createMockSearch()})
+	if err := pipeline.ServeFlow(calque.NewRequest(ctx, strings.NewReader(input)), calque.NewResponse(&buf)); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if strings.Join(first, ",") != "calculator=4,search=search results for: go" {
+		t.Errorf("first observer saw %v", first)
+	}
+	if strings.Join(second, ",") != "calculator:2+2,search:go" {
+		t.Errorf("second observer saw %v", second)
+	}
+}
+
+func TestWithPartialResultObserver(t *testing.T) {
+	stream := HandlerFunc("stream", "Writes in chunks", func(_ *calque.Request, res *calque.Response) error {
+		for _, chunk := range []string{"a", "b", "c"} {
+			if err := calque.Write(res, chunk); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	var first, second []string
+	var results []string
+	ctx := WithPartialResultObserver(context.Background(), func(_ context.Context, p PartialResult) {
+		first = append(first, p.ToolCall.Name+":"+string(p.Chunk))
+	})
+	ctx = WithPartialResultObserver(ctx, func(_ context.Context, p PartialResult) {
+		second = append(second, string(p.Chunk))
+	})
+	ctx = WithResultObserver(ctx, func(_ context.Context, r ToolResult) {
+		results = append(results, string(r.Result))
+	})
+
+	input := `{"tool_calls": [{"type": "function", "function": {"name": "stream", "arguments": "{}"}}]}`
+	var buf bytes.Buffer
+	pipeline := NewPipelineForTest([]Tool{stream})
+	if err := pipeline.ServeFlow(calque.NewRequest(ctx, strings.NewReader(input)), calque.NewResponse(&buf)); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if strings.Join(first, ",") != "stream:a,stream:b,stream:c" {
+		t.Errorf("first observer saw %v", first)
+	}
+	if strings.Join(second, ",") != "a,b,c" {
+		t.Errorf("second observer saw %v", second)
+	}
+	if strings.Join(results, ",") != "abc" {
+		t.Errorf("result observer saw %v, want the complete result", results)
+	}
+}
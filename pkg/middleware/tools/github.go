@@ -0,0 +1,478 @@
+package tools
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultGitHubAPIURL is the GitHub REST API base URL
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// GitHubScope selects which groups of GitHub tools are exposed
+type GitHubScope int
+
+// GitHub tool scopes, combined with |
+const (
+	// GitHubRead exposes read-only tools: PR diffs, issue listing and issue details
+	GitHubRead GitHubScope = 1 << iota
+	// GitHubComment exposes commenting on issues and pull requests
+	GitHubComment
+	// GitHubWrite exposes creating branches and pull requests
+	GitHubWrite
+)
+
+// GitHubConfig holds configuration for the GitHub toolset
+type GitHubConfig struct {
+	// Token authenticates requests; a fine-grained token limited to the needed
+	// repositories and permissions is recommended
+	Token string
+	// Scopes selects the tools exposed (default: GitHubRead)
+	Scopes GitHubScope
+	// Repos limits the tools to these "owner/repo" names (default: any the token can reach)
+	Repos []string
+	// MaxDiffBytes truncates PR diffs to keep them within the model's context (default: 100KB)
+	MaxDiffBytes int
+	// MaxRateLimitWait is the longest a call waits for a rate limit to reset
+	// before failing (default: 1 minute)
+	MaxRateLimitWait time.Duration
+	// APIURL is the REST API base URL, e.g. for GitHub Enterprise Server (default: DefaultGitHubAPIURL)
+	APIURL string
+	// Client is the HTTP client (default: one with a 30 second timeout)
+	Client *http.Client
+}
+
+// GitHub returns read-only tools for code-review and triage agents.
+//
+// Tools: github_get_pr_diff, github_list_issues and github_get_issue. Use
+// GitHubWithConfig to add commenting and branch or pull request creation.
+//
+// Example:
+//
+//	agent := ai.Agent(client, ai.WithTools(tools.GitHub(os.Getenv("GITHUB_TOKEN"))...))
+func GitHub(token string) []Tool {
+	return GitHubWithConfig(&GitHubConfig{Token: token})
+}
+
+// GitHubWithConfig returns the GitHub tools enabled by config.Scopes.
+//
+// Tools by scope:
+//   - GitHubRead: github_get_pr_diff, github_list_issues, github_get_issue
+//   - GitHubComment: github_comment
+//   - GitHubWrite: github_create_branch, github_create_pull_request
+//
+// Calls that hit a rate limit wait for it to reset when that is within
+// MaxRateLimitWait, and otherwise fail with the reset time so the agent can
+// move on. Repositories outside Repos are refused before any request is made.
+//
+// Example:
+//
+//	reviewer := tools.GitHubWithConfig(&tools.GitHubConfig{
+//		Token:  os.Getenv("GITHUB_TOKEN"),
+//		Scopes: tools.GitHubRead | tools.GitHubComment,
+//		Repos:  []string{"acme/api"},
+//	})
+func GitHubWithConfig(config *GitHubConfig) []Tool {
+	cfg := GitHubConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Scopes == 0 {
+		cfg.Scopes = GitHubRead
+	}
+	if cfg.MaxDiffBytes <= 0 {
+		cfg.MaxDiffBytes = 100 << 10
+	}
+	if cfg.MaxRateLimitWait == 0 {
+		cfg.MaxRateLimitWait = time.Minute
+	}
+	if cfg.APIURL == "" {
+		cfg.APIURL = DefaultGitHubAPIURL
+	}
+	cfg.APIURL = strings.TrimSuffix(cfg.APIURL, "/")
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	gh := &githubClient{config: cfg}
+	var toolset []Tool
+	if cfg.Scopes&GitHubRead != 0 {
+		toolset = append(toolset,
+			typedTool("github_get_pr_diff", "Get the unified diff of a GitHub pull request", gh.getPRDiff),
+			typedTool("github_list_issues", "List issues in a GitHub repository, newest first; pull requests are included and marked", gh.listIssues),
+			typedTool("github_get_issue", "Get a GitHub issue or pull request with its description and recent comments", gh.getIssue),
+		)
+	}
+	if cfg.Scopes&GitHubComment != 0 {
+		toolset = append(toolset,
+			typedTool("github_comment", "Add a comment to a GitHub issue or pull request", gh.comment),
+		)
+	}
+	if cfg.Scopes&GitHubWrite != 0 {
+		toolset = append(toolset,
+			typedTool("github_create_branch", "Create a branch in a GitHub repository from another branch", gh.createBranch),
+			typedTool("github_create_pull_request", "Open a GitHub pull request", gh.createPullRequest),
+		)
+	}
+	return callsHosts(toolset, cfg.APIURL)
+}
+
+// githubRepo identifies the repository every tool works on
+type githubRepo struct {
+	Owner string `json:"owner" jsonschema:"description=Repository owner (user or organization)"`
+	Repo  string `json:"repo" jsonschema:"description=Repository name"`
+}
+
+type githubNumberArgs struct {
+	githubRepo
+	Number int `json:"number" jsonschema:"description=Issue or pull request number"`
+}
+
+type githubListIssuesArgs struct {
+	githubRepo
+	State  string   `json:"state,omitempty" jsonschema:"enum=open,enum=closed,enum=all,description=Issue state (default: open)"`
+	Labels []string `json:"labels,omitempty" jsonschema:"description=Only issues with all of these labels"`
+	Limit  int      `json:"limit,omitempty" jsonschema:"minimum=1,maximum=100,description=Maximum issues to return (default: 20)"`
+}
+
+type githubCommentArgs struct {
+	githubRepo
+	Number int    `json:"number" jsonschema:"description=Issue or pull request number"`
+	Body   string `json:"body" jsonschema:"description=Comment text (Markdown)"`
+}
+
+type githubCreateBranchArgs struct {
+	githubRepo
+	Branch string `json:"branch" jsonschema:"description=Name of the new branch"`
+	From   string `json:"from,omitempty" jsonschema:"description=Branch to start from (default: the repository's default branch)"`
+}
+
+type githubCreatePRArgs struct {
+	githubRepo
+	Title string `json:"title" jsonschema:"description=Pull request title"`
+	Head  string `json:"head" jsonschema:"description=Branch containing the changes"`
+	Base  string `json:"base,omitempty" jsonschema:"description=Branch to merge into (default: the repository's default branch)"`
+	Body  string `json:"body,omitempty" jsonschema:"description=Pull request description (Markdown)"`
+	Draft bool   `json:"draft,omitempty" jsonschema:"description=Open as a draft"`
+}
+
+// githubIssue is the compact issue summary returned to the model
+type githubIssue struct {
+	Number      int      `json:"number"`
+	Title       string   `json:"title"`
+	State       string   `json:"state"`
+	Author      string   `json:"author"`
+	Labels      []string `json:"labels,omitempty"`
+	URL         string   `json:"url"`
+	PullRequest bool     `json:"pull_request,omitempty"`
+	Body        string   `json:"body,omitempty"`
+	Comments    []string `json:"comments,omitempty"`
+}
+
+// githubAPIIssue is the subset of the REST issue object the tools read
+type githubAPIIssue struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	State   string `json:"state"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+	User    struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+func (i githubAPIIssue) summary() githubIssue {
+	issue := githubIssue{
+		Number:      i.Number,
+		Title:       i.Title,
+		State:       i.State,
+		Author:      i.User.Login,
+		URL:         i.HTMLURL,
+		PullRequest: i.PullRequest != nil,
+	}
+	for _, label := range i.Labels {
+		issue.Labels = append(issue.Labels, label.Name)
+	}
+	return issue
+}
+
+type githubClient struct {
+	config GitHubConfig
+}
+
+func (g *githubClient) getPRDiff(ctx context.Context, args githubNumberArgs) (any, error) {
+	if err := g.check(ctx, args.githubRepo); err != nil {
+		return nil, err
+	}
+	var diff []byte
+	path := fmt.Sprintf("%s/pulls/%d", args.path(), args.Number)
+	if err := g.do(ctx, http.MethodGet, path, nil, "application/vnd.github.diff", &diff); err != nil {
+		return nil, err
+	}
+	if len(diff) > g.config.MaxDiffBytes {
+		cut := bytes.LastIndexByte(diff[:g.config.MaxDiffBytes], '\n') + 1
+		return fmt.Sprintf("%s\n[diff truncated: showing %d of %d bytes]", diff[:cut], cut, len(diff)), nil
+	}
+	return string(diff), nil
+}
+
+func (g *githubClient) listIssues(ctx context.Context, args githubListIssuesArgs) (any, error) {
+	if err := g.check(ctx, args.githubRepo); err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set("state", cmp.Or(args.State, "open"))
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	query.Set("per_page", strconv.Itoa(min(limit, 100)))
+	if len(args.Labels) > 0 {
+		query.Set("labels", strings.Join(args.Labels, ","))
+	}
+
+	var issues []githubAPIIssue
+	if err := g.do(ctx, http.MethodGet, args.path()+"/issues?"+query.Encode(), nil, "", &issues); err != nil {
+		return nil, err
+	}
+	summaries := make([]githubIssue, len(issues))
+	for i, issue := range issues {
+		summaries[i] = issue.summary()
+	}
+	return summaries, nil
+}
+
+func (g *githubClient) getIssue(ctx context.Context, args githubNumberArgs) (any, error) {
+	if err := g.check(ctx, args.githubRepo); err != nil {
+		return nil, err
+	}
+	var issue githubAPIIssue
+	path := fmt.Sprintf("%s/issues/%d", args.path(), args.Number)
+	if err := g.do(ctx, http.MethodGet, path, nil, "", &issue); err != nil {
+		return nil, err
+	}
+	var comments []struct {
+		Body string `json:"body"`
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	}
+	// The 20 most recent comments keep long threads within the context window
+	if err := g.do(ctx, http.MethodGet, path+"/comments?per_page=20&sort=created&direction=desc", nil, "", &comments); err != nil {
+		return nil, err
+	}
+
+	summary := issue.summary()
+	summary.Body = issue.Body
+	for i := len(comments) - 1; i >= 0; i-- {
+		summary.Comments = append(summary.Comments, comments[i].User.Login+": "+comments[i].Body)
+	}
+	return summary, nil
+}
+
+func (g *githubClient) comment(ctx context.Context, args githubCommentArgs) (any, error) {
+	if err := g.check(ctx, args.githubRepo); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(args.Body) == "" {
+		return nil, calque.NewErr(ctx, "comment body is required")
+	}
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	path := fmt.Sprintf("%s/issues/%d/comments", args.path(), args.Number)
+	if err := g.do(ctx, http.MethodPost, path, map[string]string{"body": args.Body}, "", &created); err != nil {
+		return nil, err
+	}
+	return map[string]string{"url": created.HTMLURL}, nil
+}
+
+func (g *githubClient) createBranch(ctx context.Context, args githubCreateBranchArgs) (any, error) {
+	if err := g.check(ctx, args.githubRepo); err != nil {
+		return nil, err
+	}
+	from := args.From
+	if from == "" {
+		var err error
+		if from, err = g.defaultBranch(ctx, args.githubRepo); err != nil {
+			return nil, err
+		}
+	}
+
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := g.do(ctx, http.MethodGet, args.path()+"/git/ref/heads/"+url.PathEscape(from), nil, "", &ref); err != nil {
+		return nil, err
+	}
+	body := map[string]string{"ref": "refs/heads/" + args.Branch, "sha": ref.Object.SHA}
+	if err := g.do(ctx, http.MethodPost, args.path()+"/git/refs", body, "", nil); err != nil {
+		return nil, err
+	}
+	return map[string]string{"branch": args.Branch, "from": from, "sha": ref.Object.SHA}, nil
+}
+
+func (g *githubClient) createPullRequest(ctx context.Context, args githubCreatePRArgs) (any, error) {
+	if err := g.check(ctx, args.githubRepo); err != nil {
+		return nil, err
+	}
+	base := args.Base
+	if base == "" {
+		var err error
+		if base, err = g.defaultBranch(ctx, args.githubRepo); err != nil {
+			return nil, err
+		}
+	}
+
+	var created struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	body := map[string]any{"title": args.Title, "head": args.Head, "base": base, "body": args.Body, "draft": args.Draft}
+	if err := g.do(ctx, http.MethodPost, args.path()+"/pulls", body, "", &created); err != nil {
+		return nil, err
+	}
+	return map[string]any{"number": created.Number, "url": created.HTMLURL}, nil
+}
+
+func (g *githubClient) defaultBranch(ctx context.Context, repo githubRepo) (string, error) {
+	var info struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := g.do(ctx, http.MethodGet, repo.path(), nil, "", &info); err != nil {
+		return "", err
+	}
+	return info.DefaultBranch, nil
+}
+
+func (r githubRepo) path() string {
+	return "/repos/" + url.PathEscape(r.Owner) + "/" + url.PathEscape(r.Repo)
+}
+
+// check validates the repository and enforces the Repos allowlist
+func (g *githubClient) check(ctx context.Context, repo githubRepo) error {
+	if repo.Owner == "" || repo.Repo == "" {
+		return calque.NewErr(ctx, "owner and repo are required")
+	}
+	name := repo.Owner + "/" + repo.Repo
+	if len(g.config.Repos) > 0 && !slices.ContainsFunc(g.config.Repos, func(allowed string) bool {
+		return strings.EqualFold(allowed, name)
+	}) {
+		return calque.NewErr(ctx, fmt.Sprintf("repository %s is not allowed", name))
+	}
+	return nil
+}
+
+// do calls the REST API, waiting out rate limits up to MaxRateLimitWait.
+//
+// out receives the decoded JSON response, or the raw body when it is a *[]byte.
+func (g *githubClient) do(ctx context.Context, method, path string, body any, accept string, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return calque.WrapErr(ctx, err, "failed to encode GitHub request")
+		}
+	}
+	if accept == "" {
+		accept = "application/vnd.github+json"
+	}
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, method, g.config.APIURL+path, bytes.NewReader(payload))
+		if err != nil {
+			return calque.WrapErr(ctx, err, "failed to create GitHub request")
+		}
+		req.Header.Set("Accept", accept)
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		if g.config.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+g.config.Token)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := g.config.Client.Do(req)
+		if err != nil {
+			return calque.WrapErr(ctx, err, "GitHub request failed")
+		}
+		data, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return calque.WrapErr(ctx, err, "failed to read GitHub response")
+		}
+
+		if wait, limited := githubRateLimitWait(resp, time.Now()); limited {
+			if wait > g.config.MaxRateLimitWait {
+				return calque.NewErr(ctx, fmt.Sprintf("GitHub rate limit exceeded, resets in %s", wait.Round(time.Second)))
+			}
+			calque.LogWarn(ctx, "GitHub rate limit hit, waiting", "wait", wait)
+			select {
+			case <-time.After(wait):
+				continue
+			case <-ctx.Done():
+				return calque.WrapErr(ctx, ctx.Err(), "waiting for GitHub rate limit")
+			}
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			var apiErr struct {
+				Message string `json:"message"`
+			}
+			_ = json.Unmarshal(data, &apiErr)
+			return calque.NewErr(ctx, fmt.Sprintf("GitHub %s %s: %d %s", method, path, resp.StatusCode, cmp.Or(apiErr.Message, http.StatusText(resp.StatusCode))))
+		}
+
+		switch out := out.(type) {
+		case nil:
+			return nil
+		case *[]byte:
+			*out = data
+			return nil
+		default:
+			if err := json.Unmarshal(data, out); err != nil {
+				return calque.WrapErr(ctx, err, "invalid GitHub response")
+			}
+			return nil
+		}
+	}
+}
+
+// githubRateLimitWait reports whether resp is a rate limit rejection and how
+// long to wait before retrying. Secondary limits send Retry-After; primary
+// limits exhaust X-RateLimit-Remaining and reset at X-RateLimit-Reset.
+func githubRateLimitWait(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(now), time.Second), true
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		// No hint: GitHub recommends waiting at least a minute
+		return time.Minute, true
+	}
+	return 0, false
+}
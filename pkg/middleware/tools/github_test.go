@@ -0,0 +1,352 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// fakeGitHub serves the REST endpoints the GitHub tools call and records writes
+type fakeGitHub struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   map[string]map[string]any
+	limited  atomic.Int32 // responses left to rate limit
+}
+
+func newFakeGitHub(t *testing.T) (*fakeGitHub, *httptest.Server) {
+	t.Helper()
+
+	fake := &fakeGitHub{bodies: map[string]map[string]any{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/acme/api", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"default_branch":"main"}`)
+	})
+	mux.HandleFunc("GET /repos/acme/api/pulls/7", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/vnd.github.diff" {
+			http.Error(w, `{"message":"wrong accept"}`, http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, "diff --git a/x b/x\n+one\n+two\n+three\n")
+	})
+	mux.HandleFunc("GET /repos/acme/api/issues", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{"number":1,"title":"crash","state":%q,"html_url":"u1","user":{"login":"ann"},"labels":[{"name":"bug"}]},
+			{"number":2,"title":"fix crash","state":"open","html_url":"u2","user":{"login":"bob"},"pull_request":{}}]`, r.URL.Query().Get("state"))
+	})
+	mux.HandleFunc("GET /repos/acme/api/issues/1", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"number":1,"title":"crash","state":"open","body":"it crashes","html_url":"u1","user":{"login":"ann"}}`)
+	})
+	mux.HandleFunc("GET /repos/acme/api/issues/1/comments", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `[{"body":"still broken","user":{"login":"bob"}},{"body":"repro?","user":{"login":"ann"}}]`)
+	})
+	mux.HandleFunc("GET /repos/acme/api/git/ref/heads/main", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"object":{"sha":"abc123"}}`)
+	})
+	mux.HandleFunc("POST /repos/acme/api/issues/1/comments", fake.record(`{"html_url":"c1"}`))
+	mux.HandleFunc("POST /repos/acme/api/git/refs", fake.record(`{}`))
+	mux.HandleFunc("POST /repos/acme/api/pulls", fake.record(`{"number":8,"html_url":"p8"}`))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		fake.requests = append(fake.requests, r.Method+" "+r.URL.Path)
+		fake.mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		if fake.limited.Add(-1) >= 0 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", fmt.Sprint(time.Now().Unix()))
+			http.Error(w, `{"message":"API rate limit exceeded"}`, http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeGitHub) record(response string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.mu.Lock()
+		f.bodies[r.URL.Path] = body
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, response)
+	}
+}
+
+func runTool(t *testing.T, toolset []Tool, name, args string) (string, error) {
+	t.Helper()
+
+	for _, tool := range toolset {
+		if tool.Name() != name {
+			continue
+		}
+		var out strings.Builder
+		err := tool.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader(args)), calque.NewResponse(&out))
+		return out.String(), err
+	}
+	t.Fatalf("tool %s not in toolset", name)
+	return "", nil
+}
+
+func TestGitHubScopes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		scopes GitHubScope
+		want   []string
+	}{
+		{name: "default read only", want: []string{"github_get_pr_diff", "github_list_issues", "github_get_issue"}},
+		{name: "comment only", scopes: GitHubComment, want: []string{"github_comment"}},
+		{
+			name:   "all",
+			scopes: GitHubRead | GitHubComment | GitHubWrite,
+			want: []string{"github_get_pr_diff", "github_list_issues", "github_get_issue",
+				"github_comment", "github_create_branch", "github_create_pull_request"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var names []string
+			for _, tool := range GitHubWithConfig(&GitHubConfig{Token: "t", Scopes: tt.scopes}) {
+				names = append(names, tool.Name())
+			}
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("tools = %v, want %v", names, tt.want)
+			}
+		})
+	}
+
+	if len(GitHub("t")) != 3 {
+		t.Errorf("GitHub() returned %d tools, want the 3 read tools", len(GitHub("t")))
+	}
+}
+
+func TestGitHubSchema(t *testing.T) {
+	t.Parallel()
+
+	var comment Tool
+	for _, tool := range GitHubWithConfig(&GitHubConfig{Scopes: GitHubComment}) {
+		comment = tool
+	}
+	schema := comment.ParametersSchema()
+	if schema.Type != "object" {
+		t.Errorf("schema type = %q, want object", schema.Type)
+	}
+	if strings.Join(schema.Required, ",") != "owner,repo,number,body" {
+		t.Errorf("required = %v", schema.Required)
+	}
+	if prop, ok := schema.Properties.Get("number"); !ok || prop.Type != "integer" {
+		t.Errorf("number property = %+v", prop)
+	}
+}
+
+func TestGitHubTools(t *testing.T) {
+	t.Parallel()
+
+	fake, server := newFakeGitHub(t)
+	toolset := GitHubWithConfig(&GitHubConfig{
+		Token:        "t0ken",
+		Scopes:       GitHubRead | GitHubComment | GitHubWrite,
+		Repos:        []string{"Acme/API"},
+		MaxDiffBytes: 30,
+		APIURL:       server.URL + "/",
+	})
+
+	tests := []struct {
+		name    string
+		tool    string
+		args    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "pr diff truncated at a line",
+			tool: "github_get_pr_diff",
+			args: `{"owner":"acme","repo":"api","number":7}`,
+			want: "diff --git a/x b/x\n+one\n+two\n\n[diff truncated: showing 29 of 36 bytes]",
+		},
+		{
+			name: "list issues",
+			tool: "github_list_issues",
+			args: `{"owner":"acme","repo":"api","state":"closed"}`,
+			want: `[{"number":1,"title":"crash","state":"closed","author":"ann","labels":["bug"],"url":"u1"},` +
+				`{"number":2,"title":"fix crash","state":"open","author":"bob","url":"u2","pull_request":true}]`,
+		},
+		{
+			name: "issue with comments oldest first",
+			tool: "github_get_issue",
+			args: `{"owner":"acme","repo":"api","number":1}`,
+			want: `{"number":1,"title":"crash","state":"open","author":"ann","url":"u1","body":"it crashes","comments":["ann: repro?","bob: still broken"]}`,
+		},
+		{
+			name: "comment",
+			tool: "github_comment",
+			args: `{"owner":"acme","repo":"api","number":1,"body":"Looking into it"}`,
+			want: `{"url":"c1"}`,
+		},
+		{
+			name: "branch from default branch",
+			tool: "github_create_branch",
+			args: `{"owner":"acme","repo":"api","branch":"fix/crash"}`,
+			want: `{"branch":"fix/crash","from":"main","sha":"abc123"}`,
+		},
+		{
+			name: "pull request",
+			tool: "github_create_pull_request",
+			args: `{"owner":"acme","repo":"api","title":"Fix crash","head":"fix/crash","draft":true}`,
+			want: `{"number":8,"url":"p8"}`,
+		},
+		{
+			name:    "repo not allowed",
+			tool:    "github_get_issue",
+			args:    `{"owner":"acme","repo":"secrets","number":1}`,
+			wantErr: "repository acme/secrets is not allowed",
+		},
+		{
+			name:    "missing repo",
+			tool:    "github_get_issue",
+			args:    `{"number":1}`,
+			wantErr: "owner and repo are required",
+		},
+		{
+			name:    "unknown argument",
+			tool:    "github_get_issue",
+			args:    `{"owner":"acme","repo":"api","id":1}`,
+			wantErr: "invalid arguments for github_get_issue",
+		},
+		{
+			name:    "api error",
+			tool:    "github_get_issue",
+			args:    `{"owner":"acme","repo":"api","number":404}`,
+			wantErr: "404",
+		},
+		{
+			name:    "empty comment",
+			tool:    "github_comment",
+			args:    `{"owner":"acme","repo":"api","number":1,"body":" "}`,
+			wantErr: "comment body is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := runTool(t, toolset, tt.tool, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Cleanup(func() {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		if got := fake.bodies["/repos/acme/api/git/refs"]; got["ref"] != "refs/heads/fix/crash" || got["sha"] != "abc123" {
+			t.Errorf("create ref body = %v", got)
+		}
+		if got := fake.bodies["/repos/acme/api/pulls"]; got["base"] != "main" || got["draft"] != true {
+			t.Errorf("create pull request body = %v", got)
+		}
+		for _, request := range fake.requests {
+			if strings.Contains(request, "secrets") {
+				t.Errorf("request %q sent for a repository outside the allowlist", request)
+			}
+		}
+	})
+}
+
+func TestGitHubRateLimit(t *testing.T) {
+	t.Parallel()
+
+	fake, server := newFakeGitHub(t)
+	fake.limited.Store(1)
+	toolset := GitHubWithConfig(&GitHubConfig{Token: "t0ken", APIURL: server.URL, MaxRateLimitWait: 5 * time.Second})
+
+	got, err := runTool(t, toolset, "github_get_issue", `{"owner":"acme","repo":"api","number":1}`)
+	if err != nil || !strings.Contains(got, `"title":"crash"`) {
+		t.Fatalf("output = %q, error = %v; want the call retried after the reset", got, err)
+	}
+
+	fake.limited.Store(1)
+	impatient := GitHubWithConfig(&GitHubConfig{Token: "t0ken", APIURL: server.URL, MaxRateLimitWait: time.Millisecond})
+	_, err = runTool(t, impatient, "github_get_issue", `{"owner":"acme","repo":"api","number":1}`)
+	if err == nil || !strings.Contains(err.Error(), "rate limit exceeded") {
+		t.Errorf("error = %v, want rate limit error when the reset is too far away", err)
+	}
+}
+
+func TestGitHubRateLimitWait(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name        string
+		status      int
+		headers     map[string]string
+		wantWait    time.Duration
+		wantLimited bool
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "forbidden", status: http.StatusForbidden},
+		{name: "retry after", status: http.StatusForbidden, headers: map[string]string{"Retry-After": "30"}, wantWait: 30 * time.Second, wantLimited: true},
+		{
+			name:        "primary limit",
+			status:      http.StatusForbidden,
+			headers:     map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1700000045"},
+			wantWait:    45 * time.Second,
+			wantLimited: true,
+		},
+		{
+			name:        "reset already passed",
+			status:      http.StatusTooManyRequests,
+			headers:     map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1699999999"},
+			wantWait:    time.Second,
+			wantLimited: true,
+		},
+		{name: "too many requests without hint", status: http.StatusTooManyRequests, wantWait: time.Minute, wantLimited: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}
+			for k, v := range tt.headers {
+				resp.Header.Set(k, v)
+			}
+			wait, limited := githubRateLimitWait(resp, now)
+			if wait != tt.wantWait || limited != tt.wantLimited {
+				t.Errorf("githubRateLimitWait() = %v, %v; want %v, %v", wait, limited, tt.wantWait, tt.wantLimited)
+			}
+		})
+	}
+}
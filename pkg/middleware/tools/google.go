@@ -0,0 +1,370 @@
+package tools
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Google API base URLs
+const (
+	DefaultGoogleCalendarURL = "https://www.googleapis.com/calendar/v3"
+	DefaultGmailURL          = "https://gmail.googleapis.com/gmail/v1"
+)
+
+// GoogleWorkspaceConfig holds configuration for the Google Workspace toolset
+type GoogleWorkspaceConfig struct {
+	// TokenSource supplies OAuth tokens and refreshes them (required); request
+	// Scopes.GoogleOAuthScopes() when obtaining consent
+	TokenSource oauth2.TokenSource
+	// Scopes selects the tools exposed (default: CalendarRead | MailRead)
+	Scopes AssistantScope
+	// CalendarID is the calendar to read (default: "primary")
+	CalendarID string
+	// CalendarURL and GmailURL are the API base URLs (default: the public Google endpoints)
+	CalendarURL string
+	GmailURL    string
+	// Client is the base HTTP client (default: one with a 30 second timeout)
+	Client *http.Client
+}
+
+// GoogleWorkspace returns read-only calendar and Gmail tools for assistant agents.
+//
+// Tools: google_list_events, google_search_mail and google_get_message. Use
+// GoogleWorkspaceWithConfig with MailDraft to add google_draft_reply.
+//
+// Example:
+//
+//	conf := &oauth2.Config{ /* client ID, secret, google.Endpoint */ Scopes: tools.CalendarRead.GoogleOAuthScopes()}
+//	assistant := ai.Agent(client, ai.WithTools(tools.GoogleWorkspace(conf.TokenSource(ctx, token))...))
+func GoogleWorkspace(creds oauth2.TokenSource) []Tool {
+	return GoogleWorkspaceWithConfig(&GoogleWorkspaceConfig{TokenSource: creds})
+}
+
+// GoogleWorkspaceWithConfig returns the Google Workspace tools enabled by config.Scopes.
+//
+// Tools by scope:
+//   - CalendarRead: google_list_events
+//   - MailRead: google_search_mail (Gmail search syntax), google_get_message
+//   - MailDraft: google_draft_reply, which saves a threaded draft and never sends
+//
+// Example:
+//
+//	scopes := tools.CalendarRead | tools.MailRead | tools.MailDraft
+//	assistant := tools.GoogleWorkspaceWithConfig(&tools.GoogleWorkspaceConfig{
+//		TokenSource: conf.TokenSource(ctx, token), // conf.Scopes = scopes.GoogleOAuthScopes()
+//		Scopes:      scopes,
+//	})
+func GoogleWorkspaceWithConfig(config *GoogleWorkspaceConfig) []Tool {
+	cfg := GoogleWorkspaceConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Scopes == 0 {
+		cfg.Scopes = CalendarRead | MailRead
+	}
+	cfg.CalendarID = cmp.Or(cfg.CalendarID, "primary")
+	cfg.CalendarURL = strings.TrimSuffix(cmp.Or(cfg.CalendarURL, DefaultGoogleCalendarURL), "/")
+	cfg.GmailURL = strings.TrimSuffix(cmp.Or(cfg.GmailURL, DefaultGmailURL), "/")
+
+	google := &googleClient{config: cfg, now: time.Now}
+	if cfg.TokenSource != nil {
+		google.client = oauthHTTPClient(cfg.TokenSource, cfg.Client)
+	}
+
+	var toolset []Tool
+	if cfg.Scopes&CalendarRead != 0 {
+		toolset = append(toolset, typedTool("google_list_events", "List Google Calendar events in a time range", google.listEvents))
+	}
+	if cfg.Scopes&MailRead != 0 {
+		toolset = append(toolset,
+			typedTool("google_search_mail", "Search Gmail using Gmail search syntax, newest first", google.searchMail),
+			typedTool("google_get_message", "Get a Gmail message with its plain text body", google.getMessage),
+		)
+	}
+	if cfg.Scopes&MailDraft != 0 {
+		toolset = append(toolset, typedTool("google_draft_reply", "Save a draft reply to a Gmail message without sending it", google.draftReply))
+	}
+	return callsHosts(toolset, cfg.CalendarURL, cfg.GmailURL)
+}
+
+type googleClient struct {
+	config GoogleWorkspaceConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+type googleEventTime struct {
+	DateTime string `json:"dateTime"`
+	Date     string `json:"date"`
+}
+
+func (t googleEventTime) String() string {
+	return cmp.Or(t.DateTime, t.Date)
+}
+
+func (g *googleClient) listEvents(ctx context.Context, args listEventsArgs) (any, error) {
+	from, to, err := args.eventRange(ctx, g.now())
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set("timeMin", from.Format(time.RFC3339))
+	query.Set("timeMax", to.Format(time.RFC3339))
+	query.Set("singleEvents", "true")
+	query.Set("orderBy", "startTime")
+	query.Set("maxResults", strconv.Itoa(limitOr(args.Limit, 20, 100)))
+	if args.Query != "" {
+		query.Set("q", args.Query)
+	}
+
+	var result struct {
+		Items []struct {
+			ID        string          `json:"id"`
+			Summary   string          `json:"summary"`
+			Location  string          `json:"location"`
+			HTMLLink  string          `json:"htmlLink"`
+			Start     googleEventTime `json:"start"`
+			End       googleEventTime `json:"end"`
+			Organizer struct {
+				Email string `json:"email"`
+			} `json:"organizer"`
+			Attendees []struct {
+				Email string `json:"email"`
+			} `json:"attendees"`
+		} `json:"items"`
+	}
+	path := "/calendars/" + url.PathEscape(g.config.CalendarID) + "/events?" + query.Encode()
+	if err := g.do(ctx, http.MethodGet, g.config.CalendarURL+path, nil, &result); err != nil {
+		return nil, err
+	}
+
+	events := make([]calendarEvent, len(result.Items))
+	for i, item := range result.Items {
+		events[i] = calendarEvent{
+			ID:        item.ID,
+			Title:     item.Summary,
+			Start:     item.Start.String(),
+			End:       item.End.String(),
+			Location:  item.Location,
+			Organizer: item.Organizer.Email,
+			URL:       item.HTMLLink,
+		}
+		for _, attendee := range item.Attendees {
+			events[i].Attendees = append(events[i].Attendees, attendee.Email)
+		}
+	}
+	return events, nil
+}
+
+// gmailMessage is the subset of the Gmail message resource the tools read
+type gmailMessage struct {
+	ID       string    `json:"id"`
+	ThreadID string    `json:"threadId"`
+	Snippet  string    `json:"snippet"`
+	Payload  gmailPart `json:"payload"`
+	headers  mail.Header
+}
+
+type gmailPart struct {
+	MimeType string `json:"mimeType"`
+	Headers  []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"headers"`
+	Body struct {
+		Data string `json:"data"`
+	} `json:"body"`
+	Parts []gmailPart `json:"parts"`
+}
+
+func (m *gmailMessage) header(name string) string {
+	if m.headers == nil {
+		m.headers = mail.Header{}
+		for _, h := range m.Payload.Headers {
+			key := http.CanonicalHeaderKey(h.Name)
+			m.headers[key] = append(m.headers[key], h.Value)
+		}
+	}
+	return m.headers.Get(name)
+}
+
+// addresses parses an address header, keeping unparseable values as is
+func (m *gmailMessage) addresses(name string) []string {
+	value := m.header(name)
+	if value == "" {
+		return nil
+	}
+	list, err := mail.ParseAddressList(value)
+	if err != nil {
+		return []string{value}
+	}
+	addresses := make([]string, len(list))
+	for i, address := range list {
+		addresses[i] = address.Address
+	}
+	return addresses
+}
+
+// text returns the first text/plain body in the MIME tree
+func (p gmailPart) text() (string, bool) {
+	if strings.HasPrefix(p.MimeType, "text/plain") && p.Body.Data != "" {
+		data, err := base64.URLEncoding.DecodeString(p.Body.Data)
+		if err != nil {
+			data, err = base64.RawURLEncoding.DecodeString(p.Body.Data)
+		}
+		return string(data), err == nil
+	}
+	for _, part := range p.Parts {
+		if text, ok := part.text(); ok {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (g *googleClient) searchMail(ctx context.Context, args searchMailArgs) (any, error) {
+	query := url.Values{}
+	query.Set("maxResults", strconv.Itoa(limitOr(args.Limit, 10, 50)))
+	if args.Query != "" {
+		query.Set("q", args.Query)
+	}
+	var list struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+	}
+	if err := g.do(ctx, http.MethodGet, g.config.GmailURL+"/users/me/messages?"+query.Encode(), nil, &list); err != nil {
+		return nil, err
+	}
+
+	results := make([]mailSummary, len(list.Messages))
+	for i, item := range list.Messages {
+		message, err := g.message(ctx, item.ID, "metadata")
+		if err != nil {
+			return nil, err
+		}
+		results[i] = mailSummary{
+			ID:      message.ID,
+			From:    message.header("From"),
+			Subject: message.header("Subject"),
+			Date:    message.header("Date"),
+			Snippet: message.Snippet,
+		}
+	}
+	return results, nil
+}
+
+func (g *googleClient) getMessage(ctx context.Context, args getMessageArgs) (any, error) {
+	message, err := g.message(ctx, args.ID, "full")
+	if err != nil {
+		return nil, err
+	}
+	body, ok := message.Payload.text()
+	if !ok {
+		body = message.Snippet
+	}
+	return mailMessage{
+		ID:      message.ID,
+		From:    message.header("From"),
+		To:      message.addresses("To"),
+		Cc:      message.addresses("Cc"),
+		Subject: message.header("Subject"),
+		Date:    message.header("Date"),
+		Body:    body,
+	}, nil
+}
+
+func (g *googleClient) draftReply(ctx context.Context, args draftReplyArgs) (any, error) {
+	if strings.TrimSpace(args.Body) == "" {
+		return nil, calque.NewErr(ctx, "body is required")
+	}
+	original, err := g.message(ctx, args.MessageID, "metadata")
+	if err != nil {
+		return nil, err
+	}
+
+	to := original.addresses("Reply-To")
+	if len(to) == 0 {
+		to = original.addresses("From")
+	}
+	var cc []string
+	if args.ReplyAll {
+		var profile struct {
+			EmailAddress string `json:"emailAddress"`
+		}
+		if err := g.do(ctx, http.MethodGet, g.config.GmailURL+"/users/me/profile", nil, &profile); err != nil {
+			return nil, err
+		}
+		for _, address := range append(original.addresses("To"), original.addresses("Cc")...) {
+			if !strings.EqualFold(address, profile.EmailAddress) && !slices.Contains(to, address) && !slices.Contains(cc, address) {
+				cc = append(cc, address)
+			}
+		}
+	}
+	subject := replySubject(original.header("Subject"))
+
+	var raw bytes.Buffer
+	fmt.Fprintf(&raw, "To: %s\r\n", strings.Join(to, ", "))
+	if len(cc) > 0 {
+		fmt.Fprintf(&raw, "Cc: %s\r\n", strings.Join(cc, ", "))
+	}
+	fmt.Fprintf(&raw, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	if id := original.header("Message-Id"); id != "" {
+		references := strings.TrimSpace(original.header("References") + " " + id)
+		fmt.Fprintf(&raw, "In-Reply-To: %s\r\nReferences: %s\r\n", id, references)
+	}
+	raw.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	raw.WriteString(strings.ReplaceAll(args.Body, "\n", "\r\n"))
+
+	var draft struct {
+		ID string `json:"id"`
+	}
+	body := map[string]any{"message": map[string]string{
+		"raw":      base64.URLEncoding.EncodeToString(raw.Bytes()),
+		"threadId": original.ThreadID,
+	}}
+	if err := g.do(ctx, http.MethodPost, g.config.GmailURL+"/users/me/drafts", body, &draft); err != nil {
+		return nil, err
+	}
+	return mailDraft{DraftID: draft.ID, To: to, Cc: cc, Subject: subject}, nil
+}
+
+func (g *googleClient) message(ctx context.Context, id, format string) (*gmailMessage, error) {
+	if id == "" {
+		return nil, calque.NewErr(ctx, "message id is required")
+	}
+	query := url.Values{}
+	query.Set("format", format)
+	if format == "metadata" {
+		for _, header := range []string{"From", "To", "Cc", "Reply-To", "Subject", "Date", "Message-ID", "References"} {
+			query.Add("metadataHeaders", header)
+		}
+	}
+	var message gmailMessage
+	if err := g.do(ctx, http.MethodGet, g.config.GmailURL+"/users/me/messages/"+url.PathEscape(id)+"?"+query.Encode(), nil, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (g *googleClient) do(ctx context.Context, method, url string, body, out any) error {
+	if g.client == nil {
+		return calque.NewErr(ctx, "google workspace token source is required")
+	}
+	return apiRequest{service: "Google", method: method, url: url, body: body}.do(ctx, g.client, out)
+}
@@ -0,0 +1,216 @@
+package tools
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// refreshingSource issues a new token on each call and counts them
+type refreshingSource struct {
+	issued atomic.Int32
+}
+
+func (s *refreshingSource) Token() (*oauth2.Token, error) {
+	n := s.issued.Add(1)
+	return &oauth2.Token{AccessToken: fmt.Sprintf("token-%d", n), Expiry: time.Now().Add(time.Hour)}, nil
+}
+
+func gmailHeaders(headers ...string) string {
+	var parts []string
+	for i := 0; i < len(headers); i += 2 {
+		parts = append(parts, fmt.Sprintf(`{"name":%q,"value":%q}`, headers[i], headers[i+1]))
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+func newFakeGoogle(t *testing.T) (*httptest.Server, *sync.Map) {
+	t.Helper()
+
+	var captured sync.Map
+	body := base64.URLEncoding.EncodeToString([]byte("Can we move the review to 3pm?"))
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /calendar/calendars/primary/events", func(w http.ResponseWriter, r *http.Request) {
+		captured.Store("events", r.URL.Query())
+		fmt.Fprint(w, `{"items":[
+			{"id":"e1","summary":"Design review","location":"Room 4","htmlLink":"https://cal/e1",
+			 "start":{"dateTime":"2026-10-16T14:00:00Z"},"end":{"dateTime":"2026-10-16T15:00:00Z"},
+			 "organizer":{"email":"ann@acme.com"},"attendees":[{"email":"ann@acme.com"},{"email":"me@acme.com"}]},
+			{"id":"e2","summary":"Offsite","start":{"date":"2026-10-18"},"end":{"date":"2026-10-19"}}]}`)
+	})
+	mux.HandleFunc("GET /gmail/users/me/messages", func(w http.ResponseWriter, r *http.Request) {
+		captured.Store("search", r.URL.Query())
+		fmt.Fprint(w, `{"messages":[{"id":"m1","threadId":"t1"}]}`)
+	})
+	mux.HandleFunc("GET /gmail/users/me/messages/m1", func(w http.ResponseWriter, r *http.Request) {
+		headers := gmailHeaders(
+			"From", "Ann <ann@acme.com>",
+			"To", "me@acme.com, Bob <bob@acme.com>",
+			"Cc", "carol@acme.com",
+			"Subject", "Review time",
+			"Date", "Thu, 15 Oct 2026 09:00:00 +0000",
+			"Message-ID", "<m1@mail.acme.com>",
+		)
+		if r.URL.Query().Get("format") == "full" {
+			fmt.Fprintf(w, `{"id":"m1","threadId":"t1","snippet":"Can we move","payload":{"mimeType":"multipart/alternative","headers":%s,
+				"parts":[{"mimeType":"text/html","body":{"data":"PGI-"}},{"mimeType":"text/plain; charset=utf-8","body":{"data":%q}}]}}`, headers, body)
+			return
+		}
+		fmt.Fprintf(w, `{"id":"m1","threadId":"t1","snippet":"Can we move","payload":{"headers":%s}}`, headers)
+	})
+	mux.HandleFunc("GET /gmail/users/me/profile", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"emailAddress":"Me@acme.com"}`)
+	})
+	mux.HandleFunc("POST /gmail/users/me/drafts", func(w http.ResponseWriter, r *http.Request) {
+		var draft struct {
+			Message struct {
+				Raw      string `json:"raw"`
+				ThreadID string `json:"threadId"`
+			} `json:"message"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&draft)
+		raw, _ := base64.URLEncoding.DecodeString(draft.Message.Raw)
+		captured.Store("draft", draft.Message.ThreadID+"\n"+string(raw))
+		fmt.Fprint(w, `{"id":"d1"}`)
+	})
+	mux.HandleFunc("GET /gmail/users/me/messages/forbidden", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error":{"code":403,"message":"Request had insufficient authentication scopes."}}`)
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token-") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		captured.Store("auth", r.Header.Get("Authorization"))
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &captured
+}
+
+func TestGoogleWorkspaceTools(t *testing.T) {
+	t.Parallel()
+
+	server, captured := newFakeGoogle(t)
+	source := &refreshingSource{}
+	toolset := GoogleWorkspaceWithConfig(&GoogleWorkspaceConfig{
+		TokenSource: source,
+		Scopes:      CalendarRead | MailRead | MailDraft,
+		CalendarURL: server.URL + "/calendar",
+		GmailURL:    server.URL + "/gmail/",
+	})
+
+	tests := []struct {
+		name    string
+		tool    string
+		args    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "list events",
+			tool: "google_list_events",
+			args: `{"from":"2026-10-15","to":"2026-10-20T00:00:00Z","query":"review"}`,
+			want: `[{"id":"e1","title":"Design review","start":"2026-10-16T14:00:00Z","end":"2026-10-16T15:00:00Z","location":"Room 4",` +
+				`"organizer":"ann@acme.com","attendees":["ann@acme.com","me@acme.com"],"url":"https://cal/e1"},` +
+				`{"id":"e2","title":"Offsite","start":"2026-10-18","end":"2026-10-19"}]`,
+		},
+		{
+			name: "search mail",
+			tool: "google_search_mail",
+			args: `{"query":"from:ann","limit":5}`,
+			want: `[{"id":"m1","from":"Ann <ann@acme.com>","subject":"Review time","date":"Thu, 15 Oct 2026 09:00:00 +0000","snippet":"Can we move"}]`,
+		},
+		{
+			name: "get message prefers plain text",
+			tool: "google_get_message",
+			args: `{"id":"m1"}`,
+			want: `{"id":"m1","from":"Ann <ann@acme.com>","to":["me@acme.com","bob@acme.com"],"cc":["carol@acme.com"],` +
+				`"subject":"Review time","date":"Thu, 15 Oct 2026 09:00:00 +0000","body":"Can we move the review to 3pm?"}`,
+		},
+		{
+			name: "draft reply all",
+			tool: "google_draft_reply",
+			args: `{"message_id":"m1","body":"Works for me.\nThanks","reply_all":true}`,
+			want: `{"draft_id":"d1","to":["ann@acme.com"],"cc":["bob@acme.com","carol@acme.com"],"subject":"Re: Review time"}`,
+		},
+		{
+			name:    "invalid range",
+			tool:    "google_list_events",
+			args:    `{"from":"tomorrow"}`,
+			wantErr: `invalid from "tomorrow"`,
+		},
+		{
+			name:    "missing scope",
+			tool:    "google_get_message",
+			args:    `{"id":"forbidden"}`,
+			wantErr: "403 Request had insufficient authentication scopes. (check the granted OAuth scopes)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := runTool(t, toolset, tt.tool, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("output = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Cleanup(func() {
+		if source.issued.Load() != 1 {
+			t.Errorf("issued %d tokens, want one reused until expiry", source.issued.Load())
+		}
+		events, _ := captured.Load("events")
+		if q := events.(url.Values); q.Get("timeMin") != "2026-10-15T00:00:00Z" || q.Get("singleEvents") != "true" || q.Get("q") != "review" {
+			t.Errorf("events query = %v", q)
+		}
+		draft, _ := captured.Load("draft")
+		for _, want := range []string{"t1\n", "To: ann@acme.com\r\n", "Cc: bob@acme.com, carol@acme.com\r\n", "Subject: Re: Review time\r\n",
+			"In-Reply-To: <m1@mail.acme.com>\r\n", "References: <m1@mail.acme.com>\r\n", "\r\n\r\nWorks for me.\r\nThanks"} {
+			if !strings.Contains(draft.(string), want) {
+				t.Errorf("draft missing %q:\n%s", want, draft)
+			}
+		}
+	})
+}
+
+func TestGoogleWorkspaceScopes(t *testing.T) {
+	t.Parallel()
+
+	var names []string
+	for _, tool := range GoogleWorkspace(&refreshingSource{}) {
+		names = append(names, tool.Name())
+	}
+	if strings.Join(names, ",") != "google_list_events,google_search_mail,google_get_message" {
+		t.Errorf("GoogleWorkspace() tools = %v, want the read tools", names)
+	}
+
+	_, err := runTool(t, GoogleWorkspace(nil), "google_search_mail", `{}`)
+	if err == nil || !strings.Contains(err.Error(), "token source is required") {
+		t.Errorf("error = %v, want missing token source", err)
+	}
+}
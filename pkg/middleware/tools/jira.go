@@ -0,0 +1,432 @@
+package tools
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// JiraConfig holds configuration for the Jira toolset
+type JiraConfig struct {
+	// BaseURL is the site URL, e.g. https://acme.atlassian.net (required)
+	BaseURL string
+	// Email and APIToken authenticate with Jira Cloud using basic auth
+	Email    string
+	APIToken string
+	// Token is a personal access token sent as a bearer token (Jira Data Center)
+	Token string
+	// Projects limits the tools to these project keys (default: any the credentials can reach)
+	Projects []string
+	// ReadOnly exposes only the search and get tools
+	ReadOnly bool
+	// Client is the HTTP client (default: one with a 30 second timeout)
+	Client *http.Client
+}
+
+// Jira returns typed tools for searching, creating and updating Jira issues.
+//
+// Tools: jira_search_issues (JQL), jira_get_issue, jira_create_issue and
+// jira_update_issue. The update tool edits fields, adds a comment and moves
+// the issue through a workflow transition by name, in that order. Searches
+// are confined to Projects by prefixing the JQL; other tools check the
+// project key before any request is made.
+//
+// Example:
+//
+//	triage := tools.Jira(&tools.JiraConfig{
+//		BaseURL:  "https://acme.atlassian.net",
+//		Email:    "bot@acme.com",
+//		APIToken: os.Getenv("JIRA_API_TOKEN"),
+//		Projects: []string{"SUP"},
+//	})
+func Jira(config *JiraConfig) []Tool {
+	cfg := JiraConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	jira := &jiraClient{config: cfg}
+	toolset := []Tool{
+		typedTool("jira_search_issues", "Search Jira issues with a JQL query", jira.search),
+		typedTool("jira_get_issue", "Get a Jira issue with its description and recent comments", jira.getIssue),
+	}
+	if !cfg.ReadOnly {
+		toolset = append(toolset,
+			typedTool("jira_create_issue", "Create a Jira issue", jira.createIssue),
+			typedTool("jira_update_issue", "Update a Jira issue's fields, add a comment or transition its status", jira.updateIssue),
+		)
+	}
+	return callsHosts(toolset, cfg.BaseURL)
+}
+
+type jiraSearchArgs struct {
+	JQL   string `json:"jql" jsonschema:"description=JQL query, e.g. project = SUP AND status = Open ORDER BY created DESC"`
+	Limit int    `json:"limit,omitempty" jsonschema:"minimum=1,maximum=100,description=Maximum issues to return (default: 20)"`
+}
+
+type jiraKeyArgs struct {
+	Key string `json:"key" jsonschema:"description=Issue key, e.g. SUP-123"`
+}
+
+type jiraCreateArgs struct {
+	Project     string   `json:"project" jsonschema:"description=Project key, e.g. SUP"`
+	Summary     string   `json:"summary" jsonschema:"description=One-line issue summary"`
+	Description string   `json:"description,omitempty" jsonschema:"description=Issue description"`
+	IssueType   string   `json:"issue_type,omitempty" jsonschema:"description=Issue type name (default: Task)"`
+	Priority    string   `json:"priority,omitempty" jsonschema:"description=Priority name, e.g. High"`
+	Labels      []string `json:"labels,omitempty" jsonschema:"description=Labels to set"`
+}
+
+type jiraUpdateArgs struct {
+	Key         string   `json:"key" jsonschema:"description=Issue key, e.g. SUP-123"`
+	Summary     string   `json:"summary,omitempty" jsonschema:"description=New summary"`
+	Description string   `json:"description,omitempty" jsonschema:"description=New description"`
+	Priority    string   `json:"priority,omitempty" jsonschema:"description=New priority name"`
+	Labels      []string `json:"labels,omitempty" jsonschema:"description=Labels to add"`
+	Comment     string   `json:"comment,omitempty" jsonschema:"description=Comment to add"`
+	Status      string   `json:"status,omitempty" jsonschema:"description=Transition or target status name, e.g. In Progress"`
+}
+
+// jiraIssue is the compact issue summary returned to the model
+type jiraIssue struct {
+	Key         string   `json:"key"`
+	Summary     string   `json:"summary"`
+	Status      string   `json:"status"`
+	Type        string   `json:"type,omitempty"`
+	Priority    string   `json:"priority,omitempty"`
+	Assignee    string   `json:"assignee,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	URL         string   `json:"url"`
+	Description string   `json:"description,omitempty"`
+	Comments    []string `json:"comments,omitempty"`
+}
+
+type jiraName struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// jiraAPIIssue is the subset of the REST v2 issue object the tools read
+type jiraAPIIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string    `json:"summary"`
+		Description string    `json:"description"`
+		Status      *jiraName `json:"status"`
+		IssueType   *jiraName `json:"issuetype"`
+		Priority    *jiraName `json:"priority"`
+		Assignee    *jiraName `json:"assignee"`
+		Labels      []string  `json:"labels"`
+		Comment     *struct {
+			Comments []struct {
+				Body   string   `json:"body"`
+				Author jiraName `json:"author"`
+			} `json:"comments"`
+		} `json:"comment"`
+	} `json:"fields"`
+}
+
+const jiraFields = "summary,status,issuetype,priority,assignee,labels"
+
+func (c *jiraClient) summary(issue jiraAPIIssue) jiraIssue {
+	name := func(n *jiraName) string {
+		if n == nil {
+			return ""
+		}
+		return cmp.Or(n.DisplayName, n.Name)
+	}
+	return jiraIssue{
+		Key:      issue.Key,
+		Summary:  issue.Fields.Summary,
+		Status:   name(issue.Fields.Status),
+		Type:     name(issue.Fields.IssueType),
+		Priority: name(issue.Fields.Priority),
+		Assignee: name(issue.Fields.Assignee),
+		Labels:   issue.Fields.Labels,
+		URL:      c.config.BaseURL + "/browse/" + issue.Key,
+	}
+}
+
+type jiraClient struct {
+	config JiraConfig
+}
+
+func (c *jiraClient) search(ctx context.Context, args jiraSearchArgs) (any, error) {
+	if strings.TrimSpace(args.JQL) == "" {
+		return nil, calque.NewErr(ctx, "jql is required")
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	query := url.Values{}
+	query.Set("jql", c.scopeJQL(args.JQL))
+	query.Set("maxResults", strconv.Itoa(min(limit, 100)))
+	query.Set("fields", jiraFields)
+
+	var result struct {
+		Issues []jiraAPIIssue `json:"issues"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/rest/api/2/search/jql?"+query.Encode(), nil, &result); err != nil {
+		return nil, err
+	}
+	issues := make([]jiraIssue, len(result.Issues))
+	for i, issue := range result.Issues {
+		issues[i] = c.summary(issue)
+	}
+	return issues, nil
+}
+
+// scopeJQL confines a query to the allowed projects, keeping any ORDER BY last
+func (c *jiraClient) scopeJQL(jql string) string {
+	if len(c.config.Projects) == 0 {
+		return jql
+	}
+	where, order := jql, ""
+	if i := strings.LastIndex(strings.ToUpper(jql), "ORDER BY"); i >= 0 {
+		where, order = jql[:i], " "+jql[i:]
+	}
+	projects := make([]string, len(c.config.Projects))
+	for i, project := range c.config.Projects {
+		projects[i] = strconv.Quote(project)
+	}
+	scoped := "project in (" + strings.Join(projects, ", ") + ")"
+	if where = strings.TrimSpace(where); where != "" {
+		scoped += " AND (" + where + ")"
+	}
+	return scoped + order
+}
+
+func (c *jiraClient) getIssue(ctx context.Context, args jiraKeyArgs) (any, error) {
+	if err := c.check(ctx, args.Key); err != nil {
+		return nil, err
+	}
+	var issue jiraAPIIssue
+	path := "/rest/api/2/issue/" + url.PathEscape(args.Key) + "?fields=" + jiraFields + ",description,comment"
+	if err := c.do(ctx, http.MethodGet, path, nil, &issue); err != nil {
+		return nil, err
+	}
+
+	summary := c.summary(issue)
+	summary.Description = issue.Fields.Description
+	if issue.Fields.Comment != nil {
+		comments := issue.Fields.Comment.Comments
+		// The 20 most recent comments keep long threads within the context window
+		for _, comment := range comments[max(len(comments)-20, 0):] {
+			summary.Comments = append(summary.Comments, cmp.Or(comment.Author.DisplayName, comment.Author.Name)+": "+comment.Body)
+		}
+	}
+	return summary, nil
+}
+
+func (c *jiraClient) createIssue(ctx context.Context, args jiraCreateArgs) (any, error) {
+	if err := c.checkProject(ctx, args.Project); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(args.Summary) == "" {
+		return nil, calque.NewErr(ctx, "summary is required")
+	}
+
+	fields := map[string]any{
+		"project":   map[string]string{"key": args.Project},
+		"summary":   args.Summary,
+		"issuetype": map[string]string{"name": cmp.Or(args.IssueType, "Task")},
+	}
+	if args.Description != "" {
+		fields["description"] = args.Description
+	}
+	if args.Priority != "" {
+		fields["priority"] = map[string]string{"name": args.Priority}
+	}
+	if len(args.Labels) > 0 {
+		fields["labels"] = args.Labels
+	}
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/rest/api/2/issue", map[string]any{"fields": fields}, &created); err != nil {
+		return nil, err
+	}
+	return map[string]string{"key": created.Key, "url": c.config.BaseURL + "/browse/" + created.Key}, nil
+}
+
+func (c *jiraClient) updateIssue(ctx context.Context, args jiraUpdateArgs) (any, error) {
+	if err := c.check(ctx, args.Key); err != nil {
+		return nil, err
+	}
+	path := "/rest/api/2/issue/" + url.PathEscape(args.Key)
+	var updated []string
+
+	fields := map[string]any{}
+	update := map[string]any{}
+	if args.Summary != "" {
+		fields["summary"] = args.Summary
+	}
+	if args.Description != "" {
+		fields["description"] = args.Description
+	}
+	if args.Priority != "" {
+		fields["priority"] = map[string]string{"name": args.Priority}
+	}
+	if len(args.Labels) > 0 {
+		adds := make([]map[string]string, len(args.Labels))
+		for i, label := range args.Labels {
+			adds[i] = map[string]string{"add": label}
+		}
+		update["labels"] = adds
+	}
+	if len(fields) > 0 || len(update) > 0 {
+		if err := c.do(ctx, http.MethodPut, path, map[string]any{"fields": fields, "update": update}, nil); err != nil {
+			return nil, err
+		}
+		for name := range fields {
+			updated = append(updated, name)
+		}
+		if len(update) > 0 {
+			updated = append(updated, "labels")
+		}
+	}
+
+	if args.Comment != "" {
+		if err := c.do(ctx, http.MethodPost, path+"/comment", map[string]string{"body": args.Comment}, nil); err != nil {
+			return nil, err
+		}
+		updated = append(updated, "comment")
+	}
+
+	if args.Status != "" {
+		if err := c.transition(ctx, path, args.Status); err != nil {
+			return nil, err
+		}
+		updated = append(updated, "status")
+	}
+
+	if len(updated) == 0 {
+		return nil, calque.NewErr(ctx, "nothing to update")
+	}
+	slices.Sort(updated)
+	return map[string]any{"key": args.Key, "updated": updated}, nil
+}
+
+// transition moves an issue by transition name or target status name
+func (c *jiraClient) transition(ctx context.Context, path, status string) error {
+	var result struct {
+		Transitions []struct {
+			ID   string   `json:"id"`
+			Name string   `json:"name"`
+			To   jiraName `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := c.do(ctx, http.MethodGet, path+"/transitions", nil, &result); err != nil {
+		return err
+	}
+
+	var available []string
+	for _, transition := range result.Transitions {
+		if strings.EqualFold(transition.Name, status) || strings.EqualFold(transition.To.Name, status) {
+			body := map[string]any{"transition": map[string]string{"id": transition.ID}}
+			return c.do(ctx, http.MethodPost, path+"/transitions", body, nil)
+		}
+		available = append(available, transition.To.Name)
+	}
+	return calque.NewErr(ctx, fmt.Sprintf("no transition to %q; available: %s", status, strings.Join(available, ", ")))
+}
+
+// check validates an issue key and enforces the Projects allowlist
+func (c *jiraClient) check(ctx context.Context, key string) error {
+	project, _, ok := strings.Cut(key, "-")
+	if !ok || project == "" {
+		return calque.NewErr(ctx, fmt.Sprintf("invalid issue key %q", key))
+	}
+	return c.checkProject(ctx, project)
+}
+
+func (c *jiraClient) checkProject(ctx context.Context, project string) error {
+	if project == "" {
+		return calque.NewErr(ctx, "project is required")
+	}
+	if len(c.config.Projects) > 0 && !slices.ContainsFunc(c.config.Projects, func(allowed string) bool {
+		return strings.EqualFold(allowed, project)
+	}) {
+		return calque.NewErr(ctx, fmt.Sprintf("project %s is not allowed", project))
+	}
+	return nil
+}
+
+// do calls the Jira REST API and decodes the JSON response into out when set
+func (c *jiraClient) do(ctx context.Context, method, path string, body, out any) error {
+	if c.config.BaseURL == "" {
+		return calque.NewErr(ctx, "jira base URL is required")
+	}
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return calque.WrapErr(ctx, err, "failed to encode Jira request")
+		}
+		payload = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.config.BaseURL+path, payload)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to create Jira request")
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch {
+	case c.config.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	case c.config.APIToken != "":
+		req.SetBasicAuth(c.config.Email, c.config.APIToken)
+	}
+
+	resp, err := c.config.Client.Do(req)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "Jira request failed")
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to read Jira response")
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			ErrorMessages []string          `json:"errorMessages"`
+			Errors        map[string]string `json:"errors"`
+		}
+		_ = json.Unmarshal(data, &apiErr)
+		messages := apiErr.ErrorMessages
+		for field, message := range apiErr.Errors {
+			messages = append(messages, field+": "+message)
+		}
+		slices.Sort(messages[len(apiErr.ErrorMessages):])
+		detail := cmp.Or(strings.Join(messages, "; "), http.StatusText(resp.StatusCode))
+		return calque.NewErr(ctx, fmt.Sprintf("Jira %s %s: %d %s", method, strings.SplitN(path, "?", 2)[0], resp.StatusCode, detail))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return calque.WrapErr(ctx, err, "invalid Jira response")
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeJira serves the REST v2 endpoints the Jira tools call and records writes
+type fakeJira struct {
+	mu     sync.Mutex
+	jql    []string
+	bodies map[string][]map[string]any
+}
+
+func newFakeJira(t *testing.T) (*fakeJira, *httptest.Server) {
+	t.Helper()
+
+	fake := &fakeJira{bodies: map[string][]map[string]any{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rest/api/2/search/jql", func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		fake.jql = append(fake.jql, r.URL.Query().Get("jql"))
+		fake.mu.Unlock()
+		fmt.Fprint(w, `{"issues":[{"key":"SUP-1","fields":{"summary":"Login fails","status":{"name":"Open"},
+			"priority":{"name":"High"},"assignee":{"displayName":"Ann"},"labels":["auth"]}}]}`)
+	})
+	mux.HandleFunc("GET /rest/api/2/issue/SUP-1", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"key":"SUP-1","fields":{"summary":"Login fails","description":"500 on submit","status":{"name":"Open"},
+			"comment":{"comments":[{"body":"Seen it too","author":{"displayName":"Bob"}}]}}}`)
+	})
+	mux.HandleFunc("GET /rest/api/2/issue/SUP-1/transitions", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"transitions":[{"id":"11","name":"Start work","to":{"name":"In Progress"}},{"id":"31","name":"Resolve","to":{"name":"Done"}}]}`)
+	})
+	mux.HandleFunc("GET /rest/api/2/issue/SUP-404", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"errorMessages":["Issue does not exist or you do not have permission to see it."]}`)
+	})
+	mux.HandleFunc("POST /rest/api/2/issue", func(w http.ResponseWriter, r *http.Request) {
+		fake.record(r)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"10001","key":"SUP-2"}`)
+	})
+	for _, pattern := range []string{"PUT /rest/api/2/issue/SUP-1", "POST /rest/api/2/issue/SUP-1/comment", "POST /rest/api/2/issue/SUP-1/transitions"} {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			fake.record(r)
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "bot@acme.com" || pass != "t0ken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeJira) record(r *http.Request) {
+	var body map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := r.Method + " " + r.URL.Path
+	f.bodies[key] = append(f.bodies[key], body)
+}
+
+func TestJiraTools(t *testing.T) {
+	t.Parallel()
+
+	fake, server := newFakeJira(t)
+	toolset := Jira(&JiraConfig{BaseURL: server.URL, Email: "bot@acme.com", APIToken: "t0ken", Projects: []string{"SUP"}})
+
+	tests := []struct {
+		name    string
+		tool    string
+		args    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "search",
+			tool: "jira_search_issues",
+			args: `{"jql":"status = Open ORDER BY created DESC","limit":5}`,
+			want: `[{"key":"SUP-1","summary":"Login fails","status":"Open","priority":"High","assignee":"Ann","labels":["auth"],"url":"` + server.URL + `/browse/SUP-1"}]`,
+		},
+		{
+			name: "get issue",
+			tool: "jira_get_issue",
+			args: `{"key":"SUP-1"}`,
+			want: `{"key":"SUP-1","summary":"Login fails","status":"Open","url":"` + server.URL + `/browse/SUP-1","description":"500 on submit","comments":["Bob: Seen it too"]}`,
+		},
+		{
+			name: "create issue",
+			tool: "jira_create_issue",
+			args: `{"project":"SUP","summary":"Refund request","labels":["billing"]}`,
+			want: `{"key":"SUP-2","url":"` + server.URL + `/browse/SUP-2"}`,
+		},
+		{
+			name: "update issue",
+			tool: "jira_update_issue",
+			args: `{"key":"SUP-1","priority":"Highest","labels":["p1"],"comment":"Escalating","status":"in progress"}`,
+			want: `{"key":"SUP-1","updated":["comment","labels","priority","status"]}`,
+		},
+		{
+			name:    "unknown transition",
+			tool:    "jira_update_issue",
+			args:    `{"key":"SUP-1","status":"Closed"}`,
+			wantErr: `no transition to "Closed"; available: In Progress, Done`,
+		},
+		{
+			name:    "nothing to update",
+			tool:    "jira_update_issue",
+			args:    `{"key":"SUP-1"}`,
+			wantErr: "nothing to update",
+		},
+		{
+			name:    "project not allowed",
+			tool:    "jira_create_issue",
+			args:    `{"project":"HR","summary":"Salary data"}`,
+			wantErr: "project HR is not allowed",
+		},
+		{
+			name:    "invalid key",
+			tool:    "jira_get_issue",
+			args:    `{"key":"SUP1"}`,
+			wantErr: `invalid issue key "SUP1"`,
+		},
+		{
+			name:    "api error",
+			tool:    "jira_get_issue",
+			args:    `{"key":"SUP-404"}`,
+			wantErr: "404 Issue does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := runTool(t, toolset, tt.tool, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("output = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Cleanup(func() {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		if len(fake.jql) != 1 || fake.jql[0] != `project in ("SUP") AND (status = Open) ORDER BY created DESC` {
+			t.Errorf("jql = %q, want it scoped to the allowed projects", fake.jql)
+		}
+		created := fake.bodies["POST /rest/api/2/issue"][0]["fields"].(map[string]any)
+		if created["issuetype"].(map[string]any)["name"] != "Task" {
+			t.Errorf("created fields = %v, want the default Task type", created)
+		}
+		if got := fake.bodies["POST /rest/api/2/issue/SUP-1/transitions"]; len(got) != 1 || fmt.Sprint(got[0]["transition"]) != "map[id:11]" {
+			t.Errorf("transitions = %v, want transition 11", got)
+		}
+	})
+}
+
+func TestJiraScopeJQL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		projects []string
+		jql      string
+		want     string
+	}{
+		{name: "unscoped", jql: "assignee = currentUser()", want: "assignee = currentUser()"},
+		{name: "scoped", projects: []string{"SUP", "OPS"}, jql: "text ~ refund", want: `project in ("SUP", "OPS") AND (text ~ refund)`},
+		{name: "order only", projects: []string{"SUP"}, jql: "order by updated", want: `project in ("SUP") order by updated`},
+		{name: "or cannot escape", projects: []string{"SUP"}, jql: "a = 1 OR project = HR", want: `project in ("SUP") AND (a = 1 OR project = HR)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &jiraClient{config: JiraConfig{Projects: tt.projects}}
+			if got := client.scopeJQL(tt.jql); got != tt.want {
+				t.Errorf("scopeJQL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJiraReadOnly(t *testing.T) {
+	t.Parallel()
+
+	var names []string
+	for _, tool := range Jira(&JiraConfig{BaseURL: "https://acme.atlassian.net", ReadOnly: true}) {
+		names = append(names, tool.Name())
+	}
+	if strings.Join(names, ",") != "jira_search_issues,jira_get_issue" {
+		t.Errorf("tools = %v, want only search and get", names)
+	}
+
+	_, err := runTool(t, Jira(nil), "jira_get_issue", `{"key":"SUP-1"}`)
+	if err == nil || !strings.Contains(err.Error(), "base URL is required") {
+		t.Errorf("error = %v, want missing base URL", err)
+	}
+}
@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+// summaryPrompt asks the summarizer to shrink a tool result; it is given the
+// tool name, the token limit, the call arguments and the result
+const summaryPrompt = `Summarize the output of the tool %q below in at most %d tokens.
+Keep every fact, number, name and identifier needed to answer the request that called the tool, and drop repetition and boilerplate.
+
+Arguments: %s
+
+Output:
+%s`
+
+// WithResultLimit returns a Config that keeps tool results within maxTokens.
+//
+// Input: token limit per tool result, summarizer handler (nil to truncate only)
+// Output: Config for ExecuteWithOptions or ai.WithToolsConfig
+// Behavior: BUFFERED - results over the limit are summarized, then truncated
+//
+// Large outputs such as search results or whole files are sent to summarizer,
+// typically ai.Agent with a small, cheap model, along with the tool name and
+// arguments. A summary that is still too long, a failed summarizer or a nil
+// summarizer falls back to cutting the result and noting how much was dropped.
+// Results within the limit are passed through untouched. Set Config.Tokenizer
+// to count with the model's own tokenizer.
+//
+// Example:
+//
+//	limit := tools.WithResultLimit(2000, ai.Agent(miniClient))
+//	limit.MaxConcurrentTools = 4
+//	agent := ai.Agent(client, ai.WithTools(search, readFile), ai.WithToolsConfig(limit))
+func WithResultLimit(maxTokens int, summarizer calque.Handler) Config {
+	return Config{MaxResultTokens: maxTokens, ResultSummarizer: summarizer}
+}
+
+// limitResult applies the configured result limit to a successful tool result
+func limitResult(ctx context.Context, result ToolResult, config Config) ToolResult {
+	if config.MaxResultTokens <= 0 || result.Error != "" {
+		return result
+	}
+	counter := tokenizer.OrApproximate(config.Tokenizer)
+	tokens := counter.CountTokens(result.Result)
+	if tokens <= config.MaxResultTokens {
+		return result
+	}
+
+	if config.ResultSummarizer != nil {
+		summary, err := summarizeResult(ctx, result, config.MaxResultTokens, config.ResultSummarizer)
+		switch {
+		case err != nil:
+			calque.LogWarn(ctx, "tool result summarization failed, truncating", "tool", result.ToolCall.Name, "error", err)
+		case counter.CountTokens(summary) <= config.MaxResultTokens:
+			calque.LogDebug(ctx, "tool result summarized", "tool", result.ToolCall.Name, "tokens", tokens, "max_tokens", config.MaxResultTokens)
+			result.Result = summary
+			return result
+		default:
+			result.Result = summary
+			tokens = counter.CountTokens(summary)
+		}
+	}
+
+	result.Result = truncateResult(result.Result, tokens, config.MaxResultTokens, counter)
+	return result
+}
+
+// summarizeResult runs the summarizer over a tool result
+func summarizeResult(ctx context.Context, result ToolResult, maxTokens int, summarizer calque.Handler) ([]byte, error) {
+	prompt := fmt.Sprintf(summaryPrompt, result.ToolCall.Name, maxTokens, result.ToolCall.Arguments, result.Result)
+
+	var summary bytes.Buffer
+	if err := summarizer.ServeFlow(calque.NewRequest(ctx, bytes.NewReader([]byte(prompt))), calque.NewResponse(&summary)); err != nil {
+		return nil, err
+	}
+	if summary.Len() == 0 {
+		return nil, calque.NewErr(ctx, "summarizer returned an empty summary")
+	}
+	return summary.Bytes(), nil
+}
+
+// truncateResult keeps the longest prefix of result that fits in maxTokens
+// along with a note saying how much was cut
+func truncateResult(result []byte, tokens, maxTokens int, counter tokenizer.Tokenizer) []byte {
+	note := fmt.Appendf(nil, "\n[truncated: %d of %d tokens omitted]", 0, tokens)
+	budget := max(maxTokens-counter.CountTokens(note), 0)
+
+	// Binary search for the longest prefix within budget
+	left, right := 0, len(result)
+	for left < right {
+		mid := (left + right + 1) / 2
+		if counter.CountTokens(result[:mid]) <= budget {
+			left = mid
+		} else {
+			right = mid - 1
+		}
+	}
+	cut := left
+	for cut > 0 && cut < len(result) && !utf8.RuneStart(result[cut]) {
+		cut--
+	}
+
+	kept := result[:cut]
+	truncated := make([]byte, 0, cut+len(note))
+	truncated = append(truncated, kept...)
+	return fmt.Appendf(truncated, "\n[truncated: %d of %d tokens omitted]", tokens-counter.CountTokens(kept), tokens)
+}
@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/tokenizer"
+)
+
+// fixedSummarizer answers every prompt with summary, recording the prompt
+func fixedSummarizer(summary string, prompts *[]string) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var prompt string
+		if err := calque.Read(req, &prompt); err != nil {
+			return err
+		}
+		*prompts = append(*prompts, prompt)
+		return calque.Write(res, summary)
+	})
+}
+
+func TestWithResultLimit(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("result line with details\n", 100) // 2500 bytes, 625 tokens
+	failing := calque.HandlerFunc(func(*calque.Request, *calque.Response) error {
+		return errors.New("summarizer down")
+	})
+
+	tests := []struct {
+		name       string
+		output     string
+		summary    string
+		summarizer bool
+		failing    bool
+		want       string
+		truncated  bool
+		prompted   bool
+	}{
+		{name: "within limit", output: "short", summarizer: true, want: "short"},
+		{name: "summarized", output: long, summarizer: true, summary: "100 result lines", want: "100 result lines", prompted: true},
+		{name: "truncated without summarizer", output: long, truncated: true},
+		{name: "summary over limit is truncated", output: long, summarizer: true, summary: long, truncated: true, prompted: true},
+		{name: "summarizer error falls back to truncation", output: long, failing: true, truncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var prompts []string
+			var summarizer calque.Handler
+			switch {
+			case tt.failing:
+				summarizer = failing
+			case tt.summarizer:
+				summarizer = fixedSummarizer(tt.summary, &prompts)
+			}
+
+			tool := Simple("read_file", "Reads a file", func(string) string { return tt.output })
+			input := `{"tool_calls": [{"type": "function", "function": {"name": "read_file", "arguments": "{\"path\": \"log.txt\"}"}}]}`
+			var results []ToolResult
+			ctx := WithResultObserver(context.Background(), func(_ context.Context, r ToolResult) {
+				results = append(results, r)
+			})
+
+			var buf bytes.Buffer
+			pipeline := NewPipelineForTestWithConfig([]Tool{tool}, WithResultLimit(50, summarizer))
+			if err := pipeline.ServeFlow(calque.NewRequest(ctx, strings.NewReader(input)), calque.NewResponse(&buf)); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("observed %d results, want 1", len(results))
+			}
+
+			got := string(results[0].Result)
+			if tokens := tokenizer.Approximate.CountTokens(results[0].Result); tokens > 50 {
+				t.Errorf("result has %d tokens, want at most 50", tokens)
+			}
+			if tt.truncated {
+				if !strings.HasPrefix(got, "result line") || !strings.Contains(got, "[truncated: ") {
+					t.Errorf("result = %q, want a truncated prefix with a note", got)
+				}
+			} else if got != tt.want {
+				t.Errorf("result = %q, want %q", got, tt.want)
+			}
+			if !strings.Contains(buf.String(), got) {
+				t.Errorf("Execute() output %q does not contain the limited result", buf.String())
+			}
+
+			if tt.prompted != (len(prompts) == 1) {
+				t.Fatalf("summarizer prompted %d times", len(prompts))
+			}
+			if tt.prompted && (!strings.Contains(prompts[0], `"read_file"`) || !strings.Contains(prompts[0], "log.txt") || !strings.Contains(prompts[0], "at most 50 tokens")) {
+				t.Errorf("summary prompt missing tool, arguments or limit:\n%s", prompts[0])
+			}
+		})
+	}
+}
+
+func TestTruncateResult(t *testing.T) {
+	t.Parallel()
+
+	result := []byte(strings.Repeat("héllo wörld ", 50))
+	tokens := tokenizer.Approximate.CountTokens(result)
+	got := truncateResult(result, tokens, 20, tokenizer.Approximate)
+
+	if !utf8.Valid(got) {
+		t.Errorf("truncateResult() split a rune: %q", got)
+	}
+	if n := tokenizer.Approximate.CountTokens(got); n > 20 {
+		t.Errorf("truncateResult() = %d tokens, want at most 20", n)
+	}
+	if !strings.HasSuffix(string(got), " tokens omitted]") {
+		t.Errorf("truncateResult() = %q, want a truncation note", got)
+	}
+}
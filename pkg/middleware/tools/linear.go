@@ -0,0 +1,432 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultLinearAPIURL is the Linear GraphQL endpoint
+const DefaultLinearAPIURL = "https://api.linear.app/graphql"
+
+// LinearConfig holds configuration for the Linear toolset
+type LinearConfig struct {
+	// APIKey is a personal API key; OAuth access tokens are sent as bearer tokens
+	APIKey string
+	// Teams limits the tools to these team keys, e.g. "SUP" (default: any the key can reach)
+	Teams []string
+	// ReadOnly exposes only the search and get tools
+	ReadOnly bool
+	// APIURL is the GraphQL endpoint (default: DefaultLinearAPIURL)
+	APIURL string
+	// Client is the HTTP client (default: one with a 30 second timeout)
+	Client *http.Client
+}
+
+// Linear returns typed tools for searching, creating and updating Linear issues.
+//
+// Tools: linear_search_issues, linear_get_issue, linear_create_issue and
+// linear_update_issue. Issues are addressed by identifier (e.g. SUP-42);
+// workflow states are set by name and resolved against the issue's team.
+//
+// Example:
+//
+//	triage := tools.Linear(&tools.LinearConfig{
+//		APIKey: os.Getenv("LINEAR_API_KEY"),
+//		Teams:  []string{"SUP"},
+//	})
+func Linear(config *LinearConfig) []Tool {
+	cfg := LinearConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.APIURL == "" {
+		cfg.APIURL = DefaultLinearAPIURL
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	linear := &linearClient{config: cfg}
+	toolset := []Tool{
+		typedTool("linear_search_issues", "Search Linear issues by text, team and state", linear.search),
+		typedTool("linear_get_issue", "Get a Linear issue with its description and recent comments", linear.getIssue),
+	}
+	if !cfg.ReadOnly {
+		toolset = append(toolset,
+			typedTool("linear_create_issue", "Create a Linear issue", linear.createIssue),
+			typedTool("linear_update_issue", "Update a Linear issue's fields or state, or add a comment", linear.updateIssue),
+		)
+	}
+	return callsHosts(toolset, cfg.APIURL)
+}
+
+type linearSearchArgs struct {
+	Query string `json:"query,omitempty" jsonschema:"description=Text to find in the title or description"`
+	Team  string `json:"team,omitempty" jsonschema:"description=Team key, e.g. SUP"`
+	State string `json:"state,omitempty" jsonschema:"description=Workflow state name, e.g. Todo"`
+	Limit int    `json:"limit,omitempty" jsonschema:"minimum=1,maximum=100,description=Maximum issues to return (default: 20)"`
+}
+
+type linearIDArgs struct {
+	ID string `json:"id" jsonschema:"description=Issue identifier, e.g. SUP-42"`
+}
+
+type linearCreateArgs struct {
+	Team        string `json:"team" jsonschema:"description=Team key, e.g. SUP"`
+	Title       string `json:"title" jsonschema:"description=Issue title"`
+	Description string `json:"description,omitempty" jsonschema:"description=Issue description (Markdown)"`
+	Priority    int    `json:"priority,omitempty" jsonschema:"minimum=0,maximum=4,description=0 none, 1 urgent, 2 high, 3 medium, 4 low"`
+}
+
+type linearUpdateArgs struct {
+	ID          string `json:"id" jsonschema:"description=Issue identifier, e.g. SUP-42"`
+	Title       string `json:"title,omitempty" jsonschema:"description=New title"`
+	Description string `json:"description,omitempty" jsonschema:"description=New description (Markdown)"`
+	Priority    *int   `json:"priority,omitempty" jsonschema:"minimum=0,maximum=4,description=0 none, 1 urgent, 2 high, 3 medium, 4 low"`
+	State       string `json:"state,omitempty" jsonschema:"description=Workflow state name, e.g. In Progress"`
+	Comment     string `json:"comment,omitempty" jsonschema:"description=Comment to add (Markdown)"`
+}
+
+// linearIssue is the compact issue summary returned to the model
+type linearIssue struct {
+	Identifier  string   `json:"identifier"`
+	Title       string   `json:"title"`
+	State       string   `json:"state"`
+	Priority    string   `json:"priority,omitempty"`
+	Assignee    string   `json:"assignee,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	URL         string   `json:"url"`
+	Description string   `json:"description,omitempty"`
+	Comments    []string `json:"comments,omitempty"`
+}
+
+const linearIssueFields = `identifier title url priorityLabel
+	state { name } assignee { name } labels { nodes { name } }`
+
+type linearAPIIssue struct {
+	ID            string `json:"id"`
+	Identifier    string `json:"identifier"`
+	Title         string `json:"title"`
+	URL           string `json:"url"`
+	PriorityLabel string `json:"priorityLabel"`
+	Description   string `json:"description"`
+	State         struct {
+		Name string `json:"name"`
+	} `json:"state"`
+	Assignee *struct {
+		Name string `json:"name"`
+	} `json:"assignee"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	Comments struct {
+		Nodes []struct {
+			Body string `json:"body"`
+			User *struct {
+				Name string `json:"name"`
+			} `json:"user"`
+		} `json:"nodes"`
+	} `json:"comments"`
+}
+
+func (i linearAPIIssue) summary() linearIssue {
+	issue := linearIssue{
+		Identifier: i.Identifier,
+		Title:      i.Title,
+		State:      i.State.Name,
+		URL:        i.URL,
+	}
+	if i.PriorityLabel != "No priority" {
+		issue.Priority = i.PriorityLabel
+	}
+	if i.Assignee != nil {
+		issue.Assignee = i.Assignee.Name
+	}
+	for _, label := range i.Labels.Nodes {
+		issue.Labels = append(issue.Labels, label.Name)
+	}
+	return issue
+}
+
+type linearClient struct {
+	config LinearConfig
+}
+
+func (c *linearClient) search(ctx context.Context, args linearSearchArgs) (any, error) {
+	var filters []map[string]any
+	switch {
+	case args.Team != "":
+		if err := c.checkTeam(ctx, args.Team); err != nil {
+			return nil, err
+		}
+		filters = append(filters, map[string]any{"team": map[string]any{"key": map[string]any{"eqIgnoreCase": args.Team}}})
+	case len(c.config.Teams) > 0:
+		filters = append(filters, map[string]any{"team": map[string]any{"key": map[string]any{"in": c.config.Teams}}})
+	}
+	if args.State != "" {
+		filters = append(filters, map[string]any{"state": map[string]any{"name": map[string]any{"eqIgnoreCase": args.State}}})
+	}
+	if args.Query != "" {
+		contains := map[string]any{"containsIgnoreCase": args.Query}
+		filters = append(filters, map[string]any{"or": []map[string]any{{"title": contains}, {"description": contains}}})
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	var data struct {
+		Issues struct {
+			Nodes []linearAPIIssue `json:"nodes"`
+		} `json:"issues"`
+	}
+	query := `query($filter: IssueFilter, $first: Int) {
+		issues(filter: $filter, first: $first, orderBy: updatedAt) { nodes { ` + linearIssueFields + ` } }
+	}`
+	variables := map[string]any{"first": min(limit, 100)}
+	if len(filters) > 0 {
+		variables["filter"] = map[string]any{"and": filters}
+	}
+	if err := c.do(ctx, query, variables, &data); err != nil {
+		return nil, err
+	}
+	issues := make([]linearIssue, len(data.Issues.Nodes))
+	for i, issue := range data.Issues.Nodes {
+		issues[i] = issue.summary()
+	}
+	return issues, nil
+}
+
+func (c *linearClient) getIssue(ctx context.Context, args linearIDArgs) (any, error) {
+	issue, err := c.issue(ctx, args.ID, `description comments(last: 20) { nodes { body user { name } } }`)
+	if err != nil {
+		return nil, err
+	}
+	summary := issue.summary()
+	summary.Description = issue.Description
+	for _, comment := range issue.Comments.Nodes {
+		author := "unknown"
+		if comment.User != nil {
+			author = comment.User.Name
+		}
+		summary.Comments = append(summary.Comments, author+": "+comment.Body)
+	}
+	return summary, nil
+}
+
+func (c *linearClient) createIssue(ctx context.Context, args linearCreateArgs) (any, error) {
+	if err := c.checkTeam(ctx, args.Team); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(args.Title) == "" {
+		return nil, calque.NewErr(ctx, "title is required")
+	}
+
+	var teams struct {
+		Teams struct {
+			Nodes []struct {
+				ID string `json:"id"`
+			} `json:"nodes"`
+		} `json:"teams"`
+	}
+	query := `query($key: String!) { teams(filter: { key: { eqIgnoreCase: $key } }) { nodes { id } } }`
+	if err := c.do(ctx, query, map[string]any{"key": args.Team}, &teams); err != nil {
+		return nil, err
+	}
+	if len(teams.Teams.Nodes) == 0 {
+		return nil, calque.NewErr(ctx, fmt.Sprintf("team %s not found", args.Team))
+	}
+
+	input := map[string]any{"teamId": teams.Teams.Nodes[0].ID, "title": args.Title, "priority": args.Priority}
+	if args.Description != "" {
+		input["description"] = args.Description
+	}
+	var created struct {
+		IssueCreate struct {
+			Issue linearAPIIssue `json:"issue"`
+		} `json:"issueCreate"`
+	}
+	mutation := `mutation($input: IssueCreateInput!) { issueCreate(input: $input) { issue { identifier url } } }`
+	if err := c.do(ctx, mutation, map[string]any{"input": input}, &created); err != nil {
+		return nil, err
+	}
+	return map[string]string{"id": created.IssueCreate.Issue.Identifier, "url": created.IssueCreate.Issue.URL}, nil
+}
+
+func (c *linearClient) updateIssue(ctx context.Context, args linearUpdateArgs) (any, error) {
+	input := map[string]any{}
+	var updated []string
+	if args.Title != "" {
+		input["title"] = args.Title
+		updated = append(updated, "title")
+	}
+	if args.Description != "" {
+		input["description"] = args.Description
+		updated = append(updated, "description")
+	}
+	if args.Priority != nil {
+		input["priority"] = *args.Priority
+		updated = append(updated, "priority")
+	}
+
+	issue, err := c.issue(ctx, args.ID, `id team { states { nodes { id name } } }`)
+	if err != nil {
+		return nil, err
+	}
+	if args.State != "" {
+		stateID, err := c.stateID(ctx, issue, args.State)
+		if err != nil {
+			return nil, err
+		}
+		input["stateId"] = stateID
+		updated = append(updated, "state")
+	}
+	if len(input) == 0 && args.Comment == "" {
+		return nil, calque.NewErr(ctx, "nothing to update")
+	}
+
+	if len(input) > 0 {
+		mutation := `mutation($id: String!, $input: IssueUpdateInput!) { issueUpdate(id: $id, input: $input) { success } }`
+		if err := c.do(ctx, mutation, map[string]any{"id": issue.ID, "input": input}, nil); err != nil {
+			return nil, err
+		}
+	}
+	if args.Comment != "" {
+		mutation := `mutation($input: CommentCreateInput!) { commentCreate(input: $input) { success } }`
+		if err := c.do(ctx, mutation, map[string]any{"input": map[string]any{"issueId": issue.ID, "body": args.Comment}}, nil); err != nil {
+			return nil, err
+		}
+		updated = append(updated, "comment")
+	}
+	slices.Sort(updated)
+	return map[string]any{"id": args.ID, "updated": updated}, nil
+}
+
+// issueWithStates is a linearAPIIssue plus its team's workflow states
+type issueWithStates struct {
+	linearAPIIssue
+	Team struct {
+		States struct {
+			Nodes []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"nodes"`
+		} `json:"states"`
+	} `json:"team"`
+}
+
+// issue fetches an issue by identifier with the common fields plus extra
+func (c *linearClient) issue(ctx context.Context, id, extra string) (*issueWithStates, error) {
+	team, _, ok := strings.Cut(id, "-")
+	if !ok || team == "" {
+		return nil, calque.NewErr(ctx, fmt.Sprintf("invalid issue identifier %q", id))
+	}
+	if err := c.checkTeam(ctx, team); err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Issue *issueWithStates `json:"issue"`
+	}
+	query := `query($id: String!) { issue(id: $id) { ` + linearIssueFields + ` ` + extra + ` } }`
+	if err := c.do(ctx, query, map[string]any{"id": id}, &data); err != nil {
+		return nil, err
+	}
+	if data.Issue == nil {
+		return nil, calque.NewErr(ctx, fmt.Sprintf("issue %s not found", id))
+	}
+	return data.Issue, nil
+}
+
+func (c *linearClient) stateID(ctx context.Context, issue *issueWithStates, name string) (string, error) {
+	var available []string
+	for _, state := range issue.Team.States.Nodes {
+		if strings.EqualFold(state.Name, name) {
+			return state.ID, nil
+		}
+		available = append(available, state.Name)
+	}
+	return "", calque.NewErr(ctx, fmt.Sprintf("no state %q; available: %s", name, strings.Join(available, ", ")))
+}
+
+// checkTeam enforces the Teams allowlist
+func (c *linearClient) checkTeam(ctx context.Context, team string) error {
+	if team == "" {
+		return calque.NewErr(ctx, "team is required")
+	}
+	if len(c.config.Teams) > 0 && !slices.ContainsFunc(c.config.Teams, func(allowed string) bool {
+		return strings.EqualFold(allowed, team)
+	}) {
+		return calque.NewErr(ctx, fmt.Sprintf("team %s is not allowed", team))
+	}
+	return nil
+}
+
+// do runs a GraphQL operation and decodes its data into out when set
+func (c *linearClient) do(ctx context.Context, query string, variables map[string]any, out any) error {
+	payload, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to encode Linear request")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.APIURL, bytes.NewReader(payload))
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to create Linear request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.APIKey != "" {
+		// Personal API keys are sent bare; OAuth tokens need the Bearer scheme
+		auth := c.config.APIKey
+		if !strings.HasPrefix(auth, "lin_api_") {
+			auth = "Bearer " + auth
+		}
+		req.Header.Set("Authorization", auth)
+	}
+
+	resp, err := c.config.Client.Do(req)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "Linear request failed")
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to read Linear response")
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return calque.NewErr(ctx, fmt.Sprintf("Linear API: %d %s", resp.StatusCode, http.StatusText(resp.StatusCode)))
+	}
+	if len(result.Errors) > 0 {
+		messages := make([]string, len(result.Errors))
+		for i, e := range result.Errors {
+			messages[i] = e.Message
+		}
+		return calque.NewErr(ctx, "Linear API: "+strings.Join(messages, "; "))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return calque.NewErr(ctx, fmt.Sprintf("Linear API: %d %s", resp.StatusCode, http.StatusText(resp.StatusCode)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(result.Data, out); err != nil {
+		return calque.WrapErr(ctx, err, "invalid Linear response")
+	}
+	return nil
+}
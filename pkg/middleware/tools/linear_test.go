@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeLinear answers GraphQL operations by the first field they select
+type fakeLinear struct {
+	mu         sync.Mutex
+	operations map[string][]map[string]any // field -> variables
+}
+
+func newFakeLinear(t *testing.T) (*fakeLinear, *httptest.Server) {
+	t.Helper()
+
+	fake := &fakeLinear{operations: map[string][]map[string]any{}}
+	responses := map[string]string{
+		"issues": `{"issues":{"nodes":[{"identifier":"SUP-1","title":"Login fails","url":"u1","priorityLabel":"High",
+			"state":{"name":"Todo"},"assignee":{"name":"Ann"},"labels":{"nodes":[{"name":"auth"}]}}]}}`,
+		"issue": `{"issue":{"id":"uuid-1","identifier":"SUP-1","title":"Login fails","url":"u1","priorityLabel":"No priority",
+			"state":{"name":"Todo"},"description":"500 on submit","labels":{"nodes":[]},
+			"comments":{"nodes":[{"body":"Seen it too","user":{"name":"Bob"}},{"body":"automated","user":null}]},
+			"team":{"states":{"nodes":[{"id":"s-todo","name":"Todo"},{"id":"s-doing","name":"In Progress"}]}}}}`,
+		"teams":         `{"teams":{"nodes":[{"id":"team-1"}]}}`,
+		"issueCreate":   `{"issueCreate":{"issue":{"identifier":"SUP-2","url":"u2"}}}`,
+		"issueUpdate":   `{"issueUpdate":{"success":true}}`,
+		"commentCreate": `{"commentCreate":{"success":true}}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "lin_api_key" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errors":[{"message":"Authentication required"}]}`)
+			return
+		}
+		var op struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&op)
+
+		// The selected field follows the operation's opening brace
+		_, selection, _ := strings.Cut(op.Query, "{")
+		field := strings.FieldsFunc(selection, func(r rune) bool { return r == '(' || r == ' ' || r == '\n' || r == '\t' })[0]
+		fake.mu.Lock()
+		fake.operations[field] = append(fake.operations[field], op.Variables)
+		fake.mu.Unlock()
+
+		if field == "issue" && op.Variables["id"] == "SUP-404" {
+			fmt.Fprint(w, `{"data":{"issue":null},"errors":[{"message":"Entity not found: Issue"}]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"data":%s}`, responses[field])
+	}))
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func TestLinearTools(t *testing.T) {
+	t.Parallel()
+
+	fake, server := newFakeLinear(t)
+	toolset := Linear(&LinearConfig{APIKey: "lin_api_key", Teams: []string{"SUP"}, APIURL: server.URL})
+
+	tests := []struct {
+		name    string
+		tool    string
+		args    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "search",
+			tool: "linear_search_issues",
+			args: `{"query":"login","state":"todo"}`,
+			want: `[{"identifier":"SUP-1","title":"Login fails","state":"Todo","priority":"High","assignee":"Ann","labels":["auth"],"url":"u1"}]`,
+		},
+		{
+			name: "get issue",
+			tool: "linear_get_issue",
+			args: `{"id":"SUP-1"}`,
+			want: `{"identifier":"SUP-1","title":"Login fails","state":"Todo","url":"u1","description":"500 on submit","comments":["Bob: Seen it too","unknown: automated"]}`,
+		},
+		{
+			name: "create issue",
+			tool: "linear_create_issue",
+			args: `{"team":"sup","title":"Refund request","priority":2}`,
+			want: `{"id":"SUP-2","url":"u2"}`,
+		},
+		{
+			name: "update issue",
+			tool: "linear_update_issue",
+			args: `{"id":"SUP-1","priority":0,"state":"in progress","comment":"On it"}`,
+			want: `{"id":"SUP-1","updated":["comment","priority","state"]}`,
+		},
+		{
+			name:    "unknown state",
+			tool:    "linear_update_issue",
+			args:    `{"id":"SUP-1","state":"Done"}`,
+			wantErr: `no state "Done"; available: Todo, In Progress`,
+		},
+		{
+			name:    "nothing to update",
+			tool:    "linear_update_issue",
+			args:    `{"id":"SUP-1"}`,
+			wantErr: "nothing to update",
+		},
+		{
+			name:    "team not allowed",
+			tool:    "linear_get_issue",
+			args:    `{"id":"HR-7"}`,
+			wantErr: "team HR is not allowed",
+		},
+		{
+			name:    "search team not allowed",
+			tool:    "linear_search_issues",
+			args:    `{"team":"HR"}`,
+			wantErr: "team HR is not allowed",
+		},
+		{
+			name:    "graphql error",
+			tool:    "linear_get_issue",
+			args:    `{"id":"SUP-404"}`,
+			wantErr: "Linear API: Entity not found: Issue",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := runTool(t, toolset, tt.tool, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("output = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Cleanup(func() {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		filter, _ := json.Marshal(fake.operations["issues"][0]["filter"])
+		want := `{"and":[{"team":{"key":{"in":["SUP"]}}},{"state":{"name":{"eqIgnoreCase":"todo"}}},` +
+			`{"or":[{"title":{"containsIgnoreCase":"login"}},{"description":{"containsIgnoreCase":"login"}}]}]}`
+		if string(filter) != want {
+			t.Errorf("search filter = %s, want %s", filter, want)
+		}
+		create := fake.operations["issueCreate"][0]["input"].(map[string]any)
+		if create["teamId"] != "team-1" || create["priority"] != float64(2) {
+			t.Errorf("create input = %v", create)
+		}
+		update := fake.operations["issueUpdate"][0]
+		if update["id"] != "uuid-1" || fmt.Sprint(update["input"]) != "map[priority:0 stateId:s-doing]" {
+			t.Errorf("update variables = %v", update)
+		}
+	})
+}
+
+func TestLinearAuthorization(t *testing.T) {
+	t.Parallel()
+
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+		fmt.Fprint(w, `{"data":{"issues":{"nodes":[]}}}`)
+	}))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "lin_api_abc", want: "lin_api_abc"},
+		{key: "oauth-token", want: "Bearer oauth-token"},
+	}
+	for _, tt := range tests {
+		if _, err := runTool(t, Linear(&LinearConfig{APIKey: tt.key, APIURL: server.URL}), "linear_search_issues", `{}`); err != nil {
+			t.Fatalf("error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Authorization = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultGraphURL is the Microsoft Graph v1.0 base URL
+const DefaultGraphURL = "https://graph.microsoft.com/v1.0"
+
+// MSGraphConfig holds configuration for the Microsoft Graph toolset
+type MSGraphConfig struct {
+	// TokenSource supplies OAuth tokens and refreshes them (required); request
+	// Scopes.GraphOAuthScopes() when obtaining consent
+	TokenSource oauth2.TokenSource
+	// Scopes selects the tools exposed (default: CalendarRead | MailRead)
+	Scopes AssistantScope
+	// User is the mailbox to use, as a user ID or principal name (default: the signed-in user)
+	User string
+	// BaseURL is the Graph base URL, e.g. for national clouds (default: DefaultGraphURL)
+	BaseURL string
+	// Client is the base HTTP client (default: one with a 30 second timeout)
+	Client *http.Client
+}
+
+// MSGraph returns read-only Outlook calendar and mail tools for assistant agents.
+//
+// Tools: msgraph_list_events, msgraph_search_mail and msgraph_get_message.
+// Use MSGraphWithConfig with MailDraft to add msgraph_draft_reply.
+//
+// Example:
+//
+//	conf := &oauth2.Config{ /* client ID, secret, microsoft.AzureADEndpoint(tenant) */ Scopes: tools.MailRead.GraphOAuthScopes()}
+//	assistant := ai.Agent(client, ai.WithTools(tools.MSGraph(conf.TokenSource(ctx, token))...))
+func MSGraph(creds oauth2.TokenSource) []Tool {
+	return MSGraphWithConfig(&MSGraphConfig{TokenSource: creds})
+}
+
+// MSGraphWithConfig returns the Microsoft Graph tools enabled by config.Scopes.
+//
+// Tools by scope:
+//   - CalendarRead: msgraph_list_events, with times in UTC
+//   - MailRead: msgraph_search_mail (KQL search), msgraph_get_message
+//   - MailDraft: msgraph_draft_reply, which saves a reply in Drafts and never sends
+//
+// Example:
+//
+//	assistant := tools.MSGraphWithConfig(&tools.MSGraphConfig{
+//		TokenSource: clientCredentials.TokenSource(ctx),
+//		Scopes:      tools.CalendarRead,
+//		User:        "ops@acme.com", // app-only tokens need an explicit mailbox
+//	})
+func MSGraphWithConfig(config *MSGraphConfig) []Tool {
+	cfg := MSGraphConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Scopes == 0 {
+		cfg.Scopes = CalendarRead | MailRead
+	}
+	cfg.BaseURL = strings.TrimSuffix(cmp.Or(cfg.BaseURL, DefaultGraphURL), "/")
+
+	graph := &graphClient{config: cfg, now: time.Now, user: "/me"}
+	if cfg.User != "" {
+		graph.user = "/users/" + url.PathEscape(cfg.User)
+	}
+	if cfg.TokenSource != nil {
+		graph.client = oauthHTTPClient(cfg.TokenSource, cfg.Client)
+	}
+
+	var toolset []Tool
+	if cfg.Scopes&CalendarRead != 0 {
+		toolset = append(toolset, typedTool("msgraph_list_events", "List Outlook calendar events in a time range", graph.listEvents))
+	}
+	if cfg.Scopes&MailRead != 0 {
+		toolset = append(toolset,
+			typedTool("msgraph_search_mail", "Search Outlook mail, or list the newest messages when no query is given", graph.searchMail),
+			typedTool("msgraph_get_message", "Get an Outlook message with its plain text body", graph.getMessage),
+		)
+	}
+	if cfg.Scopes&MailDraft != 0 {
+		toolset = append(toolset, typedTool("msgraph_draft_reply", "Save a draft reply to an Outlook message without sending it", graph.draftReply))
+	}
+	return callsHosts(toolset, cfg.BaseURL)
+}
+
+type graphClient struct {
+	config MSGraphConfig
+	client *http.Client
+	user   string
+	now    func() time.Time
+}
+
+type graphRecipient struct {
+	EmailAddress struct {
+		Name    string `json:"name"`
+		Address string `json:"address"`
+	} `json:"emailAddress"`
+}
+
+func graphAddresses(recipients []graphRecipient) []string {
+	var addresses []string
+	for _, r := range recipients {
+		addresses = append(addresses, r.EmailAddress.Address)
+	}
+	return addresses
+}
+
+// graphTime converts a Graph dateTimeTimeZone in UTC to RFC 3339
+type graphTime struct {
+	DateTime string `json:"dateTime"`
+}
+
+func (t graphTime) String() string {
+	parsed, err := time.Parse("2006-01-02T15:04:05.9999999", t.DateTime)
+	if err != nil {
+		return t.DateTime
+	}
+	return parsed.Format(time.RFC3339)
+}
+
+func (g *graphClient) listEvents(ctx context.Context, args listEventsArgs) (any, error) {
+	from, to, err := args.eventRange(ctx, g.now())
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set("startDateTime", from.UTC().Format(time.RFC3339))
+	query.Set("endDateTime", to.UTC().Format(time.RFC3339))
+	query.Set("$orderby", "start/dateTime")
+	query.Set("$select", "subject,start,end,location,organizer,attendees,webLink")
+	// calendarView has no text search, so filter titles after fetching a full page
+	limit := limitOr(args.Limit, 20, 100)
+	if args.Query == "" {
+		query.Set("$top", strconv.Itoa(limit))
+	} else {
+		query.Set("$top", "100")
+	}
+
+	var result struct {
+		Value []struct {
+			ID       string    `json:"id"`
+			Subject  string    `json:"subject"`
+			WebLink  string    `json:"webLink"`
+			Start    graphTime `json:"start"`
+			End      graphTime `json:"end"`
+			Location struct {
+				DisplayName string `json:"displayName"`
+			} `json:"location"`
+			Organizer graphRecipient   `json:"organizer"`
+			Attendees []graphRecipient `json:"attendees"`
+		} `json:"value"`
+	}
+	header := http.Header{"Prefer": {`outlook.timezone="UTC"`}}
+	if err := g.do(ctx, http.MethodGet, g.user+"/calendarView?"+query.Encode(), header, nil, &result); err != nil {
+		return nil, err
+	}
+
+	events := []calendarEvent{}
+	for _, item := range result.Value {
+		if args.Query != "" && !strings.Contains(strings.ToLower(item.Subject), strings.ToLower(args.Query)) {
+			continue
+		}
+		events = append(events, calendarEvent{
+			ID:        item.ID,
+			Title:     item.Subject,
+			Start:     item.Start.String(),
+			End:       item.End.String(),
+			Location:  item.Location.DisplayName,
+			Organizer: item.Organizer.EmailAddress.Address,
+			Attendees: graphAddresses(item.Attendees),
+			URL:       item.WebLink,
+		})
+		if len(events) == limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+func (g *graphClient) searchMail(ctx context.Context, args searchMailArgs) (any, error) {
+	query := url.Values{}
+	query.Set("$top", strconv.Itoa(limitOr(args.Limit, 10, 50)))
+	query.Set("$select", "subject,from,receivedDateTime,bodyPreview")
+	if args.Query != "" {
+		// $search takes a quoted KQL string and cannot be combined with $orderby
+		query.Set("$search", strconv.Quote(args.Query))
+	} else {
+		query.Set("$orderby", "receivedDateTime desc")
+	}
+
+	var result struct {
+		Value []struct {
+			ID               string         `json:"id"`
+			Subject          string         `json:"subject"`
+			ReceivedDateTime string         `json:"receivedDateTime"`
+			BodyPreview      string         `json:"bodyPreview"`
+			From             graphRecipient `json:"from"`
+		} `json:"value"`
+	}
+	if err := g.do(ctx, http.MethodGet, g.user+"/messages?"+query.Encode(), nil, nil, &result); err != nil {
+		return nil, err
+	}
+	results := make([]mailSummary, len(result.Value))
+	for i, item := range result.Value {
+		results[i] = mailSummary{
+			ID:      item.ID,
+			From:    item.From.EmailAddress.Address,
+			Subject: item.Subject,
+			Date:    item.ReceivedDateTime,
+			Snippet: item.BodyPreview,
+		}
+	}
+	return results, nil
+}
+
+func (g *graphClient) getMessage(ctx context.Context, args getMessageArgs) (any, error) {
+	if args.ID == "" {
+		return nil, calque.NewErr(ctx, "message id is required")
+	}
+	var message struct {
+		ID               string           `json:"id"`
+		Subject          string           `json:"subject"`
+		ReceivedDateTime string           `json:"receivedDateTime"`
+		From             graphRecipient   `json:"from"`
+		To               []graphRecipient `json:"toRecipients"`
+		Cc               []graphRecipient `json:"ccRecipients"`
+		Body             struct {
+			Content string `json:"content"`
+		} `json:"body"`
+	}
+	header := http.Header{"Prefer": {`outlook.body-content-type="text"`}}
+	path := g.user + "/messages/" + url.PathEscape(args.ID) + "?$select=subject,from,toRecipients,ccRecipients,receivedDateTime,body"
+	if err := g.do(ctx, http.MethodGet, path, header, nil, &message); err != nil {
+		return nil, err
+	}
+	return mailMessage{
+		ID:      message.ID,
+		From:    message.From.EmailAddress.Address,
+		To:      graphAddresses(message.To),
+		Cc:      graphAddresses(message.Cc),
+		Subject: message.Subject,
+		Date:    message.ReceivedDateTime,
+		Body:    message.Body.Content,
+	}, nil
+}
+
+func (g *graphClient) draftReply(ctx context.Context, args draftReplyArgs) (any, error) {
+	if args.MessageID == "" {
+		return nil, calque.NewErr(ctx, "message id is required")
+	}
+	if strings.TrimSpace(args.Body) == "" {
+		return nil, calque.NewErr(ctx, "body is required")
+	}
+	action := "/createReply"
+	if args.ReplyAll {
+		action = "/createReplyAll"
+	}
+
+	// Graph threads the draft and fills in recipients and subject itself
+	var draft struct {
+		ID      string           `json:"id"`
+		Subject string           `json:"subject"`
+		To      []graphRecipient `json:"toRecipients"`
+		Cc      []graphRecipient `json:"ccRecipients"`
+	}
+	path := g.user + "/messages/" + url.PathEscape(args.MessageID) + action
+	if err := g.do(ctx, http.MethodPost, path, nil, map[string]string{"comment": args.Body}, &draft); err != nil {
+		return nil, err
+	}
+	return mailDraft{DraftID: draft.ID, To: graphAddresses(draft.To), Cc: graphAddresses(draft.Cc), Subject: draft.Subject}, nil
+}
+
+func (g *graphClient) do(ctx context.Context, method, path string, header http.Header, body, out any) error {
+	if g.client == nil {
+		return calque.NewErr(ctx, "microsoft graph token source is required")
+	}
+	return apiRequest{service: "Microsoft Graph", method: method, url: g.config.BaseURL + path, header: header, body: body}.do(ctx, g.client, out)
+}
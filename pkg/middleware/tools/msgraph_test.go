@@ -0,0 +1,185 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func newFakeGraph(t *testing.T) (*httptest.Server, *sync.Map) {
+	t.Helper()
+
+	var captured sync.Map
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/ops@acme.com/calendarView", func(w http.ResponseWriter, r *http.Request) {
+		captured.Store("calendar", r.URL.Query())
+		captured.Store("calendarPrefer", r.Header.Get("Prefer"))
+		fmt.Fprint(w, `{"value":[
+			{"id":"e1","subject":"Standup","start":{"dateTime":"2026-10-16T09:00:00.0000000","timeZone":"UTC"},
+			 "end":{"dateTime":"2026-10-16T09:15:00.0000000","timeZone":"UTC"}},
+			{"id":"e2","subject":"Design Review","webLink":"https://outlook/e2","location":{"displayName":"Room 4"},
+			 "start":{"dateTime":"2026-10-16T14:00:00.0000000","timeZone":"UTC"},"end":{"dateTime":"2026-10-16T15:00:00.0000000","timeZone":"UTC"},
+			 "organizer":{"emailAddress":{"address":"ann@acme.com"}},"attendees":[{"emailAddress":{"address":"ops@acme.com"}}]}]}`)
+	})
+	mux.HandleFunc("GET /users/ops@acme.com/messages", func(w http.ResponseWriter, r *http.Request) {
+		captured.Store("search", r.URL.Query())
+		fmt.Fprint(w, `{"value":[{"id":"m1","subject":"Invoice","receivedDateTime":"2026-10-15T08:00:00Z","bodyPreview":"Attached",
+			"from":{"emailAddress":{"name":"Billing","address":"billing@vendor.com"}}}]}`)
+	})
+	mux.HandleFunc("GET /users/ops@acme.com/messages/m1", func(w http.ResponseWriter, r *http.Request) {
+		captured.Store("messagePrefer", r.Header.Get("Prefer"))
+		fmt.Fprint(w, `{"id":"m1","subject":"Invoice","receivedDateTime":"2026-10-15T08:00:00Z",
+			"from":{"emailAddress":{"address":"billing@vendor.com"}},"toRecipients":[{"emailAddress":{"address":"ops@acme.com"}}],
+			"body":{"contentType":"text","content":"Invoice 42 is attached."}}`)
+	})
+	mux.HandleFunc("POST /users/ops@acme.com/messages/m1/createReplyAll", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		captured.Store("comment", body["comment"])
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"d1","subject":"RE: Invoice","toRecipients":[{"emailAddress":{"address":"billing@vendor.com"}}]}`)
+	})
+	mux.HandleFunc("GET /users/ops@acme.com/messages/gone", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":{"code":"ErrorItemNotFound","message":"The specified object was not found in the store."}}`)
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer graph-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &captured
+}
+
+func TestMSGraphTools(t *testing.T) {
+	t.Parallel()
+
+	server, captured := newFakeGraph(t)
+	toolset := MSGraphWithConfig(&MSGraphConfig{
+		TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "graph-token"}),
+		Scopes:      CalendarRead | MailRead | MailDraft,
+		User:        "ops@acme.com",
+		BaseURL:     server.URL,
+	})
+
+	tests := []struct {
+		name    string
+		tool    string
+		args    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "list events filtered by title",
+			tool: "msgraph_list_events",
+			args: `{"from":"2026-10-16T00:00:00+02:00","to":"2026-10-17","query":"review"}`,
+			want: `[{"id":"e2","title":"Design Review","start":"2026-10-16T14:00:00Z","end":"2026-10-16T15:00:00Z","location":"Room 4",` +
+				`"organizer":"ann@acme.com","attendees":["ops@acme.com"],"url":"https://outlook/e2"}]`,
+		},
+		{
+			name: "search mail",
+			tool: "msgraph_search_mail",
+			args: `{"query":"invoice from:billing"}`,
+			want: `[{"id":"m1","from":"billing@vendor.com","subject":"Invoice","date":"2026-10-15T08:00:00Z","snippet":"Attached"}]`,
+		},
+		{
+			name: "get message",
+			tool: "msgraph_get_message",
+			args: `{"id":"m1"}`,
+			want: `{"id":"m1","from":"billing@vendor.com","to":["ops@acme.com"],"subject":"Invoice","date":"2026-10-15T08:00:00Z","body":"Invoice 42 is attached."}`,
+		},
+		{
+			name: "draft reply all",
+			tool: "msgraph_draft_reply",
+			args: `{"message_id":"m1","body":"Paid, thanks.","reply_all":true}`,
+			want: `{"draft_id":"d1","to":["billing@vendor.com"],"subject":"RE: Invoice"}`,
+		},
+		{
+			name:    "api error",
+			tool:    "msgraph_get_message",
+			args:    `{"id":"gone"}`,
+			wantErr: "Microsoft Graph API: 404 The specified object was not found in the store.",
+		},
+		{
+			name:    "empty draft",
+			tool:    "msgraph_draft_reply",
+			args:    `{"message_id":"m1","body":""}`,
+			wantErr: "body is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := runTool(t, toolset, tt.tool, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("output = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Cleanup(func() {
+		calendar, _ := captured.Load("calendar")
+		if q := calendar.(url.Values); q.Get("startDateTime") != "2026-10-15T22:00:00Z" || q.Get("$top") != "100" {
+			t.Errorf("calendar query = %v, want a UTC start and a full page to filter", q)
+		}
+		if prefer, _ := captured.Load("calendarPrefer"); prefer != `outlook.timezone="UTC"` {
+			t.Errorf("calendar Prefer = %v", prefer)
+		}
+		search, _ := captured.Load("search")
+		if q := search.(url.Values); q.Get("$search") != `"invoice from:billing"` || q.Has("$orderby") {
+			t.Errorf("search query = %v", q)
+		}
+		if prefer, _ := captured.Load("messagePrefer"); prefer != `outlook.body-content-type="text"` {
+			t.Errorf("message Prefer = %v", prefer)
+		}
+		if comment, _ := captured.Load("comment"); comment != "Paid, thanks." {
+			t.Errorf("reply comment = %v", comment)
+		}
+	})
+}
+
+func TestMSGraphUser(t *testing.T) {
+	t.Parallel()
+
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		fmt.Fprint(w, `{"value":[]}`)
+	}))
+	t.Cleanup(server.Close)
+
+	if n := len(MSGraph(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "t"}))); n != 3 {
+		t.Errorf("MSGraph() returned %d tools, want the 3 read tools", n)
+	}
+
+	toolset := MSGraphWithConfig(&MSGraphConfig{TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "t"}), BaseURL: server.URL})
+	got, err := runTool(t, toolset, "msgraph_search_mail", `{}`)
+	if err != nil || got != "[]" {
+		t.Fatalf("output = %q, error = %v", got, err)
+	}
+	if path != "/me/messages" {
+		t.Errorf("path = %q, want the signed-in user's mailbox", path)
+	}
+}
@@ -34,6 +34,11 @@ type registryHandler struct {
 	tools []Tool
 }
 
+// Egress implements calque.Egress with the destinations of the registered tools
+func (rh *registryHandler) Egress() []string {
+	return EgressOf(rh.tools)
+}
+
 func (rh *registryHandler) ServeFlow(req *calque.Request, res *calque.Response) error {
 	// Create a context with tools for this handler's execution
 	ctx := context.WithValue(req.Context, toolsContextKey{}, rh.tools)
@@ -1,6 +1,8 @@
 package tools
 
 import (
+	"slices"
+
 	"github.com/calque-ai/go-calque/pkg/calque"
 	"github.com/invopop/jsonschema"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
@@ -24,6 +26,7 @@ type toolImpl struct {
 	description      string
 	parametersSchema *jsonschema.Schema
 	handler          calque.Handler
+	egress           []string // hosts the tool calls, set by the integrations
 }
 
 func (t *toolImpl) Name() string {
@@ -42,6 +45,43 @@ func (t *toolImpl) ServeFlow(r *calque.Request, w *calque.Response) error {
 	return t.handler.ServeFlow(r, w)
 }
 
+// Egress implements calque.Egress with the hosts the tool calls, including
+// those declared by its handler
+func (t *toolImpl) Egress() []string {
+	return mergeEgress(t.egress, calque.EgressOf(t.handler))
+}
+
+// callsHosts marks tools as calling the given API URLs, so local-only flows
+// reject agents using them
+func callsHosts(toolset []Tool, urls ...string) []Tool {
+	hosts := calque.EgressHosts(urls...)
+	for _, tool := range toolset {
+		if t, ok := tool.(*toolImpl); ok {
+			t.egress = hosts
+		}
+	}
+	return toolset
+}
+
+// EgressOf returns the off-host destinations of a set of tools, e.g. for an
+// agent reporting where its tools send data
+func EgressOf(toolset []Tool) []string {
+	var destinations []string
+	for _, tool := range toolset {
+		destinations = mergeEgress(destinations, calque.EgressOf(tool))
+	}
+	return destinations
+}
+
+func mergeEgress(destinations, more []string) []string {
+	for _, dest := range more {
+		if !slices.Contains(destinations, dest) {
+			destinations = append(destinations, dest)
+		}
+	}
+	return destinations
+}
+
 // New creates a tool with full control over name, description, schema, and handler.
 // This is the most flexible constructor for complex tools.
 //
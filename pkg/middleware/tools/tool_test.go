@@ -6,11 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/invopop/jsonschema"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
+	"golang.org/x/oauth2"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
 )
@@ -344,3 +346,36 @@ func TestToolWithLargeInput(t *testing.T) {
 		t.Errorf("ServeFlow() output = %q, want %q", got, expected)
 	}
 }
+
+func TestToolEgress(t *testing.T) {
+	creds := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"})
+	tests := []struct {
+		name    string
+		toolset []Tool
+		want    []string
+	}{
+		{"plain tools", []Tool{Simple("echo", "Echo", func(s string) string { return s })}, nil},
+		{"github", GitHub("token"), []string{"api.github.com"}},
+		{"jira", Jira(&JiraConfig{BaseURL: "https://acme.atlassian.net"}), []string{"acme.atlassian.net"}},
+		{"linear", Linear(&LinearConfig{APIKey: "key"}), []string{"api.linear.app"}},
+		{"google workspace", GoogleWorkspace(creds), []string{"www.googleapis.com", "gmail.googleapis.com"}},
+		{"ms graph", MSGraph(creds), []string{"graph.microsoft.com"}},
+		{"local jira", Jira(&JiraConfig{BaseURL: "http://localhost:8080"}), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EgressOf(tt.toolset); !slices.Equal(got, tt.want) {
+				t.Errorf("EgressOf() = %v, want %v", got, tt.want)
+			}
+			if got := calque.DescribeHandler(Registry(tt.toolset...)).Egress; !slices.Equal(got, tt.want) {
+				t.Errorf("registry egress = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	flow := calque.NewFlow(calque.WithDataPolicy(calque.LocalOnly)).Use(Registry(GitHub("token")...))
+	if err := flow.Validate(); !errors.Is(err, calque.ErrDataPolicy) {
+		t.Errorf("Validate() error = %v, want a data policy violation", err)
+	}
+}
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/invopop/jsonschema"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// typedTool builds a tool whose arguments decode into T.
+//
+// The parameter schema is reflected from T: fields without omitempty are
+// required and jsonschema tags add descriptions and enums. Results are
+// written as is when they are strings and JSON-encoded otherwise.
+func typedTool[T any](name, description string, fn func(ctx context.Context, args T) (any, error)) Tool {
+	reflector := jsonschema.Reflector{ExpandedStruct: true, DoNotReference: true}
+	schema := reflector.Reflect(new(T))
+	schema.Version = ""
+
+	return New(name, description, schema, calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var raw []byte
+		if err := calque.Read(req, &raw); err != nil {
+			return err
+		}
+		var args T
+		if len(bytes.TrimSpace(raw)) > 0 {
+			decoder := json.NewDecoder(bytes.NewReader(raw))
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&args); err != nil {
+				return calque.WrapErr(req.Context, err, fmt.Sprintf("invalid arguments for %s", name))
+			}
+		}
+
+		result, err := fn(req.Context, args)
+		if err != nil {
+			return err
+		}
+		if s, ok := result.(string); ok {
+			return calque.Write(res, s)
+		}
+		// Models read results verbatim, so keep <, > and & unescaped
+		var encoded bytes.Buffer
+		encoder := json.NewEncoder(&encoded)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(result); err != nil {
+			return calque.WrapErr(req.Context, err, fmt.Sprintf("failed to encode %s result", name))
+		}
+		return calque.Write(res, bytes.TrimSuffix(encoded.Bytes(), []byte("\n")))
+	}))
+}
@@ -0,0 +1,108 @@
+package trigger
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrQueueFull is returned by Pool.Submit when every worker is busy and the queue is full
+var ErrQueueFull = errors.New("run queue is full")
+
+// ErrRunnerClosed is returned by Pool.Submit after Shutdown
+var ErrRunnerClosed = errors.New("runner is shut down")
+
+// Runner executes flow runs in the background for asynchronous webhooks.
+//
+// Submit must not block: it queues the job or returns an error, which the
+// webhook reports as 503 so the sender retries later. Shutdown stops
+// accepting jobs and waits for queued ones until ctx ends.
+type Runner interface {
+	Submit(job func(ctx context.Context)) error
+	Shutdown(ctx context.Context) error
+}
+
+// Pool is an in-process Runner with a fixed number of workers and a bounded queue
+type Pool struct {
+	jobs    chan func(context.Context)
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
+	closed  bool
+	stopped chan struct{}
+}
+
+// NewPool starts workers goroutines serving a queue of queueSize jobs.
+//
+// Jobs receive a context that is cancelled if Shutdown gives up waiting for them.
+//
+// Example:
+//
+//	pool := trigger.NewPool(8, 500)
+//	hook := trigger.WebhookWithConfig("/hooks/stripe", flow, mapper, &trigger.WebhookConfig{
+//		Async:  true,
+//		Runner: pool,
+//	})
+func NewPool(workers, queueSize int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{jobs: make(chan func(context.Context), queueSize), ctx: ctx, cancel: cancel, stopped: make(chan struct{})}
+	p.wg.Add(workers)
+	for range workers {
+		go p.work()
+	}
+	go func() {
+		p.wg.Wait()
+		close(p.stopped)
+	}()
+	return p
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		job(p.ctx)
+	}
+}
+
+// Submit queues a job, failing with ErrQueueFull instead of blocking
+func (p *Pool) Submit(job func(ctx context.Context)) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrRunnerClosed
+	}
+	select {
+	case p.jobs <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Shutdown stops accepting jobs and waits for queued and running ones.
+//
+// If ctx ends first, running jobs are cancelled and ctx.Err() is returned.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.stopped:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
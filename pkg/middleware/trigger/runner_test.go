@@ -0,0 +1,74 @@
+package trigger
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	t.Parallel()
+
+	pool := NewPool(1, 1)
+	release := make(chan struct{})
+	var done atomic.Int32
+
+	job := func(context.Context) {
+		<-release
+		done.Add(1)
+	}
+	if err := pool.Submit(job); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	// Wait for the worker to take the first job so the second one queues
+	deadline := time.Now().Add(5 * time.Second)
+	for len(pool.jobs) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := pool.Submit(job); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if err := pool.Submit(job); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Submit() on a full queue error = %v, want ErrQueueFull", err)
+	}
+
+	close(release)
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if done.Load() != 2 {
+		t.Errorf("completed %d jobs, want 2", done.Load())
+	}
+	if err := pool.Submit(job); !errors.Is(err, ErrRunnerClosed) {
+		t.Errorf("Submit() after Shutdown error = %v, want ErrRunnerClosed", err)
+	}
+}
+
+func TestPoolShutdownTimeout(t *testing.T) {
+	t.Parallel()
+
+	pool := NewPool(1, 1)
+	cancelled := make(chan struct{})
+	started := make(chan struct{})
+	if err := pool.Submit(func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+	}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := pool.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want deadline exceeded", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("running job was not cancelled")
+	}
+}
@@ -0,0 +1,159 @@
+package trigger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSignature is returned by a Source when a delivery fails verification
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// DefaultSignatureHeader is the header Generic reads signatures from
+const DefaultSignatureHeader = "X-Signature-256"
+
+// DefaultStripeTolerance is how old a Stripe signature timestamp may be
+const DefaultStripeTolerance = 5 * time.Minute
+
+// Source authenticates webhook deliveries and identifies the event they carry.
+//
+// Parse returns an error wrapping ErrInvalidSignature for deliveries that
+// fail verification.
+//
+// Built-in implementations:
+//   - GitHub: X-Hub-Signature-256 HMAC, event type and delivery ID headers
+//   - Stripe: Stripe-Signature with replay protection, type and id from the payload
+//   - Generic: optional HMAC signature header, X-Event-Type and X-Request-Id headers
+type Source interface {
+	Parse(r *http.Request, body []byte) (*Event, error)
+}
+
+// GitHub verifies GitHub webhook deliveries signed with secret.
+//
+// The event type comes from X-GitHub-Event and the delivery ID from X-GitHub-Delivery.
+func GitHub(secret string) Source {
+	return githubSource{secret: []byte(secret)}
+}
+
+type githubSource struct{ secret []byte }
+
+func (s githubSource) Parse(r *http.Request, body []byte) (*Event, error) {
+	if err := verifyHexHMAC(s.secret, r.Header.Get("X-Hub-Signature-256"), body); err != nil {
+		return nil, err
+	}
+	event := newEvent("github", r, body)
+	event.Type = r.Header.Get("X-GitHub-Event")
+	event.ID = r.Header.Get("X-GitHub-Delivery")
+	return event, nil
+}
+
+// Stripe verifies Stripe webhook deliveries signed with an endpoint secret (whsec_...).
+//
+// Signatures older than tolerance are rejected to prevent replays
+// (0 means DefaultStripeTolerance). The event type and ID come from the payload.
+func Stripe(secret string, tolerance time.Duration) Source {
+	if tolerance <= 0 {
+		tolerance = DefaultStripeTolerance
+	}
+	return stripeSource{secret: []byte(secret), tolerance: tolerance, now: time.Now}
+}
+
+type stripeSource struct {
+	secret    []byte
+	tolerance time.Duration
+	now       func() time.Time
+}
+
+func (s stripeSource) Parse(r *http.Request, body []byte) (*Event, error) {
+	// Stripe-Signature: t=1492774577,v1=5257a8...,v1=...,v0=...
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(r.Header.Get("Stripe-Signature"), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return nil, fmt.Errorf("%w: malformed Stripe-Signature header", ErrInvalidSignature)
+	}
+	if age := s.now().Sub(time.Unix(seconds, 0)); age > s.tolerance || age < -s.tolerance {
+		return nil, fmt.Errorf("%w: timestamp outside the %v tolerance", ErrInvalidSignature, s.tolerance)
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	for _, signature := range signatures {
+		if decoded, err := hex.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+			event := newEvent("stripe", r, body)
+			event.Type = event.field("type")
+			event.ID = event.field("id")
+			return event, nil
+		}
+	}
+	return nil, ErrInvalidSignature
+}
+
+// Generic accepts JSON or text deliveries from any sender.
+//
+// With a secret, the body must carry a hex HMAC-SHA256 signature in header
+// (default: DefaultSignatureHeader), optionally prefixed with "sha256=". With
+// an empty secret deliveries are not verified, so only use it behind another
+// authentication layer. The event type comes from X-Event-Type and the
+// delivery ID from Idempotency-Key or X-Request-Id.
+func Generic(header, secret string) Source {
+	if header == "" {
+		header = DefaultSignatureHeader
+	}
+	return genericSource{header: header, secret: []byte(secret)}
+}
+
+type genericSource struct {
+	header string
+	secret []byte
+}
+
+func (s genericSource) Parse(r *http.Request, body []byte) (*Event, error) {
+	if len(s.secret) > 0 {
+		if err := verifyHexHMAC(s.secret, r.Header.Get(s.header), body); err != nil {
+			return nil, err
+		}
+	}
+	event := newEvent("generic", r, body)
+	event.Type = r.Header.Get("X-Event-Type")
+	event.ID = r.Header.Get("Idempotency-Key")
+	if event.ID == "" {
+		event.ID = r.Header.Get("X-Request-Id")
+	}
+	return event, nil
+}
+
+// verifyHexHMAC checks a hex HMAC-SHA256 signature of body, with an optional "sha256=" prefix
+func verifyHexHMAC(secret []byte, signature string, body []byte) error {
+	if signature == "" {
+		return fmt.Errorf("%w: missing signature", ErrInvalidSignature)
+	}
+	decoded, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return fmt.Errorf("%w: signature is not hex", ErrInvalidSignature)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	if !hmac.Equal(decoded, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package trigger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newDelivery(body string, headers map[string]string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	return r
+}
+
+func TestGitHub(t *testing.T) {
+	t.Parallel()
+
+	body := `{"action":"opened"}`
+	tests := []struct {
+		name      string
+		signature string
+		wantErr   bool
+	}{
+		{name: "valid", signature: "sha256=" + sign("s3cret", body)},
+		{name: "wrong secret", signature: "sha256=" + sign("other", body), wantErr: true},
+		{name: "missing", signature: "", wantErr: true},
+		{name: "not hex", signature: "sha256=zz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := newDelivery(body, map[string]string{
+				"X-Hub-Signature-256": tt.signature,
+				"X-GitHub-Event":      "issues",
+				"X-GitHub-Delivery":   "d-1",
+			})
+			event, err := GitHub("s3cret").Parse(r, []byte(body))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSignature) {
+					t.Errorf("Parse() error = %v, want ErrInvalidSignature", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if event.Source != "github" || event.Type != "issues" || event.ID != "d-1" || event.field("action") != "opened" {
+				t.Errorf("event = %+v", event)
+			}
+		})
+	}
+}
+
+func TestStripe(t *testing.T) {
+	t.Parallel()
+
+	body := `{"id":"evt_1","type":"invoice.paid"}`
+	now := time.Unix(1700000000, 0)
+	header := func(ts time.Time, secret string) string {
+		unix := fmt.Sprint(ts.Unix())
+		return "t=" + unix + ",v1=" + sign(secret, unix+"."+body) + ",v0=ignored"
+	}
+
+	tests := []struct {
+		name    string
+		header  string
+		wantErr bool
+	}{
+		{name: "valid", header: header(now, "whsec_x")},
+		{name: "second signature matches", header: "t=1700000000,v1=" + sign("old", "1700000000."+body) + ",v1=" + sign("whsec_x", "1700000000."+body)},
+		{name: "wrong secret", header: header(now, "whsec_y"), wantErr: true},
+		{name: "too old", header: header(now.Add(-10*time.Minute), "whsec_x"), wantErr: true},
+		{name: "malformed", header: "v1=abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			source := Stripe("whsec_x", 0).(stripeSource)
+			source.now = func() time.Time { return now }
+			event, err := source.Parse(newDelivery(body, map[string]string{"Stripe-Signature": tt.header}), []byte(body))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSignature) {
+					t.Errorf("Parse() error = %v, want ErrInvalidSignature", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if event.Type != "invoice.paid" || event.ID != "evt_1" {
+				t.Errorf("event = %+v", event)
+			}
+		})
+	}
+}
+
+func TestGeneric(t *testing.T) {
+	t.Parallel()
+
+	body := "plain text alert"
+	unsigned, err := Generic("", "").Parse(newDelivery(body, map[string]string{
+		"X-Event-Type": "alert",
+		"X-Request-Id": "r-1",
+	}), []byte(body))
+	if err != nil || unsigned.Type != "alert" || unsigned.ID != "r-1" || unsigned.Data != nil {
+		t.Errorf("Parse() = %+v, %v", unsigned, err)
+	}
+
+	signed := Generic("X-Sig", "k")
+	if _, err := signed.Parse(newDelivery(body, map[string]string{"X-Sig": sign("k", body)}), []byte(body)); err != nil {
+		t.Errorf("Parse() with valid signature error = %v", err)
+	}
+	if _, err := signed.Parse(newDelivery(body, nil), []byte(body)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Parse() without signature error = %v", err)
+	}
+
+	keyed, _ := Generic("", "").Parse(newDelivery(body, map[string]string{"Idempotency-Key": "k-1", "X-Request-Id": "r-1"}), []byte(body))
+	if keyed.ID != "k-1" {
+		t.Errorf("ID = %q, want the idempotency key", keyed.ID)
+	}
+}
@@ -0,0 +1,96 @@
+// Package trigger starts flows from external events.
+//
+// Webhook runs a flow for every verified delivery to an HTTP endpoint:
+//
+//	hook := trigger.WebhookWithConfig("/hooks/github", triageFlow, trigger.Template(
+//		"New issue in {{.Data.repository.full_name}}: {{.Data.issue.title}}\n\n{{.Data.issue.body}}",
+//	), &trigger.WebhookConfig{
+//		Source: trigger.GitHub(os.Getenv("GITHUB_WEBHOOK_SECRET")),
+//		Events: []string{"issues"},
+//		Async:  true,
+//	})
+//	defer hook.Shutdown(ctx)
+//
+//	http.ListenAndServe(":8080", trigger.Mux(hook))
+//
+// A Source authenticates deliveries and identifies them (GitHub, Stripe or
+// generic HMAC-signed JSON); a Mapper turns each event into flow input.
+// Delivery IDs become idempotency keys, so a redelivered event returns the
+// recorded result instead of running the flow again.
+package trigger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"text/template"
+)
+
+// ErrSkipEvent can be returned by a Mapper to acknowledge an event without running the flow
+var ErrSkipEvent = errors.New("event skipped")
+
+// Event is a verified webhook delivery
+type Event struct {
+	Source string      // Source name: "github", "stripe" or "generic"
+	Type   string      // Event type, e.g. "issues" or "invoice.paid" (may be empty)
+	ID     string      // Delivery ID, used as the run's idempotency key (may be empty)
+	Header http.Header // Request headers
+	Body   []byte      // Raw request body
+	Data   any         // Body decoded as JSON, or nil if it isn't JSON
+}
+
+// newEvent builds an event, decoding the body when it is JSON
+func newEvent(source string, r *http.Request, body []byte) *Event {
+	event := &Event{Source: source, Header: r.Header, Body: body}
+	var data any
+	if json.Unmarshal(body, &data) == nil {
+		event.Data = data
+	}
+	return event
+}
+
+// field returns a top-level string field of the JSON body
+func (e *Event) field(name string) string {
+	if doc, ok := e.Data.(map[string]any); ok {
+		if s, ok := doc[name].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// Mapper turns an event into flow input: a string, []byte or io.Reader.
+//
+// Return ErrSkipEvent to acknowledge the delivery without running the flow.
+type Mapper func(ctx context.Context, event *Event) (any, error)
+
+// Body passes the raw request body to the flow
+func Body() Mapper {
+	return func(_ context.Context, event *Event) (any, error) {
+		return event.Body, nil
+	}
+}
+
+// Template renders a Go text/template with the Event to build the flow input.
+//
+// The template sees the Event fields, so .Data holds the decoded JSON payload.
+// An invalid template fails every delivery.
+//
+// Example:
+//
+//	trigger.Template("Customer {{.Data.data.object.customer}} paid invoice {{.Data.data.object.id}}")
+func Template(text string) Mapper {
+	tmpl, err := template.New("trigger").Option("missingkey=zero").Parse(text)
+	return func(_ context.Context, event *Event) (any, error) {
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, event); err != nil {
+			return nil, err
+		}
+		return buf.String(), nil
+	}
+}
@@ -0,0 +1,250 @@
+package trigger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultMaxBodySize is the largest delivery a webhook accepts by default (1 MiB)
+const DefaultMaxBodySize = 1 << 20
+
+// Default pool settings for asynchronous webhooks without a Runner
+const (
+	DefaultWorkers   = 4
+	DefaultQueueSize = 100
+)
+
+// WebhookConfig holds configuration for a webhook trigger
+type WebhookConfig struct {
+	// Source verifies and identifies deliveries (default: Generic("", ""), unverified)
+	Source Source
+	// Events limits runs to these event types; others are acknowledged with 204 (default: all)
+	Events []string
+	// Async acknowledges deliveries with 202 and runs the flow in the background.
+	// Use it for senders with short delivery timeouts, such as GitHub (10s).
+	Async bool
+	// Runner executes asynchronous runs (default: a Pool of DefaultWorkers
+	// workers and DefaultQueueSize queued runs, shut down by Shutdown)
+	Runner Runner
+	// Timeout bounds each run (default: none)
+	Timeout time.Duration
+	// MaxBodySize rejects larger deliveries with 413 (default: DefaultMaxBodySize)
+	MaxBodySize int64
+	// OnResult is called after every run with its output or error, e.g. to
+	// record asynchronous failures (optional)
+	OnResult func(ctx context.Context, event *Event, output []byte, err error)
+}
+
+// WebhookHandler runs a flow for each delivery to a webhook endpoint
+type WebhookHandler struct {
+	path      string
+	flow      *calque.Flow
+	mapper    Mapper
+	config    WebhookConfig
+	ownRunner bool
+}
+
+// Webhook creates a trigger running flow for every POST to path.
+//
+// Deliveries are not verified; use WebhookWithConfig with a Source for
+// GitHub, Stripe or signed generic webhooks. The flow runs while the sender
+// waits, and its output is the response body.
+//
+// Responses:
+//   - 200 with the flow output (202 when Async)
+//   - 204 for filtered or skipped events
+//   - 401 for failed verification, 413 for oversized bodies, 422 when the mapper fails
+//   - 500 when the flow fails (504 on timeout), so the sender retries
+//   - 503 when the async queue is full
+//
+// Example:
+//
+//	hook := trigger.Webhook("/hooks/alerts", alertFlow, trigger.Body())
+//	http.Handle(hook.Path(), hook)
+func Webhook(path string, flow *calque.Flow, mapper Mapper) *WebhookHandler {
+	return WebhookWithConfig(path, flow, mapper, nil)
+}
+
+// WebhookWithConfig creates a webhook trigger with custom configuration
+//
+// Example:
+//
+//	hook := trigger.WebhookWithConfig("/hooks/stripe", billingFlow, trigger.Body(), &trigger.WebhookConfig{
+//		Source:  trigger.Stripe(os.Getenv("STRIPE_WEBHOOK_SECRET"), 0),
+//		Events:  []string{"invoice.payment_failed"},
+//		Async:   true,
+//		Timeout: 2 * time.Minute,
+//	})
+func WebhookWithConfig(path string, flow *calque.Flow, mapper Mapper, config *WebhookConfig) *WebhookHandler {
+	h := &WebhookHandler{path: path, flow: flow, mapper: mapper}
+	if config != nil {
+		h.config = *config
+	}
+	if h.mapper == nil {
+		h.mapper = Body()
+	}
+	if h.config.Source == nil {
+		h.config.Source = Generic("", "")
+	}
+	if h.config.MaxBodySize <= 0 {
+		h.config.MaxBodySize = DefaultMaxBodySize
+	}
+	if h.config.Async && h.config.Runner == nil {
+		h.config.Runner = NewPool(DefaultWorkers, DefaultQueueSize)
+		h.ownRunner = true
+	}
+	return h
+}
+
+// Path returns the path the webhook serves
+func (h *WebhookHandler) Path() string {
+	return h.path
+}
+
+// Shutdown waits for background runs to finish.
+//
+// Only the default pool is shut down; a configured Runner is left to its owner.
+func (h *WebhookHandler) Shutdown(ctx context.Context) error {
+	if h.ownRunner {
+		return h.config.Runner.Shutdown(ctx)
+	}
+	return nil
+}
+
+// ServeHTTP verifies a delivery and runs the flow for it
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != h.path {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.config.MaxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to read payload", http.StatusBadRequest)
+		return
+	}
+
+	event, err := h.config.Source.Parse(r, body)
+	if err != nil {
+		calque.LogWarn(r.Context(), "webhook delivery rejected", "path", h.path, "error", err)
+		if errors.Is(err, ErrInvalidSignature) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if len(h.config.Events) > 0 && !slices.Contains(h.config.Events, event.Type) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	ctx := r.Context()
+	if h.config.Async {
+		// Background runs outlive the request but keep its values
+		ctx = context.WithoutCancel(ctx)
+	}
+	if event.ID != "" && calque.RequestID(ctx) == "" {
+		ctx = calque.WithRequestID(ctx, event.ID)
+	}
+
+	input, err := h.mapper(ctx, event)
+	if errors.Is(err, ErrSkipEvent) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		calque.LogWarn(ctx, "webhook payload could not be mapped", "path", h.path, "error", err)
+		http.Error(w, "unprocessable payload", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !h.config.Async {
+		output, err := h.run(ctx, event, input)
+		if err != nil {
+			http.Error(w, "flow failed", statusFor(err))
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write(output)
+		return
+	}
+
+	err = h.config.Runner.Submit(func(runnerCtx context.Context) {
+		// Stop when either the runner gives up or the run's own deadline passes
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stop := context.AfterFunc(runnerCtx, cancel)
+		defer stop()
+
+		if _, err := h.run(runCtx, event, input); err != nil {
+			calque.LogError(runCtx, "webhook run failed", err, "path", h.path, "event", event.Type)
+		}
+	})
+	if err != nil {
+		calque.LogWarn(ctx, "webhook run not queued", "path", h.path, "error", err)
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "busy, retry later", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// run executes the flow, keyed by the delivery ID so redeliveries are not run twice
+func (h *WebhookHandler) run(ctx context.Context, event *Event, input any) ([]byte, error) {
+	if h.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.config.Timeout)
+		defer cancel()
+	}
+
+	var opts []calque.RunOption
+	if event.ID != "" {
+		opts = append(opts, calque.WithIdempotencyKey("webhook:"+event.Source+":"+event.ID))
+	}
+
+	var output bytes.Buffer
+	err := h.flow.Run(ctx, input, &output, opts...)
+	if h.config.OnResult != nil {
+		h.config.OnResult(ctx, event, output.Bytes(), err)
+	}
+	return output.Bytes(), err
+}
+
+// statusFor maps a flow error to the status the sender sees
+func statusFor(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// Mux serves several webhooks from one handler
+//
+// Example:
+//
+//	http.ListenAndServe(":8080", trigger.Mux(githubHook, stripeHook))
+func Mux(hooks ...*WebhookHandler) *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, hook := range hooks {
+		mux.Handle(hook.Path(), hook)
+	}
+	return mux
+}
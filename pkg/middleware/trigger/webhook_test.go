@@ -0,0 +1,223 @@
+package trigger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// countingFlow uppercases its input and counts runs
+func countingFlow(runs *atomic.Int32) *calque.Flow {
+	return calque.NewFlow().UseFunc(func(req *calque.Request, res *calque.Response) error {
+		runs.Add(1)
+		var input string
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+		return calque.Write(res, strings.ToUpper(input)+" "+calque.RequestID(req.Context))
+	})
+}
+
+func serve(h http.Handler, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+
+	var runs atomic.Int32
+	failing := calque.NewFlow().UseFunc(func(req *calque.Request, _ *calque.Response) error {
+		return calque.NewErr(req.Context, "model unavailable")
+	})
+	slow := calque.NewFlow().UseFunc(func(req *calque.Request, _ *calque.Response) error {
+		<-req.Context.Done()
+		return req.Context.Err()
+	})
+	skipPings := func(_ context.Context, event *Event) (any, error) {
+		if event.Type == "ping" {
+			return nil, ErrSkipEvent
+		}
+		return event.Body, nil
+	}
+
+	github := func(path string, flow *calque.Flow, mapper Mapper, config WebhookConfig) *WebhookHandler {
+		config.Source = GitHub("s3cret")
+		return WebhookWithConfig(path, flow, mapper, &config)
+	}
+	mux := Mux(
+		github("/gh", countingFlow(&runs), skipPings, WebhookConfig{Events: []string{"issues", "ping"}, MaxBodySize: 64}),
+		github("/gh-template", countingFlow(&runs), Template("{{.Type}}: {{.Data.title}}"), WebhookConfig{}),
+		github("/gh-bad-template", countingFlow(&runs), Template("{{.Data.title"), WebhookConfig{}),
+		github("/gh-fails", failing, nil, WebhookConfig{}),
+		github("/gh-slow", slow, nil, WebhookConfig{Timeout: 50 * time.Millisecond}),
+	)
+
+	body := `{"title":"crash"}`
+	signed := func(event, delivery string) map[string]string {
+		return map[string]string{
+			"X-Hub-Signature-256": "sha256=" + sign("s3cret", body),
+			"X-GitHub-Event":      event,
+			"X-GitHub-Delivery":   delivery,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		headers    map[string]string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "runs flow", path: "/gh", headers: signed("issues", "d-1"), wantStatus: http.StatusOK, wantBody: `{"TITLE":"CRASH"} d-1`},
+		{name: "template mapper", path: "/gh-template", headers: signed("issues", "d-2"), wantStatus: http.StatusOK, wantBody: "ISSUES: CRASH d-2"},
+		{name: "filtered event", path: "/gh", headers: signed("push", "d-3"), wantStatus: http.StatusNoContent},
+		{name: "skipped event", path: "/gh", headers: signed("ping", "d-4"), wantStatus: http.StatusNoContent},
+		{name: "bad signature", path: "/gh", headers: map[string]string{"X-Hub-Signature-256": "sha256=00"}, wantStatus: http.StatusUnauthorized},
+		{name: "too large", path: "/gh", body: strings.Repeat("x", 100), headers: signed("issues", "d-5"), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "wrong method", method: http.MethodGet, path: "/gh", wantStatus: http.StatusMethodNotAllowed},
+		{name: "mapper fails", path: "/gh-bad-template", headers: signed("issues", "d-6"), wantStatus: http.StatusUnprocessableEntity},
+		{name: "flow fails", path: "/gh-fails", headers: signed("issues", "d-7"), wantStatus: http.StatusInternalServerError},
+		{name: "flow timeout", path: "/gh-slow", headers: signed("issues", "d-8"), wantStatus: http.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			payload := tt.body
+			if payload == "" {
+				payload = body
+			}
+			rec := serve(mux, method, tt.path, payload, tt.headers)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestWebhookRedelivery(t *testing.T) {
+	t.Parallel()
+
+	var runs atomic.Int32
+	hook := Webhook("/hook", countingFlow(&runs), nil)
+	headers := map[string]string{"Idempotency-Key": "evt-1"}
+
+	first := serve(hook, http.MethodPost, "/hook", "hello", headers)
+	second := serve(hook, http.MethodPost, "/hook", "hello", headers)
+	if first.Code != http.StatusOK || second.Body.String() != first.Body.String() {
+		t.Errorf("responses = %d %q, %d %q", first.Code, first.Body, second.Code, second.Body)
+	}
+	if runs.Load() != 1 {
+		t.Errorf("flow ran %d times for one delivery ID, want 1", runs.Load())
+	}
+
+	serve(hook, http.MethodPost, "/hook", "hello", nil)
+	serve(hook, http.MethodPost, "/hook", "hello", nil)
+	if runs.Load() != 3 {
+		t.Errorf("flow ran %d times, want deliveries without an ID to always run", runs.Load())
+	}
+
+	if rec := serve(hook, http.MethodPost, "/other", "hello", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("status for another path = %d, want 404", rec.Code)
+	}
+}
+
+func TestWebhookAsync(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var results []string
+	var runs atomic.Int32
+	release := make(chan struct{})
+	flow := calque.NewFlow().UseFunc(func(req *calque.Request, res *calque.Response) error {
+		<-release
+		runs.Add(1)
+		return calque.Write(res, "done")
+	})
+
+	hook := WebhookWithConfig("/hook", flow, nil, &WebhookConfig{
+		Async:  true,
+		Runner: NewPool(1, 1),
+		OnResult: func(_ context.Context, event *Event, output []byte, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, event.ID+"="+string(output))
+		},
+	})
+
+	// The client's context ends as soon as it has its 202
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader("x")).WithContext(ctx)
+	r.Header.Set("X-Request-Id", "a")
+	rec := httptest.NewRecorder()
+	hook.ServeHTTP(rec, r)
+	cancel()
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202", rec.Code)
+	}
+
+	// Fill the queue behind the running job, then expect 503
+	pool := hook.config.Runner.(*Pool)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(pool.jobs) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	statuses := []int{}
+	for range 3 {
+		statuses = append(statuses, serve(hook, http.MethodPost, "/hook", "x", map[string]string{"X-Request-Id": "b"}).Code)
+	}
+	if statuses[0] != http.StatusAccepted || statuses[2] != http.StatusServiceUnavailable {
+		t.Errorf("statuses = %v, want 503 once the queue is full", statuses)
+	}
+
+	close(release)
+	if err := hook.config.Runner.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(results) == 0 || results[0] != "a=done" {
+		t.Errorf("results = %v, want the first run to finish after its request ended", results)
+	}
+}
+
+func TestWebhookShutdownDefaultPool(t *testing.T) {
+	t.Parallel()
+
+	var runs atomic.Int32
+	hook := WebhookWithConfig("/hook", countingFlow(&runs), nil, &WebhookConfig{Async: true})
+	for range 5 {
+		if rec := serve(hook, http.MethodPost, "/hook", "x", nil); rec.Code != http.StatusAccepted {
+			t.Fatalf("status = %d, want 202", rec.Code)
+		}
+	}
+	if err := hook.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if runs.Load() != 5 {
+		t.Errorf("ran %d flows before Shutdown returned, want 5", runs.Load())
+	}
+}
@@ -0,0 +1,111 @@
+package repl
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+func builtinCommands() []Command {
+	return []Command{
+		{Name: "help", Help: "list commands", Run: cmdHelp},
+		{Name: "history", Help: "show this session's turns with timings", Run: cmdHistory},
+		{Name: "memory", Help: "show the conversation history sent to the flow", Run: cmdMemory},
+		{Name: "clear", Help: "forget the conversation so far", Run: cmdClear},
+		{Name: "usage", Help: "show token usage for the session", Run: cmdUsage},
+		{Name: "exit", Help: "end the session", Run: cmdExit},
+		{Name: "quit", Help: "end the session", Run: cmdExit},
+	}
+}
+
+func cmdHelp(s *Session, _ string) error {
+	names := make([]string, 0, len(s.commands))
+	width := 0
+	for name := range s.commands {
+		names = append(names, name)
+		width = max(width, len(name))
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(s.out, "  /%-*s  %s\n", width, name, s.commands[name].Help)
+	}
+	return nil
+}
+
+func cmdHistory(s *Session, _ string) error {
+	if len(s.history) == 0 {
+		fmt.Fprintln(s.out, "no turns yet")
+		return nil
+	}
+	for i, turn := range s.history {
+		status := "ok"
+		if turn.Err != nil {
+			status = "error: " + turn.Err.Error()
+		}
+		fmt.Fprintf(s.out, "%d. [%s, %s] %s\n", i+1, turn.Duration.Round(time.Millisecond), status, oneLine(turn.Input, 60))
+		if turn.Output != "" {
+			fmt.Fprintf(s.out, "   → %s\n", oneLine(turn.Output, 60))
+		}
+	}
+	return nil
+}
+
+func cmdMemory(s *Session, _ string) error {
+	if s.config.DisableMemory {
+		fmt.Fprintln(s.out, "memory is disabled for this session")
+		return nil
+	}
+	messages, err := s.config.Memory.Messages(s.ctx, s.config.MemoryKey)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		fmt.Fprintln(s.out, "memory is empty")
+		return nil
+	}
+	fmt.Fprintf(s.out, "%d messages under key %q:\n", len(messages), s.config.MemoryKey)
+	for _, m := range messages {
+		fmt.Fprintf(s.out, "  %s: %s\n", m.Role, oneLine(m.Text(), 100))
+	}
+	return nil
+}
+
+func cmdClear(s *Session, _ string) error {
+	if err := s.config.Memory.Clear(s.config.MemoryKey); err != nil {
+		return err
+	}
+	s.history = nil
+	fmt.Fprintln(s.out, "conversation cleared")
+	return nil
+}
+
+func cmdUsage(s *Session, _ string) error {
+	var elapsed time.Duration
+	for _, turn := range s.history {
+		elapsed += turn.Duration
+	}
+	fmt.Fprintf(s.out, "turns: %d, time in flow: %s\n", len(s.history), elapsed.Round(time.Millisecond))
+	if s.config.Usage == nil {
+		fmt.Fprintln(s.out, "token usage isn't tracked for this session")
+		return nil
+	}
+	calls, totals := s.config.Usage.Totals()
+	fmt.Fprintf(s.out, "model calls: %d, tokens: %d prompt + %d completion = %d total\n",
+		calls, totals.PromptTokens, totals.CompletionTokens, totals.TotalTokens)
+	return nil
+}
+
+func cmdExit(s *Session, _ string) error {
+	s.Exit()
+	return nil
+}
+
+// oneLine collapses whitespace and shortens s to at most n runes
+func oneLine(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,270 @@
+// Package repl runs a flow as an interactive terminal chat for manual testing.
+//
+// Each line typed is sent through the flow and the reply is streamed back as
+// it is produced. Earlier turns are kept in conversation memory and sent along
+// with every new message, so the flow sees the whole chat. Lines starting with
+// "/" are commands: /history, /memory, /usage, /clear, /help and /exit, plus
+// any added through Config.Commands.
+//
+// Example:
+//
+//	usage := &repl.Usage{}
+//	flow := calque.NewFlow().
+//		Use(prompt.System("You are a terse assistant.")).
+//		Use(ai.Agent(client, ai.WithUsageHandler(usage.Record)))
+//	err := repl.RunWithConfig(flow, &repl.Config{Usage: usage})
+package repl
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/memory"
+)
+
+// Config customizes a chat session
+type Config struct {
+	Context       context.Context            // Context for every turn; cancelling it ends the session (default: context.Background())
+	Input         io.Reader                  // Where lines are read from (default: os.Stdin)
+	Output        io.Writer                  // Where replies and command output go (default: os.Stdout)
+	Prompt        string                     // Shown before each line (default: "> ")
+	Greeting      string                     // Printed once at start (default: a hint about /help)
+	Memory        *memory.ConversationMemory // Conversation history store (default: in-memory)
+	MemoryKey     string                     // Conversation key within Memory (default: "repl")
+	DisableMemory bool                       // Send each line on its own, without earlier turns
+	Usage         *Usage                     // Token usage shown by /usage (optional)
+	Commands      []Command                  // Extra slash commands; these override built-ins with the same name
+}
+
+// Command is a slash command available in the session
+type Command struct {
+	Name string                              // Typed after the slash, e.g. "reset"
+	Help string                              // One-line description for /help
+	Run  func(s *Session, args string) error // Called with the text after the command name
+}
+
+// Turn is one exchange in the session history
+type Turn struct {
+	Input    string
+	Output   string
+	Duration time.Duration
+	Err      error
+}
+
+// Usage accumulates token usage across a session.
+// Pass Record to ai.WithUsageHandler so /usage can report it.
+type Usage struct {
+	mu     sync.Mutex
+	calls  int
+	totals ai.UsageMetadata
+}
+
+// Record adds one model call's usage
+func (u *Usage) Record(m *ai.UsageMetadata) {
+	if m == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.calls++
+	u.totals.PromptTokens += m.PromptTokens
+	u.totals.CompletionTokens += m.CompletionTokens
+	u.totals.TotalTokens += m.TotalTokens
+}
+
+// Totals returns the number of recorded calls and their summed usage
+func (u *Usage) Totals() (calls int, totals ai.UsageMetadata) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.calls, u.totals
+}
+
+// Session is the state of a running chat, passed to commands
+type Session struct {
+	ctx      context.Context
+	flow     calque.Handler
+	out      io.Writer
+	config   Config
+	history  []Turn
+	commands map[string]Command
+	done     bool
+}
+
+// Run chats with flow on stdin and stdout until EOF or /exit
+func Run(flow *calque.Flow) error {
+	return RunWithConfig(flow, nil)
+}
+
+// RunWithConfig chats with flow using custom settings
+//
+// Example:
+//
+//	err := repl.RunWithConfig(flow, &repl.Config{
+//		Prompt:        "you> ",
+//		DisableMemory: true,
+//	})
+func RunWithConfig(flow *calque.Flow, config *Config) error {
+	return NewSession(flow, config).Run()
+}
+
+// NewSession prepares a chat session without starting it
+func NewSession(flow *calque.Flow, config *Config) *Session {
+	cfg := Config{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Context == nil {
+		cfg.Context = context.Background()
+	}
+	if cfg.Input == nil {
+		cfg.Input = os.Stdin
+	}
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
+	}
+	if cfg.Prompt == "" {
+		cfg.Prompt = "> "
+	}
+	if cfg.Greeting == "" {
+		cfg.Greeting = "Type a message, /help for commands, /exit to quit."
+	}
+	if cfg.Memory == nil {
+		cfg.Memory = memory.NewConversation()
+	}
+	if cfg.MemoryKey == "" {
+		cfg.MemoryKey = "repl"
+	}
+
+	s := &Session{ctx: cfg.Context, flow: flow, out: cfg.Output, config: cfg, commands: map[string]Command{}}
+	if !cfg.DisableMemory {
+		s.flow = calque.NewFlow().
+			Use(cfg.Memory.Input(cfg.MemoryKey)).
+			Use(flow).
+			Use(cfg.Memory.Output(cfg.MemoryKey))
+	}
+	for _, c := range builtinCommands() {
+		s.commands[c.Name] = c
+	}
+	for _, c := range cfg.Commands {
+		s.commands[c.Name] = c
+	}
+	return s
+}
+
+// Run reads lines until EOF, /exit or context cancellation.
+// Turn errors are printed and the session continues.
+func (s *Session) Run() error {
+	fmt.Fprintln(s.out, s.config.Greeting)
+
+	// Read in the background so a cancelled context ends the session
+	// even while waiting for a line
+	lines := make(chan string)
+	readErr := make(chan error, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(s.config.Input)
+		scanner.Buffer(make([]byte, 64<<10), 1<<20)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-stop:
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	for !s.done {
+		fmt.Fprint(s.out, s.config.Prompt)
+		var line string
+		var ok bool
+		select {
+		case <-s.ctx.Done():
+			fmt.Fprintln(s.out)
+			return nil
+		case line, ok = <-lines:
+		}
+		if !ok {
+			fmt.Fprintln(s.out)
+			return <-readErr
+		}
+
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "/"):
+			s.command(line[1:])
+		default:
+			s.Send(line)
+		}
+	}
+	return nil
+}
+
+// Send runs one message through the flow, streaming the reply to the output
+func (s *Session) Send(input string) Turn {
+	var reply strings.Builder
+	started := time.Now()
+	err := s.flow.ServeFlow(
+		calque.NewRequest(s.ctx, strings.NewReader(input)),
+		calque.NewResponse(io.MultiWriter(s.out, &reply)),
+	)
+	turn := Turn{Input: input, Output: reply.String(), Duration: time.Since(started), Err: err}
+	s.history = append(s.history, turn)
+
+	if reply.Len() > 0 && !strings.HasSuffix(turn.Output, "\n") {
+		fmt.Fprintln(s.out)
+	}
+	if err != nil {
+		fmt.Fprintf(s.out, "error: %v\n", err)
+	}
+	return turn
+}
+
+// Output returns the writer the session prints to
+func (s *Session) Output() io.Writer {
+	return s.out
+}
+
+// Context returns the session context
+func (s *Session) Context() context.Context {
+	return s.ctx
+}
+
+// History returns the turns sent so far, oldest first
+func (s *Session) History() []Turn {
+	return slices.Clone(s.history)
+}
+
+// Memory returns the conversation store and key the session uses
+func (s *Session) Memory() (*memory.ConversationMemory, string) {
+	return s.config.Memory, s.config.MemoryKey
+}
+
+// Exit ends the session after the current command
+func (s *Session) Exit() {
+	s.done = true
+}
+
+func (s *Session) command(line string) {
+	name, args, _ := strings.Cut(line, " ")
+	cmd, ok := s.commands[name]
+	if !ok {
+		fmt.Fprintf(s.out, "unknown command /%s, try /help\n", name)
+		return
+	}
+	if err := cmd.Run(s, strings.TrimSpace(args)); err != nil {
+		fmt.Fprintf(s.out, "/%s: %v\n", name, err)
+	}
+}
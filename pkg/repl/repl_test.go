@@ -0,0 +1,121 @@
+package repl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+)
+
+// echoFlow replies with the number of lines it was sent and the last one
+func echoFlow(usage *Usage) *calque.Flow {
+	return calque.NewFlow().UseFunc(func(req *calque.Request, res *calque.Response) error {
+		var in string
+		if err := calque.Read(req, &in); err != nil {
+			return err
+		}
+		if strings.Contains(in, "fail") {
+			return errors.New("boom")
+		}
+		if usage != nil {
+			usage.Record(&ai.UsageMetadata{PromptTokens: 4, CompletionTokens: 1, TotalTokens: 5})
+		}
+		lines := strings.Split(in, "\n")
+		return calque.Write(res, strings.ToUpper(lines[len(lines)-1])+" ("+string(rune('0'+len(lines)))+")")
+	})
+}
+
+func chat(t *testing.T, flow *calque.Flow, cfg *Config, input string) string {
+	t.Helper()
+	var out bytes.Buffer
+	c := Config{}
+	if cfg != nil {
+		c = *cfg
+	}
+	c.Input = strings.NewReader(input)
+	c.Output = &out
+	if err := RunWithConfig(flow, &c); err != nil {
+		t.Fatalf("RunWithConfig() error = %v", err)
+	}
+	return out.String()
+}
+
+func TestRunKeepsHistory(t *testing.T) {
+	out := chat(t, echoFlow(nil), nil, "hello\nagain\n")
+
+	// Memory prepends earlier turns, so the second message arrives with three lines
+	for _, want := range []string{"/help", "> USER: HELLO (1)\n", "> USER: AGAIN (3)\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunWithoutMemory(t *testing.T) {
+	out := chat(t, echoFlow(nil), &Config{DisableMemory: true, Prompt: "you> "}, "hello\nagain\n/memory\n")
+	for _, want := range []string{"you> HELLO (1)", "you> AGAIN (1)", "memory is disabled"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	usage := &Usage{}
+	custom := Command{Name: "ping", Help: "reply pong", Run: func(s *Session, args string) error {
+		_, err := io.WriteString(s.Output(), "pong "+args+"\n")
+		return err
+	}}
+	input := strings.Join([]string{
+		"hi", "please fail", "/history", "/memory", "/usage",
+		"/ping a b", "/nope", "/help", "/clear", "/memory", "/exit", "never sent",
+	}, "\n")
+	out := chat(t, echoFlow(usage), &Config{Usage: usage, Commands: []Command{custom}}, input)
+
+	for _, want := range []string{
+		"error: boom",
+		"1. [", "ok] hi", "→ USER: HI (1)", "2. [", "error: boom] please fail",
+		`messages under key "repl"`, "user: hi", "assistant: USER: HI (1)",
+		"turns: 2", "model calls: 1, tokens: 4 prompt + 1 completion = 5 total",
+		"pong a b",
+		"unknown command /nope",
+		"/ping", "reply pong", "/usage",
+		"conversation cleared", "memory is empty",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "NEVER SENT") {
+		t.Error("input after /exit was sent")
+	}
+}
+
+func TestSendReturnsTurn(t *testing.T) {
+	var out bytes.Buffer
+	s := NewSession(echoFlow(nil), &Config{Output: &out, DisableMemory: true})
+	turn := s.Send("ok")
+	if turn.Err != nil || turn.Output != "OK (1)" {
+		t.Errorf("Send() = %+v", turn)
+	}
+	if h := s.History(); len(h) != 1 || h[0].Input != "ok" {
+		t.Errorf("History() = %+v", h)
+	}
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	var out bytes.Buffer
+	if err := RunWithConfig(echoFlow(nil), &Config{Context: ctx, Input: pr, Output: &out}); err != nil {
+		t.Errorf("RunWithConfig() error = %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+// Package tokenizer counts tokens for rate limiting, budgeting and context
+// windowing.
+//
+// Tokenizer is the shared abstraction. Approximate and Conservative are
+// dependency-free estimates, not model tokenizers: Approximate suits rate
+// limits and chunk sizing, while Conservative errs high for enforcing hard
+// limits such as context windows. Exact counters for a model family, such as
+// a tiktoken encoding or a SentencePiece model, plug in through Func.
+package tokenizer
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer counts the tokens a model would see for a piece of text
+type Tokenizer interface {
+	CountTokens(text []byte) int
+}
+
+// Func adapts a function to the Tokenizer interface.
+//
+// Example:
+//
+//	enc, _ := tiktoken.EncodingForModel("gpt-4o")
+//	counter := tokenizer.Func(func(text []byte) int {
+//		return len(enc.Encode(string(text), nil, nil))
+//	})
+type Func func(text []byte) int
+
+// CountTokens implements Tokenizer
+func (f Func) CountTokens(text []byte) int {
+	return f(text)
+}
+
+// BytesPerToken is the ratio Approximate assumes, typical for English text
+// with BPE tokenizers
+const BytesPerToken = 4
+
+// Approximate estimates tokens at BytesPerToken bytes each, rounding up.
+// It undercounts code and non-Latin scripts, so leave some headroom when
+// limits are tight.
+var Approximate Tokenizer = Func(func(text []byte) int {
+	return (len(text) + BytesPerToken - 1) / BytesPerToken
+})
+
+// Conservative estimates tokens so that it rarely counts fewer than a model
+// tokenizer would. Runs of ASCII letters, digits and spaces count at
+// BytesPerToken bytes per token, while each ASCII symbol and each non-ASCII
+// character counts as a token of its own, since BPE and SentencePiece
+// vocabularies split code, CJK text and other scripts far more finely than
+// English prose. Use it where an undercount lets oversized input through.
+var Conservative Tokenizer = Func(func(text []byte) int {
+	tokens, run := 0, 0
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		text = text[size:]
+		if r < utf8.RuneSelf && (r == ' ' || unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			run++
+			continue
+		}
+		tokens += (run+BytesPerToken-1)/BytesPerToken + 1
+		run = 0
+	}
+	return tokens + (run+BytesPerToken-1)/BytesPerToken
+})
+
+// OrApproximate returns t, or Approximate if t is nil
+func OrApproximate(t Tokenizer) Tokenizer {
+	if t == nil {
+		return Approximate
+	}
+	return t
+}
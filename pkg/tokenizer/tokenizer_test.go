@@ -0,0 +1,47 @@
+package tokenizer
+
+import "testing"
+
+func TestApproximate(t *testing.T) {
+	tests := map[string]int{
+		"":          0,
+		"a":         1,
+		"abcd":      1,
+		"abcde":     2,
+		"hello wor": 3,
+	}
+	for text, want := range tests {
+		if got := Approximate.CountTokens([]byte(text)); got != want {
+			t.Errorf("Approximate(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestConservative(t *testing.T) {
+	tests := map[string]int{
+		"":                   0,
+		"abcd":               1,
+		"hello world":        3,
+		"f(x) == 1;":         9,
+		"こんにちは":              5,
+		"line one\nline two": 5,
+	}
+	for text, want := range tests {
+		if got := Conservative.CountTokens([]byte(text)); got != want {
+			t.Errorf("Conservative(%q) = %d, want %d", text, got, want)
+		}
+		if got := Approximate.CountTokens([]byte(text)); got > Conservative.CountTokens([]byte(text)) {
+			t.Errorf("Approximate(%q) = %d, should not exceed Conservative", text, got)
+		}
+	}
+}
+
+func TestOrApproximate(t *testing.T) {
+	perByte := Func(func(text []byte) int { return len(text) })
+	if got := OrApproximate(perByte).CountTokens([]byte("abcdefgh")); got != 8 {
+		t.Errorf("custom tokenizer counted %d, want 8", got)
+	}
+	if got := OrApproximate(nil).CountTokens([]byte("abcdefgh")); got != 2 {
+		t.Errorf("nil tokenizer counted %d, want 2", got)
+	}
+}